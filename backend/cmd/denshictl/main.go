@@ -0,0 +1,354 @@
+// Command denshictl drives a Denshimon server from the command line.
+//
+//	denshictl login [-server URL] [-username NAME] [-scopes full] [-token TOKEN]
+//	denshictl pods [-n NAMESPACE]
+//	denshictl deployments pending
+//	denshictl deployments apply <id>...
+//	denshictl backup jobs
+//	denshictl backup run <job-id>
+//	denshictl logs [-n NAMESPACE] [-c CONTAINER] [-tail N] [-f] <pod>
+//
+// login signs in with a username and password, creates an API token and saves it with the
+// server address in denshictl.json under the user's config directory; -token saves an
+// existing API token instead. DENSHIMON_URL and DENSHIMON_TOKEN override the saved values.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/archellir/denshimon/pkg/apiclient"
+	"golang.org/x/term"
+)
+
+// requestTimeout bounds every call except following logs
+const requestTimeout = 30 * time.Second
+
+// settings is the saved login
+type settings struct {
+	Server string `json:"server"`
+	Token  string `json:"token"`
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "denshictl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: denshictl login | pods | deployments pending|apply | backup jobs|run | logs")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch args[0] {
+	case "login":
+		return login(ctx, args[1:])
+
+	case "pods":
+		flags := flag.NewFlagSet("pods", flag.ContinueOnError)
+		namespace := flags.String("n", "", "namespace (the server defaults to \"default\")")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		client, err := loadClient()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+		pods, err := client.ListPods(ctx, *namespace)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tREADY\tSTATUS\tRESTARTS\tAGE\tNODE")
+		for _, pod := range pods {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", pod.Name, pod.Ready, pod.Status, pod.Restarts, pod.Age, pod.Node)
+		}
+		return w.Flush()
+
+	case "deployments":
+		return deployments(ctx, args[1:])
+
+	case "backup":
+		return backup(ctx, args[1:])
+
+	case "logs":
+		return logs(ctx, args[1:])
+
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func login(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("login", flag.ContinueOnError)
+	server := flags.String("server", os.Getenv("DENSHIMON_URL"), "server address, e.g. https://denshimon.example.com")
+	username := flags.String("username", "", "username; prompted for when empty")
+	scopes := flags.String("scopes", "full", "comma-separated scopes of the created token")
+	token := flags.String("token", "", "save an existing API token instead of signing in")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *server == "" {
+		return fmt.Errorf("-server or DENSHIMON_URL is required")
+	}
+	if *token != "" {
+		return saveSettings(settings{Server: *server, Token: *token})
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	if *username == "" {
+		fmt.Fprint(os.Stderr, "Username: ")
+		line, err := stdin.ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read username: %w", err)
+		}
+		*username = strings.TrimSpace(line)
+	}
+	password, err := readPassword(stdin)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	session, err := apiclient.New(*server, "").Login(ctx, *username, password)
+	if err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+	created, err := apiclient.New(*server, session.Token).CreateAPIToken(ctx, apiclient.CreateAPITokenRequest{
+		Name:   "denshictl@" + hostname,
+		Scopes: strings.Split(*scopes, ","),
+	})
+	if err != nil {
+		return err
+	}
+	if err := saveSettings(settings{Server: *server, Token: created.Token}); err != nil {
+		return err
+	}
+	fmt.Printf("Logged in as %s (%s); token %s saved\n", session.User.Username, session.User.Role, created.APIToken.Prefix)
+	return nil
+}
+
+// readPassword prompts without echo on a terminal and reads a line otherwise, so a
+// password can be piped in
+func readPassword(stdin *bufio.Reader) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Password: ")
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		return string(password), nil
+	}
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func deployments(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: denshictl deployments pending | apply <id>...")
+	}
+	client, err := loadClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	switch args[0] {
+	case "pending":
+		pending, err := client.PendingDeployments(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAMESPACE\tNAME\tIMAGE\tSTATUS\tAUTHOR\tCOMMIT")
+		for _, d := range pending {
+			commit := d.GitCommitSHA
+			if len(commit) > 8 {
+				commit = commit[:8]
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", d.ID, d.Namespace, d.Name, d.Image, d.Status, d.Author, commit)
+		}
+		return w.Flush()
+
+	case "apply":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: denshictl deployments apply <id>...")
+		}
+		var failed int
+		for _, id := range args[1:] {
+			result, err := client.ApplyDeployment(ctx, id)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", id, err)
+				failed++
+				continue
+			}
+			fmt.Printf("%s: %s\n", id, result.Message)
+			for _, warning := range result.Warnings {
+				fmt.Printf("  warning: %s/%s: %s\n", warning.Namespace, warning.Budget, warning.Message)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d deployments failed to apply", failed, len(args)-1)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown deployments command %q", args[0])
+	}
+}
+
+func backup(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: denshictl backup jobs | run <job-id>")
+	}
+	client, err := loadClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	switch args[0] {
+	case "jobs":
+		jobs, err := client.ListBackupJobs(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSOURCE\tSTATUS\tLAST RUN")
+		for _, job := range jobs {
+			lastRun := "-"
+			if job.LastRun != nil {
+				lastRun = job.LastRun.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", job.ID, job.Name, job.Source, job.Status, lastRun)
+		}
+		return w.Flush()
+
+	case "run":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: denshictl backup run <job-id>")
+		}
+		if err := client.RunBackupJob(ctx, args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Backup job %s started\n", args[1])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown backup command %q", args[0])
+	}
+}
+
+func logs(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("logs", flag.ContinueOnError)
+	var opts apiclient.LogOptions
+	flags.StringVar(&opts.Namespace, "n", "", "namespace (the server defaults to \"default\")")
+	flags.StringVar(&opts.Container, "c", "", "container, for pods with several")
+	flags.IntVar(&opts.Tail, "tail", 0, "show only the last lines")
+	flags.BoolVar(&opts.Follow, "f", false, "follow new lines until interrupted")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: denshictl logs [-n namespace] [-c container] [-tail N] [-f] <pod>")
+	}
+	client, err := loadClient()
+	if err != nil {
+		return err
+	}
+	if !opts.Follow {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+	}
+
+	stream, err := client.PodLogs(ctx, flags.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	if _, err := io.Copy(os.Stdout, stream); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// loadClient builds a client from the saved login and the environment
+func loadClient() (*apiclient.Client, error) {
+	var saved settings
+	path, err := settingsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	if server := os.Getenv("DENSHIMON_URL"); server != "" {
+		saved.Server = server
+	}
+	if token := os.Getenv("DENSHIMON_TOKEN"); token != "" {
+		saved.Token = token
+	}
+	if saved.Server == "" || saved.Token == "" {
+		return nil, fmt.Errorf("not logged in; run denshictl login or set DENSHIMON_URL and DENSHIMON_TOKEN")
+	}
+	return apiclient.New(saved.Server, saved.Token), nil
+}
+
+// saveSettings writes the login readable only by the user, as it holds a token
+func saveSettings(s settings) error {
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func settingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "denshimon", "denshictl.json"), nil
+}
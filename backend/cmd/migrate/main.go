@@ -0,0 +1,159 @@
+// Command migrate shows and changes the schema version of the Denshimon database.
+//
+//	migrate status
+//	migrate up
+//	migrate down <module> <version>
+//	migrate encrypt
+//
+// The server applies pending migrations itself at startup; this tool is for inspecting a
+// database and reverting a module before downgrading. encrypt seals credentials stored
+// before ENCRYPTION_KEY was set with that key, including repository credentials sealed
+// under GITOPS_CREDENTIALS_KEY.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/archellir/denshimon/internal/alerts"
+	"github.com/archellir/denshimon/internal/database"
+	"github.com/archellir/denshimon/internal/database/migrate"
+	"github.com/archellir/denshimon/internal/deployments"
+	"github.com/archellir/denshimon/internal/events"
+	"github.com/archellir/denshimon/internal/gitops"
+	"github.com/archellir/denshimon/internal/incidents"
+	"github.com/archellir/denshimon/internal/monitors"
+	"github.com/archellir/denshimon/internal/opa"
+	"github.com/archellir/denshimon/internal/providers/backup"
+	"github.com/archellir/denshimon/internal/recordings"
+	"github.com/archellir/denshimon/internal/sbom"
+	"github.com/archellir/denshimon/internal/secretbox"
+	"github.com/archellir/denshimon/internal/webhooks"
+	"github.com/archellir/denshimon/pkg/config"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// encryptedColumns hold credentials sealed by secretbox
+var encryptedColumns = []struct{ table, id, column string }{
+	{"container_registries", "id", "config"},
+	{"database_connections", "id", "config"},
+	{"backup_storage", "id", "config"},
+	{"gitops_repositories", "id", "webhook_secret"},
+	{"webhooks", "id", "secret"},
+}
+
+// sources are migrated in this order; later modules may reference earlier tables
+var sources = []migrate.Source{
+	database.Migrations,
+	database.AuthMigrations,
+	gitops.Migrations,
+	deployments.Migrations,
+	backup.Migrations,
+	monitors.Migrations,
+	incidents.Migrations,
+	opa.Migrations,
+	sbom.Migrations,
+	webhooks.Migrations,
+	events.Migrations,
+	alerts.Migrations,
+	recordings.Migrations,
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate status | up | down <module> <version> | encrypt")
+	}
+
+	cfg := config.Load()
+	db, err := sql.Open("sqlite3", cfg.DatabasePath+"?_journal_mode=WAL&_foreign_keys=1")
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	switch args[0] {
+	case "status":
+		for _, src := range sources {
+			status, err := migrate.GetStatus(ctx, db, src)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%-12s version %d of %d, pending %v\n", status.Module, status.Current, status.Latest, status.Pending)
+		}
+		return nil
+
+	case "up":
+		for _, src := range sources {
+			if err := migrate.Up(ctx, db, src); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "down":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: migrate down <module> <version>")
+		}
+		version, err := strconv.Atoi(args[2])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[2])
+		}
+		for _, src := range sources {
+			if src.Module == args[1] {
+				return migrate.Down(ctx, db, src, version)
+			}
+		}
+		return fmt.Errorf("unknown module %q", args[1])
+
+	case "encrypt":
+		box, err := secretbox.New(cfg.EncryptionKey)
+		if err != nil {
+			return err
+		}
+		for _, src := range sources {
+			if err := migrate.Up(ctx, db, src); err != nil {
+				return err
+			}
+		}
+		for _, c := range encryptedColumns {
+			count, err := box.EncryptColumn(ctx, db, c.table, c.id, c.column)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%-22s %d rows encrypted\n", c.table, count)
+		}
+
+		// Repository credentials are already sealed under their own key, so they are
+		// opened with it and sealed again
+		credentialKey := os.Getenv("GITOPS_CREDENTIALS_KEY")
+		if credentialKey == "" {
+			credentialKey = cfg.PasetoKey
+		}
+		credentials := gitops.NewService(db, "", "")
+		if err := credentials.SetCredentialKey(ctx, credentialKey); err != nil && !errors.Is(err, gitops.ErrCredentialKeyMissing) {
+			return err
+		}
+		credentials.SetEncryption(box)
+		count, err := credentials.ResealCredentials(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-22s %d rows encrypted\n", "gitops_credentials", count)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
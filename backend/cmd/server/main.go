@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	httphandlers "github.com/archellir/denshimon/internal/http"
+	"github.com/archellir/denshimon/internal/admission"
+	"github.com/archellir/denshimon/internal/audit"
+	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/internal/database"
+	"github.com/archellir/denshimon/internal/discovery"
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/internal/metrics"
+	"github.com/archellir/denshimon/internal/websocket"
+	"github.com/archellir/denshimon/pkg/config"
+)
+
+//go:embed all:spa
+var frontendAssets embed.FS
+
+func main() {
+	// Initialize structured JSON logger for production
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+	slog.SetDefault(logger)
+
+	// Load configuration
+	cfg := config.Load()
+
+	// Initialize SQLite database
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	if err != nil {
+		slog.Error("Failed to initialize SQLite database", "error", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	// Start cleanup worker for expired sessions and cache
+	db.StartCleanupWorker()
+
+	// Initialize Kubernetes client (optional in development)
+	k8sClient, err := k8s.NewClient(cfg.KubeConfig)
+	if err != nil {
+		if cfg.Environment == "production" {
+			slog.Error("Failed to initialize Kubernetes client", "error", err)
+			os.Exit(1)
+		}
+		slog.Warn("Kubernetes client not available - some features will be disabled", "error", err)
+		k8sClient = nil
+	}
+
+	// Initialize PASETO auth with database adapter
+	dbAdapter := auth.NewDatabaseAdapter(db)
+	authService := auth.NewService(cfg.PasetoKey, db, dbAdapter)
+
+	// Initialize WebSocket hub
+	wsHub := websocket.NewHub()
+	go wsHub.Run() // Start the hub in a goroutine
+
+	// Initialize metrics service and WebSocket publisher
+	metricsService := metrics.NewService(k8sClient)
+	publisher := websocket.NewPublisher(wsHub, k8sClient, metricsService)
+	publisher.SetServiceDiscovery(discovery.NewService(k8sClient, discovery.NewStore(db.DB)))
+	go publisher.Start() // Start real-time data publisher
+
+	// Setup HTTP router using standard library
+	mux := http.NewServeMux()
+
+	// API routes
+	httphandlers.RegisterRoutes(mux, authService, k8sClient, db, wsHub)
+
+	// Health check endpoint
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"healthy"}`))
+	})
+
+	// Serve embedded SPA assets
+	frontendFS, err := fs.Sub(frontendAssets, "spa")
+	if err != nil {
+		slog.Error("Failed to create frontend filesystem", "error", err)
+		os.Exit(1)
+	}
+
+	// SPA handler that serves static files or index.html fallback
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// Don't serve frontend for API routes
+		if strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/health") {
+			http.NotFound(w, r)
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		if path == "" {
+			path = "index.html"
+		}
+
+		// Try to serve the static file
+		file, err := frontendFS.Open(path)
+		if err != nil {
+			// File not found - serve index.html for SPA routing
+			// This allows React Router to handle client-side routes
+			file, err = frontendFS.Open("index.html")
+			if err != nil {
+				http.Error(w, "Frontend not found", http.StatusNotFound)
+				return
+			}
+			path = "index.html"
+		}
+		defer file.Close()
+
+		// Set appropriate content type
+		if strings.HasSuffix(path, ".css") {
+			w.Header().Set("Content-Type", "text/css; charset=utf-8")
+		} else if strings.HasSuffix(path, ".js") {
+			w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+		} else if strings.HasSuffix(path, ".html") {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		} else if strings.HasSuffix(path, ".svg") {
+			w.Header().Set("Content-Type", "image/svg+xml")
+		}
+
+		// Cache static assets (not index.html)
+		if path != "index.html" {
+			w.Header().Set("Cache-Control", "public, max-age=31536000") // 1 year
+		} else {
+			w.Header().Set("Cache-Control", "no-cache") // Don't cache SPA entry point
+		}
+
+		// Copy file content to response
+		if stat, err := file.Stat(); err == nil {
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", stat.Size()))
+		}
+		io.Copy(w, file)
+	})
+
+	// Create HTTP server
+	srv := &http.Server{
+		Addr:         ":" + cfg.Port,
+		Handler:      mux,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in goroutine
+	go func() {
+		slog.Info("Starting server", "port", cfg.Port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("Server failed to start", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	webhookSrv := startAdmissionWebhook(db, k8sClient)
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	slog.Info("Shutting down server...")
+
+	// Graceful shutdown with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Stop WebSocket components
+	publisher.Stop()
+	wsHub.Shutdown()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("Server forced to shutdown", "error", err)
+	}
+	if webhookSrv != nil {
+		if err := webhookSrv.Shutdown(ctx); err != nil {
+			slog.Error("Admission webhook forced to shutdown", "error", err)
+		}
+	}
+
+	slog.Info("Server exited")
+}
+
+// startAdmissionWebhook serves the validating webhook protecting managed resources on its
+// own TLS listener when ADMISSION_WEBHOOK_ADDR is set. It returns nil when disabled.
+func startAdmissionWebhook(db *database.SQLiteDB, k8sClient *k8s.Client) *http.Server {
+	config := admission.ConfigFromEnv()
+	if config.Addr == "" {
+		return nil
+	}
+
+	webhook := admission.NewWebhook(config)
+	if store, err := audit.NewStore(db.DB); err == nil {
+		webhook.SetAuditStore(store)
+	} else {
+		slog.Warn("Break-glass edits will not be audited", "error", err)
+	}
+	if k8sClient != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := webhook.AllowSelf(ctx, k8sClient.Clientset()); err != nil {
+			slog.Warn("Admission webhook cannot identify Denshimon, add it to ADMISSION_ALLOWED_USERS", "error", err)
+		}
+		cancel()
+	}
+
+	webhookSrv, err := admission.NewServer(config, webhook)
+	if err != nil {
+		slog.Warn("Admission webhook disabled", "error", err)
+		return nil
+	}
+	go func() {
+		slog.Info("Starting admission webhook", "addr", config.Addr)
+		if err := webhookSrv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			slog.Error("Admission webhook failed", "error", err)
+		}
+	}()
+	return webhookSrv
+}
@@ -0,0 +1,240 @@
+// Package admission is an optional validating webhook that rejects edits made outside
+// Denshimon to the resources it manages, so strict GitOps setups cannot drift.
+//
+// Resources labeled managed-by=denshimon (or app.kubernetes.io/managed-by=denshimon) may
+// only be updated or deleted by Denshimon itself, the cluster's own controllers and the
+// configured users. Anyone else needs the break-glass annotation, whose value should say
+// why, e.g. denshimon.io/break-glass: "INC-42 hotfix".
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/audit"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// BreakGlassAnnotation lets a user edit a managed resource out of band
+const BreakGlassAnnotation = "denshimon.io/break-glass"
+
+// managedLabels mark resources managed by Denshimon
+var managedLabels = []string{"managed-by", "app.kubernetes.io/managed-by"}
+
+const managedBy = "denshimon"
+
+// systemUsers are the cluster's controllers, which update managed resources as part of
+// running them, e.g. the revision annotation of a Deployment
+var systemUsers = []string{"system:kube-controller-manager", "system:kube-scheduler"}
+
+const systemServiceAccountPrefix = "system:serviceaccount:kube-system:"
+
+// maxReviewSize limits the AdmissionReview bodies read
+const maxReviewSize = 3 << 20
+
+// Config is the webhook listener and who may edit managed resources
+type Config struct {
+	Addr         string   // e.g. :8443; empty disables the webhook
+	CertFile     string   // serving certificate, reloaded when it changes
+	KeyFile      string   //
+	AllowedUsers []string // users and service accounts allowed to edit, besides Denshimon
+}
+
+// ConfigFromEnv reads ADMISSION_WEBHOOK_ADDR, ADMISSION_TLS_CERT, ADMISSION_TLS_KEY and
+// the comma-separated ADMISSION_ALLOWED_USERS
+func ConfigFromEnv() Config {
+	config := Config{
+		Addr:     os.Getenv("ADMISSION_WEBHOOK_ADDR"),
+		CertFile: os.Getenv("ADMISSION_TLS_CERT"),
+		KeyFile:  os.Getenv("ADMISSION_TLS_KEY"),
+	}
+	for _, user := range strings.Split(os.Getenv("ADMISSION_ALLOWED_USERS"), ",") {
+		if user = strings.TrimSpace(user); user != "" {
+			config.AllowedUsers = append(config.AllowedUsers, user)
+		}
+	}
+	return config
+}
+
+// Webhook validates admission requests for managed resources
+type Webhook struct {
+	allowedUsers []string
+	audit        *audit.Store // records break-glass edits; nil records none
+}
+
+// NewWebhook creates a webhook allowing the configured users
+func NewWebhook(config Config) *Webhook {
+	return &Webhook{allowedUsers: config.AllowedUsers}
+}
+
+// SetAuditStore records break-glass edits in the audit trail
+func (w *Webhook) SetAuditStore(store *audit.Store) {
+	w.audit = store
+}
+
+// AllowSelf allows the identity Denshimon's Kubernetes client authenticates as, which
+// applies the resources it manages
+func (w *Webhook) AllowSelf(ctx context.Context, clientset kubernetes.Interface) error {
+	review, err := clientset.AuthenticationV1().SelfSubjectReviews().Create(ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to look up own identity: %w", err)
+	}
+	if user := review.Status.UserInfo.Username; user != "" {
+		w.allowedUsers = append(w.allowedUsers, user)
+	}
+	return nil
+}
+
+// ServeHTTP answers an AdmissionReview
+func (w *Webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, maxReviewSize))
+	if err != nil {
+		http.Error(rw, "failed to read review", http.StatusBadRequest)
+		return
+	}
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(rw, "invalid admission review", http.StatusBadRequest)
+		return
+	}
+
+	response := w.Review(r.Context(), review.Request)
+	review.Response = response
+	review.Request = nil
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(review)
+}
+
+// Review decides one admission request
+func (w *Webhook) Review(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{UID: req.UID, Allowed: true}
+	if req.Operation != admissionv1.Update && req.Operation != admissionv1.Delete {
+		return response
+	}
+	// Controllers report progress through the status subresource
+	if req.SubResource == "status" {
+		return response
+	}
+
+	old, err := objectMeta(req.OldObject.Raw)
+	if err != nil || !managed(old.Labels) {
+		return response
+	}
+	if w.allowed(req.UserInfo.Username) {
+		return response
+	}
+
+	// A delete carries no new object; the resource must have been annotated first
+	current := old
+	if req.Operation == admissionv1.Update {
+		if current, err = objectMeta(req.Object.Raw); err != nil {
+			current = old
+		}
+	}
+	resource := resourceName(req)
+	if reason := strings.TrimSpace(current.Annotations[BreakGlassAnnotation]); reason != "" {
+		slog.Warn("Break-glass edit of a Denshimon-managed resource", "user", req.UserInfo.Username, "operation", req.Operation, "resource", resource, "reason", reason)
+		w.recordBreakGlass(ctx, req, resource, reason)
+		response.Warnings = []string{fmt.Sprintf("%s is managed by Denshimon; this break-glass change drifts from GitOps until it is committed", resource)}
+		return response
+	}
+
+	response.Allowed = false
+	response.Result = &metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusForbidden,
+		Reason:  metav1.StatusReasonForbidden,
+		Message: fmt.Sprintf("%s is managed by Denshimon: change it through GitOps, or set the %s annotation to the reason for an emergency change", resource, BreakGlassAnnotation),
+	}
+	return response
+}
+
+func (w *Webhook) allowed(user string) bool {
+	if strings.HasPrefix(user, systemServiceAccountPrefix) || slices.Contains(systemUsers, user) {
+		return true
+	}
+	return slices.Contains(w.allowedUsers, user)
+}
+
+func (w *Webhook) recordBreakGlass(ctx context.Context, req *admissionv1.AdmissionRequest, resource, reason string) {
+	if w.audit == nil {
+		return
+	}
+	entry := audit.Entry{
+		User:     req.UserInfo.Username,
+		Action:   "admission.break_glass." + strings.ToLower(string(req.Operation)),
+		Resource: resource,
+		Success:  true,
+		Message:  reason,
+	}
+	if err := w.audit.Record(ctx, entry); err != nil {
+		slog.Warn("Failed to record break-glass edit", "resource", resource, "error", err)
+	}
+}
+
+func objectMeta(raw []byte) (metav1.ObjectMeta, error) {
+	var obj struct {
+		Metadata metav1.ObjectMeta `json:"metadata"`
+	}
+	if len(raw) == 0 {
+		return obj.Metadata, fmt.Errorf("no object")
+	}
+	err := json.Unmarshal(raw, &obj)
+	return obj.Metadata, err
+}
+
+func managed(labels map[string]string) bool {
+	for _, label := range managedLabels {
+		if labels[label] == managedBy {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceName is kind/namespace/name, or kind/name for cluster-scoped resources
+func resourceName(req *admissionv1.AdmissionRequest) string {
+	kind := strings.ToLower(req.Kind.Kind)
+	if req.Namespace == "" {
+		return kind + "/" + req.Name
+	}
+	return kind + "/" + req.Namespace + "/" + req.Name
+}
+
+// NewServer creates the webhook's TLS listener, serving reviews on /validate
+func NewServer(config Config, webhook *Webhook) (*http.Server, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, fmt.Errorf("ADMISSION_TLS_CERT and ADMISSION_TLS_KEY are required")
+	}
+	certificates, err := newCertReloader(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/validate", webhook)
+	mux.HandleFunc("GET /healthz", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("ok"))
+	})
+	return &http.Server{
+		Addr:         config.Addr,
+		Handler:      mux,
+		TLSConfig:    certificates.tlsConfig(),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}, nil
+}
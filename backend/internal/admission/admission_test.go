@@ -0,0 +1,178 @@
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func object(t *testing.T, labels, annotations map[string]string) runtime.RawExtension {
+	t.Helper()
+	raw, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":        "web",
+			"namespace":   "shop",
+			"labels":      labels,
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runtime.RawExtension{Raw: raw}
+}
+
+func review(t *testing.T, webhook *Webhook, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	t.Helper()
+	req.UID = "uid-1"
+	req.Kind = metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	req.Namespace = "shop"
+	req.Name = "web"
+	body, _ := json.Marshal(admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  req,
+	})
+
+	rec := httptest.NewRecorder()
+	webhook.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var out admissionv1.AdmissionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Response == nil || out.Response.UID != "uid-1" {
+		t.Fatalf("response = %+v, want the request UID", out.Response)
+	}
+	if out.APIVersion != "admission.k8s.io/v1" || out.Kind != "AdmissionReview" {
+		t.Errorf("type = %s %s", out.APIVersion, out.Kind)
+	}
+	return out.Response
+}
+
+var managedLabelSet = map[string]string{"app": "web", "managed-by": "denshimon"}
+
+func TestReviewManagedResources(t *testing.T) {
+	webhook := NewWebhook(Config{AllowedUsers: []string{"system:serviceaccount:denshimon:denshimon"}})
+	user := authenticationv1.UserInfo{Username: "alice"}
+	breakGlass := map[string]string{BreakGlassAnnotation: "INC-42 hotfix"}
+
+	tests := []struct {
+		name    string
+		req     admissionv1.AdmissionRequest
+		allowed bool
+		warned  bool
+	}{
+		{
+			name:    "managed update denied",
+			req:     admissionv1.AdmissionRequest{Operation: admissionv1.Update, UserInfo: user, Object: object(t, managedLabelSet, nil), OldObject: object(t, managedLabelSet, nil)},
+			allowed: false,
+		},
+		{
+			name:    "managed delete denied",
+			req:     admissionv1.AdmissionRequest{Operation: admissionv1.Delete, UserInfo: user, OldObject: object(t, managedLabelSet, nil)},
+			allowed: false,
+		},
+		{
+			name:    "removing the managed label denied",
+			req:     admissionv1.AdmissionRequest{Operation: admissionv1.Update, UserInfo: user, Object: object(t, map[string]string{"app": "web"}, nil), OldObject: object(t, managedLabelSet, nil)},
+			allowed: false,
+		},
+		{
+			name:    "break-glass update allowed with a warning",
+			req:     admissionv1.AdmissionRequest{Operation: admissionv1.Update, UserInfo: user, Object: object(t, managedLabelSet, breakGlass), OldObject: object(t, managedLabelSet, nil)},
+			allowed: true,
+			warned:  true,
+		},
+		{
+			name:    "break-glass delete allowed with a warning",
+			req:     admissionv1.AdmissionRequest{Operation: admissionv1.Delete, UserInfo: user, OldObject: object(t, managedLabelSet, breakGlass)},
+			allowed: true,
+			warned:  true,
+		},
+		{
+			name:    "empty break-glass reason denied",
+			req:     admissionv1.AdmissionRequest{Operation: admissionv1.Update, UserInfo: user, Object: object(t, managedLabelSet, map[string]string{BreakGlassAnnotation: " "}), OldObject: object(t, managedLabelSet, nil)},
+			allowed: false,
+		},
+		{
+			name:    "recommended label denied",
+			req:     admissionv1.AdmissionRequest{Operation: admissionv1.Update, UserInfo: user, Object: object(t, nil, nil), OldObject: object(t, map[string]string{"app.kubernetes.io/managed-by": "denshimon"}, nil)},
+			allowed: false,
+		},
+		{
+			name:    "unmanaged update allowed",
+			req:     admissionv1.AdmissionRequest{Operation: admissionv1.Update, UserInfo: user, Object: object(t, nil, nil), OldObject: object(t, map[string]string{"managed-by": "helm"}, nil)},
+			allowed: true,
+		},
+		{
+			name:    "create allowed",
+			req:     admissionv1.AdmissionRequest{Operation: admissionv1.Create, UserInfo: user, Object: object(t, managedLabelSet, nil)},
+			allowed: true,
+		},
+		{
+			name:    "status subresource allowed",
+			req:     admissionv1.AdmissionRequest{Operation: admissionv1.Update, SubResource: "status", UserInfo: user, Object: object(t, managedLabelSet, nil), OldObject: object(t, managedLabelSet, nil)},
+			allowed: true,
+		},
+		{
+			name:    "configured user allowed",
+			req:     admissionv1.AdmissionRequest{Operation: admissionv1.Update, UserInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:denshimon:denshimon"}, Object: object(t, managedLabelSet, nil), OldObject: object(t, managedLabelSet, nil)},
+			allowed: true,
+		},
+		{
+			name:    "cluster controller allowed",
+			req:     admissionv1.AdmissionRequest{Operation: admissionv1.Update, UserInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:kube-system:deployment-controller"}, Object: object(t, managedLabelSet, nil), OldObject: object(t, managedLabelSet, nil)},
+			allowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := review(t, webhook, &tt.req)
+			if response.Allowed != tt.allowed {
+				t.Fatalf("allowed = %v, want %v (%+v)", response.Allowed, tt.allowed, response.Result)
+			}
+			if !tt.allowed && (response.Result == nil || !strings.Contains(response.Result.Message, BreakGlassAnnotation)) {
+				t.Errorf("denial %+v should explain the break-glass annotation", response.Result)
+			}
+			if warned := len(response.Warnings) > 0; warned != tt.warned {
+				t.Errorf("warnings = %v, want warned %v", response.Warnings, tt.warned)
+			}
+		})
+	}
+}
+
+func TestServeHTTPRejectsInvalidReviews(t *testing.T) {
+	webhook := NewWebhook(Config{})
+	for _, body := range []string{"not json", `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview"}`} {
+		rec := httptest.NewRecorder()
+		webhook.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("ADMISSION_WEBHOOK_ADDR", ":8443")
+	t.Setenv("ADMISSION_ALLOWED_USERS", " ops-bot , ,flux ")
+	config := ConfigFromEnv()
+	if config.Addr != ":8443" {
+		t.Errorf("addr = %q", config.Addr)
+	}
+	if strings.Join(config.AllowedUsers, "|") != "ops-bot|flux" {
+		t.Errorf("allowed users = %q", config.AllowedUsers)
+	}
+}
@@ -0,0 +1,307 @@
+package alerts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/archellir/denshimon/internal/bus"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestManager(t *testing.T, alerts *[]Alert) *Manager {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewManager(store)
+	manager.AddSource("gitops", Source{
+		List: func(context.Context) ([]Alert, error) { return *alerts, nil },
+		Acknowledge: func(_ context.Context, id string) error {
+			for i := range *alerts {
+				if (*alerts)[i].ID == id {
+					(*alerts)[i].Acknowledged = true
+					return nil
+				}
+			}
+			return errors.New("alert not found")
+		},
+	})
+	return manager
+}
+
+func TestDeduplicate(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	alerts := Deduplicate([]Alert{
+		{ID: "1", Source: "gitops", Type: "sync_failure", Title: "Sync Failed", Labels: map[string]string{"repository": "infra"}, Timestamp: base},
+		{ID: "2", Source: "gitops", Type: "sync_failure", Title: "Sync Failed", Labels: map[string]string{"repository": "infra"}, Timestamp: base.Add(time.Hour), Acknowledged: true},
+		{ID: "3", Source: "gitops", Type: "sync_failure", Title: "Sync Failed", Labels: map[string]string{"repository": "apps"}, Timestamp: base},
+		{ID: "4", Source: "backups", Type: "job_failed", Title: "Backup failed", DedupKey: "backups/job-1", Timestamp: base},
+	})
+
+	if len(alerts) != 3 {
+		t.Fatalf("expected 3 alerts, got %d", len(alerts))
+	}
+	folded := alerts[0]
+	if folded.ID != "2" || folded.Count != 2 || !folded.FirstSeen.Equal(base) || !folded.Acknowledged {
+		t.Errorf("unexpected folded alert: %+v", folded)
+	}
+	if folded.DedupKey != "gitops/sync_failure/repository=infra" {
+		t.Errorf("unexpected dedup key %q", folded.DedupKey)
+	}
+	if alerts[2].DedupKey != "backups/job-1" {
+		t.Errorf("expected source dedup key to be kept, got %q", alerts[2].DedupKey)
+	}
+
+	// Acknowledging an older alert doesn't acknowledge a newer repeat
+	alerts = Deduplicate([]Alert{
+		{ID: "1", Source: "backups", Type: "job_failed", DedupKey: "backups/job-1", Timestamp: base, Acknowledged: true},
+		{ID: "2", Source: "backups", Type: "job_failed", DedupKey: "backups/job-1", Timestamp: base.Add(time.Hour)},
+	})
+	if len(alerts) != 1 || alerts[0].ID != "2" || alerts[0].Acknowledged {
+		t.Errorf("expected the newer unacknowledged alert, got %+v", alerts)
+	}
+}
+
+func TestSilences(t *testing.T) {
+	ctx := context.Background()
+	alerts := []Alert{
+		{ID: "1", Type: "sync_failure", Severity: SeverityWarning, Title: "Sync Failed", Labels: map[string]string{"repository": "infra"}, Timestamp: time.Now()},
+		{ID: "2", Type: "sync_failure", Severity: SeverityWarning, Title: "Sync Failed", Labels: map[string]string{"repository": "infra-staging"}, Timestamp: time.Now()},
+	}
+	manager := newTestManager(t, &alerts)
+
+	if _, err := manager.CreateSilence(ctx, Silence{ExpiresAt: time.Now().Add(time.Hour)}); err == nil {
+		t.Error("expected a silence without matchers to be rejected")
+	}
+	if _, err := manager.CreateSilence(ctx, Silence{Matchers: Matchers{TitlePattern: "("}, ExpiresAt: time.Now().Add(time.Hour)}); err == nil {
+		t.Error("expected an invalid pattern to be rejected")
+	}
+
+	// Label patterns are anchored, so this leaves infra-staging alone
+	silence, err := manager.CreateSilence(ctx, Silence{
+		Matchers:  Matchers{TitlePattern: "(?i)sync", Labels: map[string]string{"repository": "infra", "source": "gitops"}},
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed, err := manager.List(ctx, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].ID != "2" {
+		t.Fatalf("expected only the unsilenced alert, got %+v", listed)
+	}
+	if id := manager.SilencedBy(ctx, Alert{Source: "gitops", Title: "Sync failed", Labels: map[string]string{"repository": "infra"}}); id != silence.ID {
+		t.Errorf("expected a raised alert to be silenced by %s, got %q", silence.ID, id)
+	}
+	listed, _ = manager.List(ctx, ListOptions{IncludeSilenced: true})
+	if len(listed) != 2 {
+		t.Fatalf("expected silenced alerts when asked for, got %d", len(listed))
+	}
+
+	if err := manager.ExpireSilence(ctx, silence.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.ExpireSilence(ctx, "missing"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if listed, _ = manager.List(ctx, ListOptions{}); len(listed) != 2 {
+		t.Errorf("expected expired silence to stop applying, got %d alerts", len(listed))
+	}
+	active, _ := manager.ListSilences(ctx, false)
+	all, _ := manager.ListSilences(ctx, true)
+	if len(active) != 0 || len(all) != 1 {
+		t.Errorf("expected expired silence kept but inactive, got %d active and %d total", len(active), len(all))
+	}
+}
+
+func TestEscalate(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	alerts := []Alert{
+		{ID: "1", Type: "repository_unreachable", Severity: SeverityWarning, Title: "Repository Unreachable", Timestamp: start},
+	}
+	manager := newTestManager(t, &alerts)
+	now := start
+	manager.now = func() time.Time { return now }
+
+	if _, err := manager.CreateRule(ctx, EscalationRule{Name: "page", AfterMinutes: 30, RepeatMinutes: 15, EscalateTo: SeverityCritical}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.CreateRule(ctx, EscalationRule{Name: "bad", AfterMinutes: 5, EscalateTo: "urgent"}); err == nil {
+		t.Error("expected an unknown severity to be rejected")
+	}
+
+	notifications := func() (int, string) {
+		t.Helper()
+		if err := manager.Sync(ctx); err != nil {
+			t.Fatal(err)
+		}
+		listed, err := manager.List(ctx, ListOptions{})
+		if err != nil || len(listed) == 0 {
+			t.Fatalf("failed to list alerts: %v", err)
+		}
+		return listed[0].Notifications, listed[0].Severity
+	}
+
+	now = start.Add(10 * time.Minute)
+	if n, severity := notifications(); n != 0 || severity != SeverityWarning {
+		t.Errorf("expected no escalation before 30 minutes, got %d notifications at %s", n, severity)
+	}
+	now = start.Add(31 * time.Minute)
+	if n, severity := notifications(); n != 1 || severity != SeverityCritical {
+		t.Errorf("expected escalation to critical, got %d notifications at %s", n, severity)
+	}
+	now = start.Add(40 * time.Minute)
+	if n, _ := notifications(); n != 1 {
+		t.Errorf("expected no repeat before 15 minutes, got %d notifications", n)
+	}
+	now = start.Add(47 * time.Minute)
+	if n, _ := notifications(); n != 2 {
+		t.Errorf("expected a repeat notification, got %d notifications", n)
+	}
+
+	// Acknowledged alerts stop escalating
+	alerts[0].Acknowledged = true
+	now = start.Add(90 * time.Minute)
+	if n, _ := notifications(); n != 2 {
+		t.Errorf("expected acknowledged alert not to be notified, got %d notifications", n)
+	}
+
+	// A resolved alert forgets its escalation
+	now = now.Add(time.Minute)
+	alerts = nil
+	if err := manager.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	alerts = []Alert{{ID: "2", Type: "repository_unreachable", Severity: SeverityWarning, Title: "Repository Unreachable", Timestamp: now}}
+	if n, severity := notifications(); n != 0 || severity != SeverityWarning {
+		t.Errorf("expected a raised-again alert to start over, got %d notifications at %s", n, severity)
+	}
+}
+
+func TestInbox(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	alerts := []Alert{
+		{ID: "1", Type: "sync_failure", Severity: SeverityWarning, Title: "Sync Failed", Labels: map[string]string{"repository": "infra"}, Timestamp: start},
+		{ID: "2", Type: "repository_unreachable", Severity: SeverityCritical, Title: "Repository Unreachable", Labels: map[string]string{"repository": "apps"}, Timestamp: start},
+	}
+	manager := newTestManager(t, &alerts)
+	now := start
+	manager.now = func() time.Time { return now }
+
+	list := func(opts ListOptions) []Alert {
+		t.Helper()
+		listed, err := manager.List(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return listed
+	}
+
+	if listed := list(ListOptions{}); len(listed) != 2 || listed[0].ID != "2" || listed[0].Status != StatusActive {
+		t.Fatalf("expected both alerts, critical first, got %+v", listed)
+	}
+	if listed := list(ListOptions{Severity: SeverityWarning}); len(listed) != 1 || listed[0].ID != "1" {
+		t.Errorf("expected the warning only, got %+v", listed)
+	}
+
+	if err := manager.Acknowledge(ctx, "gitops", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Acknowledge(ctx, "backups", "1"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for an unknown source, got %v", err)
+	}
+	now = now.Add(time.Minute)
+	if listed := list(ListOptions{Status: StatusAcknowledged}); len(listed) != 1 || listed[0].ID != "1" {
+		t.Errorf("expected the acknowledged alert, got %+v", listed)
+	}
+
+	// Alerts no longer reported by their source are resolved, and come back as new
+	alerts = alerts[:1]
+	now = now.Add(time.Minute)
+	if err := manager.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if listed := list(ListOptions{}); len(listed) != 1 || listed[0].ID != "1" {
+		t.Errorf("expected the resolved alert to leave the inbox, got %+v", listed)
+	}
+	resolved := list(ListOptions{Status: StatusResolved})
+	if len(resolved) != 1 || resolved[0].ID != "2" || resolved[0].ResolvedAt == nil || !resolved[0].ResolvedAt.Equal(now) {
+		t.Fatalf("expected the resolved alert with its resolution time, got %+v", resolved)
+	}
+
+	now = now.Add(ResolvedRetention + time.Minute)
+	if err := manager.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if resolved := list(ListOptions{Status: StatusResolved}); len(resolved) != 0 {
+		t.Errorf("expected resolved alerts past retention to be pruned, got %+v", resolved)
+	}
+}
+
+func TestPublishChanges(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	alerts := []Alert{
+		{ID: "1", Type: "sync_failure", Severity: SeverityWarning, Title: "Sync Failed", Labels: map[string]string{"repository": "infra"}, Timestamp: start},
+		{ID: "2", Type: "sync_failure", Severity: SeverityWarning, Title: "Sync Failed", Labels: map[string]string{"repository": "apps"}, Timestamp: start},
+		{ID: "3", Type: "repository_unreachable", Severity: SeverityCritical, Title: "Repository Unreachable", Labels: map[string]string{"repository": "infra"}, Timestamp: start},
+	}
+	manager := newTestManager(t, &alerts)
+	now := start
+	manager.now = func() time.Time { return now }
+	if _, err := manager.CreateSilence(ctx, Silence{Matchers: Matchers{Labels: map[string]string{"repository": "apps"}}, ExpiresAt: start.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	var changes []Alert
+	events := bus.New()
+	manager.SetBus(events)
+	bus.On(events, func(fired Fired) { changes = append(changes, fired.Alert) })
+	bus.On(events, func(resolved Resolved) { changes = append(changes, resolved.Alert) })
+	sync := func() {
+		t.Helper()
+		changes = nil
+		now = now.Add(time.Minute)
+		if err := manager.Sync(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Silenced alerts fire without a notification
+	sync()
+	if len(changes) != 2 || changes[0].ResolvedAt != nil || changes[1].ResolvedAt != nil || changes[0].Status != StatusActive {
+		t.Fatalf("expected two alerts to fire, got %+v", changes)
+	}
+	sync()
+	if len(changes) != 0 {
+		t.Errorf("expected alerts still reported not to fire again, got %+v", changes)
+	}
+
+	alerts = alerts[:2]
+	sync()
+	if len(changes) != 1 || changes[0].ID != "3" || changes[0].ResolvedAt == nil || changes[0].Status != StatusResolved {
+		t.Errorf("expected the unreachable alert to resolve, got %+v", changes)
+	}
+
+	alerts = append(alerts, Alert{ID: "3", Type: "repository_unreachable", Severity: SeverityCritical, Title: "Repository Unreachable", Labels: map[string]string{"repository": "infra"}, Timestamp: now})
+	sync()
+	if len(changes) != 1 || changes[0].ID != "3" || changes[0].ResolvedAt != nil {
+		t.Errorf("expected the unreachable alert to fire again, got %+v", changes)
+	}
+}
@@ -0,0 +1,510 @@
+package alerts
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/bus"
+	"github.com/archellir/denshimon/internal/websocket"
+	"github.com/google/uuid"
+)
+
+// ResolvedRetention is how long resolved alerts stay in the inbox
+const ResolvedRetention = 30 * 24 * time.Hour
+
+// Source is a subsystem raising alerts. Subsystems keep creating alerts their own way;
+// the manager reads them through List and acknowledges them through Acknowledge.
+type Source struct {
+	List        func(ctx context.Context) ([]Alert, error)
+	Acknowledge func(ctx context.Context, id string) error // nil when alerts can't be acknowledged
+}
+
+type source struct {
+	name string
+	Source
+}
+
+// ListOptions filters Manager.List. Without a status, unresolved alerts are listed, leaving
+// out silenced ones unless IncludeSilenced is set.
+type ListOptions struct {
+	Source          string
+	Severity        string // after escalation
+	Status          string // active, acknowledged, silenced or resolved
+	IncludeSilenced bool
+	Limit           int
+}
+
+// collection is what the sources reported
+type collection struct {
+	alerts   []Alert
+	listed   []string // sources that answered
+	complete bool     // whether every source asked answered
+}
+
+// Manager keeps one inbox of the alerts of every source: it folds repeats by dedup key,
+// records them with when they resolved, applies silences and escalates alerts left
+// unacknowledged
+type Manager struct {
+	store *Store
+	hub   *websocket.Hub
+	bus   *bus.Bus
+
+	mu      sync.RWMutex
+	sources []source
+
+	recordMu sync.Mutex // one recording at a time, so each transition is published once
+
+	now func() time.Time
+}
+
+// NewManager creates an alert manager; AddSource registers where alerts come from
+func NewManager(store *Store) *Manager {
+	return &Manager{store: store, now: time.Now}
+}
+
+// SetHub sets where escalation notifications are broadcast
+func (m *Manager) SetHub(hub *websocket.Hub) {
+	m.hub = hub
+}
+
+// AddSource registers a source of alerts under the given name
+func (m *Manager) AddSource(name string, src Source) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sources = append(m.sources, source{name: name, Source: src})
+}
+
+// SetBus sets the event bus Fired and Resolved are published on
+func (m *Manager) SetBus(b *bus.Bus) {
+	m.bus = b
+}
+
+// record records collected alerts in the inbox and publishes the alerts that fired or resolved
+func (m *Manager) record(ctx context.Context, collected *collection, now time.Time) error {
+	m.recordMu.Lock()
+	changes, err := m.store.recordAlerts(ctx, collected.alerts, collected.listed, now)
+	m.recordMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if m.bus == nil || len(changes.fired)+len(changes.resolved) == 0 {
+		return nil
+	}
+	// Alerts matching a silence fire and resolve quietly
+	silences, err := m.activeSilences(ctx)
+	if err != nil {
+		return err
+	}
+	for _, alert := range changes.fired {
+		if silencedBy(alert, silences) == "" {
+			alert.Status = status(alert)
+			m.bus.Publish(Fired{alert})
+		}
+	}
+	for _, alert := range changes.resolved {
+		if silencedBy(alert, silences) == "" {
+			alert.Status = status(alert)
+			m.bus.Publish(Resolved{alert})
+		}
+	}
+	return nil
+}
+
+// List brings the inbox up to date with the sources and returns its deduplicated alerts,
+// most severe and most recent first. Resolved alerts are listed most recently resolved first.
+func (m *Manager) List(ctx context.Context, opts ListOptions) ([]Alert, error) {
+	collected, err := m.collect(ctx, opts.Source)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.record(ctx, collected, m.now()); err != nil {
+		return nil, err
+	}
+
+	resolved := opts.Status == StatusResolved
+	alerts, err := m.store.listAlerts(ctx, opts.Source, resolved)
+	if err != nil {
+		return nil, err
+	}
+	silences, err := m.activeSilences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	states, err := m.store.states(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		if !resolved {
+			alert.SilencedBy = silencedBy(alert, silences)
+			if state, ok := states[alert.DedupKey]; ok {
+				applyState(&alert, state)
+			}
+		}
+		alert.Status = status(alert)
+		switch {
+		case opts.Severity != "" && alert.Severity != opts.Severity:
+			continue
+		case opts.Status != "" && alert.Status != opts.Status:
+			continue
+		case opts.Status == "" && alert.Status == StatusSilenced && !opts.IncludeSilenced:
+			continue
+		}
+		result = append(result, alert)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if resolved {
+			return a.ResolvedAt.After(*b.ResolvedAt)
+		}
+		if severityRank(a.Severity) != severityRank(b.Severity) {
+			return severityRank(a.Severity) > severityRank(b.Severity)
+		}
+		return a.Timestamp.After(b.Timestamp)
+	})
+	if opts.Limit > 0 && len(result) > opts.Limit {
+		result = result[:opts.Limit]
+	}
+	return result, nil
+}
+
+// Acknowledge acknowledges an alert through the source that raised it
+func (m *Manager) Acknowledge(ctx context.Context, sourceName, id string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, src := range m.sources {
+		if src.name == sourceName && src.Acknowledge != nil {
+			return src.Acknowledge(ctx, id)
+		}
+	}
+	return ErrNotFound
+}
+
+// status is resolved, silenced, acknowledged or active, in that order of precedence
+func status(alert Alert) string {
+	switch {
+	case alert.ResolvedAt != nil:
+		return StatusResolved
+	case alert.SilencedBy != "":
+		return StatusSilenced
+	case alert.Acknowledged:
+		return StatusAcknowledged
+	}
+	return StatusActive
+}
+
+// SilencedBy returns the ID of the active silence matching an alert as it is raised, or
+// an empty string, so its notification can be held back
+func (m *Manager) SilencedBy(ctx context.Context, alert Alert) string {
+	alert.DedupKey = dedupKey(alert)
+	silences, err := m.activeSilences(ctx)
+	if err != nil {
+		slog.Warn("Failed to read alert silences", "error", err)
+	}
+	return silencedBy(alert, silences)
+}
+
+// CreateSilence validates and stores a silence
+func (m *Manager) CreateSilence(ctx context.Context, silence Silence) (*Silence, error) {
+	now := m.now()
+	if err := silence.Validate(now); err != nil {
+		return nil, err
+	}
+	silence.ID = uuid.New().String()
+	silence.CreatedAt = now
+	if err := m.store.CreateSilence(ctx, silence); err != nil {
+		return nil, err
+	}
+	return &silence, nil
+}
+
+// ListSilences returns the active silences, or all of them including expired ones
+func (m *Manager) ListSilences(ctx context.Context, includeExpired bool) ([]Silence, error) {
+	if includeExpired {
+		return m.store.ListSilences(ctx, time.Time{})
+	}
+	return m.store.ListSilences(ctx, m.now())
+}
+
+// ExpireSilence ends a silence now
+func (m *Manager) ExpireSilence(ctx context.Context, id string) error {
+	return m.store.ExpireSilence(ctx, id, m.now())
+}
+
+// CreateRule validates and stores an escalation rule
+func (m *Manager) CreateRule(ctx context.Context, rule EscalationRule) (*EscalationRule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	rule.ID = uuid.New().String()
+	rule.CreatedAt = m.now()
+	if err := m.store.CreateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ListRules returns the escalation rules
+func (m *Manager) ListRules(ctx context.Context) ([]EscalationRule, error) {
+	return m.store.ListRules(ctx)
+}
+
+// DeleteRule removes an escalation rule
+func (m *Manager) DeleteRule(ctx context.Context, id string) error {
+	return m.store.DeleteRule(ctx, id)
+}
+
+// Start syncs the inbox and evaluates escalation rules every interval until ctx is cancelled
+func (m *Manager) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.Sync(ctx); err != nil {
+					slog.Error("Failed to sync alerts", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Sync records the alerts of every source in the inbox, so alerts raised and cleared
+// between lists are kept too, prunes alerts resolved for ResolvedRetention and escalates
+func (m *Manager) Sync(ctx context.Context) error {
+	collected, err := m.collect(ctx, "")
+	if err != nil {
+		return err
+	}
+	now := m.now()
+	if err := m.record(ctx, collected, now); err != nil {
+		return err
+	}
+	if _, err := m.store.pruneResolved(ctx, now.Add(-ResolvedRetention)); err != nil {
+		return err
+	}
+	return m.escalate(ctx, collected.alerts, collected.complete)
+}
+
+// escalate re-notifies about unacknowledged, unsilenced alerts that an escalation rule is
+// due for, raising their severity where the rule says so. Progress is forgotten once an
+// alert is gone, so an alert raised again starts over.
+func (m *Manager) escalate(ctx context.Context, alerts []Alert, complete bool) error {
+	rules, err := m.store.ListRules(ctx)
+	if err != nil {
+		return err
+	}
+	compiled := make([]*compiledMatchers, len(rules))
+	for i, rule := range rules {
+		if compiled[i], err = rule.compile(); err != nil {
+			slog.Warn("Skipping invalid escalation rule", "rule", rule.ID, "error", err)
+		}
+	}
+	states, err := m.store.states(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := m.now()
+	present := make(map[string]bool, len(alerts))
+	for _, alert := range alerts {
+		present[alert.DedupKey] = true
+		if alert.Acknowledged || alert.SilencedBy != "" {
+			continue
+		}
+
+		// Rules are ordered by wait, so the last one due has the longest
+		var due *EscalationRule
+		waited := now.Sub(alert.FirstSeen)
+		for i := range rules {
+			if compiled[i] != nil && compiled[i].matches(alert) && waited >= time.Duration(rules[i].AfterMinutes)*time.Minute {
+				due = &rules[i]
+			}
+		}
+		if due == nil {
+			continue
+		}
+
+		state, ok := states[alert.DedupKey]
+		if !ok {
+			state = notificationState{DedupKey: alert.DedupKey}
+		}
+		switch {
+		case due.AfterMinutes > state.Level:
+			state.Level = due.AfterMinutes
+			if severityRank(due.EscalateTo) > severityRank(alert.Severity) && severityRank(due.EscalateTo) > severityRank(state.Severity) {
+				state.Severity = due.EscalateTo
+			}
+		case due.RepeatMinutes > 0 && now.Sub(state.NotifiedAt) >= time.Duration(due.RepeatMinutes)*time.Minute:
+		default:
+			continue
+		}
+		state.Notifications++
+		state.NotifiedAt = now
+		if err := m.store.saveState(ctx, state); err != nil {
+			return err
+		}
+
+		applyState(&alert, state)
+		slog.Info("Escalating alert", "dedup_key", alert.DedupKey, "rule", due.Name, "severity", alert.Severity,
+			"notifications", state.Notifications)
+		if m.hub != nil {
+			m.hub.Broadcast(websocket.MessageTypeAlerts, alert)
+		}
+	}
+
+	// A source that failed to list says nothing about whether its alerts are gone
+	for key := range states {
+		if complete && !present[key] {
+			if err := m.store.deleteState(ctx, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// collect lists the alerts of every source, or one source, folds repeats and marks
+// silenced alerts. A failing source is logged and skipped, and the collection incomplete.
+func (m *Manager) collect(ctx context.Context, only string) (*collection, error) {
+	m.mu.RLock()
+	sources := append([]source(nil), m.sources...)
+	m.mu.RUnlock()
+
+	var raw []Alert
+	collected := &collection{complete: true}
+	for _, src := range sources {
+		if only != "" && src.name != only {
+			continue
+		}
+		alerts, err := src.List(ctx)
+		if err != nil {
+			slog.Warn("Failed to list alerts", "source", src.name, "error", err)
+			collected.complete = false
+			continue
+		}
+		collected.listed = append(collected.listed, src.name)
+		for _, alert := range alerts {
+			alert.Source = src.name
+			raw = append(raw, alert)
+		}
+	}
+
+	silences, err := m.activeSilences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collected.alerts = Deduplicate(raw)
+	for i := range collected.alerts {
+		collected.alerts[i].SilencedBy = silencedBy(collected.alerts[i], silences)
+	}
+	return collected, nil
+}
+
+type activeSilence struct {
+	id       string
+	matchers *compiledMatchers
+}
+
+func (m *Manager) activeSilences(ctx context.Context) ([]activeSilence, error) {
+	silences, err := m.store.ListSilences(ctx, m.now())
+	if err != nil {
+		return nil, err
+	}
+	active := make([]activeSilence, 0, len(silences))
+	for _, silence := range silences {
+		matchers, err := silence.compile()
+		if err != nil {
+			slog.Warn("Skipping invalid silence", "silence", silence.ID, "error", err)
+			continue
+		}
+		active = append(active, activeSilence{id: silence.ID, matchers: matchers})
+	}
+	return active, nil
+}
+
+func silencedBy(alert Alert, silences []activeSilence) string {
+	for _, silence := range silences {
+		if silence.matchers.matches(alert) {
+			return silence.id
+		}
+	}
+	return ""
+}
+
+// Deduplicate folds alerts with the same dedup key into the most recent of them, counting
+// the repeats. A group is acknowledged when its most recent alert is, so an alert that
+// fires again after being acknowledged shows up unacknowledged.
+func Deduplicate(alerts []Alert) []Alert {
+	groups := make(map[string]*Alert)
+	var order []string
+	for _, alert := range alerts {
+		alert.DedupKey = dedupKey(alert)
+		group, ok := groups[alert.DedupKey]
+		if !ok {
+			alert.Count = 1
+			alert.FirstSeen = alert.Timestamp
+			groups[alert.DedupKey] = &alert
+			order = append(order, alert.DedupKey)
+			continue
+		}
+		count, firstSeen := group.Count+1, group.FirstSeen
+		if alert.Timestamp.Before(firstSeen) {
+			firstSeen = alert.Timestamp
+		}
+		if alert.Timestamp.After(group.Timestamp) {
+			*group = alert
+		}
+		group.Count, group.FirstSeen = count, firstSeen
+	}
+
+	result := make([]Alert, 0, len(order))
+	for _, key := range order {
+		result = append(result, *groups[key])
+	}
+	return result
+}
+
+// dedupKey is the alert's own key, or its source, type and labels, or its source, type and
+// title when it has no labels
+func dedupKey(alert Alert) string {
+	if alert.DedupKey != "" {
+		return alert.DedupKey
+	}
+	parts := []string{alert.Source, alert.Type}
+	if len(alert.Labels) == 0 {
+		return strings.Join(append(parts, alert.Title), "/")
+	}
+	names := make([]string, 0, len(alert.Labels))
+	for name := range alert.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, name+"="+alert.Labels[name])
+	}
+	return strings.Join(parts, "/")
+}
+
+// applyState shows an alert's escalated severity and notifications
+func applyState(alert *Alert, state notificationState) {
+	if severityRank(state.Severity) > severityRank(alert.Severity) {
+		alert.OriginalSeverity = alert.Severity
+		alert.Severity = state.Severity
+	}
+	if state.Notifications > 0 {
+		notifiedAt := state.NotifiedAt
+		alert.Notifications = state.Notifications
+		alert.LastNotified = &notifiedAt
+	}
+}
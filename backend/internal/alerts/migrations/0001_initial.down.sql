@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS alert_notifications;
+DROP TABLE IF EXISTS alert_escalation_rules;
+DROP INDEX IF EXISTS idx_alert_silences_expires;
+DROP TABLE IF EXISTS alert_silences;
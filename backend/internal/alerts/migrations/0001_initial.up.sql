@@ -0,0 +1,34 @@
+-- Silences mute matching alerts until they expire
+CREATE TABLE IF NOT EXISTS alert_silences (
+	id TEXT PRIMARY KEY,
+	title_pattern TEXT NOT NULL DEFAULT '',
+	labels TEXT NOT NULL DEFAULT '{}', -- label name to pattern, JSON
+	comment TEXT NOT NULL DEFAULT '',
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL, -- unix milliseconds
+	expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_silences_expires ON alert_silences(expires_at);
+
+-- Escalation rules notify again, at a higher severity, about alerts left unacknowledged
+CREATE TABLE IF NOT EXISTS alert_escalation_rules (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	title_pattern TEXT NOT NULL DEFAULT '',
+	labels TEXT NOT NULL DEFAULT '{}',
+	after_minutes INTEGER NOT NULL,
+	repeat_minutes INTEGER NOT NULL DEFAULT 0,
+	escalate_to TEXT NOT NULL DEFAULT '',
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL
+);
+
+-- Escalation progress of each open alert
+CREATE TABLE IF NOT EXISTS alert_notifications (
+	dedup_key TEXT PRIMARY KEY,
+	level INTEGER NOT NULL,
+	severity TEXT NOT NULL DEFAULT '',
+	notifications INTEGER NOT NULL,
+	notified_at INTEGER NOT NULL
+);
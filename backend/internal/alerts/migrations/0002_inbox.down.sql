@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_alerts_source;
+DROP TABLE IF EXISTS alerts;
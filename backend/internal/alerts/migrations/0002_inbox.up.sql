@@ -0,0 +1,19 @@
+-- One row per deduplicated alert of every source, kept after it resolves
+CREATE TABLE IF NOT EXISTS alerts (
+	dedup_key TEXT PRIMARY KEY,
+	source TEXT NOT NULL,
+	alert_id TEXT NOT NULL, -- latest alert folded in, as known to its source
+	type TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	title TEXT NOT NULL,
+	message TEXT NOT NULL,
+	labels TEXT NOT NULL DEFAULT '{}',
+	count INTEGER NOT NULL,
+	acknowledged BOOLEAN NOT NULL DEFAULT FALSE,
+	timestamp INTEGER NOT NULL, -- unix milliseconds
+	first_seen INTEGER NOT NULL,
+	synced_at INTEGER NOT NULL,
+	resolved_at INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_alerts_source ON alerts(source, resolved_at);
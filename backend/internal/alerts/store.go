@@ -0,0 +1,346 @@
+package alerts
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/database/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations is the schema of the alert inbox, silence and escalation tables
+var Migrations = migrate.NewSource("alerts", migrationFiles, "migrations")
+
+// Store persists the alert inbox, silences, escalation rules and escalation progress in SQLite
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates an alert store, migrating its tables to the latest schema version
+func NewStore(db *sql.DB) (*Store, error) {
+	if err := migrate.Up(context.Background(), db, Migrations); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// transitions are the alerts that fired or resolved while recording
+type transitions struct {
+	fired    []Alert // new, or reported again after being resolved
+	resolved []Alert
+}
+
+// recordAlerts updates the inbox with the alerts of the listed sources. Alerts of those
+// sources that are no longer reported are resolved; an alert reported again after being
+// resolved starts over.
+func (s *Store) recordAlerts(ctx context.Context, alerts []Alert, listed []string, now time.Time) (*transitions, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	open := make(map[string]bool)
+	rows, err := tx.QueryContext(ctx, `SELECT dedup_key FROM alerts WHERE resolved_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open alerts: %w", err)
+	}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		open[key] = true
+	}
+	rows.Close()
+	changes := &transitions{}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO alerts (dedup_key, source, alert_id, type, severity, title, message, labels, count, acknowledged,
+			timestamp, first_seen, synced_at, resolved_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL)
+		ON CONFLICT(dedup_key) DO UPDATE SET
+			source = excluded.source,
+			alert_id = excluded.alert_id,
+			type = excluded.type,
+			severity = excluded.severity,
+			title = excluded.title,
+			message = excluded.message,
+			labels = excluded.labels,
+			count = excluded.count,
+			acknowledged = excluded.acknowledged,
+			timestamp = excluded.timestamp,
+			first_seen = CASE WHEN alerts.resolved_at IS NULL THEN MIN(alerts.first_seen, excluded.first_seen) ELSE excluded.first_seen END,
+			synced_at = excluded.synced_at,
+			resolved_at = NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, alert := range alerts {
+		labels, err := json.Marshal(alert.Labels)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := stmt.ExecContext(ctx, alert.DedupKey, alert.Source, alert.ID, alert.Type, alert.Severity, alert.Title,
+			alert.Message, string(labels), alert.Count, alert.Acknowledged, alert.Timestamp.UnixMilli(),
+			alert.FirstSeen.UnixMilli(), now.UnixMilli()); err != nil {
+			return nil, fmt.Errorf("failed to record alert: %w", err)
+		}
+		if !open[alert.DedupKey] {
+			changes.fired = append(changes.fired, alert)
+		}
+	}
+	for _, source := range listed {
+		resolved, err := queryAlerts(ctx, tx, alertQuery+` AND source = ? AND synced_at < ?`, source, now.UnixMilli())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE alerts SET resolved_at = ?
+			WHERE source = ? AND resolved_at IS NULL AND synced_at < ?`,
+			now.UnixMilli(), source, now.UnixMilli()); err != nil {
+			return nil, fmt.Errorf("failed to resolve alerts: %w", err)
+		}
+		for i := range resolved {
+			at := now.UTC()
+			resolved[i].ResolvedAt = &at
+		}
+		changes.resolved = append(changes.resolved, resolved...)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+const alertQuery = `
+		SELECT dedup_key, source, alert_id, type, severity, title, message, labels, count, acknowledged,
+			timestamp, first_seen, resolved_at
+		FROM alerts
+		WHERE resolved_at IS NULL`
+
+// queryAlerts reads the alerts a query on alertQuery selects
+func queryAlerts(ctx context.Context, db interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}, query string, args ...interface{}) ([]Alert, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []Alert{}
+	for rows.Next() {
+		var alert Alert
+		var labels string
+		var timestamp, firstSeen int64
+		var resolvedAt sql.NullInt64
+		if err := rows.Scan(&alert.DedupKey, &alert.Source, &alert.ID, &alert.Type, &alert.Severity, &alert.Title,
+			&alert.Message, &labels, &alert.Count, &alert.Acknowledged, &timestamp, &firstSeen, &resolvedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		json.Unmarshal([]byte(labels), &alert.Labels)
+		alert.Timestamp = time.UnixMilli(timestamp).UTC()
+		alert.FirstSeen = time.UnixMilli(firstSeen).UTC()
+		if resolvedAt.Valid {
+			at := time.UnixMilli(resolvedAt.Int64).UTC()
+			alert.ResolvedAt = &at
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+// listAlerts reads the inbox: the unresolved alerts, or the resolved ones, of one source
+// or all of them
+func (s *Store) listAlerts(ctx context.Context, source string, resolved bool) ([]Alert, error) {
+	query := alertQuery
+	if resolved {
+		query = strings.Replace(query, "IS NULL", "IS NOT NULL", 1)
+	}
+	var args []interface{}
+	if source != "" {
+		query += ` AND source = ?`
+		args = append(args, source)
+	}
+	return queryAlerts(ctx, s.db, query+` ORDER BY timestamp DESC`, args...)
+}
+
+// pruneResolved deletes alerts resolved before the given time
+func (s *Store) pruneResolved(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM alerts WHERE resolved_at < ?`, before.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune alerts: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// CreateSilence stores a silence
+func (s *Store) CreateSilence(ctx context.Context, silence Silence) error {
+	labels, err := json.Marshal(silence.Labels)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO alert_silences (id, title_pattern, labels, comment, created_by, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		silence.ID, silence.TitlePattern, string(labels), silence.Comment, silence.CreatedBy,
+		silence.CreatedAt.UnixMilli(), silence.ExpiresAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to create silence: %w", err)
+	}
+	return nil
+}
+
+// ListSilences returns silences expiring after the given time, or all silences for a zero
+// time, latest expiry first
+func (s *Store) ListSilences(ctx context.Context, expiringAfter time.Time) ([]Silence, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, title_pattern, labels, comment, created_by, created_at, expires_at
+		FROM alert_silences
+		WHERE expires_at > ?
+		ORDER BY expires_at DESC, id`, expiringAfter.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list silences: %w", err)
+	}
+	defer rows.Close()
+
+	silences := []Silence{}
+	for rows.Next() {
+		var silence Silence
+		var labels string
+		var createdAt, expiresAt int64
+		if err := rows.Scan(&silence.ID, &silence.TitlePattern, &labels, &silence.Comment, &silence.CreatedBy,
+			&createdAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan silence: %w", err)
+		}
+		json.Unmarshal([]byte(labels), &silence.Labels)
+		silence.CreatedAt = time.UnixMilli(createdAt).UTC()
+		silence.ExpiresAt = time.UnixMilli(expiresAt).UTC()
+		silences = append(silences, silence)
+	}
+	return silences, rows.Err()
+}
+
+// ExpireSilence ends a silence at the given time, keeping it for reference
+func (s *Store) ExpireSilence(ctx context.Context, id string, at time.Time) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE alert_silences SET expires_at = MIN(expires_at, ?) WHERE id = ?`,
+		at.UnixMilli(), id)
+	if err != nil {
+		return fmt.Errorf("failed to expire silence: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateRule stores an escalation rule
+func (s *Store) CreateRule(ctx context.Context, rule EscalationRule) error {
+	labels, err := json.Marshal(rule.Labels)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO alert_escalation_rules (id, name, title_pattern, labels, after_minutes, repeat_minutes, escalate_to, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rule.ID, rule.Name, rule.TitlePattern, string(labels), rule.AfterMinutes, rule.RepeatMinutes, rule.EscalateTo,
+		rule.CreatedBy, rule.CreatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to create escalation rule: %w", err)
+	}
+	return nil
+}
+
+// ListRules returns the escalation rules, shortest wait first
+func (s *Store) ListRules(ctx context.Context) ([]EscalationRule, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, title_pattern, labels, after_minutes, repeat_minutes, escalate_to, created_by, created_at
+		FROM alert_escalation_rules
+		ORDER BY after_minutes, name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list escalation rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []EscalationRule{}
+	for rows.Next() {
+		var rule EscalationRule
+		var labels string
+		var createdAt int64
+		if err := rows.Scan(&rule.ID, &rule.Name, &rule.TitlePattern, &labels, &rule.AfterMinutes, &rule.RepeatMinutes,
+			&rule.EscalateTo, &rule.CreatedBy, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan escalation rule: %w", err)
+		}
+		json.Unmarshal([]byte(labels), &rule.Labels)
+		rule.CreatedAt = time.UnixMilli(createdAt).UTC()
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// DeleteRule removes an escalation rule
+func (s *Store) DeleteRule(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM alert_escalation_rules WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete escalation rule: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// states returns the escalation progress of every alert by dedup key
+func (s *Store) states(ctx context.Context) (map[string]notificationState, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT dedup_key, level, severity, notifications, notified_at FROM alert_notifications`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read escalation state: %w", err)
+	}
+	defer rows.Close()
+
+	states := make(map[string]notificationState)
+	for rows.Next() {
+		var state notificationState
+		var notifiedAt int64
+		if err := rows.Scan(&state.DedupKey, &state.Level, &state.Severity, &state.Notifications, &notifiedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan escalation state: %w", err)
+		}
+		state.NotifiedAt = time.UnixMilli(notifiedAt).UTC()
+		states[state.DedupKey] = state
+	}
+	return states, rows.Err()
+}
+
+func (s *Store) saveState(ctx context.Context, state notificationState) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO alert_notifications (dedup_key, level, severity, notifications, notified_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(dedup_key) DO UPDATE SET
+			level = excluded.level,
+			severity = excluded.severity,
+			notifications = excluded.notifications,
+			notified_at = excluded.notified_at`,
+		state.DedupKey, state.Level, state.Severity, state.Notifications, state.NotifiedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to save escalation state: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) deleteState(ctx context.Context, dedupKey string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM alert_notifications WHERE dedup_key = ?`, dedupKey); err != nil {
+		return fmt.Errorf("failed to delete escalation state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// ErrNotFound is returned for unknown alerts, sources, silences and escalation rules
+var ErrNotFound = errors.New("not found")
+
+// Severities in increasing order
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Statuses of alerts in the inbox
+const (
+	StatusActive       = "active"
+	StatusAcknowledged = "acknowledged"
+	StatusSilenced     = "silenced"
+	StatusResolved     = "resolved" // no longer reported by its source
+)
+
+// severityRank orders severities; unknown severities rank with info
+func severityRank(severity string) int {
+	switch severity {
+	case SeverityWarning:
+		return 1
+	case SeverityCritical:
+		return 2
+	}
+	return 0
+}
+
+// Alert is an alert of any source (gitops, infrastructure, certificates, backups) in one
+// shape. Sources fill in the first part; the manager the rest.
+type Alert struct {
+	ID           string            `json:"id"`
+	Source       string            `json:"source"`
+	Type         string            `json:"type"`
+	Severity     string            `json:"severity"`
+	Title        string            `json:"title"`
+	Message      string            `json:"message"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Acknowledged bool              `json:"acknowledged"`
+	Timestamp    time.Time         `json:"timestamp"`
+	// DedupKey identifies repeats of the same alert. Sources may set it; otherwise it is
+	// derived from the source, type and labels, or the title without labels.
+	DedupKey string `json:"dedup_key"`
+
+	Status     string     `json:"status"`
+	Count      int        `json:"count"` // alerts folded into this one
+	FirstSeen  time.Time  `json:"first_seen"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	// SilencedBy is the ID of the silence matching the alert
+	SilencedBy string `json:"silenced_by,omitempty"`
+	// OriginalSeverity is the severity before escalation
+	OriginalSeverity string     `json:"original_severity,omitempty"`
+	Notifications    int        `json:"notifications"` // escalation notifications sent
+	LastNotified     *time.Time `json:"last_notified,omitempty"`
+}
+
+// Fired is published when an alert enters the inbox, new or reported again after it resolved
+type Fired struct{ Alert }
+
+// Topic names fired alerts on the event bus
+func (Fired) Topic() string { return "alert.fired" }
+
+// Resolved is published when the source of an alert stops reporting it
+type Resolved struct{ Alert }
+
+// Topic names resolved alerts on the event bus
+func (Resolved) Topic() string { return "alert.resolved" }
+
+// Matchers select alerts by title and labels. Label patterns are anchored regular
+// expressions matched against the alert's labels and its source, type and severity; the
+// title pattern matches anywhere in the title. An unset pattern matches everything.
+type Matchers struct {
+	TitlePattern string            `json:"title_pattern,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+// compiledMatchers are Matchers ready to match
+type compiledMatchers struct {
+	title  *regexp.Regexp
+	labels map[string]*regexp.Regexp
+}
+
+func (m Matchers) compile() (*compiledMatchers, error) {
+	compiled := &compiledMatchers{labels: make(map[string]*regexp.Regexp, len(m.Labels))}
+	if m.TitlePattern != "" {
+		re, err := regexp.Compile(m.TitlePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid title pattern: %w", err)
+		}
+		compiled.title = re
+	}
+	for name, pattern := range m.Labels {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for label %s: %w", name, err)
+		}
+		compiled.labels[name] = re
+	}
+	return compiled, nil
+}
+
+func (c *compiledMatchers) matches(alert Alert) bool {
+	if c.title != nil && !c.title.MatchString(alert.Title) {
+		return false
+	}
+	for name, re := range c.labels {
+		if !re.MatchString(alertLabel(alert, name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// alertLabel reads a label, with source, type and severity available as labels too
+func alertLabel(alert Alert, name string) string {
+	switch name {
+	case "source":
+		return alert.Source
+	case "type":
+		return alert.Type
+	case "severity":
+		return alert.Severity
+	}
+	return alert.Labels[name]
+}
+
+// Silence hides matching alerts from lists and notifications until it expires
+type Silence struct {
+	ID string `json:"id"`
+	Matchers
+	Comment   string    `json:"comment"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Active reports whether the silence has not expired at the given time
+func (s Silence) Active(at time.Time) bool {
+	return at.Before(s.ExpiresAt)
+}
+
+// Validate checks the silence matches something specific and expires in the future
+func (s Silence) Validate(now time.Time) error {
+	if s.TitlePattern == "" && len(s.Labels) == 0 {
+		return errors.New("a title pattern or label matcher is required")
+	}
+	if !s.ExpiresAt.After(now) {
+		return errors.New("expires_at must be in the future")
+	}
+	_, err := s.compile()
+	return err
+}
+
+// EscalationRule re-notifies about matching alerts left unacknowledged for AfterMinutes,
+// raising their severity to EscalateTo when set and repeating every RepeatMinutes. When
+// several rules are due for an alert, the one with the longest wait applies.
+type EscalationRule struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Matchers
+	AfterMinutes  int       `json:"after_minutes"`
+	RepeatMinutes int       `json:"repeat_minutes,omitempty"` // 0 notifies once
+	EscalateTo    string    `json:"escalate_to,omitempty"`    // severity
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Validate checks the rule's wait, repeat interval, severity and patterns
+func (r EscalationRule) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if r.AfterMinutes <= 0 {
+		return errors.New("after_minutes must be positive")
+	}
+	if r.RepeatMinutes < 0 {
+		return errors.New("repeat_minutes must not be negative")
+	}
+	switch r.EscalateTo {
+	case "", SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return fmt.Errorf("invalid escalate_to severity: %s", r.EscalateTo)
+	}
+	_, err := r.compile()
+	return err
+}
+
+// notificationState is the escalation progress of one deduplicated alert
+type notificationState struct {
+	DedupKey      string
+	Level         int // AfterMinutes of the last rule applied
+	Severity      string
+	Notifications int
+	NotifiedAt    time.Time
+}
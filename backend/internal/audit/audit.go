@@ -0,0 +1,139 @@
+// Package audit records who did what through Denshimon in SQLite, for compliance
+// and after-the-fact review.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultLimit caps the entries returned by a query without a limit
+const DefaultLimit = 200
+
+// SystemUser is recorded for actions taken by background jobs
+const SystemUser = "system"
+
+// Entry is one recorded action
+type Entry struct {
+	ID        int64     `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	User      string    `json:"user"`
+	Action    string    `json:"action"`   // e.g. registry.retention.run
+	Resource  string    `json:"resource"` // e.g. registry/<id>
+	Success   bool      `json:"success"`
+	Message   string    `json:"message"`
+	Details   string    `json:"details,omitempty"` // JSON document
+}
+
+// Query selects audit entries, newest first. Action matches as a prefix.
+type Query struct {
+	Action   string
+	Resource string
+	User     string
+	Since    time.Time
+	Limit    int
+}
+
+// Store persists audit entries
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates an audit store and its table
+func NewStore(db *sql.DB) (*Store, error) {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp INTEGER NOT NULL, -- unix milliseconds
+			user TEXT NOT NULL,
+			action TEXT NOT NULL,
+			resource TEXT NOT NULL DEFAULT '',
+			success BOOLEAN NOT NULL,
+			message TEXT NOT NULL DEFAULT '',
+			details TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			return nil, fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+	return &Store{db: db}, nil
+}
+
+// Record appends an entry, stamping it with the current time when unset
+func (s *Store) Record(ctx context.Context, entry Entry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	if entry.User == "" {
+		entry.User = SystemUser
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO audit_log (timestamp, user, action, resource, success, message, details)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entry.Timestamp.UnixMilli(), entry.User, entry.Action, entry.Resource, entry.Success, entry.Message, entry.Details)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// List returns the entries matching a query, newest first
+func (s *Store) List(ctx context.Context, query Query) ([]Entry, error) {
+	var conditions []string
+	var args []interface{}
+	if query.Action != "" {
+		conditions = append(conditions, "action LIKE ? ESCAPE '\\'")
+		args = append(args, escapeLike(query.Action)+"%")
+	}
+	if query.Resource != "" {
+		conditions = append(conditions, "resource = ?")
+		args = append(args, query.Resource)
+	}
+	if query.User != "" {
+		conditions = append(conditions, "user = ?")
+		args = append(args, query.User)
+	}
+	if !query.Since.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, query.Since.UnixMilli())
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultLimit
+	}
+
+	statement := `SELECT id, timestamp, user, action, resource, success, message, details FROM audit_log`
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	statement += " ORDER BY timestamp DESC, id DESC LIMIT ?"
+	args = append(args, query.Limit)
+
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var entry Entry
+		var timestamp int64
+		if err := rows.Scan(&entry.ID, &timestamp, &entry.User, &entry.Action, &entry.Resource,
+			&entry.Success, &entry.Message, &entry.Details); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entry.Timestamp = time.UnixMilli(timestamp)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
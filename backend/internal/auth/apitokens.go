@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// APITokenPrefix marks bearer tokens that are API tokens rather than PASETO session tokens
+const APITokenPrefix = "dsm_"
+
+// apiTokenTouchInterval limits how often token use is written to the database
+const apiTokenTouchInterval = time.Minute
+
+var (
+	ErrAPITokenNotFound  = errors.New("API token not found")
+	ErrInvalidScope      = errors.New("invalid API token scope")
+	ErrAPITokensDisabled = errors.New("API tokens disabled - no database configured")
+)
+
+// APIToken is a long-lived credential for automation. Only a hash of the secret is stored;
+// the token itself is returned once, when it is created.
+type APIToken struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // first characters of the token, for identification
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	TokenHash  string     `json:"-"`
+}
+
+// APITokenStore persists API tokens. GetAPITokenByHash returns ErrAPITokenNotFound
+// for unknown hashes.
+type APITokenStore interface {
+	CreateAPIToken(token *APIToken) error
+	GetAPITokenByHash(hash string) (*APIToken, error)
+	GetAPIToken(id string) (*APIToken, error)
+	ListAPITokens(userID string) ([]*APIToken, error)
+	TouchAPIToken(id string, usedAt time.Time) error
+	DeleteAPIToken(id string) error
+}
+
+// scopeRule grants requests under any of paths; paths containing '*' are path.Match
+// patterns, others are prefixes
+type scopeRule struct {
+	readOnly bool
+	paths    []string
+}
+
+// APITokenScopes lists the scopes an API token can be granted. Tokens never reach
+// /api/auth, so they cannot mint further credentials, and never exceed their owner's role.
+var APITokenScopes = map[string][]scopeRule{
+	"read": {{readOnly: true, paths: []string{"/api/"}}},
+	"metrics:read": {{readOnly: true, paths: []string{
+		"/api/metrics", "/api/services", "/api/traces", "/api/log_data", "/api/live_streams",
+	}}},
+	"deploy": {
+		{paths: []string{"/api/deployments", "/api/k8s/deployments", "/api/gitops/sync/"}},
+		{paths: []string{"/api/gitops/applications/*/promote", "/api/gitops/applications/*/environments/*"}},
+	},
+	"gitops": {{paths: []string{"/api/gitops"}}},
+	"full":   {{paths: []string{"/api/"}}},
+}
+
+// SetAPITokenStore enables API token authentication
+func (s *Service) SetAPITokenStore(store APITokenStore) {
+	s.apiTokens = store
+}
+
+// CreateAPIToken issues a token for a user and returns it with its stored record.
+// A zero ttl creates a token that does not expire.
+func (s *Service) CreateAPIToken(userID, name string, scopes []string, ttl time.Duration) (string, *APIToken, error) {
+	if s.apiTokens == nil {
+		return "", nil, ErrAPITokensDisabled
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	for _, scope := range scopes {
+		if _, ok := APITokenScopes[scope]; !ok {
+			return "", nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+	plain := APITokenPrefix + hex.EncodeToString(secret)
+
+	now := time.Now().UTC()
+	token := &APIToken{
+		ID:        hex.EncodeToString(id),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    plain[:len(APITokenPrefix)+8],
+		Scopes:    scopes,
+		CreatedAt: now,
+		TokenHash: hashAPIToken(plain),
+	}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := s.apiTokens.CreateAPIToken(token); err != nil {
+		return "", nil, fmt.Errorf("failed to create API token: %w", err)
+	}
+	return plain, token, nil
+}
+
+// ListAPITokens returns a user's API tokens, newest first
+func (s *Service) ListAPITokens(userID string) ([]*APIToken, error) {
+	if s.apiTokens == nil {
+		return nil, ErrAPITokensDisabled
+	}
+	return s.apiTokens.ListAPITokens(userID)
+}
+
+// GetAPIToken returns an API token's record
+func (s *Service) GetAPIToken(id string) (*APIToken, error) {
+	if s.apiTokens == nil {
+		return nil, ErrAPITokensDisabled
+	}
+	return s.apiTokens.GetAPIToken(id)
+}
+
+// RevokeAPIToken deletes an API token; it is rejected from the next request on
+func (s *Service) RevokeAPIToken(id string) error {
+	if s.apiTokens == nil {
+		return ErrAPITokensDisabled
+	}
+	return s.apiTokens.DeleteAPIToken(id)
+}
+
+// validateAPIToken resolves an API token to claims carrying the owner's current role
+// and the token's scopes
+func (s *Service) validateAPIToken(plain string) (*TokenClaims, error) {
+	if s.apiTokens == nil || s.db == nil {
+		return nil, ErrInvalidToken
+	}
+	token, err := s.apiTokens.GetAPITokenByHash(hashAPIToken(plain))
+	if errors.Is(err, ErrAPITokenNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API token: %w", err)
+	}
+	now := time.Now().UTC()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	// Deleted users' tokens stop working, and role changes apply immediately
+	user, err := s.db.GetUserByID(token.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval {
+		if err := s.apiTokens.TouchAPIToken(token.ID, now); err != nil {
+			slog.Debug("Failed to record API token use", "error", err)
+		}
+	}
+
+	claims := &TokenClaims{
+		UserID:     user.ID,
+		Username:   user.Username,
+		Role:       user.Role,
+		Scopes:     token.Scopes,
+		APITokenID: token.ID,
+		IssuedAt:   token.CreatedAt.Unix(),
+	}
+	if token.ExpiresAt != nil {
+		claims.ExpireAt = token.ExpiresAt.Unix()
+	}
+	return claims, nil
+}
+
+// isNodeShellPath reports whether a path opens a shell on a node, /api/k8s/nodes/{name}/shell
+func isNodeShellPath(requestPath string) bool {
+	name, ok := strings.CutPrefix(requestPath, "/api/k8s/nodes/")
+	if !ok {
+		return false
+	}
+	name, ok = strings.CutSuffix(strings.TrimSuffix(name, "/"), "/shell")
+	return ok && name != "" && !strings.Contains(name, "/")
+}
+
+// ScopesAllow reports whether API token scopes permit a request
+func ScopesAllow(scopes []string, method, requestPath string) bool {
+	if strings.HasPrefix(requestPath, "/api/auth/") {
+		return false
+	}
+	readOnly := method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+	// Terminal sessions, own or shared, and node shells are opened with GET but are anything
+	// but read-only
+	if requestPath == "/api/k8s/pods/exec" || strings.HasPrefix(requestPath, "/api/k8s/pods/exec/") ||
+		isNodeShellPath(requestPath) {
+		readOnly = false
+	}
+	for _, scope := range scopes {
+		for _, rule := range APITokenScopes[scope] {
+			if rule.readOnly && !readOnly {
+				continue
+			}
+			for _, p := range rule.paths {
+				if pathMatches(p, requestPath) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func pathMatches(pattern, requestPath string) bool {
+	if strings.Contains(pattern, "*") {
+		ok, _ := path.Match(pattern, requestPath)
+		return ok
+	}
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(requestPath, pattern)
+	}
+	return requestPath == pattern || strings.HasPrefix(requestPath, pattern+"/")
+}
+
+// ScopeNames returns the scopes API tokens can be granted, sorted
+func ScopeNames() []string {
+	names := make([]string, 0, len(APITokenScopes))
+	for name := range APITokenScopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func hashAPIToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type memoryAPITokenStore struct {
+	tokens map[string]*APIToken
+}
+
+func (m *memoryAPITokenStore) CreateAPIToken(token *APIToken) error {
+	m.tokens[token.ID] = token
+	return nil
+}
+
+func (m *memoryAPITokenStore) GetAPITokenByHash(hash string) (*APIToken, error) {
+	for _, token := range m.tokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return nil, ErrAPITokenNotFound
+}
+
+func (m *memoryAPITokenStore) GetAPIToken(id string) (*APIToken, error) {
+	if token, ok := m.tokens[id]; ok {
+		return token, nil
+	}
+	return nil, ErrAPITokenNotFound
+}
+
+func (m *memoryAPITokenStore) ListAPITokens(userID string) ([]*APIToken, error) {
+	var tokens []*APIToken
+	for _, token := range m.tokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (m *memoryAPITokenStore) TouchAPIToken(id string, usedAt time.Time) error {
+	m.tokens[id].LastUsedAt = &usedAt
+	return nil
+}
+
+func (m *memoryAPITokenStore) DeleteAPIToken(id string) error {
+	delete(m.tokens, id)
+	return nil
+}
+
+type memoryUsers struct {
+	DatabaseClient
+	users map[string]*DatabaseUser
+}
+
+func (m memoryUsers) GetUserByID(userID string) (*DatabaseUser, error) {
+	if user, ok := m.users[userID]; ok {
+		return user, nil
+	}
+	return nil, errors.New("user not found")
+}
+
+func TestAPITokens(t *testing.T) {
+	users := memoryUsers{users: map[string]*DatabaseUser{
+		"user-1": {ID: "user-1", Username: "ci", Role: "operator"},
+	}}
+	store := &memoryAPITokenStore{tokens: map[string]*APIToken{}}
+	service := NewService("test-secret", noRevocations{}, users)
+	service.SetAPITokenStore(store)
+
+	if _, _, err := service.CreateAPIToken("user-1", "bad", []string{"everything"}, 0); !errors.Is(err, ErrInvalidScope) {
+		t.Fatalf("expected ErrInvalidScope, got %v", err)
+	}
+
+	plain, token, err := service.CreateAPIToken("user-1", "pipeline", []string{"deploy"}, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateAPIToken failed: %v", err)
+	}
+	if token.TokenHash == plain || token.Prefix != plain[:len(token.Prefix)] {
+		t.Errorf("unexpected stored token: %+v", token)
+	}
+
+	claims, err := service.ValidateToken(plain)
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if claims.APITokenID != token.ID || claims.Role != "operator" || claims.Username != "ci" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+	if store.tokens[token.ID].LastUsedAt == nil {
+		t.Error("expected last use to be recorded")
+	}
+
+	if _, err := service.ValidateToken(plain + "x"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for an unknown token, got %v", err)
+	}
+
+	// Tokens stop working when their owner is deleted or the token is revoked
+	delete(users.users, "user-1")
+	if _, err := service.ValidateToken(plain); err == nil {
+		t.Error("expected token of deleted user to be rejected")
+	}
+	users.users["user-1"] = &DatabaseUser{ID: "user-1", Username: "ci", Role: "operator"}
+	if err := service.RevokeAPIToken(token.ID); err != nil {
+		t.Fatalf("RevokeAPIToken failed: %v", err)
+	}
+	if _, err := service.ValidateToken(plain); err == nil {
+		t.Error("expected revoked token to be rejected")
+	}
+}
+
+func TestScopesAllow(t *testing.T) {
+	tests := []struct {
+		scopes []string
+		method string
+		path   string
+		want   bool
+	}{
+		{[]string{"read"}, "GET", "/api/k8s/pods", true},
+		{[]string{"read"}, "DELETE", "/api/k8s/pods/web", false},
+		{[]string{"read"}, "GET", "/api/k8s/pods/exec", false},
+		{[]string{"read"}, "GET", "/api/k8s/pods/exec/shared/abc", false},
+		{[]string{"read"}, "GET", "/api/k8s/nodes/worker-1/shell", false},
+		{[]string{"read"}, "GET", "/api/k8s/nodes/worker-1", true},
+		{[]string{"full"}, "GET", "/api/k8s/nodes/worker-1/shell", true},
+		{[]string{"metrics:read"}, "GET", "/api/metrics/cluster", true},
+		{[]string{"metrics:read"}, "GET", "/api/k8s/pods", false},
+		{[]string{"deploy"}, "POST", "/api/deployments", true},
+		{[]string{"deploy"}, "PATCH", "/api/k8s/deployments/web/scale", true},
+		{[]string{"deploy"}, "POST", "/api/gitops/applications/app-1/promote", true},
+		{[]string{"deploy"}, "POST", "/api/gitops/repositories", false},
+		{[]string{"deploy"}, "POST", "/api/deploymentsx", false},
+		{[]string{"full"}, "DELETE", "/api/k8s/pods/web", true},
+		{[]string{"full"}, "GET", "/api/auth/tokens", false},
+		{[]string{"read", "deploy"}, "POST", "/api/deployments", true},
+	}
+	for _, tt := range tests {
+		if got := ScopesAllow(tt.scopes, tt.method, tt.path); got != tt.want {
+			t.Errorf("ScopesAllow(%v, %s, %s) = %v, want %v", tt.scopes, tt.method, tt.path, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package auth
 
 import (
+	"database/sql"
+	"errors"
+	"time"
+
 	"github.com/archellir/denshimon/internal/database"
 )
 
@@ -90,3 +94,152 @@ func (a *DatabaseAdapter) ListUsers() ([]*DatabaseUser, error) {
 
 	return result, nil
 }
+
+func (a *DatabaseAdapter) CreateSession(session *Session) error {
+	return a.db.CreateUserSession(&database.Session{
+		ID:         session.ID,
+		UserID:     session.UserID,
+		UserAgent:  session.UserAgent,
+		IPAddress:  session.IPAddress,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+	})
+}
+
+func (a *DatabaseAdapter) GetSession(id string) (*Session, error) {
+	session, err := a.db.GetUserSession(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromDatabaseSession(session), nil
+}
+
+func (a *DatabaseAdapter) ListSessions(userID string) ([]*Session, error) {
+	sessions, err := a.db.ListUserSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Session, len(sessions))
+	for i, session := range sessions {
+		result[i] = fromDatabaseSession(session)
+	}
+	return result, nil
+}
+
+func (a *DatabaseAdapter) TouchSession(id string, lastSeen, expiresAt time.Time) error {
+	return a.db.TouchUserSession(id, lastSeen, expiresAt)
+}
+
+func (a *DatabaseAdapter) DeleteSession(id string) error {
+	return a.db.DeleteSession(id)
+}
+
+func fromDatabaseSession(session *database.Session) *Session {
+	return &Session{
+		ID:         session.ID,
+		UserID:     session.UserID,
+		UserAgent:  session.UserAgent,
+		IPAddress:  session.IPAddress,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+	}
+}
+
+func (a *DatabaseAdapter) SaveSigningKey(key *StoredKey) error {
+	return a.db.SaveSigningKey(&database.SigningKey{
+		ID:        key.ID,
+		Material:  key.Material,
+		CreatedAt: key.CreatedAt,
+		RetiredAt: key.RetiredAt,
+	})
+}
+
+func (a *DatabaseAdapter) ListSigningKeys() ([]*StoredKey, error) {
+	keys, err := a.db.ListSigningKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*StoredKey, len(keys))
+	for i, key := range keys {
+		result[i] = &StoredKey{
+			ID:        key.ID,
+			Material:  key.Material,
+			CreatedAt: key.CreatedAt,
+			RetiredAt: key.RetiredAt,
+		}
+	}
+	return result, nil
+}
+
+func (a *DatabaseAdapter) DeleteSigningKey(id string) error {
+	return a.db.DeleteSigningKey(id)
+}
+
+func (a *DatabaseAdapter) CreateAPIToken(token *APIToken) error {
+	return a.db.CreateAPIToken(&database.APIToken{
+		ID:        token.ID,
+		UserID:    token.UserID,
+		Name:      token.Name,
+		Prefix:    token.Prefix,
+		TokenHash: token.TokenHash,
+		Scopes:    token.Scopes,
+		CreatedAt: token.CreatedAt,
+		ExpiresAt: token.ExpiresAt,
+	})
+}
+
+func (a *DatabaseAdapter) GetAPITokenByHash(hash string) (*APIToken, error) {
+	return fromDatabaseAPIToken(a.db.GetAPITokenByHash(hash))
+}
+
+func (a *DatabaseAdapter) GetAPIToken(id string) (*APIToken, error) {
+	return fromDatabaseAPIToken(a.db.GetAPIToken(id))
+}
+
+func (a *DatabaseAdapter) ListAPITokens(userID string) ([]*APIToken, error) {
+	tokens, err := a.db.ListAPITokens(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*APIToken, len(tokens))
+	for i, token := range tokens {
+		result[i], _ = fromDatabaseAPIToken(token, nil)
+	}
+	return result, nil
+}
+
+func (a *DatabaseAdapter) TouchAPIToken(id string, usedAt time.Time) error {
+	return a.db.TouchAPIToken(id, usedAt)
+}
+
+func (a *DatabaseAdapter) DeleteAPIToken(id string) error {
+	return a.db.DeleteAPIToken(id)
+}
+
+func fromDatabaseAPIToken(token *database.APIToken, err error) (*APIToken, error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPITokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &APIToken{
+		ID:         token.ID,
+		UserID:     token.UserID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     token.Scopes,
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		TokenHash:  token.TokenHash,
+	}, nil
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+)
+
+// MaxTokenLifetime bounds how long issued tokens stay valid, and so how long
+// a rotated-out key must still be accepted
+const MaxTokenLifetime = 24 * time.Hour
+
+// ErrUnknownKey is returned for tokens whose footer names a key that is not loaded
+var ErrUnknownKey = errors.New("token signed with unknown key")
+
+// SigningKey is a PASETO v4 local key. Tokens carry its ID in their footer so
+// several keys can be valid at once while a rotation takes effect.
+type SigningKey struct {
+	ID        string     `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"` // no longer used for new tokens
+	Active    bool       `json:"active"`
+	key       paseto.V4SymmetricKey
+}
+
+// StoredKey is a signing key as persisted, with the key material encrypted under the master key
+type StoredKey struct {
+	ID        string
+	Material  string
+	CreatedAt time.Time
+	RetiredAt *time.Time
+}
+
+// KeyStore persists signing keys so tokens survive restarts
+type KeyStore interface {
+	SaveSigningKey(key *StoredKey) error
+	ListSigningKeys() ([]*StoredKey, error)
+	DeleteSigningKey(id string) error
+}
+
+type tokenFooter struct {
+	KeyID string `json:"kid"`
+}
+
+func newSigningKey() *SigningKey {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return &SigningKey{
+		ID:        fmt.Sprintf("k%d-%x", time.Now().Unix(), bytes),
+		CreatedAt: time.Now().UTC(),
+		key:       paseto.NewV4SymmetricKey(),
+	}
+}
+
+// SetKeyStore loads the persisted signing keys, replacing the key generated at startup.
+// Keys that cannot be decrypted with the master key (e.g. after PASETO_SECRET_KEY changed)
+// are discarded; when none remain, the current key is persisted.
+func (s *Service) SetKeyStore(store KeyStore) error {
+	stored, err := store.ListSigningKeys()
+	if err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	var keys []*SigningKey
+	for _, sk := range stored {
+		key, err := s.unwrapKey(sk)
+		if err != nil {
+			slog.Warn("Discarding signing key that cannot be decrypted", "kid", sk.ID, "error", err)
+			store.DeleteSigningKey(sk.ID)
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	s.keysMutex.Lock()
+	defer s.keysMutex.Unlock()
+	s.keyStore = store
+	if len(keys) == 0 {
+		return s.saveKey(s.keys[0])
+	}
+	// Newest first; the newest key that has not been retired signs new tokens
+	sortKeys(keys)
+	if keys[0].RetiredAt != nil {
+		if err := s.saveKey(s.keys[0]); err != nil {
+			return err
+		}
+		keys = append([]*SigningKey{s.keys[0]}, keys...)
+	}
+	s.keys = keys
+	return nil
+}
+
+// RotateKeys makes a new key active for signing. The previous key keeps validating
+// tokens until they expire, after which it is removed.
+func (s *Service) RotateKeys() (*SigningKey, error) {
+	key := newSigningKey()
+
+	s.keysMutex.Lock()
+	defer s.keysMutex.Unlock()
+
+	if err := s.saveKey(key); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	keys := []*SigningKey{key}
+	for _, old := range s.keys {
+		if old.RetiredAt == nil {
+			old.RetiredAt = &now
+			if err := s.saveKey(old); err != nil {
+				return nil, err
+			}
+		}
+		if now.Sub(*old.RetiredAt) > MaxTokenLifetime {
+			if s.keyStore != nil {
+				if err := s.keyStore.DeleteSigningKey(old.ID); err != nil {
+					return nil, fmt.Errorf("failed to delete signing key: %w", err)
+				}
+			}
+			continue
+		}
+		keys = append(keys, old)
+	}
+	s.keys = keys
+
+	slog.Info("Rotated token signing key", "kid", key.ID)
+	return s.describeKey(key, true), nil
+}
+
+// SigningKeys describes the loaded keys, newest first, without their material
+func (s *Service) SigningKeys() []*SigningKey {
+	s.keysMutex.RLock()
+	defer s.keysMutex.RUnlock()
+
+	keys := make([]*SigningKey, len(s.keys))
+	for i, key := range s.keys {
+		keys[i] = s.describeKey(key, i == 0)
+	}
+	return keys
+}
+
+// StartKeyRotation rotates the signing key whenever the active key is older than interval.
+// The key age is checked periodically, so restarts do not postpone a due rotation.
+func (s *Service) StartKeyRotation(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	check := time.Hour
+	if interval < check {
+		check = interval
+	}
+
+	go func() {
+		ticker := time.NewTicker(check)
+		defer ticker.Stop()
+		for {
+			if time.Since(s.activeKey().CreatedAt) >= interval {
+				if _, err := s.RotateKeys(); err != nil {
+					slog.Error("Failed to rotate token signing key", "error", err)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *Service) activeKey() *SigningKey {
+	s.keysMutex.RLock()
+	defer s.keysMutex.RUnlock()
+	return s.keys[0]
+}
+
+func (s *Service) keyByID(id string) (*SigningKey, bool) {
+	s.keysMutex.RLock()
+	defer s.keysMutex.RUnlock()
+	for _, key := range s.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// keyForToken reads the key ID from the token footer
+func (s *Service) keyForToken(tokenString string) (*SigningKey, error) {
+	parser := paseto.NewParser()
+	footer, err := parser.UnsafeParseFooter(paseto.V4Local, tokenString)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var f tokenFooter
+	if err := json.Unmarshal(footer, &f); err != nil || f.KeyID == "" {
+		return nil, ErrInvalidToken
+	}
+	key, ok := s.keyByID(f.KeyID)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+func (s *Service) describeKey(key *SigningKey, active bool) *SigningKey {
+	return &SigningKey{
+		ID:        key.ID,
+		CreatedAt: key.CreatedAt,
+		RetiredAt: key.RetiredAt,
+		Active:    active,
+	}
+}
+
+// saveKey persists a key when a store is configured; callers hold keysMutex
+func (s *Service) saveKey(key *SigningKey) error {
+	if s.keyStore == nil {
+		return nil
+	}
+	if err := s.keyStore.SaveSigningKey(&StoredKey{
+		ID:        key.ID,
+		Material:  s.wrapKey(key),
+		CreatedAt: key.CreatedAt,
+		RetiredAt: key.RetiredAt,
+	}); err != nil {
+		return fmt.Errorf("failed to save signing key: %w", err)
+	}
+	return nil
+}
+
+// wrapKey encrypts key material with the master key derived from PASETO_SECRET_KEY
+func (s *Service) wrapKey(key *SigningKey) string {
+	token := paseto.NewToken()
+	token.SetString("kid", key.ID)
+	token.SetString("key", key.key.ExportHex())
+	return token.V4Encrypt(s.masterKey, nil)
+}
+
+func (s *Service) unwrapKey(stored *StoredKey) (*SigningKey, error) {
+	token, err := paseto.NewParserWithoutExpiryCheck().ParseV4Local(s.masterKey, stored.Material, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The ID is bound into the encrypted material so keys cannot be swapped between rows
+	if kid, err := token.GetString("kid"); err != nil || kid != stored.ID {
+		return nil, errors.New("key ID mismatch")
+	}
+	material, err := token.GetString("key")
+	if err != nil {
+		return nil, err
+	}
+	key, err := paseto.V4SymmetricKeyFromHex(material)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{ID: stored.ID, CreatedAt: stored.CreatedAt, RetiredAt: stored.RetiredAt, key: key}, nil
+}
+
+// sortKeys orders keys with unretired keys first, then newest first
+func sortKeys(keys []*SigningKey) {
+	sort.SliceStable(keys, func(i, j int) bool {
+		if (keys[i].RetiredAt == nil) != (keys[j].RetiredAt == nil) {
+			return keys[i].RetiredAt == nil
+		}
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryKeyStore struct {
+	keys map[string]*StoredKey
+}
+
+func (m *memoryKeyStore) SaveSigningKey(key *StoredKey) error {
+	m.keys[key.ID] = key
+	return nil
+}
+
+func (m *memoryKeyStore) ListSigningKeys() ([]*StoredKey, error) {
+	var keys []*StoredKey
+	for _, key := range m.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (m *memoryKeyStore) DeleteSigningKey(id string) error {
+	delete(m.keys, id)
+	return nil
+}
+
+type memorySessionStore struct {
+	mutex    sync.Mutex
+	sessions map[string]*Session
+}
+
+func (m *memorySessionStore) CreateSession(session *Session) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	copied := *session
+	m.sessions[session.ID] = &copied
+	return nil
+}
+
+func (m *memorySessionStore) GetSession(id string) (*Session, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	session, ok := m.sessions[id]
+	if !ok || session.ExpiresAt.Before(time.Now()) {
+		return nil, ErrSessionNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (m *memorySessionStore) ListSessions(userID string) ([]*Session, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var sessions []*Session
+	for _, session := range m.sessions {
+		if session.UserID == userID {
+			copied := *session
+			sessions = append(sessions, &copied)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *memorySessionStore) TouchSession(id string, lastSeen, expiresAt time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if session, ok := m.sessions[id]; ok {
+		session.LastSeenAt = lastSeen
+		session.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+func (m *memorySessionStore) DeleteSession(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+// noRevocations stands in for the token blacklist
+type noRevocations struct{}
+
+func (noRevocations) Set(string, interface{}, time.Duration) error { return nil }
+func (noRevocations) Get(string) (string, error)                   { return "", errors.New("not found") }
+func (noRevocations) Delete(string) error                          { return nil }
+
+func TestKeyRotation(t *testing.T) {
+	store := &memoryKeyStore{keys: map[string]*StoredKey{}}
+	service := NewService("test-secret", noRevocations{}, nil)
+	if err := service.SetKeyStore(store); err != nil {
+		t.Fatalf("SetKeyStore failed: %v", err)
+	}
+	if len(store.keys) != 1 {
+		t.Fatalf("expected the startup key to be persisted, got %d keys", len(store.keys))
+	}
+
+	user := &User{ID: "user-1", Username: "alice", Role: "admin"}
+	oldToken, err := service.GenerateToken(user, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken failed: %v", err)
+	}
+
+	rotated, err := service.RotateKeys()
+	if err != nil {
+		t.Fatalf("RotateKeys failed: %v", err)
+	}
+	newToken, _ := service.GenerateToken(user, time.Hour)
+
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		claims, err := service.ValidateToken(token)
+		if err != nil {
+			t.Fatalf("%s token rejected after rotation: %v", name, err)
+		}
+		if name == "new" && claims.KeyID != rotated.ID {
+			t.Errorf("new token signed with %s, want %s", claims.KeyID, rotated.ID)
+		}
+	}
+
+	keys := service.SigningKeys()
+	if len(keys) != 2 || !keys[0].Active || keys[0].ID != rotated.ID || keys[1].RetiredAt == nil {
+		t.Errorf("unexpected keys after rotation: %+v", keys)
+	}
+
+	// A restarted service with the same secret loads both keys
+	restarted := NewService("test-secret", noRevocations{}, nil)
+	if err := restarted.SetKeyStore(store); err != nil {
+		t.Fatalf("SetKeyStore failed: %v", err)
+	}
+	if _, err := restarted.ValidateToken(oldToken); err != nil {
+		t.Errorf("old token rejected after restart: %v", err)
+	}
+	if restarted.activeKey().ID != rotated.ID {
+		t.Errorf("restarted service signs with %s, want %s", restarted.activeKey().ID, rotated.ID)
+	}
+
+	// A different secret cannot decrypt the stored keys, so their tokens are unknown
+	other := NewService("another-secret", noRevocations{}, nil)
+	if err := other.SetKeyStore(&memoryKeyStore{keys: map[string]*StoredKey{rotated.ID: store.keys[rotated.ID]}}); err != nil {
+		t.Fatalf("SetKeyStore failed: %v", err)
+	}
+	if _, err := other.ValidateToken(newToken); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestSessionRevocation(t *testing.T) {
+	sessions := &memorySessionStore{sessions: map[string]*Session{}}
+	service := NewService("test-secret", noRevocations{}, nil)
+	service.SetSessionStore(sessions)
+
+	user := &User{ID: "user-1", Username: "alice", Role: "viewer"}
+	token, session, err := service.CreateSession(user, time.Hour, "curl/8.0", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	other, _, _ := service.CreateSession(user, time.Hour, "firefox", "10.0.0.2")
+
+	claims, err := service.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if claims.SessionID != session.ID {
+		t.Errorf("token session = %q, want %q", claims.SessionID, session.ID)
+	}
+
+	// Refreshed tokens stay bound to the session
+	renewed, err := service.RenewSession(claims, time.Hour)
+	if err != nil {
+		t.Fatalf("RenewSession failed: %v", err)
+	}
+
+	list, err := service.ListSessions(user.ID, session.ID)
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	current := 0
+	for _, s := range list {
+		if s.Current {
+			current++
+		}
+	}
+	if len(list) != 2 || current != 1 {
+		t.Errorf("expected 2 sessions with 1 current, got %d with %d current", len(list), current)
+	}
+
+	if err := service.RevokeSession(session.ID); err != nil {
+		t.Fatalf("RevokeSession failed: %v", err)
+	}
+	for _, revoked := range []string{token, renewed} {
+		if _, err := service.ValidateToken(revoked); !errors.Is(err, ErrSessionRevoked) {
+			t.Errorf("expected ErrSessionRevoked, got %v", err)
+		}
+	}
+	if _, err := service.ValidateToken(other); err != nil {
+		t.Errorf("other session's token rejected: %v", err)
+	}
+}
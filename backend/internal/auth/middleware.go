@@ -2,8 +2,12 @@ package auth
 
 import (
 	"context"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+
+	"github.com/archellir/denshimon/internal/ratelimit"
 )
 
 type contextKey string
@@ -42,6 +46,20 @@ func (s *Service) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		if s.userLimiter != nil {
+			if ok, wait := s.userLimiter.Allow(claims.UserID); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		// API tokens are limited to the requests their scopes cover
+		if claims.APITokenID != "" && !ScopesAllow(claims.Scopes, r.Method, r.URL.Path) {
+			http.Error(w, "Token scope does not permit this request", http.StatusForbidden)
+			return
+		}
+
 		// Add user claims to request context
 		ctx := context.WithValue(r.Context(), UserContextKey, claims)
 		r = r.WithContext(ctx)
@@ -51,6 +69,11 @@ func (s *Service) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// SetUserRateLimit limits authenticated requests per user
+func (s *Service) SetUserRateLimit(limiter *ratelimit.Limiter) {
+	s.userLimiter = limiter
+}
+
 // RequireRole middleware checks if user has required role
 func (s *Service) RequireRole(role string) func(http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
@@ -107,7 +130,8 @@ func (s *Service) OptionalAuth(next http.HandlerFunc) http.HandlerFunc {
 			parts := strings.Split(authHeader, " ")
 			if len(parts) == 2 && parts[0] == "Bearer" {
 				token := parts[1]
-				if claims, err := s.ValidateToken(token); err == nil {
+				if claims, err := s.ValidateToken(token); err == nil &&
+					(claims.APITokenID == "" || ScopesAllow(claims.Scopes, r.Method, r.URL.Path)) {
 					ctx := context.WithValue(r.Context(), UserContextKey, claims)
 					r = r.WithContext(ctx)
 				}
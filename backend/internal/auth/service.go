@@ -8,17 +8,28 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"aidanwoods.dev/go-paseto"
+	"github.com/archellir/denshimon/internal/ratelimit"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Service struct {
 	secretKey []byte
-	pasetoKey paseto.V4SymmetricKey
+	masterKey paseto.V4SymmetricKey // encrypts persisted signing keys
 	redis     RedisClient
 	db        DatabaseClient
+	sessions  SessionStore
+	apiTokens APITokenStore
+
+	userLimiter *ratelimit.Limiter
+
+	keysMutex sync.RWMutex
+	keys      []*SigningKey // keys[0] signs new tokens
+	keyStore  KeyStore
 }
 
 type DatabaseClient interface {
@@ -53,12 +64,15 @@ type User struct {
 }
 
 type TokenClaims struct {
-	UserID   string   `json:"user_id"`
-	Username string   `json:"username"`
-	Role     string   `json:"role"`
-	Scopes   []string `json:"scopes"`
-	IssuedAt int64    `json:"iat"`
-	ExpireAt int64    `json:"exp"`
+	UserID     string   `json:"user_id"`
+	Username   string   `json:"username"`
+	Role       string   `json:"role"`
+	Scopes     []string `json:"scopes"`
+	SessionID  string   `json:"sid,omitempty"`
+	KeyID      string   `json:"kid,omitempty"`
+	APITokenID string   `json:"api_token_id,omitempty"` // set for API tokens; Scopes are then the token's scopes
+	IssuedAt   int64    `json:"iat"`
+	ExpireAt   int64    `json:"exp"`
 }
 
 func NewService(secretKey string, redis RedisClient, db DatabaseClient) *Service {
@@ -73,18 +87,25 @@ func NewService(secretKey string, redis RedisClient, db DatabaseClient) *Service
 		}
 	}
 
-	// Create PASETO symmetric key
-	pasetoKey := paseto.NewV4SymmetricKey()
+	// The master key only protects stored signing keys; tokens are encrypted with
+	// a random key that SetKeyStore replaces with the persisted ones
+	masterKey, _ := paseto.V4SymmetricKeyFromBytes(key)
 
 	return &Service{
 		secretKey: key,
-		pasetoKey: pasetoKey,
+		masterKey: masterKey,
 		redis:     redis,
 		db:        db,
+		keys:      []*SigningKey{newSigningKey()},
 	}
 }
 
 func (s *Service) GenerateToken(user *User, duration time.Duration) (string, error) {
+	return s.issueToken(user, "", duration)
+}
+
+// issueToken encrypts a token with the active key, naming the key in the footer
+func (s *Service) issueToken(user *User, sessionID string, duration time.Duration) (string, error) {
 	now := time.Now()
 
 	claims := TokenClaims{
@@ -102,11 +123,21 @@ func (s *Service) GenerateToken(user *User, duration time.Duration) (string, err
 	token.SetString("username", claims.Username)
 	token.SetString("role", claims.Role)
 	token.Set("scopes", claims.Scopes)
+	if sessionID != "" {
+		token.SetString("sid", sessionID)
+	}
 	token.SetIssuedAt(now)
 	token.SetExpiration(now.Add(duration))
 
+	key := s.activeKey()
+	footer, err := json.Marshal(tokenFooter{KeyID: key.ID})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token footer: %w", err)
+	}
+	token.SetFooter(footer)
+
 	// Encrypt the token
-	encrypted := token.V4Encrypt(s.pasetoKey, nil)
+	encrypted := token.V4Encrypt(key.key, nil)
 
 	return encrypted, nil
 }
@@ -117,15 +148,23 @@ func (s *Service) ValidateToken(tokenString string) (*TokenClaims, error) {
 		return nil, ErrTokenRevoked
 	}
 
-	// Parse and decrypt token
+	if strings.HasPrefix(tokenString, APITokenPrefix) {
+		return s.validateAPIToken(tokenString)
+	}
+
+	// Select the key named in the footer, then parse and decrypt token
+	key, err := s.keyForToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
 	parser := paseto.NewParser()
-	token, err := parser.ParseV4Local(s.pasetoKey, tokenString, nil)
+	token, err := parser.ParseV4Local(key.key, tokenString, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
 
 	// Extract claims
-	var claims TokenClaims
+	claims := TokenClaims{KeyID: key.ID}
 
 	if userID, err := token.GetString("user_id"); err == nil {
 		claims.UserID = userID
@@ -136,6 +175,9 @@ func (s *Service) ValidateToken(tokenString string) (*TokenClaims, error) {
 	if role, err := token.GetString("role"); err == nil {
 		claims.Role = role
 	}
+	if sessionID, err := token.GetString("sid"); err == nil {
+		claims.SessionID = sessionID
+	}
 	var iat int64
 	if err := token.Get("iat", &iat); err == nil {
 		claims.IssuedAt = iat
@@ -155,6 +197,10 @@ func (s *Service) ValidateToken(tokenString string) (*TokenClaims, error) {
 
 	// Note: PASETO library already validates expiration, so we don't need to check it manually
 
+	if err := s.checkSession(claims.SessionID); err != nil {
+		return nil, err
+	}
+
 	return &claims, nil
 }
 
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// sessionTouchInterval limits how often request activity is written to a session
+const sessionTouchInterval = time.Minute
+
+var (
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrSessionRevoked   = errors.New("session revoked")
+	ErrSessionsDisabled = errors.New("session management disabled - no database configured")
+)
+
+// Session is a login session. Tokens issued for it carry its ID, so deleting
+// the session revokes every token issued for it.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// SessionStore persists login sessions. GetSession returns ErrSessionNotFound
+// for missing or expired sessions.
+type SessionStore interface {
+	CreateSession(session *Session) error
+	GetSession(id string) (*Session, error)
+	ListSessions(userID string) ([]*Session, error)
+	TouchSession(id string, lastSeen, expiresAt time.Time) error
+	DeleteSession(id string) error
+}
+
+// SetSessionStore enables server-side sessions; tokens of deleted sessions are rejected
+func (s *Service) SetSessionStore(store SessionStore) {
+	s.sessions = store
+}
+
+// CreateSession starts a session for an authenticated user and issues its token
+func (s *Service) CreateSession(user *User, duration time.Duration, userAgent, ipAddress string) (string, *Session, error) {
+	now := time.Now().UTC()
+	session := &Session{
+		ID:         s.GenerateSessionID(),
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(duration),
+	}
+	if s.sessions != nil {
+		if err := s.sessions.CreateSession(session); err != nil {
+			return "", nil, fmt.Errorf("failed to create session: %w", err)
+		}
+	}
+
+	token, err := s.issueToken(user, session.ID, duration)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// RenewSession issues a new token for the same session and extends the session's expiry
+func (s *Service) RenewSession(claims *TokenClaims, duration time.Duration) (string, error) {
+	user := &User{
+		ID:       claims.UserID,
+		Username: claims.Username,
+		Role:     claims.Role,
+		Scopes:   claims.Scopes,
+	}
+	if claims.SessionID != "" && s.sessions != nil {
+		now := time.Now().UTC()
+		if err := s.sessions.TouchSession(claims.SessionID, now, now.Add(duration)); err != nil {
+			return "", fmt.Errorf("failed to extend session: %w", err)
+		}
+	}
+	return s.issueToken(user, claims.SessionID, duration)
+}
+
+// ListSessions returns a user's active sessions, marking the one with currentID
+func (s *Service) ListSessions(userID, currentID string) ([]*Session, error) {
+	if s.sessions == nil {
+		return nil, ErrSessionsDisabled
+	}
+	sessions, err := s.sessions.ListSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == currentID
+	}
+	return sessions, nil
+}
+
+// GetSession returns an active session
+func (s *Service) GetSession(sessionID string) (*Session, error) {
+	if s.sessions == nil {
+		return nil, ErrSessionsDisabled
+	}
+	return s.sessions.GetSession(sessionID)
+}
+
+// RevokeSession deletes a session; tokens issued for it stop validating immediately
+func (s *Service) RevokeSession(sessionID string) error {
+	if s.sessions == nil {
+		return ErrSessionsDisabled
+	}
+	return s.sessions.DeleteSession(sessionID)
+}
+
+// checkSession rejects tokens whose session was revoked or expired and records activity
+func (s *Service) checkSession(sessionID string) error {
+	if sessionID == "" || s.sessions == nil {
+		return nil
+	}
+	session, err := s.sessions.GetSession(sessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return ErrSessionRevoked
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check session: %w", err)
+	}
+
+	if now := time.Now().UTC(); now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.sessions.TouchSession(sessionID, now, session.ExpiresAt); err != nil {
+			slog.Debug("Failed to record session activity", "error", err)
+		}
+	}
+	return nil
+}
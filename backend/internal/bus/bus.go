@@ -0,0 +1,90 @@
+// Package bus is the in-process event bus subsystems publish typed events on, such as a
+// deployment applied or an alert fired, so consumers like the audit trail, the WebSocket
+// hub and outbound webhooks subscribe once instead of being called by each subsystem.
+package bus
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// Event is something that happened. Events are structs owned by the package that
+// publishes them, with a value receiver Topic naming their type, e.g. deployment.applied.
+type Event interface {
+	Topic() string
+}
+
+type subscription struct {
+	id      uint64
+	handler func(Event)
+}
+
+// Bus delivers published events to the subscribers of their topic. Handlers run
+// synchronously on the publishing goroutine, in the order they subscribed, so they
+// should hand slow work off. A nil Bus drops everything published on it.
+type Bus struct {
+	mu     sync.RWMutex
+	topics map[string][]subscription
+	nextID uint64
+}
+
+// New creates an event bus
+func New() *Bus {
+	return &Bus{topics: make(map[string][]subscription)}
+}
+
+// Subscribe calls handler with every event published on a topic until the returned
+// function is called
+func (b *Bus) Subscribe(topic string, handler func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	id := b.nextID
+	b.topics[topic] = append(b.topics[topic], subscription{id: id, handler: handler})
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subs := b.topics[topic]
+		for i := range subs {
+			if subs[i].id == id {
+				b.topics[topic] = append(subs[:i:i], subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// On subscribes a handler to the events of one type, e.g.
+//
+//	bus.On(b, func(change monitors.StateChange) { ... })
+func On[E Event](b *Bus, handler func(E)) (unsubscribe func()) {
+	var zero E
+	return b.Subscribe(zero.Topic(), func(event Event) {
+		if typed, ok := event.(E); ok {
+			handler(typed)
+		}
+	})
+}
+
+// Publish delivers an event to its topic's subscribers. A handler that panics is logged
+// and doesn't keep the event from the others.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	subs := b.topics[event.Topic()]
+	b.mu.RUnlock()
+	for _, sub := range subs {
+		deliver(sub.handler, event)
+	}
+}
+
+func deliver(handler func(Event), event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Event handler panicked", "topic", event.Topic(), "panic", r)
+		}
+	}()
+	handler(event)
+}
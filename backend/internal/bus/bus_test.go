@@ -0,0 +1,36 @@
+package bus
+
+import "testing"
+
+type applied struct{ Name string }
+
+func (applied) Topic() string { return "deployment.applied" }
+
+type rolledBack struct{ Name string }
+
+func (rolledBack) Topic() string { return "deployment.rolled_back" }
+
+func TestPublish(t *testing.T) {
+	b := New()
+	var got []string
+	On(b, func(e applied) { got = append(got, "first "+e.Name) })
+	On(b, func(e applied) { panic("broken handler") })
+	unsubscribe := On(b, func(e applied) { got = append(got, "second "+e.Name) })
+	On(b, func(e rolledBack) { got = append(got, "rollback "+e.Name) })
+
+	b.Publish(applied{Name: "api"})
+	b.Publish(rolledBack{Name: "web"})
+	if len(got) != 3 || got[0] != "first api" || got[1] != "second api" || got[2] != "rollback web" {
+		t.Fatalf("got %q", got)
+	}
+
+	unsubscribe()
+	got = nil
+	b.Publish(applied{Name: "api"})
+	if len(got) != 1 || got[0] != "first api" {
+		t.Errorf("after unsubscribing got %q", got)
+	}
+
+	var none *Bus
+	none.Publish(applied{Name: "api"}) // dropped
+}
@@ -0,0 +1,172 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/archellir/denshimon/internal/deployments"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// fakeDeployer records the deployments the catalog creates and applies
+type fakeDeployer struct {
+	created []deployments.CreateDeploymentRequest
+	applied []string
+}
+
+func (f *fakeDeployer) CreateDeployment(ctx context.Context, req deployments.CreateDeploymentRequest) (*deployments.Deployment, error) {
+	f.created = append(f.created, req)
+	return &deployments.Deployment{ID: "dep-1", Name: req.Name, Image: req.Image, Status: deployments.DeploymentStatusPendingApply}, nil
+}
+
+func (f *fakeDeployer) ApplyDeployment(ctx context.Context, deploymentID, appliedBy string) error {
+	f.applied = append(f.applied, deploymentID+"/"+appliedBy)
+	return nil
+}
+
+func (f *fakeDeployer) GetDeployment(ctx context.Context, id string) (*deployments.Deployment, error) {
+	return &deployments.Deployment{ID: id, Status: deployments.DeploymentStatusRunning}, nil
+}
+
+func postgresTemplate() Template {
+	return Template{
+		Name:  "postgres",
+		Image: "postgres:16",
+		Ports: []deployments.ContainerPort{{Name: "postgres", ContainerPort: 5432}},
+		Parameters: []Parameter{
+			{Name: "POSTGRES_PASSWORD", Required: true, Pattern: `^.{12,}$`},
+			{Name: "POSTGRES_DB", Default: "app"},
+			{Name: "MAX_CONNECTIONS", Type: ParameterNumber},
+			{Name: "LOG_LEVEL", Type: ParameterEnum, Options: []string{"info", "debug"}, Default: "info"},
+		},
+		Volumes: []deployments.Volume{{Name: "data", Type: deployments.VolumePVC, Source: "postgres-data", MountPath: "/var/lib/postgresql/data"}},
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	template := postgresTemplate()
+	if err := template.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if template.Replicas != 1 || template.Title != "postgres" || template.Parameters[1].Type != ParameterString {
+		t.Errorf("defaults not applied: %+v", template)
+	}
+
+	invalid := []func(*Template){
+		func(t *Template) { t.Name = "Postgres DB" },
+		func(t *Template) { t.Image = "" },
+		func(t *Template) { t.Ports[0].ContainerPort = 70000 },
+		func(t *Template) { t.Volumes[0].Source = "" },
+		func(t *Template) { t.Parameters[1].Name = "POSTGRES-DB" },
+		func(t *Template) { t.Parameters[2].Name = "POSTGRES_DB" },
+		func(t *Template) { t.Parameters[3].Default = "trace" },
+		func(t *Template) { t.Parameters[3].Options = nil },
+	}
+	for i, mutate := range invalid {
+		template := postgresTemplate()
+		mutate(&template)
+		if err := template.Validate(); err == nil {
+			t.Errorf("case %d: expected template to be invalid", i)
+		}
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	template := postgresTemplate()
+	template.Validate()
+
+	create, err := template.Instantiate(DeployRequest{
+		Name:       "orders-db",
+		Namespace:  "apps",
+		Tag:        "16.4",
+		Parameters: map[string]string{"POSTGRES_PASSWORD": "correct-horse-battery"},
+	})
+	if err != nil {
+		t.Fatalf("Instantiate failed: %v", err)
+	}
+	if create.Image != "postgres:16.4" || create.Replicas != 1 || len(create.Ports) != 1 || len(create.Volumes) != 1 {
+		t.Errorf("unexpected deployment: %+v", create)
+	}
+	if create.Environment["POSTGRES_DB"] != "app" || create.Environment["LOG_LEVEL"] != "info" {
+		t.Errorf("defaults not applied: %v", create.Environment)
+	}
+	if _, set := create.Environment["MAX_CONNECTIONS"]; set {
+		t.Error("optional parameter without default should be left unset")
+	}
+
+	_, err = template.Instantiate(DeployRequest{
+		Name:       "orders-db",
+		Namespace:  "apps",
+		Parameters: map[string]string{"POSTGRES_PASSWORD": "short", "MAX_CONNECTIONS": "many", "EXTRA": "x"},
+	})
+	var paramErr *ParameterError
+	if !errors.As(err, &paramErr) || len(paramErr.Problems) != 3 {
+		t.Fatalf("expected three parameter problems, got %v", err)
+	}
+}
+
+func TestWithTag(t *testing.T) {
+	tests := map[string]string{
+		"postgres:16":                       "postgres:v2",
+		"postgres":                          "postgres:v2",
+		"registry.local:5000/team/api:1.0":  "registry.local:5000/team/api:v2",
+		"registry.local:5000/team/api":      "registry.local:5000/team/api:v2",
+		"ghcr.io/acme/api@sha256:abcdef012": "ghcr.io/acme/api:v2",
+	}
+	for image, want := range tests {
+		if got := withTag(image, "v2"); got != want {
+			t.Errorf("withTag(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestServiceDeploy(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	deployer := &fakeDeployer{}
+	service, err := NewService(db, deployer)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := service.Create(ctx, postgresTemplate(), "admin"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := service.Create(ctx, postgresTemplate(), "admin"); !errors.Is(err, ErrExists) {
+		t.Errorf("expected ErrExists for a duplicate name, got %v", err)
+	}
+
+	stored, err := service.Get(ctx, "postgres")
+	if err != nil || len(stored.Parameters) != 4 || stored.Volumes[0].Source != "postgres-data" {
+		t.Fatalf("template not stored intact: %+v %v", stored, err)
+	}
+
+	deployment, err := service.Deploy(ctx, "postgres", DeployRequest{
+		Name:       "orders-db",
+		Namespace:  "apps",
+		Parameters: map[string]string{"POSTGRES_PASSWORD": "correct-horse-battery"},
+		Apply:      true,
+	}, "alice")
+	if err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	if deployment.Status != deployments.DeploymentStatusRunning || strings.Join(deployer.applied, ",") != "dep-1/alice" {
+		t.Errorf("deployment not applied: %+v %v", deployment, deployer.applied)
+	}
+
+	if _, err := service.Deploy(ctx, "redis", DeployRequest{Name: "cache", Namespace: "apps"}, "alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if len(deployer.created) != 1 {
+		t.Errorf("expected one deployment, got %d", len(deployer.created))
+	}
+}
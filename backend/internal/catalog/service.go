@@ -0,0 +1,122 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/archellir/denshimon/internal/deployments"
+)
+
+// Deployer runs the create → commit → apply workflow of the deployments service
+type Deployer interface {
+	CreateDeployment(ctx context.Context, req deployments.CreateDeploymentRequest) (*deployments.Deployment, error)
+	ApplyDeployment(ctx context.Context, deploymentID, appliedBy string) error
+	GetDeployment(ctx context.Context, id string) (*deployments.Deployment, error)
+}
+
+// Service manages catalog templates and deploys them
+type Service struct {
+	store    *Store
+	deployer Deployer
+}
+
+// NewService creates a catalog service backed by the given database
+func NewService(db *sql.DB, deployer Deployer) (*Service, error) {
+	store, err := NewStore(db)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{store: store, deployer: deployer}, nil
+}
+
+// List returns every template
+func (s *Service) List(ctx context.Context) ([]Template, error) {
+	return s.store.List(ctx)
+}
+
+// Get returns one template
+func (s *Service) Get(ctx context.Context, name string) (*Template, error) {
+	return s.store.Get(ctx, name)
+}
+
+// Create validates and stores a new template
+func (s *Service) Create(ctx context.Context, template Template, createdBy string) (*Template, error) {
+	if err := template.Validate(); err != nil {
+		return nil, err
+	}
+	normalize(&template)
+	now := time.Now()
+	template.CreatedBy = createdBy
+	template.CreatedAt = now
+	template.UpdatedAt = now
+	if err := s.store.Create(ctx, &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// Update replaces a template's definition; its name cannot change
+func (s *Service) Update(ctx context.Context, name string, template Template) (*Template, error) {
+	existing, err := s.store.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	template.Name = existing.Name
+	if err := template.Validate(); err != nil {
+		return nil, err
+	}
+	normalize(&template)
+	template.CreatedBy = existing.CreatedBy
+	template.CreatedAt = existing.CreatedAt
+	template.UpdatedAt = time.Now()
+	if err := s.store.Update(ctx, &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// Delete removes a template
+func (s *Service) Delete(ctx context.Context, name string) error {
+	return s.store.Delete(ctx, name)
+}
+
+// Deploy instantiates a template and hands the result to the deployments service,
+// which commits it to GitOps. With req.Apply the deployment is also applied.
+func (s *Service) Deploy(ctx context.Context, name string, req DeployRequest, user string) (*deployments.Deployment, error) {
+	template, err := s.store.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	create, err := template.Instantiate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	create.CreatedBy = user
+	deployment, err := s.deployer.CreateDeployment(ctx, *create)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deployment: %w", err)
+	}
+	if !req.Apply {
+		return deployment, nil
+	}
+	if err := s.deployer.ApplyDeployment(ctx, deployment.ID, user); err != nil {
+		return deployment, fmt.Errorf("deployment %s was committed but not applied: %w", deployment.ID, err)
+	}
+	return s.deployer.GetDeployment(ctx, deployment.ID)
+}
+
+// normalize replaces nil lists so templates always serialise with arrays
+func normalize(t *Template) {
+	if t.Ports == nil {
+		t.Ports = []deployments.ContainerPort{}
+	}
+	if t.Parameters == nil {
+		t.Parameters = []Parameter{}
+	}
+	if t.Volumes == nil {
+		t.Volumes = []deployments.Volume{}
+	}
+}
@@ -0,0 +1,165 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned for unknown template names
+var ErrNotFound = errors.New("template not found")
+
+// ErrExists is returned when creating a template whose name is taken
+var ErrExists = errors.New("template already exists")
+
+// Store persists catalog templates in SQLite
+type Store struct {
+	db *sql.DB
+}
+
+// templateSpec holds the structured parts of a template, stored as one JSON column
+type templateSpec struct {
+	Ports      interface{} `json:"ports"`
+	Parameters interface{} `json:"parameters"`
+	Resources  interface{} `json:"resources"`
+	Volumes    interface{} `json:"volumes"`
+}
+
+// NewStore creates a template store and its table
+func NewStore(db *sql.DB) (*Store, error) {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS catalog_templates (
+			name TEXT PRIMARY KEY,
+			title TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			image TEXT NOT NULL,
+			registry_id TEXT NOT NULL DEFAULT '',
+			replicas INTEGER NOT NULL DEFAULT 1,
+			spec TEXT NOT NULL, -- JSON: ports, parameters, resources, volumes
+			created_by TEXT NOT NULL DEFAULT '',
+			created_at INTEGER NOT NULL, -- unix milliseconds
+			updated_at INTEGER NOT NULL
+		)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			return nil, fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+	return &Store{db: db}, nil
+}
+
+const templateColumns = `name, title, description, image, registry_id, replicas, spec, created_by, created_at, updated_at`
+
+func scanTemplate(scanner interface{ Scan(...interface{}) error }) (*Template, error) {
+	var t Template
+	var spec string
+	var createdAt, updatedAt int64
+	if err := scanner.Scan(&t.Name, &t.Title, &t.Description, &t.Image, &t.RegistryID, &t.Replicas,
+		&spec, &t.CreatedBy, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(spec), &templateSpec{
+		Ports:      &t.Ports,
+		Parameters: &t.Parameters,
+		Resources:  &t.Resources,
+		Volumes:    &t.Volumes,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to decode template %s: %w", t.Name, err)
+	}
+	t.CreatedAt = time.UnixMilli(createdAt)
+	t.UpdatedAt = time.UnixMilli(updatedAt)
+	return &t, nil
+}
+
+// List returns all templates ordered by title
+func (s *Store) List(ctx context.Context) ([]Template, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+templateColumns+` FROM catalog_templates ORDER BY title`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []Template{}
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *template)
+	}
+	return templates, rows.Err()
+}
+
+// Get returns one template by name
+func (s *Store) Get(ctx context.Context, name string) (*Template, error) {
+	template, err := scanTemplate(s.db.QueryRowContext(ctx, `SELECT `+templateColumns+` FROM catalog_templates WHERE name = ?`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return template, nil
+}
+
+// Create inserts a new template
+func (s *Store) Create(ctx context.Context, t *Template) error {
+	spec, err := encodeSpec(t)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO catalog_templates (`+templateColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.Name, t.Title, t.Description, t.Image, t.RegistryID, t.Replicas, spec, t.CreatedBy,
+		t.CreatedAt.UnixMilli(), t.UpdatedAt.UnixMilli())
+	if err != nil {
+		if _, getErr := s.Get(ctx, t.Name); getErr == nil {
+			return ErrExists
+		}
+		return fmt.Errorf("failed to create template: %w", err)
+	}
+	return nil
+}
+
+// Update replaces a template's definition
+func (s *Store) Update(ctx context.Context, t *Template) error {
+	spec, err := encodeSpec(t)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE catalog_templates SET title = ?, description = ?, image = ?, registry_id = ?, replicas = ?,
+			spec = ?, updated_at = ?
+		WHERE name = ?`,
+		t.Title, t.Description, t.Image, t.RegistryID, t.Replicas, spec, t.UpdatedAt.UnixMilli(), t.Name)
+	if err != nil {
+		return fmt.Errorf("failed to update template: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a template; deployments created from it are unaffected
+func (s *Store) Delete(ctx context.Context, name string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM catalog_templates WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func encodeSpec(t *Template) (string, error) {
+	spec, err := json.Marshal(templateSpec{Ports: t.Ports, Parameters: t.Parameters, Resources: t.Resources, Volumes: t.Volumes})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode template: %w", err)
+	}
+	return string(spec), nil
+}
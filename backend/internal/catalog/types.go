@@ -0,0 +1,241 @@
+// Package catalog holds reusable deployment templates that admins define once and
+// users instantiate with their own parameters.
+package catalog
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/deployments"
+)
+
+// Parameter types
+const (
+	ParameterString  = "string"
+	ParameterNumber  = "number"
+	ParameterBoolean = "boolean"
+	ParameterEnum    = "enum"
+)
+
+var (
+	templateNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	envNamePattern      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Template describes an application that can be deployed from the catalog
+type Template struct {
+	Name        string                           `json:"name"` // URL-safe identifier
+	Title       string                           `json:"title"`
+	Description string                           `json:"description"`
+	Image       string                           `json:"image"` // including the default tag
+	RegistryID  string                           `json:"registry_id"`
+	Replicas    int32                            `json:"replicas"`
+	Ports       []deployments.ContainerPort      `json:"ports"`
+	Parameters  []Parameter                      `json:"parameters"` // environment variable schema
+	Resources   deployments.ResourceRequirements `json:"resources"`
+	Volumes     []deployments.Volume             `json:"volumes"`
+	CreatedBy   string                           `json:"created_by"`
+	CreatedAt   time.Time                        `json:"created_at"`
+	UpdatedAt   time.Time                        `json:"updated_at"`
+}
+
+// Parameter is an environment variable users set when deploying a template
+type Parameter struct {
+	Name        string   `json:"name"` // environment variable name
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type"` // string (default), number, boolean or enum
+	Required    bool     `json:"required"`
+	Default     string   `json:"default,omitempty"`
+	Options     []string `json:"options,omitempty"` // allowed values of an enum
+	Pattern     string   `json:"pattern,omitempty"` // regular expression string values must match
+}
+
+// Validate checks a template before it is saved, defaulting replicas and parameter types
+func (t *Template) Validate() error {
+	if !templateNamePattern.MatchString(t.Name) {
+		return fmt.Errorf("name must be lowercase letters, digits and dashes")
+	}
+	if t.Image == "" {
+		return fmt.Errorf("image is required")
+	}
+	if t.Replicas < 0 {
+		return fmt.Errorf("replicas cannot be negative")
+	}
+	if t.Replicas == 0 {
+		t.Replicas = 1
+	}
+	if t.Title == "" {
+		t.Title = t.Name
+	}
+	for _, port := range t.Ports {
+		if err := port.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, volume := range t.Volumes {
+		if err := volume.Validate(); err != nil {
+			return err
+		}
+		// Templates deploy Deployments, so claims must already exist
+		if volume.Type == deployments.VolumePVC && volume.Source == "" {
+			return fmt.Errorf("volume %s needs a source", volume.Name)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i := range t.Parameters {
+		p := &t.Parameters[i]
+		if !envNamePattern.MatchString(p.Name) {
+			return fmt.Errorf("invalid parameter name: %q", p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate parameter: %s", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Type == "" {
+			p.Type = ParameterString
+		}
+		switch p.Type {
+		case ParameterString, ParameterNumber, ParameterBoolean:
+		case ParameterEnum:
+			if len(p.Options) == 0 {
+				return fmt.Errorf("enum parameter %s needs options", p.Name)
+			}
+		default:
+			return fmt.Errorf("invalid type for parameter %s: %s", p.Name, p.Type)
+		}
+		if p.Pattern != "" {
+			if _, err := regexp.Compile(p.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern for parameter %s: %w", p.Name, err)
+			}
+		}
+		if p.Default != "" {
+			if err := p.check(p.Default); err != nil {
+				return fmt.Errorf("invalid default for parameter %s: %w", p.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// check validates a value against the parameter's type, options and pattern
+func (p Parameter) check(value string) error {
+	switch p.Type {
+	case ParameterNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("must be a number")
+		}
+	case ParameterBoolean:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+	case ParameterEnum:
+		for _, option := range p.Options {
+			if value == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(p.Options, ", "))
+	}
+	if p.Pattern != "" && !regexp.MustCompile(p.Pattern).MatchString(value) {
+		return fmt.Errorf("must match %s", p.Pattern)
+	}
+	return nil
+}
+
+// DeployRequest instantiates a template
+type DeployRequest struct {
+	Name       string            `json:"name"`
+	Namespace  string            `json:"namespace"`
+	Replicas   int32             `json:"replicas,omitempty"` // defaults to the template's
+	Tag        string            `json:"tag,omitempty"`      // overrides the template image's tag
+	Parameters map[string]string `json:"parameters"`
+	Apply      bool              `json:"apply"` // apply to the cluster right after the GitOps commit
+}
+
+// ParameterError lists every problem with a deploy request
+type ParameterError struct {
+	Problems []string
+}
+
+func (e *ParameterError) Error() string {
+	return "invalid parameters: " + strings.Join(e.Problems, "; ")
+}
+
+// Instantiate validates a deploy request against the template and builds the
+// deployment it describes. Unknown parameters are rejected rather than ignored.
+func (t *Template) Instantiate(req DeployRequest) (*deployments.CreateDeploymentRequest, error) {
+	var problems []string
+	if req.Name == "" {
+		problems = append(problems, "name is required")
+	}
+	if req.Namespace == "" {
+		problems = append(problems, "namespace is required")
+	}
+	if req.Replicas < 0 {
+		problems = append(problems, "replicas cannot be negative")
+	}
+	environment := make(map[string]string)
+	known := make(map[string]bool)
+	for _, p := range t.Parameters {
+		known[p.Name] = true
+		value, set := req.Parameters[p.Name]
+		if !set || value == "" {
+			if p.Default != "" {
+				environment[p.Name] = p.Default
+			} else if p.Required {
+				problems = append(problems, p.Name+" is required")
+			}
+			continue
+		}
+		if err := p.check(value); err != nil {
+			problems = append(problems, p.Name+" "+err.Error())
+			continue
+		}
+		environment[p.Name] = value
+	}
+	for name := range req.Parameters {
+		if !known[name] {
+			problems = append(problems, name+" is not a parameter of "+t.Name)
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, &ParameterError{Problems: problems}
+	}
+
+	image := t.Image
+	if req.Tag != "" {
+		image = withTag(image, req.Tag)
+	}
+	replicas := req.Replicas
+	if replicas == 0 {
+		replicas = t.Replicas
+	}
+	return &deployments.CreateDeploymentRequest{
+		Name:        req.Name,
+		Namespace:   req.Namespace,
+		Image:       image,
+		RegistryID:  t.RegistryID,
+		Replicas:    replicas,
+		Strategy:    deployments.DeploymentStrategy{Type: "RollingUpdate", MaxSurge: 1},
+		Resources:   t.Resources,
+		Environment: environment,
+		Ports:       t.Ports,
+		Volumes:     t.Volumes,
+	}, nil
+}
+
+// withTag replaces the tag or digest of an image reference
+func withTag(image, tag string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	} else if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image + ":" + tag
+}
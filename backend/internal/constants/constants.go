@@ -11,6 +11,8 @@ const (
 	MessageTypeEvents           = "events"
 	MessageTypeWorkflows        = "workflows"
 	MessageTypeDeployments      = "deployments"
+	MessageTypeStatefulSets     = "statefulsets"
+	MessageTypeDaemonSets       = "daemonsets"
 	MessageTypeAlerts           = "alerts"
 	MessageTypeGiteaWebhook     = "gitea_webhook"
 	MessageTypeGithubWebhook    = "github_webhook"
@@ -78,6 +80,7 @@ const (
 	DeploymentStatusRunning     = "running"
 	DeploymentStatusFailed      = "failed"
 	DeploymentStatusApplyFailed = "apply_failed"       // GitOps: apply failed
+	DeploymentStatusRejected    = "rejected"           // GitOps: pending change rejected by an approver
 	DeploymentStatusUpdating    = "updating"
 	DeploymentStatusTerminating = "terminating"
 )
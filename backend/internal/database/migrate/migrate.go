@@ -0,0 +1,267 @@
+// Package migrate applies versioned SQL migrations to the SQLite database.
+//
+// Each module keeps its migrations as numbered files, 0001_initial.up.sql with an optional
+// 0001_initial.down.sql, and its applied version in the schema_version table. Modules are
+// versioned independently, so services can be migrated in any order at startup.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSchemaTooNew means the database was migrated by a newer build than this one
+var ErrSchemaTooNew = errors.New("database schema is newer than this build supports")
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Source is one module's migration files
+type Source struct {
+	Module string
+	FS     fs.FS // directory holding the .sql files
+}
+
+// Migration is one schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string // empty when the migration can't be reverted
+}
+
+// Status reports where a module's schema stands
+type Status struct {
+	Module  string `json:"module"`
+	Current int    `json:"current"`
+	Latest  int    `json:"latest"`
+	Pending []int  `json:"pending"`
+}
+
+// Load reads and orders a source's migrations. Versions must start at 1 without gaps, and
+// every down file needs a matching up file.
+func Load(src Source) ([]Migration, error) {
+	entries, err := fs.ReadDir(src.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s migrations: %w", src.Module, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid %s migration file name %q", src.Module, entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		data, err := fs.ReadFile(src.FS, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("%s migration %d has two names: %s and %s", src.Module, version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("%s migrations must be numbered from 1 without gaps, found %d at position %d", src.Module, m.Version, i+1)
+		}
+		if m.Up == "" {
+			return nil, fmt.Errorf("%s migration %d has no up file", src.Module, m.Version)
+		}
+	}
+	return migrations, nil
+}
+
+// Up applies a module's pending migrations, each in its own transaction. It refuses to run
+// against a schema newer than the migrations it knows, e.g. after a downgrade.
+func Up(ctx context.Context, db *sql.DB, src Source) error {
+	migrations, err := Load(src)
+	if err != nil {
+		return err
+	}
+	current, err := currentVersion(ctx, db, src.Module)
+	if err != nil {
+		return err
+	}
+	if current > len(migrations) {
+		return fmt.Errorf("%w: %s is at version %d, latest known is %d", ErrSchemaTooNew, src.Module, current, len(migrations))
+	}
+
+	for _, m := range migrations[current:] {
+		if err := apply(ctx, db, src.Module, m, m.Up, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Down reverts a module's migrations newer than target, newest first
+func Down(ctx context.Context, db *sql.DB, src Source, target int) error {
+	migrations, err := Load(src)
+	if err != nil {
+		return err
+	}
+	current, err := currentVersion(ctx, db, src.Module)
+	if err != nil {
+		return err
+	}
+	if target < 0 || target > current {
+		return fmt.Errorf("invalid target version %d for %s at version %d", target, src.Module, current)
+	}
+	if current > len(migrations) {
+		return fmt.Errorf("%w: %s is at version %d, latest known is %d", ErrSchemaTooNew, src.Module, current, len(migrations))
+	}
+
+	for version := current; version > target; version-- {
+		m := migrations[version-1]
+		if m.Down == "" {
+			return fmt.Errorf("%s migration %d (%s) cannot be reverted", src.Module, m.Version, m.Name)
+		}
+		if err := apply(ctx, db, src.Module, m, m.Down, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetStatus returns a module's current and latest version and the versions still to apply
+func GetStatus(ctx context.Context, db *sql.DB, src Source) (*Status, error) {
+	migrations, err := Load(src)
+	if err != nil {
+		return nil, err
+	}
+	current, err := currentVersion(ctx, db, src.Module)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{Module: src.Module, Current: current, Latest: len(migrations), Pending: []int{}}
+	for _, m := range migrations {
+		if m.Version > current {
+			status.Pending = append(status.Pending, m.Version)
+		}
+	}
+	return status, nil
+}
+
+func ensureTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_version (
+		module TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (module, version)
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+	return nil
+}
+
+func currentVersion(ctx context.Context, db *sql.DB, module string) (int, error) {
+	if err := ensureTable(ctx, db); err != nil {
+		return 0, err
+	}
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_version WHERE module = ?`, module).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read %s schema version: %w", module, err)
+	}
+	return int(version.Int64), nil
+}
+
+// apply runs one direction of a migration and records the result. Adding a column that
+// already exists is not an error, so the first versioned run succeeds on databases whose
+// tables were created before migrations were tracked.
+func apply(ctx context.Context, db *sql.DB, module string, m Migration, script string, up bool) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, statement := range splitStatements(script) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			return fmt.Errorf("%s migration %d (%s) failed: %w", module, m.Version, m.Name, err)
+		}
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_version (module, version, name, applied_at) VALUES (?, ?, ?, ?)`,
+			module, m.Version, m.Name, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_version WHERE module = ? AND version = ?`, module, m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record %s schema version: %w", module, err)
+	}
+	return tx.Commit()
+}
+
+// splitStatements splits a script on the semicolons ending its lines, dropping comment-only
+// and empty statements. Migrations must not put a semicolon at the end of a line inside a
+// string literal.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); hasSQL(statement) {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+	for _, line := range strings.Split(script, "\n") {
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			flush()
+		}
+	}
+	flush()
+	return statements
+}
+
+func hasSQL(statement string) bool {
+	for _, line := range strings.Split(statement, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSource returns the migrations in dir of fsys, typically an embedded directory. It
+// panics if dir is not a valid path.
+func NewSource(module string, fsys fs.FS, dir string) Source {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(fmt.Sprintf("invalid %s migrations directory %q: %v", module, dir, err))
+	}
+	return Source{Module: module, FS: sub}
+}
@@ -0,0 +1,191 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func testSource() Source {
+	return Source{Module: "test", FS: fstest.MapFS{
+		"0001_initial.up.sql": {Data: []byte(`-- Items
+CREATE TABLE items (
+	id TEXT PRIMARY KEY, -- name; unique
+	name TEXT NOT NULL
+);
+CREATE INDEX idx_items_name ON items(name);
+`)},
+		"0001_initial.down.sql":   {Data: []byte("DROP TABLE items;\n")},
+		"0002_item_size.up.sql":   {Data: []byte("ALTER TABLE items ADD COLUMN size INTEGER;\n")},
+		"0002_item_size.down.sql": {Data: []byte("ALTER TABLE items DROP COLUMN size;\n")},
+	}}
+}
+
+func version(t *testing.T, db *sql.DB, src Source) int {
+	t.Helper()
+	status, err := GetStatus(context.Background(), db, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status.Current
+}
+
+func TestUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	src := testSource()
+
+	status, err := GetStatus(ctx, db, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Current != 0 || status.Latest != 2 || len(status.Pending) != 2 {
+		t.Fatalf("unexpected status before migrating: %+v", status)
+	}
+
+	if err := Up(ctx, db, src); err != nil {
+		t.Fatal(err)
+	}
+	if v := version(t, db, src); v != 2 {
+		t.Fatalf("expected version 2, got %d", v)
+	}
+	if _, err := db.Exec(`INSERT INTO items (id, name, size) VALUES ('a', 'a', 1)`); err != nil {
+		t.Fatalf("expected migrated table: %v", err)
+	}
+
+	// Running again is a no-op
+	if err := Up(ctx, db, src); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Down(ctx, db, src, 1); err != nil {
+		t.Fatal(err)
+	}
+	if v := version(t, db, src); v != 1 {
+		t.Fatalf("expected version 1, got %d", v)
+	}
+	if _, err := db.Exec(`SELECT size FROM items`); err == nil {
+		t.Fatal("expected size column to be dropped")
+	}
+
+	if err := Down(ctx, db, src, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`SELECT id FROM items`); err == nil {
+		t.Fatal("expected items table to be dropped")
+	}
+}
+
+func TestUpAdoptsExistingColumns(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	// Tables created before migrations were tracked already have the added column
+	if _, err := db.Exec(`CREATE TABLE items (id TEXT PRIMARY KEY, name TEXT NOT NULL, size INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	src := testSource()
+	src.FS.(fstest.MapFS)["0001_initial.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE IF NOT EXISTS items (id TEXT PRIMARY KEY, name TEXT NOT NULL);\n")}
+
+	if err := Up(ctx, db, src); err != nil {
+		t.Fatal(err)
+	}
+	if v := version(t, db, src); v != 2 {
+		t.Fatalf("expected version 2, got %d", v)
+	}
+}
+
+func TestUpRefusesNewerSchema(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	src := testSource()
+	if err := Up(ctx, db, src); err != nil {
+		t.Fatal(err)
+	}
+
+	older := Source{Module: "test", FS: fstest.MapFS{
+		"0001_initial.up.sql": src.FS.(fstest.MapFS)["0001_initial.up.sql"],
+	}}
+	if err := Up(ctx, db, older); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	src := testSource()
+	src.FS.(fstest.MapFS)["0002_item_size.up.sql"] = &fstest.MapFile{Data: []byte(
+		"ALTER TABLE items ADD COLUMN size INTEGER;\nALTER TABLE missing ADD COLUMN size INTEGER;\n")}
+
+	if err := Up(ctx, db, src); err == nil {
+		t.Fatal("expected migration 2 to fail")
+	}
+	if v := version(t, db, src); v != 1 {
+		t.Fatalf("expected version 1 after failure, got %d", v)
+	}
+	if _, err := db.Exec(`SELECT size FROM items`); err == nil {
+		t.Fatal("expected the partial migration to be rolled back")
+	}
+}
+
+func TestLoadValidatesFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"gap", fstest.MapFS{
+			"0001_initial.up.sql": {Data: []byte("SELECT 1;")},
+			"0003_later.up.sql":   {Data: []byte("SELECT 1;")},
+		}},
+		{"missing up", fstest.MapFS{
+			"0001_initial.down.sql": {Data: []byte("SELECT 1;")},
+		}},
+		{"bad name", fstest.MapFS{
+			"initial.sql": {Data: []byte("SELECT 1;")},
+		}},
+		{"conflicting names", fstest.MapFS{
+			"0001_initial.up.sql": {Data: []byte("SELECT 1;")},
+			"0001_other.down.sql": {Data: []byte("SELECT 1;")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(Source{Module: "test", FS: tt.files}); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestDownWithoutDownFile(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	src := testSource()
+	delete(src.FS.(fstest.MapFS), "0002_item_size.down.sql")
+
+	if err := Up(ctx, db, src); err != nil {
+		t.Fatal(err)
+	}
+	if err := Down(ctx, db, src, 0); err == nil {
+		t.Fatal("expected an error reverting a migration without a down file")
+	}
+	if v := version(t, db, src); v != 2 {
+		t.Fatalf("expected version 2, got %d", v)
+	}
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS signing_keys (
+	id TEXT PRIMARY KEY,
+	material TEXT NOT NULL, -- key encrypted with the master key
+	created_at DATETIME NOT NULL,
+	retired_at DATETIME
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_tokens (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE, -- sha256 of the token
+	scopes TEXT NOT NULL, -- JSON array
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME,
+	last_used_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id);
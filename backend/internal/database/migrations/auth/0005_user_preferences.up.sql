@@ -0,0 +1,6 @@
+-- UI state of each user, kept server-side so it follows them across browsers
+CREATE TABLE IF NOT EXISTS user_preferences (
+	user_id TEXT PRIMARY KEY,
+	preferences TEXT NOT NULL, -- JSON
+	updated_at DATETIME NOT NULL
+);
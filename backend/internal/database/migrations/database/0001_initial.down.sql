@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS cache;
+DROP TABLE IF EXISTS certificate_domains;
+DROP TABLE IF EXISTS database_connections;
+DROP TABLE IF EXISTS container_registries;
@@ -0,0 +1,58 @@
+-- Container registries for deployment management
+CREATE TABLE IF NOT EXISTS container_registries (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	type TEXT NOT NULL, -- dockerhub, gcr, ecr, generic, gitea
+	config TEXT NOT NULL, -- JSON configuration (url, credentials, etc.)
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Database connections for database browser
+CREATE TABLE IF NOT EXISTS database_connections (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL, -- postgresql, sqlite, mysql, mariadb
+	host TEXT,
+	port INTEGER,
+	database_name TEXT,
+	username TEXT,
+	password_encrypted TEXT, -- encrypted password
+	ssl_enabled BOOLEAN DEFAULT FALSE,
+	ssl_config TEXT, -- JSON SSL configuration
+	connection_timeout INTEGER DEFAULT 30,
+	max_connections INTEGER DEFAULT 10,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_connected DATETIME NULL
+);
+
+-- Certificate domain configurations for monitoring
+CREATE TABLE IF NOT EXISTS certificate_domains (
+	id TEXT PRIMARY KEY,
+	domain TEXT NOT NULL UNIQUE,
+	service TEXT NOT NULL,
+	port INTEGER NOT NULL DEFAULT 443,
+	enabled BOOLEAN NOT NULL DEFAULT TRUE,
+	check_interval INTEGER NOT NULL DEFAULT 60, -- minutes
+	warning_threshold INTEGER NOT NULL DEFAULT 30, -- days before expiration
+	critical_threshold INTEGER NOT NULL DEFAULT 7, -- days before expiration
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Cache table (replaces Redis cache)
+CREATE TABLE IF NOT EXISTS cache (
+	key TEXT PRIMARY KEY,
+	value TEXT NOT NULL, -- JSON as text
+	expires_at DATETIME NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_container_registries_type ON container_registries(type);
+CREATE INDEX IF NOT EXISTS idx_container_registries_name ON container_registries(name);
+CREATE INDEX IF NOT EXISTS idx_database_connections_type ON database_connections(type);
+CREATE INDEX IF NOT EXISTS idx_database_connections_last_connected ON database_connections(last_connected);
+CREATE INDEX IF NOT EXISTS idx_certificate_domains_enabled ON certificate_domains(enabled);
+CREATE INDEX IF NOT EXISTS idx_certificate_domains_check_interval ON certificate_domains(check_interval);
+CREATE INDEX IF NOT EXISTS idx_cache_expires_at ON cache(expires_at);
@@ -0,0 +1,4 @@
+ALTER TABLE database_connections DROP COLUMN error;
+ALTER TABLE database_connections DROP COLUMN last_tested;
+ALTER TABLE database_connections DROP COLUMN status;
+ALTER TABLE database_connections DROP COLUMN config;
@@ -0,0 +1,6 @@
+-- Columns the database browser stores connections in; the config JSON holds host,
+-- credentials and options, encrypted when an encryption key is configured
+ALTER TABLE database_connections ADD COLUMN config TEXT NOT NULL DEFAULT '{}';
+ALTER TABLE database_connections ADD COLUMN status TEXT DEFAULT 'disconnected';
+ALTER TABLE database_connections ADD COLUMN last_tested TIMESTAMP;
+ALTER TABLE database_connections ADD COLUMN error TEXT;
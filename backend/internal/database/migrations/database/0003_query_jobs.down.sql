@@ -0,0 +1,3 @@
+-- saved_queries is kept, as it may predate this migration
+DROP TABLE IF EXISTS query_job_runs;
+DROP TABLE IF EXISTS query_jobs;
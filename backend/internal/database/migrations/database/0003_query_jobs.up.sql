@@ -0,0 +1,41 @@
+-- Saved queries of the database browser, previously only in the legacy schema
+CREATE TABLE IF NOT EXISTS saved_queries (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	sql TEXT NOT NULL,
+	connection_id TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Saved queries run against a connection on a cron schedule
+CREATE TABLE IF NOT EXISTS query_jobs (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	saved_query_id TEXT NOT NULL,
+	connection_id TEXT NOT NULL,
+	cron TEXT NOT NULL,
+	timezone TEXT NOT NULL DEFAULT '',
+	enabled BOOLEAN NOT NULL DEFAULT TRUE,
+	next_run_at DATETIME,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+-- Run history of scheduled queries
+CREATE TABLE IF NOT EXISTS query_job_runs (
+	id TEXT PRIMARY KEY,
+	job_id TEXT NOT NULL,
+	status TEXT NOT NULL, -- running, succeeded, failed
+	scheduled BOOLEAN NOT NULL DEFAULT TRUE,
+	row_count INTEGER NOT NULL DEFAULT 0,
+	duration_ms REAL NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	acknowledged BOOLEAN NOT NULL DEFAULT FALSE,
+	started_at DATETIME NOT NULL,
+	finished_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_queries_connection_id ON saved_queries(connection_id);
+CREATE INDEX IF NOT EXISTS idx_query_jobs_next_run_at ON query_jobs(next_run_at);
+CREATE INDEX IF NOT EXISTS idx_query_job_runs_job_started ON query_job_runs(job_id, started_at);
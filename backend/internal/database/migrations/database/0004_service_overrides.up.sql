@@ -0,0 +1,13 @@
+-- Per-service overrides of annotation-driven infrastructure service discovery
+CREATE TABLE IF NOT EXISTS service_overrides (
+	namespace TEXT NOT NULL,
+	name TEXT NOT NULL,
+	monitor BOOLEAN, -- NULL follows the denshimon.io/monitor annotation
+	display_name TEXT NOT NULL DEFAULT '',
+	type TEXT NOT NULL DEFAULT '',
+	health_path TEXT NOT NULL DEFAULT '',
+	health_port INTEGER NOT NULL DEFAULT 0,
+	url TEXT NOT NULL DEFAULT '',
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (namespace, name)
+);
@@ -1,16 +1,33 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"embed"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/archellir/denshimon/internal/database/migrate"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+//go:embed migrations/database/*.sql migrations/auth/*.sql
+var migrationFiles embed.FS
+
+// Migrations is the schema of the core tables: registries, database connections, scheduled
+// query jobs, certificate domains, service discovery overrides and the cache
+var Migrations = migrate.NewSource("database", migrationFiles, "migrations/database")
+
+// AuthMigrations is the schema of users, sessions, signing keys, API tokens and user preferences
+var AuthMigrations = migrate.NewSource("auth", migrationFiles, "migrations/auth")
+
 type SQLiteDB struct {
 	DB *sql.DB
+
+	hooksMutex   sync.Mutex
+	cleanupHooks []func()
 }
 
 func NewSQLiteDB(dbPath string) (*SQLiteDB, error) {
@@ -47,99 +64,13 @@ func (s *SQLiteDB) Database() *sql.DB {
 	return s.DB
 }
 
-// Initialize database schema
+// InitSchema migrates the core and authentication tables to the latest schema version
 func (s *SQLiteDB) InitSchema() error {
-	schema := `
-	-- Core authentication table
-	CREATE TABLE IF NOT EXISTS users (
-		id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
-		username TEXT UNIQUE NOT NULL,
-		password_hash TEXT NOT NULL,
-		role TEXT NOT NULL DEFAULT 'viewer', -- admin, operator, viewer
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Container registries for deployment management
-	CREATE TABLE IF NOT EXISTS container_registries (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL UNIQUE,
-		type TEXT NOT NULL, -- dockerhub, gcr, ecr, generic, gitea
-		config TEXT NOT NULL, -- JSON configuration (url, credentials, etc.)
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Database connections for database browser
-	CREATE TABLE IF NOT EXISTS database_connections (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		type TEXT NOT NULL, -- postgresql, sqlite, mysql, mariadb
-		host TEXT,
-		port INTEGER,
-		database_name TEXT,
-		username TEXT,
-		password_encrypted TEXT, -- encrypted password
-		ssl_enabled BOOLEAN DEFAULT FALSE,
-		ssl_config TEXT, -- JSON SSL configuration
-		connection_timeout INTEGER DEFAULT 30,
-		max_connections INTEGER DEFAULT 10,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		last_connected DATETIME NULL
-	);
-
-	-- Certificate domain configurations for monitoring
-	CREATE TABLE IF NOT EXISTS certificate_domains (
-		id TEXT PRIMARY KEY,
-		domain TEXT NOT NULL UNIQUE,
-		service TEXT NOT NULL,
-		port INTEGER NOT NULL DEFAULT 443,
-		enabled BOOLEAN NOT NULL DEFAULT TRUE,
-		check_interval INTEGER NOT NULL DEFAULT 60, -- minutes
-		warning_threshold INTEGER NOT NULL DEFAULT 30, -- days before expiration
-		critical_threshold INTEGER NOT NULL DEFAULT 7, -- days before expiration
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Sessions table (replaces Redis sessions)
-	CREATE TABLE IF NOT EXISTS sessions (
-		id TEXT PRIMARY KEY,
-		user_id TEXT NOT NULL,
-		expires_at DATETIME NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Cache table (replaces Redis cache)
-	CREATE TABLE IF NOT EXISTS cache (
-		key TEXT PRIMARY KEY,
-		value TEXT NOT NULL, -- JSON as text
-		expires_at DATETIME NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Create performance indexes
-	CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
-	CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
-	
-	CREATE INDEX IF NOT EXISTS idx_container_registries_type ON container_registries(type);
-	CREATE INDEX IF NOT EXISTS idx_container_registries_name ON container_registries(name);
-	
-	CREATE INDEX IF NOT EXISTS idx_database_connections_type ON database_connections(type);
-	CREATE INDEX IF NOT EXISTS idx_database_connections_last_connected ON database_connections(last_connected);
-	
-	CREATE INDEX IF NOT EXISTS idx_certificate_domains_enabled ON certificate_domains(enabled);
-	CREATE INDEX IF NOT EXISTS idx_certificate_domains_check_interval ON certificate_domains(check_interval);
-	
-	CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
-	CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
-	CREATE INDEX IF NOT EXISTS idx_cache_expires_at ON cache(expires_at);
-	`
-
-	_, err := s.DB.Exec(schema)
-	if err != nil {
-		return err
+	ctx := context.Background()
+	for _, src := range []migrate.Source{Migrations, AuthMigrations} {
+		if err := migrate.Up(ctx, s.DB, src); err != nil {
+			return err
+		}
 	}
 
 	// Clean up expired sessions and cache entries on startup
@@ -175,6 +106,252 @@ func (s *SQLiteDB) DeleteSession(sessionID string) error {
 	return err
 }
 
+// Session is a login session; tokens carry its ID so it can be revoked
+type Session struct {
+	ID         string
+	UserID     string
+	UserAgent  string
+	IPAddress  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+}
+
+func (s *SQLiteDB) CreateUserSession(session *Session) error {
+	_, err := s.DB.Exec(`
+		INSERT INTO sessions (id, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, session.ID, session.UserID, session.UserAgent, session.IPAddress, session.CreatedAt.UTC(), session.LastSeenAt.UTC(), session.ExpiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// GetUserSession returns an unexpired session, or an error wrapping sql.ErrNoRows
+func (s *SQLiteDB) GetUserSession(sessionID string) (*Session, error) {
+	session, err := scanSession(s.DB.QueryRow(`
+		SELECT id, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at
+		FROM sessions WHERE id = ? AND expires_at > ?
+	`, sessionID, time.Now().UTC()))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session not found or expired: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return session, nil
+}
+
+// ListUserSessions returns a user's unexpired sessions, most recently used first
+func (s *SQLiteDB) ListUserSessions(userID string) ([]*Session, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at
+		FROM sessions WHERE user_id = ? AND expires_at > ?
+		ORDER BY last_seen_at DESC
+	`, userID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// TouchUserSession records activity on a session and sets its expiry
+func (s *SQLiteDB) TouchUserSession(sessionID string, lastSeen, expiresAt time.Time) error {
+	_, err := s.DB.Exec(`UPDATE sessions SET last_seen_at = ?, expires_at = ? WHERE id = ?`,
+		lastSeen.UTC(), expiresAt.UTC(), sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+func scanSession(row interface{ Scan(...interface{}) error }) (*Session, error) {
+	var session Session
+	var lastSeen sql.NullTime
+	if err := row.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
+		&session.CreatedAt, &lastSeen, &session.ExpiresAt); err != nil {
+		return nil, err
+	}
+	session.LastSeenAt = session.CreatedAt
+	if lastSeen.Valid {
+		session.LastSeenAt = lastSeen.Time
+	}
+	return &session, nil
+}
+
+// SigningKey is a persisted token signing key; material is encrypted by the auth service
+type SigningKey struct {
+	ID        string
+	Material  string
+	CreatedAt time.Time
+	RetiredAt *time.Time
+}
+
+func (s *SQLiteDB) SaveSigningKey(key *SigningKey) error {
+	var retiredAt interface{}
+	if key.RetiredAt != nil {
+		retiredAt = key.RetiredAt.UTC()
+	}
+	_, err := s.DB.Exec(`
+		INSERT OR REPLACE INTO signing_keys (id, material, created_at, retired_at)
+		VALUES (?, ?, ?, ?)
+	`, key.ID, key.Material, key.CreatedAt.UTC(), retiredAt)
+	if err != nil {
+		return fmt.Errorf("failed to save signing key: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteDB) ListSigningKeys() ([]*SigningKey, error) {
+	rows, err := s.DB.Query(`SELECT id, material, created_at, retired_at FROM signing_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*SigningKey
+	for rows.Next() {
+		var key SigningKey
+		var retiredAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.Material, &key.CreatedAt, &retiredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+		if retiredAt.Valid {
+			key.RetiredAt = &retiredAt.Time
+		}
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *SQLiteDB) DeleteSigningKey(id string) error {
+	if _, err := s.DB.Exec("DELETE FROM signing_keys WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete signing key: %w", err)
+	}
+	return nil
+}
+
+// APIToken is a hashed long-lived API credential
+type APIToken struct {
+	ID         string
+	UserID     string
+	Name       string
+	Prefix     string
+	TokenHash  string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+func (s *SQLiteDB) CreateAPIToken(token *APIToken) error {
+	scopes, err := json.Marshal(token.Scopes)
+	if err != nil {
+		return fmt.Errorf("failed to encode scopes: %w", err)
+	}
+	var expiresAt interface{}
+	if token.ExpiresAt != nil {
+		expiresAt = token.ExpiresAt.UTC()
+	}
+	_, err = s.DB.Exec(`
+		INSERT INTO api_tokens (id, user_id, name, prefix, token_hash, scopes, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, token.ID, token.UserID, token.Name, token.Prefix, token.TokenHash, string(scopes), token.CreatedAt.UTC(), expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create API token: %w", err)
+	}
+	return nil
+}
+
+const apiTokenColumns = `id, user_id, name, prefix, token_hash, scopes, created_at, expires_at, last_used_at`
+
+// GetAPITokenByHash returns the token with the given hash, or an error wrapping sql.ErrNoRows
+func (s *SQLiteDB) GetAPITokenByHash(hash string) (*APIToken, error) {
+	token, err := scanAPIToken(s.DB.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = ?`, hash))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("API token not found: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+	return token, nil
+}
+
+// GetAPIToken returns the token with the given ID, or an error wrapping sql.ErrNoRows
+func (s *SQLiteDB) GetAPIToken(id string) (*APIToken, error) {
+	token, err := scanAPIToken(s.DB.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("API token not found: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+	return token, nil
+}
+
+func (s *SQLiteDB) ListAPITokens(userID string) ([]*APIToken, error) {
+	rows, err := s.DB.Query(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE user_id = ? ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *SQLiteDB) TouchAPIToken(id string, usedAt time.Time) error {
+	if _, err := s.DB.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, usedAt.UTC(), id); err != nil {
+		return fmt.Errorf("failed to update API token: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteDB) DeleteAPIToken(id string) error {
+	if _, err := s.DB.Exec("DELETE FROM api_tokens WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete API token: %w", err)
+	}
+	return nil
+}
+
+func scanAPIToken(row interface{ Scan(...interface{}) error }) (*APIToken, error) {
+	var token APIToken
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+	if err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.Prefix, &token.TokenHash, &scopes,
+		&token.CreatedAt, &expiresAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &token.Scopes); err != nil {
+		return nil, fmt.Errorf("failed to decode scopes: %w", err)
+	}
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return &token, nil
+}
+
 // User management methods
 type User struct {
 	ID           string    `json:"id"`
@@ -261,6 +438,9 @@ func (s *SQLiteDB) DeleteUser(userID string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
+	if _, err := s.DB.Exec("DELETE FROM user_preferences WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete user preferences: %w", err)
+	}
 
 	return nil
 }
@@ -376,6 +556,13 @@ func (s *SQLiteDB) CleanupExpired() error {
 	return nil
 }
 
+// OnCleanup registers a function run by the cleanup worker, e.g. to expire in-memory state
+func (s *SQLiteDB) OnCleanup(hook func()) {
+	s.hooksMutex.Lock()
+	defer s.hooksMutex.Unlock()
+	s.cleanupHooks = append(s.cleanupHooks, hook)
+}
+
 // Start periodic cleanup goroutine
 func (s *SQLiteDB) StartCleanupWorker() {
 	go func() {
@@ -389,6 +576,12 @@ func (s *SQLiteDB) StartCleanupWorker() {
 					// Log error but continue
 					fmt.Printf("Error cleaning up expired entries: %v\n", err)
 				}
+				s.hooksMutex.Lock()
+				hooks := append([]func(){}, s.cleanupHooks...)
+				s.hooksMutex.Unlock()
+				for _, hook := range hooks {
+					hook()
+				}
 			}
 		}
 	}()
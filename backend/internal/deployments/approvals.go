@@ -0,0 +1,323 @@
+package deployments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotPendingApply means the deployment has no change waiting to be applied
+	ErrNotPendingApply = errors.New("deployment is not in pending_apply status")
+	// ErrApprovalRequired means a protected deployment lacks the approvals its rule requires
+	ErrApprovalRequired = errors.New("deployment requires approval")
+	// ErrSelfApproval means the user who requested a protected deployment tried to approve or apply it
+	ErrSelfApproval = errors.New("the requester of a protected deployment cannot approve or apply it")
+	// ErrUnknownRequester means a protected deployment has no recorded requester, so approvals
+	// can't be told apart from self-approval; the change has to be proposed again
+	ErrUnknownRequester = errors.New("the requester of a protected deployment is unknown")
+	// ErrApproverRole means the user lacks the role a rule requires of approvers
+	ErrApproverRole = errors.New("insufficient role to approve deployment")
+	// ErrApprovalRuleNotFound means no approval rule has the given ID
+	ErrApprovalRuleNotFound = errors.New("approval rule not found")
+)
+
+// Approval decisions
+const (
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// ApprovalRule protects the namespaces matching a pattern: deployments to them wait in
+// pending_apply until enough users other than the requester approve them
+type ApprovalRule struct {
+	ID string `json:"id"`
+	// Namespace is a namespace name or glob pattern such as prod-*
+	Namespace         string `json:"namespace"`
+	RequiredApprovals int    `json:"required_approvals"`
+	// ApproverRole is the role approvers need; admins can always approve
+	ApproverRole string    `json:"approver_role"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Validate checks the pattern and approval count, defaulting the approver role to admin
+func (r *ApprovalRule) Validate() error {
+	if r.Namespace == "" {
+		return fmt.Errorf("namespace is required")
+	}
+	if _, err := path.Match(r.Namespace, ""); err != nil {
+		return fmt.Errorf("invalid namespace pattern %q", r.Namespace)
+	}
+	if r.RequiredApprovals < 1 {
+		return fmt.Errorf("required_approvals must be at least 1")
+	}
+	if r.ApproverRole == "" {
+		r.ApproverRole = "admin"
+	}
+	return nil
+}
+
+// Matches reports whether the rule protects a namespace
+func (r ApprovalRule) Matches(namespace string) bool {
+	matched, _ := path.Match(r.Namespace, namespace)
+	return matched
+}
+
+// Approval is one user's decision on a pending deployment
+type Approval struct {
+	User      string    `json:"user"`
+	Decision  string    `json:"decision"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ApprovalStatus is where a pending deployment stands against its namespace's rule
+type ApprovalStatus struct {
+	DeploymentID string `json:"deployment_id"`
+	// Protected is false when no rule matches the namespace; the deployment can be applied by anyone
+	Protected bool          `json:"protected"`
+	Rule      *ApprovalRule `json:"rule,omitempty"`
+	// RequestedBy put the current change up for apply, and can neither approve nor apply it
+	RequestedBy string     `json:"requested_by,omitempty"`
+	Approvals   []Approval `json:"approvals"`
+	Approved    bool       `json:"approved"`
+}
+
+// ListApprovalRules returns every approval rule
+func (s *Service) ListApprovalRules(ctx context.Context) ([]ApprovalRule, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, namespace, required_approvals, approver_role, COALESCE(created_by, ''), created_at
+		FROM deployment_approval_rules ORDER BY namespace`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approval rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []ApprovalRule{}
+	for rows.Next() {
+		var rule ApprovalRule
+		if err := rows.Scan(&rule.ID, &rule.Namespace, &rule.RequiredApprovals, &rule.ApproverRole, &rule.CreatedBy, &rule.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan approval rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// SaveApprovalRule creates a rule, or replaces the rule for the same namespace pattern
+func (s *Service) SaveApprovalRule(ctx context.Context, rule ApprovalRule) (*ApprovalRule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	rule.ID = uuid.New().String()
+	rule.CreatedAt = time.Now()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO deployment_approval_rules (id, namespace, required_approvals, approver_role, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(namespace) DO UPDATE SET required_approvals = excluded.required_approvals,
+			approver_role = excluded.approver_role, created_by = excluded.created_by, created_at = excluded.created_at`,
+		rule.ID, rule.Namespace, rule.RequiredApprovals, rule.ApproverRole, rule.CreatedBy, rule.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save approval rule: %w", err)
+	}
+	if err := s.db.QueryRowContext(ctx, `SELECT id FROM deployment_approval_rules WHERE namespace = ?`, rule.Namespace).Scan(&rule.ID); err != nil {
+		return nil, fmt.Errorf("failed to read approval rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// DeleteApprovalRule removes a rule; deployments it protected can then be applied without approval
+func (s *Service) DeleteApprovalRule(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM deployment_approval_rules WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete approval rule: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrApprovalRuleNotFound
+	}
+	return nil
+}
+
+// approvalRule returns the rule protecting a namespace, or nil. When several patterns match,
+// the one requiring the most approvals wins.
+func (s *Service) approvalRule(ctx context.Context, namespace string) (*ApprovalRule, error) {
+	rules, err := s.ListApprovalRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var match *ApprovalRule
+	for i := range rules {
+		if rules[i].Matches(namespace) && (match == nil || rules[i].RequiredApprovals > match.RequiredApprovals) {
+			match = &rules[i]
+		}
+	}
+	return match, nil
+}
+
+// requestApproval records who put a deployment into pending_apply and discards the decisions
+// made on the change it replaces
+func (s *Service) requestApproval(ctx context.Context, deploymentID, requestedBy string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM deployment_approvals WHERE deployment_id = ?`, deploymentID); err != nil {
+		return fmt.Errorf("failed to clear approvals: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO deployment_approval_requests (deployment_id, requested_by, requested_at)
+		VALUES (?, ?, ?)`, deploymentID, requestedBy, time.Now()); err != nil {
+		return fmt.Errorf("failed to record approval request: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetApprovalStatus returns the rule, requester and decisions of a deployment's pending change
+func (s *Service) GetApprovalStatus(ctx context.Context, deploymentID string) (*ApprovalStatus, error) {
+	deployment, err := s.getDeploymentFromDB(deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+	return s.approvalStatus(ctx, deployment)
+}
+
+func (s *Service) approvalStatus(ctx context.Context, deployment *Deployment) (*ApprovalStatus, error) {
+	rule, err := s.approvalRule(ctx, deployment.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ApprovalStatus{
+		DeploymentID: deployment.ID,
+		Protected:    rule != nil,
+		Rule:         rule,
+		Approvals:    []Approval{},
+	}
+	var requestedBy sql.NullString
+	err = s.db.QueryRowContext(ctx, `SELECT requested_by FROM deployment_approval_requests WHERE deployment_id = ?`, deployment.ID).Scan(&requestedBy)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get approval request: %w", err)
+	}
+	status.RequestedBy = requestedBy.String
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT user, decision, COALESCE(comment, ''), created_at
+		FROM deployment_approvals WHERE deployment_id = ? ORDER BY created_at`, deployment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approvals: %w", err)
+	}
+	defer rows.Close()
+
+	approved := 0
+	for rows.Next() {
+		var approval Approval
+		if err := rows.Scan(&approval.User, &approval.Decision, &approval.Comment, &approval.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan approval: %w", err)
+		}
+		if approval.Decision == ApprovalApproved {
+			approved++
+		}
+		status.Approvals = append(status.Approvals, approval)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status.Approved = rule == nil || approved >= rule.RequiredApprovals
+	return status, nil
+}
+
+// ApproveDeployment records a user's approval of a pending deployment
+func (s *Service) ApproveDeployment(ctx context.Context, deploymentID, user, role, comment string) (*ApprovalStatus, error) {
+	return s.decide(ctx, deploymentID, user, role, ApprovalApproved, comment)
+}
+
+// RejectDeployment records a user's rejection and takes the deployment out of pending_apply.
+// It can be applied again only after a new change is proposed.
+func (s *Service) RejectDeployment(ctx context.Context, deploymentID, user, role, reason string) (*ApprovalStatus, error) {
+	return s.decide(ctx, deploymentID, user, role, ApprovalRejected, reason)
+}
+
+func (s *Service) decide(ctx context.Context, deploymentID, user, role, decision, comment string) (*ApprovalStatus, error) {
+	deployment, err := s.getDeploymentFromDB(deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+	if deployment.Status != DeploymentStatusPendingApply {
+		return nil, fmt.Errorf("%w: %s", ErrNotPendingApply, deployment.Status)
+	}
+
+	status, err := s.approvalStatus(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+	// Requesters may withdraw their own change, but never approve it
+	if decision == ApprovalApproved && status.Protected {
+		if status.RequestedBy == "" {
+			return nil, ErrUnknownRequester
+		}
+		if status.RequestedBy == user {
+			return nil, ErrSelfApproval
+		}
+	}
+	if status.Rule != nil && role != status.Rule.ApproverRole && role != "admin" {
+		return nil, ErrApproverRole
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO deployment_approvals (deployment_id, user, decision, comment, created_at)
+		VALUES (?, ?, ?, ?, ?)`, deployment.ID, user, decision, comment, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to record %s: %w", decision, err)
+	}
+
+	action := "approve"
+	if decision == ApprovalRejected {
+		action = "reject"
+		deployment.Status = DeploymentStatusRejected
+		deployment.UpdatedAt = time.Now()
+		if err := s.updateDeploymentInDB(deployment); err != nil {
+			return nil, fmt.Errorf("failed to update status: %w", err)
+		}
+	}
+	s.recordHistoryWithMetadata(deployment.ID, action, deployment.Image, deployment.Image,
+		deployment.Replicas, deployment.Replicas, true, "", user, map[string]interface{}{
+			"comment": comment,
+		})
+
+	return s.approvalStatus(ctx, deployment)
+}
+
+// checkApproval enforces the rule protecting a deployment's namespace before it is applied
+func (s *Service) checkApproval(ctx context.Context, deployment *Deployment, appliedBy string) error {
+	status, err := s.approvalStatus(ctx, deployment)
+	if err != nil {
+		return err
+	}
+	if !status.Protected {
+		return nil
+	}
+	if status.RequestedBy == "" {
+		return ErrUnknownRequester
+	}
+	if status.RequestedBy == appliedBy {
+		return ErrSelfApproval
+	}
+	if !status.Approved {
+		approved := 0
+		for _, approval := range status.Approvals {
+			if approval.Decision == ApprovalApproved {
+				approved++
+			}
+		}
+		return fmt.Errorf("%w: %d of %d approvals", ErrApprovalRequired, approved, status.Rule.RequiredApprovals)
+	}
+	return nil
+}
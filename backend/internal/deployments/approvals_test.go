@@ -0,0 +1,210 @@
+package deployments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newApprovalService(t *testing.T) *Service {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "deployments.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s := &Service{db: db}
+	if err := s.initDB(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// pendingDeployment stores a deployment waiting in pending_apply, requested by requestedBy
+func pendingDeployment(t *testing.T, s *Service, namespace, requestedBy string) *Deployment {
+	t.Helper()
+	now := time.Now()
+	deployment := &Deployment{
+		ID:        "dep-" + namespace,
+		Name:      "api",
+		Namespace: namespace,
+		Image:     "registry.example.com/api:1.2.0",
+		Replicas:  2,
+		Status:    DeploymentStatusPendingApply,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.storeDeployment(deployment); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.requestApproval(context.Background(), deployment.ID, requestedBy); err != nil {
+		t.Fatal(err)
+	}
+	return deployment
+}
+
+func TestApprovalRuleMatching(t *testing.T) {
+	s := newApprovalService(t)
+	ctx := context.Background()
+
+	for _, rule := range []ApprovalRule{
+		{Namespace: "", RequiredApprovals: 1},
+		{Namespace: "prod-[", RequiredApprovals: 1},
+		{Namespace: "prod-*", RequiredApprovals: 0},
+	} {
+		if err := rule.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", rule)
+		}
+	}
+
+	if _, err := s.SaveApprovalRule(ctx, ApprovalRule{Namespace: "prod-*", RequiredApprovals: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SaveApprovalRule(ctx, ApprovalRule{Namespace: "prod-eu", RequiredApprovals: 2, ApproverRole: "operator"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		namespace string
+		approvals int // 0 when unprotected
+	}{
+		{"prod-us", 1},
+		{"prod-eu", 2}, // the strictest matching rule wins
+		{"staging", 0},
+		{"production", 0},
+	}
+	for _, tt := range tests {
+		rule, err := s.approvalRule(ctx, tt.namespace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := 0
+		if rule != nil {
+			got = rule.RequiredApprovals
+		}
+		if got != tt.approvals {
+			t.Errorf("approvalRule(%s) requires %d approvals, want %d", tt.namespace, got, tt.approvals)
+		}
+	}
+
+	rules, _ := s.ListApprovalRules(ctx)
+	if len(rules) != 2 || rules[0].ApproverRole != "admin" || rules[1].ApproverRole != "operator" {
+		t.Errorf("rules = %+v, want the approver role defaulted to admin", rules)
+	}
+}
+
+func TestApprovalWorkflow(t *testing.T) {
+	s := newApprovalService(t)
+	ctx := context.Background()
+	if _, err := s.SaveApprovalRule(ctx, ApprovalRule{Namespace: "prod-*", RequiredApprovals: 2, ApproverRole: "operator"}); err != nil {
+		t.Fatal(err)
+	}
+	deployment := pendingDeployment(t, s, "prod-eu", "alice")
+
+	// Nothing is applied before the approvals are in
+	if err := s.ApplyDeployment(ctx, deployment.ID, "bob"); !errors.Is(err, ErrApprovalRequired) {
+		t.Errorf("apply without approvals: expected ErrApprovalRequired, got %v", err)
+	}
+	if _, err := s.ApproveDeployment(ctx, deployment.ID, "alice", "admin", ""); !errors.Is(err, ErrSelfApproval) {
+		t.Errorf("self-approval: expected ErrSelfApproval, got %v", err)
+	}
+	if _, err := s.ApproveDeployment(ctx, deployment.ID, "carol", "viewer", ""); !errors.Is(err, ErrApproverRole) {
+		t.Errorf("viewer approval: expected ErrApproverRole, got %v", err)
+	}
+
+	status, err := s.ApproveDeployment(ctx, deployment.ID, "bob", "operator", "looks good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Approved || status.RequestedBy != "alice" || len(status.Approvals) != 1 {
+		t.Errorf("status after one approval = %+v", status)
+	}
+	if err := s.ApplyDeployment(ctx, deployment.ID, "bob"); !errors.Is(err, ErrApprovalRequired) {
+		t.Errorf("apply with one approval: expected ErrApprovalRequired, got %v", err)
+	}
+
+	// Admins approve whatever the rule's role
+	if status, err = s.ApproveDeployment(ctx, deployment.ID, "dave", "admin", ""); err != nil {
+		t.Fatal(err)
+	}
+	if !status.Approved {
+		t.Errorf("status after two approvals = %+v", status)
+	}
+	if err := s.ApplyDeployment(ctx, deployment.ID, "alice"); !errors.Is(err, ErrSelfApproval) {
+		t.Errorf("apply by the requester: expected ErrSelfApproval, got %v", err)
+	}
+	if err := s.checkApproval(ctx, deployment, "bob"); err != nil {
+		t.Errorf("apply by an approver: %v", err)
+	}
+
+	// A new proposal discards the approvals of the change it replaces
+	if err := s.requestApproval(ctx, deployment.ID, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.checkApproval(ctx, deployment, "alice"); !errors.Is(err, ErrApprovalRequired) {
+		t.Errorf("apply after a new proposal: expected ErrApprovalRequired, got %v", err)
+	}
+}
+
+func TestRejectDeployment(t *testing.T) {
+	s := newApprovalService(t)
+	ctx := context.Background()
+	if _, err := s.SaveApprovalRule(ctx, ApprovalRule{Namespace: "prod", RequiredApprovals: 1}); err != nil {
+		t.Fatal(err)
+	}
+	deployment := pendingDeployment(t, s, "prod", "alice")
+
+	if _, err := s.RejectDeployment(ctx, deployment.ID, "carol", "viewer", "no"); !errors.Is(err, ErrApproverRole) {
+		t.Errorf("viewer rejection: expected ErrApproverRole, got %v", err)
+	}
+	status, err := s.RejectDeployment(ctx, deployment.ID, "bob", "admin", "wrong image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Approved || len(status.Approvals) != 1 || status.Approvals[0].Decision != ApprovalRejected {
+		t.Errorf("status = %+v", status)
+	}
+	stored, err := s.getDeploymentFromDB(deployment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != DeploymentStatusRejected {
+		t.Errorf("status = %s, want rejected", stored.Status)
+	}
+
+	// Rejected changes can neither be approved nor applied
+	if _, err := s.ApproveDeployment(ctx, deployment.ID, "dave", "admin", ""); !errors.Is(err, ErrNotPendingApply) {
+		t.Errorf("approving a rejected change: expected ErrNotPendingApply, got %v", err)
+	}
+	if err := s.ApplyDeployment(ctx, deployment.ID, "dave"); !errors.Is(err, ErrNotPendingApply) {
+		t.Errorf("applying a rejected change: expected ErrNotPendingApply, got %v", err)
+	}
+}
+
+func TestUnknownRequester(t *testing.T) {
+	s := newApprovalService(t)
+	ctx := context.Background()
+	if _, err := s.SaveApprovalRule(ctx, ApprovalRule{Namespace: "prod", RequiredApprovals: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a requester anyone could approve their own change
+	deployment := pendingDeployment(t, s, "prod", "")
+	if _, err := s.ApproveDeployment(ctx, deployment.ID, "alice", "admin", ""); !errors.Is(err, ErrUnknownRequester) {
+		t.Errorf("approval: expected ErrUnknownRequester, got %v", err)
+	}
+	if err := s.ApplyDeployment(ctx, deployment.ID, "alice"); !errors.Is(err, ErrUnknownRequester) {
+		t.Errorf("apply: expected ErrUnknownRequester, got %v", err)
+	}
+
+	// Unprotected namespaces don't need one
+	unprotected := pendingDeployment(t, s, "staging", "")
+	if err := s.checkApproval(ctx, unprotected, "alice"); err != nil {
+		t.Errorf("unprotected apply: %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package deployments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrHistoryEntryNotFound means a history entry is not among a deployment's recent history
+var ErrHistoryEntryNotFound = errors.New("history entry not found")
+
+// CompareTarget selects one side of a comparison: a deployment record, or the state of
+// the deployment after one of its history entries
+type CompareTarget struct {
+	DeploymentID string
+	HistoryID    string
+}
+
+// ComparedState is the state of one side of a comparison. History entries only record
+// the image and replicas, so their environment and resources are left out.
+type ComparedState struct {
+	DeploymentID string                `json:"deployment_id"`
+	HistoryID    string                `json:"history_id,omitempty"`
+	Name         string                `json:"name"`
+	Namespace    string                `json:"namespace"`
+	Image        string                `json:"image"`
+	Replicas     int32                 `json:"replicas"`
+	Environment  map[string]string     `json:"environment,omitempty"`
+	Resources    *ResourceRequirements `json:"resources,omitempty"`
+	Timestamp    time.Time             `json:"timestamp"` // last update, or when the history entry was recorded
+}
+
+// ValueChange is a value that differs between the two sides of a comparison
+type ValueChange struct {
+	Field string `json:"field"` // image, replicas, the env var name, or a resource such as limits.cpu
+	Op    string `json:"op"`    // add, remove, change
+	Left  string `json:"left,omitempty"`
+	Right string `json:"right,omitempty"`
+}
+
+// DeploymentComparison lists what differs between two deployments or history entries
+type DeploymentComparison struct {
+	Left        ComparedState `json:"left"`
+	Right       ComparedState `json:"right"`
+	Image       []ValueChange `json:"image"`
+	Replicas    []ValueChange `json:"replicas"`
+	Environment []ValueChange `json:"environment"`
+	Resources   []ValueChange `json:"resources"`
+	// NotCompared names the sections a history entry has no record of
+	NotCompared []string `json:"not_compared,omitempty"`
+	Identical   bool     `json:"identical"`
+}
+
+// CompareDeployments diffs the images, environment variables, resources and replica
+// counts of two deployments or history entries, e.g. staging against production
+func (s *Service) CompareDeployments(ctx context.Context, left, right CompareTarget) (*DeploymentComparison, error) {
+	leftState, err := s.comparedState(ctx, left)
+	if err != nil {
+		return nil, err
+	}
+	rightState, err := s.comparedState(ctx, right)
+	if err != nil {
+		return nil, err
+	}
+	return compareStates(*leftState, *rightState), nil
+}
+
+// comparedState reads a deployment record, or rebuilds its state after a history entry
+func (s *Service) comparedState(ctx context.Context, target CompareTarget) (*ComparedState, error) {
+	deployment, err := s.getDeploymentFromDB(target.DeploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment %s: %w", target.DeploymentID, err)
+	}
+	state := &ComparedState{
+		DeploymentID: deployment.ID,
+		Name:         deployment.Name,
+		Namespace:    deployment.Namespace,
+	}
+	if target.HistoryID == "" {
+		resources := deployment.Resources
+		state.Image = deployment.Image
+		state.Replicas = deployment.Replicas
+		state.Environment = deployment.Environment
+		if state.Environment == nil {
+			state.Environment = map[string]string{}
+		}
+		state.Resources = &resources
+		state.Timestamp = deployment.UpdatedAt
+		return state, nil
+	}
+
+	// History is newest first; entries that don't change the image (scale, restart)
+	// keep the image of the entry before them
+	history, err := s.GetDeploymentHistory(ctx, deployment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment history: %w", err)
+	}
+	for i, entry := range history {
+		if entry.ID != target.HistoryID {
+			continue
+		}
+		state.HistoryID = entry.ID
+		state.Replicas = entry.NewReplicas
+		state.Timestamp = entry.Timestamp
+		for _, earlier := range history[i:] {
+			if earlier.NewImage != "" {
+				state.Image = earlier.NewImage
+				break
+			}
+			if earlier.OldImage != "" {
+				state.Image = earlier.OldImage
+				break
+			}
+		}
+		return state, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrHistoryEntryNotFound, target.HistoryID)
+}
+
+// compareStates lists the differing values of two states. Environment and resources are
+// only compared when both sides have them.
+func compareStates(left, right ComparedState) *DeploymentComparison {
+	comparison := &DeploymentComparison{
+		Left:  left,
+		Right: right,
+		Image: compareMaps(
+			map[string]string{"image": left.Image},
+			map[string]string{"image": right.Image},
+		),
+		Replicas: compareMaps(
+			map[string]string{"replicas": strconv.Itoa(int(left.Replicas))},
+			map[string]string{"replicas": strconv.Itoa(int(right.Replicas))},
+		),
+		Environment: []ValueChange{},
+		Resources:   []ValueChange{},
+	}
+
+	if left.HistoryID == "" && right.HistoryID == "" {
+		comparison.Environment = compareMaps(left.Environment, right.Environment)
+		comparison.Resources = compareMaps(resourceValues(left.Resources), resourceValues(right.Resources))
+	} else {
+		comparison.NotCompared = []string{"environment", "resources"}
+	}
+
+	comparison.Identical = len(comparison.Image) == 0 && len(comparison.Replicas) == 0 &&
+		len(comparison.Environment) == 0 && len(comparison.Resources) == 0
+	return comparison
+}
+
+func compareMaps(left, right map[string]string) []ValueChange {
+	changes := []ValueChange{}
+	for _, key := range unionKeys(left, right) {
+		if change, ok := compareValues("", key, left, right, key); ok {
+			changes = append(changes, ValueChange{Field: key, Op: change.Op, Left: change.Old, Right: change.New})
+		}
+	}
+	return changes
+}
+
+// resourceValues flattens resource requirements to keys like requests.cpu, leaving out unset values
+func resourceValues(resources *ResourceRequirements) map[string]string {
+	values := make(map[string]string)
+	if resources == nil {
+		return values
+	}
+	for key, value := range map[string]string{
+		"requests.cpu":    resources.Requests.CPU,
+		"requests.memory": resources.Requests.Memory,
+		"limits.cpu":      resources.Limits.CPU,
+		"limits.memory":   resources.Limits.Memory,
+	} {
+		if value != "" {
+			values[key] = value
+		}
+	}
+	return values
+}
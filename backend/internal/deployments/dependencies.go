@@ -0,0 +1,295 @@
+package deployments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrDependencyCycle means dependencies would make deployments wait on each other
+	ErrDependencyCycle = errors.New("deployment dependencies form a cycle")
+	// ErrInvalidDependency means a dependency names the deployment itself or an unknown deployment
+	ErrInvalidDependency = errors.New("invalid deployment dependency")
+)
+
+// Batch apply statuses of a deployment
+const (
+	BatchApplied = "applied"
+	BatchFailed  = "failed"
+	// BatchSkipped deployments were not applied because a dependency failed or is missing
+	BatchSkipped = "skipped"
+)
+
+// Dependencies lists the deployments one must be applied after
+type Dependencies struct {
+	DeploymentID string   `json:"deployment_id"`
+	DependsOn    []string `json:"depends_on"`
+}
+
+// BatchResult is the outcome of one deployment in a batch apply
+type BatchResult struct {
+	DeploymentID string   `json:"deployment_id"`
+	Name         string   `json:"name,omitempty"`
+	Namespace    string   `json:"namespace,omitempty"`
+	DependsOn    []string `json:"depends_on,omitempty"`
+	Status       string   `json:"status"` // applied, failed, skipped
+	Error        string   `json:"error,omitempty"`
+}
+
+// GetDependencies returns the deployments a deployment depends on
+func (s *Service) GetDependencies(ctx context.Context, id string) (*Dependencies, error) {
+	if _, err := s.getDeploymentFromDB(id); err != nil {
+		return nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+	graph, err := s.dependencyGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deps := graph[id]
+	if deps == nil {
+		deps = []string{}
+	}
+	return &Dependencies{DeploymentID: id, DependsOn: deps}, nil
+}
+
+// SetDependencies replaces the deployments a deployment depends on. Dependencies must be
+// other existing deployments and may not form a cycle.
+func (s *Service) SetDependencies(ctx context.Context, id string, dependsOn []string) (*Dependencies, error) {
+	if _, err := s.getDeploymentFromDB(id); err != nil {
+		return nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	deps := []string{}
+	for _, dep := range dependsOn {
+		if seen[dep] {
+			continue
+		}
+		seen[dep] = true
+		if dep == id {
+			return nil, fmt.Errorf("%w: a deployment cannot depend on itself", ErrInvalidDependency)
+		}
+		if _, err := s.getDeploymentFromDB(dep); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("%w: unknown deployment %s", ErrInvalidDependency, dep)
+			}
+			return nil, fmt.Errorf("failed to get deployment: %w", err)
+		}
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+
+	graph, err := s.dependencyGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+	graph[id] = deps
+	if cycle := findCycle(graph); cycle != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, " -> "))
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM deployment_dependencies WHERE deployment_id = ?`, id); err != nil {
+		return nil, fmt.Errorf("failed to save dependencies: %w", err)
+	}
+	for _, dep := range deps {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO deployment_dependencies (deployment_id, depends_on) VALUES (?, ?)`, id, dep); err != nil {
+			return nil, fmt.Errorf("failed to save dependencies: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to save dependencies: %w", err)
+	}
+	return &Dependencies{DeploymentID: id, DependsOn: deps}, nil
+}
+
+// dependencyGraph maps each deployment to the deployments it depends on
+func (s *Service) dependencyGraph(ctx context.Context) (map[string][]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT deployment_id, depends_on FROM deployment_dependencies ORDER BY deployment_id, depends_on`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	graph := make(map[string][]string)
+	for rows.Next() {
+		var id, dep string
+		if err := rows.Scan(&id, &dep); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency: %w", err)
+		}
+		graph[id] = append(graph[id], dep)
+	}
+	return graph, rows.Err()
+}
+
+// BatchApplyDeployments applies deployments in dependency order: a deployment is applied
+// only after the deployments it depends on in the batch were applied. When one fails, the
+// deployments depending on it are skipped and independent ones still go ahead. A dependency
+// outside the batch must have been applied before. Results are in the order applied.
+func (s *Service) BatchApplyDeployments(ctx context.Context, deploymentIDs []string, appliedBy string) ([]BatchResult, error) {
+	graph, err := s.dependencyGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+	order, err := applyOrder(deploymentIDs, graph)
+	if err != nil {
+		return nil, err
+	}
+
+	inBatch := make(map[string]bool, len(order))
+	for _, id := range order {
+		inBatch[id] = true
+	}
+	outcome := make(map[string]string, len(order))
+	results := make([]BatchResult, 0, len(order))
+
+	for _, id := range order {
+		result := BatchResult{DeploymentID: id, DependsOn: graph[id]}
+		if deployment, err := s.getDeploymentFromDB(id); err == nil {
+			result.Name, result.Namespace = deployment.Name, deployment.Namespace
+		}
+
+		if reason := s.blockedBy(id, graph[id], inBatch, outcome); reason != "" {
+			result.Status, result.Error = BatchSkipped, reason
+		} else if err := s.ApplyDeployment(ctx, id, appliedBy); err != nil {
+			result.Status, result.Error = BatchFailed, err.Error()
+		} else {
+			result.Status = BatchApplied
+		}
+		outcome[id] = result.Status
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// blockedBy explains why a deployment can't be applied yet, or returns ""
+func (s *Service) blockedBy(id string, dependsOn []string, inBatch map[string]bool, outcome map[string]string) string {
+	for _, dep := range dependsOn {
+		if inBatch[dep] {
+			switch outcome[dep] {
+			case BatchFailed:
+				return fmt.Sprintf("dependency %s failed to apply", dep)
+			case BatchSkipped:
+				return fmt.Sprintf("dependency %s was skipped", dep)
+			}
+			continue
+		}
+		deployment, err := s.getDeploymentFromDB(dep)
+		if err != nil {
+			return fmt.Sprintf("dependency %s not found", dep)
+		}
+		if deployment.AppliedAt == nil {
+			return fmt.Sprintf("dependency %s has not been applied and is not in this batch", dep)
+		}
+	}
+	return ""
+}
+
+// applyOrder sorts deployments so each comes after its dependencies in the batch. Among
+// deployments that are ready together, the requested order is kept.
+func applyOrder(ids []string, graph map[string][]string) ([]string, error) {
+	position := make(map[string]int, len(ids))
+	var unique []string
+	for _, id := range ids {
+		if _, ok := position[id]; !ok {
+			position[id] = len(unique)
+			unique = append(unique, id)
+		}
+	}
+
+	// Count each deployment's unapplied dependencies within the batch
+	waiting := make(map[string]int, len(unique))
+	dependents := make(map[string][]string)
+	for _, id := range unique {
+		for _, dep := range graph[id] {
+			if _, ok := position[dep]; ok {
+				waiting[id]++
+				dependents[dep] = append(dependents[dep], id)
+			}
+		}
+	}
+
+	var ready, order []string
+	for _, id := range unique {
+		if waiting[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool { return position[ready[i]] < position[ready[j]] })
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+		for _, dependent := range dependents[id] {
+			if waiting[dependent]--; waiting[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(order) < len(unique) {
+		batch := make(map[string][]string)
+		for _, id := range unique {
+			batch[id] = graph[id]
+		}
+		return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(findCycle(batch), " -> "))
+	}
+	return order, nil
+}
+
+// findCycle returns a dependency cycle as a path that starts and ends with the same
+// deployment, or nil when the graph is acyclic
+func findCycle(graph map[string][]string) []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var stack []string
+
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, dep := range graph[id] {
+			switch state[dep] {
+			case visiting:
+				for i, onStack := range stack {
+					if onStack == dep {
+						return append(append([]string{}, stack[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+		return nil
+	}
+
+	ids := make([]string, 0, len(graph))
+	for id := range graph {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if state[id] == unvisited {
+			if cycle := visit(id); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
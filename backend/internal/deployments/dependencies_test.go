@@ -0,0 +1,172 @@
+package deployments
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     []string
+		graph   map[string][]string
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "requested order without dependencies",
+			ids:  []string{"c", "a", "b"},
+			want: []string{"c", "a", "b"},
+		},
+		{
+			name:  "diamond",
+			ids:   []string{"app", "api", "worker", "db"},
+			graph: map[string][]string{"app": {"api", "worker"}, "api": {"db"}, "worker": {"db"}},
+			want:  []string{"db", "api", "worker", "app"},
+		},
+		{
+			name:  "dependencies outside the batch don't hold it up",
+			ids:   []string{"api", "web"},
+			graph: map[string][]string{"api": {"db"}, "web": {"api"}},
+			want:  []string{"api", "web"},
+		},
+		{
+			name: "duplicates are applied once",
+			ids:  []string{"a", "b", "a"},
+			want: []string{"a", "b"},
+		},
+		{
+			name:    "cycle",
+			ids:     []string{"a", "b", "c"},
+			graph:   map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}},
+			wantErr: ErrDependencyCycle,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyOrder(tt.ids, tt.graph)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("applyOrder() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph map[string][]string
+		want  []string
+	}{
+		{"empty", nil, nil},
+		{"diamond", map[string][]string{"app": {"api", "worker"}, "api": {"db"}, "worker": {"db"}}, nil},
+		{"self", map[string][]string{"a": {"a"}}, []string{"a", "a"}},
+		{"loop", map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}}, []string{"a", "b", "c", "a"}},
+		{"loop behind a tail", map[string][]string{"app": {"api"}, "api": {"db"}, "db": {"api"}}, []string{"api", "db", "api"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findCycle(tt.graph); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findCycle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockedBy(t *testing.T) {
+	s := newApprovalService(t)
+	now := time.Now()
+	for _, id := range []string{"db", "cache"} {
+		deployment := &Deployment{ID: id, Name: id, Namespace: "default", Image: id + ":1.0.0", Replicas: 1,
+			Status: DeploymentStatusRunning, CreatedAt: now, UpdatedAt: now}
+		if id == "db" {
+			deployment.AppliedAt = &now
+		}
+		if err := s.storeDeployment(deployment); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		graph map[string][]string
+		batch []string
+		fails map[string]bool // deployments whose apply fails
+		want  map[string]string
+	}{
+		{
+			name:  "diamond applies in order",
+			graph: map[string][]string{"app": {"api", "worker"}, "api": {"db"}, "worker": {"db"}},
+			batch: []string{"app", "api", "worker"},
+			want:  map[string]string{"api": BatchApplied, "worker": BatchApplied, "app": BatchApplied},
+		},
+		{
+			name:  "dependency outside the batch never applied",
+			graph: map[string][]string{"api": {"cache"}},
+			batch: []string{"api", "web"},
+			want:  map[string]string{"api": "dependency cache has not been applied", "web": BatchApplied},
+		},
+		{
+			name:  "unknown dependency outside the batch",
+			graph: map[string][]string{"api": {"queue"}},
+			batch: []string{"api"},
+			want:  map[string]string{"api": "dependency queue not found"},
+		},
+		{
+			name:  "failed dependency skips its dependents only",
+			graph: map[string][]string{"app": {"api"}, "api": {"migrations"}, "web": {"db"}},
+			batch: []string{"app", "api", "migrations", "web"},
+			fails: map[string]bool{"migrations": true},
+			want: map[string]string{
+				"migrations": BatchFailed,
+				"api":        "dependency migrations failed to apply",
+				"app":        "dependency api was skipped",
+				"web":        BatchApplied,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := applyOrder(tt.batch, tt.graph)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inBatch := make(map[string]bool)
+			for _, id := range order {
+				inBatch[id] = true
+			}
+
+			// Walk the batch the way BatchApplyDeployments does, failing instead of applying
+			outcome := make(map[string]string)
+			for _, id := range order {
+				reason := s.blockedBy(id, tt.graph[id], inBatch, outcome)
+				switch {
+				case reason != "":
+					outcome[id] = BatchSkipped
+				case tt.fails[id]:
+					outcome[id] = BatchFailed
+				default:
+					outcome[id] = BatchApplied
+				}
+
+				want := tt.want[id]
+				switch want {
+				case BatchApplied, BatchFailed:
+					if outcome[id] != want {
+						t.Errorf("%s: got %s (%s), want %s", id, outcome[id], reason, want)
+					}
+				default:
+					if !strings.HasPrefix(reason, want) {
+						t.Errorf("%s: blocked by %q, want %q", id, reason, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package deployments
+
+import (
+	"context"
+
+	"github.com/archellir/denshimon/internal/k8s"
+)
+
+// DisruptionBudgetWarnings reports the PodDisruptionBudgets that applying a deployment would
+// violate: budgets its replica count can never satisfy during a drain, and budgets its
+// rollout would take more pods down than they allow. Applying is not blocked.
+func (s *Service) DisruptionBudgetWarnings(ctx context.Context, id string) ([]k8s.BudgetConflict, error) {
+	deployment, err := s.getDeploymentFromDB(id)
+	if err != nil {
+		return nil, err
+	}
+
+	podLabels := s.deployer.buildDeploymentSpec(*deployment, "").Spec.Template.Labels
+	return s.k8sClient.CheckWorkloadBudgets(ctx, deployment.Namespace, podLabels, deployment.Replicas, rolloutUnavailable(*deployment))
+}
+
+// rolloutUnavailable is how many pods applying a deployment may take down at once
+func rolloutUnavailable(deployment Deployment) int32 {
+	switch {
+	case deployment.AppliedAt == nil:
+		// Nothing is running yet
+		return 0
+	case isStateful(deployment):
+		return 1
+	case isProgressiveStrategy(deployment.Strategy.Type):
+		// New pods start next to the stable ones
+		return 0
+	case deployment.Strategy.Type == "Recreate":
+		return deployment.Replicas
+	default:
+		return deployment.Strategy.MaxUnavailable
+	}
+}
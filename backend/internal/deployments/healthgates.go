@@ -0,0 +1,387 @@
+package deployments
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/archellir/denshimon/internal/websocket"
+	"github.com/google/uuid"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	defaultBakePeriod  = 2 * time.Minute
+	defaultMaxRestarts = 3
+	// maxGateWarnings caps the warning events kept on a gate run
+	maxGateWarnings = 20
+	// healthGateUser is recorded in the history for rollbacks made by a failed gate
+	healthGateUser = "health-gate"
+)
+
+// crashReasons are container waiting reasons that mean the new revision will not come up
+var crashReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+}
+
+// activeGate is the health gate running for a deployment
+type activeGate struct {
+	id     string
+	cancel context.CancelFunc
+}
+
+// revisionHealth is the state of a deployment's current revision at one poll
+type revisionHealth struct {
+	revision int64
+	// previous is the revision a failed gate rolls back to; zero when there is none
+	previous int64
+	desired  int32
+	ready    int32
+	complete bool
+	restarts int32
+	warnings []string
+	// failure explains why the revision is considered broken; empty while it is healthy
+	failure string
+}
+
+// checkRevision checks the pods of a deployment's current ReplicaSet for crash loops, image
+// pull errors and restarts, and collects the warning events recorded on them since a time
+func (d *KubernetesDeployer) checkRevision(ctx context.Context, namespace, name string, since time.Time, maxRestarts int32) (*revisionHealth, error) {
+	deployment, replicaSets, err := d.replicaSetsFor(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	health := &revisionHealth{desired: 1, ready: deployment.Status.ReadyReplicas, warnings: []string{}}
+	if deployment.Spec.Replicas != nil {
+		health.desired = *deployment.Spec.Replicas
+	}
+	health.complete = deploymentComplete(deployment, health.desired)
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse &&
+			condition.Reason == "ProgressDeadlineExceeded" {
+			health.failure = condition.Message
+		}
+	}
+
+	var current *appsv1.ReplicaSet
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		if rs.Annotations[revisionAnnotation] == deployment.Annotations[revisionAnnotation] {
+			current = rs
+		}
+	}
+	if current == nil {
+		// The controller hasn't created the new ReplicaSet yet
+		return health, nil
+	}
+	health.revision = revisionNumber(current)
+	for i := range replicaSets {
+		if revision := revisionNumber(&replicaSets[i]); revision < health.revision && revision > health.previous {
+			health.previous = revision
+		}
+	}
+
+	clientset := d.k8sClient.Clientset()
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{
+			appsv1.DefaultDeploymentUniqueLabelKey: current.Labels[appsv1.DefaultDeploymentUniqueLabelKey],
+		}).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	podNames := make(map[string]bool, len(pods.Items))
+	for _, pod := range pods.Items {
+		podNames[pod.Name] = true
+		restarts, reason := podHealth(pod)
+		health.restarts += restarts
+		if reason != "" && health.failure == "" {
+			health.failure = fmt.Sprintf("pod %s: %s", pod.Name, reason)
+		}
+	}
+	if health.failure == "" && health.restarts > maxRestarts {
+		health.failure = fmt.Sprintf("new pods restarted %d times (limit %d)", health.restarts, maxRestarts)
+	}
+
+	events, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("type", corev1.EventTypeWarning).String(),
+	})
+	if err != nil {
+		slog.Debug("Failed to list warning events", "deployment", name, "error", err)
+		return health, nil
+	}
+	for _, event := range events.Items {
+		if event.InvolvedObject.Kind != "Pod" || !podNames[event.InvolvedObject.Name] || eventTime(event).Before(since) {
+			continue
+		}
+		if len(health.warnings) == maxGateWarnings {
+			break
+		}
+		health.warnings = append(health.warnings, fmt.Sprintf("%s %s: %s", event.InvolvedObject.Name, event.Reason, event.Message))
+	}
+	return health, nil
+}
+
+// podHealth returns a pod's container restarts and the reason it is crashing, if it is
+func podHealth(pod corev1.Pod) (int32, string) {
+	var restarts int32
+	var reason string
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+		if waiting := status.State.Waiting; waiting != nil && crashReasons[waiting.Reason] && reason == "" {
+			reason = fmt.Sprintf("container %s is in %s", status.Name, waiting.Reason)
+		}
+	}
+	return restarts, reason
+}
+
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+// gateSettings applies the defaults to a deployment's health gate
+func gateSettings(gate *HealthGate) HealthGate {
+	settings := HealthGate{}
+	if gate != nil {
+		settings = *gate
+	}
+	if settings.BakeSeconds <= 0 {
+		settings.BakeSeconds = int(defaultBakePeriod / time.Second)
+	}
+	if settings.MaxRestarts <= 0 {
+		settings.MaxRestarts = defaultMaxRestarts
+	}
+	return settings
+}
+
+// startHealthGate watches an applied deployment's new revision in the background, replacing
+// any gate still running for an earlier apply
+func (s *Service) startHealthGate(deployment Deployment, user string) {
+	settings := gateSettings(deployment.Strategy.HealthGate)
+	if settings.Disabled {
+		return
+	}
+
+	now := time.Now()
+	run := &HealthGateRun{
+		ID:           uuid.New().String(),
+		DeploymentID: deployment.ID,
+		Phase:        HealthGatePhaseProgressing,
+		BakeSeconds:  settings.BakeSeconds,
+		Desired:      deployment.Replicas,
+		Warnings:     []string{},
+		User:         user,
+		StartedAt:    now,
+		UpdatedAt:    now,
+	}
+	bake := time.Duration(settings.BakeSeconds) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), rolloutTimeout+bake)
+
+	s.gatesMu.Lock()
+	if s.gates == nil {
+		s.gates = make(map[string]activeGate)
+	}
+	if previous, ok := s.gates[deployment.ID]; ok {
+		previous.cancel()
+	}
+	s.gates[deployment.ID] = activeGate{id: run.ID, cancel: cancel}
+	s.gatesMu.Unlock()
+
+	s.saveHealthGate(run)
+	go s.watchHealthGate(ctx, cancel, deployment, run, settings)
+}
+
+// watchHealthGate polls the new revision until it has rolled out and stayed healthy for the
+// bake period, failing the gate as soon as it crashes
+func (s *Service) watchHealthGate(ctx context.Context, cancel context.CancelFunc, deployment Deployment, run *HealthGateRun, settings HealthGate) {
+	defer func() {
+		cancel()
+		s.gatesMu.Lock()
+		if active, ok := s.gates[deployment.ID]; ok && active.id == run.ID {
+			delete(s.gates, deployment.ID)
+		}
+		s.gatesMu.Unlock()
+	}()
+
+	ticker := time.NewTicker(rolloutPollInterval)
+	defer ticker.Stop()
+
+	bake := time.Duration(settings.BakeSeconds) * time.Second
+	var bakeUntil time.Time
+	for {
+		health, err := s.deployer.checkRevision(ctx, deployment.Namespace, deployment.Name, run.StartedAt, settings.MaxRestarts)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			slog.Warn("Health gate check failed", "deployment", deployment.Name, "error", err)
+		case err == nil:
+			run.Revision, run.Desired, run.Ready = health.revision, health.desired, health.ready
+			run.Restarts, run.Warnings = health.restarts, health.warnings
+
+			if health.failure != "" {
+				s.failHealthGate(deployment, run, settings, health.previous, health.failure)
+				return
+			}
+			switch {
+			case run.Phase == HealthGatePhaseProgressing && health.complete:
+				bakeUntil = time.Now().Add(bake)
+				run.Phase = HealthGatePhaseBaking
+				run.Message = fmt.Sprintf("revision %d rolled out, baking for %s", health.revision, bake)
+			case run.Phase == HealthGatePhaseBaking && time.Now().After(bakeUntil):
+				if !health.complete {
+					s.failHealthGate(deployment, run, settings, health.previous,
+						fmt.Sprintf("%d of %d replicas ready after the bake period", health.ready, health.desired))
+					return
+				}
+				s.passHealthGate(deployment, run)
+				return
+			}
+			s.saveHealthGate(run)
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				s.failHealthGate(deployment, run, settings, 0, "timed out waiting for the new revision to roll out")
+				return
+			}
+			s.completeHealthGate(run, HealthGatePhaseCancelled, "superseded by a newer apply")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) passHealthGate(deployment Deployment, run *HealthGateRun) {
+	s.completeHealthGate(run, HealthGatePhasePassed, fmt.Sprintf("revision %d healthy for %ds", run.Revision, run.BakeSeconds))
+	s.recordHistoryWithMetadata(deployment.ID, "health_gate", deployment.Image, deployment.Image,
+		deployment.Replicas, deployment.Replicas, true, "", run.User, gateMetadata(run))
+}
+
+// failHealthGate marks the deployment failed and, when the gate asks for it, rolls back to
+// the previous revision
+func (s *Service) failHealthGate(deployment Deployment, run *HealthGateRun, settings HealthGate, previous int64, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), rolloutTimeout)
+	defer cancel()
+
+	s.recordHistoryWithMetadata(deployment.ID, "health_gate", deployment.Image, deployment.Image,
+		deployment.Replicas, deployment.Replicas, false, reason, run.User, gateMetadata(run))
+
+	phase, message := HealthGatePhaseFailed, reason
+	if settings.Rollback && previous > 0 {
+		if _, err := s.RollbackDeployment(ctx, deployment.ID, previous, healthGateUser); err != nil {
+			message = fmt.Sprintf("%s; rollback to revision %d failed: %v", reason, previous, err)
+		} else {
+			phase = HealthGatePhaseRolledBack
+			message = fmt.Sprintf("%s; rolled back to revision %d", reason, previous)
+		}
+	}
+
+	if current, err := s.getDeploymentFromDB(deployment.ID); err == nil {
+		current.Status = DeploymentStatusFailed
+		current.UpdatedAt = time.Now()
+		if err := s.updateDeploymentInDB(current); err != nil {
+			slog.Error("Failed to mark deployment failed", "deployment", deployment.ID, "error", err)
+		}
+	}
+	s.completeHealthGate(run, phase, message)
+}
+
+func (s *Service) completeHealthGate(run *HealthGateRun, phase HealthGatePhase, message string) {
+	now := time.Now()
+	run.Phase = phase
+	run.Message = message
+	run.CompletedAt = &now
+	s.saveHealthGate(run)
+}
+
+func gateMetadata(run *HealthGateRun) map[string]interface{} {
+	return map[string]interface{}{
+		"revision": run.Revision,
+		"restarts": run.Restarts,
+		"warnings": run.Warnings,
+	}
+}
+
+// saveHealthGate persists a gate run and publishes it on the deployments channel
+func (s *Service) saveHealthGate(run *HealthGateRun) {
+	run.UpdatedAt = time.Now()
+	warnings, _ := json.Marshal(run.Warnings)
+
+	_, err := s.db.Exec(`
+		INSERT OR REPLACE INTO deployment_health_gates (
+			id, deployment_id, phase, revision, bake_seconds, desired, ready, restarts,
+			warnings, message, user, started_at, updated_at, completed_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		run.ID, run.DeploymentID, run.Phase, run.Revision, run.BakeSeconds, run.Desired, run.Ready, run.Restarts,
+		string(warnings), run.Message, run.User, run.StartedAt, run.UpdatedAt, run.CompletedAt,
+	)
+	if err != nil {
+		slog.Error("Failed to save health gate", "gate", run.ID, "error", err)
+	}
+
+	if s.hub != nil {
+		s.hub.Broadcast(websocket.MessageTypeDeployments, map[string]interface{}{
+			"health_gate": run,
+			"timestamp":   time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+// GetLatestHealthGate returns the most recent health gate run of a deployment
+func (s *Service) GetLatestHealthGate(ctx context.Context, deploymentID string) (*HealthGateRun, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, deployment_id, phase, revision, bake_seconds, desired, ready, restarts,
+		       warnings, message, user, started_at, updated_at, completed_at
+		FROM deployment_health_gates
+		WHERE deployment_id = ?
+		ORDER BY started_at DESC
+		LIMIT 1
+	`, deploymentID)
+
+	var run HealthGateRun
+	var warnings, message, user sql.NullString
+	var completedAt sql.NullTime
+	err := row.Scan(
+		&run.ID, &run.DeploymentID, &run.Phase, &run.Revision, &run.BakeSeconds, &run.Desired, &run.Ready, &run.Restarts,
+		&warnings, &message, &user, &run.StartedAt, &run.UpdatedAt, &completedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no health gates found for deployment %s", deploymentID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	run.Warnings = []string{}
+	if warnings.Valid {
+		json.Unmarshal([]byte(warnings.String), &run.Warnings)
+	}
+	run.Message = message.String
+	run.User = user.String
+	if completedAt.Valid {
+		run.CompletedAt = &completedAt.Time
+	}
+	return &run, nil
+}
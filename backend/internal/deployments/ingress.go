@@ -0,0 +1,60 @@
+package deployments
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/archellir/denshimon/internal/providers/certificates"
+)
+
+// SetCertificateManager sets where the TLS hosts of deployment ingresses are monitored,
+// and registers the hosts of existing deployments
+func (s *Service) SetCertificateManager(manager *certificates.Manager) {
+	s.certificates = manager
+
+	rows, err := s.db.Query(`SELECT name, ingress FROM deployments WHERE ingress IS NOT NULL AND ingress != 'null'`)
+	if err != nil {
+		slog.Warn("Failed to load deployment ingresses", "error", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var deployment Deployment
+		var ingress string
+		if err := rows.Scan(&deployment.Name, &ingress); err != nil {
+			slog.Warn("Failed to load deployment ingresses", "error", err)
+			return
+		}
+		if err := json.Unmarshal([]byte(ingress), &deployment.Ingress); err == nil {
+			s.trackIngressDomain(&deployment)
+		}
+	}
+}
+
+// trackIngressDomain monitors the certificate of a deployment's TLS host
+func (s *Service) trackIngressDomain(deployment *Deployment) {
+	if s.certificates == nil || deployment.Ingress == nil || !deployment.Ingress.TLS() {
+		return
+	}
+	s.certificates.AddDomainConfig(certificates.DomainConfig{
+		Domain:        deployment.Ingress.Host,
+		Service:       deployment.Name,
+		Port:          443,
+		Enabled:       true,
+		CheckInterval: 60,
+	})
+}
+
+// untrackIngressDomain stops monitoring a deployment's TLS host, unless the domain was
+// added for another service
+func (s *Service) untrackIngressDomain(deployment *Deployment) {
+	if s.certificates == nil || deployment.Ingress == nil {
+		return
+	}
+	configs, _ := s.certificates.GetDomainConfigs()
+	for _, config := range configs {
+		if config.Domain == deployment.Ingress.Host && config.Service == deployment.Name {
+			s.certificates.RemoveDomainConfig(config.Domain)
+		}
+	}
+}
@@ -2,14 +2,18 @@ package deployments
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/archellir/denshimon/internal/k8s"
 	"github.com/archellir/denshimon/internal/providers"
+	"github.com/archellir/denshimon/internal/secretrefs"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -19,6 +23,7 @@ import (
 type KubernetesDeployer struct {
 	k8sClient       *k8s.Client
 	registryManager *providers.RegistryManager
+	secrets         *secretrefs.Resolver
 }
 
 // NewKubernetesDeployer creates a new Kubernetes deployer
@@ -26,25 +31,22 @@ func NewKubernetesDeployer(k8sClient *k8s.Client, registryManager *providers.Reg
 	return &KubernetesDeployer{
 		k8sClient:       k8sClient,
 		registryManager: registryManager,
+		secrets:         secretrefs.NewResolver(), // no stores until SetSecretResolver
 	}
 }
 
 // Deploy creates a new deployment in Kubernetes
 func (d *KubernetesDeployer) Deploy(ctx context.Context, deployment Deployment) (*appsv1.Deployment, error) {
-	// Get registry provider for authentication
-	registryProvider, err := d.registryManager.GetProvider(deployment.RegistryID)
+	secretName, err := d.imagePullSecret(ctx, deployment)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get registry provider: %w", err)
-	}
-
-	// Create image pull secret if needed
-	secretName, err := d.createImagePullSecret(ctx, deployment.Namespace, deployment.RegistryID, registryProvider)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create image pull secret: %w", err)
+		return nil, err
 	}
 
 	// Build Kubernetes deployment spec
 	k8sDeployment := d.buildDeploymentSpec(deployment, secretName)
+	if err := d.injectSecrets(ctx, deployment, &k8sDeployment.Spec.Template.Spec.Containers[0]); err != nil {
+		return nil, err
+	}
 
 	// Create deployment
 	clientset := d.k8sClient.Clientset()
@@ -54,11 +56,39 @@ func (d *KubernetesDeployer) Deploy(ctx context.Context, deployment Deployment)
 		return nil, fmt.Errorf("failed to create deployment: %w", err)
 	}
 
+	// Progressive strategies route traffic through a managed service
+	if isProgressiveStrategy(deployment.Strategy.Type) && deployment.Strategy.ServicePort > 0 {
+		if err := d.ensureService(ctx, deployment); err != nil {
+			return nil, err
+		}
+	}
+
 	return result, nil
 }
 
+// imagePullSecret creates the registry pull secret for a deployment and returns its name
+func (d *KubernetesDeployer) imagePullSecret(ctx context.Context, deployment Deployment) (string, error) {
+	// Get registry provider for authentication
+	registryProvider, err := d.registryManager.GetProvider(deployment.RegistryID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get registry provider: %w", err)
+	}
+
+	// Create image pull secret if needed
+	secretName, err := d.createImagePullSecret(ctx, deployment.Namespace, deployment.RegistryID, registryProvider)
+	if err != nil {
+		return "", fmt.Errorf("failed to create image pull secret: %w", err)
+	}
+
+	return secretName, nil
+}
+
 // Update updates an existing deployment in Kubernetes
 func (d *KubernetesDeployer) Update(ctx context.Context, deployment Deployment) error {
+	if isStateful(deployment) {
+		return d.updateStatefulSet(ctx, deployment)
+	}
+
 	clientset := d.k8sClient.Clientset()
 
 	// Get existing deployment
@@ -69,18 +99,36 @@ func (d *KubernetesDeployer) Update(ctx context.Context, deployment Deployment)
 
 	// Update the deployment spec
 	existing.Spec.Replicas = &deployment.Replicas
-	existing.Spec.Template.Spec.Containers[0].Image = deployment.Image
+	if err := d.updateContainer(ctx, deployment, &existing.Spec.Template.Spec.Containers[0]); err != nil {
+		return err
+	}
+
+	// Apply the update
+	_, err = clientset.AppsV1().Deployments(deployment.Namespace).Update(ctx, existing, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update deployment: %w", err)
+	}
+
+	return nil
+}
+
+// updateContainer applies the image, environment and resources of a deployment to its container
+func (d *KubernetesDeployer) updateContainer(ctx context.Context, deployment Deployment, container *corev1.Container) error {
+	setContainer(deployment, container)
+	if deployment.Environment != nil {
+		return d.injectSecrets(ctx, deployment, container)
+	}
+	return nil
+}
+
+// setContainer sets the image, environment and resources of a deployment on its container,
+// leaving secret references unresolved
+func setContainer(deployment Deployment, container *corev1.Container) {
+	container.Image = deployment.Image
 
 	// Update environment variables
 	if deployment.Environment != nil {
-		envVars := make([]corev1.EnvVar, 0, len(deployment.Environment))
-		for key, value := range deployment.Environment {
-			envVars = append(envVars, corev1.EnvVar{
-				Name:  key,
-				Value: value,
-			})
-		}
-		existing.Spec.Template.Spec.Containers[0].Env = envVars
+		container.Env = environmentVars(deployment.Environment)
 	}
 
 	// Update resources
@@ -109,31 +157,42 @@ func (d *KubernetesDeployer) Update(ctx context.Context, deployment Deployment)
 			}
 		}
 
-		existing.Spec.Template.Spec.Containers[0].Resources = resources
+		container.Resources = resources
 	}
+}
 
-	// Apply the update
-	_, err = clientset.AppsV1().Deployments(deployment.Namespace).Update(ctx, existing, metav1.UpdateOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to update deployment: %w", err)
+// environmentVars sorts variables by name so the pod template only changes with the values
+func environmentVars(environment map[string]string) []corev1.EnvVar {
+	names := make([]string, 0, len(environment))
+	for name := range environment {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	return nil
+	envVars := make([]corev1.EnvVar, 0, len(names))
+	for _, name := range names {
+		envVars = append(envVars, corev1.EnvVar{Name: name, Value: environment[name]})
+	}
+	return envVars
 }
 
 // Delete removes a deployment from Kubernetes
 func (d *KubernetesDeployer) Delete(ctx context.Context, namespace, name string) error {
 	clientset := d.k8sClient.Clientset()
 
-	// Delete the deployment
+	// Delete the deployment, or the StatefulSet of a database
 	err := clientset.AppsV1().Deployments(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		err = d.deleteStatefulSet(ctx, namespace, name)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete deployment: %w", err)
 	}
 
-	// Clean up associated image pull secret
+	// Clean up associated image pull and external secrets
 	secretName := fmt.Sprintf("%s-registry-secret", name)
 	clientset.CoreV1().Secrets(namespace).Delete(ctx, secretName, metav1.DeleteOptions{})
+	clientset.CoreV1().Secrets(namespace).Delete(ctx, externalSecretName(name), metav1.DeleteOptions{})
 
 	return nil
 }
@@ -142,8 +201,14 @@ func (d *KubernetesDeployer) Delete(ctx context.Context, namespace, name string)
 func (d *KubernetesDeployer) Restart(ctx context.Context, namespace, name string) error {
 	clientset := d.k8sClient.Clientset()
 
-	// Get existing deployment
+	// Get existing deployment; databases run as StatefulSets
 	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if err := d.k8sClient.RestartStatefulSet(ctx, namespace, name); err != nil {
+			return fmt.Errorf("failed to restart deployment: %w", err)
+		}
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get deployment: %w", err)
 	}
@@ -152,7 +217,7 @@ func (d *KubernetesDeployer) Restart(ctx context.Context, namespace, name string
 	if deployment.Spec.Template.Annotations == nil {
 		deployment.Spec.Template.Annotations = make(map[string]string)
 	}
-	deployment.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"] = time.Now().Format(time.RFC3339)
+	deployment.Spec.Template.Annotations[k8s.RestartedAtAnnotation] = time.Now().Format(time.RFC3339)
 
 	// Update the deployment
 	_, err = clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
@@ -163,42 +228,178 @@ func (d *KubernetesDeployer) Restart(ctx context.Context, namespace, name string
 	return nil
 }
 
-// buildDeploymentSpec creates a Kubernetes deployment specification
-func (d *KubernetesDeployer) buildDeploymentSpec(deployment Deployment, imagePullSecret string) *appsv1.Deployment {
-	labels := map[string]string{
-		"app":        deployment.Name,
-		"managed-by": "denshimon",
+// externalSecretName is the Secret holding a deployment's resolved secret references
+func externalSecretName(deployment string) string {
+	return deployment + "-external-secrets"
+}
+
+// externalSecretEnv reads a resolved secret reference from the deployment's external secrets
+func externalSecretEnv(deployment, name string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: externalSecretName(deployment)},
+			Key:                  name,
+		}},
 	}
+}
 
-	// Build environment variables
-	envVars := make([]corev1.EnvVar, 0, len(deployment.Environment))
-	for key, value := range deployment.Environment {
-		envVars = append(envVars, corev1.EnvVar{
-			Name:  key,
-			Value: value,
-		})
+// injectSecrets resolves environment values that reference external secret stores
+// and moves them into a Secret the container reads them from, so resolved values
+// never appear in the Deployment spec, the database or the GitOps manifests
+func (d *KubernetesDeployer) injectSecrets(ctx context.Context, deployment Deployment, container *corev1.Container) error {
+	resolved := make(map[string][]byte)
+	for i, env := range container.Env {
+		if !d.secrets.IsReference(env.Value) {
+			continue
+		}
+		value, err := d.secrets.Resolve(ctx, env.Value)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", env.Name, err)
+		}
+		resolved[env.Name] = []byte(value)
+		container.Env[i] = externalSecretEnv(deployment.Name, env.Name)
+	}
+	if len(resolved) == 0 {
+		return nil
 	}
 
-	// Build resource requirements
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      externalSecretName(deployment.Name),
+			Namespace: deployment.Namespace,
+			Labels:    map[string]string{"app": deployment.Name, "managed-by": "denshimon"},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: resolved,
+	}
+	secrets := d.k8sClient.Clientset().CoreV1().Secrets(deployment.Namespace)
+	if _, err := secrets.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create external secrets: %w", err)
+		}
+		if _, err := secrets.Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update external secrets: %w", err)
+		}
+	}
+	return nil
+}
+
+// volumeSource maps a deployment volume to its Kubernetes source
+func volumeSource(volume Volume) corev1.VolumeSource {
+	switch volume.Type {
+	case VolumeConfigMap:
+		return corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: volume.Source},
+		}}
+	case VolumeSecret:
+		return corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: volume.Source}}
+	case VolumePVC:
+		return corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: volume.Source,
+			ReadOnly:  volume.ReadOnly,
+		}}
+	default:
+		return corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+	}
+}
+
+// containerSpec maps an init or sidecar container, mounting the deployment volumes it names
+func containerSpec(container Container, volumes []Volume) corev1.Container {
+	spec := corev1.Container{
+		Name:      container.Name,
+		Image:     container.Image,
+		Command:   container.Command,
+		Args:      container.Args,
+		Env:       environmentVars(container.Environment),
+		Resources: resourceRequirements(container.Resources),
+	}
+	for _, name := range container.Volumes {
+		for _, volume := range volumes {
+			if volume.Name == name {
+				spec.VolumeMounts = append(spec.VolumeMounts, corev1.VolumeMount{
+					Name:      volume.Name,
+					MountPath: volume.MountPath,
+					ReadOnly:  volume.ReadOnly,
+				})
+			}
+		}
+	}
+	return spec
+}
+
+// probeSpec maps a deployment probe, returning nil when it is unset
+func probeSpec(probe *Probe) *corev1.Probe {
+	if probe == nil {
+		return nil
+	}
+	spec := &corev1.Probe{
+		InitialDelaySeconds: probe.InitialDelaySeconds,
+		PeriodSeconds:       probe.PeriodSeconds,
+		TimeoutSeconds:      probe.TimeoutSeconds,
+		SuccessThreshold:    probe.SuccessThreshold,
+		FailureThreshold:    probe.FailureThreshold,
+	}
+	switch {
+	case probe.HTTPGet != nil:
+		spec.HTTPGet = &corev1.HTTPGetAction{
+			Path:   probe.HTTPGet.Path,
+			Port:   intstr.FromInt32(probe.HTTPGet.Port),
+			Scheme: corev1.URISchemeHTTP,
+		}
+		if probe.HTTPGet.Scheme != "" {
+			spec.HTTPGet.Scheme = corev1.URIScheme(probe.HTTPGet.Scheme)
+		}
+	case probe.TCPSocket != nil:
+		spec.TCPSocket = &corev1.TCPSocketAction{Port: intstr.FromInt32(probe.TCPSocket.Port)}
+	default:
+		spec.Exec = &corev1.ExecAction{Command: probe.Exec}
+	}
+	return spec
+}
+
+// resourceRequirements converts resource limits and requests, leaving unset values out
+func resourceRequirements(requirements ResourceRequirements) corev1.ResourceRequirements {
 	resources := corev1.ResourceRequirements{}
-	if deployment.Resources.Limits.CPU != "" || deployment.Resources.Limits.Memory != "" {
+	if requirements.Limits.CPU != "" || requirements.Limits.Memory != "" {
 		resources.Limits = make(corev1.ResourceList)
-		if deployment.Resources.Limits.CPU != "" {
-			resources.Limits[corev1.ResourceCPU] = mustParseQuantity(deployment.Resources.Limits.CPU)
+		if requirements.Limits.CPU != "" {
+			resources.Limits[corev1.ResourceCPU] = mustParseQuantity(requirements.Limits.CPU)
 		}
-		if deployment.Resources.Limits.Memory != "" {
-			resources.Limits[corev1.ResourceMemory] = mustParseQuantity(deployment.Resources.Limits.Memory)
+		if requirements.Limits.Memory != "" {
+			resources.Limits[corev1.ResourceMemory] = mustParseQuantity(requirements.Limits.Memory)
 		}
 	}
-	if deployment.Resources.Requests.CPU != "" || deployment.Resources.Requests.Memory != "" {
+	if requirements.Requests.CPU != "" || requirements.Requests.Memory != "" {
 		resources.Requests = make(corev1.ResourceList)
-		if deployment.Resources.Requests.CPU != "" {
-			resources.Requests[corev1.ResourceCPU] = mustParseQuantity(deployment.Resources.Requests.CPU)
+		if requirements.Requests.CPU != "" {
+			resources.Requests[corev1.ResourceCPU] = mustParseQuantity(requirements.Requests.CPU)
 		}
-		if deployment.Resources.Requests.Memory != "" {
-			resources.Requests[corev1.ResourceMemory] = mustParseQuantity(deployment.Resources.Requests.Memory)
+		if requirements.Requests.Memory != "" {
+			resources.Requests[corev1.ResourceMemory] = mustParseQuantity(requirements.Requests.Memory)
 		}
 	}
+	return resources
+}
+
+// buildDeploymentSpec creates a Kubernetes deployment specification
+func (d *KubernetesDeployer) buildDeploymentSpec(deployment Deployment, imagePullSecret string) *appsv1.Deployment {
+	labels := map[string]string{
+		"app":        deployment.Name,
+		"managed-by": "denshimon",
+	}
+
+	// Progressive strategies run a secondary deployment next to the primary, so pods carry a track label
+	// to keep the selectors of both deployments disjoint
+	if isProgressiveStrategy(deployment.Strategy.Type) {
+		labels[trackLabel] = trackStable
+	}
+
+	// Build environment variables
+	envVars := environmentVars(deployment.Environment)
+
+	// Build resource requirements
+	resources := resourceRequirements(deployment.Resources)
 
 	// Build deployment strategy
 	deploymentStrategy := appsv1.DeploymentStrategy{
@@ -207,7 +408,7 @@ func (d *KubernetesDeployer) buildDeploymentSpec(deployment Deployment, imagePul
 
 	if deployment.Strategy.Type == "Recreate" {
 		deploymentStrategy.Type = appsv1.RecreateDeploymentStrategyType
-	} else {
+	} else if !isProgressiveStrategy(deployment.Strategy.Type) {
 		deploymentStrategy.RollingUpdate = &appsv1.RollingUpdateDeployment{
 			MaxSurge:       &intstr.IntOrString{Type: intstr.Int, IntVal: deployment.Strategy.MaxSurge},
 			MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: deployment.Strategy.MaxUnavailable},
@@ -228,6 +429,35 @@ func (d *KubernetesDeployer) buildDeploymentSpec(deployment Deployment, imagePul
 		RestartPolicy: corev1.RestartPolicyAlways,
 	}
 
+	// Add container ports and volumes
+	for _, port := range deployment.Ports {
+		podSpec.Containers[0].Ports = append(podSpec.Containers[0].Ports, corev1.ContainerPort{
+			Name:          port.Name,
+			ContainerPort: port.ContainerPort,
+			Protocol:      corev1.Protocol(port.Protocol),
+		})
+	}
+	for _, volume := range deployment.Volumes {
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{Name: volume.Name, VolumeSource: volumeSource(volume)})
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      volume.Name,
+			MountPath: volume.MountPath,
+			ReadOnly:  volume.ReadOnly,
+		})
+	}
+
+	podSpec.Containers[0].LivenessProbe = probeSpec(deployment.Probes.Liveness)
+	podSpec.Containers[0].ReadinessProbe = probeSpec(deployment.Probes.Readiness)
+	podSpec.Containers[0].StartupProbe = probeSpec(deployment.Probes.Startup)
+
+	// Init containers and sidecars run with the main container in every pod
+	for _, container := range deployment.InitContainers {
+		podSpec.InitContainers = append(podSpec.InitContainers, containerSpec(container, deployment.Volumes))
+	}
+	for _, container := range deployment.Sidecars {
+		podSpec.Containers = append(podSpec.Containers, containerSpec(container, deployment.Volumes))
+	}
+
 	// Add image pull secret if provided
 	if imagePullSecret != "" {
 		podSpec.ImagePullSecrets = []corev1.LocalObjectReference{
@@ -290,57 +520,64 @@ func (d *KubernetesDeployer) buildDeploymentSpec(deployment Deployment, imagePul
 	}
 }
 
-// createImagePullSecret creates a secret for registry authentication
+// createImagePullSecret creates or updates the pull secret of a registry in a namespace,
+// returning "" for registries that need no credentials
 func (d *KubernetesDeployer) createImagePullSecret(ctx context.Context, namespace, registryID string, provider providers.RegistryProvider) (string, error) {
-	// Get auth config from provider
 	authConfig, err := provider.GetAuthConfig()
 	if err != nil {
 		return "", err
 	}
-
-	// If no auth is needed, return empty string
 	if authConfig == nil {
 		return "", nil
 	}
 
-	clientset := d.k8sClient.Clientset()
-
-	secretName := fmt.Sprintf("registry-%s", registryID)
-
-	// Create docker config JSON
-	dockerConfig := map[string]interface{}{
-		"auths": map[string]interface{}{
-			authConfig.ServerAddress: authConfig,
-		},
-	}
-
-	dockerConfigJSON, err := json.Marshal(dockerConfig)
+	dockerConfigJSON, err := dockerConfigJSON(authConfig)
 	if err != nil {
 		return "", err
 	}
-
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      secretName,
+			Name:      pullSecretName(registryID),
 			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "denshimon",
+				PullSecretRegistryLabel:        registryID,
+			},
 		},
 		Type: corev1.SecretTypeDockerConfigJson,
 		Data: map[string][]byte{
-			".dockerconfigjson": dockerConfigJSON,
+			corev1.DockerConfigJsonKey: dockerConfigJSON,
 		},
 	}
 
-	// Create or update the secret
-	_, err = clientset.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+	secrets := d.k8sClient.Clientset().CoreV1().Secrets(namespace)
+	_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	}
 	if err != nil {
-		// If secret exists, update it
-		_, updateErr := clientset.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
-		if updateErr != nil {
-			return "", fmt.Errorf("failed to create/update secret: %w", updateErr)
-		}
+		return "", fmt.Errorf("failed to create/update secret: %w", err)
 	}
+	return secret.Name, nil
+}
 
-	return secretName, nil
+// dockerConfigJSON is the .dockerconfigjson of a registry's credentials. The auth field is
+// filled in from the username and password, since some runtimes only read that.
+func dockerConfigJSON(authConfig *providers.AuthConfig) ([]byte, error) {
+	entry := *authConfig
+	if entry.Auth == "" && entry.Username != "" {
+		entry.Auth = base64.StdEncoding.EncodeToString([]byte(entry.Username + ":" + entry.Password))
+	}
+	return json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			authConfig.ServerAddress: entry,
+		},
+	})
+}
+
+// pullSecretName is the image pull secret of a registry
+func pullSecretName(registryID string) string {
+	return fmt.Sprintf("registry-%s", registryID)
 }
 
 // KubernetesScaler handles scaling operations
@@ -359,8 +596,11 @@ func NewKubernetesScaler(k8sClient *k8s.Client) *KubernetesScaler {
 func (s *KubernetesScaler) Scale(ctx context.Context, namespace, name string, replicas int32) error {
 	clientset := s.k8sClient.Clientset()
 
-	// Get the scale subresource
+	// Get the scale subresource; databases run as StatefulSets
 	scale, err := clientset.AppsV1().Deployments(namespace).GetScale(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return s.k8sClient.ScaleStatefulSet(ctx, namespace, name, replicas)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get deployment scale: %w", err)
 	}
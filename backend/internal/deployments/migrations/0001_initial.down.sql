@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS deployment_resources;
+DROP TABLE IF EXISTS deployment_rollouts;
+DROP TABLE IF EXISTS autoscalers;
+DROP TABLE IF EXISTS deployment_history;
+DROP TABLE IF EXISTS deployments;
@@ -0,0 +1,80 @@
+CREATE TABLE IF NOT EXISTS deployments (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	namespace TEXT NOT NULL,
+	image TEXT NOT NULL,
+	registry_id TEXT NOT NULL,
+	replicas INTEGER DEFAULT 1,
+	node_selector TEXT,
+	strategy TEXT,
+	resources TEXT,
+	environment TEXT,
+	status TEXT DEFAULT 'pending',
+	source TEXT DEFAULT 'internal',
+	author TEXT,
+	git_commit_sha TEXT,
+	manifest_path TEXT,
+	applied_by TEXT,
+	applied_at TIMESTAMP,
+	service_type TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS deployment_history (
+	id TEXT PRIMARY KEY,
+	deployment_id TEXT NOT NULL,
+	action TEXT NOT NULL,
+	old_image TEXT,
+	new_image TEXT,
+	old_replicas INTEGER,
+	new_replicas INTEGER,
+	success BOOLEAN DEFAULT FALSE,
+	error TEXT,
+	user TEXT,
+	timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	metadata TEXT,
+	FOREIGN KEY (deployment_id) REFERENCES deployments(id)
+);
+
+CREATE TABLE IF NOT EXISTS autoscalers (
+	id TEXT PRIMARY KEY,
+	deployment_id TEXT NOT NULL,
+	min_replicas INTEGER NOT NULL,
+	max_replicas INTEGER NOT NULL,
+	target_cpu_percent INTEGER,
+	target_memory_percent INTEGER,
+	enabled BOOLEAN DEFAULT TRUE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (deployment_id) REFERENCES deployments(id)
+);
+
+CREATE TABLE IF NOT EXISTS deployment_rollouts (
+	id TEXT PRIMARY KEY,
+	deployment_id TEXT NOT NULL,
+	strategy TEXT NOT NULL,
+	phase TEXT NOT NULL,
+	secondary TEXT NOT NULL,
+	stable_image TEXT,
+	candidate_image TEXT,
+	desired INTEGER DEFAULT 0,
+	ready INTEGER DEFAULT 0,
+	message TEXT,
+	user TEXT,
+	started_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	completed_at TIMESTAMP,
+	candidate TEXT,
+	FOREIGN KEY (deployment_id) REFERENCES deployments(id)
+);
+
+CREATE TABLE IF NOT EXISTS deployment_resources (
+	id TEXT PRIMARY KEY,
+	deployment_id TEXT NOT NULL,
+	resource_type TEXT NOT NULL,
+	resource_name TEXT NOT NULL,
+	namespace TEXT NOT NULL,
+	k8s_uid TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (deployment_id) REFERENCES deployments(id)
+);
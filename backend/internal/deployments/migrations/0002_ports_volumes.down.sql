@@ -0,0 +1,2 @@
+ALTER TABLE deployments DROP COLUMN volumes;
+ALTER TABLE deployments DROP COLUMN ports;
@@ -0,0 +1,3 @@
+-- Container ports and volume mounts as JSON
+ALTER TABLE deployments ADD COLUMN ports TEXT;
+ALTER TABLE deployments ADD COLUMN volumes TEXT;
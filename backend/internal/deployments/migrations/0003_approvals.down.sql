@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS deployment_approvals;
+DROP TABLE IF EXISTS deployment_approval_requests;
+DROP TABLE IF EXISTS deployment_approval_rules;
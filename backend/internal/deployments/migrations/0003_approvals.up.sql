@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS deployment_approval_rules (
+	id TEXT PRIMARY KEY,
+	namespace TEXT NOT NULL UNIQUE,
+	required_approvals INTEGER NOT NULL DEFAULT 1,
+	approver_role TEXT NOT NULL DEFAULT 'admin',
+	created_by TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS deployment_approval_requests (
+	deployment_id TEXT PRIMARY KEY,
+	requested_by TEXT,
+	requested_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (deployment_id) REFERENCES deployments(id)
+);
+
+CREATE TABLE IF NOT EXISTS deployment_approvals (
+	deployment_id TEXT NOT NULL,
+	user TEXT NOT NULL,
+	decision TEXT NOT NULL,
+	comment TEXT,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (deployment_id, user),
+	FOREIGN KEY (deployment_id) REFERENCES deployments(id)
+);
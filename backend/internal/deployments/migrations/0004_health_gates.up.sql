@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS deployment_health_gates (
+	id TEXT PRIMARY KEY,
+	deployment_id TEXT NOT NULL,
+	phase TEXT NOT NULL,
+	revision INTEGER DEFAULT 0,
+	bake_seconds INTEGER DEFAULT 0,
+	desired INTEGER DEFAULT 0,
+	ready INTEGER DEFAULT 0,
+	restarts INTEGER DEFAULT 0,
+	warnings TEXT,
+	message TEXT,
+	user TEXT,
+	started_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	completed_at TIMESTAMP,
+	FOREIGN KEY (deployment_id) REFERENCES deployments(id)
+);
@@ -0,0 +1,8 @@
+-- Deployments that must be applied before another when both are in a batch
+CREATE TABLE IF NOT EXISTS deployment_dependencies (
+	deployment_id TEXT NOT NULL,
+	depends_on TEXT NOT NULL,
+	PRIMARY KEY (deployment_id, depends_on),
+	FOREIGN KEY (deployment_id) REFERENCES deployments(id),
+	FOREIGN KEY (depends_on) REFERENCES deployments(id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS deployment_restart_pause;
+DROP TABLE IF EXISTS deployment_restart_schedules;
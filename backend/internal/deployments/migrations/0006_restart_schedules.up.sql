@@ -0,0 +1,21 @@
+-- Periodic rolling restarts of a deployment
+CREATE TABLE IF NOT EXISTS deployment_restart_schedules (
+	deployment_id TEXT PRIMARY KEY,
+	cron TEXT NOT NULL,
+	timezone TEXT,
+	jitter_seconds INTEGER DEFAULT 0,
+	enabled BOOLEAN DEFAULT 1,
+	next_run_at TIMESTAMP,
+	last_run_at TIMESTAMP,
+	last_error TEXT,
+	updated_by TEXT,
+	updated_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (deployment_id) REFERENCES deployments(id)
+);
+
+-- Pauses every restart schedule while set
+CREATE TABLE IF NOT EXISTS deployment_restart_pause (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	paused_by TEXT,
+	paused_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,2 @@
+ALTER TABLE deployments DROP COLUMN sidecars;
+ALTER TABLE deployments DROP COLUMN init_containers;
@@ -0,0 +1,3 @@
+-- Init and sidecar containers as JSON
+ALTER TABLE deployments ADD COLUMN init_containers TEXT;
+ALTER TABLE deployments ADD COLUMN sidecars TEXT;
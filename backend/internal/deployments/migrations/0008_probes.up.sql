@@ -0,0 +1,2 @@
+-- Liveness, readiness and startup probes as JSON
+ALTER TABLE deployments ADD COLUMN probes TEXT;
@@ -0,0 +1,2 @@
+-- Ingress host, path and TLS as JSON
+ALTER TABLE deployments ADD COLUMN ingress TEXT;
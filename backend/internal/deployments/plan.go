@@ -0,0 +1,314 @@
+package deployments
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Plan actions
+const (
+	PlanCreate = "create"
+	PlanUpdate = "update"
+	PlanNoOp   = "no-op"
+)
+
+// PlannedResource is one object applying a deployment writes, with the fields it changes
+type PlannedResource struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Namespace  string            `json:"namespace"`
+	Name       string            `json:"name"`
+	Action     string            `json:"action"` // create, update, no-op
+	Changes    []k8s.FieldChange `json:"changes"`
+	// Manifest is the object as it will be after the apply
+	Manifest map[string]interface{} `json:"manifest"`
+}
+
+// PlanSummary counts the planned resources by action
+type PlanSummary struct {
+	Create int `json:"create"`
+	Update int `json:"update"`
+	NoOp   int `json:"noOp"`
+}
+
+// DeploymentPlan previews what applying a pending deployment does to the cluster
+type DeploymentPlan struct {
+	DeploymentID string            `json:"deployment_id"`
+	Resources    []PlannedResource `json:"resources"`
+	Summary      PlanSummary       `json:"summary"`
+	// Warnings are disruption budgets the change conflicts with
+	Warnings    []k8s.BudgetConflict `json:"warnings,omitempty"`
+	GeneratedAt time.Time            `json:"generated_at"`
+}
+
+// PlanDeployment compares what applying a pending deployment would write with the live
+// cluster objects. Nothing is changed: secret references are shown as the Secret keys
+// they will be read from, and pull and external Secrets are not listed.
+func (s *Service) PlanDeployment(ctx context.Context, id string) (*DeploymentPlan, error) {
+	deployment, err := s.getDeploymentFromDB(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+	if deployment.Status != DeploymentStatusPendingApply {
+		return nil, fmt.Errorf("%w: %s", ErrNotPendingApply, deployment.Status)
+	}
+
+	resources, err := s.deployer.Plan(ctx, *deployment)
+	if err != nil {
+		return nil, err
+	}
+	plan := &DeploymentPlan{DeploymentID: deployment.ID, Resources: resources, GeneratedAt: time.Now()}
+	for _, resource := range resources {
+		switch resource.Action {
+		case PlanCreate:
+			plan.Summary.Create++
+		case PlanUpdate:
+			plan.Summary.Update++
+		default:
+			plan.Summary.NoOp++
+		}
+	}
+
+	if plan.Warnings, err = s.DisruptionBudgetWarnings(ctx, id); err != nil {
+		slog.Warn("Failed to check disruption budgets", "deployment", id, "error", err)
+	}
+	return plan, nil
+}
+
+// Plan renders the objects Deploy, DeployStatefulSet or Update would write and diffs them
+// against the cluster. Like ApplyDeployment, a deployment applied before is updated in place.
+func (d *KubernetesDeployer) Plan(ctx context.Context, deployment Deployment) ([]PlannedResource, error) {
+	stateful := isStateful(deployment)
+	if deployment.AppliedAt != nil {
+		workload, err := d.planUpdate(ctx, deployment, stateful)
+		if err != nil {
+			return nil, err
+		}
+		return []PlannedResource{workload}, nil
+	}
+
+	// Deploy and DeployStatefulSet create the workload and fail when it already exists
+	if err := d.checkAbsent(ctx, deployment, stateful); err != nil {
+		return nil, err
+	}
+	secretName, err := d.plannedPullSecret(deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []PlannedResource
+	var workload runtime.Object
+	if stateful {
+		statefulSet := d.buildStatefulSetSpec(deployment, secretName)
+		d.referenceSecrets(deployment, &statefulSet.Spec.Template.Spec.Containers[0])
+		statefulSet.TypeMeta = metav1.TypeMeta{APIVersion: "apps/v1", Kind: "StatefulSet"}
+		workload = statefulSet
+
+		// The headless service is created before the StatefulSet
+		service, err := d.planService(ctx, headlessService(deployment))
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, service)
+	} else {
+		k8sDeployment := d.buildDeploymentSpec(deployment, secretName)
+		d.referenceSecrets(deployment, &k8sDeployment.Spec.Template.Spec.Containers[0])
+		k8sDeployment.TypeMeta = metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"}
+		workload = k8sDeployment
+	}
+
+	created, err := plannedCreate(workload)
+	if err != nil {
+		return nil, err
+	}
+	resources = append(resources, created)
+
+	if !stateful && isProgressiveStrategy(deployment.Strategy.Type) && deployment.Strategy.ServicePort > 0 {
+		service, err := d.planService(ctx, progressiveService(deployment))
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, service)
+	}
+	return resources, nil
+}
+
+// planUpdate applies the deployment to a copy of its live workload the way Update does
+func (d *KubernetesDeployer) planUpdate(ctx context.Context, deployment Deployment, stateful bool) (PlannedResource, error) {
+	apps := d.k8sClient.Clientset().AppsV1()
+	var live, desired runtime.Object
+	if stateful {
+		existing, err := apps.StatefulSets(deployment.Namespace).Get(ctx, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return PlannedResource{}, fmt.Errorf("failed to get existing statefulset: %w", err)
+		}
+		existing.TypeMeta = metav1.TypeMeta{APIVersion: "apps/v1", Kind: "StatefulSet"}
+		updated := existing.DeepCopy()
+		updated.Spec.Replicas = &deployment.Replicas
+		d.planContainer(deployment, &updated.Spec.Template.Spec.Containers[0])
+		live, desired = existing, updated
+	} else {
+		existing, err := apps.Deployments(deployment.Namespace).Get(ctx, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return PlannedResource{}, fmt.Errorf("failed to get existing deployment: %w", err)
+		}
+		existing.TypeMeta = metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"}
+		updated := existing.DeepCopy()
+		updated.Spec.Replicas = &deployment.Replicas
+		d.planContainer(deployment, &updated.Spec.Template.Spec.Containers[0])
+		live, desired = existing, updated
+	}
+
+	liveObject, err := runtime.DefaultUnstructuredConverter.ToUnstructured(live)
+	if err != nil {
+		return PlannedResource{}, err
+	}
+	resource, err := plannedResource(desired)
+	if err != nil {
+		return PlannedResource{}, err
+	}
+	resource.Changes = k8s.DiffObjects(liveObject, resource.Manifest)
+	resource.Action = PlanUpdate
+	if len(resource.Changes) == 0 {
+		resource.Action = PlanNoOp
+	}
+	return resource, nil
+}
+
+// planContainer is updateContainer without resolving secret references
+func (d *KubernetesDeployer) planContainer(deployment Deployment, container *corev1.Container) {
+	setContainer(deployment, container)
+	if deployment.Environment != nil {
+		d.referenceSecrets(deployment, container)
+	}
+}
+
+// referenceSecrets points secret references at the external secrets injectSecrets would write
+func (d *KubernetesDeployer) referenceSecrets(deployment Deployment, container *corev1.Container) {
+	for i, env := range container.Env {
+		if d.secrets.IsReference(env.Value) {
+			container.Env[i] = externalSecretEnv(deployment.Name, env.Name)
+		}
+	}
+}
+
+// plannedPullSecret is the pull secret name imagePullSecret would return, without writing it
+func (d *KubernetesDeployer) plannedPullSecret(deployment Deployment) (string, error) {
+	provider, err := d.registryManager.GetProvider(deployment.RegistryID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get registry provider: %w", err)
+	}
+	authConfig, err := provider.GetAuthConfig()
+	if err != nil {
+		return "", err
+	}
+	if authConfig == nil {
+		return "", nil
+	}
+	return pullSecretName(deployment.RegistryID), nil
+}
+
+func (d *KubernetesDeployer) checkAbsent(ctx context.Context, deployment Deployment, stateful bool) error {
+	apps := d.k8sClient.Clientset().AppsV1()
+	var err error
+	if stateful {
+		_, err = apps.StatefulSets(deployment.Namespace).Get(ctx, deployment.Name, metav1.GetOptions{})
+	} else {
+		_, err = apps.Deployments(deployment.Namespace).Get(ctx, deployment.Name, metav1.GetOptions{})
+	}
+	switch {
+	case err == nil:
+		return fmt.Errorf("%s/%s already exists in the cluster; applying would fail", deployment.Namespace, deployment.Name)
+	case apierrors.IsNotFound(err):
+		return nil
+	default:
+		return err
+	}
+}
+
+// planService plans a service that is created when missing and otherwise left as it is
+func (d *KubernetesDeployer) planService(ctx context.Context, service *corev1.Service) (PlannedResource, error) {
+	service.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Service"}
+	existing, err := d.k8sClient.Clientset().CoreV1().Services(service.Namespace).Get(ctx, service.Name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		existing.TypeMeta = service.TypeMeta
+		resource, err := plannedResource(existing)
+		if err != nil {
+			return PlannedResource{}, err
+		}
+		resource.Action = PlanNoOp
+		return resource, nil
+	case apierrors.IsNotFound(err):
+		return plannedCreate(service)
+	default:
+		return PlannedResource{}, err
+	}
+}
+
+// plannedCreate lists every field of a new object as added
+func plannedCreate(obj runtime.Object) (PlannedResource, error) {
+	resource, err := plannedResource(obj)
+	if err != nil {
+		return PlannedResource{}, err
+	}
+	resource.Action = PlanCreate
+	for _, change := range k8s.DiffObjects(nil, resource.Manifest) {
+		addedFields(change.Path, change.New, &resource.Changes)
+	}
+	return resource, nil
+}
+
+// addedFields expands an added value into one change per leaf field
+func addedFields(path string, value interface{}, changes *[]k8s.FieldChange) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			addedFields(path+"."+key, value[key], changes)
+		}
+	case []interface{}:
+		for i, item := range value {
+			addedFields(fmt.Sprintf("%s[%d]", path, i), item, changes)
+		}
+	case nil:
+		// Unset optional fields are left out
+	default:
+		*changes = append(*changes, k8s.FieldChange{Path: path, Op: "add", New: value})
+	}
+}
+
+// plannedResource describes an object by its manifest, without status and server-managed metadata
+func plannedResource(obj runtime.Object) (PlannedResource, error) {
+	manifest, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return PlannedResource{}, fmt.Errorf("failed to render manifest: %w", err)
+	}
+	delete(manifest, "status")
+	unstructured.RemoveNestedField(manifest, "metadata", "managedFields")
+	unstructured.RemoveNestedField(manifest, "metadata", "creationTimestamp")
+
+	u := unstructured.Unstructured{Object: manifest}
+	return PlannedResource{
+		APIVersion: u.GetAPIVersion(),
+		Kind:       u.GetKind(),
+		Namespace:  u.GetNamespace(),
+		Name:       u.GetName(),
+		Changes:    []k8s.FieldChange{},
+		Manifest:   manifest,
+	}, nil
+}
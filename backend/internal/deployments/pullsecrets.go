@@ -0,0 +1,154 @@
+package deployments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PullSecretRegistryLabel names the registry a pull secret holds the credentials of
+const PullSecretRegistryLabel = "denshimon.io/registry"
+
+// ErrNoRegistryCredentials means a registry needs no pull secret
+var ErrNoRegistryCredentials = errors.New("registry has no credentials to put in a pull secret")
+
+// PullSecret is a registry's pull secret in a namespace and the service accounts using it
+type PullSecret struct {
+	RegistryID      string   `json:"registry_id"`
+	Namespace       string   `json:"namespace"`
+	Name            string   `json:"name"`
+	ServiceAccounts []string `json:"service_accounts"`
+}
+
+// SyncPullSecret creates or updates a registry's pull secret in a namespace from its stored
+// credentials and adds it to the image pull secrets of the service accounts, so their pods
+// can pull from the registry without naming the secret
+func (s *Service) SyncPullSecret(ctx context.Context, registryID, namespace string, serviceAccounts []string) (*PullSecret, error) {
+	provider, err := s.registryManager.GetProvider(registryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get registry provider: %w", err)
+	}
+	name, err := s.deployer.createImagePullSecret(ctx, namespace, registryID, provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image pull secret: %w", err)
+	}
+	if name == "" {
+		return nil, ErrNoRegistryCredentials
+	}
+
+	for _, account := range serviceAccounts {
+		if err := s.attachPullSecret(ctx, namespace, account, name); err != nil {
+			return nil, err
+		}
+	}
+	return s.pullSecret(ctx, registryID, namespace, name)
+}
+
+// attachPullSecret adds a pull secret to a service account unless it's already there
+func (s *Service) attachPullSecret(ctx context.Context, namespace, account, secretName string) error {
+	accounts := s.k8sClient.Clientset().CoreV1().ServiceAccounts(namespace)
+	sa, err := accounts.Get(ctx, account, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get service account %s: %w", account, err)
+	}
+	for _, ref := range sa.ImagePullSecrets {
+		if ref.Name == secretName {
+			return nil
+		}
+	}
+	sa.ImagePullSecrets = append(sa.ImagePullSecrets, corev1.LocalObjectReference{Name: secretName})
+	if _, err := accounts.Update(ctx, sa, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to attach pull secret to service account %s: %w", account, err)
+	}
+	return nil
+}
+
+// ListPullSecrets lists a registry's pull secrets in every namespace
+func (s *Service) ListPullSecrets(ctx context.Context, registryID string) ([]PullSecret, error) {
+	secrets, err := s.k8sClient.Clientset().CoreV1().Secrets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: PullSecretRegistryLabel + "=" + registryID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull secrets: %w", err)
+	}
+	result := make([]PullSecret, 0, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		pullSecret, err := s.pullSecret(ctx, registryID, secret.Namespace, secret.Name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *pullSecret)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Namespace < result[j].Namespace })
+	return result, nil
+}
+
+// pullSecret describes a pull secret with the service accounts referencing it
+func (s *Service) pullSecret(ctx context.Context, registryID, namespace, name string) (*PullSecret, error) {
+	accounts, err := s.k8sClient.Clientset().CoreV1().ServiceAccounts(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service accounts: %w", err)
+	}
+	pullSecret := &PullSecret{RegistryID: registryID, Namespace: namespace, Name: name, ServiceAccounts: []string{}}
+	for _, account := range accounts.Items {
+		for _, ref := range account.ImagePullSecrets {
+			if ref.Name == name {
+				pullSecret.ServiceAccounts = append(pullSecret.ServiceAccounts, account.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(pullSecret.ServiceAccounts)
+	return pullSecret, nil
+}
+
+// DeletePullSecret detaches a registry's pull secret from the namespace's service accounts
+// and deletes it. Deployments applied later recreate it.
+func (s *Service) DeletePullSecret(ctx context.Context, registryID, namespace string) error {
+	name := pullSecretName(registryID)
+	pullSecret, err := s.pullSecret(ctx, registryID, namespace, name)
+	if err != nil {
+		return err
+	}
+	for _, account := range pullSecret.ServiceAccounts {
+		if err := s.detachPullSecret(ctx, namespace, account, name); err != nil {
+			return err
+		}
+	}
+	if err := s.k8sClient.Clientset().CoreV1().Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return fmt.Errorf("failed to delete pull secret: %w", err)
+	}
+	return nil
+}
+
+// detachPullSecret removes a pull secret from a service account
+func (s *Service) detachPullSecret(ctx context.Context, namespace, account, secretName string) error {
+	sa, err := s.k8sClient.Clientset().CoreV1().ServiceAccounts(namespace).Get(ctx, account, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get service account %s: %w", account, err)
+	}
+	refs := make([]corev1.LocalObjectReference, 0, len(sa.ImagePullSecrets))
+	for _, ref := range sa.ImagePullSecrets {
+		if ref.Name != secretName {
+			refs = append(refs, ref)
+		}
+	}
+	patch, err := json.Marshal(map[string]interface{}{"imagePullSecrets": refs})
+	if err != nil {
+		return err
+	}
+	if _, err := s.k8sClient.Clientset().CoreV1().ServiceAccounts(namespace).Patch(ctx, account, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to detach pull secret from service account %s: %w", account, err)
+	}
+	return nil
+}
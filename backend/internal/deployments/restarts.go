@@ -0,0 +1,268 @@
+package deployments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/archellir/denshimon/pkg/cron"
+)
+
+// restartSchedulerInterval is how often due restart schedules are checked
+const restartSchedulerInterval = time.Minute
+
+// maxRestartJitter bounds the random delay added to scheduled restarts
+const maxRestartJitter = 6 * time.Hour
+
+// ErrInvalidRestartSchedule means a restart schedule's cron, timezone or jitter is invalid
+var ErrInvalidRestartSchedule = errors.New("invalid restart schedule")
+
+// RestartSchedule restarts a deployment's pods on a cron schedule. Each run is delayed by a
+// random amount up to the jitter, so schedules sharing a time don't restart together.
+type RestartSchedule struct {
+	DeploymentID  string     `json:"deployment_id"`
+	Cron          string     `json:"cron"`
+	Timezone      string     `json:"timezone,omitempty"`
+	JitterSeconds int        `json:"jitter_seconds"`
+	Enabled       bool       `json:"enabled"`
+	NextRunAt     *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	UpdatedBy     string     `json:"updated_by,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// RestartSchedules lists every restart schedule and whether they're paused
+type RestartSchedules struct {
+	Paused    bool              `json:"paused"`
+	PausedBy  string            `json:"paused_by,omitempty"`
+	PausedAt  *time.Time        `json:"paused_at,omitempty"`
+	Schedules []RestartSchedule `json:"schedules"`
+}
+
+// StartRestartScheduler restarts deployments whose schedule is due until ctx is cancelled
+func (s *Service) StartRestartScheduler(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(restartSchedulerInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.runDueRestarts(ctx, time.Now())
+			}
+		}
+	}()
+}
+
+// runDueRestarts restarts the deployments whose next run has passed, unless paused. The next
+// run is stored after every attempt, failed or not, so a failing restart isn't retried every
+// minute.
+func (s *Service) runDueRestarts(ctx context.Context, now time.Time) {
+	schedules, err := s.ListRestartSchedules(ctx)
+	if err != nil {
+		slog.Error("Failed to list restart schedules", "error", err)
+		return
+	}
+	if schedules.Paused {
+		return
+	}
+
+	for _, schedule := range schedules.Schedules {
+		if !schedule.Enabled || schedule.NextRunAt == nil || schedule.NextRunAt.After(now) {
+			continue
+		}
+		next, err := nextRestart(schedule, now)
+		if err != nil {
+			slog.Error("Failed to compute next restart", "deployment", schedule.DeploymentID, "error", err)
+			continue
+		}
+
+		restartErr := s.scheduledRestart(ctx, schedule)
+		lastError := ""
+		if restartErr != nil {
+			lastError = restartErr.Error()
+			slog.Error("Scheduled restart failed", "deployment", schedule.DeploymentID, "error", restartErr)
+		}
+		if _, err := s.db.ExecContext(ctx, `UPDATE deployment_restart_schedules SET next_run_at = ?, last_run_at = ?, last_error = ?
+			WHERE deployment_id = ?`, next, now, lastError, schedule.DeploymentID); err != nil {
+			slog.Error("Failed to advance restart schedule", "deployment", schedule.DeploymentID, "error", err)
+		}
+	}
+}
+
+// scheduledRestart restarts a deployment and records it in its history
+func (s *Service) scheduledRestart(ctx context.Context, schedule RestartSchedule) error {
+	deployment, err := s.GetDeployment(ctx, schedule.DeploymentID)
+	if err != nil {
+		return err
+	}
+	metadata := map[string]interface{}{"cron": schedule.Cron, "scheduled": true}
+	if err := s.deployer.Restart(ctx, deployment.Namespace, deployment.Name); err != nil {
+		err = fmt.Errorf("failed to restart deployment: %w", err)
+		s.recordHistoryWithMetadata(schedule.DeploymentID, "scheduled_restart", "", "", deployment.Replicas, deployment.Replicas, false, err.Error(), "scheduler", metadata)
+		return err
+	}
+	s.recordHistoryWithMetadata(schedule.DeploymentID, "scheduled_restart", "", "", deployment.Replicas, deployment.Replicas, true, "", "scheduler", metadata)
+	return nil
+}
+
+// nextRestart is the schedule's next activation after t plus a random jitter
+func nextRestart(schedule RestartSchedule, t time.Time) (time.Time, error) {
+	next, err := cron.NextInZone(schedule.Cron, schedule.Timezone, t)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidRestartSchedule, err)
+	}
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("%w: %q never fires", ErrInvalidRestartSchedule, schedule.Cron)
+	}
+	if schedule.JitterSeconds > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(schedule.JitterSeconds)+1)) * time.Second)
+	}
+	return next, nil
+}
+
+// GetRestartSchedule returns a deployment's restart schedule, or sql.ErrNoRows without one
+func (s *Service) GetRestartSchedule(ctx context.Context, deploymentID string) (*RestartSchedule, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT deployment_id, cron, timezone, jitter_seconds, enabled, next_run_at,
+		last_run_at, last_error, updated_by, updated_at FROM deployment_restart_schedules WHERE deployment_id = ?`, deploymentID)
+	return scanRestartSchedule(row)
+}
+
+// SetRestartSchedule creates or replaces a deployment's restart schedule
+func (s *Service) SetRestartSchedule(ctx context.Context, schedule RestartSchedule, user string) (*RestartSchedule, error) {
+	if _, err := s.getDeploymentFromDB(schedule.DeploymentID); err != nil {
+		return nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+	if schedule.JitterSeconds < 0 || time.Duration(schedule.JitterSeconds)*time.Second > maxRestartJitter {
+		return nil, fmt.Errorf("%w: jitter must be between 0 and %s", ErrInvalidRestartSchedule, maxRestartJitter)
+	}
+	now := time.Now()
+	next, err := nextRestart(schedule, now)
+	if err != nil {
+		return nil, err
+	}
+	schedule.UpdatedBy, schedule.UpdatedAt = user, now
+	schedule.NextRunAt = nil
+	if schedule.Enabled {
+		schedule.NextRunAt = &next
+	}
+
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO deployment_restart_schedules
+		(deployment_id, cron, timezone, jitter_seconds, enabled, next_run_at, updated_by, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(deployment_id) DO UPDATE SET cron = excluded.cron, timezone = excluded.timezone,
+			jitter_seconds = excluded.jitter_seconds, enabled = excluded.enabled, next_run_at = excluded.next_run_at,
+			updated_by = excluded.updated_by, updated_at = excluded.updated_at`,
+		schedule.DeploymentID, schedule.Cron, schedule.Timezone, schedule.JitterSeconds, schedule.Enabled,
+		schedule.NextRunAt, user, now); err != nil {
+		return nil, fmt.Errorf("failed to save restart schedule: %w", err)
+	}
+	return s.GetRestartSchedule(ctx, schedule.DeploymentID)
+}
+
+// DeleteRestartSchedule removes a deployment's restart schedule
+func (s *Service) DeleteRestartSchedule(ctx context.Context, deploymentID string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM deployment_restart_schedules WHERE deployment_id = ?`, deploymentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete restart schedule: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListRestartSchedules returns every restart schedule, soonest first, and the global pause
+func (s *Service) ListRestartSchedules(ctx context.Context) (*RestartSchedules, error) {
+	result := &RestartSchedules{Schedules: []RestartSchedule{}}
+
+	var pausedBy sql.NullString
+	var pausedAt time.Time
+	err := s.db.QueryRowContext(ctx, `SELECT paused_by, paused_at FROM deployment_restart_pause WHERE id = 1`).Scan(&pausedBy, &pausedAt)
+	switch {
+	case err == nil:
+		result.Paused, result.PausedBy, result.PausedAt = true, pausedBy.String, &pausedAt
+	case err != sql.ErrNoRows:
+		return nil, fmt.Errorf("failed to read restart pause: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT deployment_id, cron, timezone, jitter_seconds, enabled, next_run_at,
+		last_run_at, last_error, updated_by, updated_at FROM deployment_restart_schedules
+		ORDER BY next_run_at IS NULL, next_run_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list restart schedules: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		schedule, err := scanRestartSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		result.Schedules = append(result.Schedules, *schedule)
+	}
+	return result, rows.Err()
+}
+
+// PauseRestartSchedules stops every scheduled restart until resumed. Runs missed while
+// paused are skipped, not caught up.
+func (s *Service) PauseRestartSchedules(ctx context.Context, user string) error {
+	_, err := s.db.ExecContext(ctx, `INSERT OR IGNORE INTO deployment_restart_pause (id, paused_by, paused_at) VALUES (1, ?, ?)`, user, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to pause restart schedules: %w", err)
+	}
+	return nil
+}
+
+// ResumeRestartSchedules lifts the pause, moving runs that fell due meanwhile to their next slot
+func (s *Service) ResumeRestartSchedules(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM deployment_restart_pause`); err != nil {
+		return fmt.Errorf("failed to resume restart schedules: %w", err)
+	}
+	schedules, err := s.ListRestartSchedules(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, schedule := range schedules.Schedules {
+		if !schedule.Enabled || schedule.NextRunAt == nil || schedule.NextRunAt.After(now) {
+			continue
+		}
+		next, err := nextRestart(schedule, now)
+		if err != nil {
+			continue
+		}
+		if _, err := s.db.ExecContext(ctx, `UPDATE deployment_restart_schedules SET next_run_at = ? WHERE deployment_id = ?`,
+			next, schedule.DeploymentID); err != nil {
+			return fmt.Errorf("failed to reschedule restart: %w", err)
+		}
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRestartSchedule(row rowScanner) (*RestartSchedule, error) {
+	var schedule RestartSchedule
+	var timezone, lastError, updatedBy sql.NullString
+	var nextRun, lastRun sql.NullTime
+	if err := row.Scan(&schedule.DeploymentID, &schedule.Cron, &timezone, &schedule.JitterSeconds, &schedule.Enabled,
+		&nextRun, &lastRun, &lastError, &updatedBy, &schedule.UpdatedAt); err != nil {
+		return nil, err
+	}
+	schedule.Timezone, schedule.LastError, schedule.UpdatedBy = timezone.String, lastError.String, updatedBy.String
+	if nextRun.Valid {
+		schedule.NextRunAt = &nextRun.Time
+	}
+	if lastRun.Valid {
+		schedule.LastRunAt = &lastRun.Time
+	}
+	return &schedule, nil
+}
@@ -0,0 +1,112 @@
+package deployments
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNextRestart(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone data unavailable:", err)
+	}
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule RestartSchedule
+		want     time.Time
+	}{
+		{"utc by default", RestartSchedule{Cron: "0 4 * * *"}, time.Date(2026, 1, 6, 4, 0, 0, 0, time.UTC)},
+		{"in the schedule's timezone", RestartSchedule{Cron: "0 4 * * *", Timezone: "Europe/Berlin"}, time.Date(2026, 1, 6, 4, 0, 0, 0, berlin)},
+		{"same day when still ahead", RestartSchedule{Cron: "0 14 * * *", Timezone: "Europe/Berlin"}, time.Date(2026, 1, 5, 14, 0, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextRestart(tt.schedule, now)
+			if err != nil {
+				t.Fatalf("nextRestart() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("nextRestart() = %s, want %s", got.UTC(), tt.want.UTC())
+			}
+		})
+	}
+
+	// Jitter only ever delays a run, and by no more than the schedule allows
+	schedule := RestartSchedule{Cron: "0 4 * * *", JitterSeconds: 1800}
+	base := time.Date(2026, 1, 6, 4, 0, 0, 0, time.UTC)
+	delayed := false
+	for i := 0; i < 200; i++ {
+		got, err := nextRestart(schedule, now)
+		if err != nil {
+			t.Fatalf("nextRestart() error = %v", err)
+		}
+		if got.Before(base) || got.After(base.Add(30*time.Minute)) {
+			t.Fatalf("nextRestart() = %s, want within 30m after %s", got, base)
+		}
+		delayed = delayed || got.After(base)
+	}
+	if !delayed {
+		t.Error("jitter never delayed a run")
+	}
+
+	for _, invalid := range []RestartSchedule{
+		{Cron: "not a cron"},
+		{Cron: "0 4 * * *", Timezone: "Mars/Olympus_Mons"},
+		{Cron: "0 0 30 2 *"}, // February 30th never comes
+	} {
+		if _, err := nextRestart(invalid, now); !errors.Is(err, ErrInvalidRestartSchedule) {
+			t.Errorf("nextRestart(%+v) error = %v, want ErrInvalidRestartSchedule", invalid, err)
+		}
+	}
+}
+
+func TestPausedRestartsSkipMissedRuns(t *testing.T) {
+	s := newApprovalService(t)
+	ctx := context.Background()
+	now := time.Now()
+	deployment := &Deployment{ID: "dep-api", Name: "api", Namespace: "default", Image: "api:1.0.0", Replicas: 1,
+		Status: DeploymentStatusRunning, CreatedAt: now, UpdatedAt: now}
+	if err := s.storeDeployment(deployment); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetRestartSchedule(ctx, RestartSchedule{DeploymentID: deployment.ID, Cron: "0 * * * *", Enabled: true}, "alice"); err != nil {
+		t.Fatalf("SetRestartSchedule() error = %v", err)
+	}
+	if err := s.PauseRestartSchedules(ctx, "alice"); err != nil {
+		t.Fatalf("PauseRestartSchedules() error = %v", err)
+	}
+
+	// A run falls due while paused
+	missed := now.Add(-2 * time.Hour)
+	if _, err := s.db.ExecContext(ctx, `UPDATE deployment_restart_schedules SET next_run_at = ? WHERE deployment_id = ?`, missed, deployment.ID); err != nil {
+		t.Fatal(err)
+	}
+	s.runDueRestarts(ctx, now)
+	schedule, err := s.GetRestartSchedule(ctx, deployment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedule.LastRunAt != nil || !schedule.NextRunAt.Equal(missed) {
+		t.Fatalf("paused schedule ran: last run %v, next run %v", schedule.LastRunAt, schedule.NextRunAt)
+	}
+
+	// Resuming moves the missed run to the next slot instead of catching it up
+	if err := s.ResumeRestartSchedules(ctx); err != nil {
+		t.Fatalf("ResumeRestartSchedules() error = %v", err)
+	}
+	schedules, err := s.ListRestartSchedules(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedules.Paused || len(schedules.Schedules) != 1 {
+		t.Fatalf("ListRestartSchedules() = %+v", schedules)
+	}
+	schedule = &schedules.Schedules[0]
+	if schedule.LastRunAt != nil || schedule.NextRunAt == nil || !schedule.NextRunAt.After(now) {
+		t.Errorf("missed run not skipped: last run %v, next run %v", schedule.LastRunAt, schedule.NextRunAt)
+	}
+}
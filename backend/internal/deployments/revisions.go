@@ -0,0 +1,310 @@
+package deployments
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	revisionAnnotation    = "deployment.kubernetes.io/revision"
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+)
+
+// Revisions lists the rollout revisions of a deployment, newest first
+func (d *KubernetesDeployer) Revisions(ctx context.Context, namespace, name string) ([]Revision, error) {
+	deployment, replicaSets, err := d.replicaSetsFor(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	current := deployment.Annotations[revisionAnnotation]
+	revisions := make([]Revision, 0, len(replicaSets))
+	for _, rs := range replicaSets {
+		revision := revisionFromReplicaSet(rs)
+		revision.Current = rs.Annotations[revisionAnnotation] == current
+		revisions = append(revisions, revision)
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+// Rollback restores the pod template of the given revision, like kubectl rollout undo --to-revision
+func (d *KubernetesDeployer) Rollback(ctx context.Context, namespace, name string, revision int64) (*Revision, error) {
+	deployment, replicaSets, err := d.replicaSetsFor(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *appsv1.ReplicaSet
+	for i := range replicaSets {
+		if revisionNumber(&replicaSets[i]) == revision {
+			target = &replicaSets[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("revision %d not found for deployment %s", revision, name)
+	}
+	if deployment.Annotations[revisionAnnotation] == target.Annotations[revisionAnnotation] {
+		return nil, fmt.Errorf("revision %d is already the current revision", revision)
+	}
+
+	template := target.Spec.Template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	deployment.Spec.Template = *template
+
+	if deployment.Annotations == nil {
+		deployment.Annotations = make(map[string]string)
+	}
+	deployment.Annotations[changeCauseAnnotation] = fmt.Sprintf("rollback to revision %d", revision)
+
+	if _, err := d.k8sClient.Clientset().AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to roll back deployment: %w", err)
+	}
+
+	result := revisionFromReplicaSet(*target)
+	return &result, nil
+}
+
+// replicaSetsFor returns a deployment along with the ReplicaSets it owns
+func (d *KubernetesDeployer) replicaSetsFor(ctx context.Context, namespace, name string) (*appsv1.Deployment, []appsv1.ReplicaSet, error) {
+	clientset := d.k8sClient.Clientset()
+
+	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid deployment selector: %w", err)
+	}
+
+	list, err := clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list replica sets: %w", err)
+	}
+
+	var owned []appsv1.ReplicaSet
+	for _, rs := range list.Items {
+		if owner := metav1.GetControllerOf(&rs); owner != nil && owner.UID == deployment.UID {
+			owned = append(owned, rs)
+		}
+	}
+	return deployment, owned, nil
+}
+
+func revisionNumber(rs *appsv1.ReplicaSet) int64 {
+	revision, _ := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	return revision
+}
+
+func revisionFromReplicaSet(rs appsv1.ReplicaSet) Revision {
+	revision := Revision{
+		Revision:    revisionNumber(&rs),
+		ReplicaSet:  rs.Name,
+		Images:      make(map[string]string),
+		Environment: make(map[string]map[string]string),
+		ChangeCause: rs.Annotations[changeCauseAnnotation],
+		CreatedAt:   rs.CreationTimestamp.Time,
+	}
+	if rs.Spec.Replicas != nil {
+		revision.Replicas = *rs.Spec.Replicas
+	}
+
+	for _, container := range rs.Spec.Template.Spec.Containers {
+		revision.Images[container.Name] = container.Image
+		env := make(map[string]string, len(container.Env))
+		for _, envVar := range container.Env {
+			env[envVar.Name] = envValue(envVar)
+		}
+		revision.Environment[container.Name] = env
+	}
+	return revision
+}
+
+// envValue renders an env var for comparison; references are described rather than resolved
+func envValue(envVar corev1.EnvVar) string {
+	source := envVar.ValueFrom
+	switch {
+	case source == nil:
+		return envVar.Value
+	case source.SecretKeyRef != nil:
+		return fmt.Sprintf("secret:%s/%s", source.SecretKeyRef.Name, source.SecretKeyRef.Key)
+	case source.ConfigMapKeyRef != nil:
+		return fmt.Sprintf("configmap:%s/%s", source.ConfigMapKeyRef.Name, source.ConfigMapKeyRef.Key)
+	case source.FieldRef != nil:
+		return "field:" + source.FieldRef.FieldPath
+	case source.ResourceFieldRef != nil:
+		return "resource:" + source.ResourceFieldRef.Resource
+	default:
+		return ""
+	}
+}
+
+// diffRevisions compares container images and environment variables between two revisions
+func diffRevisions(from, to Revision) RevisionDiff {
+	diff := RevisionDiff{
+		FromRevision: from.Revision,
+		ToRevision:   to.Revision,
+		Images:       []RevisionChange{},
+		Environment:  []RevisionChange{},
+	}
+
+	for _, container := range unionKeys(from.Images, to.Images) {
+		if change, ok := compareValues(container, "", from.Images, to.Images, container); ok {
+			diff.Images = append(diff.Images, change)
+		}
+	}
+
+	containers := make(map[string]bool)
+	for name := range from.Environment {
+		containers[name] = true
+	}
+	for name := range to.Environment {
+		containers[name] = true
+	}
+	names := make([]string, 0, len(containers))
+	for name := range containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, container := range names {
+		oldEnv, newEnv := from.Environment[container], to.Environment[container]
+		for _, key := range unionKeys(oldEnv, newEnv) {
+			if change, ok := compareValues(container, key, oldEnv, newEnv, key); ok {
+				diff.Environment = append(diff.Environment, change)
+			}
+		}
+	}
+
+	return diff
+}
+
+func compareValues(container, name string, oldValues, newValues map[string]string, key string) (RevisionChange, bool) {
+	oldValue, inOld := oldValues[key]
+	newValue, inNew := newValues[key]
+	change := RevisionChange{Container: container, Name: name, Old: oldValue, New: newValue}
+
+	switch {
+	case !inOld:
+		change.Op = "add"
+	case !inNew:
+		change.Op = "remove"
+	case oldValue != newValue:
+		change.Op = "change"
+	default:
+		return change, false
+	}
+	return change, true
+}
+
+func unionKeys(a, b map[string]string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	keys := make([]string, 0, len(a)+len(b))
+	for _, m := range []map[string]string{a, b} {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ListRevisions returns the Kubernetes rollout revisions of a deployment
+func (s *Service) ListRevisions(ctx context.Context, id string) ([]Revision, error) {
+	deployment, err := s.GetDeployment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.deployer.Revisions(ctx, deployment.Namespace, deployment.Name)
+}
+
+// GetRevisionDiff returns the image and environment diff between two revisions.
+// A zero "to" revision compares against the current revision.
+func (s *Service) GetRevisionDiff(ctx context.Context, id string, from, to int64) (*RevisionDiff, error) {
+	revisions, err := s.ListRevisions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var fromRevision, toRevision *Revision
+	for i := range revisions {
+		if revisions[i].Revision == from {
+			fromRevision = &revisions[i]
+		}
+		if revisions[i].Revision == to || (to == 0 && revisions[i].Current) {
+			toRevision = &revisions[i]
+		}
+	}
+	if fromRevision == nil {
+		return nil, fmt.Errorf("revision %d not found", from)
+	}
+	if toRevision == nil {
+		return nil, fmt.Errorf("revision %d not found", to)
+	}
+
+	diff := diffRevisions(*fromRevision, *toRevision)
+	return &diff, nil
+}
+
+// RollbackDeployment rolls a deployment back to a previous Kubernetes revision and records it in the history
+func (s *Service) RollbackDeployment(ctx context.Context, id string, revision int64, user string) (*Revision, error) {
+	deployment, err := s.GetDeployment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	oldImage := deployment.Image
+	var fromRevision int64
+	if revisions, err := s.deployer.Revisions(ctx, deployment.Namespace, deployment.Name); err == nil {
+		for _, r := range revisions {
+			if r.Current {
+				fromRevision = r.Revision
+			}
+		}
+	}
+	metadata := map[string]interface{}{
+		"from_revision": fromRevision,
+		"to_revision":   revision,
+	}
+
+	target, err := s.deployer.Rollback(ctx, deployment.Namespace, deployment.Name, revision)
+	if err != nil {
+		s.recordHistoryWithMetadata(id, "rollback", oldImage, "", deployment.Replicas, deployment.Replicas, false, err.Error(), user, metadata)
+		return nil, err
+	}
+
+	// Keep the stored image in sync with the primary container of the restored revision
+	if image, ok := target.Images[deployment.Name]; ok {
+		deployment.Image = image
+	} else if len(target.Images) == 1 {
+		for _, image := range target.Images {
+			deployment.Image = image
+		}
+	}
+	deployment.Status = DeploymentStatusUpdating
+	deployment.UpdatedAt = time.Now()
+
+	if err := s.updateDeploymentInDB(deployment); err != nil {
+		return nil, fmt.Errorf("failed to update deployment in database: %w", err)
+	}
+
+	s.recordHistoryWithMetadata(id, "rollback", oldImage, deployment.Image, deployment.Replicas, deployment.Replicas, true, "", user, metadata)
+
+	return target, nil
+}
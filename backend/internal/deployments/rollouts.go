@@ -0,0 +1,506 @@
+package deployments
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/archellir/denshimon/internal/websocket"
+	"github.com/google/uuid"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	trackLabel   = "track"
+	trackStable  = "stable"
+	trackCanary  = "canary"
+	trackPreview = "preview"
+
+	rolloutPollInterval = 5 * time.Second
+	rolloutTimeout      = 10 * time.Minute
+)
+
+func isProgressiveStrategy(strategyType string) bool {
+	return strategyType == StrategyCanary || strategyType == StrategyBlueGreen
+}
+
+// secondaryName returns the name and track of the secondary deployment for a progressive strategy
+func secondaryName(deployment Deployment) (string, string) {
+	if deployment.Strategy.Type == StrategyBlueGreen {
+		return deployment.Name + "-" + trackPreview, trackPreview
+	}
+	return deployment.Name + "-" + trackCanary, trackCanary
+}
+
+// ensureService creates the service fronting a progressively delivered deployment.
+// Canary services select every track so traffic splits by replica count; blue-green services select one track.
+func (d *KubernetesDeployer) ensureService(ctx context.Context, deployment Deployment) error {
+	_, err := d.k8sClient.Clientset().CoreV1().Services(deployment.Namespace).Create(ctx, progressiveService(deployment), metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create service: %w", err)
+	}
+	return nil
+}
+
+// progressiveService builds the service that routes the traffic of a progressive deployment
+func progressiveService(deployment Deployment) *corev1.Service {
+	selector := map[string]string{
+		"app":        deployment.Name,
+		"managed-by": "denshimon",
+	}
+	if deployment.Strategy.Type == StrategyBlueGreen {
+		selector[trackLabel] = trackStable
+	}
+
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deployment.Name,
+			Namespace: deployment.Namespace,
+			Labels: map[string]string{
+				"app":        deployment.Name,
+				"managed-by": "denshimon",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: selector,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "http",
+					Port:       deployment.Strategy.ServicePort,
+					TargetPort: intstr.FromInt32(deployment.Strategy.ServicePort),
+				},
+			},
+		},
+	}
+}
+
+// SwitchTraffic points a blue-green service at the given track
+func (d *KubernetesDeployer) SwitchTraffic(ctx context.Context, namespace, name, track string) error {
+	clientset := d.k8sClient.Clientset()
+
+	service, err := clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get service: %w", err)
+	}
+	if service.Spec.Selector == nil {
+		service.Spec.Selector = make(map[string]string)
+	}
+	service.Spec.Selector[trackLabel] = track
+
+	if _, err := clientset.CoreV1().Services(namespace).Update(ctx, service, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to switch service selector: %w", err)
+	}
+	return nil
+}
+
+// DeploySecondary creates the canary or preview deployment running the candidate spec
+func (d *KubernetesDeployer) DeploySecondary(ctx context.Context, candidate Deployment) (string, error) {
+	secretName, err := d.imagePullSecret(ctx, candidate)
+	if err != nil {
+		return "", err
+	}
+
+	name, track := secondaryName(candidate)
+	spec := d.buildDeploymentSpec(candidate, secretName)
+	if err := d.injectSecrets(ctx, candidate, &spec.Spec.Template.Spec.Containers[0]); err != nil {
+		return "", err
+	}
+	spec.Name = name
+	for _, labels := range []map[string]string{spec.Labels, spec.Spec.Selector.MatchLabels, spec.Spec.Template.Labels} {
+		labels[trackLabel] = track
+	}
+
+	if candidate.Strategy.Type == StrategyCanary {
+		replicas := candidate.Strategy.CanaryReplicas
+		if replicas <= 0 {
+			replicas = 1
+		}
+		spec.Spec.Replicas = &replicas
+	}
+
+	deployments := d.k8sClient.Clientset().AppsV1().Deployments(candidate.Namespace)
+	if _, err := deployments.Create(ctx, spec, metav1.CreateOptions{}); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return "", fmt.Errorf("failed to create %s deployment: %w", track, err)
+		}
+		// Replace a leftover secondary from an earlier rollout
+		existing, err := deployments.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get %s deployment: %w", track, err)
+		}
+		existing.Spec.Replicas = spec.Spec.Replicas
+		existing.Spec.Template = spec.Spec.Template
+		if _, err := deployments.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+			return "", fmt.Errorf("failed to update %s deployment: %w", track, err)
+		}
+	}
+
+	return name, nil
+}
+
+// WaitForReady polls a deployment until all desired replicas are updated and available
+func (d *KubernetesDeployer) WaitForReady(ctx context.Context, namespace, name string, progress func(ready, desired int32)) error {
+	ticker := time.NewTicker(rolloutPollInterval)
+	defer ticker.Stop()
+
+	for {
+		deployment, err := d.k8sClient.Clientset().AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get deployment: %w", err)
+		}
+
+		desired := int32(1)
+		if deployment.Spec.Replicas != nil {
+			desired = *deployment.Spec.Replicas
+		}
+		progress(deployment.Status.ReadyReplicas, desired)
+
+		if deploymentComplete(deployment, desired) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for deployment %s: %w", name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func deploymentComplete(deployment *appsv1.Deployment, desired int32) bool {
+	status := deployment.Status
+	return status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas == desired &&
+		status.ReadyReplicas == desired &&
+		status.AvailableReplicas == desired
+}
+
+// SetHub enables publishing rollout progress on the deployments WebSocket channel
+func (s *Service) SetHub(hub *websocket.Hub) {
+	s.hub = hub
+}
+
+// startRollout deploys the candidate next to the stable deployment instead of updating it in place
+func (s *Service) startRollout(ctx context.Context, stable, candidate Deployment, user string) (*Rollout, error) {
+	if active, err := s.GetActiveRollout(ctx, stable.ID); err == nil && active != nil {
+		return nil, fmt.Errorf("a %s rollout is already in progress", active.Strategy)
+	}
+
+	secondary, err := s.deployer.DeploySecondary(ctx, candidate)
+	if err != nil {
+		s.recordHistory(stable.ID, "rollout", stable.Image, candidate.Image, stable.Replicas, candidate.Replicas, false, err.Error(), user)
+		return nil, err
+	}
+
+	now := time.Now()
+	rollout := &Rollout{
+		ID:             uuid.New().String(),
+		DeploymentID:   stable.ID,
+		Strategy:       candidate.Strategy.Type,
+		Phase:          RolloutPhaseProgressing,
+		Secondary:      secondary,
+		StableImage:    stable.Image,
+		CandidateImage: candidate.Image,
+		User:           user,
+		StartedAt:      now,
+		UpdatedAt:      now,
+		candidate:      candidate,
+	}
+	if err := s.saveRollout(rollout); err != nil {
+		return nil, fmt.Errorf("failed to store rollout: %w", err)
+	}
+
+	s.recordHistory(stable.ID, "rollout", stable.Image, candidate.Image, stable.Replicas, candidate.Replicas, true, "", user)
+	s.publishRollout(rollout)
+
+	go s.watchSecondary(rollout)
+
+	return rollout, nil
+}
+
+// watchSecondary tracks the secondary deployment until it is ready to be promoted
+func (s *Service) watchSecondary(rollout *Rollout) {
+	ctx, cancel := context.WithTimeout(context.Background(), rolloutTimeout)
+	defer cancel()
+
+	err := s.deployer.WaitForReady(ctx, rollout.candidate.Namespace, rollout.Secondary, func(ready, desired int32) {
+		if ready != rollout.Ready || desired != rollout.Desired {
+			rollout.Ready, rollout.Desired = ready, desired
+			s.updateRolloutProgress(rollout)
+		}
+	})
+
+	// The rollout may have been aborted while we were waiting
+	if current, getErr := s.getRollout(rollout.ID); getErr != nil || current.Phase != RolloutPhaseProgressing {
+		return
+	}
+
+	if err != nil {
+		s.updateRollout(rollout, RolloutPhaseFailed, err.Error())
+		return
+	}
+	s.updateRollout(rollout, RolloutPhaseReady, fmt.Sprintf("%s ready for promotion", rollout.Secondary))
+}
+
+// PromoteRollout shifts all traffic to the candidate and removes the secondary deployment.
+// Promotion runs in the background; progress is published on the deployments channel.
+func (s *Service) PromoteRollout(ctx context.Context, deploymentID, user string) (*Rollout, error) {
+	rollout, err := s.GetActiveRollout(ctx, deploymentID)
+	if err != nil {
+		return nil, err
+	}
+	if rollout == nil {
+		return nil, fmt.Errorf("no rollout in progress")
+	}
+	if rollout.Phase != RolloutPhaseReady {
+		return nil, fmt.Errorf("rollout is %s, only ready rollouts can be promoted", rollout.Phase)
+	}
+
+	rollout.User = user
+	s.updateRollout(rollout, RolloutPhasePromoting, "")
+
+	go s.promote(rollout)
+
+	return rollout, nil
+}
+
+func (s *Service) promote(rollout *Rollout) {
+	ctx, cancel := context.WithTimeout(context.Background(), rolloutTimeout)
+	defer cancel()
+
+	candidate := rollout.candidate
+	blueGreen := rollout.Strategy == StrategyBlueGreen
+
+	fail := func(err error) {
+		// Never leave a blue-green service pointing at a deployment that is about to be removed
+		if blueGreen {
+			s.deployer.SwitchTraffic(ctx, candidate.Namespace, candidate.Name, trackStable)
+		}
+		s.updateRollout(rollout, RolloutPhaseFailed, err.Error())
+		s.recordHistory(rollout.DeploymentID, "promote", rollout.StableImage, rollout.CandidateImage, candidate.Replicas, candidate.Replicas, false, err.Error(), rollout.User)
+	}
+
+	// Blue-green: cut traffic over to the preview while the primary is updated in place
+	if blueGreen {
+		if err := s.deployer.SwitchTraffic(ctx, candidate.Namespace, candidate.Name, trackPreview); err != nil {
+			fail(err)
+			return
+		}
+		s.updateRollout(rollout, RolloutPhasePromoting, "traffic switched to preview")
+	}
+
+	if err := s.deployer.Update(ctx, candidate); err != nil {
+		fail(err)
+		return
+	}
+	rollout.Message = "updating primary deployment"
+	err := s.deployer.WaitForReady(ctx, candidate.Namespace, candidate.Name, func(ready, desired int32) {
+		if ready != rollout.Ready || desired != rollout.Desired {
+			rollout.Ready, rollout.Desired = ready, desired
+			s.updateRolloutProgress(rollout)
+		}
+	})
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	if blueGreen {
+		if err := s.deployer.SwitchTraffic(ctx, candidate.Namespace, candidate.Name, trackStable); err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	if err := s.deployer.k8sClient.Clientset().AppsV1().Deployments(candidate.Namespace).Delete(ctx, rollout.Secondary, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		slog.Warn("Failed to remove secondary deployment", "deployment", rollout.Secondary, "error", err)
+	}
+
+	deployment, err := s.GetDeployment(ctx, rollout.DeploymentID)
+	if err == nil {
+		deployment.Image = candidate.Image
+		deployment.Replicas = candidate.Replicas
+		deployment.Resources = candidate.Resources
+		deployment.Environment = candidate.Environment
+		deployment.Status = DeploymentStatusRunning
+		deployment.UpdatedAt = time.Now()
+		s.updateDeploymentInDB(deployment)
+	}
+
+	s.updateRollout(rollout, RolloutPhasePromoted, "")
+	s.recordHistory(rollout.DeploymentID, "promote", rollout.StableImage, rollout.CandidateImage, candidate.Replicas, candidate.Replicas, true, "", rollout.User)
+}
+
+// AbortRollout removes the secondary deployment, leaving the stable deployment serving all traffic
+func (s *Service) AbortRollout(ctx context.Context, deploymentID, user string) (*Rollout, error) {
+	rollout, err := s.GetActiveRollout(ctx, deploymentID)
+	if err != nil {
+		return nil, err
+	}
+	if rollout == nil {
+		return nil, fmt.Errorf("no rollout in progress")
+	}
+	if rollout.Phase == RolloutPhasePromoting {
+		return nil, fmt.Errorf("rollout is already being promoted")
+	}
+
+	candidate := rollout.candidate
+	if rollout.Strategy == StrategyBlueGreen {
+		if err := s.deployer.SwitchTraffic(ctx, candidate.Namespace, candidate.Name, trackStable); err != nil && !apierrors.IsNotFound(err) {
+			slog.Warn("Failed to reset service selector", "service", candidate.Name, "error", err)
+		}
+	}
+	err = s.deployer.k8sClient.Clientset().AppsV1().Deployments(candidate.Namespace).Delete(ctx, rollout.Secondary, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to delete %s: %w", rollout.Secondary, err)
+	}
+
+	rollout.User = user
+	s.updateRollout(rollout, RolloutPhaseAborted, "")
+	s.recordHistory(deploymentID, "abort", rollout.StableImage, rollout.CandidateImage, candidate.Replicas, candidate.Replicas, true, "", user)
+
+	return rollout, nil
+}
+
+// GetActiveRollout returns the in-flight rollout for a deployment, or nil if there is none
+func (s *Service) GetActiveRollout(ctx context.Context, deploymentID string) (*Rollout, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, deployment_id, strategy, phase, secondary, stable_image, candidate_image,
+		       desired, ready, message, user, started_at, updated_at, completed_at, candidate
+		FROM deployment_rollouts
+		WHERE deployment_id = ? AND phase IN (?, ?, ?)
+		ORDER BY started_at DESC
+		LIMIT 1
+	`, deploymentID, RolloutPhaseProgressing, RolloutPhaseReady, RolloutPhasePromoting)
+
+	rollout, err := scanRollout(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rollout, err
+}
+
+// GetLatestRollout returns the most recent rollout for a deployment
+func (s *Service) GetLatestRollout(ctx context.Context, deploymentID string) (*Rollout, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, deployment_id, strategy, phase, secondary, stable_image, candidate_image,
+		       desired, ready, message, user, started_at, updated_at, completed_at, candidate
+		FROM deployment_rollouts
+		WHERE deployment_id = ?
+		ORDER BY started_at DESC
+		LIMIT 1
+	`, deploymentID)
+
+	rollout, err := scanRollout(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no rollouts found for deployment %s", deploymentID)
+	}
+	return rollout, err
+}
+
+func (s *Service) getRollout(id string) (*Rollout, error) {
+	row := s.db.QueryRow(`
+		SELECT id, deployment_id, strategy, phase, secondary, stable_image, candidate_image,
+		       desired, ready, message, user, started_at, updated_at, completed_at, candidate
+		FROM deployment_rollouts
+		WHERE id = ?
+	`, id)
+	return scanRollout(row)
+}
+
+func (s *Service) saveRollout(rollout *Rollout) error {
+	candidate, _ := json.Marshal(rollout.candidate)
+
+	_, err := s.db.Exec(`
+		INSERT INTO deployment_rollouts (
+			id, deployment_id, strategy, phase, secondary, stable_image, candidate_image,
+			desired, ready, message, user, started_at, updated_at, completed_at, candidate
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		rollout.ID, rollout.DeploymentID, rollout.Strategy, rollout.Phase, rollout.Secondary,
+		rollout.StableImage, rollout.CandidateImage, rollout.Desired, rollout.Ready, rollout.Message,
+		rollout.User, rollout.StartedAt, rollout.UpdatedAt, rollout.CompletedAt, string(candidate),
+	)
+	return err
+}
+
+// updateRollout persists a phase change and publishes it
+func (s *Service) updateRollout(rollout *Rollout, phase RolloutPhase, message string) {
+	now := time.Now()
+	rollout.Phase = phase
+	rollout.Message = message
+	rollout.UpdatedAt = now
+	if phase == RolloutPhasePromoted || phase == RolloutPhaseAborted || phase == RolloutPhaseFailed {
+		rollout.CompletedAt = &now
+	}
+
+	_, err := s.db.Exec(`
+		UPDATE deployment_rollouts SET
+			phase = ?, desired = ?, ready = ?, message = ?, user = ?, updated_at = ?, completed_at = ?
+		WHERE id = ?
+	`, rollout.Phase, rollout.Desired, rollout.Ready, rollout.Message, rollout.User, rollout.UpdatedAt, rollout.CompletedAt, rollout.ID)
+	if err != nil {
+		slog.Error("Failed to update rollout", "rollout", rollout.ID, "error", err)
+	}
+
+	s.publishRollout(rollout)
+}
+
+// updateRolloutProgress records replica progress unless the rollout changed phase in the meantime
+func (s *Service) updateRolloutProgress(rollout *Rollout) {
+	rollout.UpdatedAt = time.Now()
+
+	result, err := s.db.Exec(`
+		UPDATE deployment_rollouts SET desired = ?, ready = ?, message = ?, updated_at = ?
+		WHERE id = ? AND phase = ?
+	`, rollout.Desired, rollout.Ready, rollout.Message, rollout.UpdatedAt, rollout.ID, rollout.Phase)
+	if err != nil {
+		slog.Error("Failed to update rollout progress", "rollout", rollout.ID, "error", err)
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		s.publishRollout(rollout)
+	}
+}
+
+func (s *Service) publishRollout(rollout *Rollout) {
+	if s.hub == nil {
+		return
+	}
+	s.hub.Broadcast(websocket.MessageTypeDeployments, map[string]interface{}{
+		"rollout":   rollout,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+func scanRollout(row interface{ Scan(...interface{}) error }) (*Rollout, error) {
+	var rollout Rollout
+	var message, user, candidate sql.NullString
+	var completedAt sql.NullTime
+
+	err := row.Scan(
+		&rollout.ID, &rollout.DeploymentID, &rollout.Strategy, &rollout.Phase, &rollout.Secondary,
+		&rollout.StableImage, &rollout.CandidateImage, &rollout.Desired, &rollout.Ready,
+		&message, &user, &rollout.StartedAt, &rollout.UpdatedAt, &completedAt, &candidate,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rollout.Message = message.String
+	rollout.User = user.String
+	if completedAt.Valid {
+		rollout.CompletedAt = &completedAt.Time
+	}
+	if candidate.Valid {
+		json.Unmarshal([]byte(candidate.String), &rollout.candidate)
+	}
+	return &rollout, nil
+}
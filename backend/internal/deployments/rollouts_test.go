@@ -0,0 +1,183 @@
+package deployments
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newRolloutService returns a service backed by a fake cluster running the stable "api"
+// deployment, its service and a secondary deployment for the strategy
+func newRolloutService(t *testing.T, strategy string) (*Service, *fake.Clientset, *Rollout) {
+	t.Helper()
+	s := newApprovalService(t)
+	now := time.Now()
+	stable := &Deployment{ID: "dep-api", Name: "api", Namespace: "default", Image: "api:1.0.0", Replicas: 2,
+		Strategy: DeploymentStrategy{Type: strategy, ServicePort: 8080}, Status: DeploymentStatusRunning,
+		CreatedAt: now, UpdatedAt: now, AppliedAt: &now}
+	if err := s.storeDeployment(stable); err != nil {
+		t.Fatal(err)
+	}
+
+	candidate := *stable
+	candidate.Image = "api:2.0.0"
+	secondary, track := secondaryName(candidate)
+	clientset := fake.NewSimpleClientset(
+		progressiveService(*stable),
+		readyDeployment("api", stable.Image, 2, nil),
+		readyDeployment(secondary, candidate.Image, 1, map[string]string{trackLabel: track}),
+	)
+	client := k8s.NewClientForClientset(clientset)
+	s.k8sClient = client
+	s.deployer = NewKubernetesDeployer(client, nil)
+
+	rollout := &Rollout{
+		ID:             "rollout-1",
+		DeploymentID:   stable.ID,
+		Strategy:       strategy,
+		Phase:          RolloutPhaseReady,
+		Secondary:      secondary,
+		StableImage:    stable.Image,
+		CandidateImage: candidate.Image,
+		StartedAt:      now,
+		UpdatedAt:      now,
+		candidate:      candidate,
+	}
+	if err := s.saveRollout(rollout); err != nil {
+		t.Fatal(err)
+	}
+	clientset.ClearActions()
+	return s, clientset, rollout
+}
+
+// readyDeployment is a deployment whose replicas are all updated and available
+func readyDeployment(name, image string, replicas int32, labels map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "api", Image: image}}}},
+		},
+		Status: appsv1.DeploymentStatus{UpdatedReplicas: replicas, ReadyReplicas: replicas, AvailableReplicas: replicas},
+	}
+}
+
+// selectorUpdates lists the track the service selected after each of its updates
+func selectorUpdates(clientset *fake.Clientset) []string {
+	var tracks []string
+	for _, action := range clientset.Actions() {
+		update, ok := action.(k8stesting.UpdateAction)
+		if !ok || action.GetResource().Resource != "services" {
+			continue
+		}
+		tracks = append(tracks, update.GetObject().(*corev1.Service).Spec.Selector[trackLabel])
+	}
+	return tracks
+}
+
+func TestPromoteBlueGreenSwitchesSelector(t *testing.T) {
+	s, clientset, rollout := newRolloutService(t, StrategyBlueGreen)
+	ctx := context.Background()
+	rollout.Phase = RolloutPhasePromoting
+
+	s.promote(rollout)
+
+	// Traffic goes to the preview while the primary is updated, then back to the primary
+	tracks := selectorUpdates(clientset)
+	if len(tracks) != 2 || tracks[0] != trackPreview || tracks[1] != trackStable {
+		t.Fatalf("service selector switched to %v, want [%s %s]", tracks, trackPreview, trackStable)
+	}
+	primary, err := clientset.AppsV1().Deployments("default").Get(ctx, "api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := primary.Spec.Template.Spec.Containers[0].Image; image != "api:2.0.0" {
+		t.Errorf("primary runs %s, want api:2.0.0", image)
+	}
+	if _, err := clientset.AppsV1().Deployments("default").Get(ctx, rollout.Secondary, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("preview deployment not removed: %v", err)
+	}
+
+	stored, err := s.getRollout(rollout.ID)
+	if err != nil || stored.Phase != RolloutPhasePromoted {
+		t.Fatalf("rollout = %+v, %v, want promoted", stored, err)
+	}
+	deployment, err := s.getDeploymentFromDB(rollout.DeploymentID)
+	if err != nil || deployment.Image != "api:2.0.0" {
+		t.Errorf("stored deployment = %+v, %v, want the candidate image", deployment, err)
+	}
+}
+
+func TestPromoteBlueGreenFailureKeepsStableSelector(t *testing.T) {
+	s, clientset, rollout := newRolloutService(t, StrategyBlueGreen)
+	ctx := context.Background()
+	rollout.Phase = RolloutPhasePromoting
+	if err := clientset.AppsV1().Deployments("default").Delete(ctx, "api", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	clientset.ClearActions()
+
+	s.promote(rollout)
+
+	tracks := selectorUpdates(clientset)
+	if len(tracks) == 0 || tracks[len(tracks)-1] != trackStable {
+		t.Errorf("service selector switched to %v, want it back on %s", tracks, trackStable)
+	}
+	if _, err := clientset.AppsV1().Deployments("default").Get(ctx, rollout.Secondary, metav1.GetOptions{}); err != nil {
+		t.Errorf("preview deployment removed after a failed promotion: %v", err)
+	}
+	if stored, err := s.getRollout(rollout.ID); err != nil || stored.Phase != RolloutPhaseFailed {
+		t.Errorf("rollout = %+v, %v, want failed", stored, err)
+	}
+}
+
+func TestAbortRolloutRemovesSecondary(t *testing.T) {
+	for _, strategy := range []string{StrategyCanary, StrategyBlueGreen} {
+		t.Run(strategy, func(t *testing.T) {
+			s, clientset, rollout := newRolloutService(t, strategy)
+			ctx := context.Background()
+			if strategy == StrategyBlueGreen {
+				// Left on the preview, e.g. by a promotion cut short
+				if err := s.deployer.SwitchTraffic(ctx, "default", "api", trackPreview); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			aborted, err := s.AbortRollout(ctx, rollout.DeploymentID, "alice")
+			if err != nil {
+				t.Fatalf("AbortRollout() error = %v", err)
+			}
+			if aborted.Phase != RolloutPhaseAborted || aborted.User != "alice" {
+				t.Errorf("AbortRollout() = %+v", aborted)
+			}
+			if _, err := clientset.AppsV1().Deployments("default").Get(ctx, rollout.Secondary, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+				t.Errorf("secondary deployment not removed: %v", err)
+			}
+
+			// The stable deployment keeps serving, untouched
+			primary, err := clientset.AppsV1().Deployments("default").Get(ctx, "api", metav1.GetOptions{})
+			if err != nil || primary.Spec.Template.Spec.Containers[0].Image != "api:1.0.0" {
+				t.Errorf("stable deployment changed: %v", err)
+			}
+			service, err := clientset.CoreV1().Services("default").Get(ctx, "api", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if track, ok := service.Spec.Selector[trackLabel]; strategy == StrategyBlueGreen && track != trackStable || strategy == StrategyCanary && ok {
+				t.Errorf("service selector = %v after abort", service.Spec.Selector)
+			}
+
+			if active, err := s.GetActiveRollout(ctx, rollout.DeploymentID); err != nil || active != nil {
+				t.Errorf("GetActiveRollout() = %+v, %v, want none", active, err)
+			}
+		})
+	}
+}
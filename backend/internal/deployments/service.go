@@ -3,19 +3,34 @@ package deployments
 import (
 	"context"
 	"database/sql"
+	"embed"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/archellir/denshimon/internal/bus"
+	"github.com/archellir/denshimon/internal/database/migrate"
 	"github.com/archellir/denshimon/internal/gitops"
 	"github.com/archellir/denshimon/internal/k8s"
 	"github.com/archellir/denshimon/internal/providers"
+	"github.com/archellir/denshimon/internal/providers/certificates"
+	"github.com/archellir/denshimon/internal/secretbox"
+	"github.com/archellir/denshimon/internal/secretrefs"
+	"github.com/archellir/denshimon/internal/websocket"
 	"github.com/google/uuid"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations is the schema of the deployment tables
+var Migrations = migrate.NewSource("deployments", migrationFiles, "migrations")
+
 // Service manages deployments and integrates with Kubernetes and registries
 type Service struct {
 	k8sClient       *k8s.Client
@@ -25,6 +40,14 @@ type Service struct {
 	scaler          *KubernetesScaler
 	gitopsService   *gitops.Service
 	syncEngine      *gitops.SyncEngine
+	hub             *websocket.Hub
+	box             *secretbox.Box        // encrypts registry configs at rest
+	certificates    *certificates.Manager // monitors the TLS hosts of ingresses, optional
+
+	gatesMu sync.Mutex
+	gates   map[string]activeGate // running health gates by deployment ID
+
+	bus *bus.Bus // applies and rollbacks are published on it, optional
 }
 
 // NewService creates a new deployment service
@@ -46,81 +69,52 @@ func NewService(k8sClient *k8s.Client, registryManager *providers.RegistryManage
 		syncEngine:      syncEngine,
 	}
 
-	// Initialize database tables
-	service.initDB()
+	if err := service.initDB(); err != nil {
+		slog.Error("Failed to migrate deployment tables", "error", err)
+	}
 
 	return service
 }
 
-// initDB creates necessary database tables
+// SetSecretResolver sets the external secret stores environment references such as
+// vault:secret/data/api#password are resolved from when deployments are applied
+func (s *Service) SetSecretResolver(resolver *secretrefs.Resolver) {
+	s.deployer.secrets = resolver
+}
+
+// SetEncryption sets the box registry configs are encrypted with. Registries stored
+// before encryption was enabled are still read.
+func (s *Service) SetEncryption(box *secretbox.Box) {
+	s.box = box
+}
+
+// SetLintPolicy sets the policy manifests of new deployments are checked against
+// before they are committed
+func (s *Service) SetLintPolicy(policy gitops.LintPolicy) {
+	s.gitopsService.SetLintPolicy(policy)
+}
+
+// SetPolicyEvaluator adds external policies, such as Rego, to the manifest policy
+func (s *Service) SetPolicyEvaluator(evaluator gitops.PolicyEvaluator) {
+	s.gitopsService.SetPolicyEvaluator(evaluator)
+}
+
+// SetBus sets the event bus applied and rolled back deployments are published on
+func (s *Service) SetBus(b *bus.Bus) {
+	s.bus = b
+}
+
+// initDB migrates the deployment tables to the latest schema version
 func (s *Service) initDB() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS deployments (
-			id TEXT PRIMARY KEY,
-			name TEXT NOT NULL,
-			namespace TEXT NOT NULL,
-			image TEXT NOT NULL,
-			registry_id TEXT NOT NULL,
-			replicas INTEGER DEFAULT 1,
-			node_selector TEXT,
-			strategy TEXT,
-			resources TEXT,
-			environment TEXT,
-			status TEXT DEFAULT 'pending',
-			source TEXT DEFAULT 'internal',
-			author TEXT,
-			git_commit_sha TEXT,
-			manifest_path TEXT,
-			applied_by TEXT,
-			applied_at TIMESTAMP,
-			service_type TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS deployment_history (
-			id TEXT PRIMARY KEY,
-			deployment_id TEXT NOT NULL,
-			action TEXT NOT NULL,
-			old_image TEXT,
-			new_image TEXT,
-			old_replicas INTEGER,
-			new_replicas INTEGER,
-			success BOOLEAN DEFAULT FALSE,
-			error TEXT,
-			user TEXT,
-			timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			metadata TEXT,
-			FOREIGN KEY (deployment_id) REFERENCES deployments(id)
-		)`,
-		`CREATE TABLE IF NOT EXISTS autoscalers (
-			id TEXT PRIMARY KEY,
-			deployment_id TEXT NOT NULL,
-			min_replicas INTEGER NOT NULL,
-			max_replicas INTEGER NOT NULL,
-			target_cpu_percent INTEGER,
-			target_memory_percent INTEGER,
-			enabled BOOLEAN DEFAULT TRUE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (deployment_id) REFERENCES deployments(id)
-		)`,
-		`CREATE TABLE IF NOT EXISTS deployment_resources (
-			id TEXT PRIMARY KEY,
-			deployment_id TEXT NOT NULL,
-			resource_type TEXT NOT NULL,
-			resource_name TEXT NOT NULL,
-			namespace TEXT NOT NULL,
-			k8s_uid TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (deployment_id) REFERENCES deployments(id)
-		)`,
-	}
-
-	for _, query := range queries {
-		if _, err := s.db.Exec(query); err != nil {
-			return fmt.Errorf("failed to create table: %w", err)
-		}
+	if err := migrate.Up(context.Background(), s.db, Migrations); err != nil {
+		return err
 	}
 
+	// Gates left running by a restart are no longer watched
+	if _, err := s.db.Exec(`UPDATE deployment_health_gates SET phase = ?, message = 'interrupted by restart', completed_at = CURRENT_TIMESTAMP
+		WHERE phase IN (?, ?)`, HealthGatePhaseCancelled, HealthGatePhaseProgressing, HealthGatePhaseBaking); err != nil {
+		return fmt.Errorf("failed to close interrupted health gates: %w", err)
+	}
 	return nil
 }
 
@@ -130,21 +124,28 @@ func (s *Service) CreateDeployment(ctx context.Context, req CreateDeploymentRequ
 	deploymentID := fmt.Sprintf("dep-%s", uuid.New().String()[:8])
 	
 	deployment := &Deployment{
-		ID:           deploymentID,
-		Name:         req.Name,
-		Namespace:    req.Namespace,
-		Image:        req.Image,
-		RegistryID:   req.RegistryID,
-		Replicas:     req.Replicas,
-		NodeSelector: req.NodeSelector,
-		Strategy:     req.Strategy,
-		Status:       DeploymentStatusPending,
-		Resources:    req.Resources,
-		Environment:  req.Environment,
-		Source:       "internal", // Created through Denshimon UI
-		ServiceType:  req.ServiceType,  // Will be added to CreateDeploymentRequest
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		ID:             deploymentID,
+		Name:           req.Name,
+		Namespace:      req.Namespace,
+		Image:          req.Image,
+		RegistryID:     req.RegistryID,
+		Replicas:       req.Replicas,
+		NodeSelector:   req.NodeSelector,
+		Strategy:       req.Strategy,
+		Status:         DeploymentStatusPending,
+		Resources:      req.Resources,
+		Environment:    req.Environment,
+		Ports:          req.Ports,
+		Volumes:        req.Volumes,
+		InitContainers: req.InitContainers,
+		Sidecars:       req.Sidecars,
+		Probes:         req.probes(),
+		Ingress:        req.Ingress,
+		Source:         "internal", // Created through Denshimon UI
+		Author:         req.CreatedBy,
+		ServiceType:    req.ServiceType,  // Will be added to CreateDeploymentRequest
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}
 
 	// Generate and commit manifest to git (but don't deploy to K8s yet)
@@ -163,8 +164,13 @@ func (s *Service) CreateDeployment(ctx context.Context, req CreateDeploymentRequ
 		return nil, fmt.Errorf("failed to store deployment: %w", err)
 	}
 
+	if err := s.requestApproval(ctx, deployment.ID, req.CreatedBy); err != nil {
+		return nil, err
+	}
+	s.trackIngressDomain(deployment)
+
 	// Record successful creation (committed to git, not deployed yet)
-	s.recordHistory(deployment.ID, "create", "", deployment.Image, 0, deployment.Replicas, true, "Committed to git", "")
+	s.recordHistory(deployment.ID, "create", "", deployment.Image, 0, deployment.Replicas, true, "Committed to git", req.CreatedBy)
 
 	return deployment, nil
 }
@@ -262,6 +268,17 @@ func (s *Service) UpdateDeployment(ctx context.Context, id string, req UpdateDep
 	deployment.Status = DeploymentStatusUpdating
 	deployment.UpdatedAt = time.Now()
 
+	// Canary and blue-green deployments roll the change out next to the stable deployment;
+	// the stored spec is only updated once the rollout is promoted
+	if isProgressiveStrategy(deployment.Strategy.Type) {
+		stable, err := s.GetDeployment(ctx, id)
+		if err != nil {
+			return err
+		}
+		_, err = s.startRollout(ctx, *stable, *deployment, "")
+		return err
+	}
+
 	// Update in Kubernetes
 	if err := s.deployer.Update(ctx, *deployment); err != nil {
 		s.recordHistory(id, "update", oldImage, deployment.Image, oldReplicas, deployment.Replicas, false, err.Error(), "")
@@ -286,6 +303,11 @@ func (s *Service) DeleteDeployment(ctx context.Context, id string) error {
 		return err
 	}
 
+	// Remove any canary or preview deployment first
+	if rollout, err := s.GetActiveRollout(ctx, id); err == nil && rollout != nil {
+		s.AbortRollout(ctx, id, "")
+	}
+
 	// Delete from Kubernetes
 	if err := s.deployer.Delete(ctx, deployment.Namespace, deployment.Name); err != nil {
 		s.recordHistory(id, "delete", "", "", deployment.Replicas, 0, false, err.Error(), "")
@@ -296,6 +318,7 @@ func (s *Service) DeleteDeployment(ctx context.Context, id string) error {
 	if err := s.deleteDeploymentFromDB(id); err != nil {
 		return fmt.Errorf("failed to delete deployment from database: %w", err)
 	}
+	s.untrackIngressDomain(deployment)
 
 	// Record successful deletion
 	s.recordHistory(id, "delete", "", "", deployment.Replicas, 0, true, "", "")
@@ -320,6 +343,48 @@ func (s *Service) RestartDeployment(ctx context.Context, id string) error {
 	return nil
 }
 
+// RecentHistory returns changes to all deployments in a namespace ("" for all) since the given time, oldest first
+func (s *Service) RecentHistory(ctx context.Context, namespace string, since time.Time) ([]NamedHistory, error) {
+	query := `
+		SELECT h.id, h.deployment_id, h.action, h.old_image, h.new_image, h.old_replicas, h.new_replicas,
+		       h.success, h.error, h.user, h.timestamp, h.metadata, d.name, d.namespace
+		FROM deployment_history h
+		JOIN deployments d ON d.id = h.deployment_id
+		WHERE h.timestamp >= ?
+	`
+	args := []interface{}{since}
+	if namespace != "" {
+		query += ` AND d.namespace = ?`
+		args = append(args, namespace)
+	}
+	query += ` ORDER BY h.timestamp ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deployment history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []NamedHistory
+	for rows.Next() {
+		var h NamedHistory
+		var oldImage, newImage, errorMsg, user, metadataJSON sql.NullString
+		if err := rows.Scan(
+			&h.ID, &h.DeploymentID, &h.Action, &oldImage, &newImage,
+			&h.OldReplicas, &h.NewReplicas, &h.Success, &errorMsg,
+			&user, &h.Timestamp, &metadataJSON, &h.Name, &h.Namespace,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan deployment history: %w", err)
+		}
+		h.OldImage, h.NewImage, h.Error, h.User = oldImage.String, newImage.String, errorMsg.String, user.String
+		if metadataJSON.Valid {
+			json.Unmarshal([]byte(metadataJSON.String), &h.Metadata)
+		}
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
 // GetDeploymentHistory returns the history of changes for a deployment
 func (s *Service) GetDeploymentHistory(ctx context.Context, deploymentID string) ([]DeploymentHistory, error) {
 	query := `
@@ -409,14 +474,21 @@ func (s *Service) storeDeployment(deployment *Deployment) error {
 	strategy, _ := json.Marshal(deployment.Strategy)
 	resources, _ := json.Marshal(deployment.Resources)
 	environment, _ := json.Marshal(deployment.Environment)
+	ports, _ := json.Marshal(deployment.Ports)
+	volumes, _ := json.Marshal(deployment.Volumes)
+	initContainers, _ := json.Marshal(deployment.InitContainers)
+	sidecars, _ := json.Marshal(deployment.Sidecars)
+	probes, _ := json.Marshal(deployment.Probes)
+	ingress, _ := json.Marshal(deployment.Ingress)
 
 	query := `
 		INSERT INTO deployments (
 			id, name, namespace, image, registry_id, replicas,
 			node_selector, strategy, resources, environment, status,
 			source, author, git_commit_sha, manifest_path, applied_by,
-			applied_at, service_type, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			applied_at, service_type, created_at, updated_at, ports, volumes,
+			init_containers, sidecars, probes, ingress
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := s.db.Exec(query,
@@ -426,6 +498,8 @@ func (s *Service) storeDeployment(deployment *Deployment) error {
 		deployment.Status, deployment.Source, deployment.Author,
 		deployment.GitCommitSHA, deployment.ManifestPath, deployment.AppliedBy,
 		deployment.AppliedAt, deployment.ServiceType, deployment.CreatedAt, deployment.UpdatedAt,
+		string(ports), string(volumes), string(initContainers), string(sidecars), string(probes),
+		string(ingress),
 	)
 
 	return err
@@ -436,13 +510,20 @@ func (s *Service) updateDeploymentInDB(deployment *Deployment) error {
 	strategy, _ := json.Marshal(deployment.Strategy)
 	resources, _ := json.Marshal(deployment.Resources)
 	environment, _ := json.Marshal(deployment.Environment)
+	ports, _ := json.Marshal(deployment.Ports)
+	volumes, _ := json.Marshal(deployment.Volumes)
+	initContainers, _ := json.Marshal(deployment.InitContainers)
+	sidecars, _ := json.Marshal(deployment.Sidecars)
+	probes, _ := json.Marshal(deployment.Probes)
+	ingress, _ := json.Marshal(deployment.Ingress)
 
 	query := `
 		UPDATE deployments SET
 			name = ?, namespace = ?, image = ?, registry_id = ?, replicas = ?,
 			node_selector = ?, strategy = ?, resources = ?, environment = ?,
 			status = ?, source = ?, author = ?, git_commit_sha = ?, manifest_path = ?,
-			applied_by = ?, applied_at = ?, service_type = ?, updated_at = ?
+			applied_by = ?, applied_at = ?, service_type = ?, updated_at = ?,
+			ports = ?, volumes = ?, init_containers = ?, sidecars = ?, probes = ?, ingress = ?
 		WHERE id = ?
 	`
 
@@ -453,6 +534,8 @@ func (s *Service) updateDeploymentInDB(deployment *Deployment) error {
 		deployment.Status, deployment.Source, deployment.Author, 
 		deployment.GitCommitSHA, deployment.ManifestPath, deployment.AppliedBy,
 		deployment.AppliedAt, deployment.ServiceType, deployment.UpdatedAt, 
+		string(ports), string(volumes), string(initContainers), string(sidecars), string(probes),
+		string(ingress),
 		deployment.ID,
 	)
 
@@ -464,7 +547,8 @@ func (s *Service) getDeploymentFromDB(id string) (*Deployment, error) {
 		SELECT id, name, namespace, image, registry_id, replicas,
 		       node_selector, strategy, resources, environment, status,
 		       source, author, git_commit_sha, manifest_path, applied_by,
-		       applied_at, service_type, created_at, updated_at
+		       applied_at, service_type, created_at, updated_at, ports, volumes,
+		       init_containers, sidecars, probes, ingress
 		FROM deployments
 		WHERE id = ?
 	`
@@ -472,7 +556,8 @@ func (s *Service) getDeploymentFromDB(id string) (*Deployment, error) {
 	row := s.db.QueryRow(query, id)
 
 	var deployment Deployment
-	var nodeSelector, strategy, resources, environment sql.NullString
+	var nodeSelector, strategy, resources, environment, ports, volumes sql.NullString
+	var initContainers, sidecars, probes, ingress sql.NullString
 	var appliedAt sql.NullTime
 
 	err := row.Scan(
@@ -482,6 +567,7 @@ func (s *Service) getDeploymentFromDB(id string) (*Deployment, error) {
 		&deployment.Status, &deployment.Source, &deployment.Author,
 		&deployment.GitCommitSHA, &deployment.ManifestPath, &deployment.AppliedBy,
 		&appliedAt, &deployment.ServiceType, &deployment.CreatedAt, &deployment.UpdatedAt,
+		&ports, &volumes, &initContainers, &sidecars, &probes, &ingress,
 	)
 
 	if err != nil {
@@ -501,6 +587,24 @@ func (s *Service) getDeploymentFromDB(id string) (*Deployment, error) {
 	if environment.Valid {
 		json.Unmarshal([]byte(environment.String), &deployment.Environment)
 	}
+	if ports.Valid {
+		json.Unmarshal([]byte(ports.String), &deployment.Ports)
+	}
+	if volumes.Valid {
+		json.Unmarshal([]byte(volumes.String), &deployment.Volumes)
+	}
+	if initContainers.Valid {
+		json.Unmarshal([]byte(initContainers.String), &deployment.InitContainers)
+	}
+	if sidecars.Valid {
+		json.Unmarshal([]byte(sidecars.String), &deployment.Sidecars)
+	}
+	if probes.Valid {
+		json.Unmarshal([]byte(probes.String), &deployment.Probes)
+	}
+	if ingress.Valid {
+		json.Unmarshal([]byte(ingress.String), &deployment.Ingress)
+	}
 	if appliedAt.Valid {
 		deployment.AppliedAt = &appliedAt.Time
 	}
@@ -587,32 +691,75 @@ func (s *Service) deleteDeploymentFromDB(id string) error {
 		return err
 	}
 
+	// Delete dependencies in both directions
+	_, err = s.db.Exec("DELETE FROM deployment_dependencies WHERE deployment_id = ? OR depends_on = ?", id, id)
+	if err != nil {
+		return err
+	}
+
 	// Delete deployment
 	_, err = s.db.Exec("DELETE FROM deployments WHERE id = ?", id)
 	return err
 }
 
 func (s *Service) recordHistory(deploymentID, action, oldImage, newImage string, oldReplicas, newReplicas int32, success bool, errorMsg, user string) {
+	s.recordHistoryWithMetadata(deploymentID, action, oldImage, newImage, oldReplicas, newReplicas, success, errorMsg, user, nil)
+}
+
+func (s *Service) recordHistoryWithMetadata(deploymentID, action, oldImage, newImage string, oldReplicas, newReplicas int32, success bool, errorMsg, user string, metadata map[string]interface{}) {
 	historyID := uuid.New().String()
 
+	var metadataJSON sql.NullString
+	if metadata != nil {
+		if data, err := json.Marshal(metadata); err == nil {
+			metadataJSON = sql.NullString{String: string(data), Valid: true}
+		}
+	}
+
 	query := `
 		INSERT INTO deployment_history (
 			id, deployment_id, action, old_image, new_image,
-			old_replicas, new_replicas, success, error, user, timestamp
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			old_replicas, new_replicas, success, error, user, timestamp, metadata
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
+	now := time.Now()
 	s.db.Exec(query,
 		historyID, deploymentID, action, oldImage, newImage,
-		oldReplicas, newReplicas, success, errorMsg, user, time.Now(),
+		oldReplicas, newReplicas, success, errorMsg, user, now, metadataJSON,
 	)
 
+	if s.bus != nil && success {
+		s.publishHistory(NamedHistory{DeploymentHistory: DeploymentHistory{
+			ID: historyID, DeploymentID: deploymentID, Action: action, OldImage: oldImage, NewImage: newImage,
+			OldReplicas: oldReplicas, NewReplicas: newReplicas, Success: success, Error: errorMsg, User: user,
+			Timestamp: now, Metadata: metadata,
+		}})
+	}
+
 	// Trigger GitOps sync if deployment was successful
 	if success && s.gitopsService != nil {
 		go s.syncToGitOps(deploymentID, action, user)
 	}
 }
 
+// publishHistory publishes successful applies, updates and promotions as Applied and
+// rollbacks as RolledBack
+func (s *Service) publishHistory(entry NamedHistory) {
+	switch entry.Action {
+	case "apply", "update", "promote", "rollback":
+	default:
+		return
+	}
+	// Deleted deployments are published without their name
+	s.db.QueryRow("SELECT name, namespace FROM deployments WHERE id = ?", entry.DeploymentID).Scan(&entry.Name, &entry.Namespace)
+	if entry.Action == "rollback" {
+		s.bus.Publish(RolledBack{entry})
+		return
+	}
+	s.bus.Publish(Applied{entry})
+}
+
 // syncToGitOps automatically syncs deployment changes to GitOps repository
 func (s *Service) syncToGitOps(deploymentID, action, user string) {
 	ctx := context.Background()
@@ -664,7 +811,12 @@ func (s *Service) syncToGitOps(deploymentID, action, user string) {
 	syncConfig := gitops.DefaultSyncConfig()
 	syncConfig.CommitMessage = fmt.Sprintf("feat(%s): %s deployment %s", deployment.Namespace, action, deployment.Name)
 
-	s.syncEngine.SyncApplicationToGit(ctx, deploymentID, syncConfig)
+	s.syncEngine.Sync(ctx, gitops.SyncRequest{
+		Kind:    gitops.SyncKindApplication,
+		Target:  deploymentID,
+		Trigger: "deployment",
+		Config:  syncConfig,
+	})
 }
 
 func (s *Service) updateDeploymentStatus(deployment *Deployment, k8sDeployment interface{}) {
@@ -714,6 +866,10 @@ func (s *Service) CreateRegistry(ctx context.Context, registry providers.Registr
 	if err != nil {
 		return fmt.Errorf("failed to marshal registry config: %w", err)
 	}
+	config, err := s.box.Encrypt(string(configJSON))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt registry config: %w", err)
+	}
 
 	query := `
 		INSERT INTO container_registries (id, name, type, config, created_at, updated_at)
@@ -724,7 +880,7 @@ func (s *Service) CreateRegistry(ctx context.Context, registry providers.Registr
 		registry.ID,
 		registry.Name,
 		registry.Type,
-		string(configJSON),
+		config,
 		registry.CreatedAt,
 		registry.UpdatedAt,
 	)
@@ -768,6 +924,10 @@ func (s *Service) ListRegistries(ctx context.Context) ([]providers.Registry, err
 		}
 
 		// Parse config JSON
+		configJSON, err = s.box.Decrypt(configJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt config of registry %s: %w", registry.ID, err)
+		}
 		if err := json.Unmarshal([]byte(configJSON), &registry.Config); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal registry config: %w", err)
 		}
@@ -824,10 +984,15 @@ func (s *Service) commitToGitOps(ctx context.Context, deployment *Deployment) er
 		"autoscaling":   false, // Can be enhanced later
 	}
 	
-	_, err := s.gitopsService.GenerateFullManifest(app, options)
+	manifest, err := s.gitopsService.GenerateFullManifest(app, options)
 	if err != nil {
 		return fmt.Errorf("failed to generate manifest: %w", err)
 	}
+	lint, err := s.gitopsService.CheckManifest(ctx, manifest)
+	if err != nil {
+		return err
+	}
+	deployment.PolicyViolations = lint.Violations
 	
 	// Create GitOps application
 	gitopsApp, err := s.gitopsService.CreateApplication(ctx, 
@@ -843,9 +1008,17 @@ func (s *Service) commitToGitOps(ctx context.Context, deployment *Deployment) er
 	if err != nil {
 		return fmt.Errorf("failed to create gitops application: %w", err)
 	}
+	workload := gitops.Workload{Volumes: app.Volumes, Probes: app.Probes, Ingress: app.Ingress}
+	if err := s.gitopsService.SetApplicationWorkload(ctx, gitopsApp.ID, workload); err != nil {
+		return fmt.Errorf("failed to set gitops application workload: %w", err)
+	}
 	
 	// Sync to git repository (this commits the manifest)
-	err = s.syncEngine.SyncApplicationToGit(ctx, gitopsApp.ID, nil)
+	_, err = s.syncEngine.Sync(ctx, gitops.SyncRequest{
+		Kind:    gitops.SyncKindApplication,
+		Target:  gitopsApp.ID,
+		Trigger: "deployment",
+	})
 	if err != nil {
 		return fmt.Errorf("failed to sync to git: %w", err)
 	}
@@ -866,7 +1039,12 @@ func (s *Service) ApplyDeployment(ctx context.Context, deploymentID, appliedBy s
 	}
 	
 	if deployment.Status != DeploymentStatusPendingApply {
-		return fmt.Errorf("deployment is not in pending_apply status: %s", deployment.Status)
+		return fmt.Errorf("%w: %s", ErrNotPendingApply, deployment.Status)
+	}
+
+	// Protected namespaces need approvals from someone other than the requester
+	if err := s.checkApproval(ctx, deployment, appliedBy); err != nil {
+		return err
 	}
 	
 	// Update status to applying
@@ -876,8 +1054,23 @@ func (s *Service) ApplyDeployment(ctx context.Context, deploymentID, appliedBy s
 		return fmt.Errorf("failed to update status: %w", err)
 	}
 	
-	// Deploy to Kubernetes using the deployer
-	k8sDeployment, err := s.deployer.Deploy(ctx, *deployment)
+	// Deploy to Kubernetes using the deployer; deployments already in the cluster
+	// (e.g. with proposed resource changes) are updated in place. Databases run as StatefulSets.
+	var k8sWorkload interface{}
+	switch {
+	case deployment.AppliedAt != nil:
+		if err = s.deployer.Update(ctx, *deployment); err == nil {
+			if isStateful(*deployment) {
+				k8sWorkload, err = s.deployer.GetStatefulSet(ctx, deployment.Namespace, deployment.Name)
+			} else {
+				k8sWorkload, err = s.k8sClient.Clientset().AppsV1().Deployments(deployment.Namespace).Get(ctx, deployment.Name, metav1.GetOptions{})
+			}
+		}
+	case isStateful(*deployment):
+		k8sWorkload, err = s.deployer.DeployStatefulSet(ctx, *deployment)
+	default:
+		k8sWorkload, err = s.deployer.Deploy(ctx, *deployment)
+	}
 	if err != nil {
 		// Mark as apply failed
 		deployment.Status = DeploymentStatusApplyFailed
@@ -895,14 +1088,84 @@ func (s *Service) ApplyDeployment(ctx context.Context, deploymentID, appliedBy s
 	deployment.UpdatedAt = now
 	
 	// Update with live status from Kubernetes
-	s.updateDeploymentStatus(deployment, k8sDeployment)
+	s.updateDeploymentStatus(deployment, k8sWorkload)
+	if err := s.updateDeploymentInDB(deployment); err != nil {
+		return fmt.Errorf("failed to update status: %w", err)
+	}
 	
 	// Record successful apply
 	s.recordHistory(deployment.ID, "apply", "", deployment.Image, 0, deployment.Replicas, true, "Applied to cluster", appliedBy)
+
+	// Watch the new revision; StatefulSets have no ReplicaSet revisions to gate or roll back
+	if !isStateful(*deployment) {
+		s.startHealthGate(*deployment, appliedBy)
+	}
 	
 	return nil
 }
 
+// ProposeResources stages new resource requirements for a deployment. The change is
+// synced to GitOps and waits in pending_apply until someone applies it.
+func (s *Service) ProposeResources(ctx context.Context, deploymentID string, resources ResourceRequirements, proposedBy, reason string) (*Deployment, error) {
+	deployment, err := s.getDeploymentFromDB(deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+	if deployment.Status == DeploymentStatusApplying {
+		return nil, fmt.Errorf("deployment is being applied")
+	}
+
+	oldResources := deployment.Resources
+	deployment.Resources = resources
+	deployment.Status = DeploymentStatusPendingApply
+	deployment.UpdatedAt = time.Now()
+	if err := s.updateDeploymentInDB(deployment); err != nil {
+		return nil, fmt.Errorf("failed to update deployment: %w", err)
+	}
+	if err := s.requestApproval(ctx, deployment.ID, proposedBy); err != nil {
+		return nil, err
+	}
+
+	s.recordHistoryWithMetadata(deployment.ID, "propose_resources", deployment.Image, deployment.Image,
+		deployment.Replicas, deployment.Replicas, true, "", proposedBy, map[string]interface{}{
+			"old_resources": oldResources,
+			"new_resources": resources,
+			"reason":        reason,
+		})
+
+	return deployment, nil
+}
+
+// ProposeImage stages a new image for a deployment, such as one built from its repository.
+// The change waits in pending_apply until someone applies it.
+func (s *Service) ProposeImage(ctx context.Context, deploymentID, image, proposedBy, reason string) (*Deployment, error) {
+	deployment, err := s.getDeploymentFromDB(deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+	if deployment.Status == DeploymentStatusApplying {
+		return nil, fmt.Errorf("deployment is being applied")
+	}
+
+	oldImage := deployment.Image
+	deployment.Image = image
+	deployment.Status = DeploymentStatusPendingApply
+	deployment.UpdatedAt = time.Now()
+	if err := s.updateDeploymentInDB(deployment); err != nil {
+		return nil, fmt.Errorf("failed to update deployment: %w", err)
+	}
+	if err := s.requestApproval(ctx, deployment.ID, proposedBy); err != nil {
+		return nil, err
+	}
+
+	s.recordHistoryWithMetadata(deployment.ID, "propose_image", oldImage, image,
+		deployment.Replicas, deployment.Replicas, true, "", proposedBy, map[string]interface{}{
+			"reason": reason,
+		})
+
+	return deployment, nil
+}
+
 // GetPendingDeployments returns all deployments with pending_apply status
 func (s *Service) GetPendingDeployments(ctx context.Context) ([]Deployment, error) {
 	query := `
@@ -975,18 +1238,6 @@ func (s *Service) GetPendingDeployments(ctx context.Context) ([]Deployment, erro
 	return deployments, nil
 }
 
-// BatchApplyDeployments applies multiple deployments at once
-func (s *Service) BatchApplyDeployments(ctx context.Context, deploymentIDs []string, appliedBy string) map[string]error {
-	results := make(map[string]error)
-	
-	for _, id := range deploymentIDs {
-		err := s.ApplyDeployment(ctx, id, appliedBy)
-		results[id] = err
-	}
-	
-	return results
-}
-
 // Helper methods
 
 // deploymentToGitOpsApp converts a Deployment to GitOps Application
@@ -999,11 +1250,78 @@ func (s *Service) deploymentToGitOpsApp(deployment *Deployment) *gitops.Applicat
 		Replicas:     int(deployment.Replicas),
 		Resources:    s.resourcesMapFromRequirements(deployment.Resources),
 		Environment:  deployment.Environment,
+		Volumes:      gitopsVolumes(deployment.Volumes),
+		Probes:       gitopsProbes(deployment.Probes),
+		Ingress:      gitopsIngress(deployment.Ingress),
 		Status:       "Healthy", // Default status
 		CreatedAt:    deployment.CreatedAt,
 	}
 }
 
+// gitopsVolumes converts deployment volumes for the GitOps manifest. Per-pod claims of
+// database deployments have no claim to reference, so they are left out.
+func gitopsVolumes(volumes []Volume) []gitops.Volume {
+	var converted []gitops.Volume
+	for _, volume := range volumes {
+		if volume.isClaimTemplate() {
+			continue
+		}
+		converted = append(converted, gitops.Volume{
+			Name:      volume.Name,
+			Type:      volume.Type,
+			Source:    volume.Source,
+			MountPath: volume.MountPath,
+			ReadOnly:  volume.ReadOnly,
+		})
+	}
+	return converted
+}
+
+// gitopsProbes converts deployment probes for the GitOps manifest
+func gitopsProbes(probes Probes) *gitops.Probes {
+	return &gitops.Probes{
+		Liveness:  gitopsProbe(probes.Liveness),
+		Readiness: gitopsProbe(probes.Readiness),
+		Startup:   gitopsProbe(probes.Startup),
+	}
+}
+
+func gitopsProbe(probe *Probe) *gitops.Probe {
+	if probe == nil {
+		return nil
+	}
+	converted := &gitops.Probe{
+		Exec:                probe.Exec,
+		InitialDelaySeconds: probe.InitialDelaySeconds,
+		PeriodSeconds:       probe.PeriodSeconds,
+		TimeoutSeconds:      probe.TimeoutSeconds,
+		SuccessThreshold:    probe.SuccessThreshold,
+		FailureThreshold:    probe.FailureThreshold,
+	}
+	if probe.HTTPGet != nil {
+		converted.HTTPGet = &gitops.HTTPGetAction{Path: probe.HTTPGet.Path, Port: probe.HTTPGet.Port, Scheme: probe.HTTPGet.Scheme}
+	}
+	if probe.TCPSocket != nil {
+		converted.TCPSocket = &gitops.TCPSocketAction{Port: probe.TCPSocket.Port}
+	}
+	return converted
+}
+
+// gitopsIngress converts a deployment ingress for the GitOps manifest
+func gitopsIngress(ingress *Ingress) *gitops.Ingress {
+	if ingress == nil {
+		return nil
+	}
+	return &gitops.Ingress{
+		Host:         ingress.Host,
+		Path:         ingress.Path,
+		TLSSecret:    ingress.TLSSecret,
+		Issuer:       ingress.Issuer,
+		IssuerKind:   ingress.IssuerKind,
+		SeparateFile: ingress.SeparateFile,
+	}
+}
+
 // resourcesMapFromRequirements converts ResourceRequirements to map[string]string
 func (s *Service) resourcesMapFromRequirements(req ResourceRequirements) map[string]string {
 	resources := make(map[string]string)
@@ -0,0 +1,188 @@
+package deployments
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// isStateful reports whether a deployment runs as a StatefulSet rather than a Deployment
+func isStateful(deployment Deployment) bool {
+	return deployment.ServiceType == ServiceTypeDatabase
+}
+
+// headlessServiceName is the governing service that gives StatefulSet pods stable DNS names
+func headlessServiceName(name string) string {
+	return name + "-headless"
+}
+
+// DeployStatefulSet creates the StatefulSet and headless service of a database deployment
+func (d *KubernetesDeployer) DeployStatefulSet(ctx context.Context, deployment Deployment) (*appsv1.StatefulSet, error) {
+	secretName, err := d.imagePullSecret(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	statefulSet := d.buildStatefulSetSpec(deployment, secretName)
+	if err := d.injectSecrets(ctx, deployment, &statefulSet.Spec.Template.Spec.Containers[0]); err != nil {
+		return nil, err
+	}
+
+	if err := d.ensureHeadlessService(ctx, deployment); err != nil {
+		return nil, err
+	}
+
+	result, err := d.k8sClient.Clientset().AppsV1().StatefulSets(deployment.Namespace).Create(ctx, statefulSet, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create statefulset: %w", err)
+	}
+	d.k8sClient.InvalidateCache("statefulsets")
+
+	return result, nil
+}
+
+// buildStatefulSetSpec creates a StatefulSet with the pod template of a Deployment. Claim
+// template volumes are removed from the pod volumes; the StatefulSet controller adds a claim
+// per pod under the same name, so the container's mounts resolve to them.
+func (d *KubernetesDeployer) buildStatefulSetSpec(deployment Deployment, imagePullSecret string) *appsv1.StatefulSet {
+	spec := d.buildDeploymentSpec(deployment, imagePullSecret)
+	template := spec.Spec.Template
+
+	claims := make(map[string]bool)
+	var claimTemplates []corev1.PersistentVolumeClaim
+	for _, volume := range deployment.Volumes {
+		if !volume.isClaimTemplate() {
+			continue
+		}
+		claims[volume.Name] = true
+		claimTemplates = append(claimTemplates, claimTemplate(volume))
+	}
+
+	volumes := make([]corev1.Volume, 0, len(template.Spec.Volumes))
+	for _, volume := range template.Spec.Volumes {
+		if !claims[volume.Name] {
+			volumes = append(volumes, volume)
+		}
+	}
+	template.Spec.Volumes = volumes
+
+	return &appsv1.StatefulSet{
+		ObjectMeta: spec.ObjectMeta,
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    &deployment.Replicas,
+			ServiceName: headlessServiceName(deployment.Name),
+			Selector:    spec.Spec.Selector,
+			Template:    template,
+			// Pods start and stop one at a time so replicas can join and leave a cluster in order
+			PodManagementPolicy: appsv1.OrderedReadyPodManagement,
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: appsv1.RollingUpdateStatefulSetStrategyType,
+			},
+			VolumeClaimTemplates: claimTemplates,
+		},
+	}
+}
+
+// claimTemplate builds the per-pod claim of a volume
+func claimTemplate(volume Volume) corev1.PersistentVolumeClaim {
+	size := volume.Size
+	if size == "" {
+		size = DefaultClaimSize
+	}
+	claim := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: volume.Name,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: mustParseQuantity(size)},
+			},
+		},
+	}
+	if volume.StorageClass != "" {
+		claim.Spec.StorageClassName = &volume.StorageClass
+	}
+	return claim
+}
+
+// ensureHeadlessService creates the governing service of a StatefulSet
+func (d *KubernetesDeployer) ensureHeadlessService(ctx context.Context, deployment Deployment) error {
+	service := headlessService(deployment)
+	_, err := d.k8sClient.Clientset().CoreV1().Services(deployment.Namespace).Create(ctx, service, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create headless service: %w", err)
+	}
+	return nil
+}
+
+// headlessService builds the governing service of a StatefulSet
+func headlessService(deployment Deployment) *corev1.Service {
+	labels := map[string]string{
+		"app":        deployment.Name,
+		"managed-by": "denshimon",
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      headlessServiceName(deployment.Name),
+			Namespace: deployment.Namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  labels,
+			// Peers discover each other before they are ready, e.g. to form a replica set
+			PublishNotReadyAddresses: true,
+		},
+	}
+	for _, port := range deployment.Ports {
+		service.Spec.Ports = append(service.Spec.Ports, corev1.ServicePort{
+			Name:     port.Name,
+			Port:     port.ContainerPort,
+			Protocol: corev1.Protocol(port.Protocol),
+		})
+	}
+	return service
+}
+
+// updateStatefulSet applies replicas, image, environment and resources to a StatefulSet.
+// Claim templates can't change after creation and are left as they are.
+func (d *KubernetesDeployer) updateStatefulSet(ctx context.Context, deployment Deployment) error {
+	statefulSets := d.k8sClient.Clientset().AppsV1().StatefulSets(deployment.Namespace)
+
+	existing, err := statefulSets.Get(ctx, deployment.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get existing statefulset: %w", err)
+	}
+
+	existing.Spec.Replicas = &deployment.Replicas
+	if err := d.updateContainer(ctx, deployment, &existing.Spec.Template.Spec.Containers[0]); err != nil {
+		return err
+	}
+
+	if _, err := statefulSets.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update statefulset: %w", err)
+	}
+	d.k8sClient.InvalidateCache("statefulsets")
+	return nil
+}
+
+// GetStatefulSet returns the StatefulSet of a database deployment
+func (d *KubernetesDeployer) GetStatefulSet(ctx context.Context, namespace, name string) (*appsv1.StatefulSet, error) {
+	return d.k8sClient.GetStatefulSet(ctx, namespace, name)
+}
+
+// deleteStatefulSet removes a StatefulSet and its headless service. Claims are kept so
+// the data survives redeploying the database.
+func (d *KubernetesDeployer) deleteStatefulSet(ctx context.Context, namespace, name string) error {
+	if err := d.k8sClient.DeleteStatefulSet(ctx, namespace, name); err != nil {
+		return err
+	}
+	d.k8sClient.Clientset().CoreV1().Services(namespace).Delete(ctx, headlessServiceName(name), metav1.DeleteOptions{})
+	return nil
+}
@@ -1,7 +1,13 @@
 package deployments
 
 import (
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/archellir/denshimon/internal/gitops"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // Deployment represents a deployed application in Kubernetes
@@ -21,27 +27,58 @@ type Deployment struct {
 	NodeDistribution  map[string]int       `json:"node_distribution"`
 	Resources         ResourceRequirements `json:"resources,omitempty"`
 	Environment       map[string]string    `json:"environment,omitempty"`
+	Ports             []ContainerPort      `json:"ports,omitempty"`
+	Volumes           []Volume             `json:"volumes,omitempty"`
+	InitContainers    []Container          `json:"init_containers,omitempty"`
+	Sidecars          []Container          `json:"sidecars,omitempty"`
+	Probes            Probes               `json:"probes"`
+	Ingress           *Ingress             `json:"ingress,omitempty"`
 	// GitOps tracking fields
 	Source           string    `json:"source"`            // "internal" or "external"
-	Author           string    `json:"author,omitempty"`  // Who created (for external)
+	Author           string    `json:"author,omitempty"`  // Who created it
 	GitCommitSHA     string    `json:"git_commit_sha,omitempty"`
 	ManifestPath     string    `json:"manifest_path,omitempty"`
 	AppliedBy        string    `json:"applied_by,omitempty"`
 	AppliedAt        *time.Time `json:"applied_at,omitempty"`
 	ServiceType      string    `json:"service_type,omitempty"` // For infra/service-type label
+	// Policy warnings on the committed manifest; only set in the create response
+	PolicyViolations []gitops.Violation `json:"policy_violations,omitempty"`
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
 }
 
 // DeploymentStrategy defines how deployments are rolled out
 type DeploymentStrategy struct {
-	Type           string `json:"type"` // "RollingUpdate" or "Recreate"
+	Type           string `json:"type"` // "RollingUpdate", "Recreate", "canary" or "blue-green"
 	MaxSurge       int32  `json:"max_surge,omitempty"`
 	MaxUnavailable int32  `json:"max_unavailable,omitempty"`
 	NodeSpread     bool   `json:"node_spread"` // Spread across nodes
 	ZoneSpread     bool   `json:"zone_spread"` // Spread across zones
+	// Replicas of the canary deployment (default 1)
+	CanaryReplicas int32 `json:"canary_replicas,omitempty"`
+	// Container port exposed by the service managed for progressive strategies
+	ServicePort int32 `json:"service_port,omitempty"`
+	// Checks run on the new revision after an apply; defaults apply when unset
+	HealthGate *HealthGate `json:"health_gate,omitempty"`
+}
+
+// HealthGate configures the checks a deployment must pass after it is applied
+type HealthGate struct {
+	Disabled bool `json:"disabled,omitempty"`
+	// Seconds the new revision must stay healthy once rolled out (default 120)
+	BakeSeconds int `json:"bake_seconds,omitempty"`
+	// Container restarts the new pods may have in total before the gate fails (default 3)
+	MaxRestarts int32 `json:"max_restarts,omitempty"`
+	// Roll back to the previous revision when the gate fails
+	Rollback bool `json:"rollback,omitempty"`
 }
 
+// Progressive delivery strategies
+const (
+	StrategyCanary    = "canary"
+	StrategyBlueGreen = "blue-green"
+)
+
 // DeploymentStatus represents the current status of a deployment
 type DeploymentStatus string
 
@@ -53,6 +90,7 @@ const (
 	DeploymentStatusRunning      DeploymentStatus = "running"
 	DeploymentStatusFailed       DeploymentStatus = "failed"
 	DeploymentStatusApplyFailed  DeploymentStatus = "apply_failed"  // NEW - apply failed
+	DeploymentStatusRejected     DeploymentStatus = "rejected"      // pending change rejected by an approver
 	DeploymentStatusUpdating     DeploymentStatus = "updating"
 	DeploymentStatusTerminating  DeploymentStatus = "terminating"
 )
@@ -92,7 +130,331 @@ type CreateDeploymentRequest struct {
 	Strategy     DeploymentStrategy   `json:"strategy"`
 	Resources    ResourceRequirements `json:"resources,omitempty"`
 	Environment  map[string]string    `json:"environment,omitempty"`
-	ServiceType  string               `json:"service_type,omitempty"` // For infra/service-type label
+	Ports        []ContainerPort      `json:"ports,omitempty"`
+	Volumes      []Volume             `json:"volumes,omitempty"`
+	// Init containers run in order before the main container; sidecars run next to it
+	InitContainers []Container `json:"init_containers,omitempty"`
+	Sidecars       []Container `json:"sidecars,omitempty"`
+	// Probes of the main container; without them the service type's defaults are used,
+	// and an empty object sets none
+	Probes      *Probes  `json:"probes,omitempty"`
+	Ingress     *Ingress `json:"ingress,omitempty"`
+	ServiceType string   `json:"service_type,omitempty"` // For infra/service-type label
+	// CreatedBy is the authenticated user creating the deployment, set by the caller
+	CreatedBy string `json:"-"`
+}
+
+// ContainerPort is a port exposed by the deployment's container
+type ContainerPort struct {
+	Name          string `json:"name,omitempty"`
+	ContainerPort int32  `json:"container_port"`
+	Protocol      string `json:"protocol,omitempty"` // TCP (default), UDP or SCTP
+}
+
+// Validate checks the port number and protocol
+func (p ContainerPort) Validate() error {
+	if p.ContainerPort < 1 || p.ContainerPort > 65535 {
+		return fmt.Errorf("invalid container port: %d", p.ContainerPort)
+	}
+	switch p.Protocol {
+	case "", "TCP", "UDP", "SCTP":
+		return nil
+	}
+	return fmt.Errorf("invalid protocol for port %d: %s", p.ContainerPort, p.Protocol)
+}
+
+// Container is an init or sidecar container in the deployment's pods. Volumes names the
+// deployment volumes it mounts, at the same path as the main container. Its environment is
+// set as given; secret references are only resolved for the main container.
+type Container struct {
+	Name        string               `json:"name"`
+	Image       string               `json:"image"`
+	Command     []string             `json:"command,omitempty"`
+	Args        []string             `json:"args,omitempty"`
+	Environment map[string]string    `json:"environment,omitempty"`
+	Resources   ResourceRequirements `json:"resources,omitempty"`
+	Volumes     []string             `json:"volumes,omitempty"`
+}
+
+// Validate checks the container has a valid name, an image and parseable resources
+func (c Container) Validate() error {
+	if errs := validation.IsDNS1123Label(c.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid container name %q: %s", c.Name, strings.Join(errs, ", "))
+	}
+	if c.Image == "" {
+		return fmt.Errorf("container %s needs an image", c.Name)
+	}
+	for _, quantity := range []string{c.Resources.Limits.CPU, c.Resources.Limits.Memory, c.Resources.Requests.CPU, c.Resources.Requests.Memory} {
+		if quantity == "" {
+			continue
+		}
+		if _, err := resource.ParseQuantity(quantity); err != nil {
+			return fmt.Errorf("invalid resources for container %s: %s", c.Name, quantity)
+		}
+	}
+	return nil
+}
+
+// ServiceTypeDatabase deployments run as StatefulSets with per-pod volume claims
+const ServiceTypeDatabase = "database"
+
+// Probes are the health checks of the deployment's main container
+type Probes struct {
+	Liveness  *Probe `json:"liveness,omitempty"`
+	Readiness *Probe `json:"readiness,omitempty"`
+	Startup   *Probe `json:"startup,omitempty"`
+}
+
+// Probe checks a container with exactly one of an HTTP GET, a TCP connection or a command.
+// Unset timings and thresholds use the Kubernetes defaults.
+type Probe struct {
+	HTTPGet             *HTTPGetAction   `json:"http_get,omitempty"`
+	TCPSocket           *TCPSocketAction `json:"tcp_socket,omitempty"`
+	Exec                []string         `json:"exec,omitempty"`
+	InitialDelaySeconds int32            `json:"initial_delay_seconds,omitempty"`
+	PeriodSeconds       int32            `json:"period_seconds,omitempty"`
+	TimeoutSeconds      int32            `json:"timeout_seconds,omitempty"`
+	SuccessThreshold    int32            `json:"success_threshold,omitempty"`
+	FailureThreshold    int32            `json:"failure_threshold,omitempty"`
+}
+
+// HTTPGetAction probes a path; any status from 200 to 399 passes
+type HTTPGetAction struct {
+	Path   string `json:"path"`
+	Port   int32  `json:"port"`
+	Scheme string `json:"scheme,omitempty"` // HTTP (default) or HTTPS
+}
+
+// TCPSocketAction probes that a port accepts connections
+type TCPSocketAction struct {
+	Port int32 `json:"port"`
+}
+
+// Validate checks the probes. Liveness and startup probes must succeed once to pass.
+func (p Probes) Validate() error {
+	for _, probe := range []struct {
+		name  string
+		probe *Probe
+	}{{"liveness", p.Liveness}, {"readiness", p.Readiness}, {"startup", p.Startup}} {
+		if probe.probe == nil {
+			continue
+		}
+		if err := probe.probe.Validate(); err != nil {
+			return fmt.Errorf("invalid %s probe: %w", probe.name, err)
+		}
+		if probe.name != "readiness" && probe.probe.SuccessThreshold > 1 {
+			return fmt.Errorf("invalid %s probe: success_threshold must be 1", probe.name)
+		}
+	}
+	return nil
+}
+
+// Validate checks the probe has one action with a valid port, and no negative timings
+func (p Probe) Validate() error {
+	actions := 0
+	if p.HTTPGet != nil {
+		actions++
+		if p.HTTPGet.Port < 1 || p.HTTPGet.Port > 65535 {
+			return fmt.Errorf("invalid port: %d", p.HTTPGet.Port)
+		}
+		switch p.HTTPGet.Scheme {
+		case "", "HTTP", "HTTPS":
+		default:
+			return fmt.Errorf("invalid scheme: %s", p.HTTPGet.Scheme)
+		}
+	}
+	if p.TCPSocket != nil {
+		actions++
+		if p.TCPSocket.Port < 1 || p.TCPSocket.Port > 65535 {
+			return fmt.Errorf("invalid port: %d", p.TCPSocket.Port)
+		}
+	}
+	if len(p.Exec) > 0 {
+		actions++
+	}
+	if actions != 1 {
+		return fmt.Errorf("exactly one of http_get, tcp_socket and exec is required")
+	}
+	if p.InitialDelaySeconds < 0 || p.PeriodSeconds < 0 || p.TimeoutSeconds < 0 || p.SuccessThreshold < 0 || p.FailureThreshold < 0 {
+		return fmt.Errorf("timings and thresholds can't be negative")
+	}
+	return nil
+}
+
+// DefaultProbes are the probes of a deployment that doesn't set any, checking its first
+// port: databases get TCP readiness and a lenient liveness check, frontend and web
+// services an HTTP readiness check on /, and other services TCP readiness. Deployments
+// without ports get none.
+func DefaultProbes(serviceType string, ports []ContainerPort) Probes {
+	var port int32
+	for _, p := range ports {
+		if p.Protocol == "" || p.Protocol == "TCP" {
+			port = p.ContainerPort
+			break
+		}
+	}
+	if port == 0 {
+		return Probes{}
+	}
+
+	switch serviceType {
+	case ServiceTypeDatabase:
+		return Probes{
+			Readiness: &Probe{TCPSocket: &TCPSocketAction{Port: port}, PeriodSeconds: 10},
+			Liveness:  &Probe{TCPSocket: &TCPSocketAction{Port: port}, InitialDelaySeconds: 30, PeriodSeconds: 20, FailureThreshold: 6},
+		}
+	case "frontend", "web":
+		return Probes{Readiness: &Probe{HTTPGet: &HTTPGetAction{Path: "/", Port: port}, PeriodSeconds: 5}}
+	default:
+		return Probes{Readiness: &Probe{TCPSocket: &TCPSocketAction{Port: port}, PeriodSeconds: 5}}
+	}
+}
+
+// Ingress exposes a deployment's service on a host in its GitOps manifest. TLS uses
+// TLSSecret, or the certificate cert-manager issues from Issuer into <name>-tls.
+type Ingress struct {
+	Host       string `json:"host"`
+	Path       string `json:"path,omitempty"` // default /
+	TLSSecret  string `json:"tls_secret,omitempty"`
+	Issuer     string `json:"issuer,omitempty"`
+	IssuerKind string `json:"issuer_kind,omitempty"` // ClusterIssuer (default) or Issuer
+	// SeparateFile writes the Ingress to <name>-ingress.yaml next to the deployment manifest
+	SeparateFile bool `json:"separate_file,omitempty"`
+}
+
+// TLS reports whether the ingress terminates TLS
+func (i Ingress) TLS() bool {
+	return i.TLSSecret != "" || i.Issuer != ""
+}
+
+// Validate checks the host, path and issuer kind
+func (i Ingress) Validate() error {
+	if errs := validation.IsDNS1123Subdomain(i.Host); len(errs) > 0 {
+		return fmt.Errorf("invalid ingress host %q: %s", i.Host, strings.Join(errs, ", "))
+	}
+	if i.Path != "" && !strings.HasPrefix(i.Path, "/") {
+		return fmt.Errorf("ingress path must start with /: %s", i.Path)
+	}
+	switch i.IssuerKind {
+	case "", "ClusterIssuer", "Issuer":
+	default:
+		return fmt.Errorf("invalid issuer kind: %s", i.IssuerKind)
+	}
+	return nil
+}
+
+// probes returns the request's probes, or the defaults of its service type
+func (r CreateDeploymentRequest) probes() Probes {
+	if r.Probes != nil {
+		return *r.Probes
+	}
+	return DefaultProbes(r.ServiceType, r.Ports)
+}
+
+// Volume types
+const (
+	VolumeEmptyDir  = "empty_dir"
+	VolumeConfigMap = "config_map"
+	VolumeSecret    = "secret"
+	VolumePVC       = "pvc"
+)
+
+// Volume is mounted into the deployment's container. Source names the ConfigMap,
+// Secret or PersistentVolumeClaim and is unused for empty_dir. A pvc volume of a
+// database deployment without a source becomes a volumeClaimTemplate, giving each
+// pod its own claim of Size in StorageClass (the cluster default when empty).
+type Volume struct {
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Source       string `json:"source,omitempty"`
+	MountPath    string `json:"mount_path"`
+	ReadOnly     bool   `json:"read_only,omitempty"`
+	Size         string `json:"size,omitempty"`
+	StorageClass string `json:"storage_class,omitempty"`
+}
+
+// DefaultClaimSize is the size of volumeClaimTemplates that don't set one
+const DefaultClaimSize = "1Gi"
+
+// Validate checks the volume has a name, mount path and a source for its type
+func (v Volume) Validate() error {
+	if v.Name == "" || v.MountPath == "" {
+		return fmt.Errorf("volumes need a name and mount_path")
+	}
+	switch v.Type {
+	case VolumeEmptyDir:
+		return nil
+	case VolumePVC:
+		if v.Size != "" {
+			if _, err := resource.ParseQuantity(v.Size); err != nil {
+				return fmt.Errorf("invalid size for volume %s: %s", v.Name, v.Size)
+			}
+		}
+		return nil
+	case VolumeConfigMap, VolumeSecret:
+		if v.Source == "" {
+			return fmt.Errorf("volume %s needs a source", v.Name)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid type for volume %s: %s", v.Name, v.Type)
+}
+
+// isClaimTemplate reports whether a volume of a database deployment is provisioned per pod
+func (v Volume) isClaimTemplate() bool {
+	return v.Type == VolumePVC && v.Source == ""
+}
+
+// ValidateWorkload checks the ports, volumes, extra containers, probes and ingress of a request. Database deployments run as
+// StatefulSets, which don't support canary or blue-green rollouts; other deployments need
+// an existing claim for pvc volumes.
+func (r CreateDeploymentRequest) ValidateWorkload() error {
+	for _, port := range r.Ports {
+		if err := port.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, volume := range r.Volumes {
+		if err := volume.Validate(); err != nil {
+			return err
+		}
+		if volume.isClaimTemplate() && r.ServiceType != ServiceTypeDatabase {
+			return fmt.Errorf("volume %s needs a source", volume.Name)
+		}
+	}
+	volumes := make(map[string]bool, len(r.Volumes))
+	for _, volume := range r.Volumes {
+		volumes[volume.Name] = true
+	}
+	names := map[string]bool{r.Name: true}
+	for _, container := range append(append([]Container(nil), r.InitContainers...), r.Sidecars...) {
+		if err := container.Validate(); err != nil {
+			return err
+		}
+		if names[container.Name] {
+			return fmt.Errorf("duplicate container name: %s", container.Name)
+		}
+		names[container.Name] = true
+		for _, volume := range container.Volumes {
+			if !volumes[volume] {
+				return fmt.Errorf("container %s mounts unknown volume %s", container.Name, volume)
+			}
+		}
+	}
+	if r.Probes != nil {
+		if err := r.Probes.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.Ingress != nil {
+		if err := r.Ingress.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.ServiceType == ServiceTypeDatabase && isProgressiveStrategy(r.Strategy.Type) {
+		return fmt.Errorf("%s strategy is not supported for database deployments", r.Strategy.Type)
+	}
+	return nil
 }
 
 // ScaleDeploymentRequest represents a request to scale a deployment
@@ -160,3 +522,120 @@ type DeploymentHistory struct {
 	Timestamp    time.Time              `json:"timestamp"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
 }
+
+// NamedHistory is a history entry together with the deployment it belongs to
+type NamedHistory struct {
+	DeploymentHistory
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// Applied is published when a deployment was applied to the cluster, updated in place or
+// had its rollout promoted
+type Applied struct{ NamedHistory }
+
+// Topic names applied deployments on the event bus
+func (Applied) Topic() string { return "deployment.applied" }
+
+// RolledBack is published when a deployment was rolled back to an earlier revision
+type RolledBack struct{ NamedHistory }
+
+// Topic names rolled back deployments on the event bus
+func (RolledBack) Topic() string { return "deployment.rolled_back" }
+
+// Revision represents a Kubernetes rollout revision backed by a ReplicaSet
+type Revision struct {
+	Revision    int64                        `json:"revision"`
+	ReplicaSet  string                       `json:"replica_set"`
+	Images      map[string]string            `json:"images"`      // container name -> image
+	Environment map[string]map[string]string `json:"environment"` // container name -> env vars
+	Replicas    int32                        `json:"replicas"`
+	Current     bool                         `json:"current"`
+	ChangeCause string                       `json:"change_cause,omitempty"`
+	CreatedAt   time.Time                    `json:"created_at"`
+}
+
+// RevisionChange describes a changed value between two revisions
+type RevisionChange struct {
+	Container string `json:"container"`
+	Name      string `json:"name,omitempty"` // env var name, empty for image changes
+	Op        string `json:"op"`             // add, remove, change
+	Old       string `json:"old,omitempty"`
+	New       string `json:"new,omitempty"`
+}
+
+// RevisionDiff represents the image and environment differences between two revisions
+type RevisionDiff struct {
+	FromRevision int64            `json:"from_revision"`
+	ToRevision   int64            `json:"to_revision"`
+	Images       []RevisionChange `json:"images"`
+	Environment  []RevisionChange `json:"environment"`
+}
+
+// RollbackRequest represents a request to roll a deployment back to a revision
+type RollbackRequest struct {
+	Revision int64  `json:"revision"`
+	User     string `json:"user,omitempty"`
+}
+
+// RolloutPhase represents the state of a progressive rollout
+type RolloutPhase string
+
+const (
+	RolloutPhaseProgressing RolloutPhase = "progressing" // secondary deployment is rolling out
+	RolloutPhaseReady       RolloutPhase = "ready"       // secondary deployment is ready to promote
+	RolloutPhasePromoting   RolloutPhase = "promoting"
+	RolloutPhasePromoted    RolloutPhase = "promoted"
+	RolloutPhaseAborted     RolloutPhase = "aborted"
+	RolloutPhaseFailed      RolloutPhase = "failed"
+)
+
+// HealthGatePhase represents the state of a post-apply health gate
+type HealthGatePhase string
+
+const (
+	HealthGatePhaseProgressing HealthGatePhase = "progressing" // new revision is rolling out
+	HealthGatePhaseBaking      HealthGatePhase = "baking"      // rolled out, watched for the bake period
+	HealthGatePhasePassed      HealthGatePhase = "passed"
+	HealthGatePhaseFailed      HealthGatePhase = "failed"
+	HealthGatePhaseRolledBack  HealthGatePhase = "rolled_back" // failed and the previous revision was restored
+	HealthGatePhaseCancelled   HealthGatePhase = "cancelled"   // superseded by a newer apply
+)
+
+// HealthGateRun tracks the health of a deployment's new revision after an apply
+type HealthGateRun struct {
+	ID           string          `json:"id"`
+	DeploymentID string          `json:"deployment_id"`
+	Phase        HealthGatePhase `json:"phase"`
+	Revision     int64           `json:"revision"`
+	BakeSeconds  int             `json:"bake_seconds"`
+	Desired      int32           `json:"desired"`
+	Ready        int32           `json:"ready"`
+	Restarts     int32           `json:"restarts"`
+	// Warning events seen on the new pods, e.g. BackOff or Unhealthy (failed probes)
+	Warnings    []string   `json:"warnings"`
+	Message     string     `json:"message,omitempty"`
+	User        string     `json:"user,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Rollout tracks a canary or blue-green rollout of a deployment
+type Rollout struct {
+	ID             string       `json:"id"`
+	DeploymentID   string       `json:"deployment_id"`
+	Strategy       string       `json:"strategy"`
+	Phase          RolloutPhase `json:"phase"`
+	Secondary      string       `json:"secondary"` // name of the canary/preview deployment
+	StableImage    string       `json:"stable_image"`
+	CandidateImage string       `json:"candidate_image"`
+	Desired        int32        `json:"desired"`
+	Ready          int32        `json:"ready"`
+	Message        string       `json:"message,omitempty"`
+	User           string       `json:"user,omitempty"`
+	StartedAt      time.Time    `json:"started_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	CompletedAt    *time.Time   `json:"completed_at,omitempty"`
+	candidate      Deployment
+}
@@ -0,0 +1,179 @@
+// Package discovery finds the infrastructure services Denshimon monitors. Services opt
+// in with the denshimon.io/monitor annotation and describe their health check with
+// further annotations; overrides stored in SQLite take precedence over both.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Annotations read from services
+const (
+	AnnotationMonitor    = "denshimon.io/monitor"     // "true" to monitor the service
+	AnnotationHealthPath = "denshimon.io/health-path" // HTTP path probed for health
+	AnnotationHealthPort = "denshimon.io/health-port" // port number or name; defaults to the first port
+	AnnotationName       = "denshimon.io/name"        // display name
+	AnnotationType       = "denshimon.io/type"        // e.g. gitea, postgresql
+	AnnotationURL        = "denshimon.io/url"         // where users reach the service
+
+	// Synthetic checks of the URL
+	AnnotationExpectedStatus = "denshimon.io/expected-status"   // final status required; any 2xx or 3xx by default
+	AnnotationKeyword        = "denshimon.io/keyword"           // must appear in the final response body
+	AnnotationMaxResponseMs  = "denshimon.io/max-response-time" // milliseconds before the check warns
+)
+
+// Where a target's monitoring comes from
+const (
+	SourceAnnotation = "annotation"
+	SourceOverride   = "override"
+)
+
+// probeTimeout bounds a health check request
+const probeTimeout = 5 * time.Second
+
+// Target is a discovered service with its overrides applied
+type Target struct {
+	Namespace   string            `json:"namespace"`
+	Name        string            `json:"name"`
+	DisplayName string            `json:"displayName"`
+	Type        string            `json:"type"`
+	HealthPath  string            `json:"healthPath,omitempty"`
+	HealthPort  int               `json:"healthPort,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	Source      string            `json:"source"`
+	Selector    map[string]string `json:"selector,omitempty"`
+	ClusterIP   string            `json:"clusterIP,omitempty"`
+
+	// Assertions of the synthetic check of URL
+	ExpectedStatus int    `json:"expectedStatus,omitempty"`
+	Keyword        string `json:"keyword,omitempty"`
+	MaxResponseMs  int    `json:"maxResponseMs,omitempty"`
+}
+
+// Key identifies a target as namespace/name
+func (t Target) Key() string {
+	return t.Namespace + "/" + t.Name
+}
+
+// Discover returns the services to monitor, sorted by namespace and name. A service is
+// monitored when annotated denshimon.io/monitor=true, unless an override says otherwise;
+// an override can also monitor a service without the annotation.
+func Discover(services []corev1.Service, overrides map[string]Override) []Target {
+	targets := []Target{}
+	for _, svc := range services {
+		annotations := svc.Annotations
+		override, hasOverride := overrides[svc.Namespace+"/"+svc.Name]
+
+		monitored := strings.EqualFold(annotations[AnnotationMonitor], "true")
+		source := SourceAnnotation
+		if hasOverride && override.Monitor != nil {
+			monitored = *override.Monitor
+			source = SourceOverride
+		}
+		if !monitored {
+			continue
+		}
+
+		target := Target{
+			Namespace:   svc.Namespace,
+			Name:        svc.Name,
+			DisplayName: first(override.DisplayName, annotations[AnnotationName], svc.Name),
+			Type:        first(override.Type, annotations[AnnotationType], svc.Labels["app.kubernetes.io/name"], "generic"),
+			HealthPath:  first(override.HealthPath, annotations[AnnotationHealthPath]),
+			URL:         first(override.URL, annotations[AnnotationURL]),
+			Source:      source,
+			Selector:    svc.Spec.Selector,
+			Keyword:     annotations[AnnotationKeyword],
+		}
+		target.ExpectedStatus, _ = strconv.Atoi(annotations[AnnotationExpectedStatus])
+		target.MaxResponseMs, _ = strconv.Atoi(annotations[AnnotationMaxResponseMs])
+		if svc.Spec.ClusterIP != corev1.ClusterIPNone {
+			target.ClusterIP = svc.Spec.ClusterIP
+		}
+		if target.HealthPath != "" {
+			target.HealthPort = override.HealthPort
+			if target.HealthPort == 0 {
+				target.HealthPort = resolvePort(svc, annotations[AnnotationHealthPort])
+			}
+		}
+		targets = append(targets, target)
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Key() < targets[j].Key() })
+	return targets
+}
+
+// resolvePort resolves a port number or name against a service's ports, defaulting to
+// its first port
+func resolvePort(svc corev1.Service, port string) int {
+	if n, err := strconv.Atoi(port); err == nil {
+		return n
+	}
+	for _, p := range svc.Spec.Ports {
+		if port == "" || p.Name == port {
+			return int(p.Port)
+		}
+	}
+	return 0
+}
+
+func first(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// HealthCheck is the result of probing a target's health path
+type HealthCheck struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	LatencyMs  int64  `json:"latencyMs"`
+	Healthy    bool   `json:"healthy"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Probe requests a target's health path on host, its cluster IP or a pod IP. Any 2xx or
+// 3xx response is healthy.
+func Probe(ctx context.Context, client *http.Client, target Target, host string) HealthCheck {
+	path := target.HealthPath
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	check := HealthCheck{URL: "http://" + net.JoinHostPort(host, strconv.Itoa(target.HealthPort)) + path}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, check.URL, nil)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	started := time.Now()
+	resp, err := client.Do(req)
+	check.LatencyMs = time.Since(started).Milliseconds()
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	resp.Body.Close()
+
+	check.StatusCode = resp.StatusCode
+	check.Healthy = resp.StatusCode >= 200 && resp.StatusCode < 400
+	if !check.Healthy {
+		check.Error = fmt.Sprintf("health check returned %d", resp.StatusCode)
+	}
+	return check
+}
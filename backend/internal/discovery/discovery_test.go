@@ -0,0 +1,214 @@
+package discovery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/archellir/denshimon/internal/database"
+	"github.com/archellir/denshimon/internal/database/migrate"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func service(namespace, name string, annotations map[string]string, ports ...corev1.ServicePort) corev1.Service {
+	return corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Annotations: annotations,
+			Labels: map[string]string{"app.kubernetes.io/name": name}},
+		Spec: corev1.ServiceSpec{Selector: map[string]string{"app": name}, ClusterIP: "10.0.0.1", Ports: ports},
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	no := false
+	yes := true
+	services := []corev1.Service{
+		service("apps", "gitea", map[string]string{
+			AnnotationMonitor:    "true",
+			AnnotationHealthPath: "/api/healthz",
+			AnnotationHealthPort: "http",
+			AnnotationName:       "Gitea",
+		}, corev1.ServicePort{Name: "ssh", Port: 22}, corev1.ServicePort{Name: "http", Port: 3000}),
+		service("apps", "memos", map[string]string{AnnotationMonitor: "true", AnnotationHealthPath: "/healthz"},
+			corev1.ServicePort{Name: "web", Port: 5230}),
+		service("apps", "umami", map[string]string{AnnotationMonitor: "true"}),
+		service("db", "postgres", nil, corev1.ServicePort{Port: 5432}),
+		service("kube-system", "kube-dns", nil),
+	}
+	overrides := map[string]Override{
+		"apps/umami":  {Monitor: &no},
+		"db/postgres": {Monitor: &yes, DisplayName: "PostgreSQL", Type: "postgresql"},
+		"apps/memos":  {HealthPort: 8080, URL: "https://memos.example.com"},
+	}
+
+	targets := Discover(services, overrides)
+	if len(targets) != 3 {
+		t.Fatalf("expected gitea, memos and postgres, got %+v", targets)
+	}
+	gitea, memos, postgres := targets[0], targets[1], targets[2]
+	if gitea.Key() != "apps/gitea" || gitea.DisplayName != "Gitea" || gitea.Type != "gitea" || gitea.HealthPort != 3000 || gitea.Source != SourceAnnotation {
+		t.Errorf("unexpected gitea target %+v", gitea)
+	}
+	if memos.HealthPath != "/healthz" || memos.HealthPort != 8080 || memos.URL != "https://memos.example.com" || memos.Source != SourceAnnotation {
+		t.Errorf("unexpected memos target %+v", memos)
+	}
+	if postgres.DisplayName != "PostgreSQL" || postgres.Type != "postgresql" || postgres.HealthPort != 0 || postgres.Source != SourceOverride {
+		t.Errorf("unexpected postgres target %+v", postgres)
+	}
+}
+
+func TestOverrideStore(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "discovery.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := migrate.Up(ctx, db, database.Migrations); err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(db)
+
+	if _, err := store.Upsert(ctx, &Override{Namespace: "apps", Name: "gitea", HealthPort: 70000}); !errors.Is(err, ErrInvalidOverride) {
+		t.Errorf("expected an out-of-range port to be refused, got %v", err)
+	}
+	monitor := true
+	if _, err := store.Upsert(ctx, &Override{Namespace: "apps", Name: "gitea", Monitor: &monitor, Type: "git"}); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := store.Upsert(ctx, &Override{Namespace: "apps", Name: "gitea", HealthPath: "/api/healthz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Monitor != nil || saved.Type != "" || saved.HealthPath != "/api/healthz" {
+		t.Errorf("expected the override to be replaced, got %+v", saved)
+	}
+
+	overrides, err := store.Map(ctx)
+	if err != nil || len(overrides) != 1 || overrides["apps/gitea"].HealthPath != "/api/healthz" {
+		t.Errorf("unexpected overrides %+v (%v)", overrides, err)
+	}
+	if err := store.Delete(ctx, "apps", "gitea"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "apps", "gitea"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected deleting a missing override to fail, got %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	healthy := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" || !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	healthPort, _ := strconv.Atoi(port)
+
+	pod := func(name string, ready corev1.ConditionStatus) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: name, Labels: map[string]string{"app": "memos"}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: host,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}}},
+		}
+	}
+	pods := []corev1.Pod{pod("memos-1", corev1.ConditionFalse), pod("memos-0", corev1.ConditionTrue)}
+	pods = append(pods, corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "memos-x", Labels: map[string]string{"app": "memos"}}})
+
+	s := NewService(nil, nil)
+	target := Target{Namespace: "apps", Name: "memos", DisplayName: "Memos", Selector: map[string]string{"app": "memos"},
+		HealthPath: "healthz", HealthPort: healthPort}
+	matched := MatchPods(target, pods)
+	if len(matched) != 2 {
+		t.Fatalf("expected the two pods in the namespace to match, got %d", len(matched))
+	}
+
+	health := s.check(context.Background(), target, matched)
+	if health.Status != StatusWarning || health.Pods != 2 || health.ReadyPods != 1 || health.HealthCheck == nil || !health.HealthCheck.Healthy {
+		t.Errorf("expected a warning with a passing health check, got %+v", health)
+	}
+
+	healthy = false
+	health = s.check(context.Background(), target, matched[1:])
+	if health.Status != StatusCritical || health.HealthCheck.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected a failed health check to be critical, got %+v", health)
+	}
+
+	if _, err := s.Check(context.Background()); !errors.Is(err, ErrNoCluster) {
+		t.Errorf("expected discovery without a cluster to fail, got %v", err)
+	}
+}
+
+func TestSynthetic(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/user/login", http.StatusFound)
+		case "/slow":
+			time.Sleep(20 * time.Millisecond)
+		case "/user/login":
+			w.Write([]byte("<title>Sign In - Gitea</title>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s := NewService(nil, nil)
+	s.transport = server.Client().Transport
+	ctx := context.Background()
+	target := Target{Namespace: "apps", Name: "gitea", DisplayName: "Gitea", URL: server.URL + "/", Keyword: "Sign In"}
+
+	result := s.runSynthetic(ctx, target)
+	if result.Status != StatusHealthy || !result.Accessible || result.HTTPStatus != http.StatusOK || result.Domain != "127.0.0.1" {
+		t.Fatalf("expected a healthy check, got %+v", result)
+	}
+	if len(result.Redirects) != 1 || result.Redirects[0].StatusCode != http.StatusFound || result.FinalURL != server.URL+"/user/login" {
+		t.Errorf("unexpected redirect chain %+v to %s", result.Redirects, result.FinalURL)
+	}
+	if result.KeywordFound == nil || !*result.KeywordFound {
+		t.Error("expected the keyword to be found")
+	}
+	if !result.SSLValid || result.TLS == nil || !result.TLS.Verified || result.TLS.Version == "" || result.TLS.DaysRemaining < certificateWarningDays {
+		t.Errorf("unexpected TLS details %+v", result.TLS)
+	}
+
+	target.Keyword = "Dashboard"
+	if result = s.runSynthetic(ctx, target); result.Status != StatusCritical || *result.KeywordFound || result.Error == "" {
+		t.Errorf("expected a missing keyword to be critical, got %+v", result)
+	}
+
+	target.URL, target.Keyword, target.MaxResponseMs = server.URL+"/slow", "", 1
+	if result = s.runSynthetic(ctx, target); result.Status != StatusWarning {
+		t.Errorf("expected a slow response to warn, got %+v", result)
+	}
+
+	target.URL, target.ExpectedStatus = server.URL+"/missing", http.StatusOK
+	if result = s.runSynthetic(ctx, target); result.Status != StatusCritical || result.HTTPStatus != http.StatusNotFound {
+		t.Errorf("expected an unexpected status to be critical, got %+v", result)
+	}
+
+	// Without the test server's CA the certificate fails verification but is still described
+	s.transport = nil
+	target.URL, target.ExpectedStatus = server.URL+"/", 0
+	result = s.runSynthetic(ctx, target)
+	if result.Status != StatusCritical || result.Accessible || result.SSLValid || result.TLS == nil || result.TLS.Verified || result.TLS.NotAfter.IsZero() {
+		t.Errorf("expected an untrusted certificate to be critical and described, got %+v (%+v)", result, result.TLS)
+	}
+
+	if status := WorstStatus(StatusHealthy, StatusWarning, StatusHealthy); status != StatusWarning {
+		t.Errorf("expected warning, got %s", status)
+	}
+	if status := WorstStatus(); status != StatusUnknown {
+		t.Errorf("expected unknown without statuses, got %s", status)
+	}
+}
@@ -0,0 +1,202 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ErrNoCluster is returned when discovering services without a Kubernetes client
+var ErrNoCluster = errors.New("kubernetes client not available")
+
+// Service statuses
+const (
+	StatusHealthy  = "healthy"
+	StatusWarning  = "warning"
+	StatusCritical = "critical"
+	StatusUnknown  = "unknown"
+)
+
+// ServiceHealth is the state of a discovered service: its pods and, if it has a health
+// path, the result of probing it
+type ServiceHealth struct {
+	ID          string       `json:"id"` // namespace/name
+	Name        string       `json:"name"`
+	Type        string       `json:"type"`
+	Status      string       `json:"status"`
+	LastChecked time.Time    `json:"lastChecked"`
+	URL         string       `json:"url,omitempty"`
+	Namespace   string       `json:"namespace"`
+	Service     string       `json:"service"`
+	Source      string       `json:"source"`
+	Pods        int          `json:"pods"`
+	ReadyPods   int          `json:"readyPods"`
+	NodeName    string       `json:"nodeName,omitempty"`
+	PodIP       string       `json:"podIP,omitempty"`
+	StartTime   *metav1.Time `json:"startTime,omitempty"`
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+}
+
+// Service discovers monitored services in the cluster and checks their health
+type Service struct {
+	k8sClient  *k8s.Client
+	store      *Store
+	httpClient *http.Client
+	transport  http.RoundTripper // of synthetic checks; nil uses http.DefaultTransport
+
+	syntheticMu sync.RWMutex
+	synthetics  map[string]SyntheticResult
+}
+
+// NewService creates a discovery service; without a store no overrides apply
+func NewService(k8sClient *k8s.Client, store *Store) *Service {
+	return &Service{
+		k8sClient: k8sClient,
+		store:     store,
+		// Health paths may redirect to a login page; the redirect itself means the service is up
+		httpClient: &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }},
+	}
+}
+
+// Store returns the override store, or nil
+func (s *Service) Store() *Store {
+	return s.store
+}
+
+// Targets returns the services to monitor with their overrides applied
+func (s *Service) Targets(ctx context.Context) ([]Target, error) {
+	if s.k8sClient == nil {
+		return nil, ErrNoCluster
+	}
+	services, err := s.k8sClient.ListServices(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	overrides := map[string]Override{}
+	if s.store != nil {
+		if overrides, err = s.store.Map(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return Discover(services.Items, overrides), nil
+}
+
+// Check discovers the monitored services and checks each one
+func (s *Service) Check(ctx context.Context) ([]ServiceHealth, error) {
+	targets, err := s.Targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := s.k8sClient.ListPods(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	health := make([]ServiceHealth, 0, len(targets))
+	for _, target := range targets {
+		health = append(health, s.check(ctx, target, MatchPods(target, pods.Items)))
+	}
+	return health, nil
+}
+
+// check works out a service's status from its pods, then probes its health path: all
+// pods ready is healthy, some is a warning and none, or a failed probe, is critical
+func (s *Service) check(ctx context.Context, target Target, pods []corev1.Pod) ServiceHealth {
+	health := ServiceHealth{
+		ID:          target.Key(),
+		Name:        target.DisplayName,
+		Type:        target.Type,
+		Status:      StatusUnknown,
+		LastChecked: time.Now().UTC(),
+		URL:         target.URL,
+		Namespace:   target.Namespace,
+		Service:     target.Name,
+		Source:      target.Source,
+		Pods:        len(pods),
+	}
+
+	var ready *corev1.Pod
+	for i := range pods {
+		if podReady(&pods[i]) {
+			health.ReadyPods++
+			if ready == nil {
+				ready = &pods[i]
+			}
+		}
+	}
+	if ready == nil && len(pods) > 0 {
+		ready = &pods[0]
+	}
+	if ready != nil {
+		health.NodeName = ready.Spec.NodeName
+		health.PodIP = ready.Status.PodIP
+		health.StartTime = ready.Status.StartTime
+	}
+
+	switch {
+	case len(target.Selector) == 0:
+		// Services without a selector have no pods to judge them by
+	case health.ReadyPods == 0:
+		health.Status = StatusCritical
+	case health.ReadyPods < health.Pods:
+		health.Status = StatusWarning
+	default:
+		health.Status = StatusHealthy
+	}
+
+	if target.HealthPath == "" || target.HealthPort == 0 {
+		return health
+	}
+	host := target.ClusterIP
+	if host == "" {
+		host = health.PodIP
+	}
+	if host == "" {
+		health.HealthCheck = &HealthCheck{Error: "service has no cluster IP or ready pod to probe"}
+		health.Status = StatusCritical
+		return health
+	}
+	check := Probe(ctx, s.httpClient, target, host)
+	health.HealthCheck = &check
+	if !check.Healthy {
+		health.Status = StatusCritical
+	} else if health.Status == StatusUnknown {
+		health.Status = StatusHealthy
+	}
+	return health
+}
+
+// MatchPods returns the pods a target's selector picks in its namespace
+func MatchPods(target Target, pods []corev1.Pod) []corev1.Pod {
+	if len(target.Selector) == 0 {
+		return nil
+	}
+	selector := labels.SelectorFromSet(target.Selector)
+	var matched []corev1.Pod
+	for _, pod := range pods {
+		if pod.Namespace == target.Namespace && selector.Matches(labels.Set(pod.Labels)) {
+			matched = append(matched, pod)
+		}
+	}
+	return matched
+}
+
+func podReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package discovery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidOverride is returned for overrides missing a namespace or name, or with an
+// out-of-range port
+var ErrInvalidOverride = errors.New("invalid service override")
+
+// Override changes how a service is discovered. Monitor set to true monitors the service
+// without the annotation and false stops monitoring it; nil follows the annotation. Empty
+// fields fall back to the service's annotations.
+type Override struct {
+	Namespace   string    `json:"namespace"`
+	Name        string    `json:"name"`
+	Monitor     *bool     `json:"monitor,omitempty"`
+	DisplayName string    `json:"displayName,omitempty"`
+	Type        string    `json:"type,omitempty"`
+	HealthPath  string    `json:"healthPath,omitempty"`
+	HealthPort  int       `json:"healthPort,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Store persists service overrides in the service_overrides table
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates an override store; the table is created by the core migrations
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+const overrideColumns = `namespace, name, monitor, display_name, type, health_path, health_port, url, updated_at`
+
+func scanOverride(scanner interface{ Scan(...interface{}) error }) (*Override, error) {
+	var o Override
+	var monitor sql.NullBool
+	if err := scanner.Scan(&o.Namespace, &o.Name, &monitor, &o.DisplayName, &o.Type, &o.HealthPath,
+		&o.HealthPort, &o.URL, &o.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if monitor.Valid {
+		o.Monitor = &monitor.Bool
+	}
+	return &o, nil
+}
+
+// List returns all overrides ordered by namespace and name
+func (s *Store) List(ctx context.Context) ([]Override, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+overrideColumns+` FROM service_overrides ORDER BY namespace, name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service overrides: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := []Override{}
+	for rows.Next() {
+		o, err := scanOverride(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan service override: %w", err)
+		}
+		overrides = append(overrides, *o)
+	}
+	return overrides, rows.Err()
+}
+
+// Map returns all overrides keyed by namespace/name, as Discover takes them
+func (s *Store) Map(ctx context.Context) (map[string]Override, error) {
+	overrides, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]Override, len(overrides))
+	for _, o := range overrides {
+		byKey[o.Namespace+"/"+o.Name] = o
+	}
+	return byKey, nil
+}
+
+// Get returns the override of a service, or sql.ErrNoRows if it has none
+func (s *Store) Get(ctx context.Context, namespace, name string) (*Override, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+overrideColumns+` FROM service_overrides WHERE namespace = ? AND name = ?`,
+		namespace, name)
+	return scanOverride(row)
+}
+
+// Upsert creates or replaces the override of a service
+func (s *Store) Upsert(ctx context.Context, o *Override) (*Override, error) {
+	o.HealthPath = strings.TrimSpace(o.HealthPath)
+	switch {
+	case o.Namespace == "" || o.Name == "":
+		return nil, fmt.Errorf("%w: namespace and name are required", ErrInvalidOverride)
+	case o.HealthPort < 0 || o.HealthPort > 65535:
+		return nil, fmt.Errorf("%w: health port must be between 1 and 65535", ErrInvalidOverride)
+	}
+
+	var monitor sql.NullBool
+	if o.Monitor != nil {
+		monitor = sql.NullBool{Bool: *o.Monitor, Valid: true}
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO service_overrides (namespace, name, monitor, display_name, type, health_path, health_port, url, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (namespace, name) DO UPDATE SET
+			monitor = excluded.monitor, display_name = excluded.display_name, type = excluded.type,
+			health_path = excluded.health_path, health_port = excluded.health_port, url = excluded.url,
+			updated_at = excluded.updated_at
+	`, o.Namespace, o.Name, monitor, o.DisplayName, o.Type, o.HealthPath, o.HealthPort, o.URL, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to save service override: %w", err)
+	}
+	return s.Get(ctx, o.Namespace, o.Name)
+}
+
+// Delete removes the override of a service, or returns sql.ErrNoRows if it has none
+func (s *Store) Delete(ctx context.Context, namespace, name string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM service_overrides WHERE namespace = ? AND name = ?`, namespace, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete service override: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
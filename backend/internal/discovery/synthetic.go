@@ -0,0 +1,310 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Synthetic check limits and defaults
+const (
+	DefaultSyntheticInterval = time.Minute
+	syntheticTimeout         = 10 * time.Second
+	syntheticConcurrency     = 8
+	defaultMaxResponseMs     = 2000
+	maxRedirects             = 10
+	maxSyntheticBody         = 1 << 20
+	// certificateWarningDays is how close to expiry a certificate makes the check warn
+	certificateWarningDays = 14
+)
+
+// SyntheticResult is the outcome of requesting a discovered service's URL the way a
+// browser would: following redirects, verifying TLS and reading the page
+type SyntheticResult struct {
+	ID           string      `json:"id"` // namespace/name of the service
+	Name         string      `json:"name"`
+	Domain       string      `json:"domain"`
+	URL          string      `json:"url"`
+	FinalURL     string      `json:"finalUrl,omitempty"`
+	Status       string      `json:"status"` // healthy, warning or critical
+	Accessible   bool        `json:"accessible"`
+	ResponseTime int64       `json:"responseTime"` // ms until the final response's headers
+	HTTPStatus   int         `json:"httpStatus,omitempty"`
+	Redirects    []Redirect  `json:"redirects,omitempty"`
+	SSLValid     bool        `json:"sslValid"`
+	TLS          *TLSDetails `json:"tls,omitempty"`
+	KeywordFound *bool       `json:"keywordFound,omitempty"`
+	Failures     []string    `json:"failures,omitempty"`
+	Error        string      `json:"error,omitempty"` // the failures joined
+	LastChecked  time.Time   `json:"lastChecked"`
+}
+
+// Redirect is one hop of a redirect chain
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode"`
+	Location   string `json:"location"`
+}
+
+// TLSDetails describes the connection and leaf certificate of an https URL. Invalid
+// certificates are still described, with Verified false.
+type TLSDetails struct {
+	Version       string    `json:"version"`
+	CipherSuite   string    `json:"cipherSuite"`
+	Subject       string    `json:"subject"`
+	Issuer        string    `json:"issuer"`
+	DNSNames      []string  `json:"dnsNames,omitempty"`
+	NotBefore     time.Time `json:"notBefore"`
+	NotAfter      time.Time `json:"notAfter"`
+	DaysRemaining int       `json:"daysRemaining"`
+	Verified      bool      `json:"verified"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// StartSynthetics checks the URL of every discovered service each interval until ctx is
+// done, keeping the latest results for Synthetics
+func (s *Service) StartSynthetics(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.RunSynthetics(ctx); err != nil && !errors.Is(err, ErrNoCluster) {
+				slog.Error("Failed to run synthetic checks", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunSynthetics checks the URL of every discovered service now
+func (s *Service) RunSynthetics(ctx context.Context) ([]SyntheticResult, error) {
+	targets, err := s.Targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]SyntheticResult, len(targets))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, syntheticConcurrency)
+	for _, target := range targets {
+		if target.URL == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(target Target) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			result := s.runSynthetic(ctx, target)
+			mu.Lock()
+			results[target.Key()] = result
+			mu.Unlock()
+		}(target)
+	}
+	wg.Wait()
+
+	s.syntheticMu.Lock()
+	s.synthetics = results
+	s.syntheticMu.Unlock()
+	return s.Synthetics(), nil
+}
+
+// Synthetics returns the latest synthetic check results, sorted by service
+func (s *Service) Synthetics() []SyntheticResult {
+	s.syntheticMu.RLock()
+	defer s.syntheticMu.RUnlock()
+
+	results := make([]SyntheticResult, 0, len(s.synthetics))
+	for _, result := range s.synthetics {
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+	return results
+}
+
+// runSynthetic requests a target's URL and checks the final status, the response time,
+// the keyword and the TLS certificate. Unreachable URLs, a wrong status, a missing keyword
+// and invalid or expired certificates are critical; slow responses and certificates close
+// to expiry are warnings.
+func (s *Service) runSynthetic(ctx context.Context, target Target) (result SyntheticResult) {
+	result = SyntheticResult{ID: target.Key(), Name: target.DisplayName, URL: target.URL, LastChecked: time.Now().UTC()}
+	if u, err := url.Parse(target.URL); err == nil {
+		result.Domain = u.Hostname()
+	}
+	var critical, warning []string
+	defer func() {
+		result.Failures = append(critical, warning...)
+		result.Error = strings.Join(result.Failures, "; ")
+		switch {
+		case len(critical) > 0:
+			result.Status = StatusCritical
+		case len(warning) > 0:
+			result.Status = StatusWarning
+		default:
+			result.Status = StatusHealthy
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, syntheticTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL, nil)
+	if err != nil {
+		critical = append(critical, err.Error())
+		return result
+	}
+	req.Header.Set("User-Agent", "denshimon-synthetic")
+
+	client := &http.Client{
+		Transport: s.transport,
+		CheckRedirect: func(next *http.Request, via []*http.Request) error {
+			redirect := next.Response
+			result.Redirects = append(result.Redirects, Redirect{
+				URL:        redirect.Request.URL.String(),
+				StatusCode: redirect.StatusCode,
+				Location:   next.URL.String(),
+			})
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		},
+	}
+
+	started := time.Now()
+	resp, err := client.Do(req)
+	result.ResponseTime = time.Since(started).Milliseconds()
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		var unknownAuthority x509.UnknownAuthorityError
+		var hostnameErr x509.HostnameError
+		var invalidErr x509.CertificateInvalidError
+		if errors.As(err, &certErr) || errors.As(err, &unknownAuthority) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+			result.TLS = inspectTLS(ctx, lastURL(target.URL, result.Redirects), err)
+		}
+		critical = append(critical, fmt.Sprintf("unreachable: %v", err))
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.Accessible = true
+	result.HTTPStatus = resp.StatusCode
+	result.FinalURL = resp.Request.URL.String()
+
+	if target.ExpectedStatus != 0 {
+		if resp.StatusCode != target.ExpectedStatus {
+			critical = append(critical, fmt.Sprintf("status %d, expected %d", resp.StatusCode, target.ExpectedStatus))
+		}
+	} else if resp.StatusCode >= 400 {
+		critical = append(critical, fmt.Sprintf("status %d", resp.StatusCode))
+	}
+
+	if target.Keyword != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxSyntheticBody))
+		found := err == nil && bytes.Contains(body, []byte(target.Keyword))
+		result.KeywordFound = &found
+		if !found {
+			critical = append(critical, fmt.Sprintf("keyword %q not found in response", target.Keyword))
+		}
+	}
+
+	maxResponseMs := target.MaxResponseMs
+	if maxResponseMs <= 0 {
+		maxResponseMs = defaultMaxResponseMs
+	}
+	if result.ResponseTime > int64(maxResponseMs) {
+		warning = append(warning, fmt.Sprintf("response took %dms, over %dms", result.ResponseTime, maxResponseMs))
+	}
+
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		result.TLS = describeTLS(resp.TLS, true)
+		result.SSLValid = true
+		switch days := result.TLS.DaysRemaining; {
+		case days < 0:
+			result.SSLValid = false
+			critical = append(critical, "certificate expired")
+		case days < certificateWarningDays:
+			warning = append(warning, fmt.Sprintf("certificate expires in %d days", days))
+		}
+	}
+	return result
+}
+
+// inspectTLS describes the certificate of a URL that failed verification
+func inspectTLS(ctx context.Context, rawURL string, verifyErr error) *TLSDetails {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" {
+		return nil
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: true}}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	details := &TLSDetails{Error: verifyErr.Error()}
+	if err != nil {
+		return details
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	if len(state.PeerCertificates) > 0 {
+		details = describeTLS(&state, false)
+		details.Error = verifyErr.Error()
+	}
+	return details
+}
+
+func describeTLS(state *tls.ConnectionState, verified bool) *TLSDetails {
+	leaf := state.PeerCertificates[0]
+	return &TLSDetails{
+		Version:       tls.VersionName(state.Version),
+		CipherSuite:   tls.CipherSuiteName(state.CipherSuite),
+		Subject:       leaf.Subject.CommonName,
+		Issuer:        leaf.Issuer.CommonName,
+		DNSNames:      leaf.DNSNames,
+		NotBefore:     leaf.NotBefore,
+		NotAfter:      leaf.NotAfter,
+		DaysRemaining: int(time.Until(leaf.NotAfter).Hours() / 24),
+		Verified:      verified,
+	}
+}
+
+// lastURL is the URL requested last: the target of the final redirect, if any
+func lastURL(rawURL string, redirects []Redirect) string {
+	if len(redirects) > 0 {
+		return redirects[len(redirects)-1].Location
+	}
+	return rawURL
+}
+
+// WorstStatus returns the most severe of the statuses: critical, then warning, then
+// healthy; unknown when there are none
+func WorstStatus(statuses ...string) string {
+	worst := StatusUnknown
+	rank := map[string]int{StatusUnknown: 0, StatusHealthy: 1, StatusWarning: 2, StatusCritical: 3}
+	for _, status := range statuses {
+		if rank[status] > rank[worst] {
+			worst = status
+		}
+	}
+	return worst
+}
@@ -0,0 +1,298 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/websocket"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ExternalChangeReason is the reason external changes are recorded under
+const ExternalChangeReason = "ExternalChange"
+
+// DefaultIgnoredManagers are field managers of cluster components whose routine updates
+// (rollout revisions, endpoints) are not changes made by people
+var DefaultIgnoredManagers = []string{"kube-controller-manager", "kube-scheduler", "kubelet"}
+
+const lastAppliedPath = "metadata.annotations.kubectl.kubernetes.io/last-applied-configuration"
+
+// maxChangedFields caps the fields listed in an external change message
+const maxChangedFields = 10
+
+// ExternalChange is a modification made with a field manager other than Denshimon's,
+// such as kubectl edit or another controller
+type ExternalChange struct {
+	Namespace       string
+	Kind            string
+	Name            string
+	UID             string
+	ResourceVersion string
+	Manager         string
+	Operation       string   // Update or Apply
+	Fields          []string // fields the manager owns, e.g. spec.replicas
+	Annotations     map[string]string
+	Application     string // gitops application tracking the resource, if any
+	Time            time.Time
+}
+
+// ChangeWatcher watches Deployments, Services and ConfigMaps for modifications whose field
+// manager is not Denshimon's and records them as ExternalChange events
+type ChangeWatcher struct {
+	client     kubernetes.Interface
+	store      *Store
+	ownManager string
+	ignored    map[string]bool
+	track      func(ctx context.Context, change ExternalChange) string
+	onDrift    func(ctx context.Context, change ExternalChange)
+	hub        *websocket.Hub
+	ctx        context.Context
+}
+
+// NewChangeWatcher creates a watcher attributing changes made with field managers starting
+// with ownManager to Denshimon. Changes are recorded in store when it is set.
+func NewChangeWatcher(client kubernetes.Interface, store *Store, ownManager string) *ChangeWatcher {
+	w := &ChangeWatcher{client: client, store: store, ownManager: ownManager}
+	w.SetIgnoredManagers(DefaultIgnoredManagers)
+	return w
+}
+
+// SetIgnoredManagers sets field managers whose changes are not reported
+func (w *ChangeWatcher) SetIgnoredManagers(managers []string) {
+	w.ignored = make(map[string]bool, len(managers))
+	for _, manager := range managers {
+		w.ignored[manager] = true
+	}
+}
+
+// SetHub sets where external changes are streamed live, as events on the events channel
+func (w *ChangeWatcher) SetHub(hub *websocket.Hub) {
+	w.hub = hub
+}
+
+// SetTracker sets how the gitops application tracking a changed resource is found; it
+// returns an empty string for untracked resources
+func (w *ChangeWatcher) SetTracker(track func(ctx context.Context, change ExternalChange) string) {
+	w.track = track
+}
+
+// OnDrift registers a callback for external changes to resources tracked by a gitops application
+func (w *ChangeWatcher) OnDrift(fn func(ctx context.Context, change ExternalChange)) {
+	w.onDrift = fn
+}
+
+// Start watches until ctx is cancelled. Objects present when it starts are not reported.
+func (w *ChangeWatcher) Start(ctx context.Context) {
+	w.ctx = ctx
+	factory := informers.NewSharedInformerFactory(w.client, 0)
+	for kind, informer := range map[string]cache.SharedIndexInformer{
+		"Deployment": factory.Apps().V1().Deployments().Informer(),
+		"Service":    factory.Core().V1().Services().Informer(),
+		"ConfigMap":  factory.Core().V1().ConfigMaps().Informer(),
+	} {
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(oldObj, newObj interface{}) { w.handleUpdate(kind, oldObj, newObj) },
+		})
+	}
+	factory.Start(ctx.Done())
+}
+
+func (w *ChangeWatcher) handleUpdate(kind string, oldObj, newObj interface{}) {
+	oldMeta, okOld := oldObj.(metav1.Object)
+	newMeta, okNew := newObj.(metav1.Object)
+	if !okOld || !okNew || oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+		return // periodic resync
+	}
+	for _, change := range w.ExternalChanges(kind, oldMeta, newMeta) {
+		w.report(change)
+	}
+}
+
+// ExternalChanges compares the managed fields of two versions of an object and returns a
+// change for every manager entry that is new or updated and neither Denshimon's nor ignored.
+// Status updates are left out; scale updates (kubectl scale) are not.
+func (w *ChangeWatcher) ExternalChanges(kind string, oldMeta, newMeta metav1.Object) []ExternalChange {
+	type entryKey struct{ manager, operation, subresource string }
+	previous := make(map[entryKey]metav1.ManagedFieldsEntry)
+	for _, entry := range oldMeta.GetManagedFields() {
+		previous[entryKey{entry.Manager, string(entry.Operation), entry.Subresource}] = entry
+	}
+
+	var changes []ExternalChange
+	for _, entry := range newMeta.GetManagedFields() {
+		if entry.Subresource == "status" || w.ignored[entry.Manager] ||
+			(w.ownManager != "" && strings.HasPrefix(entry.Manager, w.ownManager)) {
+			continue
+		}
+		// An entry's fields also shrink when another manager takes some over, so only a new
+		// timestamp means the manager made a change
+		if old, ok := previous[entryKey{entry.Manager, string(entry.Operation), entry.Subresource}]; ok &&
+			timeEqual(old.Time, entry.Time) {
+			continue
+		}
+		change := ExternalChange{
+			Namespace:       newMeta.GetNamespace(),
+			Kind:            kind,
+			Name:            newMeta.GetName(),
+			UID:             string(newMeta.GetUID()),
+			ResourceVersion: newMeta.GetResourceVersion(),
+			Manager:         entry.Manager,
+			Operation:       string(entry.Operation),
+			Fields:          managedPaths(entry.FieldsV1),
+			Annotations:     newMeta.GetAnnotations(),
+			Time:            time.Now(),
+		}
+		if entry.Time != nil {
+			change.Time = entry.Time.Time
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func (w *ChangeWatcher) report(change ExternalChange) {
+	ctx := w.ctx
+	if w.track != nil {
+		change.Application = w.track(ctx, change)
+	}
+	slog.Info("External change detected", "kind", change.Kind, "namespace", change.Namespace, "name", change.Name,
+		"manager", change.Manager, "application", change.Application)
+
+	event := change.Event()
+	if w.store != nil {
+		if err := w.store.Record(ctx, []Event{event}); err != nil {
+			slog.Error("Failed to record external change", "error", err)
+		}
+	}
+	if w.hub != nil {
+		w.hub.Broadcast(websocket.MessageTypeEvents, event)
+	}
+	if change.Application != "" && w.onDrift != nil {
+		w.onDrift(ctx, change)
+	}
+}
+
+// Event records the change as a Kubernetes-style event, a Warning when it drifts a
+// resource from its gitops application
+func (c ExternalChange) Event() Event {
+	eventType := "Normal"
+	if c.Application != "" {
+		eventType = "Warning"
+	}
+	return Event{
+		UID:       c.UID + "/" + c.ResourceVersion + "/" + c.Manager,
+		Namespace: c.Namespace,
+		Kind:      c.Kind,
+		Name:      c.Name,
+		Reason:    ExternalChangeReason,
+		Type:      eventType,
+		Message:   c.Message(),
+		Component: c.Manager,
+		Count:     1,
+		FirstSeen: c.Time,
+		LastSeen:  c.Time,
+	}
+}
+
+// Message describes the change, e.g. "Deployment default/api modified by kubectl-edit
+// (Update): spec.replicas"
+func (c ExternalChange) Message() string {
+	message := fmt.Sprintf("%s %s/%s modified by %s (%s)", c.Kind, c.Namespace, c.Name, c.Manager, c.Operation)
+	if len(c.Fields) > 0 {
+		message += ": " + strings.Join(c.Fields, ", ")
+	}
+	if c.Application != "" {
+		message += "; drifted from gitops application " + c.Application
+	}
+	return message
+}
+
+func timeEqual(a, b *metav1.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(b)
+}
+
+// managedPaths lists the fields of a managed fields entry as dotted paths, three levels
+// deep (metadata.labels.app, spec.template.spec), leaving out kubectl's last applied
+// configuration
+func managedPaths(fields *metav1.FieldsV1) []string {
+	if fields == nil {
+		return nil
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal(fields.Raw, &tree); err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var walk func(prefix string, node map[string]interface{}, depth int)
+	walk = func(prefix string, node map[string]interface{}, depth int) {
+		if depth == 3 || len(node) == 0 {
+			if prefix != "" {
+				seen[prefix] = true
+			}
+			return
+		}
+		leaf := true
+		for key, child := range node {
+			if key == "." {
+				continue
+			}
+			leaf = false
+			path := prefix + fieldPathSegment(key)
+			childNode, _ := child.(map[string]interface{})
+			walk(strings.TrimPrefix(path, "."), childNode, depth+1)
+		}
+		if leaf && prefix != "" {
+			seen[prefix] = true
+		}
+	}
+	walk("", tree, 0)
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		if path == lastAppliedPath {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if len(paths) > maxChangedFields {
+		more := len(paths) - maxChangedFields
+		paths = append(paths[:maxChangedFields], fmt.Sprintf("and %d more", more))
+	}
+	return paths
+}
+
+// fieldPathSegment turns a managed fields key into a path segment: f:name is .name,
+// k:{"name":"api"} is [name=api] and v:"x" is [x]
+func fieldPathSegment(key string) string {
+	switch {
+	case strings.HasPrefix(key, "f:"):
+		return "." + key[2:]
+	case strings.HasPrefix(key, "k:"):
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(key[2:]), &fields); err != nil {
+			return "[" + key[2:] + "]"
+		}
+		parts := make([]string, 0, len(fields))
+		for name, value := range fields {
+			parts = append(parts, fmt.Sprintf("%s=%v", name, value))
+		}
+		sort.Strings(parts)
+		return "[" + strings.Join(parts, ",") + "]"
+	case strings.HasPrefix(key, "v:"):
+		return "[" + strings.Trim(key[2:], `"`) + "]"
+	}
+	return "." + key
+}
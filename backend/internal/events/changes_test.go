@@ -0,0 +1,91 @@
+package events
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func managedEntry(manager string, operation metav1.ManagedFieldsOperationType, subresource string, at time.Time, fields string) metav1.ManagedFieldsEntry {
+	t := metav1.NewTime(at)
+	return metav1.ManagedFieldsEntry{
+		Manager:     manager,
+		Operation:   operation,
+		Subresource: subresource,
+		Time:        &t,
+		FieldsType:  "FieldsV1",
+		FieldsV1:    &metav1.FieldsV1{Raw: []byte(fields)},
+	}
+}
+
+func TestExternalChanges(t *testing.T) {
+	created := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	edited := created.Add(time.Hour)
+	replicas := `{"f:spec":{"f:replicas":{}}}`
+	template := `{"f:metadata":{"f:labels":{".":{},"f:app":{}}},"f:spec":{"f:template":{"f:spec":{"f:containers":{}}}}}`
+
+	old := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name: "api", Namespace: "default", UID: "uid-1", ResourceVersion: "1",
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			managedEntry("denshimon", metav1.ManagedFieldsOperationApply, "", created, template),
+			managedEntry("kubectl-edit", metav1.ManagedFieldsOperationUpdate, "", created, replicas),
+		},
+	}}
+	updated := old.DeepCopy()
+	updated.ResourceVersion = "2"
+	updated.ManagedFields = []metav1.ManagedFieldsEntry{
+		managedEntry("denshimon", metav1.ManagedFieldsOperationApply, "", edited, template),
+		managedEntry("kubectl-edit", metav1.ManagedFieldsOperationUpdate, "", edited, replicas),
+		managedEntry("kubectl", metav1.ManagedFieldsOperationUpdate, "scale", edited, replicas),
+		managedEntry("kube-controller-manager", metav1.ManagedFieldsOperationUpdate, "", edited, `{"f:metadata":{}}`),
+		managedEntry("kube-controller-manager", metav1.ManagedFieldsOperationUpdate, "status", edited, `{"f:status":{}}`),
+	}
+
+	watcher := NewChangeWatcher(nil, nil, "denshimon")
+	changes := watcher.ExternalChanges("Deployment", old, updated)
+	if len(changes) != 2 {
+		t.Fatalf("expected kubectl-edit and kubectl scale changes, got %+v", changes)
+	}
+	change := changes[0]
+	if change.Manager != "kubectl-edit" || change.Operation != "Update" || !change.Time.Equal(edited) {
+		t.Errorf("unexpected change %+v", change)
+	}
+	if !reflect.DeepEqual(change.Fields, []string{"spec.replicas"}) {
+		t.Errorf("unexpected fields %v", change.Fields)
+	}
+	if changes[1].Manager != "kubectl" {
+		t.Errorf("expected scale subresource updates to count, got %+v", changes[1])
+	}
+
+	// Denshimon taking fields over leaves the edit's timestamp alone
+	again := updated.DeepCopy()
+	again.ResourceVersion = "3"
+	again.ManagedFields[1].FieldsV1 = &metav1.FieldsV1{Raw: []byte(`{}`)}
+	if changes := watcher.ExternalChanges("Deployment", updated, again); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+
+	change.Application = "app-1"
+	event := change.Event()
+	if event.Reason != ExternalChangeReason || event.Type != "Warning" || event.Component != "kubectl-edit" || event.UID != "uid-1/2/kubectl-edit" {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if !strings.Contains(event.Message, "Deployment default/api modified by kubectl-edit (Update): spec.replicas") {
+		t.Errorf("unexpected message %q", event.Message)
+	}
+}
+
+func TestManagedPaths(t *testing.T) {
+	fields := &metav1.FieldsV1{Raw: []byte(`{
+		"f:metadata":{"f:annotations":{"f:kubectl.kubernetes.io/last-applied-configuration":{}},"f:labels":{"f:app":{}}},
+		"f:spec":{"f:ports":{"k:{\"port\":80,\"protocol\":\"TCP\"}":{".":{},"f:port":{}}},"f:selector":{}}
+	}`)}
+	expected := []string{"metadata.labels.app", "spec.ports[port=80,protocol=TCP]", "spec.selector"}
+	if paths := managedPaths(fields); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+}
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_k8s_events_reason;
+DROP INDEX IF EXISTS idx_k8s_events_namespace;
+DROP INDEX IF EXISTS idx_k8s_events_last_seen;
+DROP TABLE IF EXISTS k8s_events;
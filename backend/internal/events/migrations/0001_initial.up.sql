@@ -0,0 +1,18 @@
+-- Kubernetes events, kept beyond the hour etcd holds them; repeats fold into count
+CREATE TABLE IF NOT EXISTS k8s_events (
+	uid TEXT PRIMARY KEY,
+	namespace TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	name TEXT NOT NULL,
+	reason TEXT NOT NULL,
+	type TEXT NOT NULL,
+	message TEXT NOT NULL,
+	component TEXT NOT NULL,
+	count INTEGER NOT NULL,
+	first_seen INTEGER NOT NULL, -- unix milliseconds
+	last_seen INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_k8s_events_last_seen ON k8s_events(last_seen);
+CREATE INDEX IF NOT EXISTS idx_k8s_events_namespace ON k8s_events(namespace, last_seen);
+CREATE INDEX IF NOT EXISTS idx_k8s_events_reason ON k8s_events(reason, last_seen);
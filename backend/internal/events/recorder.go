@@ -0,0 +1,173 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// RecorderConfig sets how long recorded events are kept
+type RecorderConfig struct {
+	Retention        time.Duration // Normal events
+	WarningRetention time.Duration // Warning events, usually kept longer
+	BatchSize        int
+}
+
+// DefaultRecorderConfig keeps Normal events for two weeks and warnings for 30 days
+func DefaultRecorderConfig() RecorderConfig {
+	return RecorderConfig{
+		Retention:        14 * 24 * time.Hour,
+		WarningRetention: 30 * 24 * time.Hour,
+		BatchSize:        500,
+	}
+}
+
+// Recorder watches events in all namespaces and writes them to a Store
+type Recorder struct {
+	client kubernetes.Interface
+	store  *Store
+	config RecorderConfig
+	events chan Event
+}
+
+// NewRecorder creates an event recorder; Start begins recording
+func NewRecorder(client kubernetes.Interface, store *Store, config RecorderConfig) *Recorder {
+	defaults := DefaultRecorderConfig()
+	if config.Retention <= 0 {
+		config.Retention = defaults.Retention
+	}
+	if config.WarningRetention <= 0 {
+		config.WarningRetention = defaults.WarningRetention
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+
+	return &Recorder{
+		client: client,
+		store:  store,
+		config: config,
+		events: make(chan Event, config.BatchSize*2),
+	}
+}
+
+// Start runs the watch, writer and retention loops until ctx is cancelled. Events present
+// when it starts are recorded too; recording an event again only updates it.
+func (r *Recorder) Start(ctx context.Context) {
+	go r.writeLoop(ctx)
+	go r.pruneLoop(ctx)
+
+	informer := informers.NewSharedInformerFactory(r.client, 0).Core().V1().Events().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    r.enqueue,
+		UpdateFunc: func(_, obj interface{}) { r.enqueue(obj) },
+	})
+	go informer.Run(ctx.Done())
+}
+
+func (r *Recorder) enqueue(obj interface{}) {
+	event, ok := obj.(*corev1.Event)
+	if !ok {
+		return
+	}
+	select {
+	case r.events <- FromKubernetes(event):
+	default:
+		slog.Warn("Event recorder falling behind, dropping event", "namespace", event.Namespace, "reason", event.Reason)
+	}
+}
+
+// FromKubernetes converts an event of either the legacy (count and timestamps) or the
+// events.k8s.io (series and event time) shape
+func FromKubernetes(event *corev1.Event) Event {
+	recorded := Event{
+		UID:       string(event.UID),
+		Namespace: event.Namespace,
+		Kind:      event.InvolvedObject.Kind,
+		Name:      event.InvolvedObject.Name,
+		Reason:    event.Reason,
+		Type:      event.Type,
+		Message:   event.Message,
+		Component: event.Source.Component,
+		Count:     event.Count,
+		FirstSeen: event.FirstTimestamp.Time,
+		LastSeen:  event.LastTimestamp.Time,
+	}
+	if recorded.Component == "" {
+		recorded.Component = event.ReportingController
+	}
+	if recorded.FirstSeen.IsZero() {
+		recorded.FirstSeen = event.EventTime.Time
+	}
+	if recorded.FirstSeen.IsZero() {
+		recorded.FirstSeen = event.CreationTimestamp.Time
+	}
+	if event.Series != nil {
+		recorded.Count = event.Series.Count
+		if recorded.LastSeen.IsZero() {
+			recorded.LastSeen = event.Series.LastObservedTime.Time
+		}
+	}
+	if recorded.LastSeen.IsZero() {
+		recorded.LastSeen = recorded.FirstSeen
+	}
+	if recorded.Count < 1 {
+		recorded.Count = 1
+	}
+	return recorded
+}
+
+// writeLoop batches events and writes them once a second or when the batch is full
+func (r *Recorder) writeLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, r.config.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.store.Record(context.Background(), batch); err != nil {
+			slog.Error("Failed to record events", "count", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			flush()
+			return
+		case event := <-r.events:
+			batch = append(batch, event)
+			if len(batch) >= r.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (r *Recorder) pruneLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		if removed, err := r.store.Prune(ctx, now.Add(-r.config.Retention), now.Add(-r.config.WarningRetention)); err != nil {
+			slog.Error("Failed to prune events", "error", err)
+		} else if removed > 0 {
+			slog.Info("Pruned events", "removed", removed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
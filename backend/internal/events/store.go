@@ -0,0 +1,188 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/database/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations is the schema of the event table
+var Migrations = migrate.NewSource("events", migrationFiles, "migrations")
+
+// Event is a Kubernetes event as recorded, with repeats folded into Count
+type Event struct {
+	UID       string    `json:"uid"`
+	Namespace string    `json:"namespace"`
+	Kind      string    `json:"kind"` // kind of the involved object
+	Name      string    `json:"name"` // name of the involved object
+	Reason    string    `json:"reason"`
+	Type      string    `json:"type"` // Normal or Warning
+	Message   string    `json:"message"`
+	Component string    `json:"component"`
+	Count     int32     `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Query filters event searches. Zero values match everything; times apply to when an
+// event was last seen.
+type Query struct {
+	Namespace string
+	Kind      string
+	Name      string
+	Reason    string
+	Type      string
+	Text      string // matched against messages
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+	Offset    int
+}
+
+// Store persists Kubernetes events in SQLite, beyond the hour they are kept in etcd
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates an event store, migrating its table to the latest schema version
+func NewStore(db *sql.DB) (*Store, error) {
+	if err := migrate.Up(context.Background(), db, Migrations); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Record stores a batch of events in a single transaction. An event seen before is
+// updated with its latest message, count and last seen time.
+func (s *Store) Record(ctx context.Context, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO k8s_events (uid, namespace, kind, name, reason, type, message, component, count, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(uid) DO UPDATE SET
+			type = excluded.type,
+			message = excluded.message,
+			count = excluded.count,
+			last_seen = excluded.last_seen`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, event := range events {
+		if _, err := stmt.ExecContext(ctx, event.UID, event.Namespace, event.Kind, event.Name, event.Reason, event.Type,
+			event.Message, event.Component, event.Count, event.FirstSeen.UnixMilli(), event.LastSeen.UnixMilli()); err != nil {
+			return fmt.Errorf("failed to record event: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Search returns events matching the query, most recently seen first, and the total number of matches
+func (s *Store) Search(ctx context.Context, q Query) ([]Event, int, error) {
+	where, args := q.where()
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM k8s_events`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT uid, namespace, kind, name, reason, type, message, component, count, first_seen, last_seen
+		FROM k8s_events`+where+`
+		ORDER BY last_seen DESC, uid
+		LIMIT ? OFFSET ?`, append(args, limit, q.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var event Event
+		var firstSeen, lastSeen int64
+		if err := rows.Scan(&event.UID, &event.Namespace, &event.Kind, &event.Name, &event.Reason, &event.Type,
+			&event.Message, &event.Component, &event.Count, &firstSeen, &lastSeen); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan event: %w", err)
+		}
+		event.FirstSeen = time.UnixMilli(firstSeen).UTC()
+		event.LastSeen = time.UnixMilli(lastSeen).UTC()
+		events = append(events, event)
+	}
+	return events, total, rows.Err()
+}
+
+// Prune deletes Warning events last seen before warningsBefore and other events last seen
+// before before, and returns how many were removed
+func (s *Store) Prune(ctx context.Context, before, warningsBefore time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM k8s_events
+		WHERE (type = 'Warning' AND last_seen < ?) OR (type != 'Warning' AND last_seen < ?)`,
+		warningsBefore.UnixMilli(), before.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune events: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// where builds the WHERE clause of a query against k8s_events
+func (q Query) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	for _, filter := range []struct {
+		column, value string
+	}{
+		{"namespace", q.Namespace},
+		{"kind", q.Kind},
+		{"name", q.Name},
+		{"reason", q.Reason},
+		{"type", q.Type},
+	} {
+		if filter.value != "" {
+			conditions = append(conditions, filter.column+" = ? COLLATE NOCASE")
+			args = append(args, filter.value)
+		}
+	}
+	if !q.Since.IsZero() {
+		conditions = append(conditions, "last_seen >= ?")
+		args = append(args, q.Since.UnixMilli())
+	}
+	if !q.Until.IsZero() {
+		conditions = append(conditions, "last_seen <= ?")
+		args = append(args, q.Until.UnixMilli())
+	}
+	for _, term := range strings.Fields(q.Text) {
+		conditions = append(conditions, `message LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+likeEscaper.Replace(term)+"%")
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
@@ -0,0 +1,143 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func setupTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	return store
+}
+
+func TestRecordAndSearch(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	base := time.Now().Add(-10 * 24 * time.Hour).Truncate(time.Millisecond)
+
+	events := []Event{
+		{UID: "1", Namespace: "apps", Kind: "Pod", Name: "web-1", Reason: "BackOff", Type: "Warning", Message: "Back-off restarting failed container", Count: 3, FirstSeen: base, LastSeen: base.Add(time.Minute)},
+		{UID: "2", Namespace: "apps", Kind: "Deployment", Name: "web", Reason: "ScalingReplicaSet", Type: "Normal", Message: "Scaled up replica set web-7d9f8 to 2", Count: 1, FirstSeen: base.Add(time.Hour), LastSeen: base.Add(time.Hour)},
+		{UID: "3", Namespace: "system", Kind: "Node", Name: "node-1", Reason: "NodeNotReady", Type: "Warning", Message: "Node node-1 status is now: NodeNotReady", Count: 1, FirstSeen: base.Add(2 * time.Hour), LastSeen: base.Add(2 * time.Hour)},
+	}
+	if err := store.Record(ctx, events); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	// Recording an event again updates its count and last seen time
+	events[0].Count, events[0].LastSeen = 7, base.Add(3*time.Hour)
+	if err := store.Record(ctx, events[:1]); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	all, total, err := store.Search(ctx, Query{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if total != 3 || len(all) != 3 || all[0].UID != "1" || all[0].Count != 7 || !all[0].FirstSeen.Equal(base) {
+		t.Fatalf("unexpected events %+v", all)
+	}
+
+	tests := map[string]struct {
+		query Query
+		want  []string
+	}{
+		"namespace":         {Query{Namespace: "apps"}, []string{"1", "2"}},
+		"kind ignores case": {Query{Kind: "pod"}, []string{"1"}},
+		"reason":            {Query{Reason: "NodeNotReady"}, []string{"3"}},
+		"type":              {Query{Type: "Warning"}, []string{"1", "3"}},
+		"text":              {Query{Text: "replica set"}, []string{"2"}},
+		"since":             {Query{Since: base.Add(90 * time.Minute)}, []string{"1", "3"}},
+		"until":             {Query{Until: base.Add(90 * time.Minute)}, []string{"2"}},
+		"page":              {Query{Limit: 1, Offset: 1}, []string{"3"}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			found, _, err := store.Search(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			var uids []string
+			for _, event := range found {
+				uids = append(uids, event.UID)
+			}
+			if len(uids) != len(tt.want) {
+				t.Fatalf("got %v, want %v", uids, tt.want)
+			}
+			for i := range uids {
+				if uids[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", uids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPruneKeepsWarningsLonger(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	old := time.Now().Add(-20 * 24 * time.Hour)
+
+	if err := store.Record(ctx, []Event{
+		{UID: "normal", Type: "Normal", Count: 1, FirstSeen: old, LastSeen: old},
+		{UID: "warning", Type: "Warning", Count: 1, FirstSeen: old, LastSeen: old},
+		{UID: "recent", Type: "Normal", Count: 1, FirstSeen: time.Now(), LastSeen: time.Now()},
+	}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	config := DefaultRecorderConfig()
+	removed, err := store.Prune(ctx, time.Now().Add(-config.Retention), time.Now().Add(-config.WarningRetention))
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("removed %d events, want 1", removed)
+	}
+	if _, total, _ := store.Search(ctx, Query{Type: "Normal"}); total != 1 {
+		t.Errorf("expected the recent Normal event to remain, got %d", total)
+	}
+}
+
+func TestFromKubernetes(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	observed := created.Add(5 * time.Minute)
+	event := &corev1.Event{
+		ObjectMeta:          metav1.ObjectMeta{UID: "abc", Namespace: "apps", CreationTimestamp: metav1.NewTime(created)},
+		InvolvedObject:      corev1.ObjectReference{Kind: "Pod", Name: "web-1"},
+		Reason:              "Unhealthy",
+		Type:                corev1.EventTypeWarning,
+		EventTime:           metav1.NewMicroTime(created),
+		ReportingController: "kubelet",
+		Series:              &corev1.EventSeries{Count: 4, LastObservedTime: metav1.NewMicroTime(observed)},
+	}
+
+	recorded := FromKubernetes(event)
+	if recorded.Component != "kubelet" || recorded.Count != 4 || !recorded.FirstSeen.Equal(created) || !recorded.LastSeen.Equal(observed) {
+		t.Errorf("unexpected series event %+v", recorded)
+	}
+
+	event.Series, event.EventTime = nil, metav1.MicroTime{}
+	recorded = FromKubernetes(event)
+	if recorded.Count != 1 || !recorded.FirstSeen.Equal(created) || !recorded.LastSeen.Equal(created) {
+		t.Errorf("unexpected single event %+v", recorded)
+	}
+}
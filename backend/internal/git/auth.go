@@ -0,0 +1,114 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// AuthMethod identifies how the client authenticates against the remote
+type AuthMethod string
+
+const (
+	AuthNone       AuthMethod = "none"
+	AuthSSHKey     AuthMethod = "ssh_key"
+	AuthDeployKey  AuthMethod = "deploy_key"
+	AuthHTTPSToken AuthMethod = "https_token"
+)
+
+// Credentials holds the secret material for authenticating remote operations.
+// SSH and deploy keys must be unencrypted; the token is used as the HTTPS password.
+type Credentials struct {
+	Method     AuthMethod
+	Username   string
+	Token      string
+	PrivateKey []byte
+	KnownHosts []byte
+}
+
+// askPassScript answers git's username and password prompts from the environment
+const askPassScript = `#!/bin/sh
+case "$1" in
+Username*) printf '%s\n' "$DENSHIMON_GIT_USERNAME" ;;
+*) printf '%s\n' "$DENSHIMON_GIT_PASSWORD" ;;
+esac
+`
+
+// SetCredentials sets the credentials used for clone, pull, push and reachability checks
+func (c *Client) SetCredentials(creds *Credentials) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.credentials = creds
+}
+
+// remoteCommand builds a git command that talks to the remote using the configured credentials.
+// Key material is written to a private temporary directory that the returned cleanup removes.
+func (c *Client) remoteCommand(args ...string) (*exec.Cmd, func(), error) {
+	c.mutex.RLock()
+	creds := c.credentials
+	c.mutex.RUnlock()
+
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if creds == nil || creds.Method == AuthNone || creds.Method == "" {
+		return cmd, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "denshimon-git-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	switch creds.Method {
+	case AuthSSHKey, AuthDeployKey:
+		keyPath := filepath.Join(dir, "id_key")
+		if err := os.WriteFile(keyPath, creds.PrivateKey, 0600); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to write ssh key: %w", err)
+		}
+
+		sshCommand := fmt.Sprintf("ssh -i %s -o IdentitiesOnly=yes -o BatchMode=yes", shellQuote(keyPath))
+		if len(creds.KnownHosts) > 0 {
+			knownHostsPath := filepath.Join(dir, "known_hosts")
+			if err := os.WriteFile(knownHostsPath, creds.KnownHosts, 0600); err != nil {
+				cleanup()
+				return nil, nil, fmt.Errorf("failed to write known hosts: %w", err)
+			}
+			sshCommand += fmt.Sprintf(" -o StrictHostKeyChecking=yes -o UserKnownHostsFile=%s", shellQuote(knownHostsPath))
+		} else {
+			// Without pinned host keys, trust the first key seen but never a changed one
+			sshCommand += " -o StrictHostKeyChecking=accept-new"
+		}
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND="+sshCommand)
+
+	case AuthHTTPSToken:
+		askPassPath := filepath.Join(dir, "askpass.sh")
+		if err := os.WriteFile(askPassPath, []byte(askPassScript), 0700); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to write askpass helper: %w", err)
+		}
+
+		username := creds.Username
+		if username == "" {
+			username = "git"
+		}
+		cmd.Env = append(cmd.Env,
+			"GIT_ASKPASS="+askPassPath,
+			"DENSHIMON_GIT_USERNAME="+username,
+			"DENSHIMON_GIT_PASSWORD="+creds.Token,
+		)
+
+	default:
+		cleanup()
+		return nil, nil, fmt.Errorf("unsupported auth method: %s", creds.Method)
+	}
+
+	return cmd, cleanup, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
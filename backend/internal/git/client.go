@@ -9,14 +9,17 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
 // Client wraps Git operations for repository management
 type Client struct {
-	repoPath string
-	repoURL  string
-	branch   string
+	repoPath    string
+	repoURL     string
+	branch      string
+	credentials *Credentials
+	mutex       sync.RWMutex
 }
 
 // Repository represents a Git repository configuration
@@ -70,7 +73,12 @@ func (c *Client) Clone() error {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	cmd := exec.Command("git", "clone", "-b", c.branch, c.repoURL, c.repoPath)
+	cmd, cleanup, err := c.remoteCommand("clone", "-b", c.branch, c.repoURL, c.repoPath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to clone repository: %s", string(output))
@@ -81,7 +89,12 @@ func (c *Client) Clone() error {
 
 // Pull fetches and merges the latest changes from remote
 func (c *Client) Pull() error {
-	cmd := exec.Command("git", "pull", "origin", c.branch)
+	cmd, cleanup, err := c.remoteCommand("pull", "origin", c.branch)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
 	cmd.Dir = c.repoPath
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -123,7 +136,12 @@ func (c *Client) Commit(message string) error {
 
 // Push pushes commits to the remote repository
 func (c *Client) Push() error {
-	cmd := exec.Command("git", "push", "origin", c.branch)
+	cmd, cleanup, err := c.remoteCommand("push", "origin", c.branch)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
 	cmd.Dir = c.repoPath
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -243,6 +261,23 @@ func (c *Client) WriteFile(relativePath string, content []byte) error {
 	return nil
 }
 
+// RemovePath deletes a file or directory from the repository and reports whether it existed.
+// Paths resolving to the repository root or outside it are refused.
+func (c *Client) RemovePath(relativePath string) (bool, error) {
+	if !filepath.IsLocal(relativePath) || filepath.Clean(relativePath) == "." {
+		return false, fmt.Errorf("refusing to remove %q: not inside the repository", relativePath)
+	}
+	fullPath := filepath.Join(c.repoPath, relativePath)
+	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	if err := os.RemoveAll(fullPath); err != nil {
+		return false, fmt.Errorf("failed to remove %s: %w", relativePath, err)
+	}
+	return true, nil
+}
+
 // ReadFile reads content from a file in the repository
 func (c *Client) ReadFile(relativePath string) ([]byte, error) {
 	fullPath := filepath.Join(c.repoPath, relativePath)
@@ -255,9 +290,13 @@ func (c *Client) ReadFile(relativePath string) ([]byte, error) {
 
 // IsReachable checks if the remote repository is reachable
 func (c *Client) IsReachable() bool {
-	cmd := exec.Command("git", "ls-remote", "--exit-code", "--heads", c.repoURL)
-	err := cmd.Run()
-	return err == nil
+	cmd, cleanup, err := c.remoteCommand("ls-remote", "--exit-code", "--heads", c.repoURL)
+	if err != nil {
+		return false
+	}
+	defer cleanup()
+
+	return cmd.Run() == nil
 }
 
 // CommitAndPush is a convenience method that adds, commits, and pushes changes
@@ -0,0 +1,162 @@
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Job statuses reported by Gitea Actions
+const (
+	JobStatusQueued     = "queued"
+	JobStatusWaiting    = "waiting"
+	JobStatusInProgress = "in_progress"
+	JobStatusCompleted  = "completed"
+)
+
+// maxJobLog bounds how much of a job log is fetched in one request
+const maxJobLog = 16 << 20
+
+// JobStep is one step of an Actions job
+type JobStep struct {
+	Name        string     `json:"name"`
+	Number      int        `json:"number"`
+	Status      string     `json:"status"`
+	Conclusion  string     `json:"conclusion,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Job is an Actions job within a workflow run
+type Job struct {
+	ID          int64      `json:"id"`
+	RunID       int64      `json:"run_id"`
+	Name        string     `json:"name"`
+	HeadSHA     string     `json:"head_sha,omitempty"`
+	Status      string     `json:"status"`
+	Conclusion  string     `json:"conclusion,omitempty"`
+	RunnerName  string     `json:"runner_name,omitempty"`
+	HTMLURL     string     `json:"html_url,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Steps       []JobStep  `json:"steps,omitempty"`
+}
+
+// Done reports whether the job has finished and its log will not grow
+func (j *Job) Done() bool {
+	return j.Status == JobStatusCompleted
+}
+
+func actionsID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// ListRunJobs returns the jobs of a workflow run
+func (c *Client) ListRunJobs(ctx context.Context, owner, repo string, runID int64) ([]Job, error) {
+	var result struct {
+		Jobs []Job `json:"jobs"`
+	}
+	if err := c.do(ctx, http.MethodGet, repoPath(owner, repo, "actions/runs", actionsID(runID), "jobs"), nil, &result); err != nil {
+		return nil, err
+	}
+	if result.Jobs == nil {
+		result.Jobs = []Job{}
+	}
+	return result.Jobs, nil
+}
+
+// GetJob returns a single Actions job
+func (c *Client) GetJob(ctx context.Context, owner, repo string, jobID int64) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodGet, repoPath(owner, repo, "actions/jobs", actionsID(jobID)), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// JobLogs returns the log of a job as written so far
+func (c *Client) JobLogs(ctx context.Context, owner, repo string, jobID int64) ([]byte, error) {
+	resp, err := c.send(ctx, http.MethodGet, repoPath(owner, repo, "actions/jobs", actionsID(jobID), "logs"), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	logs, err := io.ReadAll(io.LimitReader(resp.Body, maxJobLog))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job logs: %w", err)
+	}
+	return logs, nil
+}
+
+// RerunFailedJobs re-runs the failed and cancelled jobs of a finished workflow run
+func (c *Client) RerunFailedJobs(ctx context.Context, owner, repo string, runID int64) error {
+	return c.do(ctx, http.MethodPost, repoPath(owner, repo, "actions/runs", actionsID(runID), "rerun-failed-jobs"), nil, nil)
+}
+
+// CancelRun cancels a queued or in-progress workflow run
+func (c *Client) CancelRun(ctx context.Context, owner, repo string, runID int64) error {
+	return c.do(ctx, http.MethodPost, repoPath(owner, repo, "actions/runs", actionsID(runID), "cancel"), nil, nil)
+}
+
+// Run conclusions reported by Gitea Actions
+const (
+	RunConclusionSuccess = "success"
+)
+
+// WorkflowRun is a run of an Actions workflow
+type WorkflowRun struct {
+	ID          int64      `json:"id"`
+	RunNumber   int64      `json:"run_number"`
+	Event       string     `json:"event"`
+	Path        string     `json:"path"` // workflow file and ref, e.g. build.yml@refs/heads/main
+	HeadSHA     string     `json:"head_sha"`
+	HeadBranch  string     `json:"head_branch"`
+	Status      string     `json:"status"`
+	Conclusion  string     `json:"conclusion,omitempty"`
+	HTMLURL     string     `json:"html_url,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Done reports whether the run has finished
+func (r *WorkflowRun) Done() bool {
+	return r.Status == JobStatusCompleted
+}
+
+// DispatchWorkflow triggers a workflow_dispatch run of a workflow file on a ref
+func (c *Client) DispatchWorkflow(ctx context.Context, owner, repo, workflow, ref string, inputs map[string]string) error {
+	body := map[string]interface{}{"ref": ref}
+	if len(inputs) > 0 {
+		body["inputs"] = inputs
+	}
+	return c.do(ctx, http.MethodPost, repoPath(owner, repo, "actions/workflows", url.PathEscape(workflow), "dispatches"), body, nil)
+}
+
+// ListWorkflowRuns returns the most recent runs triggered by an event ("" for any) for a
+// commit, newest first
+func (c *Client) ListWorkflowRuns(ctx context.Context, owner, repo, event, headSHA string) ([]WorkflowRun, error) {
+	query := url.Values{"head_sha": {headSHA}}
+	if event != "" {
+		query.Set("event", event)
+	}
+	var result struct {
+		WorkflowRuns []WorkflowRun `json:"workflow_runs"`
+	}
+	if err := c.do(ctx, http.MethodGet, repoPath(owner, repo, "actions/runs")+"?"+query.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+	return result.WorkflowRuns, nil
+}
+
+// GetWorkflowRun returns a single workflow run
+func (c *Client) GetWorkflowRun(ctx context.Context, owner, repo string, runID int64) (*WorkflowRun, error) {
+	var run WorkflowRun
+	if err := c.do(ctx, http.MethodGet, repoPath(owner, repo, "actions/runs", actionsID(runID)), nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
@@ -0,0 +1,313 @@
+package gitea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/deployments"
+	"github.com/google/uuid"
+)
+
+// Build statuses
+const (
+	BuildStatusDispatched   = "dispatched"    // waiting for Gitea to start the run
+	BuildStatusRunning      = "running"       // the workflow run is in progress
+	BuildStatusPendingApply = "pending_apply" // the built image is staged on the deployment
+	BuildStatusFailed       = "failed"
+)
+
+var (
+	// runPollInterval is how often a build's workflow run is checked
+	runPollInterval = 10 * time.Second
+	// buildTimeout bounds how long a build may take, from dispatch to image tag
+	buildTimeout = time.Hour
+)
+
+// ErrInvalidBuild is returned for build requests that can't be dispatched
+var ErrInvalidBuild = errors.New("invalid build request")
+
+// DeploymentImages looks up deployments and stages new images on them.
+// *deployments.Service implements it.
+type DeploymentImages interface {
+	GetDeployment(ctx context.Context, id string) (*deployments.Deployment, error)
+	ProposeImage(ctx context.Context, deploymentID, image, proposedBy, reason string) (*deployments.Deployment, error)
+}
+
+// BuildRequest builds a deployment's image from a repository ref with a
+// workflow_dispatch workflow
+type BuildRequest struct {
+	Ref          string `json:"ref"`
+	DeploymentID string `json:"deployment_id"`
+	// Image is the repository the workflow pushes to, e.g. gitea.example.com/ops/api.
+	// Defaults to the deployment's current image without its tag.
+	Image string `json:"image,omitempty"`
+	// Tag is the tag the workflow pushes, with {sha}, {short_sha}, {ref} and {run}
+	// placeholders. When empty the tag naming the built commit is looked up.
+	Tag    string            `json:"tag,omitempty"`
+	Inputs map[string]string `json:"inputs,omitempty"`
+}
+
+// Build tracks a workflow run building an image for a deployment
+type Build struct {
+	ID           string     `json:"id"`
+	Owner        string     `json:"owner"`
+	Repo         string     `json:"repo"`
+	Workflow     string     `json:"workflow"`
+	Ref          string     `json:"ref"`
+	CommitSHA    string     `json:"commit_sha"`
+	DeploymentID string     `json:"deployment_id"`
+	Status       string     `json:"status"`
+	RunID        int64      `json:"run_id,omitempty"`
+	RunURL       string     `json:"run_url,omitempty"`
+	Image        string     `json:"image,omitempty"` // the built image, once resolved
+	Error        string     `json:"error,omitempty"`
+	RequestedBy  string     `json:"requested_by,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+
+	repository string // image repository without tag
+	tag        string
+	previous   int64 // newest run of the workflow for the commit before dispatch
+}
+
+// ResolveCommit returns the commit SHA a branch, tag or SHA points to
+func (c *Client) ResolveCommit(ctx context.Context, owner, repo, ref string) (string, error) {
+	query := url.Values{"sha": {ref}, "limit": {"1"}, "stat": {"false"}, "verification": {"false"}, "files": {"false"}}
+	var commits []struct {
+		SHA string `json:"sha"`
+	}
+	if err := c.do(ctx, http.MethodGet, repoPath(owner, repo, "commits")+"?"+query.Encode(), nil, &commits); err != nil {
+		return "", err
+	}
+	if len(commits) == 0 {
+		return "", &APIError{StatusCode: http.StatusNotFound, Message: "ref not found: " + ref}
+	}
+	return commits[0].SHA, nil
+}
+
+// ContainerTags returns the tags of a container package, skipping untagged manifests
+func (c *Client) ContainerTags(ctx context.Context, owner, name string) ([]string, error) {
+	const limit = 50
+	tags := []string{}
+	for page := 1; ; page++ {
+		query := url.Values{"type": {"container"}, "q": {name}, "limit": {fmt.Sprint(limit)}, "page": {fmt.Sprint(page)}}
+		var packages []struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		if err := c.do(ctx, http.MethodGet, "/packages/"+url.PathEscape(owner)+"?"+query.Encode(), nil, &packages); err != nil {
+			return nil, err
+		}
+		for _, pkg := range packages {
+			if pkg.Name == name && !strings.HasPrefix(pkg.Version, "sha256:") {
+				tags = append(tags, pkg.Version)
+			}
+		}
+		if len(packages) < limit {
+			return tags, nil
+		}
+	}
+}
+
+// imageRepository strips the tag or digest from an image reference and splits the
+// rest into the Gitea package owner and name
+func imageRepository(image string) (repository, owner, name string, err error) {
+	repository, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository = repository[:i]
+	}
+	parts := strings.SplitN(repository, "/", 3)
+	if len(parts) < 3 || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("%w: image %q is not in a Gitea registry (host/owner/name)", ErrInvalidBuild, image)
+	}
+	return repository, parts[1], parts[2], nil
+}
+
+// matchesWorkflow reports whether a run's path names the workflow file
+func matchesWorkflow(runPath, workflow string) bool {
+	file, _, _ := strings.Cut(runPath, "@")
+	return path.Base(file) == path.Base(workflow)
+}
+
+// commitTag returns the tag naming a commit: its SHA, a prefix of at least seven
+// characters, or either with a sha- prefix
+func commitTag(tags []string, sha string) string {
+	for _, tag := range tags {
+		candidate := strings.TrimPrefix(tag, "sha-")
+		if candidate == sha || (len(candidate) >= 7 && strings.HasPrefix(sha, candidate)) {
+			return tag
+		}
+	}
+	return ""
+}
+
+// expandTag fills the placeholders of a tag template
+func (b *Build) expandTag() string {
+	short := b.CommitSHA
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	return strings.NewReplacer(
+		"{sha}", b.CommitSHA,
+		"{short_sha}", short,
+		"{ref}", strings.ReplaceAll(path.Base(b.Ref), "/", "-"),
+		"{run}", fmt.Sprint(b.RunID),
+	).Replace(b.tag)
+}
+
+// prepareBuild validates a build against its deployment and resolves the commit to build
+func (h *Handler) prepareBuild(ctx context.Context, build *Build, request BuildRequest) error {
+	if request.Ref == "" || request.DeploymentID == "" {
+		return fmt.Errorf("%w: ref and deployment_id are required", ErrInvalidBuild)
+	}
+	deployment, err := h.deployments.GetDeployment(ctx, request.DeploymentID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBuild, err)
+	}
+	image := request.Image
+	if image == "" {
+		image = deployment.Image
+	}
+	if build.repository, _, _, err = imageRepository(image); err != nil {
+		return err
+	}
+
+	sha, err := h.client.ResolveCommit(ctx, build.Owner, build.Repo, request.Ref)
+	if err != nil {
+		return err
+	}
+	build.CommitSHA = sha
+	runs, err := h.client.ListWorkflowRuns(ctx, build.Owner, build.Repo, "workflow_dispatch", sha)
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		if matchesWorkflow(run.Path, build.Workflow) && run.ID > build.previous {
+			build.previous = run.ID
+		}
+	}
+	return nil
+}
+
+// runBuild waits for the dispatched run, then stages the image it built on the deployment
+func (h *Handler) runBuild(build *Build) {
+	ctx, cancel := context.WithTimeout(context.Background(), buildTimeout)
+	defer cancel()
+
+	image, err := h.awaitBuild(ctx, build)
+	if err == nil {
+		reason := fmt.Sprintf("Built from %s/%s@%s", build.Owner, build.Repo, build.Ref)
+		if build.RunID != 0 {
+			reason += fmt.Sprintf(" (run %d)", build.RunID)
+		}
+		_, err = h.deployments.ProposeImage(ctx, build.DeploymentID, image, build.RequestedBy, reason)
+	}
+
+	now := time.Now()
+	h.buildsMu.Lock()
+	defer h.buildsMu.Unlock()
+	build.CompletedAt = &now
+	if err != nil {
+		build.Status = BuildStatusFailed
+		build.Error = err.Error()
+		slog.Warn("Build from repository failed", "repository", build.Owner+"/"+build.Repo, "ref", build.Ref, "deployment", build.DeploymentID, "error", err)
+		return
+	}
+	build.Status = BuildStatusPendingApply
+	build.Image = image
+	slog.Info("Built image staged for apply", "repository", build.Owner+"/"+build.Repo, "ref", build.Ref, "deployment", build.DeploymentID, "image", image)
+}
+
+// awaitBuild polls for the dispatched run until it completes and returns the image it built
+func (h *Handler) awaitBuild(ctx context.Context, build *Build) (string, error) {
+	ticker := time.NewTicker(runPollInterval)
+	defer ticker.Stop()
+
+	var run *WorkflowRun
+	for run == nil || !run.Done() {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("timed out waiting for the workflow run")
+		case <-ticker.C:
+		}
+
+		var next *WorkflowRun
+		var err error
+		if run == nil {
+			next, err = h.dispatchedRun(ctx, build)
+		} else {
+			next, err = h.client.GetWorkflowRun(ctx, build.Owner, build.Repo, run.ID)
+		}
+		if err != nil {
+			slog.Debug("Failed to check workflow run", "repository", build.Owner+"/"+build.Repo, "error", err)
+			continue // Gitea may be briefly unavailable; retry until the build times out
+		}
+		if next == nil {
+			continue
+		}
+		run = next
+		h.buildsMu.Lock()
+		build.Status, build.RunID, build.RunURL = BuildStatusRunning, run.ID, run.HTMLURL
+		h.buildsMu.Unlock()
+	}
+	if run.Conclusion != RunConclusionSuccess {
+		return "", fmt.Errorf("workflow run %d concluded %s", run.ID, run.Conclusion)
+	}
+
+	repository, owner, name, _ := imageRepository(build.repository)
+	tags, err := h.client.ContainerTags(ctx, owner, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to list image tags: %w", err)
+	}
+	if build.tag != "" {
+		tag := build.expandTag()
+		for _, t := range tags {
+			if t == tag {
+				return repository + ":" + tag, nil
+			}
+		}
+		return "", fmt.Errorf("the run succeeded but %s:%s was not pushed", repository, tag)
+	}
+	if tag := commitTag(tags, build.CommitSHA); tag != "" {
+		return repository + ":" + tag, nil
+	}
+	return "", fmt.Errorf("the run succeeded but no tag of %s names commit %s", repository, build.CommitSHA)
+}
+
+// dispatchedRun finds the run started by the dispatch, or returns nil while Gitea has
+// yet to start it
+func (h *Handler) dispatchedRun(ctx context.Context, build *Build) (*WorkflowRun, error) {
+	runs, err := h.client.ListWorkflowRuns(ctx, build.Owner, build.Repo, "workflow_dispatch", build.CommitSHA)
+	if err != nil {
+		return nil, err
+	}
+	var found *WorkflowRun
+	for i, run := range runs {
+		if matchesWorkflow(run.Path, build.Workflow) && run.ID > build.previous && (found == nil || run.ID < found.ID) {
+			found = &runs[i]
+		}
+	}
+	return found, nil
+}
+
+func newBuild(owner, repo, workflow, user string, request BuildRequest) *Build {
+	return &Build{
+		ID:           uuid.New().String(),
+		Owner:        owner,
+		Repo:         repo,
+		Workflow:     workflow,
+		Ref:          request.Ref,
+		DeploymentID: request.DeploymentID,
+		Status:       BuildStatusDispatched,
+		RequestedBy:  user,
+		StartedAt:    time.Now(),
+		tag:          request.Tag,
+	}
+}
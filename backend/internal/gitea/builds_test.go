@@ -0,0 +1,121 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/archellir/denshimon/internal/deployments"
+)
+
+type fakeDeployments struct {
+	mu     sync.Mutex
+	image  string
+	staged string
+}
+
+func (f *fakeDeployments) GetDeployment(ctx context.Context, id string) (*deployments.Deployment, error) {
+	return &deployments.Deployment{ID: id, Image: f.image}, nil
+}
+
+func (f *fakeDeployments) ProposeImage(ctx context.Context, deploymentID, image, proposedBy, reason string) (*deployments.Deployment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.staged = image
+	return &deployments.Deployment{ID: deploymentID, Image: image}, nil
+}
+
+func TestStartBuild(t *testing.T) {
+	runPollInterval = 10 * time.Millisecond
+	const sha = "3f2a9c1e8b7d6a5f4e3d2c1b0a9f8e7d6c5b4a39"
+
+	var mu sync.Mutex
+	dispatched := false
+	var dispatch map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/repos/ops/api/commits":
+			json.NewEncoder(w).Encode([]map[string]string{{"sha": sha}})
+		case "GET /api/v1/repos/ops/api/actions/runs":
+			// An earlier run of the same workflow must not be mistaken for the new one
+			runs := []WorkflowRun{{ID: 4, Path: "build.yml@refs/heads/main", HeadSHA: sha, Status: JobStatusCompleted, Conclusion: "failure"}}
+			if dispatched {
+				runs = append(runs, WorkflowRun{ID: 7, Path: "build.yml@refs/heads/main", HeadSHA: sha, Status: JobStatusInProgress})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"workflow_runs": runs})
+		case "POST /api/v1/repos/ops/api/actions/workflows/build.yml/dispatches":
+			json.NewDecoder(r.Body).Decode(&dispatch)
+			dispatched = true
+			w.WriteHeader(http.StatusNoContent)
+		case "GET /api/v1/repos/ops/api/actions/runs/7":
+			json.NewEncoder(w).Encode(WorkflowRun{ID: 7, Status: JobStatusCompleted, Conclusion: RunConclusionSuccess})
+		case "GET /api/v1/packages/ops":
+			w.Write([]byte(`[
+				{"name": "api", "version": "v1.2.0"},
+				{"name": "api", "version": "sha256:0123"},
+				{"name": "api", "version": "sha-3f2a9c1"}
+			]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	images := &fakeDeployments{image: "gitea.example.com/ops/api:v1.2.0"}
+	handler := NewHandler(NewClient(server.URL, "secret"))
+	handler.SetDeploymentService(images)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/gitea/repositories/{owner}/{repo}/actions/workflows/{workflow}/build", handler.StartBuild)
+	mux.HandleFunc("GET /api/gitea/builds/{id}", handler.GetBuild)
+
+	body := `{"ref": "main", "deployment_id": "dep-1", "inputs": {"push": "true"}}`
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/gitea/repositories/ops/api/actions/workflows/build.yml/build", strings.NewReader(body)))
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var build Build
+	json.NewDecoder(recorder.Body).Decode(&build)
+	if build.CommitSHA != sha || build.Status != BuildStatusDispatched {
+		t.Errorf("unexpected build %+v", build)
+	}
+	mu.Lock()
+	if dispatch["ref"] != "main" || dispatch["inputs"].(map[string]interface{})["push"] != "true" {
+		t.Errorf("unexpected dispatch %v", dispatch)
+	}
+	mu.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for build.Status != BuildStatusPendingApply && build.Status != BuildStatusFailed && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		recorder = httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/gitea/builds/"+build.ID, nil))
+		json.NewDecoder(recorder.Body).Decode(&build)
+	}
+	if build.Status != BuildStatusPendingApply || build.RunID != 7 {
+		t.Fatalf("unexpected build %+v", build)
+	}
+	if want := "gitea.example.com/ops/api:sha-3f2a9c1"; build.Image != want || images.staged != want {
+		t.Errorf("expected %s staged, got %s (%s)", want, images.staged, build.Image)
+	}
+}
+
+func TestBuildTag(t *testing.T) {
+	build := &Build{CommitSHA: "3f2a9c1e8b7d", Ref: "refs/heads/main", RunID: 12, tag: "{ref}-{short_sha}-{run}"}
+	if tag := build.expandTag(); tag != "main-3f2a9c1-12" {
+		t.Errorf("unexpected tag %s", tag)
+	}
+	if tag := commitTag([]string{"latest", "3f2a9c", "3f2a9c1e"}, "3f2a9c1e8b7d"); tag != "3f2a9c1e" {
+		t.Errorf("expected the 8 character prefix, got %q", tag)
+	}
+	if _, _, _, err := imageRepository("nginx:latest"); err == nil {
+		t.Error("expected an error for an image outside a registry host")
+	}
+}
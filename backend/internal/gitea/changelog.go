@@ -0,0 +1,79 @@
+package gitea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/archellir/denshimon/internal/gitops"
+)
+
+// maxChangelogCommits bounds the commits listed in a changelog; the total is still reported
+const maxChangelogCommits = 100
+
+// CompareCommits returns the commits reachable from head but not from base, and their
+// total count. It needs Gitea 1.22 or later.
+func (c *Client) CompareCommits(ctx context.Context, owner, repo, base, head string) ([]Commit, int, error) {
+	var result struct {
+		TotalCommits int         `json:"total_commits"`
+		Commits      []apiCommit `json:"commits"`
+	}
+	if err := c.do(ctx, http.MethodGet, repoPath(owner, repo, "compare", url.PathEscape(base+"..."+head)), nil, &result); err != nil {
+		return nil, 0, err
+	}
+	commits := make([]Commit, 0, len(result.Commits))
+	for i := range result.Commits {
+		commits = append(commits, *result.Commits[i].commit())
+	}
+	return commits, result.TotalCommits, nil
+}
+
+// Changelog lists the commits between the commits two images were built from, found as
+// for provenance. It implements gitops.ChangelogSource.
+func (h *Handler) Changelog(ctx context.Context, previousImage, image string) (*gitops.Changelog, error) {
+	if h.client == nil {
+		return nil, errors.New("Gitea integration not configured")
+	}
+	from, warnings := h.findCommit(ctx, "", previousImage)
+	if from == nil {
+		return nil, errors.New(strings.Join(warnings, "; "))
+	}
+	to, warnings := h.findCommit(ctx, "", image)
+	if to == nil {
+		return nil, errors.New(strings.Join(warnings, "; "))
+	}
+	if from.owner != to.owner || from.repo != to.repo {
+		return nil, fmt.Errorf("the images were built from different repositories, %s/%s and %s/%s", from.owner, from.repo, to.owner, to.repo)
+	}
+
+	commits, total, err := h.client.CompareCommits(ctx, to.owner, to.repo, from.sha, to.sha)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s...%s: %w", from.sha, to.sha, err)
+	}
+	changelog := &gitops.Changelog{
+		PreviousImage: previousImage,
+		Repository:    to.owner + "/" + to.repo,
+		From:          from.sha,
+		To:            to.sha,
+		CompareURL:    fmt.Sprintf("%s/%s/%s/compare/%s...%s", h.client.baseURL, url.PathEscape(to.owner), url.PathEscape(to.repo), from.sha, to.sha),
+		Commits:       []gitops.ChangelogCommit{},
+		TotalCommits:  total,
+	}
+	for i, commit := range commits {
+		if i == maxChangelogCommits {
+			break
+		}
+		subject, _, _ := strings.Cut(commit.Message, "\n")
+		changelog.Commits = append(changelog.Commits, gitops.ChangelogCommit{
+			SHA:     commit.SHA,
+			Message: strings.TrimSpace(subject),
+			Author:  commit.Author.Name,
+			Date:    commit.Author.Date,
+			URL:     commit.HTMLURL,
+		})
+	}
+	return changelog, nil
+}
@@ -0,0 +1,45 @@
+package gitea
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChangelog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/ops/api/compare/1a2b3c4...5d6e7f8" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"total_commits": 2, "commits": [
+			{"sha": "5d6e7f8a", "html_url": "https://gitea.example.com/ops/api/commit/5d6e7f8a",
+			 "commit": {"message": "Fix login redirect\n\nThe session cookie was dropped.", "author": {"name": "Dana", "date": "2026-03-02T10:00:00Z"}}},
+			{"sha": "4c5d6e7f", "commit": {"message": "Add audit log", "author": {"name": "Kim", "date": "2026-03-01T10:00:00Z"}}}
+		]}`))
+	}))
+	defer server.Close()
+	handler := NewHandler(NewClient(server.URL, "secret"))
+
+	changelog, err := handler.Changelog(context.Background(), "gitea.example.com/ops/api:sha-1a2b3c4", "gitea.example.com/ops/api:sha-5d6e7f8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changelog.Repository != "ops/api" || changelog.TotalCommits != 2 || len(changelog.Commits) != 2 {
+		t.Fatalf("changelog = %+v", changelog)
+	}
+	if commit := changelog.Commits[0]; commit.Message != "Fix login redirect" || commit.Author != "Dana" || commit.URL == "" {
+		t.Errorf("commit = %+v", commit)
+	}
+	if changelog.CompareURL != server.URL+"/ops/api/compare/1a2b3c4...5d6e7f8" {
+		t.Errorf("compare URL = %s", changelog.CompareURL)
+	}
+
+	if _, err := handler.Changelog(context.Background(), "gitea.example.com/ops/api:v1.0.0", "gitea.example.com/ops/api:sha-5d6e7f8"); err == nil {
+		t.Error("expected an error for an image whose commit is unknown")
+	}
+	if _, err := handler.Changelog(context.Background(), "gitea.example.com/ops/web:sha-1a2b3c4", "gitea.example.com/ops/api:sha-5d6e7f8"); err == nil {
+		t.Error("expected an error for images of different repositories")
+	}
+}
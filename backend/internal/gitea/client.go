@@ -0,0 +1,117 @@
+// Package gitea talks to a Gitea server's API with a server-side token and exposes
+// the parts the dashboard needs through Handler.
+package gitea
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Client calls the Gitea API as the account owning the configured token
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the Gitea server at baseURL
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// NewClientFromEnv creates a client from GITEA_URL and GITEA_TOKEN, or returns nil
+// when the integration is not configured
+func NewClientFromEnv() *Client {
+	baseURL, token := os.Getenv("GITEA_URL"), os.Getenv("GITEA_TOKEN")
+	if baseURL == "" || token == "" {
+		return nil
+	}
+	return NewClient(baseURL, token)
+}
+
+// APIError is a non-2xx response from Gitea
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("gitea returned %d: %s", e.StatusCode, e.Message)
+}
+
+// repoPath builds an API path under /repos/{owner}/{repo}, escaping both segments
+func repoPath(owner, repo string, parts ...string) string {
+	path := "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+	if len(parts) > 0 {
+		path += "/" + strings.Join(parts, "/")
+	}
+	return path
+}
+
+// send performs an API request. The token is only ever sent to Gitea; callers' own
+// credentials are never forwarded.
+func (c *Client) send(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api/v1"+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "token "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gitea request failed: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var parsed struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(message, &parsed) == nil && parsed.Message != "" {
+			message = []byte(parsed.Message)
+		}
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+	}
+	return resp, nil
+}
+
+// do performs an API request and decodes the JSON response into out when set
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	resp, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode gitea response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,522 @@
+package gitea
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/internal/gitops"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+// logPollInterval is how often a followed job log is re-fetched
+var logPollInterval = 2 * time.Second
+
+// Handler serves /api/gitea endpoints. Gitea responses are passed through as-is;
+// the configured token stays on the server.
+type Handler struct {
+	client      *Client
+	gitops      *gitops.Service
+	deployments DeploymentImages
+	imageLabels ImageLabels
+	publicURL   string
+
+	buildsMu sync.Mutex
+	builds   map[string]*Build
+}
+
+// NewHandler creates a handler; with a nil client every endpoint reports that the
+// integration is not configured
+func NewHandler(client *Client) *Handler {
+	return &Handler{client: client, builds: make(map[string]*Build)}
+}
+
+// SetGitOpsService enables registering provisioned repositories for GitOps
+func (h *Handler) SetGitOpsService(service *gitops.Service) {
+	h.gitops = service
+}
+
+// SetDeploymentService enables building deployment images from repositories
+func (h *Handler) SetDeploymentService(service DeploymentImages) {
+	h.deployments = service
+}
+
+// SetPublicURL sets the address Gitea reaches Denshimon on, used for webhook URLs.
+// When empty the URL is derived from the incoming request.
+func (h *Handler) SetPublicURL(url string) {
+	h.publicURL = strings.TrimSuffix(url, "/")
+}
+
+// available writes a 503 when Gitea is not configured
+func (h *Handler) available(w http.ResponseWriter) bool {
+	if h.client == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Gitea integration not configured")
+		return false
+	}
+	return true
+}
+
+// sendError maps a Gitea failure onto a response. Authentication failures are
+// reported as a bad gateway: they concern the server's token, not the caller.
+func sendError(w http.ResponseWriter, action string, err error) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			slog.Warn("Gitea rejected the configured token", "action", action, "status", apiErr.StatusCode)
+			response.SendError(w, http.StatusBadGateway, "Gitea rejected the configured token")
+			return
+		case http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity:
+			response.SendError(w, apiErr.StatusCode, action+": "+apiErr.Message)
+			return
+		case http.StatusMethodNotAllowed:
+			// Gitea's answer when a pull request cannot be merged as requested
+			response.SendError(w, http.StatusConflict, action+": "+apiErr.Message)
+			return
+		}
+	}
+	response.SendError(w, http.StatusBadGateway, action+": "+err.Error())
+}
+
+// pathID parses a numeric path value such as a run or job ID
+func pathID(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil || id <= 0 {
+		response.SendError(w, http.StatusBadRequest, "Invalid "+name)
+		return 0, false
+	}
+	return id, true
+}
+
+// ListRunJobs handles GET /api/gitea/repositories/{owner}/{repo}/actions/runs/{run}/jobs
+func (h *Handler) ListRunJobs(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w) {
+		return
+	}
+	runID, ok := pathID(w, r, "run")
+	if !ok {
+		return
+	}
+
+	jobs, err := h.client.ListRunJobs(r.Context(), r.PathValue("owner"), r.PathValue("repo"), runID)
+	if err != nil {
+		sendError(w, "Failed to list jobs", err)
+		return
+	}
+	response.SendJSON(w, http.StatusOK, jobs)
+}
+
+// GetJobLogs handles GET /api/gitea/repositories/{owner}/{repo}/actions/jobs/{job}/logs.
+// With follow=true the log is streamed as it grows until the job completes or the
+// client disconnects.
+func (h *Handler) GetJobLogs(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w) {
+		return
+	}
+	jobID, ok := pathID(w, r, "job")
+	if !ok {
+		return
+	}
+	owner, repo := r.PathValue("owner"), r.PathValue("repo")
+	ctx := r.Context()
+
+	logs, err := h.client.JobLogs(ctx, owner, repo, jobID)
+	if err != nil {
+		sendError(w, "Failed to get job logs", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.URL.Query().Get("follow") != "true" {
+		w.Write(logs)
+		return
+	}
+	w.Header().Set("Connection", "keep-alive")
+	// A followed log outlives the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Warn("Failed to clear the write deadline of a followed job log", "error", err)
+	}
+
+	flush := func() {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	w.Write(logs)
+	flush()
+
+	sent := logs
+	ticker := time.NewTicker(logPollInterval)
+	defer ticker.Stop()
+	for {
+		// Check completion before fetching so the final fetch includes the whole log
+		job, err := h.client.GetJob(ctx, owner, repo, jobID)
+		done := err == nil && job.Done()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		logs, err := h.client.JobLogs(ctx, owner, repo, jobID)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("Stopped following job log", "owner", owner, "repo", repo, "job", jobID, "error", err)
+			}
+			return
+		}
+		if bytes.HasPrefix(logs, sent) {
+			w.Write(logs[len(sent):])
+		} else {
+			// The job was re-run and its log restarted
+			w.Write([]byte("\n--- log restarted ---\n"))
+			w.Write(logs)
+		}
+		sent = logs
+		flush()
+
+		if done {
+			return
+		}
+	}
+}
+
+// RerunWorkflow handles POST /api/gitea/repositories/{owner}/{repo}/actions/runs/{run}/rerun,
+// re-running the run's failed jobs
+func (h *Handler) RerunWorkflow(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w) {
+		return
+	}
+	runID, ok := pathID(w, r, "run")
+	if !ok {
+		return
+	}
+
+	if err := h.client.RerunFailedJobs(r.Context(), r.PathValue("owner"), r.PathValue("repo"), runID); err != nil {
+		sendError(w, "Failed to re-run workflow", err)
+		return
+	}
+	response.SendJSON(w, http.StatusOK, map[string]interface{}{"status": "rerun_requested", "run_id": runID})
+}
+
+// CancelWorkflow handles POST /api/gitea/repositories/{owner}/{repo}/actions/runs/{run}/cancel
+func (h *Handler) CancelWorkflow(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w) {
+		return
+	}
+	runID, ok := pathID(w, r, "run")
+	if !ok {
+		return
+	}
+
+	if err := h.client.CancelRun(r.Context(), r.PathValue("owner"), r.PathValue("repo"), runID); err != nil {
+		sendError(w, "Failed to cancel workflow", err)
+		return
+	}
+	response.SendJSON(w, http.StatusOK, map[string]interface{}{"status": "cancel_requested", "run_id": runID})
+}
+
+// StartBuild handles POST /api/gitea/repositories/{owner}/{repo}/actions/workflows/{workflow}/build.
+// It dispatches the workflow on the ref and returns at once; the build then waits for the
+// run, resolves the image tag it pushed and stages that image on the deployment for apply.
+func (h *Handler) StartBuild(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w) {
+		return
+	}
+	if h.deployments == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Deployment service not available")
+		return
+	}
+	var request BuildRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user := ""
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		user = claims.Username
+	}
+	ctx := r.Context()
+	build := newBuild(r.PathValue("owner"), r.PathValue("repo"), r.PathValue("workflow"), user, request)
+	if err := h.prepareBuild(ctx, build, request); err != nil {
+		if errors.Is(err, ErrInvalidBuild) {
+			response.SendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		sendError(w, "Failed to prepare build", err)
+		return
+	}
+	if err := h.client.DispatchWorkflow(ctx, build.Owner, build.Repo, build.Workflow, build.Ref, request.Inputs); err != nil {
+		sendError(w, "Failed to dispatch workflow", err)
+		return
+	}
+
+	h.buildsMu.Lock()
+	h.builds[build.ID] = build
+	snapshot := *build
+	h.buildsMu.Unlock()
+	go h.runBuild(build)
+
+	slog.Info("Build dispatched", "repository", build.Owner+"/"+build.Repo, "workflow", build.Workflow, "ref", build.Ref, "commit", build.CommitSHA, "deployment", build.DeploymentID, "user", user)
+	response.SendJSON(w, http.StatusAccepted, snapshot)
+}
+
+// GetBuild handles GET /api/gitea/builds/{id}
+func (h *Handler) GetBuild(w http.ResponseWriter, r *http.Request) {
+	h.buildsMu.Lock()
+	build, ok := h.builds[r.PathValue("id")]
+	var snapshot Build
+	if ok {
+		snapshot = *build
+	}
+	h.buildsMu.Unlock()
+	if !ok {
+		response.SendError(w, http.StatusNotFound, "Build not found")
+		return
+	}
+	response.SendJSON(w, http.StatusOK, snapshot)
+}
+
+// attribute notes the dashboard user on text posted with the shared Gitea token
+func attribute(r *http.Request, body string) string {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil || claims.Username == "" {
+		return body
+	}
+	note := "_Submitted from Denshimon by " + claims.Username + "_"
+	if body == "" {
+		return note
+	}
+	return body + "\n\n" + note
+}
+
+// CommentOnPull handles POST /api/gitea/repositories/{owner}/{repo}/pulls/{index}/comments
+func (h *Handler) CommentOnPull(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w) {
+		return
+	}
+	index, ok := pathID(w, r, "index")
+	if !ok {
+		return
+	}
+	var request struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || strings.TrimSpace(request.Body) == "" {
+		response.SendError(w, http.StatusBadRequest, "Comment body is required")
+		return
+	}
+
+	comment, err := h.client.CommentOnPull(r.Context(), r.PathValue("owner"), r.PathValue("repo"), index, attribute(r, request.Body))
+	if err != nil {
+		sendError(w, "Failed to comment on pull request", err)
+		return
+	}
+	response.SendJSON(w, http.StatusCreated, comment)
+}
+
+// reviewEvents maps request values onto Gitea review events
+var reviewEvents = map[string]string{
+	"comment":         ReviewComment,
+	"approve":         ReviewApprove,
+	"request_changes": ReviewRequestChanges,
+}
+
+// ReviewPull handles POST /api/gitea/repositories/{owner}/{repo}/pulls/{index}/reviews
+// with {"event": "approve" | "request_changes" | "comment", "body": "..."}
+func (h *Handler) ReviewPull(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w) {
+		return
+	}
+	index, ok := pathID(w, r, "index")
+	if !ok {
+		return
+	}
+	var request struct {
+		Event string `json:"event"`
+		Body  string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	event, ok := reviewEvents[strings.ToLower(request.Event)]
+	if !ok {
+		response.SendError(w, http.StatusBadRequest, "event must be approve, request_changes or comment")
+		return
+	}
+	if event != ReviewApprove && strings.TrimSpace(request.Body) == "" {
+		response.SendError(w, http.StatusBadRequest, "A review body is required unless approving")
+		return
+	}
+
+	review, err := h.client.ReviewPull(r.Context(), r.PathValue("owner"), r.PathValue("repo"), index, event, attribute(r, request.Body))
+	if err != nil {
+		sendError(w, "Failed to review pull request", err)
+		return
+	}
+	response.SendJSON(w, http.StatusCreated, review)
+}
+
+// MergePull handles POST /api/gitea/repositories/{owner}/{repo}/pulls/{index}/merge
+// with {"method": "merge" | "rebase" | "rebase-merge" | "squash" | "fast-forward-only", ...}
+func (h *Handler) MergePull(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w) {
+		return
+	}
+	index, ok := pathID(w, r, "index")
+	if !ok {
+		return
+	}
+	var request struct {
+		Method       string `json:"method"`
+		Title        string `json:"title"`
+		Message      string `json:"message"`
+		HeadCommitID string `json:"head_commit_id"`
+		DeleteBranch bool   `json:"delete_branch"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if request.Method == "" {
+		request.Method = "merge"
+	}
+	if !slices.Contains(MergeMethods, request.Method) {
+		response.SendError(w, http.StatusBadRequest, "method must be one of "+strings.Join(MergeMethods, ", "))
+		return
+	}
+
+	owner, repo := r.PathValue("owner"), r.PathValue("repo")
+	err := h.client.MergePull(r.Context(), owner, repo, index, MergeOptions{
+		Method:            request.Method,
+		Title:             request.Title,
+		Message:           request.Message,
+		HeadCommitID:      request.HeadCommitID,
+		DeleteBranchAfter: request.DeleteBranch,
+	})
+	if err != nil {
+		sendError(w, "Failed to merge pull request", err)
+		return
+	}
+
+	user := ""
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		user = claims.Username
+	}
+	slog.Info("Pull request merged", "owner", owner, "repo", repo, "index", index, "method", request.Method, "user", user)
+	response.SendJSON(w, http.StatusOK, map[string]interface{}{"status": "merged", "index": index, "method": request.Method})
+}
+
+// webhookURL returns the address of Denshimon's Gitea webhook receiver
+func (h *Handler) webhookURL(r *http.Request) string {
+	base := h.publicURL
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			scheme = proto
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + "/api/gitea/webhook"
+}
+
+// ProvisionRepository handles POST /api/gitea/repositories/provision. It creates a
+// repository from a template, points a signed push webhook at Denshimon and registers
+// the repository for GitOps. Gitea changes are undone if a later step fails.
+func (h *Handler) ProvisionRepository(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w) {
+		return
+	}
+	if h.gitops == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "GitOps service not available")
+		return
+	}
+	var request struct {
+		TemplateOwner string `json:"template_owner"`
+		TemplateRepo  string `json:"template_repo"`
+		Owner         string `json:"owner"`
+		Name          string `json:"name"`
+		Description   string `json:"description"`
+		Private       bool   `json:"private"`
+		Branch        string `json:"branch"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if request.TemplateOwner == "" || request.TemplateRepo == "" || request.Owner == "" || request.Name == "" {
+		response.SendError(w, http.StatusBadRequest, "template_owner, template_repo, owner and name are required")
+		return
+	}
+
+	ctx := r.Context()
+	repository, err := h.client.GenerateFromTemplate(ctx, request.TemplateOwner, request.TemplateRepo, GenerateOptions{
+		Owner:         request.Owner,
+		Name:          request.Name,
+		Description:   request.Description,
+		Private:       request.Private,
+		DefaultBranch: request.Branch,
+		GitContent:    true,
+		Topics:        true,
+		Labels:        true,
+	})
+	if err != nil {
+		sendError(w, "Failed to create repository from template", err)
+		return
+	}
+	branch := repository.DefaultBranch
+	if branch == "" {
+		branch = "main"
+	}
+
+	rollback := func(step string, cause error) {
+		if err := h.client.DeleteRepository(context.Background(), request.Owner, repository.Name); err != nil {
+			slog.Error("Failed to remove partially provisioned repository", "repository", repository.FullName, "error", err)
+		}
+		sendError(w, step, cause)
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		rollback("Failed to generate webhook secret", err)
+		return
+	}
+	hook, err := h.client.CreatePushHook(ctx, request.Owner, repository.Name, h.webhookURL(r), secret, branch)
+	if err != nil {
+		rollback("Failed to create webhook", err)
+		return
+	}
+
+	record, err := h.gitops.CreateRepository(ctx, repository.Name, repository.CloneURL, branch, request.Description, gitops.ProviderGitea, secret)
+	if err != nil {
+		rollback("Failed to register GitOps repository", err)
+		return
+	}
+
+	user := ""
+	if claims := auth.GetUserFromContext(ctx); claims != nil {
+		user = claims.Username
+	}
+	slog.Info("Provisioned Gitea repository", "repository", repository.FullName, "template", request.TemplateOwner+"/"+request.TemplateRepo, "user", user)
+	response.SendJSON(w, http.StatusCreated, map[string]interface{}{
+		"repository":        repository,
+		"webhook":           hook,
+		"gitops_repository": record,
+	})
+}
@@ -0,0 +1,187 @@
+package gitea
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGitea serves the Actions API for one job whose log grows on every fetch
+type fakeGitea struct {
+	mu        sync.Mutex
+	logChunks []string
+	fetches   int
+	requests  []string
+}
+
+func (f *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	if r.Header.Get("Authorization") != "token secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"message": "token is required"})
+		return
+	}
+
+	switch r.Method + " " + r.URL.Path {
+	case "GET /api/v1/repos/ops/app/actions/runs/7/jobs":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jobs":        []Job{{ID: 42, RunID: 7, Name: "build", Status: JobStatusInProgress}},
+			"total_count": 1,
+		})
+	case "GET /api/v1/repos/ops/app/actions/jobs/42":
+		status := JobStatusInProgress
+		if f.fetches >= len(f.logChunks)-1 {
+			status = JobStatusCompleted
+		}
+		json.NewEncoder(w).Encode(Job{ID: 42, RunID: 7, Status: status})
+	case "GET /api/v1/repos/ops/app/actions/jobs/42/logs":
+		log := ""
+		for i := 0; i <= f.fetches && i < len(f.logChunks); i++ {
+			log += f.logChunks[i]
+		}
+		f.fetches++
+		w.Write([]byte(log))
+	case "POST /api/v1/repos/ops/app/actions/runs/7/rerun-failed-jobs", "POST /api/v1/repos/ops/app/actions/runs/7/cancel":
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "run not found"})
+	}
+}
+
+func setupHandler(t *testing.T, token string) (*Handler, *fakeGitea, *http.ServeMux) {
+	t.Helper()
+	fake := &fakeGitea{logChunks: []string{"step 1\n", "step 2\n", "done\n"}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	handler := NewHandler(NewClient(server.URL+"/", token))
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/gitea/repositories/{owner}/{repo}/actions/runs/{run}/jobs", handler.ListRunJobs)
+	mux.HandleFunc("GET /api/gitea/repositories/{owner}/{repo}/actions/jobs/{job}/logs", handler.GetJobLogs)
+	mux.HandleFunc("POST /api/gitea/repositories/{owner}/{repo}/actions/runs/{run}/rerun", handler.RerunWorkflow)
+	mux.HandleFunc("POST /api/gitea/repositories/{owner}/{repo}/actions/runs/{run}/cancel", handler.CancelWorkflow)
+	return handler, fake, mux
+}
+
+func serve(mux *http.ServeMux, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer dashboard-session")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNotConfigured(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	NewHandler(nil).GetJobLogs(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", rec.Code)
+	}
+}
+
+func TestListRunJobs(t *testing.T) {
+	_, _, mux := setupHandler(t, "secret")
+
+	rec := serve(mux, http.MethodGet, "/api/gitea/repositories/ops/app/actions/runs/7/jobs")
+	var jobs []Job
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &jobs) != nil || len(jobs) != 1 || jobs[0].ID != 42 {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := serve(mux, http.MethodGet, "/api/gitea/repositories/ops/app/actions/runs/abc/jobs"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a non-numeric run, got %d", rec.Code)
+	}
+	if rec := serve(mux, http.MethodGet, "/api/gitea/repositories/ops/app/actions/runs/8/jobs"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected Gitea's 404 to pass through, got %d", rec.Code)
+	}
+}
+
+func TestRejectedToken(t *testing.T) {
+	_, _, mux := setupHandler(t, "wrong")
+
+	// The dashboard session must not be forwarded in place of the server token
+	rec := serve(mux, http.MethodGet, "/api/gitea/repositories/ops/app/actions/runs/7/jobs")
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("expected 502 for a rejected token, got %d", rec.Code)
+	}
+}
+
+func TestGetJobLogs(t *testing.T) {
+	_, _, mux := setupHandler(t, "secret")
+
+	rec := serve(mux, http.MethodGet, "/api/gitea/repositories/ops/app/actions/jobs/42/logs")
+	if rec.Code != http.StatusOK || rec.Body.String() != "step 1\n" {
+		t.Errorf("unexpected log %d: %q", rec.Code, rec.Body)
+	}
+}
+
+func TestFollowJobLogs(t *testing.T) {
+	logPollInterval = 10 * time.Millisecond
+	defer func() { logPollInterval = 2 * time.Second }()
+	_, _, mux := setupHandler(t, "secret")
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- serve(mux, http.MethodGet, "/api/gitea/repositories/ops/app/actions/jobs/42/logs?follow=true")
+	}()
+
+	select {
+	case rec := <-done:
+		if rec.Body.String() != "step 1\nstep 2\ndone\n" {
+			t.Errorf("unexpected streamed log: %q", rec.Body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("following did not stop when the job completed")
+	}
+}
+
+func TestFollowJobLogsOutlivesWriteTimeout(t *testing.T) {
+	logPollInterval = 40 * time.Millisecond
+	defer func() { logPollInterval = 2 * time.Second }()
+	_, fake, mux := setupHandler(t, "secret")
+	fake.logChunks = []string{"step 1\n", "step 2\n", "step 3\n", "step 4\n", "done\n"}
+
+	server := httptest.NewUnstartedServer(mux)
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/gitea/repositories/ops/app/actions/jobs/42/logs?follow=true", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "step 1\nstep 2\nstep 3\nstep 4\ndone\n" {
+		t.Errorf("streamed log cut off: %q, %v", body, err)
+	}
+}
+
+func TestRerunAndCancel(t *testing.T) {
+	_, fake, mux := setupHandler(t, "secret")
+
+	if rec := serve(mux, http.MethodPost, "/api/gitea/repositories/ops/app/actions/runs/7/rerun"); rec.Code != http.StatusOK {
+		t.Errorf("rerun failed %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(mux, http.MethodPost, "/api/gitea/repositories/ops/app/actions/runs/7/cancel"); rec.Code != http.StatusOK {
+		t.Errorf("cancel failed %d: %s", rec.Code, rec.Body)
+	}
+
+	want := []string{
+		"POST /api/v1/repos/ops/app/actions/runs/7/rerun-failed-jobs",
+		"POST /api/v1/repos/ops/app/actions/runs/7/cancel",
+	}
+	if len(fake.requests) != len(want) || fake.requests[0] != want[0] || fake.requests[1] != want[1] {
+		t.Errorf("unexpected Gitea requests: %v", fake.requests)
+	}
+}
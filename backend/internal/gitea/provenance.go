@@ -0,0 +1,318 @@
+package gitea
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/gitops"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+// How the commit behind an image was found
+const (
+	ProvenanceBuild = "build"       // a build started from Denshimon pushed the image
+	ProvenanceLabel = "image_label" // the image's org.opencontainers.image.revision label
+	ProvenanceTag   = "tag"         // the image tag names the commit
+)
+
+// OCI image labels read for provenance
+const (
+	labelRevision = "org.opencontainers.image.revision"
+	labelSource   = "org.opencontainers.image.source"
+)
+
+// ImageLabels reads the labels of an image from a configured registry
+type ImageLabels func(ctx context.Context, registryID, image string) (map[string]string, error)
+
+// Commit is a repository commit with its author
+type Commit struct {
+	SHA     string       `json:"sha"`
+	Message string       `json:"message"`
+	HTMLURL string       `json:"html_url,omitempty"`
+	Author  CommitAuthor `json:"author"`
+	User    *User        `json:"user,omitempty"` // the author's Gitea account, when it is linked
+}
+
+// CommitAuthor is the git author of a commit
+type CommitAuthor struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	Date  time.Time `json:"date"`
+}
+
+// PullRequest is a pull request that brought a commit in
+type PullRequest struct {
+	Number   int64      `json:"number"`
+	Title    string     `json:"title"`
+	State    string     `json:"state"`
+	HTMLURL  string     `json:"html_url,omitempty"`
+	User     User       `json:"user"`
+	MergedAt *time.Time `json:"merged_at,omitempty"`
+}
+
+// Provenance traces the image of a deployment back to the commit, pull request and
+// workflow run that built it
+type Provenance struct {
+	DeploymentID   string       `json:"deployment_id"`
+	Image          string       `json:"image"`
+	ManifestCommit string       `json:"manifest_commit,omitempty"` // GitOps repository commit that deployed the image
+	Owner          string       `json:"owner,omitempty"`
+	Repo           string       `json:"repo,omitempty"`
+	Source         string       `json:"source,omitempty"` // how the commit was found; empty when it wasn't
+	BuildID        string       `json:"build_id,omitempty"`
+	Commit         *Commit      `json:"commit,omitempty"`
+	PullRequest    *PullRequest `json:"pull_request,omitempty"`
+	Run            *WorkflowRun `json:"run,omitempty"`
+	Warnings       []string     `json:"warnings"`
+}
+
+// apiCommit is a commit as the Gitea API returns it
+type apiCommit struct {
+	SHA     string `json:"sha"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Message string       `json:"message"`
+		Author  CommitAuthor `json:"author"`
+	} `json:"commit"`
+	Author *User `json:"author"`
+}
+
+func (a *apiCommit) commit() *Commit {
+	commit := &Commit{
+		SHA:     a.SHA,
+		Message: a.Commit.Message,
+		HTMLURL: a.HTMLURL,
+		Author:  a.Commit.Author,
+	}
+	if a.Author != nil && a.Author.Login != "" {
+		commit.User = a.Author
+	}
+	return commit
+}
+
+// commitQuery leaves out the parts of commits nothing here reads
+var commitQuery = url.Values{"stat": {"false"}, "verification": {"false"}, "files": {"false"}}.Encode()
+
+// GetCommit returns a commit by SHA or ref
+func (c *Client) GetCommit(ctx context.Context, owner, repo, sha string) (*Commit, error) {
+	var result apiCommit
+	if err := c.do(ctx, http.MethodGet, repoPath(owner, repo, "git/commits", url.PathEscape(sha))+"?"+commitQuery, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.commit(), nil
+}
+
+// CommitPull returns the pull request a commit was merged by, or nil when it wasn't
+// merged through one
+func (c *Client) CommitPull(ctx context.Context, owner, repo, sha string) (*PullRequest, error) {
+	var pull PullRequest
+	err := c.do(ctx, http.MethodGet, repoPath(owner, repo, "commits", url.PathEscape(sha), "pull"), nil, &pull)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pull, nil
+}
+
+// SetImageLabels enables finding commits through the revision label of images
+func (h *Handler) SetImageLabels(labels ImageLabels) {
+	h.imageLabels = labels
+}
+
+// GetDeploymentProvenance handles GET /api/deployments/{id}/provenance for deployments
+// and GitOps deployment records, showing the commit, pull request and CI run behind the
+// running image. When the commit can't be found the response says why in its warnings.
+func (h *Handler) GetDeploymentProvenance(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w) {
+		return
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/deployments/"), "/provenance")
+	if id == "" || strings.Contains(id, "/") {
+		response.SendError(w, http.StatusBadRequest, "Deployment ID is required")
+		return
+	}
+
+	ctx := r.Context()
+	var image, registryID, manifestCommit string
+	found := false
+	if h.deployments != nil {
+		deployment, err := h.deployments.GetDeployment(ctx, id)
+		if err == nil {
+			image, registryID, manifestCommit, found = deployment.Image, deployment.RegistryID, deployment.GitCommitSHA, true
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			response.SendError(w, http.StatusInternalServerError, "Failed to get deployment: "+err.Error())
+			return
+		}
+	}
+	if !found && h.gitops != nil {
+		record, err := h.gitops.GetDeploymentRecord(ctx, id)
+		if err == nil {
+			image, manifestCommit, found = record.Image, record.GitHash, true
+		} else if !errors.Is(err, gitops.ErrDeploymentNotFound) {
+			response.SendError(w, http.StatusInternalServerError, "Failed to get deployment record: "+err.Error())
+			return
+		}
+	}
+	if !found {
+		response.SendError(w, http.StatusNotFound, "Deployment not found")
+		return
+	}
+
+	provenance := h.provenance(ctx, registryID, image)
+	provenance.DeploymentID = id
+	provenance.ManifestCommit = manifestCommit
+	response.SendJSON(w, http.StatusOK, provenance)
+}
+
+// imageCommit is the commit an image was built from and how it was found
+type imageCommit struct {
+	owner, repo, sha string
+	source           string
+	build            *Build
+}
+
+// findCommit finds the commit an image was built from, trying in turn a tracked build
+// that pushed it, its revision label and a tag naming the commit. The repository defaults
+// to the image's owner and name. The reasons lookups failed are returned as warnings.
+func (h *Handler) findCommit(ctx context.Context, registryID, image string) (*imageCommit, []string) {
+	var warnings []string
+	var found *imageCommit
+	if build := h.buildOf(image); build != nil {
+		found = &imageCommit{owner: build.Owner, repo: build.Repo, sha: build.CommitSHA, source: ProvenanceBuild, build: build}
+	}
+	if found == nil && h.imageLabels != nil && registryID != "" {
+		labels, err := h.imageLabels(ctx, registryID, image)
+		if err != nil {
+			warnings = append(warnings, "Failed to read image labels: "+err.Error())
+		} else if revision := labels[labelRevision]; revision != "" {
+			owner, repo := sourceRepository(labels[labelSource])
+			found = &imageCommit{owner: owner, repo: repo, sha: revision, source: ProvenanceLabel}
+		}
+	}
+	if found == nil {
+		if sha := tagCommit(image); sha != "" {
+			found = &imageCommit{sha: sha, source: ProvenanceTag}
+		}
+	}
+	if found == nil {
+		return nil, append(warnings, "No build, revision label or commit tag identifies the commit of "+image)
+	}
+	if found.owner == "" {
+		_, owner, name, err := imageRepository(image)
+		if err != nil {
+			return nil, append(warnings, "The image doesn't name its source repository: "+err.Error())
+		}
+		found.owner, found.repo = owner, name
+	}
+	return found, warnings
+}
+
+// provenance finds the commit an image was built from, then looks up the commit's pull
+// request and workflow run. Lookups that fail become warnings.
+func (h *Handler) provenance(ctx context.Context, registryID, image string) *Provenance {
+	p := &Provenance{Image: image, Warnings: []string{}}
+	found, warnings := h.findCommit(ctx, registryID, image)
+	p.Warnings = append(p.Warnings, warnings...)
+	if found == nil {
+		return p
+	}
+	owner, repo := found.owner, found.repo
+	p.Owner, p.Repo, p.Source = owner, repo, found.source
+	var runID int64
+	if found.build != nil {
+		p.BuildID, runID = found.build.ID, found.build.RunID
+	}
+
+	commit, err := h.client.GetCommit(ctx, owner, repo, found.sha)
+	if err != nil {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("Failed to get commit %s of %s/%s: %v", found.sha, owner, repo, err))
+		return p
+	}
+	p.Commit = commit
+
+	if p.PullRequest, err = h.client.CommitPull(ctx, owner, repo, commit.SHA); err != nil {
+		p.Warnings = append(p.Warnings, "Failed to get the pull request of the commit: "+err.Error())
+	}
+
+	if runID > 0 {
+		if p.Run, err = h.client.GetWorkflowRun(ctx, owner, repo, runID); err != nil {
+			p.Warnings = append(p.Warnings, "Failed to get the build's workflow run: "+err.Error())
+		}
+	} else if runs, err := h.client.ListWorkflowRuns(ctx, owner, repo, "", commit.SHA); err != nil {
+		p.Warnings = append(p.Warnings, "Failed to list the workflow runs of the commit: "+err.Error())
+	} else {
+		p.Run = commitRun(runs)
+	}
+	return p
+}
+
+// buildOf returns the newest tracked build that pushed an image
+func (h *Handler) buildOf(image string) *Build {
+	h.buildsMu.Lock()
+	defer h.buildsMu.Unlock()
+	var newest *Build
+	for _, build := range h.builds {
+		if build.Image == image && build.CommitSHA != "" && (newest == nil || build.StartedAt.After(newest.StartedAt)) {
+			snapshot := *build
+			newest = &snapshot
+		}
+	}
+	return newest
+}
+
+// commitRun picks the run that most likely built a commit's image from its runs, newest
+// first: the newest successful one, else the newest
+func commitRun(runs []WorkflowRun) *WorkflowRun {
+	for i := range runs {
+		if runs[i].Conclusion == RunConclusionSuccess {
+			return &runs[i]
+		}
+	}
+	if len(runs) > 0 {
+		return &runs[0]
+	}
+	return nil
+}
+
+// sourceRepository reads the owner and name of a repository from its URL, as set in the
+// org.opencontainers.image.source label
+func sourceRepository(source string) (string, string) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return "", ""
+	}
+	parts := strings.Split(strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// tagCommit returns the commit SHA an image tag names, with or without a sha- prefix,
+// or "" for other tags
+func tagCommit(image string) string {
+	reference, _, _ := strings.Cut(image, "@")
+	i := strings.LastIndex(reference, ":")
+	if i <= strings.LastIndex(reference, "/") {
+		return ""
+	}
+	candidate := strings.TrimPrefix(reference[i+1:], "sha-")
+	if len(candidate) < 7 || len(candidate) > 40 {
+		return ""
+	}
+	for _, c := range candidate {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return ""
+		}
+	}
+	return candidate
+}
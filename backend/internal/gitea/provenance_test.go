@@ -0,0 +1,100 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// provenanceServer serves a commit merged by pull request 12 and built by two runs
+func provenanceServer(t *testing.T, sha string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/repos/ops/api/git/commits/3f2a9c1", "/api/v1/repos/ops/api/git/commits/" + sha:
+			w.Write([]byte(`{"sha": "` + sha + `", "html_url": "https://gitea.example.com/ops/api/commit/` + sha + `",
+				"commit": {"message": "Fix login redirect\n", "author": {"name": "Dana", "email": "dana@example.com", "date": "2026-03-01T10:00:00Z"}},
+				"author": {"id": 3, "login": "dana"}}`))
+		case "/api/v1/repos/ops/api/commits/" + sha + "/pull":
+			w.Write([]byte(`{"number": 12, "title": "Fix login redirect", "state": "closed", "user": {"id": 3, "login": "dana"}, "merged_at": "2026-03-01T10:05:00Z"}`))
+		case "/api/v1/repos/ops/api/actions/runs":
+			if r.URL.Query().Get("head_sha") != sha || r.URL.Query().Has("event") {
+				t.Errorf("unexpected run query %q", r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"workflow_runs": []WorkflowRun{
+				{ID: 9, Path: "lint.yml@refs/heads/main", HeadSHA: sha, Status: JobStatusInProgress},
+				{ID: 8, Path: "build.yml@refs/heads/main", HeadSHA: sha, Status: JobStatusCompleted, Conclusion: RunConclusionSuccess},
+			}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDeploymentProvenance(t *testing.T) {
+	const sha = "3f2a9c1e8b7d6a5f4e3d2c1b0a9f8e7d6c5b4a39"
+	server := provenanceServer(t, sha)
+	handler := NewHandler(NewClient(server.URL, "secret"))
+	handler.SetDeploymentService(&fakeDeployments{image: "gitea.example.com/ops/api:sha-3f2a9c1"})
+
+	recorder := httptest.NewRecorder()
+	handler.GetDeploymentProvenance(recorder, httptest.NewRequest(http.MethodGet, "/api/deployments/dep-1/provenance", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", recorder.Code, recorder.Body)
+	}
+	var provenance Provenance
+	json.NewDecoder(recorder.Body).Decode(&provenance)
+	if provenance.DeploymentID != "dep-1" || provenance.Source != ProvenanceTag || provenance.Owner != "ops" || provenance.Repo != "api" {
+		t.Errorf("provenance = %+v", provenance)
+	}
+	if provenance.Commit == nil || provenance.Commit.SHA != sha || provenance.Commit.Author.Name != "Dana" || provenance.Commit.User.Login != "dana" {
+		t.Errorf("commit = %+v", provenance.Commit)
+	}
+	if provenance.PullRequest == nil || provenance.PullRequest.Number != 12 {
+		t.Errorf("pull request = %+v", provenance.PullRequest)
+	}
+	if provenance.Run == nil || provenance.Run.ID != 8 {
+		t.Errorf("expected the successful run, got %+v", provenance.Run)
+	}
+	if len(provenance.Warnings) != 0 {
+		t.Errorf("warnings = %v", provenance.Warnings)
+	}
+}
+
+func TestProvenanceFromLabels(t *testing.T) {
+	const sha = "3f2a9c1e8b7d6a5f4e3d2c1b0a9f8e7d6c5b4a39"
+	server := provenanceServer(t, sha)
+	handler := NewHandler(NewClient(server.URL, "secret"))
+	handler.SetImageLabels(func(ctx context.Context, registryID, image string) (map[string]string, error) {
+		return map[string]string{labelRevision: sha, labelSource: "https://gitea.example.com/ops/api.git"}, nil
+	})
+
+	// The image lives under another owner than its repository
+	provenance := handler.provenance(context.Background(), "gitea", "registry.example.com/builds/api:v1.4.0")
+	if provenance.Source != ProvenanceLabel || provenance.Owner != "ops" || provenance.Commit == nil {
+		t.Errorf("provenance = %+v", provenance)
+	}
+
+	// Without a registry only the tag is left, and v1.4.0 names no commit
+	provenance = handler.provenance(context.Background(), "", "registry.example.com/builds/api:v1.4.0")
+	if provenance.Source != "" || provenance.Commit != nil || len(provenance.Warnings) != 1 {
+		t.Errorf("provenance = %+v", provenance)
+	}
+}
+
+func TestTagCommit(t *testing.T) {
+	for image, want := range map[string]string{
+		"gitea.example.com/ops/api:sha-3f2a9c1":            "3f2a9c1",
+		"gitea.example.com/ops/api:3f2a9c1e8b7d@sha256:00": "3f2a9c1e8b7d",
+		"gitea.example.com/ops/api:v1.2.0":                 "",
+		"gitea.example.com/ops/api:beef":                   "",
+		"localhost:5000/api":                               "",
+	} {
+		if got := tagCommit(image); got != want {
+			t.Errorf("tagCommit(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
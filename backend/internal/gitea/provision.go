@@ -0,0 +1,87 @@
+package gitea
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Repository is a Gitea repository
+type Repository struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Description   string `json:"description"`
+	Private       bool   `json:"private"`
+	DefaultBranch string `json:"default_branch"`
+	CloneURL      string `json:"clone_url"`
+	SSHURL        string `json:"ssh_url"`
+	HTMLURL       string `json:"html_url"`
+}
+
+// GenerateOptions describes a repository created from a template
+type GenerateOptions struct {
+	Owner         string `json:"owner"` // user or organisation receiving the repository
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+	Private       bool   `json:"private"`
+	DefaultBranch string `json:"default_branch,omitempty"`
+	GitContent    bool   `json:"git_content"`
+	Topics        bool   `json:"topics"`
+	Labels        bool   `json:"labels"`
+	Webhooks      bool   `json:"webhooks"`
+}
+
+// Hook is a repository webhook
+type Hook struct {
+	ID     int64             `json:"id"`
+	Type   string            `json:"type"`
+	Active bool              `json:"active"`
+	Events []string          `json:"events"`
+	Config map[string]string `json:"config"`
+}
+
+// GenerateFromTemplate creates a repository from a template repository
+func (c *Client) GenerateFromTemplate(ctx context.Context, templateOwner, templateRepo string, options GenerateOptions) (*Repository, error) {
+	var repository Repository
+	if err := c.do(ctx, http.MethodPost, repoPath(templateOwner, templateRepo, "generate"), options, &repository); err != nil {
+		return nil, err
+	}
+	return &repository, nil
+}
+
+// DeleteRepository deletes a repository
+func (c *Client) DeleteRepository(ctx context.Context, owner, repo string) error {
+	return c.do(ctx, http.MethodDelete, repoPath(owner, repo), nil, nil)
+}
+
+// CreatePushHook adds a Gitea-format webhook sending push events to url, signed with secret
+func (c *Client) CreatePushHook(ctx context.Context, owner, repo, url, secret, branchFilter string) (*Hook, error) {
+	request := map[string]interface{}{
+		"type":   "gitea",
+		"active": true,
+		"events": []string{"push"},
+		"config": map[string]string{
+			"url":          url,
+			"content_type": "json",
+			"secret":       secret,
+		},
+		"branch_filter": branchFilter,
+	}
+	var hook Hook
+	if err := c.do(ctx, http.MethodPost, repoPath(owner, repo, "hooks"), request, &hook); err != nil {
+		return nil, err
+	}
+	delete(hook.Config, "secret")
+	return &hook, nil
+}
+
+// generateSecret returns a random hex webhook secret
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
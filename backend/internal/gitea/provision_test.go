@@ -0,0 +1,94 @@
+package gitea
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/archellir/denshimon/internal/gitops"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestProvisionRepository(t *testing.T) {
+	var hookConfig map[string]string
+	deleted := false
+	failHook := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/repos/templates/service/generate":
+			var options GenerateOptions
+			json.NewDecoder(r.Body).Decode(&options)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(Repository{
+				ID: 9, Name: options.Name, FullName: options.Owner + "/" + options.Name,
+				DefaultBranch: "main", CloneURL: "https://gitea.example.com/" + options.Owner + "/" + options.Name + ".git",
+			})
+		case "POST /api/v1/repos/ops/billing/hooks":
+			if failHook {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				json.NewEncoder(w).Encode(map[string]string{"message": "invalid url"})
+				return
+			}
+			var hook Hook
+			json.NewDecoder(r.Body).Decode(&hook)
+			hookConfig = hook.Config
+			hook.ID = 3
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(hook)
+		case "DELETE /api/v1/repos/ops/billing":
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	gitopsService := gitops.NewService(db, "", t.TempDir())
+
+	handler := NewHandler(NewClient(server.URL, "secret"))
+	handler.SetGitOpsService(gitopsService)
+	handler.SetPublicURL("https://denshimon.example.com/")
+
+	provision := func() *httptest.ResponseRecorder {
+		body := `{"template_owner":"templates","template_repo":"service","owner":"ops","name":"billing"}`
+		rec := httptest.NewRecorder()
+		handler.ProvisionRepository(rec, httptest.NewRequest(http.MethodPost, "/api/gitea/repositories/provision", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := provision()
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("provision failed %d: %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), hookConfig["secret"]) {
+		t.Error("webhook secret returned to the caller")
+	}
+	if hookConfig["url"] != "https://denshimon.example.com/api/gitea/webhook" || len(hookConfig["secret"]) != 64 {
+		t.Errorf("unexpected webhook config: %v", hookConfig)
+	}
+
+	repos, err := gitopsService.ListRepositories(context.Background())
+	if err != nil || len(repos) != 1 {
+		t.Fatalf("expected one GitOps repository, got %v %v", repos, err)
+	}
+	if repos[0].WebhookSecret != hookConfig["secret"] || repos[0].Provider != gitops.ProviderGitea || repos[0].Branch != "main" {
+		t.Errorf("unexpected GitOps repository: %+v", repos[0])
+	}
+
+	// A failing step removes the generated repository
+	failHook = true
+	if rec := provision(); rec.Code != http.StatusUnprocessableEntity || !deleted {
+		t.Errorf("expected rollback after a webhook failure, got %d (deleted %v)", rec.Code, deleted)
+	}
+}
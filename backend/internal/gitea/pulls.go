@@ -0,0 +1,89 @@
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Review events accepted by Gitea
+const (
+	ReviewComment        = "COMMENT"
+	ReviewApprove        = "APPROVED"
+	ReviewRequestChanges = "REQUEST_CHANGES"
+)
+
+// Merge methods accepted by Gitea
+var MergeMethods = []string{"merge", "rebase", "rebase-merge", "squash", "fast-forward-only"}
+
+// Comment is an issue or pull request comment
+type Comment struct {
+	ID        int64     `json:"id"`
+	Body      string    `json:"body"`
+	HTMLURL   string    `json:"html_url,omitempty"`
+	User      User      `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Review is a pull request review
+type Review struct {
+	ID          int64     `json:"id"`
+	State       string    `json:"state"`
+	Body        string    `json:"body"`
+	CommitID    string    `json:"commit_id,omitempty"`
+	HTMLURL     string    `json:"html_url,omitempty"`
+	User        User      `json:"user"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// User is the author of a comment or review
+type User struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+}
+
+// MergeOptions selects how a pull request is merged
+type MergeOptions struct {
+	Method            string `json:"Do"`
+	Title             string `json:"MergeTitleField,omitempty"`
+	Message           string `json:"MergeMessageField,omitempty"`
+	HeadCommitID      string `json:"head_commit_id,omitempty"` // refuse to merge if the head moved
+	DeleteBranchAfter bool   `json:"delete_branch_after_merge,omitempty"`
+}
+
+func pullPath(owner, repo string, index int64, parts ...string) string {
+	return repoPath(owner, repo, append([]string{"pulls", strconv.FormatInt(index, 10)}, parts...)...)
+}
+
+// CommentOnPull adds a conversation comment to a pull request
+func (c *Client) CommentOnPull(ctx context.Context, owner, repo string, index int64, body string) (*Comment, error) {
+	// Pull requests share the issue comment API
+	path := repoPath(owner, repo, "issues", strconv.FormatInt(index, 10), "comments")
+	var comment Comment
+	if err := c.do(ctx, http.MethodPost, path, map[string]string{"body": body}, &comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// ReviewPull submits a review with the given event: COMMENT, APPROVED or REQUEST_CHANGES
+func (c *Client) ReviewPull(ctx context.Context, owner, repo string, index int64, event, body string) (*Review, error) {
+	switch event {
+	case ReviewComment, ReviewApprove, ReviewRequestChanges:
+	default:
+		return nil, fmt.Errorf("unknown review event %q", event)
+	}
+	var review Review
+	request := map[string]string{"event": event, "body": body}
+	if err := c.do(ctx, http.MethodPost, pullPath(owner, repo, index, "reviews"), request, &review); err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// MergePull merges a pull request with the chosen method
+func (c *Client) MergePull(ctx context.Context, owner, repo string, index int64, options MergeOptions) error {
+	return c.do(ctx, http.MethodPost, pullPath(owner, repo, index, "merge"), options, nil)
+}
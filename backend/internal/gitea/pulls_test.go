@@ -0,0 +1,83 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/archellir/denshimon/internal/auth"
+)
+
+func TestPullRequestActions(t *testing.T) {
+	bodies := map[string]map[string]interface{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies[r.URL.Path] = body
+
+		switch r.URL.Path {
+		case "/api/v1/repos/ops/app/issues/3/comments":
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(Comment{ID: 1, Body: body["body"].(string)})
+		case "/api/v1/repos/ops/app/pulls/3/reviews":
+			json.NewEncoder(w).Encode(Review{ID: 2, State: body["event"].(string)})
+		case "/api/v1/repos/ops/app/pulls/3/merge":
+			w.WriteHeader(http.StatusOK)
+		case "/api/v1/repos/ops/app/pulls/4/merge":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Please try again later"})
+		}
+	}))
+	defer server.Close()
+
+	handler := NewHandler(NewClient(server.URL, "secret"))
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/gitea/repositories/{owner}/{repo}/pulls/{index}/comments", handler.CommentOnPull)
+	mux.HandleFunc("POST /api/gitea/repositories/{owner}/{repo}/pulls/{index}/reviews", handler.ReviewPull)
+	mux.HandleFunc("POST /api/gitea/repositories/{owner}/{repo}/pulls/{index}/merge", handler.MergePull)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, &auth.TokenClaims{Username: "alice"}))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("/api/gitea/repositories/ops/app/pulls/3/comments", `{"body":"Looks good"}`); rec.Code != http.StatusCreated {
+		t.Errorf("comment failed %d: %s", rec.Code, rec.Body)
+	}
+	if body := bodies["/api/v1/repos/ops/app/issues/3/comments"]["body"]; body != "Looks good\n\n_Submitted from Denshimon by alice_" {
+		t.Errorf("comment not attributed: %q", body)
+	}
+
+	if rec := post("/api/gitea/repositories/ops/app/pulls/3/reviews", `{"event":"approve"}`); rec.Code != http.StatusCreated {
+		t.Errorf("approve failed %d: %s", rec.Code, rec.Body)
+	}
+	if event := bodies["/api/v1/repos/ops/app/pulls/3/reviews"]["event"]; event != ReviewApprove {
+		t.Errorf("unexpected review event %v", event)
+	}
+	if rec := post("/api/gitea/repositories/ops/app/pulls/3/reviews", `{"event":"request_changes"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for request_changes without a body, got %d", rec.Code)
+	}
+	if rec := post("/api/gitea/repositories/ops/app/pulls/3/reviews", `{"event":"reject","body":"no"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown event, got %d", rec.Code)
+	}
+
+	if rec := post("/api/gitea/repositories/ops/app/pulls/3/merge", `{"method":"squash","delete_branch":true}`); rec.Code != http.StatusOK {
+		t.Errorf("merge failed %d: %s", rec.Code, rec.Body)
+	}
+	merge := bodies["/api/v1/repos/ops/app/pulls/3/merge"]
+	if merge["Do"] != "squash" || merge["delete_branch_after_merge"] != true {
+		t.Errorf("unexpected merge options: %v", merge)
+	}
+	if rec := post("/api/gitea/repositories/ops/app/pulls/3/merge", `{"method":"octopus"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown merge method, got %d", rec.Code)
+	}
+	if rec := post("/api/gitea/repositories/ops/app/pulls/4/merge", `{}`); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for an unmergeable pull request, got %d", rec.Code)
+	}
+}
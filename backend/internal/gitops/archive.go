@@ -0,0 +1,217 @@
+package gitops
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ErrClusterUnavailable is returned when pruning live resources without a Kubernetes client
+var ErrClusterUnavailable = errors.New("kubernetes client not available")
+
+// ArchivedApplication is a deleted application kept together with its deployment history
+type ArchivedApplication struct {
+	Application Application        `json:"application"`
+	Deployments []DeploymentRecord `json:"deployments"`
+	// GitHash is the commit that removed the application's manifests, empty if none were in the repository
+	GitHash string `json:"git_hash"`
+	// Pruned lists the live resources deleted with the application
+	Pruned    []k8s.ApplyResult `json:"pruned"`
+	DeletedBy string            `json:"deleted_by"`
+	DeletedAt time.Time         `json:"deleted_at"`
+}
+
+// SetK8sClient sets the Kubernetes client used to prune live resources of deleted applications
+func (s *Service) SetK8sClient(k8sClient *k8s.Client) {
+	s.k8sClient = k8sClient
+}
+
+// DeleteApplication removes an application's manifests from the repository in one commit,
+// optionally deletes its live resources, and archives the application with its deployment
+// history. Live resources are deleted after the commit so GitOps controllers watching the
+// repository don't recreate them.
+func (s *Service) DeleteApplication(ctx context.Context, appID, deletedBy string, prune bool) (*ArchivedApplication, error) {
+	app, err := s.getApplication(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	// Name and namespace locate the manifests to remove
+	if err := validateApplication(app.Name, app.Namespace); err != nil {
+		return nil, err
+	}
+	if prune && s.k8sClient == nil {
+		return nil, ErrClusterUnavailable
+	}
+
+	// Render before the manifests are removed, overlays may set other namespaces
+	var objects []*unstructured.Unstructured
+	if prune {
+		if objects, err = s.applicationObjects(ctx, app); err != nil {
+			return nil, fmt.Errorf("failed to render application resources: %w", err)
+		}
+	}
+
+	history, err := s.GetDeploymentHistory(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	archived := &ArchivedApplication{
+		Application: *app,
+		Deployments: history,
+		Pruned:      []k8s.ApplyResult{},
+		DeletedBy:   deletedBy,
+		DeletedAt:   time.Now(),
+	}
+	if archived.Deployments == nil {
+		archived.Deployments = []DeploymentRecord{}
+	}
+
+	if archived.GitHash, err = s.removeManifests(app, deletedBy); err != nil {
+		return nil, err
+	}
+	if prune {
+		archived.Pruned = s.k8sClient.DeleteObjects(ctx, objects)
+	}
+
+	if err := s.archiveApplication(ctx, archived); err != nil {
+		return nil, err
+	}
+	return archived, nil
+}
+
+// removeManifests deletes every manifest layout an application may have in the repository:
+// the deploy manifest, the synced manifest and its separate ingress, and the Kustomize directory
+func (s *Service) removeManifests(app *Application, deletedBy string) (string, error) {
+	manifestPath := DefaultSyncConfig().ManifestPath
+	candidates := []string{
+		filepath.Join("k8s", app.Namespace, fmt.Sprintf("%s-deployment.yaml", app.Name)),
+		filepath.Join(manifestPath, app.Namespace, fmt.Sprintf("%s.yaml", app.Name)),
+		filepath.Join(manifestPath, app.Namespace, fmt.Sprintf("%s-ingress.yaml", app.Name)),
+		filepath.Join(manifestPath, app.Namespace, app.Name),
+	}
+
+	var removed []string
+	for _, candidate := range candidates {
+		existed, err := s.gitClient.RemovePath(candidate)
+		if err != nil {
+			return "", err
+		}
+		if existed {
+			removed = append(removed, candidate)
+		}
+	}
+	if len(removed) == 0 {
+		return "", nil
+	}
+
+	commitMsg := fmt.Sprintf("chore(%s): remove %s\n\nApplication deleted by %s", app.Namespace, app.Name, deletedBy)
+	if err := s.gitClient.CommitAndPush(commitMsg, removed...); err != nil {
+		return "", fmt.Errorf("failed to commit manifest removal: %w", err)
+	}
+
+	commits, err := s.gitClient.Log(1)
+	if err != nil {
+		return "", fmt.Errorf("failed to get git hash: %w", err)
+	}
+	if len(commits) == 0 {
+		return "", nil
+	}
+	return commits[0].Hash, nil
+}
+
+// applicationObjects renders the objects an application declares in its base and in every
+// environment overlay, each object once
+func (s *Service) applicationObjects(ctx context.Context, app *Application) ([]*unstructured.Unstructured, error) {
+	seen := make(map[string]bool)
+	var objects []*unstructured.Unstructured
+	for _, env := range append([]string{""}, DefaultEnvironments...) {
+		rendered, err := s.RenderApplication(ctx, app.ID, env)
+		if err != nil {
+			if env == "" {
+				return nil, err
+			}
+			// The application has no overlay for this environment
+			continue
+		}
+		for _, obj := range rendered {
+			if obj.GetNamespace() == "" {
+				obj.SetNamespace(app.Namespace)
+			}
+			key := obj.GetAPIVersion() + "/" + obj.GetKind() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+			if !seen[key] {
+				seen[key] = true
+				objects = append(objects, obj)
+			}
+		}
+	}
+	return objects, nil
+}
+
+// archiveApplication stores the archive record and deletes the application with its history
+func (s *Service) archiveApplication(ctx context.Context, archived *ArchivedApplication) error {
+	appJSON, _ := json.Marshal(archived.Application)
+	deploymentsJSON, _ := json.Marshal(archived.Deployments)
+	prunedJSON, _ := json.Marshal(archived.Pruned)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	app := archived.Application
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO gitops_archived_applications (id, name, namespace, repository_id, application, deployments, git_hash, pruned, deleted_by, deleted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		app.ID, app.Name, app.Namespace, app.RepositoryID, string(appJSON), string(deploymentsJSON),
+		archived.GitHash, string(prunedJSON), archived.DeletedBy, archived.DeletedAt); err != nil {
+		return fmt.Errorf("failed to archive application: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM gitops_deployments WHERE application_id = ?`, app.ID); err != nil {
+		return fmt.Errorf("failed to delete deployment history: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM gitops_applications WHERE id = ?`, app.ID); err != nil {
+		return fmt.Errorf("failed to delete application: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListArchivedApplications returns deleted applications with their history, most recently deleted first
+func (s *Service) ListArchivedApplications(ctx context.Context) ([]ArchivedApplication, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT application, deployments, git_hash, pruned, deleted_by, deleted_at
+		FROM gitops_archived_applications
+		ORDER BY deleted_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archived applications: %w", err)
+	}
+	defer rows.Close()
+
+	archived := []ArchivedApplication{}
+	for rows.Next() {
+		var entry ArchivedApplication
+		var appJSON, deploymentsJSON, prunedJSON string
+		var gitHash sql.NullString
+		if err := rows.Scan(&appJSON, &deploymentsJSON, &gitHash, &prunedJSON, &entry.DeletedBy, &entry.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan archived application: %w", err)
+		}
+		json.Unmarshal([]byte(appJSON), &entry.Application)
+		json.Unmarshal([]byte(deploymentsJSON), &entry.Deployments)
+		json.Unmarshal([]byte(prunedJSON), &entry.Pruned)
+		entry.GitHash = gitHash.String
+		archived = append(archived, entry)
+	}
+	return archived, rows.Err()
+}
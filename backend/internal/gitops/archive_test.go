@@ -0,0 +1,82 @@
+package gitops
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/archellir/denshimon/internal/git"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestApplicationNamesStayInsideTheRepository(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	root := t.TempDir()
+	service := NewService(db, "https://git.example.com/homelab/base_infrastructure.git", filepath.Join(root, "repo"))
+	ctx := context.Background()
+
+	for _, app := range []struct{ name, namespace string }{
+		{"..", "prod"},
+		{".", "prod"},
+		{"api", ".."},
+		{"api/v2", "prod"},
+		{"API", "prod"},
+		{"api", ""},
+	} {
+		if _, err := service.CreateApplication(ctx, app.name, app.namespace, "", "", "nginx:1.27", 1, nil, nil); !errors.Is(err, ErrInvalidApplication) {
+			t.Errorf("CreateApplication(%q, %q): expected ErrInvalidApplication, got %v", app.name, app.namespace, err)
+		}
+	}
+
+	// Applications stored before names were validated aren't deleted either
+	app, err := service.CreateApplication(ctx, "api", "prod", "", "", "nginx:1.27", 1, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE gitops_applications SET namespace = '..' WHERE id = ?`, app.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.DeleteApplication(ctx, app.ID, "admin", false); !errors.Is(err, ErrInvalidApplication) {
+		t.Errorf("DeleteApplication: expected ErrInvalidApplication, got %v", err)
+	}
+}
+
+func TestRemovePathContainment(t *testing.T) {
+	root := t.TempDir()
+	repoPath := filepath.Join(root, "repo")
+	manifest := filepath.Join(repoPath, "k8s", "prod", "api-deployment.yaml")
+	outside := filepath.Join(root, "keep.txt")
+	if err := os.MkdirAll(filepath.Dir(manifest), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{manifest, outside} {
+		if err := os.WriteFile(path, []byte("kind: Deployment\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := git.NewClient("https://git.example.com/homelab/base_infrastructure.git", repoPath, "main")
+
+	for _, path := range []string{"..", ".", "", "k8s/../..", "../keep.txt", outside} {
+		if _, err := client.RemovePath(path); err == nil {
+			t.Errorf("RemovePath(%q) succeeded, want it refused", path)
+		}
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("file outside the repository was removed: %v", err)
+	}
+
+	removed, err := client.RemovePath(filepath.Join("k8s", "prod", "api-deployment.yaml"))
+	if err != nil || !removed {
+		t.Fatalf("RemovePath = %v, %v", removed, err)
+	}
+	if _, err := os.Stat(manifest); !os.IsNotExist(err) {
+		t.Errorf("manifest still exists: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package gitops
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// changelogTimeout bounds generating the changelog of a deployment
+const changelogTimeout = 30 * time.Second
+
+// Changelog lists the commits between the previously deployed image of an application
+// and the one a deployment rolled out
+type Changelog struct {
+	PreviousImage string            `json:"previous_image"`
+	Repository    string            `json:"repository,omitempty"` // owner/name the commits are from
+	From          string            `json:"from,omitempty"`       // commit of the previous image
+	To            string            `json:"to,omitempty"`         // commit of the deployed image
+	CompareURL    string            `json:"compare_url,omitempty"`
+	Commits       []ChangelogCommit `json:"commits"`
+	TotalCommits  int               `json:"total_commits"`   // more than listed when the range is long
+	Error         string            `json:"error,omitempty"` // why the commit range couldn't be computed
+	GeneratedAt   time.Time         `json:"generated_at"`
+}
+
+// ChangelogCommit is one commit of a changelog
+type ChangelogCommit struct {
+	SHA     string    `json:"sha"`
+	Message string    `json:"message"` // first line of the commit message
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	URL     string    `json:"url,omitempty"`
+}
+
+// ChangelogSource computes the commits between two images, such as the Gitea
+// integration finding the commits they were built from
+type ChangelogSource interface {
+	Changelog(ctx context.Context, previousImage, image string) (*Changelog, error)
+}
+
+// SetChangelogSource enables attaching changelogs to deployments of new images
+func (s *Service) SetChangelogSource(source ChangelogSource) {
+	s.changelogSource = source
+}
+
+// previousImage returns the image of the application's last deployment before the given one,
+// or "" for its first deployment
+func (s *Service) previousImage(ctx context.Context, appID, deploymentID string) (string, error) {
+	var image string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT image FROM gitops_deployments
+		WHERE application_id = ? AND deployed_at < (SELECT deployed_at FROM gitops_deployments WHERE id = ?)
+		ORDER BY deployed_at DESC
+		LIMIT 1`, appID, deploymentID).Scan(&image)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return image, err
+}
+
+// attachChangelog generates the changelog of a deployment that changed the application's
+// image and stores it on the deployment record. Failures to compute the commit range are
+// kept in the changelog so the history shows why it's missing.
+func (s *Service) attachChangelog(deployment *DeploymentRecord) {
+	if s.changelogSource == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), changelogTimeout)
+	defer cancel()
+
+	previous, err := s.previousImage(ctx, deployment.ApplicationID, deployment.ID)
+	if err != nil {
+		slog.Warn("Failed to find the previous deployment", "application", deployment.ApplicationID, "error", err)
+		return
+	}
+	if previous == "" || previous == deployment.Image {
+		return
+	}
+
+	changelog, err := s.changelogSource.Changelog(ctx, previous, deployment.Image)
+	if err != nil {
+		changelog = &Changelog{PreviousImage: previous, Commits: []ChangelogCommit{}, Error: err.Error()}
+	}
+	changelog.GeneratedAt = time.Now()
+	if err := s.saveChangelog(ctx, deployment.ID, changelog); err != nil {
+		slog.Warn("Failed to store deployment changelog", "deployment", deployment.ID, "error", err)
+	}
+}
+
+func (s *Service) saveChangelog(ctx context.Context, deploymentID string, changelog *Changelog) error {
+	data, err := json.Marshal(changelog)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE gitops_deployments SET changelog = ? WHERE id = ?`, string(data), deploymentID); err != nil {
+		return fmt.Errorf("failed to store changelog: %w", err)
+	}
+	return nil
+}
+
+// parseChangelog reads a stored changelog, nil when there is none
+func parseChangelog(data sql.NullString) *Changelog {
+	if !data.Valid || data.String == "" {
+		return nil
+	}
+	var changelog Changelog
+	if err := json.Unmarshal([]byte(data.String), &changelog); err != nil {
+		return nil
+	}
+	return &changelog
+}
@@ -0,0 +1,85 @@
+package gitops
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type fakeChangelogSource struct {
+	err   error
+	calls int
+}
+
+func (f *fakeChangelogSource) Changelog(ctx context.Context, previousImage, image string) (*Changelog, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &Changelog{
+		PreviousImage: previousImage,
+		Repository:    "ops/api",
+		Commits:       []ChangelogCommit{{SHA: "bbbbbbb", Message: "Fix login redirect", Author: "Dana"}},
+		TotalCommits:  1,
+	}, nil
+}
+
+func TestAttachChangelog(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	service := NewService(db, "https://git.example.com/homelab/base_infrastructure.git", t.TempDir())
+
+	ctx := context.Background()
+	deployedAt := time.Now().Add(-time.Hour)
+	var records []*DeploymentRecord
+	for i, image := range []string{"git.example.com/ops/api:sha-aaaaaaa", "git.example.com/ops/api:sha-bbbbbbb", "git.example.com/ops/api:sha-bbbbbbb"} {
+		record := &DeploymentRecord{ID: string(rune('a' + i)), ApplicationID: "app-1", Image: image, DeployedAt: deployedAt.Add(time.Duration(i) * time.Minute)}
+		if _, err := db.ExecContext(ctx, `INSERT INTO gitops_deployments (id, application_id, image, replicas, environment, git_hash, status, message, deployed_by, deployed_at)
+			VALUES (?, ?, ?, 1, '{}', '', 'deployed', '', 'admin', ?)`, record.ID, record.ApplicationID, record.Image, record.DeployedAt); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+
+	// Nothing is generated without a source
+	service.attachChangelog(records[1])
+
+	source := &fakeChangelogSource{}
+	service.SetChangelogSource(source)
+	for _, record := range records {
+		service.attachChangelog(record)
+	}
+	// Only the deployment changing the image has a changelog
+	if source.calls != 1 {
+		t.Errorf("changelog generated %d times, want 1", source.calls)
+	}
+	history, err := service.GetDeploymentHistory(ctx, "app-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 || history[0].Changelog != nil || history[2].Changelog != nil {
+		t.Fatalf("history = %+v", history)
+	}
+	changelog := history[1].Changelog
+	if changelog == nil || changelog.PreviousImage != records[0].Image || len(changelog.Commits) != 1 || changelog.GeneratedAt.IsZero() {
+		t.Errorf("changelog = %+v", changelog)
+	}
+
+	// Failures are kept on the record
+	source.err = errors.New("no commit tag")
+	service.attachChangelog(records[1])
+	record, err := service.GetDeploymentRecord(ctx, records[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Changelog == nil || record.Changelog.Error != "no commit tag" {
+		t.Errorf("changelog = %+v", record.Changelog)
+	}
+}
@@ -0,0 +1,396 @@
+package gitops
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/git"
+	"github.com/archellir/denshimon/internal/secretbox"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	// ErrCredentialNotFound is returned when a repository has no stored credential
+	ErrCredentialNotFound = errors.New("credential not found")
+	// ErrCredentialKeyMissing is returned when credentials are used before an encryption key is set
+	ErrCredentialKeyMissing = errors.New("credential encryption key not configured")
+)
+
+// RepositoryCredential describes the credential attached to a repository. Secret material is never exposed.
+type RepositoryCredential struct {
+	ID           string         `json:"id"`
+	RepositoryID string         `json:"repository_id"`
+	Type         git.AuthMethod `json:"type"`
+	Username     string         `json:"username,omitempty"`
+	PublicKey    string         `json:"public_key,omitempty"`
+	Fingerprint  string         `json:"fingerprint,omitempty"`
+	KnownHosts   string         `json:"known_hosts,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+// CredentialInput is the request to set a repository credential.
+// A deploy_key without a private key generates a new ed25519 key pair.
+type CredentialInput struct {
+	Type       git.AuthMethod `json:"type"`
+	Username   string         `json:"username"`
+	Token      string         `json:"token"`
+	PrivateKey string         `json:"private_key"`
+	KnownHosts string         `json:"known_hosts"`
+}
+
+// SetEncryption sets the box repository credentials and webhook secrets are sealed with at
+// rest. Credentials sealed before under the credential key are still read, until
+// ResealCredentials moves them.
+func (s *Service) SetEncryption(box *secretbox.Box) {
+	s.box = box
+}
+
+// SetCredentialKey sets the key credentials are encrypted with at rest when no box is set,
+// and applies any stored credential for the base infrastructure repository to the
+// service's git client
+func (s *Service) SetCredentialKey(ctx context.Context, key string) error {
+	if key == "" {
+		return ErrCredentialKeyMissing
+	}
+	sum := sha256.Sum256([]byte(key))
+	s.credentialKey = sum[:]
+
+	return s.applyBaseCredentials(ctx)
+}
+
+// SetRepositoryCredential validates, encrypts and stores a repository credential, replacing any existing one
+func (s *Service) SetRepositoryCredential(ctx context.Context, repoID string, input CredentialInput) (*RepositoryCredential, error) {
+	repo, err := s.GetRepository(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	credential := &RepositoryCredential{
+		ID:           uuid.New().String(),
+		RepositoryID: repo.ID,
+		Type:         input.Type,
+		Username:     input.Username,
+		KnownHosts:   strings.TrimSpace(input.KnownHosts),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	var secret []byte
+	switch input.Type {
+	case git.AuthHTTPSToken:
+		if input.Token == "" {
+			return nil, fmt.Errorf("token is required for %s credentials", input.Type)
+		}
+		secret = []byte(input.Token)
+
+	case git.AuthSSHKey, git.AuthDeployKey:
+		privateKey := []byte(input.PrivateKey)
+		if len(privateKey) == 0 {
+			if input.Type != git.AuthDeployKey {
+				return nil, fmt.Errorf("private key is required for %s credentials", input.Type)
+			}
+			if privateKey, err = generateDeployKey(repo.Name); err != nil {
+				return nil, err
+			}
+		}
+
+		signer, err := ssh.ParsePrivateKey(privateKey)
+		if err != nil {
+			var passphraseErr *ssh.PassphraseMissingError
+			if errors.As(err, &passphraseErr) {
+				return nil, fmt.Errorf("passphrase-protected keys are not supported")
+			}
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		credential.PublicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+		credential.Fingerprint = ssh.FingerprintSHA256(signer.PublicKey())
+		secret = privateKey
+
+	default:
+		return nil, fmt.Errorf("unsupported credential type: %s", input.Type)
+	}
+
+	encrypted, err := s.encryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO gitops_credentials (id, repository_id, type, username, secret_encrypted, public_key, fingerprint, known_hosts, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(repository_id) DO UPDATE SET
+			type = excluded.type,
+			username = excluded.username,
+			secret_encrypted = excluded.secret_encrypted,
+			public_key = excluded.public_key,
+			fingerprint = excluded.fingerprint,
+			known_hosts = excluded.known_hosts,
+			updated_at = excluded.updated_at`,
+		credential.ID, credential.RepositoryID, credential.Type, credential.Username, encrypted,
+		credential.PublicKey, credential.Fingerprint, credential.KnownHosts, credential.CreatedAt, credential.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store credential: %w", err)
+	}
+
+	if s.isBaseRepository(repo) {
+		if err := s.applyBaseCredentials(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetRepositoryCredential(ctx, repo.ID)
+}
+
+// GetRepositoryCredential returns the credential metadata for a repository
+func (s *Service) GetRepositoryCredential(ctx context.Context, repoID string) (*RepositoryCredential, error) {
+	credential, _, err := s.getCredential(ctx, repoID)
+	return credential, err
+}
+
+// DeleteRepositoryCredential removes a repository's credential so it is accessed anonymously
+func (s *Service) DeleteRepositoryCredential(ctx context.Context, repoID string) error {
+	repo, err := s.GetRepository(ctx, repoID)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM gitops_credentials WHERE repository_id = ?`, repo.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete credential: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrCredentialNotFound
+	}
+
+	if s.isBaseRepository(repo) {
+		s.gitClient.SetCredentials(nil)
+	}
+	return nil
+}
+
+// gitCredentials decrypts a repository's credential for use by the git client; nil means anonymous access
+func (s *Service) gitCredentials(ctx context.Context, repoID string) (*git.Credentials, error) {
+	credential, encrypted, err := s.getCredential(ctx, repoID)
+	if errors.Is(err, ErrCredentialNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := s.decryptSecret(encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := &git.Credentials{
+		Method:     credential.Type,
+		Username:   credential.Username,
+		KnownHosts: []byte(credential.KnownHosts),
+	}
+	if credential.Type == git.AuthHTTPSToken {
+		creds.Token = string(secret)
+	} else {
+		creds.PrivateKey = secret
+	}
+	return creds, nil
+}
+
+func (s *Service) getCredential(ctx context.Context, repoID string) (*RepositoryCredential, string, error) {
+	var credential RepositoryCredential
+	var encrypted string
+	var username, publicKey, fingerprint, knownHosts sql.NullString
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, repository_id, type, username, secret_encrypted, public_key, fingerprint, known_hosts, created_at, updated_at
+		FROM gitops_credentials WHERE repository_id = ?`, repoID).Scan(
+		&credential.ID, &credential.RepositoryID, &credential.Type, &username, &encrypted,
+		&publicKey, &fingerprint, &knownHosts, &credential.CreatedAt, &credential.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, "", ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get credential: %w", err)
+	}
+
+	credential.Username = username.String
+	credential.PublicKey = publicKey.String
+	credential.Fingerprint = fingerprint.String
+	credential.KnownHosts = knownHosts.String
+	return &credential, encrypted, nil
+}
+
+// clientForRepository returns a git client for a registered repository with its credential applied.
+// The base infrastructure repository shares the service's client.
+func (s *Service) clientForRepository(ctx context.Context, repo *Repository) (*git.Client, error) {
+	if s.isBaseRepository(repo) {
+		return s.gitClient, nil
+	}
+
+	client := git.NewClient(repo.URL, repo.Path, repo.Branch)
+	creds, err := s.gitCredentials(ctx, repo.ID)
+	if err != nil {
+		return nil, err
+	}
+	client.SetCredentials(creds)
+	return client, nil
+}
+
+// applyBaseCredentials loads the credential of the registered repository matching the base infrastructure URL
+func (s *Service) applyBaseCredentials(ctx context.Context) error {
+	repos, err := s.ListRepositories(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range repos {
+		if !s.isBaseRepository(&repos[i]) {
+			continue
+		}
+		creds, err := s.gitCredentials(ctx, repos[i].ID)
+		if err != nil {
+			return err
+		}
+		s.gitClient.SetCredentials(creds)
+		return nil
+	}
+	return nil
+}
+
+func (s *Service) isBaseRepository(repo *Repository) bool {
+	return s.baseInfraRepoURL != "" && normalizeRepoURL(repo.URL) == normalizeRepoURL(s.baseInfraRepoURL)
+}
+
+// encryptSecret seals a secret with the encryption box, or without one with AES-256-GCM
+// under the credential key; the nonce is prepended to the ciphertext
+func (s *Service) encryptSecret(plaintext []byte) (string, error) {
+	if s.box != nil {
+		return s.box.Encrypt(string(plaintext))
+	}
+	gcm, err := s.credentialCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *Service) decryptSecret(encoded string) ([]byte, error) {
+	if secretbox.IsEncrypted(encoded) {
+		plaintext, err := s.box.Decrypt(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt credential: %w", err)
+		}
+		return []byte(plaintext), nil
+	}
+
+	gcm, err := s.credentialCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("corrupt credential")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credential: %w", err)
+	}
+	return plaintext, nil
+}
+
+// ResealCredentials moves credentials sealed under the credential key to the encryption
+// box and returns how many it changed. Credentials already in the box are left alone, so
+// it can be run repeatedly.
+func (s *Service) ResealCredentials(ctx context.Context) (int, error) {
+	if s.box == nil {
+		return 0, secretbox.ErrKeyMissing
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, secret_encrypted FROM gitops_credentials`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read credentials: %w", err)
+	}
+	legacy := make(map[string]string)
+	for rows.Next() {
+		var id, encrypted string
+		if err := rows.Scan(&id, &encrypted); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan credential: %w", err)
+		}
+		if !secretbox.IsEncrypted(encrypted) {
+			legacy[id] = encrypted
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, encrypted := range legacy {
+		secret, err := s.decryptSecret(encrypted)
+		if err != nil {
+			return 0, fmt.Errorf("credential %s: %w", id, err)
+		}
+		sealed, err := s.box.Encrypt(string(secret))
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE gitops_credentials SET secret_encrypted = ? WHERE id = ?`, sealed, id); err != nil {
+			return 0, fmt.Errorf("failed to reseal credential %s: %w", id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return len(legacy), nil
+}
+
+func (s *Service) credentialCipher() (cipher.AEAD, error) {
+	if len(s.credentialKey) == 0 {
+		return nil, ErrCredentialKeyMissing
+	}
+	block, err := aes.NewCipher(s.credentialKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// generateDeployKey creates a new ed25519 private key in OpenSSH format
+func generateDeployKey(comment string) ([]byte, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate deploy key: %w", err)
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, "denshimon-"+comment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode deploy key: %w", err)
+	}
+	return pem.EncodeToMemory(block), nil
+}
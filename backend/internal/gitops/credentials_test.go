@@ -0,0 +1,192 @@
+package gitops
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/archellir/denshimon/internal/git"
+	"github.com/archellir/denshimon/internal/secretbox"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupCredentialService(t *testing.T) (*Service, *Repository) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	service := NewService(db, "https://git.example.com/homelab/base_infrastructure.git", t.TempDir())
+	if err := service.SetCredentialKey(context.Background(), "test-key"); err != nil {
+		t.Fatalf("SetCredentialKey() error = %v", err)
+	}
+
+	repo, err := service.CreateRepository(context.Background(), "apps", "git@git.example.com:homelab/apps.git", "main", "", ProviderGitea, "")
+	if err != nil {
+		t.Fatalf("CreateRepository() error = %v", err)
+	}
+	return service, repo
+}
+
+func TestCredentialEncryptionRoundTrip(t *testing.T) {
+	service, _ := setupCredentialService(t)
+
+	encrypted, err := service.encryptSecret([]byte("ghp_token"))
+	if err != nil {
+		t.Fatalf("encryptSecret() error = %v", err)
+	}
+	if strings.Contains(encrypted, "ghp_token") {
+		t.Fatal("secret stored in plaintext")
+	}
+
+	decrypted, err := service.decryptSecret(encrypted)
+	if err != nil || string(decrypted) != "ghp_token" {
+		t.Fatalf("decryptSecret() = %q, %v", decrypted, err)
+	}
+
+	other := &Service{}
+	other.credentialKey = []byte("0123456789abcdef0123456789abcdef")
+	if _, err := other.decryptSecret(encrypted); err == nil {
+		t.Error("decrypting with a different key should fail")
+	}
+}
+
+func TestCredentialsSealedWithBox(t *testing.T) {
+	service, repo := setupCredentialService(t)
+	ctx := context.Background()
+
+	// Stored under the credential key before a box was configured
+	if _, err := service.SetRepositoryCredential(ctx, repo.ID, CredentialInput{Type: git.AuthHTTPSToken, Username: "bot", Token: "ghp_token"}); err != nil {
+		t.Fatalf("SetRepositoryCredential() error = %v", err)
+	}
+	box, err := secretbox.New("master-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.SetEncryption(box)
+
+	resealed, err := service.ResealCredentials(ctx)
+	if err != nil || resealed != 1 {
+		t.Fatalf("ResealCredentials() = %d, %v", resealed, err)
+	}
+	var stored string
+	if err := service.db.QueryRowContext(ctx, `SELECT secret_encrypted FROM gitops_credentials WHERE repository_id = ?`, repo.ID).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !secretbox.IsEncrypted(stored) {
+		t.Errorf("credential not sealed with the box: %q", stored)
+	}
+	if resealed, err = service.ResealCredentials(ctx); err != nil || resealed != 0 {
+		t.Errorf("second ResealCredentials() = %d, %v, want nothing to do", resealed, err)
+	}
+
+	creds, err := service.gitCredentials(ctx, repo.ID)
+	if err != nil || creds.Token != "ghp_token" {
+		t.Fatalf("gitCredentials() = %+v, %v", creds, err)
+	}
+
+	// New credentials go straight into the box
+	encrypted, err := service.encryptSecret([]byte("another"))
+	if err != nil || !secretbox.IsEncrypted(encrypted) {
+		t.Errorf("encryptSecret() = %q, %v", encrypted, err)
+	}
+}
+
+func TestWebhookSecretSealedWithBox(t *testing.T) {
+	service, existing := setupCredentialService(t)
+	ctx := context.Background()
+	box, err := secretbox.New("master-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.SetEncryption(box)
+
+	repo, err := service.CreateRepository(ctx, "hooks", "https://git.example.com/homelab/hooks.git", "main", "", ProviderGitea, "hook-secret")
+	if err != nil {
+		t.Fatalf("CreateRepository() error = %v", err)
+	}
+	var stored string
+	if err := service.db.QueryRowContext(ctx, `SELECT webhook_secret FROM gitops_repositories WHERE id = ?`, repo.ID).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !secretbox.IsEncrypted(stored) {
+		t.Errorf("webhook secret not sealed with the box: %q", stored)
+	}
+
+	got, err := service.GetRepository(ctx, repo.ID)
+	if err != nil || got.WebhookSecret != "hook-secret" || !got.HasWebhookSecret {
+		t.Fatalf("GetRepository() = %+v, %v", got, err)
+	}
+
+	// Repositories without a secret keep the column empty
+	plain, err := service.GetRepository(ctx, existing.ID)
+	if err != nil || plain.HasWebhookSecret {
+		t.Errorf("GetRepository() = %+v, %v, want no webhook secret", plain, err)
+	}
+}
+
+func TestSetRepositoryCredentialGeneratesDeployKey(t *testing.T) {
+	service, repo := setupCredentialService(t)
+	ctx := context.Background()
+
+	credential, err := service.SetRepositoryCredential(ctx, repo.ID, CredentialInput{Type: git.AuthDeployKey})
+	if err != nil {
+		t.Fatalf("SetRepositoryCredential() error = %v", err)
+	}
+	if !strings.HasPrefix(credential.PublicKey, "ssh-ed25519 ") || !strings.HasPrefix(credential.Fingerprint, "SHA256:") {
+		t.Errorf("unexpected public key %q fingerprint %q", credential.PublicKey, credential.Fingerprint)
+	}
+
+	creds, err := service.gitCredentials(ctx, repo.ID)
+	if err != nil {
+		t.Fatalf("gitCredentials() error = %v", err)
+	}
+	if creds.Method != git.AuthDeployKey || !strings.Contains(string(creds.PrivateKey), "OPENSSH PRIVATE KEY") {
+		t.Errorf("unexpected decrypted credentials: method %s", creds.Method)
+	}
+
+	// Replacing the credential keeps a single row per repository
+	if _, err := service.SetRepositoryCredential(ctx, repo.ID, CredentialInput{Type: git.AuthHTTPSToken, Username: "bot", Token: "secret"}); err != nil {
+		t.Fatalf("SetRepositoryCredential() error = %v", err)
+	}
+	creds, err = service.gitCredentials(ctx, repo.ID)
+	if err != nil || creds.Token != "secret" || creds.Username != "bot" {
+		t.Fatalf("gitCredentials() = %+v, %v", creds, err)
+	}
+
+	if err := service.DeleteRepositoryCredential(ctx, repo.ID); err != nil {
+		t.Fatalf("DeleteRepositoryCredential() error = %v", err)
+	}
+	if _, err := service.GetRepositoryCredential(ctx, repo.ID); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("GetRepositoryCredential() error = %v, want ErrCredentialNotFound", err)
+	}
+}
+
+func TestSetRepositoryCredentialValidation(t *testing.T) {
+	service, repo := setupCredentialService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		input CredentialInput
+	}{
+		{"token missing", CredentialInput{Type: git.AuthHTTPSToken}},
+		{"ssh key missing", CredentialInput{Type: git.AuthSSHKey}},
+		{"invalid key", CredentialInput{Type: git.AuthSSHKey, PrivateKey: "not a key"}},
+		{"unknown type", CredentialInput{Type: "password"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.SetRepositoryCredential(ctx, repo.ID, tt.input); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+
+	if _, err := service.SetRepositoryCredential(ctx, "missing", CredentialInput{Type: git.AuthHTTPSToken, Token: "x"}); !errors.Is(err, ErrRepositoryNotFound) {
+		t.Errorf("error = %v, want ErrRepositoryNotFound", err)
+	}
+}
@@ -0,0 +1,334 @@
+package gitops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// ErrInvalidPromotion is returned when a promotion does not move forward through the environments
+var ErrInvalidPromotion = errors.New("invalid promotion")
+
+const deploymentPatchFile = "deployment-patch.yaml"
+
+// AppEnvironment is the declared state of an application in one environment, as rendered from its overlay
+type AppEnvironment struct {
+	Environment string            `json:"environment"`
+	Namespace   string            `json:"namespace"`
+	Name        string            `json:"name"`
+	Image       string            `json:"image"`
+	Replicas    int64             `json:"replicas"`
+	Env         map[string]string `json:"env"`
+}
+
+// Promotion records an image promoted from one environment to the next
+type Promotion struct {
+	ApplicationID string    `json:"application_id"`
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	Image         string    `json:"image"`
+	PreviousImage string    `json:"previous_image"`
+	GitHash       string    `json:"git_hash"`
+	PromotedBy    string    `json:"promoted_by"`
+	PromotedAt    time.Time `json:"promoted_at"`
+}
+
+// NextEnvironment returns the environment after env in promotion order, or "" for the last one
+func NextEnvironment(env string) string {
+	for i, name := range DefaultEnvironments {
+		if name == env && i+1 < len(DefaultEnvironments) {
+			return DefaultEnvironments[i+1]
+		}
+	}
+	return ""
+}
+
+func environmentIndex(env string) int {
+	for i, name := range DefaultEnvironments {
+		if name == env {
+			return i
+		}
+	}
+	return -1
+}
+
+// ListAppEnvironments returns the declared state of an application in each environment
+func (s *Service) ListAppEnvironments(ctx context.Context, appID string) ([]AppEnvironment, error) {
+	environments := make([]AppEnvironment, 0, len(DefaultEnvironments))
+	for _, env := range DefaultEnvironments {
+		state, err := s.GetAppEnvironment(ctx, appID, env)
+		if err != nil {
+			return nil, err
+		}
+		environments = append(environments, *state)
+	}
+	return environments, nil
+}
+
+// GetAppEnvironment renders an application's overlay and extracts its deployment state
+func (s *Service) GetAppEnvironment(ctx context.Context, appID, env string) (*AppEnvironment, error) {
+	app, err := s.getApplication(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := s.RenderApplication(ctx, appID, env)
+	if err != nil {
+		return nil, err
+	}
+	return environmentState(app, env, objects)
+}
+
+// SetAppEnvironment writes an environment's image, replica, env and namespace overrides to its overlay and commits them
+func (s *Service) SetAppEnvironment(ctx context.Context, appID string, overlay Overlay, user string) (*AppEnvironment, error) {
+	app, err := s.getApplication(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	if environmentIndex(overlay.Environment) < 0 {
+		return nil, fmt.Errorf("unknown environment: %q", overlay.Environment)
+	}
+
+	written, err := s.writeOverlay(app, overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	commitMsg := fmt.Sprintf("feat(%s): update %s %s overrides\n\nUpdated by %s [denshimon]", app.Namespace, app.Name, overlay.Environment, user)
+	if err := s.gitClient.CommitAndPush(commitMsg, written...); err != nil {
+		return nil, fmt.Errorf("failed to commit changes: %w", err)
+	}
+
+	return s.GetAppEnvironment(ctx, appID, overlay.Environment)
+}
+
+// PromoteApplication copies an image from one environment's overlay to the next and commits the change.
+// image is the version currently running in the source environment; when empty the declared image is used.
+func (s *Service) PromoteApplication(ctx context.Context, appID, from, to, image, user string) (*Promotion, error) {
+	app, err := s.getApplication(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	if to == "" {
+		to = NextEnvironment(from)
+	}
+	fromIndex, toIndex := environmentIndex(from), environmentIndex(to)
+	if fromIndex < 0 || toIndex < 0 {
+		return nil, fmt.Errorf("%w: unknown environment %q or %q", ErrInvalidPromotion, from, to)
+	}
+	if toIndex <= fromIndex {
+		return nil, fmt.Errorf("%w: %s does not come after %s", ErrInvalidPromotion, to, from)
+	}
+
+	if image == "" {
+		source, err := s.GetAppEnvironment(ctx, appID, from)
+		if err != nil {
+			return nil, err
+		}
+		image = source.Image
+	}
+
+	target, err := s.GetAppEnvironment(ctx, appID, to)
+	if err != nil {
+		return nil, err
+	}
+	if target.Image == image {
+		return nil, fmt.Errorf("%w: %s already runs %s", ErrInvalidPromotion, to, image)
+	}
+
+	written, err := s.writeOverlay(app, Overlay{Environment: to, Image: image})
+	if err != nil {
+		return nil, err
+	}
+
+	commitMsg := fmt.Sprintf("feat(%s): promote %s from %s to %s\n\nImage %s (was %s), promoted by %s [denshimon]",
+		app.Namespace, app.Name, from, to, image, target.Image, user)
+	if err := s.gitClient.CommitAndPush(commitMsg, written...); err != nil {
+		return nil, fmt.Errorf("failed to commit changes: %w", err)
+	}
+
+	var gitHash string
+	if commits, err := s.gitClient.Log(1); err == nil && len(commits) > 0 {
+		gitHash = commits[0].Hash
+	}
+
+	promotion := &Promotion{
+		ApplicationID: appID,
+		From:          from,
+		To:            to,
+		Image:         image,
+		PreviousImage: target.Image,
+		GitHash:       gitHash,
+		PromotedBy:    user,
+		PromotedAt:    time.Now(),
+	}
+
+	envJSON, _ := json.Marshal(target.Env)
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO gitops_deployments (id, application_id, image, replicas, environment, git_hash, status, message, deployed_by, deployed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		uuid.New().String(), appID, image, target.Replicas, string(envJSON), gitHash, "promoted",
+		fmt.Sprintf("Promoted from %s to %s", from, to), user, promotion.PromotedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record promotion: %w", err)
+	}
+
+	return promotion, nil
+}
+
+// writeOverlay merges overrides into an environment's deployment patch and kustomization,
+// generating the application's Kustomize layout first if the repository does not have one yet.
+// Fields left empty in the overlay keep their current value.
+func (s *Service) writeOverlay(app *Application, overlay Overlay) ([]string, error) {
+	appDir := filepath.Join(DefaultSyncConfig().ManifestPath, app.Namespace, app.Name)
+	var written []string
+
+	if _, err := s.gitClient.ReadFile(filepath.Join(appDir, "base", kustomizationFile)); err != nil {
+		files, err := s.GenerateKustomization(app, nil, map[string]interface{}{})
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range sortedKeys(files) {
+			filePath := filepath.Join(appDir, filepath.FromSlash(name))
+			if err := s.gitClient.WriteFile(filePath, []byte(files[name])); err != nil {
+				return nil, fmt.Errorf("failed to write manifest: %w", err)
+			}
+			written = append(written, filePath)
+		}
+	}
+
+	overlayDir := filepath.Join(appDir, "overlays", overlay.Environment)
+	kustomizationPath := filepath.Join(overlayDir, kustomizationFile)
+	k := kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  []string{"../../base"},
+	}
+	if data, err := s.gitClient.ReadFile(kustomizationPath); err == nil {
+		if err := yaml.UnmarshalStrict(data, &k); err != nil {
+			return nil, fmt.Errorf("unsupported kustomization in %s: %w", kustomizationPath, err)
+		}
+	}
+	if overlay.Namespace != "" {
+		k.Namespace = overlay.Namespace
+	}
+
+	// Start from the existing patch so earlier overrides survive
+	patchPath := filepath.Join(overlayDir, deploymentPatchFile)
+	current := map[string]interface{}{}
+	if data, err := s.gitClient.ReadFile(patchPath); err == nil {
+		if err := yaml.Unmarshal(data, &current); err != nil {
+			return nil, fmt.Errorf("invalid deployment patch %s: %w", patchPath, err)
+		}
+	}
+
+	if patch := deploymentPatch(app, overlay); patch != nil {
+		merged, err := strategicMerge(&unstructured.Unstructured{Object: patchBase(app, current)}, &unstructured.Unstructured{Object: patch})
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge deployment patch: %w", err)
+		}
+		data, err := yaml.Marshal(merged.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode deployment patch: %w", err)
+		}
+		if err := s.gitClient.WriteFile(patchPath, data); err != nil {
+			return nil, fmt.Errorf("failed to write deployment patch: %w", err)
+		}
+		written = append(written, patchPath)
+
+		if !hasPatch(k, deploymentPatchFile) {
+			k.Patches = append(k.Patches, kustomizePatch{Path: deploymentPatchFile})
+		}
+	}
+
+	data, err := yaml.Marshal(k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode kustomization: %w", err)
+	}
+	if err := s.gitClient.WriteFile(kustomizationPath, data); err != nil {
+		return nil, fmt.Errorf("failed to write kustomization: %w", err)
+	}
+	written = append(written, kustomizationPath)
+
+	return written, nil
+}
+
+// patchBase returns the existing deployment patch, or an empty one identifying the application's Deployment
+func patchBase(app *Application, current map[string]interface{}) map[string]interface{} {
+	if len(current) > 0 {
+		return current
+	}
+	return map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": app.Name},
+	}
+}
+
+func hasPatch(k kustomization, name string) bool {
+	for _, patch := range k.Patches {
+		if path.Clean(patch.Path) == name {
+			return true
+		}
+	}
+	for _, entry := range k.PatchesStrategicMerge {
+		if path.Clean(entry) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// environmentState extracts the application's Deployment settings from rendered objects
+func environmentState(app *Application, env string, objects []*unstructured.Unstructured) (*AppEnvironment, error) {
+	var deployment *unstructured.Unstructured
+	for _, obj := range objects {
+		if obj.GetKind() != "Deployment" {
+			continue
+		}
+		if deployment == nil || obj.GetName() == app.Name {
+			deployment = obj
+		}
+	}
+	if deployment == nil {
+		return nil, fmt.Errorf("no deployment found in %s overlay for %s", env, app.Name)
+	}
+
+	state := &AppEnvironment{
+		Environment: env,
+		Namespace:   deployment.GetNamespace(),
+		Name:        deployment.GetName(),
+		Env:         map[string]string{},
+	}
+	state.Replicas, _, _ = unstructured.NestedInt64(deployment.Object, "spec", "replicas")
+
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	var container map[string]interface{}
+	for _, c := range containers {
+		if candidate, ok := c.(map[string]interface{}); ok && (container == nil || candidate["name"] == app.Name) {
+			container = candidate
+		}
+	}
+	if container == nil {
+		return state, nil
+	}
+
+	state.Image, _ = container["image"].(string)
+	envVars, _ := container["env"].([]interface{})
+	for _, e := range envVars {
+		if envVar, ok := e.(map[string]interface{}); ok {
+			name, _ := envVar["name"].(string)
+			value, _ := envVar["value"].(string)
+			state.Env[name] = value
+		}
+	}
+	return state, nil
+}
@@ -0,0 +1,68 @@
+package gitops
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestNextEnvironment(t *testing.T) {
+	tests := map[string]string{
+		"dev":     "staging",
+		"staging": "prod",
+		"prod":    "",
+		"qa":      "",
+	}
+	for env, want := range tests {
+		if got := NextEnvironment(env); got != want {
+			t.Errorf("NextEnvironment(%q) = %q, want %q", env, got, want)
+		}
+	}
+}
+
+func TestEnvironmentState(t *testing.T) {
+	app := &Application{Name: "web", Namespace: "apps"}
+	deployment := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "apps-staging"},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "sidecar", "image": "proxy:1"},
+						map[string]interface{}{
+							"name":  "web",
+							"image": "web:1.2.0",
+							"env":   []interface{}{map[string]interface{}{"name": "LOG_LEVEL", "value": "debug"}},
+						},
+					},
+				},
+			},
+		},
+	}}
+	service := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata":   map[string]interface{}{"name": "web"},
+	}}
+
+	state, err := environmentState(app, "staging", []*unstructured.Unstructured{service, deployment})
+	if err != nil {
+		t.Fatalf("environmentState: %v", err)
+	}
+	if state.Namespace != "apps-staging" || state.Replicas != 3 {
+		t.Errorf("unexpected state: %+v", state)
+	}
+	if state.Image != "web:1.2.0" {
+		t.Errorf("expected the application container image, got %q", state.Image)
+	}
+	if state.Env["LOG_LEVEL"] != "debug" {
+		t.Errorf("expected env to be extracted, got %v", state.Env)
+	}
+
+	if _, err := environmentState(app, "staging", []*unstructured.Unstructured{service}); err == nil {
+		t.Error("expected an error when the overlay has no deployment")
+	}
+}
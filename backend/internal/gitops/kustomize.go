@@ -0,0 +1,553 @@
+package gitops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultEnvironments are the overlays generated when none are requested, in promotion order
+var DefaultEnvironments = []string{"dev", "staging", "prod"}
+
+const kustomizationFile = "kustomization.yaml"
+
+// maxKustomizeDepth guards against resource cycles between kustomization directories
+const maxKustomizeDepth = 10
+
+var environmentNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// clusterScopedKinds are left without a namespace when an overlay sets one
+var clusterScopedKinds = map[string]bool{
+	"Namespace":                      true,
+	"Node":                           true,
+	"PersistentVolume":               true,
+	"StorageClass":                   true,
+	"IngressClass":                   true,
+	"PriorityClass":                  true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"CustomResourceDefinition":       true,
+	"APIService":                     true,
+	"ValidatingWebhookConfiguration": true,
+	"MutatingWebhookConfiguration":   true,
+}
+
+// Overlay holds the per-environment overrides layered on top of the base
+type Overlay struct {
+	Environment string            `json:"environment"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Image       string            `json:"image,omitempty"`
+	Replicas    *int              `json:"replicas,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	Patches     []string          `json:"patches,omitempty"` // additional strategic merge patches as YAML
+}
+
+// kustomization is the subset of the Kustomization file format that RenderKustomization understands
+type kustomization struct {
+	APIVersion            string             `json:"apiVersion,omitempty"`
+	Kind                  string             `json:"kind,omitempty"`
+	Resources             []string           `json:"resources,omitempty"`
+	Namespace             string             `json:"namespace,omitempty"`
+	Images                []kustomizeImage   `json:"images,omitempty"`
+	Replicas              []kustomizeReplica `json:"replicas,omitempty"`
+	Patches               []kustomizePatch   `json:"patches,omitempty"`
+	PatchesStrategicMerge []string           `json:"patchesStrategicMerge,omitempty"`
+}
+
+type kustomizeImage struct {
+	Name    string `json:"name"`
+	NewName string `json:"newName,omitempty"`
+	NewTag  string `json:"newTag,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+}
+
+type kustomizeReplica struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type kustomizePatch struct {
+	Path  string `json:"path,omitempty"`
+	Patch string `json:"patch,omitempty"`
+}
+
+// ReadFileFunc reads a slash-separated path relative to the kustomize root
+type ReadFileFunc func(name string) ([]byte, error)
+
+// GenerateKustomization lays out an application as a Kustomize base plus one overlay per environment.
+// The returned map is keyed by path relative to the application directory.
+func (s *Service) GenerateKustomization(app *Application, overlays []Overlay, options map[string]interface{}) (map[string]string, error) {
+	manifest, err := s.GenerateFullManifest(app, options)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := k8s.DecodeManifests([]byte(manifest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode generated manifest: %w", err)
+	}
+
+	files := make(map[string]string)
+	base := kustomization{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization"}
+	for _, obj := range objects {
+		name := strings.ToLower(obj.GetKind()) + ".yaml"
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", obj.GetKind(), err)
+		}
+		files["base/"+name] = string(data)
+		base.Resources = append(base.Resources, name)
+	}
+	if err := writeKustomization(files, "base", base); err != nil {
+		return nil, err
+	}
+
+	if len(overlays) == 0 {
+		for _, env := range DefaultEnvironments {
+			overlays = append(overlays, Overlay{Environment: env})
+		}
+	}
+
+	for _, overlay := range overlays {
+		if !environmentNamePattern.MatchString(overlay.Environment) {
+			return nil, fmt.Errorf("invalid environment name: %q", overlay.Environment)
+		}
+		dir := "overlays/" + overlay.Environment
+		k := kustomization{
+			APIVersion: "kustomize.config.k8s.io/v1beta1",
+			Kind:       "Kustomization",
+			Resources:  []string{"../../base"},
+			Namespace:  overlay.Namespace,
+		}
+
+		if patch := deploymentPatch(app, overlay); patch != nil {
+			data, err := yaml.Marshal(patch)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode deployment patch: %w", err)
+			}
+			files[dir+"/deployment-patch.yaml"] = string(data)
+			k.Patches = append(k.Patches, kustomizePatch{Path: "deployment-patch.yaml"})
+		}
+
+		for i, patch := range overlay.Patches {
+			name := fmt.Sprintf("patch-%d.yaml", i+1)
+			files[dir+"/"+name] = patch
+			k.Patches = append(k.Patches, kustomizePatch{Path: name})
+		}
+
+		if err := writeKustomization(files, dir, k); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// deploymentPatch builds the strategic merge patch carrying an overlay's image, replica and env overrides
+func deploymentPatch(app *Application, overlay Overlay) map[string]interface{} {
+	if overlay.Image == "" && overlay.Replicas == nil && len(overlay.Env) == 0 {
+		return nil
+	}
+
+	spec := map[string]interface{}{}
+	if overlay.Replicas != nil {
+		spec["replicas"] = *overlay.Replicas
+	}
+
+	if overlay.Image != "" || len(overlay.Env) > 0 {
+		container := map[string]interface{}{"name": app.Name}
+		if overlay.Image != "" {
+			container["image"] = overlay.Image
+		}
+		if len(overlay.Env) > 0 {
+			keys := make([]string, 0, len(overlay.Env))
+			for key := range overlay.Env {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			env := make([]interface{}, 0, len(keys))
+			for _, key := range keys {
+				env = append(env, map[string]interface{}{"name": key, "value": overlay.Env[key]})
+			}
+			container["env"] = env
+		}
+		spec["template"] = map[string]interface{}{
+			"spec": map[string]interface{}{
+				"containers": []interface{}{container},
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": app.Name},
+		"spec":       spec,
+	}
+}
+
+func writeKustomization(files map[string]string, dir string, k kustomization) error {
+	data, err := yaml.Marshal(k)
+	if err != nil {
+		return fmt.Errorf("failed to encode kustomization: %w", err)
+	}
+	files[dir+"/"+kustomizationFile] = string(data)
+	return nil
+}
+
+// RenderKustomization builds the kustomization in dir into its final objects, like `kustomize build`.
+// It supports resources, namespace, strategic merge patches, images and replicas; other fields are rejected.
+func RenderKustomization(read ReadFileFunc, dir string) ([]*unstructured.Unstructured, error) {
+	return renderKustomization(read, path.Clean(dir), 0)
+}
+
+// RenderKustomizationFiles renders dir from an in-memory set of files such as GenerateKustomization returns
+func RenderKustomizationFiles(files map[string]string, dir string) ([]*unstructured.Unstructured, error) {
+	return RenderKustomization(func(name string) ([]byte, error) {
+		content, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("file not found: %s", name)
+		}
+		return []byte(content), nil
+	}, dir)
+}
+
+func renderKustomization(read ReadFileFunc, dir string, depth int) ([]*unstructured.Unstructured, error) {
+	if depth > maxKustomizeDepth {
+		return nil, fmt.Errorf("kustomization nesting too deep at %s", dir)
+	}
+
+	data, err := read(path.Join(dir, kustomizationFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path.Join(dir, kustomizationFile), err)
+	}
+	var k kustomization
+	if err := yaml.UnmarshalStrict(data, &k); err != nil {
+		return nil, fmt.Errorf("unsupported kustomization in %s: %w", dir, err)
+	}
+
+	var objects []*unstructured.Unstructured
+	for _, resource := range k.Resources {
+		resourcePath, err := resolvePath(dir, resource)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := read(path.Join(resourcePath, kustomizationFile)); err == nil {
+			nested, err := renderKustomization(read, resourcePath, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, nested...)
+			continue
+		}
+
+		content, err := read(resourcePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read resource %s: %w", resourcePath, err)
+		}
+		decoded, err := k8s.DecodeManifests(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", resourcePath, err)
+		}
+		objects = append(objects, decoded...)
+	}
+
+	if k.Namespace != "" {
+		for _, obj := range objects {
+			if !clusterScopedKinds[obj.GetKind()] {
+				obj.SetNamespace(k.Namespace)
+			}
+		}
+	}
+
+	// patchesStrategicMerge entries may be file paths or inline YAML; an inline patch always spans lines
+	patches := k.Patches
+	for _, entry := range k.PatchesStrategicMerge {
+		if strings.Contains(entry, "\n") {
+			patches = append(patches, kustomizePatch{Patch: entry})
+		} else {
+			patches = append(patches, kustomizePatch{Path: entry})
+		}
+	}
+	for i, patch := range patches {
+		content := []byte(patch.Patch)
+		if patch.Path != "" {
+			patchPath, err := resolvePath(dir, patch.Path)
+			if err != nil {
+				return nil, err
+			}
+			if content, err = read(patchPath); err != nil {
+				return nil, fmt.Errorf("failed to read patch %s: %w", patchPath, err)
+			}
+		}
+		if err := applyPatches(objects, content); err != nil {
+			return nil, fmt.Errorf("patch %d in %s: %w", i+1, dir, err)
+		}
+	}
+
+	for _, image := range k.Images {
+		for _, obj := range objects {
+			if err := setImages(obj, image); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, replica := range k.Replicas {
+		for _, obj := range objects {
+			switch obj.GetKind() {
+			case "Deployment", "StatefulSet", "ReplicaSet":
+				if obj.GetName() == replica.Name {
+					if err := unstructured.SetNestedField(obj.Object, replica.Count, "spec", "replicas"); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+
+	return objects, nil
+}
+
+// resolvePath joins a kustomization-relative reference, refusing paths that escape the root
+func resolvePath(dir, ref string) (string, error) {
+	if path.IsAbs(ref) || strings.Contains(ref, "://") {
+		return "", fmt.Errorf("unsupported resource reference %q: only relative paths are allowed", ref)
+	}
+	resolved := path.Join(dir, ref)
+	if resolved == ".." || strings.HasPrefix(resolved, "../") {
+		return "", fmt.Errorf("resource reference %q escapes the repository", ref)
+	}
+	return resolved, nil
+}
+
+// applyPatches applies every document in a strategic merge patch file to the object it names
+func applyPatches(objects []*unstructured.Unstructured, content []byte) error {
+	patches, err := k8s.DecodeManifests(content)
+	if err != nil {
+		return err
+	}
+
+	for _, patch := range patches {
+		matched := false
+		for i, obj := range objects {
+			if !patchTargets(patch, obj) {
+				continue
+			}
+			patched, err := strategicMerge(obj, patch)
+			if err != nil {
+				return fmt.Errorf("failed to patch %s/%s: %w", obj.GetKind(), obj.GetName(), err)
+			}
+			objects[i].Object = patched.Object
+			matched = true
+		}
+		if !matched {
+			return fmt.Errorf("no resource matches patch for %s/%s", patch.GetKind(), patch.GetName())
+		}
+	}
+	return nil
+}
+
+func patchTargets(patch, obj *unstructured.Unstructured) bool {
+	if patch.GetKind() != obj.GetKind() || patch.GetName() != obj.GetName() {
+		return false
+	}
+	if patch.GroupVersionKind().Group != obj.GroupVersionKind().Group {
+		return false
+	}
+	return patch.GetNamespace() == "" || patch.GetNamespace() == obj.GetNamespace()
+}
+
+// strategicMerge applies a strategic merge patch using the built-in type's merge keys,
+// falling back to a JSON merge patch for kinds without a registered Go type (CRDs)
+func strategicMerge(obj, patch *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	original, err := json.Marshal(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	patchData := patch.DeepCopy()
+	patchData.SetNamespace(obj.GetNamespace())
+	patchJSON, err := json.Marshal(patchData.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	var merged []byte
+	if typed, err := scheme.Scheme.New(obj.GroupVersionKind()); err == nil {
+		merged, err = strategicpatch.StrategicMergePatch(original, patchJSON, typed)
+		if err != nil {
+			return nil, err
+		}
+	} else if merged, err = jsonpatch.MergePatch(original, patchJSON); err != nil {
+		return nil, err
+	}
+
+	// utiljson keeps integers as int64 like the YAML decoder does
+	result := &unstructured.Unstructured{}
+	if err := utiljson.Unmarshal(merged, &result.Object); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// setImages rewrites container images matching a kustomize image override
+func setImages(obj *unstructured.Unstructured, image kustomizeImage) error {
+	specPath := []string{"spec", "template", "spec"}
+	switch obj.GetKind() {
+	case "Pod":
+		specPath = []string{"spec"}
+	case "CronJob":
+		specPath = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	}
+
+	for _, field := range []string{"containers", "initContainers"} {
+		fieldPath := append(append([]string{}, specPath...), field)
+		containers, found, err := unstructured.NestedSlice(obj.Object, fieldPath...)
+		if err != nil || !found {
+			continue
+		}
+
+		changed := false
+		for i, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			current, _ := container["image"].(string)
+			if imageName(current) != image.Name {
+				continue
+			}
+			container["image"] = overrideImage(current, image)
+			containers[i] = container
+			changed = true
+		}
+
+		if changed {
+			if err := unstructured.SetNestedSlice(obj.Object, containers, fieldPath...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// imageName strips the tag or digest from an image reference
+func imageName(image string) string {
+	if at := strings.Index(image, "@"); at >= 0 {
+		image = image[:at]
+	}
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		image = image[:colon]
+	}
+	return image
+}
+
+func overrideImage(current string, image kustomizeImage) string {
+	name := imageName(current)
+	suffix := strings.TrimPrefix(current, name)
+	if image.NewName != "" {
+		name = image.NewName
+	}
+	switch {
+	case image.Digest != "":
+		return name + "@" + image.Digest
+	case image.NewTag != "":
+		return name + ":" + image.NewTag
+	default:
+		return name + suffix
+	}
+}
+
+// RenderManifest encodes rendered objects as a multi-document YAML stream
+func RenderManifest(objects []*unstructured.Unstructured) (string, error) {
+	docs := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode %s/%s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		docs = append(docs, string(data))
+	}
+	return strings.Join(docs, "---\n"), nil
+}
+
+// RenderApplication renders an application's overlay for an environment, or its base when environment is empty.
+// The layout committed to the local repository is used when present; otherwise one is generated.
+func (s *Service) RenderApplication(ctx context.Context, appID, environment string) ([]*unstructured.Unstructured, error) {
+	app, err := s.getApplication(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := "base"
+	if environment != "" {
+		if !environmentNamePattern.MatchString(environment) {
+			return nil, fmt.Errorf("invalid environment name: %q", environment)
+		}
+		dir = "overlays/" + environment
+	}
+
+	root := filepath.Join(s.localRepoPath, DefaultSyncConfig().ManifestPath, app.Namespace, app.Name)
+	readRepo := func(name string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+	}
+	if _, err := readRepo(path.Join(dir, kustomizationFile)); err == nil {
+		return RenderKustomization(readRepo, dir)
+	}
+
+	files, err := s.GenerateKustomization(app, nil, map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := files[path.Join(dir, kustomizationFile)]; !ok {
+		return nil, fmt.Errorf("environment %q not found for application %s", environment, app.Name)
+	}
+	return RenderKustomizationFiles(files, dir)
+}
+
+// StoredManifests reads the YAML files committed under the manifest path of the local
+// repository, keyed by their path in the repository. A repository not cloned yet has none.
+func (s *Service) StoredManifests(ctx context.Context) (map[string][]byte, error) {
+	manifests := make(map[string][]byte)
+	root := filepath.Join(s.localRepoPath, DefaultSyncConfig().ManifestPath)
+	err := filepath.WalkDir(root, func(name string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && name == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() || (filepath.Ext(name) != ".yaml" && filepath.Ext(name) != ".yml") {
+			return nil
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		relative, _ := filepath.Rel(s.localRepoPath, name)
+		manifests[filepath.ToSlash(relative)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored manifests: %w", err)
+	}
+	return manifests, nil
+}
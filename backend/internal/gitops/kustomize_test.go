@@ -0,0 +1,220 @@
+package gitops
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func findObject(objects []*unstructured.Unstructured, kind string) *unstructured.Unstructured {
+	for _, obj := range objects {
+		if obj.GetKind() == kind {
+			return obj
+		}
+	}
+	return nil
+}
+
+func TestGenerateAndRenderKustomizationOverlay(t *testing.T) {
+	service := &Service{}
+	app := &Application{
+		Name:        "api",
+		Namespace:   "apps",
+		Image:       "registry.lan/api:1.0.0",
+		Replicas:    1,
+		Environment: map[string]string{"LOG_LEVEL": "info"},
+	}
+	replicas := 3
+	overlays := []Overlay{
+		{Environment: "dev"},
+		{Environment: "prod", Namespace: "apps-prod", Image: "registry.lan/api:1.1.0", Replicas: &replicas, Env: map[string]string{"LOG_LEVEL": "warn"}},
+	}
+
+	files, err := service.GenerateKustomization(app, overlays, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("GenerateKustomization() error = %v", err)
+	}
+	for _, name := range []string{"base/kustomization.yaml", "base/deployment.yaml", "overlays/dev/kustomization.yaml", "overlays/prod/deployment-patch.yaml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing generated file %s", name)
+		}
+	}
+
+	objects, err := RenderKustomizationFiles(files, "overlays/prod")
+	if err != nil {
+		t.Fatalf("RenderKustomizationFiles() error = %v", err)
+	}
+
+	deployment := findObject(objects, "Deployment")
+	if deployment == nil {
+		t.Fatal("rendered output has no Deployment")
+	}
+	if deployment.GetNamespace() != "apps-prod" {
+		t.Errorf("namespace = %s, want apps-prod", deployment.GetNamespace())
+	}
+	if got, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas"); got != 3 {
+		t.Errorf("replicas = %d, want 3", got)
+	}
+
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	if len(containers) != 1 {
+		t.Fatalf("containers = %d, want the patch merged into the existing container", len(containers))
+	}
+	container := containers[0].(map[string]interface{})
+	if container["image"] != "registry.lan/api:1.1.0" {
+		t.Errorf("image = %v, want registry.lan/api:1.1.0", container["image"])
+	}
+	// Strategic merge keeps the base container's other fields
+	if _, ok := container["livenessProbe"]; !ok {
+		t.Error("strategic merge dropped livenessProbe from the base container")
+	}
+	env := container["env"].([]interface{})
+	if len(env) != 1 || env[0].(map[string]interface{})["value"] != "warn" {
+		t.Errorf("env = %v, want LOG_LEVEL=warn merged by name", env)
+	}
+
+	dev, err := RenderKustomizationFiles(files, "overlays/dev")
+	if err != nil {
+		t.Fatalf("RenderKustomizationFiles(dev) error = %v", err)
+	}
+	if got := findObject(dev, "Deployment").GetNamespace(); got != "apps" {
+		t.Errorf("dev namespace = %s, want base namespace apps", got)
+	}
+}
+
+func TestRenderKustomizationTransformers(t *testing.T) {
+	files := map[string]string{
+		"base/kustomization.yaml": "resources:\n- app.yaml\n- crd.yaml\n",
+		"base/app.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.25
+      initContainers:
+      - name: init
+        image: busybox
+`,
+		"base/crd.yaml": `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: web
+spec:
+  size: small
+  color: blue
+`,
+		"overlays/prod/kustomization.yaml": `resources:
+- ../../base
+images:
+- name: nginx
+  newTag: "1.27"
+- name: busybox
+  newName: registry.lan/busybox
+replicas:
+- name: web
+  count: 4
+patchesStrategicMerge:
+- |
+  apiVersion: example.com/v1
+  kind: Widget
+  metadata:
+    name: web
+  spec:
+    size: large
+`,
+	}
+
+	objects, err := RenderKustomizationFiles(files, "overlays/prod")
+	if err != nil {
+		t.Fatalf("RenderKustomizationFiles() error = %v", err)
+	}
+
+	deployment := findObject(objects, "Deployment")
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	if image := containers[0].(map[string]interface{})["image"]; image != "nginx:1.27" {
+		t.Errorf("image = %v, want nginx:1.27", image)
+	}
+	initContainers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "initContainers")
+	if image := initContainers[0].(map[string]interface{})["image"]; image != "registry.lan/busybox" {
+		t.Errorf("init image = %v, want registry.lan/busybox", image)
+	}
+	if got, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas"); got != 4 {
+		t.Errorf("replicas = %d, want 4", got)
+	}
+
+	widget := findObject(objects, "Widget")
+	spec, _, _ := unstructured.NestedStringMap(widget.Object, "spec")
+	if spec["size"] != "large" || spec["color"] != "blue" {
+		t.Errorf("widget spec = %v, want merge patch applied", spec)
+	}
+}
+
+func TestRenderKustomizationErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "escaping resource",
+			files: map[string]string{"kustomization.yaml": "resources:\n- ../secrets.yaml\n"},
+			want:  "escapes",
+		},
+		{
+			name:  "unsupported field",
+			files: map[string]string{"kustomization.yaml": "configMapGenerator:\n- name: cfg\n"},
+			want:  "unsupported kustomization",
+		},
+		{
+			name: "unmatched patch",
+			files: map[string]string{
+				"kustomization.yaml": "patches:\n- path: patch.yaml\n",
+				"patch.yaml":         "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: missing\n",
+			},
+			want: "no resource matches",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RenderKustomizationFiles(tt.files, ".")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoredManifests(t *testing.T) {
+	dir := t.TempDir()
+	service := &Service{localRepoPath: dir}
+	if manifests, err := service.StoredManifests(context.Background()); err != nil || len(manifests) != 0 {
+		t.Fatalf("uncloned repository: %v, %v", manifests, err)
+	}
+
+	appDir := filepath.Join(dir, "k8s", "apps", "api")
+	if err := os.MkdirAll(appDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"deployment.yaml": "kind: Deployment\n", "README.md": "# api\n"} {
+		if err := os.WriteFile(filepath.Join(appDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifests, err := service.StoredManifests(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifests) != 1 || string(manifests["k8s/apps/api/deployment.yaml"]) != "kind: Deployment\n" {
+		t.Errorf("manifests = %v", manifests)
+	}
+}
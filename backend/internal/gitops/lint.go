@@ -0,0 +1,274 @@
+package gitops
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Built-in manifest policy rules
+const (
+	RuleLatestTag      = "latest-tag"      // images must be pinned to a tag other than latest, or a digest
+	RuleResourceLimits = "resource-limits" // containers must set CPU and memory limits
+	RuleRunAsNonRoot   = "run-as-non-root" // containers must not run as root
+	RuleHostPath       = "host-path"       // pods must not mount hostPath volumes
+	RuleRego           = "rego"            // violations reported by a PolicyEvaluator
+)
+
+// Policy severities; a block violation stops manifests from being committed
+const (
+	SeverityOff   = "off"
+	SeverityWarn  = "warn"
+	SeverityBlock = "block"
+)
+
+// LintPolicy is the severity of each rule
+type LintPolicy map[string]string
+
+// DefaultLintPolicy warns on every rule without blocking
+func DefaultLintPolicy() LintPolicy {
+	return LintPolicy{
+		RuleLatestTag:      SeverityWarn,
+		RuleResourceLimits: SeverityWarn,
+		RuleRunAsNonRoot:   SeverityWarn,
+		RuleHostPath:       SeverityWarn,
+	}
+}
+
+// ParseLintPolicy reads rule severities as comma-separated rule=severity pairs, such as
+// "latest-tag=block,run-as-non-root=off". Rules left out keep their default.
+func ParseLintPolicy(value string) (LintPolicy, error) {
+	policy := DefaultLintPolicy()
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		rule, severity, ok := strings.Cut(pair, "=")
+		rule, severity = strings.TrimSpace(rule), strings.TrimSpace(severity)
+		if _, known := policy[rule]; !ok || !known {
+			return nil, fmt.Errorf("unknown policy rule %q", pair)
+		}
+		switch severity {
+		case SeverityOff, SeverityWarn, SeverityBlock:
+			policy[rule] = severity
+		default:
+			return nil, fmt.Errorf("policy rule %s: severity must be off, warn or block", rule)
+		}
+	}
+	return policy, nil
+}
+
+// Violation is a manifest breaking a policy rule
+type Violation struct {
+	Rule      string `json:"rule"`
+	Severity  string `json:"severity"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Container string `json:"container,omitempty"`
+	Message   string `json:"message"`
+}
+
+// LintResult lists the violations of a manifest
+type LintResult struct {
+	Violations []Violation `json:"violations"`
+	Blocked    bool        `json:"blocked"` // some violation has block severity
+}
+
+// PolicyError is returned when a manifest has violations of block severity
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	var messages []string
+	for _, v := range e.Violations {
+		if v.Severity == SeverityBlock {
+			messages = append(messages, fmt.Sprintf("%s %s: %s", v.Kind, v.Name, v.Message))
+		}
+	}
+	return "manifest blocked by policy: " + strings.Join(messages, "; ")
+}
+
+// PolicyEvaluator checks manifests against policies kept outside Denshimon, such as Rego.
+// commit is false for dry runs, when nothing is committed.
+type PolicyEvaluator interface {
+	EvaluateManifest(ctx context.Context, objects []*unstructured.Unstructured, commit bool) []Violation
+}
+
+// SetLintPolicy sets the policy manifests are checked against before they are committed
+func (s *Service) SetLintPolicy(policy LintPolicy) {
+	s.lintPolicy = policy
+}
+
+// SetPolicyEvaluator adds external policies to the built-in rules
+func (s *Service) SetPolicyEvaluator(evaluator PolicyEvaluator) {
+	s.policyEvaluator = evaluator
+}
+
+// LintManifest checks a multi-document manifest against the service's policy without
+// committing it
+func (s *Service) LintManifest(ctx context.Context, manifest string) (*LintResult, error) {
+	return s.lintManifest(ctx, manifest, false)
+}
+
+// CheckManifest checks a manifest about to be committed, returning a *PolicyError when
+// it has violations of block severity
+func (s *Service) CheckManifest(ctx context.Context, manifest string) (*LintResult, error) {
+	result, err := s.lintManifest(ctx, manifest, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lint manifest: %w", err)
+	}
+	if result.Blocked {
+		return nil, &PolicyError{Violations: result.Violations}
+	}
+	return result, nil
+}
+
+func (s *Service) lintManifest(ctx context.Context, manifest string, commit bool) (*LintResult, error) {
+	policy := s.lintPolicy
+	if policy == nil {
+		policy = DefaultLintPolicy()
+	}
+	result, err := LintManifest(manifest, policy)
+	if err != nil || s.policyEvaluator == nil {
+		return result, err
+	}
+
+	objects, err := k8s.DecodeManifests([]byte(manifest))
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range s.policyEvaluator.EvaluateManifest(ctx, objects, commit) {
+		if v.Severity == SeverityBlock {
+			result.Blocked = true
+		}
+		result.Violations = append(result.Violations, v)
+	}
+	return result, nil
+}
+
+// LintManifest checks the workloads of a multi-document manifest against a policy
+func LintManifest(manifest string, policy LintPolicy) (*LintResult, error) {
+	objects, err := k8s.DecodeManifests([]byte(manifest))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LintResult{Violations: []Violation{}}
+	for _, obj := range objects {
+		for _, v := range lintObject(obj) {
+			v.Severity = policy[v.Rule]
+			if v.Severity == "" || v.Severity == SeverityOff {
+				continue
+			}
+			if v.Severity == SeverityBlock {
+				result.Blocked = true
+			}
+			result.Violations = append(result.Violations, v)
+		}
+	}
+	return result, nil
+}
+
+// podSpecPath is where a workload kind keeps its pod spec
+func podSpecPath(kind string) []string {
+	switch kind {
+	case "Pod":
+		return []string{"spec"}
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController", "Job":
+		return []string{"spec", "template", "spec"}
+	case "CronJob":
+		return []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	}
+	return nil
+}
+
+// lintObject checks one object against every rule
+func lintObject(obj *unstructured.Unstructured) []Violation {
+	specPath := podSpecPath(obj.GetKind())
+	if specPath == nil {
+		return nil
+	}
+	spec, found, err := unstructured.NestedMap(obj.Object, specPath...)
+	if err != nil || !found {
+		return nil
+	}
+
+	var violations []Violation
+	add := func(rule, container, message string) {
+		violations = append(violations, Violation{Rule: rule, Kind: obj.GetKind(), Name: obj.GetName(), Container: container, Message: message})
+	}
+
+	volumes, _, _ := unstructured.NestedSlice(spec, "volumes")
+	for _, v := range volumes {
+		volume, _ := v.(map[string]interface{})
+		if _, ok := volume["hostPath"]; ok {
+			add(RuleHostPath, "", fmt.Sprintf("volume %v mounts a host path", volume["name"]))
+		}
+	}
+
+	podContext, _, _ := unstructured.NestedMap(spec, "securityContext")
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _, _ := unstructured.NestedSlice(spec, field)
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := container["name"].(string)
+
+			image, _ := container["image"].(string)
+			if !pinnedImage(image) {
+				add(RuleLatestTag, name, fmt.Sprintf("image %q is not pinned to a tag or digest", image))
+			}
+
+			for _, resource := range []string{"cpu", "memory"} {
+				if _, found, _ := unstructured.NestedFieldNoCopy(container, "resources", "limits", resource); !found {
+					add(RuleResourceLimits, name, fmt.Sprintf("no %s limit", resource))
+				}
+			}
+
+			containerContext, _ := container["securityContext"].(map[string]interface{})
+			if !runsAsNonRoot(podContext, containerContext) {
+				add(RuleRunAsNonRoot, name, "may run as root; set runAsNonRoot or a non-zero runAsUser")
+			}
+		}
+	}
+	return violations
+}
+
+// pinnedImage reports whether an image names a digest or a tag other than latest
+func pinnedImage(image string) bool {
+	if strings.Contains(image, "@") {
+		return true
+	}
+	tag := strings.TrimPrefix(image, imageName(image))
+	return tag != "" && tag != ":latest"
+}
+
+// runsAsNonRoot applies the container's security context over the pod's, as the kubelet does
+func runsAsNonRoot(podContext, containerContext map[string]interface{}) bool {
+	nonRoot, user := securityUser(podContext, false, -1)
+	nonRoot, user = securityUser(containerContext, nonRoot, user)
+	if user >= 0 {
+		return user > 0
+	}
+	return nonRoot
+}
+
+func securityUser(securityContext map[string]interface{}, nonRoot bool, user int64) (bool, int64) {
+	if value, ok := securityContext["runAsNonRoot"].(bool); ok {
+		nonRoot = value
+	}
+	switch value := securityContext["runAsUser"].(type) {
+	case int64:
+		user = value
+	case float64:
+		user = int64(value)
+	}
+	return nonRoot, user
+}
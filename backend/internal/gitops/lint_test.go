@@ -0,0 +1,115 @@
+package gitops
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const lintManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsNonRoot: true
+      volumes:
+      - name: docker
+        hostPath:
+          path: /var/run/docker.sock
+      initContainers:
+      - name: migrate
+        image: registry.lan/api@sha256:abc
+        securityContext:
+          runAsUser: 0
+        resources:
+          limits: {cpu: 100m, memory: 64Mi}
+      containers:
+      - name: api
+        image: registry.lan:5000/api
+        resources:
+          limits: {memory: 128Mi}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+spec:
+  ports:
+  - port: 80
+`
+
+// denyServices is a PolicyEvaluator denying every Service
+type denyServices struct{ commits int }
+
+func (d *denyServices) EvaluateManifest(_ context.Context, objects []*unstructured.Unstructured, commit bool) []Violation {
+	if commit {
+		d.commits++
+	}
+	var violations []Violation
+	for _, obj := range objects {
+		if obj.GetKind() == "Service" {
+			violations = append(violations, Violation{Rule: RuleRego, Severity: SeverityBlock, Kind: obj.GetKind(), Name: obj.GetName(), Message: "no services"})
+		}
+	}
+	return violations
+}
+
+func TestLintManifest(t *testing.T) {
+	result, err := LintManifest(lintManifest, DefaultLintPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, v := range result.Violations {
+		found = append(found, v.Rule+"/"+v.Container)
+	}
+	sort.Strings(found)
+	want := []string{"host-path/", "latest-tag/api", "resource-limits/api", "run-as-non-root/migrate"}
+	if strings.Join(found, ",") != strings.Join(want, ",") {
+		t.Errorf("expected violations %v, got %v", want, found)
+	}
+	if result.Blocked {
+		t.Error("expected the default policy only to warn")
+	}
+
+	policy, err := ParseLintPolicy("host-path=block, latest-tag=off")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := &Service{}
+	service.SetLintPolicy(policy)
+	result, _ = service.LintManifest(context.Background(), lintManifest)
+	if !result.Blocked || len(result.Violations) != 3 {
+		t.Errorf("expected a blocked result without latest-tag, got %+v", result)
+	}
+	var policyErr *PolicyError
+	if _, err := service.CheckManifest(context.Background(), lintManifest); !errors.As(err, &policyErr) || !strings.Contains(err.Error(), "host path") {
+		t.Errorf("expected a policy error, got %v", err)
+	}
+
+	evaluator := &denyServices{}
+	service = &Service{}
+	service.SetPolicyEvaluator(evaluator)
+	if result, err := service.LintManifest(context.Background(), lintManifest); err != nil || !result.Blocked || len(result.Violations) != 5 {
+		t.Errorf("expected the evaluator's violations after the built-in ones, got %+v (%v)", result, err)
+	}
+	if _, err := service.CheckManifest(context.Background(), lintManifest); !errors.As(err, &policyErr) || !strings.Contains(err.Error(), "no services") {
+		t.Errorf("expected the evaluator to block the commit, got %v", err)
+	}
+	if evaluator.commits != 1 {
+		t.Errorf("expected only the check to count as a commit, got %d", evaluator.commits)
+	}
+
+	for _, value := range []string{"latest-tag=deny", "no-such-rule=warn", "host-path"} {
+		if _, err := ParseLintPolicy(value); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS gitops_credentials;
+DROP TABLE IF EXISTS gitops_alerts;
+DROP TABLE IF EXISTS gitops_deployments;
+DROP TABLE IF EXISTS gitops_applications;
+DROP TABLE IF EXISTS gitops_repositories;
@@ -0,0 +1,76 @@
+CREATE TABLE IF NOT EXISTS gitops_repositories (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	url TEXT NOT NULL,
+	branch TEXT NOT NULL DEFAULT 'main',
+	path TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	description TEXT,
+	last_sync TIMESTAMP NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS gitops_applications (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	namespace TEXT NOT NULL,
+	repository_id TEXT,
+	path TEXT,
+	image TEXT NOT NULL,
+	replicas INTEGER NOT NULL DEFAULT 1,
+	resources TEXT,
+	environment TEXT,
+	status TEXT NOT NULL DEFAULT 'pending',
+	health TEXT NOT NULL DEFAULT 'unknown',
+	sync_status TEXT NOT NULL DEFAULT 'out-of-sync',
+	last_deployed TIMESTAMP NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (repository_id) REFERENCES gitops_repositories(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS gitops_deployments (
+	id TEXT PRIMARY KEY,
+	application_id TEXT NOT NULL,
+	image TEXT NOT NULL,
+	replicas INTEGER NOT NULL,
+	environment TEXT,
+	git_hash TEXT,
+	status TEXT NOT NULL,
+	message TEXT,
+	deployed_by TEXT NOT NULL,
+	deployed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (application_id) REFERENCES gitops_applications(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS gitops_alerts (
+	id TEXT PRIMARY KEY,
+	type TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	title TEXT NOT NULL,
+	message TEXT NOT NULL,
+	metadata TEXT NOT NULL DEFAULT '{}',
+	status TEXT NOT NULL DEFAULT 'active',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	resolved_at TIMESTAMP NULL
+);
+
+CREATE TABLE IF NOT EXISTS gitops_credentials (
+	id TEXT PRIMARY KEY,
+	repository_id TEXT NOT NULL UNIQUE,
+	type TEXT NOT NULL,
+	username TEXT,
+	secret_encrypted TEXT NOT NULL,
+	public_key TEXT,
+	fingerprint TEXT,
+	known_hosts TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (repository_id) REFERENCES gitops_repositories(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_gitops_applications_repository_id ON gitops_applications(repository_id);
+CREATE INDEX IF NOT EXISTS idx_gitops_deployments_application_id ON gitops_deployments(application_id);
+CREATE INDEX IF NOT EXISTS idx_gitops_alerts_status ON gitops_alerts(status);
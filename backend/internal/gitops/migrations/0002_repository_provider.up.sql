@@ -0,0 +1,3 @@
+-- Git provider and webhook secret of registered repositories
+ALTER TABLE gitops_repositories ADD COLUMN provider TEXT NOT NULL DEFAULT 'gitea';
+ALTER TABLE gitops_repositories ADD COLUMN webhook_secret TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS gitops_archived_applications;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS gitops_archived_applications (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	namespace TEXT NOT NULL,
+	repository_id TEXT,
+	application TEXT NOT NULL,
+	deployments TEXT NOT NULL DEFAULT '[]',
+	git_hash TEXT,
+	pruned TEXT NOT NULL DEFAULT '[]',
+	deleted_by TEXT NOT NULL,
+	deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS gitops_sync_runs;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS gitops_sync_runs (
+	id TEXT PRIMARY KEY,
+	repository TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	target TEXT NOT NULL DEFAULT '',
+	trigger TEXT NOT NULL,
+	status TEXT NOT NULL,
+	error TEXT NOT NULL DEFAULT '',
+	deduplicated INTEGER NOT NULL DEFAULT 0,
+	queued_at TIMESTAMP NOT NULL,
+	started_at TIMESTAMP NULL,
+	finished_at TIMESTAMP NULL,
+	duration_ms INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_gitops_sync_runs_queued_at ON gitops_sync_runs(queued_at);
@@ -0,0 +1 @@
+ALTER TABLE gitops_applications DROP COLUMN volumes;
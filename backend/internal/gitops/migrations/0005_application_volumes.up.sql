@@ -0,0 +1,2 @@
+-- Pod volumes rendered into generated manifests, as JSON
+ALTER TABLE gitops_applications ADD COLUMN volumes TEXT;
@@ -0,0 +1,2 @@
+-- Container probes rendered into generated manifests, as JSON
+ALTER TABLE gitops_applications ADD COLUMN probes TEXT;
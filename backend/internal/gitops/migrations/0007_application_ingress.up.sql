@@ -0,0 +1,2 @@
+-- Ingress host, path and TLS rendered into generated manifests, as JSON
+ALTER TABLE gitops_applications ADD COLUMN ingress TEXT;
@@ -0,0 +1 @@
+ALTER TABLE gitops_deployments DROP COLUMN changelog;
@@ -0,0 +1,2 @@
+-- Commits between the previous and the deployed image, as JSON
+ALTER TABLE gitops_deployments ADD COLUMN changelog TEXT;
@@ -0,0 +1,293 @@
+package gitops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SyncKind is what a sync run synchronizes
+type SyncKind string
+
+const (
+	// SyncKindAll writes every application to the base infrastructure repository
+	SyncKindAll SyncKind = "all"
+	// SyncKindApplication writes one application to the base infrastructure repository
+	SyncKindApplication SyncKind = "application"
+	// SyncKindRepository clones or pulls a registered repository
+	SyncKindRepository SyncKind = "repository"
+)
+
+// Sync run statuses
+const (
+	SyncRunQueued    = "queued"
+	SyncRunRunning   = "running"
+	SyncRunSucceeded = "succeeded"
+	SyncRunFailed    = "failed"
+)
+
+// BaseRepository is the repository of application syncs, which write to the base
+// infrastructure repository rather than a registered one
+const BaseRepository = "base"
+
+// defaultSyncRunLimit is how many runs ListSyncRuns returns without a limit
+const defaultSyncRunLimit = 50
+
+// SyncRequest asks the queue for a sync
+type SyncRequest struct {
+	Kind    SyncKind
+	Target  string // application ID or repository ID
+	Trigger string // manual, force, auto, webhook, deployment
+	Config  *SyncConfig
+}
+
+// SyncRun records one sync through the queue
+type SyncRun struct {
+	ID         string   `json:"id"`
+	Repository string   `json:"repository"` // repository ID, or BaseRepository
+	Kind       SyncKind `json:"kind"`
+	Target     string   `json:"target,omitempty"`
+	Trigger    string   `json:"trigger"`
+	Status     string   `json:"status"`
+	Error      string   `json:"error,omitempty"`
+	// Deduplicated counts the requests merged into this run while it was queued
+	Deduplicated int        `json:"deduplicated"`
+	QueuedAt     time.Time  `json:"queued_at"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	DurationMs   int64      `json:"duration_ms"`
+}
+
+// SyncRunFilter narrows ListSyncRuns
+type SyncRunFilter struct {
+	Repository string
+	Status     string
+	Limit      int
+}
+
+// syncQueue runs syncs one at a time per repository, so auto-sync, forced syncs and webhooks
+// never write to the same working copy concurrently
+type syncQueue struct {
+	mu      sync.Mutex
+	pending map[string][]*syncJob // by repository
+	running map[string]bool       // repositories with an active worker
+}
+
+type syncJob struct {
+	run     *SyncRun
+	request SyncRequest
+	done    chan struct{}
+	err     error
+}
+
+func newSyncQueue() *syncQueue {
+	return &syncQueue{
+		pending: make(map[string][]*syncJob),
+		running: make(map[string]bool),
+	}
+}
+
+// covers reports whether a queued job already does what a request asks for. An application
+// sync is covered by a queued sync of all applications. Application syncs are only covered
+// by a job writing them with the same config, since the merged request's config is dropped.
+func (j *syncJob) covers(req SyncRequest) bool {
+	if req.Kind != SyncKindRepository && *j.request.Config != *req.Config {
+		return false
+	}
+	if j.request.Kind == SyncKindAll && req.Kind == SyncKindApplication {
+		return true
+	}
+	return j.request.Kind == req.Kind && j.request.Target == req.Target
+}
+
+func syncRepository(req SyncRequest) string {
+	if req.Kind == SyncKindRepository {
+		return req.Target
+	}
+	return BaseRepository
+}
+
+// Enqueue queues a sync and returns its run without waiting. A request matching one still
+// queued for the same repository with the same config is merged into it.
+func (se *SyncEngine) Enqueue(req SyncRequest) SyncRun {
+	_, run := se.enqueue(req)
+	return run
+}
+
+// Sync queues a sync and waits for it to finish. If ctx ends first the sync still runs and
+// the queued run is returned with the context's error.
+func (se *SyncEngine) Sync(ctx context.Context, req SyncRequest) (SyncRun, error) {
+	job, run := se.enqueue(req)
+	select {
+	case <-ctx.Done():
+		return run, ctx.Err()
+	case <-job.done:
+	}
+
+	se.queue.mu.Lock()
+	defer se.queue.mu.Unlock()
+	return *job.run, job.err
+}
+
+func (se *SyncEngine) enqueue(req SyncRequest) (*syncJob, SyncRun) {
+	if req.Config == nil {
+		req.Config = DefaultSyncConfig()
+	}
+	if req.Trigger == "" {
+		req.Trigger = "manual"
+	}
+	repository := syncRepository(req)
+
+	q := se.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, job := range q.pending[repository] {
+		if job.covers(req) {
+			job.run.Deduplicated++
+			se.logger.Debug("sync request merged into queued run", "run_id", job.run.ID, "kind", req.Kind, "target", req.Target)
+			return job, *job.run
+		}
+	}
+
+	job := &syncJob{
+		run: &SyncRun{
+			ID:         uuid.New().String(),
+			Repository: repository,
+			Kind:       req.Kind,
+			Target:     req.Target,
+			Trigger:    req.Trigger,
+			Status:     SyncRunQueued,
+			QueuedAt:   time.Now(),
+		},
+		request: req,
+		done:    make(chan struct{}),
+	}
+	se.saveSyncRun(*job.run)
+	q.pending[repository] = append(q.pending[repository], job)
+
+	if !q.running[repository] {
+		q.running[repository] = true
+		go se.runQueue(repository)
+	}
+	return job, *job.run
+}
+
+// runQueue works through a repository's queued syncs and exits when none are left
+func (se *SyncEngine) runQueue(repository string) {
+	q := se.queue
+	for {
+		q.mu.Lock()
+		jobs := q.pending[repository]
+		if len(jobs) == 0 {
+			delete(q.pending, repository)
+			delete(q.running, repository)
+			q.mu.Unlock()
+			return
+		}
+		job := jobs[0]
+		q.pending[repository] = jobs[1:]
+		started := time.Now()
+		job.run.Status = SyncRunRunning
+		job.run.StartedAt = &started
+		run := *job.run
+		q.mu.Unlock()
+
+		se.saveSyncRun(run)
+		err := se.execute(context.Background(), job.request)
+
+		q.mu.Lock()
+		finished := time.Now()
+		job.run.FinishedAt = &finished
+		job.run.DurationMs = finished.Sub(started).Milliseconds()
+		job.run.Status = SyncRunSucceeded
+		if err != nil {
+			job.run.Status = SyncRunFailed
+			job.run.Error = err.Error()
+		}
+		job.err = err
+		run = *job.run
+		q.mu.Unlock()
+
+		se.saveSyncRun(run)
+		se.logger.Info("sync run finished", "run_id", run.ID, "kind", run.Kind, "target", run.Target,
+			"status", run.Status, "duration_ms", run.DurationMs)
+		close(job.done)
+	}
+}
+
+func (se *SyncEngine) execute(ctx context.Context, req SyncRequest) error {
+	switch req.Kind {
+	case SyncKindAll:
+		return se.SyncAllApplications(ctx, req.Config)
+	case SyncKindApplication:
+		return se.SyncApplicationToGit(ctx, req.Target, req.Config)
+	case SyncKindRepository:
+		return se.service.SyncRepository(ctx, req.Target)
+	default:
+		return fmt.Errorf("unknown sync kind: %q", req.Kind)
+	}
+}
+
+// saveSyncRun stores a run's current state. Failing to record a run doesn't stop the sync.
+func (se *SyncEngine) saveSyncRun(run SyncRun) {
+	_, err := se.service.db.Exec(`
+		INSERT OR REPLACE INTO gitops_sync_runs (id, repository, kind, target, trigger, status, error, deduplicated, queued_at, started_at, finished_at, duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.ID, run.Repository, string(run.Kind), run.Target, run.Trigger, run.Status, run.Error,
+		run.Deduplicated, run.QueuedAt, run.StartedAt, run.FinishedAt, run.DurationMs)
+	if err != nil {
+		se.logger.Error("failed to record sync run", "run_id", run.ID, "error", err)
+	}
+}
+
+// ListSyncRuns returns recorded sync runs, newest first
+func (se *SyncEngine) ListSyncRuns(ctx context.Context, filter SyncRunFilter) ([]SyncRun, error) {
+	query := `
+		SELECT id, repository, kind, target, trigger, status, error, deduplicated, queued_at, started_at, finished_at, duration_ms
+		FROM gitops_sync_runs WHERE 1 = 1`
+	var args []interface{}
+	if filter.Repository != "" {
+		query += ` AND repository = ?`
+		args = append(args, filter.Repository)
+	}
+	if filter.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, filter.Status)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSyncRunLimit
+	}
+	query += ` ORDER BY queued_at DESC LIMIT ?`
+	args = append(args, filter.Limit)
+
+	rows, err := se.service.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sync runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []SyncRun{}
+	for rows.Next() {
+		var run SyncRun
+		var kind string
+		var startedAt, finishedAt sql.NullTime
+		if err := rows.Scan(&run.ID, &run.Repository, &kind, &run.Target, &run.Trigger, &run.Status, &run.Error,
+			&run.Deduplicated, &run.QueuedAt, &startedAt, &finishedAt, &run.DurationMs); err != nil {
+			return nil, fmt.Errorf("failed to scan sync run: %w", err)
+		}
+		run.Kind = SyncKind(kind)
+		if startedAt.Valid {
+			run.StartedAt = &startedAt.Time
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
@@ -0,0 +1,34 @@
+package gitops
+
+import "testing"
+
+func TestSyncJobCovers(t *testing.T) {
+	kustomize := DefaultSyncConfig()
+	kustomize.Kustomize = true
+
+	tests := []struct {
+		name   string
+		queued SyncRequest
+		req    SyncRequest
+		want   bool
+	}{
+		{"same application", SyncRequest{Kind: SyncKindApplication, Target: "api", Config: DefaultSyncConfig()},
+			SyncRequest{Kind: SyncKindApplication, Target: "api", Config: DefaultSyncConfig()}, true},
+		{"other application", SyncRequest{Kind: SyncKindApplication, Target: "api", Config: DefaultSyncConfig()},
+			SyncRequest{Kind: SyncKindApplication, Target: "web", Config: DefaultSyncConfig()}, false},
+		{"application under a sync of all", SyncRequest{Kind: SyncKindAll, Config: DefaultSyncConfig()},
+			SyncRequest{Kind: SyncKindApplication, Target: "api", Config: DefaultSyncConfig()}, true},
+		{"application with another config", SyncRequest{Kind: SyncKindApplication, Target: "api", Config: DefaultSyncConfig()},
+			SyncRequest{Kind: SyncKindApplication, Target: "api", Config: kustomize}, false},
+		{"sync of all with another config", SyncRequest{Kind: SyncKindAll, Config: DefaultSyncConfig()},
+			SyncRequest{Kind: SyncKindApplication, Target: "api", Config: kustomize}, false},
+		{"repository pull ignores the config", SyncRequest{Kind: SyncKindRepository, Target: "repo-1", Config: DefaultSyncConfig()},
+			SyncRequest{Kind: SyncKindRepository, Target: "repo-1", Config: kustomize}, true},
+	}
+	for _, tt := range tests {
+		job := &syncJob{request: tt.queued}
+		if got := job.covers(tt.req); got != tt.want {
+			t.Errorf("%s: covers = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
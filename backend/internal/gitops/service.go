@@ -5,31 +5,90 @@ package gitops
 import (
 	"context"
 	"database/sql"
+	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/archellir/denshimon/internal/bus"
+	"github.com/archellir/denshimon/internal/database/migrate"
 	"github.com/archellir/denshimon/internal/git"
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/internal/secretbox"
 	"github.com/google/uuid"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations is the schema of the GitOps tables
+var Migrations = migrate.NewSource("gitops", migrationFiles, "migrations")
+
 // Service provides GitOps operations
 type Service struct {
 	db               *sql.DB
 	gitClient        *git.Client
 	baseInfraRepoURL string
 	localRepoPath    string
+	credentialKey    []byte         // legacy key of credentials sealed before box was set
+	box              *secretbox.Box // seals repository credentials at rest
+	k8sClient        *k8s.Client    // prunes live resources of deleted applications
+	lintPolicy       LintPolicy     // checked before manifests are committed
+	policyEvaluator  PolicyEvaluator
+	changelogSource  ChangelogSource // commits between deployed images
+	bus              *bus.Bus        // deployments are published on it, optional
 }
 
+var (
+	// ErrRepositoryNotFound is returned when a repository ID does not exist
+	ErrRepositoryNotFound = errors.New("repository not found")
+	// ErrApplicationNotFound is returned when an application ID does not exist
+	ErrApplicationNotFound = errors.New("application not found")
+	// ErrDeploymentNotFound is returned when a deployment record ID does not exist
+	ErrDeploymentNotFound = errors.New("deployment record not found")
+	// ErrInvalidApplication is returned for an application name or namespace that isn't a
+	// DNS-1123 label, and so can't be used in manifest paths
+	ErrInvalidApplication = errors.New("invalid application")
+)
+
 // NewService creates a new GitOps service
 func NewService(db *sql.DB, baseInfraRepoURL, localRepoPath string) *Service {
-	return &Service{
+	service := &Service{
 		db:               db,
 		baseInfraRepoURL: baseInfraRepoURL,
 		localRepoPath:    localRepoPath,
 		gitClient:        git.NewClient(baseInfraRepoURL, localRepoPath, "main"),
 	}
+
+	if err := service.initDB(); err != nil {
+		slog.Error("Failed to migrate GitOps tables", "error", err)
+	}
+
+	return service
+}
+
+// SetBus sets the event bus applications deployed to the base infrastructure are published on
+func (s *Service) SetBus(b *bus.Bus) {
+	s.bus = b
+}
+
+// initDB migrates the GitOps tables to the latest schema version
+func (s *Service) initDB() error {
+	if err := migrate.Up(context.Background(), s.db, Migrations); err != nil {
+		return err
+	}
+
+	// Runs queued or running when the process stopped will never finish
+	if _, err := s.db.Exec(`UPDATE gitops_sync_runs SET status = 'failed', error = 'interrupted by restart'
+		WHERE status IN ('queued', 'running')`); err != nil {
+		return fmt.Errorf("failed to close interrupted sync runs: %w", err)
+	}
+	return nil
 }
 
 // Repository represents a GitOps repository
@@ -39,11 +98,16 @@ type Repository struct {
 	URL         string     `json:"url"`
 	Branch      string     `json:"branch"`
 	Path        string     `json:"path"`
+	Provider    Provider   `json:"provider"`
 	LastSync    *time.Time `json:"last_sync,omitempty"`
 	Status      string     `json:"status"`
 	Description string     `json:"description"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// WebhookSecret validates incoming webhooks and is never returned by the API
+	WebhookSecret    string `json:"-"`
+	HasWebhookSecret bool   `json:"has_webhook_secret"`
 }
 
 // Application represents a deployed application
@@ -57,6 +121,9 @@ type Application struct {
 	Replicas      int               `json:"replicas"`
 	Resources     map[string]string `json:"resources"`
 	Environment   map[string]string `json:"environment"`
+	Volumes       []Volume          `json:"volumes,omitempty"`
+	Probes        *Probes           `json:"probes,omitempty"` // nil renders the /health and /ready defaults
+	Ingress       *Ingress          `json:"ingress,omitempty"`
 	LastDeployed  *time.Time        `json:"last_deployed,omitempty"`
 	Status        string            `json:"status"`
 	Health        string            `json:"health"`
@@ -65,6 +132,74 @@ type Application struct {
 	UpdatedAt     time.Time         `json:"updated_at"`
 }
 
+// Volume types
+const (
+	VolumeEmptyDir  = "empty_dir"
+	VolumeConfigMap = "config_map"
+	VolumeSecret    = "secret"
+	VolumePVC       = "pvc"
+)
+
+// Volume is a pod volume mounted into the application's container. Source names the
+// ConfigMap, Secret or PersistentVolumeClaim and is unused for empty_dir.
+type Volume struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Source    string `json:"source,omitempty"`
+	MountPath string `json:"mount_path"`
+	ReadOnly  bool   `json:"read_only,omitempty"`
+}
+
+// Probes are the health checks of the application's container
+type Probes struct {
+	Liveness  *Probe `json:"liveness,omitempty"`
+	Readiness *Probe `json:"readiness,omitempty"`
+	Startup   *Probe `json:"startup,omitempty"`
+}
+
+// Probe checks the container with one of an HTTP GET, a TCP connection or a command
+type Probe struct {
+	HTTPGet             *HTTPGetAction   `json:"http_get,omitempty"`
+	TCPSocket           *TCPSocketAction `json:"tcp_socket,omitempty"`
+	Exec                []string         `json:"exec,omitempty"`
+	InitialDelaySeconds int32            `json:"initial_delay_seconds,omitempty"`
+	PeriodSeconds       int32            `json:"period_seconds,omitempty"`
+	TimeoutSeconds      int32            `json:"timeout_seconds,omitempty"`
+	SuccessThreshold    int32            `json:"success_threshold,omitempty"`
+	FailureThreshold    int32            `json:"failure_threshold,omitempty"`
+}
+
+// HTTPGetAction probes a path on a port
+type HTTPGetAction struct {
+	Path   string `json:"path"`
+	Port   int32  `json:"port"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// TCPSocketAction probes that a port accepts connections
+type TCPSocketAction struct {
+	Port int32 `json:"port"`
+}
+
+// Ingress is the host and path an application's Ingress routes to its service. TLS uses
+// TLSSecret, or the certificate cert-manager issues from Issuer into <name>-tls.
+type Ingress struct {
+	Host         string `json:"host"`
+	Path         string `json:"path,omitempty"`
+	TLSSecret    string `json:"tls_secret,omitempty"`
+	Issuer       string `json:"issuer,omitempty"`
+	IssuerKind   string `json:"issuer_kind,omitempty"` // ClusterIssuer (default) or Issuer
+	SeparateFile bool   `json:"separate_file,omitempty"`
+}
+
+// Workload is what an application's manifests render besides its image, replicas,
+// resources and environment
+type Workload struct {
+	Volumes []Volume
+	Probes  *Probes
+	Ingress *Ingress
+}
+
 // DeploymentRecord represents a deployment history entry
 type DeploymentRecord struct {
 	ID            string            `json:"id"`
@@ -77,8 +212,22 @@ type DeploymentRecord struct {
 	Message       string            `json:"message"`
 	DeployedBy    string            `json:"deployed_by"`
 	DeployedAt    time.Time         `json:"deployed_at"`
+	Changelog     *Changelog        `json:"changelog,omitempty"` // generated shortly after a deployment of a new image
 }
 
+// ApplicationDeployment is a deployment record together with its application's name and namespace
+type ApplicationDeployment struct {
+	DeploymentRecord
+	Application string `json:"application"`
+	Namespace   string `json:"namespace"`
+}
+
+// Deployed is published when an application was deployed to the base infrastructure
+type Deployed struct{ ApplicationDeployment }
+
+// Topic names GitOps deployments on the event bus
+func (Deployed) Topic() string { return "gitops.deployed" }
+
 // Alert represents a GitOps monitoring alert
 type Alert struct {
 	ID          string            `json:"id"`
@@ -127,14 +276,23 @@ func (s *Service) InitializeRepository() error {
 	return s.gitClient.Clone()
 }
 
-// SyncRepository pulls the latest changes from the remote repository
+// SyncRepository clones or pulls the repository using its stored credential
 func (s *Service) SyncRepository(ctx context.Context, repoID string) error {
-	if err := s.gitClient.Pull(); err != nil {
+	repo, err := s.GetRepository(ctx, repoID)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.clientForRepository(ctx, repo)
+	if err != nil {
+		return err
+	}
+	if err := client.Clone(); err != nil {
 		return fmt.Errorf("failed to sync repository: %w", err)
 	}
 
 	// Update last sync time in database
-	_, err := s.db.ExecContext(ctx, `
+	_, err = s.db.ExecContext(ctx, `
 		UPDATE gitops_repositories 
 		SET last_sync = ?, updated_at = ? 
 		WHERE id = ?`,
@@ -144,23 +302,42 @@ func (s *Service) SyncRepository(ctx context.Context, repoID string) error {
 }
 
 // CreateRepository creates a new GitOps repository record
-func (s *Service) CreateRepository(ctx context.Context, name, url, branch, description string) (*Repository, error) {
+func (s *Service) CreateRepository(ctx context.Context, name, url, branch, description string, provider Provider, webhookSecret string) (*Repository, error) {
+	if provider == "" {
+		provider = ProviderGitea
+	}
+	if !ValidProvider(provider) {
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+
 	repo := &Repository{
-		ID:          uuid.New().String(),
-		Name:        name,
-		URL:         url,
-		Branch:      branch,
-		Path:        filepath.Join(s.localRepoPath, name),
-		Status:      "pending",
-		Description: description,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:               uuid.New().String(),
+		Name:             name,
+		URL:              url,
+		Branch:           branch,
+		Path:             filepath.Join(s.localRepoPath, name),
+		Provider:         provider,
+		Status:           "pending",
+		Description:      description,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+		WebhookSecret:    webhookSecret,
+		HasWebhookSecret: webhookSecret != "",
+	}
+
+	sealedSecret := ""
+	if webhookSecret != "" {
+		sealed, err := s.box.Encrypt(webhookSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt webhook secret: %w", err)
+		}
+		sealedSecret = sealed
 	}
 
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO gitops_repositories (id, name, url, branch, path, status, description, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		repo.ID, repo.Name, repo.URL, repo.Branch, repo.Path, repo.Status, repo.Description, repo.CreatedAt, repo.UpdatedAt)
+		INSERT INTO gitops_repositories (id, name, url, branch, path, provider, webhook_secret, status, description, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		repo.ID, repo.Name, repo.URL, repo.Branch, repo.Path, repo.Provider, sealedSecret, repo.Status, repo.Description, repo.CreatedAt, repo.UpdatedAt)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create repository: %w", err)
@@ -172,7 +349,7 @@ func (s *Service) CreateRepository(ctx context.Context, name, url, branch, descr
 // ListRepositories returns all GitOps repositories
 func (s *Service) ListRepositories(ctx context.Context) ([]Repository, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, name, url, branch, path, status, description, last_sync, created_at, updated_at
+		SELECT id, name, url, branch, path, provider, webhook_secret, status, description, last_sync, created_at, updated_at
 		FROM gitops_repositories
 		ORDER BY created_at DESC`)
 	if err != nil {
@@ -182,27 +359,62 @@ func (s *Service) ListRepositories(ctx context.Context) ([]Repository, error) {
 
 	var repos []Repository
 	for rows.Next() {
-		var repo Repository
-		var lastSync sql.NullTime
-		
-		err := rows.Scan(&repo.ID, &repo.Name, &repo.URL, &repo.Branch, &repo.Path, 
-			&repo.Status, &repo.Description, &lastSync, &repo.CreatedAt, &repo.UpdatedAt)
+		repo, err := s.scanRepository(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan repository: %w", err)
 		}
+		repos = append(repos, *repo)
+	}
 
-		if lastSync.Valid {
-			repo.LastSync = &lastSync.Time
-		}
+	return repos, nil
+}
 
-		repos = append(repos, repo)
+// GetRepository returns a single GitOps repository
+func (s *Service) GetRepository(ctx context.Context, repoID string) (*Repository, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, name, url, branch, path, provider, webhook_secret, status, description, last_sync, created_at, updated_at
+		FROM gitops_repositories
+		WHERE id = ?`, repoID)
+
+	repo, err := s.scanRepository(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrRepositoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository: %w", err)
 	}
+	return repo, nil
+}
 
-	return repos, nil
+// scanRepository reads a repository row and opens its webhook secret
+func (s *Service) scanRepository(scanner interface{ Scan(...interface{}) error }) (*Repository, error) {
+	var repo Repository
+	var lastSync sql.NullTime
+	var description sql.NullString
+
+	err := scanner.Scan(&repo.ID, &repo.Name, &repo.URL, &repo.Branch, &repo.Path, &repo.Provider, &repo.WebhookSecret,
+		&repo.Status, &description, &lastSync, &repo.CreatedAt, &repo.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	repo.WebhookSecret, err = s.box.Decrypt(repo.WebhookSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+	repo.Description = description.String
+	repo.HasWebhookSecret = repo.WebhookSecret != ""
+	if lastSync.Valid {
+		repo.LastSync = &lastSync.Time
+	}
+	return &repo, nil
 }
 
 // CreateApplication creates a new application deployment
 func (s *Service) CreateApplication(ctx context.Context, name, namespace, repoID, path, image string, replicas int, resources, environment map[string]string) (*Application, error) {
+	if err := validateApplication(name, namespace); err != nil {
+		return nil, err
+	}
 	app := &Application{
 		ID:           uuid.New().String(),
 		Name:         name,
@@ -237,11 +449,47 @@ func (s *Service) CreateApplication(ctx context.Context, name, namespace, repoID
 	return app, nil
 }
 
+// validateApplication checks that an application's name and namespace are DNS-1123 labels.
+// Both are joined into repository paths, so anything else could reach outside them.
+func validateApplication(name, namespace string) error {
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return fmt.Errorf("%w: name %q: %s", ErrInvalidApplication, name, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return fmt.Errorf("%w: namespace %q: %s", ErrInvalidApplication, namespace, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// SetApplicationWorkload replaces the volumes, probes and ingress rendered into an
+// application's manifests
+func (s *Service) SetApplicationWorkload(ctx context.Context, appID string, workload Workload) error {
+	volumesJSON, _ := json.Marshal(workload.Volumes)
+	result, err := s.db.ExecContext(ctx, `UPDATE gitops_applications SET volumes = ?, probes = ?, ingress = ?, updated_at = ? WHERE id = ?`,
+		string(volumesJSON), nullJSON(workload.Probes), nullJSON(workload.Ingress), time.Now(), appID)
+	if err != nil {
+		return fmt.Errorf("failed to update application workload: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrApplicationNotFound
+	}
+	return nil
+}
+
+// nullJSON encodes a pointer as JSON, or NULL when it is nil
+func nullJSON[T any](value *T) sql.NullString {
+	if value == nil {
+		return sql.NullString{}
+	}
+	data, _ := json.Marshal(value)
+	return sql.NullString{String: string(data), Valid: true}
+}
+
 // ListApplications returns all applications
 func (s *Service) ListApplications(ctx context.Context) ([]Application, error) {
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT id, name, namespace, repository_id, path, image, replicas, resources, environment, 
-			   status, health, sync_status, last_deployed, created_at, updated_at
+			   status, health, sync_status, last_deployed, created_at, updated_at, volumes, probes, ingress
 		FROM gitops_applications
 		ORDER BY created_at DESC`)
 	if err != nil {
@@ -253,11 +501,12 @@ func (s *Service) ListApplications(ctx context.Context) ([]Application, error) {
 	for rows.Next() {
 		var app Application
 		var resourcesJSON, envJSON string
+		var volumesJSON, probesJSON, ingressJSON sql.NullString
 		var lastDeployed sql.NullTime
 		
 		err := rows.Scan(&app.ID, &app.Name, &app.Namespace, &app.RepositoryID, &app.Path, 
 			&app.Image, &app.Replicas, &resourcesJSON, &envJSON, &app.Status, &app.Health, 
-			&app.SyncStatus, &lastDeployed, &app.CreatedAt, &app.UpdatedAt)
+			&app.SyncStatus, &lastDeployed, &app.CreatedAt, &app.UpdatedAt, &volumesJSON, &probesJSON, &ingressJSON)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan application: %w", err)
 		}
@@ -265,6 +514,15 @@ func (s *Service) ListApplications(ctx context.Context) ([]Application, error) {
 		// Deserialize JSON maps
 		json.Unmarshal([]byte(resourcesJSON), &app.Resources)
 		json.Unmarshal([]byte(envJSON), &app.Environment)
+		if volumesJSON.Valid {
+			json.Unmarshal([]byte(volumesJSON.String), &app.Volumes)
+		}
+		if probesJSON.Valid {
+			json.Unmarshal([]byte(probesJSON.String), &app.Probes)
+		}
+		if ingressJSON.Valid {
+			json.Unmarshal([]byte(ingressJSON.String), &app.Ingress)
+		}
 
 		if lastDeployed.Valid {
 			app.LastDeployed = &lastDeployed.Time
@@ -276,28 +534,55 @@ func (s *Service) ListApplications(ctx context.Context) ([]Application, error) {
 	return apps, nil
 }
 
+// getApplication returns a single application by ID
+func (s *Service) getApplication(ctx context.Context, appID string) (*Application, error) {
+	apps, err := s.ListApplications(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range apps {
+		if apps[i].ID == appID {
+			return &apps[i], nil
+		}
+	}
+	return nil, ErrApplicationNotFound
+}
+
 // DeployApplication deploys an application and records the deployment
 func (s *Service) DeployApplication(ctx context.Context, appID string, deployedBy string) (*DeploymentRecord, error) {
 	// Get application details
 	var app Application
 	var resourcesJSON, envJSON string
+	var volumesJSON, probesJSON, ingressJSON sql.NullString
 	err := s.db.QueryRowContext(ctx, `
-		SELECT id, name, namespace, repository_id, path, image, replicas, resources, environment
+		SELECT id, name, namespace, repository_id, path, image, replicas, resources, environment, volumes, probes, ingress
 		FROM gitops_applications WHERE id = ?`, appID).Scan(
 		&app.ID, &app.Name, &app.Namespace, &app.RepositoryID, &app.Path, 
-		&app.Image, &app.Replicas, &resourcesJSON, &envJSON)
+		&app.Image, &app.Replicas, &resourcesJSON, &envJSON, &volumesJSON, &probesJSON, &ingressJSON)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get application: %w", err)
 	}
 
 	json.Unmarshal([]byte(resourcesJSON), &app.Resources)
 	json.Unmarshal([]byte(envJSON), &app.Environment)
+	if volumesJSON.Valid {
+		json.Unmarshal([]byte(volumesJSON.String), &app.Volumes)
+	}
+	if probesJSON.Valid {
+		json.Unmarshal([]byte(probesJSON.String), &app.Probes)
+	}
+	if ingressJSON.Valid {
+		json.Unmarshal([]byte(ingressJSON.String), &app.Ingress)
+	}
 
 	// Generate Kubernetes manifest
 	manifest, err := s.generateDeploymentManifest(&app)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate manifest: %w", err)
 	}
+	if _, err := s.CheckManifest(ctx, manifest); err != nil {
+		return nil, err
+	}
 
 	// Write manifest to repository
 	manifestPath := filepath.Join("k8s", app.Namespace, fmt.Sprintf("%s-deployment.yaml", app.Name))
@@ -358,13 +643,16 @@ func (s *Service) DeployApplication(ctx context.Context, appID string, deployedB
 		return nil, fmt.Errorf("failed to update application status: %w", err)
 	}
 
+	s.bus.Publish(Deployed{ApplicationDeployment{DeploymentRecord: *deployment, Application: app.Name, Namespace: app.Namespace}})
+	go s.attachChangelog(deployment)
+
 	return deployment, nil
 }
 
 // GetDeploymentHistory returns deployment history for an application
 func (s *Service) GetDeploymentHistory(ctx context.Context, appID string) ([]DeploymentRecord, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, application_id, image, replicas, environment, git_hash, status, message, deployed_by, deployed_at
+		SELECT id, application_id, image, replicas, environment, git_hash, status, message, deployed_by, deployed_at, changelog
 		FROM gitops_deployments 
 		WHERE application_id = ?
 		ORDER BY deployed_at DESC`,
@@ -378,21 +666,86 @@ func (s *Service) GetDeploymentHistory(ctx context.Context, appID string) ([]Dep
 	for rows.Next() {
 		var deployment DeploymentRecord
 		var envJSON string
+		var changelogJSON sql.NullString
 		
 		err := rows.Scan(&deployment.ID, &deployment.ApplicationID, &deployment.Image, 
 			&deployment.Replicas, &envJSON, &deployment.GitHash, &deployment.Status, 
-			&deployment.Message, &deployment.DeployedBy, &deployment.DeployedAt)
+			&deployment.Message, &deployment.DeployedBy, &deployment.DeployedAt, &changelogJSON)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan deployment: %w", err)
 		}
 
 		json.Unmarshal([]byte(envJSON), &deployment.Environment)
+		deployment.Changelog = parseChangelog(changelogJSON)
 		deployments = append(deployments, deployment)
 	}
 
 	return deployments, nil
 }
 
+// GetDeploymentRecord returns a single deployment history entry
+func (s *Service) GetDeploymentRecord(ctx context.Context, id string) (*DeploymentRecord, error) {
+	var deployment DeploymentRecord
+	var envJSON string
+	var changelogJSON sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, application_id, image, replicas, environment, git_hash, status, message, deployed_by, deployed_at, changelog
+		FROM gitops_deployments
+		WHERE id = ?`, id).Scan(&deployment.ID, &deployment.ApplicationID, &deployment.Image,
+		&deployment.Replicas, &envJSON, &deployment.GitHash, &deployment.Status,
+		&deployment.Message, &deployment.DeployedBy, &deployment.DeployedAt, &changelogJSON)
+	if err == sql.ErrNoRows {
+		return nil, ErrDeploymentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment record: %w", err)
+	}
+
+	json.Unmarshal([]byte(envJSON), &deployment.Environment)
+	deployment.Changelog = parseChangelog(changelogJSON)
+	return &deployment, nil
+}
+
+// RecentDeployments returns deployments of all applications in a namespace ("" for all) since the given time, oldest first
+func (s *Service) RecentDeployments(ctx context.Context, namespace string, since time.Time) ([]ApplicationDeployment, error) {
+	query := `
+		SELECT d.id, d.application_id, d.image, d.replicas, d.environment, d.git_hash, d.status, d.message,
+		       d.deployed_by, d.deployed_at, a.name, a.namespace
+		FROM gitops_deployments d
+		JOIN gitops_applications a ON a.id = d.application_id
+		WHERE d.deployed_at >= ?`
+	args := []interface{}{since}
+	if namespace != "" {
+		query += ` AND a.namespace = ?`
+		args = append(args, namespace)
+	}
+	query += ` ORDER BY d.deployed_at ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent deployments: %w", err)
+	}
+	defer rows.Close()
+
+	var deployments []ApplicationDeployment
+	for rows.Next() {
+		var deployment ApplicationDeployment
+		var envJSON, gitHash, message sql.NullString
+		if err := rows.Scan(&deployment.ID, &deployment.ApplicationID, &deployment.Image,
+			&deployment.Replicas, &envJSON, &gitHash, &deployment.Status,
+			&message, &deployment.DeployedBy, &deployment.DeployedAt,
+			&deployment.Application, &deployment.Namespace); err != nil {
+			return nil, fmt.Errorf("failed to scan deployment: %w", err)
+		}
+		deployment.GitHash, deployment.Message = gitHash.String, message.String
+		if envJSON.Valid {
+			json.Unmarshal([]byte(envJSON.String), &deployment.Environment)
+		}
+		deployments = append(deployments, deployment)
+	}
+	return deployments, rows.Err()
+}
+
 // RollbackApplication rolls back an application to a previous deployment
 func (s *Service) RollbackApplication(ctx context.Context, appID string, targetDeploymentID string, rolledBackBy string) (*DeploymentRecord, error) {
 	// Get the target deployment to rollback to
@@ -415,17 +768,27 @@ func (s *Service) RollbackApplication(ctx context.Context, appID string, targetD
 	// Get application details
 	var app Application
 	var resourcesJSON, appEnvJSON string
+	var volumesJSON, probesJSON, ingressJSON sql.NullString
 	err = s.db.QueryRowContext(ctx, `
-		SELECT id, name, namespace, repository_id, path, image, replicas, resources, environment
+		SELECT id, name, namespace, repository_id, path, image, replicas, resources, environment, volumes, probes, ingress
 		FROM gitops_applications WHERE id = ?`, appID).Scan(
 		&app.ID, &app.Name, &app.Namespace, &app.RepositoryID, &app.Path,
-		&app.Image, &app.Replicas, &resourcesJSON, &appEnvJSON)
+		&app.Image, &app.Replicas, &resourcesJSON, &appEnvJSON, &volumesJSON, &probesJSON, &ingressJSON)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get application: %w", err)
 	}
 
 	json.Unmarshal([]byte(resourcesJSON), &app.Resources)
 	json.Unmarshal([]byte(appEnvJSON), &app.Environment)
+	if volumesJSON.Valid {
+		json.Unmarshal([]byte(volumesJSON.String), &app.Volumes)
+	}
+	if probesJSON.Valid {
+		json.Unmarshal([]byte(probesJSON.String), &app.Probes)
+	}
+	if ingressJSON.Valid {
+		json.Unmarshal([]byte(ingressJSON.String), &app.Ingress)
+	}
 
 	// Update application with rollback values
 	app.Image = targetDeployment.Image
@@ -437,6 +800,9 @@ func (s *Service) RollbackApplication(ctx context.Context, appID string, targetD
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate rollback manifest: %w", err)
 	}
+	if _, err := s.CheckManifest(ctx, manifest); err != nil {
+		return nil, err
+	}
 
 	// Write manifest to repository
 	manifestPath := filepath.Join("k8s", app.Namespace, fmt.Sprintf("%s-deployment.yaml", app.Name))
@@ -609,6 +975,34 @@ func (s *Service) ListAlerts(ctx context.Context) ([]Alert, error) {
 	return alerts, nil
 }
 
+// AlertsSince returns alerts raised since the given time in any status, oldest first
+func (s *Service) AlertsSince(ctx context.Context, since time.Time) ([]Alert, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, type, severity, title, message, metadata, status, created_at, updated_at, resolved_at
+		FROM gitops_alerts
+		WHERE created_at >= ?
+		ORDER BY created_at ASC`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []Alert
+	for rows.Next() {
+		var alert Alert
+		var metadataJSON string
+		var resolvedAt *time.Time
+		if err := rows.Scan(&alert.ID, &alert.Type, &alert.Severity, &alert.Title,
+			&alert.Message, &metadataJSON, &alert.Status, &alert.CreatedAt, &alert.UpdatedAt, &resolvedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		json.Unmarshal([]byte(metadataJSON), &alert.Metadata)
+		alert.ResolvedAt = resolvedAt
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
 // AcknowledgeAlert acknowledges an alert
 func (s *Service) AcknowledgeAlert(ctx context.Context, alertID string) error {
 	_, err := s.db.ExecContext(ctx, `
@@ -5,8 +5,11 @@ package gitops
 import (
 	"context"
 	"fmt"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/archellir/denshimon/internal/git"
@@ -18,6 +21,10 @@ import (
 type SyncEngine struct {
 	service *Service
 	logger  *slog.Logger
+	queue   *syncQueue
+
+	autoSyncMu   sync.Mutex
+	stopAutoSync context.CancelFunc // stops the running auto-sync loop
 }
 
 // NewSyncEngine creates a new sync engine
@@ -25,6 +32,7 @@ func NewSyncEngine(service *Service) *SyncEngine {
 	return &SyncEngine{
 		service: service,
 		logger:  logger.New(slog.LevelInfo),
+		queue:   newSyncQueue(),
 	}
 }
 
@@ -39,6 +47,7 @@ type SyncConfig struct {
 	IncludeIngress   bool          `json:"include_ingress"`
 	IncludeConfigMap bool          `json:"include_configmap"`
 	AutoScaling      bool          `json:"auto_scaling"`
+	Kustomize        bool          `json:"kustomize"` // write a Kustomize base and environment overlays instead of one file
 }
 
 // DefaultSyncConfig returns default synchronization configuration
@@ -94,29 +103,16 @@ func (se *SyncEngine) SyncApplicationToGit(ctx context.Context, appID string, co
 		},
 	}
 
-	manifest, err := se.service.GenerateFullManifest(app, manifestOptions)
+	manifestPaths, err := se.writeManifests(ctx, app, config, manifestOptions)
 	if err != nil {
-		return fmt.Errorf("failed to generate manifest: %w", err)
-	}
-
-	// Validate manifest before writing
-	if err := se.service.ValidateManifest(manifest); err != nil {
-		return fmt.Errorf("manifest validation failed: %w", err)
-	}
-
-	// Determine manifest file path
-	manifestPath := filepath.Join(config.ManifestPath, app.Namespace, fmt.Sprintf("%s.yaml", app.Name))
-
-	// Write manifest to repository
-	if err := se.service.gitClient.WriteFile(manifestPath, []byte(manifest)); err != nil {
-		return fmt.Errorf("failed to write manifest: %w", err)
+		return err
 	}
 
 	// Generate commit message with deployment details
 	commitMsg := se.generateCommitMessage(app, config.CommitMessage)
 
 	// Commit and push changes
-	if err := se.service.gitClient.CommitAndPush(commitMsg, manifestPath); err != nil {
+	if err := se.service.gitClient.CommitAndPush(commitMsg, manifestPaths...); err != nil {
 		return fmt.Errorf("failed to sync to git: %w", err)
 	}
 
@@ -124,7 +120,7 @@ func (se *SyncEngine) SyncApplicationToGit(ctx context.Context, appID string, co
 		"app_id", appID, 
 		"app_name", app.Name,
 		"namespace", app.Namespace,
-		"manifest_path", manifestPath)
+		"manifest_paths", manifestPaths)
 
 	return nil
 }
@@ -172,26 +168,13 @@ func (se *SyncEngine) SyncAllApplications(ctx context.Context, config *SyncConfi
 				},
 			}
 
-			manifest, err := se.service.GenerateFullManifest(&app, manifestOptions)
+			manifestPaths, err := se.writeManifests(ctx, &app, config, manifestOptions)
 			if err != nil {
-				se.logger.Error("failed to generate manifest", "app_id", app.ID, "error", err)
-				continue
-			}
-
-			// Validate manifest
-			if err := se.service.ValidateManifest(manifest); err != nil {
-				se.logger.Error("manifest validation failed", "app_id", app.ID, "error", err)
-				continue
-			}
-
-			// Write manifest
-			manifestPath := filepath.Join(config.ManifestPath, namespace, fmt.Sprintf("%s.yaml", app.Name))
-			if err := se.service.gitClient.WriteFile(manifestPath, []byte(manifest)); err != nil {
 				se.logger.Error("failed to write manifest", "app_id", app.ID, "error", err)
 				continue
 			}
 
-			syncedFiles = append(syncedFiles, manifestPath)
+			syncedFiles = append(syncedFiles, manifestPaths...)
 		}
 	}
 
@@ -210,12 +193,115 @@ func (se *SyncEngine) SyncAllApplications(ctx context.Context, config *SyncConfi
 	return nil
 }
 
-// StartAutoSync starts automatic synchronization background process
+// writeManifests writes an application's manifests to the repository and returns the written paths.
+// With Kustomize enabled the base is regenerated on every sync, while existing overlays are left
+// untouched so environment-specific edits in the repository survive. An ingress configured for its
+// own file is written to <name>-ingress.yaml next to the manifest.
+func (se *SyncEngine) writeManifests(ctx context.Context, app *Application, config *SyncConfig, options map[string]interface{}) ([]string, error) {
+	if !config.Kustomize {
+		separateIngress := app.Ingress != nil && app.Ingress.SeparateFile
+		if separateIngress {
+			options = withOption(options, "separate_ingress", true)
+		}
+		manifest, err := se.service.GenerateFullManifest(app, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate manifest: %w", err)
+		}
+
+		// Validate manifest before writing
+		if err := se.service.ValidateManifest(manifest); err != nil {
+			return nil, fmt.Errorf("manifest validation failed: %w", err)
+		}
+		if _, err := se.service.CheckManifest(ctx, manifest); err != nil {
+			return nil, err
+		}
+
+		manifestPath := filepath.Join(config.ManifestPath, app.Namespace, fmt.Sprintf("%s.yaml", app.Name))
+		if err := se.service.gitClient.WriteFile(manifestPath, []byte(manifest)); err != nil {
+			return nil, fmt.Errorf("failed to write manifest: %w", err)
+		}
+		if !separateIngress {
+			return []string{manifestPath}, nil
+		}
+
+		ingress, err := se.service.GenerateManifest(app, "Ingress", options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ingress: %w", err)
+		}
+		ingressPath := filepath.Join(config.ManifestPath, app.Namespace, fmt.Sprintf("%s-ingress.yaml", app.Name))
+		if err := se.service.gitClient.WriteFile(ingressPath, []byte(ingress)); err != nil {
+			return nil, fmt.Errorf("failed to write ingress manifest: %w", err)
+		}
+		return []string{manifestPath, ingressPath}, nil
+	}
+
+	files, err := se.service.GenerateKustomization(app, nil, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate kustomization: %w", err)
+	}
+	for name, content := range files {
+		// Overlay patches are partial objects; the base holds the complete workload
+		if strings.HasPrefix(name, "base/") && path.Base(name) != kustomizationFile {
+			if _, err := se.service.CheckManifest(ctx, content); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	appDir := filepath.Join(config.ManifestPath, app.Namespace, app.Name)
+	var written []string
+	for _, name := range sortedKeys(files) {
+		manifestPath := filepath.Join(appDir, filepath.FromSlash(name))
+		if strings.HasPrefix(name, "overlays/") {
+			overlayDir := filepath.Join(appDir, filepath.FromSlash(path.Dir(name)))
+			if _, err := se.service.gitClient.ReadFile(filepath.Join(overlayDir, kustomizationFile)); err == nil {
+				continue
+			}
+		}
+		if err := se.service.gitClient.WriteFile(manifestPath, []byte(files[name])); err != nil {
+			return nil, fmt.Errorf("failed to write manifest: %w", err)
+		}
+		written = append(written, manifestPath)
+	}
+	return written, nil
+}
+
+// withOption copies manifest options with one more set
+func withOption(options map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(options)+1)
+	for k, v := range options {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
+
+func sortedKeys(files map[string]string) []string {
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// StartAutoSync queues a sync of all applications every interval until ctx ends. Starting
+// auto sync again replaces the running loop, so there is never more than one.
 func (se *SyncEngine) StartAutoSync(ctx context.Context, config *SyncConfig) error {
+	se.autoSyncMu.Lock()
+	if se.stopAutoSync != nil {
+		se.stopAutoSync()
+		se.stopAutoSync = nil
+	}
 	if !config.AutoSync {
+		se.autoSyncMu.Unlock()
 		se.logger.Info("auto sync disabled")
 		return nil
 	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	se.stopAutoSync = cancel
+	se.autoSyncMu.Unlock()
 
 	se.logger.Info("starting auto sync", "interval", config.SyncInterval)
 
@@ -228,10 +314,8 @@ func (se *SyncEngine) StartAutoSync(ctx context.Context, config *SyncConfig) err
 			se.logger.Info("auto sync stopped")
 			return ctx.Err()
 		case <-ticker.C:
-			se.logger.Debug("running scheduled sync")
-			if err := se.SyncAllApplications(ctx, config); err != nil {
-				se.logger.Error("auto sync failed", "error", err)
-			}
+			se.logger.Debug("queueing scheduled sync")
+			se.Enqueue(SyncRequest{Kind: SyncKindAll, Trigger: "auto", Config: config})
 		}
 	}
 }
@@ -323,55 +407,65 @@ Synchronized %d Kubernetes manifests to base infrastructure repository
 	return message + description
 }
 
-// ForceSync performs immediate synchronization regardless of auto-sync settings
-func (se *SyncEngine) ForceSync(ctx context.Context, config *SyncConfig) error {
+// ForceSync syncs all applications now, queued behind any sync already running for the
+// base repository, and waits for it to finish
+func (se *SyncEngine) ForceSync(ctx context.Context, config *SyncConfig) (SyncRun, error) {
 	se.logger.Info("performing force sync")
-	
-	// Backup original auto-sync setting
-	originalAutoSync := config.AutoSync
-	config.AutoSync = true
-	defer func() {
-		config.AutoSync = originalAutoSync
-	}()
-
-	return se.SyncAllApplications(ctx, config)
+	return se.Sync(ctx, SyncRequest{Kind: SyncKindAll, Trigger: "force", Config: config})
 }
 
-// WebhookPayload represents incoming webhook data
+// WebhookPayload represents incoming webhook data, normalised to the Gitea/GitHub push shape
 type WebhookPayload struct {
 	Repository struct {
 		Name        string `json:"name"`
 		FullName    string `json:"full_name"`
 		CloneURL    string `json:"clone_url"`
+		SSHURL      string `json:"ssh_url"`
+		HTMLURL     string `json:"html_url"`
 		DefaultBranch string `json:"default_branch"`
 	} `json:"repository"`
 	Ref        string `json:"ref"`
 	Before     string `json:"before"`
 	After      string `json:"after"`
-	Commits    []struct {
-		ID      string `json:"id"`
-		Message string `json:"message"`
-		Author  struct {
-			Name  string `json:"name"`
-			Email string `json:"email"`
-		} `json:"author"`
-		Modified []string `json:"modified"`
-		Added    []string `json:"added"`
-		Removed  []string `json:"removed"`
-	} `json:"commits"`
+	Commits    []WebhookCommit `json:"commits"`
 	Pusher struct {
 		Name  string `json:"name"`
 		Email string `json:"email"`
 	} `json:"pusher"`
+
+	// Set by ParseWebhook rather than decoded from the body
+	Provider Provider `json:"-"`
+	Event    string   `json:"-"` // push, tag, ping
+	Tag      string   `json:"-"`
+}
+
+// WebhookCommit is a single commit in a push event
+type WebhookCommit struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+	Author  struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	} `json:"author"`
+	Modified []string `json:"modified"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
 }
 
 // ProcessWebhook handles incoming Git webhook notifications for external commit detection
 func (se *SyncEngine) ProcessWebhook(ctx context.Context, payload *WebhookPayload) error {
 	se.logger.Info("processing webhook", 
+		"provider", payload.Provider,
 		"repository", payload.Repository.FullName,
 		"ref", payload.Ref,
 		"commits", len(payload.Commits))
 
+	// Tag pushes carry no file changes; they only trigger a repository sync
+	if payload.Event == WebhookEventTag {
+		se.logger.Info("webhook tag push", "repository", payload.Repository.FullName, "tag", payload.Tag)
+		return nil
+	}
+
 	// Check if this is a push to the main branch
 	if !se.isMainBranchPush(payload) {
 		se.logger.Debug("ignoring webhook: not a main branch push", "ref", payload.Ref)
@@ -482,17 +576,7 @@ func (se *SyncEngine) isInternalCommit(message string) bool {
 }
 
 // detectExternalDeployments creates deployment records for external manifest changes
-func (se *SyncEngine) detectExternalDeployments(ctx context.Context, commit struct {
-	ID      string `json:"id"`
-	Message string `json:"message"`
-	Author  struct {
-		Name  string `json:"name"`
-		Email string `json:"email"`
-	} `json:"author"`
-	Modified []string `json:"modified"`
-	Added    []string `json:"added"`
-	Removed  []string `json:"removed"`
-}, payload *WebhookPayload) []string {
+func (se *SyncEngine) detectExternalDeployments(ctx context.Context, commit WebhookCommit, payload *WebhookPayload) []string {
 	var deploymentIDs []string
 	
 	// Process added and modified files
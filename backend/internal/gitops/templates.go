@@ -91,6 +91,30 @@ spec:
             memory: {{index .App.Resources "memory_request"}}
           {{- end}}
         {{- end}}
+        {{- if .App.Volumes}}
+        volumeMounts:
+        {{- range .App.Volumes}}
+        - name: {{.Name}}
+          mountPath: {{.MountPath}}
+          {{- if .ReadOnly}}
+          readOnly: true
+          {{- end}}
+        {{- end}}
+        {{- end}}
+        {{- if .App.Probes}}
+        {{- with .App.Probes.Liveness}}
+        livenessProbe:
+          {{- template "probe" .}}
+        {{- end}}
+        {{- with .App.Probes.Readiness}}
+        readinessProbe:
+          {{- template "probe" .}}
+        {{- end}}
+        {{- with .App.Probes.Startup}}
+        startupProbe:
+          {{- template "probe" .}}
+        {{- end}}
+        {{- else}}
         livenessProbe:
           httpGet:
             path: /health
@@ -103,10 +127,67 @@ spec:
             port: 8080
           initialDelaySeconds: 5
           periodSeconds: 5
+        {{- end}}
       restartPolicy: Always
+      {{- if .App.Volumes}}
+      volumes:
+      {{- range .App.Volumes}}
+      - name: {{.Name}}
+        {{- if eq .Type "config_map"}}
+        configMap:
+          name: {{.Source}}
+        {{- else if eq .Type "secret"}}
+        secret:
+          secretName: {{.Source}}
+        {{- else if eq .Type "pvc"}}
+        persistentVolumeClaim:
+          claimName: {{.Source}}
+          {{- if .ReadOnly}}
+          readOnly: true
+          {{- end}}
+        {{- else}}
+        emptyDir: {}
+        {{- end}}
+      {{- end}}
+      {{- end}}
 ---`,
 }
 
+// probeTemplate renders the body of a container probe in deploymentTemplate
+const probeTemplate = `
+          {{- if .HTTPGet}}
+          httpGet:
+            path: {{.HTTPGet.Path}}
+            port: {{.HTTPGet.Port}}
+            {{- if .HTTPGet.Scheme}}
+            scheme: {{.HTTPGet.Scheme}}
+            {{- end}}
+          {{- else if .TCPSocket}}
+          tcpSocket:
+            port: {{.TCPSocket.Port}}
+          {{- else}}
+          exec:
+            command:
+            {{- range .Exec}}
+            - {{printf "%q" .}}
+            {{- end}}
+          {{- end}}
+          {{- if .InitialDelaySeconds}}
+          initialDelaySeconds: {{.InitialDelaySeconds}}
+          {{- end}}
+          {{- if .PeriodSeconds}}
+          periodSeconds: {{.PeriodSeconds}}
+          {{- end}}
+          {{- if .TimeoutSeconds}}
+          timeoutSeconds: {{.TimeoutSeconds}}
+          {{- end}}
+          {{- if .SuccessThreshold}}
+          successThreshold: {{.SuccessThreshold}}
+          {{- end}}
+          {{- if .FailureThreshold}}
+          failureThreshold: {{.FailureThreshold}}
+          {{- end}}`
+
 // serviceTemplate generates a Kubernetes Service manifest
 var serviceTemplate = ManifestTemplate{
 	Kind: "Service",
@@ -155,16 +236,32 @@ metadata:
     {{$key}}: {{$value}}
     {{- end}}
   annotations:
+    {{- with .App.Ingress}}
+    {{- if .Issuer}}
+    {{- if eq .IssuerKind "Issuer"}}
+    cert-manager.io/issuer: {{.Issuer}}
+    {{- else}}
+    cert-manager.io/cluster-issuer: {{.Issuer}}
+    {{- end}}
+    {{- end}}
+    {{- else}}
     nginx.ingress.kubernetes.io/rewrite-target: /
+    {{- end}}
     {{- range $key, $value := .Annotations}}
     {{$key}}: {{$value}}
     {{- end}}
 spec:
+  {{- if and .App.Ingress (or .App.Ingress.TLSSecret .App.Ingress.Issuer)}}
+  tls:
+  - hosts:
+    - {{.App.Ingress.Host}}
+    secretName: {{if .App.Ingress.TLSSecret}}{{.App.Ingress.TLSSecret}}{{else}}{{.App.Name}}-tls{{end}}
+  {{- end}}
   rules:
-  - host: {{.App.Name}}.local
+  - host: {{if .App.Ingress}}{{.App.Ingress.Host}}{{else}}{{.App.Name}}.local{{end}}
     http:
       paths:
-      - path: /
+      - path: {{if and .App.Ingress .App.Ingress.Path}}{{.App.Ingress.Path}}{{else}}/{{end}}
         pathType: Prefix
         backend:
           service:
@@ -277,6 +374,9 @@ func (s *Service) GenerateManifest(app *Application, resourceType string, option
 	switch resourceType {
 	case "Deployment":
 		tmpl, err = template.New("deployment").Parse(deploymentTemplate.Template)
+		if err == nil {
+			_, err = tmpl.New("probe").Parse(probeTemplate)
+		}
 	case "Service":
 		tmpl, err = template.New("service").Parse(serviceTemplate.Template)
 	case "Ingress":
@@ -321,8 +421,10 @@ func (s *Service) GenerateFullManifest(app *Application, options map[string]inte
 		manifests = append(manifests, service)
 	}
 
-	// Generate ingress if enabled
-	if needsIngress, ok := options["ingress"].(bool); ok && needsIngress {
+	// Generate ingress if enabled, or configured for the application and not written to its own file
+	needsIngress, _ := options["ingress"].(bool)
+	separateIngress, _ := options["separate_ingress"].(bool)
+	if needsIngress || (app.Ingress != nil && !separateIngress) {
 		ingress, err := s.GenerateManifest(app, "Ingress", options)
 		if err != nil {
 			return "", fmt.Errorf("failed to generate ingress: %w", err)
@@ -0,0 +1,274 @@
+package gitops
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Provider identifies the Git hosting service that sent a webhook
+type Provider string
+
+const (
+	ProviderGitea  Provider = "gitea"
+	ProviderGitHub Provider = "github"
+	ProviderGitLab Provider = "gitlab"
+)
+
+// Webhook event kinds after normalisation
+const (
+	WebhookEventPush = "push"
+	WebhookEventTag  = "tag"
+	WebhookEventPing = "ping"
+)
+
+var (
+	// ErrUnsupportedEvent is returned for webhook events that do not affect sync (issues, PRs, ...)
+	ErrUnsupportedEvent = errors.New("unsupported webhook event")
+	// ErrInvalidSignature is returned when a webhook fails secret validation
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// ValidProvider reports whether p is a supported provider
+func ValidProvider(p Provider) bool {
+	switch p {
+	case ProviderGitea, ProviderGitHub, ProviderGitLab:
+		return true
+	}
+	return false
+}
+
+// DetectProvider determines the webhook provider from its request headers.
+// Gitea also sends X-GitHub-Event for compatibility, so it is checked first. Requests without
+// any provider header are treated as Gitea, the payload shape the endpoint originally accepted.
+func DetectProvider(header http.Header) Provider {
+	switch {
+	case header.Get("X-Gitea-Event") != "" || header.Get("X-Gogs-Event") != "":
+		return ProviderGitea
+	case header.Get("X-Gitlab-Event") != "":
+		return ProviderGitLab
+	case header.Get("X-GitHub-Event") != "":
+		return ProviderGitHub
+	}
+	return ProviderGitea
+}
+
+// ParseWebhook normalises a provider-specific push or tag event into a WebhookPayload
+func ParseWebhook(provider Provider, header http.Header, body []byte) (*WebhookPayload, error) {
+	switch provider {
+	case ProviderGitea, ProviderGitHub:
+		return parseGitHubStyleWebhook(provider, header, body)
+	case ProviderGitLab:
+		return parseGitLabWebhook(header, body)
+	}
+	return nil, fmt.Errorf("unsupported webhook provider: %s", provider)
+}
+
+// ValidateWebhookSecret checks a webhook against the repository secret using the provider's scheme:
+// GitHub signs with X-Hub-Signature-256, Gitea with X-Gitea-Signature and GitLab sends the token verbatim.
+func ValidateWebhookSecret(provider Provider, header http.Header, body []byte, secret string) error {
+	if secret == "" {
+		return nil
+	}
+
+	switch provider {
+	case ProviderGitHub:
+		signature := strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+		return verifyHMAC(body, secret, signature)
+	case ProviderGitea:
+		signature := header.Get("X-Gitea-Signature")
+		if signature == "" {
+			signature = strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+		}
+		return verifyHMAC(body, secret, signature)
+	case ProviderGitLab:
+		token := header.Get("X-Gitlab-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported webhook provider: %s", provider)
+}
+
+func verifyHMAC(body []byte, secret, signature string) error {
+	if signature == "" {
+		return ErrInvalidSignature
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// parseGitHubStyleWebhook parses GitHub and Gitea push events, which share a payload shape
+func parseGitHubStyleWebhook(provider Provider, header http.Header, body []byte) (*WebhookPayload, error) {
+	event := header.Get("X-GitHub-Event")
+	if provider == ProviderGitea {
+		event = header.Get("X-Gitea-Event")
+		if event == "" {
+			event = header.Get("X-Gogs-Event")
+		}
+	}
+
+	switch event {
+	case "ping":
+		return &WebhookPayload{Provider: provider, Event: WebhookEventPing}, nil
+	case "push", "":
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEvent, event)
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", provider, err)
+	}
+	payload.Provider = provider
+	payload.setRefEvent()
+	return &payload, nil
+}
+
+// gitlabPushEvent is the subset of GitLab's push and tag push hook payload we use
+type gitlabPushEvent struct {
+	ObjectKind string `json:"object_kind"`
+	Ref        string `json:"ref"`
+	Before     string `json:"before"`
+	After      string `json:"after"`
+	UserName   string `json:"user_name"`
+	UserEmail  string `json:"user_email"`
+	Project    struct {
+		Name              string `json:"name"`
+		PathWithNamespace string `json:"path_with_namespace"`
+		GitHTTPURL        string `json:"git_http_url"`
+		GitSSHURL         string `json:"git_ssh_url"`
+		WebURL            string `json:"web_url"`
+		DefaultBranch     string `json:"default_branch"`
+	} `json:"project"`
+	Commits []WebhookCommit `json:"commits"`
+}
+
+func parseGitLabWebhook(header http.Header, body []byte) (*WebhookPayload, error) {
+	switch event := header.Get("X-Gitlab-Event"); event {
+	case "Push Hook", "Tag Push Hook":
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEvent, event)
+	}
+
+	var event gitlabPushEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid gitlab payload: %w", err)
+	}
+
+	payload := &WebhookPayload{
+		Provider: ProviderGitLab,
+		Ref:      event.Ref,
+		Before:   event.Before,
+		After:    event.After,
+		Commits:  event.Commits,
+	}
+	payload.Repository.Name = event.Project.Name
+	payload.Repository.FullName = event.Project.PathWithNamespace
+	payload.Repository.CloneURL = event.Project.GitHTTPURL
+	payload.Repository.SSHURL = event.Project.GitSSHURL
+	payload.Repository.HTMLURL = event.Project.WebURL
+	payload.Repository.DefaultBranch = event.Project.DefaultBranch
+	payload.Pusher.Name = event.UserName
+	payload.Pusher.Email = event.UserEmail
+	payload.setRefEvent()
+	return payload, nil
+}
+
+// setRefEvent classifies the payload as a branch push or tag push from its ref
+func (p *WebhookPayload) setRefEvent() {
+	if tag, ok := strings.CutPrefix(p.Ref, "refs/tags/"); ok {
+		p.Event = WebhookEventTag
+		p.Tag = tag
+		return
+	}
+	p.Event = WebhookEventPush
+}
+
+// TriggersSync reports whether the webhook should sync the repository:
+// tag pushes always do, branch pushes only when they target the tracked branch
+func (p *WebhookPayload) TriggersSync(repo *Repository) bool {
+	switch p.Event {
+	case WebhookEventTag:
+		return true
+	case WebhookEventPush:
+		branch := repo.Branch
+		if branch == "" {
+			branch = "main"
+		}
+		return strings.TrimPrefix(p.Ref, "refs/heads/") == branch
+	}
+	return false
+}
+
+// FindWebhookRepository returns the registered repository a webhook refers to, or nil if none matches
+func (s *Service) FindWebhookRepository(ctx context.Context, payload *WebhookPayload) (*Repository, error) {
+	repos, err := s.ListRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []string{payload.Repository.CloneURL, payload.Repository.SSHURL, payload.Repository.HTMLURL}
+	for i := range repos {
+		url := normalizeRepoURL(repos[i].URL)
+		for _, candidate := range candidates {
+			if candidate != "" && normalizeRepoURL(candidate) == url {
+				return &repos[i], nil
+			}
+		}
+		if fullName := strings.ToLower(payload.Repository.FullName); fullName != "" && strings.HasSuffix(url, "/"+fullName) {
+			return &repos[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// normalizeRepoURL reduces https, ssh and scp-style clone URLs to host/owner/name so they compare equal
+func normalizeRepoURL(url string) string {
+	url = strings.ToLower(strings.TrimSpace(url))
+	for _, prefix := range []string{"https://", "http://", "ssh://", "git://"} {
+		url = strings.TrimPrefix(url, prefix)
+	}
+	if at := strings.Index(url, "@"); at >= 0 {
+		url = url[at+1:]
+	}
+	// scp-style git@host:owner/name
+	if colon := strings.Index(url, ":"); colon >= 0 {
+		rest := url[colon+1:]
+		if slash := strings.Index(rest, "/"); slash >= 0 && isPort(rest[:slash]) {
+			rest = rest[slash+1:]
+		}
+		url = url[:colon] + "/" + rest
+	}
+	url = strings.TrimSuffix(url, "/")
+	return strings.TrimSuffix(url, ".git")
+}
+
+func isPort(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
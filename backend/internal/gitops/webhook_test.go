@@ -0,0 +1,122 @@
+package gitops
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestDetectProvider(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   Provider
+	}{
+		{"github", map[string]string{"X-GitHub-Event": "push"}, ProviderGitHub},
+		{"gitlab", map[string]string{"X-Gitlab-Event": "Push Hook"}, ProviderGitLab},
+		{"gitea sends github headers too", map[string]string{"X-Gitea-Event": "push", "X-GitHub-Event": "push"}, ProviderGitea},
+		{"no headers", map[string]string{}, ProviderGitea},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.header {
+				header.Set(k, v)
+			}
+			if got := DetectProvider(header); got != tt.want {
+				t.Errorf("DetectProvider() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGitLabTagPush(t *testing.T) {
+	body := []byte(`{
+		"object_kind": "tag_push",
+		"ref": "refs/tags/v1.2.0",
+		"after": "abc123",
+		"user_name": "alice",
+		"project": {
+			"name": "infra",
+			"path_with_namespace": "homelab/infra",
+			"git_http_url": "https://gitlab.example.com/homelab/infra.git",
+			"default_branch": "main"
+		},
+		"commits": []
+	}`)
+	header := http.Header{}
+	header.Set("X-Gitlab-Event", "Tag Push Hook")
+
+	payload, err := ParseWebhook(ProviderGitLab, header, body)
+	if err != nil {
+		t.Fatalf("ParseWebhook() error = %v", err)
+	}
+	if payload.Event != WebhookEventTag || payload.Tag != "v1.2.0" {
+		t.Errorf("event = %s tag = %s, want tag v1.2.0", payload.Event, payload.Tag)
+	}
+	if payload.Repository.FullName != "homelab/infra" || payload.Pusher.Name != "alice" {
+		t.Errorf("unexpected normalised payload: %+v", payload)
+	}
+}
+
+func TestParseWebhookUnsupportedEvent(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-GitHub-Event", "issues")
+
+	_, err := ParseWebhook(ProviderGitHub, header, []byte(`{}`))
+	if !errors.Is(err, ErrUnsupportedEvent) {
+		t.Errorf("ParseWebhook() error = %v, want ErrUnsupportedEvent", err)
+	}
+}
+
+func TestValidateWebhookSecret(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	github := http.Header{}
+	github.Set("X-Hub-Signature-256", "sha256="+signature)
+	if err := ValidateWebhookSecret(ProviderGitHub, github, body, "s3cret"); err != nil {
+		t.Errorf("valid github signature rejected: %v", err)
+	}
+	if err := ValidateWebhookSecret(ProviderGitHub, github, body, "other"); err == nil {
+		t.Error("github signature with wrong secret accepted")
+	}
+
+	gitea := http.Header{}
+	gitea.Set("X-Gitea-Signature", signature)
+	if err := ValidateWebhookSecret(ProviderGitea, gitea, body, "s3cret"); err != nil {
+		t.Errorf("valid gitea signature rejected: %v", err)
+	}
+
+	gitlab := http.Header{}
+	gitlab.Set("X-Gitlab-Token", "s3cret")
+	if err := ValidateWebhookSecret(ProviderGitLab, gitlab, body, "s3cret"); err != nil {
+		t.Errorf("valid gitlab token rejected: %v", err)
+	}
+	if err := ValidateWebhookSecret(ProviderGitLab, http.Header{}, body, "s3cret"); err == nil {
+		t.Error("missing gitlab token accepted")
+	}
+}
+
+func TestNormalizeRepoURL(t *testing.T) {
+	want := "github.com/homelab/infra"
+	for _, url := range []string{
+		"https://github.com/homelab/infra.git",
+		"git@github.com:homelab/infra.git",
+		"ssh://git@github.com/homelab/infra",
+		"https://GitHub.com/homelab/infra/",
+	} {
+		if got := normalizeRepoURL(url); got != want {
+			t.Errorf("normalizeRepoURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+	if got := normalizeRepoURL("ssh://git@gitea.lan:2222/homelab/infra.git"); got != "gitea.lan/homelab/infra" {
+		t.Errorf("port not stripped: %q", got)
+	}
+}
@@ -0,0 +1,537 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is absent when the request was rejected before
+// execution, and partial when some fields failed.
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is a request or field error. Path leads to the field that failed.
+type Error struct {
+	Message   string        `json:"message"`
+	Locations []Location    `json:"locations,omitempty"`
+	Path      []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Execute runs a query. Root fields are resolved concurrently, so a view's independent
+// lists are fetched in parallel.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{err.(*Error)}}
+	}
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{err.(*Error)}}
+	}
+	if op.kind != "query" {
+		return &Response{Errors: []*Error{{
+			Message:   fmt.Sprintf("%s operations are not supported; use the REST API for changes", op.kind),
+			Locations: []Location{op.loc},
+		}}}
+	}
+	if errs := s.validate(doc, op); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+	variables, errs := s.coerceVariables(op, req.Variables)
+	if len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+
+	e := &executor{schema: s, doc: doc, variables: variables}
+	data, nullified := e.executeRoot(ctx, op.selections)
+	if nullified {
+		return &Response{Data: json.RawMessage("null"), Errors: e.errors}
+	}
+	return &Response{Data: data, Errors: e.errors}
+}
+
+func selectOperation(doc *document, name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, &Error{Message: "Must provide operation name if query contains multiple operations"}
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, &Error{Message: fmt.Sprintf("Unknown operation named %q", name)}
+}
+
+// coerceVariables checks the request's variables against the operation's definitions
+func (s *Schema) coerceVariables(op *operation, given map[string]interface{}) (map[string]interface{}, []*Error) {
+	variables := make(map[string]interface{})
+	var errs []*Error
+	for _, def := range op.variables {
+		t, err := s.inputType(def.typ)
+		if err != nil {
+			errs = append(errs, &Error{Message: fmt.Sprintf("Variable \"$%s\": %v", def.name, err), Locations: []Location{def.loc}})
+			continue
+		}
+		value, ok := given[def.name]
+		if !ok && def.hasDefault {
+			value, ok = def.defaultValue, true
+		}
+		if !ok {
+			if _, required := t.(*NonNull); required {
+				errs = append(errs, &Error{
+					Message:   fmt.Sprintf("Variable \"$%s\" of required type %q was not provided", def.name, def.typ),
+					Locations: []Location{def.loc},
+				})
+			}
+			continue
+		}
+		coerced, err := coerceInput(t, value)
+		if err != nil {
+			errs = append(errs, &Error{Message: fmt.Sprintf("Variable \"$%s\" got invalid value: %v", def.name, err), Locations: []Location{def.loc}})
+			continue
+		}
+		variables[def.name] = coerced
+	}
+	return variables, errs
+}
+
+// inputType finds the schema type of a variable definition; only scalars are input types
+func (s *Schema) inputType(ref *typeRef) (Type, error) {
+	var t Type
+	if ref.elem != nil {
+		elem, err := s.inputType(ref.elem)
+		if err != nil {
+			return nil, err
+		}
+		t = NewList(elem)
+	} else {
+		scalar, ok := s.types[ref.name].(*Scalar)
+		if !ok {
+			return nil, fmt.Errorf("unknown input type %q", ref.name)
+		}
+		t = scalar
+	}
+	if ref.nonNull {
+		t = NewNonNull(t)
+	}
+	return t, nil
+}
+
+func coerceInput(t Type, value interface{}) (interface{}, error) {
+	if nonNull, ok := t.(*NonNull); ok {
+		if value == nil {
+			return nil, fmt.Errorf("expected non-null value of type %s", t)
+		}
+		return coerceInput(nonNull.OfType, value)
+	}
+	if value == nil {
+		return nil, nil
+	}
+	switch t := t.(type) {
+	case *List:
+		items, ok := value.([]interface{})
+		if !ok {
+			items = []interface{}{value}
+		}
+		coerced := make([]interface{}, len(items))
+		for i, item := range items {
+			v, err := coerceInput(t.OfType, item)
+			if err != nil {
+				return nil, err
+			}
+			coerced[i] = v
+		}
+		return coerced, nil
+	case *Scalar:
+		return t.ParseValue(value)
+	}
+	return nil, fmt.Errorf("%s is not an input type", t)
+}
+
+type executor struct {
+	schema    *Schema
+	doc       *document
+	variables map[string]interface{}
+
+	mu     sync.Mutex
+	errors []*Error
+}
+
+func (e *executor) addError(err error, f *field, path []interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errors = append(e.errors, &Error{
+		Message:   err.Error(),
+		Locations: []Location{f.loc},
+		Path:      append([]interface{}(nil), path...),
+	})
+}
+
+func (e *executor) executeRoot(ctx context.Context, selections []selection) (orderedObject, bool) {
+	groups := e.collectFields(e.schema.Query, selections, &fieldGroups{}, make(map[string]bool))
+	values := make(orderedObject, len(groups.keys))
+	nullified := make([]bool, len(groups.keys))
+
+	var wg sync.WaitGroup
+	for i, key := range groups.keys {
+		wg.Add(1)
+		go func(i int, key string) {
+			defer wg.Done()
+			value, failed := e.executeField(ctx, e.schema.Query, map[string]interface{}{}, groups.fields[key], []interface{}{key})
+			values[i] = orderedField{key: key, value: value}
+			nullified[i] = failed
+		}(i, key)
+	}
+	wg.Wait()
+
+	// Report errors in the order of the query rather than the order resolvers finished
+	order := make(map[string]int, len(groups.keys))
+	for i, key := range groups.keys {
+		order[key] = i
+	}
+	sort.SliceStable(e.errors, func(i, j int) bool {
+		return order[fmt.Sprint(e.errors[i].Path[0])] < order[fmt.Sprint(e.errors[j].Path[0])]
+	})
+
+	for _, failed := range nullified {
+		if failed {
+			return nil, true
+		}
+	}
+	return values, false
+}
+
+// fieldGroups are the selected fields by response key, in query order
+type fieldGroups struct {
+	keys   []string
+	fields map[string][]*field
+}
+
+func (g *fieldGroups) add(f *field) {
+	if g.fields == nil {
+		g.fields = make(map[string][]*field)
+	}
+	key := f.responseKey()
+	if _, ok := g.fields[key]; !ok {
+		g.keys = append(g.keys, key)
+	}
+	g.fields[key] = append(g.fields[key], f)
+}
+
+func (e *executor) collectFields(object *Object, selections []selection, groups *fieldGroups, visited map[string]bool) *fieldGroups {
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			if e.included(sel.directives) {
+				groups.add(sel)
+			}
+		case *fragmentSpread:
+			if !e.included(sel.directives) || visited[sel.name] {
+				continue
+			}
+			visited[sel.name] = true
+			if frag := e.doc.fragments[sel.name]; frag.typeCondition == object.Name {
+				e.collectFields(object, frag.selections, groups, visited)
+			}
+		case *inlineFragment:
+			if e.included(sel.directives) && (sel.typeCondition == "" || sel.typeCondition == object.Name) {
+				e.collectFields(object, sel.selections, groups, visited)
+			}
+		}
+	}
+	return groups
+}
+
+// included applies @skip(if:) and @include(if:)
+func (e *executor) included(directives []*directive) bool {
+	for _, d := range directives {
+		condition, _ := e.resolveVariables(d.arguments["if"]).(bool)
+		if d.name == "skip" && condition || d.name == "include" && !condition {
+			return false
+		}
+	}
+	return true
+}
+
+// executeField resolves a field and its selections. nullified reports that the field is null
+// because of an error and its non-null type makes the parent null as well.
+func (e *executor) executeField(ctx context.Context, object *Object, source map[string]interface{}, fields []*field, path []interface{}) (value interface{}, nullified bool) {
+	f := fields[0]
+	if f.name == "__typename" {
+		return object.Name, false
+	}
+	def := object.Fields[f.name]
+	_, nonNull := def.Type.(*NonNull)
+
+	args, err := e.coerceArguments(def.Args, f.arguments)
+	if err != nil {
+		e.addError(err, f, path)
+		return nil, nonNull
+	}
+
+	if def.Resolve != nil {
+		value, err = e.resolve(ctx, def.Resolve, ResolveParams{Context: ctx, Source: source, Args: args})
+		if err != nil {
+			e.addError(err, f, path)
+			return nil, nonNull
+		}
+	} else {
+		value = source[f.name]
+	}
+
+	var selections []selection
+	for _, merged := range fields {
+		selections = append(selections, merged.selections...)
+	}
+	return e.completeValue(ctx, def.Type, f, selections, value, path)
+}
+
+func (e *executor) resolve(ctx context.Context, resolve ResolveFunc, params ResolveParams) (value interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal error: %v", r)
+		}
+	}()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return resolve(params)
+}
+
+func (e *executor) coerceArguments(defs Args, given map[string]interface{}) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(defs))
+	for name, def := range defs {
+		raw, ok := given[name]
+		if ref, isVariable := raw.(variableRef); ok && isVariable {
+			raw, ok = e.variables[string(ref)]
+		}
+		if !ok {
+			if def.Default != nil {
+				args[name] = def.Default
+			} else if _, required := def.Type.(*NonNull); required {
+				return nil, fmt.Errorf("Argument %q of required type %s was not provided", name, def.Type)
+			}
+			continue
+		}
+		value, err := coerceInput(def.Type, e.resolveVariables(raw))
+		if err != nil {
+			return nil, fmt.Errorf("Argument %q has invalid value: %v", name, err)
+		}
+		args[name] = value
+	}
+	return args, nil
+}
+
+// resolveVariables replaces variable references in a literal with their values
+func (e *executor) resolveVariables(value interface{}) interface{} {
+	switch v := value.(type) {
+	case variableRef:
+		return e.variables[string(v)]
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved[i] = e.resolveVariables(item)
+		}
+		return resolved
+	}
+	return value
+}
+
+// completeValue shapes a resolved value by its type. Non-null violations make the value's
+// nearest nullable parent null.
+func (e *executor) completeValue(ctx context.Context, t Type, f *field, selections []selection, value interface{}, path []interface{}) (interface{}, bool) {
+	if nonNull, ok := t.(*NonNull); ok {
+		completed, nullified := e.completeNullable(ctx, nonNull.OfType, f, selections, value, path)
+		if nullified {
+			return nil, true
+		}
+		if completed == nil {
+			e.addError(fmt.Errorf("Cannot return null for non-nullable field %s", f.name), f, path)
+			return nil, true
+		}
+		return completed, false
+	}
+
+	completed, nullified := e.completeNullable(ctx, t, f, selections, value, path)
+	if nullified {
+		return nil, false
+	}
+	return completed, false
+}
+
+func (e *executor) completeNullable(ctx context.Context, t Type, f *field, selections []selection, value interface{}, path []interface{}) (interface{}, bool) {
+	if list, ok := t.(*List); ok {
+		items, err := toList(value)
+		if err != nil {
+			e.addError(err, f, path)
+			return nil, true
+		}
+		if items == nil {
+			return nil, false
+		}
+		completed := make([]interface{}, len(items))
+		for i, item := range items {
+			v, nullified := e.completeValue(ctx, list.OfType, f, selections, item, append(path, i))
+			if nullified {
+				return nil, true
+			}
+			completed[i] = v
+		}
+		return completed, false
+	}
+
+	if isNil(value) {
+		return nil, false
+	}
+
+	switch t := t.(type) {
+	case *Scalar:
+		serialized, err := t.Serialize(normalizeScalar(value))
+		if err != nil {
+			e.addError(err, f, path)
+			return nil, true
+		}
+		return serialized, false
+	case *Object:
+		source, err := toObject(value)
+		if err != nil {
+			e.addError(err, f, path)
+			return nil, true
+		}
+		return e.executeFields(ctx, t, source, selections, path)
+	}
+	e.addError(fmt.Errorf("unsupported type %s", t), f, path)
+	return nil, true
+}
+
+func (e *executor) executeFields(ctx context.Context, object *Object, source map[string]interface{}, selections []selection, path []interface{}) (interface{}, bool) {
+	groups := e.collectFields(object, selections, &fieldGroups{}, make(map[string]bool))
+	values := make(orderedObject, 0, len(groups.keys))
+	for _, key := range groups.keys {
+		value, nullified := e.executeField(ctx, object, source, groups.fields[key], append(path, key))
+		if nullified {
+			return nil, true
+		}
+		values = append(values, orderedField{key: key, value: value})
+	}
+	return values, false
+}
+
+// toList reads a slice; nil slices are empty lists, since services return them for no results
+func toList(value interface{}) ([]interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if items, ok := value.([]interface{}); ok {
+		return items, nil
+	}
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected a list, got %T", value)
+	}
+	items := make([]interface{}, v.Len())
+	for i := range items {
+		items[i] = v.Index(i).Interface()
+	}
+	return items, nil
+}
+
+// toObject decodes a resolved value into the map its fields are read from
+func toObject(value interface{}) (map[string]interface{}, error) {
+	if object, ok := value.(map[string]interface{}); ok {
+		return object, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %T: %w", value, err)
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(encoded, &object); err != nil {
+		return nil, fmt.Errorf("expected an object, got %T", value)
+	}
+	return object, nil
+}
+
+// normalizeScalar converts named and sized basic types, e.g. a string enum or int32, to the
+// types the scalars serialize
+func normalizeScalar(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return value
+}
+
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// orderedObject is a result object; its fields are encoded in query order
+type orderedObject []orderedField
+
+type orderedField struct {
+	key   string
+	value interface{}
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		b.Write(key)
+		b.WriteByte(':')
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
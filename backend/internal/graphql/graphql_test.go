@@ -0,0 +1,226 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type testPod struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Restarts  int32             `json:"restarts"`
+	Labels    map[string]string `json:"labels"`
+}
+
+func testSchema(t *testing.T, metricsCalls *int32) *Schema {
+	t.Helper()
+	metrics := &Object{Name: "PodMetrics", Fields: Fields{
+		"cpu": {Type: Float},
+	}}
+	pod := &Object{Name: "Pod", Fields: Fields{
+		"name":      {Type: NewNonNull(String)},
+		"namespace": {Type: NewNonNull(String)},
+		"restarts":  {Type: Int},
+		"labels":    {Type: JSON},
+		"metrics": {Type: metrics, Resolve: func(p ResolveParams) (interface{}, error) {
+			atomic.AddInt32(metricsCalls, 1)
+			if p.Source["name"] == "broken" {
+				return nil, errors.New("metrics unavailable")
+			}
+			return map[string]interface{}{"cpu": 0.25}, nil
+		}},
+		"owner": {Type: NewNonNull(String), Resolve: func(p ResolveParams) (interface{}, error) {
+			return nil, nil
+		}},
+	}}
+	pods := []testPod{
+		{Name: "web", Namespace: "prod", Restarts: 2, Labels: map[string]string{"app": "web"}},
+		{Name: "broken", Namespace: "dev"},
+	}
+	query := &Object{Name: "Query", Fields: Fields{
+		"pods": {
+			Type: NewNonNull(NewList(NewNonNull(pod))),
+			Args: Args{"namespace": {Type: String}, "limit": {Type: Int, Default: 10}},
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				var result []testPod
+				for _, item := range pods {
+					if ns := p.String("namespace"); ns == "" || item.Namespace == ns {
+						result = append(result, item)
+					}
+				}
+				if limit := p.Int("limit", 10); len(result) > limit {
+					result = result[:limit]
+				}
+				return result, nil
+			},
+		},
+		"pod": {
+			Type: pod,
+			Args: Args{"name": {Type: NewNonNull(String)}},
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				for _, item := range pods {
+					if item.Name == p.String("name") {
+						return &item, nil
+					}
+				}
+				return nil, nil
+			},
+		},
+	}}
+	schema, err := NewSchema(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func execute(t *testing.T, schema *Schema, req Request) string {
+	t.Helper()
+	encoded, err := json.Marshal(schema.Execute(context.Background(), req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(encoded)
+}
+
+func TestExecuteSelectsFields(t *testing.T) {
+	var calls int32
+	schema := testSchema(t, &calls)
+
+	got := execute(t, schema, Request{Query: `{ pods(namespace: "prod") { name restarts labels } }`})
+	want := `{"data":{"pods":[{"name":"web","restarts":2,"labels":{"app":"web"}}]}}`
+	if got != want {
+		t.Fatalf("got %s\nwant %s", got, want)
+	}
+	if calls != 0 {
+		t.Fatalf("expected unselected resolvers not to run, got %d calls", calls)
+	}
+}
+
+func TestExecuteAliasesFragmentsAndVariables(t *testing.T) {
+	var calls int32
+	schema := testSchema(t, &calls)
+
+	got := execute(t, schema, Request{
+		Query: `
+			query View($ns: String, $withMetrics: Boolean!) {
+				prod: pods(namespace: $ns) { ...podFields metrics @include(if: $withMetrics) { cpu } }
+				one: pod(name: "web") { ... on Pod { __typename name } }
+			}
+			fragment podFields on Pod { name namespace }`,
+		Variables: map[string]interface{}{"ns": "prod", "withMetrics": true},
+	})
+	want := `{"data":{"prod":[{"name":"web","namespace":"prod","metrics":{"cpu":0.25}}],"one":{"__typename":"Pod","name":"web"}}}`
+	if got != want {
+		t.Fatalf("got %s\nwant %s", got, want)
+	}
+}
+
+func TestExecuteFieldErrors(t *testing.T) {
+	var calls int32
+	schema := testSchema(t, &calls)
+
+	// A failing nullable field is null with an error; the rest of the result is kept
+	got := execute(t, schema, Request{Query: `{ pods { name metrics { cpu } } }`})
+	want := `{"data":{"pods":[{"name":"web","metrics":{"cpu":0.25}},{"name":"broken","metrics":null}]},` +
+		`"errors":[{"message":"metrics unavailable","locations":[{"line":1,"column":15}],"path":["pods",1,"metrics"]}]}`
+	if got != want {
+		t.Fatalf("got %s\nwant %s", got, want)
+	}
+
+	// A null non-null field makes its nearest nullable parent null
+	got = execute(t, schema, Request{Query: `{ pod(name: "web") { name owner } }`})
+	if !strings.HasPrefix(got, `{"data":{"pod":null},"errors":[{"message":"Cannot return null for non-nullable field owner"`) {
+		t.Fatalf("unexpected result %s", got)
+	}
+}
+
+func TestExecuteRejectsInvalidQueries(t *testing.T) {
+	var calls int32
+	schema := testSchema(t, &calls)
+
+	tests := []struct {
+		query string
+		error string
+	}{
+		{`{ pods { name`, "Syntax Error: Unexpected end of query"},
+		{`{ pods { uid } }`, `Cannot query field "uid" on type "Pod"`},
+		{`{ pods }`, `Field "pods" of type "[Pod!]!" must have a selection of subfields`},
+		{`{ pod { name } }`, `Field "Query.pod" argument "name" of type "String!" is required, but it was not provided`},
+		{`{ pods(selector: "app") { name } }`, `Unknown argument "selector" on field "Query.pods"`},
+		{`{ pods(namespace: $ns) { name } }`, `Variable "$ns" is not defined`},
+		{`{ pods { ...missing } }`, `Unknown fragment "missing"`},
+		{`{ pods { ...a } } fragment a on Pod { ...a }`, `Cannot spread fragment "a" within itself`},
+		{`mutation { pods { name } }`, "mutation operations are not supported; use the REST API for changes"},
+	}
+	for _, tt := range tests {
+		resp := schema.Execute(context.Background(), Request{Query: tt.query})
+		if resp.Data != nil || len(resp.Errors) == 0 || resp.Errors[0].Message != tt.error {
+			t.Errorf("%s: expected error %q, got %+v", tt.query, tt.error, resp.Errors)
+		}
+	}
+}
+
+func TestExecuteLimitsDepth(t *testing.T) {
+	node := &Object{Name: "Node", Fields: Fields{"id": {Type: ID}}}
+	node.Fields["parent"] = &Field{Type: node}
+	schema, err := NewSchema(&Object{Name: "Query", Fields: Fields{
+		"node": {Type: node, Resolve: func(p ResolveParams) (interface{}, error) {
+			return map[string]interface{}{"id": "1"}, nil
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nested := func(parents int) string {
+		return "{ node { " + strings.Repeat("parent { ", parents) + "id" + strings.Repeat(" }", parents) + " } }"
+	}
+	if got := execute(t, schema, Request{Query: nested(maxDepth - 2)}); !strings.HasPrefix(got, `{"data":{"node":{"parent":null}}`) {
+		t.Fatalf("unexpected result %s", got)
+	}
+	resp := schema.Execute(context.Background(), Request{Query: nested(maxDepth)})
+	if len(resp.Errors) != 1 || !strings.HasPrefix(resp.Errors[0].Message, "Query is nested too deeply") {
+		t.Fatalf("expected a depth error, got %+v", resp.Errors)
+	}
+}
+
+func TestExecuteCoercesVariables(t *testing.T) {
+	var calls int32
+	schema := testSchema(t, &calls)
+
+	got := execute(t, schema, Request{
+		Query:     `query($limit: Int) { pods(limit: $limit) { name } }`,
+		Variables: map[string]interface{}{"limit": float64(1)},
+	})
+	if got != `{"data":{"pods":[{"name":"web"}]}}` {
+		t.Fatalf("unexpected result %s", got)
+	}
+
+	resp := schema.Execute(context.Background(), Request{
+		Query:     `query($limit: Int) { pods(limit: $limit) { name } }`,
+		Variables: map[string]interface{}{"limit": "one"},
+	})
+	if len(resp.Errors) != 1 || !strings.HasPrefix(resp.Errors[0].Message, `Variable "$limit" got invalid value`) {
+		t.Fatalf("expected a variable error, got %+v", resp.Errors)
+	}
+}
+
+func TestSDL(t *testing.T) {
+	var calls int32
+	sdl := testSchema(t, &calls).SDL()
+	for _, want := range []string{
+		"schema {\n  query: Query\n}",
+		"scalar JSON",
+		"  pods(limit: Int = 10, namespace: String): [Pod!]!",
+		"  pod(name: String!): Pod",
+	} {
+		if !strings.Contains(sdl, want) {
+			t.Errorf("expected SDL to contain %q:\n%s", want, sdl)
+		}
+	}
+}
@@ -0,0 +1,591 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Location is a position in the query text, reported with parse and validation errors
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string // query, mutation or subscription
+	name       string
+	variables  []*variableDefinition
+	selections []selection
+	loc        Location
+}
+
+type variableDefinition struct {
+	name         string
+	typ          *typeRef
+	defaultValue interface{}
+	hasDefault   bool
+	loc          Location
+}
+
+// typeRef is a type as written in a variable definition, e.g. [String!]!
+type typeRef struct {
+	name    string
+	elem    *typeRef // set for lists
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+type selection interface{}
+
+type field struct {
+	alias      string
+	name       string
+	arguments  map[string]interface{}
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+// responseKey is the name the field's value has in the result
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+	loc        Location
+}
+
+type inlineFragment struct {
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+	loc           Location
+}
+
+type fragment struct {
+	name          string
+	typeCondition string
+	selections    []selection
+	loc           Location
+}
+
+type directive struct {
+	name      string
+	arguments map[string]interface{}
+}
+
+// Literal values are parsed into Go values: strings, int64, float64, bool, nil, lists as
+// []interface{} and objects as map[string]interface{}. Variables and enum values keep their
+// own types so they can be told apart from strings.
+type (
+	variableRef string
+	enumValue   string
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	loc   Location
+}
+
+type parser struct {
+	src  string
+	pos  int
+	line int
+	col  int
+	tok  token
+}
+
+func parse(src string) (doc *document, err error) {
+	p := &parser{src: src, line: 1, col: 1}
+	defer func() {
+		if r := recover(); r != nil {
+			perr, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, perr
+		}
+	}()
+
+	p.next()
+	doc = &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek("{"):
+			doc.operations = append(doc.operations, &operation{kind: "query", loc: p.tok.loc, selections: p.parseSelectionSet()})
+		case p.tok.kind == tokenName && p.tok.value == "fragment":
+			frag := p.parseFragment()
+			if _, exists := doc.fragments[frag.name]; exists {
+				p.fail(frag.loc, "There can be only one fragment named %q", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+		case p.tok.kind == tokenName && (p.tok.value == "query" || p.tok.value == "mutation" || p.tok.value == "subscription"):
+			doc.operations = append(doc.operations, p.parseOperation())
+		default:
+			p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, &Error{Message: "Document contains no operations"}
+	}
+	return doc, nil
+}
+
+func (p *parser) parseOperation() *operation {
+	op := &operation{kind: p.tok.value, loc: p.tok.loc}
+	p.next()
+	if p.tok.kind == tokenName {
+		op.name = p.tok.value
+		p.next()
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			op.variables = append(op.variables, p.parseVariableDefinition())
+		}
+	}
+	p.parseDirectives()
+	op.selections = p.parseSelectionSet()
+	return op
+}
+
+func (p *parser) parseVariableDefinition() *variableDefinition {
+	def := &variableDefinition{loc: p.tok.loc}
+	p.expect("$")
+	def.name = p.parseName()
+	p.expect(":")
+	def.typ = p.parseType()
+	if p.skip("=") {
+		def.defaultValue = p.parseValue(true)
+		def.hasDefault = true
+	}
+	p.parseDirectives()
+	return def
+}
+
+func (p *parser) parseType() *typeRef {
+	var t *typeRef
+	if p.skip("[") {
+		t = &typeRef{elem: p.parseType()}
+		p.expect("]")
+	} else {
+		t = &typeRef{name: p.parseName()}
+	}
+	if p.skip("!") {
+		t.nonNull = true
+	}
+	return t
+}
+
+func (p *parser) parseFragment() *fragment {
+	frag := &fragment{loc: p.tok.loc}
+	p.next()
+	frag.name = p.parseName()
+	if frag.name == "on" {
+		p.fail(frag.loc, "Fragment can't be named \"on\"")
+	}
+	if p.tok.kind != tokenName || p.tok.value != "on" {
+		p.unexpected()
+	}
+	p.next()
+	frag.typeCondition = p.parseName()
+	p.parseDirectives()
+	frag.selections = p.parseSelectionSet()
+	return frag
+}
+
+func (p *parser) parseSelectionSet() []selection {
+	p.expect("{")
+	var selections []selection
+	for !p.skip("}") {
+		selections = append(selections, p.parseSelection())
+	}
+	if len(selections) == 0 {
+		p.fail(p.tok.loc, "Selection set can't be empty")
+	}
+	return selections
+}
+
+func (p *parser) parseSelection() selection {
+	loc := p.tok.loc
+	if p.skip("...") {
+		if p.tok.kind == tokenName && p.tok.value != "on" {
+			return &fragmentSpread{name: p.parseName(), directives: p.parseDirectives(), loc: loc}
+		}
+		inline := &inlineFragment{loc: loc}
+		if p.tok.kind == tokenName {
+			p.next()
+			inline.typeCondition = p.parseName()
+		}
+		inline.directives = p.parseDirectives()
+		inline.selections = p.parseSelectionSet()
+		return inline
+	}
+
+	f := &field{name: p.parseName(), loc: loc}
+	if p.skip(":") {
+		f.alias, f.name = f.name, p.parseName()
+	}
+	f.arguments = p.parseArguments()
+	f.directives = p.parseDirectives()
+	if p.peek("{") {
+		f.selections = p.parseSelectionSet()
+	}
+	return f
+}
+
+func (p *parser) parseArguments() map[string]interface{} {
+	if !p.skip("(") {
+		return nil
+	}
+	args := make(map[string]interface{})
+	for !p.skip(")") {
+		loc := p.tok.loc
+		name := p.parseName()
+		if _, exists := args[name]; exists {
+			p.fail(loc, "There can be only one argument named %q", name)
+		}
+		p.expect(":")
+		args[name] = p.parseValue(false)
+	}
+	return args
+}
+
+func (p *parser) parseDirectives() []*directive {
+	var directives []*directive
+	for p.skip("@") {
+		directives = append(directives, &directive{name: p.parseName(), arguments: p.parseArguments()})
+	}
+	return directives
+}
+
+// parseValue reads a literal; constant values, such as variable defaults, can't use variables
+func (p *parser) parseValue(constant bool) interface{} {
+	tok := p.tok
+	switch tok.kind {
+	case tokenPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				p.unexpected()
+			}
+			p.next()
+			return variableRef(p.parseName())
+		case "[":
+			p.next()
+			list := []interface{}{}
+			for !p.skip("]") {
+				list = append(list, p.parseValue(constant))
+			}
+			return list
+		case "{":
+			p.next()
+			object := map[string]interface{}{}
+			for !p.skip("}") {
+				name := p.parseName()
+				p.expect(":")
+				object[name] = p.parseValue(constant)
+			}
+			return object
+		}
+	case tokenInt:
+		p.next()
+		n, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			p.fail(tok.loc, "Invalid integer %s", tok.value)
+		}
+		return n
+	case tokenFloat:
+		p.next()
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			p.fail(tok.loc, "Invalid number %s", tok.value)
+		}
+		return f
+	case tokenString:
+		p.next()
+		return tok.value
+	case tokenName:
+		p.next()
+		switch tok.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return enumValue(tok.value)
+	}
+	p.unexpected()
+	return nil
+}
+
+func (p *parser) parseName() string {
+	if p.tok.kind != tokenName {
+		p.unexpected()
+	}
+	name := p.tok.value
+	p.next()
+	return name
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokenPunct && p.tok.value == punct
+}
+
+func (p *parser) skip(punct string) bool {
+	if p.peek(punct) {
+		p.next()
+		return true
+	}
+	if p.tok.kind == tokenEOF {
+		p.unexpected()
+	}
+	return false
+}
+
+func (p *parser) expect(punct string) {
+	if !p.peek(punct) {
+		p.unexpected()
+	}
+	p.next()
+}
+
+func (p *parser) unexpected() {
+	switch p.tok.kind {
+	case tokenEOF:
+		p.fail(p.tok.loc, "Syntax Error: Unexpected end of query")
+	case tokenString:
+		p.fail(p.tok.loc, "Syntax Error: Unexpected string %q", p.tok.value)
+	default:
+		p.fail(p.tok.loc, "Syntax Error: Unexpected %q", p.tok.value)
+	}
+}
+
+func (p *parser) fail(loc Location, format string, args ...interface{}) {
+	panic(&Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}})
+}
+
+// next reads the following token, skipping whitespace, commas and comments
+func (p *parser) next() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.advance(1)
+			}
+			continue
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' && c != ',' {
+			break
+		}
+		p.advance(1)
+	}
+
+	loc := Location{Line: p.line, Column: p.col}
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokenEOF, loc: loc}
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.advance(3)
+		p.tok = token{kind: tokenPunct, value: "...", loc: loc}
+	case strings.IndexByte("!$()[]{}:=@|&", c) >= 0:
+		p.advance(1)
+		p.tok = token{kind: tokenPunct, value: string(c), loc: loc}
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		start := p.pos
+		for p.pos < len(p.src) && isNameChar(p.src[p.pos]) {
+			p.advance(1)
+		}
+		p.tok = token{kind: tokenName, value: p.src[start:p.pos], loc: loc}
+	case c == '-' || c >= '0' && c <= '9':
+		p.tok = p.readNumber(loc)
+	case c == '"':
+		p.tok = token{kind: tokenString, value: p.readString(loc), loc: loc}
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		p.fail(loc, "Syntax Error: Unexpected character %q", r)
+	}
+}
+
+func (p *parser) readNumber(loc Location) token {
+	start := p.pos
+	kind := tokenInt
+	if p.src[p.pos] == '-' {
+		p.advance(1)
+	}
+	digits := func() {
+		n := p.pos
+		for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+			p.advance(1)
+		}
+		if p.pos == n {
+			p.fail(loc, "Syntax Error: Invalid number %s", p.src[start:p.pos])
+		}
+	}
+	digits()
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		kind = tokenFloat
+		p.advance(1)
+		digits()
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		kind = tokenFloat
+		p.advance(1)
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.advance(1)
+		}
+		digits()
+	}
+	if p.pos < len(p.src) && isNameChar(p.src[p.pos]) {
+		p.fail(loc, "Syntax Error: Invalid number %s", p.src[start:p.pos+1])
+	}
+	return token{kind: kind, value: p.src[start:p.pos], loc: loc}
+}
+
+func (p *parser) readString(loc Location) string {
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		p.advance(3)
+		end := strings.Index(p.src[p.pos:], `"""`)
+		if end < 0 {
+			p.fail(loc, "Syntax Error: Unterminated string")
+		}
+		value := p.src[p.pos : p.pos+end]
+		p.advance(end + 3)
+		return blockString(value)
+	}
+
+	p.advance(1)
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			p.fail(loc, "Syntax Error: Unterminated string")
+		}
+		c := p.src[p.pos]
+		switch c {
+		case '"':
+			p.advance(1)
+			return b.String()
+		case '\\':
+			if p.pos+1 >= len(p.src) {
+				p.fail(loc, "Syntax Error: Unterminated string")
+			}
+			escape := p.src[p.pos+1]
+			p.advance(2)
+			switch escape {
+			case '"', '\\', '/':
+				b.WriteByte(escape)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if p.pos+4 > len(p.src) {
+					p.fail(loc, "Syntax Error: Invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+				if err != nil {
+					p.fail(loc, "Syntax Error: Invalid unicode escape")
+				}
+				b.WriteRune(rune(code))
+				p.advance(4)
+			default:
+				p.fail(loc, "Syntax Error: Invalid escape \\%c", escape)
+			}
+		default:
+			b.WriteByte(c)
+			p.advance(1)
+		}
+	}
+}
+
+// blockString removes the common indentation and surrounding blank lines of a """ string
+func blockString(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p *parser) advance(n int) {
+	for i := 0; i < n && p.pos < len(p.src); i++ {
+		if p.src[p.pos] == '\n' {
+			p.line++
+			p.col = 1
+		} else {
+			p.col++
+		}
+		p.pos++
+	}
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,377 @@
+// Package graphql serves read-only GraphQL queries over the existing services.
+//
+// It implements the query subset of GraphQL: fields with aliases and arguments, variables,
+// fragments and the @include and @skip directives. Mutations stay on the REST API. Objects
+// are resolved as JSON: the value a resolver returns for an object type is encoded with
+// encoding/json, so fields are looked up by the json tags of the Go types and only fields
+// with their own resolver are computed, and only when selected.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is a Scalar, *Object, *List or *NonNull
+type Type interface {
+	String() string
+}
+
+// Scalar is a leaf type
+type Scalar struct {
+	Name        string
+	Description string
+	// Serialize converts a resolved value for the response
+	Serialize func(value interface{}) (interface{}, error)
+	// ParseValue converts an argument or variable value
+	ParseValue func(value interface{}) (interface{}, error)
+}
+
+func (s *Scalar) String() string { return s.Name }
+
+// Object is a type with fields
+type Object struct {
+	Name        string
+	Description string
+	Fields      Fields
+}
+
+func (o *Object) String() string { return o.Name }
+
+// Fields are an object's fields by name
+type Fields map[string]*Field
+
+// Field is one field of an object. Without Resolve its value is read from the parent object
+// under the field's name.
+type Field struct {
+	Type        Type
+	Description string
+	Args        Args
+	Resolve     ResolveFunc
+}
+
+// Args are a field's arguments by name
+type Args map[string]*Argument
+
+// Argument is one argument of a field
+type Argument struct {
+	Type        Type
+	Description string
+	Default     interface{}
+}
+
+// ResolveFunc computes a field's value
+type ResolveFunc func(p ResolveParams) (interface{}, error)
+
+// ResolveParams are passed to a field's resolver
+type ResolveParams struct {
+	Context context.Context
+	// Source is the parent object as decoded from JSON; empty for root fields
+	Source map[string]interface{}
+	// Args are the coerced arguments; optional arguments that were not given are absent
+	Args map[string]interface{}
+}
+
+// String returns a string argument, or "" when it wasn't given
+func (p ResolveParams) String(name string) string {
+	s, _ := p.Args[name].(string)
+	return s
+}
+
+// Int returns an integer argument, or def when it wasn't given
+func (p ResolveParams) Int(name string, def int) int {
+	if n, ok := p.Args[name].(int); ok {
+		return n
+	}
+	return def
+}
+
+// List is a list of another type
+type List struct {
+	OfType Type
+}
+
+// NewList wraps a type in a list
+func NewList(of Type) *List { return &List{OfType: of} }
+
+func (l *List) String() string { return "[" + l.OfType.String() + "]" }
+
+// NonNull marks a type as never null
+type NonNull struct {
+	OfType Type
+}
+
+// NewNonNull marks a type as never null
+func NewNonNull(of Type) *NonNull { return &NonNull{OfType: of} }
+
+func (n *NonNull) String() string { return n.OfType.String() + "!" }
+
+// Built-in scalars. JSON carries arbitrary values such as label maps.
+var (
+	String = &Scalar{
+		Name:       "String",
+		Serialize:  serializeString,
+		ParseValue: parseString,
+	}
+	ID = &Scalar{
+		Name:       "ID",
+		Serialize:  serializeString,
+		ParseValue: parseID,
+	}
+	Int = &Scalar{
+		Name:       "Int",
+		Serialize:  serializeInt,
+		ParseValue: parseInt,
+	}
+	Float = &Scalar{
+		Name:       "Float",
+		Serialize:  serializeFloat,
+		ParseValue: serializeFloat,
+	}
+	Boolean = &Scalar{
+		Name:       "Boolean",
+		Serialize:  parseBoolean,
+		ParseValue: parseBoolean,
+	}
+	JSON = &Scalar{
+		Name:        "JSON",
+		Description: "Any JSON value",
+		Serialize:   func(value interface{}) (interface{}, error) { return value, nil },
+		ParseValue:  func(value interface{}) (interface{}, error) { return value, nil },
+	}
+)
+
+func serializeString(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	return nil, fmt.Errorf("String cannot represent value: %v", value)
+}
+
+func parseString(value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("String cannot represent a non string value: %v", value)
+}
+
+func parseID(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		if v == math.Trunc(v) {
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+	}
+	return nil, fmt.Errorf("ID cannot represent value: %v", value)
+}
+
+func serializeInt(value interface{}) (interface{}, error) {
+	var n float64
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		n = float64(v)
+	case float64:
+		n = v
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return nil, fmt.Errorf("Int cannot represent non-integer value: %v", value)
+	}
+	if n != math.Trunc(n) || n > math.MaxInt32 || n < math.MinInt32 {
+		return nil, fmt.Errorf("Int cannot represent non 32-bit signed integer value: %v", value)
+	}
+	return int(n), nil
+}
+
+func parseInt(value interface{}) (interface{}, error) {
+	if _, ok := value.(bool); ok {
+		return nil, fmt.Errorf("Int cannot represent non-integer value: %v", value)
+	}
+	return serializeInt(value)
+}
+
+func serializeFloat(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	}
+	return nil, fmt.Errorf("Float cannot represent non numeric value: %v", value)
+}
+
+func parseBoolean(value interface{}) (interface{}, error) {
+	if b, ok := value.(bool); ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("Boolean cannot represent a non boolean value: %v", value)
+}
+
+// Schema is the set of types reachable from the query root
+type Schema struct {
+	Query *Object
+	types map[string]Type
+}
+
+// NewSchema checks the types reachable from the query root. Type names must be unique.
+func NewSchema(query *Object) (*Schema, error) {
+	s := &Schema{Query: query, types: make(map[string]Type)}
+	for _, scalar := range []*Scalar{String, ID, Int, Float, Boolean} {
+		s.types[scalar.Name] = scalar
+	}
+	if err := s.collect(query); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Schema) collect(t Type) error {
+	switch t := t.(type) {
+	case *List:
+		return s.collect(t.OfType)
+	case *NonNull:
+		return s.collect(t.OfType)
+	case nil:
+		return fmt.Errorf("type is nil")
+	}
+
+	name := t.String()
+	if existing, ok := s.types[name]; ok {
+		if existing != t {
+			return fmt.Errorf("schema has two types named %s", name)
+		}
+		return nil
+	}
+	s.types[name] = t
+
+	object, ok := t.(*Object)
+	if !ok {
+		return nil
+	}
+	for fieldName, f := range object.Fields {
+		if f.Type == nil {
+			return fmt.Errorf("%s.%s has no type", object.Name, fieldName)
+		}
+		if err := s.collect(f.Type); err != nil {
+			return fmt.Errorf("%s.%s: %w", object.Name, fieldName, err)
+		}
+		for argName, arg := range f.Args {
+			if _, isScalar := namedType(arg.Type).(*Scalar); !isScalar {
+				return fmt.Errorf("%s.%s(%s): arguments must be scalars", object.Name, fieldName, argName)
+			}
+			if err := s.collect(arg.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SDL describes the schema in the GraphQL schema language
+func (s *Schema) SDL() string {
+	names := make([]string, 0, len(s.types))
+	for name := range s.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	writeDescription := func(indent, description string) {
+		if description != "" {
+			fmt.Fprintf(&b, "%s\"\"\"%s\"\"\"\n", indent, description)
+		}
+	}
+
+	writeDescription("", s.Query.Description)
+	fmt.Fprintf(&b, "schema {\n  query: %s\n}\n", s.Query.Name)
+	for _, name := range names {
+		switch t := s.types[name].(type) {
+		case *Scalar:
+			if t == String || t == ID || t == Int || t == Float || t == Boolean {
+				continue
+			}
+			b.WriteString("\n")
+			writeDescription("", t.Description)
+			fmt.Fprintf(&b, "scalar %s\n", t.Name)
+		case *Object:
+			b.WriteString("\n")
+			writeDescription("", t.Description)
+			fmt.Fprintf(&b, "type %s {\n", t.Name)
+			fieldNames := make([]string, 0, len(t.Fields))
+			for fieldName := range t.Fields {
+				fieldNames = append(fieldNames, fieldName)
+			}
+			sort.Strings(fieldNames)
+			for _, fieldName := range fieldNames {
+				f := t.Fields[fieldName]
+				writeDescription("  ", f.Description)
+				fmt.Fprintf(&b, "  %s%s: %s\n", fieldName, formatArgs(f.Args), f.Type)
+			}
+			b.WriteString("}\n")
+		}
+	}
+	return b.String()
+}
+
+func formatArgs(args Args) string {
+	if len(args) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		arg := args[name]
+		part := name + ": " + arg.Type.String()
+		if arg.Default != nil {
+			encoded, _ := json.Marshal(arg.Default)
+			part += " = " + string(encoded)
+		}
+		parts = append(parts, part)
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// namedType strips list and non-null wrappers
+func namedType(t Type) Type {
+	for {
+		switch wrapped := t.(type) {
+		case *List:
+			t = wrapped.OfType
+		case *NonNull:
+			t = wrapped.OfType
+		default:
+			return t
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package graphql
+
+import "fmt"
+
+// maxDepth limits how deeply selections may nest, so a query can't fan out without bound
+const maxDepth = 10
+
+type validator struct {
+	schema    *Schema
+	doc       *document
+	variables map[string]bool
+	errors    []*Error
+	tooDeep   bool
+}
+
+// validate checks an operation's selections against the schema before anything is resolved
+func (s *Schema) validate(doc *document, op *operation) []*Error {
+	v := &validator{schema: s, doc: doc, variables: make(map[string]bool)}
+	for _, def := range op.variables {
+		if v.variables[def.name] {
+			v.fail(def.loc, "There can be only one variable named \"$%s\"", def.name)
+		}
+		v.variables[def.name] = true
+	}
+	v.visit(s.Query, op.selections, 1, nil)
+	return v.errors
+}
+
+func (v *validator) fail(loc Location, format string, args ...interface{}) {
+	v.errors = append(v.errors, &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}})
+}
+
+func (v *validator) visit(object *Object, selections []selection, depth int, spreads []string) {
+	if depth > maxDepth {
+		if !v.tooDeep {
+			v.tooDeep = true
+			v.errors = append(v.errors, &Error{Message: fmt.Sprintf("Query is nested too deeply; the maximum depth is %d", maxDepth)})
+		}
+		return
+	}
+
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			v.visitDirectives(sel.directives, sel.loc)
+			v.visitField(object, sel, depth, spreads)
+
+		case *fragmentSpread:
+			v.visitDirectives(sel.directives, sel.loc)
+			frag, ok := v.doc.fragments[sel.name]
+			if !ok {
+				v.fail(sel.loc, "Unknown fragment %q", sel.name)
+				continue
+			}
+			if contains(spreads, sel.name) {
+				v.fail(sel.loc, "Cannot spread fragment %q within itself", sel.name)
+				continue
+			}
+			if !v.matches(frag.typeCondition, object, frag.loc) {
+				continue
+			}
+			v.visit(object, frag.selections, depth, append(spreads, sel.name))
+
+		case *inlineFragment:
+			v.visitDirectives(sel.directives, sel.loc)
+			if sel.typeCondition != "" && !v.matches(sel.typeCondition, object, sel.loc) {
+				continue
+			}
+			v.visit(object, sel.selections, depth, spreads)
+		}
+	}
+}
+
+func (v *validator) visitField(object *Object, f *field, depth int, spreads []string) {
+	if f.name == "__typename" {
+		if len(f.selections) > 0 {
+			v.fail(f.loc, "Field \"__typename\" must not have a selection since type \"String!\" has no subfields")
+		}
+		return
+	}
+
+	def, ok := object.Fields[f.name]
+	if !ok {
+		v.fail(f.loc, "Cannot query field %q on type %q", f.name, object.Name)
+		return
+	}
+
+	for name, value := range f.arguments {
+		if _, ok := def.Args[name]; !ok {
+			v.fail(f.loc, "Unknown argument %q on field \"%s.%s\"", name, object.Name, f.name)
+			continue
+		}
+		v.visitValue(value, f.loc)
+	}
+	for name, arg := range def.Args {
+		if _, required := arg.Type.(*NonNull); required && arg.Default == nil {
+			if _, given := f.arguments[name]; !given {
+				v.fail(f.loc, "Field \"%s.%s\" argument %q of type %q is required, but it was not provided", object.Name, f.name, name, arg.Type)
+			}
+		}
+	}
+
+	if child, ok := namedType(def.Type).(*Object); ok {
+		if len(f.selections) == 0 {
+			v.fail(f.loc, "Field %q of type %q must have a selection of subfields", f.name, def.Type)
+			return
+		}
+		v.visit(child, f.selections, depth+1, spreads)
+	} else if len(f.selections) > 0 {
+		v.fail(f.loc, "Field %q must not have a selection since type %q has no subfields", f.name, def.Type)
+	}
+}
+
+// visitDirectives allows @skip and @include with their if argument
+func (v *validator) visitDirectives(directives []*directive, loc Location) {
+	for _, d := range directives {
+		if d.name != "skip" && d.name != "include" {
+			v.fail(loc, "Unknown directive \"@%s\"", d.name)
+			continue
+		}
+		condition, ok := d.arguments["if"]
+		if !ok {
+			v.fail(loc, "Directive \"@%s\" argument \"if\" of type \"Boolean!\" is required, but it was not provided", d.name)
+			continue
+		}
+		if _, isVariable := condition.(variableRef); !isVariable {
+			if _, isBool := condition.(bool); !isBool {
+				v.fail(loc, "Directive \"@%s\" argument \"if\" must be a Boolean", d.name)
+			}
+		}
+		v.visitValue(condition, loc)
+	}
+}
+
+// visitValue checks that the variables a value uses are defined
+func (v *validator) visitValue(value interface{}, loc Location) {
+	switch value := value.(type) {
+	case variableRef:
+		if !v.variables[string(value)] {
+			v.fail(loc, "Variable \"$%s\" is not defined", value)
+		}
+	case []interface{}:
+		for _, item := range value {
+			v.visitValue(item, loc)
+		}
+	case map[string]interface{}:
+		for _, item := range value {
+			v.visitValue(item, loc)
+		}
+	}
+}
+
+// matches checks a fragment's type condition; without interfaces or unions a fragment
+// applies only to the object type it names
+func (v *validator) matches(typeCondition string, object *Object, loc Location) bool {
+	if _, ok := v.schema.types[typeCondition].(*Object); !ok {
+		v.fail(loc, "Unknown type %q", typeCondition)
+		return false
+	}
+	if typeCondition != object.Name {
+		v.fail(loc, "Fragment cannot be spread here as objects of type %q can never be of type %q", object.Name, typeCondition)
+		return false
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,388 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/archellir/denshimon/internal/alerts"
+	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/internal/bus"
+	"github.com/archellir/denshimon/internal/database"
+	"github.com/archellir/denshimon/internal/gitops"
+	"github.com/archellir/denshimon/internal/providers/backup"
+	"github.com/archellir/denshimon/internal/providers/certificates"
+	"github.com/archellir/denshimon/internal/providers/databases"
+	"github.com/archellir/denshimon/internal/websocket"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+// AlertHandlers serves the alerts of all sources with their silences and escalation rules
+type AlertHandlers struct {
+	manager *alerts.Manager
+}
+
+func NewAlertHandlers(manager *alerts.Manager) *AlertHandlers {
+	return &AlertHandlers{manager: manager}
+}
+
+// ListAlerts handles GET /api/alerts?source=&severity=&status=&include_silenced=true&limit=,
+// the one inbox of the deduplicated alerts of gitops, infrastructure, certificates and backups
+func (h *AlertHandlers) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := alerts.ListOptions{
+		Source:          query.Get("source"),
+		Severity:        query.Get("severity"),
+		Status:          query.Get("status"),
+		IncludeSilenced: query.Get("include_silenced") == "true",
+	}
+	switch opts.Status {
+	case "", alerts.StatusActive, alerts.StatusAcknowledged, alerts.StatusSilenced, alerts.StatusResolved:
+	default:
+		response.SendError(w, http.StatusBadRequest, "status must be active, acknowledged, silenced or resolved")
+		return
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			response.SendError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		opts.Limit = limit
+	}
+
+	list, err := h.manager.List(r.Context(), opts)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to list alerts")
+		return
+	}
+	response.SendSuccess(w, list)
+}
+
+// AcknowledgeAlert handles POST /api/alerts/{source}/{id}/acknowledge, acknowledging the
+// alert in the subsystem that raised it
+func (h *AlertHandlers) AcknowledgeAlert(w http.ResponseWriter, r *http.Request) {
+	if err := h.manager.Acknowledge(r.Context(), r.PathValue("source"), r.PathValue("id")); err != nil {
+		sendAlertError(w, err)
+		return
+	}
+	response.SendSuccessWithMessage(w, "Alert acknowledged")
+}
+
+// createSilenceRequest is a silence that expires at a time or after a duration
+type createSilenceRequest struct {
+	alerts.Matchers
+	Comment   string     `json:"comment"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Duration  string     `json:"duration,omitempty"` // e.g. 2h or 1d
+}
+
+// ListSilences handles GET /api/alerts/silences?expired=true
+func (h *AlertHandlers) ListSilences(w http.ResponseWriter, r *http.Request) {
+	silences, err := h.manager.ListSilences(r.Context(), r.URL.Query().Get("expired") == "true")
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to list silences")
+		return
+	}
+	response.SendSuccess(w, silences)
+}
+
+// CreateSilence handles POST /api/alerts/silences
+func (h *AlertHandlers) CreateSilence(w http.ResponseWriter, r *http.Request) {
+	var req createSilenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	silence := alerts.Silence{Matchers: req.Matchers, Comment: req.Comment}
+	switch {
+	case req.ExpiresAt != nil:
+		silence.ExpiresAt = *req.ExpiresAt
+	case req.Duration != "":
+		d, err := parseTimeRange(req.Duration)
+		if err != nil {
+			response.SendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		silence.ExpiresAt = time.Now().Add(d)
+	default:
+		response.SendError(w, http.StatusBadRequest, "expires_at or duration is required")
+		return
+	}
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		silence.CreatedBy = claims.Username
+	}
+
+	created, err := h.manager.CreateSilence(r.Context(), silence)
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.SendSuccess(w, created)
+}
+
+// ExpireSilence handles DELETE /api/alerts/silences/{id}; the silence ends now but is kept
+func (h *AlertHandlers) ExpireSilence(w http.ResponseWriter, r *http.Request) {
+	if err := h.manager.ExpireSilence(r.Context(), r.PathValue("id")); err != nil {
+		sendAlertError(w, err)
+		return
+	}
+	response.SendSuccessWithMessage(w, "Silence expired")
+}
+
+// ListEscalationRules handles GET /api/alerts/escalations
+func (h *AlertHandlers) ListEscalationRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.manager.ListRules(r.Context())
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to list escalation rules")
+		return
+	}
+	response.SendSuccess(w, rules)
+}
+
+// CreateEscalationRule handles POST /api/alerts/escalations
+func (h *AlertHandlers) CreateEscalationRule(w http.ResponseWriter, r *http.Request) {
+	var rule alerts.EscalationRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	rule.CreatedBy = ""
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		rule.CreatedBy = claims.Username
+	}
+
+	created, err := h.manager.CreateRule(r.Context(), rule)
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.SendSuccess(w, created)
+}
+
+// DeleteEscalationRule handles DELETE /api/alerts/escalations/{id}
+func (h *AlertHandlers) DeleteEscalationRule(w http.ResponseWriter, r *http.Request) {
+	if err := h.manager.DeleteRule(r.Context(), r.PathValue("id")); err != nil {
+		sendAlertError(w, err)
+		return
+	}
+	response.SendSuccessWithMessage(w, "Escalation rule deleted")
+}
+
+func sendAlertError(w http.ResponseWriter, err error) {
+	if errors.Is(err, alerts.ErrNotFound) {
+		response.SendError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	response.SendError(w, http.StatusInternalServerError, err.Error())
+}
+
+// initAlertManager merges the alerts of gitops, infrastructure, certificates, backups and
+// scheduled database queries into one inbox, syncing it and evaluating escalation rules every ALERT_ESCALATION_INTERVAL
+// (default 1m). The subsystems keep raising alerts as before.
+func initAlertManager(
+	db *database.SQLiteDB,
+	hub *websocket.Hub,
+	gitopsService *gitops.Service,
+	infrastructureHandlers *InfrastructureHandlers,
+	certificateManager *certificates.Manager,
+	backupManager *backup.Manager,
+	databaseManager *databases.Manager,
+	events *bus.Bus,
+) *alerts.Manager {
+	store, err := alerts.NewStore(db.DB)
+	if err != nil {
+		slog.Warn("Alert silences and escalation disabled", "error", err)
+		return nil
+	}
+	manager := alerts.NewManager(store)
+	manager.SetHub(hub)
+	manager.SetBus(events) // fired and resolved alerts
+
+	if gitopsService != nil {
+		manager.AddSource("gitops", alerts.Source{
+			List: func(ctx context.Context) ([]alerts.Alert, error) {
+				list, err := gitopsService.ListAlerts(ctx)
+				if err != nil {
+					return nil, err
+				}
+				result := make([]alerts.Alert, 0, len(list))
+				for _, alert := range list {
+					result = append(result, gitopsAlert(alert))
+				}
+				return result, nil
+			},
+			Acknowledge: gitopsService.AcknowledgeAlert,
+		})
+	}
+	manager.AddSource("infrastructure", alerts.Source{
+		List: func(context.Context) ([]alerts.Alert, error) {
+			list := infrastructureHandlers.ListAlerts()
+			result := make([]alerts.Alert, 0, len(list))
+			for _, alert := range list {
+				result = append(result, infrastructureAlert(alert))
+			}
+			return result, nil
+		},
+		Acknowledge: func(_ context.Context, id string) error {
+			if !infrastructureHandlers.Acknowledge(id) {
+				return alerts.ErrNotFound
+			}
+			return nil
+		},
+	})
+	manager.AddSource("certificates", alerts.Source{
+		// Acknowledged alerts are listed too, or they would count as resolved
+		List: func(context.Context) ([]alerts.Alert, error) {
+			list := certificateManager.ListAlerts()
+			result := make([]alerts.Alert, 0, len(list))
+			for _, alert := range list {
+				result = append(result, alerts.Alert{
+					ID:           alert.ID,
+					Type:         alert.Type,
+					Severity:     alert.Severity,
+					Title:        alert.Message,
+					Message:      alert.Message,
+					Labels:       map[string]string{"domain": alert.Domain},
+					Acknowledged: alert.Acknowledged,
+					Timestamp:    alert.Timestamp,
+				})
+			}
+			return result, nil
+		},
+		Acknowledge: func(_ context.Context, id string) error {
+			if err := certificateManager.AcknowledgeAlert(id); err != nil {
+				return fmt.Errorf("%w: %v", alerts.ErrNotFound, err)
+			}
+			return nil
+		},
+	})
+	manager.AddSource("backups", alerts.Source{
+		List: func(context.Context) ([]alerts.Alert, error) {
+			list, err := backupManager.GetAlerts()
+			if err != nil {
+				return nil, err
+			}
+			result := make([]alerts.Alert, 0, len(list))
+			for _, alert := range list {
+				converted := alerts.Alert{
+					ID:           alert.ID,
+					Type:         string(alert.Type),
+					Severity:     string(alert.Severity),
+					Title:        alert.Message,
+					Message:      alert.Message,
+					Acknowledged: alert.Acknowledged,
+					Timestamp:    alert.Timestamp,
+				}
+				if alert.JobID != "" {
+					converted.Labels = map[string]string{"job_id": alert.JobID}
+				}
+				result = append(result, converted)
+			}
+			return result, nil
+		},
+		Acknowledge: func(_ context.Context, id string) error {
+			err := backupManager.AcknowledgeAlert(id)
+			if errors.Is(err, sql.ErrNoRows) {
+				return alerts.ErrNotFound
+			}
+			return err
+		},
+	})
+
+	manager.AddSource("databases", alerts.Source{
+		// A failed job stays in the inbox until a later run succeeds
+		List: func(ctx context.Context) ([]alerts.Alert, error) {
+			jobs, err := databaseManager.FailedJobs(ctx)
+			if err != nil {
+				return nil, err
+			}
+			result := make([]alerts.Alert, 0, len(jobs))
+			for _, job := range jobs {
+				run := job.LastRun
+				result = append(result, alerts.Alert{
+					ID:           run.ID,
+					Type:         "query_job_failed",
+					Severity:     alerts.SeverityCritical,
+					Title:        "Scheduled query " + job.Name + " failed",
+					Message:      run.Error,
+					Labels:       map[string]string{"job_id": job.ID, "connection_id": job.ConnectionID},
+					Acknowledged: run.Acknowledged,
+					Timestamp:    run.StartedAt,
+					DedupKey:     "databases/query_job_failed/" + job.ID,
+				})
+			}
+			return result, nil
+		},
+		Acknowledge: func(ctx context.Context, id string) error {
+			err := databaseManager.AcknowledgeJobRun(ctx, id)
+			if errors.Is(err, databases.ErrJobNotFound) {
+				return alerts.ErrNotFound
+			}
+			return err
+		},
+	})
+
+	interval := time.Minute
+	if value := os.Getenv("ALERT_ESCALATION_INTERVAL"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			interval = d
+		} else {
+			slog.Warn("Ignoring invalid ALERT_ESCALATION_INTERVAL", "value", value)
+		}
+	}
+	manager.Start(context.Background(), interval)
+	return manager
+}
+
+// gitopsAlert converts a gitops alert. The health check raises a new alert on every run,
+// so repeats are keyed by the repository or application rather than by the metadata,
+// which carries changing details such as errors and sync times.
+func gitopsAlert(alert gitops.Alert) alerts.Alert {
+	dedupKey := ""
+	for _, label := range []string{"repository", "application"} {
+		if value := alert.Metadata[label]; value != "" {
+			dedupKey = "gitops/" + alert.Type + "/" + label + "=" + value
+			break
+		}
+	}
+	if dedupKey == "" {
+		dedupKey = "gitops/" + alert.Type + "/" + alert.Title
+	}
+	return alerts.Alert{
+		ID:           alert.ID,
+		Type:         alert.Type,
+		Severity:     alert.Severity,
+		Title:        alert.Title,
+		Message:      alert.Message,
+		Labels:       alert.Metadata,
+		Acknowledged: alert.Status == "acknowledged",
+		Timestamp:    alert.CreatedAt,
+		DedupKey:     dedupKey,
+	}
+}
+
+// infrastructureAlert converts an infrastructure alert, whose ID is already stable per
+// condition (e.g. monitor-<id>)
+func infrastructureAlert(alert InfrastructureAlert) alerts.Alert {
+	return alerts.Alert{
+		ID:           alert.ID,
+		Source:       "infrastructure",
+		Type:         alert.Type,
+		Severity:     alert.Severity,
+		Title:        alert.Message,
+		Message:      alert.Message,
+		Labels:       map[string]string{"origin": alert.Source},
+		Acknowledged: alert.Acknowledged,
+		Timestamp:    alert.Timestamp,
+		DedupKey:     "infrastructure/" + alert.ID,
+	}
+}
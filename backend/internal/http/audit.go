@@ -0,0 +1,52 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/archellir/denshimon/internal/audit"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+type AuditHandlers struct {
+	store *audit.Store
+}
+
+func NewAuditHandlers(store *audit.Store) *AuditHandlers {
+	return &AuditHandlers{store: store}
+}
+
+// ListAuditEntries handles GET /api/audit?action=&resource=&user=&since=7d&limit=,
+// newest first. action matches as a prefix, e.g. registry.retention.
+func (h *AuditHandlers) ListAuditEntries(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := audit.Query{
+		Action:   params.Get("action"),
+		Resource: params.Get("resource"),
+		User:     params.Get("user"),
+	}
+	if value := params.Get("since"); value != "" {
+		since, err := parseTimeRange(value)
+		if err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid since: "+value)
+			return
+		}
+		query.Since = time.Now().Add(-since)
+	}
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			response.SendError(w, http.StatusBadRequest, "Invalid limit: "+value)
+			return
+		}
+		query.Limit = limit
+	}
+
+	entries, err := h.store.List(r.Context(), query)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to list audit entries: "+err.Error())
+		return
+	}
+	response.SendSuccess(w, entries)
+}
@@ -2,39 +2,44 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/archellir/denshimon/internal/auth"
 	"github.com/archellir/denshimon/internal/database"
+	"github.com/archellir/denshimon/internal/ratelimit"
+	"github.com/archellir/denshimon/pkg/apiclient"
 )
 
 type AuthHandlers struct {
 	authService *auth.Service
 	db          *database.SQLiteDB
+	lockout     *ratelimit.Lockout
 }
 
-type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-}
+// The auth wire types are shared with API clients
+type (
+	LoginRequest          = apiclient.LoginRequest
+	LoginResponse         = apiclient.LoginResponse
+	UserInfo              = apiclient.UserInfo
+	CreateAPITokenRequest = apiclient.CreateAPITokenRequest
+)
 
-type LoginResponse struct {
+type RefreshResponse struct {
 	Token     string    `json:"token"`
 	ExpiresAt time.Time `json:"expires_at"`
-	User      UserInfo  `json:"user"`
 }
 
-type UserInfo struct {
-	ID       string   `json:"id"`
-	Username string   `json:"username"`
-	Role     string   `json:"role"`
-	Scopes   []string `json:"scopes"`
-}
-
-type RefreshResponse struct {
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
+type CreateAPITokenResponse struct {
+	Token    string         `json:"token"` // shown only once
+	APIToken *auth.APIToken `json:"api_token"`
 }
 
 type CreateUserRequest struct {
@@ -56,6 +61,12 @@ func NewAuthHandlers(authService *auth.Service, db *database.SQLiteDB) *AuthHand
 	}
 }
 
+// SetLoginLockout refuses logins with exponential backoff after repeated failures
+// for the same username or client IP
+func (h *AuthHandlers) SetLoginLockout(lockout *ratelimit.Lockout) {
+	h.lockout = lockout
+}
+
 func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -68,27 +79,41 @@ func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lockoutKeys := []string{"user:" + req.Username, "ip:" + clientIP(r)}
+	if h.lockout != nil {
+		for _, key := range lockoutKeys {
+			if wait := h.lockout.Locked(key); wait > 0 {
+				sendTooManyRequests(w, wait)
+				return
+			}
+		}
+	}
+
 	// Authenticate user using the auth service
 	user, err := h.authService.AuthenticateUser(req.Username, req.Password)
 	if err != nil {
+		if h.lockout != nil {
+			for _, key := range lockoutKeys {
+				h.lockout.Failure(key)
+			}
+		}
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
+	if h.lockout != nil {
+		for _, key := range lockoutKeys {
+			h.lockout.Success(key)
+		}
+	}
 
-	// Generate token
+	// Start a session and issue its token
 	duration := 24 * time.Hour
-	token, err := h.authService.GenerateToken(user, duration)
+	token, _, err := h.authService.CreateSession(user, duration, r.UserAgent(), clientIP(r))
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
-	// Store session in database
-	sessionID := h.authService.GenerateSessionID()
-	if err := h.db.SetSession(sessionID, user.ID, duration); err != nil {
-		// Log error but don't fail the login
-	}
-
 	response := LoginResponse{
 		Token:     token,
 		ExpiresAt: time.Now().Add(duration),
@@ -112,7 +137,15 @@ func (h *AuthHandlers) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token := authHeader[len("Bearer "):]
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+
+	// End the token's session, if it still has one
+	if claims, err := h.authService.ValidateToken(token); err == nil && claims.SessionID != "" {
+		if err := h.authService.RevokeSession(claims.SessionID); err != nil && err != auth.ErrSessionsDisabled {
+			http.Error(w, "Failed to logout", http.StatusInternalServerError)
+			return
+		}
+	}
 
 	// Revoke token (add to blacklist)
 	if err := h.authService.RevokeToken(token); err != nil {
@@ -132,17 +165,9 @@ func (h *AuthHandlers) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create new user object from claims
-	user := &auth.User{
-		ID:       claims.UserID,
-		Username: claims.Username,
-		Role:     claims.Role,
-		Scopes:   claims.Scopes,
-	}
-
-	// Generate new token
+	// Generate new token for the same session
 	duration := 24 * time.Hour
-	token, err := h.authService.GenerateToken(user, duration)
+	token, err := h.authService.RenewSession(claims, duration)
 	if err != nil {
 		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
 		return
@@ -306,3 +331,271 @@ func (h *AuthHandlers) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "User deleted successfully"})
 }
+
+// Session management endpoints
+
+// GET /api/auth/sessions - List the current user's active sessions
+func (h *AuthHandlers) ListSessions(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(claims.UserID, claims.SessionID)
+	if err != nil {
+		if err == auth.ErrSessionsDisabled {
+			http.Error(w, "Session management disabled", http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, "Failed to list sessions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if sessions == nil {
+		sessions = []*auth.Session{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// DELETE /api/auth/sessions/{id} - Revoke a session (own sessions, or any for admins)
+func (h *AuthHandlers) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID := r.PathValue("id")
+	session, err := h.authService.GetSession(sessionID)
+	if err != nil {
+		if err == auth.ErrSessionsDisabled {
+			http.Error(w, "Session management disabled", http.StatusServiceUnavailable)
+			return
+		}
+		if err == auth.ErrSessionNotFound {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get session: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Other users' sessions are reported as missing rather than forbidden
+	if session.UserID != claims.UserID && claims.Role != "admin" {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.authService.RevokeSession(sessionID); err != nil {
+		http.Error(w, "Failed to revoke session: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Session revoked successfully"})
+}
+
+// API token endpoints
+
+// POST /api/auth/tokens - Create an API token for the current user
+func (h *AuthHandlers) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	var ttl time.Duration
+	if req.ExpiresIn != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.ExpiresIn); err != nil || ttl <= 0 {
+			http.Error(w, "Invalid expires_in duration", http.StatusBadRequest)
+			return
+		}
+	}
+
+	token, apiToken, err := h.authService.CreateAPIToken(claims.UserID, req.Name, req.Scopes, ttl)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidScope) {
+			http.Error(w, err.Error()+" (valid scopes: "+strings.Join(auth.ScopeNames(), ", ")+")", http.StatusBadRequest)
+			return
+		}
+		if err == auth.ErrAPITokensDisabled {
+			http.Error(w, "API tokens disabled", http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, "Failed to create API token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateAPITokenResponse{Token: token, APIToken: apiToken})
+}
+
+// GET /api/auth/tokens - List the current user's API tokens
+func (h *AuthHandlers) ListAPITokens(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.authService.ListAPITokens(claims.UserID)
+	if err != nil {
+		if err == auth.ErrAPITokensDisabled {
+			http.Error(w, "API tokens disabled", http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, "Failed to list API tokens: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if tokens == nil {
+		tokens = []*auth.APIToken{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// DELETE /api/auth/tokens/{id} - Revoke an API token (own tokens, or any for admins)
+func (h *AuthHandlers) DeleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	tokenID := r.PathValue("id")
+	token, err := h.authService.GetAPIToken(tokenID)
+	if err != nil {
+		if err == auth.ErrAPITokensDisabled {
+			http.Error(w, "API tokens disabled", http.StatusServiceUnavailable)
+			return
+		}
+		if err == auth.ErrAPITokenNotFound {
+			http.Error(w, "API token not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get API token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if token.UserID != claims.UserID && claims.Role != "admin" {
+		http.Error(w, "API token not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.authService.RevokeAPIToken(tokenID); err != nil {
+		http.Error(w, "Failed to revoke API token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "API token revoked successfully"})
+}
+
+// GET /api/auth/keys - List token signing keys (admin only)
+func (h *AuthHandlers) ListSigningKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.authService.SigningKeys())
+}
+
+// POST /api/auth/keys/rotate - Force rotation of the token signing key (admin only)
+func (h *AuthHandlers) RotateSigningKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.authService.RotateKeys()
+	if err != nil {
+		http.Error(w, "Failed to rotate signing key: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(key)
+}
+
+// defaultTrustedProxies are the networks ingress controllers and load balancers usually
+// connect from: loopback and private ranges
+const defaultTrustedProxies = "127.0.0.0/8,::1/128,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,fc00::/7"
+
+// trustedProxies are the networks whose X-Forwarded-For is believed, from TRUSTED_PROXIES
+// (comma separated CIDRs or addresses)
+var trustedProxies = sync.OnceValue(func() []netip.Prefix {
+	value, ok := os.LookupEnv("TRUSTED_PROXIES")
+	if !ok {
+		value = defaultTrustedProxies
+	}
+	return parseTrustedProxies(value)
+})
+
+// parseTrustedProxies parses comma separated CIDRs and addresses, skipping invalid ones
+func parseTrustedProxies(value string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		} else {
+			slog.Warn("Ignoring invalid trusted proxy", "entry", entry)
+		}
+	}
+	return prefixes
+}
+
+// clientIP returns the originating client address, which keys the per-IP rate limits and
+// login lockouts
+func clientIP(r *http.Request) string {
+	return clientIPFrom(r, trustedProxies())
+}
+
+// clientIPFrom returns the request's peer address unless it is a trusted proxy. Behind
+// proxies, X-Forwarded-For is read from the right, skipping the trusted hops the proxies
+// appended, so entries a client adds itself are never used.
+func clientIPFrom(r *http.Request, proxies []netip.Prefix) string {
+	client := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		client = host
+	}
+	if !isTrustedProxy(client, proxies) {
+		return client
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break // not an address a proxy appended
+		}
+		client = hop
+		if !isTrustedProxy(hop, proxies) {
+			break
+		}
+	}
+	return client
+}
+
+func isTrustedProxy(ip string, proxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
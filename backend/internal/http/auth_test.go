@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies := parseTrustedProxies("10.0.0.0/8, 192.168.1.5, not-an-ip")
+	if len(proxies) != 2 {
+		t.Fatalf("proxies = %v, want the invalid entry skipped", proxies)
+	}
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{"direct", "203.0.113.7:5120", nil, "203.0.113.7"},
+		{"direct client forging the header", "203.0.113.7:5120", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"behind a proxy", "10.0.0.4:443", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"entries prepended by the client", "10.0.0.4:443", []string{"198.51.100.1, 198.51.100.2, 203.0.113.7"}, "203.0.113.7"},
+		{"chain of proxies", "10.0.0.4:443", []string{"198.51.100.1, 203.0.113.7, 192.168.1.5", "10.1.2.3"}, "203.0.113.7"},
+		{"garbage appended", "10.0.0.4:443", []string{"203.0.113.7, bogus"}, "10.0.0.4"},
+		{"proxy without the header", "10.0.0.4:443", nil, "10.0.0.4"},
+		{"only proxies", "10.0.0.4:443", []string{"10.9.9.9"}, "10.9.9.9"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/api/auth/login", nil)
+		r.RemoteAddr = tt.remote
+		for _, value := range tt.forwarded {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+		if got := clientIPFrom(r, proxies); got != tt.want {
+			t.Errorf("%s: clientIP = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
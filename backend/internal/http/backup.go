@@ -1,7 +1,9 @@
 package http
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -26,9 +28,13 @@ func (h *BackupHandlers) ListJobs(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	sanitized := make([]backup.Job, 0, len(jobs))
+	for _, job := range jobs {
+		sanitized = append(sanitized, job.Sanitized())
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: jobs})
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: sanitized})
 }
 
 // CreateJob handles POST /api/backup/jobs
@@ -41,13 +47,13 @@ func (h *BackupHandlers) CreateJob(w http.ResponseWriter, r *http.Request) {
 
 	createdJob, err := h.backupManager.CreateJob(&job)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), backupErrorStatus(err))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: createdJob})
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: createdJob.Sanitized()})
 }
 
 // GetJob handles GET /api/backup/jobs/{id}
@@ -65,7 +71,7 @@ func (h *BackupHandlers) GetJob(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: job})
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: job.Sanitized()})
 }
 
 // UpdateJob handles PUT /api/backup/jobs/{id}
@@ -85,12 +91,12 @@ func (h *BackupHandlers) UpdateJob(w http.ResponseWriter, r *http.Request) {
 	job.ID = id
 	updatedJob, err := h.backupManager.UpdateJob(&job)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), backupErrorStatus(err))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: updatedJob})
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: updatedJob.Sanitized()})
 }
 
 // DeleteJob handles DELETE /api/backup/jobs/{id}
@@ -120,7 +126,7 @@ func (h *BackupHandlers) RunJob(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.backupManager.RunJob(id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), backupErrorStatus(err))
 		return
 	}
 
@@ -146,6 +152,44 @@ func (h *BackupHandlers) CancelJob(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Message: "Job cancelled successfully"})
 }
 
+// PlanRetention handles GET /api/backup/jobs/{id}/retention
+func (h *BackupHandlers) PlanRetention(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/backup/jobs/")
+	id := strings.TrimSuffix(path, "/retention")
+	if id == "" {
+		http.Error(w, "Job ID is required", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.backupManager.PlanRetention(id)
+	if err != nil {
+		http.Error(w, err.Error(), backupErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: plan})
+}
+
+// PruneJob handles POST /api/backup/jobs/{id}/prune
+func (h *BackupHandlers) PruneJob(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/backup/jobs/")
+	id := strings.TrimSuffix(path, "/prune")
+	if id == "" {
+		http.Error(w, "Job ID is required", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.backupManager.PruneJob(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), backupErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: run})
+}
+
 // GetHistory handles GET /api/backup/history
 func (h *BackupHandlers) GetHistory(w http.ResponseWriter, r *http.Request) {
 	jobID := r.URL.Query().Get("jobId")
@@ -162,7 +206,7 @@ func (h *BackupHandlers) GetHistory(w http.ResponseWriter, r *http.Request) {
 
 // GetStorage handles GET /api/backup/storage
 func (h *BackupHandlers) GetStorage(w http.ResponseWriter, r *http.Request) {
-	storage, err := h.backupManager.GetStorage()
+	storage, err := h.backupManager.GetStorage(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -172,6 +216,55 @@ func (h *BackupHandlers) GetStorage(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: storage})
 }
 
+// ListStorageConfigs handles GET /api/backup/storage/configs
+func (h *BackupHandlers) ListStorageConfigs(w http.ResponseWriter, r *http.Request) {
+	configs := h.backupManager.ListStorageConfigs()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: configs})
+}
+
+// AddStorage handles POST /api/backup/storage
+func (h *BackupHandlers) AddStorage(w http.ResponseWriter, r *http.Request) {
+	var config backup.StorageConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if config.Name == "" {
+		http.Error(w, "Storage name is required", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.backupManager.AddStorage(r.Context(), config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: created})
+}
+
+// RemoveStorage handles DELETE /api/backup/storage/{id}
+func (h *BackupHandlers) RemoveStorage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Storage ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.backupManager.RemoveStorage(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Message: "Storage removed successfully"})
+}
+
 // GetStatistics handles GET /api/backup/statistics
 func (h *BackupHandlers) GetStatistics(w http.ResponseWriter, r *http.Request) {
 	stats, err := h.backupManager.GetStatistics()
@@ -217,8 +310,12 @@ func (h *BackupHandlers) VerifyBackup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.backupManager.VerifyBackup(id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// The body is optional; only backups encrypted with a passphrase need one
+	var req backup.VerifyRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	if err := h.backupManager.VerifyBackup(id, req.Passphrase); err != nil {
+		http.Error(w, err.Error(), backupErrorStatus(err))
 		return
 	}
 
@@ -226,6 +323,29 @@ func (h *BackupHandlers) VerifyBackup(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Message: "Backup verification started"})
 }
 
+// PreviewRestore handles POST /api/backup/history/{id}/preview
+func (h *BackupHandlers) PreviewRestore(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/backup/history/")
+	id := strings.TrimSuffix(path, "/preview")
+	if id == "" {
+		http.Error(w, "Backup ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// The body is optional; only backups encrypted with a passphrase need one
+	var req backup.VerifyRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	preview, err := h.backupManager.PreviewRestore(r.Context(), id, req.Passphrase)
+	if err != nil {
+		http.Error(w, err.Error(), backupErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: preview})
+}
+
 // StartRecovery handles POST /api/backup/history/{id}/recover
 func (h *BackupHandlers) StartRecovery(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/backup/history/")
@@ -248,7 +368,7 @@ func (h *BackupHandlers) StartRecovery(w http.ResponseWriter, r *http.Request) {
 
 	recoveryID, err := h.backupManager.StartRecovery(id, options)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), backupErrorStatus(err))
 		return
 	}
 
@@ -299,3 +419,112 @@ func (h *BackupHandlers) UpdateSchedule(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Message: "Schedule updated successfully"})
 }
+
+// ListSnapshotClasses handles GET /api/backup/snapshots/classes
+func (h *BackupHandlers) ListSnapshotClasses(w http.ResponseWriter, r *http.Request) {
+	classes, err := h.backupManager.ListSnapshotClasses(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: classes})
+}
+
+// ListSnapshots handles GET /api/backup/snapshots
+func (h *BackupHandlers) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	namespace := r.URL.Query().Get("namespace")
+
+	snapshots, err := h.backupManager.ListSnapshots(r.Context(), namespace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: snapshots})
+}
+
+// CreateSnapshot handles POST /api/backup/snapshots
+func (h *BackupHandlers) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	var req backup.SnapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Namespace == "" || req.PVCName == "" {
+		http.Error(w, "Namespace and PVC name are required", http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := h.backupManager.CreateSnapshot(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Data: snapshot})
+}
+
+// DeleteSnapshot handles DELETE /api/backup/snapshots/{namespace}/{name}
+func (h *BackupHandlers) DeleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	namespace := r.PathValue("namespace")
+	name := r.PathValue("name")
+
+	if err := h.backupManager.DeleteSnapshot(r.Context(), namespace, name); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true, Message: "Snapshot deleted successfully"})
+}
+
+// RestoreSnapshot handles POST /api/backup/snapshots/{namespace}/{name}/restore
+func (h *BackupHandlers) RestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	namespace := r.PathValue("namespace")
+	name := r.PathValue("name")
+
+	var req backup.SnapshotRestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ClaimName == "" {
+		http.Error(w, "Claim name is required", http.StatusBadRequest)
+		return
+	}
+
+	recoveryID, err := h.backupManager.RestoreSnapshot(r.Context(), namespace, name, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response.APIResponse{Success: true,
+		Data:    map[string]string{"recoveryId": recoveryID},
+		Message: "Snapshot restore started successfully",
+	})
+}
+
+// backupErrorStatus maps backup errors to response statuses; missing and wrong encryption
+// keys and invalid archive settings are the caller's to fix
+func backupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, backup.ErrNoArchive):
+		return http.StatusConflict
+	case errors.Is(err, backup.ErrEncryptionKeyRequired), errors.Is(err, backup.ErrWrongEncryptionKey),
+		errors.Is(err, backup.ErrInvalidArchive), errors.Is(err, backup.ErrInvalidHook):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
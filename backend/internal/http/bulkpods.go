@@ -0,0 +1,127 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+// BulkPodRequest names pods explicitly, selects them by label and status, or both
+type BulkPodRequest struct {
+	Action   string           `json:"action"` // delete or restart
+	Pods     []k8s.PodRef     `json:"pods,omitempty"`
+	Selector *k8s.PodSelector `json:"selector,omitempty"`
+	DryRun   bool             `json:"dryRun"` // return the matched pods without acting on them
+}
+
+// BulkPodResponse reports the outcome for every pod
+type BulkPodResponse struct {
+	Action    string              `json:"action"`
+	DryRun    bool                `json:"dryRun"`
+	Total     int                 `json:"total"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+	Results   []k8s.BulkPodResult `json:"results"`
+}
+
+// BulkPodOperation handles POST /api/k8s/pods/bulk, deleting or restarting many pods in one call.
+// Failures are reported per pod; the request itself succeeds unless it is invalid.
+func (h *KubernetesHandlers) BulkPodOperation(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	var req BulkPodRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	permission := map[string]string{"delete": "delete", "restart": "update"}[req.Action]
+	if permission == "" {
+		response.SendError(w, http.StatusBadRequest, "action must be delete or restart")
+		return
+	}
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil || !hasPermission(claims.Role, "pods", permission) {
+		response.SendError(w, http.StatusForbidden, "Insufficient permissions")
+		return
+	}
+
+	pods, err := h.bulkPodTargets(r, req)
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result := BulkPodResponse{Action: req.Action, DryRun: req.DryRun, Total: len(pods)}
+	switch {
+	case req.DryRun:
+		result.Results = make([]k8s.BulkPodResult, len(pods))
+		for i, pod := range pods {
+			result.Results[i] = k8s.BulkPodResult{Namespace: pod.Namespace, Name: pod.Name}
+		}
+	case req.Action == "delete":
+		result.Results = h.k8sClient.DeletePods(r.Context(), pods)
+	default:
+		result.Results = h.k8sClient.RestartPods(r.Context(), pods)
+	}
+	for _, item := range result.Results {
+		if item.Success {
+			result.Succeeded++
+		} else if !req.DryRun {
+			result.Failed++
+		}
+	}
+
+	response.SendSuccess(w, result)
+}
+
+// bulkPodTargets combines explicitly named pods with those matching the selector, without duplicates
+func (h *KubernetesHandlers) bulkPodTargets(r *http.Request, req BulkPodRequest) ([]k8s.PodRef, error) {
+	var pods []k8s.PodRef
+	seen := map[k8s.PodRef]bool{}
+	add := func(pod k8s.PodRef) {
+		if !seen[pod] {
+			seen[pod] = true
+			pods = append(pods, pod)
+		}
+	}
+
+	for _, pod := range req.Pods {
+		if pod.Name == "" {
+			return nil, fmt.Errorf("every pod needs a name")
+		}
+		if pod.Namespace == "" {
+			pod.Namespace = "default"
+		}
+		add(pod)
+	}
+
+	if req.Selector != nil {
+		// An empty selector would match every pod in the namespace or cluster
+		if req.Selector.LabelSelector == "" && req.Selector.Status == "" {
+			return nil, fmt.Errorf("selector needs a labelSelector or status")
+		}
+		matched, err := h.k8sClient.SelectPods(r.Context(), *req.Selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, pod := range matched {
+			add(pod)
+		}
+	}
+
+	if len(pods) == 0 && req.Selector == nil {
+		return nil, fmt.Errorf("pods or selector is required")
+	}
+	if len(pods) > k8s.MaxBulkPods {
+		return nil, fmt.Errorf("%d pods selected, at most %d per request", len(pods), k8s.MaxBulkPods)
+	}
+	return pods, nil
+}
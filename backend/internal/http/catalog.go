@@ -0,0 +1,116 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/archellir/denshimon/internal/catalog"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+type CatalogHandlers struct {
+	service *catalog.Service
+}
+
+func NewCatalogHandlers(service *catalog.Service) *CatalogHandlers {
+	return &CatalogHandlers{service: service}
+}
+
+// ListTemplates handles GET /api/catalog
+func (h *CatalogHandlers) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.service.List(r.Context())
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to list templates: "+err.Error())
+		return
+	}
+	response.SendSuccess(w, templates)
+}
+
+// GetTemplate handles GET /api/catalog/{template}
+func (h *CatalogHandlers) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	template, err := h.service.Get(r.Context(), r.PathValue("template"))
+	if err != nil {
+		sendCatalogError(w, err)
+		return
+	}
+	response.SendSuccess(w, template)
+}
+
+// CreateTemplate handles POST /api/catalog
+func (h *CatalogHandlers) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var template catalog.Template
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	created, err := h.service.Create(r.Context(), template, requestUser(r))
+	if err != nil {
+		sendCatalogError(w, err)
+		return
+	}
+	response.SendSuccess(w, created)
+}
+
+// UpdateTemplate handles PUT /api/catalog/{template}
+func (h *CatalogHandlers) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	var template catalog.Template
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	updated, err := h.service.Update(r.Context(), r.PathValue("template"), template)
+	if err != nil {
+		sendCatalogError(w, err)
+		return
+	}
+	response.SendSuccess(w, updated)
+}
+
+// DeleteTemplate handles DELETE /api/catalog/{template}
+func (h *CatalogHandlers) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("template")
+	if err := h.service.Delete(r.Context(), name); err != nil {
+		sendCatalogError(w, err)
+		return
+	}
+	response.SendSuccess(w, map[string]string{"message": "Template deleted", "name": name})
+}
+
+// DeployTemplate handles POST /api/catalog/{template}/deploy. The parameters are
+// validated against the template, then the deployment is created and committed to
+// GitOps like any other, waiting in pending_apply unless "apply" is set.
+func (h *CatalogHandlers) DeployTemplate(w http.ResponseWriter, r *http.Request) {
+	var req catalog.DeployRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	deployment, err := h.service.Deploy(r.Context(), r.PathValue("template"), req, requestUser(r))
+	var paramErr *catalog.ParameterError
+	switch {
+	case err == nil:
+	case errors.Is(err, catalog.ErrNotFound), errors.As(err, &paramErr):
+		sendCatalogError(w, err)
+		return
+	default:
+		// Includes an apply failing after the commit; the error names the deployment
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.SendSuccess(w, deployment)
+}
+
+func sendCatalogError(w http.ResponseWriter, err error) {
+	var paramErr *catalog.ParameterError
+	switch {
+	case errors.Is(err, catalog.ErrNotFound):
+		response.SendError(w, http.StatusNotFound, "Template not found")
+	case errors.Is(err, catalog.ErrExists):
+		response.SendError(w, http.StatusConflict, err.Error())
+	case errors.As(err, &paramErr):
+		response.SendError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		response.SendError(w, http.StatusBadRequest, err.Error())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/archellir/denshimon/pkg/response"
 	"github.com/archellir/denshimon/internal/providers/certificates"
@@ -120,6 +121,16 @@ func (h *CertificateHandlers) AddDomainConfig(w http.ResponseWriter, r *http.Req
 	if config.CheckInterval == 0 {
 		config.CheckInterval = 60 // default 1 hour
 	}
+	if config.CheckInterval < 0 {
+		h.writeError(w, http.StatusBadRequest, "Check interval must be positive", nil)
+		return
+	}
+	if config.MinTLSVersion != "" {
+		if _, err := certificates.ParseTLSVersion(config.MinTLSVersion); err != nil {
+			h.writeError(w, http.StatusBadRequest, "Invalid minimum TLS version", err)
+			return
+		}
+	}
 
 	err := h.manager.AddDomainConfig(config)
 	if err != nil {
@@ -185,6 +196,27 @@ func (h *CertificateHandlers) AcknowledgeAlert(w http.ResponseWriter, r *http.Re
 	h.writeSuccess(w, map[string]string{"message": "Alert acknowledged successfully"})
 }
 
+// GetCheckHistory returns scheduled check results, e.g. GET /api/certificates/history?domain=example.com&since=30d
+func (h *CertificateHandlers) GetCheckHistory(w http.ResponseWriter, r *http.Request) {
+	since := 30 * 24 * time.Hour
+	if value := r.URL.Query().Get("since"); value != "" {
+		d, err := parseTimeRange(value)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "Invalid since parameter", err)
+			return
+		}
+		since = d
+	}
+
+	history, err := h.manager.GetCheckHistory(r.URL.Query().Get("domain"), time.Now().Add(-since))
+	if err != nil {
+		h.writeError(w, http.StatusServiceUnavailable, "Failed to get certificate history", err)
+		return
+	}
+
+	h.writeSuccess(w, history)
+}
+
 // RefreshCertificates triggers a refresh of all certificates
 func (h *CertificateHandlers) RefreshCertificates(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
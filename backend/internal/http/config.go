@@ -0,0 +1,75 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/prometheus"
+	"github.com/archellir/denshimon/pkg/config"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+// configCheckInterval is how often the config file is checked for changes
+const configCheckInterval = 5 * time.Second
+
+// logLevel is the level of the default logger; it follows LOG_LEVEL across config reloads
+var logLevel = new(slog.LevelVar)
+
+type ConfigHandlers struct {
+	watcher *config.Watcher
+}
+
+func NewConfigHandlers(watcher *config.Watcher) *ConfigHandlers {
+	return &ConfigHandlers{
+		watcher: watcher,
+	}
+}
+
+// GetConfig handles GET /api/config, returning the effective configuration with secrets redacted
+func (h *ConfigHandlers) GetConfig(w http.ResponseWriter, r *http.Request) {
+	response.SendSuccess(w, config.Describe(h.watcher))
+}
+
+// initConfigReload sets the log level from LOG_LEVEL and, when a config file is used, watches it
+// and applies reloaded log level, Prometheus and tracing settings. Other settings need a restart.
+func initConfigReload(watcher *config.Watcher, servicesHandlers *ServicesHandlers, tracesHandlers *TracesHandlers) {
+	setLogLevel(os.Getenv("LOG_LEVEL"))
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})))
+	if watcher == nil {
+		return
+	}
+
+	watcher.OnReload(func(*config.File) {
+		setLogLevel(os.Getenv("LOG_LEVEL"))
+		servicesHandlers.SetPrometheusService(prometheus.NewService(prometheusURL()))
+		tracesClient := initTracesClient()
+		servicesHandlers.SetTracesClient(tracesClient)
+		tracesHandlers.SetClient(tracesClient)
+	})
+	watcher.Start(context.Background(), configCheckInterval)
+}
+
+// prometheusURL returns PROMETHEUS_URL or the in-cluster default
+func prometheusURL() string {
+	if url := os.Getenv("PROMETHEUS_URL"); url != "" {
+		return url
+	}
+	return "http://prometheus-service.monitoring.svc.cluster.local:9090" // default value
+}
+
+func setLogLevel(level string) {
+	switch strings.ToLower(level) {
+	case "debug":
+		logLevel.Set(slog.LevelDebug)
+	case "warn":
+		logLevel.Set(slog.LevelWarn)
+	case "error":
+		logLevel.Set(slog.LevelError)
+	default:
+		logLevel.Set(slog.LevelInfo)
+	}
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/archellir/denshimon/internal/metrics"
+)
+
+// GET /api/metrics/costs?namespace=&window=7d
+// Estimated monthly cost per workload and namespace
+func (h *MetricsHandlers) GetCosts(w http.ResponseWriter, r *http.Request) {
+	if h.metricsService == nil {
+		sendMetricsError(w, http.StatusServiceUnavailable, "Metrics service not available")
+		return
+	}
+
+	window := metrics.DefaultUsageRetention
+	if value := r.URL.Query().Get("window"); value != "" {
+		d, err := parseTimeRange(value)
+		if err != nil {
+			sendMetricsError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		window = d
+	}
+
+	report, err := h.metricsService.GetCosts(r.Context(), r.URL.Query().Get("namespace"), window)
+	if err != nil {
+		sendMetricsError(w, http.StatusInternalServerError, "Failed to estimate costs")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// initCostModel reads the prices of cost estimates from COST_CPU_HOUR, COST_MEMORY_GB_HOUR
+// and COST_CURRENCY, keeping the defaults for unset or invalid values
+func initCostModel(metricsService *metrics.Service) {
+	rates := metrics.DefaultCostRates()
+	for key, price := range map[string]*float64{
+		"COST_CPU_HOUR":       &rates.CPUHour,
+		"COST_MEMORY_GB_HOUR": &rates.MemoryGBHour,
+	} {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 {
+			slog.Warn("Ignoring invalid price", "variable", key, "value", value)
+			continue
+		}
+		*price = parsed
+	}
+	if currency := os.Getenv("COST_CURRENCY"); currency != "" {
+		rates.Currency = currency
+	}
+	metricsService.SetCostRates(rates)
+}
@@ -3,11 +3,14 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/archellir/denshimon/pkg/response"
+	"github.com/archellir/denshimon/internal/auth"
 	"github.com/archellir/denshimon/internal/providers/databases"
 )
 
@@ -54,6 +57,12 @@ func (h *DatabasesHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/databases/connections/{id}/databases/{database}/tables", h.GetTables)
 	mux.HandleFunc("GET /api/databases/connections/{id}/databases/{database}/tables/{table}/columns", h.GetColumns)
 	mux.HandleFunc("POST /api/databases/connections/{id}/query", h.ExecuteQuery)
+	mux.HandleFunc("POST /api/databases/connections/{id}/explain", h.Explain)
+	mux.HandleFunc("GET /api/databases/connections/{id}/slow-queries", h.GetSlowQueries)
+	mux.HandleFunc("GET /api/databases/connections/{id}/databases/{database}/keys", h.ScanKeys)
+	mux.HandleFunc("GET /api/databases/connections/{id}/databases/{database}/keys/value", h.GetKey)
+	mux.HandleFunc("DELETE /api/databases/connections/{id}/databases/{database}/keys", h.DeleteKeys)
+	mux.HandleFunc("POST /api/databases/connections/{id}/databases/{database}/flush", h.FlushDatabase)
 	mux.HandleFunc("POST /api/databases/connections/{id}/tables/{table}/data", h.GetTableData)
 	mux.HandleFunc("PUT /api/databases/connections/{id}/tables/{table}/rows", h.UpdateRow)
 	mux.HandleFunc("DELETE /api/databases/connections/{id}/tables/{table}/rows", h.DeleteRow)
@@ -66,6 +75,14 @@ func (h *DatabasesHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/databases/saved-queries", h.CreateSavedQuery)
 	mux.HandleFunc("PUT /api/databases/saved-queries/{id}", h.UpdateSavedQuery)
 	mux.HandleFunc("DELETE /api/databases/saved-queries/{id}", h.DeleteSavedQuery)
+
+	// Scheduled query jobs routes
+	mux.HandleFunc("GET /api/databases/jobs", h.ListJobs)
+	mux.HandleFunc("POST /api/databases/jobs", h.CreateJob)
+	mux.HandleFunc("PUT /api/databases/jobs/{id}", h.UpdateJob)
+	mux.HandleFunc("DELETE /api/databases/jobs/{id}", h.DeleteJob)
+	mux.HandleFunc("POST /api/databases/jobs/{id}/run", h.RunJob)
+	mux.HandleFunc("GET /api/databases/jobs/{id}/runs", h.GetJobRuns)
 }
 
 // ListConnections returns all database connections
@@ -333,6 +350,65 @@ func (h *DatabasesHandler) GetColumns(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// GetTableSchema returns the indexes, foreign keys and constraints of a table
+func (h *DatabasesHandler) GetTableSchema(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	database := r.PathValue("database")
+	table := r.PathValue("table")
+
+	provider, err := h.manager.GetProvider(id)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Database not connected", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	schema, err := provider.GetTableSchema(ctx, database, table)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Failed to get table schema", err)
+		return
+	}
+
+	response := response.APIResponse{
+		Success: true,
+		Data:    schema,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetRelationships returns the tables, columns and foreign keys of a database for an ER diagram
+func (h *DatabasesHandler) GetRelationships(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	database := r.PathValue("database")
+
+	provider, err := h.manager.GetProvider(id)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Database not connected", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	diagram, err := databases.BuildDiagram(ctx, provider, database)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Failed to get relationships", err)
+		return
+	}
+
+	response := response.APIResponse{
+		Success: true,
+		Data:    diagram,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // ExecuteQuery executes a SQL query
 func (h *DatabasesHandler) ExecuteQuery(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
@@ -342,6 +418,9 @@ func (h *DatabasesHandler) ExecuteQuery(w http.ResponseWriter, r *http.Request)
 		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		req.Admin = claims.Role == "admin"
+	}
 
 	provider, err := h.manager.GetProvider(id)
 	if err != nil {
@@ -353,7 +432,11 @@ func (h *DatabasesHandler) ExecuteQuery(w http.ResponseWriter, r *http.Request)
 	defer cancel()
 
 	result, err := provider.ExecuteQuery(ctx, req)
-	if err != nil {
+	switch {
+	case errors.Is(err, databases.ErrReadOnlyConnection), errors.Is(err, databases.ErrAdminCommand):
+		writeErrorResponse(w, http.StatusForbidden, "Failed to execute query", err)
+		return
+	case err != nil:
 		writeErrorResponse(w, http.StatusInternalServerError, "Failed to execute query", err)
 		return
 	}
@@ -367,6 +450,94 @@ func (h *DatabasesHandler) ExecuteQuery(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(response)
 }
 
+// Explain returns the execution plan of a statement
+func (h *DatabasesHandler) Explain(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var req databases.ExplainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	provider, err := h.manager.GetProvider(id)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Database not connected", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	plan, err := provider.Explain(ctx, req)
+	switch {
+	case errors.Is(err, databases.ErrAnalyzeNotAllowed):
+		writeErrorResponse(w, http.StatusForbidden, "Failed to explain query", err)
+		return
+	case errors.Is(err, databases.ErrInvalidExplain):
+		writeErrorResponse(w, http.StatusBadRequest, "Failed to explain query", err)
+		return
+	case err != nil:
+		writeErrorResponse(w, http.StatusInternalServerError, "Failed to explain query", err)
+		return
+	}
+
+	response := response.APIResponse{
+		Success: true,
+		Data:    plan,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetSlowQueries returns the slowest statements of a database
+func (h *DatabasesHandler) GetSlowQueries(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	limit := 20
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+		limit = parsed
+	}
+
+	provider, err := h.manager.GetProvider(id)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Database not connected", err)
+		return
+	}
+	log, ok := provider.(databases.SlowQueryLog)
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, "Slow queries are not tracked for this database type", nil)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	queries, err := log.SlowQueries(ctx, databases.SlowQueryThreshold, limit)
+	if errors.Is(err, databases.ErrSlowQueriesUnavailable) {
+		writeErrorResponse(w, http.StatusNotImplemented, "Failed to get slow queries", err)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Failed to get slow queries", err)
+		return
+	}
+
+	response := response.APIResponse{
+		Success: true,
+		Data:    queries,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // GetTableData returns paginated table data
 func (h *DatabasesHandler) GetTableData(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
@@ -513,21 +684,197 @@ func (h *DatabasesHandler) InsertRow(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetStats returns database statistics
-func (h *DatabasesHandler) GetStats(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
-
+// keyValueStore returns the key-value store of a connected database, writing the error
+// response when it isn't one
+func (h *DatabasesHandler) keyValueStore(w http.ResponseWriter, id string) (databases.KeyValueStore, bool) {
 	provider, err := h.manager.GetProvider(id)
 	if err != nil {
 		writeErrorResponse(w, http.StatusBadRequest, "Database not connected", err)
+		return nil, false
+	}
+	store, ok := provider.(databases.KeyValueStore)
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, "Keys can only be browsed on key-value databases", nil)
+		return nil, false
+	}
+	return store, true
+}
+
+// ScanKeys returns a page of a key-value database's keys
+func (h *DatabasesHandler) ScanKeys(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	database := r.PathValue("database")
+
+	query := r.URL.Query()
+	req := databases.KeyScanRequest{
+		Pattern: query.Get("pattern"),
+		Type:    query.Get("type"),
+	}
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+		req.Cursor = cursor
+	}
+	if value := query.Get("count"); value != "" {
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || count < 1 || count > 1000 {
+			writeErrorResponse(w, http.StatusBadRequest, "Count must be between 1 and 1000", err)
+			return
+		}
+		req.Count = count
+	}
+
+	store, ok := h.keyValueStore(w, id)
+	if !ok {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	stats, err := provider.GetStats(ctx)
+	result, err := store.ScanKeys(ctx, database, req)
 	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Failed to scan keys", err)
+		return
+	}
+
+	response := response.APIResponse{
+		Success: true,
+		Data:    result,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetKey returns the value of the key given in the key query parameter
+func (h *DatabasesHandler) GetKey(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	database := r.PathValue("database")
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "Key is required", nil)
+		return
+	}
+
+	store, ok := h.keyValueStore(w, id)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	value, err := store.GetKey(ctx, database, key)
+	switch {
+	case errors.Is(err, databases.ErrKeyNotFound):
+		writeErrorResponse(w, http.StatusNotFound, "Failed to get key", err)
+		return
+	case err != nil:
+		writeErrorResponse(w, http.StatusInternalServerError, "Failed to get key", err)
+		return
+	}
+
+	response := response.APIResponse{
+		Success: true,
+		Data:    value,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteKeys deletes keys of a key-value database
+func (h *DatabasesHandler) DeleteKeys(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	database := r.PathValue("database")
+
+	var req databases.KeyDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if len(req.Keys) == 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "At least one key is required", nil)
+		return
+	}
+
+	store, ok := h.keyValueStore(w, id)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	deleted, err := store.DeleteKeys(ctx, database, req.Keys)
+	switch {
+	case errors.Is(err, databases.ErrReadOnlyConnection):
+		writeErrorResponse(w, http.StatusForbidden, "Failed to delete keys", err)
+		return
+	case err != nil:
+		writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete keys", err)
+		return
+	}
+
+	response := response.APIResponse{
+		Success: true,
+		Data:    map[string]int64{"deleted": deleted},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// FlushDatabase deletes every key of a key-value database
+func (h *DatabasesHandler) FlushDatabase(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	database := r.PathValue("database")
+
+	store, ok := h.keyValueStore(w, id)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	err := store.FlushDatabase(ctx, database)
+	switch {
+	case errors.Is(err, databases.ErrReadOnlyConnection):
+		writeErrorResponse(w, http.StatusForbidden, "Failed to flush database", err)
+		return
+	case err != nil:
+		writeErrorResponse(w, http.StatusInternalServerError, "Failed to flush database", err)
+		return
+	}
+
+	response := response.APIResponse{
+		Success: true,
+		Message: "Database flushed successfully",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetStats returns database statistics with the connection's pool usage and health
+func (h *DatabasesHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := h.manager.Stats(ctx, id)
+	switch {
+	case errors.Is(err, databases.ErrNotConnected):
+		writeErrorResponse(w, http.StatusBadRequest, "Database not connected", err)
+		return
+	case err != nil:
 		writeErrorResponse(w, http.StatusInternalServerError, "Failed to get statistics", err)
 		return
 	}
@@ -674,3 +1021,143 @@ func (h *DatabasesHandler) DeleteSavedQuery(w http.ResponseWriter, r *http.Reque
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// writeJobError maps query job errors to response statuses
+func writeJobError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, databases.ErrJobNotFound):
+		writeErrorResponse(w, http.StatusNotFound, "Query job not found", err)
+	case errors.Is(err, databases.ErrInvalidJob):
+		writeErrorResponse(w, http.StatusBadRequest, message, err)
+	case errors.Is(err, databases.ErrJobRunning):
+		writeErrorResponse(w, http.StatusConflict, message, err)
+	default:
+		writeErrorResponse(w, http.StatusInternalServerError, message, err)
+	}
+}
+
+// ListJobs returns the scheduled query jobs with their most recent run
+func (h *DatabasesHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := h.manager.ListJobs(r.Context())
+	if err != nil {
+		writeJobError(w, "Failed to get query jobs", err)
+		return
+	}
+
+	response := response.APIResponse{
+		Success: true,
+		Data:    jobs,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateJob schedules a saved query against a connection
+func (h *DatabasesHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	var req databases.QueryJob
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	job, err := h.manager.CreateJob(r.Context(), req)
+	if err != nil {
+		writeJobError(w, "Failed to create query job", err)
+		return
+	}
+
+	response := response.APIResponse{
+		Success: true,
+		Data:    job,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateJob replaces a query job's settings
+func (h *DatabasesHandler) UpdateJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var req databases.QueryJob
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	job, err := h.manager.UpdateJob(r.Context(), id, req)
+	if err != nil {
+		writeJobError(w, "Failed to update query job", err)
+		return
+	}
+
+	response := response.APIResponse{
+		Success: true,
+		Data:    job,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteJob removes a query job and its run history
+func (h *DatabasesHandler) DeleteJob(w http.ResponseWriter, r *http.Request) {
+	if err := h.manager.DeleteJob(r.Context(), r.PathValue("id")); err != nil {
+		writeJobError(w, "Failed to delete query job", err)
+		return
+	}
+
+	response := response.APIResponse{
+		Success: true,
+		Message: "Query job deleted successfully",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RunJob runs a query job now and returns the recorded run
+func (h *DatabasesHandler) RunJob(w http.ResponseWriter, r *http.Request) {
+	run, err := h.manager.RunJob(r.Context(), r.PathValue("id"), false)
+	if err != nil {
+		writeJobError(w, "Failed to run query job", err)
+		return
+	}
+
+	response := response.APIResponse{
+		Success: true,
+		Data:    run,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetJobRuns returns a query job's run history, newest first
+func (h *DatabasesHandler) GetJobRuns(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := h.manager.ListJobRuns(r.Context(), r.PathValue("id"), limit)
+	if err != nil {
+		writeJobError(w, "Failed to get query job runs", err)
+		return
+	}
+
+	response := response.APIResponse{
+		Success: true,
+		Data:    runs,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/pkg/response"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// CheckConnectivity handles POST /api/k8s/debug/connectivity, running DNS lookups, port probes
+// and HTTP requests against a target from a debug pod. It answers once the pod has finished,
+// which takes up to a couple of minutes when the image still has to be pulled.
+func (h *KubernetesHandlers) CheckConnectivity(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	var req k8s.ConnectivityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Image = h.debugImage
+
+	report, err := h.k8sClient.CheckConnectivity(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, k8s.ErrInvalidConnectivityCheck), apierrors.IsInvalid(err):
+			response.SendError(w, http.StatusBadRequest, err.Error())
+		case apierrors.IsForbidden(err):
+			response.SendError(w, http.StatusForbidden, err.Error())
+		default:
+			response.SendError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	response.SendSuccess(w, report)
+}
+
+// debugContainerRequest is the body of a debug container request
+type debugContainerRequest struct {
+	Image  string `json:"image,omitempty"`  // busybox (default), netshoot or an image reference
+	Target string `json:"target,omitempty"` // container to debug, the first by default
+	TTL    string `json:"ttl,omitempty"`    // e.g. 30m, 1h by default
+}
+
+// AttachDebugContainer handles POST /api/k8s/pods/{name}/debug?namespace=default, attaching an
+// ephemeral debug container and returning the exec WebSocket path of its terminal. The
+// container stops when the terminal closes.
+func (h *KubernetesHandlers) AttachDebugContainer(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	var req debugContainerRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		d, err := parseTimeRange(req.TTL)
+		if err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid ttl: "+err.Error())
+			return
+		}
+		ttl = d
+	}
+
+	container, err := h.k8sClient.AttachDebugContainer(r.Context(), queryNamespace(r), r.PathValue("name"), k8s.DebugContainerRequest{
+		Image:  req.Image,
+		Target: req.Target,
+		TTL:    ttl,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, k8s.ErrInvalidDebugContainer), apierrors.IsInvalid(err):
+			response.SendError(w, http.StatusBadRequest, err.Error())
+		case apierrors.IsNotFound(err):
+			response.SendError(w, http.StatusNotFound, err.Error())
+		case apierrors.IsForbidden(err):
+			response.SendError(w, http.StatusForbidden, err.Error())
+		default:
+			response.SendError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	container.Exec = "/api/k8s/pods/exec?" + url.Values{
+		"namespace": {container.Namespace},
+		"pod":       {container.Pod},
+		"container": {container.Container},
+		"command":   {"sh"},
+	}.Encode()
+
+	response.SendJSON(w, http.StatusCreated, response.APIResponse{
+		Success: true,
+		Data:    container,
+		Message: "Debug container attached",
+	})
+}
@@ -1,15 +1,24 @@
 package http
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/archellir/denshimon/internal/auth"
 	"github.com/archellir/denshimon/internal/deployments"
 	"github.com/archellir/denshimon/internal/providers"
 	"github.com/archellir/denshimon/internal/providers/registries"
+	"github.com/archellir/denshimon/pkg/apiclient"
 	"github.com/google/uuid"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 // DeploymentHandlers handles HTTP requests for deployments
@@ -139,6 +148,90 @@ func (h *DeploymentHandlers) TestRegistry(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// pullSecretRequest is the body of a pull secret sync
+type pullSecretRequest struct {
+	Namespace       string   `json:"namespace"`
+	ServiceAccounts []string `json:"service_accounts,omitempty"` // ["default"] when omitted
+}
+
+// ListPullSecrets lists a registry's pull secrets and the service accounts using them
+func (h *DeploymentHandlers) ListPullSecrets(w http.ResponseWriter, r *http.Request) {
+	registryID := extractIDFromPath(r.URL.Path, "/api/deployments/registries/")
+	if _, err := h.registryManager.GetProvider(registryID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	secrets, err := h.service.ListPullSecrets(r.Context(), registryID)
+	if err != nil {
+		http.Error(w, err.Error(), pullSecretErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, secrets)
+}
+
+// SyncPullSecret creates or updates a registry's pull secret in a namespace from its stored
+// credentials and attaches it to service accounts
+func (h *DeploymentHandlers) SyncPullSecret(w http.ResponseWriter, r *http.Request) {
+	registryID := extractIDFromPath(r.URL.Path, "/api/deployments/registries/")
+	if _, err := h.registryManager.GetProvider(registryID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var req pullSecretRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Namespace == "" {
+		http.Error(w, "Namespace is required", http.StatusBadRequest)
+		return
+	}
+	if req.ServiceAccounts == nil {
+		req.ServiceAccounts = []string{"default"}
+	}
+
+	secret, err := h.service.SyncPullSecret(r.Context(), registryID, req.Namespace, req.ServiceAccounts)
+	if err != nil {
+		http.Error(w, err.Error(), pullSecretErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, secret)
+}
+
+// DeletePullSecret detaches and deletes a registry's pull secret in ?namespace=
+func (h *DeploymentHandlers) DeletePullSecret(w http.ResponseWriter, r *http.Request) {
+	registryID := extractIDFromPath(r.URL.Path, "/api/deployments/registries/")
+	namespace := r.URL.Query().Get("namespace")
+	if namespace == "" {
+		http.Error(w, "Namespace is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeletePullSecret(r.Context(), registryID, namespace); err != nil {
+		http.Error(w, err.Error(), pullSecretErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func pullSecretErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, deployments.ErrNoRegistryCredentials):
+		return http.StatusBadRequest
+	case apierrors.IsNotFound(err):
+		return http.StatusNotFound
+	case apierrors.IsForbidden(err):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // Image Management
 
 // ListImages returns images from all or specific registries
@@ -244,6 +337,109 @@ func (h *DeploymentHandlers) GetImageTags(w http.ResponseWriter, r *http.Request
 	writeJSON(w, map[string][]string{"tags": tags})
 }
 
+// InspectManifest returns an image's manifest details: digest, size, layers, created
+// date and platforms. GET /api/deployments/images/{registry}/manifest?repository=&reference=
+func (h *DeploymentHandlers) InspectManifest(w http.ResponseWriter, r *http.Request) {
+	repository, reference := r.URL.Query().Get("repository"), r.URL.Query().Get("reference")
+	if repository == "" || reference == "" {
+		http.Error(w, "repository and reference are required", http.StatusBadRequest)
+		return
+	}
+
+	provider, err := h.registryManager.GetProvider(r.PathValue("registry"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	inspector, ok := provider.(providers.ManifestInspector)
+	if !ok {
+		http.Error(w, fmt.Sprintf("%s registries do not support manifest inspection", provider.Type()), http.StatusNotImplemented)
+		return
+	}
+
+	manifest, err := inspector.InspectManifest(r.Context(), repository, reference)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, manifest)
+}
+
+// imageLabels reads the labels of an image, such as registry.example.com/team/api:v1, from
+// one of the configured registries
+func (h *DeploymentHandlers) imageLabels(ctx context.Context, registryID, image string) (map[string]string, error) {
+	provider, err := h.registryManager.GetProvider(registryID)
+	if err != nil {
+		return nil, err
+	}
+	inspector, ok := provider.(providers.ManifestInspector)
+	if !ok {
+		return nil, fmt.Errorf("%s registries do not support manifest inspection", provider.Type())
+	}
+
+	// The digest pins the image over its tag
+	repository, reference, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		if reference == "" {
+			reference = repository[i+1:]
+		}
+		repository = repository[:i]
+	}
+	if reference == "" {
+		reference = "latest"
+	}
+	if host, rest, ok := strings.Cut(repository, "/"); ok && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		repository = rest
+	}
+
+	manifest, err := inspector.InspectManifest(ctx, repository, reference)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Labels, nil
+}
+
+// DeleteImageTag removes a tag where the registry API permits it.
+// DELETE /api/deployments/images/{registry}/tags?repository=&tag=
+func (h *DeploymentHandlers) DeleteImageTag(w http.ResponseWriter, r *http.Request) {
+	repository, tag := r.URL.Query().Get("repository"), r.URL.Query().Get("tag")
+	if repository == "" || tag == "" {
+		http.Error(w, "repository and tag are required", http.StatusBadRequest)
+		return
+	}
+
+	provider, err := h.registryManager.GetProvider(r.PathValue("registry"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	deleter, ok := provider.(providers.TagDeleter)
+	if !ok {
+		http.Error(w, fmt.Sprintf("%s registries do not support tag deletion", provider.Type()), http.StatusNotImplemented)
+		return
+	}
+
+	if err := deleter.DeleteTag(r.Context(), repository, tag); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LoadRegistries creates providers for the registries stored in the database
+func LoadRegistries(ctx context.Context, service *deployments.Service, manager *providers.RegistryManager) {
+	stored, err := service.ListRegistries(ctx)
+	if err != nil {
+		slog.Warn("Failed to load container registries", "error", err)
+		return
+	}
+	for _, registry := range stored {
+		if err := manager.AddRegistry(ctx, registry); err != nil {
+			slog.Warn("Container registry unavailable", "registry", registry.Name, "type", registry.Type, "error", err)
+		}
+	}
+}
+
 // Deployment Management
 
 // CreateDeployment creates a new deployment
@@ -260,12 +456,21 @@ func (h *DeploymentHandlers) CreateDeployment(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if err := req.ValidateWorkload(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	if req.Replicas == 0 {
 		req.Replicas = 1
 	}
+	req.CreatedBy = requestUser(r)
 
 	deployment, err := h.service.CreateDeployment(r.Context(), req)
 	if err != nil {
+		if sendPolicyError(w, err) {
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -444,6 +649,156 @@ func (h *DeploymentHandlers) GetDeploymentHistory(w http.ResponseWriter, r *http
 	writeJSON(w, history)
 }
 
+// GetDeploymentRevisions returns the Kubernetes rollout revisions of a deployment
+func (h *DeploymentHandlers) GetDeploymentRevisions(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.service.ListRevisions(r.Context(), deploymentID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, revisions)
+}
+
+// GetRevisionDiff returns the image/env diff between two revisions (?from=1&to=3, to defaults to current)
+func (h *DeploymentHandlers) GetRevisionDiff(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	from, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	if err != nil {
+		http.Error(w, "from revision is required", http.StatusBadRequest)
+		return
+	}
+	var to int64
+	if toParam := r.URL.Query().Get("to"); toParam != "" {
+		if to, err = strconv.ParseInt(toParam, 10, 64); err != nil {
+			http.Error(w, "Invalid to revision", http.StatusBadRequest)
+			return
+		}
+	}
+
+	diff, err := h.service.GetRevisionDiff(r.Context(), deploymentID, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, diff)
+}
+
+// RollbackDeployment rolls a deployment back to a previous Kubernetes revision
+func (h *DeploymentHandlers) RollbackDeployment(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req deployments.RollbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Revision <= 0 {
+		http.Error(w, "Revision is required", http.StatusBadRequest)
+		return
+	}
+	if user := requestUser(r); user != "" {
+		req.User = user
+	}
+
+	revision, err := h.service.RollbackDeployment(r.Context(), deploymentID, req.Revision, req.User)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"status":   "rolled_back",
+		"message":  "Deployment rolled back successfully",
+		"revision": revision,
+	})
+}
+
+// GetRollout returns the latest canary or blue-green rollout of a deployment
+func (h *DeploymentHandlers) GetRollout(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	rollout, err := h.service.GetLatestRollout(r.Context(), deploymentID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, rollout)
+}
+
+// GetHealthGate returns the latest post-apply health gate of a deployment
+func (h *DeploymentHandlers) GetHealthGate(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	gate, err := h.service.GetLatestHealthGate(r.Context(), deploymentID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, gate)
+}
+
+// PromoteRollout shifts all traffic to the candidate of an in-progress rollout
+func (h *DeploymentHandlers) PromoteRollout(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	rollout, err := h.service.PromoteRollout(r.Context(), deploymentID, requestUser(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, rollout)
+}
+
+// AbortRollout removes the candidate of an in-progress rollout
+func (h *DeploymentHandlers) AbortRollout(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	rollout, err := h.service.AbortRollout(r.Context(), deploymentID, requestUser(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	writeJSON(w, rollout)
+}
+
 // ApplyDeployment manually applies a committed deployment to Kubernetes
 func (h *DeploymentHandlers) ApplyDeployment(w http.ResponseWriter, r *http.Request) {
 	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
@@ -454,28 +809,39 @@ func (h *DeploymentHandlers) ApplyDeployment(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	
-	var req struct {
-		AppliedBy string `json:"applied_by"`
-	}
+	var req apiclient.ApplyDeploymentRequest
 	
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 	
+	if user := requestUser(r); user != "" {
+		req.AppliedBy = user
+	}
 	if req.AppliedBy == "" {
 		req.AppliedBy = "system"
 	}
 	
+	// Disruption budget conflicts are reported with the result rather than blocking the apply
+	warnings, err := h.service.DisruptionBudgetWarnings(r.Context(), deploymentID)
+	if err != nil {
+		slog.Warn("Failed to check disruption budgets", "deployment", deploymentID, "error", err)
+	}
+
 	if err := h.service.ApplyDeployment(r.Context(), deploymentID, req.AppliedBy); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), approvalErrorStatus(err))
 		return
 	}
 	
-	writeJSON(w, map[string]string{
+	result := map[string]interface{}{
 		"status": "applied",
 		"message": "Deployment applied successfully",
-	})
+	}
+	if len(warnings) > 0 {
+		result["warnings"] = warnings
+	}
+	writeJSON(w, result)
 }
 
 // GetPendingDeployments returns all deployments with pending_apply status
@@ -506,19 +872,29 @@ func (h *DeploymentHandlers) BatchApplyDeployments(w http.ResponseWriter, r *htt
 		return
 	}
 	
+	if user := requestUser(r); user != "" {
+		req.AppliedBy = user
+	}
 	if req.AppliedBy == "" {
 		req.AppliedBy = "system"
 	}
 	
-	results := h.service.BatchApplyDeployments(r.Context(), req.DeploymentIDs, req.AppliedBy)
+	results, err := h.service.BatchApplyDeployments(r.Context(), req.DeploymentIDs, req.AppliedBy)
+	if err != nil {
+		http.Error(w, err.Error(), dependencyErrorStatus(err))
+		return
+	}
 	
-	// Count successes and failures
-	var successes, failures int
-	for _, err := range results {
-		if err == nil {
+	// Count outcomes; skipped deployments depend on one that failed or is missing
+	var successes, failures, skipped int
+	for _, result := range results {
+		switch result.Status {
+		case deployments.BatchApplied:
 			successes++
-		} else {
+		case deployments.BatchFailed:
 			failures++
+		default:
+			skipped++
 		}
 	}
 	
@@ -526,14 +902,222 @@ func (h *DeploymentHandlers) BatchApplyDeployments(w http.ResponseWriter, r *htt
 		"status":    "completed",
 		"successes": successes,
 		"failures":  failures,
+		"skipped":   skipped,
 		"results":   results,
 	}
 	
-	if failures > 0 {
+	w.Header().Set("Content-Type", "application/json")
+	if failures > 0 || skipped > 0 {
 		w.WriteHeader(http.StatusPartialContent)
 	}
-	
-	writeJSON(w, response)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetDeploymentDependencies returns the deployments a deployment is applied after
+func (h *DeploymentHandlers) GetDeploymentDependencies(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	deps, err := h.service.GetDependencies(r.Context(), deploymentID)
+	if err != nil {
+		http.Error(w, err.Error(), dependencyErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, deps)
+}
+
+// SetDeploymentDependencies replaces the deployments a deployment is applied after
+func (h *DeploymentHandlers) SetDeploymentDependencies(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		DependsOn []string `json:"depends_on"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	deps, err := h.service.SetDependencies(r.Context(), deploymentID, req.DependsOn)
+	if err != nil {
+		http.Error(w, err.Error(), dependencyErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, deps)
+}
+
+func dependencyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, deployments.ErrInvalidDependency), errors.Is(err, deployments.ErrDependencyCycle):
+		return http.StatusBadRequest
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// restartScheduleRequest is the body of a restart schedule
+type restartScheduleRequest struct {
+	Cron     string `json:"cron"`
+	Timezone string `json:"timezone,omitempty"`
+	Jitter   string `json:"jitter,omitempty"` // e.g. 15m
+	Enabled  *bool  `json:"enabled,omitempty"` // true by default
+}
+
+// GetRestartSchedule returns a deployment's restart schedule
+func (h *DeploymentHandlers) GetRestartSchedule(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := h.service.GetRestartSchedule(r.Context(), deploymentID)
+	if err != nil {
+		http.Error(w, err.Error(), restartScheduleErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, schedule)
+}
+
+// SetRestartSchedule creates or replaces a deployment's restart schedule
+func (h *DeploymentHandlers) SetRestartSchedule(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req restartScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	schedule := deployments.RestartSchedule{
+		DeploymentID: deploymentID,
+		Cron:         req.Cron,
+		Timezone:     req.Timezone,
+		Enabled:      req.Enabled == nil || *req.Enabled,
+	}
+	if req.Jitter != "" {
+		jitter, err := time.ParseDuration(req.Jitter)
+		if err != nil {
+			http.Error(w, "Invalid jitter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		schedule.JitterSeconds = int(jitter.Seconds())
+	}
+
+	saved, err := h.service.SetRestartSchedule(r.Context(), schedule, requestUser(r))
+	if err != nil {
+		http.Error(w, err.Error(), restartScheduleErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, saved)
+}
+
+// DeleteRestartSchedule removes a deployment's restart schedule
+func (h *DeploymentHandlers) DeleteRestartSchedule(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteRestartSchedule(r.Context(), deploymentID); err != nil {
+		http.Error(w, err.Error(), restartScheduleErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Restart schedule deleted"})
+}
+
+// ListRestartSchedules returns every restart schedule and whether they're paused
+func (h *DeploymentHandlers) ListRestartSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.service.ListRestartSchedules(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, schedules)
+}
+
+// PauseRestartSchedules stops all scheduled restarts until resumed
+func (h *DeploymentHandlers) PauseRestartSchedules(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.PauseRestartSchedules(r.Context(), requestUser(r)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Restart schedules paused"})
+}
+
+// ResumeRestartSchedules resumes scheduled restarts; runs missed while paused are skipped
+func (h *DeploymentHandlers) ResumeRestartSchedules(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.ResumeRestartSchedules(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Restart schedules resumed"})
+}
+
+func restartScheduleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, deployments.ErrInvalidRestartSchedule):
+		return http.StatusBadRequest
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CompareDeployment diffs a deployment, or its state after a history entry, against another
+// deployment or history entry: ?with={otherId}, ?history={entryId}, ?with_history={entryId}.
+// Without with, with_history is an entry of the same deployment.
+func (h *DeploymentHandlers) CompareDeployment(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	left := deployments.CompareTarget{DeploymentID: deploymentID, HistoryID: query.Get("history")}
+	right := deployments.CompareTarget{DeploymentID: query.Get("with"), HistoryID: query.Get("with_history")}
+	if right.DeploymentID == "" {
+		if right.HistoryID == "" {
+			http.Error(w, "with or with_history is required", http.StatusBadRequest)
+			return
+		}
+		right.DeploymentID = deploymentID
+	}
+
+	comparison, err := h.service.CompareDeployments(r.Context(), left, right)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, deployments.ErrHistoryEntryNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	writeJSON(w, comparison)
 }
 
 // GetDeploymentManifest returns the generated YAML manifest for a deployment
@@ -571,6 +1155,151 @@ func writeJSON(w http.ResponseWriter, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
+// requestUser returns the authenticated username, if any
+// GetDeploymentApprovals returns the approval rule, requester and decisions of a pending deployment
+func (h *DeploymentHandlers) GetDeploymentApprovals(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.service.GetApprovalStatus(r.Context(), deploymentID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, status)
+}
+
+// GetDeploymentPlan previews the objects applying a pending deployment creates or changes
+func (h *DeploymentHandlers) GetDeploymentPlan(w http.ResponseWriter, r *http.Request) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.service.PlanDeployment(r.Context(), deploymentID)
+	if err != nil {
+		http.Error(w, err.Error(), approvalErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, plan)
+}
+
+// ApproveDeployment records the caller's approval of a pending deployment
+func (h *DeploymentHandlers) ApproveDeployment(w http.ResponseWriter, r *http.Request) {
+	h.decideDeployment(w, r, h.service.ApproveDeployment)
+}
+
+// RejectDeployment records the caller's rejection of a pending deployment
+func (h *DeploymentHandlers) RejectDeployment(w http.ResponseWriter, r *http.Request) {
+	h.decideDeployment(w, r, h.service.RejectDeployment)
+}
+
+func (h *DeploymentHandlers) decideDeployment(w http.ResponseWriter, r *http.Request,
+	decide func(ctx context.Context, deploymentID, user, role, comment string) (*deployments.ApprovalStatus, error)) {
+	deploymentID := extractIDFromPath(r.URL.Path, "/api/deployments/")
+	if deploymentID == "" {
+		http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+		return
+	}
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Comment string `json:"comment"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+
+	status, err := decide(r.Context(), deploymentID, claims.Username, claims.Role, req.Comment)
+	if err != nil {
+		http.Error(w, err.Error(), approvalErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, status)
+}
+
+// ListApprovalRules returns the namespaces whose deployments need approval
+func (h *DeploymentHandlers) ListApprovalRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.ListApprovalRules(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, rules)
+}
+
+// SaveApprovalRule creates or replaces the approval rule for a namespace pattern
+func (h *DeploymentHandlers) SaveApprovalRule(w http.ResponseWriter, r *http.Request) {
+	var rule deployments.ApprovalRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := rule.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rule.CreatedBy = requestUser(r)
+
+	saved, err := h.service.SaveApprovalRule(r.Context(), rule)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, saved)
+}
+
+// DeleteApprovalRule removes an approval rule
+func (h *DeploymentHandlers) DeleteApprovalRule(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteApprovalRule(r.Context(), r.PathValue("id")); err != nil {
+		if errors.Is(err, deployments.ErrApprovalRuleNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// approvalErrorStatus maps approval failures to 403, deployments that aren't pending to 409
+// and anything else to 500
+func approvalErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, deployments.ErrApprovalRequired), errors.Is(err, deployments.ErrSelfApproval),
+		errors.Is(err, deployments.ErrApproverRole), errors.Is(err, deployments.ErrUnknownRequester):
+		return http.StatusForbidden
+	case errors.Is(err, deployments.ErrNotPendingApply):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func requestUser(r *http.Request) string {
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		return claims.Username
+	}
+	return ""
+}
+
 func extractIDFromPath(path, prefix string) string {
 	if !strings.HasPrefix(path, prefix) {
 		return ""
@@ -602,5 +1331,13 @@ func InitializeProviders() *providers.ProviderRegistry {
 		return registries.NewGenericProvider(config)
 	})
 
+	registry.Register("ghcr", func(config providers.RegistryConfig) (providers.RegistryProvider, error) {
+		return registries.NewGHCRProvider(config)
+	})
+
+	registry.Register("harbor", func(config providers.RegistryConfig) (providers.RegistryProvider, error) {
+		return registries.NewHarborProvider(config)
+	})
+
 	return registry
 }
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/alerts"
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/internal/smart"
+	"github.com/archellir/denshimon/internal/websocket"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+type DiskHealthHandlers struct {
+	service *smart.Service
+}
+
+func NewDiskHealthHandlers(service *smart.Service) *DiskHealthHandlers {
+	return &DiskHealthHandlers{service: service}
+}
+
+// ListDisks handles GET /api/k8s/nodes/disks?node=, the SMART health of the disks found at
+// the last check
+func (h *DiskHealthHandlers) ListDisks(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.Report()
+	if errors.Is(err, smart.ErrUnavailable) {
+		response.SendError(w, http.StatusServiceUnavailable, "Disk health not checked yet")
+		return
+	}
+	if node := r.URL.Query().Get("node"); node != "" {
+		filtered := *report
+		filtered.Disks = []smart.Disk{}
+		filtered.Counts = map[string]int{}
+		for _, disk := range report.Disks {
+			if disk.Node == node {
+				filtered.Disks = append(filtered.Disks, disk)
+				filtered.Counts[disk.Status]++
+			}
+		}
+		report = &filtered
+	}
+	response.SendSuccess(w, report)
+}
+
+// CheckDisks handles POST /api/k8s/nodes/disks/check, checking every disk in the background
+func (h *DiskHealthHandlers) CheckDisks(w http.ResponseWriter, r *http.Request) {
+	go func() {
+		if _, err := h.service.Check(context.Background()); err != nil {
+			slog.Warn("Failed to check disk health", "error", err)
+		}
+	}()
+	response.SendJSON(w, http.StatusAccepted, response.APIResponse{
+		Success: true,
+		Message: "Disk health check started",
+	})
+}
+
+// initDiskHealth deploys the smartctl DaemonSet when SMART_MONITORING_ENABLED=true and checks
+// node disks every SMART_CHECK_INTERVAL (default 1h), raising infrastructure alerts for disks
+// wearing out, running hot or reallocating sectors
+func initDiskHealth(k8sClient *k8s.Client, infrastructureHandlers *InfrastructureHandlers, hub *websocket.Hub,
+	alertManager *alerts.Manager) *DiskHealthHandlers {
+	if k8sClient == nil || os.Getenv("SMART_MONITORING_ENABLED") != "true" {
+		return nil
+	}
+
+	service := smart.NewService(k8sClient, k8s.SmartAgentConfig{
+		Namespace: os.Getenv("SMART_NAMESPACE"),
+		Image:     os.Getenv("SMART_IMAGE"),
+	})
+	interval := smart.DefaultInterval
+	if value := os.Getenv("SMART_CHECK_INTERVAL"); value != "" {
+		if d, err := parseTimeRange(value); err == nil {
+			interval = d
+		}
+	}
+	service.OnReport(diskAlerts(infrastructureHandlers, hub, alertManager))
+	service.Start(context.Background(), interval)
+	return NewDiskHealthHandlers(service)
+}
+
+// diskAlerts raises an alert for every disk graded warning or critical and clears it once the
+// disk is healthy again. Alerts of nodes that couldn't be checked are kept. Like storage
+// alerts, they're raised again only when their severity changes, and broadcast unless silenced.
+func diskAlerts(infrastructureHandlers *InfrastructureHandlers, hub *websocket.Hub,
+	alertManager *alerts.Manager) func(context.Context, *smart.Report) {
+	var mu sync.Mutex
+	raised := make(map[string]string) // alert ID to severity
+	nodes := make(map[string]string)  // alert ID to node
+
+	return func(ctx context.Context, report *smart.Report) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		failing := make(map[string]bool)
+		for _, disk := range report.Disks {
+			if disk.Status == smart.StatusOK {
+				continue
+			}
+			alertID := "disk-" + disk.Node + "-" + strings.TrimPrefix(disk.Device, "/dev/")
+			failing[alertID] = true
+			nodes[alertID] = disk.Node
+
+			alert := &InfrastructureAlert{
+				ID:        alertID,
+				Type:      "disk",
+				Severity:  disk.Status,
+				Message:   fmt.Sprintf("Disk %s (%s) on node %s: %s", disk.Device, disk.Model, disk.Node, strings.Join(disk.Problems, ", ")),
+				Timestamp: time.Now(),
+				Source:    "smart",
+			}
+			if raised[alertID] == disk.Status {
+				continue // already raised; replacing it would drop its acknowledgement
+			}
+			raised[alertID] = disk.Status
+			infrastructureHandlers.RaiseAlert(alert)
+
+			if alertManager != nil && alertManager.SilencedBy(ctx, infrastructureAlert(*alert)) != "" {
+				continue
+			}
+			if hub != nil {
+				hub.Broadcast(websocket.MessageTypeAlerts, alert)
+			}
+		}
+
+		for alertID := range raised {
+			if _, unchecked := report.Errors[nodes[alertID]]; unchecked || failing[alertID] {
+				continue
+			}
+			infrastructureHandlers.ClearAlert(alertID)
+			delete(raised, alertID)
+			delete(nodes, alertID)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/pkg/response"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// ListDisruptionBudgets handles GET /api/k8s/pdbs?namespace=
+func (h *KubernetesHandlers) ListDisruptionBudgets(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	budgets, err := h.k8sClient.ListPodDisruptionBudgets(r.Context(), r.URL.Query().Get("namespace"))
+	if err != nil {
+		sendDisruptionBudgetError(w, err)
+		return
+	}
+
+	response.SendSuccess(w, budgets.Items)
+}
+
+// GetDisruptionBudget handles GET /api/k8s/pdbs/{name}?namespace=default
+func (h *KubernetesHandlers) GetDisruptionBudget(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	budget, err := h.k8sClient.GetPodDisruptionBudget(r.Context(), queryNamespace(r), r.PathValue("name"))
+	if err != nil {
+		sendDisruptionBudgetError(w, err)
+		return
+	}
+
+	response.SendSuccess(w, budget)
+}
+
+// CreateDisruptionBudget handles POST /api/k8s/pdbs with a PodDisruptionBudget as JSON or YAML.
+// The namespace defaults to the namespace query parameter, then "default".
+func (h *KubernetesHandlers) CreateDisruptionBudget(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	budget, ok := decodeDisruptionBudget(w, r)
+	if !ok {
+		return
+	}
+	if budget.Namespace == "" {
+		budget.Namespace = queryNamespace(r)
+	}
+
+	created, err := h.k8sClient.CreatePodDisruptionBudget(r.Context(), budget)
+	if err != nil {
+		sendDisruptionBudgetError(w, err)
+		return
+	}
+
+	response.SendJSON(w, http.StatusCreated, response.APIResponse{
+		Success: true,
+		Data:    created,
+		Message: "Disruption budget created",
+	})
+}
+
+// UpdateDisruptionBudget handles PUT /api/k8s/pdbs/{name}?namespace=default
+func (h *KubernetesHandlers) UpdateDisruptionBudget(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	budget, ok := decodeDisruptionBudget(w, r)
+	if !ok {
+		return
+	}
+	budget.Name = r.PathValue("name")
+	budget.Namespace = queryNamespace(r)
+
+	updated, err := h.k8sClient.UpdatePodDisruptionBudget(r.Context(), budget)
+	if err != nil {
+		sendDisruptionBudgetError(w, err)
+		return
+	}
+
+	response.SendSuccess(w, updated)
+}
+
+// DeleteDisruptionBudget handles DELETE /api/k8s/pdbs/{name}?namespace=default
+func (h *KubernetesHandlers) DeleteDisruptionBudget(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	if err := h.k8sClient.DeletePodDisruptionBudget(r.Context(), queryNamespace(r), r.PathValue("name")); err != nil {
+		sendDisruptionBudgetError(w, err)
+		return
+	}
+
+	response.SendSuccessWithMessage(w, "Disruption budget deleted")
+}
+
+func decodeDisruptionBudget(w http.ResponseWriter, r *http.Request) (*policyv1.PodDisruptionBudget, bool) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxManifestSize))
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, "Failed to read request body")
+		return nil, false
+	}
+
+	var budget policyv1.PodDisruptionBudget
+	if err := yaml.UnmarshalStrict(data, &budget); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid disruption budget: "+err.Error())
+		return nil, false
+	}
+	if budget.Kind != "" && budget.Kind != "PodDisruptionBudget" {
+		response.SendError(w, http.StatusBadRequest, "Expected kind PodDisruptionBudget")
+		return nil, false
+	}
+	return &budget, true
+}
+
+func sendDisruptionBudgetError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, k8s.ErrInvalidBudget), apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		response.SendError(w, http.StatusBadRequest, err.Error())
+	case apierrors.IsNotFound(err):
+		response.SendError(w, http.StatusNotFound, err.Error())
+	case apierrors.IsAlreadyExists(err), apierrors.IsConflict(err):
+		response.SendError(w, http.StatusConflict, err.Error())
+	case apierrors.IsForbidden(err):
+		response.SendError(w, http.StatusForbidden, err.Error())
+	default:
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+	}
+}
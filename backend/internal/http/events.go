@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/archellir/denshimon/internal/audit"
+	"github.com/archellir/denshimon/internal/bus"
+	"github.com/archellir/denshimon/internal/deployments"
+	"github.com/archellir/denshimon/internal/gitops"
+	"github.com/archellir/denshimon/internal/monitors"
+	"github.com/archellir/denshimon/internal/providers/backup"
+	"github.com/archellir/denshimon/internal/websocket"
+)
+
+// publishEvents forwards the events clients follow live to the WebSocket hub
+func publishEvents(events *bus.Bus, hub *websocket.Hub) {
+	if hub == nil {
+		return
+	}
+	bus.On(events, func(change monitors.StateChange) {
+		hub.Broadcast(websocket.MessageTypeMonitors, change)
+	})
+}
+
+// auditEvents records deployments, rollbacks, backup runs and GitOps deployments in the
+// audit trail
+func auditEvents(events *bus.Bus, store *audit.Store) {
+	record := func(entry audit.Entry, details interface{}) {
+		if data, err := json.Marshal(details); err == nil {
+			entry.Details = string(data)
+		}
+		if err := store.Record(context.Background(), entry); err != nil {
+			slog.Warn("Failed to record audit entry", "action", entry.Action, "resource", entry.Resource, "error", err)
+		}
+	}
+	deployment := func(entry deployments.NamedHistory) {
+		record(audit.Entry{
+			User:     entry.User,
+			Action:   "deployment." + entry.Action,
+			Resource: "deployment/" + entry.DeploymentID,
+			Success:  entry.Success,
+			Message:  fmt.Sprintf("%s of %s/%s to %s", entry.Action, entry.Namespace, entry.Name, entry.NewImage),
+		}, entry)
+	}
+	bus.On(events, func(event deployments.Applied) { deployment(event.NamedHistory) })
+	bus.On(events, func(event deployments.RolledBack) { deployment(event.NamedHistory) })
+
+	bus.On(events, func(event backup.Finished) {
+		record(audit.Entry{
+			Action:   "backup.run",
+			Resource: "backup/" + event.JobID,
+			Success:  event.Status == backup.StatusCompleted,
+			Message:  fmt.Sprintf("Backup %s %s", event.JobName, event.Status),
+		}, event.History)
+	})
+
+	bus.On(events, func(event gitops.Deployed) {
+		record(audit.Entry{
+			User:     event.DeployedBy,
+			Action:   "gitops.deploy",
+			Resource: "application/" + event.ApplicationID,
+			Success:  true,
+			Message:  fmt.Sprintf("Deployed %s/%s with %s at %s", event.Namespace, event.Application, event.Image, event.GitHash),
+		}, event.ApplicationDeployment)
+	})
+}
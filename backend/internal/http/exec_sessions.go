@@ -0,0 +1,96 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/archellir/denshimon/internal/recordings"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+type ExecSessionHandlers struct {
+	store *recordings.Store
+}
+
+func NewExecSessionHandlers(store *recordings.Store) *ExecSessionHandlers {
+	return &ExecSessionHandlers{
+		store: store,
+	}
+}
+
+// ListSessions handles GET /api/k8s/exec-sessions
+func (h *ExecSessionHandlers) ListSessions(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Session recording not available")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	sessions, err := h.store.List(recordings.Filter{
+		Namespace: query.Get("namespace"),
+		Pod:       query.Get("pod"),
+		Node:      query.Get("node"),
+		User:      query.Get("user"),
+		Limit:     limit,
+	})
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.SendSuccess(w, sessions)
+}
+
+// GetSession handles GET /api/k8s/exec-sessions/{id}
+func (h *ExecSessionHandlers) GetSession(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Session recording not available")
+		return
+	}
+
+	session, err := h.store.Get(r.PathValue("id"))
+	if err != nil {
+		response.SendError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	response.SendSuccess(w, session)
+}
+
+// ReplaySession handles GET /api/k8s/exec-sessions/{id}/replay and streams the asciicast v2 file
+func (h *ExecSessionHandlers) ReplaySession(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Session recording not available")
+		return
+	}
+
+	id := r.PathValue("id")
+	file, err := h.store.Open(id)
+	if err != nil {
+		response.SendError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.Header().Set("Content-Disposition", "inline; filename=\""+id+".cast\"")
+	io.Copy(w, file)
+}
+
+// DeleteSession handles DELETE /api/k8s/exec-sessions/{id}
+func (h *ExecSessionHandlers) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Session recording not available")
+		return
+	}
+
+	if err := h.store.Delete(r.PathValue("id")); err != nil {
+		response.SendError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	response.SendSuccessWithMessage(w, "Session recording deleted successfully")
+}
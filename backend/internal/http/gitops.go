@@ -4,13 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/archellir/denshimon/internal/gitops"
+	"github.com/archellir/denshimon/internal/k8s"
 	"github.com/archellir/denshimon/pkg/response"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"log/slog"
 )
 
@@ -19,6 +23,9 @@ type GitOpsHandler struct {
 	service    *gitops.Service
 	syncEngine *gitops.SyncEngine
 	logger     *slog.Logger
+	k8sClient  *k8s.Client
+
+	webhookSecret string
 }
 
 // NewGitOpsHandler creates a new GitOps handler
@@ -73,10 +80,12 @@ func (h *GitOpsHandler) ListRepositories(w http.ResponseWriter, r *http.Request)
 // CreateRepository creates a new GitOps repository
 func (h *GitOpsHandler) CreateRepository(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Name        string `json:"name"`
-		URL         string `json:"url"`
-		Branch      string `json:"branch"`
-		Description string `json:"description"`
+		Name          string `json:"name"`
+		URL           string `json:"url"`
+		Branch        string `json:"branch"`
+		Description   string `json:"description"`
+		Provider      string `json:"provider"` // gitea, github, gitlab
+		WebhookSecret string `json:"webhook_secret"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -93,7 +102,12 @@ func (h *GitOpsHandler) CreateRepository(w http.ResponseWriter, r *http.Request)
 		req.Branch = "main"
 	}
 
-	repo, err := h.service.CreateRepository(r.Context(), req.Name, req.URL, req.Branch, req.Description)
+	if req.Provider != "" && !gitops.ValidProvider(gitops.Provider(req.Provider)) {
+		response.SendError(w, http.StatusBadRequest, "Provider must be one of gitea, github, gitlab")
+		return
+	}
+
+	repo, err := h.service.CreateRepository(r.Context(), req.Name, req.URL, req.Branch, req.Description, gitops.Provider(req.Provider), req.WebhookSecret)
 	if err != nil {
 		h.logger.Error("failed to create repository", "error", err)
 		response.SendError(w, http.StatusInternalServerError, "Failed to create repository")
@@ -111,13 +125,82 @@ func (h *GitOpsHandler) SyncRepository(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.SyncRepository(r.Context(), repoID); err != nil {
+	run, err := h.syncEngine.Sync(r.Context(), gitops.SyncRequest{Kind: gitops.SyncKindRepository, Target: repoID})
+	if err != nil {
+		if errors.Is(err, gitops.ErrRepositoryNotFound) {
+			response.SendError(w, http.StatusNotFound, "Repository not found")
+			return
+		}
 		h.logger.Error("failed to sync repository", "repo_id", repoID, "error", err)
 		response.SendError(w, http.StatusInternalServerError, "Failed to sync repository")
 		return
 	}
 
-	response.SendSuccess(w, map[string]string{"status": "synced"})
+	response.SendSuccess(w, map[string]interface{}{"status": "synced", "run": run})
+}
+
+// SetCredentialKey sets the key used to encrypt repository credentials at rest
+func (h *GitOpsHandler) SetCredentialKey(key string) {
+	if err := h.service.SetCredentialKey(context.Background(), key); err != nil {
+		h.logger.Error("failed to load repository credentials", "error", err)
+	}
+}
+
+// GetRepositoryCredential handles GET /api/gitops/repositories/{id}/credentials
+func (h *GitOpsHandler) GetRepositoryCredential(w http.ResponseWriter, r *http.Request) {
+	credential, err := h.service.GetRepositoryCredential(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.sendCredentialError(w, err, "Failed to get credential")
+		return
+	}
+
+	response.SendSuccess(w, credential)
+}
+
+// SetRepositoryCredential handles PUT /api/gitops/repositories/{id}/credentials.
+// Posting a deploy_key without a private key generates one; add the returned public key to the Git host.
+func (h *GitOpsHandler) SetRepositoryCredential(w http.ResponseWriter, r *http.Request) {
+	var input gitops.CredentialInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	credential, err := h.service.SetRepositoryCredential(r.Context(), r.PathValue("id"), input)
+	if err != nil {
+		h.sendCredentialError(w, err, "")
+		return
+	}
+
+	h.logger.Info("repository credential updated", "repo_id", credential.RepositoryID, "type", credential.Type)
+	response.SendSuccess(w, credential)
+}
+
+// DeleteRepositoryCredential handles DELETE /api/gitops/repositories/{id}/credentials
+func (h *GitOpsHandler) DeleteRepositoryCredential(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteRepositoryCredential(r.Context(), r.PathValue("id")); err != nil {
+		h.sendCredentialError(w, err, "Failed to delete credential")
+		return
+	}
+
+	response.SendSuccess(w, map[string]string{"status": "deleted"})
+}
+
+// sendCredentialError maps credential errors to status codes; an empty message reports validation errors verbatim
+func (h *GitOpsHandler) sendCredentialError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, gitops.ErrRepositoryNotFound):
+		response.SendError(w, http.StatusNotFound, "Repository not found")
+	case errors.Is(err, gitops.ErrCredentialNotFound):
+		response.SendError(w, http.StatusNotFound, "Credential not found")
+	case errors.Is(err, gitops.ErrCredentialKeyMissing):
+		response.SendError(w, http.StatusServiceUnavailable, "Credential encryption is not configured")
+	case message == "":
+		response.SendError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.logger.Error(strings.ToLower(message), "error", err)
+		response.SendError(w, http.StatusInternalServerError, message)
+	}
 }
 
 // InitializeRepository initializes the GitOps repository
@@ -173,6 +256,10 @@ func (h *GitOpsHandler) CreateApplication(w http.ResponseWriter, r *http.Request
 	app, err := h.service.CreateApplication(r.Context(), req.Name, req.Namespace, req.RepositoryID, 
 		req.Path, req.Image, req.Replicas, req.Resources, req.Environment)
 	if err != nil {
+		if errors.Is(err, gitops.ErrInvalidApplication) {
+			response.SendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.logger.Error("failed to create application", "error", err)
 		response.SendError(w, http.StatusInternalServerError, "Failed to create application")
 		return
@@ -206,6 +293,45 @@ func (h *GitOpsHandler) GetApplication(w http.ResponseWriter, r *http.Request) {
 	response.SendError(w, http.StatusNotFound, "Application not found")
 }
 
+// DeleteApplication handles DELETE /api/gitops/applications/{id}?prune=true. The manifests are
+// removed from the repository and the application is archived with its history; prune also
+// deletes its live resources.
+func (h *GitOpsHandler) DeleteApplication(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("id")
+	prune := r.URL.Query().Get("prune") == "true"
+
+	archived, err := h.service.DeleteApplication(r.Context(), appID, requestUser(r), prune)
+	if err != nil {
+		switch {
+		case errors.Is(err, gitops.ErrApplicationNotFound):
+			response.SendError(w, http.StatusNotFound, "Application not found")
+		case errors.Is(err, gitops.ErrClusterUnavailable):
+			response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available to prune resources")
+		case errors.Is(err, gitops.ErrInvalidApplication):
+			response.SendError(w, http.StatusBadRequest, err.Error())
+		default:
+			h.logger.Error("failed to delete application", "app_id", appID, "error", err)
+			response.SendError(w, http.StatusInternalServerError, "Failed to delete application")
+		}
+		return
+	}
+
+	h.logger.Info("application deleted", "app_id", appID, "git_hash", archived.GitHash, "pruned", len(archived.Pruned))
+	response.SendSuccess(w, archived)
+}
+
+// ListArchivedApplications handles GET /api/gitops/applications/archived
+func (h *GitOpsHandler) ListArchivedApplications(w http.ResponseWriter, r *http.Request) {
+	archived, err := h.service.ListArchivedApplications(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list archived applications", "error", err)
+		response.SendError(w, http.StatusInternalServerError, "Failed to list archived applications")
+		return
+	}
+
+	response.SendSuccess(w, archived)
+}
+
 // DeployApplication deploys an application
 func (h *GitOpsHandler) DeployApplication(w http.ResponseWriter, r *http.Request) {
 	appID := extractGitOpsIDFromPath(r.URL.Path, "/api/gitops/applications/")
@@ -229,6 +355,9 @@ func (h *GitOpsHandler) DeployApplication(w http.ResponseWriter, r *http.Request
 
 	deployment, err := h.service.DeployApplication(r.Context(), appID, req.DeployedBy)
 	if err != nil {
+		if sendPolicyError(w, err) {
+			return
+		}
 		h.logger.Error("failed to deploy application", "app_id", appID, "error", err)
 		response.SendError(w, http.StatusInternalServerError, "Failed to deploy application")
 		return
@@ -313,6 +442,275 @@ func (h *GitOpsHandler) ValidateManifest(w http.ResponseWriter, r *http.Request)
 	response.SendSuccess(w, map[string]string{"status": "valid"})
 }
 
+// LintManifest checks a manifest against the manifest policy without committing it
+func (h *GitOpsHandler) LintManifest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Manifest string `json:"manifest"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.service.LintManifest(r.Context(), req.Manifest)
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.SendSuccess(w, result)
+}
+
+// sendPolicyError answers 422 with the violations when err is a manifest policy block
+func sendPolicyError(w http.ResponseWriter, err error) bool {
+	var policyErr *gitops.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	response.SendJSON(w, http.StatusUnprocessableEntity, response.APIResponse{
+		Error: policyErr.Error(),
+		Data:  gitops.LintResult{Violations: policyErr.Violations, Blocked: true},
+	})
+	return true
+}
+
+// GenerateKustomization generates a Kustomize base and per-environment overlays for an application
+func (h *GitOpsHandler) GenerateKustomization(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Application gitops.Application     `json:"application"`
+		Overlays    []gitops.Overlay       `json:"overlays"`
+		Options     map[string]interface{} `json:"options"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Options == nil {
+		req.Options = make(map[string]interface{})
+	}
+
+	files, err := h.service.GenerateKustomization(&req.Application, req.Overlays, req.Options)
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.SendSuccess(w, map[string]interface{}{"files": files})
+}
+
+// RenderKustomization renders a kustomization server-side for preview. The request either carries
+// the files to render (as returned by GenerateKustomization) or an application to generate them from.
+func (h *GitOpsHandler) RenderKustomization(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Files       map[string]string      `json:"files"`
+		Path        string                 `json:"path"` // e.g. overlays/prod
+		Application *gitops.Application    `json:"application"`
+		Overlays    []gitops.Overlay       `json:"overlays"`
+		Options     map[string]interface{} `json:"options"`
+		Environment string                 `json:"environment"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	files, dir := req.Files, req.Path
+	if len(files) == 0 {
+		if req.Application == nil {
+			response.SendError(w, http.StatusBadRequest, "Either files or application is required")
+			return
+		}
+		if req.Options == nil {
+			req.Options = make(map[string]interface{})
+		}
+
+		var err error
+		if files, err = h.service.GenerateKustomization(req.Application, req.Overlays, req.Options); err != nil {
+			response.SendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		dir = "base"
+		if req.Environment != "" {
+			dir = "overlays/" + req.Environment
+		}
+	}
+	if dir == "" {
+		dir = "."
+	}
+
+	objects, err := gitops.RenderKustomizationFiles(files, dir)
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	manifest, err := gitops.RenderManifest(objects)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.SendSuccess(w, map[string]interface{}{
+		"manifest":  manifest,
+		"resources": len(objects),
+	})
+}
+
+// GetApplicationDrift handles GET /api/gitops/applications/{id}/drift?environment=prod.
+// The rendered overlay is dry-run applied and compared with the live objects in the cluster.
+func (h *GitOpsHandler) GetApplicationDrift(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	appID := r.PathValue("id")
+	environment := r.URL.Query().Get("environment")
+
+	objects, err := h.service.RenderApplication(r.Context(), appID, environment)
+	if err != nil {
+		if errors.Is(err, gitops.ErrApplicationNotFound) {
+			response.SendError(w, http.StatusNotFound, "Application not found")
+			return
+		}
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	manifest, err := gitops.RenderManifest(objects)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	results, err := h.k8sClient.ApplyManifests(r.Context(), []byte(manifest), k8s.ApplyOptions{DryRun: true, Force: true})
+	if err != nil {
+		h.logger.Error("failed to compare rendered manifests", "app_id", appID, "error", err)
+		response.SendError(w, http.StatusInternalServerError, "Failed to compare with cluster")
+		return
+	}
+
+	drifted := 0
+	for _, result := range results {
+		if result.Action != "unchanged" {
+			drifted++
+		}
+	}
+
+	response.SendSuccess(w, map[string]interface{}{
+		"application_id": appID,
+		"environment":    environment,
+		"in_sync":        drifted == 0,
+		"drifted":        drifted,
+		"resources":      results,
+		"manifest":       manifest,
+	})
+}
+
+// ListAppEnvironments handles GET /api/gitops/applications/{id}/environments
+func (h *GitOpsHandler) ListAppEnvironments(w http.ResponseWriter, r *http.Request) {
+	environments, err := h.service.ListAppEnvironments(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.sendEnvironmentError(w, err, "Failed to list environments")
+		return
+	}
+
+	response.SendSuccess(w, environments)
+}
+
+// SetAppEnvironment handles PUT /api/gitops/applications/{id}/environments/{env}
+// and commits the environment's image, replica, env and namespace overrides to its overlay
+func (h *GitOpsHandler) SetAppEnvironment(w http.ResponseWriter, r *http.Request) {
+	var overlay gitops.Overlay
+	if err := json.NewDecoder(r.Body).Decode(&overlay); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	overlay.Environment = r.PathValue("env")
+	overlay.Patches = nil
+
+	environment, err := h.service.SetAppEnvironment(r.Context(), r.PathValue("id"), overlay, requestUser(r))
+	if err != nil {
+		h.sendEnvironmentError(w, err, "Failed to update environment")
+		return
+	}
+
+	response.SendSuccess(w, environment)
+}
+
+// PromoteApplication handles POST /api/gitops/applications/{id}/promote.
+// The image running in the source environment is copied to the next environment's overlay.
+func (h *GitOpsHandler) PromoteApplication(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("id")
+
+	var req struct {
+		From string `json:"from"`
+		To   string `json:"to"` // defaults to the environment after from
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.From == "" {
+		response.SendError(w, http.StatusBadRequest, "Source environment is required")
+		return
+	}
+
+	image := h.runningImage(r.Context(), appID, req.From)
+
+	promotion, err := h.service.PromoteApplication(r.Context(), appID, req.From, req.To, image, requestUser(r))
+	if err != nil {
+		h.sendEnvironmentError(w, err, "Failed to promote application")
+		return
+	}
+
+	h.logger.Info("application promoted", "app_id", appID, "from", promotion.From, "to", promotion.To, "image", promotion.Image)
+	response.SendSuccess(w, promotion)
+}
+
+// runningImage returns the image of the application's Deployment in the cluster for an environment,
+// or "" when it cannot be determined and the declared image should be used
+func (h *GitOpsHandler) runningImage(ctx context.Context, appID, env string) string {
+	if h.k8sClient == nil {
+		return ""
+	}
+	source, err := h.service.GetAppEnvironment(ctx, appID, env)
+	if err != nil {
+		return ""
+	}
+
+	deployment, err := h.k8sClient.Clientset().AppsV1().Deployments(source.Namespace).Get(ctx, source.Name, metav1.GetOptions{})
+	if err != nil {
+		h.logger.Debug("running deployment not found, promoting declared image", "namespace", source.Namespace, "name", source.Name, "error", err)
+		return ""
+	}
+
+	containers := deployment.Spec.Template.Spec.Containers
+	for _, container := range containers {
+		if container.Name == source.Name {
+			return container.Image
+		}
+	}
+	if len(containers) > 0 {
+		return containers[0].Image
+	}
+	return ""
+}
+
+func (h *GitOpsHandler) sendEnvironmentError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, gitops.ErrApplicationNotFound):
+		response.SendError(w, http.StatusNotFound, "Application not found")
+	case errors.Is(err, gitops.ErrInvalidPromotion):
+		response.SendError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.logger.Error(strings.ToLower(message), "error", err)
+		response.SendError(w, http.StatusInternalServerError, message)
+	}
+}
+
 // GetSupportedTypes returns supported resource types
 func (h *GitOpsHandler) GetSupportedTypes(w http.ResponseWriter, r *http.Request) {
 	types := h.service.GetSupportedResourceTypes()
@@ -349,7 +747,7 @@ func (h *GitOpsHandler) StartSync(w http.ResponseWriter, r *http.Request) {
 	// Start sync in background
 	go func() {
 		ctx := context.Background()
-		if err := h.syncEngine.StartAutoSync(ctx, req.Config); err != nil {
+		if err := h.syncEngine.StartAutoSync(ctx, req.Config); err != nil && !errors.Is(err, context.Canceled) {
 			h.logger.Error("auto sync failed", "error", err)
 		}
 	}()
@@ -372,13 +770,14 @@ func (h *GitOpsHandler) ForceSync(w http.ResponseWriter, r *http.Request) {
 		req.Config = gitops.DefaultSyncConfig()
 	}
 
-	if err := h.syncEngine.ForceSync(r.Context(), req.Config); err != nil {
+	run, err := h.syncEngine.ForceSync(r.Context(), req.Config)
+	if err != nil {
 		h.logger.Error("failed to force sync", "error", err)
 		response.SendError(w, http.StatusInternalServerError, "Failed to force sync")
 		return
 	}
 
-	response.SendSuccess(w, map[string]string{"status": "completed"})
+	response.SendSuccess(w, map[string]interface{}{"status": "completed", "run": run})
 }
 
 // SyncApplication synchronizes a specific application
@@ -402,13 +801,46 @@ func (h *GitOpsHandler) SyncApplication(w http.ResponseWriter, r *http.Request)
 		req.Config = gitops.DefaultSyncConfig()
 	}
 
-	if err := h.syncEngine.SyncApplicationToGit(r.Context(), appID, req.Config); err != nil {
+	run, err := h.syncEngine.Sync(r.Context(), gitops.SyncRequest{
+		Kind:   gitops.SyncKindApplication,
+		Target: appID,
+		Config: req.Config,
+	})
+	if err != nil {
+		if sendPolicyError(w, err) {
+			return
+		}
 		h.logger.Error("failed to sync application", "app_id", appID, "error", err)
 		response.SendError(w, http.StatusInternalServerError, "Failed to sync application")
 		return
 	}
 
-	response.SendSuccess(w, map[string]string{"status": "synced"})
+	response.SendSuccess(w, map[string]interface{}{"status": "synced", "run": run})
+}
+
+// ListSyncRuns lists recorded sync runs, newest first
+func (h *GitOpsHandler) ListSyncRuns(w http.ResponseWriter, r *http.Request) {
+	filter := gitops.SyncRunFilter{
+		Repository: r.URL.Query().Get("repository"),
+		Status:     r.URL.Query().Get("status"),
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			response.SendError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		filter.Limit = n
+	}
+
+	runs, err := h.syncEngine.ListSyncRuns(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("failed to list sync runs", "error", err)
+		response.SendError(w, http.StatusInternalServerError, "Failed to list sync runs")
+		return
+	}
+
+	response.SendSuccess(w, runs)
 }
 
 // RollbackApplication rolls back an application to a previous deployment
@@ -440,6 +872,9 @@ func (h *GitOpsHandler) RollbackApplication(w http.ResponseWriter, r *http.Reque
 
 	deployment, err := h.service.RollbackApplication(r.Context(), appID, req.TargetDeploymentID, req.RolledBackBy)
 	if err != nil {
+		if sendPolicyError(w, err) {
+			return
+		}
 		h.logger.Error("failed to rollback application", "app_id", appID, "target_deployment", req.TargetDeploymentID, "error", err)
 		response.SendError(w, http.StatusInternalServerError, "Failed to rollback application")
 		return
@@ -474,16 +909,61 @@ func (h *GitOpsHandler) GetRollbackTargets(w http.ResponseWriter, r *http.Reques
 	response.SendSuccess(w, targets)
 }
 
-// ProcessWebhook handles incoming Git webhook notifications for auto-sync
+// maxWebhookSize limits the size of webhook payloads read for signature validation
+const maxWebhookSize = 5 << 20
+
+// SetK8sClient sets the Kubernetes client used for drift comparison and pruning deleted applications
+func (h *GitOpsHandler) SetK8sClient(k8sClient *k8s.Client) {
+	h.k8sClient = k8sClient
+	h.service.SetK8sClient(k8sClient)
+}
+
+// SetLintPolicy sets the policy manifests are checked against before they are committed
+func (h *GitOpsHandler) SetLintPolicy(policy gitops.LintPolicy) {
+	h.service.SetLintPolicy(policy)
+}
+
+// SetPolicyEvaluator adds external policies, such as Rego, to the manifest policy
+func (h *GitOpsHandler) SetPolicyEvaluator(evaluator gitops.PolicyEvaluator) {
+	h.service.SetPolicyEvaluator(evaluator)
+}
+
+// SetWebhookSecret sets the secret used to validate webhooks for repositories without their own secret
+func (h *GitOpsHandler) SetWebhookSecret(secret string) {
+	h.webhookSecret = secret
+}
+
+// ProcessWebhook handles incoming Gitea, GitHub and GitLab push/tag webhooks for auto-sync
 func (h *GitOpsHandler) ProcessWebhook(w http.ResponseWriter, r *http.Request) {
-	var payload gitops.WebhookPayload
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		h.logger.Error("failed to read webhook payload", "error", err)
+		response.SendError(w, http.StatusBadRequest, "Invalid webhook payload")
+		return
+	}
 
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		h.logger.Error("failed to decode webhook payload", "error", err)
+	provider := gitops.DetectProvider(r.Header)
+	payload, err := gitops.ParseWebhook(provider, r.Header, body)
+	if errors.Is(err, gitops.ErrUnsupportedEvent) {
+		h.logger.Debug("ignoring webhook event", "provider", provider, "error", err)
+		response.SendSuccess(w, map[string]string{"status": "ignored", "provider": string(provider)})
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to decode webhook payload", "provider", provider, "error", err)
 		response.SendError(w, http.StatusBadRequest, "Invalid webhook payload")
 		return
 	}
 
+	if payload.Event == gitops.WebhookEventPing {
+		if err := gitops.ValidateWebhookSecret(provider, r.Header, body, h.webhookSecret); err != nil {
+			response.SendError(w, http.StatusUnauthorized, "Invalid webhook signature")
+			return
+		}
+		response.SendSuccess(w, map[string]string{"status": "pong", "provider": string(provider)})
+		return
+	}
+
 	// Validate webhook payload
 	if payload.Repository.FullName == "" || payload.Ref == "" {
 		h.logger.Error("incomplete webhook payload", "repository", payload.Repository.FullName, "ref", payload.Ref)
@@ -491,7 +971,33 @@ func (h *GitOpsHandler) ProcessWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	repo, err := h.service.FindWebhookRepository(r.Context(), payload)
+	if err != nil {
+		h.logger.Error("failed to look up webhook repository", "error", err)
+		response.SendError(w, http.StatusInternalServerError, "Failed to process webhook")
+		return
+	}
+
+	secret := h.webhookSecret
+	if repo != nil {
+		if repo.Provider != provider {
+			h.logger.Warn("webhook provider mismatch", "repository", repo.Name, "expected", repo.Provider, "got", provider)
+			response.SendError(w, http.StatusBadRequest, "Webhook provider does not match repository")
+			return
+		}
+		if repo.WebhookSecret != "" {
+			secret = repo.WebhookSecret
+		}
+	}
+	if err := gitops.ValidateWebhookSecret(provider, r.Header, body, secret); err != nil {
+		h.logger.Warn("rejected webhook", "provider", provider, "repository", payload.Repository.FullName, "error", err)
+		response.SendError(w, http.StatusUnauthorized, "Invalid webhook signature")
+		return
+	}
+
 	h.logger.Info("received webhook", 
+		"provider", provider,
+		"event", payload.Event,
 		"repository", payload.Repository.FullName,
 		"ref", payload.Ref,
 		"pusher", payload.Pusher.Name)
@@ -506,7 +1012,7 @@ func (h *GitOpsHandler) ProcessWebhook(w http.ResponseWriter, r *http.Request) {
 			ProcessedAt: time.Now(),
 		}
 
-		if err := h.syncEngine.ProcessWebhook(ctx, &payload); err != nil {
+		if err := h.syncEngine.ProcessWebhook(ctx, payload); err != nil {
 			h.logger.Error("webhook processing failed", "error", err, "repository", payload.Repository.FullName)
 			result.Error = err.Error()
 		} else {
@@ -518,6 +1024,15 @@ func (h *GitOpsHandler) ProcessWebhook(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		if repo != nil && payload.TriggersSync(repo) {
+			run := h.syncEngine.Enqueue(gitops.SyncRequest{
+				Kind:    gitops.SyncKindRepository,
+				Target:  repo.ID,
+				Trigger: "webhook",
+			})
+			h.logger.Info("webhook repository sync queued", "repository", repo.Name, "run_id", run.ID)
+		}
+
 		h.logger.Info("webhook processing completed", 
 			"repository", result.Repository,
 			"triggered", result.Triggered,
@@ -527,6 +1042,8 @@ func (h *GitOpsHandler) ProcessWebhook(w http.ResponseWriter, r *http.Request) {
 	// Return immediate response to avoid webhook timeout
 	response.SendSuccess(w, map[string]string{
 		"status":     "received",
+		"provider":   string(provider),
+		"event":      payload.Event,
 		"repository": payload.Repository.FullName,
 		"ref":        payload.Ref,
 		"processed":  "async",
@@ -541,7 +1058,12 @@ func (h *GitOpsHandler) ConfigureWebhook(w http.ResponseWriter, r *http.Request)
 		"content_type": "application/json",
 		"events": []string{
 			"push",
-			"repository",
+			"tag",
+		},
+		"providers": map[string]interface{}{
+			"gitea":  map[string]string{"events": "Push", "secret_header": "X-Gitea-Signature"},
+			"github": map[string]string{"events": "push", "secret_header": "X-Hub-Signature-256"},
+			"gitlab": map[string]string{"events": "Push events, Tag push events", "secret_header": "X-Gitlab-Token"},
 		},
 		"description": "GitOps auto-sync webhook for base infrastructure repository",
 		"example_payload": map[string]interface{}{
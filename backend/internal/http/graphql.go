@@ -0,0 +1,475 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/deployments"
+	"github.com/archellir/denshimon/internal/gitops"
+	"github.com/archellir/denshimon/internal/graphql"
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/internal/metrics"
+	"github.com/archellir/denshimon/pkg/response"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// GraphQLHandlers serves queries over the services behind the REST endpoints, so a view can
+// fetch pods, deployments, metrics, GitOps applications and alerts in one request
+type GraphQLHandlers struct {
+	schema            *graphql.Schema
+	k8sClient         *k8s.Client
+	metricsService    *metrics.Service
+	deploymentService *deployments.Service
+	gitopsService     *gitops.Service
+	infrastructure    *InfrastructureHandlers
+}
+
+func NewGraphQLHandlers(k8sClient *k8s.Client, metricsService *metrics.Service, deploymentService *deployments.Service, gitopsService *gitops.Service, infrastructure *InfrastructureHandlers) (*GraphQLHandlers, error) {
+	h := &GraphQLHandlers{
+		k8sClient:         k8sClient,
+		metricsService:    metricsService,
+		deploymentService: deploymentService,
+		gitopsService:     gitopsService,
+		infrastructure:    infrastructure,
+	}
+	schema, err := graphql.NewSchema(h.queryType())
+	if err != nil {
+		return nil, err
+	}
+	h.schema = schema
+	return h, nil
+}
+
+// Execute handles POST /api/graphql with {"query", "variables", "operationName"}, and GET with
+// the same as query parameters. Field errors come back next to the partial data with status
+// 200; requests that can't be executed get status 400.
+func (h *GraphQLHandlers) Execute(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	if r.Method == http.MethodGet {
+		params := r.URL.Query()
+		req.Query = params.Get("query")
+		req.OperationName = params.Get("operationName")
+		if variables := params.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				response.SendError(w, http.StatusBadRequest, "Invalid variables")
+				return
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		response.SendError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	result := h.schema.Execute(r.Context(), req)
+	status := http.StatusOK
+	if result.Data == nil {
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetSchema handles GET /api/graphql/schema, describing the types in the GraphQL schema language
+func (h *GraphQLHandlers) GetSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(h.schema.SDL()))
+}
+
+func (h *GraphQLHandlers) queryType() *graphql.Object {
+	str, integer, float, boolean := graphql.String, graphql.Int, graphql.Float, graphql.Boolean
+	nonNull, list := graphql.NewNonNull, graphql.NewList
+	listOf := func(t graphql.Type) graphql.Type { return nonNull(list(nonNull(t))) }
+	// Lists fetched by a resolver are nullable, so a failing service leaves the rest of the
+	// result intact
+	fetched := func(t graphql.Type) graphql.Type { return list(nonNull(t)) }
+
+	usage := &graphql.Object{Name: "ResourceUsage", Fields: graphql.Fields{
+		"used":          {Type: float},
+		"total":         {Type: float},
+		"available":     {Type: float},
+		"usage_percent": {Type: float},
+	}}
+
+	podMetrics := &graphql.Object{Name: "PodMetrics", Fields: graphql.Fields{
+		"cpu_usage":     {Type: usage, Description: "CPU in millicores"},
+		"memory_usage":  {Type: usage, Description: "Memory in bytes"},
+		"usage_source":  {Type: str},
+		"restart_count": {Type: integer},
+	}}
+
+	pod := &graphql.Object{Name: "Pod", Fields: graphql.Fields{
+		"name":        {Type: nonNull(str)},
+		"namespace":   {Type: nonNull(str)},
+		"status":      {Type: str},
+		"ready":       {Type: str, Description: "Ready containers, e.g. 1/2"},
+		"restarts":    {Type: integer},
+		"age":         {Type: str},
+		"node":        {Type: str},
+		"ip":          {Type: str},
+		"labels":      {Type: graphql.JSON},
+		"annotations": {Type: graphql.JSON},
+		"metrics":     {Type: podMetrics, Resolve: h.resolvePodMetrics},
+	}}
+
+	deploymentPod := &graphql.Object{Name: "DeploymentPod", Fields: graphql.Fields{
+		"name":       {Type: nonNull(str)},
+		"phase":      {Type: str},
+		"ready":      {Type: boolean},
+		"restarts":   {Type: integer},
+		"node_name":  {Type: str},
+		"ip":         {Type: str},
+		"created_at": {Type: str},
+	}}
+
+	deploymentHistory := &graphql.Object{Name: "DeploymentHistory", Fields: graphql.Fields{
+		"id":           {Type: nonNull(graphql.ID)},
+		"action":       {Type: str},
+		"old_image":    {Type: str},
+		"new_image":    {Type: str},
+		"old_replicas": {Type: integer},
+		"new_replicas": {Type: integer},
+		"success":      {Type: boolean},
+		"error":        {Type: str},
+		"user":         {Type: str},
+		"timestamp":    {Type: str},
+	}}
+
+	deployment := &graphql.Object{Name: "Deployment", Fields: graphql.Fields{
+		"id":                 {Type: nonNull(graphql.ID)},
+		"name":               {Type: nonNull(str)},
+		"namespace":          {Type: nonNull(str)},
+		"image":              {Type: str},
+		"replicas":           {Type: integer},
+		"available_replicas": {Type: integer},
+		"ready_replicas":     {Type: integer},
+		"status":             {Type: str},
+		"source":             {Type: str},
+		"service_type":       {Type: str},
+		"strategy":           {Type: graphql.JSON},
+		"environment":        {Type: graphql.JSON},
+		"node_distribution":  {Type: graphql.JSON},
+		"pods":               {Type: listOf(deploymentPod)},
+		"applied_by":         {Type: str},
+		"applied_at":         {Type: str},
+		"created_at":         {Type: str},
+		"updated_at":         {Type: str},
+		"history": {
+			Type:        fetched(deploymentHistory),
+			Description: "Changes, newest first",
+			Args:        graphql.Args{"limit": {Type: integer, Default: 20}},
+			Resolve:     h.resolveDeploymentHistory,
+		},
+	}}
+
+	nodeMetrics := &graphql.Object{Name: "NodeMetrics", Fields: graphql.Fields{
+		"name":          {Type: nonNull(str)},
+		"status":        {Type: str},
+		"cpu_usage":     {Type: usage},
+		"memory_usage":  {Type: usage},
+		"storage_usage": {Type: usage},
+		"usage_source":  {Type: str},
+		"pod_count":     {Type: integer},
+		"age":           {Type: str},
+	}}
+
+	namespaceMetrics := &graphql.Object{Name: "NamespaceMetrics", Fields: graphql.Fields{
+		"name":           {Type: nonNull(str)},
+		"pod_count":      {Type: integer},
+		"cpu_usage":      {Type: usage},
+		"memory_usage":   {Type: usage},
+		"status":         {Type: str},
+		"age":            {Type: str},
+		"quota_warnings": {Type: graphql.JSON},
+	}}
+
+	clusterMetrics := &graphql.Object{Name: "ClusterMetrics", Fields: graphql.Fields{
+		"timestamp":         {Type: str},
+		"total_nodes":       {Type: integer},
+		"ready_nodes":       {Type: integer},
+		"total_pods":        {Type: integer},
+		"running_pods":      {Type: integer},
+		"pending_pods":      {Type: integer},
+		"failed_pods":       {Type: integer},
+		"total_namespaces":  {Type: integer},
+		"cpu_usage":         {Type: usage},
+		"memory_usage":      {Type: usage},
+		"storage_usage":     {Type: usage},
+		"usage_source":      {Type: str},
+		"storage_source":    {Type: str},
+		"network_metrics":   {Type: graphql.JSON},
+		"node_metrics":      {Type: listOf(nodeMetrics)},
+		"namespace_metrics": {Type: listOf(namespaceMetrics)},
+	}}
+
+	applicationDeployment := &graphql.Object{Name: "ApplicationDeployment", Fields: graphql.Fields{
+		"id":          {Type: nonNull(graphql.ID)},
+		"image":       {Type: str},
+		"replicas":    {Type: integer},
+		"git_hash":    {Type: str},
+		"status":      {Type: str},
+		"message":     {Type: str},
+		"deployed_by": {Type: str},
+		"deployed_at": {Type: str},
+	}}
+
+	application := &graphql.Object{Name: "Application", Fields: graphql.Fields{
+		"id":            {Type: nonNull(graphql.ID)},
+		"name":          {Type: nonNull(str)},
+		"namespace":     {Type: str},
+		"repository_id": {Type: str},
+		"path":          {Type: str},
+		"image":         {Type: str},
+		"replicas":      {Type: integer},
+		"status":        {Type: str},
+		"health":        {Type: str},
+		"sync_status":   {Type: str},
+		"last_deployed": {Type: str},
+		"created_at":    {Type: str},
+		"updated_at":    {Type: str},
+		"deployments": {
+			Type:        fetched(applicationDeployment),
+			Description: "Deployments, newest first",
+			Args:        graphql.Args{"limit": {Type: integer, Default: 20}},
+			Resolve:     h.resolveApplicationDeployments,
+		},
+	}}
+
+	alert := &graphql.Object{Name: "Alert", Fields: graphql.Fields{
+		"id":        {Type: nonNull(graphql.ID)},
+		"source":    {Type: nonNull(str), Description: "infrastructure or gitops"},
+		"type":      {Type: str},
+		"severity":  {Type: str},
+		"title":     {Type: str},
+		"message":   {Type: str},
+		"status":    {Type: str},
+		"timestamp": {Type: str},
+	}}
+
+	return &graphql.Object{Name: "Query", Fields: graphql.Fields{
+		"pods": {
+			Type:        fetched(pod),
+			Description: "Pods of a namespace, or of all namespaces",
+			Args:        graphql.Args{"namespace": {Type: str}},
+			Resolve:     h.resolvePods,
+		},
+		"pod": {
+			Type:    pod,
+			Args:    graphql.Args{"namespace": {Type: str, Default: "default"}, "name": {Type: nonNull(str)}},
+			Resolve: h.resolvePod,
+		},
+		"deployments": {
+			Type:        fetched(deployment),
+			Description: "Managed deployments of a namespace, or of all namespaces",
+			Args:        graphql.Args{"namespace": {Type: str}},
+			Resolve:     h.resolveDeployments,
+		},
+		"deployment": {
+			Type:    deployment,
+			Args:    graphql.Args{"id": {Type: nonNull(graphql.ID)}},
+			Resolve: h.resolveDeployment,
+		},
+		"cluster_metrics": {
+			Type:    clusterMetrics,
+			Resolve: h.resolveClusterMetrics,
+		},
+		"applications": {
+			Type:        fetched(application),
+			Description: "GitOps applications, newest first",
+			Args:        graphql.Args{"namespace": {Type: str}},
+			Resolve:     h.resolveApplications,
+		},
+		"application": {
+			Type:    application,
+			Args:    graphql.Args{"id": {Type: nonNull(graphql.ID)}},
+			Resolve: h.resolveApplication,
+		},
+		"alerts": {
+			Type:        fetched(alert),
+			Description: "Open infrastructure and GitOps alerts, newest first",
+			Args:        graphql.Args{"severity": {Type: str}, "source": {Type: str}},
+			Resolve:     h.resolveAlerts,
+		},
+	}}
+}
+
+var errK8sUnavailable = errors.New("Kubernetes client not available")
+
+func (h *GraphQLHandlers) resolvePods(p graphql.ResolveParams) (interface{}, error) {
+	if h.k8sClient == nil {
+		return nil, errK8sUnavailable
+	}
+	pods, err := h.k8sClient.ListPods(p.Context, p.String("namespace"))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]PodInfo, 0, len(pods.Items))
+	for i := range pods.Items {
+		infos = append(infos, newPodInfo(&pods.Items[i]))
+	}
+	return infos, nil
+}
+
+func (h *GraphQLHandlers) resolvePod(p graphql.ResolveParams) (interface{}, error) {
+	if h.k8sClient == nil {
+		return nil, errK8sUnavailable
+	}
+	pod, err := h.k8sClient.GetPod(p.Context, p.String("namespace"), p.String("name"))
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newPodInfo(pod), nil
+}
+
+func (h *GraphQLHandlers) resolvePodMetrics(p graphql.ResolveParams) (interface{}, error) {
+	namespace, _ := p.Source["namespace"].(string)
+	name, _ := p.Source["name"].(string)
+	return h.metricsService.GetPodMetrics(p.Context, namespace, name)
+}
+
+func (h *GraphQLHandlers) resolveDeployments(p graphql.ResolveParams) (interface{}, error) {
+	return h.deploymentService.ListDeployments(p.Context, p.String("namespace"))
+}
+
+func (h *GraphQLHandlers) resolveDeployment(p graphql.ResolveParams) (interface{}, error) {
+	deployment, err := h.deploymentService.GetDeployment(p.Context, p.String("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return deployment, err
+}
+
+func (h *GraphQLHandlers) resolveDeploymentHistory(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Source["id"].(string)
+	history, err := h.deploymentService.GetDeploymentHistory(p.Context, id)
+	if err != nil {
+		return nil, err
+	}
+	if limit := p.Int("limit", 20); len(history) > limit {
+		history = history[:limit]
+	}
+	return history, nil
+}
+
+func (h *GraphQLHandlers) resolveClusterMetrics(p graphql.ResolveParams) (interface{}, error) {
+	return h.metricsService.GetClusterMetrics(p.Context)
+}
+
+func (h *GraphQLHandlers) resolveApplications(p graphql.ResolveParams) (interface{}, error) {
+	apps, err := h.gitopsService.ListApplications(p.Context)
+	if err != nil {
+		return nil, err
+	}
+	namespace := p.String("namespace")
+	if namespace == "" {
+		return apps, nil
+	}
+	filtered := make([]gitops.Application, 0, len(apps))
+	for _, app := range apps {
+		if app.Namespace == namespace {
+			filtered = append(filtered, app)
+		}
+	}
+	return filtered, nil
+}
+
+func (h *GraphQLHandlers) resolveApplication(p graphql.ResolveParams) (interface{}, error) {
+	apps, err := h.gitopsService.ListApplications(p.Context)
+	if err != nil {
+		return nil, err
+	}
+	for _, app := range apps {
+		if app.ID == p.String("id") {
+			return app, nil
+		}
+	}
+	return nil, nil
+}
+
+func (h *GraphQLHandlers) resolveApplicationDeployments(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Source["id"].(string)
+	records, err := h.gitopsService.GetDeploymentHistory(p.Context, id)
+	if err != nil {
+		return nil, err
+	}
+	if limit := p.Int("limit", 20); len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+// graphQLAlert is the common shape of infrastructure and GitOps alerts
+type graphQLAlert struct {
+	ID        string    `json:"id"`
+	Source    string    `json:"source"`
+	Type      string    `json:"type"`
+	Severity  string    `json:"severity"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func (h *GraphQLHandlers) resolveAlerts(p graphql.ResolveParams) (interface{}, error) {
+	source := p.String("source")
+	var alerts []graphQLAlert
+
+	if source == "" || source == "infrastructure" {
+		for _, alert := range h.infrastructure.ListAlerts() {
+			status := "active"
+			if alert.Acknowledged {
+				status = "acknowledged"
+			}
+			alerts = append(alerts, graphQLAlert{
+				ID:        alert.ID,
+				Source:    "infrastructure",
+				Type:      alert.Type,
+				Severity:  alert.Severity,
+				Title:     alert.Source,
+				Message:   alert.Message,
+				Status:    status,
+				Timestamp: alert.Timestamp,
+			})
+		}
+	}
+	if source == "" || source == "gitops" {
+		gitopsAlerts, err := h.gitopsService.ListAlerts(p.Context)
+		if err != nil {
+			return nil, err
+		}
+		for _, alert := range gitopsAlerts {
+			alerts = append(alerts, graphQLAlert{
+				ID:        alert.ID,
+				Source:    "gitops",
+				Type:      alert.Type,
+				Severity:  alert.Severity,
+				Title:     alert.Title,
+				Message:   alert.Message,
+				Status:    alert.Status,
+				Timestamp: alert.CreatedAt,
+			})
+		}
+	}
+
+	severity := p.String("severity")
+	filtered := alerts[:0]
+	for _, alert := range alerts {
+		if severity == "" || alert.Severity == severity {
+			filtered = append(filtered, alert)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool { return filtered[i].Timestamp.After(filtered[j].Timestamp) })
+	return filtered, nil
+}
@@ -0,0 +1,213 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/archellir/denshimon/internal/alerts"
+	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/internal/database"
+	"github.com/archellir/denshimon/internal/incidents"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+// IncidentHandlers serves incidents, their updates and linked alerts, and the status page
+type IncidentHandlers struct {
+	manager *incidents.Manager
+}
+
+func NewIncidentHandlers(manager *incidents.Manager) *IncidentHandlers {
+	return &IncidentHandlers{manager: manager}
+}
+
+// incidentRequest is an incident with the dedup keys of the alerts to group into it
+type incidentRequest struct {
+	Title       string   `json:"title"`
+	Status      string   `json:"status,omitempty"`
+	Impact      string   `json:"impact,omitempty"`
+	Summary     string   `json:"summary,omitempty"`
+	Services    []string `json:"services,omitempty"`
+	Deployments []string `json:"deployments,omitempty"`
+	DedupKeys   []string `json:"dedup_keys,omitempty"` // only when creating
+}
+
+func (req incidentRequest) incident() incidents.Incident {
+	return incidents.Incident{
+		Title:       req.Title,
+		Status:      req.Status,
+		Impact:      req.Impact,
+		Summary:     req.Summary,
+		Services:    req.Services,
+		Deployments: req.Deployments,
+	}
+}
+
+// linkAlertsRequest names the alerts to group into an incident
+type linkAlertsRequest struct {
+	DedupKeys []string `json:"dedup_keys"`
+}
+
+// ListIncidents handles GET /api/incidents?status=&open=true&limit=
+func (h *IncidentHandlers) ListIncidents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := incidents.ListOptions{Status: query.Get("status"), Open: query.Get("open") == "true"}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			response.SendError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		opts.Limit = limit
+	}
+
+	list, err := h.manager.List(r.Context(), opts)
+	if err != nil {
+		sendIncidentError(w, err)
+		return
+	}
+	response.SendSuccess(w, list)
+}
+
+// CreateIncident handles POST /api/incidents, grouping the alerts with the given dedup keys
+// into the new incident
+func (h *IncidentHandlers) CreateIncident(w http.ResponseWriter, r *http.Request) {
+	var req incidentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	created, err := h.manager.Create(r.Context(), req.incident(), req.DedupKeys, incidentUser(r))
+	if err != nil {
+		sendIncidentError(w, err)
+		return
+	}
+	response.SendSuccess(w, created)
+}
+
+// GetIncident handles GET /api/incidents/{id}
+func (h *IncidentHandlers) GetIncident(w http.ResponseWriter, r *http.Request) {
+	incident, err := h.manager.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		sendIncidentError(w, err)
+		return
+	}
+	response.SendSuccess(w, incident)
+}
+
+// UpdateIncident handles PUT /api/incidents/{id}; the status changes by posting an update
+func (h *IncidentHandlers) UpdateIncident(w http.ResponseWriter, r *http.Request) {
+	var req incidentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	updated, err := h.manager.Update(r.Context(), r.PathValue("id"), req.incident())
+	if err != nil {
+		sendIncidentError(w, err)
+		return
+	}
+	response.SendSuccess(w, updated)
+}
+
+// DeleteIncident handles DELETE /api/incidents/{id}
+func (h *IncidentHandlers) DeleteIncident(w http.ResponseWriter, r *http.Request) {
+	if err := h.manager.Delete(r.Context(), r.PathValue("id")); err != nil {
+		sendIncidentError(w, err)
+		return
+	}
+	response.SendSuccessWithMessage(w, "Incident deleted")
+}
+
+// PostIncidentUpdate handles POST /api/incidents/{id}/updates, posting a message and
+// optionally moving the incident to a new status
+func (h *IncidentHandlers) PostIncidentUpdate(w http.ResponseWriter, r *http.Request) {
+	var update incidents.Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	incident, err := h.manager.PostUpdate(r.Context(), r.PathValue("id"), update, incidentUser(r))
+	if err != nil {
+		sendIncidentError(w, err)
+		return
+	}
+	response.SendSuccess(w, incident)
+}
+
+// LinkIncidentAlerts handles POST /api/incidents/{id}/alerts
+func (h *IncidentHandlers) LinkIncidentAlerts(w http.ResponseWriter, r *http.Request) {
+	var req linkAlertsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	incident, err := h.manager.LinkAlerts(r.Context(), r.PathValue("id"), req.DedupKeys, incidentUser(r))
+	if err != nil {
+		sendIncidentError(w, err)
+		return
+	}
+	response.SendSuccess(w, incident)
+}
+
+// UnlinkIncidentAlert handles DELETE /api/incidents/{id}/alerts/{key...}; dedup keys
+// contain slashes
+func (h *IncidentHandlers) UnlinkIncidentAlert(w http.ResponseWriter, r *http.Request) {
+	if err := h.manager.UnlinkAlert(r.Context(), r.PathValue("id"), r.PathValue("key")); err != nil {
+		sendIncidentError(w, err)
+		return
+	}
+	response.SendSuccessWithMessage(w, "Alert unlinked")
+}
+
+// GetStatusPage handles GET /api/status: the overall status with open and recently
+// resolved incidents
+func (h *IncidentHandlers) GetStatusPage(w http.ResponseWriter, r *http.Request) {
+	page, err := h.manager.StatusPage(r.Context())
+	if err != nil {
+		sendIncidentError(w, err)
+		return
+	}
+	response.SendSuccess(w, page)
+}
+
+func incidentUser(r *http.Request) string {
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		return claims.Username
+	}
+	return ""
+}
+
+func sendIncidentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, incidents.ErrNotFound):
+		response.SendError(w, http.StatusNotFound, "Not found")
+	case errors.Is(err, incidents.ErrInvalid):
+		response.SendError(w, http.StatusBadRequest, err.Error())
+	default:
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// initIncidents creates the incident manager, grouping alerts of the inbox when the alert
+// manager is available
+func initIncidents(db *database.SQLiteDB, alertManager *alerts.Manager) *IncidentHandlers {
+	store, err := incidents.NewStore(db.DB)
+	if err != nil {
+		slog.Warn("Incident management disabled", "error", err)
+		return nil
+	}
+	var alertLister incidents.AlertLister
+	if alertManager != nil {
+		alertLister = alertManager
+	}
+	return NewIncidentHandlers(incidents.NewManager(store, alertLister))
+}
+
+// statusPagePublic is whether the status page is served without authentication
+func statusPagePublic() bool {
+	return os.Getenv("STATUS_PAGE_PUBLIC") == "true"
+}
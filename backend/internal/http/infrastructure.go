@@ -1,10 +1,17 @@
 package http
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 
+	"github.com/archellir/denshimon/internal/discovery"
 	"github.com/archellir/denshimon/pkg/response"
 )
 
@@ -12,6 +19,8 @@ import (
 type InfrastructureHandlers struct {
 	mu     sync.RWMutex
 	alerts map[string]*InfrastructureAlert
+
+	serviceDiscovery *discovery.Service
 }
 
 // InfrastructureAlert represents an infrastructure alert
@@ -37,6 +46,11 @@ func NewInfrastructureHandlers() *InfrastructureHandlers {
 	return h
 }
 
+// SetServiceDiscovery sets the discovery of the services listed by GetServices
+func (h *InfrastructureHandlers) SetServiceDiscovery(serviceDiscovery *discovery.Service) {
+	h.serviceDiscovery = serviceDiscovery
+}
+
 // Mock alert initialization removed - alerts should come from real monitoring systems
 // Frontend will handle mock data display when backend returns empty results
 
@@ -47,16 +61,19 @@ func (h *InfrastructureHandlers) GetAlerts(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	response.SendSuccess(w, h.ListAlerts())
+}
+
+// ListAlerts returns a copy of the current alerts
+func (h *InfrastructureHandlers) ListAlerts() []InfrastructureAlert {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	// Convert map to slice
-	alerts := make([]*InfrastructureAlert, 0, len(h.alerts))
+	alerts := make([]InfrastructureAlert, 0, len(h.alerts))
 	for _, alert := range h.alerts {
-		alerts = append(alerts, alert)
+		alerts = append(alerts, *alert)
 	}
-
-	response.SendSuccess(w, alerts)
+	return alerts
 }
 
 // AcknowledgeAlert marks an infrastructure alert as acknowledged
@@ -86,57 +103,220 @@ func (h *InfrastructureHandlers) AcknowledgeAlert(w http.ResponseWriter, r *http
 		return
 	}
 
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	alert, exists := h.alerts[alertID]
-	if !exists {
+	if !h.Acknowledge(alertID) {
 		response.SendError(w, http.StatusNotFound, "Alert not found")
 		return
 	}
 
-	// Mark as acknowledged
-	alert.Acknowledged = true
-	h.alerts[alertID] = alert
-
 	response.SendSuccess(w, map[string]interface{}{
 		"message": "Alert acknowledged successfully",
 		"alertId": alertID,
 	})
 }
 
-// GetServices returns mock service health data (placeholder for now)
+// GetServices returns the health of services discovered via the denshimon.io/monitor
+// annotation and overrides
 func (h *InfrastructureHandlers) GetServices(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		response.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
+	if h.serviceDiscovery == nil {
+		response.SendSuccess(w, []discovery.ServiceHealth{})
+		return
+	}
+
+	services, err := h.serviceDiscovery.Check(r.Context())
+	if errors.Is(err, discovery.ErrNoCluster) {
+		response.SendSuccess(w, []discovery.ServiceHealth{})
+		return
+	}
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.SendSuccess(w, services)
+}
+
+// ListServiceOverrides handles GET /api/infrastructure/services/overrides
+func (h *InfrastructureHandlers) ListServiceOverrides(w http.ResponseWriter, r *http.Request) {
+	store := h.overrideStore(w)
+	if store == nil {
+		return
+	}
+	overrides, err := store.List(r.Context())
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to list service overrides")
+		return
+	}
+	response.SendSuccess(w, overrides)
+}
 
-	// Return empty for now - service health data is handled by mock data in frontend
-	response.SendSuccess(w, []interface{}{})
+// SaveServiceOverride handles PUT /api/infrastructure/services/overrides/{namespace}/{name},
+// replacing the service's override
+func (h *InfrastructureHandlers) SaveServiceOverride(w http.ResponseWriter, r *http.Request) {
+	store := h.overrideStore(w)
+	if store == nil {
+		return
+	}
+	var override discovery.Override
+	if err := json.NewDecoder(r.Body).Decode(&override); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	override.Namespace, override.Name = r.PathValue("namespace"), r.PathValue("name")
+
+	saved, err := store.Upsert(r.Context(), &override)
+	if errors.Is(err, discovery.ErrInvalidOverride) {
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.SendSuccess(w, saved)
+}
+
+// DeleteServiceOverride handles DELETE /api/infrastructure/services/overrides/{namespace}/{name};
+// the service goes back to its annotations
+func (h *InfrastructureHandlers) DeleteServiceOverride(w http.ResponseWriter, r *http.Request) {
+	store := h.overrideStore(w)
+	if store == nil {
+		return
+	}
+	err := store.Delete(r.Context(), r.PathValue("namespace"), r.PathValue("name"))
+	if errors.Is(err, sql.ErrNoRows) {
+		response.SendError(w, http.StatusNotFound, "Service override not found")
+		return
+	}
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.SendSuccessWithMessage(w, "Service override deleted")
 }
 
-// GetStatus returns infrastructure status (placeholder for now)
+func (h *InfrastructureHandlers) overrideStore(w http.ResponseWriter) *discovery.Store {
+	if h.serviceDiscovery == nil || h.serviceDiscovery.Store() == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Service overrides are not available")
+		return nil
+	}
+	return h.serviceDiscovery.Store()
+}
+
+// InfrastructureStatus is the health of the discovered services and the latest
+// synthetic checks of their URLs
+type InfrastructureStatus struct {
+	Overall             string                      `json:"overall"`
+	Services            []discovery.ServiceHealth   `json:"services"`
+	DomainAccessibility []discovery.SyntheticResult `json:"domainAccessibility"`
+	IngressRules        []interface{}               `json:"ingressRules"`
+	NetworkPolicies     []interface{}               `json:"networkPolicies"`
+	LastChecked         time.Time                   `json:"lastChecked"`
+}
+
+// GetStatus returns the infrastructure status; overall is the worst status of the
+// services and synthetic checks
 func (h *InfrastructureHandlers) GetStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		response.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	// Return empty for now - infrastructure status is handled by mock data in frontend
-	response.SendSuccess(w, map[string]interface{}{})
+	status := InfrastructureStatus{
+		Overall:             discovery.StatusUnknown,
+		Services:            []discovery.ServiceHealth{},
+		DomainAccessibility: []discovery.SyntheticResult{},
+		IngressRules:        []interface{}{},
+		NetworkPolicies:     []interface{}{},
+		LastChecked:         time.Now().UTC(),
+	}
+	if h.serviceDiscovery != nil {
+		services, err := h.serviceDiscovery.Check(r.Context())
+		if err != nil && !errors.Is(err, discovery.ErrNoCluster) {
+			response.SendError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if services != nil {
+			status.Services = services
+		}
+		status.DomainAccessibility = h.serviceDiscovery.Synthetics()
+	}
+
+	var statuses []string
+	for _, service := range status.Services {
+		statuses = append(statuses, service.Status)
+	}
+	for _, result := range status.DomainAccessibility {
+		statuses = append(statuses, result.Status)
+	}
+	status.Overall = discovery.WorstStatus(statuses...)
+	response.SendSuccess(w, status)
 }
 
-// RefreshServices triggers a refresh of infrastructure services
+// RefreshServices runs the synthetic checks of the discovered services now
 func (h *InfrastructureHandlers) RefreshServices(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		response.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
+	if h.serviceDiscovery == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Service discovery is not available")
+		return
+	}
 
-	// For now, just return success - in a real implementation this would trigger
-	// actual service health checks and data refresh
-	response.SendSuccess(w, map[string]string{
-		"message": "Service refresh triggered successfully",
-	})
-}
\ No newline at end of file
+	results, err := h.serviceDiscovery.RunSynthetics(r.Context())
+	if errors.Is(err, discovery.ErrNoCluster) {
+		response.SendError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.SendSuccess(w, results)
+}
+
+// Acknowledge marks an alert as acknowledged, reporting whether it exists
+func (h *InfrastructureHandlers) Acknowledge(alertID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	alert, exists := h.alerts[alertID]
+	if exists {
+		alert.Acknowledged = true
+	}
+	return exists
+}
+// RaiseAlert adds or replaces an alert raised by a monitoring source
+func (h *InfrastructureHandlers) RaiseAlert(alert *InfrastructureAlert) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.alerts[alert.ID] = alert
+}
+
+// ClearAlert removes an alert once its condition has resolved
+func (h *InfrastructureHandlers) ClearAlert(alertID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.alerts, alertID)
+}
+
+// initSyntheticChecks requests the URLs of discovered services every SYNTHETIC_CHECK_INTERVAL
+// unless SYNTHETIC_CHECKS_ENABLED=false
+func initSyntheticChecks(serviceDiscovery *discovery.Service) {
+	if os.Getenv("SYNTHETIC_CHECKS_ENABLED") == "false" {
+		return
+	}
+
+	interval := discovery.DefaultSyntheticInterval
+	if value := os.Getenv("SYNTHETIC_CHECK_INTERVAL"); value != "" {
+		if d, err := parseTimeRange(value); err == nil && d > 0 {
+			interval = d
+		} else {
+			slog.Warn("Ignoring invalid SYNTHETIC_CHECK_INTERVAL", "value", value)
+		}
+	}
+	serviceDiscovery.StartSynthetics(context.Background(), interval)
+}
@@ -0,0 +1,127 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/pkg/response"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// userClusterRoles maps Denshimon roles to the built-in ClusterRoles granting matching
+// access in a namespace
+var userClusterRoles = map[string]string{
+	"viewer":   "view",
+	"operator": "edit",
+	"admin":    "admin",
+}
+
+// kubeconfigRequest is the body of a kubeconfig request
+type kubeconfigRequest struct {
+	Username   string   `json:"username"`
+	Namespaces []string `json:"namespaces"`
+	Duration   string   `json:"duration,omitempty"` // e.g. 8h or 7d, 24h by default
+}
+
+// CreateUserKubeconfig handles POST /api/k8s/kubeconfigs, minting a service account bound to
+// the ClusterRole mirroring the user's Denshimon role in each namespace and returning a
+// kubeconfig for it. Requesting again re-mints the token and updates the namespaces.
+func (h *KubernetesHandlers) CreateUserKubeconfig(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	var req kubeconfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	var duration time.Duration
+	if req.Duration != "" {
+		d, err := parseTimeRange(req.Duration)
+		if err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid duration: "+err.Error())
+			return
+		}
+		duration = d
+	}
+
+	role, err := h.userRole(req.Username)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	clusterRole, ok := userClusterRoles[role]
+	if !ok {
+		response.SendError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	kubeconfig, err := h.k8sClient.CreateUserKubeconfig(r.Context(), k8s.KubeconfigRequest{
+		User:        req.Username,
+		Namespaces:  req.Namespaces,
+		ClusterRole: clusterRole,
+		Duration:    duration,
+		Server:      h.kubeconfigServer,
+	})
+	if err != nil {
+		sendKubeconfigError(w, err)
+		return
+	}
+
+	response.SendJSON(w, http.StatusCreated, response.APIResponse{
+		Success: true,
+		Data:    kubeconfig,
+		Message: "Kubeconfig created",
+	})
+}
+
+// RevokeUserKubeconfig handles DELETE /api/k8s/kubeconfigs/{username}, deleting the user's
+// service account so its kubeconfigs stop working
+func (h *KubernetesHandlers) RevokeUserKubeconfig(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	if err := h.k8sClient.RevokeUserKubeconfig(r.Context(), r.PathValue("username")); err != nil {
+		sendKubeconfigError(w, err)
+		return
+	}
+
+	response.SendSuccessWithMessage(w, "Kubeconfig access revoked")
+}
+
+// userRole returns the Denshimon role of a user, or "" when there's no such user
+func (h *KubernetesHandlers) userRole(username string) (string, error) {
+	if h.authService == nil {
+		return "", errors.New("user management not available")
+	}
+	users, err := h.authService.ListUsers()
+	if err != nil {
+		return "", err
+	}
+	for _, user := range users {
+		if user.Username == username {
+			return user.Role, nil
+		}
+	}
+	return "", nil
+}
+
+func sendKubeconfigError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, k8s.ErrInvalidKubeconfigRequest), apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		response.SendError(w, http.StatusBadRequest, err.Error())
+	case apierrors.IsNotFound(err):
+		response.SendError(w, http.StatusNotFound, err.Error())
+	case apierrors.IsForbidden(err):
+		response.SendError(w, http.StatusForbidden, err.Error())
+	default:
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -4,33 +4,52 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/archellir/denshimon/internal/auth"
 	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/internal/metrics"
+	"github.com/archellir/denshimon/internal/opa"
+	"github.com/archellir/denshimon/internal/recordings"
+	"github.com/archellir/denshimon/pkg/apiclient"
 	"github.com/archellir/denshimon/pkg/response"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type KubernetesHandlers struct {
-	k8sClient *k8s.Client
-}
-
-type PodInfo struct {
-	Name        string            `json:"name"`
-	Namespace   string            `json:"namespace"`
-	Status      string            `json:"status"`
-	Ready       string            `json:"ready"`
-	Restarts    int32             `json:"restarts"`
-	Age         string            `json:"age"`
-	Node        string            `json:"node"`
-	IP          string            `json:"ip"`
-	Labels      map[string]string `json:"labels"`
-	Annotations map[string]string `json:"annotations"`
+	k8sClient        *k8s.Client
+	recordingStore   *recordings.Store
+	shares           *k8s.ShareRegistry // exec sessions shared with other users
+	authService      *auth.Service
+	metricsService   *metrics.Service     // storage growth projections
+	kubeconfigServer string               // API server address written into user kubeconfigs
+	debugImage       string               // image of connectivity debug pods
+	policies         *opa.Engine          // Rego policies applied manifests must pass
+	nodeShell        *k8s.NodeShellConfig // nil unless node shells are enabled
+}
+
+// PodInfo is shared with API clients
+type PodInfo = apiclient.PodInfo
+
+func newPodInfo(pod *corev1.Pod) PodInfo {
+	return PodInfo{
+		Name:        pod.Name,
+		Namespace:   pod.Namespace,
+		Status:      string(pod.Status.Phase),
+		Ready:       getPodReadyStatus(pod),
+		Restarts:    getPodRestarts(pod),
+		Age:         formatAge(pod.CreationTimestamp.Time),
+		Node:        pod.Spec.NodeName,
+		IP:          pod.Status.PodIP,
+		Labels:      pod.Labels,
+		Annotations: pod.Annotations,
+	}
 }
 
 type NodeInfo struct {
@@ -87,12 +106,41 @@ type EventInfo struct {
 	Labels    map[string]string `json:"labels"`
 }
 
-func NewKubernetesHandlers(k8sClient *k8s.Client) *KubernetesHandlers {
+func NewKubernetesHandlers(k8sClient *k8s.Client, recordingStore *recordings.Store, authService *auth.Service) *KubernetesHandlers {
 	return &KubernetesHandlers{
-		k8sClient: k8sClient,
+		k8sClient:      k8sClient,
+		recordingStore: recordingStore,
+		shares:         k8s.NewShareRegistry(),
+		authService:    authService,
 	}
 }
 
+// SetMetricsService sets where the growth projections of storage usage come from
+func (h *KubernetesHandlers) SetMetricsService(metricsService *metrics.Service) {
+	h.metricsService = metricsService
+}
+
+// SetKubeconfigServer sets the API server address written into user kubeconfigs, for when
+// Denshimon reaches the cluster on an address teammates can't
+func (h *KubernetesHandlers) SetKubeconfigServer(server string) {
+	h.kubeconfigServer = server
+}
+
+// SetDebugImage sets the image of connectivity debug pods, for clusters pulling from a mirror
+func (h *KubernetesHandlers) SetDebugImage(image string) {
+	h.debugImage = image
+}
+
+// SetNodeShell enables shells on cluster nodes through privileged agent pods
+func (h *KubernetesHandlers) SetNodeShell(config k8s.NodeShellConfig) {
+	h.nodeShell = &config
+}
+
+// SetPolicyEngine sets the Rego policies manifests are checked against before they are applied
+func (h *KubernetesHandlers) SetPolicyEngine(engine *opa.Engine) {
+	h.policies = engine
+}
+
 // GET /api/k8s/pods
 func (h *KubernetesHandlers) ListPods(w http.ResponseWriter, r *http.Request) {
 	if h.k8sClient == nil {
@@ -104,7 +152,7 @@ func (h *KubernetesHandlers) ListPods(w http.ResponseWriter, r *http.Request) {
 		namespace = "default"
 	}
 
-	pods, err := h.k8sClient.Clientset().CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{})
+	pods, err := h.k8sClient.ListPods(listContext(r), namespace)
 	if err != nil {
 		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list pods: %v", err))
 		return
@@ -112,19 +160,7 @@ func (h *KubernetesHandlers) ListPods(w http.ResponseWriter, r *http.Request) {
 
 	var podInfos []PodInfo
 	for _, pod := range pods.Items {
-		podInfo := PodInfo{
-			Name:        pod.Name,
-			Namespace:   pod.Namespace,
-			Status:      string(pod.Status.Phase),
-			Ready:       getPodReadyStatus(&pod),
-			Restarts:    getPodRestarts(&pod),
-			Age:         formatAge(pod.CreationTimestamp.Time),
-			Node:        pod.Spec.NodeName,
-			IP:          pod.Status.PodIP,
-			Labels:      pod.Labels,
-			Annotations: pod.Annotations,
-		}
-		podInfos = append(podInfos, podInfo)
+		podInfos = append(podInfos, newPodInfo(&pod))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -145,18 +181,7 @@ func (h *KubernetesHandlers) GetPod(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	podInfo := PodInfo{
-		Name:        pod.Name,
-		Namespace:   pod.Namespace,
-		Status:      string(pod.Status.Phase),
-		Ready:       getPodReadyStatus(pod),
-		Restarts:    getPodRestarts(pod),
-		Age:         formatAge(pod.CreationTimestamp.Time),
-		Node:        pod.Spec.NodeName,
-		IP:          pod.Status.PodIP,
-		Labels:      pod.Labels,
-		Annotations: pod.Annotations,
-	}
+	podInfo := newPodInfo(pod)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(podInfo)
@@ -183,6 +208,7 @@ func (h *KubernetesHandlers) RestartPod(w http.ResponseWriter, r *http.Request)
 		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to restart pod: %v", err))
 		return
 	}
+	h.k8sClient.InvalidateCache("pods")
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Pod restart initiated"})
@@ -208,6 +234,7 @@ func (h *KubernetesHandlers) DeletePod(w http.ResponseWriter, r *http.Request) {
 		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete pod: %v", err))
 		return
 	}
+	h.k8sClient.InvalidateCache("pods")
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Pod deleted successfully"})
@@ -273,7 +300,7 @@ func (h *KubernetesHandlers) ListNodes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	nodes, err := h.k8sClient.Clientset().CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	nodes, err := h.k8sClient.ListNodes(listContext(r))
 	if err != nil {
 		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list nodes: %v", err))
 		return
@@ -311,7 +338,7 @@ func (h *KubernetesHandlers) ListDeployments(w http.ResponseWriter, r *http.Requ
 		namespace = "default"
 	}
 
-	deployments, err := h.k8sClient.Clientset().AppsV1().Deployments(namespace).List(context.Background(), metav1.ListOptions{})
+	deployments, err := h.k8sClient.ListDeployments(listContext(r), namespace)
 	if err != nil {
 		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list deployments: %v", err))
 		return
@@ -372,6 +399,7 @@ func (h *KubernetesHandlers) ScaleDeployment(w http.ResponseWriter, r *http.Requ
 		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to scale deployment: %v", err))
 		return
 	}
+	h.k8sClient.InvalidateCache("deployments")
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -429,7 +457,103 @@ func (h *KubernetesHandlers) HandlePodExec(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	h.k8sClient.HandlePodExec(w, r)
+	opts := k8s.ExecOptions{
+		Authenticate: h.terminalAuthenticator(r),
+		Shares:       h.shares,
+	}
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		opts.User = claims.Username
+	}
+	if h.recordingStore != nil {
+		opts.NewRecorder = h.recordingStore.Start
+	}
+
+	h.k8sClient.HandlePodExec(w, r, opts)
+}
+
+// GET /api/k8s/pods/exec/shared/{id}?token= - WebSocket joining an exec session its owner
+// shared. Viewers without a bearer token authenticate with an in-band auth message first.
+func (h *KubernetesHandlers) JoinSharedExec(w http.ResponseWriter, r *http.Request) {
+	opts := k8s.JoinOptions{Authenticate: h.terminalAuthenticator(r)}
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		opts.User = claims.Username
+	}
+	h.shares.Join(w, r, r.PathValue("id"), r.URL.Query().Get("token"), opts)
+}
+
+// terminalAuthenticator resolves the user of an in-band auth message on a terminal socket,
+// holding API tokens to their scopes for the request
+func (h *KubernetesHandlers) terminalAuthenticator(r *http.Request) func(token string) (string, error) {
+	return func(token string) (string, error) {
+		claims, err := h.authService.ValidateToken(token)
+		if err != nil {
+			return "", err
+		}
+		if claims.APITokenID != "" && !auth.ScopesAllow(claims.Scopes, r.Method, r.URL.Path) {
+			return "", auth.ErrNoPermission
+		}
+		return claims.Username, nil
+	}
+}
+
+// GET /api/k8s/nodes/{name}/shell - WebSocket terminal on a node, for admins only. Users
+// without a bearer token authenticate with an in-band auth message first.
+func (h *KubernetesHandlers) HandleNodeShell(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		http.Error(w, "Kubernetes client not available", http.StatusServiceUnavailable)
+		return
+	}
+	if h.nodeShell == nil {
+		http.Error(w, "Node shells are disabled", http.StatusNotFound)
+		return
+	}
+
+	opts := k8s.ExecOptions{Authenticate: h.nodeShellAuthenticator(r)}
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		if claims.Role != "admin" {
+			http.Error(w, "Insufficient permissions", http.StatusForbidden)
+			return
+		}
+		opts.User = claims.Username
+	}
+	if h.recordingStore != nil {
+		opts.NewRecorder = h.recordingStore.Start
+	}
+
+	h.k8sClient.HandleNodeShell(w, r, r.PathValue("name"), *h.nodeShell, opts)
+}
+
+// nodeShellAuthenticator resolves the user of an in-band auth message on a node shell,
+// refusing anyone but admins
+func (h *KubernetesHandlers) nodeShellAuthenticator(r *http.Request) func(token string) (string, error) {
+	return func(token string) (string, error) {
+		claims, err := h.authService.ValidateToken(token)
+		if err != nil {
+			return "", err
+		}
+		if claims.Role != "admin" || (claims.APITokenID != "" && !auth.ScopesAllow(claims.Scopes, r.Method, r.URL.Path)) {
+			return "", auth.ErrNoPermission
+		}
+		return claims.Username, nil
+	}
+}
+
+// nodeShellConfig reads the node shell settings: NODE_SHELL_ENABLED (default false),
+// NODE_SHELL_NAMESPACE of the agent pods (default kube-system), NODE_SHELL_IMAGE (any image
+// with nsenter, default busybox) and NODE_SHELL_MAX_DURATION (default 8h)
+func nodeShellConfig() (k8s.NodeShellConfig, bool) {
+	config := k8s.NodeShellConfig{
+		Namespace: os.Getenv("NODE_SHELL_NAMESPACE"),
+		Image:     os.Getenv("NODE_SHELL_IMAGE"),
+	}
+	if value := os.Getenv("NODE_SHELL_MAX_DURATION"); value != "" {
+		duration, err := parseTimeRange(value)
+		if err != nil {
+			slog.Warn("Invalid NODE_SHELL_MAX_DURATION, using the default", "value", value, "error", err)
+		}
+		config.MaxDuration = duration
+	}
+	return config, os.Getenv("NODE_SHELL_ENABLED") == "true"
 }
 
 // GET /api/k8s/pods/logs/stream - Advanced log streaming
@@ -484,7 +608,7 @@ func (h *KubernetesHandlers) ListServices(w http.ResponseWriter, r *http.Request
 		namespace = "default"
 	}
 
-	services, err := h.k8sClient.Clientset().CoreV1().Services(namespace).List(context.Background(), metav1.ListOptions{})
+	services, err := h.k8sClient.ListServices(listContext(r), namespace)
 	if err != nil {
 		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list services: %v", err))
 		return
@@ -535,7 +659,7 @@ func (h *KubernetesHandlers) ListEvents(w http.ResponseWriter, r *http.Request)
 		namespace = "default"
 	}
 
-	events, err := h.k8sClient.Clientset().CoreV1().Events(namespace).List(context.Background(), metav1.ListOptions{})
+	events, err := h.k8sClient.ListEvents(listContext(r), namespace)
 	if err != nil {
 		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list events: %v", err))
 		return
@@ -569,7 +693,7 @@ func (h *KubernetesHandlers) ListNamespaces(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	namespaces, err := h.k8sClient.ListNamespaces(context.Background())
+	namespaces, err := h.k8sClient.ListNamespaces(listContext(r))
 	if err != nil {
 		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list namespaces: %v", err))
 		return
@@ -600,6 +724,11 @@ func (h *KubernetesHandlers) GetStorageInfo(w http.ResponseWriter, r *http.Reque
 		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get storage info: %v", err))
 		return
 	}
+	if h.metricsService != nil {
+		if err := h.metricsService.AddStorageTrends(r.Context(), storageInfo.Usage); err != nil {
+			slog.Warn("Failed to project storage growth", "error", err)
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(storageInfo)
@@ -674,19 +803,25 @@ func formatAge(creationTime time.Time) string {
 func hasPermission(role, resource, action string) bool {
 	permissions := map[string]map[string][]string{
 		"admin": {
-			"pods":        {"create", "read", "update", "delete", "exec"},
-			"deployments": {"create", "read", "update", "delete", "scale"},
-			"nodes":       {"read"},
+			"pods":         {"create", "read", "update", "delete", "exec"},
+			"deployments":  {"create", "read", "update", "delete", "scale"},
+			"statefulsets": {"create", "read", "update", "delete", "scale"},
+			"daemonsets":   {"create", "read", "update", "delete"},
+			"nodes":        {"read"},
 		},
 		"operator": {
-			"pods":        {"read", "update", "delete"},
-			"deployments": {"read", "update", "scale"},
-			"nodes":       {"read"},
+			"pods":         {"read", "update", "delete"},
+			"deployments":  {"read", "update", "scale"},
+			"statefulsets": {"read", "update", "scale"},
+			"daemonsets":   {"read", "update"},
+			"nodes":        {"read"},
 		},
 		"viewer": {
-			"pods":        {"read"},
-			"deployments": {"read"},
-			"nodes":       {"read"},
+			"pods":         {"read"},
+			"deployments":  {"read"},
+			"statefulsets": {"read"},
+			"daemonsets":   {"read"},
+			"nodes":        {"read"},
 		},
 	}
 
@@ -702,3 +837,10 @@ func hasPermission(role, resource, action string) bool {
 	return false
 }
 
+// listContext returns the request context, marked to bypass the list cache for ?refresh=true
+func listContext(r *http.Request) context.Context {
+	if r.URL.Query().Get("refresh") == "true" {
+		return k8s.WithRefresh(r.Context())
+	}
+	return r.Context()
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/internal/opa"
+	"github.com/archellir/denshimon/pkg/response"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// maxManifestSize limits the size of manifests accepted by the apply endpoint
+const maxManifestSize = 5 << 20
+
+// redactedSecretValue replaces Secret values shown to non-admins
+const redactedSecretValue = "********"
+
+// GetResourceYAML handles GET /api/k8s/{kind}/{name}/yaml?namespace=default&format=yaml|json
+func (h *KubernetesHandlers) GetResourceYAML(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	query := r.URL.Query()
+	includeManagedFields, _ := strconv.ParseBool(query.Get("managedFields"))
+
+	obj, err := h.k8sClient.GetManifest(r.Context(), r.PathValue("kind"), query.Get("namespace"), r.PathValue("name"), includeManagedFields)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if apierrors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		response.SendError(w, status, err.Error())
+		return
+	}
+	// Secret values are for admins; everyone else sees which keys there are
+	if claims := auth.GetUserFromContext(r.Context()); claims == nil || claims.Role != "admin" {
+		redactSecret(obj)
+	}
+
+	if query.Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(obj.Object)
+		return
+	}
+
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to encode manifest: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(data)
+}
+
+// redactSecret masks the values of a Secret's data and stringData, and drops the
+// last-applied annotation that may repeat them. Other kinds are left alone.
+func redactSecret(obj *unstructured.Unstructured) {
+	if obj.GetKind() != "Secret" {
+		return
+	}
+	for _, field := range []string{"data", "stringData"} {
+		values, ok := obj.Object[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key := range values {
+			values[key] = redactedSecretValue
+		}
+	}
+	if annotations := obj.GetAnnotations(); annotations != nil {
+		if _, ok := annotations[corev1.LastAppliedConfigAnnotation]; ok {
+			delete(annotations, corev1.LastAppliedConfigAnnotation)
+			obj.SetAnnotations(annotations)
+		}
+	}
+}
+
+// ApplyManifests handles POST /api/k8s/apply?dryRun=true&force=false&namespace=default
+// The body is raw YAML or JSON and may contain multiple documents separated by "---".
+func (h *KubernetesHandlers) ApplyManifests(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxManifestSize))
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read manifest: %v", err))
+		return
+	}
+
+	query := r.URL.Query()
+	dryRun, _ := strconv.ParseBool(query.Get("dryRun"))
+	force, _ := strconv.ParseBool(query.Get("force"))
+
+	var warnings int
+	if h.policies != nil {
+		objects, err := k8s.DecodeManifests(body)
+		if err != nil {
+			response.SendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		decisions := h.policies.Evaluate(r.Context(), opa.Request{Source: opa.SourceApply, User: requestUser(r), Objects: objects})
+		denied := 0
+		for _, decision := range decisions {
+			warnings += len(decision.Warnings)
+			if !decision.Allowed {
+				denied++
+			}
+		}
+		if denied > 0 {
+			response.SendJSON(w, http.StatusUnprocessableEntity, response.APIResponse{
+				Error: fmt.Sprintf("%d resource(s) denied by policy", denied),
+				Data:  decisions,
+			})
+			return
+		}
+	}
+
+	results, err := h.k8sClient.ApplyManifests(r.Context(), body, k8s.ApplyOptions{
+		Namespace: query.Get("namespace"),
+		DryRun:    dryRun,
+		Force:     force,
+	})
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Action == "failed" {
+			failed++
+		}
+	}
+
+	status := http.StatusOK
+	message := fmt.Sprintf("Applied %d resource(s)", len(results))
+	if dryRun {
+		message = fmt.Sprintf("Dry run of %d resource(s)", len(results))
+	}
+	if failed > 0 {
+		status = http.StatusUnprocessableEntity
+		message = fmt.Sprintf("%s, %d failed", message, failed)
+	}
+	if warnings > 0 {
+		message = fmt.Sprintf("%s, %d policy warning(s)", message, warnings)
+	}
+
+	response.SendJSON(w, status, response.APIResponse{
+		Success: failed == 0,
+		Data:    results,
+		Message: message,
+	})
+}
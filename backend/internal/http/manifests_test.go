@@ -0,0 +1,45 @@
+package http
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRedactSecret(t *testing.T) {
+	secret := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"name": "db",
+			"annotations": map[string]interface{}{
+				corev1.LastAppliedConfigAnnotation: `{"stringData":{"password":"hunter2"}}`,
+				"team":                             "platform",
+			},
+		},
+		"data":       map[string]interface{}{"password": "aHVudGVyMg=="},
+		"stringData": map[string]interface{}{"username": "app"},
+	}}
+	redactSecret(secret)
+
+	data, _, _ := unstructured.NestedStringMap(secret.Object, "data")
+	stringData, _, _ := unstructured.NestedStringMap(secret.Object, "stringData")
+	if data["password"] != redactedSecretValue || stringData["username"] != redactedSecretValue {
+		t.Errorf("values not redacted: data=%v stringData=%v", data, stringData)
+	}
+	annotations := secret.GetAnnotations()
+	if _, ok := annotations[corev1.LastAppliedConfigAnnotation]; ok || annotations["team"] != "platform" {
+		t.Errorf("annotations = %v, want only the last-applied configuration dropped", annotations)
+	}
+
+	// Other kinds keep their data
+	configMap := &unstructured.Unstructured{Object: map[string]interface{}{
+		"kind": "ConfigMap",
+		"data": map[string]interface{}{"mode": "production"},
+	}}
+	redactSecret(configMap)
+	if mode, _, _ := unstructured.NestedString(configMap.Object, "data", "mode"); mode != "production" {
+		t.Errorf("ConfigMap data = %q, want it unchanged", mode)
+	}
+}
@@ -1,15 +1,23 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"log/slog"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 
+	"github.com/archellir/denshimon/internal/deployments"
 	"github.com/archellir/denshimon/internal/metrics"
+	"github.com/archellir/denshimon/internal/websocket"
 )
 
 type MetricsHandlers struct {
-	metricsService *metrics.Service
+	metricsService    *metrics.Service
+	deploymentService *deployments.Service   // applies resource recommendations
+	hostCollector     *metrics.HostCollector // metrics of the host Denshimon runs on
 }
 
 func NewMetricsHandlers(metricsService *metrics.Service) *MetricsHandlers {
@@ -227,13 +235,136 @@ func (h *MetricsHandlers) GetResourceMetrics(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	resources := map[string]interface{}{
 		"cpu":     clusterMetrics.CPUUsage,
 		"memory":  clusterMetrics.MemoryUsage,
 		"storage": clusterMetrics.StorageUsage,
 		"network": clusterMetrics.NetworkMetrics,
-	})
+		// Clients show usage as unknown rather than zero when the source is "none"
+		"usage_source":   clusterMetrics.UsageSource,
+		"storage_source": clusterMetrics.StorageSource,
+	}
+
+	// Capacity cost by namespace; the per-workload breakdown is at /api/metrics/costs
+	if costs, err := h.metricsService.GetCosts(r.Context(), "", metrics.DefaultUsageRetention); err == nil {
+		resources["costs"] = map[string]interface{}{
+			"rates":        costs.Rates,
+			"usage_basis":  costs.UsageBasis,
+			"cluster":      costs.Cluster,
+			"idle_monthly": costs.Idle,
+			"monthly":      costs.Monthly,
+			"namespaces":   costs.Namespaces,
+		}
+	} else {
+		slog.Debug("Cost estimates unavailable", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resources)
+}
+
+// GET /api/metrics/capabilities - Report which metrics sources are live
+func (h *MetricsHandlers) GetCapabilities(w http.ResponseWriter, r *http.Request) {
+	if h.metricsService == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "Metrics service not available",
+		})
+		return
+	}
+
+	capabilities, err := h.metricsService.GetCapabilities(r.Context())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "Failed to probe metrics sources",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(capabilities)
+}
+
+// GET /api/metrics/top/nodes?sort=cpu|memory - kubectl top nodes
+func (h *MetricsHandlers) GetTopNodes(w http.ResponseWriter, r *http.Request) {
+	if h.metricsService == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "Metrics service not available",
+		})
+		return
+	}
+
+	top, err := h.metricsService.GetTopNodes(r.Context(), r.URL.Query().Get("sort"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "Failed to get node usage",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(top)
+}
+
+// GET /api/metrics/host - CPU, memory, disk and network of the host itself
+func (h *MetricsHandlers) GetHostMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.hostCollector == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "Host metrics not enabled",
+		})
+		return
+	}
+
+	host := h.hostCollector.Latest()
+	if host == nil {
+		var err error
+		if host, err = h.hostCollector.Collect(r.Context()); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error": "Failed to get host metrics",
+			})
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(host)
+}
+
+// GET /api/metrics/top/pods?namespace=&sort=cpu|memory - kubectl top pods
+func (h *MetricsHandlers) GetTopPods(w http.ResponseWriter, r *http.Request) {
+	if h.metricsService == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "Metrics service not available",
+		})
+		return
+	}
+
+	query := r.URL.Query()
+	top, err := h.metricsService.GetTopPods(r.Context(), query.Get("namespace"), query.Get("sort"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "Failed to get pod usage",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(top)
 }
 
 // GET /api/metrics/health
@@ -382,3 +513,47 @@ func (h *MetricsHandlers) GetStorageMetrics(w http.ResponseWriter, r *http.Reque
 		"source":    "prometheus",
 	})
 }
+
+// initHostMetrics starts collecting metrics of the host itself, published on the host
+// channel. It is configured with HOST_METRICS_ENABLED, HOST_METRICS_INTERVAL (default 5s),
+// HOST_NODE_EXPORTER_URL to read a node_exporter instead of procfs, and HOST_PROC and
+// HOST_ROOT for a container with the host's /proc and root mounted.
+func initHostMetrics(metricsHandlers *MetricsHandlers, hub *websocket.Hub) {
+	if os.Getenv("HOST_METRICS_ENABLED") == "false" {
+		return
+	}
+	collector := metrics.NewHostCollector(os.Getenv("HOST_PROC"), os.Getenv("HOST_NODE_EXPORTER_URL"))
+	collector.SetRootPath(os.Getenv("HOST_ROOT"))
+	collector.OnSample(func(host *metrics.HostMetrics) {
+		hub.Broadcast(websocket.MessageTypeHost, host)
+	})
+
+	interval := metrics.DefaultHostInterval
+	if value := os.Getenv("HOST_METRICS_INTERVAL"); value != "" {
+		if d, err := parseTimeRange(value); err == nil {
+			interval = d
+		} else {
+			slog.Warn("Ignoring invalid HOST_METRICS_INTERVAL", "value", value)
+		}
+	}
+	collector.Start(context.Background(), interval)
+	metricsHandlers.hostCollector = collector
+}
+
+// initNodeExporter reads how node_exporter pods are found from NODE_EXPORTER_SELECTOR and
+// NODE_EXPORTER_PORT. An empty selector scrapes the kubelet's cAdvisor only.
+func initNodeExporter(metricsService *metrics.Service) {
+	selector, ok := os.LookupEnv("NODE_EXPORTER_SELECTOR")
+	if !ok {
+		selector = metrics.DefaultNodeExporterSelector
+	}
+	port := metrics.DefaultNodeExporterPort
+	if value := os.Getenv("NODE_EXPORTER_PORT"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 && parsed < 65536 {
+			port = parsed
+		} else {
+			slog.Warn("Ignoring invalid NODE_EXPORTER_PORT", "value", value)
+		}
+	}
+	metricsService.SetNodeExporter(selector, port)
+}
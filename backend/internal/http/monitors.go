@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/archellir/denshimon/internal/alerts"
+	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/internal/monitors"
+	"github.com/archellir/denshimon/internal/websocket"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+type MonitorHandlers struct {
+	service *monitors.Service
+}
+
+func NewMonitorHandlers(service *monitors.Service) *MonitorHandlers {
+	return &MonitorHandlers{service: service}
+}
+
+// ListMonitors handles GET /api/monitors, returning every monitor with its state and
+// uptime over the 24h, 7d and 30d SLA windows
+func (h *MonitorHandlers) ListMonitors(w http.ResponseWriter, r *http.Request) {
+	statuses, err := h.service.List(r.Context())
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list monitors: %v", err))
+		return
+	}
+	response.SendSuccess(w, statuses)
+}
+
+// GetMonitor handles GET /api/monitors/{id}
+func (h *MonitorHandlers) GetMonitor(w http.ResponseWriter, r *http.Request) {
+	status, err := h.service.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		sendMonitorError(w, err)
+		return
+	}
+	response.SendSuccess(w, status)
+}
+
+// CreateMonitor handles POST /api/monitors
+func (h *MonitorHandlers) CreateMonitor(w http.ResponseWriter, r *http.Request) {
+	var monitor monitors.Monitor
+	if err := json.NewDecoder(r.Body).Decode(&monitor); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	createdBy := ""
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		createdBy = claims.Username
+	}
+	created, err := h.service.Create(r.Context(), monitor, createdBy)
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.SendSuccess(w, created)
+}
+
+// UpdateMonitor handles PUT /api/monitors/{id}, replacing the monitor's settings
+func (h *MonitorHandlers) UpdateMonitor(w http.ResponseWriter, r *http.Request) {
+	var monitor monitors.Monitor
+	if err := json.NewDecoder(r.Body).Decode(&monitor); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	updated, err := h.service.Update(r.Context(), r.PathValue("id"), monitor)
+	if err != nil {
+		sendMonitorError(w, err)
+		return
+	}
+	response.SendSuccess(w, updated)
+}
+
+// DeleteMonitor handles DELETE /api/monitors/{id}
+func (h *MonitorHandlers) DeleteMonitor(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		sendMonitorError(w, err)
+		return
+	}
+	response.SendSuccess(w, map[string]string{"message": "Monitor deleted", "id": id})
+}
+
+// GetMonitorHistory handles GET /api/monitors/{id}/history?since=24h
+func (h *MonitorHandlers) GetMonitorHistory(w http.ResponseWriter, r *http.Request) {
+	since := 24 * time.Hour
+	if value := r.URL.Query().Get("since"); value != "" {
+		parsed, err := parseTimeRange(value)
+		if err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid since: "+value)
+			return
+		}
+		since = parsed
+	}
+
+	results, err := h.service.History(r.Context(), r.PathValue("id"), time.Now().Add(-since))
+	if err != nil {
+		sendMonitorError(w, err)
+		return
+	}
+	response.SendSuccess(w, results)
+}
+
+// CheckMonitor handles POST /api/monitors/{id}/check, running the check immediately
+func (h *MonitorHandlers) CheckMonitor(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.CheckNow(r.Context(), r.PathValue("id"))
+	if err != nil {
+		sendMonitorError(w, err)
+		return
+	}
+	response.SendSuccess(w, result)
+}
+
+func sendMonitorError(w http.ResponseWriter, err error) {
+	if errors.Is(err, monitors.ErrNotFound) {
+		response.SendError(w, http.StatusNotFound, "Monitor not found")
+		return
+	}
+	response.SendError(w, http.StatusBadRequest, err.Error())
+}
+
+// monitorAlerts raises an infrastructure alert while a monitor is down and clears it
+// once the monitor recovers. Alerts matching a silence are raised without a notification.
+func monitorAlerts(infrastructureHandlers *InfrastructureHandlers, hub *websocket.Hub, alertManager *alerts.Manager) func(monitors.StateChange) {
+	return func(change monitors.StateChange) {
+		alertID := "monitor-" + change.Monitor.ID
+		switch change.Current {
+		case monitors.StatusDown:
+			alert := &InfrastructureAlert{
+				ID:        alertID,
+				Type:      "monitor",
+				Severity:  "critical",
+				Message:   fmt.Sprintf("%s (%s %s) is down: %s", change.Monitor.Name, change.Monitor.Type, change.Monitor.Target, change.Result.Error),
+				Timestamp: change.Timestamp,
+				Source:    "monitor",
+			}
+			infrastructureHandlers.RaiseAlert(alert)
+			if alertManager != nil && alertManager.SilencedBy(context.Background(), infrastructureAlert(*alert)) != "" {
+				return
+			}
+			if hub != nil {
+				hub.Broadcast(websocket.MessageTypeAlerts, alert)
+			}
+		case monitors.StatusUp:
+			infrastructureHandlers.ClearAlert(alertID)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/archellir/denshimon/pkg/response"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// ListNetworkPolicies handles GET /api/k8s/networkpolicies?namespace=
+func (h *KubernetesHandlers) ListNetworkPolicies(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	policies, err := h.k8sClient.ListNetworkPolicies(r.Context(), r.URL.Query().Get("namespace"))
+	if err != nil {
+		sendNetworkPolicyError(w, err)
+		return
+	}
+
+	response.SendSuccess(w, policies.Items)
+}
+
+// GetNetworkPolicy handles GET /api/k8s/networkpolicies/{name}?namespace=default
+func (h *KubernetesHandlers) GetNetworkPolicy(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	policy, err := h.k8sClient.GetNetworkPolicy(r.Context(), queryNamespace(r), r.PathValue("name"))
+	if err != nil {
+		sendNetworkPolicyError(w, err)
+		return
+	}
+
+	response.SendSuccess(w, policy)
+}
+
+// CreateNetworkPolicy handles POST /api/k8s/networkpolicies with a NetworkPolicy as JSON or YAML.
+// The namespace defaults to the namespace query parameter, then "default".
+func (h *KubernetesHandlers) CreateNetworkPolicy(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	policy, ok := decodeNetworkPolicy(w, r)
+	if !ok {
+		return
+	}
+	if policy.Namespace == "" {
+		policy.Namespace = queryNamespace(r)
+	}
+
+	created, err := h.k8sClient.CreateNetworkPolicy(r.Context(), policy)
+	if err != nil {
+		sendNetworkPolicyError(w, err)
+		return
+	}
+
+	response.SendJSON(w, http.StatusCreated, response.APIResponse{
+		Success: true,
+		Data:    created,
+		Message: "Network policy created",
+	})
+}
+
+// UpdateNetworkPolicy handles PUT /api/k8s/networkpolicies/{name}?namespace=default
+func (h *KubernetesHandlers) UpdateNetworkPolicy(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	policy, ok := decodeNetworkPolicy(w, r)
+	if !ok {
+		return
+	}
+	policy.Name = r.PathValue("name")
+	policy.Namespace = queryNamespace(r)
+
+	updated, err := h.k8sClient.UpdateNetworkPolicy(r.Context(), policy)
+	if err != nil {
+		sendNetworkPolicyError(w, err)
+		return
+	}
+
+	response.SendSuccess(w, updated)
+}
+
+// DeleteNetworkPolicy handles DELETE /api/k8s/networkpolicies/{name}?namespace=default
+func (h *KubernetesHandlers) DeleteNetworkPolicy(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	if err := h.k8sClient.DeleteNetworkPolicy(r.Context(), queryNamespace(r), r.PathValue("name")); err != nil {
+		sendNetworkPolicyError(w, err)
+		return
+	}
+
+	response.SendSuccessWithMessage(w, "Network policy deleted")
+}
+
+// GetConnectivity handles GET /api/k8s/connectivity?level=namespace|pod&namespace=
+// and returns which namespaces (or pods) may connect to each other under the current policies.
+func (h *KubernetesHandlers) GetConnectivity(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	level := r.URL.Query().Get("level")
+	if level == "" {
+		level = "namespace"
+	}
+	if level != "namespace" && level != "pod" {
+		response.SendError(w, http.StatusBadRequest, "level must be namespace or pod")
+		return
+	}
+
+	matrix, err := h.k8sClient.NetworkConnectivity(r.Context(), level, r.URL.Query().Get("namespace"))
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.SendSuccess(w, matrix)
+}
+
+func queryNamespace(r *http.Request) string {
+	if namespace := r.URL.Query().Get("namespace"); namespace != "" {
+		return namespace
+	}
+	return "default"
+}
+
+func decodeNetworkPolicy(w http.ResponseWriter, r *http.Request) (*networkingv1.NetworkPolicy, bool) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxManifestSize))
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, "Failed to read request body")
+		return nil, false
+	}
+
+	var policy networkingv1.NetworkPolicy
+	if err := yaml.UnmarshalStrict(data, &policy); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid network policy: "+err.Error())
+		return nil, false
+	}
+	if policy.Kind != "" && policy.Kind != "NetworkPolicy" {
+		response.SendError(w, http.StatusBadRequest, "Expected kind NetworkPolicy")
+		return nil, false
+	}
+	return &policy, true
+}
+
+func sendNetworkPolicyError(w http.ResponseWriter, err error) {
+	switch {
+	case apierrors.IsNotFound(err):
+		response.SendError(w, http.StatusNotFound, err.Error())
+	case apierrors.IsAlreadyExists(err), apierrors.IsConflict(err):
+		response.SendError(w, http.StatusConflict, err.Error())
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		response.SendError(w, http.StatusBadRequest, err.Error())
+	case apierrors.IsForbidden(err):
+		response.SendError(w, http.StatusForbidden, err.Error())
+	default:
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+	}
+}
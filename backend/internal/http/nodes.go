@@ -0,0 +1,263 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/internal/websocket"
+	"github.com/archellir/denshimon/pkg/response"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type NodeHandlers struct {
+	k8sClient *k8s.Client
+	wsHub     *websocket.Hub
+	drains    map[string]*DrainStatus
+	mutex     sync.RWMutex
+}
+
+// DrainStatus tracks the latest drain of a node
+type DrainStatus struct {
+	Node        string           `json:"node"`
+	Status      string           `json:"status"` // running, completed, failed
+	StartedAt   time.Time        `json:"startedAt"`
+	CompletedAt *time.Time       `json:"completedAt,omitempty"`
+	LastEvent   *k8s.DrainEvent  `json:"lastEvent,omitempty"`
+	Result      *k8s.DrainResult `json:"result,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	// Warnings name the disruption budgets that will hold up evictions
+	Warnings []k8s.BudgetConflict `json:"warnings,omitempty"`
+}
+
+type TaintRequest struct {
+	Key    string             `json:"key"`
+	Value  string             `json:"value"`
+	Effect corev1.TaintEffect `json:"effect"`
+}
+
+func NewNodeHandlers(k8sClient *k8s.Client, wsHub *websocket.Hub) *NodeHandlers {
+	return &NodeHandlers{
+		k8sClient: k8sClient,
+		wsHub:     wsHub,
+		drains:    make(map[string]*DrainStatus),
+	}
+}
+
+// CordonNode handles POST /api/k8s/nodes/{name}/cordon
+func (h *NodeHandlers) CordonNode(w http.ResponseWriter, r *http.Request) {
+	h.setSchedulable(w, r, false)
+}
+
+// UncordonNode handles POST /api/k8s/nodes/{name}/uncordon
+func (h *NodeHandlers) UncordonNode(w http.ResponseWriter, r *http.Request) {
+	h.setSchedulable(w, r, true)
+}
+
+func (h *NodeHandlers) setSchedulable(w http.ResponseWriter, r *http.Request, schedulable bool) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	name := r.PathValue("name")
+	node, err := h.k8sClient.SetNodeSchedulable(r.Context(), name, schedulable)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	action := "cordoned"
+	if schedulable {
+		action = "uncordoned"
+	}
+	h.broadcast(k8s.DrainEvent{Node: name, Phase: action})
+
+	response.SendSuccess(w, map[string]interface{}{
+		"node":          node.Name,
+		"unschedulable": node.Spec.Unschedulable,
+	})
+}
+
+// DrainNode handles POST /api/k8s/nodes/{name}/drain
+// The drain runs in the background; progress is broadcast on the node_operations channel.
+func (h *NodeHandlers) DrainNode(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	opts := k8s.DrainOptions{IgnoreDaemonSets: true}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	name := r.PathValue("name")
+
+	// Evictions blocked by a budget retry until the drain times out, so say up front which will
+	warnings, err := h.k8sClient.CheckDrainBudgets(r.Context(), name, opts)
+	if err != nil {
+		slog.Warn("Failed to check disruption budgets before drain", "node", name, "error", err)
+	}
+
+	h.mutex.Lock()
+	if existing, ok := h.drains[name]; ok && existing.Status == "running" {
+		h.mutex.Unlock()
+		response.SendError(w, http.StatusConflict, "Node is already being drained")
+		return
+	}
+	status := &DrainStatus{
+		Node:      name,
+		Status:    "running",
+		StartedAt: time.Now(),
+		Warnings:  warnings,
+	}
+	h.drains[name] = status
+	h.mutex.Unlock()
+
+	go h.runDrain(name, opts)
+
+	message := "Drain started"
+	if len(warnings) > 0 {
+		message = fmt.Sprintf("Drain started; %d disruption budget(s) will delay evictions", len(warnings))
+	}
+	response.SendJSON(w, http.StatusAccepted, response.APIResponse{
+		Success: true,
+		Data:    status,
+		Message: message,
+	})
+}
+
+// GetDrainStatus handles GET /api/k8s/nodes/{name}/drain
+func (h *NodeHandlers) GetDrainStatus(w http.ResponseWriter, r *http.Request) {
+	h.mutex.RLock()
+	status, ok := h.drains[r.PathValue("name")]
+	var snapshot DrainStatus
+	if ok {
+		snapshot = *status
+	}
+	h.mutex.RUnlock()
+
+	if !ok {
+		response.SendError(w, http.StatusNotFound, "No drain recorded for node")
+		return
+	}
+	response.SendSuccess(w, snapshot)
+}
+
+func (h *NodeHandlers) runDrain(name string, opts k8s.DrainOptions) {
+	result, err := h.k8sClient.DrainNode(context.Background(), name, opts, func(event k8s.DrainEvent) {
+		h.mutex.Lock()
+		h.drains[name].LastEvent = &event
+		h.mutex.Unlock()
+		h.broadcast(event)
+	})
+
+	now := time.Now()
+	final := k8s.DrainEvent{Node: name, Phase: "completed"}
+	if result != nil {
+		final.Evicted = len(result.EvictedPods)
+	}
+
+	h.mutex.Lock()
+	status := h.drains[name]
+	status.CompletedAt = &now
+	status.Result = result
+	if err != nil {
+		status.Status = "failed"
+		status.Error = err.Error()
+		final.Phase = "failed"
+		final.Message = err.Error()
+	} else {
+		status.Status = "completed"
+	}
+	status.LastEvent = &final
+	h.mutex.Unlock()
+
+	if err != nil {
+		slog.Error("Node drain failed", "node", name, "error", err)
+	} else {
+		slog.Info("Node drained", "node", name, "evicted", final.Evicted)
+	}
+	h.broadcast(final)
+}
+
+// ListTaints handles GET /api/k8s/nodes/{name}/taints
+func (h *NodeHandlers) ListTaints(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	taints, err := h.k8sClient.GetNodeTaints(r.Context(), r.PathValue("name"))
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if taints == nil {
+		taints = []corev1.Taint{}
+	}
+	response.SendSuccess(w, taints)
+}
+
+// AddTaint handles POST /api/k8s/nodes/{name}/taints
+func (h *NodeHandlers) AddTaint(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	var req TaintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Key == "" || req.Effect == "" {
+		response.SendError(w, http.StatusBadRequest, "key and effect are required")
+		return
+	}
+
+	taints, err := h.k8sClient.SetNodeTaint(r.Context(), r.PathValue("name"), corev1.Taint{
+		Key:    req.Key,
+		Value:  req.Value,
+		Effect: req.Effect,
+	})
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.SendSuccess(w, taints)
+}
+
+// RemoveTaint handles DELETE /api/k8s/nodes/{name}/taints/{key}?effect=NoSchedule
+func (h *NodeHandlers) RemoveTaint(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	effect := corev1.TaintEffect(r.URL.Query().Get("effect"))
+	taints, err := h.k8sClient.RemoveNodeTaint(r.Context(), r.PathValue("name"), r.PathValue("key"), effect)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if taints == nil {
+		taints = []corev1.Taint{}
+	}
+	response.SendSuccess(w, taints)
+}
+
+func (h *NodeHandlers) broadcast(event k8s.DrainEvent) {
+	if h.wsHub != nil {
+		h.wsHub.Broadcast(websocket.MessageTypeNodeOperations, event)
+	}
+}
@@ -3,24 +3,40 @@ package http
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/archellir/denshimon/internal/database"
+	"github.com/archellir/denshimon/internal/events"
+	"github.com/archellir/denshimon/internal/gitops"
 	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/internal/logs"
+	"github.com/archellir/denshimon/internal/websocket"
 	"github.com/archellir/denshimon/pkg/response"
 )
 
 type ObservabilityHandlers struct {
-	k8sClient *k8s.Client
+	k8sClient  *k8s.Client
+	logStore   *logs.Store   // nil when log ingestion is disabled
+	eventStore *events.Store // nil when event recording is disabled
 }
 
-func NewObservabilityHandlers(k8sClient *k8s.Client) *ObservabilityHandlers {
+func NewObservabilityHandlers(k8sClient *k8s.Client, logStore *logs.Store) *ObservabilityHandlers {
 	return &ObservabilityHandlers{
 		k8sClient: k8sClient,
+		logStore:  logStore,
 	}
 }
 
+// SetEventStore serves system changes from recorded events instead of those still in the cluster
+func (h *ObservabilityHandlers) SetEventStore(store *events.Store) {
+	h.eventStore = store
+}
+
 // LogEntry represents a log entry matching frontend LogEntry interface
 type LogEntry struct {
 	ID        string                 `json:"id"`
@@ -60,8 +76,13 @@ type EventImpact struct {
 	Unit     string `json:"unit"`
 }
 
-// GetLogs returns paginated log entries from Kubernetes pods
+// GetLogs returns paginated log entries from Kubernetes pods.
+// With log ingestion enabled it searches the index; otherwise it reads a single pod's logs directly.
 func (h *ObservabilityHandlers) GetLogs(w http.ResponseWriter, r *http.Request) {
+	if h.logStore != nil {
+		h.searchLogs(w, r)
+		return
+	}
 	if h.k8sClient == nil {
 		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
 		return
@@ -128,8 +149,14 @@ func (h *ObservabilityHandlers) GetLogs(w http.ResponseWriter, r *http.Request)
 	})
 }
 
-// GetEvents returns Kubernetes events
+// GetEvents returns Kubernetes events.
+// With event recording enabled it searches the recorded history; otherwise it lists the
+// events the cluster still has, which age out after about an hour.
 func (h *ObservabilityHandlers) GetEvents(w http.ResponseWriter, r *http.Request) {
+	if h.eventStore != nil {
+		h.searchEvents(w, r)
+		return
+	}
 	if h.k8sClient == nil {
 		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
 		return
@@ -154,11 +181,15 @@ func (h *ObservabilityHandlers) GetEvents(w http.ResponseWriter, r *http.Request
 	}
 
 	// Convert to TimelineEvent format
+	kind, reason := r.URL.Query().Get("kind"), r.URL.Query().Get("reason")
 	events := make([]TimelineEvent, 0, len(k8sEvents.Items))
-	for i, event := range k8sEvents.Items {
-		if i >= limit {
+	for _, event := range k8sEvents.Items {
+		if len(events) >= limit {
 			break
 		}
+		if (kind != "" && !strings.EqualFold(event.InvolvedObject.Kind, kind)) || (reason != "" && !strings.EqualFold(event.Reason, reason)) {
+			continue
+		}
 
 		// Determine severity based on event type
 		severity := "info"
@@ -246,6 +277,10 @@ func (h *ObservabilityHandlers) GetLogStreams(w http.ResponseWriter, r *http.Req
 
 // GetLogAnalytics returns log analytics based on actual pod logs
 func (h *ObservabilityHandlers) GetLogAnalytics(w http.ResponseWriter, r *http.Request) {
+	if h.logStore != nil {
+		h.logAnalytics(w, r)
+		return
+	}
 	if h.k8sClient == nil {
 		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
 		return
@@ -316,4 +351,419 @@ func (h *ObservabilityHandlers) GetLogAnalytics(w http.ResponseWriter, r *http.R
 	}
 
 	response.SendSuccess(w, analytics)
-}
\ No newline at end of file
+}
+
+// searchLogs handles GET /api/log_data against the ingested log index.
+// Filters: namespace, pod, container, level (comma separated), q (full text),
+// since/until (RFC3339) or timeRange (e.g. 15m, 24h, 7d), limit and offset.
+func (h *ObservabilityHandlers) searchLogs(w http.ResponseWriter, r *http.Request) {
+	query, err := parseLogQuery(r)
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, total, err := h.logStore.Search(r.Context(), query)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to search logs: %v", err))
+		return
+	}
+
+	logEntries := make([]LogEntry, 0, len(entries))
+	for _, entry := range entries {
+		metadata := map[string]interface{}{
+			"namespace": entry.Namespace,
+			"pod":       entry.Pod,
+			"container": entry.Container,
+			"format":    entry.Format,
+		}
+		if len(entry.Fields) > 0 {
+			metadata["fields"] = entry.Fields
+		}
+		logEntries = append(logEntries, LogEntry{
+			ID:        strconv.FormatInt(entry.ID, 10),
+			Timestamp: entry.Timestamp.Format(time.RFC3339Nano),
+			Level:     entry.Level,
+			Source:    entry.Pod,
+			Message:   entry.Message,
+			Metadata:  metadata,
+		})
+	}
+
+	response.SendSuccess(w, map[string]interface{}{
+		"logs":  logEntries,
+		"total": total,
+		"metadata": map[string]interface{}{
+			"namespace": query.Namespace,
+			"pod":       query.Pod,
+			"limit":     query.Limit,
+			"offset":    query.Offset,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+// logAnalytics handles GET /api/log_data/analytics with level counts, top sources and a histogram.
+// The histogram interval defaults to a sixtieth of the time range and can be set with interval.
+func (h *ObservabilityHandlers) logAnalytics(w http.ResponseWriter, r *http.Request) {
+	timeRange := r.URL.Query().Get("timeRange")
+	if timeRange == "" {
+		timeRange = "24h"
+	}
+
+	query, err := parseLogQuery(r)
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if query.Since.IsZero() {
+		query.Since = time.Now().Add(-24 * time.Hour)
+	}
+
+	until := query.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+	interval := until.Sub(query.Since) / 60
+	if value := r.URL.Query().Get("interval"); value != "" {
+		if interval, err = parseTimeRange(value); err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid interval")
+			return
+		}
+	}
+
+	ctx := r.Context()
+	counts, err := h.logStore.LevelCounts(ctx, query)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get log analytics: %v", err))
+		return
+	}
+	sources, err := h.logStore.TopSources(ctx, query, 10)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get log analytics: %v", err))
+		return
+	}
+	histogram, err := h.logStore.Histogram(ctx, query, interval)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get log analytics: %v", err))
+		return
+	}
+
+	totalLogs := 0
+	for _, count := range counts {
+		totalLogs += count
+	}
+	percent := func(count, total int) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(count) / float64(total) * 100
+	}
+
+	topSources := make([]map[string]interface{}, 0, len(sources))
+	for _, source := range sources {
+		topSources = append(topSources, map[string]interface{}{
+			"source":     source.Pod,
+			"namespace":  source.Namespace,
+			"logCount":   source.Total,
+			"errorRate":  percent(source.Errors, source.Total),
+			"percentage": percent(source.Total, totalLogs),
+		})
+	}
+
+	response.SendSuccess(w, map[string]interface{}{
+		"timeRange": timeRange,
+		"overview": map[string]interface{}{
+			"totalLogs":   totalLogs,
+			"errorLogs":   counts[logs.LevelError],
+			"warningLogs": counts[logs.LevelWarn],
+			"infoLogs":    counts[logs.LevelInfo],
+			"debugLogs":   counts[logs.LevelDebug],
+			"errorRate":   percent(counts[logs.LevelError], totalLogs),
+			"warningRate": percent(counts[logs.LevelWarn], totalLogs),
+		},
+		"topSources": topSources,
+		"histogram":  histogram,
+		"interval":   interval.String(),
+		"timestamp":  time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// parseLogQuery reads log search filters from the query string
+func parseLogQuery(r *http.Request) (logs.Query, error) {
+	params := r.URL.Query()
+	query := logs.Query{
+		Namespace: params.Get("namespace"),
+		Pod:       params.Get("pod"),
+		Container: params.Get("container"),
+		Text:      params.Get("q"),
+		Limit:     100,
+	}
+
+	if levels := params.Get("level"); levels != "" {
+		for _, level := range strings.Split(levels, ",") {
+			if level = strings.TrimSpace(level); level != "" {
+				query.Levels = append(query.Levels, level)
+			}
+		}
+	}
+	if limit, err := strconv.Atoi(params.Get("limit")); err == nil && limit > 0 && limit <= 1000 {
+		query.Limit = limit
+	}
+	if offset, err := strconv.Atoi(params.Get("offset")); err == nil && offset > 0 {
+		query.Offset = offset
+	}
+
+	if value := params.Get("timeRange"); value != "" {
+		window, err := parseTimeRange(value)
+		if err != nil {
+			return query, fmt.Errorf("invalid timeRange: %s", value)
+		}
+		query.Since = time.Now().Add(-window)
+	}
+	if value := params.Get("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid since: %s", value)
+		}
+		query.Since = since
+	}
+	if value := params.Get("until"); value != "" {
+		until, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid until: %s", value)
+		}
+		query.Until = until
+	}
+	return query, nil
+}
+
+// parseTimeRange parses a Go duration, additionally accepting whole days such as 7d
+func parseTimeRange(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid time range: %s", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid time range: %s", value)
+	}
+	return d, nil
+}
+
+// initLogIngestion creates the log store and starts tailing pods. It is configured with
+// LOG_INGEST_ENABLED, LOG_INGEST_NAMESPACES (comma separated, default all),
+// LOG_INGEST_SELECTOR (pod label selector) and LOG_RETENTION (default 72h).
+// It returns nil when ingestion is disabled or Kubernetes is unavailable.
+func initLogIngestion(db *database.SQLiteDB, k8sClient *k8s.Client) *logs.Store {
+	if k8sClient == nil || os.Getenv("LOG_INGEST_ENABLED") == "false" {
+		return nil
+	}
+
+	store, err := logs.NewStore(db.DB)
+	if err != nil {
+		slog.Warn("Log ingestion disabled", "error", err)
+		return nil
+	}
+
+	config := logs.DefaultCollectorConfig()
+	config.Selector = os.Getenv("LOG_INGEST_SELECTOR")
+	for _, namespace := range strings.Split(os.Getenv("LOG_INGEST_NAMESPACES"), ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			config.Namespaces = append(config.Namespaces, namespace)
+		}
+	}
+	if value := os.Getenv("LOG_RETENTION"); value != "" {
+		if retention, err := parseTimeRange(value); err == nil {
+			config.Retention = retention
+		}
+	}
+
+	logs.NewCollector(k8sClient.Clientset(), store, config).Start(context.Background())
+	return store
+}
+
+// searchEvents handles GET /api/system_changes from the recorded event history, filtered by
+// namespace, kind, name, reason, type, q (message text) and timeRange or since/until
+func (h *ObservabilityHandlers) searchEvents(w http.ResponseWriter, r *http.Request) {
+	query, err := parseEventQuery(r)
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	recorded, total, err := h.eventStore.Search(r.Context(), query)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to search events: %v", err))
+		return
+	}
+
+	timelineEvents := make([]TimelineEvent, 0, len(recorded))
+	for _, event := range recorded {
+		severity := "info"
+		if event.Type == "Warning" {
+			severity = "warning"
+		}
+		duration := int(event.LastSeen.Sub(event.FirstSeen).Milliseconds())
+		timelineEvents = append(timelineEvents, TimelineEvent{
+			ID:          event.UID,
+			Timestamp:   event.FirstSeen.Format(time.RFC3339),
+			Category:    event.Component,
+			Severity:    severity,
+			Title:       event.Reason,
+			Description: event.Message,
+			Source: EventSource{
+				Type:      event.Component,
+				Name:      event.Name,
+				Namespace: event.Namespace,
+			},
+			Duration: &duration,
+			Metadata: map[string]interface{}{
+				"kind":      event.Kind,
+				"count":     event.Count,
+				"last_seen": event.LastSeen.Format(time.RFC3339),
+			},
+		})
+	}
+
+	response.SendSuccess(w, map[string]interface{}{
+		"events": timelineEvents,
+		"total":  total,
+		"metadata": map[string]interface{}{
+			"namespace": query.Namespace,
+			"limit":     query.Limit,
+			"offset":    query.Offset,
+			"recorded":  true,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+func parseEventQuery(r *http.Request) (events.Query, error) {
+	params := r.URL.Query()
+	query := events.Query{
+		Namespace: params.Get("namespace"),
+		Kind:      params.Get("kind"),
+		Name:      params.Get("name"),
+		Reason:    params.Get("reason"),
+		Type:      params.Get("type"),
+		Text:      params.Get("q"),
+		Limit:     50,
+	}
+	if limit, err := strconv.Atoi(params.Get("limit")); err == nil && limit > 0 && limit <= 1000 {
+		query.Limit = limit
+	}
+	if offset, err := strconv.Atoi(params.Get("offset")); err == nil && offset > 0 {
+		query.Offset = offset
+	}
+
+	if value := params.Get("timeRange"); value != "" {
+		window, err := parseTimeRange(value)
+		if err != nil {
+			return query, fmt.Errorf("invalid timeRange: %s", value)
+		}
+		query.Since = time.Now().Add(-window)
+	}
+	if value := params.Get("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid since: %s", value)
+		}
+		query.Since = since
+	}
+	if value := params.Get("until"); value != "" {
+		until, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid until: %s", value)
+		}
+		query.Until = until
+	}
+	return query, nil
+}
+
+// initEventRecording creates the event store and starts watching events. It is configured
+// with EVENT_RECORDING_ENABLED, EVENT_RETENTION (default 14d) and EVENT_WARNING_RETENTION
+// (default 30d). It returns nil when recording is disabled or Kubernetes is unavailable.
+func initEventRecording(db *database.SQLiteDB, k8sClient *k8s.Client) *events.Store {
+	if k8sClient == nil || os.Getenv("EVENT_RECORDING_ENABLED") == "false" {
+		return nil
+	}
+
+	store, err := events.NewStore(db.DB)
+	if err != nil {
+		slog.Warn("Event recording disabled", "error", err)
+		return nil
+	}
+
+	config := events.DefaultRecorderConfig()
+	if value := os.Getenv("EVENT_RETENTION"); value != "" {
+		if retention, err := parseTimeRange(value); err == nil {
+			config.Retention = retention
+		}
+	}
+	if value := os.Getenv("EVENT_WARNING_RETENTION"); value != "" {
+		if retention, err := parseTimeRange(value); err == nil {
+			config.WarningRetention = retention
+		}
+	}
+
+	events.NewRecorder(k8sClient.Clientset(), store, config).Start(context.Background())
+	return store
+}
+
+// initExternalChanges watches Deployments, Services and ConfigMaps for changes made outside
+// Denshimon (kubectl edit, other controllers). They are streamed on the events channel and
+// recorded as ExternalChange events, and raise a drift alert when they touch a resource of
+// a gitops application. Disabled with EXTERNAL_CHANGES_ENABLED=false;
+// EXTERNAL_CHANGES_IGNORED_MANAGERS replaces the field managers left out.
+func initExternalChanges(k8sClient *k8s.Client, store *events.Store, hub *websocket.Hub, gitopsService *gitops.Service) {
+	if k8sClient == nil || os.Getenv("EXTERNAL_CHANGES_ENABLED") == "false" {
+		return
+	}
+
+	watcher := events.NewChangeWatcher(k8sClient.Clientset(), store, k8s.DefaultFieldManager)
+	watcher.SetHub(hub)
+	if value := os.Getenv("EXTERNAL_CHANGES_IGNORED_MANAGERS"); value != "" {
+		var managers []string
+		for _, manager := range strings.Split(value, ",") {
+			if manager = strings.TrimSpace(manager); manager != "" {
+				managers = append(managers, manager)
+			}
+		}
+		watcher.SetIgnoredManagers(managers)
+	}
+	if gitopsService != nil {
+		watcher.SetTracker(func(ctx context.Context, change events.ExternalChange) string {
+			if appID := change.Annotations["denshimon.io/app-id"]; appID != "" {
+				return appID
+			}
+			apps, err := gitopsService.ListApplications(ctx)
+			if err != nil {
+				slog.Warn("Failed to list gitops applications for external change", "error", err)
+				return ""
+			}
+			for _, app := range apps {
+				if app.Namespace == change.Namespace && app.Name == change.Name {
+					return app.ID
+				}
+			}
+			return ""
+		})
+		watcher.OnDrift(func(ctx context.Context, change events.ExternalChange) {
+			if _, err := gitopsService.CreateAlert(ctx, "drift_detected", "warning", "External Change Detected", change.Message(),
+				map[string]string{
+					"application": change.Application,
+					"kind":        change.Kind,
+					"namespace":   change.Namespace,
+					"name":        change.Name,
+					"manager":     change.Manager,
+				}); err != nil {
+				slog.Error("Failed to raise drift alert", "error", err)
+			}
+		})
+	}
+	watcher.Start(context.Background())
+}
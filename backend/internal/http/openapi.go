@@ -0,0 +1,89 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/archellir/denshimon/internal/openapi"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+// routeRecorder registers handlers on a ServeMux and keeps their patterns, so the OpenAPI
+// document lists exactly the routes being served, including only the optional ones enabled
+type routeRecorder struct {
+	*http.ServeMux
+	patterns []string
+}
+
+func newRouteRecorder(mux *http.ServeMux) *routeRecorder {
+	return &routeRecorder{ServeMux: mux}
+}
+
+func (m *routeRecorder) Handle(pattern string, handler http.Handler) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.Handle(pattern, handler)
+}
+
+func (m *routeRecorder) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.HandleFunc(pattern, handler)
+}
+
+// OpenAPIHandlers serves the OpenAPI document of the registered routes
+type OpenAPIHandlers struct {
+	routes *routeRecorder
+	once   sync.Once
+	spec   []byte
+	err    error
+}
+
+func NewOpenAPIHandlers(routes *routeRecorder) *OpenAPIHandlers {
+	return &OpenAPIHandlers{routes: routes}
+}
+
+// GetSpec handles GET /api/openapi.json. The document is built on the first request,
+// after RegisterRoutes has returned.
+func (h *OpenAPIHandlers) GetSpec(w http.ResponseWriter, r *http.Request) {
+	h.once.Do(func() {
+		h.spec, h.err = buildOpenAPISpec(h.routes.patterns)
+	})
+	if h.err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to build OpenAPI document: "+h.err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(h.spec)
+}
+
+func buildOpenAPISpec(patterns []string) ([]byte, error) {
+	doc, err := openapi.Generate(openapi.Info{
+		Title:       "Denshimon API",
+		Version:     "1.0.0",
+		Description: "Kubernetes, GitOps and infrastructure management API.",
+	}, documentedRoutes(patterns))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// documentedRoutes annotates the registered /api routes. A subtree pattern stands for the
+// operations its handler dispatches, which are listed in subtreeRoutes.
+func documentedRoutes(patterns []string) map[string]openapi.Route {
+	routes := make(map[string]openapi.Route)
+	for _, pattern := range patterns {
+		_, path, hasMethod := strings.Cut(pattern, " ")
+		if !hasMethod {
+			for operation, route := range subtreeRoutes[pattern] {
+				routes[operation] = route
+			}
+			continue
+		}
+		if strings.HasPrefix(path, "/api/") {
+			routes[pattern] = apiRoutes[pattern]
+		}
+	}
+	return routes
+}
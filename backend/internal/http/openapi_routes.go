@@ -0,0 +1,494 @@
+package http
+
+import (
+	"github.com/archellir/denshimon/internal/alerts"
+	"github.com/archellir/denshimon/internal/audit"
+	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/internal/catalog"
+	"github.com/archellir/denshimon/internal/deployments"
+	"github.com/archellir/denshimon/internal/discovery"
+	"github.com/archellir/denshimon/internal/gitea"
+	"github.com/archellir/denshimon/internal/gitops"
+	"github.com/archellir/denshimon/internal/graphql"
+	"github.com/archellir/denshimon/internal/incidents"
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/internal/metrics"
+	"github.com/archellir/denshimon/internal/monitors"
+	"github.com/archellir/denshimon/internal/opa"
+	"github.com/archellir/denshimon/internal/openapi"
+	"github.com/archellir/denshimon/internal/preferences"
+	"github.com/archellir/denshimon/internal/providers"
+	"github.com/archellir/denshimon/internal/providers/backup"
+	"github.com/archellir/denshimon/internal/providers/certificates"
+	"github.com/archellir/denshimon/internal/providers/databases"
+	"github.com/archellir/denshimon/internal/retention"
+	"github.com/archellir/denshimon/internal/sbom"
+	"github.com/archellir/denshimon/internal/smart"
+	"github.com/archellir/denshimon/internal/timeline"
+	"github.com/archellir/denshimon/internal/upgrades"
+	"github.com/archellir/denshimon/internal/webhooks"
+	"github.com/archellir/denshimon/internal/websocket"
+	"github.com/archellir/denshimon/pkg/apiclient"
+)
+
+// jsonObject documents request bodies decoded into anonymous structs
+var jsonObject = map[string]interface{}{}
+
+// apiRoutes annotates the routes registered in RegisterRoutes, keyed by their pattern.
+// Request and Response are set where the handler's body types are known; routes without
+// them are documented as taking and returning any JSON.
+var apiRoutes = map[string]openapi.Route{
+	// Authentication and users
+	"POST /api/auth/login":           {Summary: "Log in with a username and password", Public: true, Request: LoginRequest{}, Response: LoginResponse{}},
+	"POST /api/auth/logout":          {Summary: "Log out, revoking the session", Public: true},
+	"POST /api/auth/refresh":         {Summary: "Refresh the session token"},
+	"GET /api/preferences":           {Summary: "Get your UI preferences", Response: preferences.Preferences{}, Envelope: true},
+	"PUT /api/preferences":           {Summary: "Update your UI preferences; fields left out are kept", Request: preferences.Preferences{}, Response: preferences.Preferences{}, Envelope: true},
+	"DELETE /api/preferences":        {Summary: "Reset your UI preferences to the defaults"},
+	"GET /api/auth/me":               {Summary: "Get the current user", Response: UserInfo{}},
+	"GET /api/auth/sessions":         {Summary: "List sessions", Response: []auth.Session{}},
+	"DELETE /api/auth/sessions/{id}": {Summary: "Revoke a session"},
+	"POST /api/auth/tokens":          {Summary: "Create an API token", Request: CreateAPITokenRequest{}, Response: CreateAPITokenResponse{}},
+	"GET /api/auth/tokens":           {Summary: "List API tokens", Response: []auth.APIToken{}},
+	"DELETE /api/auth/tokens/{id}":   {Summary: "Revoke an API token"},
+	"GET /api/auth/keys":             {Summary: "List signing keys", Role: "admin", Response: []auth.SigningKey{}},
+	"POST /api/auth/keys/rotate":     {Summary: "Rotate the token signing key", Role: "admin"},
+	"POST /api/auth/users":           {Summary: "Create a user", Role: "admin", Request: CreateUserRequest{}},
+	"GET /api/auth/users":            {Summary: "List users", Role: "admin", Response: []UserInfo{}},
+	"PUT /api/auth/users/{id}":       {Summary: "Update a user", Role: "admin", Request: UpdateUserRequest{}},
+	"DELETE /api/auth/users/{id}":    {Summary: "Delete a user", Role: "admin"},
+
+	// Kubernetes resources
+	"GET /api/k8s/pods":                         {Summary: "List pods", Query: []openapi.Param{{Name: "namespace"}}, Response: []PodInfo{}},
+	"GET /api/k8s/pods/{name}":                  {Summary: "Get a pod", Query: []openapi.Param{{Name: "namespace"}}, Response: PodInfo{}},
+	"POST /api/k8s/pods/{name}/restart":         {Summary: "Restart a pod", Query: []openapi.Param{{Name: "namespace"}}},
+	"DELETE /api/k8s/pods/{name}":               {Summary: "Delete a pod", Query: []openapi.Param{{Name: "namespace"}}},
+	"GET /api/k8s/pods/{name}/logs":             {Summary: "Get pod logs", Query: []openapi.Param{{Name: "container"}, {Name: "follow", Type: "boolean"}, {Name: "namespace"}, {Name: "tail", Type: "integer"}}},
+	"POST /api/k8s/pods/bulk":                   {Summary: "Restart or delete several pods", Role: "operator", Request: BulkPodRequest{}},
+	"GET /api/k8s/deployments":                  {Summary: "List deployments", Query: []openapi.Param{{Name: "namespace"}}, Response: []DeploymentInfo{}},
+	"PATCH /api/k8s/deployments/{name}/scale":   {Summary: "Scale a deployment", Query: []openapi.Param{{Name: "namespace"}}, Request: jsonObject},
+	"GET /api/k8s/statefulsets":                 {Summary: "List stateful sets", Query: []openapi.Param{{Name: "namespace"}}},
+	"GET /api/k8s/statefulsets/{name}":          {Summary: "Get a stateful set", Query: []openapi.Param{{Name: "namespace"}}},
+	"PATCH /api/k8s/statefulsets/{name}/scale":  {Summary: "Scale a stateful set", Role: "operator", Query: []openapi.Param{{Name: "namespace"}}, Request: jsonObject},
+	"POST /api/k8s/statefulsets/{name}/restart": {Summary: "Restart a stateful set", Role: "operator", Query: []openapi.Param{{Name: "namespace"}}},
+	"DELETE /api/k8s/statefulsets/{name}":       {Summary: "Delete a stateful set", Role: "operator", Query: []openapi.Param{{Name: "namespace"}}},
+	"GET /api/k8s/daemonsets":                   {Summary: "List daemon sets", Query: []openapi.Param{{Name: "namespace"}}},
+	"GET /api/k8s/daemonsets/{name}":            {Summary: "Get a daemon set", Query: []openapi.Param{{Name: "namespace"}}},
+	"POST /api/k8s/daemonsets/{name}/restart":   {Summary: "Restart a daemon set", Role: "operator", Query: []openapi.Param{{Name: "namespace"}}},
+	"DELETE /api/k8s/daemonsets/{name}":         {Summary: "Delete a daemon set", Role: "operator", Query: []openapi.Param{{Name: "namespace"}}},
+	"GET /api/k8s/nodes":                        {Summary: "List nodes", Response: []NodeInfo{}},
+	"GET /api/k8s/nodes/disks":                  {Summary: "Get the SMART health of node disks", Query: []openapi.Param{{Name: "node"}}, Response: smart.Report{}, Envelope: true},
+	"GET /api/k8s/deprecated-apis":              {Summary: "List manifests and live objects using API versions removed by an upgrade", Query: []openapi.Param{{Name: "target"}}, Response: upgrades.DeprecationScan{}, Envelope: true},
+	"GET /api/k8s/upgrades":                     {Summary: "Compare cluster and addon versions with their latest releases and find manifests an upgrade breaks", Query: []openapi.Param{{Name: "target"}}, Response: upgrades.Report{}, Envelope: true},
+	"POST /api/k8s/nodes/disks/check":           {Summary: "Check the SMART health of node disks in the background", Role: "operator"},
+	"POST /api/k8s/nodes/{name}/cordon":         {Summary: "Cordon a node", Role: "operator"},
+	"POST /api/k8s/nodes/{name}/uncordon":       {Summary: "Uncordon a node", Role: "operator"},
+	"POST /api/k8s/nodes/{name}/drain":          {Summary: "Start draining a node", Role: "operator", Request: k8s.DrainOptions{}},
+	"GET /api/k8s/nodes/{name}/drain":           {Summary: "Get a node's drain progress"},
+	"GET /api/k8s/nodes/{name}/taints":          {Summary: "List a node's taints"},
+	"POST /api/k8s/nodes/{name}/taints":         {Summary: "Add a taint to a node", Role: "operator", Request: TaintRequest{}},
+	"DELETE /api/k8s/nodes/{name}/taints/{key}": {Summary: "Remove a taint from a node", Role: "operator", Query: []openapi.Param{{Name: "effect"}}},
+	"GET /api/k8s/services":                     {Summary: "List services", Query: []openapi.Param{{Name: "namespace"}}, Response: []ServiceInfo{}},
+	"GET /api/k8s/events":                       {Summary: "List events", Query: []openapi.Param{{Name: "namespace"}}, Response: []EventInfo{}},
+
+	// Change timeline
+	"GET /api/timeline": {Summary: "Get the change timeline", Query: []openapi.Param{{Name: "app"}, {Name: "limit", Type: "integer"}, {Name: "namespace"}, {Name: "since"}, {Name: "until"}, {Name: "window"}}, Response: timeline.Timeline{}, Envelope: true},
+
+	// GraphQL
+	"GET /api/graphql":        {Summary: "Run a GraphQL query", Query: []openapi.Param{{Name: "query", Required: true}, {Name: "operationName"}, {Name: "variables", Description: "JSON object"}}, Response: graphql.Response{}},
+	"POST /api/graphql":       {Summary: "Run a GraphQL query", Request: graphql.Request{}, Response: graphql.Response{}},
+	"GET /api/graphql/schema": {Summary: "Get the GraphQL schema", ContentType: "text/plain"},
+
+	// Namespaces, storage, volume claims, network policies, RBAC and disruption budgets
+	"GET /api/k8s/namespaces":                     {Summary: "List namespaces"},
+	"GET /api/k8s/namespaces/{name}/quotas":       {Summary: "Get a namespace's quotas and limit ranges"},
+	"GET /api/k8s/storage":                        {Summary: "Get storage information with per node and claim usage"},
+	"GET /api/k8s/health":                         {Summary: "Check the Kubernetes connection", Public: true},
+	"GET /api/k8s/storageclasses":                 {Summary: "List storage classes, the default first", Response: []k8s.StorageClassInfo{}, Envelope: true},
+	"POST /api/k8s/storageclasses":                {Summary: "Create a storage class", Role: "admin"},
+	"DELETE /api/k8s/storageclasses/{name}":       {Summary: "Delete a storage class", Role: "admin"},
+	"POST /api/k8s/storageclasses/{name}/default": {Summary: "Make a storage class the default", Role: "admin"},
+	"POST /api/k8s/pvcs/{name}/expand":            {Summary: "Expand a persistent volume claim", Role: "operator", Query: []openapi.Param{{Name: "namespace"}}, Request: expandVolumeRequest{}, Response: k8s.VolumeResize{}, Envelope: true},
+	"GET /api/k8s/pvcs/{name}/resize":             {Summary: "Get the resize progress of a persistent volume claim", Query: []openapi.Param{{Name: "namespace"}}, Response: k8s.VolumeResize{}, Envelope: true},
+	"GET /api/k8s/networkpolicies":                {Summary: "List network policies", Query: []openapi.Param{{Name: "namespace"}}},
+	"GET /api/k8s/networkpolicies/{name}":         {Summary: "Get a network policy", Query: []openapi.Param{{Name: "namespace"}}},
+	"POST /api/k8s/networkpolicies":               {Summary: "Create a network policy", Role: "operator", Query: []openapi.Param{{Name: "namespace"}}},
+	"PUT /api/k8s/networkpolicies/{name}":         {Summary: "Update a network policy", Role: "operator", Query: []openapi.Param{{Name: "namespace"}}},
+	"DELETE /api/k8s/networkpolicies/{name}":      {Summary: "Delete a network policy", Role: "operator", Query: []openapi.Param{{Name: "namespace"}}},
+	"GET /api/k8s/connectivity":                   {Summary: "Get the namespace connectivity matrix", Query: []openapi.Param{{Name: "level"}, {Name: "namespace"}}},
+	"GET /api/k8s/rbac/can-i":                     {Summary: "Check whether a user or service account may perform an action", Role: "operator", Query: []openapi.Param{{Name: "verb"}, {Name: "resource"}, {Name: "group"}, {Name: "subresource"}, {Name: "namespace"}, {Name: "name"}, {Name: "user"}, {Name: "groups"}}, Response: k8s.AccessResult{}, Envelope: true},
+	"GET /api/k8s/rbac/who-can":                   {Summary: "List the subjects whose role bindings grant an action", Role: "operator", Query: []openapi.Param{{Name: "verb"}, {Name: "resource"}, {Name: "group"}, {Name: "subresource"}, {Name: "namespace"}, {Name: "name"}}, Response: []k8s.AccessGrant{}, Envelope: true},
+	"GET /api/k8s/rbac/roles":                     {Summary: "List roles and cluster roles", Role: "operator", Query: []openapi.Param{{Name: "namespace"}}, Response: []k8s.RoleSummary{}, Envelope: true},
+	"GET /api/k8s/rbac/bindings":                  {Summary: "List role bindings and cluster role bindings", Role: "operator", Query: []openapi.Param{{Name: "namespace"}, {Name: "subject"}}, Response: []k8s.BindingSummary{}, Envelope: true},
+	"GET /api/k8s/security/audit":                 {Summary: "Audit workloads against the Pod Security Standards", Role: "operator", Query: []openapi.Param{{Name: "namespace"}, {Name: "refresh", Type: "boolean"}}, Response: k8s.PodSecurityReport{}, Envelope: true},
+	"POST /api/k8s/kubeconfigs":                   {Summary: "Mint a kubeconfig mirroring a user's role in namespaces", Role: "admin", Request: kubeconfigRequest{}, Response: k8s.UserKubeconfig{}, Envelope: true},
+	"DELETE /api/k8s/kubeconfigs/{username}":      {Summary: "Revoke a user's kubeconfig access", Role: "admin"},
+	"GET /api/k8s/pdbs":                           {Summary: "List pod disruption budgets", Query: []openapi.Param{{Name: "namespace"}}},
+	"GET /api/k8s/pdbs/{name}":                    {Summary: "Get a pod disruption budget", Query: []openapi.Param{{Name: "namespace"}}},
+	"POST /api/k8s/pdbs":                          {Summary: "Create a pod disruption budget", Role: "operator", Query: []openapi.Param{{Name: "namespace"}}},
+	"PUT /api/k8s/pdbs/{name}":                    {Summary: "Update a pod disruption budget", Role: "operator", Query: []openapi.Param{{Name: "namespace"}}},
+	"DELETE /api/k8s/pdbs/{name}":                 {Summary: "Delete a pod disruption budget", Role: "operator", Query: []openapi.Param{{Name: "namespace"}}},
+	"GET /api/k8s/{kind}/{name}/yaml":             {Summary: "Get a resource's manifest; Secret values are redacted for non-admins", Query: []openapi.Param{{Name: "format"}, {Name: "managedFields", Type: "boolean"}, {Name: "namespace"}}},
+	"POST /api/k8s/apply":                         {Summary: "Apply manifests", Role: "operator", Query: []openapi.Param{{Name: "dryRun", Type: "boolean"}, {Name: "force", Type: "boolean"}, {Name: "namespace"}}},
+
+	// Service mesh and topology
+	"GET /api/services/mesh":      {Summary: "Get the service mesh"},
+	"GET /api/services/topology":  {Summary: "Get service topology"},
+	"GET /api/services/endpoints": {Summary: "Get service endpoints"},
+	"GET /api/services/flows":     {Summary: "Get service flows"},
+
+	// Traces
+	"GET /api/traces":      {Summary: "Search traces", Query: []openapi.Param{{Name: "end"}, {Name: "limit", Type: "integer"}, {Name: "lookback"}, {Name: "maxDuration"}, {Name: "minDuration"}, {Name: "operation"}, {Name: "service"}, {Name: "start"}}},
+	"GET /api/traces/{id}": {Summary: "Get a trace"},
+
+	// Configuration
+	"GET /api/config": {Summary: "Get the effective configuration", Role: "admin"},
+
+	// Service gateway
+	"GET /api/services/gateway": {Summary: "Get service gateway"},
+
+	// Pod exec, log streaming, port forwarding and file transfer
+	"GET /api/k8s/pods/exec":                 {Summary: "Open a shell in a pod over WebSocket"},
+	"GET /api/k8s/pods/exec/shared/{id}":     {Summary: "Join an exec session shared with you over WebSocket", Query: []openapi.Param{{Name: "token"}}},
+	"GET /api/k8s/nodes/{name}/shell":        {Summary: "Open a recorded shell on a node over WebSocket", Role: "admin"},
+	"GET /api/k8s/pods/logs/stream":          {Summary: "Stream pod logs over WebSocket"},
+	"POST /api/k8s/pods/portforward":         {Summary: "Forward a port to a pod"},
+	"POST /api/k8s/pods/files/upload":        {Summary: "Upload a file to a pod"},
+	"GET /api/k8s/pods/files/download":       {Summary: "Download a file from a pod"},
+	"POST /api/k8s/pods/{name}/debug":        {Summary: "Attach an ephemeral debug container to a pod", Role: "operator", Query: []openapi.Param{{Name: "namespace"}}, Request: debugContainerRequest{}, Response: k8s.DebugContainer{}, Envelope: true},
+	"POST /api/k8s/debug/connectivity":       {Summary: "Run DNS, port and HTTP checks from a debug pod", Role: "operator", Request: k8s.ConnectivityRequest{}, Response: k8s.ConnectivityReport{}, Envelope: true},
+	"GET /api/k8s/exec-sessions":             {Summary: "List recorded exec sessions", Query: []openapi.Param{{Name: "limit", Type: "integer"}, {Name: "namespace"}, {Name: "node"}, {Name: "pod"}, {Name: "user"}}},
+	"GET /api/k8s/exec-sessions/{id}":        {Summary: "Get a recorded exec session"},
+	"GET /api/k8s/exec-sessions/{id}/replay": {Summary: "Get an exec session's recording"},
+	"DELETE /api/k8s/exec-sessions/{id}":     {Summary: "Delete an exec session recording", Role: "admin"},
+
+	// Metrics
+	"GET /api/metrics/cluster":                {Summary: "Get cluster metrics", Response: metrics.ClusterMetrics{}},
+	"GET /api/metrics/nodes":                  {Summary: "Get node metrics", Query: []openapi.Param{{Name: "node"}}},
+	"GET /api/metrics/pods":                   {Summary: "Get pod metrics", Query: []openapi.Param{{Name: "namespace"}, {Name: "pod"}}},
+	"GET /api/metrics/history":                {Summary: "Get metrics history", Query: []openapi.Param{{Name: "duration"}}},
+	"GET /api/metrics/namespaces":             {Summary: "Get namespace metrics"},
+	"GET /api/metrics/resources":              {Summary: "Get resource metrics"},
+	"GET /api/metrics/network":                {Summary: "Get network metrics", Query: []openapi.Param{{Name: "duration"}}},
+	"GET /api/metrics/storage":                {Summary: "Get storage metrics"},
+	"GET /api/metrics/capabilities":           {Summary: "Get the available metrics sources"},
+	"GET /api/metrics/host":                   {Summary: "Get CPU, memory, disk and network of the host", Response: metrics.HostMetrics{}},
+	"GET /api/metrics/top/nodes":              {Summary: "Get nodes by usage", Query: []openapi.Param{{Name: "sort"}}},
+	"GET /api/metrics/top/pods":               {Summary: "Get pods by usage", Query: []openapi.Param{{Name: "namespace"}, {Name: "sort"}}},
+	"GET /api/metrics/recommendations":        {Summary: "Get resource request recommendations"},
+	"POST /api/metrics/recommendations/apply": {Summary: "Apply a resource recommendation", Role: "operator", Request: ApplyRecommendationRequest{}},
+	"GET /api/metrics/costs":                  {Summary: "Get estimated monthly costs of workloads and namespaces", Query: []openapi.Param{{Name: "namespace"}, {Name: "window"}}, Response: metrics.CostReport{}},
+	"GET /api/metrics/health":                 {Summary: "Get metrics health", Public: true},
+
+	// Logs and system changes
+	"GET /api/log_data":           {Summary: "Search logs", Query: []openapi.Param{{Name: "container"}, {Name: "limit", Type: "integer"}, {Name: "namespace"}, {Name: "pod"}}},
+	"GET /api/live_streams":       {Summary: "List log streams"},
+	"GET /api/log_data/analytics": {Summary: "Get log analytics", Query: []openapi.Param{{Name: "timeRange"}}},
+	"GET /api/system_changes":     {Summary: "List system changes", Query: []openapi.Param{{Name: "kind"}, {Name: "limit", Type: "integer"}, {Name: "name"}, {Name: "namespace"}, {Name: "offset", Type: "integer"}, {Name: "q"}, {Name: "reason"}, {Name: "since"}, {Name: "timeRange"}, {Name: "type"}, {Name: "until"}}},
+
+	// Infrastructure
+	"GET /api/infrastructure/services":                                 {Summary: "List the health of services discovered via the denshimon.io/monitor annotation", Response: []discovery.ServiceHealth{}, Envelope: true},
+	"GET /api/infrastructure/status":                                   {Summary: "Get infrastructure status"},
+	"GET /api/infrastructure/alerts":                                   {Summary: "List infrastructure alerts"},
+	"POST /api/infrastructure/refresh":                                 {Summary: "Refresh infrastructure services"},
+	"GET /api/infrastructure/services/overrides":                       {Summary: "List service discovery overrides", Response: []discovery.Override{}, Envelope: true},
+	"PUT /api/infrastructure/services/overrides/{namespace}/{name}":    {Summary: "Create or replace the discovery override of a service", Role: "operator", Request: discovery.Override{}, Response: discovery.Override{}, Envelope: true},
+	"DELETE /api/infrastructure/services/overrides/{namespace}/{name}": {Summary: "Delete the discovery override of a service", Role: "operator", Envelope: true},
+
+	// Audit trail
+	"GET /api/audit":            {Summary: "List audit entries", Role: "admin", Query: []openapi.Param{{Name: "action"}, {Name: "limit", Type: "integer"}, {Name: "resource"}, {Name: "since"}, {Name: "user"}}, Response: []audit.Entry{}, Envelope: true},
+	"GET /api/policy/decisions": {Summary: "List Rego policy decisions", Role: "admin", Query: []openapi.Param{{Name: "allowed", Type: "boolean"}, {Name: "limit", Type: "integer"}, {Name: "since"}, {Name: "source"}, {Name: "user"}}, Response: []opa.Decision{}, Envelope: true},
+
+	// Alerts of all sources, silences and escalation rules
+	"GET /api/alerts": {Summary: "List deduplicated alerts of gitops, infrastructure, certificates and backups", Query: []openapi.Param{{Name: "source"}, {Name: "severity"}, {Name: "status"}, {Name: "include_silenced", Type: "boolean"}, {Name: "limit", Type: "integer"}}, Response: []alerts.Alert{}, Envelope: true},
+	"POST /api/alerts/{source}/{id}/acknowledge": {Summary: "Acknowledge an alert in the subsystem that raised it", Envelope: true},
+	"GET /api/alerts/silences":                   {Summary: "List alert silences", Query: []openapi.Param{{Name: "expired", Type: "boolean"}}, Response: []alerts.Silence{}, Envelope: true},
+	"POST /api/alerts/silences":                  {Summary: "Silence alerts matching title and label patterns until expiry", Role: "operator", Request: createSilenceRequest{}, Response: alerts.Silence{}, Envelope: true},
+	"DELETE /api/alerts/silences/{id}":           {Summary: "Expire an alert silence", Role: "operator", Envelope: true},
+	"GET /api/alerts/escalations":                {Summary: "List alert escalation rules", Response: []alerts.EscalationRule{}, Envelope: true},
+	"POST /api/alerts/escalations":               {Summary: "Create an alert escalation rule", Role: "admin", Request: alerts.EscalationRule{}, Response: alerts.EscalationRule{}, Envelope: true},
+	"DELETE /api/alerts/escalations/{id}":        {Summary: "Delete an alert escalation rule", Role: "admin", Envelope: true},
+
+	// Uptime monitors
+	"GET /api/monitors":              {Summary: "List uptime monitors", Response: []monitors.Status{}, Envelope: true},
+	"POST /api/monitors":             {Summary: "Create an uptime monitor", Role: "operator", Request: monitors.Monitor{}, Response: monitors.Monitor{}, Envelope: true},
+	"GET /api/monitors/{id}":         {Summary: "Get an uptime monitor", Response: monitors.Status{}, Envelope: true},
+	"PUT /api/monitors/{id}":         {Summary: "Update an uptime monitor", Role: "operator", Request: monitors.Monitor{}, Response: monitors.Monitor{}, Envelope: true},
+	"DELETE /api/monitors/{id}":      {Summary: "Delete an uptime monitor", Role: "operator", Envelope: true},
+	"GET /api/monitors/{id}/history": {Summary: "Get an uptime monitor's check history", Query: []openapi.Param{{Name: "since"}}, Response: []monitors.Result{}, Envelope: true},
+	"POST /api/monitors/{id}/check":  {Summary: "Run an uptime check now", Role: "operator", Response: monitors.Result{}, Envelope: true},
+
+	// Outbound webhooks
+	"GET /api/webhooks":                 {Summary: "List outbound webhooks, without their secrets", Role: "admin", Response: []webhooks.Webhook{}, Envelope: true},
+	"POST /api/webhooks":                {Summary: "Create an outbound webhook for deployment, alert, backup and certificate events", Role: "admin", Request: webhooks.Webhook{}, Response: webhooks.Webhook{}, Envelope: true},
+	"GET /api/webhooks/events":          {Summary: "List the event types webhooks can subscribe to", Response: webhookEvents{}, Envelope: true},
+	"GET /api/webhooks/{id}":            {Summary: "Get an outbound webhook", Role: "admin", Response: webhooks.Webhook{}, Envelope: true},
+	"PUT /api/webhooks/{id}":            {Summary: "Update an outbound webhook; an empty secret keeps the current one", Role: "admin", Request: webhooks.Webhook{}, Response: webhooks.Webhook{}, Envelope: true},
+	"DELETE /api/webhooks/{id}":         {Summary: "Delete an outbound webhook and its delivery log", Role: "admin", Envelope: true},
+	"POST /api/webhooks/{id}/test":      {Summary: "Send a test event to a webhook now", Role: "admin", Response: webhooks.Delivery{}, Envelope: true},
+	"GET /api/webhooks/{id}/deliveries": {Summary: "Get a webhook's delivery log, newest first", Role: "admin", Query: []openapi.Param{{Name: "limit", Type: "integer"}}, Response: []webhooks.Delivery{}, Envelope: true},
+
+	// Incidents
+	"GET /api/incidents":                         {Summary: "List incidents, newest first", Query: []openapi.Param{{Name: "status"}, {Name: "open", Type: "boolean"}, {Name: "limit", Type: "integer"}}, Response: []incidents.Incident{}, Envelope: true},
+	"POST /api/incidents":                        {Summary: "Open an incident, grouping alerts by dedup key", Role: "operator", Request: incidentRequest{}, Response: incidents.Incident{}, Envelope: true},
+	"GET /api/incidents/{id}":                    {Summary: "Get an incident with its updates and alerts", Response: incidents.Incident{}, Envelope: true},
+	"PUT /api/incidents/{id}":                    {Summary: "Update an incident's title, impact, summary and affected services and deployments", Role: "operator", Request: incidentRequest{}, Response: incidents.Incident{}, Envelope: true},
+	"DELETE /api/incidents/{id}":                 {Summary: "Delete an incident", Role: "operator", Envelope: true},
+	"POST /api/incidents/{id}/updates":           {Summary: "Post an update to an incident, optionally changing its status", Role: "operator", Request: incidents.Update{}, Response: incidents.Incident{}, Envelope: true},
+	"POST /api/incidents/{id}/alerts":            {Summary: "Group alerts into an incident by dedup key", Role: "operator", Request: linkAlertsRequest{}, Response: incidents.Incident{}, Envelope: true},
+	"DELETE /api/incidents/{id}/alerts/{key...}": {Summary: "Remove an alert from an incident", Role: "operator", Envelope: true},
+	"GET /api/status":                            {Summary: "Get the status page: overall status with open and recently resolved incidents", Response: incidents.StatusPage{}, Envelope: true},
+
+	// Registries
+	"GET /api/deployments/registries":  {Summary: "List registries", Response: []providers.Registry{}},
+	"POST /api/deployments/registries": {Summary: "Add a registry", Request: jsonObject},
+
+	// Deployment template catalog
+	"GET /api/catalog":                    {Summary: "List deployment templates", Response: []catalog.Template{}, Envelope: true},
+	"POST /api/catalog":                   {Summary: "Create a deployment template", Role: "admin", Request: catalog.Template{}, Response: catalog.Template{}, Envelope: true},
+	"GET /api/catalog/{template}":         {Summary: "Get a deployment template", Response: catalog.Template{}, Envelope: true},
+	"PUT /api/catalog/{template}":         {Summary: "Update a deployment template", Role: "admin", Request: catalog.Template{}, Response: catalog.Template{}, Envelope: true},
+	"DELETE /api/catalog/{template}":      {Summary: "Delete a deployment template", Role: "admin", Envelope: true},
+	"POST /api/catalog/{template}/deploy": {Summary: "Deploy a template", Request: catalog.DeployRequest{}, Response: deployments.Deployment{}, Envelope: true},
+
+	// Image retention policies
+	"GET /api/deployments/retention/policies":              {Summary: "List image retention policies", Response: []retention.Policy{}, Envelope: true},
+	"POST /api/deployments/retention/policies":             {Summary: "Create an image retention policy", Role: "operator", Request: retention.Policy{}, Response: retention.Policy{}, Envelope: true},
+	"GET /api/deployments/retention/policies/{id}":         {Summary: "Get an image retention policy", Response: retention.Policy{}, Envelope: true},
+	"PUT /api/deployments/retention/policies/{id}":         {Summary: "Update an image retention policy", Role: "operator", Request: retention.Policy{}, Response: retention.Policy{}, Envelope: true},
+	"DELETE /api/deployments/retention/policies/{id}":      {Summary: "Delete an image retention policy", Role: "operator", Envelope: true},
+	"GET /api/deployments/retention/policies/{id}/preview": {Summary: "Preview the tags a retention policy would delete", Response: retention.Plan{}, Envelope: true},
+	"POST /api/deployments/retention/policies/{id}/run":    {Summary: "Run an image retention policy", Role: "operator", Response: retention.Run{}, Envelope: true},
+
+	// Image SBOMs
+	"GET /api/deployments/sboms":          {Summary: "List image SBOMs with the pods running each image", Query: []openapi.Param{{Name: "running", Type: "boolean"}}, Response: []sbom.SBOM{}, Envelope: true},
+	"POST /api/deployments/sboms":         {Summary: "Generate an image's SBOM with syft in the background", Role: "operator", Request: generateSBOMRequest{}, Response: sbom.ScanStatus{}, Envelope: true},
+	"GET /api/deployments/sboms/scan":     {Summary: "Get the running or last SBOM scan", Response: sbom.ScanStatus{}, Envelope: true},
+	"POST /api/deployments/sboms/scan":    {Summary: "Generate SBOMs for running images without one in the background", Role: "operator", Response: sbom.ScanStatus{}, Envelope: true},
+	"GET /api/deployments/sboms/packages": {Summary: "Find the images containing a package", Query: []openapi.Param{{Name: "name"}, {Name: "version"}, {Name: "type"}, {Name: "running", Type: "boolean"}, {Name: "limit", Type: "integer"}}, Response: []sbom.PackageMatch{}, Envelope: true},
+	"GET /api/deployments/sboms/licenses": {Summary: "Summarize the licenses of packages in image SBOMs", Query: []openapi.Param{{Name: "running", Type: "boolean"}}, Response: []sbom.LicenseSummary{}, Envelope: true},
+	"GET /api/deployments/sboms/{id}":     {Summary: "Get an image SBOM with its packages, or the syft document", Query: []openapi.Param{{Name: "format"}}, Response: sbom.SBOM{}, Envelope: true},
+	"DELETE /api/deployments/sboms/{id}":  {Summary: "Delete an image SBOM", Role: "operator", Envelope: true},
+
+	// Images and deployments
+	"GET /api/deployments/images":                     {Summary: "List images", Query: []openapi.Param{{Name: "namespace"}, {Name: "registry"}}},
+	"GET /api/deployments/images/search":              {Summary: "Search images", Query: []openapi.Param{{Name: "q"}}},
+	"GET /api/deployments/images/{registry}/manifest": {Summary: "Inspect an image manifest", Query: []openapi.Param{{Name: "reference"}, {Name: "repository"}}},
+	"DELETE /api/deployments/images/{registry}/tags":  {Summary: "Delete an image tag", Role: "operator", Query: []openapi.Param{{Name: "repository"}, {Name: "tag"}}},
+	"GET /api/deployments":                            {Summary: "List deployments", Query: []openapi.Param{{Name: "namespace"}}, Response: []deployments.Deployment{}},
+	"POST /api/deployments":                           {Summary: "Create a deployment", Request: deployments.CreateDeploymentRequest{}, Response: deployments.Deployment{}},
+	"GET /api/deployments/nodes":                      {Summary: "List nodes available for scheduling"},
+	"GET /api/deployments/pending":                    {Summary: "List deployments waiting to be applied"},
+	"POST /api/deployments/batch-apply":               {Summary: "Apply several pending deployments in dependency order", Request: jsonObject},
+	"GET /api/deployments/approval-rules":             {Summary: "List approval rules"},
+	"POST /api/deployments/approval-rules":            {Summary: "Create or update an approval rule", Role: "admin", Request: deployments.ApprovalRule{}},
+	"DELETE /api/deployments/approval-rules/{id}":     {Summary: "Delete an approval rule", Role: "admin"},
+	"GET /api/deployments/restart-schedules":          {Summary: "List restart schedules and whether they're paused", Response: deployments.RestartSchedules{}},
+	"POST /api/deployments/restart-schedules/pause":   {Summary: "Pause all scheduled restarts", Role: "operator"},
+	"POST /api/deployments/restart-schedules/resume":  {Summary: "Resume scheduled restarts", Role: "operator"},
+	"PUT /api/deployments/{id}/restart-schedule":      {Summary: "Set a deployment's restart schedule", Request: restartScheduleRequest{}, Response: deployments.RestartSchedule{}, Role: "operator"},
+	"DELETE /api/deployments/{id}/restart-schedule":   {Summary: "Delete a deployment's restart schedule", Role: "operator"},
+
+	// Database management
+	"GET /api/databases/connections":                                                  {Summary: "List database connections"},
+	"POST /api/databases/connections":                                                 {Summary: "Create a database connection", Request: databases.DatabaseConfig{}},
+	"GET /api/databases/connections/{id}":                                             {Summary: "Get a database connection"},
+	"PUT /api/databases/connections/{id}":                                             {Summary: "Update a database connection", Request: databases.DatabaseConfig{}},
+	"DELETE /api/databases/connections/{id}":                                          {Summary: "Delete a database connection"},
+	"POST /api/databases/connections/{id}/connect":                                    {Summary: "Connect to a database"},
+	"POST /api/databases/connections/{id}/disconnect":                                 {Summary: "Disconnect from a database"},
+	"POST /api/databases/connections/test":                                            {Summary: "Test database connection settings", Request: databases.DatabaseConfig{}},
+	"GET /api/databases/connections/{id}/databases":                                   {Summary: "List databases"},
+	"GET /api/databases/connections/{id}/databases/{database}/tables":                 {Summary: "List tables"},
+	"GET /api/databases/connections/{id}/databases/{database}/tables/{table}/columns": {Summary: "List a table's columns"},
+	"GET /api/databases/connections/{id}/databases/{database}/tables/{table}/schema":  {Summary: "Get a table's indexes, foreign keys and constraints", Response: databases.TableSchema{}, Envelope: true},
+	"GET /api/databases/connections/{id}/databases/{database}/relationships":          {Summary: "Get tables and foreign keys for an ER diagram", Response: databases.SchemaDiagram{}, Envelope: true},
+	"POST /api/databases/connections/{id}/query":                                      {Summary: "Run a SQL query", Request: databases.QueryRequest{}},
+	"POST /api/databases/connections/{id}/explain":                                    {Summary: "Get a statement's execution plan", Request: databases.ExplainRequest{}, Response: databases.QueryPlan{}, Envelope: true},
+	"GET /api/databases/connections/{id}/slow-queries":                                {Summary: "List the slowest statements from pg_stat_statements or performance_schema", Response: []databases.SlowQuery{}, Envelope: true, Query: []openapi.Param{{Name: "limit", Type: "integer", Description: "Defaults to 20"}}},
+	"GET /api/databases/connections/{id}/databases/{database}/keys": {Summary: "Scan a key-value database's keys with their type and TTL", Response: databases.KeyScanResult{}, Envelope: true, Query: []openapi.Param{
+		{Name: "pattern", Type: "string", Description: "Glob pattern, e.g. session:*"},
+		{Name: "type", Type: "string", Description: "string, hash, list, set or zset"},
+		{Name: "cursor", Type: "integer", Description: "Cursor returned by the previous page; 0 starts a scan"},
+		{Name: "count", Type: "integer", Description: "Keys examined per page, up to 1000 (default 100)"},
+	}},
+	"GET /api/databases/connections/{id}/databases/{database}/keys/value": {Summary: "Get a key's value", Response: databases.KeyValue{}, Envelope: true, Query: []openapi.Param{{Name: "key", Type: "string", Description: "Key to read"}}},
+	"DELETE /api/databases/connections/{id}/databases/{database}/keys":    {Summary: "Delete keys", Role: "admin", Request: databases.KeyDeleteRequest{}},
+	"POST /api/databases/connections/{id}/databases/{database}/flush":     {Summary: "Delete every key of a database (FLUSHDB)", Role: "admin"},
+	"POST /api/databases/connections/{id}/tables/{table}/data":            {Summary: "Browse a table's rows", Request: databases.TableDataRequest{}},
+	"PUT /api/databases/connections/{id}/tables/{table}/rows":             {Summary: "Update a row", Request: databases.RowUpdateRequest{}},
+	"DELETE /api/databases/connections/{id}/tables/{table}/rows":          {Summary: "Delete a row", Request: databases.RowDeleteRequest{}},
+	"POST /api/databases/connections/{id}/tables/{table}/rows":            {Summary: "Insert a row", Request: databases.RowInsertRequest{}},
+	"GET /api/databases/connections/{id}/stats":                           {Summary: "Get database statistics with connection pool usage and health", Response: databases.DatabaseStats{}, Envelope: true},
+	"GET /api/databases/types":                                            {Summary: "List supported database types"},
+	"GET /api/databases/saved-queries":                                    {Summary: "List saved queries"},
+	"POST /api/databases/saved-queries":                                   {Summary: "Save a query", Request: databases.SavedQuery{}},
+	"PUT /api/databases/saved-queries/{id}":                               {Summary: "Update a saved query", Request: databases.SavedQuery{}},
+	"DELETE /api/databases/saved-queries/{id}":                            {Summary: "Delete a saved query"},
+	"GET /api/databases/jobs":                                             {Summary: "List scheduled query jobs with their last run", Response: []databases.QueryJob{}, Envelope: true},
+	"POST /api/databases/jobs":                                            {Summary: "Schedule a saved query on a cron", Request: databases.QueryJob{}, Response: databases.QueryJob{}, Envelope: true},
+	"PUT /api/databases/jobs/{id}":                                        {Summary: "Update a scheduled query job", Request: databases.QueryJob{}, Response: databases.QueryJob{}, Envelope: true},
+	"DELETE /api/databases/jobs/{id}":                                     {Summary: "Delete a scheduled query job and its run history"},
+	"POST /api/databases/jobs/{id}/run":                                   {Summary: "Run a scheduled query job now", Response: databases.QueryJobRun{}, Envelope: true},
+	"GET /api/databases/jobs/{id}/runs":                                   {Summary: "List a query job's runs with row counts and durations", Response: []databases.QueryJobRun{}, Envelope: true, Query: []openapi.Param{{Name: "limit", Type: "integer", Description: "Defaults to 20"}}},
+
+	// Certificates
+	"GET /api/certificates":                     {Summary: "List certificates"},
+	"GET /api/certificates/stats":               {Summary: "Get certificate statistics"},
+	"GET /api/certificates/alerts":              {Summary: "List certificate alerts"},
+	"POST /api/certificates/alerts/acknowledge": {Summary: "Acknowledge a certificate alert", Query: []openapi.Param{{Name: "alert_id"}}},
+	"GET /api/certificates/history":             {Summary: "Get certificate check history", Query: []openapi.Param{{Name: "domain"}, {Name: "since"}}},
+	"GET /api/certificates/check":               {Summary: "Check a domain's certificate", Query: []openapi.Param{{Name: "domain"}, {Name: "port", Type: "integer"}}},
+	"POST /api/certificates/refresh":            {Summary: "Recheck all certificates"},
+	"GET /api/certificates/domains":             {Summary: "List monitored domains"},
+	"POST /api/certificates/domains":            {Summary: "Add a monitored domain", Request: certificates.DomainConfig{}},
+	"DELETE /api/certificates/domains":          {Summary: "Remove a monitored domain", Query: []openapi.Param{{Name: "domain"}}},
+
+	// Backup and recovery
+	"GET /api/backup/jobs":                                  {Summary: "List backup jobs"},
+	"POST /api/backup/jobs":                                 {Summary: "Create a backup job", Role: "operator", Request: backup.Job{}},
+	"GET /api/backup/history":                               {Summary: "List backups", Query: []openapi.Param{{Name: "jobId"}}},
+	"GET /api/backup/storage":                               {Summary: "Get backup storage usage"},
+	"POST /api/backup/storage":                              {Summary: "Add a backup storage location", Role: "admin", Request: backup.StorageConfig{}},
+	"GET /api/backup/storage/configs":                       {Summary: "List backup storage locations"},
+	"DELETE /api/backup/storage/{id}":                       {Summary: "Remove a backup storage location", Role: "admin"},
+	"GET /api/backup/statistics":                            {Summary: "Get backup statistics"},
+	"GET /api/backup/recoveries/active":                     {Summary: "List running recoveries"},
+	"GET /api/backup/alerts":                                {Summary: "List backup alerts"},
+	"GET /api/backup/snapshots/classes":                     {Summary: "List volume snapshot classes"},
+	"GET /api/backup/snapshots":                             {Summary: "List volume snapshots", Query: []openapi.Param{{Name: "namespace"}}},
+	"POST /api/backup/snapshots":                            {Summary: "Create a volume snapshot", Role: "operator", Request: backup.SnapshotRequest{}},
+	"DELETE /api/backup/snapshots/{namespace}/{name}":       {Summary: "Delete a volume snapshot", Role: "operator"},
+	"POST /api/backup/snapshots/{namespace}/{name}/restore": {Summary: "Restore a volume snapshot to a new claim", Role: "operator", Request: backup.SnapshotRestoreRequest{}},
+
+	// GitOps
+	"GET /api/gitops/repositories":                         {Summary: "List repositories"},
+	"POST /api/gitops/repositories":                        {Summary: "Add a repository", Request: jsonObject},
+	"POST /api/gitops/repositories/init":                   {Summary: "Initialize the base infrastructure repository"},
+	"GET /api/gitops/applications":                         {Summary: "List applications"},
+	"POST /api/gitops/applications":                        {Summary: "Create an application", Request: jsonObject},
+	"POST /api/gitops/manifests/generate":                  {Summary: "Generate a manifest", Request: jsonObject},
+	"POST /api/gitops/manifests/validate":                  {Summary: "Validate a manifest", Request: jsonObject},
+	"POST /api/gitops/manifests/lint":                      {Summary: "Check a manifest against the manifest policy", Request: jsonObject, Response: gitops.LintResult{}, Envelope: true},
+	"POST /api/gitops/manifests/kustomize":                 {Summary: "Generate a kustomization", Request: jsonObject},
+	"POST /api/gitops/manifests/render":                    {Summary: "Render a kustomization", Request: jsonObject},
+	"GET /api/gitops/applications/{id}/environments":       {Summary: "List an application's environments"},
+	"PUT /api/gitops/applications/{id}/environments/{env}": {Summary: "Set an application's environment overlay", Role: "operator", Request: gitops.Overlay{}},
+	"POST /api/gitops/applications/{id}/promote":           {Summary: "Promote an application between environments", Role: "operator", Request: jsonObject},
+	"DELETE /api/gitops/applications/{id}":                 {Summary: "Delete an application", Role: "operator", Query: []openapi.Param{{Name: "prune", Type: "boolean"}}},
+	"GET /api/gitops/applications/archived":                {Summary: "List archived applications"},
+	"GET /api/gitops/applications/{id}/drift":              {Summary: "Get an application's drift from Git", Query: []openapi.Param{{Name: "environment"}}},
+	"GET /api/gitops/manifests/types":                      {Summary: "List supported manifest types"},
+	"GET /api/gitops/sync/status":                          {Summary: "Get sync status"},
+	"POST /api/gitops/sync/start":                          {Summary: "Start a sync", Request: jsonObject},
+	"POST /api/gitops/sync/force":                          {Summary: "Force a sync", Request: jsonObject},
+	"GET /api/gitops/sync/runs":                            {Summary: "List sync runs", Query: []openapi.Param{{Name: "limit", Type: "integer"}, {Name: "repository"}, {Name: "status"}}},
+	"POST /api/gitops/webhook":                             {Summary: "Receive a Git push webhook", Public: true},
+	"GET /api/gitops/webhook/config":                       {Summary: "Get the webhook configuration"},
+	"GET /api/gitops/repositories/{id}/credentials":        {Summary: "Get a repository's credential"},
+	"PUT /api/gitops/repositories/{id}/credentials":        {Summary: "Set a repository's credential", Role: "admin", Request: gitops.CredentialInput{}},
+	"DELETE /api/gitops/repositories/{id}/credentials":     {Summary: "Delete a repository's credential", Role: "admin"},
+
+	// Secrets
+	"GET /api/secrets":          {Summary: "Get secrets"},
+	"PUT /api/secrets":          {Summary: "Replace all secrets", Request: jsonObject},
+	"GET /api/secrets/template": {Summary: "Get the secrets template"},
+	"POST /api/secrets/apply":   {Summary: "Apply secrets to the cluster", Request: jsonObject},
+	"GET /api/secrets/status":   {Summary: "Get secret sync status", Query: []openapi.Param{{Name: "namespace"}}},
+
+	// Gitea
+	"GET /api/gitea/repositories/{owner}/{repo}/actions/runs/{run}/jobs":             {Summary: "List a workflow run's jobs"},
+	"GET /api/gitea/repositories/{owner}/{repo}/actions/jobs/{job}/logs":             {Summary: "Get a workflow job's logs", Query: []openapi.Param{{Name: "follow", Type: "boolean"}}},
+	"POST /api/gitea/repositories/{owner}/{repo}/actions/runs/{run}/rerun":           {Summary: "Rerun a workflow run", Role: "operator"},
+	"POST /api/gitea/repositories/{owner}/{repo}/actions/runs/{run}/cancel":          {Summary: "Cancel a workflow run", Role: "operator"},
+	"POST /api/gitea/repositories/{owner}/{repo}/actions/workflows/{workflow}/build": {Summary: "Build a deployment's image from a ref and stage it for apply", Role: "operator", Request: gitea.BuildRequest{}, Response: gitea.Build{}},
+	"GET /api/gitea/builds/{id}":                                         {Summary: "Get a build's status", Response: gitea.Build{}},
+	"POST /api/gitea/repositories/{owner}/{repo}/pulls/{index}/comments": {Summary: "Comment on a pull request", Role: "operator", Request: jsonObject},
+	"POST /api/gitea/repositories/{owner}/{repo}/pulls/{index}/reviews":  {Summary: "Review a pull request", Role: "operator", Request: jsonObject},
+	"POST /api/gitea/repositories/{owner}/{repo}/pulls/{index}/merge":    {Summary: "Merge a pull request", Role: "operator", Request: jsonObject},
+	"POST /api/gitea/repositories/provision":                             {Summary: "Provision a repository", Role: "operator", Request: jsonObject},
+	"POST /api/gitea/webhook":                                            {Summary: "Receive a Gitea webhook", Public: true},
+
+	// WebSocket
+	"GET /api/ws/snapshot/{channel}": {Summary: "Get a WebSocket channel's current state", Query: []openapi.Param{{Name: "namespace"}}, Response: websocket.Snapshot{}, Envelope: true},
+
+	// API description
+	"GET /api/openapi.json": {Summary: "Get this OpenAPI document", Public: true},
+}
+
+// subtreeRoutes annotates the operations that method-less subtree handlers in RegisterRoutes
+// dispatch by path suffix, keyed by the subtree pattern
+var subtreeRoutes = map[string]map[string]openapi.Route{
+	"/api/infrastructure/alerts/": {
+		"POST /api/infrastructure/alerts/{id}/acknowledge": {Summary: "Acknowledge an infrastructure alert", Envelope: true},
+	},
+	"/api/deployments/registries/": {
+		"GET /api/deployments/registries/{id}/pull-secrets":    {Summary: "List a registry's pull secrets and the service accounts using them", Response: []deployments.PullSecret{}},
+		"PUT /api/deployments/registries/{id}/pull-secrets":    {Summary: "Create or update a registry's pull secret in a namespace and attach it to service accounts", Role: "operator", Request: pullSecretRequest{}, Response: deployments.PullSecret{}},
+		"DELETE /api/deployments/registries/{id}/pull-secrets": {Summary: "Detach and delete a registry's pull secret", Role: "operator", Query: []openapi.Param{{Name: "namespace", Required: true}}},
+		"POST /api/deployments/registries/{id}/test":           {Summary: "Test a registry connection"},
+		"DELETE /api/deployments/registries/{id}":              {Summary: "Delete a registry"},
+	},
+	"/api/deployments/images/": {
+		"GET /api/deployments/images/{registry}/{repository}/tags": {Summary: "List an image's tags", Description: "The repository may span several path segments."},
+	},
+	"/api/deployments/": {
+		"GET /api/deployments/{id}":                  {Summary: "Get a deployment", Response: deployments.Deployment{}},
+		"PUT /api/deployments/{id}":                  {Summary: "Update a deployment", Request: deployments.UpdateDeploymentRequest{}, Response: deployments.Deployment{}},
+		"DELETE /api/deployments/{id}":               {Summary: "Delete a deployment"},
+		"POST /api/deployments/{id}/apply":           {Summary: "Apply a pending deployment", Request: apiclient.ApplyDeploymentRequest{}, Response: apiclient.ApplyResult{}},
+		"GET /api/deployments/{id}/approvals":        {Summary: "List a deployment's approvals"},
+		"GET /api/deployments/{id}/dependencies":     {Summary: "List the deployments a deployment is applied after", Response: deployments.Dependencies{}},
+		"PUT /api/deployments/{id}/dependencies":     {Summary: "Set the deployments a deployment is applied after", Request: deployments.Dependencies{}, Response: deployments.Dependencies{}},
+		"GET /api/deployments/{id}/plan":             {Summary: "Preview what applying a pending deployment changes", Response: deployments.DeploymentPlan{}},
+		"POST /api/deployments/{id}/approve":         {Summary: "Approve a deployment"},
+		"POST /api/deployments/{id}/reject":          {Summary: "Reject a deployment"},
+		"GET /api/deployments/{id}/manifest":         {Summary: "Get a deployment's manifest"},
+		"PATCH /api/deployments/{id}/scale":          {Summary: "Scale a deployment", Request: deployments.ScaleDeploymentRequest{}},
+		"POST /api/deployments/{id}/restart":         {Summary: "Restart a deployment"},
+		"GET /api/deployments/{id}/restart-schedule": {Summary: "Get a deployment's restart schedule", Response: deployments.RestartSchedule{}},
+		"GET /api/deployments/{id}/pods":             {Summary: "List a deployment's pods"},
+		"GET /api/deployments/{id}/history":          {Summary: "Get a deployment's history"},
+		"GET /api/deployments/{id}/provenance":       {Summary: "Trace a deployment's or GitOps deployment record's image to its commit, pull request and CI run", Response: gitea.Provenance{}},
+		"GET /api/deployments/{id}/compare":          {Summary: "Compare a deployment or history entry with another", Query: []openapi.Param{{Name: "with"}, {Name: "history"}, {Name: "with_history"}}, Response: deployments.DeploymentComparison{}},
+		"GET /api/deployments/{id}/revisions":        {Summary: "List a deployment's revisions"},
+		"GET /api/deployments/{id}/revisions/diff":   {Summary: "Compare two revisions", Query: []openapi.Param{{Name: "from", Type: "integer", Required: true}, {Name: "to", Type: "integer", Required: true}}},
+		"POST /api/deployments/{id}/rollback":        {Summary: "Roll back to a revision", Request: deployments.RollbackRequest{}},
+		"GET /api/deployments/{id}/rollout":          {Summary: "Get a progressive rollout's state"},
+		"GET /api/deployments/{id}/health-gate":      {Summary: "Get a rollout's health gate"},
+		"POST /api/deployments/{id}/promote":         {Summary: "Promote a progressive rollout"},
+		"POST /api/deployments/{id}/abort":           {Summary: "Abort a progressive rollout"},
+	},
+	"/api/backup/jobs/": {
+		"GET /api/backup/jobs/{id}":           {Summary: "Get a backup job"},
+		"PUT /api/backup/jobs/{id}":           {Summary: "Update a backup job", Role: "operator", Request: backup.Job{}},
+		"DELETE /api/backup/jobs/{id}":        {Summary: "Delete a backup job"},
+		"POST /api/backup/jobs/{id}/run":      {Summary: "Run a backup job now", Envelope: true},
+		"POST /api/backup/jobs/{id}/cancel":   {Summary: "Cancel a running backup job"},
+		"PUT /api/backup/jobs/{id}/schedule":  {Summary: "Update a backup job's schedule", Request: backup.Schedule{}},
+		"GET /api/backup/jobs/{id}/retention": {Summary: "Dry-run a backup job's retention policy: the backups pruning would keep and delete", Response: backup.RetentionPlan{}, Envelope: true},
+		"POST /api/backup/jobs/{id}/prune":    {Summary: "Prune a backup job's backups beyond its retention policy now", Response: backup.PruneRun{}, Envelope: true},
+	},
+	"/api/backup/history/": {
+		"DELETE /api/backup/history/{id}":       {Summary: "Delete a backup"},
+		"POST /api/backup/history/{id}/verify":  {Summary: "Verify a backup; encrypted backups need their passphrase or the configured key", Request: backup.VerifyRequest{}},
+		"POST /api/backup/history/{id}/recover": {Summary: "Recover from a backup", Request: backup.RecoveryOptions{}},
+		"POST /api/backup/history/{id}/preview": {Summary: "List the databases, tables or files a backup would restore, with the point-in-time window of WAL-archiving PostgreSQL jobs", Request: backup.VerifyRequest{}, Response: backup.RestorePreview{}, Envelope: true},
+	},
+	"/api/secrets/": {
+		"PUT /api/secrets/{key}": {Summary: "Set a secret", Request: jsonObject},
+	},
+	"/api/gitops/repositories/": {
+		"POST /api/gitops/repositories/{id}/sync": {Summary: "Sync a repository", Envelope: true},
+	},
+	"/api/gitops/applications/": {
+		"GET /api/gitops/applications/{id}":                  {Summary: "Get an application", Envelope: true},
+		"POST /api/gitops/applications/{id}/deploy":          {Summary: "Deploy an application", Request: jsonObject, Envelope: true},
+		"POST /api/gitops/applications/{id}/rollback":        {Summary: "Roll back an application", Request: jsonObject, Envelope: true},
+		"GET /api/gitops/applications/{id}/rollback-targets": {Summary: "List an application's rollback targets", Query: []openapi.Param{{Name: "limit", Type: "integer"}}, Envelope: true},
+		"GET /api/gitops/applications/{id}/history":          {Summary: "Get an application's deployment history", Envelope: true},
+	},
+	"/api/gitops/sync/application/": {
+		"POST /api/gitops/sync/application/{id}": {Summary: "Sync an application", Request: jsonObject, Envelope: true},
+	},
+}
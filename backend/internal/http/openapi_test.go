@@ -0,0 +1,118 @@
+package http
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// registeredPatterns reads the patterns RegisterRoutes passes to mux.Handle and mux.HandleFunc
+func registeredPatterns(t *testing.T) []string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "routes.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var patterns []string
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || (selector.Sel.Name != "Handle" && selector.Sel.Name != "HandleFunc") {
+			return true
+		}
+		if receiver, ok := selector.X.(*ast.Ident); !ok || receiver.Name != "mux" {
+			return true
+		}
+		literal, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || literal.Kind != token.STRING {
+			t.Fatalf("route pattern %v is not a string literal", call.Args[0])
+		}
+		pattern, _ := strconv.Unquote(literal.Value)
+		patterns = append(patterns, pattern)
+		return true
+	})
+	if len(patterns) == 0 {
+		t.Fatal("found no routes in routes.go")
+	}
+	return patterns
+}
+
+func TestAPIRoutesAreAnnotated(t *testing.T) {
+	patterns := registeredPatterns(t)
+	registered := make(map[string]bool)
+	for _, pattern := range patterns {
+		registered[pattern] = true
+
+		_, path, hasMethod := strings.Cut(pattern, " ")
+		switch {
+		case !hasMethod:
+			if len(subtreeRoutes[pattern]) == 0 {
+				t.Errorf("subtree %s has no operations in subtreeRoutes", pattern)
+			}
+		case strings.HasPrefix(path, "/api/"):
+			if _, ok := apiRoutes[pattern]; !ok {
+				t.Errorf("route %s has no entry in apiRoutes", pattern)
+			}
+		}
+	}
+
+	for pattern := range apiRoutes {
+		if !registered[pattern] {
+			t.Errorf("apiRoutes documents %s, which isn't registered", pattern)
+		}
+	}
+	for subtree, operations := range subtreeRoutes {
+		if !registered[subtree] {
+			t.Errorf("subtreeRoutes documents %s, which isn't registered", subtree)
+		}
+		for operation := range operations {
+			if _, path, _ := strings.Cut(operation, " "); !strings.HasPrefix(path, subtree) {
+				t.Errorf("operation %s isn't under %s", operation, subtree)
+			}
+		}
+	}
+}
+
+func TestBuildOpenAPISpec(t *testing.T) {
+	spec, err := buildOpenAPISpec(registeredPatterns(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Summary     string `json:"summary"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc.Paths["/ws"]; ok {
+		t.Error("expected only /api routes to be documented")
+	}
+	if _, ok := doc.Paths["/api/deployments/{id}/rollback"]; !ok {
+		t.Error("expected subtree operations to be documented")
+	}
+
+	ids := make(map[string]string)
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			if op.Summary == "" {
+				t.Errorf("%s %s has no summary", method, path)
+			}
+			if other, ok := ids[op.OperationID]; ok {
+				t.Errorf("%s %s and %s share operationId %s", method, path, other, op.OperationID)
+			}
+			ids[op.OperationID] = method + " " + path
+		}
+	}
+}
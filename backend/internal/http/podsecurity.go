@@ -0,0 +1,24 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+// GetSecurityAudit handles GET /api/k8s/security/audit?namespace=, scoring every workload's
+// pod template against the Pod Security Standards with remediation for each finding
+func (h *KubernetesHandlers) GetSecurityAudit(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	report, err := h.k8sClient.AuditPodSecurity(listContext(r), r.URL.Query().Get("namespace"))
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to audit pod security: "+err.Error())
+		return
+	}
+
+	response.SendSuccess(w, report)
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/internal/database"
+	"github.com/archellir/denshimon/internal/gitops"
+	"github.com/archellir/denshimon/internal/opa"
+	"github.com/archellir/denshimon/pkg/response"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// PolicyHandlers serves the log of Rego policy decisions
+type PolicyHandlers struct {
+	store *opa.Store
+}
+
+func NewPolicyHandlers(store *opa.Store) *PolicyHandlers {
+	return &PolicyHandlers{store: store}
+}
+
+// ListPolicyDecisions handles GET /api/policy/decisions?source=&user=&allowed=&since=7d&limit=,
+// newest first
+func (h *PolicyHandlers) ListPolicyDecisions(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := opa.Query{
+		Source: params.Get("source"),
+		User:   params.Get("user"),
+	}
+	if value := params.Get("allowed"); value != "" {
+		allowed, err := strconv.ParseBool(value)
+		if err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid allowed: "+value)
+			return
+		}
+		query.Allowed = &allowed
+	}
+	if value := params.Get("since"); value != "" {
+		since, err := parseTimeRange(value)
+		if err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid since: "+value)
+			return
+		}
+		query.Since = time.Now().Add(-since)
+	}
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			response.SendError(w, http.StatusBadRequest, "Invalid limit: "+value)
+			return
+		}
+		query.Limit = limit
+	}
+
+	decisions, err := h.store.List(r.Context(), query)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to list policy decisions: "+err.Error())
+		return
+	}
+	response.SendSuccess(w, decisions)
+}
+
+// regoPolicies adds Rego policies to the manifest policy of the GitOps flow
+type regoPolicies struct {
+	engine *opa.Engine
+}
+
+func (p regoPolicies) EvaluateManifest(ctx context.Context, objects []*unstructured.Unstructured, commit bool) []gitops.Violation {
+	source := opa.SourceLint
+	if commit {
+		source = opa.SourceGitOps
+	}
+	return regoViolations(p.engine.Evaluate(ctx, opa.Request{Source: source, User: contextUser(ctx), Objects: objects}))
+}
+
+// regoViolations turns policy decisions into manifest policy violations
+func regoViolations(decisions []opa.Decision) []gitops.Violation {
+	var violations []gitops.Violation
+	for _, d := range decisions {
+		add := func(severity, message string) {
+			violations = append(violations, gitops.Violation{Rule: gitops.RuleRego, Severity: severity, Kind: d.Kind, Name: d.Name, Message: message})
+		}
+		if d.Error != "" {
+			add(gitops.SeverityBlock, "policy evaluation failed: "+d.Error)
+		}
+		for _, message := range d.Denies {
+			add(gitops.SeverityBlock, message)
+		}
+		for _, message := range d.Warnings {
+			add(gitops.SeverityWarn, message)
+		}
+	}
+	return violations
+}
+
+// contextUser is the authenticated username, or empty for background work
+func contextUser(ctx context.Context) string {
+	if claims := auth.GetUserFromContext(ctx); claims != nil {
+		return claims.Username
+	}
+	return ""
+}
+
+// initPolicies opens the decision log and, when OPA is configured, the Rego policy engine.
+// Either may be nil.
+func initPolicies(db *database.SQLiteDB, repoPath string) (*opa.Engine, *PolicyHandlers) {
+	store, err := opa.NewStore(db.DB)
+	if err != nil {
+		slog.Warn("Policy decision log disabled", "error", err)
+		store = nil
+	}
+	engine := opa.NewEngineFromEnv(repoPath, store)
+	if store == nil {
+		return engine, nil
+	}
+	return engine, NewPolicyHandlers(store)
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/internal/preferences"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+// PreferencesHandlers serves the UI preferences of the signed-in user
+type PreferencesHandlers struct {
+	store *preferences.Store
+}
+
+func NewPreferencesHandlers(store *preferences.Store) *PreferencesHandlers {
+	return &PreferencesHandlers{store: store}
+}
+
+// GetPreferences handles GET /api/preferences; users who saved none get the defaults
+func (h *PreferencesHandlers) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		response.SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	prefs, err := h.store.Get(r.Context(), claims.UserID)
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to get preferences")
+		return
+	}
+	response.SendSuccess(w, prefs)
+}
+
+// UpdatePreferences handles PUT /api/preferences. Fields in the body replace the stored
+// ones; fields left out are kept, so each view can save its own settings.
+func (h *PreferencesHandlers) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		response.SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, preferences.MaxBytes))
+	if err != nil {
+		response.SendError(w, http.StatusRequestEntityTooLarge, "Preferences are too large")
+		return
+	}
+	prefs, err := h.store.Update(r.Context(), claims.UserID, body)
+	if errors.Is(err, preferences.ErrInvalid) {
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to save preferences")
+		return
+	}
+	response.SendSuccess(w, prefs)
+}
+
+// ResetPreferences handles DELETE /api/preferences, going back to the defaults
+func (h *PreferencesHandlers) ResetPreferences(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		response.SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if err := h.store.Reset(r.Context(), claims.UserID); err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to reset preferences")
+		return
+	}
+	response.SendSuccessWithMessage(w, "Preferences reset")
+}
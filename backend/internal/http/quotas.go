@@ -0,0 +1,29 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/archellir/denshimon/pkg/response"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// GetNamespaceQuotas handles GET /api/k8s/namespaces/{name}/quotas, returning ResourceQuotas with
+// used-vs-hard percentages, LimitRanges and warnings for resources near their limits
+func (h *KubernetesHandlers) GetNamespaceQuotas(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	quotas, err := h.k8sClient.GetNamespaceQuotas(listContext(r), r.PathValue("name"))
+	if apierrors.IsNotFound(err) {
+		response.SendError(w, http.StatusNotFound, "Namespace not found")
+		return
+	}
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to get quotas: "+err.Error())
+		return
+	}
+
+	response.SendSuccess(w, quotas)
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/internal/database"
+	"github.com/archellir/denshimon/internal/ratelimit"
+)
+
+// rateLimits holds the per-IP request and login limiters and the login lockout
+type rateLimits struct {
+	ip      *ratelimit.Limiter
+	login   *ratelimit.Limiter
+	lockout *ratelimit.Lockout
+}
+
+// initRateLimits creates the limiters from RATE_LIMIT_* settings, enables the per-user
+// limit on the auth service and expires idle state with the database cleanup worker.
+// It returns nil when rate limiting is disabled.
+func initRateLimits(db *database.SQLiteDB, authService *auth.Service) *rateLimits {
+	config := ratelimit.LoadConfig()
+	if !config.Enabled {
+		slog.Warn("Rate limiting disabled")
+		return nil
+	}
+
+	limits := &rateLimits{
+		ip:      ratelimit.NewLimiter(config.IPRate, config.IPBurst),
+		login:   ratelimit.NewLimiter(config.LoginRate, config.LoginBurst),
+		lockout: ratelimit.NewLockout(config.LockoutThreshold, config.LockoutBase, config.LockoutMax),
+	}
+	userLimiter := ratelimit.NewLimiter(config.UserRate, config.UserBurst)
+	authService.SetUserRateLimit(userLimiter)
+
+	db.OnCleanup(func() {
+		limits.ip.Cleanup()
+		limits.login.Cleanup()
+		limits.lockout.Cleanup()
+		userLimiter.Cleanup()
+	})
+	return limits
+}
+
+// limitIP applies the per-IP limit
+func (l *rateLimits) limitIP(next http.HandlerFunc) http.HandlerFunc {
+	return limitBy(l.ip, next)
+}
+
+// limitLogin applies the stricter per-IP limit on login attempts
+func (l *rateLimits) limitLogin(next http.HandlerFunc) http.HandlerFunc {
+	return limitBy(l.login, next)
+}
+
+func limitBy(limiter *ratelimit.Limiter, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := limiter.Allow(clientIP(r)); !ok {
+			sendTooManyRequests(w, wait)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// sendTooManyRequests responds 429 with Retry-After in whole seconds
+func sendTooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "Too many requests", http.StatusTooManyRequests)
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/pkg/response"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// accessCheckFromQuery reads verb, resource, group, subresource, namespace, name, user and
+// comma-separated groups. Unlike other endpoints, an absent namespace means all namespaces.
+func accessCheckFromQuery(r *http.Request) k8s.AccessCheck {
+	query := r.URL.Query()
+	check := k8s.AccessCheck{
+		User:        query.Get("user"),
+		Verb:        query.Get("verb"),
+		Group:       query.Get("group"),
+		Resource:    query.Get("resource"),
+		Subresource: query.Get("subresource"),
+		Namespace:   query.Get("namespace"),
+		Name:        query.Get("name"),
+	}
+	for _, group := range strings.Split(query.Get("groups"), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			check.Groups = append(check.Groups, group)
+		}
+	}
+	return check
+}
+
+// CanI handles GET /api/k8s/rbac/can-i?verb=get&resource=pods&namespace=prod&user=, asking the
+// API server through a SubjectAccessReview. Without user it checks Denshimon's own access.
+func (h *KubernetesHandlers) CanI(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	result, err := h.k8sClient.CanI(r.Context(), accessCheckFromQuery(r))
+	if err != nil {
+		sendRBACError(w, err)
+		return
+	}
+
+	response.SendSuccess(w, result)
+}
+
+// WhoCan handles GET /api/k8s/rbac/who-can?verb=delete&resource=pods&namespace=prod, listing
+// the subjects whose role bindings grant the access
+func (h *KubernetesHandlers) WhoCan(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	grants, err := h.k8sClient.WhoCan(r.Context(), accessCheckFromQuery(r))
+	if err != nil {
+		sendRBACError(w, err)
+		return
+	}
+
+	response.SendSuccess(w, grants)
+}
+
+// ListRoles handles GET /api/k8s/rbac/roles?namespace=
+func (h *KubernetesHandlers) ListRoles(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	roles, err := h.k8sClient.ListRoles(r.Context(), r.URL.Query().Get("namespace"))
+	if err != nil {
+		sendRBACError(w, err)
+		return
+	}
+
+	response.SendSuccess(w, roles)
+}
+
+// ListRoleBindings handles GET /api/k8s/rbac/bindings?namespace=&subject=
+func (h *KubernetesHandlers) ListRoleBindings(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	query := r.URL.Query()
+	bindings, err := h.k8sClient.ListBindings(r.Context(), query.Get("namespace"), query.Get("subject"))
+	if err != nil {
+		sendRBACError(w, err)
+		return
+	}
+
+	response.SendSuccess(w, bindings)
+}
+
+func sendRBACError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, k8s.ErrInvalidAccessCheck), apierrors.IsBadRequest(err):
+		response.SendError(w, http.StatusBadRequest, err.Error())
+	case apierrors.IsForbidden(err):
+		response.SendError(w, http.StatusForbidden, err.Error())
+	default:
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,230 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/internal/database"
+	"github.com/archellir/denshimon/internal/deployments"
+	"github.com/archellir/denshimon/internal/metrics"
+)
+
+// ApplyRecommendationRequest selects the container whose recommendation should be applied
+type ApplyRecommendationRequest struct {
+	Namespace  string   `json:"namespace"`
+	Workload   string   `json:"workload"`
+	Container  string   `json:"container"`
+	Percentile *float64 `json:"percentile,omitempty"`
+	Headroom   *float64 `json:"headroom,omitempty"`
+}
+
+// SetDeploymentService enables applying recommendations through the pending-apply GitOps flow
+func (h *MetricsHandlers) SetDeploymentService(service *deployments.Service) {
+	h.deploymentService = service
+}
+
+// GET /api/metrics/recommendations?namespace=&window=7d&percentile=95&headroom=0.15
+func (h *MetricsHandlers) GetRecommendations(w http.ResponseWriter, r *http.Request) {
+	if h.metricsService == nil {
+		sendMetricsError(w, http.StatusServiceUnavailable, "Metrics service not available")
+		return
+	}
+
+	opts, err := recommendationOptions(r)
+	if err != nil {
+		sendMetricsError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	recommendations, err := h.metricsService.GetRecommendations(r.Context(), opts)
+	if errors.Is(err, metrics.ErrNoUsageHistory) {
+		sendMetricsError(w, http.StatusServiceUnavailable, "Usage history not available")
+		return
+	}
+	if err != nil {
+		sendMetricsError(w, http.StatusInternalServerError, "Failed to compute recommendations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"recommendations": recommendations,
+		"percentile":      opts.Percentile,
+		"headroom":        opts.Headroom,
+		"window":          opts.Window.String(),
+	})
+}
+
+// POST /api/metrics/recommendations/apply
+// Stages the recommended resources on a managed deployment; the change is synced to
+// GitOps and waits in pending_apply until it is applied.
+func (h *MetricsHandlers) ApplyRecommendation(w http.ResponseWriter, r *http.Request) {
+	if h.metricsService == nil || h.deploymentService == nil {
+		sendMetricsError(w, http.StatusServiceUnavailable, "Deployment service not available")
+		return
+	}
+
+	var req ApplyRecommendationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendMetricsError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Namespace == "" || req.Workload == "" {
+		sendMetricsError(w, http.StatusBadRequest, "namespace and workload are required")
+		return
+	}
+	if req.Container == "" {
+		req.Container = req.Workload
+	}
+
+	opts, err := recommendationOptions(r)
+	if err != nil {
+		sendMetricsError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts.Namespace = req.Namespace
+	if req.Percentile != nil {
+		opts.Percentile = *req.Percentile
+	}
+	if req.Headroom != nil {
+		opts.Headroom = *req.Headroom
+	}
+
+	recommendations, err := h.metricsService.GetRecommendations(r.Context(), opts)
+	if err != nil {
+		sendMetricsError(w, http.StatusServiceUnavailable, "Failed to compute recommendations")
+		return
+	}
+	var recommendation *metrics.ContainerRecommendation
+	for i := range recommendations {
+		rec := &recommendations[i]
+		if rec.WorkloadKind == "Deployment" && rec.Workload == req.Workload && rec.Container == req.Container {
+			recommendation = rec
+			break
+		}
+	}
+	if recommendation == nil {
+		sendMetricsError(w, http.StatusNotFound, "No recommendation for this deployment container")
+		return
+	}
+
+	deployment, err := h.managedDeployment(r.Context(), req.Namespace, req.Workload)
+	if err != nil {
+		sendMetricsError(w, http.StatusInternalServerError, "Failed to look up deployment")
+		return
+	}
+	// Only deployments created through denshimon have a stored spec that GitOps tracks,
+	// and their single container is named after the deployment
+	if deployment == nil || req.Container != deployment.Name {
+		sendMetricsError(w, http.StatusConflict, "Recommendations can only be applied to the container of a deployment managed by denshimon")
+		return
+	}
+
+	user := ""
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		user = claims.Username
+	}
+	resources := deployments.ResourceRequirements{
+		Requests: deployments.ResourceList{CPU: recommendation.Recommended.CPURequest, Memory: recommendation.Recommended.MemoryRequest},
+		Limits:   deployments.ResourceList{CPU: recommendation.Recommended.CPULimit, Memory: recommendation.Recommended.MemoryLimit},
+	}
+	reason := fmt.Sprintf("P%g usage over %s with %g%% headroom", opts.Percentile, opts.Window, opts.Headroom*100)
+	updated, err := h.deploymentService.ProposeResources(r.Context(), deployment.ID, resources, user, reason)
+	if err != nil {
+		sendMetricsError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deployment":     updated,
+		"recommendation": recommendation,
+		"message":        "Recommendation staged for apply",
+	})
+}
+
+// managedDeployment finds the denshimon deployment with the given name, or nil
+func (h *MetricsHandlers) managedDeployment(ctx context.Context, namespace, name string) (*deployments.Deployment, error) {
+	list, err := h.deploymentService.ListDeployments(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].Name == name {
+			return &list[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// recommendationOptions reads recommendation parameters from the query string
+func recommendationOptions(r *http.Request) (metrics.RecommendationOptions, error) {
+	opts := metrics.DefaultRecommendationOptions()
+	query := r.URL.Query()
+	opts.Namespace = query.Get("namespace")
+	if value := query.Get("window"); value != "" {
+		window, err := parseTimeRange(value)
+		if err != nil {
+			return opts, err
+		}
+		opts.Window = window
+	}
+	if value := query.Get("percentile"); value != "" {
+		p, err := strconv.ParseFloat(value, 64)
+		if err != nil || p <= 0 || p > 100 {
+			return opts, fmt.Errorf("percentile must be between 0 and 100")
+		}
+		opts.Percentile = p
+	}
+	if value := query.Get("headroom"); value != "" {
+		headroom, err := strconv.ParseFloat(value, 64)
+		if err != nil || headroom < 0 || headroom > 10 {
+			return opts, fmt.Errorf("headroom must be a fraction between 0 and 10")
+		}
+		opts.Headroom = headroom
+	}
+	return opts, nil
+}
+
+func sendMetricsError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": message,
+	})
+}
+
+// initUsageSampling records container usage for recommendations unless USAGE_SAMPLING_ENABLED=false
+func initUsageSampling(db *database.SQLiteDB, metricsService *metrics.Service) {
+	if os.Getenv("USAGE_SAMPLING_ENABLED") == "false" {
+		return
+	}
+
+	store, err := metrics.NewUsageStore(db.DB)
+	if err != nil {
+		slog.Warn("Container usage sampling disabled", "error", err)
+		return
+	}
+	metricsService.SetUsageStore(store)
+
+	interval := metrics.DefaultUsageSampleInterval
+	if value := os.Getenv("USAGE_SAMPLE_INTERVAL"); value != "" {
+		if d, err := parseTimeRange(value); err == nil {
+			interval = d
+		}
+	}
+	retention := metrics.DefaultUsageRetention
+	if value := os.Getenv("USAGE_RETENTION"); value != "" {
+		if d, err := parseTimeRange(value); err == nil {
+			retention = d
+		}
+	}
+	metricsService.StartUsageSampling(context.Background(), interval, retention)
+}
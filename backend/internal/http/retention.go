@@ -0,0 +1,126 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/archellir/denshimon/internal/retention"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+type RetentionHandlers struct {
+	service *retention.Service
+}
+
+func NewRetentionHandlers(service *retention.Service) *RetentionHandlers {
+	return &RetentionHandlers{service: service}
+}
+
+// ListPolicies handles GET /api/deployments/retention/policies
+func (h *RetentionHandlers) ListPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.service.List(r.Context())
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to list retention policies: "+err.Error())
+		return
+	}
+	response.SendSuccess(w, policies)
+}
+
+// GetPolicy handles GET /api/deployments/retention/policies/{id}
+func (h *RetentionHandlers) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := h.service.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		sendRetentionError(w, err)
+		return
+	}
+	response.SendSuccess(w, policy)
+}
+
+// CreatePolicy handles POST /api/deployments/retention/policies
+func (h *RetentionHandlers) CreatePolicy(w http.ResponseWriter, r *http.Request) {
+	var policy retention.Policy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	created, err := h.service.Create(r.Context(), policy, requestUser(r))
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.SendSuccess(w, created)
+}
+
+// UpdatePolicy handles PUT /api/deployments/retention/policies/{id}
+func (h *RetentionHandlers) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	var policy retention.Policy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	updated, err := h.service.Update(r.Context(), r.PathValue("id"), policy)
+	if err != nil {
+		sendRetentionError(w, err)
+		return
+	}
+	response.SendSuccess(w, updated)
+}
+
+// DeletePolicy handles DELETE /api/deployments/retention/policies/{id}
+func (h *RetentionHandlers) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		sendRetentionError(w, err)
+		return
+	}
+	response.SendSuccess(w, map[string]string{"message": "Retention policy deleted", "id": id})
+}
+
+// PreviewPolicy handles GET /api/deployments/retention/policies/{id}/preview, a dry run
+// listing the tags the policy would delete right now
+func (h *RetentionHandlers) PreviewPolicy(w http.ResponseWriter, r *http.Request) {
+	plan, err := h.service.Preview(r.Context(), r.PathValue("id"))
+	if err != nil {
+		sendRetentionError(w, err)
+		return
+	}
+	response.SendSuccess(w, plan)
+}
+
+// RunPolicy handles POST /api/deployments/retention/policies/{id}/run, deleting the
+// selected tags immediately and recording the result in the audit trail
+func (h *RetentionHandlers) RunPolicy(w http.ResponseWriter, r *http.Request) {
+	run, err := h.service.Execute(r.Context(), r.PathValue("id"), requestUser(r))
+	if err != nil {
+		sendRetentionError(w, err)
+		return
+	}
+	response.SendSuccess(w, run)
+}
+
+func sendRetentionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, retention.ErrNotFound) {
+		response.SendError(w, http.StatusNotFound, "Retention policy not found")
+		return
+	}
+	response.SendError(w, http.StatusBadRequest, err.Error())
+}
+
+// retentionInterval returns IMAGE_RETENTION_INTERVAL (default 24h); zero disables
+// scheduled runs
+func retentionInterval() time.Duration {
+	value := os.Getenv("IMAGE_RETENTION_INTERVAL")
+	if value == "" {
+		return 24 * time.Hour
+	}
+	interval, err := parseTimeRange(value)
+	if err != nil && value != "0" {
+		slog.Warn("Invalid IMAGE_RETENTION_INTERVAL, scheduled retention disabled", "value", value, "error", err)
+		return 0
+	}
+	return interval
+}
@@ -4,51 +4,122 @@
 package http
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"strings"
 
+	"github.com/archellir/denshimon/internal/audit"
 	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/internal/bus"
+	"github.com/archellir/denshimon/internal/catalog"
 	"github.com/archellir/denshimon/internal/database"
 	"github.com/archellir/denshimon/internal/deployments"
+	"github.com/archellir/denshimon/internal/discovery"
+	"github.com/archellir/denshimon/internal/gitea"
+	"github.com/archellir/denshimon/internal/gitops"
 	"github.com/archellir/denshimon/internal/k8s"
 	"github.com/archellir/denshimon/internal/metrics"
+	"github.com/archellir/denshimon/internal/monitors"
+	"github.com/archellir/denshimon/internal/preferences"
+	"github.com/archellir/denshimon/internal/prometheus"
 	"github.com/archellir/denshimon/internal/providers"
 	"github.com/archellir/denshimon/internal/providers/backup"
 	"github.com/archellir/denshimon/internal/providers/certificates"
 	"github.com/archellir/denshimon/internal/providers/databases"
+	"github.com/archellir/denshimon/internal/recordings"
+	"github.com/archellir/denshimon/internal/retention"
+	"github.com/archellir/denshimon/internal/secretbox"
+	"github.com/archellir/denshimon/internal/secretrefs"
 	"github.com/archellir/denshimon/internal/secrets"
+	"github.com/archellir/denshimon/internal/timeline"
 	"github.com/archellir/denshimon/internal/websocket"
+	"github.com/archellir/denshimon/pkg/config"
 	"github.com/archellir/denshimon/pkg/logger"
 	"log/slog"
 )
 
 func RegisterRoutes(
-	mux *http.ServeMux,
+	serveMux *http.ServeMux,
 	authService *auth.Service,
 	k8sClient *k8s.Client,
 	db *database.SQLiteDB,
 	wsHub *websocket.Hub,
 ) {
+	// Patterns are recorded for the OpenAPI document
+	mux := newRouteRecorder(serveMux)
+
+	// Persist login sessions, API tokens and token signing keys, rotating keys on schedule
+	authStore := auth.NewDatabaseAdapter(db)
+	authService.SetSessionStore(authStore)
+	authService.SetAPITokenStore(authStore)
+	if err := authService.SetKeyStore(authStore); err != nil {
+		slog.Warn("Token signing keys not persisted - tokens will not survive restarts", "error", err)
+	}
+	authService.StartKeyRotation(context.Background(), config.Load().KeyRotationInterval)
+
+	// List results are shared between REST handlers, metrics and the WebSocket publisher
+	if k8sClient != nil {
+		k8sClient.SetCacheTTL(config.Load().K8sCacheTTL)
+	}
+
+	// Subsystems publish their events on the bus, consumed by notifications, the audit
+	// trail, the WebSocket hub and outbound webhooks
+	eventBus := bus.New()
+	publishEvents(eventBus, wsHub)
+
 	// Initialize services
 	metricsService := metrics.NewService(k8sClient)
+	initUsageSampling(db, metricsService) // usage history for resource recommendations
+	initCostModel(metricsService)
+	initNodeExporter(metricsService) // node system metrics without Prometheus
 
 	// Initialize provider registry and deployment service
 	providerRegistry := InitializeProviders()
 	registryManager := providers.NewRegistryManager(providerRegistry)
 	deploymentService := deployments.NewService(k8sClient, registryManager, db.DB)
+	box, err := secretbox.New(config.Load().EncryptionKey)
+	if err != nil {
+		slog.Warn("Stored credentials will not be encrypted", "error", err)
+	}
+	deploymentService.SetEncryption(box) // registry configs at rest
+	deploymentService.SetHub(wsHub) // canary/blue-green rollout progress
+	deploymentService.SetBus(eventBus)
 	deploymentHandlers := NewDeploymentHandlers(deploymentService, registryManager, providerRegistry)
+	go LoadRegistries(context.Background(), deploymentService, registryManager) // connection tests may be slow
+	if os.Getenv("RESTART_SCHEDULES_ENABLED") != "false" {
+		deploymentService.StartRestartScheduler(context.Background())
+	}
 
 	// Initialize database management
 	databaseManager := databases.NewManager(db.DB)
+	databaseManager.SetEncryption(box) // connection passwords at rest
+	databaseManager.SetHub(wsHub)
+	databaseManager.StartStatsPublisher(context.Background())
+	databaseManager.StartHealthChecks(context.Background())
+	databaseManager.StartJobScheduler(context.Background())
 	databaseHandlers := NewDatabasesHandler(databaseManager)
 
 	// Initialize certificate management
 	certificateManager := certificates.NewManager()
+	certificateManager.SetBus(eventBus)
+	if certificateStore, err := certificates.NewStore(db.DB); err == nil {
+		certificateManager.SetStore(certificateStore)
+	} else {
+		slog.Warn("Certificate check history disabled", "error", err)
+	}
+	if os.Getenv("CERT_CHECK_ENABLED") != "false" {
+		certificateManager.StartScheduler(context.Background())
+	}
 	certificateHandlers := NewCertificateHandlers(certificateManager)
+	deploymentService.SetCertificateManager(certificateManager) // TLS hosts of deployment ingresses
 
 	// Initialize backup management
-	backupManager := backup.NewManager(db.DB)
+	backupManager := backup.NewManager(db.DB, k8sClient)
+	backupManager.SetEncryption(box) // job passphrases at rest
+	backupManager.SetEncryptionKey(config.Load().BackupEncryptionKey)
+	backupManager.SetBus(eventBus)
+	backupManager.StartScheduler()
 	backupHandlers := NewBackupHandlers(backupManager)
 
 	// Initialize GitOps management
@@ -62,13 +133,58 @@ func RegisterRoutes(
 		localRepoPath = "/tmp/base_infrastructure" // default value
 	}
 	gitopsHandlers := NewGitOpsHandler(db.DB, baseInfraRepoURL, localRepoPath, gitopsLogger)
+	gitopsHandlers.service.SetBus(eventBus)
+	deploymentService.SetSecretResolver(secretrefs.NewResolverFromEnv(localRepoPath)) // vault: and sops: environment references
+	gitopsHandlers.SetWebhookSecret(os.Getenv("GITOPS_WEBHOOK_SECRET")) // fallback for repositories without their own secret
+	gitopsHandlers.service.SetEncryption(box) // repository credentials and webhook secrets at rest
+	credentialKey := os.Getenv("GITOPS_CREDENTIALS_KEY")
+	if credentialKey == "" {
+		credentialKey = config.Load().PasetoKey // fall back to the token signing key
+	}
+	// Credentials sealed before ENCRYPTION_KEY was set are still read with the credential key
+	gitopsHandlers.SetCredentialKey(credentialKey)
+	gitopsHandlers.SetK8sClient(k8sClient)
+	if policy, err := gitops.ParseLintPolicy(os.Getenv("MANIFEST_POLICY")); err == nil {
+		gitopsHandlers.SetLintPolicy(policy)
+		deploymentService.SetLintPolicy(policy)
+	} else {
+		slog.Warn("Invalid MANIFEST_POLICY, using the default manifest policy", "error", err)
+	}
+	policyEngine, policyHandlers := initPolicies(db, localRepoPath)
+	if policyEngine != nil {
+		gitopsHandlers.SetPolicyEvaluator(regoPolicies{engine: policyEngine})
+		deploymentService.SetPolicyEvaluator(regoPolicies{engine: policyEngine})
+	}
 
 	// Initialize secrets management
 	secretsService := secrets.NewSecretsService(localRepoPath, k8sClient.Clientset())
 	secretsHandlers := NewSecretsHandlers(secretsService)
 
+	// Initialize exec session recording
+	recordingPath := os.Getenv("EXEC_RECORDINGS_PATH")
+	if recordingPath == "" {
+		recordingPath = "/app/data/recordings" // default value
+	}
+	recordingStore, err := recordings.NewStore(db.DB, recordingPath, recordings.InputMode(os.Getenv("EXEC_RECORDING_INPUT")))
+	if err != nil {
+		slog.Warn("Exec session recording disabled", "error", err)
+		recordingStore = nil
+	}
+	execSessionHandlers := NewExecSessionHandlers(recordingStore)
+
+	// Initialize log ingestion
+	logStore := initLogIngestion(db, k8sClient)
+	eventStore := initEventRecording(db, k8sClient)
+	initExternalChanges(k8sClient, eventStore, wsHub, gitopsHandlers.service)
+
+	// Rate limits per IP and user, with lockout after repeated login failures
+	limits := initRateLimits(db, authService)
+
 	// CORS middleware for development
 	corsMiddleware := func(next http.HandlerFunc) http.HandlerFunc {
+		if limits != nil {
+			next = limits.limitIP(next)
+		}
 		return func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
@@ -85,20 +201,123 @@ func RegisterRoutes(
 
 	// Initialize handlers
 	authHandlers := NewAuthHandlers(authService, db)
-	k8sHandlers := NewKubernetesHandlers(k8sClient)
+	k8sHandlers := NewKubernetesHandlers(k8sClient, recordingStore, authService)
 	metricsHandlers := NewMetricsHandlers(metricsService)
+	metricsHandlers.SetDeploymentService(deploymentService)
+	initHostMetrics(metricsHandlers, wsHub) // the VPS itself, with or without Kubernetes
 	servicesHandlers := NewServicesHandlers(k8sClient)
-	observabilityHandlers := NewObservabilityHandlers(k8sClient)
+	tracesClient := initTracesClient()
+	servicesHandlers.SetTracesClient(tracesClient)
+	servicesHandlers.SetPrometheusService(prometheus.NewService(prometheusURL()))
+	tracesHandlers := NewTracesHandlers(tracesClient)
+	configHandlers := NewConfigHandlers(config.FileWatcher())
+	initConfigReload(config.FileWatcher(), servicesHandlers, tracesHandlers)
+	observabilityHandlers := NewObservabilityHandlers(k8sClient, logStore)
+	observabilityHandlers.SetEventStore(eventStore)
 	infrastructureHandlers := NewInfrastructureHandlers()
+	serviceDiscovery := discovery.NewService(k8sClient, discovery.NewStore(db.DB))
+	infrastructureHandlers.SetServiceDiscovery(serviceDiscovery)
+	initSyntheticChecks(serviceDiscovery)
+	nodeHandlers := NewNodeHandlers(k8sClient, wsHub)
+	alertManager := initAlertManager(db, wsHub, gitopsHandlers.service, infrastructureHandlers, certificateManager, backupManager, databaseManager, eventBus)
+	initStorageTrends(db, metricsService, k8sHandlers, infrastructureHandlers, wsHub, alertManager)
+	diskHealthHandlers := initDiskHealth(k8sClient, infrastructureHandlers, wsHub, alertManager)
+	upgradeHandlers := initUpgrades(k8sClient, gitopsHandlers.service, infrastructureHandlers, wsHub, alertManager)
+	incidentHandlers := initIncidents(db, alertManager)
+	webhookHandlers := initWebhooks(db, box, eventBus)
+	k8sHandlers.SetKubeconfigServer(os.Getenv("KUBECONFIG_SERVER"))
+	k8sHandlers.SetDebugImage(os.Getenv("DEBUG_POD_IMAGE"))
+	k8sHandlers.SetPolicyEngine(policyEngine)
+	if config, ok := nodeShellConfig(); ok {
+		k8sHandlers.SetNodeShell(config)
+	}
+	var monitorHandlers *MonitorHandlers
+	if monitorService, err := monitors.NewService(db.DB); err == nil {
+		monitorService.SetBus(eventBus)
+		bus.On(eventBus, monitorAlerts(infrastructureHandlers, wsHub, alertManager))
+		if os.Getenv("MONITORS_ENABLED") != "false" {
+			if err := monitorService.Start(context.Background()); err != nil {
+				slog.Warn("Failed to start uptime monitors", "error", err)
+			}
+		}
+		monitorHandlers = NewMonitorHandlers(monitorService)
+	} else {
+		slog.Warn("Uptime monitors disabled", "error", err)
+	}
+	preferencesHandlers := NewPreferencesHandlers(preferences.NewStore(db.DB))
+	var auditHandlers *AuditHandlers
+	auditStore, err := audit.NewStore(db.DB)
+	if err == nil {
+		auditEvents(eventBus, auditStore)
+		auditHandlers = NewAuditHandlers(auditStore)
+	} else {
+		slog.Warn("Audit trail disabled", "error", err)
+	}
+	var retentionHandlers *RetentionHandlers
+	if retentionService, err := retention.NewService(db.DB, registryManager); err == nil {
+		if auditStore != nil {
+			retentionService.SetAuditStore(auditStore)
+		}
+		if interval := retentionInterval(); interval > 0 {
+			retentionService.Start(context.Background(), interval)
+		}
+		retentionHandlers = NewRetentionHandlers(retentionService)
+	} else {
+		slog.Warn("Image retention policies disabled", "error", err)
+	}
+	sbomHandlers := initSBOMs(db, k8sClient)
+	var catalogHandlers *CatalogHandlers
+	if catalogService, err := catalog.NewService(db.DB, deploymentService); err == nil {
+		catalogHandlers = NewCatalogHandlers(catalogService)
+	} else {
+		slog.Warn("Deployment catalog disabled", "error", err)
+	}
+	giteaClient := gitea.NewClientFromEnv()
+	giteaHandler := gitea.NewHandler(giteaClient)
+	giteaHandler.SetGitOpsService(gitopsHandlers.service)
+	if giteaClient != nil {
+		gitopsHandlers.service.SetChangelogSource(giteaHandler) // release notes of deployed images
+	}
+	giteaHandler.SetPublicURL(os.Getenv("DENSHIMON_PUBLIC_URL"))
+	giteaHandler.SetDeploymentService(deploymentService) // build-from-repo stages images for apply
+	giteaHandler.SetImageLabels(deploymentHandlers.imageLabels)
+	timelineHandlers := NewTimelineHandlers(timeline.NewEngine(k8sClient, deploymentService, gitopsHandlers.service))
+	graphQLHandlers, err := NewGraphQLHandlers(k8sClient, metricsService, deploymentService, gitopsHandlers.service, infrastructureHandlers)
+	if err != nil {
+		slog.Warn("GraphQL API disabled", "error", err)
+	}
 
 	// Auth endpoints (no auth required)
-	mux.HandleFunc("POST /api/auth/login", corsMiddleware(authHandlers.Login))
+	loginHandler := authHandlers.Login
+	if limits != nil {
+		authHandlers.SetLoginLockout(limits.lockout)
+		loginHandler = limits.limitLogin(loginHandler)
+	}
+	mux.HandleFunc("POST /api/auth/login", corsMiddleware(loginHandler))
 	mux.HandleFunc("POST /api/auth/logout", corsMiddleware(authHandlers.Logout))
 
 	// Protected auth endpoints
 	mux.HandleFunc("POST /api/auth/refresh", corsMiddleware(authService.AuthMiddleware(authHandlers.Refresh)))
 	mux.HandleFunc("GET /api/auth/me", corsMiddleware(authService.AuthMiddleware(authHandlers.Me)))
 
+	// Session management endpoints
+	mux.HandleFunc("GET /api/auth/sessions", corsMiddleware(authService.AuthMiddleware(authHandlers.ListSessions)))
+	mux.HandleFunc("DELETE /api/auth/sessions/{id}", corsMiddleware(authService.AuthMiddleware(authHandlers.DeleteSession)))
+
+	// API tokens for automation
+	mux.HandleFunc("POST /api/auth/tokens", corsMiddleware(authService.AuthMiddleware(authHandlers.CreateAPIToken)))
+	mux.HandleFunc("GET /api/auth/tokens", corsMiddleware(authService.AuthMiddleware(authHandlers.ListAPITokens)))
+	mux.HandleFunc("DELETE /api/auth/tokens/{id}", corsMiddleware(authService.AuthMiddleware(authHandlers.DeleteAPIToken)))
+
+	// Per-user UI preferences
+	mux.HandleFunc("GET /api/preferences", corsMiddleware(authService.AuthMiddleware(preferencesHandlers.GetPreferences)))
+	mux.HandleFunc("PUT /api/preferences", corsMiddleware(authService.AuthMiddleware(preferencesHandlers.UpdatePreferences)))
+	mux.HandleFunc("DELETE /api/preferences", corsMiddleware(authService.AuthMiddleware(preferencesHandlers.ResetPreferences)))
+
+	// Token signing key endpoints (admin only)
+	mux.HandleFunc("GET /api/auth/keys", corsMiddleware(authService.RequireRole("admin")(authHandlers.ListSigningKeys)))
+	mux.HandleFunc("POST /api/auth/keys/rotate", corsMiddleware(authService.RequireRole("admin")(authHandlers.RotateSigningKey)))
+
 	// User management endpoints (admin only)
 	mux.HandleFunc("POST /api/auth/users", corsMiddleware(authService.RequireRole("admin")(authHandlers.CreateUser)))
 	mux.HandleFunc("GET /api/auth/users", corsMiddleware(authService.RequireRole("admin")(authHandlers.ListUsers)))
@@ -113,30 +332,120 @@ func RegisterRoutes(
 	mux.HandleFunc("POST /api/k8s/pods/{name}/restart", corsMiddleware(authService.AuthMiddleware(k8sHandlers.RestartPod)))
 	mux.HandleFunc("DELETE /api/k8s/pods/{name}", corsMiddleware(authService.AuthMiddleware(k8sHandlers.DeletePod)))
 	mux.HandleFunc("GET /api/k8s/pods/{name}/logs", corsMiddleware(authService.AuthMiddleware(k8sHandlers.GetPodLogs)))
+	mux.HandleFunc("POST /api/k8s/pods/bulk", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.BulkPodOperation)))
 
 	mux.HandleFunc("GET /api/k8s/deployments", corsMiddleware(authService.AuthMiddleware(k8sHandlers.ListDeployments)))
 	mux.HandleFunc("PATCH /api/k8s/deployments/{name}/scale", corsMiddleware(authService.AuthMiddleware(k8sHandlers.ScaleDeployment)))
 
+	mux.HandleFunc("GET /api/k8s/statefulsets", corsMiddleware(authService.AuthMiddleware(k8sHandlers.ListStatefulSets)))
+	mux.HandleFunc("GET /api/k8s/statefulsets/{name}", corsMiddleware(authService.AuthMiddleware(k8sHandlers.GetStatefulSet)))
+	mux.HandleFunc("PATCH /api/k8s/statefulsets/{name}/scale", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.ScaleStatefulSet)))
+	mux.HandleFunc("POST /api/k8s/statefulsets/{name}/restart", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.RestartStatefulSet)))
+	mux.HandleFunc("DELETE /api/k8s/statefulsets/{name}", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.DeleteStatefulSet)))
+
+	mux.HandleFunc("GET /api/k8s/daemonsets", corsMiddleware(authService.AuthMiddleware(k8sHandlers.ListDaemonSets)))
+	mux.HandleFunc("GET /api/k8s/daemonsets/{name}", corsMiddleware(authService.AuthMiddleware(k8sHandlers.GetDaemonSet)))
+	mux.HandleFunc("POST /api/k8s/daemonsets/{name}/restart", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.RestartDaemonSet)))
+	mux.HandleFunc("DELETE /api/k8s/daemonsets/{name}", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.DeleteDaemonSet)))
+
 	mux.HandleFunc("GET /api/k8s/nodes", corsMiddleware(authService.AuthMiddleware(k8sHandlers.ListNodes)))
+	if diskHealthHandlers != nil {
+		mux.HandleFunc("GET /api/k8s/nodes/disks", corsMiddleware(authService.AuthMiddleware(diskHealthHandlers.ListDisks)))
+		mux.HandleFunc("POST /api/k8s/nodes/disks/check", corsMiddleware(authService.RequireRole("operator")(diskHealthHandlers.CheckDisks)))
+	}
+	if upgradeHandlers != nil {
+		mux.HandleFunc("GET /api/k8s/upgrades", corsMiddleware(authService.AuthMiddleware(upgradeHandlers.CheckUpgrades)))
+		mux.HandleFunc("GET /api/k8s/deprecated-apis", corsMiddleware(authService.AuthMiddleware(upgradeHandlers.ScanDeprecatedAPIs)))
+	}
+
+	// Node maintenance endpoints (operator role required); drain progress is broadcast on node_operations
+	mux.HandleFunc("POST /api/k8s/nodes/{name}/cordon", corsMiddleware(authService.RequireRole("operator")(nodeHandlers.CordonNode)))
+	mux.HandleFunc("POST /api/k8s/nodes/{name}/uncordon", corsMiddleware(authService.RequireRole("operator")(nodeHandlers.UncordonNode)))
+	mux.HandleFunc("POST /api/k8s/nodes/{name}/drain", corsMiddleware(authService.RequireRole("operator")(nodeHandlers.DrainNode)))
+	mux.HandleFunc("GET /api/k8s/nodes/{name}/drain", corsMiddleware(authService.AuthMiddleware(nodeHandlers.GetDrainStatus)))
+	mux.HandleFunc("GET /api/k8s/nodes/{name}/taints", corsMiddleware(authService.AuthMiddleware(nodeHandlers.ListTaints)))
+	mux.HandleFunc("POST /api/k8s/nodes/{name}/taints", corsMiddleware(authService.RequireRole("operator")(nodeHandlers.AddTaint)))
+	mux.HandleFunc("DELETE /api/k8s/nodes/{name}/taints/{key}", corsMiddleware(authService.RequireRole("operator")(nodeHandlers.RemoveTaint)))
+
 	mux.HandleFunc("GET /api/k8s/services", corsMiddleware(authService.AuthMiddleware(k8sHandlers.ListServices)))
 	mux.HandleFunc("GET /api/k8s/events", corsMiddleware(authService.AuthMiddleware(k8sHandlers.ListEvents)))
+	mux.HandleFunc("GET /api/timeline", corsMiddleware(authService.AuthMiddleware(timelineHandlers.GetTimeline)))
+	if graphQLHandlers != nil {
+		mux.HandleFunc("GET /api/graphql", corsMiddleware(authService.AuthMiddleware(graphQLHandlers.Execute)))
+		mux.HandleFunc("POST /api/graphql", corsMiddleware(authService.AuthMiddleware(graphQLHandlers.Execute)))
+		mux.HandleFunc("GET /api/graphql/schema", corsMiddleware(authService.AuthMiddleware(graphQLHandlers.GetSchema)))
+	}
 	mux.HandleFunc("GET /api/k8s/namespaces", corsMiddleware(authService.AuthMiddleware(k8sHandlers.ListNamespaces)))
+	mux.HandleFunc("GET /api/k8s/namespaces/{name}/quotas", corsMiddleware(authService.AuthMiddleware(k8sHandlers.GetNamespaceQuotas)))
 	mux.HandleFunc("GET /api/k8s/storage", corsMiddleware(authService.AuthMiddleware(k8sHandlers.GetStorageInfo)))
 	mux.HandleFunc("GET /api/k8s/health", corsMiddleware(k8sHandlers.HealthCheck)) // No auth required for health check
 
+	// Storage classes and volume claim expansion
+	mux.HandleFunc("GET /api/k8s/storageclasses", corsMiddleware(authService.AuthMiddleware(k8sHandlers.ListStorageClasses)))
+	mux.HandleFunc("POST /api/k8s/storageclasses", corsMiddleware(authService.RequireRole("admin")(k8sHandlers.CreateStorageClass)))
+	mux.HandleFunc("DELETE /api/k8s/storageclasses/{name}", corsMiddleware(authService.RequireRole("admin")(k8sHandlers.DeleteStorageClass)))
+	mux.HandleFunc("POST /api/k8s/storageclasses/{name}/default", corsMiddleware(authService.RequireRole("admin")(k8sHandlers.SetDefaultStorageClass)))
+	mux.HandleFunc("POST /api/k8s/pvcs/{name}/expand", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.ExpandVolumeClaim)))
+	mux.HandleFunc("GET /api/k8s/pvcs/{name}/resize", corsMiddleware(authService.AuthMiddleware(k8sHandlers.GetVolumeResize)))
+
+	// Network policies and the connectivity they allow
+	mux.HandleFunc("GET /api/k8s/networkpolicies", corsMiddleware(authService.AuthMiddleware(k8sHandlers.ListNetworkPolicies)))
+	mux.HandleFunc("GET /api/k8s/networkpolicies/{name}", corsMiddleware(authService.AuthMiddleware(k8sHandlers.GetNetworkPolicy)))
+	mux.HandleFunc("POST /api/k8s/networkpolicies", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.CreateNetworkPolicy)))
+	mux.HandleFunc("PUT /api/k8s/networkpolicies/{name}", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.UpdateNetworkPolicy)))
+	mux.HandleFunc("DELETE /api/k8s/networkpolicies/{name}", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.DeleteNetworkPolicy)))
+	mux.HandleFunc("GET /api/k8s/connectivity", corsMiddleware(authService.AuthMiddleware(k8sHandlers.GetConnectivity)))
+
+	// RBAC inspection
+	mux.HandleFunc("GET /api/k8s/rbac/can-i", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.CanI)))
+	mux.HandleFunc("GET /api/k8s/rbac/who-can", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.WhoCan)))
+	mux.HandleFunc("GET /api/k8s/rbac/roles", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.ListRoles)))
+	mux.HandleFunc("GET /api/k8s/rbac/bindings", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.ListRoleBindings)))
+	mux.HandleFunc("GET /api/k8s/security/audit", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.GetSecurityAudit)))
+
+	// Restricted kubectl access for Denshimon users
+	mux.HandleFunc("POST /api/k8s/kubeconfigs", corsMiddleware(authService.RequireRole("admin")(k8sHandlers.CreateUserKubeconfig)))
+	mux.HandleFunc("DELETE /api/k8s/kubeconfigs/{username}", corsMiddleware(authService.RequireRole("admin")(k8sHandlers.RevokeUserKubeconfig)))
+
+	// Pod disruption budgets
+	mux.HandleFunc("GET /api/k8s/pdbs", corsMiddleware(authService.AuthMiddleware(k8sHandlers.ListDisruptionBudgets)))
+	mux.HandleFunc("GET /api/k8s/pdbs/{name}", corsMiddleware(authService.AuthMiddleware(k8sHandlers.GetDisruptionBudget)))
+	mux.HandleFunc("POST /api/k8s/pdbs", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.CreateDisruptionBudget)))
+	mux.HandleFunc("PUT /api/k8s/pdbs/{name}", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.UpdateDisruptionBudget)))
+	mux.HandleFunc("DELETE /api/k8s/pdbs/{name}", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.DeleteDisruptionBudget)))
+
+	// Generic manifest endpoints for resources without dedicated handlers
+	mux.HandleFunc("GET /api/k8s/{kind}/{name}/yaml", corsMiddleware(authService.AuthMiddleware(k8sHandlers.GetResourceYAML)))
+	mux.HandleFunc("POST /api/k8s/apply", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.ApplyManifests)))
+
 	// Service Mesh endpoints (require authentication)
 	mux.HandleFunc("GET /api/services/mesh", corsMiddleware(authService.AuthMiddleware(servicesHandlers.GetServiceMesh)))
 	mux.HandleFunc("GET /api/services/topology", corsMiddleware(authService.AuthMiddleware(servicesHandlers.GetServiceTopology)))
 	mux.HandleFunc("GET /api/services/endpoints", corsMiddleware(authService.AuthMiddleware(servicesHandlers.GetServiceEndpoints)))
 	mux.HandleFunc("GET /api/services/flows", corsMiddleware(authService.AuthMiddleware(servicesHandlers.GetServiceFlows)))
+	mux.HandleFunc("GET /api/traces", corsMiddleware(authService.AuthMiddleware(tracesHandlers.SearchTraces)))
+	mux.HandleFunc("GET /api/traces/{id}", corsMiddleware(authService.AuthMiddleware(tracesHandlers.GetTrace)))
+
+	// Effective configuration, secrets redacted (admin only)
+	mux.HandleFunc("GET /api/config", corsMiddleware(authService.RequireRole("admin")(configHandlers.GetConfig)))
 	mux.HandleFunc("GET /api/services/gateway", corsMiddleware(authService.AuthMiddleware(servicesHandlers.GetServiceGateway)))
 
 	// Pod debugging endpoints
-	mux.HandleFunc("GET /api/k8s/pods/exec", k8sHandlers.HandlePodExec) // WebSocket - no CORS middleware needed
+	mux.HandleFunc("GET /api/k8s/pods/exec", authService.OptionalAuth(k8sHandlers.HandlePodExec)) // WebSocket - no CORS middleware needed
+	mux.HandleFunc("GET /api/k8s/pods/exec/shared/{id}", authService.OptionalAuth(k8sHandlers.JoinSharedExec)) // WebSocket - joins a shared exec session
+	mux.HandleFunc("GET /api/k8s/nodes/{name}/shell", authService.OptionalAuth(k8sHandlers.HandleNodeShell)) // WebSocket - admin shell on a node, recorded
 	mux.HandleFunc("GET /api/k8s/pods/logs/stream", corsMiddleware(authService.AuthMiddleware(k8sHandlers.HandlePodLogs)))
 	mux.HandleFunc("POST /api/k8s/pods/portforward", corsMiddleware(authService.AuthMiddleware(k8sHandlers.HandlePodPortForward)))
 	mux.HandleFunc("POST /api/k8s/pods/files/upload", corsMiddleware(authService.AuthMiddleware(k8sHandlers.HandleFileUpload)))
 	mux.HandleFunc("GET /api/k8s/pods/files/download", corsMiddleware(authService.AuthMiddleware(k8sHandlers.HandleFileDownload)))
+	mux.HandleFunc("POST /api/k8s/pods/{name}/debug", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.AttachDebugContainer)))
+	mux.HandleFunc("POST /api/k8s/debug/connectivity", corsMiddleware(authService.RequireRole("operator")(k8sHandlers.CheckConnectivity)))
+
+	// Exec session recordings
+	mux.HandleFunc("GET /api/k8s/exec-sessions", corsMiddleware(authService.AuthMiddleware(execSessionHandlers.ListSessions)))
+	mux.HandleFunc("GET /api/k8s/exec-sessions/{id}", corsMiddleware(authService.AuthMiddleware(execSessionHandlers.GetSession)))
+	mux.HandleFunc("GET /api/k8s/exec-sessions/{id}/replay", corsMiddleware(authService.AuthMiddleware(execSessionHandlers.ReplaySession)))
+	mux.HandleFunc("DELETE /api/k8s/exec-sessions/{id}", corsMiddleware(authService.RequireRole("admin")(execSessionHandlers.DeleteSession)))
 
 	// Events stream endpoint removed - using existing events handler
 
@@ -149,6 +458,13 @@ func RegisterRoutes(
 	mux.HandleFunc("GET /api/metrics/resources", corsMiddleware(authService.AuthMiddleware(metricsHandlers.GetResourceMetrics)))
 	mux.HandleFunc("GET /api/metrics/network", corsMiddleware(authService.AuthMiddleware(metricsHandlers.GetNetworkMetrics)))
 	mux.HandleFunc("GET /api/metrics/storage", corsMiddleware(authService.AuthMiddleware(metricsHandlers.GetStorageMetrics)))
+	mux.HandleFunc("GET /api/metrics/capabilities", corsMiddleware(authService.AuthMiddleware(metricsHandlers.GetCapabilities)))
+	mux.HandleFunc("GET /api/metrics/host", corsMiddleware(authService.AuthMiddleware(metricsHandlers.GetHostMetrics)))
+	mux.HandleFunc("GET /api/metrics/top/nodes", corsMiddleware(authService.AuthMiddleware(metricsHandlers.GetTopNodes)))
+	mux.HandleFunc("GET /api/metrics/top/pods", corsMiddleware(authService.AuthMiddleware(metricsHandlers.GetTopPods)))
+	mux.HandleFunc("GET /api/metrics/recommendations", corsMiddleware(authService.AuthMiddleware(metricsHandlers.GetRecommendations)))
+	mux.HandleFunc("GET /api/metrics/costs", corsMiddleware(authService.AuthMiddleware(metricsHandlers.GetCosts)))
+	mux.HandleFunc("POST /api/metrics/recommendations/apply", corsMiddleware(authService.RequireRole("operator")(metricsHandlers.ApplyRecommendation)))
 	mux.HandleFunc("GET /api/metrics/health", corsMiddleware(metricsHandlers.GetHealthMetrics)) // No auth required for health check
 
 	// Observability endpoints (require authentication)
@@ -162,6 +478,9 @@ func RegisterRoutes(
 	mux.HandleFunc("GET /api/infrastructure/status", corsMiddleware(authService.AuthMiddleware(infrastructureHandlers.GetStatus)))
 	mux.HandleFunc("GET /api/infrastructure/alerts", corsMiddleware(authService.AuthMiddleware(infrastructureHandlers.GetAlerts)))
 	mux.HandleFunc("POST /api/infrastructure/refresh", corsMiddleware(authService.AuthMiddleware(infrastructureHandlers.RefreshServices)))
+	mux.HandleFunc("GET /api/infrastructure/services/overrides", corsMiddleware(authService.AuthMiddleware(infrastructureHandlers.ListServiceOverrides)))
+	mux.HandleFunc("PUT /api/infrastructure/services/overrides/{namespace}/{name}", corsMiddleware(authService.RequireRole("operator")(infrastructureHandlers.SaveServiceOverride)))
+	mux.HandleFunc("DELETE /api/infrastructure/services/overrides/{namespace}/{name}", corsMiddleware(authService.RequireRole("operator")(infrastructureHandlers.DeleteServiceOverride)))
 
 	// Infrastructure alert operations
 	mux.Handle("/api/infrastructure/alerts/", corsMiddleware(authService.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -172,27 +491,133 @@ func RegisterRoutes(
 		}
 	}))))
 
+	// Audit trail
+	if auditHandlers != nil {
+		mux.HandleFunc("GET /api/audit", corsMiddleware(authService.RequireRole("admin")(auditHandlers.ListAuditEntries)))
+	}
+
+	// Decisions of the Rego policies
+	if policyHandlers != nil {
+		mux.HandleFunc("GET /api/policy/decisions", corsMiddleware(authService.RequireRole("admin")(policyHandlers.ListPolicyDecisions)))
+	}
+
+	// One inbox for the alerts of all subsystems, with silences and escalation
+	if alertManager != nil {
+		alertHandlers := NewAlertHandlers(alertManager)
+		mux.HandleFunc("GET /api/alerts", corsMiddleware(authService.AuthMiddleware(alertHandlers.ListAlerts)))
+		mux.HandleFunc("POST /api/alerts/{source}/{id}/acknowledge", corsMiddleware(authService.AuthMiddleware(alertHandlers.AcknowledgeAlert)))
+		mux.HandleFunc("GET /api/alerts/silences", corsMiddleware(authService.AuthMiddleware(alertHandlers.ListSilences)))
+		mux.HandleFunc("POST /api/alerts/silences", corsMiddleware(authService.RequireRole("operator")(alertHandlers.CreateSilence)))
+		mux.HandleFunc("DELETE /api/alerts/silences/{id}", corsMiddleware(authService.RequireRole("operator")(alertHandlers.ExpireSilence)))
+		mux.HandleFunc("GET /api/alerts/escalations", corsMiddleware(authService.AuthMiddleware(alertHandlers.ListEscalationRules)))
+		mux.HandleFunc("POST /api/alerts/escalations", corsMiddleware(authService.RequireRole("admin")(alertHandlers.CreateEscalationRule)))
+		mux.HandleFunc("DELETE /api/alerts/escalations/{id}", corsMiddleware(authService.RequireRole("admin")(alertHandlers.DeleteEscalationRule)))
+	}
+
+	// Incidents grouping alerts, with the status page built from them
+	if incidentHandlers != nil {
+		mux.HandleFunc("GET /api/incidents", corsMiddleware(authService.AuthMiddleware(incidentHandlers.ListIncidents)))
+		mux.HandleFunc("POST /api/incidents", corsMiddleware(authService.RequireRole("operator")(incidentHandlers.CreateIncident)))
+		mux.HandleFunc("GET /api/incidents/{id}", corsMiddleware(authService.AuthMiddleware(incidentHandlers.GetIncident)))
+		mux.HandleFunc("PUT /api/incidents/{id}", corsMiddleware(authService.RequireRole("operator")(incidentHandlers.UpdateIncident)))
+		mux.HandleFunc("DELETE /api/incidents/{id}", corsMiddleware(authService.RequireRole("operator")(incidentHandlers.DeleteIncident)))
+		mux.HandleFunc("POST /api/incidents/{id}/updates", corsMiddleware(authService.RequireRole("operator")(incidentHandlers.PostIncidentUpdate)))
+		mux.HandleFunc("POST /api/incidents/{id}/alerts", corsMiddleware(authService.RequireRole("operator")(incidentHandlers.LinkIncidentAlerts)))
+		mux.HandleFunc("DELETE /api/incidents/{id}/alerts/{key...}", corsMiddleware(authService.RequireRole("operator")(incidentHandlers.UnlinkIncidentAlert)))
+		if statusPagePublic() {
+			mux.HandleFunc("GET /api/status", corsMiddleware(incidentHandlers.GetStatusPage)) // STATUS_PAGE_PUBLIC=true
+		} else {
+			mux.HandleFunc("GET /api/status", corsMiddleware(authService.AuthMiddleware(incidentHandlers.GetStatusPage)))
+		}
+	}
+
+	// Uptime monitors for external services
+	if monitorHandlers != nil {
+		mux.HandleFunc("GET /api/monitors", corsMiddleware(authService.AuthMiddleware(monitorHandlers.ListMonitors)))
+		mux.HandleFunc("POST /api/monitors", corsMiddleware(authService.RequireRole("operator")(monitorHandlers.CreateMonitor)))
+		mux.HandleFunc("GET /api/monitors/{id}", corsMiddleware(authService.AuthMiddleware(monitorHandlers.GetMonitor)))
+		mux.HandleFunc("PUT /api/monitors/{id}", corsMiddleware(authService.RequireRole("operator")(monitorHandlers.UpdateMonitor)))
+		mux.HandleFunc("DELETE /api/monitors/{id}", corsMiddleware(authService.RequireRole("operator")(monitorHandlers.DeleteMonitor)))
+		mux.HandleFunc("GET /api/monitors/{id}/history", corsMiddleware(authService.AuthMiddleware(monitorHandlers.GetMonitorHistory)))
+		mux.HandleFunc("POST /api/monitors/{id}/check", corsMiddleware(authService.RequireRole("operator")(monitorHandlers.CheckMonitor)))
+	}
+
+	// Outbound webhooks for deployment, alert, backup and certificate events
+	if webhookHandlers != nil {
+		mux.HandleFunc("GET /api/webhooks", corsMiddleware(authService.RequireRole("admin")(webhookHandlers.ListWebhooks)))
+		mux.HandleFunc("POST /api/webhooks", corsMiddleware(authService.RequireRole("admin")(webhookHandlers.CreateWebhook)))
+		mux.HandleFunc("GET /api/webhooks/events", corsMiddleware(authService.AuthMiddleware(webhookHandlers.ListWebhookEvents)))
+		mux.HandleFunc("GET /api/webhooks/{id}", corsMiddleware(authService.RequireRole("admin")(webhookHandlers.GetWebhook)))
+		mux.HandleFunc("PUT /api/webhooks/{id}", corsMiddleware(authService.RequireRole("admin")(webhookHandlers.UpdateWebhook)))
+		mux.HandleFunc("DELETE /api/webhooks/{id}", corsMiddleware(authService.RequireRole("admin")(webhookHandlers.DeleteWebhook)))
+		mux.HandleFunc("POST /api/webhooks/{id}/test", corsMiddleware(authService.RequireRole("admin")(webhookHandlers.TestWebhook)))
+		mux.HandleFunc("GET /api/webhooks/{id}/deliveries", corsMiddleware(authService.RequireRole("admin")(webhookHandlers.GetWebhookDeliveries)))
+	}
+
 	// Deployment endpoints (require authentication)
 	// Registry management
 	mux.HandleFunc("GET /api/deployments/registries", corsMiddleware(authService.AuthMiddleware(deploymentHandlers.ListRegistries)))
 	mux.HandleFunc("POST /api/deployments/registries", corsMiddleware(authService.AuthMiddleware(deploymentHandlers.AddRegistry)))
 
-	// Registry operations (using pattern matching)
-	mux.Handle("/api/deployments/registries/", corsMiddleware(authService.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	// Registry operations (using pattern matching); pull secrets are written to the cluster,
+	// so managing them needs an operator
+	mux.Handle("/api/deployments/registries/", corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch {
 		case strings.HasSuffix(r.URL.Path, "/test") && r.Method == "POST":
-			deploymentHandlers.TestRegistry(w, r)
+			authService.AuthMiddleware(deploymentHandlers.TestRegistry)(w, r)
+		case strings.HasSuffix(r.URL.Path, "/pull-secrets") && r.Method == "GET":
+			authService.AuthMiddleware(deploymentHandlers.ListPullSecrets)(w, r)
+		case strings.HasSuffix(r.URL.Path, "/pull-secrets") && r.Method == "PUT":
+			authService.RequireRole("operator")(deploymentHandlers.SyncPullSecret)(w, r)
+		case strings.HasSuffix(r.URL.Path, "/pull-secrets") && r.Method == "DELETE":
+			authService.RequireRole("operator")(deploymentHandlers.DeletePullSecret)(w, r)
 		case r.Method == "DELETE":
-			deploymentHandlers.DeleteRegistry(w, r)
+			authService.AuthMiddleware(deploymentHandlers.DeleteRegistry)(w, r)
 		default:
-			http.NotFound(w, r)
+			authService.AuthMiddleware(http.NotFound)(w, r)
 		}
-	}))))
+	})))
+
+	// Deployment template catalog; admins manage templates, users deploy them
+	if catalogHandlers != nil {
+		mux.HandleFunc("GET /api/catalog", corsMiddleware(authService.AuthMiddleware(catalogHandlers.ListTemplates)))
+		mux.HandleFunc("POST /api/catalog", corsMiddleware(authService.RequireRole("admin")(catalogHandlers.CreateTemplate)))
+		mux.HandleFunc("GET /api/catalog/{template}", corsMiddleware(authService.AuthMiddleware(catalogHandlers.GetTemplate)))
+		mux.HandleFunc("PUT /api/catalog/{template}", corsMiddleware(authService.RequireRole("admin")(catalogHandlers.UpdateTemplate)))
+		mux.HandleFunc("DELETE /api/catalog/{template}", corsMiddleware(authService.RequireRole("admin")(catalogHandlers.DeleteTemplate)))
+		mux.HandleFunc("POST /api/catalog/{template}/deploy", corsMiddleware(authService.AuthMiddleware(catalogHandlers.DeployTemplate)))
+	}
+
+	// Image retention policies
+	if retentionHandlers != nil {
+		mux.HandleFunc("GET /api/deployments/retention/policies", corsMiddleware(authService.AuthMiddleware(retentionHandlers.ListPolicies)))
+		mux.HandleFunc("POST /api/deployments/retention/policies", corsMiddleware(authService.RequireRole("operator")(retentionHandlers.CreatePolicy)))
+		mux.HandleFunc("GET /api/deployments/retention/policies/{id}", corsMiddleware(authService.AuthMiddleware(retentionHandlers.GetPolicy)))
+		mux.HandleFunc("PUT /api/deployments/retention/policies/{id}", corsMiddleware(authService.RequireRole("operator")(retentionHandlers.UpdatePolicy)))
+		mux.HandleFunc("DELETE /api/deployments/retention/policies/{id}", corsMiddleware(authService.RequireRole("operator")(retentionHandlers.DeletePolicy)))
+		mux.HandleFunc("GET /api/deployments/retention/policies/{id}/preview", corsMiddleware(authService.AuthMiddleware(retentionHandlers.PreviewPolicy)))
+		mux.HandleFunc("POST /api/deployments/retention/policies/{id}/run", corsMiddleware(authService.RequireRole("operator")(retentionHandlers.RunPolicy)))
+	}
+
+	// Image SBOMs and licenses
+	if sbomHandlers != nil {
+		mux.HandleFunc("GET /api/deployments/sboms", corsMiddleware(authService.AuthMiddleware(sbomHandlers.ListSBOMs)))
+		mux.HandleFunc("POST /api/deployments/sboms", corsMiddleware(authService.RequireRole("operator")(sbomHandlers.GenerateSBOM)))
+		mux.HandleFunc("GET /api/deployments/sboms/scan", corsMiddleware(authService.AuthMiddleware(sbomHandlers.GetScanStatus)))
+		mux.HandleFunc("POST /api/deployments/sboms/scan", corsMiddleware(authService.RequireRole("operator")(sbomHandlers.ScanRunningImages)))
+		mux.HandleFunc("GET /api/deployments/sboms/packages", corsMiddleware(authService.AuthMiddleware(sbomHandlers.SearchPackages)))
+		mux.HandleFunc("GET /api/deployments/sboms/licenses", corsMiddleware(authService.AuthMiddleware(sbomHandlers.ListLicenses)))
+		mux.HandleFunc("GET /api/deployments/sboms/{id}", corsMiddleware(authService.AuthMiddleware(sbomHandlers.GetSBOM)))
+		mux.HandleFunc("DELETE /api/deployments/sboms/{id}", corsMiddleware(authService.RequireRole("operator")(sbomHandlers.DeleteSBOM)))
+	}
 
 	// Image management
 	mux.HandleFunc("GET /api/deployments/images", corsMiddleware(authService.AuthMiddleware(deploymentHandlers.ListImages)))
 	mux.HandleFunc("GET /api/deployments/images/search", corsMiddleware(authService.AuthMiddleware(deploymentHandlers.SearchImages)))
 
+	mux.HandleFunc("GET /api/deployments/images/{registry}/manifest", corsMiddleware(authService.AuthMiddleware(deploymentHandlers.InspectManifest)))
+	mux.HandleFunc("DELETE /api/deployments/images/{registry}/tags", corsMiddleware(authService.RequireRole("operator")(deploymentHandlers.DeleteImageTag)))
+
 	// Image operations
 	mux.Handle("/api/deployments/images/", corsMiddleware(authService.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasSuffix(r.URL.Path, "/tags") && r.Method == "GET" {
@@ -211,22 +636,66 @@ func RegisterRoutes(
 	mux.HandleFunc("GET /api/deployments/pending", corsMiddleware(authService.AuthMiddleware(deploymentHandlers.GetPendingDeployments)))
 	mux.HandleFunc("POST /api/deployments/batch-apply", corsMiddleware(authService.AuthMiddleware(deploymentHandlers.BatchApplyDeployments)))
 
+	// Approval rules for protected namespaces
+	mux.HandleFunc("GET /api/deployments/approval-rules", corsMiddleware(authService.AuthMiddleware(deploymentHandlers.ListApprovalRules)))
+	mux.HandleFunc("POST /api/deployments/approval-rules", corsMiddleware(authService.RequireRole("admin")(deploymentHandlers.SaveApprovalRule)))
+	mux.HandleFunc("DELETE /api/deployments/approval-rules/{id}", corsMiddleware(authService.RequireRole("admin")(deploymentHandlers.DeleteApprovalRule)))
+
+	// Scheduled rolling restarts, paused and resumed together
+	mux.HandleFunc("GET /api/deployments/restart-schedules", corsMiddleware(authService.AuthMiddleware(deploymentHandlers.ListRestartSchedules)))
+	mux.HandleFunc("POST /api/deployments/restart-schedules/pause", corsMiddleware(authService.RequireRole("operator")(deploymentHandlers.PauseRestartSchedules)))
+	mux.HandleFunc("POST /api/deployments/restart-schedules/resume", corsMiddleware(authService.RequireRole("operator")(deploymentHandlers.ResumeRestartSchedules)))
+	mux.HandleFunc("PUT /api/deployments/{id}/restart-schedule", corsMiddleware(authService.RequireRole("operator")(deploymentHandlers.SetRestartSchedule)))
+	mux.HandleFunc("DELETE /api/deployments/{id}/restart-schedule", corsMiddleware(authService.RequireRole("operator")(deploymentHandlers.DeleteRestartSchedule)))
+
 	// Deployment operations
 	mux.Handle("/api/deployments/", corsMiddleware(authService.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
 		switch {
 		case strings.HasSuffix(path, "/apply") && r.Method == "POST":
 			deploymentHandlers.ApplyDeployment(w, r)
+		case strings.HasSuffix(path, "/approvals") && r.Method == "GET":
+			deploymentHandlers.GetDeploymentApprovals(w, r)
+		case strings.HasSuffix(path, "/plan") && r.Method == "GET":
+			deploymentHandlers.GetDeploymentPlan(w, r)
+		case strings.HasSuffix(path, "/dependencies") && r.Method == "GET":
+			deploymentHandlers.GetDeploymentDependencies(w, r)
+		case strings.HasSuffix(path, "/dependencies") && r.Method == "PUT":
+			deploymentHandlers.SetDeploymentDependencies(w, r)
+		case strings.HasSuffix(path, "/approve") && r.Method == "POST":
+			deploymentHandlers.ApproveDeployment(w, r)
+		case strings.HasSuffix(path, "/reject") && r.Method == "POST":
+			deploymentHandlers.RejectDeployment(w, r)
 		case strings.HasSuffix(path, "/manifest") && r.Method == "GET":
 			deploymentHandlers.GetDeploymentManifest(w, r)
 		case strings.HasSuffix(path, "/scale") && r.Method == "PATCH":
 			deploymentHandlers.ScaleDeployment(w, r)
 		case strings.HasSuffix(path, "/restart") && r.Method == "POST":
 			deploymentHandlers.RestartDeployment(w, r)
+		case strings.HasSuffix(path, "/restart-schedule") && r.Method == "GET":
+			deploymentHandlers.GetRestartSchedule(w, r)
 		case strings.HasSuffix(path, "/pods") && r.Method == "GET":
 			deploymentHandlers.GetDeploymentPods(w, r)
 		case strings.HasSuffix(path, "/history") && r.Method == "GET":
 			deploymentHandlers.GetDeploymentHistory(w, r)
+		case strings.HasSuffix(path, "/provenance") && r.Method == "GET":
+			giteaHandler.GetDeploymentProvenance(w, r)
+		case strings.HasSuffix(path, "/compare") && r.Method == "GET":
+			deploymentHandlers.CompareDeployment(w, r)
+		case strings.HasSuffix(path, "/revisions/diff") && r.Method == "GET":
+			deploymentHandlers.GetRevisionDiff(w, r)
+		case strings.HasSuffix(path, "/revisions") && r.Method == "GET":
+			deploymentHandlers.GetDeploymentRevisions(w, r)
+		case strings.HasSuffix(path, "/rollback") && r.Method == "POST":
+			deploymentHandlers.RollbackDeployment(w, r)
+		case strings.HasSuffix(path, "/rollout") && r.Method == "GET":
+			deploymentHandlers.GetRollout(w, r)
+		case strings.HasSuffix(path, "/health-gate") && r.Method == "GET":
+			deploymentHandlers.GetHealthGate(w, r)
+		case strings.HasSuffix(path, "/promote") && r.Method == "POST":
+			deploymentHandlers.PromoteRollout(w, r)
+		case strings.HasSuffix(path, "/abort") && r.Method == "POST":
+			deploymentHandlers.AbortRollout(w, r)
 		case r.Method == "GET":
 			deploymentHandlers.GetDeployment(w, r)
 		case r.Method == "PUT":
@@ -250,7 +719,15 @@ func RegisterRoutes(
 	mux.HandleFunc("GET /api/databases/connections/{id}/databases", corsMiddleware(authService.AuthMiddleware(databaseHandlers.GetDatabases)))
 	mux.HandleFunc("GET /api/databases/connections/{id}/databases/{database}/tables", corsMiddleware(authService.AuthMiddleware(databaseHandlers.GetTables)))
 	mux.HandleFunc("GET /api/databases/connections/{id}/databases/{database}/tables/{table}/columns", corsMiddleware(authService.AuthMiddleware(databaseHandlers.GetColumns)))
+	mux.HandleFunc("GET /api/databases/connections/{id}/databases/{database}/tables/{table}/schema", corsMiddleware(authService.AuthMiddleware(databaseHandlers.GetTableSchema)))
+	mux.HandleFunc("GET /api/databases/connections/{id}/databases/{database}/relationships", corsMiddleware(authService.AuthMiddleware(databaseHandlers.GetRelationships)))
 	mux.HandleFunc("POST /api/databases/connections/{id}/query", corsMiddleware(authService.AuthMiddleware(databaseHandlers.ExecuteQuery)))
+	mux.HandleFunc("POST /api/databases/connections/{id}/explain", corsMiddleware(authService.AuthMiddleware(databaseHandlers.Explain)))
+	mux.HandleFunc("GET /api/databases/connections/{id}/slow-queries", corsMiddleware(authService.AuthMiddleware(databaseHandlers.GetSlowQueries)))
+	mux.HandleFunc("GET /api/databases/connections/{id}/databases/{database}/keys", corsMiddleware(authService.AuthMiddleware(databaseHandlers.ScanKeys)))
+	mux.HandleFunc("GET /api/databases/connections/{id}/databases/{database}/keys/value", corsMiddleware(authService.AuthMiddleware(databaseHandlers.GetKey)))
+	mux.HandleFunc("DELETE /api/databases/connections/{id}/databases/{database}/keys", corsMiddleware(authService.RequireRole("admin")(databaseHandlers.DeleteKeys)))
+	mux.HandleFunc("POST /api/databases/connections/{id}/databases/{database}/flush", corsMiddleware(authService.RequireRole("admin")(databaseHandlers.FlushDatabase)))
 	mux.HandleFunc("POST /api/databases/connections/{id}/tables/{table}/data", corsMiddleware(authService.AuthMiddleware(databaseHandlers.GetTableData)))
 	mux.HandleFunc("PUT /api/databases/connections/{id}/tables/{table}/rows", corsMiddleware(authService.AuthMiddleware(databaseHandlers.UpdateRow)))
 	mux.HandleFunc("DELETE /api/databases/connections/{id}/tables/{table}/rows", corsMiddleware(authService.AuthMiddleware(databaseHandlers.DeleteRow)))
@@ -264,11 +741,20 @@ func RegisterRoutes(
 	mux.HandleFunc("PUT /api/databases/saved-queries/{id}", corsMiddleware(authService.AuthMiddleware(databaseHandlers.UpdateSavedQuery)))
 	mux.HandleFunc("DELETE /api/databases/saved-queries/{id}", corsMiddleware(authService.AuthMiddleware(databaseHandlers.DeleteSavedQuery)))
 
+	// Scheduled query jobs endpoints (require authentication)
+	mux.HandleFunc("GET /api/databases/jobs", corsMiddleware(authService.AuthMiddleware(databaseHandlers.ListJobs)))
+	mux.HandleFunc("POST /api/databases/jobs", corsMiddleware(authService.AuthMiddleware(databaseHandlers.CreateJob)))
+	mux.HandleFunc("PUT /api/databases/jobs/{id}", corsMiddleware(authService.AuthMiddleware(databaseHandlers.UpdateJob)))
+	mux.HandleFunc("DELETE /api/databases/jobs/{id}", corsMiddleware(authService.AuthMiddleware(databaseHandlers.DeleteJob)))
+	mux.HandleFunc("POST /api/databases/jobs/{id}/run", corsMiddleware(authService.AuthMiddleware(databaseHandlers.RunJob)))
+	mux.HandleFunc("GET /api/databases/jobs/{id}/runs", corsMiddleware(authService.AuthMiddleware(databaseHandlers.GetJobRuns)))
+
 	// Certificate management endpoints (require authentication)
 	mux.HandleFunc("GET /api/certificates", corsMiddleware(authService.AuthMiddleware(certificateHandlers.GetCertificates)))
 	mux.HandleFunc("GET /api/certificates/stats", corsMiddleware(authService.AuthMiddleware(certificateHandlers.GetCertificateStats)))
 	mux.HandleFunc("GET /api/certificates/alerts", corsMiddleware(authService.AuthMiddleware(certificateHandlers.GetAlerts)))
 	mux.HandleFunc("POST /api/certificates/alerts/acknowledge", corsMiddleware(authService.AuthMiddleware(certificateHandlers.AcknowledgeAlert)))
+	mux.HandleFunc("GET /api/certificates/history", corsMiddleware(authService.AuthMiddleware(certificateHandlers.GetCheckHistory)))
 	mux.HandleFunc("GET /api/certificates/check", corsMiddleware(authService.AuthMiddleware(certificateHandlers.CheckCertificate)))
 	mux.HandleFunc("POST /api/certificates/refresh", corsMiddleware(authService.AuthMiddleware(certificateHandlers.RefreshCertificates)))
 	mux.HandleFunc("GET /api/certificates/domains", corsMiddleware(authService.AuthMiddleware(certificateHandlers.GetDomainConfigs)))
@@ -277,33 +763,48 @@ func RegisterRoutes(
 
 	// Backup & Recovery management endpoints (require authentication)
 	mux.HandleFunc("GET /api/backup/jobs", corsMiddleware(authService.AuthMiddleware(backupHandlers.ListJobs)))
-	mux.HandleFunc("POST /api/backup/jobs", corsMiddleware(authService.AuthMiddleware(backupHandlers.CreateJob)))
+	// Job hooks run commands in the job's pods, so writing jobs needs an operator
+	mux.HandleFunc("POST /api/backup/jobs", corsMiddleware(authService.RequireRole("operator")(backupHandlers.CreateJob)))
 	mux.HandleFunc("GET /api/backup/history", corsMiddleware(authService.AuthMiddleware(backupHandlers.GetHistory)))
 	mux.HandleFunc("GET /api/backup/storage", corsMiddleware(authService.AuthMiddleware(backupHandlers.GetStorage)))
+	mux.HandleFunc("POST /api/backup/storage", corsMiddleware(authService.RequireRole("admin")(backupHandlers.AddStorage)))
+	mux.HandleFunc("GET /api/backup/storage/configs", corsMiddleware(authService.AuthMiddleware(backupHandlers.ListStorageConfigs)))
+	mux.HandleFunc("DELETE /api/backup/storage/{id}", corsMiddleware(authService.RequireRole("admin")(backupHandlers.RemoveStorage)))
 	mux.HandleFunc("GET /api/backup/statistics", corsMiddleware(authService.AuthMiddleware(backupHandlers.GetStatistics)))
 	mux.HandleFunc("GET /api/backup/recoveries/active", corsMiddleware(authService.AuthMiddleware(backupHandlers.GetActiveRecoveries)))
 	mux.HandleFunc("GET /api/backup/alerts", corsMiddleware(authService.AuthMiddleware(backupHandlers.GetAlerts)))
 
+	// PVC snapshot endpoints
+	mux.HandleFunc("GET /api/backup/snapshots/classes", corsMiddleware(authService.AuthMiddleware(backupHandlers.ListSnapshotClasses)))
+	mux.HandleFunc("GET /api/backup/snapshots", corsMiddleware(authService.AuthMiddleware(backupHandlers.ListSnapshots)))
+	mux.HandleFunc("POST /api/backup/snapshots", corsMiddleware(authService.RequireRole("operator")(backupHandlers.CreateSnapshot)))
+	mux.HandleFunc("DELETE /api/backup/snapshots/{namespace}/{name}", corsMiddleware(authService.RequireRole("operator")(backupHandlers.DeleteSnapshot)))
+	mux.HandleFunc("POST /api/backup/snapshots/{namespace}/{name}/restore", corsMiddleware(authService.RequireRole("operator")(backupHandlers.RestoreSnapshot)))
+
 	// Backup job operations
-	mux.Handle("/api/backup/jobs/", corsMiddleware(authService.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/api/backup/jobs/", corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
 		switch {
 		case strings.HasSuffix(path, "/run") && r.Method == "POST":
-			backupHandlers.RunJob(w, r)
+			authService.AuthMiddleware(backupHandlers.RunJob)(w, r)
 		case strings.HasSuffix(path, "/cancel") && r.Method == "POST":
-			backupHandlers.CancelJob(w, r)
+			authService.AuthMiddleware(backupHandlers.CancelJob)(w, r)
 		case strings.HasSuffix(path, "/schedule") && r.Method == "PUT":
-			backupHandlers.UpdateSchedule(w, r)
+			authService.AuthMiddleware(backupHandlers.UpdateSchedule)(w, r)
+		case strings.HasSuffix(path, "/retention") && r.Method == "GET":
+			authService.AuthMiddleware(backupHandlers.PlanRetention)(w, r)
+		case strings.HasSuffix(path, "/prune") && r.Method == "POST":
+			authService.AuthMiddleware(backupHandlers.PruneJob)(w, r)
 		case r.Method == "GET":
-			backupHandlers.GetJob(w, r)
+			authService.AuthMiddleware(backupHandlers.GetJob)(w, r)
 		case r.Method == "PUT":
-			backupHandlers.UpdateJob(w, r)
+			authService.RequireRole("operator")(backupHandlers.UpdateJob)(w, r)
 		case r.Method == "DELETE":
-			backupHandlers.DeleteJob(w, r)
+			authService.AuthMiddleware(backupHandlers.DeleteJob)(w, r)
 		default:
-			http.NotFound(w, r)
+			authService.AuthMiddleware(http.NotFound)(w, r)
 		}
-	}))))
+	})))
 
 	// Backup history operations
 	mux.Handle("/api/backup/history/", corsMiddleware(authService.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -313,6 +814,8 @@ func RegisterRoutes(
 			backupHandlers.VerifyBackup(w, r)
 		case strings.HasSuffix(path, "/recover") && r.Method == "POST":
 			backupHandlers.StartRecovery(w, r)
+		case strings.HasSuffix(path, "/preview") && r.Method == "POST":
+			backupHandlers.PreviewRestore(w, r)
 		case r.Method == "DELETE":
 			backupHandlers.DeleteBackup(w, r)
 		default:
@@ -328,12 +831,25 @@ func RegisterRoutes(
 	mux.HandleFunc("POST /api/gitops/applications", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.CreateApplication)))
 	mux.HandleFunc("POST /api/gitops/manifests/generate", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.GenerateManifest)))
 	mux.HandleFunc("POST /api/gitops/manifests/validate", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.ValidateManifest)))
+	mux.HandleFunc("POST /api/gitops/manifests/lint", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.LintManifest)))
+	mux.HandleFunc("POST /api/gitops/manifests/kustomize", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.GenerateKustomization)))
+	mux.HandleFunc("POST /api/gitops/manifests/render", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.RenderKustomization)))
+	mux.HandleFunc("GET /api/gitops/applications/{id}/environments", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.ListAppEnvironments)))
+	mux.HandleFunc("PUT /api/gitops/applications/{id}/environments/{env}", corsMiddleware(authService.RequireRole("operator")(gitopsHandlers.SetAppEnvironment)))
+	mux.HandleFunc("POST /api/gitops/applications/{id}/promote", corsMiddleware(authService.RequireRole("operator")(gitopsHandlers.PromoteApplication)))
+	mux.HandleFunc("DELETE /api/gitops/applications/{id}", corsMiddleware(authService.RequireRole("operator")(gitopsHandlers.DeleteApplication)))
+	mux.HandleFunc("GET /api/gitops/applications/archived", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.ListArchivedApplications)))
+	mux.HandleFunc("GET /api/gitops/applications/{id}/drift", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.GetApplicationDrift)))
 	mux.HandleFunc("GET /api/gitops/manifests/types", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.GetSupportedTypes)))
 	mux.HandleFunc("GET /api/gitops/sync/status", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.GetSyncStatus)))
 	mux.HandleFunc("POST /api/gitops/sync/start", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.StartSync)))
 	mux.HandleFunc("POST /api/gitops/sync/force", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.ForceSync)))
+	mux.HandleFunc("GET /api/gitops/sync/runs", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.ListSyncRuns)))
 	mux.HandleFunc("POST /api/gitops/webhook", corsMiddleware(gitopsHandlers.ProcessWebhook)) // No auth required for webhooks
 	mux.HandleFunc("GET /api/gitops/webhook/config", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.ConfigureWebhook)))
+	mux.HandleFunc("GET /api/gitops/repositories/{id}/credentials", corsMiddleware(authService.AuthMiddleware(gitopsHandlers.GetRepositoryCredential)))
+	mux.HandleFunc("PUT /api/gitops/repositories/{id}/credentials", corsMiddleware(authService.RequireRole("admin")(gitopsHandlers.SetRepositoryCredential)))
+	mux.HandleFunc("DELETE /api/gitops/repositories/{id}/credentials", corsMiddleware(authService.RequireRole("admin")(gitopsHandlers.DeleteRepositoryCredential)))
 
 	// Secrets endpoints (require authentication)
 	mux.HandleFunc("GET /api/secrets", corsMiddleware(authService.AuthMiddleware(secretsHandlers.GetSecrets)))
@@ -349,6 +865,23 @@ func RegisterRoutes(
 		}
 	}))))
 
+	// Gitea Actions (proxied with the server's GITEA_TOKEN)
+	mux.HandleFunc("GET /api/gitea/repositories/{owner}/{repo}/actions/runs/{run}/jobs", corsMiddleware(authService.AuthMiddleware(giteaHandler.ListRunJobs)))
+	mux.HandleFunc("GET /api/gitea/repositories/{owner}/{repo}/actions/jobs/{job}/logs", corsMiddleware(authService.AuthMiddleware(giteaHandler.GetJobLogs)))
+	mux.HandleFunc("POST /api/gitea/repositories/{owner}/{repo}/actions/runs/{run}/rerun", corsMiddleware(authService.RequireRole("operator")(giteaHandler.RerunWorkflow)))
+	mux.HandleFunc("POST /api/gitea/repositories/{owner}/{repo}/actions/runs/{run}/cancel", corsMiddleware(authService.RequireRole("operator")(giteaHandler.CancelWorkflow)))
+	mux.HandleFunc("POST /api/gitea/repositories/{owner}/{repo}/actions/workflows/{workflow}/build", corsMiddleware(authService.RequireRole("operator")(giteaHandler.StartBuild)))
+	mux.HandleFunc("GET /api/gitea/builds/{id}", corsMiddleware(authService.AuthMiddleware(giteaHandler.GetBuild)))
+
+	// Gitea pull request review
+	mux.HandleFunc("POST /api/gitea/repositories/{owner}/{repo}/pulls/{index}/comments", corsMiddleware(authService.RequireRole("operator")(giteaHandler.CommentOnPull)))
+	mux.HandleFunc("POST /api/gitea/repositories/{owner}/{repo}/pulls/{index}/reviews", corsMiddleware(authService.RequireRole("operator")(giteaHandler.ReviewPull)))
+	mux.HandleFunc("POST /api/gitea/repositories/{owner}/{repo}/pulls/{index}/merge", corsMiddleware(authService.RequireRole("operator")(giteaHandler.MergePull)))
+
+	// Gitea repository provisioning and its webhook receiver
+	mux.HandleFunc("POST /api/gitea/repositories/provision", corsMiddleware(authService.RequireRole("operator")(giteaHandler.ProvisionRepository)))
+	mux.HandleFunc("POST /api/gitea/webhook", corsMiddleware(gitopsHandlers.ProcessWebhook)) // No auth required for webhooks
+
 	// GitOps operations with path parameters
 	mux.Handle("/api/gitops/repositories/", corsMiddleware(authService.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasSuffix(r.URL.Path, "/sync") && r.Method == "POST" {
@@ -387,4 +920,9 @@ func RegisterRoutes(
 	// WebSocket endpoint for real-time updates
 	wsHandler := websocket.NewHandler(wsHub)
 	mux.HandleFunc("GET /ws", wsHandler.HandleWebSocket)
+	mux.Handle("GET /api/ws/snapshot/{channel}", corsMiddleware(authService.AuthMiddleware(http.HandlerFunc(wsHandler.HandleSnapshot))))
+
+	// OpenAPI document of the routes above, public so client generators can fetch it
+	openAPIHandlers := NewOpenAPIHandlers(mux)
+	mux.HandleFunc("GET /api/openapi.json", corsMiddleware(openAPIHandlers.GetSpec))
 }
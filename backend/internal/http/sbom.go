@@ -0,0 +1,223 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/database"
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/internal/sbom"
+	"github.com/archellir/denshimon/pkg/response"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type SBOMHandlers struct {
+	service *sbom.Service
+}
+
+func NewSBOMHandlers(service *sbom.Service) *SBOMHandlers {
+	return &SBOMHandlers{service: service}
+}
+
+// ListSBOMs handles GET /api/deployments/sboms?running=true, newest first with the pods
+// running each image
+func (h *SBOMHandlers) ListSBOMs(w http.ResponseWriter, r *http.Request) {
+	sboms, err := h.service.List(r.Context(), r.URL.Query().Get("running") == "true")
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to list SBOMs: "+err.Error())
+		return
+	}
+	response.SendSuccess(w, sboms)
+}
+
+// GetSBOM handles GET /api/deployments/sboms/{id}; with ?format=syft-json it downloads the
+// document syft produced
+func (h *SBOMHandlers) GetSBOM(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid SBOM ID")
+		return
+	}
+	if format := r.URL.Query().Get("format"); format == "syft-json" {
+		document, err := h.service.Store().Document(r.Context(), id)
+		if err != nil {
+			sendSBOMError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="sbom-`+r.PathValue("id")+`.syft.json"`)
+		w.Write(document)
+		return
+	} else if format != "" {
+		response.SendError(w, http.StatusBadRequest, "Unsupported format: "+format)
+		return
+	}
+
+	result, err := h.service.Store().Get(r.Context(), id)
+	if err != nil {
+		sendSBOMError(w, err)
+		return
+	}
+	response.SendSuccess(w, result)
+}
+
+type generateSBOMRequest struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest,omitempty"`
+}
+
+// GenerateSBOM handles POST /api/deployments/sboms with {"image": "...", "digest": "sha256:..."},
+// generating the image's SBOM in the background
+func (h *SBOMHandlers) GenerateSBOM(w http.ResponseWriter, r *http.Request) {
+	var req generateSBOMRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Image = strings.TrimSpace(req.Image)
+	if req.Image == "" || strings.HasPrefix(req.Image, "-") {
+		response.SendError(w, http.StatusBadRequest, "image is required")
+		return
+	}
+	h.startScan(w, req.Image, req.Digest, "SBOM generation started")
+}
+
+// ScanRunningImages handles POST /api/deployments/sboms/scan, generating SBOMs in the
+// background for the running images that have none
+func (h *SBOMHandlers) ScanRunningImages(w http.ResponseWriter, r *http.Request) {
+	h.startScan(w, "", "", "SBOM scan started")
+}
+
+func (h *SBOMHandlers) startScan(w http.ResponseWriter, image, digest, message string) {
+	status, err := h.service.StartScan(image, digest)
+	if err != nil {
+		sendSBOMError(w, err)
+		return
+	}
+	response.SendJSON(w, http.StatusAccepted, response.APIResponse{
+		Success: true,
+		Data:    status,
+		Message: message,
+	})
+}
+
+// GetScanStatus handles GET /api/deployments/sboms/scan, the running or last scan or generation
+func (h *SBOMHandlers) GetScanStatus(w http.ResponseWriter, r *http.Request) {
+	status := h.service.LastScan()
+	if status == nil {
+		response.SendError(w, http.StatusNotFound, "No SBOM scan recorded")
+		return
+	}
+	response.SendSuccess(w, status)
+}
+
+// DeleteSBOM handles DELETE /api/deployments/sboms/{id}
+func (h *SBOMHandlers) DeleteSBOM(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid SBOM ID")
+		return
+	}
+	if err := h.service.Store().Delete(r.Context(), id); err != nil {
+		sendSBOMError(w, err)
+		return
+	}
+	response.SendSuccessWithMessage(w, "SBOM deleted")
+}
+
+// SearchPackages handles GET /api/deployments/sboms/packages?name=log4j-core&version=2.14.*&type=&running=true&limit=,
+// listing the images containing matching packages
+func (h *SBOMHandlers) SearchPackages(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := sbom.PackageQuery{
+		Name:    params.Get("name"),
+		Version: params.Get("version"),
+		Type:    params.Get("type"),
+	}
+	if query.Name == "" {
+		response.SendError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			response.SendError(w, http.StatusBadRequest, "Invalid limit: "+value)
+			return
+		}
+		query.Limit = limit
+	}
+	matches, err := h.service.Search(r.Context(), query, params.Get("running") == "true")
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to search packages: "+err.Error())
+		return
+	}
+	response.SendSuccess(w, matches)
+}
+
+// ListLicenses handles GET /api/deployments/sboms/licenses?running=true, the licenses of
+// the packages in stored SBOMs by the number of images using them
+func (h *SBOMHandlers) ListLicenses(w http.ResponseWriter, r *http.Request) {
+	licenses, err := h.service.Licenses(r.Context(), r.URL.Query().Get("running") == "true")
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, "Failed to summarize licenses: "+err.Error())
+		return
+	}
+	response.SendSuccess(w, licenses)
+}
+
+func sendSBOMError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sbom.ErrNotFound):
+		response.SendError(w, http.StatusNotFound, "SBOM not found")
+	case errors.Is(err, sbom.ErrScanInProgress):
+		response.SendError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, sbom.ErrUnavailable):
+		response.SendError(w, http.StatusServiceUnavailable, "SBOM generation unavailable: install syft or set SYFT_BINARY")
+	default:
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// initSBOMs opens the SBOM store and, when syft is installed and SBOM_SCAN_INTERVAL
+// (default 24h) is not 0, scans the running images on that interval
+func initSBOMs(db *database.SQLiteDB, k8sClient *k8s.Client) *SBOMHandlers {
+	store, err := sbom.NewStore(db.DB)
+	if err != nil {
+		slog.Warn("Image SBOMs disabled", "error", err)
+		return nil
+	}
+	service := sbom.NewService(store)
+	if k8sClient != nil {
+		service.SetPodLister(func(ctx context.Context) ([]corev1.Pod, error) {
+			pods, err := k8sClient.ListPods(ctx, "")
+			if err != nil {
+				return nil, err
+			}
+			return pods.Items, nil
+		})
+		if interval := sbomScanInterval(); interval > 0 && service.Available() {
+			service.Start(context.Background(), interval)
+		}
+	}
+	return NewSBOMHandlers(service)
+}
+
+func sbomScanInterval() time.Duration {
+	value := os.Getenv("SBOM_SCAN_INTERVAL")
+	if value == "" {
+		return 24 * time.Hour
+	}
+	interval, err := parseTimeRange(value)
+	if err != nil && value != "0" {
+		slog.Warn("Invalid SBOM_SCAN_INTERVAL, scheduled SBOM scans disabled", "value", value, "error", err)
+		return 0
+	}
+	return interval
+}
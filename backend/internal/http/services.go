@@ -1,17 +1,27 @@
 package http
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/internal/prometheus"
+	"github.com/archellir/denshimon/internal/traces"
 	"github.com/archellir/denshimon/pkg/response"
 )
 
 type ServicesHandlers struct {
 	k8sClient *k8s.Client
+
+	mutex             sync.RWMutex // traffic sources are replaced when the config file is reloaded
+	tracesClient      *traces.Client
+	prometheusService *prometheus.Service
 }
 
 func NewServicesHandlers(k8sClient *k8s.Client) *ServicesHandlers {
@@ -119,6 +129,7 @@ type ServiceMeshMetrics struct {
 		HighErrorRate       []ServiceNode       `json:"highErrorRate"`
 		HighLatency         []ServiceNode       `json:"highLatency"`
 		SecurityIssues      []ServiceConnection `json:"securityIssues"`
+		UnprotectedServices []ServiceNode       `json:"unprotectedServices"`
 	} `json:"alerts"`
 	Trends struct {
 		RequestRateTrend string `json:"requestRateTrend"`
@@ -127,6 +138,27 @@ type ServiceMeshMetrics struct {
 	} `json:"trends"`
 }
 
+// SetTracesClient enables latency figures derived from distributed traces
+func (h *ServicesHandlers) SetTracesClient(client *traces.Client) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.tracesClient = client
+}
+
+// SetPrometheusService enables service and connection metrics from Istio, Linkerd or ingress-nginx traffic
+func (h *ServicesHandlers) SetPrometheusService(service *prometheus.Service) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.prometheusService = service
+}
+
+// trafficSources returns the current tracing client and Prometheus service, either may be nil
+func (h *ServicesHandlers) trafficSources() (*traces.Client, *prometheus.Service) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.tracesClient, h.prometheusService
+}
+
 // Service classification constants
 const (
 	InfraServiceTypeLabel    = "infra/service-type"
@@ -261,11 +293,21 @@ func (h *ServicesHandlers) GetServiceMesh(w http.ResponseWriter, r *http.Request
 	// Convert Kubernetes services to ServiceNodes with label-based types
 	services := h.convertK8sServicesToNodes(servicesList.Items)
 	
-	// Generate connections based on service relationships (simplified)
-	connections := h.generateServiceConnections(services)
+	// Derive metrics and connections from observed traffic when Prometheus has mesh metrics,
+	// otherwise generate connections based on service relationships (simplified)
+	connections, ok := h.meshTrafficConnections(ctx, services)
+	if !ok {
+		connections = h.generateServiceConnections(services)
+
+		// Replace latency figures with values observed in traces
+		if tracesClient, _ := h.trafficSources(); tracesClient != nil {
+			h.applyTraceLatency(ctx, tracesClient, services, connections)
+		}
+	}
 	
 	// Calculate metrics from real services
 	metrics := h.calculateServiceMeshMetrics(services, connections)
+	metrics.Alerts.UnprotectedServices = h.getServicesWithoutNetworkPolicy(ctx, servicesList.Items, services)
 	
 	// Build service mesh data response
 	meshData := ServiceMeshData{
@@ -278,6 +320,120 @@ func (h *ServicesHandlers) GetServiceMesh(w http.ResponseWriter, r *http.Request
 	response.SendSuccess(w, meshData)
 }
 
+// meshTrafficConnections fills service metrics from the last 5 minutes of mesh traffic and returns
+// one connection per observed source/target pair. It reports false when no traffic metrics are available.
+func (h *ServicesHandlers) meshTrafficConnections(ctx context.Context, services []ServiceNode) ([]ServiceConnection, bool) {
+	_, prometheusService := h.trafficSources()
+	if prometheusService == nil {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	traffic, err := prometheusService.GetMeshTraffic(ctx, 5*time.Minute)
+	if err != nil {
+		if !errors.Is(err, prometheus.ErrNoMeshMetrics) {
+			slog.Debug("Mesh traffic metrics unavailable", "error", err)
+		}
+		return nil, false
+	}
+
+	// Match by namespace and name, falling back to name alone when the namespace is unknown
+	byKey := make(map[string]int, len(services))
+	byName := make(map[string]int, len(services))
+	for i, svc := range services {
+		byKey[svc.Namespace+"/"+svc.Name] = i
+		byName[svc.Name] = i
+	}
+	lookup := func(name, namespace string) (int, bool) {
+		if i, ok := byKey[namespace+"/"+name]; ok {
+			return i, true
+		}
+		i, ok := byName[name]
+		return i, ok && namespace == ""
+	}
+
+	for _, observed := range traffic.Services {
+		i, ok := lookup(observed.Service, observed.Namespace)
+		if !ok {
+			continue
+		}
+		metrics := &services[i].Metrics
+		metrics.RequestRate = observed.RequestRate
+		metrics.ErrorRate = observed.ErrorRate
+		metrics.SuccessRate = 100 - observed.ErrorRate
+		metrics.Latency.P50 = observed.P50
+		metrics.Latency.P95 = observed.P95
+		metrics.Latency.P99 = observed.P99
+	}
+
+	loadBalancing := map[prometheus.MeshSource]string{
+		prometheus.MeshSourceIstio:        "least_request",
+		prometheus.MeshSourceLinkerd:      "ewma",
+		prometheus.MeshSourceNginxIngress: "round_robin",
+	}[traffic.Source]
+
+	connections := []ServiceConnection{}
+	for _, edge := range traffic.Edges {
+		source, ok := lookup(edge.Source, edge.SourceNamespace)
+		if !ok {
+			continue
+		}
+		target, ok := lookup(edge.Target, edge.TargetNamespace)
+		if !ok {
+			continue
+		}
+
+		var conn ServiceConnection
+		conn.ID = services[source].ID + "-" + services[target].ID
+		conn.Source = services[source].ID
+		conn.Target = services[target].ID
+		conn.Protocol = edge.Protocol
+		conn.Metrics.RequestRate = edge.RequestRate
+		conn.Metrics.ErrorRate = edge.ErrorRate
+		conn.Metrics.Latency = edge.LatencyMs
+		conn.Security.MTLS = edge.MTLS
+		conn.Security.Encrypted = edge.MTLS
+		conn.LoadBalancing = loadBalancing
+		connections = append(connections, conn)
+	}
+	return connections, true
+}
+
+// applyTraceLatency sets service latency percentiles from the last 15 minutes of traces
+// and each connection's latency to its target's median. Services without traces keep zero values.
+func (h *ServicesHandlers) applyTraceLatency(ctx context.Context, tracesClient *traces.Client, services []ServiceNode, connections []ServiceConnection) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	latencies := make([]traces.Latency, len(services))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for i := range services {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if latency, err := tracesClient.ServiceLatency(ctx, services[i].Name, 15*time.Minute); err == nil {
+				latencies[i] = latency
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	medians := make(map[string]float64, len(services))
+	for i := range services {
+		services[i].Metrics.Latency.P50 = latencies[i].P50
+		services[i].Metrics.Latency.P95 = latencies[i].P95
+		services[i].Metrics.Latency.P99 = latencies[i].P99
+		medians[services[i].ID] = latencies[i].P50
+	}
+	for i := range connections {
+		connections[i].Metrics.Latency = medians[connections[i].Target]
+	}
+}
+
 // generateServiceConnections creates connections between services based on common patterns
 func (h *ServicesHandlers) generateServiceConnections(services []ServiceNode) []ServiceConnection {
 	var connections []ServiceConnection
@@ -422,11 +578,13 @@ func (h *ServicesHandlers) calculateServiceMeshMetrics(services []ServiceNode, c
 			HighErrorRate       []ServiceNode       `json:"highErrorRate"`
 			HighLatency         []ServiceNode       `json:"highLatency"`
 			SecurityIssues      []ServiceConnection `json:"securityIssues"`
+			UnprotectedServices []ServiceNode       `json:"unprotectedServices"`
 		}{
 			CircuitBreakersOpen: h.getServicesWithOpenCircuitBreakers(services),
 			HighErrorRate:       h.getServicesWithHighErrorRate(services),
 			HighLatency:         h.getServicesWithHighLatency(services),
 			SecurityIssues:      h.getConnectionsWithSecurityIssues(connections),
+			UnprotectedServices: []ServiceNode{},
 		},
 		Trends: struct {
 			RequestRateTrend string `json:"requestRateTrend"`
@@ -509,6 +667,28 @@ func (h *ServicesHandlers) getServicesWithHighLatency(services []ServiceNode) []
 	return result
 }
 
+// getServicesWithoutNetworkPolicy returns the services whose pods no NetworkPolicy selects
+func (h *ServicesHandlers) getServicesWithoutNetworkPolicy(ctx context.Context, k8sServices []corev1.Service, services []ServiceNode) []ServiceNode {
+	policies, err := h.k8sClient.ListNetworkPolicies(ctx, "")
+	if err != nil {
+		slog.Debug("Failed to list network policies", "error", err)
+		return []ServiceNode{}
+	}
+
+	uncovered := make(map[string]bool)
+	for _, svc := range k8s.ServicesWithoutNetworkPolicy(k8sServices, policies.Items) {
+		uncovered[svc.Namespace+"/"+svc.Name] = true
+	}
+
+	result := []ServiceNode{}
+	for _, service := range services {
+		if uncovered[service.Namespace+"/"+service.Name] {
+			result = append(result, service)
+		}
+	}
+	return result
+}
+
 func (h *ServicesHandlers) getConnectionsWithSecurityIssues(connections []ServiceConnection) []ServiceConnection {
 	var result []ServiceConnection
 	for _, conn := range connections {
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/alerts"
+	"github.com/archellir/denshimon/internal/database"
+	"github.com/archellir/denshimon/internal/metrics"
+	"github.com/archellir/denshimon/internal/websocket"
+)
+
+// Defaults for days-until-full alerts on node filesystems and claims
+const (
+	defaultStorageFullWarningDays  = 7
+	defaultStorageFullCriticalDays = 2
+)
+
+// initStorageTrends samples node filesystem and claim usage for growth projections, raising
+// infrastructure alerts for filesystems projected to fill up. STORAGE_SAMPLING_ENABLED=false
+// turns it off.
+func initStorageTrends(db *database.SQLiteDB, metricsService *metrics.Service, k8sHandlers *KubernetesHandlers,
+	infrastructureHandlers *InfrastructureHandlers, hub *websocket.Hub, alertManager *alerts.Manager) {
+	if os.Getenv("STORAGE_SAMPLING_ENABLED") == "false" {
+		return
+	}
+
+	store, err := metrics.NewUsageStore(db.DB)
+	if err != nil {
+		slog.Warn("Storage usage sampling disabled", "error", err)
+		return
+	}
+	metricsService.SetStorageStore(store)
+	k8sHandlers.SetMetricsService(metricsService)
+
+	interval := metrics.DefaultStorageSampleInterval
+	if value := os.Getenv("STORAGE_SAMPLE_INTERVAL"); value != "" {
+		if d, err := parseTimeRange(value); err == nil {
+			interval = d
+		}
+	}
+	window := metrics.DefaultStorageTrendWindow
+	if value := os.Getenv("STORAGE_TREND_WINDOW"); value != "" {
+		if d, err := parseTimeRange(value); err == nil {
+			window = d
+		}
+	}
+	warningDays := envDays("STORAGE_FULL_WARNING_DAYS", defaultStorageFullWarningDays)
+	criticalDays := envDays("STORAGE_FULL_CRITICAL_DAYS", defaultStorageFullCriticalDays)
+
+	metricsService.OnStorageTrends(storageAlerts(infrastructureHandlers, hub, alertManager, warningDays, criticalDays))
+	metricsService.StartStorageSampling(context.Background(), interval, window)
+}
+
+func envDays(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	days, err := strconv.ParseFloat(value, 64)
+	if err != nil || days < 0 {
+		slog.Warn("Ignoring invalid number of days", "variable", key, "value", value)
+		return fallback
+	}
+	return days
+}
+
+// storageAlerts raises an alert for every filesystem projected to be full within warningDays,
+// critical within criticalDays, and clears it once the projection moves out again. Alerts are
+// raised again only when their severity changes, and broadcast unless silenced.
+func storageAlerts(infrastructureHandlers *InfrastructureHandlers, hub *websocket.Hub, alertManager *alerts.Manager,
+	warningDays, criticalDays float64) func(context.Context, []metrics.StorageTrend) {
+	var mu sync.Mutex
+	raised := make(map[string]string) // alert ID to severity
+
+	return func(ctx context.Context, trends []metrics.StorageTrend) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		filling := make(map[string]bool)
+		for _, trend := range trends {
+			if !trend.Filling(warningDays) {
+				continue
+			}
+			severity := "warning"
+			if trend.Filling(criticalDays) {
+				severity = "critical"
+			}
+			alertID := "storage-" + trend.Kind + "-" + trend.Name
+			filling[alertID] = true
+
+			subject := "Node " + trend.Name + " filesystem"
+			if trend.Kind == metrics.StorageKindVolume {
+				subject = "Volume claim " + trend.Name
+			}
+			alert := &InfrastructureAlert{
+				ID:       alertID,
+				Type:     "storage",
+				Severity: severity,
+				Message: fmt.Sprintf("%s is projected to be full in %.1f days (%.0f%% used, growing %.2f GiB/day)",
+					subject, trend.DaysUntilFull, float64(trend.UsedBytes)/float64(trend.CapacityBytes)*100,
+					trend.GrowthBytesPerDay/(1<<30)),
+				Timestamp: time.Now(),
+				Source:    "storage",
+			}
+			if raised[alertID] == severity {
+				continue // already raised; replacing it would drop its acknowledgement
+			}
+			raised[alertID] = severity
+			infrastructureHandlers.RaiseAlert(alert)
+
+			if alertManager != nil && alertManager.SilencedBy(ctx, infrastructureAlert(*alert)) != "" {
+				continue
+			}
+			if hub != nil {
+				hub.Broadcast(websocket.MessageTypeAlerts, alert)
+			}
+		}
+
+		for alertID := range raised {
+			if !filling[alertID] {
+				infrastructureHandlers.ClearAlert(alertID)
+				delete(raised, alertID)
+			}
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/pkg/response"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// ListStorageClasses handles GET /api/k8s/storageclasses
+func (h *KubernetesHandlers) ListStorageClasses(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	classes, err := h.k8sClient.ListStorageClasses(r.Context())
+	if err != nil {
+		sendStorageError(w, err)
+		return
+	}
+
+	response.SendSuccess(w, classes)
+}
+
+// CreateStorageClass handles POST /api/k8s/storageclasses with a StorageClass as JSON or YAML
+func (h *KubernetesHandlers) CreateStorageClass(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxManifestSize))
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var class storagev1.StorageClass
+	if err := yaml.UnmarshalStrict(data, &class); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid storage class: "+err.Error())
+		return
+	}
+	if class.Kind != "" && class.Kind != "StorageClass" {
+		response.SendError(w, http.StatusBadRequest, "Expected kind StorageClass")
+		return
+	}
+
+	created, err := h.k8sClient.CreateStorageClass(r.Context(), &class)
+	if err != nil {
+		sendStorageError(w, err)
+		return
+	}
+
+	response.SendJSON(w, http.StatusCreated, response.APIResponse{
+		Success: true,
+		Data:    created,
+		Message: "Storage class created",
+	})
+}
+
+// DeleteStorageClass handles DELETE /api/k8s/storageclasses/{name}
+func (h *KubernetesHandlers) DeleteStorageClass(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	if err := h.k8sClient.DeleteStorageClass(r.Context(), r.PathValue("name")); err != nil {
+		sendStorageError(w, err)
+		return
+	}
+
+	response.SendSuccessWithMessage(w, "Storage class deleted")
+}
+
+// SetDefaultStorageClass handles POST /api/k8s/storageclasses/{name}/default, unmarking the
+// previous default
+func (h *KubernetesHandlers) SetDefaultStorageClass(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	class, err := h.k8sClient.SetDefaultStorageClass(r.Context(), r.PathValue("name"))
+	if err != nil {
+		sendStorageError(w, err)
+		return
+	}
+
+	response.SendSuccess(w, class)
+}
+
+// expandVolumeRequest is the body of a claim expansion
+type expandVolumeRequest struct {
+	Size string `json:"size"` // e.g. 20Gi
+}
+
+// ExpandVolumeClaim handles POST /api/k8s/pvcs/{name}/expand?namespace=default, returning the
+// resize progress
+func (h *KubernetesHandlers) ExpandVolumeClaim(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	var req expandVolumeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	size, err := resource.ParseQuantity(req.Size)
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid size: "+err.Error())
+		return
+	}
+
+	resize, err := h.k8sClient.ExpandPersistentVolumeClaim(r.Context(), queryNamespace(r), r.PathValue("name"), size)
+	if err != nil {
+		sendStorageError(w, err)
+		return
+	}
+
+	response.SendSuccess(w, resize)
+}
+
+// GetVolumeResize handles GET /api/k8s/pvcs/{name}/resize?namespace=default
+func (h *KubernetesHandlers) GetVolumeResize(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	resize, err := h.k8sClient.GetVolumeResize(r.Context(), queryNamespace(r), r.PathValue("name"))
+	if err != nil {
+		sendStorageError(w, err)
+		return
+	}
+
+	response.SendSuccess(w, resize)
+}
+
+func sendStorageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, k8s.ErrInvalidStorageClass), errors.Is(err, k8s.ErrInvalidExpansion),
+		apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		response.SendError(w, http.StatusBadRequest, err.Error())
+	case apierrors.IsNotFound(err):
+		response.SendError(w, http.StatusNotFound, err.Error())
+	case apierrors.IsAlreadyExists(err), apierrors.IsConflict(err):
+		response.SendError(w, http.StatusConflict, err.Error())
+	case apierrors.IsForbidden(err):
+		response.SendError(w, http.StatusForbidden, err.Error())
+	default:
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/archellir/denshimon/internal/timeline"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+type TimelineHandlers struct {
+	engine *timeline.Engine
+}
+
+func NewTimelineHandlers(engine *timeline.Engine) *TimelineHandlers {
+	return &TimelineHandlers{engine: engine}
+}
+
+// GetTimeline handles GET /api/timeline?app=&namespace=&since=&until=&limit=&window=, merging
+// Kubernetes events, deployment history, GitOps deployments and alerts oldest first.
+// since and until take a duration ago (1h, 7d) or an RFC3339 time; window is how far
+// back warnings are linked to preceding changes.
+func (h *TimelineHandlers) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := timeline.Query{
+		App:       params.Get("app"),
+		Namespace: params.Get("namespace"),
+	}
+
+	var err error
+	if value := params.Get("since"); value != "" {
+		if query.Since, err = parseTimelineTime(value); err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid since: "+value)
+			return
+		}
+	}
+	if value := params.Get("until"); value != "" {
+		if query.Until, err = parseTimelineTime(value); err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid until: "+value)
+			return
+		}
+	}
+	if !query.Since.IsZero() && !query.Until.IsZero() && !query.Since.Before(query.Until) {
+		response.SendError(w, http.StatusBadRequest, "since must be before until")
+		return
+	}
+	if value := params.Get("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit <= 0 {
+			response.SendError(w, http.StatusBadRequest, "Invalid limit: "+value)
+			return
+		}
+	}
+	if value := params.Get("window"); value != "" {
+		if query.CorrelationWindow, err = parseTimeRange(value); err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid window: "+value)
+			return
+		}
+	}
+
+	response.SendSuccess(w, h.engine.Build(listContext(r), query))
+}
+
+// parseTimelineTime accepts an RFC3339 time or a duration before now
+func parseTimelineTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := parseTimeRange(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(-d), nil
+}
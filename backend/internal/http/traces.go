@@ -0,0 +1,145 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/traces"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+type TracesHandlers struct {
+	mutex  sync.RWMutex
+	client *traces.Client
+}
+
+func NewTracesHandlers(client *traces.Client) *TracesHandlers {
+	return &TracesHandlers{
+		client: client,
+	}
+}
+
+// SetClient replaces the tracing client, e.g. when the config file is reloaded; nil disables trace viewing
+func (h *TracesHandlers) SetClient(client *traces.Client) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.client = client
+}
+
+func (h *TracesHandlers) currentClient() *traces.Client {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.client
+}
+
+// SearchTraces handles GET /api/traces.
+// Filters: service, operation, minDuration and maxDuration (e.g. 250ms),
+// start/end (RFC3339) or lookback (default 1h), and limit.
+func (h *TracesHandlers) SearchTraces(w http.ResponseWriter, r *http.Request) {
+	client := h.currentClient()
+	if client == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Tracing backend not configured")
+		return
+	}
+
+	params := r.URL.Query()
+	query := traces.SearchQuery{
+		Service:   params.Get("service"),
+		Operation: params.Get("operation"),
+	}
+
+	var err error
+	if value := params.Get("minDuration"); value != "" {
+		if query.MinDuration, err = time.ParseDuration(value); err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid minDuration")
+			return
+		}
+	}
+	if value := params.Get("maxDuration"); value != "" {
+		if query.MaxDuration, err = time.ParseDuration(value); err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid maxDuration")
+			return
+		}
+	}
+	if value := params.Get("end"); value != "" {
+		if query.End, err = time.Parse(time.RFC3339, value); err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid end")
+			return
+		}
+	}
+	if value := params.Get("start"); value != "" {
+		if query.Start, err = time.Parse(time.RFC3339, value); err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid start")
+			return
+		}
+	} else if value := params.Get("lookback"); value != "" {
+		lookback, err := parseTimeRange(value)
+		if err != nil {
+			response.SendError(w, http.StatusBadRequest, "Invalid lookback")
+			return
+		}
+		query.Start = time.Now().Add(-lookback)
+	}
+	if limit, err := strconv.Atoi(params.Get("limit")); err == nil && limit > 0 && limit <= 500 {
+		query.Limit = limit
+	}
+
+	results, err := client.Search(r.Context(), query)
+	if err != nil {
+		response.SendError(w, http.StatusBadGateway, "Failed to search traces: "+err.Error())
+		return
+	}
+
+	response.SendSuccess(w, map[string]interface{}{
+		"traces":    results,
+		"total":     len(results),
+		"provider":  client.Provider(),
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// GetTrace handles GET /api/traces/{id}, returning spans in waterfall order
+func (h *TracesHandlers) GetTrace(w http.ResponseWriter, r *http.Request) {
+	client := h.currentClient()
+	if client == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Tracing backend not configured")
+		return
+	}
+
+	trace, err := client.GetTrace(r.Context(), r.PathValue("id"))
+	if errors.Is(err, traces.ErrTraceNotFound) {
+		response.SendError(w, http.StatusNotFound, "Trace not found")
+		return
+	}
+	if err != nil {
+		response.SendError(w, http.StatusBadGateway, "Failed to get trace: "+err.Error())
+		return
+	}
+
+	response.SendSuccess(w, trace)
+}
+
+// initTracesClient creates a tracing client from TRACING_PROVIDER (tempo or jaeger, default tempo)
+// and TRACING_URL. It returns nil when no tracing backend is configured.
+func initTracesClient() *traces.Client {
+	tracingURL := os.Getenv("TRACING_URL")
+	if tracingURL == "" {
+		return nil
+	}
+	provider := traces.Provider(os.Getenv("TRACING_PROVIDER"))
+	if provider == "" {
+		provider = traces.ProviderTempo
+	}
+
+	client, err := traces.NewClient(provider, tracingURL)
+	if err != nil {
+		slog.Warn("Trace viewing disabled", "error", err)
+		return nil
+	}
+	return client
+}
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/alerts"
+	"github.com/archellir/denshimon/internal/gitops"
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/internal/upgrades"
+	"github.com/archellir/denshimon/internal/websocket"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+type UpgradeHandlers struct {
+	service *upgrades.Service
+}
+
+func NewUpgradeHandlers(service *upgrades.Service) *UpgradeHandlers {
+	return &UpgradeHandlers{service: service}
+}
+
+// CheckUpgrades handles GET /api/k8s/upgrades?target=1.31, comparing Kubernetes and addon
+// versions against their latest releases and listing the stored manifests using APIs the
+// target removes (by default the next minor release)
+func (h *UpgradeHandlers) CheckUpgrades(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.Check(r.Context(), r.URL.Query().Get("target"))
+	if err != nil {
+		sendUpgradeError(w, "Failed to check upgrades: ", err)
+		return
+	}
+	response.SendSuccess(w, report)
+}
+
+// ScanDeprecatedAPIs handles GET /api/k8s/deprecated-apis?target=1.31, listing the GitOps
+// manifests and live objects using API versions removed by the target or later releases
+func (h *UpgradeHandlers) ScanDeprecatedAPIs(w http.ResponseWriter, r *http.Request) {
+	scan, err := h.service.ScanDeprecatedAPIs(r.Context(), r.URL.Query().Get("target"))
+	if err != nil {
+		sendUpgradeError(w, "Failed to scan for deprecated APIs: ", err)
+		return
+	}
+	response.SendSuccess(w, scan)
+}
+
+func sendUpgradeError(w http.ResponseWriter, message string, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, upgrades.ErrInvalidTarget) {
+		status = http.StatusBadRequest
+	}
+	response.SendError(w, status, message+err.Error())
+}
+
+// initUpgrades checks the manifests of the GitOps repository and, unless
+// UPGRADE_RELEASE_CHECK=false for air-gapped installs, looks up latest releases on GitHub,
+// with GITHUB_TOKEN when set. Every DEPRECATED_API_SCAN_INTERVAL (default 24h, 0 turns it
+// off) manifests and live objects are scanned for APIs the next release removes.
+func initUpgrades(k8sClient *k8s.Client, gitopsService *gitops.Service, infrastructureHandlers *InfrastructureHandlers,
+	hub *websocket.Hub, alertManager *alerts.Manager) *UpgradeHandlers {
+	if k8sClient == nil {
+		return nil
+	}
+	var releases *upgrades.Releases
+	if os.Getenv("UPGRADE_RELEASE_CHECK") != "false" {
+		releases = upgrades.NewReleases(os.Getenv("UPGRADE_RELEASES_URL"), os.Getenv("GITHUB_TOKEN"))
+	}
+	service := upgrades.NewService(k8sClient, releases)
+	if gitopsService != nil {
+		service.SetManifestSource(gitopsService.StoredManifests)
+	}
+
+	interval := upgrades.DefaultScanInterval
+	if value := os.Getenv("DEPRECATED_API_SCAN_INTERVAL"); value == "0" {
+		interval = 0
+	} else if value != "" {
+		if d, err := parseTimeRange(value); err == nil {
+			interval = d
+		} else {
+			slog.Warn("Invalid DEPRECATED_API_SCAN_INTERVAL, using the default", "value", value, "error", err)
+		}
+	}
+	if interval > 0 {
+		service.OnScan(deprecatedAPIAlerts(infrastructureHandlers, hub, alertManager))
+		service.Start(context.Background(), interval)
+	}
+	return NewUpgradeHandlers(service)
+}
+
+// deprecatedAPIAlerts raises an alert for every manifest or live object that upgrading to the
+// next release breaks, and for every manifest that can't be applied today, clearing it once
+// the API is no longer used. Like storage alerts, they're raised again only when their
+// severity changes, and broadcast unless silenced.
+func deprecatedAPIAlerts(infrastructureHandlers *InfrastructureHandlers, hub *websocket.Hub,
+	alertManager *alerts.Manager) func(context.Context, *upgrades.DeprecationScan) {
+	var mu sync.Mutex
+	raised := make(map[string]string) // alert ID to severity
+
+	return func(ctx context.Context, scan *upgrades.DeprecationScan) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		used := make(map[string]bool)
+		for _, api := range scan.APIs {
+			var severity, alertID, message string
+			switch {
+			case api.Status == upgrades.StatusBreaks && api.Source == upgrades.SourceLive:
+				severity = "warning"
+				alertID = fmt.Sprintf("deprecated-api-%s-%s/%s-%s", api.Kind, api.Namespace, api.Name, api.Manager)
+				message = fmt.Sprintf("%s %s is written as %s by %s, which Kubernetes %s removes; use %s",
+					api.Kind, api.Name, api.APIVersion, api.Manager, api.RemovedIn, api.Replacement)
+			case api.Status == upgrades.StatusBreaks:
+				severity = "warning"
+				alertID = fmt.Sprintf("deprecated-api-%s-%s-%s", api.File, api.Kind, api.Name)
+				message = fmt.Sprintf("%s %s in %s uses %s, which Kubernetes %s removes; use %s",
+					api.Kind, api.Name, api.File, api.APIVersion, api.RemovedIn, api.Replacement)
+			case api.Status == upgrades.StatusRemoved && api.Source == upgrades.SourceManifest:
+				severity = "critical"
+				alertID = fmt.Sprintf("deprecated-api-%s-%s-%s", api.File, api.Kind, api.Name)
+				message = fmt.Sprintf("%s %s in %s can't be applied: %s was removed in Kubernetes %s; use %s",
+					api.Kind, api.Name, api.File, api.APIVersion, api.RemovedIn, api.Replacement)
+			default:
+				continue
+			}
+			used[alertID] = true
+			if raised[alertID] == severity {
+				continue // already raised; replacing it would drop its acknowledgement
+			}
+			raised[alertID] = severity
+
+			alert := &InfrastructureAlert{
+				ID:        alertID,
+				Type:      "deprecated_api",
+				Severity:  severity,
+				Message:   message,
+				Timestamp: time.Now(),
+				Source:    "upgrades",
+			}
+			infrastructureHandlers.RaiseAlert(alert)
+			if alertManager != nil && alertManager.SilencedBy(ctx, infrastructureAlert(*alert)) != "" {
+				continue
+			}
+			if hub != nil {
+				hub.Broadcast(websocket.MessageTypeAlerts, alert)
+			}
+		}
+
+		for alertID := range raised {
+			if !used[alertID] {
+				infrastructureHandlers.ClearAlert(alertID)
+				delete(raised, alertID)
+			}
+		}
+	}
+}
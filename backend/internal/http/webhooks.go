@@ -0,0 +1,190 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/archellir/denshimon/internal/alerts"
+	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/internal/bus"
+	"github.com/archellir/denshimon/internal/database"
+	"github.com/archellir/denshimon/internal/deployments"
+	"github.com/archellir/denshimon/internal/providers/backup"
+	"github.com/archellir/denshimon/internal/providers/certificates"
+	"github.com/archellir/denshimon/internal/secretbox"
+	"github.com/archellir/denshimon/internal/webhooks"
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+// defaultDeliveryLimit is how many deliveries are listed without a limit
+const defaultDeliveryLimit = 50
+
+// WebhookHandlers serves outbound webhooks and their delivery logs
+type WebhookHandlers struct {
+	service *webhooks.Service
+}
+
+func NewWebhookHandlers(service *webhooks.Service) *WebhookHandlers {
+	return &WebhookHandlers{service: service}
+}
+
+// webhookEvents lists the event types webhooks can subscribe to
+type webhookEvents struct {
+	Events []string `json:"events"`
+}
+
+// ListWebhooks handles GET /api/webhooks
+func (h *WebhookHandlers) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.List(r.Context())
+	if err != nil {
+		sendWebhookError(w, err)
+		return
+	}
+	response.SendSuccess(w, list)
+}
+
+// ListWebhookEvents handles GET /api/webhooks/events
+func (h *WebhookHandlers) ListWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	response.SendSuccess(w, webhookEvents{Events: webhooks.Events})
+}
+
+// GetWebhook handles GET /api/webhooks/{id}
+func (h *WebhookHandlers) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, err := h.service.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		sendWebhookError(w, err)
+		return
+	}
+	response.SendSuccess(w, webhook)
+}
+
+// CreateWebhook handles POST /api/webhooks
+func (h *WebhookHandlers) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var webhook webhooks.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	createdBy := ""
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		createdBy = claims.Username
+	}
+	created, err := h.service.Create(r.Context(), webhook, createdBy)
+	if err != nil {
+		sendWebhookError(w, err)
+		return
+	}
+	response.SendSuccess(w, created)
+}
+
+// UpdateWebhook handles PUT /api/webhooks/{id}, replacing the webhook's settings. An empty
+// secret keeps the current one.
+func (h *WebhookHandlers) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	var webhook webhooks.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		response.SendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	updated, err := h.service.Update(r.Context(), r.PathValue("id"), webhook)
+	if err != nil {
+		sendWebhookError(w, err)
+		return
+	}
+	response.SendSuccess(w, updated)
+}
+
+// DeleteWebhook handles DELETE /api/webhooks/{id}
+func (h *WebhookHandlers) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		sendWebhookError(w, err)
+		return
+	}
+	response.SendSuccess(w, map[string]string{"message": "Webhook deleted", "id": id})
+}
+
+// TestWebhook handles POST /api/webhooks/{id}/test, sending a webhook.test event now and
+// returning its delivery
+func (h *WebhookHandlers) TestWebhook(w http.ResponseWriter, r *http.Request) {
+	user := ""
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		user = claims.Username
+	}
+	delivery, err := h.service.Test(r.Context(), r.PathValue("id"), user)
+	if err != nil {
+		sendWebhookError(w, err)
+		return
+	}
+	response.SendSuccess(w, delivery)
+}
+
+// GetWebhookDeliveries handles GET /api/webhooks/{id}/deliveries?limit=50, newest first
+func (h *WebhookHandlers) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	limit := defaultDeliveryLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			response.SendError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = parsed
+	}
+	deliveries, err := h.service.Deliveries(r.Context(), r.PathValue("id"), limit)
+	if err != nil {
+		sendWebhookError(w, err)
+		return
+	}
+	response.SendSuccess(w, deliveries)
+}
+
+func sendWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, webhooks.ErrNotFound):
+		response.SendError(w, http.StatusNotFound, "Webhook not found")
+	case errors.Is(err, webhooks.ErrInvalid):
+		response.SendError(w, http.StatusBadRequest, err.Error())
+	default:
+		response.SendError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// initWebhooks creates the webhook service and emits the events of deployments, alerts,
+// backups and certificates published on the bus to it. Deliveries are sent unless
+// WEBHOOKS_ENABLED=false.
+func initWebhooks(db *database.SQLiteDB, box *secretbox.Box, events *bus.Bus) *WebhookHandlers {
+	service, err := webhooks.NewService(db.DB)
+	if err != nil {
+		slog.Warn("Outbound webhooks disabled", "error", err)
+		return nil
+	}
+	service.SetEncryption(box) // webhook secrets at rest
+
+	bus.On(events, func(event deployments.Applied) {
+		service.Emit(webhooks.EventDeploymentApplied, event.NamedHistory)
+	})
+	bus.On(events, func(event deployments.RolledBack) {
+		service.Emit(webhooks.EventDeploymentRolledBack, event.NamedHistory)
+	})
+	bus.On(events, func(event alerts.Fired) {
+		service.Emit(webhooks.EventAlertFired, event.Alert)
+	})
+	bus.On(events, func(event alerts.Resolved) {
+		service.Emit(webhooks.EventAlertResolved, event.Alert)
+	})
+	bus.On(events, func(event backup.Finished) {
+		service.Emit(webhooks.EventBackupFinished, event.History)
+	})
+	bus.On(events, func(event certificates.Expiring) {
+		service.Emit(webhooks.EventCertificateExpiring, event.Certificate)
+	})
+
+	if os.Getenv("WEBHOOKS_ENABLED") != "false" {
+		service.Start(context.Background())
+	}
+	return NewWebhookHandlers(service)
+}
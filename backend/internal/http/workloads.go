@@ -0,0 +1,274 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/archellir/denshimon/internal/auth"
+	"github.com/archellir/denshimon/pkg/response"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+type StatefulSetInfo struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Ready       string            `json:"ready"`
+	Replicas    int32             `json:"replicas"`
+	UpToDate    int32             `json:"up_to_date"`
+	ServiceName string            `json:"service_name"`
+	Images      []string          `json:"images"`
+	Claims      []string          `json:"volume_claims"`
+	Age         string            `json:"age"`
+	Labels      map[string]string `json:"labels"`
+}
+
+type DaemonSetInfo struct {
+	Name         string            `json:"name"`
+	Namespace    string            `json:"namespace"`
+	Desired      int32             `json:"desired"`
+	Current      int32             `json:"current"`
+	Ready        int32             `json:"ready"`
+	UpToDate     int32             `json:"up_to_date"`
+	Available    int32             `json:"available"`
+	NodeSelector map[string]string `json:"node_selector"`
+	Images       []string          `json:"images"`
+	Age          string            `json:"age"`
+	Labels       map[string]string `json:"labels"`
+}
+
+func newStatefulSetInfo(statefulSet *appsv1.StatefulSet) StatefulSetInfo {
+	var replicas int32 = 1
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	claims := []string{}
+	for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+		claims = append(claims, template.Name)
+	}
+	return StatefulSetInfo{
+		Name:        statefulSet.Name,
+		Namespace:   statefulSet.Namespace,
+		Ready:       fmt.Sprintf("%d/%d", statefulSet.Status.ReadyReplicas, replicas),
+		Replicas:    replicas,
+		UpToDate:    statefulSet.Status.UpdatedReplicas,
+		ServiceName: statefulSet.Spec.ServiceName,
+		Images:      containerImages(statefulSet.Spec.Template.Spec.Containers),
+		Claims:      claims,
+		Age:         formatAge(statefulSet.CreationTimestamp.Time),
+		Labels:      statefulSet.Labels,
+	}
+}
+
+func newDaemonSetInfo(daemonSet *appsv1.DaemonSet) DaemonSetInfo {
+	return DaemonSetInfo{
+		Name:         daemonSet.Name,
+		Namespace:    daemonSet.Namespace,
+		Desired:      daemonSet.Status.DesiredNumberScheduled,
+		Current:      daemonSet.Status.CurrentNumberScheduled,
+		Ready:        daemonSet.Status.NumberReady,
+		UpToDate:     daemonSet.Status.UpdatedNumberScheduled,
+		Available:    daemonSet.Status.NumberAvailable,
+		NodeSelector: daemonSet.Spec.Template.Spec.NodeSelector,
+		Images:       containerImages(daemonSet.Spec.Template.Spec.Containers),
+		Age:          formatAge(daemonSet.CreationTimestamp.Time),
+		Labels:       daemonSet.Labels,
+	}
+}
+
+// GET /api/k8s/statefulsets
+func (h *KubernetesHandlers) ListStatefulSets(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	statefulSets, err := h.k8sClient.ListStatefulSets(listContext(r), queryNamespace(r))
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list statefulsets: %v", err))
+		return
+	}
+
+	infos := []StatefulSetInfo{}
+	for i := range statefulSets.Items {
+		infos = append(infos, newStatefulSetInfo(&statefulSets.Items[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(infos)
+}
+
+// GET /api/k8s/statefulsets/{name}
+func (h *KubernetesHandlers) GetStatefulSet(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	statefulSet, err := h.k8sClient.GetStatefulSet(r.Context(), queryNamespace(r), r.PathValue("name"))
+	if err != nil {
+		sendWorkloadError(w, "statefulset", "get", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newStatefulSetInfo(statefulSet))
+}
+
+// PATCH /api/k8s/statefulsets/{name}/scale
+func (h *KubernetesHandlers) ScaleStatefulSet(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeWorkload(w, r, "statefulsets", "update") {
+		return
+	}
+
+	var req struct {
+		Replicas int32 `json:"replicas"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Replicas < 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.k8sClient.ScaleStatefulSet(r.Context(), queryNamespace(r), r.PathValue("name"), req.Replicas); err != nil {
+		sendWorkloadError(w, "statefulset", "scale", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "StatefulSet scaled successfully",
+		"replicas": req.Replicas,
+	})
+}
+
+// POST /api/k8s/statefulsets/{name}/restart
+func (h *KubernetesHandlers) RestartStatefulSet(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeWorkload(w, r, "statefulsets", "update") {
+		return
+	}
+
+	if err := h.k8sClient.RestartStatefulSet(r.Context(), queryNamespace(r), r.PathValue("name")); err != nil {
+		sendWorkloadError(w, "statefulset", "restart", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "StatefulSet restart initiated"})
+}
+
+// DELETE /api/k8s/statefulsets/{name}
+func (h *KubernetesHandlers) DeleteStatefulSet(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeWorkload(w, r, "statefulsets", "delete") {
+		return
+	}
+
+	if err := h.k8sClient.DeleteStatefulSet(r.Context(), queryNamespace(r), r.PathValue("name")); err != nil {
+		sendWorkloadError(w, "statefulset", "delete", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "StatefulSet deleted successfully"})
+}
+
+// GET /api/k8s/daemonsets
+func (h *KubernetesHandlers) ListDaemonSets(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	daemonSets, err := h.k8sClient.ListDaemonSets(listContext(r), queryNamespace(r))
+	if err != nil {
+		response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list daemonsets: %v", err))
+		return
+	}
+
+	infos := []DaemonSetInfo{}
+	for i := range daemonSets.Items {
+		infos = append(infos, newDaemonSetInfo(&daemonSets.Items[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(infos)
+}
+
+// GET /api/k8s/daemonsets/{name}
+func (h *KubernetesHandlers) GetDaemonSet(w http.ResponseWriter, r *http.Request) {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return
+	}
+
+	daemonSet, err := h.k8sClient.GetDaemonSet(r.Context(), queryNamespace(r), r.PathValue("name"))
+	if err != nil {
+		sendWorkloadError(w, "daemonset", "get", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newDaemonSetInfo(daemonSet))
+}
+
+// POST /api/k8s/daemonsets/{name}/restart
+func (h *KubernetesHandlers) RestartDaemonSet(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeWorkload(w, r, "daemonsets", "update") {
+		return
+	}
+
+	if err := h.k8sClient.RestartDaemonSet(r.Context(), queryNamespace(r), r.PathValue("name")); err != nil {
+		sendWorkloadError(w, "daemonset", "restart", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "DaemonSet restart initiated"})
+}
+
+// DELETE /api/k8s/daemonsets/{name}
+func (h *KubernetesHandlers) DeleteDaemonSet(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeWorkload(w, r, "daemonsets", "delete") {
+		return
+	}
+
+	if err := h.k8sClient.DeleteDaemonSet(r.Context(), queryNamespace(r), r.PathValue("name")); err != nil {
+		sendWorkloadError(w, "daemonset", "delete", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "DaemonSet deleted successfully"})
+}
+
+// authorizeWorkload rejects the request unless the cluster is reachable and the user's role
+// allows action on resource
+func (h *KubernetesHandlers) authorizeWorkload(w http.ResponseWriter, r *http.Request, resource, action string) bool {
+	if h.k8sClient == nil {
+		response.SendError(w, http.StatusServiceUnavailable, "Kubernetes client not available")
+		return false
+	}
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil || !hasPermission(claims.Role, resource, action) {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func sendWorkloadError(w http.ResponseWriter, kind, action string, err error) {
+	if apierrors.IsNotFound(err) {
+		response.SendError(w, http.StatusNotFound, fmt.Sprintf("Failed to %s %s: %v", action, kind, err))
+		return
+	}
+	response.SendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to %s %s: %v", action, kind, err))
+}
+
+func containerImages(containers []corev1.Container) []string {
+	images := []string{}
+	for _, container := range containers {
+		images = append(images, container.Image)
+	}
+	return images
+}
@@ -0,0 +1,160 @@
+package incidents
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/archellir/denshimon/internal/alerts"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// fakeAlerts is an inbox with one active and one resolved alert
+type fakeAlerts struct{}
+
+func (fakeAlerts) List(_ context.Context, opts alerts.ListOptions) ([]alerts.Alert, error) {
+	seen := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	if opts.Status == alerts.StatusResolved {
+		return []alerts.Alert{{DedupKey: "backup/failed/job=gitea", Source: "backup", Type: "failed", Severity: "warning", Title: "Backup failed", FirstSeen: seen}}, nil
+	}
+	return []alerts.Alert{{DedupKey: "infrastructure/service_down/service=gitea", Source: "infrastructure", Type: "service_down", Severity: "critical", Title: "Gitea is down", FirstSeen: seen}}, nil
+}
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewManager(store, fakeAlerts{})
+}
+
+func TestIncidentLifecycle(t *testing.T) {
+	m := newTestManager(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	if _, err := m.Create(ctx, Incident{Title: " "}, nil, "alice"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected a missing title to be invalid, got %v", err)
+	}
+	if _, err := m.Create(ctx, Incident{Title: "Gitea down", Services: []string{"gitea"}}, nil, "alice"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected a service without a namespace to be invalid, got %v", err)
+	}
+	if _, err := m.Create(ctx, Incident{Title: "Gitea down"}, []string{"unknown"}, "alice"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected an unknown alert to be invalid, got %v", err)
+	}
+
+	incident, err := m.Create(ctx, Incident{
+		Title:       "Gitea down",
+		Impact:      ImpactMajor,
+		Summary:     "Gitea returns 502",
+		Services:    []string{"apps/gitea", "apps/gitea"},
+		Deployments: []string{"apps/gitea"},
+	}, []string{"infrastructure/service_down/service=gitea"}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if incident.Status != StatusInvestigating || len(incident.Services) != 1 || incident.CreatedBy != "alice" {
+		t.Errorf("unexpected incident %+v", incident)
+	}
+	if len(incident.Updates) != 1 || incident.Updates[0].Message != "Gitea returns 502" {
+		t.Errorf("expected the summary as the first update, got %+v", incident.Updates)
+	}
+	if len(incident.Alerts) != 1 || incident.Alerts[0].Severity != "critical" || incident.Alerts[0].LinkedBy != "alice" {
+		t.Errorf("unexpected alerts %+v", incident.Alerts)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := m.PostUpdate(ctx, incident.ID, Update{Status: "fixed", Message: "done"}, "bob"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected an unknown status to be invalid, got %v", err)
+	}
+	if incident, err = m.PostUpdate(ctx, incident.ID, Update{Status: StatusResolved, Message: "Restarted the pod"}, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if incident.Status != StatusResolved || incident.ResolvedAt == nil || !incident.ResolvedAt.Equal(now) || incident.Updates[0].CreatedBy != "bob" {
+		t.Errorf("expected the incident to be resolved, got %+v", incident)
+	}
+
+	if incident, err = m.LinkAlerts(ctx, incident.ID, []string{"backup/failed/job=gitea"}, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if len(incident.Alerts) != 2 {
+		t.Errorf("expected the resolved alert to be linked, got %+v", incident.Alerts)
+	}
+	if err := m.UnlinkAlert(ctx, incident.ID, "backup/failed/job=gitea"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UnlinkAlert(ctx, incident.ID, "backup/failed/job=gitea"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected unlinking twice to fail, got %v", err)
+	}
+
+	// Posting another status reopens the incident
+	if incident, err = m.PostUpdate(ctx, incident.ID, Update{Status: StatusMonitoring, Message: "Errors again"}, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if incident.ResolvedAt != nil {
+		t.Error("expected the incident to be reopened")
+	}
+	got, err := m.Get(ctx, incident.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Updates) != 3 || got.Updates[0].Status != StatusMonitoring || len(got.Alerts) != 1 {
+		t.Errorf("unexpected stored incident %+v", got)
+	}
+
+	open, err := m.List(ctx, ListOptions{Open: true})
+	if err != nil || len(open) != 1 {
+		t.Errorf("expected one open incident, got %d (%v)", len(open), err)
+	}
+	if err := m.Delete(ctx, incident.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Get(ctx, incident.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the incident to be deleted, got %v", err)
+	}
+}
+
+func TestStatusPage(t *testing.T) {
+	m := newTestManager(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	page, err := m.StatusPage(ctx)
+	if err != nil || page.Status != OverallOperational {
+		t.Fatalf("expected operational without incidents, got %+v (%v)", page, err)
+	}
+
+	old, _ := m.Create(ctx, Incident{Title: "Old outage", Impact: ImpactCritical, Status: StatusResolved}, nil, "")
+	now = now.Add(8 * 24 * time.Hour)
+	recent, _ := m.Create(ctx, Incident{Title: "Slow registry", Impact: ImpactCritical}, nil, "")
+	m.PostUpdate(ctx, recent.ID, Update{Status: StatusResolved, Message: "Fixed"}, "")
+	m.Create(ctx, Incident{Title: "Memos slow", Services: []string{"apps/memos"}}, nil, "")
+	m.Create(ctx, Incident{Title: "Gitea down", Impact: ImpactMajor, Services: []string{"apps/gitea", "apps/memos"}}, nil, "")
+
+	if page, err = m.StatusPage(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if page.Status != OverallPartialOutage || len(page.ActiveIncidents) != 2 {
+		t.Errorf("expected a partial outage with two open incidents, got %s with %d", page.Status, len(page.ActiveIncidents))
+	}
+	if len(page.RecentlyResolved) != 1 || page.RecentlyResolved[0].ID == old.ID {
+		t.Errorf("expected only the recently resolved incident, got %+v", page.RecentlyResolved)
+	}
+	if len(page.AffectedServices) != 2 || page.AffectedServices[0] != "apps/gitea" {
+		t.Errorf("unexpected affected services %v", page.AffectedServices)
+	}
+	if len(page.ActiveIncidents[0].Updates) != 1 {
+		t.Errorf("expected open incidents to carry their updates, got %+v", page.ActiveIncidents[0])
+	}
+}
@@ -0,0 +1,288 @@
+package incidents
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/alerts"
+	"github.com/google/uuid"
+)
+
+// StatusPageWindow is how long resolved incidents stay on the status page
+const StatusPageWindow = 7 * 24 * time.Hour
+
+// Overall statuses of the status page
+const (
+	OverallOperational   = "operational"
+	OverallDegraded      = "degraded"
+	OverallPartialOutage = "partial_outage"
+	OverallMajorOutage   = "major_outage"
+)
+
+// AlertLister finds the alerts grouped into incidents; the alert manager implements it
+type AlertLister interface {
+	List(ctx context.Context, opts alerts.ListOptions) ([]alerts.Alert, error)
+}
+
+// Manager records incidents and groups alerts of the inbox into them
+type Manager struct {
+	store  *Store
+	alerts AlertLister
+	now    func() time.Time
+}
+
+// NewManager creates an incident manager; without an alert lister alerts can't be linked
+func NewManager(store *Store, alertLister AlertLister) *Manager {
+	return &Manager{store: store, alerts: alertLister, now: time.Now}
+}
+
+// List returns incidents newest first, without their alerts and updates
+func (m *Manager) List(ctx context.Context, opts ListOptions) ([]Incident, error) {
+	if opts.Status != "" && !slices.Contains(Statuses, opts.Status) {
+		return nil, fmt.Errorf("%w: status must be one of %s", ErrInvalid, strings.Join(Statuses, ", "))
+	}
+	return m.store.list(ctx, opts)
+}
+
+// Get returns an incident with its alerts and updates
+func (m *Manager) Get(ctx context.Context, id string) (*Incident, error) {
+	return m.store.get(ctx, id)
+}
+
+// Create opens an incident, recording its summary as the first update and linking the
+// given alerts
+func (m *Manager) Create(ctx context.Context, incident Incident, dedupKeys []string, user string) (*Incident, error) {
+	if err := incident.validate(); err != nil {
+		return nil, err
+	}
+	now := m.now().UTC()
+	incident.ID = uuid.New().String()
+	incident.CreatedBy = user
+	incident.CreatedAt, incident.UpdatedAt = now, now
+	incident.ResolvedAt = nil
+	if incident.Status == StatusResolved {
+		incident.ResolvedAt = &now
+	}
+	found, err := m.findAlerts(ctx, dedupKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.store.save(ctx, &incident); err != nil {
+		return nil, err
+	}
+	message := incident.Summary
+	if message == "" {
+		message = "Incident opened"
+	}
+	update := Update{ID: uuid.New().String(), Status: incident.Status, Message: message, CreatedBy: user, CreatedAt: now}
+	if err := m.store.addUpdate(ctx, incident.ID, update); err != nil {
+		return nil, err
+	}
+	if err := m.link(ctx, incident.ID, found, user, now); err != nil {
+		return nil, err
+	}
+	return m.store.get(ctx, incident.ID)
+}
+
+// Update changes an incident's title, impact, summary and affected services and
+// deployments. Its status changes through PostUpdate.
+func (m *Manager) Update(ctx context.Context, id string, changes Incident) (*Incident, error) {
+	incident, err := m.store.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	changes.Status = incident.Status
+	if err := changes.validate(); err != nil {
+		return nil, err
+	}
+	incident.Title, incident.Impact, incident.Summary = changes.Title, changes.Impact, changes.Summary
+	incident.Services, incident.Deployments = changes.Services, changes.Deployments
+	incident.UpdatedAt = m.now().UTC()
+	if err := m.store.save(ctx, incident); err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+// Delete removes an incident with its updates and alert links
+func (m *Manager) Delete(ctx context.Context, id string) error {
+	return m.store.delete(ctx, id)
+}
+
+// PostUpdate posts a message to an incident, moving it to the update's status; without a
+// status the incident keeps its own. Resolving records when; posting a status other than
+// resolved to a resolved incident reopens it.
+func (m *Manager) PostUpdate(ctx context.Context, id string, update Update, user string) (*Incident, error) {
+	incident, err := m.store.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	update.Message = strings.TrimSpace(update.Message)
+	if update.Message == "" {
+		return nil, fmt.Errorf("%w: message is required", ErrInvalid)
+	}
+	if update.Status == "" {
+		update.Status = incident.Status
+	}
+	if !slices.Contains(Statuses, update.Status) {
+		return nil, fmt.Errorf("%w: status must be one of %s", ErrInvalid, strings.Join(Statuses, ", "))
+	}
+
+	now := m.now().UTC()
+	update.ID, update.CreatedBy, update.CreatedAt = uuid.New().String(), user, now
+	switch {
+	case update.Status == StatusResolved && incident.ResolvedAt == nil:
+		incident.ResolvedAt = &now
+	case update.Status != StatusResolved:
+		incident.ResolvedAt = nil
+	}
+	incident.Status = update.Status
+	incident.UpdatedAt = now
+	if err := m.store.save(ctx, incident); err != nil {
+		return nil, err
+	}
+	if err := m.store.addUpdate(ctx, id, update); err != nil {
+		return nil, err
+	}
+	incident.Updates = append([]Update{update}, incident.Updates...)
+	return incident, nil
+}
+
+// LinkAlerts groups alerts of the inbox, active or resolved, into an incident by dedup key
+func (m *Manager) LinkAlerts(ctx context.Context, id string, dedupKeys []string, user string) (*Incident, error) {
+	if len(dedupKeys) == 0 {
+		return nil, fmt.Errorf("%w: dedup_keys are required", ErrInvalid)
+	}
+	if _, err := m.store.get(ctx, id); err != nil {
+		return nil, err
+	}
+	found, err := m.findAlerts(ctx, dedupKeys)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.link(ctx, id, found, user, m.now().UTC()); err != nil {
+		return nil, err
+	}
+	return m.store.get(ctx, id)
+}
+
+// UnlinkAlert removes an alert from an incident
+func (m *Manager) UnlinkAlert(ctx context.Context, id, dedupKey string) error {
+	return m.store.unlinkAlert(ctx, id, dedupKey)
+}
+
+func (m *Manager) link(ctx context.Context, id string, found []alerts.Alert, user string, now time.Time) error {
+	for _, alert := range found {
+		err := m.store.linkAlert(ctx, id, Alert{
+			DedupKey:  alert.DedupKey,
+			Source:    alert.Source,
+			Type:      alert.Type,
+			Severity:  alert.Severity,
+			Title:     alert.Title,
+			FirstSeen: alert.FirstSeen,
+			LinkedBy:  user,
+			LinkedAt:  now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findAlerts looks up alerts of the inbox by dedup key; unknown keys are invalid
+func (m *Manager) findAlerts(ctx context.Context, dedupKeys []string) ([]alerts.Alert, error) {
+	if len(dedupKeys) == 0 {
+		return nil, nil
+	}
+	if m.alerts == nil {
+		return nil, fmt.Errorf("%w: alerts are not available", ErrInvalid)
+	}
+	active, err := m.alerts.List(ctx, alerts.ListOptions{IncludeSilenced: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	resolved, err := m.alerts.List(ctx, alerts.ListOptions{Status: alerts.StatusResolved})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	byKey := make(map[string]alerts.Alert, len(active)+len(resolved))
+	for _, alert := range append(resolved, active...) {
+		byKey[alert.DedupKey] = alert
+	}
+
+	var found []alerts.Alert
+	for _, key := range dedupKeys {
+		alert, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: no alert with dedup key %q", ErrInvalid, key)
+		}
+		if !slices.ContainsFunc(found, func(a alerts.Alert) bool { return a.DedupKey == key }) {
+			found = append(found, alert)
+		}
+	}
+	return found, nil
+}
+
+// StatusPage is what the status page shows: the overall status, open incidents and those
+// resolved recently
+type StatusPage struct {
+	Status           string     `json:"status"`
+	ActiveIncidents  []Incident `json:"active_incidents"`
+	RecentlyResolved []Incident `json:"recently_resolved"`
+	AffectedServices []string   `json:"affected_services"`
+	GeneratedAt      time.Time  `json:"generated_at"`
+}
+
+// StatusPage builds the status page. The overall status follows the worst impact of the
+// open incidents: none or minor is degraded, major a partial and critical a major outage.
+func (m *Manager) StatusPage(ctx context.Context) (*StatusPage, error) {
+	incidents, err := m.store.list(ctx, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	now := m.now().UTC()
+	page := &StatusPage{
+		Status:           OverallOperational,
+		ActiveIncidents:  []Incident{},
+		RecentlyResolved: []Incident{},
+		AffectedServices: []string{},
+		GeneratedAt:      now,
+	}
+
+	worst := -1
+	for _, incident := range incidents {
+		if incident.ResolvedAt != nil {
+			if now.Sub(*incident.ResolvedAt) <= StatusPageWindow {
+				page.RecentlyResolved = append(page.RecentlyResolved, incident)
+			}
+			continue
+		}
+		if incident.Updates, err = m.store.updates(ctx, incident.ID); err != nil {
+			return nil, err
+		}
+		page.ActiveIncidents = append(page.ActiveIncidents, incident)
+		worst = max(worst, impactRank(incident.Impact))
+		for _, service := range incident.Services {
+			if !slices.Contains(page.AffectedServices, service) {
+				page.AffectedServices = append(page.AffectedServices, service)
+			}
+		}
+	}
+	slices.Sort(page.AffectedServices)
+
+	switch {
+	case worst < 0:
+	case worst >= impactRank(ImpactCritical):
+		page.Status = OverallMajorOutage
+	case worst >= impactRank(ImpactMajor):
+		page.Status = OverallPartialOutage
+	default:
+		page.Status = OverallDegraded
+	}
+	return page, nil
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS incident_alerts;
+DROP INDEX IF EXISTS idx_incident_updates_incident;
+DROP TABLE IF EXISTS incident_updates;
+DROP INDEX IF EXISTS idx_incidents_resolved;
+DROP TABLE IF EXISTS incidents;
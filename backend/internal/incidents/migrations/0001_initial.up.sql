@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS incidents (
+	id TEXT PRIMARY KEY,
+	title TEXT NOT NULL,
+	status TEXT NOT NULL,
+	impact TEXT NOT NULL,
+	summary TEXT NOT NULL DEFAULT '',
+	services TEXT NOT NULL DEFAULT '[]', -- namespace/name of affected services, JSON
+	deployments TEXT NOT NULL DEFAULT '[]', -- namespace/name of affected deployments, JSON
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL, -- unix milliseconds
+	updated_at INTEGER NOT NULL,
+	resolved_at INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_resolved ON incidents(resolved_at, created_at);
+
+CREATE TABLE IF NOT EXISTS incident_updates (
+	id TEXT PRIMARY KEY,
+	incident_id TEXT NOT NULL,
+	status TEXT NOT NULL,
+	message TEXT NOT NULL,
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL,
+	FOREIGN KEY (incident_id) REFERENCES incidents(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_updates_incident ON incident_updates(incident_id, created_at);
+
+-- Alerts grouped into an incident, copied so they stay readable once the inbox prunes them
+CREATE TABLE IF NOT EXISTS incident_alerts (
+	incident_id TEXT NOT NULL,
+	dedup_key TEXT NOT NULL,
+	source TEXT NOT NULL,
+	type TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	title TEXT NOT NULL,
+	first_seen INTEGER NOT NULL,
+	linked_by TEXT NOT NULL DEFAULT '',
+	linked_at INTEGER NOT NULL,
+	PRIMARY KEY (incident_id, dedup_key),
+	FOREIGN KEY (incident_id) REFERENCES incidents(id) ON DELETE CASCADE
+);
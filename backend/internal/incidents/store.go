@@ -0,0 +1,236 @@
+package incidents
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/database/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations is the schema of the incident tables
+var Migrations = migrate.NewSource("incidents", migrationFiles, "migrations")
+
+// Store persists incidents with their updates and alerts in SQLite
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates an incident store, migrating its tables to the latest schema version
+func NewStore(db *sql.DB) (*Store, error) {
+	if err := migrate.Up(context.Background(), db, Migrations); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+const incidentColumns = `id, title, status, impact, summary, services, deployments, created_by, created_at, updated_at, resolved_at`
+
+func scanIncident(scanner interface{ Scan(...interface{}) error }) (*Incident, error) {
+	var i Incident
+	var services, deployments string
+	var createdAt, updatedAt int64
+	var resolvedAt sql.NullInt64
+	if err := scanner.Scan(&i.ID, &i.Title, &i.Status, &i.Impact, &i.Summary, &services, &deployments,
+		&i.CreatedBy, &createdAt, &updatedAt, &resolvedAt); err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(services), &i.Services)
+	json.Unmarshal([]byte(deployments), &i.Deployments)
+	i.CreatedAt = time.UnixMilli(createdAt)
+	i.UpdatedAt = time.UnixMilli(updatedAt)
+	if resolvedAt.Valid {
+		resolved := time.UnixMilli(resolvedAt.Int64)
+		i.ResolvedAt = &resolved
+	}
+	i.Alerts = []Alert{}
+	i.Updates = []Update{}
+	return &i, nil
+}
+
+// list returns incidents newest first, without their alerts and updates
+func (s *Store) list(ctx context.Context, opts ListOptions) ([]Incident, error) {
+	var conditions []string
+	var args []interface{}
+	if opts.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, opts.Status)
+	}
+	if opts.Open {
+		conditions = append(conditions, "resolved_at IS NULL")
+	}
+	query := `SELECT ` + incidentColumns + ` FROM incidents`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY created_at DESC`
+	if opts.Limit > 0 {
+		query += fmt.Sprintf(` LIMIT %d`, opts.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incidents: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := []Incident{}
+	for rows.Next() {
+		incident, err := scanIncident(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan incident: %w", err)
+		}
+		incidents = append(incidents, *incident)
+	}
+	return incidents, rows.Err()
+}
+
+// get returns an incident with its alerts and updates
+func (s *Store) get(ctx context.Context, id string) (*Incident, error) {
+	incident, err := scanIncident(s.db.QueryRowContext(ctx, `SELECT `+incidentColumns+` FROM incidents WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+	if incident.Updates, err = s.updates(ctx, id); err != nil {
+		return nil, err
+	}
+	if incident.Alerts, err = s.alerts(ctx, id); err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+// save inserts or replaces an incident's own fields
+func (s *Store) save(ctx context.Context, i *Incident) error {
+	services, _ := json.Marshal(i.Services)
+	deployments, _ := json.Marshal(i.Deployments)
+	var resolvedAt sql.NullInt64
+	if i.ResolvedAt != nil {
+		resolvedAt = sql.NullInt64{Int64: i.ResolvedAt.UnixMilli(), Valid: true}
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO incidents (`+incidentColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			title = excluded.title, status = excluded.status, impact = excluded.impact,
+			summary = excluded.summary, services = excluded.services, deployments = excluded.deployments,
+			updated_at = excluded.updated_at, resolved_at = excluded.resolved_at`,
+		i.ID, i.Title, i.Status, i.Impact, i.Summary, string(services), string(deployments), i.CreatedBy,
+		i.CreatedAt.UnixMilli(), i.UpdatedAt.UnixMilli(), resolvedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save incident: %w", err)
+	}
+	return nil
+}
+
+// delete removes an incident with its updates and alerts
+func (s *Store) delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM incidents WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete incident: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	// Removed explicitly as SQLite only cascades with foreign keys enabled
+	for _, query := range []string{`DELETE FROM incident_updates WHERE incident_id = ?`, `DELETE FROM incident_alerts WHERE incident_id = ?`} {
+		if _, err := s.db.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("failed to delete incident: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) addUpdate(ctx context.Context, incidentID string, u Update) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO incident_updates (id, incident_id, status, message, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		u.ID, incidentID, u.Status, u.Message, u.CreatedBy, u.CreatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to store incident update: %w", err)
+	}
+	return nil
+}
+
+// updates returns an incident's updates, newest first
+func (s *Store) updates(ctx context.Context, incidentID string) ([]Update, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, status, message, created_by, created_at FROM incident_updates
+		WHERE incident_id = ? ORDER BY created_at DESC, rowid DESC`, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query incident updates: %w", err)
+	}
+	defer rows.Close()
+
+	updates := []Update{}
+	for rows.Next() {
+		var u Update
+		var createdAt int64
+		if err := rows.Scan(&u.ID, &u.Status, &u.Message, &u.CreatedBy, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan incident update: %w", err)
+		}
+		u.CreatedAt = time.UnixMilli(createdAt)
+		updates = append(updates, u)
+	}
+	return updates, rows.Err()
+}
+
+// linkAlert groups an alert into an incident; linking it again refreshes its copy
+func (s *Store) linkAlert(ctx context.Context, incidentID string, a Alert) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO incident_alerts (incident_id, dedup_key, source, type, severity, title, first_seen, linked_by, linked_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(incident_id, dedup_key) DO UPDATE SET
+			severity = excluded.severity, title = excluded.title`,
+		incidentID, a.DedupKey, a.Source, a.Type, a.Severity, a.Title, a.FirstSeen.UnixMilli(), a.LinkedBy, a.LinkedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to link alert: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) unlinkAlert(ctx context.Context, incidentID, dedupKey string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM incident_alerts WHERE incident_id = ? AND dedup_key = ?`, incidentID, dedupKey)
+	if err != nil {
+		return fmt.Errorf("failed to unlink alert: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// alerts returns the alerts grouped into an incident, in the order they were linked
+func (s *Store) alerts(ctx context.Context, incidentID string) ([]Alert, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT dedup_key, source, type, severity, title, first_seen, linked_by, linked_at FROM incident_alerts
+		WHERE incident_id = ? ORDER BY linked_at, dedup_key`, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query incident alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []Alert{}
+	for rows.Next() {
+		var a Alert
+		var firstSeen, linkedAt int64
+		if err := rows.Scan(&a.DedupKey, &a.Source, &a.Type, &a.Severity, &a.Title, &firstSeen, &a.LinkedBy, &linkedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan incident alert: %w", err)
+		}
+		a.FirstSeen = time.UnixMilli(firstSeen)
+		a.LinkedAt = time.UnixMilli(linkedAt)
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
@@ -0,0 +1,132 @@
+// Package incidents records incidents: related alerts grouped under one title, the
+// services and deployments they affect, and the updates posted while they are worked on.
+package incidents
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned for unknown incidents and alerts
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalid is returned for incidents and updates with missing or unknown fields
+var ErrInvalid = errors.New("invalid incident")
+
+// Incident statuses, in the order an incident usually moves through them
+const (
+	StatusInvestigating = "investigating"
+	StatusIdentified    = "identified"
+	StatusMonitoring    = "monitoring"
+	StatusResolved      = "resolved"
+)
+
+// Statuses lists the incident statuses
+var Statuses = []string{StatusInvestigating, StatusIdentified, StatusMonitoring, StatusResolved}
+
+// Impacts in increasing order
+const (
+	ImpactNone     = "none"
+	ImpactMinor    = "minor"
+	ImpactMajor    = "major"
+	ImpactCritical = "critical"
+)
+
+// Impacts lists the impacts, least severe first
+var Impacts = []string{ImpactNone, ImpactMinor, ImpactMajor, ImpactCritical}
+
+// Incident is a disruption and everything known about it
+type Incident struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+	Impact string `json:"impact"`
+	// Summary is the current description; updates keep the history
+	Summary string `json:"summary,omitempty"`
+	// Services and Deployments are the namespace/name of what is affected
+	Services    []string   `json:"services"`
+	Deployments []string   `json:"deployments"`
+	Alerts      []Alert    `json:"alerts"`
+	Updates     []Update   `json:"updates"` // newest first
+	CreatedBy   string     `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Update is a status change or note posted to an incident
+type Update struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status"` // the incident's status from this update on
+	Message   string    `json:"message"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Alert is an alert of the inbox grouped into an incident
+type Alert struct {
+	DedupKey  string    `json:"dedup_key"`
+	Source    string    `json:"source"`
+	Type      string    `json:"type"`
+	Severity  string    `json:"severity"`
+	Title     string    `json:"title"`
+	FirstSeen time.Time `json:"first_seen"`
+	LinkedBy  string    `json:"linked_by,omitempty"`
+	LinkedAt  time.Time `json:"linked_at"`
+}
+
+// ListOptions filter listed incidents
+type ListOptions struct {
+	Status string // one status, or empty for all
+	Open   bool   // only unresolved incidents
+	Limit  int
+}
+
+// validate fills in defaults and checks an incident's fields
+func (i *Incident) validate() error {
+	i.Title = strings.TrimSpace(i.Title)
+	if i.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalid)
+	}
+	if i.Status == "" {
+		i.Status = StatusInvestigating
+	}
+	if !slices.Contains(Statuses, i.Status) {
+		return fmt.Errorf("%w: status must be one of %s", ErrInvalid, strings.Join(Statuses, ", "))
+	}
+	if i.Impact == "" {
+		i.Impact = ImpactMinor
+	}
+	if !slices.Contains(Impacts, i.Impact) {
+		return fmt.Errorf("%w: impact must be one of %s", ErrInvalid, strings.Join(Impacts, ", "))
+	}
+	var err error
+	if i.Services, err = refs("service", i.Services); err != nil {
+		return err
+	}
+	i.Deployments, err = refs("deployment", i.Deployments)
+	return err
+}
+
+// refs checks and deduplicates namespace/name references
+func refs(kind string, values []string) ([]string, error) {
+	result := []string{}
+	for _, value := range values {
+		namespace, name, ok := strings.Cut(strings.TrimSpace(value), "/")
+		if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("%w: %s %q must be namespace/name", ErrInvalid, kind, value)
+		}
+		if ref := namespace + "/" + name; !slices.Contains(result, ref) {
+			result = append(result, ref)
+		}
+	}
+	return result, nil
+}
+
+// impactRank orders impacts; unknown impacts rank with none
+func impactRank(impact string) int {
+	return max(slices.Index(Impacts, impact), 0)
+}
@@ -0,0 +1,131 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is how long list results are served from memory. The WebSocket publisher
+// lists pods and nodes every few seconds, so REST handlers and metrics share its results.
+const DefaultCacheTTL = 5 * time.Second
+
+// cacheFetchTimeout bounds a shared fetch, which outlives the request that started it
+const cacheFetchTimeout = 30 * time.Second
+
+type refreshKey struct{}
+
+// WithRefresh marks a context so that list calls made with it bypass the cache,
+// e.g. for ?refresh=true. The fresh result replaces the cached one.
+func WithRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshKey{}, true)
+}
+
+func refreshRequested(ctx context.Context) bool {
+	refresh, _ := ctx.Value(refreshKey{}).(bool)
+	return refresh
+}
+
+// listCache holds recent list results by kind and namespace. Concurrent misses for the
+// same list share a single API call.
+type listCache struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	ready     chan struct{} // closed once the fetch completes
+	value     any
+	err       error
+	fetchedAt time.Time
+}
+
+func newListCache(ttl time.Duration) *listCache {
+	return &listCache{
+		ttl:     ttl,
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+// SetCacheTTL sets how long list results are cached; 0 disables caching
+func (c *Client) SetCacheTTL(ttl time.Duration) {
+	if c.cache == nil {
+		return
+	}
+	c.cache.mutex.Lock()
+	defer c.cache.mutex.Unlock()
+	c.cache.ttl = ttl
+	c.cache.entries = make(map[string]*cacheEntry)
+}
+
+// InvalidateCache drops cached lists of a kind (pods, nodes, namespaces, services, events,
+// deployments, statefulsets or daemonsets) in every namespace, so the next list reflects a change just made
+func (c *Client) InvalidateCache(kind string) {
+	if c.cache == nil {
+		return
+	}
+	c.cache.mutex.Lock()
+	defer c.cache.mutex.Unlock()
+	for key := range c.cache.entries {
+		if strings.HasPrefix(key, kind+"/") {
+			delete(c.cache.entries, key)
+		}
+	}
+}
+
+// cachedList returns a cached list of kind in namespace ("" for all namespaces), calling
+// fetch when the cached result is missing, stale or a refresh was requested. Callers must
+// not modify the returned list.
+func cachedList[L any](cache *listCache, ctx context.Context, kind, namespace string, fetch func(context.Context, string) (L, error)) (L, error) {
+	if cache == nil {
+		return fetch(ctx, namespace)
+	}
+	key := kind + "/" + namespace
+
+	cache.mutex.Lock()
+	if cache.ttl <= 0 {
+		cache.mutex.Unlock()
+		return fetch(ctx, namespace)
+	}
+	if entry := cache.entries[key]; entry != nil && !refreshRequested(ctx) {
+		select {
+		case <-entry.ready:
+			if entry.err == nil && time.Since(entry.fetchedAt) < cache.ttl {
+				cache.mutex.Unlock()
+				return entry.value.(L), nil
+			}
+		default:
+			// A fetch is in flight; wait for its result
+			cache.mutex.Unlock()
+			select {
+			case <-entry.ready:
+				if entry.err != nil {
+					var zero L
+					return zero, entry.err
+				}
+				return entry.value.(L), nil
+			case <-ctx.Done():
+				var zero L
+				return zero, ctx.Err()
+			}
+		}
+	}
+	entry := &cacheEntry{ready: make(chan struct{})}
+	cache.entries[key] = entry
+	cache.mutex.Unlock()
+
+	fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheFetchTimeout)
+	defer cancel()
+	value, err := fetch(fetchCtx, namespace)
+
+	cache.mutex.Lock()
+	entry.value, entry.err, entry.fetchedAt = value, err, time.Now()
+	close(entry.ready)
+	if err != nil && cache.entries[key] == entry {
+		delete(cache.entries, key)
+	}
+	cache.mutex.Unlock()
+	return value, err
+}
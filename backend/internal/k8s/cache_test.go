@@ -0,0 +1,106 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachedList(t *testing.T) {
+	client := &Client{cache: newListCache(time.Minute)}
+	var calls atomic.Int32
+	fetch := func(ctx context.Context, namespace string) ([]string, error) {
+		calls.Add(1)
+		return []string{namespace}, nil
+	}
+	list := func(ctx context.Context, namespace string) []string {
+		t.Helper()
+		value, err := cachedList(client.cache, ctx, "pods", namespace, fetch)
+		if err != nil {
+			t.Fatalf("cachedList failed: %v", err)
+		}
+		return value
+	}
+
+	ctx := context.Background()
+	list(ctx, "default")
+	list(ctx, "default")
+	if calls.Load() != 1 {
+		t.Fatalf("expected cached result, got %d fetches", calls.Load())
+	}
+	if got := list(ctx, "kube-system"); got[0] != "kube-system" || calls.Load() != 2 {
+		t.Errorf("namespaces not cached separately: %v after %d fetches", got, calls.Load())
+	}
+
+	list(WithRefresh(ctx), "default")
+	if calls.Load() != 3 {
+		t.Errorf("refresh did not bypass the cache: %d fetches", calls.Load())
+	}
+	list(ctx, "default")
+	if calls.Load() != 3 {
+		t.Errorf("refreshed result not cached: %d fetches", calls.Load())
+	}
+
+	client.InvalidateCache("pods")
+	list(ctx, "default")
+	list(ctx, "kube-system")
+	if calls.Load() != 5 {
+		t.Errorf("invalidation did not drop every namespace: %d fetches", calls.Load())
+	}
+
+	client.SetCacheTTL(0)
+	list(ctx, "default")
+	list(ctx, "default")
+	if calls.Load() != 7 {
+		t.Errorf("disabled cache served cached results: %d fetches", calls.Load())
+	}
+}
+
+func TestCachedListSharesFetches(t *testing.T) {
+	cache := newListCache(time.Minute)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context, namespace string) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, err := cachedList(cache, context.Background(), "nodes", "", fetch); err != nil || value != 42 {
+				t.Errorf("unexpected result %d, %v", value, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("concurrent misses made %d fetches, want 1", calls.Load())
+	}
+}
+
+func TestCachedListDoesNotCacheErrors(t *testing.T) {
+	cache := newListCache(time.Minute)
+	var calls atomic.Int32
+	fetch := func(ctx context.Context, namespace string) (int, error) {
+		if calls.Add(1) == 1 {
+			return 0, errors.New("apiserver unavailable")
+		}
+		return 1, nil
+	}
+
+	if _, err := cachedList(cache, context.Background(), "events", "", fetch); err == nil {
+		t.Fatal("expected the first fetch to fail")
+	}
+	if value, err := cachedList(cache, context.Background(), "events", "", fetch); err != nil || value != 1 {
+		t.Errorf("failed fetch was cached: %d, %v", value, err)
+	}
+}
@@ -3,14 +3,19 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 )
@@ -18,7 +23,10 @@ import (
 // Client wraps Kubernetes clientset and configuration for cluster operations
 type Client struct {
 	clientset kubernetes.Interface
+	dynamic   dynamic.Interface
+	mapper    meta.ResettableRESTMapper
 	config    *rest.Config
+	cache     *listCache
 }
 
 func NewClient(kubeconfigPath string) (*Client, error) {
@@ -35,17 +43,41 @@ func NewClient(kubeconfigPath string) (*Client, error) {
 		}
 	}
 
+	// Updates without an explicit field manager are recorded under the user agent, so
+	// Denshimon's changes can be told apart from external ones
+	config.UserAgent = DefaultFieldManager
+
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Kubernetes clientset: %w", err)
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes dynamic client: %w", err)
+	}
+
+	// Discovery results are cached and refreshed on a mapping miss (e.g. newly installed CRDs)
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+
 	return &Client{
 		clientset: clientset,
+		dynamic:   dynamicClient,
+		mapper:    mapper,
 		config:    config,
+		cache:     newListCache(DefaultCacheTTL),
 	}, nil
 }
 
+// NewClientForClientset wraps an existing clientset, such as a fake one in tests. The
+// dynamic client and REST mapping are not available on it.
+func NewClientForClientset(clientset kubernetes.Interface) *Client {
+	return &Client{
+		clientset: clientset,
+		cache:     newListCache(DefaultCacheTTL),
+	}
+}
+
 func buildConfigFromKubeconfig(kubeconfigPath string) (*rest.Config, error) {
 	if kubeconfigPath == "" {
 		// Try default locations
@@ -71,6 +103,11 @@ func (c *Client) Clientset() kubernetes.Interface {
 	return c.clientset
 }
 
+// Dynamic returns the dynamic client used for CRDs without generated clientsets
+func (c *Client) Dynamic() dynamic.Interface {
+	return c.dynamic
+}
+
 func (c *Client) Config() *rest.Config {
 	return c.config
 }
@@ -81,9 +118,18 @@ func (c *Client) HealthCheck(ctx context.Context) error {
 	return err
 }
 
+// List methods are served from the list cache; see WithRefresh to bypass it.
+// The returned lists are copies and may be modified.
+
 // Additional methods needed by metrics service
 func (c *Client) ListNodes(ctx context.Context) (*corev1.NodeList, error) {
-	return c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	list, err := cachedList(c.cache, ctx, "nodes", "", func(ctx context.Context, _ string) (*corev1.NodeList, error) {
+		return c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list.DeepCopy(), nil
 }
 
 func (c *Client) GetNode(ctx context.Context, name string) (*corev1.Node, error) {
@@ -91,10 +137,13 @@ func (c *Client) GetNode(ctx context.Context, name string) (*corev1.Node, error)
 }
 
 func (c *Client) ListPods(ctx context.Context, namespace string) (*corev1.PodList, error) {
-	if namespace == "" {
-		return c.clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	list, err := cachedList(c.cache, ctx, "pods", namespace, func(ctx context.Context, namespace string) (*corev1.PodList, error) {
+		return c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return nil, err
 	}
-	return c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	return list.DeepCopy(), nil
 }
 
 func (c *Client) GetPod(ctx context.Context, namespace, name string) (*corev1.Pod, error) {
@@ -102,28 +151,43 @@ func (c *Client) GetPod(ctx context.Context, namespace, name string) (*corev1.Po
 }
 
 func (c *Client) ListNamespaces(ctx context.Context) (*corev1.NamespaceList, error) {
-	return c.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	list, err := cachedList(c.cache, ctx, "namespaces", "", func(ctx context.Context, _ string) (*corev1.NamespaceList, error) {
+		return c.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list.DeepCopy(), nil
 }
 
 func (c *Client) ListServices(ctx context.Context, namespace string) (*corev1.ServiceList, error) {
-	if namespace == "" {
-		return c.clientset.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	list, err := cachedList(c.cache, ctx, "services", namespace, func(ctx context.Context, namespace string) (*corev1.ServiceList, error) {
+		return c.clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return nil, err
 	}
-	return c.clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	return list.DeepCopy(), nil
 }
 
 func (c *Client) ListEvents(ctx context.Context, namespace string) (*corev1.EventList, error) {
-	if namespace == "" {
-		return c.clientset.CoreV1().Events(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	list, err := cachedList(c.cache, ctx, "events", namespace, func(ctx context.Context, namespace string) (*corev1.EventList, error) {
+		return c.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return nil, err
 	}
-	return c.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	return list.DeepCopy(), nil
 }
 
 func (c *Client) ListDeployments(ctx context.Context, namespace string) (*appsv1.DeploymentList, error) {
-	if namespace == "" {
-		return c.clientset.AppsV1().Deployments(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	list, err := cachedList(c.cache, ctx, "deployments", namespace, func(ctx context.Context, namespace string) (*appsv1.DeploymentList, error) {
+		return c.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return nil, err
 	}
-	return c.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	return list.DeepCopy(), nil
 }
 
 // StorageInfo represents basic storage information
@@ -132,6 +196,8 @@ type StorageInfo struct {
 	TotalPVCs     int     `json:"total_pvcs"`
 	StorageClasses int    `json:"storage_classes"`
 	TotalCapacity string  `json:"total_capacity"`
+	// Usage of node filesystems and mounted claims from the kubelets; nil when none answer
+	Usage *StorageUsage `json:"usage,omitempty"`
 }
 
 func (c *Client) GetStorageInfo(ctx context.Context) (*StorageInfo, error) {
@@ -159,11 +225,17 @@ func (c *Client) GetStorageInfo(ctx context.Context) (*StorageInfo, error) {
 		totalCapacity = fmt.Sprintf("%dGi", len(pvList.Items)*10) // Simplified calculation
 	}
 
+	usage, err := c.GetStorageUsage(ctx)
+	if err != nil {
+		slog.Debug("Storage usage unavailable", "error", err)
+	}
+
 	return &StorageInfo{
 		TotalPVs:       len(pvList.Items),
 		TotalPVCs:      len(pvcList.Items),
 		StorageClasses: len(scList.Items),
 		TotalCapacity:  totalCapacity,
+		Usage:          usage,
 	}, nil
 }
 
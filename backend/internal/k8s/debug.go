@@ -0,0 +1,453 @@
+package k8s
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const (
+	// DefaultDebugImage ships curl along with the busybox nslookup and nc the checks use
+	DefaultDebugImage = "curlimages/curl:8.10.1"
+	// DebugPodLabel marks the pods Denshimon runs for debugging
+	DebugPodLabel = "denshimon.io/debug"
+
+	debugPodDeadline  = 2 * time.Minute
+	debugPollInterval = time.Second
+	debugCheckTimeout = 5 // seconds per probe
+)
+
+// Connectivity check types
+const (
+	CheckDNS  = "dns"
+	CheckTCP  = "tcp"
+	CheckHTTP = "http"
+)
+
+// ErrInvalidConnectivityCheck is returned for targets, ports or paths that can't be checked
+var ErrInvalidConnectivityCheck = errors.New("invalid connectivity check")
+
+var urlPathPattern = regexp.MustCompile(`^/[A-Za-z0-9._~!$&'()*+,;=:@%/?-]*$`)
+
+// ConnectivityRequest describes checks run from a debug pod against a service or host
+type ConnectivityRequest struct {
+	Target    string `json:"target"`              // service name, service.namespace, DNS name or IP
+	Namespace string `json:"namespace,omitempty"` // where the debug pod runs, default by default
+	Node      string `json:"node,omitempty"`      // pins the debug pod to a node
+	Ports     []int  `json:"ports,omitempty"`     // probed over TCP, 80 by default
+	HTTP      bool   `json:"http,omitempty"`      // also request each port over HTTP(S)
+	HTTPS     bool   `json:"https,omitempty"`
+	Path      string `json:"path,omitempty"` // HTTP path, / by default
+	Image     string `json:"-"`
+}
+
+// ConnectivityCheck is the result of one lookup or probe
+type ConnectivityCheck struct {
+	Type       string   `json:"type"`
+	Port       int      `json:"port,omitempty"`
+	Success    bool     `json:"success"`
+	ExitCode   int      `json:"exitCode"`
+	Addresses  []string `json:"addresses,omitempty"`  // resolved by DNS checks
+	StatusCode int      `json:"statusCode,omitempty"` // of HTTP checks
+	Output     string   `json:"output,omitempty"`
+}
+
+// ConnectivityReport is the outcome of the checks from a debug pod
+type ConnectivityReport struct {
+	Target    string              `json:"target"`
+	Namespace string              `json:"namespace"`
+	Pod       string              `json:"pod"`
+	Node      string              `json:"node,omitempty"`
+	Image     string              `json:"image"`
+	Success   bool                `json:"success"` // every check succeeded
+	Checks    []ConnectivityCheck `json:"checks"`
+	Duration  string              `json:"duration"`
+}
+
+// CheckConnectivity runs DNS lookups, TCP probes and optional HTTP requests against a target
+// from a short-lived pod in the cluster, so they see the cluster's DNS, routes and network
+// policies. The pod is deleted afterwards.
+func (c *Client) CheckConnectivity(ctx context.Context, req ConnectivityRequest) (*ConnectivityReport, error) {
+	if err := normalizeConnectivityRequest(&req); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	deadline := int64(debugPodDeadline.Seconds())
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "denshimon-debug-",
+			Namespace:    req.Namespace,
+			Labels:       map[string]string{managedByLabel: "denshimon", DebugPodLabel: "connectivity"},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:         corev1.RestartPolicyNever,
+			ActiveDeadlineSeconds: &deadline,
+			NodeName:              req.Node,
+			Containers: []corev1.Container{{
+				Name:    "debug",
+				Image:   req.Image,
+				Command: []string{"sh", "-c", ConnectivityScript(req)},
+				Env: []corev1.EnvVar{
+					{Name: "TARGET", Value: req.Target},
+					{Name: "URL_PATH", Value: req.Path},
+				},
+			}},
+		},
+	}
+	created, err := c.clientset.CoreV1().Pods(req.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create debug pod: %w", err)
+	}
+	defer func() {
+		// the request may be cancelled by now; the pod must go regardless
+		_ = c.clientset.CoreV1().Pods(req.Namespace).Delete(context.Background(), created.Name, metav1.DeleteOptions{})
+	}()
+
+	finished, err := c.waitForPodCompletion(ctx, req.Namespace, created.Name)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := c.clientset.CoreV1().Pods(req.Namespace).GetLogs(created.Name, &corev1.PodLogOptions{Container: "debug"}).DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read debug pod output: %w", err)
+	}
+
+	checks := ParseConnectivityOutput(string(logs))
+	report := &ConnectivityReport{
+		Target:    req.Target,
+		Namespace: req.Namespace,
+		Pod:       created.Name,
+		Node:      finished.Spec.NodeName,
+		Image:     req.Image,
+		Success:   len(checks) > 0,
+		Checks:    checks,
+		Duration:  time.Since(start).Round(time.Millisecond).String(),
+	}
+	for _, check := range checks {
+		report.Success = report.Success && check.Success
+	}
+	return report, nil
+}
+
+func normalizeConnectivityRequest(req *ConnectivityRequest) error {
+	req.Target = strings.TrimSpace(req.Target)
+	if req.Target == "" {
+		return fmt.Errorf("%w: target is required", ErrInvalidConnectivityCheck)
+	}
+	if net.ParseIP(req.Target) == nil && len(validation.IsDNS1123Subdomain(strings.ToLower(req.Target))) > 0 {
+		return fmt.Errorf("%w: target must be a DNS name or IP address", ErrInvalidConnectivityCheck)
+	}
+	if len(req.Ports) == 0 {
+		req.Ports = []int{80}
+	}
+	for _, port := range req.Ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("%w: port %d out of range", ErrInvalidConnectivityCheck, port)
+		}
+	}
+	if req.Path == "" {
+		req.Path = "/"
+	}
+	if !urlPathPattern.MatchString(req.Path) {
+		return fmt.Errorf("%w: invalid path %q", ErrInvalidConnectivityCheck, req.Path)
+	}
+	if req.Namespace == "" {
+		req.Namespace = "default"
+	}
+	if req.Image == "" {
+		req.Image = DefaultDebugImage
+	}
+	return nil
+}
+
+// ConnectivityScript is the shell script a debug pod runs. The target and path come from the
+// environment rather than the script, and every check's output is framed by markers carrying
+// its name and exit code.
+func ConnectivityScript(req ConnectivityRequest) string {
+	var script strings.Builder
+	script.WriteString(`run() { name=$1; shift; echo "@@begin $name"; "$@" 2>&1; echo "@@end $name $?"; }` + "\n")
+	script.WriteString(`run dns nslookup "$TARGET"` + "\n")
+	for _, port := range req.Ports {
+		fmt.Fprintf(&script, `run tcp:%d nc -z -w %d "$TARGET" %d`+"\n", port, debugCheckTimeout, port)
+	}
+	if req.HTTP || req.HTTPS {
+		scheme := "http"
+		if req.HTTPS {
+			scheme = "https"
+		}
+		for _, port := range req.Ports {
+			fmt.Fprintf(&script, `run http:%d curl -sS -k -o /dev/null -m %d -w '%%{http_code}\n' "%s://$TARGET:%d$URL_PATH"`+"\n",
+				port, debugCheckTimeout*2, scheme, port)
+		}
+	}
+	script.WriteString("exit 0\n")
+	return script.String()
+}
+
+// ParseConnectivityOutput reads the checks from a debug pod's output. DNS checks list the
+// addresses after the answer's Name line and succeed with any, since nslookup also fails when
+// only the AAAA lookup does; HTTP checks succeed below status 400.
+func ParseConnectivityOutput(output string) []ConnectivityCheck {
+	checks := []ConnectivityCheck{}
+	var current *ConnectivityCheck
+	var lines []string
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "@@begin "); ok {
+			current, lines = newConnectivityCheck(name), nil
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "@@end "); ok && current != nil {
+			fields := strings.Fields(rest)
+			if len(fields) == 2 {
+				current.ExitCode, _ = strconv.Atoi(fields[1])
+			}
+			finishConnectivityCheck(current, lines)
+			checks = append(checks, *current)
+			current = nil
+			continue
+		}
+		if current != nil {
+			lines = append(lines, line)
+		}
+	}
+	return checks
+}
+
+func newConnectivityCheck(name string) *ConnectivityCheck {
+	checkType, port, _ := strings.Cut(name, ":")
+	check := &ConnectivityCheck{Type: checkType}
+	check.Port, _ = strconv.Atoi(port)
+	return check
+}
+
+func finishConnectivityCheck(check *ConnectivityCheck, lines []string) {
+	check.Output = strings.TrimSpace(strings.Join(lines, "\n"))
+	check.Success = check.ExitCode == 0
+
+	switch check.Type {
+	case CheckDNS:
+		answer := false
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "Name:") {
+				answer = true
+				continue
+			}
+			if address, ok := strings.CutPrefix(line, "Address:"); ok && answer {
+				check.Addresses = append(check.Addresses, strings.TrimSpace(address))
+			}
+		}
+		check.Success = len(check.Addresses) > 0
+	case CheckHTTP:
+		if len(lines) > 0 {
+			check.StatusCode, _ = strconv.Atoi(strings.TrimSpace(lines[len(lines)-1]))
+		}
+		check.Success = check.Success && check.StatusCode > 0 && check.StatusCode < 400
+	}
+}
+
+// waitForPodCompletion waits for a pod to succeed or fail, failing when it can't start
+func (c *Client) waitForPodCompletion(ctx context.Context, namespace, name string) (*corev1.Pod, error) {
+	var pod *corev1.Pod
+	err := wait.PollUntilContextTimeout(ctx, debugPollInterval, debugPodDeadline, true, func(ctx context.Context) (bool, error) {
+		current, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		pod = current
+		if current.Status.Phase == corev1.PodSucceeded || current.Status.Phase == corev1.PodFailed {
+			return true, nil
+		}
+		for _, status := range current.Status.ContainerStatuses {
+			if waiting := status.State.Waiting; waiting != nil && (waiting.Reason == "ErrImagePull" || waiting.Reason == "ImagePullBackOff" || waiting.Reason == "CreateContainerConfigError") {
+				return false, fmt.Errorf("debug pod can't start: %s: %s", waiting.Reason, waiting.Message)
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("debug pod didn't complete: %w", err)
+	}
+	return pod, nil
+}
+
+// Debug container images by short name
+var debugImages = map[string]string{
+	"busybox":  "busybox:1.36",
+	"netshoot": "nicolaka/netshoot:v0.13",
+}
+
+const (
+	// DebugContainerPrefix names the ephemeral containers Denshimon attaches
+	DebugContainerPrefix = "denshimon-debug-"
+
+	DefaultDebugContainerTTL = time.Hour
+	maxDebugContainerTTL     = 12 * time.Hour
+	debugContainerStartup    = time.Minute
+	// debugPIDFile holds the PID of the debug container's main process, so it can be stopped
+	debugPIDFile = "/tmp/denshimon-debug.pid"
+)
+
+// ErrInvalidDebugContainer is returned for debug containers that can't be attached
+var ErrInvalidDebugContainer = errors.New("invalid debug container")
+
+// DebugContainerRequest describes an ephemeral container to attach to a pod
+type DebugContainerRequest struct {
+	Image  string        // busybox, netshoot or an image reference; busybox by default
+	Target string        // container whose processes it sees; the first container by default
+	TTL    time.Duration // after which it stops on its own
+}
+
+// DebugContainer is an ephemeral container attached to a pod
+type DebugContainer struct {
+	Namespace string    `json:"namespace"`
+	Pod       string    `json:"pod"`
+	Container string    `json:"container"`
+	Image     string    `json:"image"`
+	Target    string    `json:"target"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Exec      string    `json:"exec,omitempty"` // terminal WebSocket path
+}
+
+// IsDebugContainer reports whether a container is an ephemeral container Denshimon attached
+func IsDebugContainer(name string) bool {
+	return strings.HasPrefix(name, DebugContainerPrefix)
+}
+
+// AttachDebugContainer adds an ephemeral container to a running pod, sharing the target
+// container's process namespace, and waits for it to run. Ephemeral containers can't be
+// removed from a pod, so it idles until the TTL and is stopped once its terminal closes.
+func (c *Client) AttachDebugContainer(ctx context.Context, namespace, podName string, req DebugContainerRequest) (*DebugContainer, error) {
+	if image, ok := debugImages[req.Image]; ok {
+		req.Image = image
+	} else if req.Image == "" {
+		req.Image = debugImages["busybox"]
+	}
+	if req.TTL == 0 {
+		req.TTL = DefaultDebugContainerTTL
+	}
+	if req.TTL < time.Minute || req.TTL > maxDebugContainerTTL {
+		return nil, fmt.Errorf("%w: ttl must be between 1m and %s", ErrInvalidDebugContainer, maxDebugContainerTTL)
+	}
+
+	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		return nil, fmt.Errorf("%w: pod is %s, not running", ErrInvalidDebugContainer, pod.Status.Phase)
+	}
+	if req.Target == "" && len(pod.Spec.Containers) > 0 {
+		req.Target = pod.Spec.Containers[0].Name
+	}
+	found := false
+	for _, container := range pod.Spec.Containers {
+		found = found || container.Name == req.Target
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: pod has no container %q", ErrInvalidDebugContainer, req.Target)
+	}
+
+	name := DebugContainerPrefix + utilrand.String(5)
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:    name,
+			Image:   req.Image,
+			Command: []string{"sh", "-c", fmt.Sprintf("echo $$ > %s; exec sleep %d", debugPIDFile, int(req.TTL.Seconds()))},
+			Stdin:   true,
+			TTY:     true,
+		},
+		TargetContainerName: req.Target,
+	})
+	if _, err := c.clientset.CoreV1().Pods(namespace).UpdateEphemeralContainers(ctx, podName, pod, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to attach debug container: %w", err)
+	}
+	if err := c.waitForEphemeralContainer(ctx, namespace, podName, name); err != nil {
+		return nil, err
+	}
+
+	return &DebugContainer{
+		Namespace: namespace,
+		Pod:       podName,
+		Container: name,
+		Image:     req.Image,
+		Target:    req.Target,
+		ExpiresAt: time.Now().Add(req.TTL),
+	}, nil
+}
+
+// waitForEphemeralContainer waits for an ephemeral container to run, failing when it can't start
+func (c *Client) waitForEphemeralContainer(ctx context.Context, namespace, podName, name string) error {
+	err := wait.PollUntilContextTimeout(ctx, debugPollInterval, debugContainerStartup, true, func(ctx context.Context) (bool, error) {
+		pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			if status.Name != name {
+				continue
+			}
+			switch {
+			case status.State.Running != nil:
+				return true, nil
+			case status.State.Terminated != nil:
+				return false, fmt.Errorf("debug container exited: %s", status.State.Terminated.Reason)
+			case status.State.Waiting != nil && (status.State.Waiting.Reason == "ErrImagePull" || status.State.Waiting.Reason == "ImagePullBackOff"):
+				return false, fmt.Errorf("debug container can't start: %s: %s", status.State.Waiting.Reason, status.State.Waiting.Message)
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("debug container didn't start: %w", err)
+	}
+	return nil
+}
+
+// StopDebugContainer ends a debug container's main process, which stops the container.
+// Its spec stays in the pod, as ephemeral containers can't be removed.
+func (c *Client) StopDebugContainer(ctx context.Context, namespace, podName, name string) error {
+	if !IsDebugContainer(name) {
+		return fmt.Errorf("%w: %s wasn't attached by Denshimon", ErrInvalidDebugContainer, name)
+	}
+	req := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: name,
+			Command:   []string{"sh", "-c", "kill $(cat " + debugPIDFile + ")"},
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(c.config, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("failed to create executor: %w", err)
+	}
+	var stderr bytes.Buffer
+	if err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: io.Discard, Stderr: &stderr}); err != nil {
+		return fmt.Errorf("failed to stop debug container: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNormalizeConnectivityRequest(t *testing.T) {
+	req := ConnectivityRequest{Target: " api.prod.svc.cluster.local "}
+	if err := normalizeConnectivityRequest(&req); err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if req.Target != "api.prod.svc.cluster.local" || req.Namespace != "default" || req.Path != "/" ||
+		req.Image != DefaultDebugImage || !reflect.DeepEqual(req.Ports, []int{80}) {
+		t.Errorf("defaults = %+v", req)
+	}
+
+	for name, invalid := range map[string]ConnectivityRequest{
+		"empty":          {},
+		"shell in host":  {Target: "api; rm -rf /"},
+		"port":           {Target: "10.0.0.1", Ports: []int{70000}},
+		"path":           {Target: "api", Path: "/health\"; id"},
+		"path no leader": {Target: "api", Path: "health"},
+	} {
+		if err := normalizeConnectivityRequest(&invalid); !errors.Is(err, ErrInvalidConnectivityCheck) {
+			t.Errorf("%s: err = %v, want ErrInvalidConnectivityCheck", name, err)
+		}
+	}
+}
+
+func TestConnectivityScript(t *testing.T) {
+	script := ConnectivityScript(ConnectivityRequest{Target: "api", Ports: []int{80, 8443}, HTTPS: true})
+	for _, want := range []string{
+		`run dns nslookup "$TARGET"`,
+		`run tcp:8443 nc -z -w 5 "$TARGET" 8443`,
+		`run http:80 curl -sS -k -o /dev/null -m 10 -w '%{http_code}\n' "https://$TARGET:80$URL_PATH"`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %q:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, "api") {
+		t.Error("target interpolated into the script instead of read from the environment")
+	}
+}
+
+func TestParseConnectivityOutput(t *testing.T) {
+	output := `@@begin dns
+Server:		10.96.0.10
+Address:	10.96.0.10:53
+
+Name:	api.prod.svc.cluster.local
+Address: 10.96.14.2
+
+** server can't find api.prod.svc.cluster.local: NXDOMAIN
+@@end dns 1
+@@begin tcp:80
+@@end tcp:80 0
+@@begin tcp:5432
+nc: api.prod.svc.cluster.local (10.96.14.2:5432): Operation timed out
+@@end tcp:5432 1
+@@begin http:80
+503
+@@end http:80 0
+`
+	want := []ConnectivityCheck{
+		{Type: CheckDNS, Success: true, ExitCode: 1, Addresses: []string{"10.96.14.2"}},
+		{Type: CheckTCP, Port: 80, Success: true},
+		{Type: CheckTCP, Port: 5432, ExitCode: 1, Output: "nc: api.prod.svc.cluster.local (10.96.14.2:5432): Operation timed out"},
+		{Type: CheckHTTP, Port: 80, StatusCode: 503, Output: "503"},
+	}
+	got := ParseConnectivityOutput(output)
+	for i := range got {
+		if got[i].Type == CheckDNS {
+			got[i].Output = "" // nslookup's full output isn't under test
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseConnectivityOutput =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestAttachDebugContainer(t *testing.T) {
+	ctx := context.Background()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-0", Namespace: "prod"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "sidecar"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	clientset := fake.NewSimpleClientset(pod)
+	// the kubelet starts the container as soon as it's added
+	clientset.PrependReactor("update", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "ephemeralcontainers" {
+			return false, nil, nil
+		}
+		updated := action.(k8stesting.UpdateAction).GetObject().(*corev1.Pod).DeepCopy()
+		for _, container := range updated.Spec.EphemeralContainers {
+			updated.Status.EphemeralContainerStatuses = append(updated.Status.EphemeralContainerStatuses, corev1.ContainerStatus{
+				Name:  container.Name,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			})
+		}
+		return true, updated, clientset.Tracker().Update(corev1.SchemeGroupVersion.WithResource("pods"), updated, "prod")
+	})
+	client := &Client{clientset: clientset}
+
+	container, err := client.AttachDebugContainer(ctx, "prod", "api-0", DebugContainerRequest{Image: "netshoot", TTL: 30 * time.Minute})
+	if err != nil {
+		t.Fatalf("AttachDebugContainer: %v", err)
+	}
+	if !IsDebugContainer(container.Container) || container.Image != debugImages["netshoot"] || container.Target != "app" {
+		t.Errorf("container = %+v", container)
+	}
+
+	updated, _ := clientset.CoreV1().Pods("prod").Get(ctx, "api-0", metav1.GetOptions{})
+	if len(updated.Spec.EphemeralContainers) != 1 {
+		t.Fatalf("ephemeral containers = %d, want 1", len(updated.Spec.EphemeralContainers))
+	}
+	ephemeral := updated.Spec.EphemeralContainers[0]
+	if ephemeral.TargetContainerName != "app" || !strings.Contains(ephemeral.Command[2], "exec sleep 1800") {
+		t.Errorf("ephemeral container = %+v", ephemeral)
+	}
+
+	for name, req := range map[string]DebugContainerRequest{
+		"unknown target": {Target: "db"},
+		"ttl too long":   {TTL: 24 * time.Hour},
+	} {
+		if _, err := client.AttachDebugContainer(ctx, "prod", "api-0", req); !errors.Is(err, ErrInvalidDebugContainer) {
+			t.Errorf("%s: err = %v, want ErrInvalidDebugContainer", name, err)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// BudgetConflict describes a PodDisruptionBudget that a drain or rollout would violate
+type BudgetConflict struct {
+	Namespace string `json:"namespace"`
+	Budget    string `json:"budget"`
+	// Pods are the budget's pods the change would take down
+	Pods               []string `json:"pods"`
+	DisruptionsAllowed int32    `json:"disruptionsAllowed"`
+	Message            string   `json:"message"`
+}
+
+// ListPodDisruptionBudgets lists disruption budgets in a namespace, or all namespaces when empty
+func (c *Client) ListPodDisruptionBudgets(ctx context.Context, namespace string) (*policyv1.PodDisruptionBudgetList, error) {
+	if namespace == "" {
+		namespace = metav1.NamespaceAll
+	}
+	return c.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+}
+
+// GetPodDisruptionBudget returns a single disruption budget
+func (c *Client) GetPodDisruptionBudget(ctx context.Context, namespace, name string) (*policyv1.PodDisruptionBudget, error) {
+	return c.clientset.PolicyV1().PodDisruptionBudgets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// CreatePodDisruptionBudget creates a disruption budget in its namespace
+func (c *Client) CreatePodDisruptionBudget(ctx context.Context, budget *policyv1.PodDisruptionBudget) (*policyv1.PodDisruptionBudget, error) {
+	if err := validateBudget(budget); err != nil {
+		return nil, err
+	}
+	return c.clientset.PolicyV1().PodDisruptionBudgets(budget.Namespace).Create(ctx, budget, metav1.CreateOptions{})
+}
+
+// UpdatePodDisruptionBudget replaces the spec, labels and annotations of an existing disruption budget
+func (c *Client) UpdatePodDisruptionBudget(ctx context.Context, budget *policyv1.PodDisruptionBudget) (*policyv1.PodDisruptionBudget, error) {
+	if err := validateBudget(budget); err != nil {
+		return nil, err
+	}
+	current, err := c.GetPodDisruptionBudget(ctx, budget.Namespace, budget.Name)
+	if err != nil {
+		return nil, err
+	}
+	current.Spec = budget.Spec
+	current.Labels = budget.Labels
+	current.Annotations = budget.Annotations
+	return c.clientset.PolicyV1().PodDisruptionBudgets(budget.Namespace).Update(ctx, current, metav1.UpdateOptions{})
+}
+
+// DeletePodDisruptionBudget deletes a disruption budget
+func (c *Client) DeletePodDisruptionBudget(ctx context.Context, namespace, name string) error {
+	return c.clientset.PolicyV1().PodDisruptionBudgets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// ErrInvalidBudget is returned for budgets the API server would reject
+var ErrInvalidBudget = errors.New("invalid disruption budget")
+
+func validateBudget(budget *policyv1.PodDisruptionBudget) error {
+	if budget.Spec.Selector == nil {
+		return fmt.Errorf("%w: selector is required", ErrInvalidBudget)
+	}
+	if (budget.Spec.MinAvailable == nil) == (budget.Spec.MaxUnavailable == nil) {
+		return fmt.Errorf("%w: set exactly one of minAvailable and maxUnavailable", ErrInvalidBudget)
+	}
+	return nil
+}
+
+// CheckDrainBudgets reports the disruption budgets draining a node would violate, i.e.
+// budgets with more pods on the node than they currently allow to be disrupted. Pods the
+// drain would skip (mirror, DaemonSet and terminated pods) are not counted.
+func (c *Client) CheckDrainBudgets(ctx context.Context, node string, opts DrainOptions) ([]BudgetConflict, error) {
+	pods, err := c.ListPods(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	var evicted []corev1.Pod
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != node {
+			continue
+		}
+		// Pods that would stop the drain are reported by the drain itself
+		if skip, _, err := drainFilter(pod, opts); err == nil && !skip {
+			evicted = append(evicted, pod)
+		}
+	}
+	if len(evicted) == 0 {
+		return []BudgetConflict{}, nil
+	}
+
+	budgets, err := c.ListPodDisruptionBudgets(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list disruption budgets: %w", err)
+	}
+	return DrainBudgetConflicts(budgets.Items, evicted), nil
+}
+
+// DrainBudgetConflicts returns the budgets that allow fewer disruptions than the number of
+// their pods about to be evicted
+func DrainBudgetConflicts(budgets []policyv1.PodDisruptionBudget, evicted []corev1.Pod) []BudgetConflict {
+	conflicts := []BudgetConflict{}
+	for _, budget := range budgets {
+		selector, err := metav1.LabelSelectorAsSelector(budget.Spec.Selector)
+		if err != nil || selector.Empty() {
+			continue
+		}
+		var pods []string
+		for _, pod := range evicted {
+			if pod.Namespace == budget.Namespace && selector.Matches(labels.Set(pod.Labels)) {
+				pods = append(pods, pod.Name)
+			}
+		}
+		if int32(len(pods)) <= budget.Status.DisruptionsAllowed {
+			continue
+		}
+		sort.Strings(pods)
+		conflicts = append(conflicts, BudgetConflict{
+			Namespace:          budget.Namespace,
+			Budget:             budget.Name,
+			Pods:               pods,
+			DisruptionsAllowed: budget.Status.DisruptionsAllowed,
+			Message: fmt.Sprintf("PodDisruptionBudget %s/%s allows %d disruption(s) but %d of its pods are on the node (%s); evictions will wait until replacements are ready elsewhere",
+				budget.Namespace, budget.Name, budget.Status.DisruptionsAllowed, len(pods), describeBudget(budget)),
+		})
+	}
+	return conflicts
+}
+
+// CheckWorkloadBudgets reports the disruption budgets in namespace selecting pods with
+// podLabels that a workload of replicas pods would violate while up to unavailable of
+// them are down during a rollout
+func (c *Client) CheckWorkloadBudgets(ctx context.Context, namespace string, podLabels map[string]string, replicas, unavailable int32) ([]BudgetConflict, error) {
+	budgets, err := c.ListPodDisruptionBudgets(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list disruption budgets: %w", err)
+	}
+	return WorkloadBudgetConflicts(budgets.Items, podLabels, replicas, unavailable), nil
+}
+
+// WorkloadBudgetConflicts checks a workload's replica count and rollout against the budgets
+// selecting its pods. Budgets are evaluated as if the workload's pods were all they select.
+func WorkloadBudgetConflicts(budgets []policyv1.PodDisruptionBudget, podLabels map[string]string, replicas, unavailable int32) []BudgetConflict {
+	conflicts := []BudgetConflict{}
+	for _, budget := range budgets {
+		selector, err := metav1.LabelSelectorAsSelector(budget.Spec.Selector)
+		if err != nil || selector.Empty() || !selector.Matches(labels.Set(podLabels)) {
+			continue
+		}
+
+		var message string
+		switch {
+		case budget.Spec.MinAvailable != nil:
+			minAvailable, err := intstr.GetScaledValueFromIntOrPercent(budget.Spec.MinAvailable, int(replicas), true)
+			if err != nil {
+				continue
+			}
+			if int(replicas) <= minAvailable {
+				message = fmt.Sprintf("%d replica(s) with minAvailable %d of PodDisruptionBudget %s/%s leave no pod evictable, which blocks node drains",
+					replicas, minAvailable, budget.Namespace, budget.Name)
+			} else if int(replicas-unavailable) < minAvailable {
+				message = fmt.Sprintf("the rollout may take %d of %d pods down, below minAvailable %d of PodDisruptionBudget %s/%s",
+					unavailable, replicas, minAvailable, budget.Namespace, budget.Name)
+			}
+		case budget.Spec.MaxUnavailable != nil:
+			maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(budget.Spec.MaxUnavailable, int(replicas), true)
+			if err != nil {
+				continue
+			}
+			if maxUnavailable == 0 {
+				message = fmt.Sprintf("PodDisruptionBudget %s/%s allows no pod to be unavailable, which blocks node drains",
+					budget.Namespace, budget.Name)
+			} else if int(unavailable) > maxUnavailable {
+				message = fmt.Sprintf("the rollout may take %d pods down, more than maxUnavailable %d of PodDisruptionBudget %s/%s",
+					unavailable, maxUnavailable, budget.Namespace, budget.Name)
+			}
+		}
+		if message == "" {
+			continue
+		}
+		conflicts = append(conflicts, BudgetConflict{
+			Namespace:          budget.Namespace,
+			Budget:             budget.Name,
+			Pods:               []string{},
+			DisruptionsAllowed: budget.Status.DisruptionsAllowed,
+			Message:            message,
+		})
+	}
+	return conflicts
+}
+
+// blockingBudgets describes the budgets selecting a pod whose eviction was refused
+func (c *Client) blockingBudgets(ctx context.Context, pod corev1.Pod) string {
+	budgets, err := c.ListPodDisruptionBudgets(ctx, pod.Namespace)
+	if err != nil {
+		return ""
+	}
+	var names []string
+	for _, budget := range budgets.Items {
+		selector, err := metav1.LabelSelectorAsSelector(budget.Spec.Selector)
+		if err != nil || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		names = append(names, fmt.Sprintf("%s (%s, %d disruption(s) allowed, %d/%d healthy)",
+			budget.Name, describeBudget(budget), budget.Status.DisruptionsAllowed, budget.Status.CurrentHealthy, budget.Status.ExpectedPods))
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return "blocked by PodDisruptionBudget " + strings.Join(names, ", ")
+}
+
+func describeBudget(budget policyv1.PodDisruptionBudget) string {
+	if budget.Spec.MinAvailable != nil {
+		return "minAvailable " + budget.Spec.MinAvailable.String()
+	}
+	if budget.Spec.MaxUnavailable != nil {
+		return "maxUnavailable " + budget.Spec.MaxUnavailable.String()
+	}
+	return "no limit"
+}
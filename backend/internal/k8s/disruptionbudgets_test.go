@@ -0,0 +1,103 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func budget(name string, minAvailable, maxUnavailable *intstr.IntOrString, allowed int32) policyv1.PodDisruptionBudget {
+	return policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			MinAvailable:   minAvailable,
+			MaxUnavailable: maxUnavailable,
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: allowed},
+	}
+}
+
+func intOrString(value intstr.IntOrString) *intstr.IntOrString {
+	return &value
+}
+
+func TestDrainBudgetConflicts(t *testing.T) {
+	pod := func(namespace, name, app string) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": app}}}
+	}
+	evicted := []corev1.Pod{
+		pod("default", "api-1", "api"),
+		pod("default", "api-2", "api"),
+		pod("default", "web-1", "web"),
+		pod("other", "api-1", "api"),
+	}
+
+	conflicts := DrainBudgetConflicts([]policyv1.PodDisruptionBudget{
+		budget("api", intOrString(intstr.FromInt32(2)), nil, 1),
+	}, evicted)
+	if len(conflicts) != 1 || len(conflicts[0].Pods) != 2 || conflicts[0].Budget != "api" {
+		t.Fatalf("expected the api budget with 2 pods, got %+v", conflicts)
+	}
+
+	if conflicts := DrainBudgetConflicts([]policyv1.PodDisruptionBudget{
+		budget("api", intOrString(intstr.FromInt32(1)), nil, 2),
+	}, evicted); len(conflicts) != 0 {
+		t.Errorf("budget allowing both evictions reported: %+v", conflicts)
+	}
+}
+
+func TestWorkloadBudgetConflicts(t *testing.T) {
+	labels := map[string]string{"app": "api", "managed-by": "denshimon"}
+	tests := []struct {
+		name        string
+		budget      policyv1.PodDisruptionBudget
+		replicas    int32
+		unavailable int32
+		conflict    bool
+	}{
+		{"min available met", budget("b", intOrString(intstr.FromInt32(2)), nil, 1), 3, 1, false},
+		{"rollout below min available", budget("b", intOrString(intstr.FromInt32(2)), nil, 1), 3, 2, true},
+		{"replicas equal min available", budget("b", intOrString(intstr.FromInt32(2)), nil, 0), 2, 0, true},
+		{"percentage rounds up", budget("b", intOrString(intstr.FromString("50%")), nil, 1), 3, 1, false},
+		{"max unavailable exceeded", budget("b", nil, intOrString(intstr.FromInt32(1)), 1), 4, 4, true},
+		{"max unavailable zero", budget("b", nil, intOrString(intstr.FromInt32(0)), 0), 4, 0, true},
+	}
+	for _, tt := range tests {
+		conflicts := WorkloadBudgetConflicts([]policyv1.PodDisruptionBudget{tt.budget}, labels, tt.replicas, tt.unavailable)
+		if got := len(conflicts) > 0; got != tt.conflict {
+			t.Errorf("%s: conflict = %v, want %v (%+v)", tt.name, got, tt.conflict, conflicts)
+		}
+	}
+
+	other := budget("b", intOrString(intstr.FromInt32(5)), nil, 0)
+	other.Spec.Selector.MatchLabels["app"] = "web"
+	if conflicts := WorkloadBudgetConflicts([]policyv1.PodDisruptionBudget{other}, labels, 1, 1); len(conflicts) != 0 {
+		t.Errorf("budget for other pods reported: %+v", conflicts)
+	}
+}
+
+func TestCreatePodDisruptionBudgetValidation(t *testing.T) {
+	client := &Client{clientset: fake.NewSimpleClientset()}
+	ctx := context.Background()
+
+	both := budget("both", intOrString(intstr.FromInt32(1)), intOrString(intstr.FromInt32(1)), 0)
+	if _, err := client.CreatePodDisruptionBudget(ctx, &both); !errors.Is(err, ErrInvalidBudget) {
+		t.Errorf("expected ErrInvalidBudget for both limits, got %v", err)
+	}
+	valid := budget("valid", intOrString(intstr.FromInt32(1)), nil, 0)
+	if _, err := client.CreatePodDisruptionBudget(ctx, &valid); err != nil {
+		t.Fatalf("CreatePodDisruptionBudget failed: %v", err)
+	}
+
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "default", Labels: map[string]string{"app": "api"}}}
+	if message := client.blockingBudgets(ctx, pod); message == "" {
+		t.Error("expected the blocking budget to be named")
+	}
+}
@@ -0,0 +1,235 @@
+package k8s
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// UserAccessLabel names the Denshimon user a service account or role binding was minted for
+	UserAccessLabel = "denshimon.io/user"
+	managedByLabel  = "app.kubernetes.io/managed-by"
+
+	DefaultKubeconfigDuration = 24 * time.Hour
+	// MaxKubeconfigDuration caps token lifetimes; a kubeconfig is re-minted rather than kept
+	MaxKubeconfigDuration = 90 * 24 * time.Hour
+	// minKubeconfigDuration is the shortest token the API server issues
+	minKubeconfigDuration = 10 * time.Minute
+)
+
+// ErrInvalidKubeconfigRequest is returned for kubeconfig requests that can't be minted
+var ErrInvalidKubeconfigRequest = errors.New("invalid kubeconfig request")
+
+var invalidNameCharacters = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// KubeconfigRequest describes the restricted access a kubeconfig grants: ClusterRole bound
+// in each namespace, through a service account in the first one
+type KubeconfigRequest struct {
+	User        string
+	Namespaces  []string
+	ClusterRole string // e.g. view, edit or admin
+	Duration    time.Duration
+	// Server is the API server address written into the kubeconfig; empty uses the address
+	// Denshimon connects to, which is cluster-internal when running in a pod
+	Server string
+}
+
+// UserKubeconfig is a minted kubeconfig and the access behind it
+type UserKubeconfig struct {
+	ServiceAccount string    `json:"service_account"`
+	Namespace      string    `json:"namespace"` // of the service account
+	Namespaces     []string  `json:"namespaces"`
+	ClusterRole    string    `json:"cluster_role"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	Kubeconfig     string    `json:"kubeconfig"`
+}
+
+// UserServiceAccountName is the service account minted for a Denshimon user. The user
+// name is made a valid DNS label, and a hash of the original keeps names that map to the
+// same label, such as Alice and alice, apart.
+func UserServiceAccountName(user string) string {
+	sum := sha256.Sum256([]byte(user))
+	suffix := hex.EncodeToString(sum[:4])
+
+	name := strings.Trim(invalidNameCharacters.ReplaceAllString(strings.ToLower(user), "-"), "-")
+	name = "denshimon-user-" + name
+	if maxLength := 63 - len(suffix) - 1; len(name) > maxLength {
+		name = strings.TrimRight(name[:maxLength], "-")
+	}
+	return name + "-" + suffix
+}
+
+// CreateUserKubeconfig creates or updates the user's service account and role bindings,
+// removes bindings in namespaces no longer requested, and returns a kubeconfig holding a
+// token bound to the service account that expires after the requested duration
+func (c *Client) CreateUserKubeconfig(ctx context.Context, req KubeconfigRequest) (*UserKubeconfig, error) {
+	if req.User == "" || len(req.Namespaces) == 0 || req.ClusterRole == "" {
+		return nil, fmt.Errorf("%w: user, namespaces and role are required", ErrInvalidKubeconfigRequest)
+	}
+	if req.Duration == 0 {
+		req.Duration = DefaultKubeconfigDuration
+	}
+	if req.Duration < minKubeconfigDuration || req.Duration > MaxKubeconfigDuration {
+		return nil, fmt.Errorf("%w: duration must be between %s and %s", ErrInvalidKubeconfigRequest, minKubeconfigDuration, MaxKubeconfigDuration)
+	}
+
+	name := UserServiceAccountName(req.User)
+	home := req.Namespaces[0]
+	labels := map[string]string{managedByLabel: "denshimon", UserAccessLabel: name}
+
+	account := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: home, Labels: labels}}
+	if _, err := c.clientset.CoreV1().ServiceAccounts(home).Create(ctx, account, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create service account: %w", err)
+	}
+
+	requested := make(map[string]bool, len(req.Namespaces))
+	for _, namespace := range req.Namespaces {
+		requested[namespace] = true
+		if err := c.bindUserRole(ctx, namespace, name, home, req.ClusterRole, labels); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.deleteUserBindings(ctx, name, requested); err != nil {
+		return nil, err
+	}
+
+	expiration := int64(req.Duration.Seconds())
+	token, err := c.clientset.CoreV1().ServiceAccounts(home).CreateToken(ctx, name, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expiration},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service account token: %w", err)
+	}
+
+	kubeconfig, err := c.kubeconfig(req.Server, name, home, token.Status.Token)
+	if err != nil {
+		return nil, err
+	}
+	return &UserKubeconfig{
+		ServiceAccount: name,
+		Namespace:      home,
+		Namespaces:     req.Namespaces,
+		ClusterRole:    req.ClusterRole,
+		ExpiresAt:      token.Status.ExpirationTimestamp.Time,
+		Kubeconfig:     string(kubeconfig),
+	}, nil
+}
+
+// bindUserRole binds the cluster role to the service account in a namespace. A binding
+// to another role is replaced, since a binding's role can't change.
+func (c *Client) bindUserRole(ctx context.Context, namespace, name, home, clusterRole string, labels map[string]string) error {
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: clusterRole},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: name, Namespace: home}},
+	}
+	bindings := c.clientset.RbacV1().RoleBindings(namespace)
+	existing, err := bindings.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return fmt.Errorf("failed to get role binding in %s: %w", namespace, err)
+	case existing.RoleRef == binding.RoleRef:
+		existing.Subjects = binding.Subjects
+		if _, err := bindings.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update role binding in %s: %w", namespace, err)
+		}
+		return nil
+	default:
+		if err := bindings.Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+			return fmt.Errorf("failed to replace role binding in %s: %w", namespace, err)
+		}
+	}
+	if _, err := bindings.Create(ctx, binding, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create role binding in %s: %w", namespace, err)
+	}
+	return nil
+}
+
+// deleteUserBindings deletes the service account's role bindings outside the kept namespaces
+func (c *Client) deleteUserBindings(ctx context.Context, name string, keep map[string]bool) error {
+	bindings, err := c.clientset.RbacV1().RoleBindings(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: UserAccessLabel + "=" + name,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list role bindings: %w", err)
+	}
+	for _, binding := range bindings.Items {
+		if keep[binding.Namespace] {
+			continue
+		}
+		if err := c.clientset.RbacV1().RoleBindings(binding.Namespace).Delete(ctx, binding.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete role binding in %s: %w", binding.Namespace, err)
+		}
+	}
+	return nil
+}
+
+// RevokeUserKubeconfig deletes the user's service account and role bindings, invalidating
+// every token minted for it
+func (c *Client) RevokeUserKubeconfig(ctx context.Context, user string) error {
+	name := UserServiceAccountName(user)
+	if err := c.deleteUserBindings(ctx, name, nil); err != nil {
+		return err
+	}
+	accounts, err := c.clientset.CoreV1().ServiceAccounts(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: UserAccessLabel + "=" + name,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list service accounts: %w", err)
+	}
+	if len(accounts.Items) == 0 {
+		return apierrors.NewNotFound(corev1.Resource("serviceaccounts"), name)
+	}
+	for _, account := range accounts.Items {
+		if err := c.clientset.CoreV1().ServiceAccounts(account.Namespace).Delete(ctx, account.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete service account: %w", err)
+		}
+	}
+	return nil
+}
+
+// kubeconfig writes a kubeconfig for a token, trusting the CA Denshimon trusts
+func (c *Client) kubeconfig(server, name, namespace, token string) ([]byte, error) {
+	if server == "" && c.config != nil {
+		server = c.config.Host
+	}
+	if server == "" {
+		return nil, fmt.Errorf("%w: API server address unknown", ErrInvalidKubeconfigRequest)
+	}
+
+	cluster := &clientcmdapi.Cluster{Server: server}
+	if c.config != nil {
+		cluster.InsecureSkipTLSVerify = c.config.Insecure
+		cluster.CertificateAuthorityData = c.config.CAData
+		if len(cluster.CertificateAuthorityData) == 0 && c.config.CAFile != "" {
+			data, err := os.ReadFile(c.config.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read cluster CA: %w", err)
+			}
+			cluster.CertificateAuthorityData = data
+		}
+	}
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["denshimon"] = cluster
+	config.AuthInfos[name] = &clientcmdapi.AuthInfo{Token: token}
+	config.Contexts[name] = &clientcmdapi.Context{Cluster: "denshimon", AuthInfo: name, Namespace: namespace}
+	config.CurrentContext = name
+	return clientcmd.Write(*config)
+}
@@ -0,0 +1,120 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestUserServiceAccountName(t *testing.T) {
+	tests := map[string]string{
+		"alice":             "denshimon-user-alice-",
+		"Bob.Smith@corp.io": "denshimon-user-bob-smith-corp-io-",
+		"__x__":             "denshimon-user-x-",
+	}
+	for user, prefix := range tests {
+		if got := UserServiceAccountName(user); !strings.HasPrefix(got, prefix) || len(got) != len(prefix)+8 {
+			t.Errorf("UserServiceAccountName(%q) = %q, want %q and a hash", user, got, prefix)
+		}
+	}
+
+	// Users whose names map to the same label get their own account
+	names := map[string]string{}
+	for _, user := range []string{
+		"alice", "Alice", "alice.smith", "alice-smith", "alice_smith",
+		"a-very-long-user-name-that-goes-on-and-on-and-on-forever-one",
+		"a-very-long-user-name-that-goes-on-and-on-and-on-forever-two",
+	} {
+		name := UserServiceAccountName(user)
+		if len(name) > 63 {
+			t.Errorf("name %q longer than 63 characters", name)
+		}
+		if other, ok := names[name]; ok {
+			t.Errorf("%q and %q share the service account %q", user, other, name)
+		}
+		names[name] = user
+	}
+	if UserServiceAccountName("alice") != UserServiceAccountName("alice") {
+		t.Error("names are not stable")
+	}
+}
+
+func TestCreateUserKubeconfig(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		request := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest)
+		expires := time.Now().Add(time.Duration(*request.Spec.ExpirationSeconds) * time.Second)
+		request.Status = authenticationv1.TokenRequestStatus{Token: "minted", ExpirationTimestamp: metav1.NewTime(expires)}
+		return true, request, nil
+	})
+	client := &Client{clientset: clientset, config: &rest.Config{Host: "https://10.0.0.1:6443"}}
+
+	if _, err := client.CreateUserKubeconfig(ctx, KubeconfigRequest{User: "alice", Namespaces: []string{"dev", "staging"}, ClusterRole: "view"}); err != nil {
+		t.Fatalf("CreateUserKubeconfig: %v", err)
+	}
+	minted, err := client.CreateUserKubeconfig(ctx, KubeconfigRequest{
+		User: "alice", Namespaces: []string{"dev"}, ClusterRole: "edit", Server: "https://k8s.example.com",
+	})
+	if err != nil {
+		t.Fatalf("CreateUserKubeconfig: %v", err)
+	}
+
+	binding, err := clientset.RbacV1().RoleBindings("dev").Get(ctx, UserServiceAccountName("alice"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("binding in dev: %v", err)
+	}
+	if binding.RoleRef.Name != "edit" || binding.Subjects[0].Kind != rbacv1.ServiceAccountKind || binding.Subjects[0].Namespace != "dev" {
+		t.Errorf("binding = %+v, want edit for the dev service account", binding)
+	}
+	if _, err := clientset.RbacV1().RoleBindings("staging").Get(ctx, UserServiceAccountName("alice"), metav1.GetOptions{}); err == nil {
+		t.Error("binding in staging kept after the namespace was dropped")
+	}
+
+	config, err := clientcmd.Load([]byte(minted.Kubeconfig))
+	if err != nil {
+		t.Fatalf("kubeconfig doesn't load: %v", err)
+	}
+	current := config.Contexts[config.CurrentContext]
+	if current.Namespace != "dev" || config.AuthInfos[current.AuthInfo].Token != "minted" ||
+		config.Clusters[current.Cluster].Server != "https://k8s.example.com" {
+		t.Errorf("kubeconfig context = %+v", current)
+	}
+	if time.Until(minted.ExpiresAt) < 23*time.Hour {
+		t.Errorf("expires at %s, want the default day", minted.ExpiresAt)
+	}
+
+	if err := client.RevokeUserKubeconfig(ctx, "alice"); err != nil {
+		t.Fatalf("RevokeUserKubeconfig: %v", err)
+	}
+	if _, err := clientset.CoreV1().ServiceAccounts("dev").Get(ctx, UserServiceAccountName("alice"), metav1.GetOptions{}); err == nil {
+		t.Error("service account kept after revoking")
+	}
+}
+
+func TestCreateUserKubeconfigValidation(t *testing.T) {
+	client := &Client{clientset: fake.NewSimpleClientset(), config: &rest.Config{Host: "https://10.0.0.1:6443"}}
+	for name, req := range map[string]KubeconfigRequest{
+		"no namespaces": {User: "alice", ClusterRole: "view"},
+		"too short":     {User: "alice", Namespaces: []string{"dev"}, ClusterRole: "view", Duration: time.Minute},
+		"too long":      {User: "alice", Namespaces: []string{"dev"}, ClusterRole: "view", Duration: 365 * 24 * time.Hour},
+	} {
+		if _, err := client.CreateUserKubeconfig(context.Background(), req); !errors.Is(err, ErrInvalidKubeconfigRequest) {
+			t.Errorf("%s: err = %v, want ErrInvalidKubeconfigRequest", name, err)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// DefaultFieldManager is the field manager recorded for server-side applies made through the API
+const DefaultFieldManager = "denshimon"
+
+// ApplyOptions configures a server-side apply
+type ApplyOptions struct {
+	Namespace    string // default namespace for namespaced objects that do not set one
+	DryRun       bool
+	Force        bool // take ownership of fields managed by other field managers
+	FieldManager string
+}
+
+// FieldChange describes a single difference between the live and applied object
+type FieldChange struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"` // add, remove, replace
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// ApplyResult reports the outcome of applying one manifest document
+type ApplyResult struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Namespace  string        `json:"namespace,omitempty"`
+	Name       string        `json:"name"`
+	Action     string        `json:"action"` // created, configured, unchanged, deleted, absent, failed
+	DryRun     bool          `json:"dryRun"`
+	Changes    []FieldChange `json:"changes"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// noisyMetadataFields are server-populated fields excluded from diffs
+var noisyMetadataFields = []string{"managedFields", "resourceVersion", "generation", "uid", "creationTimestamp", "selfLink"}
+
+// ResolveResource maps a user-supplied kind ("deployment", "Deployment", "deployments.apps", "cronjobs.v1.batch")
+// to its resource and reports whether it is namespaced
+func (c *Client) ResolveResource(kind string) (schema.GroupVersionResource, bool, error) {
+	var partial schema.GroupVersionResource
+	if fullySpecified, groupResource := schema.ParseResourceArg(strings.ToLower(kind)); fullySpecified != nil {
+		partial = *fullySpecified
+	} else {
+		partial = groupResource.WithVersion("")
+	}
+
+	gvr, err := c.mapper.ResourceFor(partial)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, fmt.Errorf("unknown resource kind %q: %w", kind, err)
+	}
+
+	gvk, err := c.mapper.KindFor(gvr)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, fmt.Errorf("unknown resource kind %q: %w", kind, err)
+	}
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, err
+	}
+
+	return gvr, mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
+
+// GetManifest returns the live object for any resource kind
+func (c *Client) GetManifest(ctx context.Context, kind, namespace, name string, includeManagedFields bool) (*unstructured.Unstructured, error) {
+	gvr, namespaced, err := c.ResolveResource(kind)
+	if err != nil {
+		return nil, err
+	}
+	if !namespaced {
+		namespace = ""
+	} else if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	obj, err := c.dynamic.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if !includeManagedFields {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "managedFields")
+	}
+	return obj, nil
+}
+
+// ListObjects returns the live objects of any resource kind in every namespace, with their
+// managed fields
+func (c *Client) ListObjects(ctx context.Context, kind string) ([]unstructured.Unstructured, error) {
+	gvr, _, err := c.ResolveResource(kind)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.dynamic.Resource(gvr).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+	}
+	return list.Items, nil
+}
+
+// ApplyManifests server-side applies every document in a single or multi-document YAML (or JSON) payload.
+// Documents are applied in order; a failing document is reported in its result and does not stop the rest.
+func (c *Client) ApplyManifests(ctx context.Context, data []byte, opts ApplyOptions) ([]ApplyResult, error) {
+	objects, err := DecodeManifests(data)
+	if err != nil {
+		return nil, err
+	}
+	if opts.FieldManager == "" {
+		opts.FieldManager = DefaultFieldManager
+	}
+
+	results := make([]ApplyResult, 0, len(objects))
+	for _, obj := range objects {
+		result, err := c.applyObject(ctx, obj, opts)
+		if err != nil {
+			result.Action = "failed"
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (c *Client) applyObject(ctx context.Context, obj *unstructured.Unstructured, opts ApplyOptions) (ApplyResult, error) {
+	gvk := obj.GroupVersionKind()
+	result := ApplyResult{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Name:       obj.GetName(),
+		DryRun:     opts.DryRun,
+		Changes:    []FieldChange{},
+	}
+
+	if obj.GetName() == "" {
+		return result, fmt.Errorf("metadata.name is required for server-side apply")
+	}
+
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return result, fmt.Errorf("unknown resource kind %s: %w", gvk.String(), err)
+	}
+
+	namespace := ""
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		namespace = obj.GetNamespace()
+		if namespace == "" {
+			namespace = opts.Namespace
+		}
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+		}
+		obj.SetNamespace(namespace)
+	} else {
+		obj.SetNamespace("")
+	}
+	result.Namespace = namespace
+	unstructured.RemoveNestedField(obj.Object, "metadata", "managedFields")
+
+	resource := c.dynamic.Resource(mapping.Resource).Namespace(namespace)
+
+	live := map[string]interface{}{}
+	exists := true
+	current, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	switch {
+	case err == nil:
+		live = current.Object
+	case apierrors.IsNotFound(err):
+		exists = false
+	default:
+		return result, err
+	}
+
+	applyOpts := metav1.ApplyOptions{FieldManager: opts.FieldManager, Force: opts.Force}
+	if opts.DryRun {
+		applyOpts.DryRun = []string{metav1.DryRunAll}
+	}
+
+	applied, err := resource.Apply(ctx, obj.GetName(), obj, applyOpts)
+	if err != nil {
+		return result, err
+	}
+
+	result.Changes = DiffObjects(live, applied.Object)
+	switch {
+	case !exists:
+		result.Action = "created"
+	case len(result.Changes) > 0:
+		result.Action = "configured"
+	default:
+		result.Action = "unchanged"
+	}
+	return result, nil
+}
+
+// DeleteObjects deletes live objects, dependents in the background. Objects that no longer
+// exist are reported as absent; a failing object does not stop the rest.
+func (c *Client) DeleteObjects(ctx context.Context, objects []*unstructured.Unstructured) []ApplyResult {
+	results := make([]ApplyResult, 0, len(objects))
+	for _, obj := range objects {
+		result := ApplyResult{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+			Action:     "deleted",
+			Changes:    []FieldChange{},
+		}
+		if err := c.deleteObject(ctx, obj); apierrors.IsNotFound(err) {
+			result.Action = "absent"
+		} else if err != nil {
+			result.Action = "failed"
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func (c *Client) deleteObject(ctx context.Context, obj *unstructured.Unstructured) error {
+	gvk := obj.GroupVersionKind()
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return fmt.Errorf("unknown resource kind %s: %w", gvk.String(), err)
+	}
+
+	namespace := ""
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		namespace = obj.GetNamespace()
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+		}
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	return c.dynamic.Resource(mapping.Resource).Namespace(namespace).Delete(ctx, obj.GetName(), metav1.DeleteOptions{PropagationPolicy: &propagation})
+}
+
+// DecodeManifests splits a YAML or JSON payload into objects, expanding List kinds and skipping empty documents
+func DecodeManifests(data []byte) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+
+	var objects []*unstructured.Unstructured
+	for index := 0; ; index++ {
+		var doc map[string]interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("document %d: %w", index, err)
+		}
+		if len(doc) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{Object: doc}
+		if obj.IsList() {
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, fmt.Errorf("document %d: %w", index, err)
+			}
+			for i := range list.Items {
+				objects = append(objects, &list.Items[i])
+			}
+			continue
+		}
+
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" {
+			return nil, fmt.Errorf("document %d: apiVersion and kind are required", index)
+		}
+		objects = append(objects, obj)
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("no manifests found")
+	}
+	return objects, nil
+}
+
+// DiffObjects returns the field-level changes between two objects, ignoring status and server-managed metadata
+func DiffObjects(oldObj, newObj map[string]interface{}) []FieldChange {
+	changes := []FieldChange{}
+	diffValues("", normalizeForDiff(oldObj), normalizeForDiff(newObj), &changes)
+	return changes
+}
+
+func normalizeForDiff(obj map[string]interface{}) map[string]interface{} {
+	if len(obj) == 0 {
+		return map[string]interface{}{}
+	}
+	normalized := (&unstructured.Unstructured{Object: obj}).DeepCopy().Object
+	delete(normalized, "status")
+	for _, field := range noisyMetadataFields {
+		unstructured.RemoveNestedField(normalized, "metadata", field)
+	}
+	return normalized
+}
+
+func diffValues(path string, oldValue, newValue interface{}, changes *[]FieldChange) {
+	oldMap, oldIsMap := oldValue.(map[string]interface{})
+	newMap, newIsMap := newValue.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		keys := make([]string, 0, len(oldMap)+len(newMap))
+		for key := range oldMap {
+			keys = append(keys, key)
+		}
+		for key := range newMap {
+			if _, ok := oldMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			childPath := path + "." + key
+			oldChild, inOld := oldMap[key]
+			newChild, inNew := newMap[key]
+			switch {
+			case !inOld:
+				*changes = append(*changes, FieldChange{Path: childPath, Op: "add", New: newChild})
+			case !inNew:
+				*changes = append(*changes, FieldChange{Path: childPath, Op: "remove", Old: oldChild})
+			default:
+				diffValues(childPath, oldChild, newChild, changes)
+			}
+		}
+		return
+	}
+
+	oldSlice, oldIsSlice := oldValue.([]interface{})
+	newSlice, newIsSlice := newValue.([]interface{})
+	if oldIsSlice && newIsSlice && len(oldSlice) == len(newSlice) {
+		for i := range oldSlice {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), oldSlice[i], newSlice[i], changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(oldValue, newValue) {
+		*changes = append(*changes, FieldChange{Path: path, Op: "replace", Old: oldValue, New: newValue})
+	}
+}
@@ -0,0 +1,98 @@
+package k8s
+
+import (
+	"testing"
+)
+
+func TestDecodeManifests(t *testing.T) {
+	data := []byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  key: value
+---
+# empty document
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: web
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: web
+`)
+
+	objects, err := DecodeManifests(data)
+	if err != nil {
+		t.Fatalf("DecodeManifests failed: %v", err)
+	}
+	if len(objects) != 3 {
+		t.Fatalf("expected 3 objects, got %d", len(objects))
+	}
+	kinds := []string{"ConfigMap", "Service", "Deployment"}
+	for i, obj := range objects {
+		if obj.GetKind() != kinds[i] {
+			t.Errorf("object %d: expected kind %s, got %s", i, kinds[i], obj.GetKind())
+		}
+	}
+
+	if _, err := DecodeManifests([]byte("metadata:\n  name: missing-kind\n")); err == nil {
+		t.Error("expected error for document without apiVersion and kind")
+	}
+	if _, err := DecodeManifests([]byte("---\n")); err == nil {
+		t.Error("expected error for payload without manifests")
+	}
+}
+
+func TestDiffObjects(t *testing.T) {
+	live := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "web",
+			"resourceVersion": "100",
+			"labels":          map[string]interface{}{"app": "web", "tier": "frontend"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"ports":    []interface{}{map[string]interface{}{"port": int64(80)}},
+		},
+		"status": map[string]interface{}{"readyReplicas": int64(2)},
+	}
+	applied := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "web",
+			"resourceVersion": "101",
+			"labels":          map[string]interface{}{"app": "web", "version": "v2"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"ports":    []interface{}{map[string]interface{}{"port": int64(8080)}},
+		},
+		"status": map[string]interface{}{"readyReplicas": int64(0)},
+	}
+
+	changes := DiffObjects(live, applied)
+	expected := map[string]string{
+		".metadata.labels.tier":    "remove",
+		".metadata.labels.version": "add",
+		".spec.ports[0].port":      "replace",
+		".spec.replicas":           "replace",
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %+v", len(expected), changes)
+	}
+	for _, change := range changes {
+		if op, ok := expected[change.Path]; !ok || op != change.Op {
+			t.Errorf("unexpected change %+v", change)
+		}
+	}
+
+	if changes := DiffObjects(live, live); len(changes) != 0 {
+		t.Errorf("expected no changes for identical objects, got %+v", changes)
+	}
+}
@@ -0,0 +1,330 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Connectivity levels of a matrix cell
+const (
+	ConnectivityAllowed = "allowed"
+	ConnectivityPartial = "partial" // only some pod pairs may connect
+	ConnectivityDenied  = "denied"
+)
+
+// ConnectivityMatrix describes which namespaces or pods may open connections to each other.
+// Matrix[i][j] is the connectivity from Nodes[i] to Nodes[j] on at least one port.
+type ConnectivityMatrix struct {
+	Level   string     `json:"level"` // namespace or pod
+	Nodes   []string   `json:"nodes"`
+	Matrix  [][]string `json:"matrix"`
+	Summary struct {
+		Allowed int `json:"allowed"`
+		Partial int `json:"partial"`
+		Denied  int `json:"denied"`
+	} `json:"summary"`
+	// Isolated lists the nodes selected by at least one ingress policy
+	Isolated []string `json:"isolated"`
+}
+
+// ListNetworkPolicies lists network policies in a namespace, or all namespaces when empty
+func (c *Client) ListNetworkPolicies(ctx context.Context, namespace string) (*networkingv1.NetworkPolicyList, error) {
+	if namespace == "" {
+		namespace = metav1.NamespaceAll
+	}
+	return c.clientset.NetworkingV1().NetworkPolicies(namespace).List(ctx, metav1.ListOptions{})
+}
+
+// GetNetworkPolicy returns a single network policy
+func (c *Client) GetNetworkPolicy(ctx context.Context, namespace, name string) (*networkingv1.NetworkPolicy, error) {
+	return c.clientset.NetworkingV1().NetworkPolicies(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// CreateNetworkPolicy creates a network policy in its namespace
+func (c *Client) CreateNetworkPolicy(ctx context.Context, policy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	return c.clientset.NetworkingV1().NetworkPolicies(policy.Namespace).Create(ctx, policy, metav1.CreateOptions{})
+}
+
+// UpdateNetworkPolicy replaces the spec, labels and annotations of an existing network policy
+func (c *Client) UpdateNetworkPolicy(ctx context.Context, policy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	current, err := c.GetNetworkPolicy(ctx, policy.Namespace, policy.Name)
+	if err != nil {
+		return nil, err
+	}
+	current.Spec = policy.Spec
+	current.Labels = policy.Labels
+	current.Annotations = policy.Annotations
+	return c.clientset.NetworkingV1().NetworkPolicies(policy.Namespace).Update(ctx, current, metav1.UpdateOptions{})
+}
+
+// DeleteNetworkPolicy deletes a network policy
+func (c *Client) DeleteNetworkPolicy(ctx context.Context, namespace, name string) error {
+	return c.clientset.NetworkingV1().NetworkPolicies(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// NetworkConnectivity loads namespaces, pods and policies and computes the connectivity matrix.
+// With level "pod" the matrix covers the pods of namespace (or all pods when empty).
+func (c *Client) NetworkConnectivity(ctx context.Context, level, namespace string) (*ConnectivityMatrix, error) {
+	namespaces, err := c.ListNamespaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	pods, err := c.ListPods(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	policies, err := c.ListNetworkPolicies(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network policies: %w", err)
+	}
+
+	if level == "pod" {
+		var selected []corev1.Pod
+		for _, pod := range pods.Items {
+			if namespace == "" || pod.Namespace == namespace {
+				selected = append(selected, pod)
+			}
+		}
+		return AnalyzePodConnectivity(namespaces.Items, selected, policies.Items), nil
+	}
+	return AnalyzeNamespaceConnectivity(namespaces.Items, pods.Items, policies.Items), nil
+}
+
+// AnalyzePodConnectivity computes pod-to-pod connectivity. A connection is allowed when the
+// source's egress policies and the destination's ingress policies both permit it; ports and
+// ipBlock peers are not considered.
+func AnalyzePodConnectivity(namespaces []corev1.Namespace, pods []corev1.Pod, policies []networkingv1.NetworkPolicy) *ConnectivityMatrix {
+	a := newPolicyAnalyzer(namespaces, policies)
+	sorted := append([]corev1.Pod(nil), pods...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Namespace+"/"+sorted[i].Name < sorted[j].Namespace+"/"+sorted[j].Name
+	})
+
+	matrix := &ConnectivityMatrix{Level: "pod", Nodes: []string{}, Matrix: [][]string{}, Isolated: []string{}}
+	for _, pod := range sorted {
+		key := pod.Namespace + "/" + pod.Name
+		matrix.Nodes = append(matrix.Nodes, key)
+		if a.ingressIsolated(&pod) {
+			matrix.Isolated = append(matrix.Isolated, key)
+		}
+	}
+	for i := range sorted {
+		row := make([]string, len(sorted))
+		for j := range sorted {
+			row[j] = ConnectivityDenied
+			if a.allowed(&sorted[i], &sorted[j]) {
+				row[j] = ConnectivityAllowed
+			}
+			matrix.count(row[j])
+		}
+		matrix.Matrix = append(matrix.Matrix, row)
+	}
+	return matrix
+}
+
+// AnalyzeNamespaceConnectivity aggregates pod connectivity per namespace pair. Namespaces without
+// pods are represented by an unlabelled pod, i.e. the default for new workloads.
+func AnalyzeNamespaceConnectivity(namespaces []corev1.Namespace, pods []corev1.Pod, policies []networkingv1.NetworkPolicy) *ConnectivityMatrix {
+	a := newPolicyAnalyzer(namespaces, policies)
+
+	names := make([]string, 0, len(namespaces))
+	podsByNamespace := make(map[string][]*corev1.Pod)
+	for i := range pods {
+		podsByNamespace[pods[i].Namespace] = append(podsByNamespace[pods[i].Namespace], &pods[i])
+	}
+	for _, ns := range namespaces {
+		names = append(names, ns.Name)
+		if len(podsByNamespace[ns.Name]) == 0 {
+			podsByNamespace[ns.Name] = []*corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name}}}
+		}
+	}
+	sort.Strings(names)
+
+	matrix := &ConnectivityMatrix{Level: "namespace", Nodes: names, Matrix: [][]string{}, Isolated: []string{}}
+	for _, name := range names {
+		for _, pod := range podsByNamespace[name] {
+			if a.ingressIsolated(pod) {
+				matrix.Isolated = append(matrix.Isolated, name)
+				break
+			}
+		}
+	}
+	for _, from := range names {
+		row := make([]string, len(names))
+		for j, to := range names {
+			allowed, total := 0, 0
+			for _, src := range podsByNamespace[from] {
+				for _, dst := range podsByNamespace[to] {
+					total++
+					if a.allowed(src, dst) {
+						allowed++
+					}
+				}
+			}
+			switch {
+			case allowed == total:
+				row[j] = ConnectivityAllowed
+			case allowed == 0:
+				row[j] = ConnectivityDenied
+			default:
+				row[j] = ConnectivityPartial
+			}
+			matrix.count(row[j])
+		}
+		matrix.Matrix = append(matrix.Matrix, row)
+	}
+	return matrix
+}
+
+// ServicesWithoutNetworkPolicy returns the services whose pods are not selected by any ingress
+// policy in their namespace and so accept traffic from anywhere in the cluster.
+// Services without a selector are skipped.
+func ServicesWithoutNetworkPolicy(services []corev1.Service, policies []networkingv1.NetworkPolicy) []corev1.Service {
+	a := newPolicyAnalyzer(nil, policies)
+	var uncovered []corev1.Service
+	for _, svc := range services {
+		if len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		// A pod carrying exactly the service selector's labels stands in for its backends
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: svc.Namespace, Labels: svc.Spec.Selector}}
+		if !a.ingressIsolated(pod) {
+			uncovered = append(uncovered, svc)
+		}
+	}
+	return uncovered
+}
+
+func (m *ConnectivityMatrix) count(cell string) {
+	switch cell {
+	case ConnectivityAllowed:
+		m.Summary.Allowed++
+	case ConnectivityPartial:
+		m.Summary.Partial++
+	default:
+		m.Summary.Denied++
+	}
+}
+
+// policyAnalyzer evaluates NetworkPolicy semantics for pod pairs
+type policyAnalyzer struct {
+	namespaceLabels map[string]labels.Set
+	policies        map[string][]networkingv1.NetworkPolicy // by namespace
+}
+
+func newPolicyAnalyzer(namespaces []corev1.Namespace, policies []networkingv1.NetworkPolicy) *policyAnalyzer {
+	a := &policyAnalyzer{
+		namespaceLabels: make(map[string]labels.Set, len(namespaces)),
+		policies:        make(map[string][]networkingv1.NetworkPolicy),
+	}
+	for _, ns := range namespaces {
+		set := labels.Set{}
+		for k, v := range ns.Labels {
+			set[k] = v
+		}
+		// Set by the API server since 1.22, relied on by most namespace selectors
+		set["kubernetes.io/metadata.name"] = ns.Name
+		a.namespaceLabels[ns.Name] = set
+	}
+	for _, policy := range policies {
+		a.policies[policy.Namespace] = append(a.policies[policy.Namespace], policy)
+	}
+	return a
+}
+
+func (a *policyAnalyzer) allowed(src, dst *corev1.Pod) bool {
+	return a.egressAllowed(src, dst) && a.ingressAllowed(src, dst)
+}
+
+func (a *policyAnalyzer) ingressIsolated(pod *corev1.Pod) bool {
+	for _, policy := range a.policies[pod.Namespace] {
+		if hasPolicyType(&policy, networkingv1.PolicyTypeIngress) && selectsPod(&policy, pod) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *policyAnalyzer) ingressAllowed(src, dst *corev1.Pod) bool {
+	isolated := false
+	for _, policy := range a.policies[dst.Namespace] {
+		if !hasPolicyType(&policy, networkingv1.PolicyTypeIngress) || !selectsPod(&policy, dst) {
+			continue
+		}
+		isolated = true
+		for _, rule := range policy.Spec.Ingress {
+			if a.peersMatch(rule.From, policy.Namespace, src) {
+				return true
+			}
+		}
+	}
+	return !isolated
+}
+
+func (a *policyAnalyzer) egressAllowed(src, dst *corev1.Pod) bool {
+	isolated := false
+	for _, policy := range a.policies[src.Namespace] {
+		if !hasPolicyType(&policy, networkingv1.PolicyTypeEgress) || !selectsPod(&policy, src) {
+			continue
+		}
+		isolated = true
+		for _, rule := range policy.Spec.Egress {
+			if a.peersMatch(rule.To, policy.Namespace, dst) {
+				return true
+			}
+		}
+	}
+	return !isolated
+}
+
+// peersMatch reports whether a rule's peers include the pod; an empty peer list matches everything
+func (a *policyAnalyzer) peersMatch(peers []networkingv1.NetworkPolicyPeer, policyNamespace string, pod *corev1.Pod) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for _, peer := range peers {
+		if peer.IPBlock != nil {
+			continue
+		}
+		if peer.NamespaceSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(peer.NamespaceSelector)
+			if err != nil || !selector.Matches(a.namespaceLabels[pod.Namespace]) {
+				continue
+			}
+		} else if pod.Namespace != policyNamespace {
+			continue
+		}
+		if peer.PodSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(peer.PodSelector)
+			if err != nil || !selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func selectsPod(policy *networkingv1.NetworkPolicy, pod *corev1.Pod) bool {
+	selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+	return err == nil && selector.Matches(labels.Set(pod.Labels))
+}
+
+// hasPolicyType applies the API defaults: Ingress always, Egress when egress rules are present
+func hasPolicyType(policy *networkingv1.NetworkPolicy, policyType networkingv1.PolicyType) bool {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return policyType == networkingv1.PolicyTypeIngress || len(policy.Spec.Egress) > 0
+	}
+	for _, t := range policy.Spec.PolicyTypes {
+		if t == policyType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package k8s
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func labelledNamespace(name string, labels map[string]string) corev1.Namespace {
+	return corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func labelledPod(namespace, name string, labels map[string]string) corev1.Pod {
+	return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels}}
+}
+
+func networkPolicy(namespace, name string, spec networkingv1.NetworkPolicySpec) networkingv1.NetworkPolicy {
+	return networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}, Spec: spec}
+}
+
+func cell(t *testing.T, m *ConnectivityMatrix, from, to string) string {
+	t.Helper()
+	index := func(name string) int {
+		for i, node := range m.Nodes {
+			if node == name {
+				return i
+			}
+		}
+		t.Fatalf("node %s not in matrix %v", name, m.Nodes)
+		return -1
+	}
+	return m.Matrix[index(from)][index(to)]
+}
+
+func TestAnalyzePodConnectivity(t *testing.T) {
+	namespaces := []corev1.Namespace{labelledNamespace("shop", nil), labelledNamespace("monitoring", nil)}
+	pods := []corev1.Pod{
+		labelledPod("shop", "web", map[string]string{"app": "web"}),
+		labelledPod("shop", "db", map[string]string{"app": "db"}),
+		labelledPod("monitoring", "prometheus", map[string]string{"app": "prometheus"}),
+	}
+
+	// Without policies every pod may reach every other pod
+	m := AnalyzePodConnectivity(namespaces, pods, nil)
+	if m.Summary.Allowed != 9 || m.Summary.Denied != 0 || len(m.Isolated) != 0 {
+		t.Fatalf("expected default allow, got %+v", m.Summary)
+	}
+
+	// db only accepts traffic from web pods in its namespace and from the monitoring namespace
+	policies := []networkingv1.NetworkPolicy{
+		networkPolicy("shop", "db-ingress", networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{
+					{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+					{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "monitoring"}}},
+				},
+			}},
+		}),
+	}
+	m = AnalyzePodConnectivity(namespaces, pods, policies)
+
+	tests := []struct {
+		from, to, want string
+	}{
+		{"shop/web", "shop/db", ConnectivityAllowed},
+		{"monitoring/prometheus", "shop/db", ConnectivityAllowed},
+		{"shop/db", "shop/web", ConnectivityAllowed},
+		{"shop/db", "shop/db", ConnectivityDenied},
+	}
+	for _, tt := range tests {
+		if got := cell(t, m, tt.from, tt.to); got != tt.want {
+			t.Errorf("%s -> %s: expected %s, got %s", tt.from, tt.to, tt.want, got)
+		}
+	}
+	if len(m.Isolated) != 1 || m.Isolated[0] != "shop/db" {
+		t.Errorf("expected only shop/db to be isolated, got %v", m.Isolated)
+	}
+}
+
+func TestAnalyzePodConnectivityEgress(t *testing.T) {
+	namespaces := []corev1.Namespace{labelledNamespace("shop", nil)}
+	pods := []corev1.Pod{
+		labelledPod("shop", "web", map[string]string{"app": "web"}),
+		labelledPod("shop", "api", map[string]string{"app": "api"}),
+	}
+	// Egress-only policy with no rules denies all egress of the selected pods
+	policies := []networkingv1.NetworkPolicy{
+		networkPolicy("shop", "web-egress", networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		}),
+	}
+
+	m := AnalyzePodConnectivity(namespaces, pods, policies)
+	if got := cell(t, m, "shop/web", "shop/api"); got != ConnectivityDenied {
+		t.Errorf("expected web egress to be denied, got %s", got)
+	}
+	if got := cell(t, m, "shop/api", "shop/web"); got != ConnectivityAllowed {
+		t.Errorf("expected ingress to web to stay allowed, got %s", got)
+	}
+	if len(m.Isolated) != 0 {
+		t.Errorf("egress policies should not isolate ingress, got %v", m.Isolated)
+	}
+}
+
+func TestAnalyzeNamespaceConnectivity(t *testing.T) {
+	namespaces := []corev1.Namespace{
+		labelledNamespace("shop", nil),
+		labelledNamespace("other", nil),
+		labelledNamespace("empty", nil),
+	}
+	pods := []corev1.Pod{
+		labelledPod("shop", "web", map[string]string{"app": "web"}),
+		labelledPod("shop", "db", map[string]string{"app": "db"}),
+		labelledPod("other", "client", nil),
+	}
+	// Only db is locked down to same-namespace traffic
+	policies := []networkingv1.NetworkPolicy{
+		networkPolicy("shop", "db-ingress", networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}},
+			}},
+		}),
+	}
+
+	m := AnalyzeNamespaceConnectivity(namespaces, pods, policies)
+	if len(m.Nodes) != 3 || m.Nodes[0] != "empty" {
+		t.Fatalf("expected sorted namespaces, got %v", m.Nodes)
+	}
+	if got := cell(t, m, "other", "shop"); got != ConnectivityPartial {
+		t.Errorf("other -> shop: expected partial, got %s", got)
+	}
+	if got := cell(t, m, "shop", "shop"); got != ConnectivityAllowed {
+		t.Errorf("shop -> shop: expected allowed, got %s", got)
+	}
+	if got := cell(t, m, "empty", "other"); got != ConnectivityAllowed {
+		t.Errorf("empty -> other: expected allowed, got %s", got)
+	}
+	if m.Summary.Partial != 2 {
+		t.Errorf("expected 2 partial cells, got %+v", m.Summary)
+	}
+}
+
+func TestServicesWithoutNetworkPolicy(t *testing.T) {
+	services := []corev1.Service{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "db"}, Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "db"}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "web"}, Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "web"}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "external"}},
+	}
+	policies := []networkingv1.NetworkPolicy{
+		networkPolicy("shop", "db-ingress", networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+		}),
+		// Egress policies leave ingress open
+		networkPolicy("shop", "web-egress", networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		}),
+	}
+
+	uncovered := ServicesWithoutNetworkPolicy(services, policies)
+	if len(uncovered) != 1 || uncovered[0].Name != "web" {
+		t.Errorf("expected only web to be uncovered, got %v", uncovered)
+	}
+}
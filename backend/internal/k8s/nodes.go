@@ -0,0 +1,310 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// evictionRetryInterval is how long to wait before retrying an eviction blocked by a PodDisruptionBudget
+	evictionRetryInterval = 5 * time.Second
+	defaultDrainTimeout   = 10 * time.Minute
+)
+
+// DrainOptions configures node drain behaviour, mirroring kubectl drain flags
+type DrainOptions struct {
+	IgnoreDaemonSets   bool   `json:"ignoreDaemonSets"`
+	DeleteEmptyDirData bool   `json:"deleteEmptyDirData"`
+	Force              bool   `json:"force"`
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"`
+	TimeoutSeconds     int    `json:"timeoutSeconds,omitempty"`
+}
+
+// DrainEvent reports progress of a node drain
+type DrainEvent struct {
+	Node      string `json:"node"`
+	Phase     string `json:"phase"` // started, evicting, blocked, evicted, skipped, completed, failed
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Evicted   int    `json:"evicted"`
+	Total     int    `json:"total"`
+}
+
+// DrainResult summarises a completed drain
+type DrainResult struct {
+	Node        string   `json:"node"`
+	EvictedPods []string `json:"evictedPods"`
+	SkippedPods []string `json:"skippedPods"`
+}
+
+// SetNodeSchedulable cordons (false) or uncordons (true) a node
+func (c *Client) SetNodeSchedulable(ctx context.Context, name string, schedulable bool) (*corev1.Node, error) {
+	var updated *corev1.Node
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := c.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if node.Spec.Unschedulable == !schedulable {
+			updated = node
+			return nil
+		}
+		node.Spec.Unschedulable = !schedulable
+		updated, err = c.clientset.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update node %s: %w", name, err)
+	}
+	c.InvalidateCache("nodes")
+	return updated, nil
+}
+
+// GetNodeStatsSummary returns the raw kubelet stats summary of a node, read through the
+// API server's node proxy so no direct kubelet access is needed
+func (c *Client) GetNodeStatsSummary(ctx context.Context, name string) ([]byte, error) {
+	return c.clientset.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/nodes", name, "proxy", "stats", "summary").
+		DoRaw(ctx)
+}
+
+// GetNodeCadvisorMetrics returns the kubelet's cAdvisor metrics of a node in the Prometheus
+// text format, read through the API server's node proxy
+func (c *Client) GetNodeCadvisorMetrics(ctx context.Context, name string) ([]byte, error) {
+	return c.clientset.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/nodes", name, "proxy", "metrics", "cadvisor").
+		DoRaw(ctx)
+}
+
+// GetNodeExporterMetrics returns the metrics of the running node_exporter pod on a node, found
+// by label selector and read through the API server's pod proxy on the given port
+func (c *Client) GetNodeExporterMetrics(ctx context.Context, name, selector string, port int) ([]byte, error) {
+	pods, err := c.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		LabelSelector: selector,
+		FieldSelector: fields.AndSelectors(
+			fields.OneTermEqualSelector("spec.nodeName", name),
+			fields.OneTermEqualSelector("status.phase", string(corev1.PodRunning)),
+		).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list node_exporter pods: %w", err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("no running node_exporter pod matching %q on node %s", selector, name)
+	}
+	pod := pods.Items[0]
+	return c.clientset.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/namespaces", pod.Namespace, "pods", fmt.Sprintf("%s:%d", pod.Name, port), "proxy", "metrics").
+		DoRaw(ctx)
+}
+
+// GetNodeTaints returns the taints on a node
+func (c *Client) GetNodeTaints(ctx context.Context, name string) ([]corev1.Taint, error) {
+	node, err := c.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %w", name, err)
+	}
+	return node.Spec.Taints, nil
+}
+
+// SetNodeTaint adds a taint to a node, replacing any existing taint with the same key and effect
+func (c *Client) SetNodeTaint(ctx context.Context, name string, taint corev1.Taint) ([]corev1.Taint, error) {
+	if taint.Key == "" {
+		return nil, fmt.Errorf("taint key is required")
+	}
+	switch taint.Effect {
+	case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		return nil, fmt.Errorf("invalid taint effect %q", taint.Effect)
+	}
+	if taint.Effect == corev1.TaintEffectNoExecute {
+		now := metav1.Now()
+		taint.TimeAdded = &now
+	}
+
+	return c.updateNodeTaints(ctx, name, func(taints []corev1.Taint) []corev1.Taint {
+		result := make([]corev1.Taint, 0, len(taints)+1)
+		for _, t := range taints {
+			if t.Key != taint.Key || t.Effect != taint.Effect {
+				result = append(result, t)
+			}
+		}
+		return append(result, taint)
+	})
+}
+
+// RemoveNodeTaint removes taints matching key (and effect, when given) from a node
+func (c *Client) RemoveNodeTaint(ctx context.Context, name, key string, effect corev1.TaintEffect) ([]corev1.Taint, error) {
+	return c.updateNodeTaints(ctx, name, func(taints []corev1.Taint) []corev1.Taint {
+		result := make([]corev1.Taint, 0, len(taints))
+		for _, t := range taints {
+			if t.Key == key && (effect == "" || t.Effect == effect) {
+				continue
+			}
+			result = append(result, t)
+		}
+		return result
+	})
+}
+
+// updateNodeTaints applies a mutation to a node's taints, retrying on conflicts
+func (c *Client) updateNodeTaints(ctx context.Context, name string, mutate func([]corev1.Taint) []corev1.Taint) ([]corev1.Taint, error) {
+	var taints []corev1.Taint
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := c.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		node.Spec.Taints = mutate(node.Spec.Taints)
+		updated, err := c.clientset.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+		taints = updated.Spec.Taints
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update taints on node %s: %w", name, err)
+	}
+	c.InvalidateCache("nodes")
+	return taints, nil
+}
+
+// DrainNode cordons a node and evicts its pods through the Eviction API so PodDisruptionBudgets are honoured
+func (c *Client) DrainNode(ctx context.Context, name string, opts DrainOptions, progress func(DrainEvent)) (*DrainResult, error) {
+	if progress == nil {
+		progress = func(DrainEvent) {}
+	}
+	timeout := defaultDrainTimeout
+	if opts.TimeoutSeconds > 0 {
+		timeout = time.Duration(opts.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if _, err := c.SetNodeSchedulable(ctx, name, false); err != nil {
+		return nil, err
+	}
+
+	pods, err := c.clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods on node %s: %w", name, err)
+	}
+
+	result := &DrainResult{Node: name, EvictedPods: []string{}, SkippedPods: []string{}}
+	var toEvict []corev1.Pod
+	for _, pod := range pods.Items {
+		skip, reason, err := drainFilter(pod, opts)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			result.SkippedPods = append(result.SkippedPods, pod.Namespace+"/"+pod.Name)
+			progress(DrainEvent{Node: name, Phase: "skipped", Namespace: pod.Namespace, Pod: pod.Name, Message: reason})
+			continue
+		}
+		toEvict = append(toEvict, pod)
+	}
+
+	total := len(toEvict)
+	progress(DrainEvent{Node: name, Phase: "started", Total: total})
+
+	for _, pod := range toEvict {
+		progress(DrainEvent{Node: name, Phase: "evicting", Namespace: pod.Namespace, Pod: pod.Name, Evicted: len(result.EvictedPods), Total: total})
+
+		if err := c.evictPod(ctx, pod, opts.GracePeriodSeconds, func(message string) {
+			progress(DrainEvent{Node: name, Phase: "blocked", Namespace: pod.Namespace, Pod: pod.Name, Message: message, Evicted: len(result.EvictedPods), Total: total})
+		}); err != nil {
+			return result, fmt.Errorf("failed to evict pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+
+		result.EvictedPods = append(result.EvictedPods, pod.Namespace+"/"+pod.Name)
+		c.InvalidateCache("pods")
+		progress(DrainEvent{Node: name, Phase: "evicted", Namespace: pod.Namespace, Pod: pod.Name, Evicted: len(result.EvictedPods), Total: total})
+	}
+
+	return result, nil
+}
+
+// drainFilter decides whether a pod is skipped during a drain, or blocks it entirely
+func drainFilter(pod corev1.Pod, opts DrainOptions) (bool, string, error) {
+	if _, isMirror := pod.Annotations[corev1.MirrorPodAnnotationKey]; isMirror {
+		return true, "mirror pod", nil
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return true, "pod already terminated", nil
+	}
+
+	controller := metav1.GetControllerOf(&pod)
+	if controller != nil && controller.Kind == "DaemonSet" {
+		if !opts.IgnoreDaemonSets {
+			return false, "", fmt.Errorf("pod %s/%s is managed by a DaemonSet; set ignoreDaemonSets to proceed", pod.Namespace, pod.Name)
+		}
+		return true, "managed by DaemonSet", nil
+	}
+	if controller == nil && !opts.Force {
+		return false, "", fmt.Errorf("pod %s/%s is not managed by a controller; set force to proceed", pod.Namespace, pod.Name)
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil && !opts.DeleteEmptyDirData {
+			return false, "", fmt.Errorf("pod %s/%s uses emptyDir volume %s; set deleteEmptyDirData to proceed", pod.Namespace, pod.Name, volume.Name)
+		}
+	}
+	return false, "", nil
+}
+
+// evictPod evicts a pod, retrying while a PodDisruptionBudget blocks it, then waits for it to terminate
+func (c *Client) evictPod(ctx context.Context, pod corev1.Pod, gracePeriod *int64, blocked func(string)) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+		DeleteOptions: &metav1.DeleteOptions{GracePeriodSeconds: gracePeriod},
+	}
+
+	for {
+		err := c.clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		if err == nil || apierrors.IsNotFound(err) {
+			break
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			return err
+		}
+
+		message := err.Error()
+		if budgets := c.blockingBudgets(ctx, pod); budgets != "" {
+			message = budgets
+		}
+		blocked(message)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for disruption budget (%s): %w", message, ctx.Err())
+		case <-time.After(evictionRetryInterval):
+		}
+	}
+
+	// Wait until the pod is gone (or replaced by a new pod with the same name)
+	for {
+		current, err := c.clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && current.UID != pod.UID) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for pod termination: %w", ctx.Err())
+		case <-time.After(time.Second):
+		}
+	}
+}
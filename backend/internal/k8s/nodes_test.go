@@ -0,0 +1,56 @@
+package k8s
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testPod(ownerKind string, volumes ...corev1.Volume) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+		Spec:       corev1.PodSpec{Volumes: volumes},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if ownerKind != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: "owner", Controller: &controller}}
+	}
+	return pod
+}
+
+func TestDrainFilter(t *testing.T) {
+	emptyDir := corev1.Volume{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+	mirror := testPod("")
+	mirror.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "hash"}
+
+	tests := []struct {
+		name    string
+		pod     corev1.Pod
+		opts    DrainOptions
+		skip    bool
+		wantErr bool
+	}{
+		{"replicaset pod is evicted", testPod("ReplicaSet"), DrainOptions{}, false, false},
+		{"mirror pod is skipped", mirror, DrainOptions{}, true, false},
+		{"daemonset pod is skipped", testPod("DaemonSet"), DrainOptions{IgnoreDaemonSets: true}, true, false},
+		{"daemonset pod blocks without flag", testPod("DaemonSet"), DrainOptions{}, false, true},
+		{"bare pod blocks without force", testPod(""), DrainOptions{}, false, true},
+		{"bare pod is evicted with force", testPod(""), DrainOptions{Force: true}, false, false},
+		{"emptyDir blocks without flag", testPod("ReplicaSet", emptyDir), DrainOptions{}, false, true},
+		{"emptyDir is evicted with flag", testPod("ReplicaSet", emptyDir), DrainOptions{DeleteEmptyDirData: true}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skip, _, err := drainFilter(tt.pod, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("drainFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if skip != tt.skip {
+				t.Errorf("drainFilter() skip = %v, want %v", skip, tt.skip)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// DefaultNodeShellNamespace is where node shell agent pods run
+	DefaultNodeShellNamespace = "kube-system"
+	// DefaultNodeShellImage provides the nsenter a node shell enters the host with
+	DefaultNodeShellImage = "busybox:1.36"
+	// DefaultNodeShellMaxDuration bounds a node shell, in case its agent pod outlives the session
+	DefaultNodeShellMaxDuration = 8 * time.Hour
+
+	nodeShellContainer = "shell"
+	nodeShellStartup   = 2 * time.Minute
+)
+
+// nodeShellCommand enters the namespaces of the node's init process, giving a login shell on the host
+var nodeShellCommand = []string{"nsenter", "--target", "1", "--mount", "--uts", "--ipc", "--net", "--pid", "--",
+	"sh", "-c", "if command -v bash >/dev/null; then exec bash -l; else exec sh -l; fi"}
+
+// NodeShellConfig configures the agent pods node shells run through
+type NodeShellConfig struct {
+	Namespace   string
+	Image       string
+	MaxDuration time.Duration
+}
+
+// NodeShellPod builds the privileged agent pod of a shell on a node. It shares the host's
+// PID, network and IPC namespaces and tolerates every taint, so it runs on any node,
+// and idles until the shell execs nsenter in it.
+func NodeShellPod(node string, config NodeShellConfig) *corev1.Pod {
+	config = normalizeNodeShellConfig(config)
+	deadline := int64(config.MaxDuration.Seconds())
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "denshimon-node-shell-",
+			Namespace:    config.Namespace,
+			Labels:       map[string]string{managedByLabel: "denshimon", DebugPodLabel: "node-shell"},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:                      node,
+			RestartPolicy:                 corev1.RestartPolicyNever,
+			ActiveDeadlineSeconds:         &deadline,
+			TerminationGracePeriodSeconds: ptr.To(int64(0)),
+			HostPID:                       true,
+			HostNetwork:                   true,
+			HostIPC:                       true,
+			AutomountServiceAccountToken:  ptr.To(false),
+			Tolerations:                   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+			Containers: []corev1.Container{{
+				Name:            nodeShellContainer,
+				Image:           config.Image,
+				Command:         []string{"sleep", fmt.Sprint(deadline)},
+				Stdin:           true,
+				TTY:             true,
+				SecurityContext: &corev1.SecurityContext{Privileged: ptr.To(true)},
+			}},
+		},
+	}
+}
+
+func normalizeNodeShellConfig(config NodeShellConfig) NodeShellConfig {
+	if config.Namespace == "" {
+		config.Namespace = DefaultNodeShellNamespace
+	}
+	if config.Image == "" {
+		config.Image = DefaultNodeShellImage
+	}
+	if config.MaxDuration <= 0 {
+		config.MaxDuration = DefaultNodeShellMaxDuration
+	}
+	return config
+}
+
+// HandleNodeShell opens a WebSocket terminal on a node through a short-lived agent pod,
+// deleted when the terminal closes. Node shells must be recorded and aren't shared. Users
+// without a bearer token authenticate with an in-band auth message first, which
+// opts.Authenticate must refuse for anyone not allowed a shell on the node.
+func (c *Client) HandleNodeShell(w http.ResponseWriter, r *http.Request, node string, config NodeShellConfig, opts ExecOptions) {
+	config = normalizeNodeShellConfig(config)
+	if opts.NewRecorder == nil {
+		http.Error(w, "node shells require session recording", http.StatusServiceUnavailable)
+		return
+	}
+	if _, err := c.clientset.CoreV1().Nodes().Get(r.Context(), node, metav1.GetOptions{}); err != nil {
+		http.Error(w, fmt.Sprintf("failed to get node: %v", err), http.StatusNotFound)
+		return
+	}
+
+	session, err := NewTerminalSession(w, r, c.clientset, c.config)
+	if err != nil {
+		slog.Error("Failed to create terminal session", "error", err)
+		http.Error(w, "Failed to create terminal session", http.StatusInternalServerError)
+		return
+	}
+	if opts.User == "" {
+		if opts.User, err = authenticateViewer(session.conn, opts.Authenticate); err != nil {
+			session.sendError("Sign in as an admin to open a node shell")
+			session.close()
+			return
+		}
+	}
+	session.share.owner = opts.User
+	session.send(TerminalMessage{Type: "data", Data: fmt.Sprintf("Starting a shell on node %s...\r\n", node)})
+
+	pod, err := c.startNodeShellPod(session.ctx, node, config)
+	if pod != nil {
+		defer func() {
+			// the session's context is cancelled by now; the agent must go regardless
+			if err := c.clientset.CoreV1().Pods(pod.Namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{}); err != nil {
+				slog.Warn("Failed to delete node shell pod", "node", node, "pod", pod.Name, "error", err)
+			}
+		}()
+	}
+	if err != nil {
+		session.sendError(err.Error())
+		session.close()
+		return
+	}
+
+	if !session.startRecording(opts, ExecSessionInfo{
+		Namespace: pod.Namespace,
+		Pod:       pod.Name,
+		Container: nodeShellContainer,
+		Command:   "nsenter --target 1",
+		Node:      node,
+		User:      opts.User,
+	}) {
+		return
+	}
+	slog.Info("Node shell opened", "node", node, "user", opts.User, "pod", pod.Name)
+
+	go session.handleExec(c, pod.Namespace, pod.Name, nodeShellContainer, nodeShellCommand)
+	session.handleWebSocketMessages()
+}
+
+// startNodeShellPod creates a node's agent pod and waits for it to run. The pod is
+// returned even if it didn't start, for the caller to delete.
+func (c *Client) startNodeShellPod(ctx context.Context, node string, config NodeShellConfig) (*corev1.Pod, error) {
+	created, err := c.clientset.CoreV1().Pods(config.Namespace).Create(ctx, NodeShellPod(node, config), metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create node shell pod: %w", err)
+	}
+	err = wait.PollUntilContextTimeout(ctx, debugPollInterval, nodeShellStartup, true, func(ctx context.Context) (bool, error) {
+		pod, err := c.clientset.CoreV1().Pods(created.Namespace).Get(ctx, created.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		switch pod.Status.Phase {
+		case corev1.PodRunning:
+			return true, nil
+		case corev1.PodSucceeded, corev1.PodFailed:
+			return false, fmt.Errorf("node shell pod exited: %s", pod.Status.Reason)
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if waiting := status.State.Waiting; waiting != nil && (waiting.Reason == "ErrImagePull" || waiting.Reason == "ImagePullBackOff" || waiting.Reason == "CreateContainerConfigError") {
+				return false, fmt.Errorf("node shell pod can't start: %s: %s", waiting.Reason, waiting.Message)
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return created, fmt.Errorf("node shell pod didn't start: %w", err)
+	}
+	return created, nil
+}
@@ -0,0 +1,130 @@
+package k8s
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNodeShellPod(t *testing.T) {
+	pod := NodeShellPod("worker-1", NodeShellConfig{})
+	spec := pod.Spec
+	if pod.Namespace != DefaultNodeShellNamespace || spec.NodeName != "worker-1" || pod.Labels[DebugPodLabel] != "node-shell" {
+		t.Errorf("pod = %+v", pod.ObjectMeta)
+	}
+	if !spec.HostPID || !spec.HostNetwork || !spec.HostIPC || len(spec.Tolerations) != 1 || spec.Tolerations[0].Operator != corev1.TolerationOpExists {
+		t.Errorf("agent pod must share the host namespaces and tolerate every taint: %+v", spec)
+	}
+	container := spec.Containers[0]
+	if container.Image != DefaultNodeShellImage || container.SecurityContext == nil || !*container.SecurityContext.Privileged {
+		t.Errorf("container = %+v", container)
+	}
+	if *spec.ActiveDeadlineSeconds != int64(DefaultNodeShellMaxDuration.Seconds()) {
+		t.Errorf("deadline = %d", *spec.ActiveDeadlineSeconds)
+	}
+
+	pod = NodeShellPod("worker-1", NodeShellConfig{Namespace: "ops", Image: "mirror/busybox:1.36", MaxDuration: time.Hour})
+	if pod.Namespace != "ops" || pod.Spec.Containers[0].Image != "mirror/busybox:1.36" || *pod.Spec.ActiveDeadlineSeconds != 3600 {
+		t.Errorf("configured pod = %+v", pod)
+	}
+}
+
+// startingPods names created pods, as the API server would, and gives them the status phase
+// or waiting reason
+func startingPods(clientset *fake.Clientset, phase corev1.PodPhase, waiting string) {
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		pod.Name = pod.GenerateName + "x7k2p"
+		pod.Status.Phase = phase
+		if waiting != "" {
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+				Name:  nodeShellContainer,
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: waiting}},
+			}}
+		}
+		return false, nil, nil
+	})
+}
+
+func TestStartNodeShellPod(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	startingPods(clientset, corev1.PodRunning, "")
+	client := &Client{clientset: clientset}
+
+	pod, err := client.startNodeShellPod(context.Background(), "worker-1", normalizeNodeShellConfig(NodeShellConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pod.Name != "denshimon-node-shell-x7k2p" || pod.Namespace != DefaultNodeShellNamespace {
+		t.Errorf("pod = %s/%s", pod.Namespace, pod.Name)
+	}
+
+	clientset = fake.NewSimpleClientset()
+	startingPods(clientset, corev1.PodPending, "ImagePullBackOff")
+	client = &Client{clientset: clientset}
+	pod, err = client.startNodeShellPod(context.Background(), "worker-1", normalizeNodeShellConfig(NodeShellConfig{}))
+	if err == nil || !strings.Contains(err.Error(), "ImagePullBackOff") {
+		t.Errorf("expected the image pull to fail the start, got %v", err)
+	}
+	if pod == nil {
+		t.Error("a pod that didn't start must still be returned for deletion")
+	}
+}
+
+func TestHandleNodeShellAuthentication(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}})
+	client := &Client{clientset: clientset}
+	recordings := 0
+	opts := ExecOptions{
+		NewRecorder: func(ExecSessionInfo) (SessionRecorder, error) {
+			recordings++
+			return &fakeRecorder{}, nil
+		},
+		Authenticate: func(token string) (string, error) {
+			if token != "admin-token" {
+				return "", http.ErrNoCookie
+			}
+			return "admin", nil
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opts := opts
+		if r.URL.Query().Get("unrecorded") != "" {
+			opts.NewRecorder = nil
+		}
+		client.HandleNodeShell(w, r, strings.TrimPrefix(r.URL.Path, "/"), NodeShellConfig{}, opts)
+	}))
+	defer server.Close()
+
+	if resp, err := http.Get(server.URL + "/worker-1?unrecorded=1"); err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("unrecorded node shell: %v %v", resp.StatusCode, err)
+	}
+	if resp, err := http.Get(server.URL + "/worker-9"); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown node: %v %v", resp.StatusCode, err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/worker-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.WriteJSON(TerminalMessage{Type: "auth", Data: map[string]string{"token": "viewer-token"}})
+	if msg := readUntil(t, conn, "error"); !strings.Contains(msg.Data.(string), "admin") {
+		t.Errorf("error = %v", msg.Data)
+	}
+
+	pods, _ := clientset.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{})
+	if len(pods.Items) != 0 || recordings != 0 {
+		t.Errorf("refused user got %d agent pods and %d recordings", len(pods.Items), recordings)
+	}
+}
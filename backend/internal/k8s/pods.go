@@ -0,0 +1,135 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// MaxBulkPods limits how many pods one bulk operation may touch
+const MaxBulkPods = 500
+
+// bulkPodWorkers bounds concurrent API calls during bulk operations
+const bulkPodWorkers = 10
+
+// ErrNoController is returned when restarting a pod that nothing would recreate
+var ErrNoController = errors.New("pod has no controller, deleting it would not recreate it")
+
+// PodRef identifies a pod
+type PodRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// PodSelector selects pods by label and, optionally, by status
+type PodSelector struct {
+	Namespace     string `json:"namespace"` // empty selects all namespaces
+	LabelSelector string `json:"labelSelector"`
+	// Status matches the pod phase (e.g. Failed) or a container's waiting or
+	// terminated reason (e.g. CrashLoopBackOff, OOMKilled)
+	Status string `json:"status,omitempty"`
+}
+
+// BulkPodResult is the outcome of a bulk operation for one pod
+type BulkPodResult struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SelectPods returns the pods matching a selector, read fresh from the API server
+func (c *Client) SelectPods(ctx context.Context, selector PodSelector) ([]PodRef, error) {
+	if _, err := labels.Parse(selector.LabelSelector); err != nil {
+		return nil, fmt.Errorf("invalid label selector: %w", err)
+	}
+	pods, err := c.clientset.CoreV1().Pods(selector.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.LabelSelector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	refs := []PodRef{}
+	for _, pod := range pods.Items {
+		if selector.Status == "" || podHasStatus(&pod, selector.Status) {
+			refs = append(refs, PodRef{Namespace: pod.Namespace, Name: pod.Name})
+		}
+	}
+	return refs, nil
+}
+
+// DeletePods deletes pods, returning one result per pod in the order given
+func (c *Client) DeletePods(ctx context.Context, pods []PodRef) []BulkPodResult {
+	defer c.InvalidateCache("pods")
+	return c.forEachPod(ctx, pods, func(ctx context.Context, pod PodRef) error {
+		return c.clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+	})
+}
+
+// RestartPods deletes pods so their controllers recreate them. Pods without a
+// controller are left alone and reported with ErrNoController.
+func (c *Client) RestartPods(ctx context.Context, pods []PodRef) []BulkPodResult {
+	defer c.InvalidateCache("pods")
+	return c.forEachPod(ctx, pods, func(ctx context.Context, ref PodRef) error {
+		pod, err := c.clientset.CoreV1().Pods(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if metav1.GetControllerOf(pod) == nil {
+			return ErrNoController
+		}
+		// Guard against deleting a replacement pod created under the same name
+		return c.clientset.CoreV1().Pods(ref.Namespace).Delete(ctx, ref.Name, metav1.DeleteOptions{
+			Preconditions: metav1.NewUIDPreconditions(string(pod.UID)),
+		})
+	})
+}
+
+func (c *Client) forEachPod(ctx context.Context, pods []PodRef, operation func(context.Context, PodRef) error) []BulkPodResult {
+	results := make([]BulkPodResult, len(pods))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, bulkPodWorkers)
+	for i, pod := range pods {
+		wg.Add(1)
+		go func(i int, pod PodRef) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = BulkPodResult{Namespace: pod.Namespace, Name: pod.Name, Success: true}
+			if err := operation(ctx, pod); err != nil {
+				results[i].Success = false
+				results[i].Error = err.Error()
+			}
+		}(i, pod)
+	}
+	wg.Wait()
+	return results
+}
+
+// podHasStatus reports whether a pod is in the given phase or has a container
+// waiting or terminated with the given reason
+func podHasStatus(pod *corev1.Pod, status string) bool {
+	if string(pod.Status.Phase) == status || pod.Status.Reason == status {
+		return true
+	}
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, container := range statuses {
+		if waiting := container.State.Waiting; waiting != nil && waiting.Reason == status {
+			return true
+		}
+		if terminated := container.State.Terminated; terminated != nil && terminated.Reason == status {
+			return true
+		}
+		if last := container.LastTerminationState.Terminated; last != nil && last.Reason == status && container.State.Running == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package k8s
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodHasStatus(t *testing.T) {
+	crashLooping := &corev1.Pod{Status: corev1.PodStatus{
+		Phase: corev1.PodRunning,
+		ContainerStatuses: []corev1.ContainerStatus{{
+			State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled"}},
+		}},
+	}}
+	recovered := &corev1.Pod{Status: corev1.PodStatus{
+		Phase: corev1.PodRunning,
+		ContainerStatuses: []corev1.ContainerStatus{{
+			State:                corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled"}},
+		}},
+	}}
+	evicted := &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted"}}
+
+	tests := []struct {
+		name   string
+		pod    *corev1.Pod
+		status string
+		want   bool
+	}{
+		{"waiting reason", crashLooping, "CrashLoopBackOff", true},
+		{"last termination while not running", crashLooping, "OOMKilled", true},
+		{"last termination of a running container", recovered, "OOMKilled", false},
+		{"phase", evicted, "Failed", true},
+		{"pod reason", evicted, "Evicted", true},
+		{"no match", recovered, "CrashLoopBackOff", false},
+	}
+	for _, tt := range tests {
+		if got := podHasStatus(tt.pod, tt.status); got != tt.want {
+			t.Errorf("%s: podHasStatus(%q) = %v, want %v", tt.name, tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,427 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Pod Security Standards levels, from least to most restrictive
+const (
+	PodSecurityPrivileged = "privileged"
+	PodSecurityBaseline   = "baseline"
+	PodSecurityRestricted = "restricted"
+)
+
+// PodSecurityBestPractice marks findings outside the standards, such as a writable root
+// filesystem, that still weaken a workload
+const PodSecurityBestPractice = "best-practice"
+
+// Score deducted from a workload's 100 for each finding, by the level it violates
+var podSecurityPenalties = map[string]int{
+	PodSecurityBaseline:     25,
+	PodSecurityRestricted:   10,
+	PodSecurityBestPractice: 5,
+}
+
+// podSecurityEnforceLabel is the namespace label of the Pod Security admission controller
+const podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+
+// Capabilities the baseline level allows to be added
+var baselineCapabilities = []string{
+	"AUDIT_WRITE", "CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "KILL", "MKNOD", "NET_BIND_SERVICE",
+	"SETFCAP", "SETGID", "SETPCAP", "SETUID", "SYS_CHROOT",
+}
+
+// Sysctls the baseline level considers safe
+var safeSysctls = []string{
+	"kernel.shm_rmid_forced", "net.ipv4.ip_local_port_range", "net.ipv4.ip_unprivileged_port_start",
+	"net.ipv4.tcp_syncookies", "net.ipv4.ping_group_range", "net.ipv4.ip_local_reserved_ports",
+	"net.ipv4.tcp_keepalive_time", "net.ipv4.tcp_fin_timeout", "net.ipv4.tcp_keepalive_intvl",
+	"net.ipv4.tcp_keepalive_probes",
+}
+
+// PodSecurityFinding is one way a workload falls short of a level
+type PodSecurityFinding struct {
+	Check       string `json:"check"`
+	Level       string `json:"level"` // baseline, restricted or best-practice
+	Container   string `json:"container,omitempty"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation"`
+}
+
+// PodSecurityWorkload is a workload's pod template to audit
+type PodSecurityWorkload struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Spec      corev1.PodSpec
+	Metadata  metav1.ObjectMeta // of the pod template, for AppArmor annotations
+}
+
+// WorkloadSecurity is the audit of one workload
+type WorkloadSecurity struct {
+	Kind      string               `json:"kind"`
+	Namespace string               `json:"namespace"`
+	Name      string               `json:"name"`
+	Level     string               `json:"level"` // most restrictive level the workload meets
+	Score     int                  `json:"score"` // 0-100
+	Findings  []PodSecurityFinding `json:"findings"`
+}
+
+// NamespaceSecurity is the audit of a namespace's workloads
+type NamespaceSecurity struct {
+	Namespace string `json:"namespace"`
+	// Enforced is the level the namespace's pod-security.kubernetes.io/enforce label sets, if any
+	Enforced  string             `json:"enforced,omitempty"`
+	Score     int                `json:"score"`  // average of the workload scores, 100 without workloads
+	Counts    map[string]int     `json:"counts"` // workloads meeting each level at most
+	Workloads []WorkloadSecurity `json:"workloads"`
+}
+
+// PodSecurityReport is the Pod Security Standards audit of the cluster, worst namespaces first
+type PodSecurityReport struct {
+	GeneratedAt time.Time           `json:"generatedAt"`
+	Score       int                 `json:"score"`
+	Counts      map[string]int      `json:"counts"`
+	Namespaces  []NamespaceSecurity `json:"namespaces"`
+}
+
+// AuditPodSecurity evaluates the pod templates of Deployments, StatefulSets, DaemonSets,
+// CronJobs, Jobs and standalone Pods in a namespace, or all namespaces for "", against
+// the Pod Security Standards
+func (c *Client) AuditPodSecurity(ctx context.Context, namespace string) (*PodSecurityReport, error) {
+	var workloads []PodSecurityWorkload
+	add := func(kind string, meta metav1.ObjectMeta, template corev1.PodTemplateSpec) {
+		workloads = append(workloads, PodSecurityWorkload{Kind: kind, Namespace: meta.Namespace, Name: meta.Name, Spec: template.Spec, Metadata: template.ObjectMeta})
+	}
+
+	deployments, err := c.ListDeployments(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, d := range deployments.Items {
+		add("Deployment", d.ObjectMeta, d.Spec.Template)
+	}
+	statefulSets, err := c.ListStatefulSets(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for _, s := range statefulSets.Items {
+		add("StatefulSet", s.ObjectMeta, s.Spec.Template)
+	}
+	daemonSets, err := c.ListDaemonSets(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %w", err)
+	}
+	for _, d := range daemonSets.Items {
+		add("DaemonSet", d.ObjectMeta, d.Spec.Template)
+	}
+	cronJobs, err := c.clientset.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cronjobs: %w", err)
+	}
+	for _, j := range cronJobs.Items {
+		add("CronJob", j.ObjectMeta, j.Spec.JobTemplate.Spec.Template)
+	}
+	jobs, err := c.clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	for _, j := range jobs.Items {
+		// Jobs of a CronJob are audited through its template
+		if len(j.OwnerReferences) == 0 {
+			add("Job", j.ObjectMeta, j.Spec.Template)
+		}
+	}
+	pods, err := c.ListPods(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	for _, p := range pods.Items {
+		if len(p.OwnerReferences) == 0 {
+			add("Pod", p.ObjectMeta, corev1.PodTemplateSpec{ObjectMeta: p.ObjectMeta, Spec: p.Spec})
+		}
+	}
+
+	enforced := map[string]string{}
+	if namespaces, err := c.ListNamespaces(ctx); err == nil {
+		for _, ns := range namespaces.Items {
+			if namespace == "" || ns.Name == namespace {
+				enforced[ns.Name] = ns.Labels[podSecurityEnforceLabel]
+			}
+		}
+	}
+	return BuildPodSecurityReport(workloads, enforced), nil
+}
+
+// BuildPodSecurityReport audits workloads and groups them by namespace, including the
+// namespaces in enforced that have none
+func BuildPodSecurityReport(workloads []PodSecurityWorkload, enforced map[string]string) *PodSecurityReport {
+	byNamespace := map[string]*NamespaceSecurity{}
+	namespaceFor := func(name string) *NamespaceSecurity {
+		if ns, ok := byNamespace[name]; ok {
+			return ns
+		}
+		ns := &NamespaceSecurity{Namespace: name, Enforced: enforced[name], Counts: newLevelCounts(), Workloads: []WorkloadSecurity{}}
+		byNamespace[name] = ns
+		return ns
+	}
+	for name := range enforced {
+		namespaceFor(name)
+	}
+
+	report := &PodSecurityReport{GeneratedAt: time.Now(), Counts: newLevelCounts(), Namespaces: []NamespaceSecurity{}}
+	total := 0
+	for _, workload := range workloads {
+		audit := AuditWorkload(workload)
+		ns := namespaceFor(workload.Namespace)
+		ns.Workloads = append(ns.Workloads, audit)
+		ns.Counts[audit.Level]++
+		report.Counts[audit.Level]++
+		total += audit.Score
+	}
+	report.Score = 100
+	if len(workloads) > 0 {
+		report.Score = total / len(workloads)
+	}
+
+	for _, ns := range byNamespace {
+		ns.Score = 100
+		if len(ns.Workloads) > 0 {
+			sum := 0
+			for _, w := range ns.Workloads {
+				sum += w.Score
+			}
+			ns.Score = sum / len(ns.Workloads)
+		}
+		sort.Slice(ns.Workloads, func(i, j int) bool {
+			a, b := ns.Workloads[i], ns.Workloads[j]
+			if a.Score != b.Score {
+				return a.Score < b.Score
+			}
+			return a.Kind+"/"+a.Name < b.Kind+"/"+b.Name
+		})
+		report.Namespaces = append(report.Namespaces, *ns)
+	}
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		a, b := report.Namespaces[i], report.Namespaces[j]
+		if a.Score != b.Score {
+			return a.Score < b.Score
+		}
+		return a.Namespace < b.Namespace
+	})
+	return report
+}
+
+func newLevelCounts() map[string]int {
+	return map[string]int{PodSecurityPrivileged: 0, PodSecurityBaseline: 0, PodSecurityRestricted: 0}
+}
+
+// AuditWorkload checks a workload's pod template against the baseline and restricted levels
+func AuditWorkload(workload PodSecurityWorkload) WorkloadSecurity {
+	findings := AuditPodSpec(&workload.Spec, workload.Metadata.Annotations)
+	audit := WorkloadSecurity{
+		Kind:      workload.Kind,
+		Namespace: workload.Namespace,
+		Name:      workload.Name,
+		Level:     PodSecurityRestricted,
+		Score:     100,
+		Findings:  findings,
+	}
+	for _, finding := range findings {
+		audit.Score -= podSecurityPenalties[finding.Level]
+		switch finding.Level {
+		case PodSecurityBaseline:
+			audit.Level = PodSecurityPrivileged
+		case PodSecurityRestricted:
+			if audit.Level == PodSecurityRestricted {
+				audit.Level = PodSecurityBaseline
+			}
+		}
+	}
+	if audit.Score < 0 {
+		audit.Score = 0
+	}
+	return audit
+}
+
+// AuditPodSpec returns the findings for a pod spec; annotations are the pod's, which may
+// hold AppArmor profiles on clusters older than 1.30
+func AuditPodSpec(spec *corev1.PodSpec, annotations map[string]string) []PodSecurityFinding {
+	findings := []PodSecurityFinding{}
+	add := func(check, level, container, message, remediation string) {
+		findings = append(findings, PodSecurityFinding{Check: check, Level: level, Container: container, Message: message, Remediation: remediation})
+	}
+	podContext := spec.SecurityContext
+	if podContext == nil {
+		podContext = &corev1.PodSecurityContext{}
+	}
+
+	// Baseline, pod level
+	if spec.HostNetwork {
+		add("host-namespaces", PodSecurityBaseline, "", "uses the host network", "Remove hostNetwork: true and expose the pod through a Service")
+	}
+	if spec.HostPID {
+		add("host-namespaces", PodSecurityBaseline, "", "shares the host PID namespace", "Remove hostPID: true")
+	}
+	if spec.HostIPC {
+		add("host-namespaces", PodSecurityBaseline, "", "shares the host IPC namespace", "Remove hostIPC: true")
+	}
+	for _, volume := range spec.Volumes {
+		if volume.HostPath != nil {
+			add("host-path", PodSecurityBaseline, "", fmt.Sprintf("mounts host path %s (volume %s)", volume.HostPath.Path, volume.Name),
+				"Replace the hostPath volume with a PersistentVolumeClaim, ConfigMap or emptyDir")
+		} else if !restrictedVolume(volume) {
+			add("volume-types", PodSecurityRestricted, "", fmt.Sprintf("volume %s has a type the restricted level does not allow", volume.Name),
+				"Use configMap, csi, downwardAPI, emptyDir, ephemeral, persistentVolumeClaim, projected or secret volumes")
+		}
+	}
+	for _, sysctl := range podContext.Sysctls {
+		if !slices.Contains(safeSysctls, sysctl.Name) {
+			add("sysctls", PodSecurityBaseline, "", "sets unsafe sysctl "+sysctl.Name, "Remove the sysctl or set it on the node instead")
+		}
+	}
+	if selinux := podContext.SELinuxOptions; selinux != nil {
+		if message := seLinuxViolation(selinux); message != "" {
+			add("selinux", PodSecurityBaseline, "", message, "Remove the custom SELinux user, role and type")
+		}
+	}
+	podSeccomp := seccompType(podContext.SeccompProfile)
+	if podSeccomp == corev1.SeccompProfileTypeUnconfined {
+		add("seccomp", PodSecurityBaseline, "", "disables seccomp for the pod", "Set securityContext.seccompProfile.type to RuntimeDefault")
+	}
+	if podContext.RunAsUser != nil && *podContext.RunAsUser == 0 {
+		add("run-as-user", PodSecurityRestricted, "", "runs as UID 0", "Set securityContext.runAsUser to a non-zero UID")
+	}
+	if podContext.AppArmorProfile != nil && podContext.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined {
+		add("apparmor", PodSecurityBaseline, "", "disables AppArmor for the pod", "Remove the Unconfined AppArmor profile")
+	}
+
+	var containers []corev1.Container
+	containers = append(containers, spec.InitContainers...)
+	containers = append(containers, spec.Containers...)
+	for _, container := range containers {
+		auditContainer(container, podContext, podSeccomp, annotations, add)
+	}
+	for _, ephemeral := range spec.EphemeralContainers {
+		auditContainer(corev1.Container(ephemeral.EphemeralContainerCommon), podContext, podSeccomp, annotations, add)
+	}
+	return findings
+}
+
+func auditContainer(container corev1.Container, podContext *corev1.PodSecurityContext, podSeccomp corev1.SeccompProfileType, annotations map[string]string,
+	add func(check, level, container, message, remediation string)) {
+	name := container.Name
+	securityContext := container.SecurityContext
+	if securityContext == nil {
+		securityContext = &corev1.SecurityContext{}
+	}
+
+	// Baseline
+	if securityContext.Privileged != nil && *securityContext.Privileged {
+		add("privileged", PodSecurityBaseline, name, "runs privileged", "Remove securityContext.privileged: true and grant only the capabilities needed")
+	}
+	if caps := securityContext.Capabilities; caps != nil {
+		for _, capability := range caps.Add {
+			if !slices.Contains(baselineCapabilities, strings.TrimPrefix(string(capability), "CAP_")) {
+				add("capabilities", PodSecurityBaseline, name, "adds capability "+string(capability), "Remove "+string(capability)+" from securityContext.capabilities.add")
+			}
+		}
+	}
+	for _, port := range container.Ports {
+		if port.HostPort != 0 {
+			add("host-ports", PodSecurityBaseline, name, fmt.Sprintf("binds host port %d", port.HostPort), "Remove hostPort and expose the port through a Service")
+			break
+		}
+	}
+	if securityContext.ProcMount != nil && *securityContext.ProcMount != corev1.DefaultProcMount {
+		add("proc-mount", PodSecurityBaseline, name, "unmasks /proc", "Remove securityContext.procMount")
+	}
+	if selinux := securityContext.SELinuxOptions; selinux != nil {
+		if message := seLinuxViolation(selinux); message != "" {
+			add("selinux", PodSecurityBaseline, name, message, "Remove the custom SELinux user, role and type")
+		}
+	}
+	if annotations["container.apparmor.security.beta.kubernetes.io/"+name] == "unconfined" ||
+		(securityContext.AppArmorProfile != nil && securityContext.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined) {
+		add("apparmor", PodSecurityBaseline, name, "disables AppArmor", "Remove the unconfined AppArmor profile")
+	}
+
+	seccomp := seccompType(securityContext.SeccompProfile)
+	if seccomp == corev1.SeccompProfileTypeUnconfined {
+		add("seccomp", PodSecurityBaseline, name, "disables seccomp", "Set securityContext.seccompProfile.type to RuntimeDefault")
+	} else if seccomp == "" && podSeccomp == "" {
+		add("seccomp", PodSecurityRestricted, name, "has no seccomp profile", "Set securityContext.seccompProfile.type to RuntimeDefault on the pod or container")
+	}
+
+	// Restricted
+	if securityContext.AllowPrivilegeEscalation == nil || *securityContext.AllowPrivilegeEscalation {
+		add("privilege-escalation", PodSecurityRestricted, name, "allows privilege escalation", "Set securityContext.allowPrivilegeEscalation: false")
+	}
+	runAsNonRoot := podContext.RunAsNonRoot != nil && *podContext.RunAsNonRoot
+	if securityContext.RunAsNonRoot != nil {
+		runAsNonRoot = *securityContext.RunAsNonRoot
+	}
+	if !runAsNonRoot {
+		add("run-as-non-root", PodSecurityRestricted, name, "may run as root", "Set securityContext.runAsNonRoot: true and a non-zero runAsUser")
+	}
+	if securityContext.RunAsUser != nil && *securityContext.RunAsUser == 0 {
+		add("run-as-user", PodSecurityRestricted, name, "runs as UID 0", "Set securityContext.runAsUser to a non-zero UID")
+	}
+	dropsAll := false
+	if caps := securityContext.Capabilities; caps != nil {
+		for _, capability := range caps.Drop {
+			if strings.EqualFold(string(capability), "ALL") {
+				dropsAll = true
+			}
+		}
+		for _, capability := range caps.Add {
+			added := strings.TrimPrefix(string(capability), "CAP_")
+			if added != "NET_BIND_SERVICE" && slices.Contains(baselineCapabilities, added) {
+				add("capabilities", PodSecurityRestricted, name, "adds capability "+string(capability), "Add only NET_BIND_SERVICE")
+			}
+		}
+	}
+	if !dropsAll {
+		add("capabilities", PodSecurityRestricted, name, "does not drop all capabilities", "Set securityContext.capabilities.drop: [\"ALL\"]")
+	}
+
+	// Best practice
+	if securityContext.ReadOnlyRootFilesystem == nil || !*securityContext.ReadOnlyRootFilesystem {
+		add("read-only-root-filesystem", PodSecurityBestPractice, name, "has a writable root filesystem",
+			"Set securityContext.readOnlyRootFilesystem: true and mount emptyDir volumes where the application writes")
+	}
+}
+
+func seccompType(profile *corev1.SeccompProfile) corev1.SeccompProfileType {
+	if profile == nil {
+		return ""
+	}
+	return profile.Type
+}
+
+// seLinuxViolation describes SELinux options the baseline level forbids, or returns ""
+func seLinuxViolation(options *corev1.SELinuxOptions) string {
+	allowedTypes := []string{"", "container_t", "container_init_t", "container_kvm_t", "container_engine_t"}
+	if !slices.Contains(allowedTypes, options.Type) {
+		return "sets SELinux type " + options.Type
+	}
+	if options.User != "" || options.Role != "" {
+		return "sets a custom SELinux user or role"
+	}
+	return ""
+}
+
+// restrictedVolume reports whether the restricted level allows a volume's type
+func restrictedVolume(volume corev1.Volume) bool {
+	source := volume.VolumeSource
+	return source.ConfigMap != nil || source.CSI != nil || source.DownwardAPI != nil || source.EmptyDir != nil ||
+		source.Ephemeral != nil || source.PersistentVolumeClaim != nil || source.Projected != nil || source.Secret != nil
+}
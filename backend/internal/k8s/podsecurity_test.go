@@ -0,0 +1,146 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func restrictedSpec() corev1.PodSpec {
+	return corev1.PodSpec{
+		SecurityContext: &corev1.PodSecurityContext{
+			RunAsNonRoot:   ptr.To(true),
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		},
+		Containers: []corev1.Container{{
+			Name:  "app",
+			Image: "app:1.0",
+			SecurityContext: &corev1.SecurityContext{
+				AllowPrivilegeEscalation: ptr.To(false),
+				ReadOnlyRootFilesystem:   ptr.To(true),
+				Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}, Add: []corev1.Capability{"NET_BIND_SERVICE"}},
+			},
+		}},
+		Volumes: []corev1.Volume{{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+	}
+}
+
+func checks(findings []PodSecurityFinding) map[string]string {
+	out := map[string]string{}
+	for _, f := range findings {
+		out[f.Check] = f.Level
+	}
+	return out
+}
+
+func TestAuditPodSpec(t *testing.T) {
+	restricted := restrictedSpec()
+	if findings := AuditPodSpec(&restricted, nil); len(findings) != 0 {
+		t.Errorf("restricted spec has findings: %+v", findings)
+	}
+
+	// A container without a security context meets baseline only
+	plain := corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}
+	got := checks(AuditPodSpec(&plain, nil))
+	for _, check := range []string{"seccomp", "privilege-escalation", "run-as-non-root", "capabilities"} {
+		if got[check] != PodSecurityRestricted {
+			t.Errorf("%s = %q, want restricted", check, got[check])
+		}
+	}
+	if got["read-only-root-filesystem"] != PodSecurityBestPractice {
+		t.Errorf("writable root filesystem not reported: %v", got)
+	}
+
+	privileged := restrictedSpec()
+	privileged.HostNetwork = true
+	privileged.Volumes = append(privileged.Volumes, corev1.Volume{Name: "docker", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/run/docker.sock"}}})
+	privileged.SecurityContext.Sysctls = []corev1.Sysctl{{Name: "kernel.msgmax", Value: "65536"}}
+	container := &privileged.Containers[0]
+	container.SecurityContext.Privileged = ptr.To(true)
+	container.SecurityContext.Capabilities.Add = []corev1.Capability{"SYS_ADMIN"}
+	container.SecurityContext.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined}
+	container.Ports = []corev1.ContainerPort{{ContainerPort: 80, HostPort: 80}}
+	got = checks(AuditPodSpec(&privileged, nil))
+	for _, check := range []string{"host-namespaces", "host-path", "sysctls", "privileged", "capabilities", "seccomp", "host-ports"} {
+		if got[check] != PodSecurityBaseline {
+			t.Errorf("%s = %q, want baseline", check, got[check])
+		}
+	}
+}
+
+func TestAuditWorkloadScore(t *testing.T) {
+	restricted := AuditWorkload(PodSecurityWorkload{Kind: "Deployment", Name: "api", Spec: restrictedSpec()})
+	if restricted.Level != PodSecurityRestricted || restricted.Score != 100 {
+		t.Errorf("restricted workload = %s/%d", restricted.Level, restricted.Score)
+	}
+
+	plain := AuditWorkload(PodSecurityWorkload{Kind: "Deployment", Name: "web", Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}})
+	if plain.Level != PodSecurityBaseline || plain.Score != 55 {
+		t.Errorf("plain workload = %s/%d, want baseline/55", plain.Level, plain.Score)
+	}
+
+	spec := restrictedSpec()
+	spec.Containers[0].SecurityContext.Privileged = ptr.To(true)
+	privileged := AuditWorkload(PodSecurityWorkload{Kind: "DaemonSet", Name: "agent", Spec: spec})
+	if privileged.Level != PodSecurityPrivileged || privileged.Score != 75 {
+		t.Errorf("privileged workload = %s/%d, want privileged/75", privileged.Level, privileged.Score)
+	}
+	for _, finding := range privileged.Findings {
+		if finding.Remediation == "" {
+			t.Errorf("finding without remediation: %+v", finding)
+		}
+	}
+}
+
+func TestAuditPodSecurity(t *testing.T) {
+	template := func(spec corev1.PodSpec) corev1.PodTemplateSpec { return corev1.PodTemplateSpec{Spec: spec} }
+	clientset := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop", Labels: map[string]string{podSecurityEnforceLabel: "baseline"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "empty"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"}, Spec: appsv1.DeploymentSpec{Template: template(restrictedSpec())}},
+		&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "report", Namespace: "shop"}, Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: template(corev1.PodSpec{Containers: []corev1.Container{{Name: "job"}}})}}}},
+		// Owned by the CronJob, so audited through its template
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "report-1", Namespace: "shop", OwnerReferences: []metav1.OwnerReference{{Kind: "CronJob", Name: "report"}}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "ops"}, Spec: corev1.PodSpec{HostPID: true, Containers: []corev1.Container{{Name: "shell"}}}},
+		// Owned by a ReplicaSet, so audited through its Deployment
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api-abc", Namespace: "shop", OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "api-1"}}}},
+	)
+	client := &Client{clientset: clientset}
+
+	report, err := client.AuditPodSecurity(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Counts[PodSecurityRestricted] != 1 || report.Counts[PodSecurityBaseline] != 1 || report.Counts[PodSecurityPrivileged] != 1 {
+		t.Errorf("counts = %v", report.Counts)
+	}
+	var order []string
+	for _, ns := range report.Namespaces {
+		order = append(order, ns.Namespace)
+	}
+	// Worst namespace first; a namespace without workloads scores 100
+	if len(order) != 3 || order[0] != "ops" || order[1] != "shop" || order[2] != "empty" {
+		t.Fatalf("namespaces = %v", order)
+	}
+	shop := report.Namespaces[1]
+	if shop.Enforced != "baseline" || len(shop.Workloads) != 2 || shop.Score != (100+55)/2 {
+		t.Errorf("shop = %+v", shop)
+	}
+	if shop.Workloads[0].Kind != "CronJob" {
+		t.Errorf("worst workload first, got %s", shop.Workloads[0].Kind)
+	}
+
+	report, err = client.AuditPodSecurity(context.Background(), "ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Namespaces) != 1 || report.Namespaces[0].Workloads[0].Level != PodSecurityPrivileged {
+		t.Errorf("ops report = %+v", report.Namespaces)
+	}
+}
@@ -0,0 +1,153 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Quota usage thresholds for pressure warnings, in percent of the hard limit
+const (
+	QuotaWarningPercent  = 80
+	QuotaCriticalPercent = 95
+)
+
+// QuotaResource is one resource tracked by a ResourceQuota
+type QuotaResource struct {
+	Resource     string  `json:"resource"`
+	Hard         string  `json:"hard"`
+	Used         string  `json:"used"`
+	UsagePercent float64 `json:"usagePercent"`
+}
+
+// QuotaUsage is a ResourceQuota with used-vs-hard figures per resource
+type QuotaUsage struct {
+	Name      string          `json:"name"`
+	Namespace string          `json:"namespace"`
+	Scopes    []string        `json:"scopes,omitempty"`
+	Resources []QuotaResource `json:"resources"`
+}
+
+// QuotaPressure warns that a quota resource is close to, or at, its hard limit
+type QuotaPressure struct {
+	Namespace    string  `json:"namespace"`
+	Quota        string  `json:"quota"`
+	Resource     string  `json:"resource"`
+	Used         string  `json:"used"`
+	Hard         string  `json:"hard"`
+	UsagePercent float64 `json:"usagePercent"`
+	Severity     string  `json:"severity"` // warning or critical
+}
+
+// NamespaceQuotas holds the quotas and limit ranges that constrain a namespace
+type NamespaceQuotas struct {
+	Namespace   string              `json:"namespace"`
+	Quotas      []QuotaUsage        `json:"quotas"`
+	LimitRanges []corev1.LimitRange `json:"limitRanges"`
+	Warnings    []QuotaPressure     `json:"warnings"`
+}
+
+// ListResourceQuotas lists ResourceQuotas in a namespace, or all namespaces for ""
+func (c *Client) ListResourceQuotas(ctx context.Context, namespace string) (*corev1.ResourceQuotaList, error) {
+	list, err := cachedList(c.cache, ctx, "resourcequotas", namespace, func(ctx context.Context, namespace string) (*corev1.ResourceQuotaList, error) {
+		return c.clientset.CoreV1().ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list.DeepCopy(), nil
+}
+
+// GetNamespaceQuotas returns a namespace's ResourceQuotas with usage and its LimitRanges
+func (c *Client) GetNamespaceQuotas(ctx context.Context, namespace string) (*NamespaceQuotas, error) {
+	if _, err := c.clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err != nil {
+		return nil, err
+	}
+	quotas, err := c.ListResourceQuotas(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource quotas: %w", err)
+	}
+	limitRanges, err := c.clientset.CoreV1().LimitRanges(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list limit ranges: %w", err)
+	}
+
+	result := &NamespaceQuotas{
+		Namespace:   namespace,
+		Quotas:      []QuotaUsage{},
+		LimitRanges: limitRanges.Items,
+	}
+	for i := range quotas.Items {
+		result.Quotas = append(result.Quotas, QuotaUsageFor(&quotas.Items[i]))
+	}
+	result.Warnings = QuotaWarnings(result.Quotas)
+	return result, nil
+}
+
+// QuotaUsageFor computes usage percentages for every resource with a hard limit
+func QuotaUsageFor(quota *corev1.ResourceQuota) QuotaUsage {
+	usage := QuotaUsage{
+		Name:      quota.Name,
+		Namespace: quota.Namespace,
+		Resources: []QuotaResource{},
+	}
+	for _, scope := range quota.Spec.Scopes {
+		usage.Scopes = append(usage.Scopes, string(scope))
+	}
+
+	// Status.Hard reflects what the quota controller enforces; fall back to the spec before it syncs
+	hard := quota.Status.Hard
+	if len(hard) == 0 {
+		hard = quota.Spec.Hard
+	}
+	for name, limit := range hard {
+		used := quota.Status.Used[name]
+		resource := QuotaResource{
+			Resource: string(name),
+			Hard:     limit.String(),
+			Used:     used.String(),
+		}
+		if limitValue := limit.AsApproximateFloat64(); limitValue > 0 {
+			resource.UsagePercent = used.AsApproximateFloat64() / limitValue * 100
+		} else if !used.IsZero() {
+			resource.UsagePercent = 100
+		}
+		usage.Resources = append(usage.Resources, resource)
+	}
+	sort.Slice(usage.Resources, func(i, j int) bool {
+		return usage.Resources[i].Resource < usage.Resources[j].Resource
+	})
+	return usage
+}
+
+// QuotaWarnings returns the resources at or above QuotaWarningPercent, most used first
+func QuotaWarnings(quotas []QuotaUsage) []QuotaPressure {
+	warnings := []QuotaPressure{}
+	for _, quota := range quotas {
+		for _, resource := range quota.Resources {
+			if resource.UsagePercent < QuotaWarningPercent {
+				continue
+			}
+			severity := "warning"
+			if resource.UsagePercent >= QuotaCriticalPercent {
+				severity = "critical"
+			}
+			warnings = append(warnings, QuotaPressure{
+				Namespace:    quota.Namespace,
+				Quota:        quota.Name,
+				Resource:     resource.Resource,
+				Used:         resource.Used,
+				Hard:         resource.Hard,
+				UsagePercent: resource.UsagePercent,
+				Severity:     severity,
+			})
+		}
+	}
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].UsagePercent > warnings[j].UsagePercent
+	})
+	return warnings
+}
@@ -0,0 +1,50 @@
+package k8s
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestQuotaUsage(t *testing.T) {
+	quota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "apps"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{
+				corev1.ResourceLimitsMemory: resource.MustParse("8Gi"),
+				corev1.ResourceRequestsCPU:  resource.MustParse("4"),
+				corev1.ResourcePods:         resource.MustParse("10"),
+			},
+			Used: corev1.ResourceList{
+				corev1.ResourceLimitsMemory: resource.MustParse("6Gi"),
+				corev1.ResourceRequestsCPU:  resource.MustParse("3900m"),
+				corev1.ResourcePods:         resource.MustParse("2"),
+			},
+		},
+	}
+
+	usage := QuotaUsageFor(quota)
+	percents := map[string]float64{}
+	for _, r := range usage.Resources {
+		percents[r.Resource] = r.UsagePercent
+	}
+	if percents["limits.memory"] != 75 || percents["requests.cpu"] != 97.5 || percents["pods"] != 20 {
+		t.Errorf("unexpected usage percentages: %v", percents)
+	}
+
+	warnings := QuotaWarnings([]QuotaUsage{usage})
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %+v", warnings)
+	}
+	if w := warnings[0]; w.Resource != "requests.cpu" || w.Severity != "critical" || w.Namespace != "apps" || w.Used != "3900m" {
+		t.Errorf("unexpected warning: %+v", w)
+	}
+
+	// Before the quota controller syncs, the spec limits are used
+	unsynced := &corev1.ResourceQuota{Spec: corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("5")}}}
+	if r := QuotaUsageFor(unsynced).Resources; len(r) != 1 || r[0].UsagePercent != 0 {
+		t.Errorf("unexpected usage for unsynced quota: %+v", r)
+	}
+}
@@ -0,0 +1,311 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ErrInvalidAccessCheck is returned for access checks without a verb or resource
+var ErrInvalidAccessCheck = errors.New("invalid access check")
+
+// AccessCheck asks whether a user, or Denshimon's own service account when User is empty, may
+// perform a verb on a resource. Service accounts are users named
+// system:serviceaccount:<namespace>:<name>.
+type AccessCheck struct {
+	User        string   `json:"user,omitempty"`
+	Groups      []string `json:"groups,omitempty"`
+	Verb        string   `json:"verb"`
+	Group       string   `json:"group,omitempty"` // API group, "" for the core group
+	Resource    string   `json:"resource"`
+	Subresource string   `json:"subresource,omitempty"`
+	Namespace   string   `json:"namespace,omitempty"` // "" checks all namespaces
+	Name        string   `json:"name,omitempty"`
+}
+
+// AccessResult is the API server's answer to an access check
+type AccessResult struct {
+	AccessCheck
+	Allowed bool   `json:"allowed"`
+	Denied  bool   `json:"denied"` // explicitly denied, not just not allowed
+	Reason  string `json:"reason,omitempty"`
+}
+
+// RoleSummary is a Role or ClusterRole with its rules
+type RoleSummary struct {
+	Kind      string              `json:"kind"`
+	Name      string              `json:"name"`
+	Namespace string              `json:"namespace,omitempty"`
+	Rules     []rbacv1.PolicyRule `json:"rules"`
+	// Aggregated ClusterRoles collect the rules of the roles their selectors match
+	Aggregated bool `json:"aggregated,omitempty"`
+}
+
+// BindingSummary is a RoleBinding or ClusterRoleBinding
+type BindingSummary struct {
+	Kind      string           `json:"kind"`
+	Name      string           `json:"name"`
+	Namespace string           `json:"namespace,omitempty"`
+	RoleRef   rbacv1.RoleRef   `json:"roleRef"`
+	Subjects  []rbacv1.Subject `json:"subjects"`
+}
+
+// AccessGrant is a subject granted an access by a binding
+type AccessGrant struct {
+	Subject rbacv1.Subject `json:"subject"`
+	Binding BindingSummary `json:"binding"`
+}
+
+// CanI checks an access with a SubjectAccessReview, or a SelfSubjectAccessReview for
+// Denshimon's own identity
+func (c *Client) CanI(ctx context.Context, check AccessCheck) (*AccessResult, error) {
+	if check.Verb == "" || check.Resource == "" {
+		return nil, fmt.Errorf("%w: verb and resource are required", ErrInvalidAccessCheck)
+	}
+	attributes := &authorizationv1.ResourceAttributes{
+		Namespace:   check.Namespace,
+		Verb:        check.Verb,
+		Group:       check.Group,
+		Resource:    check.Resource,
+		Subresource: check.Subresource,
+		Name:        check.Name,
+	}
+
+	var status authorizationv1.SubjectAccessReviewStatus
+	if check.User == "" {
+		review, err := c.clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attributes},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to review access: %w", err)
+		}
+		status = review.Status
+	} else {
+		review, err := c.clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes: attributes,
+				User:               check.User,
+				Groups:             subjectGroups(check.User, check.Groups),
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to review access: %w", err)
+		}
+		status = review.Status
+	}
+
+	return &AccessResult{
+		AccessCheck: check,
+		Allowed:     status.Allowed,
+		Denied:      status.Denied,
+		Reason:      status.Reason,
+	}, nil
+}
+
+// subjectGroups adds the groups the API server gives a service account, since a
+// SubjectAccessReview only sees the groups it's sent
+func subjectGroups(user string, groups []string) []string {
+	namespace, _, ok := serviceAccountUser(user)
+	if !ok {
+		return groups
+	}
+	return appendMissing(groups, "system:serviceaccounts", "system:serviceaccounts:"+namespace, "system:authenticated")
+}
+
+// serviceAccountUser splits a system:serviceaccount:<namespace>:<name> user name
+func serviceAccountUser(user string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(user, "system:serviceaccount:")
+	if !ok {
+		return "", "", false
+	}
+	namespace, name, ok := strings.Cut(rest, ":")
+	return namespace, name, ok && namespace != "" && name != ""
+}
+
+func appendMissing(values []string, extra ...string) []string {
+	result := append([]string(nil), values...)
+	for _, value := range extra {
+		found := false
+		for _, existing := range result {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// ListRoles lists the Roles of a namespace, or of all namespaces when empty, followed by the
+// ClusterRoles
+func (c *Client) ListRoles(ctx context.Context, namespace string) ([]RoleSummary, error) {
+	roles, err := c.clientset.RbacV1().Roles(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	clusterRoles, err := c.clientset.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster roles: %w", err)
+	}
+
+	summaries := make([]RoleSummary, 0, len(roles.Items)+len(clusterRoles.Items))
+	for _, role := range roles.Items {
+		summaries = append(summaries, RoleSummary{Kind: "Role", Name: role.Name, Namespace: role.Namespace, Rules: role.Rules})
+	}
+	for _, role := range clusterRoles.Items {
+		summaries = append(summaries, RoleSummary{
+			Kind:       "ClusterRole",
+			Name:       role.Name,
+			Rules:      role.Rules,
+			Aggregated: role.AggregationRule != nil,
+		})
+	}
+	return summaries, nil
+}
+
+// ListBindings lists the RoleBindings of a namespace, or of all namespaces when empty, followed
+// by the ClusterRoleBindings. A non-empty subject keeps the bindings naming it, by name or by
+// namespace/name for service accounts.
+func (c *Client) ListBindings(ctx context.Context, namespace, subject string) ([]BindingSummary, error) {
+	bindings, err := c.listBindings(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if subject == "" {
+		return bindings, nil
+	}
+	filtered := make([]BindingSummary, 0)
+	for _, binding := range bindings {
+		for _, s := range binding.Subjects {
+			if s.Name == subject || (s.Namespace != "" && s.Namespace+"/"+s.Name == subject) {
+				filtered = append(filtered, binding)
+				break
+			}
+		}
+	}
+	return filtered, nil
+}
+
+func (c *Client) listBindings(ctx context.Context, namespace string) ([]BindingSummary, error) {
+	roleBindings, err := c.clientset.RbacV1().RoleBindings(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list role bindings: %w", err)
+	}
+	clusterRoleBindings, err := c.clientset.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster role bindings: %w", err)
+	}
+
+	bindings := make([]BindingSummary, 0, len(roleBindings.Items)+len(clusterRoleBindings.Items))
+	for _, binding := range roleBindings.Items {
+		bindings = append(bindings, BindingSummary{
+			Kind:      "RoleBinding",
+			Name:      binding.Name,
+			Namespace: binding.Namespace,
+			RoleRef:   binding.RoleRef,
+			Subjects:  binding.Subjects,
+		})
+	}
+	for _, binding := range clusterRoleBindings.Items {
+		bindings = append(bindings, BindingSummary{
+			Kind:     "ClusterRoleBinding",
+			Name:     binding.Name,
+			RoleRef:  binding.RoleRef,
+			Subjects: binding.Subjects,
+		})
+	}
+	return bindings, nil
+}
+
+// WhoCan lists the subjects whose bindings grant an access: ClusterRoleBindings anywhere, and
+// RoleBindings in the checked namespace. It reads the RBAC objects only, so access granted by
+// other authorizers (webhooks, node authorization) isn't listed.
+func (c *Client) WhoCan(ctx context.Context, check AccessCheck) ([]AccessGrant, error) {
+	if check.Verb == "" || check.Resource == "" {
+		return nil, fmt.Errorf("%w: verb and resource are required", ErrInvalidAccessCheck)
+	}
+	roles, err := c.ListRoles(ctx, check.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	bindings, err := c.listBindings(ctx, check.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return WhoCan(roles, bindings, check), nil
+}
+
+// WhoCan returns the subjects of the bindings whose role has a rule allowing the access.
+// Cluster-wide checks (no namespace) are only granted by ClusterRoleBindings.
+func WhoCan(roles []RoleSummary, bindings []BindingSummary, check AccessCheck) []AccessGrant {
+	rules := make(map[string][]rbacv1.PolicyRule, len(roles))
+	for _, role := range roles {
+		rules[role.Kind+"/"+role.Namespace+"/"+role.Name] = role.Rules
+	}
+
+	grants := []AccessGrant{}
+	for _, binding := range bindings {
+		if binding.Kind == "RoleBinding" && binding.Namespace != check.Namespace {
+			continue
+		}
+		namespace := ""
+		if binding.RoleRef.Kind == "Role" {
+			namespace = binding.Namespace
+		}
+		allowed := false
+		for _, rule := range rules[binding.RoleRef.Kind+"/"+namespace+"/"+binding.RoleRef.Name] {
+			if RuleAllows(rule, check) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			continue
+		}
+		for _, subject := range binding.Subjects {
+			grants = append(grants, AccessGrant{Subject: subject, Binding: binding})
+		}
+	}
+	sort.SliceStable(grants, func(i, j int) bool {
+		if grants[i].Subject.Kind != grants[j].Subject.Kind {
+			return grants[i].Subject.Kind < grants[j].Subject.Kind
+		}
+		return grants[i].Subject.Name < grants[j].Subject.Name
+	})
+	return grants
+}
+
+// RuleAllows reports whether a policy rule covers an access, honouring * wildcards and
+// resource names
+func RuleAllows(rule rbacv1.PolicyRule, check AccessCheck) bool {
+	resource := check.Resource
+	if check.Subresource != "" {
+		resource += "/" + check.Subresource
+	}
+	if !matchesRuleValue(rule.Verbs, check.Verb) || !matchesRuleValue(rule.APIGroups, check.Group) {
+		return false
+	}
+	if !matchesRuleValue(rule.Resources, resource) &&
+		!(check.Subresource != "" && matchesRuleValue(rule.Resources, "*/"+check.Subresource)) {
+		return false
+	}
+	return len(rule.ResourceNames) == 0 || (check.Name != "" && matchesRuleValue(rule.ResourceNames, check.Name))
+}
+
+func matchesRuleValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == rbacv1.VerbAll || v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRuleAllows(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  rbacv1.PolicyRule
+		check AccessCheck
+		want  bool
+	}{
+		{"exact", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}, AccessCheck{Verb: "get", Resource: "pods"}, true},
+		{"other verb", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}, AccessCheck{Verb: "delete", Resource: "pods"}, false},
+		{"other group", rbacv1.PolicyRule{Verbs: []string{"*"}, APIGroups: []string{""}, Resources: []string{"deployments"}}, AccessCheck{Verb: "get", Group: "apps", Resource: "deployments"}, false},
+		{"wildcards", rbacv1.PolicyRule{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}}, AccessCheck{Verb: "patch", Group: "apps", Resource: "deployments"}, true},
+		{"subresource", rbacv1.PolicyRule{Verbs: []string{"create"}, APIGroups: []string{""}, Resources: []string{"pods/exec"}}, AccessCheck{Verb: "create", Resource: "pods", Subresource: "exec"}, true},
+		{"resource without subresource", rbacv1.PolicyRule{Verbs: []string{"create"}, APIGroups: []string{""}, Resources: []string{"pods"}}, AccessCheck{Verb: "create", Resource: "pods", Subresource: "exec"}, false},
+		{"named", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"secrets"}, ResourceNames: []string{"tls"}}, AccessCheck{Verb: "get", Resource: "secrets", Name: "tls"}, true},
+		{"named without name", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"secrets"}, ResourceNames: []string{"tls"}}, AccessCheck{Verb: "get", Resource: "secrets"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RuleAllows(tt.rule, tt.check); got != tt.want {
+				t.Errorf("RuleAllows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhoCan(t *testing.T) {
+	ctx := context.Background()
+	readPods := []rbacv1.PolicyRule{{Verbs: []string{"get", "list"}, APIGroups: []string{""}, Resources: []string{"pods"}}}
+	client := &Client{clientset: fake.NewSimpleClientset(
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "cluster-admin"}, Rules: []rbacv1.PolicyRule{{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}}}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "pod-reader"}, Rules: readPods},
+		&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "pod-reader", Namespace: "dev"}, Rules: readPods},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "admins"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "cluster-admin"},
+			Subjects:   []rbacv1.Subject{{Kind: "Group", Name: "system:masters"}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "ci", Namespace: "dev"},
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: "pod-reader"},
+			Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Name: "ci", Namespace: "dev"}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "viewers", Namespace: "prod"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "pod-reader"},
+			Subjects:   []rbacv1.Subject{{Kind: "User", Name: "alice"}},
+		},
+	)}
+
+	grants, err := client.WhoCan(ctx, AccessCheck{Verb: "list", Resource: "pods", Namespace: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var subjects []string
+	for _, grant := range grants {
+		subjects = append(subjects, grant.Subject.Kind+":"+grant.Subject.Name)
+	}
+	if want := []string{"Group:system:masters", "ServiceAccount:ci"}; !reflect.DeepEqual(subjects, want) {
+		t.Errorf("expected %v, got %v", want, subjects)
+	}
+
+	grants, _ = client.WhoCan(ctx, AccessCheck{Verb: "list", Resource: "pods"})
+	if len(grants) != 1 || grants[0].Binding.Name != "admins" {
+		t.Errorf("expected only cluster role bindings to grant cluster-wide access, got %+v", grants)
+	}
+
+	bindings, err := client.ListBindings(ctx, "", "dev/ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bindings) != 1 || bindings[0].Name != "ci" {
+		t.Errorf("expected the binding of dev/ci, got %+v", bindings)
+	}
+}
+
+func TestSubjectGroups(t *testing.T) {
+	groups := subjectGroups("system:serviceaccount:dev:ci", []string{"system:authenticated"})
+	want := []string{"system:authenticated", "system:serviceaccounts", "system:serviceaccounts:dev"}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("expected %v, got %v", want, groups)
+	}
+	if groups := subjectGroups("alice", nil); groups != nil {
+		t.Errorf("expected users to keep their groups, got %v", groups)
+	}
+}
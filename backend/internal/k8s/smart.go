@@ -0,0 +1,129 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// SmartAgentName names the DaemonSet reading disk health on every node
+	SmartAgentName = "denshimon-smart"
+	// SmartAgentContainer is the container of the agent pods smartctl runs in
+	SmartAgentContainer = "smartctl"
+	// DefaultSmartAgentNamespace is where the agent DaemonSet runs
+	DefaultSmartAgentNamespace = "kube-system"
+	// DefaultSmartAgentImage is installed smartmontools into when it lacks smartctl
+	DefaultSmartAgentImage = "alpine:3.20"
+)
+
+// smartAgentScript installs smartmontools unless the image has it, then idles for execs
+const smartAgentScript = "command -v smartctl >/dev/null || apk add --no-cache smartmontools; exec sleep 2147483647"
+
+// SmartAgentConfig configures the DaemonSet reading disk health
+type SmartAgentConfig struct {
+	Namespace string
+	Image     string
+}
+
+func normalizeSmartAgentConfig(config SmartAgentConfig) SmartAgentConfig {
+	if config.Namespace == "" {
+		config.Namespace = DefaultSmartAgentNamespace
+	}
+	if config.Image == "" {
+		config.Image = DefaultSmartAgentImage
+	}
+	return config
+}
+
+// SmartAgentDaemonSet builds the DaemonSet of privileged pods smartctl is run in. The pods
+// see the node's block devices through /dev, tolerate every taint so every node is covered,
+// and idle between checks.
+func SmartAgentDaemonSet(config SmartAgentConfig) *appsv1.DaemonSet {
+	config = normalizeSmartAgentConfig(config)
+	podLabels := map[string]string{"app.kubernetes.io/name": SmartAgentName}
+	objectLabels := map[string]string{"app.kubernetes.io/name": SmartAgentName, managedByLabel: "denshimon"}
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: SmartAgentName, Namespace: config.Namespace, Labels: objectLabels},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: objectLabels},
+				Spec: corev1.PodSpec{
+					Tolerations:                   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+					AutomountServiceAccountToken:  ptr.To(false),
+					TerminationGracePeriodSeconds: ptr.To(int64(0)),
+					Containers: []corev1.Container{{
+						Name:            SmartAgentContainer,
+						Image:           config.Image,
+						Command:         []string{"sh", "-c", smartAgentScript},
+						SecurityContext: &corev1.SecurityContext{Privileged: ptr.To(true)},
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse("5m"),
+								corev1.ResourceMemory: resource.MustParse("16Mi"),
+							},
+							Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+						},
+						VolumeMounts: []corev1.VolumeMount{{Name: "dev", MountPath: "/dev"}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name:         "dev",
+						VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/dev"}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// EnsureSmartAgent creates the disk health DaemonSet, or updates its pod template when the
+// configuration changed
+func (c *Client) EnsureSmartAgent(ctx context.Context, config SmartAgentConfig) error {
+	desired := SmartAgentDaemonSet(config)
+	daemonSets := c.clientset.AppsV1().DaemonSets(desired.Namespace)
+	existing, err := daemonSets.Get(ctx, desired.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if _, err := daemonSets.Create(ctx, desired, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create disk health agent: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get disk health agent: %w", err)
+	}
+	if existing.Spec.Template.Spec.Containers[0].Image == desired.Spec.Template.Spec.Containers[0].Image {
+		return nil
+	}
+	existing.Spec.Template = desired.Spec.Template
+	if _, err := daemonSets.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update disk health agent: %w", err)
+	}
+	return nil
+}
+
+// SmartAgentPods lists the running pods of the disk health DaemonSet
+func (c *Client) SmartAgentPods(ctx context.Context, namespace string) ([]corev1.Pod, error) {
+	if namespace == "" {
+		namespace = DefaultSmartAgentNamespace
+	}
+	selector := labels.SelectorFromSet(labels.Set{"app.kubernetes.io/name": SmartAgentName, managedByLabel: "denshimon"})
+	list, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list disk health agent pods: %w", err)
+	}
+	var pods []corev1.Pod
+	for _, pod := range list.Items {
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
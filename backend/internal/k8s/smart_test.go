@@ -0,0 +1,59 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEnsureSmartAgent(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	client := &Client{clientset: clientset}
+	ctx := context.Background()
+
+	if err := client.EnsureSmartAgent(ctx, SmartAgentConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	daemonSet, err := clientset.AppsV1().DaemonSets(DefaultSmartAgentNamespace).Get(ctx, SmartAgentName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := daemonSet.Spec.Template.Spec
+	if spec.Containers[0].Image != DefaultSmartAgentImage || !*spec.Containers[0].SecurityContext.Privileged || spec.Volumes[0].HostPath.Path != "/dev" {
+		t.Errorf("agent = %+v", spec)
+	}
+
+	if err := client.EnsureSmartAgent(ctx, SmartAgentConfig{Image: "mirror/smartmontools:7.4"}); err != nil {
+		t.Fatal(err)
+	}
+	daemonSet, _ = clientset.AppsV1().DaemonSets(DefaultSmartAgentNamespace).Get(ctx, SmartAgentName, metav1.GetOptions{})
+	if daemonSet.Spec.Template.Spec.Containers[0].Image != "mirror/smartmontools:7.4" {
+		t.Errorf("image = %s, want the agent updated", daemonSet.Spec.Template.Spec.Containers[0].Image)
+	}
+}
+
+func TestSmartAgentPods(t *testing.T) {
+	labels := SmartAgentDaemonSet(SmartAgentConfig{}).Spec.Template.Labels
+	pod := func(name string, phase corev1.PodPhase, podLabels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: DefaultSmartAgentNamespace, Labels: podLabels},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	client := &Client{clientset: fake.NewSimpleClientset(
+		pod("smart-a", corev1.PodRunning, labels),
+		pod("smart-b", corev1.PodPending, labels),
+		pod("coredns", corev1.PodRunning, map[string]string{"k8s-app": "kube-dns"}),
+	)}
+
+	pods, err := client.SmartAgentPods(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 1 || pods[0].Name != "smart-a" {
+		t.Errorf("pods = %v", pods)
+	}
+}
@@ -0,0 +1,228 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	volumeSnapshotGVR = schema.GroupVersionResource{
+		Group:    "snapshot.storage.k8s.io",
+		Version:  "v1",
+		Resource: "volumesnapshots",
+	}
+	volumeSnapshotClassGVR = schema.GroupVersionResource{
+		Group:    "snapshot.storage.k8s.io",
+		Version:  "v1",
+		Resource: "volumesnapshotclasses",
+	}
+)
+
+// VolumeSnapshotClass represents a CSI snapshot class
+type VolumeSnapshotClass struct {
+	Name           string `json:"name"`
+	Driver         string `json:"driver"`
+	DeletionPolicy string `json:"deletionPolicy"`
+	IsDefault      bool   `json:"isDefault"`
+}
+
+// VolumeSnapshot represents a CSI snapshot of a persistent volume claim
+type VolumeSnapshot struct {
+	Name         string            `json:"name"`
+	Namespace    string            `json:"namespace"`
+	SourcePVC    string            `json:"sourcePvc"`
+	ClassName    string            `json:"className,omitempty"`
+	ReadyToUse   bool              `json:"readyToUse"`
+	RestoreSize  string            `json:"restoreSize,omitempty"`
+	CreationTime *time.Time        `json:"creationTime,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+// ListVolumeSnapshotClasses returns all VolumeSnapshotClasses in the cluster
+func (c *Client) ListVolumeSnapshotClasses(ctx context.Context) ([]VolumeSnapshotClass, error) {
+	list, err := c.dynamic.Resource(volumeSnapshotClassGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volume snapshot classes: %w", err)
+	}
+
+	classes := make([]VolumeSnapshotClass, 0, len(list.Items))
+	for _, item := range list.Items {
+		driver, _, _ := unstructured.NestedString(item.Object, "driver")
+		policy, _, _ := unstructured.NestedString(item.Object, "deletionPolicy")
+		classes = append(classes, VolumeSnapshotClass{
+			Name:           item.GetName(),
+			Driver:         driver,
+			DeletionPolicy: policy,
+			IsDefault:      item.GetAnnotations()["snapshot.storage.kubernetes.io/is-default-class"] == "true",
+		})
+	}
+	return classes, nil
+}
+
+// ListVolumeSnapshots returns VolumeSnapshots in a namespace (all namespaces if empty)
+func (c *Client) ListVolumeSnapshots(ctx context.Context, namespace string) ([]VolumeSnapshot, error) {
+	list, err := c.dynamic.Resource(volumeSnapshotGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volume snapshots: %w", err)
+	}
+
+	snapshots := make([]VolumeSnapshot, 0, len(list.Items))
+	for i := range list.Items {
+		snapshots = append(snapshots, parseVolumeSnapshot(&list.Items[i]))
+	}
+	return snapshots, nil
+}
+
+// GetVolumeSnapshot returns a single VolumeSnapshot
+func (c *Client) GetVolumeSnapshot(ctx context.Context, namespace, name string) (*VolumeSnapshot, error) {
+	item, err := c.dynamic.Resource(volumeSnapshotGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volume snapshot: %w", err)
+	}
+
+	snapshot := parseVolumeSnapshot(item)
+	return &snapshot, nil
+}
+
+// CreateVolumeSnapshot snapshots a PVC using the given (or default) snapshot class
+func (c *Client) CreateVolumeSnapshot(ctx context.Context, namespace, pvcName, name, className string, labels map[string]string) (*VolumeSnapshot, error) {
+	if _, err := c.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, pvcName, metav1.GetOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to get persistent volume claim: %w", err)
+	}
+
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{
+			"persistentVolumeClaimName": pvcName,
+		},
+	}
+	if className != "" {
+		spec["volumeSnapshotClassName"] = className
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "snapshot.storage.k8s.io/v1",
+		"kind":       "VolumeSnapshot",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+		},
+		"spec": spec,
+	}}
+	obj.SetLabels(labels)
+
+	created, err := c.dynamic.Resource(volumeSnapshotGVR).Namespace(namespace).Create(ctx, obj, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create volume snapshot: %w", err)
+	}
+
+	snapshot := parseVolumeSnapshot(created)
+	return &snapshot, nil
+}
+
+// DeleteVolumeSnapshot deletes a VolumeSnapshot
+func (c *Client) DeleteVolumeSnapshot(ctx context.Context, namespace, name string) error {
+	return c.dynamic.Resource(volumeSnapshotGVR).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// RestorePVCFromSnapshot creates a new PVC whose data source is the given snapshot
+func (c *Client) RestorePVCFromSnapshot(ctx context.Context, namespace, snapshotName, claimName, storageClass string) (*corev1.PersistentVolumeClaim, error) {
+	snapshot, err := c.GetVolumeSnapshot(ctx, namespace, snapshotName)
+	if err != nil {
+		return nil, err
+	}
+	if !snapshot.ReadyToUse {
+		return nil, fmt.Errorf("volume snapshot %s is not ready to use", snapshotName)
+	}
+
+	// Copy access modes and storage class from the source claim when it still exists
+	accessModes := []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	if source, err := c.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, snapshot.SourcePVC, metav1.GetOptions{}); err == nil {
+		accessModes = source.Spec.AccessModes
+		if storageClass == "" && source.Spec.StorageClassName != nil {
+			storageClass = *source.Spec.StorageClassName
+		}
+	}
+
+	size, err := resource.ParseQuantity(snapshot.RestoreSize)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s has no usable restore size: %w", snapshotName, err)
+	}
+
+	apiGroup := volumeSnapshotGVR.Group
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      claimName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "denshimon",
+			},
+			Annotations: map[string]string{
+				"denshimon.io/restored-from": snapshotName,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: accessModes,
+			DataSource: &corev1.TypedLocalObjectReference{
+				APIGroup: &apiGroup,
+				Kind:     "VolumeSnapshot",
+				Name:     snapshotName,
+			},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+		},
+	}
+	if storageClass != "" {
+		pvc.Spec.StorageClassName = &storageClass
+	}
+
+	created, err := c.clientset.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, pvc, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create persistent volume claim: %w", err)
+	}
+	return created, nil
+}
+
+// GetPersistentVolumeClaim returns a PVC
+func (c *Client) GetPersistentVolumeClaim(ctx context.Context, namespace, name string) (*corev1.PersistentVolumeClaim, error) {
+	return c.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// GetStorageClass returns a StorageClass
+func (c *Client) GetStorageClass(ctx context.Context, name string) (*storagev1.StorageClass, error) {
+	return c.clientset.StorageV1().StorageClasses().Get(ctx, name, metav1.GetOptions{})
+}
+
+// parseVolumeSnapshot converts an unstructured VolumeSnapshot into its summary form
+func parseVolumeSnapshot(item *unstructured.Unstructured) VolumeSnapshot {
+	snapshot := VolumeSnapshot{
+		Name:      item.GetName(),
+		Namespace: item.GetNamespace(),
+		Labels:    item.GetLabels(),
+	}
+
+	snapshot.SourcePVC, _, _ = unstructured.NestedString(item.Object, "spec", "source", "persistentVolumeClaimName")
+	snapshot.ClassName, _, _ = unstructured.NestedString(item.Object, "spec", "volumeSnapshotClassName")
+	snapshot.ReadyToUse, _, _ = unstructured.NestedBool(item.Object, "status", "readyToUse")
+	snapshot.RestoreSize, _, _ = unstructured.NestedString(item.Object, "status", "restoreSize")
+	snapshot.Error, _, _ = unstructured.NestedString(item.Object, "status", "error", "message")
+
+	if created, found, _ := unstructured.NestedString(item.Object, "status", "creationTime"); found {
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			snapshot.CreationTime = &t
+		}
+	}
+
+	return snapshot
+}
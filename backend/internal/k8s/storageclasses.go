@@ -0,0 +1,266 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DefaultStorageClassAnnotation marks the storage class used for claims that don't name one
+const DefaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+// Errors for storage classes and claim expansions the API server or cluster would reject
+var (
+	ErrInvalidStorageClass = errors.New("invalid storage class")
+	ErrInvalidExpansion    = errors.New("invalid volume expansion")
+)
+
+// StorageClassInfo is the summary of a storage class
+type StorageClassInfo struct {
+	Name                 string            `json:"name"`
+	Provisioner          string            `json:"provisioner"`
+	ReclaimPolicy        string            `json:"reclaimPolicy"`
+	VolumeBindingMode    string            `json:"volumeBindingMode"`
+	AllowVolumeExpansion bool              `json:"allowVolumeExpansion"`
+	IsDefault            bool              `json:"isDefault"`
+	Parameters           map[string]string `json:"parameters,omitempty"`
+	CreatedAt            time.Time         `json:"createdAt"`
+}
+
+// Resize statuses of a claim
+const (
+	ResizePending           = "pending"                 // requested, not picked up yet
+	ResizeInProgress        = "resizing"                // the volume is being expanded
+	ResizeFileSystemPending = "filesystemResizePending" // waits for a pod to mount the claim
+	ResizeCompleted         = "completed"
+	ResizeFailed            = "failed"
+)
+
+// VolumeResize is the progress of a claim's expansion, from its conditions and allocated
+// resource statuses
+type VolumeResize struct {
+	Namespace  string                                  `json:"namespace"`
+	Name       string                                  `json:"name"`
+	Requested  string                                  `json:"requested"`
+	Capacity   string                                  `json:"capacity"`
+	Status     string                                  `json:"status"`
+	Message    string                                  `json:"message,omitempty"`
+	Conditions []corev1.PersistentVolumeClaimCondition `json:"conditions,omitempty"`
+}
+
+// ListStorageClasses lists storage classes, the default first
+func (c *Client) ListStorageClasses(ctx context.Context) ([]StorageClassInfo, error) {
+	list, err := c.clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage classes: %w", err)
+	}
+	classes := make([]StorageClassInfo, 0, len(list.Items))
+	for i := range list.Items {
+		classes = append(classes, newStorageClassInfo(&list.Items[i]))
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if classes[i].IsDefault != classes[j].IsDefault {
+			return classes[i].IsDefault
+		}
+		return classes[i].Name < classes[j].Name
+	})
+	return classes, nil
+}
+
+func newStorageClassInfo(class *storagev1.StorageClass) StorageClassInfo {
+	info := StorageClassInfo{
+		Name:                 class.Name,
+		Provisioner:          class.Provisioner,
+		ReclaimPolicy:        string(corev1.PersistentVolumeReclaimDelete),
+		VolumeBindingMode:    string(storagev1.VolumeBindingImmediate),
+		AllowVolumeExpansion: class.AllowVolumeExpansion != nil && *class.AllowVolumeExpansion,
+		IsDefault:            class.Annotations[DefaultStorageClassAnnotation] == "true",
+		Parameters:           class.Parameters,
+		CreatedAt:            class.CreationTimestamp.Time,
+	}
+	if class.ReclaimPolicy != nil {
+		info.ReclaimPolicy = string(*class.ReclaimPolicy)
+	}
+	if class.VolumeBindingMode != nil {
+		info.VolumeBindingMode = string(*class.VolumeBindingMode)
+	}
+	return info
+}
+
+// CreateStorageClass creates a storage class. Marking it default leaves other defaults alone;
+// use SetDefaultStorageClass to switch.
+func (c *Client) CreateStorageClass(ctx context.Context, class *storagev1.StorageClass) (*storagev1.StorageClass, error) {
+	if class.Name == "" || class.Provisioner == "" {
+		return nil, fmt.Errorf("%w: name and provisioner are required", ErrInvalidStorageClass)
+	}
+	return c.clientset.StorageV1().StorageClasses().Create(ctx, class, metav1.CreateOptions{})
+}
+
+// DeleteStorageClass deletes a storage class. Existing volumes keep working; new claims
+// naming it stay pending.
+func (c *Client) DeleteStorageClass(ctx context.Context, name string) error {
+	return c.clientset.StorageV1().StorageClasses().Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// SetDefaultStorageClass marks a storage class as the default and unmarks every other one
+func (c *Client) SetDefaultStorageClass(ctx context.Context, name string) (*storagev1.StorageClass, error) {
+	target, err := c.GetStorageClass(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage classes: %w", err)
+	}
+	for _, class := range list.Items {
+		if class.Name == name || class.Annotations[DefaultStorageClassAnnotation] != "true" {
+			continue
+		}
+		if _, err := c.patchDefaultStorageClass(ctx, class.Name, false); err != nil {
+			return nil, fmt.Errorf("failed to unset default storage class %s: %w", class.Name, err)
+		}
+	}
+	if target.Annotations[DefaultStorageClassAnnotation] == "true" {
+		return target, nil
+	}
+	return c.patchDefaultStorageClass(ctx, name, true)
+}
+
+func (c *Client) patchDefaultStorageClass(ctx context.Context, name string, isDefault bool) (*storagev1.StorageClass, error) {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{DefaultStorageClassAnnotation: fmt.Sprint(isDefault)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.clientset.StorageV1().StorageClasses().Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+}
+
+// ExpandPersistentVolumeClaim raises the storage request of a bound claim whose storage class
+// allows volume expansion. Claims can't shrink.
+func (c *Client) ExpandPersistentVolumeClaim(ctx context.Context, namespace, name string, size resource.Quantity) (*VolumeResize, error) {
+	pvc, err := c.GetPersistentVolumeClaim(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkExpansion(ctx, pvc, size); err != nil {
+		return nil, err
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"resources": map[string]interface{}{
+				"requests": map[string]string{string(corev1.ResourceStorage): size.String()},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	updated, err := c.clientset.CoreV1().PersistentVolumeClaims(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand persistent volume claim: %w", err)
+	}
+	return NewVolumeResize(updated), nil
+}
+
+func (c *Client) checkExpansion(ctx context.Context, pvc *corev1.PersistentVolumeClaim, size resource.Quantity) error {
+	if pvc.Status.Phase != corev1.ClaimBound {
+		return fmt.Errorf("%w: claim is %s, only bound claims can be expanded", ErrInvalidExpansion, pvc.Status.Phase)
+	}
+	current := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if size.Cmp(current) <= 0 {
+		return fmt.Errorf("%w: new size %s must be larger than the current request %s", ErrInvalidExpansion, size.String(), current.String())
+	}
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return fmt.Errorf("%w: claim has no storage class", ErrInvalidExpansion)
+	}
+	class, err := c.GetStorageClass(ctx, *pvc.Spec.StorageClassName)
+	if err != nil {
+		return fmt.Errorf("failed to get storage class %s: %w", *pvc.Spec.StorageClassName, err)
+	}
+	if class.AllowVolumeExpansion == nil || !*class.AllowVolumeExpansion {
+		return fmt.Errorf("%w: storage class %s does not allow volume expansion", ErrInvalidExpansion, class.Name)
+	}
+	return nil
+}
+
+// GetVolumeResize returns the expansion progress of a claim
+func (c *Client) GetVolumeResize(ctx context.Context, namespace, name string) (*VolumeResize, error) {
+	pvc, err := c.GetPersistentVolumeClaim(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return NewVolumeResize(pvc), nil
+}
+
+// NewVolumeResize reads a claim's expansion progress. Errors and infeasible sizes fail it;
+// otherwise it's complete once the capacity reaches the request.
+func NewVolumeResize(pvc *corev1.PersistentVolumeClaim) *VolumeResize {
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+	resize := &VolumeResize{
+		Namespace:  pvc.Namespace,
+		Name:       pvc.Name,
+		Requested:  requested.String(),
+		Capacity:   capacity.String(),
+		Status:     ResizePending,
+		Conditions: pvc.Status.Conditions,
+	}
+
+	switch pvc.Status.AllocatedResourceStatuses[corev1.ResourceStorage] {
+	case corev1.PersistentVolumeClaimControllerResizeInfeasible:
+		resize.Status, resize.Message = ResizeFailed, "The storage provider rejected the new size"
+		return resize
+	case corev1.PersistentVolumeClaimNodeResizeInfeasible:
+		resize.Status, resize.Message = ResizeFailed, "The node rejected the new size"
+		return resize
+	}
+
+	status := map[corev1.PersistentVolumeClaimConditionType]corev1.PersistentVolumeClaimCondition{}
+	for _, condition := range pvc.Status.Conditions {
+		if condition.Status == corev1.ConditionTrue {
+			status[condition.Type] = condition
+		}
+	}
+	for _, failure := range []corev1.PersistentVolumeClaimConditionType{
+		corev1.PersistentVolumeClaimControllerResizeError, corev1.PersistentVolumeClaimNodeResizeError,
+	} {
+		if condition, ok := status[failure]; ok {
+			resize.Status, resize.Message = ResizeFailed, condition.Message
+			return resize
+		}
+	}
+
+	switch {
+	case !capacity.IsZero() && capacity.Cmp(requested) >= 0:
+		resize.Status = ResizeCompleted
+	case hasCondition(status, corev1.PersistentVolumeClaimFileSystemResizePending) ||
+		pvc.Status.AllocatedResourceStatuses[corev1.ResourceStorage] == corev1.PersistentVolumeClaimNodeResizePending:
+		resize.Status = ResizeFileSystemPending
+		resize.Message = "The volume was expanded; the filesystem is resized once a pod mounts the claim"
+	case hasCondition(status, corev1.PersistentVolumeClaimResizing) ||
+		pvc.Status.AllocatedResourceStatuses[corev1.ResourceStorage] != "":
+		resize.Status = ResizeInProgress
+		if condition, ok := status[corev1.PersistentVolumeClaimResizing]; ok {
+			resize.Message = condition.Message
+		}
+	}
+	return resize
+}
+
+func hasCondition(conditions map[corev1.PersistentVolumeClaimConditionType]corev1.PersistentVolumeClaimCondition, conditionType corev1.PersistentVolumeClaimConditionType) bool {
+	_, ok := conditions[conditionType]
+	return ok
+}
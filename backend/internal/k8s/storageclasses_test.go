@@ -0,0 +1,109 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testClaim(class, requested, capacity string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &class,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(requested)},
+			},
+		},
+		Status: corev1.PersistentVolumeClaimStatus{
+			Phase:    corev1.ClaimBound,
+			Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(capacity)},
+		},
+	}
+}
+
+func TestExpandPersistentVolumeClaim(t *testing.T) {
+	ctx := context.Background()
+	expandable, fixed := true, false
+	client := &Client{clientset: fake.NewSimpleClientset(
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}, AllowVolumeExpansion: &expandable},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fixed"}, AllowVolumeExpansion: &fixed},
+		testClaim("fast", "10Gi", "10Gi"),
+	)}
+
+	if _, err := client.ExpandPersistentVolumeClaim(ctx, "default", "data", resource.MustParse("5Gi")); !errors.Is(err, ErrInvalidExpansion) {
+		t.Errorf("expected shrinking to be rejected, got %v", err)
+	}
+	resize, err := client.ExpandPersistentVolumeClaim(ctx, "default", "data", resource.MustParse("20Gi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resize.Requested != "20Gi" || resize.Capacity != "10Gi" || resize.Status != ResizePending {
+		t.Errorf("unexpected resize %+v", resize)
+	}
+
+	if err := client.checkExpansion(ctx, testClaim("fixed", "10Gi", "10Gi"), resource.MustParse("20Gi")); !errors.Is(err, ErrInvalidExpansion) {
+		t.Errorf("expected a class without expansion to be rejected, got %v", err)
+	}
+}
+
+func TestNewVolumeResize(t *testing.T) {
+	condition := func(conditionType corev1.PersistentVolumeClaimConditionType, message string) corev1.PersistentVolumeClaimCondition {
+		return corev1.PersistentVolumeClaimCondition{Type: conditionType, Status: corev1.ConditionTrue, Message: message}
+	}
+	tests := []struct {
+		name       string
+		capacity   string
+		conditions []corev1.PersistentVolumeClaimCondition
+		allocated  corev1.ClaimResourceStatus
+		want       string
+	}{
+		{"not picked up", "10Gi", nil, "", ResizePending},
+		{"controller resizing", "10Gi", []corev1.PersistentVolumeClaimCondition{condition(corev1.PersistentVolumeClaimResizing, "")}, "", ResizeInProgress},
+		{"waits for a mount", "10Gi", []corev1.PersistentVolumeClaimCondition{condition(corev1.PersistentVolumeClaimFileSystemResizePending, "")}, "", ResizeFileSystemPending},
+		{"node resize pending", "10Gi", nil, corev1.PersistentVolumeClaimNodeResizePending, ResizeFileSystemPending},
+		{"controller error", "10Gi", []corev1.PersistentVolumeClaimCondition{condition(corev1.PersistentVolumeClaimControllerResizeError, "quota exceeded")}, "", ResizeFailed},
+		{"infeasible", "10Gi", nil, corev1.PersistentVolumeClaimControllerResizeInfeasible, ResizeFailed},
+		{"done", "20Gi", nil, "", ResizeCompleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pvc := testClaim("fast", "20Gi", tt.capacity)
+			pvc.Status.Conditions = tt.conditions
+			if tt.allocated != "" {
+				pvc.Status.AllocatedResourceStatuses = map[corev1.ResourceName]corev1.ClaimResourceStatus{corev1.ResourceStorage: tt.allocated}
+			}
+			if got := NewVolumeResize(pvc); got.Status != tt.want {
+				t.Errorf("status = %q, want %q (%s)", got.Status, tt.want, got.Message)
+			}
+		})
+	}
+}
+
+func TestSetDefaultStorageClass(t *testing.T) {
+	ctx := context.Background()
+	client := &Client{clientset: fake.NewSimpleClientset(
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "standard", Annotations: map[string]string{DefaultStorageClassAnnotation: "true"}}},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}},
+	)}
+
+	if _, err := client.SetDefaultStorageClass(ctx, "fast"); err != nil {
+		t.Fatal(err)
+	}
+	classes, err := client.ListStorageClasses(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(classes) != 2 || classes[0].Name != "fast" || !classes[0].IsDefault || classes[1].IsDefault {
+		t.Errorf("expected fast to be the only default, got %+v", classes)
+	}
+	if _, err := client.SetDefaultStorageClass(ctx, "missing"); err == nil {
+		t.Error("expected an unknown class to fail")
+	}
+}
@@ -0,0 +1,154 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// NodeFilesystemUsage is the used and total bytes of a node's root filesystem
+type NodeFilesystemUsage struct {
+	Node           string  `json:"node"`
+	UsedBytes      int64   `json:"used_bytes"`
+	CapacityBytes  int64   `json:"capacity_bytes"`
+	AvailableBytes int64   `json:"available_bytes"`
+	UsagePercent   float64 `json:"usage_percent"`
+	StorageTrend
+}
+
+// VolumeUsage is the used and total bytes of a persistent volume claim, as reported by the
+// kubelet of a node where a pod mounts it
+type VolumeUsage struct {
+	Namespace      string  `json:"namespace"`
+	PVC            string  `json:"pvc"`
+	Pod            string  `json:"pod"`
+	Node           string  `json:"node"`
+	UsedBytes      int64   `json:"used_bytes"`
+	CapacityBytes  int64   `json:"capacity_bytes"`
+	AvailableBytes int64   `json:"available_bytes"`
+	UsagePercent   float64 `json:"usage_percent"`
+	StorageTrend
+}
+
+// StorageTrend is the growth of a filesystem projected from its usage history. It's left
+// empty until enough history has been recorded.
+type StorageTrend struct {
+	GrowthBytesPerDay *float64 `json:"growth_bytes_per_day,omitempty"`
+	// DaysUntilFull is set only for filesystems that are growing
+	DaysUntilFull *float64 `json:"days_until_full,omitempty"`
+}
+
+// StorageUsage is the usage of every node filesystem and mounted persistent volume claim
+type StorageUsage struct {
+	Nodes   []NodeFilesystemUsage `json:"nodes"`
+	Volumes []VolumeUsage         `json:"volumes"`
+}
+
+// GetStorageUsage reads node filesystem and volume usage from the kubelet stats summary of
+// every node. Nodes whose kubelet doesn't answer are left out; it fails only when none answer.
+// Claims that aren't mounted by a running pod have no usage to report.
+func (c *Client) GetStorageUsage(ctx context.Context) (*StorageUsage, error) {
+	nodes, err := c.ListNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &StorageUsage{Nodes: []NodeFilesystemUsage{}, Volumes: []VolumeUsage{}}
+	var lastErr error
+	for _, node := range nodes.Items {
+		data, err := c.GetNodeStatsSummary(ctx, node.Name)
+		if err == nil {
+			err = addStorageSummary(usage, node.Name, data)
+		}
+		if err != nil {
+			lastErr = fmt.Errorf("failed to read stats summary of node %s: %w", node.Name, err)
+			slog.Debug("Node storage usage unavailable", "node", node.Name, "error", err)
+		}
+	}
+	if len(usage.Nodes) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	sort.Slice(usage.Volumes, func(i, j int) bool {
+		if usage.Volumes[i].Namespace != usage.Volumes[j].Namespace {
+			return usage.Volumes[i].Namespace < usage.Volumes[j].Namespace
+		}
+		return usage.Volumes[i].PVC < usage.Volumes[j].PVC
+	})
+	return usage, nil
+}
+
+// addStorageSummary adds the node filesystem and claim volumes of one stats summary. A claim
+// mounted by several pods is reported once.
+func addStorageSummary(usage *StorageUsage, node string, data []byte) error {
+	var summary struct {
+		Node struct {
+			Fs fsStats `json:"fs"`
+		} `json:"node"`
+		Pods []struct {
+			PodRef struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"podRef"`
+			Volumes []struct {
+				fsStats
+				PVCRef *struct {
+					Name      string `json:"name"`
+					Namespace string `json:"namespace"`
+				} `json:"pvcRef"`
+			} `json:"volume"`
+		} `json:"pods"`
+	}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return fmt.Errorf("invalid stats summary: %w", err)
+	}
+
+	fs := summary.Node.Fs
+	usage.Nodes = append(usage.Nodes, NodeFilesystemUsage{
+		Node:           node,
+		UsedBytes:      fs.UsedBytes,
+		CapacityBytes:  fs.CapacityBytes,
+		AvailableBytes: fs.AvailableBytes,
+		UsagePercent:   fs.percent(),
+	})
+
+	seen := make(map[string]bool, len(usage.Volumes))
+	for _, volume := range usage.Volumes {
+		seen[volume.Namespace+"/"+volume.PVC] = true
+	}
+	for _, pod := range summary.Pods {
+		for _, volume := range pod.Volumes {
+			if volume.PVCRef == nil || seen[volume.PVCRef.Namespace+"/"+volume.PVCRef.Name] {
+				continue
+			}
+			seen[volume.PVCRef.Namespace+"/"+volume.PVCRef.Name] = true
+			usage.Volumes = append(usage.Volumes, VolumeUsage{
+				Namespace:      volume.PVCRef.Namespace,
+				PVC:            volume.PVCRef.Name,
+				Pod:            pod.PodRef.Name,
+				Node:           node,
+				UsedBytes:      volume.UsedBytes,
+				CapacityBytes:  volume.CapacityBytes,
+				AvailableBytes: volume.AvailableBytes,
+				UsagePercent:   volume.percent(),
+			})
+		}
+	}
+	return nil
+}
+
+// fsStats are the filesystem figures of a kubelet stats summary
+type fsStats struct {
+	UsedBytes      int64 `json:"usedBytes"`
+	CapacityBytes  int64 `json:"capacityBytes"`
+	AvailableBytes int64 `json:"availableBytes"`
+}
+
+func (fs fsStats) percent() float64 {
+	if fs.CapacityBytes == 0 {
+		return 0
+	}
+	return float64(fs.UsedBytes) / float64(fs.CapacityBytes) * 100
+}
@@ -0,0 +1,37 @@
+package k8s
+
+import "testing"
+
+func TestAddStorageSummary(t *testing.T) {
+	summary := `{
+		"node": {"fs": {"usedBytes": 30, "capacityBytes": 120, "availableBytes": 90}},
+		"pods": [
+			{"podRef": {"name": "db-0", "namespace": "data"}, "volume": [
+				{"name": "data", "usedBytes": 40, "capacityBytes": 50, "availableBytes": 10, "pvcRef": {"name": "data-db-0", "namespace": "data"}},
+				{"name": "kube-api-access", "usedBytes": 1, "capacityBytes": 100}
+			]},
+			{"podRef": {"name": "backup", "namespace": "data"}, "volume": [
+				{"name": "data", "usedBytes": 40, "capacityBytes": 50, "pvcRef": {"name": "data-db-0", "namespace": "data"}}
+			]}
+		]
+	}`
+
+	usage := &StorageUsage{}
+	if err := addStorageSummary(usage, "node-1", []byte(summary)); err != nil {
+		t.Fatal(err)
+	}
+	if len(usage.Nodes) != 1 || usage.Nodes[0].Node != "node-1" || usage.Nodes[0].UsagePercent != 25 {
+		t.Errorf("unexpected node usage %+v", usage.Nodes)
+	}
+	if len(usage.Volumes) != 1 {
+		t.Fatalf("expected the claim once and no other volumes, got %+v", usage.Volumes)
+	}
+	volume := usage.Volumes[0]
+	if volume.Namespace != "data" || volume.PVC != "data-db-0" || volume.Pod != "db-0" || volume.Node != "node-1" || volume.UsagePercent != 80 {
+		t.Errorf("unexpected volume usage %+v", volume)
+	}
+
+	if err := addStorageSummary(usage, "node-2", []byte("not json")); err == nil {
+		t.Error("expected an invalid summary to be rejected")
+	}
+}
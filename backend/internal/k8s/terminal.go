@@ -6,6 +6,8 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	v1 "k8s.io/api/core/v1"
@@ -18,16 +20,64 @@ import (
 
 // TerminalSession represents a WebSocket terminal session
 type TerminalSession struct {
-	conn     *websocket.Conn
-	sizeChan chan *remotecommand.TerminalSize
-	doneChan chan struct{}
-	ctx      context.Context
-	cancel   context.CancelFunc
+	conn         *websocket.Conn
+	writeMu      sync.Mutex // serializes writes to conn
+	sizeChan     chan *remotecommand.TerminalSize
+	doneChan     chan struct{}
+	input        chan []byte // keystrokes of whoever holds write, for Read
+	pending      []byte      // input read from the channel but not yet consumed
+	ctx          context.Context
+	cancel       context.CancelFunc
+	closeOnce    sync.Once
+	recorder     SessionRecorder
+	authenticate func(token string) (string, error)
+
+	// Sharing with other users; see terminal_share.go
+	shares *ShareRegistry
+	share  shareState
+}
+
+// SessionRecorder captures terminal session activity for audit and replay
+type SessionRecorder interface {
+	RecordOutput(data []byte)
+	RecordInput(data []byte)
+	RecordResize(cols, rows uint16)
+	// RecordMarker records an event of a shared session, such as a user joining
+	RecordMarker(label string)
+	SetUser(user string)
+	// AddParticipant notes a user who joined the session through a share link
+	AddParticipant(user string)
+	Close() error
+}
+
+// ExecSessionInfo describes an exec session being recorded. Node is set for node shells,
+// whose agent pod is Namespace/Pod.
+type ExecSessionInfo struct {
+	Namespace string
+	Pod       string
+	Container string
+	Command   string
+	Node      string
+	User      string
+	Cols      uint16
+	Rows      uint16
+}
+
+// ExecOptions configures optional behaviour of exec sessions
+type ExecOptions struct {
+	// User is the authenticated user opening the session, if known
+	User string
+	// NewRecorder starts a recording for the session; nil disables recording
+	NewRecorder func(info ExecSessionInfo) (SessionRecorder, error)
+	// Authenticate resolves the username for in-band "auth" messages
+	Authenticate func(token string) (string, error)
+	// Shares lets the owner share the session with other users; nil disables sharing
+	Shares *ShareRegistry
 }
 
 // TerminalMessage represents messages sent over WebSocket
 type TerminalMessage struct {
-	Type string      `json:"type"` // "data", "resize", "close"
+	Type string      `json:"type"` // "data", "resize", "auth", "close", or a sharing message
 	Data interface{} `json:"data"`
 }
 
@@ -57,6 +107,7 @@ func NewTerminalSession(w http.ResponseWriter, r *http.Request, clientset kubern
 		conn:     conn,
 		sizeChan: make(chan *remotecommand.TerminalSize, 10),
 		doneChan: make(chan struct{}),
+		input:    make(chan []byte, 32),
 		ctx:      ctx,
 		cancel:   cancel,
 	}
@@ -65,7 +116,7 @@ func NewTerminalSession(w http.ResponseWriter, r *http.Request, clientset kubern
 }
 
 // HandlePodExec handles WebSocket-based pod execution
-func (c *Client) HandlePodExec(w http.ResponseWriter, r *http.Request) {
+func (c *Client) HandlePodExec(w http.ResponseWriter, r *http.Request, opts ExecOptions) {
 	// Extract parameters
 	namespace := r.URL.Query().Get("namespace")
 	podName := r.URL.Query().Get("pod")
@@ -101,11 +152,50 @@ func (c *Client) HandlePodExec(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	session.authenticate = opts.Authenticate
+	session.shares = opts.Shares
+	session.share.owner = opts.User
+	if !session.startRecording(opts, ExecSessionInfo{
+		Namespace: namespace,
+		Pod:       podName,
+		Container: containerName,
+		Command:   command,
+		User:      opts.User,
+	}) {
+		return
+	}
+
 	// Start the exec session
 	go session.handleExec(c, namespace, podName, containerName, []string{command})
 
 	// Handle WebSocket messages
 	session.handleWebSocketMessages()
+
+	// Debug containers only exist for their terminal
+	if IsDebugContainer(containerName) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := c.StopDebugContainer(ctx, namespace, podName, containerName); err != nil {
+			slog.Warn("Failed to stop debug container", "pod", podName, "container", containerName, "error", err)
+		}
+	}
+}
+
+// startRecording starts the session's recording if the options ask for one. It refuses
+// unrecorded sessions rather than silently losing the audit trail, closing the session.
+func (ts *TerminalSession) startRecording(opts ExecOptions, info ExecSessionInfo) bool {
+	if opts.NewRecorder == nil {
+		return true
+	}
+	recorder, err := opts.NewRecorder(info)
+	if err != nil {
+		slog.Error("Failed to start session recording", "error", err)
+		ts.sendError("Failed to start session recording")
+		ts.close()
+		return false
+	}
+	ts.recorder = recorder
+	return true
 }
 
 // handleExec starts the kubectl exec session
@@ -135,79 +225,104 @@ func (ts *TerminalSession) handleExec(client *Client, namespace, podName, contai
 	}
 }
 
-// handleWebSocketMessages processes incoming WebSocket messages
+// handleWebSocketMessages reads the owner's WebSocket messages, queueing keystrokes for Read.
+// It is the only reader of the connection.
 func (ts *TerminalSession) handleWebSocketMessages() {
 	defer ts.close()
 
 	for {
-		select {
-		case <-ts.ctx.Done():
-			return
-		default:
-			var msg TerminalMessage
-			if err := ts.conn.ReadJSON(&msg); err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					slog.Error("WebSocket read error", "error", err)
-				}
-				return
+		var msg TerminalMessage
+		if err := ts.conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				slog.Error("WebSocket read error", "error", err)
 			}
+			return
+		}
 
-			switch msg.Type {
-			case "data":
-				// Send input to terminal
-				if _, ok := msg.Data.(string); ok {
-					// Input will be handled by the Read method
-					select {
-					case <-ts.ctx.Done():
-						return
-					default:
-						// Store input for Read method to consume
-						// This is handled by the WebSocket Read implementation
-					}
-				}
-			case "resize":
-				// Handle terminal resize
-				if sizeData, ok := msg.Data.(map[string]interface{}); ok {
-					if rows, ok := sizeData["rows"].(float64); ok {
-						if cols, ok := sizeData["cols"].(float64); ok {
-							ts.resize(uint16(rows), uint16(cols))
-						}
-					}
+		switch msg.Type {
+		case "data":
+			if data, ok := msg.Data.(string); ok && ts.ownerWrites() {
+				ts.queueInput([]byte(data))
+			}
+		case "resize":
+			if sizeData, ok := msg.Data.(map[string]interface{}); ok {
+				rows, rowsOK := sizeData["rows"].(float64)
+				cols, colsOK := sizeData["cols"].(float64)
+				if rowsOK && colsOK {
+					ts.resize(uint16(rows), uint16(cols))
 				}
-			case "close":
-				return
 			}
+		case "auth":
+			ts.handleAuth(msg.Data)
+		case "share", "unshare", "handoff":
+			ts.handleShareMessage(msg)
+		case "close":
+			return
 		}
 	}
 }
 
+// queueInput passes keystrokes to the exec stream
+func (ts *TerminalSession) queueInput(data []byte) {
+	select {
+	case ts.input <- data:
+	case <-ts.doneChan:
+	}
+}
+
 // Implement io.Reader for stdin
 func (ts *TerminalSession) Read(p []byte) (int, error) {
-	// Read data from WebSocket and pass to kubectl exec
-	var msg TerminalMessage
-	if err := ts.conn.ReadJSON(&msg); err != nil {
-		return 0, err
+	if len(ts.pending) == 0 {
+		select {
+		case data := <-ts.input:
+			ts.pending = data
+		case <-ts.doneChan:
+			return 0, io.EOF
+		}
 	}
 
-	if msg.Type == "data" {
-		if data, ok := msg.Data.(string); ok {
-			n := copy(p, []byte(data))
-			return n, nil
-		}
+	n := copy(p, ts.pending)
+	ts.pending = ts.pending[n:]
+	if ts.recorder != nil {
+		ts.recorder.RecordInput(p[:n])
 	}
+	return n, nil
+}
 
-	return 0, io.EOF
+// handleAuth attributes the session, and its recording, to the user behind an in-band auth token
+func (ts *TerminalSession) handleAuth(data interface{}) {
+	if ts.authenticate == nil {
+		return
+	}
+	payload, ok := data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	token, _ := payload["token"].(string)
+	user, err := ts.authenticate(token)
+	if err != nil {
+		return
+	}
+	ts.setOwner(user)
+	if ts.recorder != nil {
+		ts.recorder.SetUser(user)
+	}
 }
 
 // Implement io.Writer for stdout/stderr
 func (ts *TerminalSession) Write(p []byte) (int, error) {
+	if ts.recorder != nil {
+		ts.recorder.RecordOutput(p)
+	}
+
 	// Send output to WebSocket client
 	msg := TerminalMessage{
 		Type: "data",
 		Data: string(p),
 	}
 
-	if err := ts.conn.WriteJSON(msg); err != nil {
+	ts.broadcastToViewers(msg)
+	if err := ts.send(msg); err != nil {
 		return 0, err
 	}
 
@@ -226,6 +341,10 @@ func (ts *TerminalSession) Next() *remotecommand.TerminalSize {
 
 // resize sends a terminal resize event
 func (ts *TerminalSession) resize(rows, cols uint16) {
+	if ts.recorder != nil {
+		ts.recorder.RecordResize(cols, rows)
+	}
+
 	select {
 	case ts.sizeChan <- &remotecommand.TerminalSize{
 		Width:  cols,
@@ -241,14 +360,29 @@ func (ts *TerminalSession) sendError(message string) {
 		Type: "error",
 		Data: message,
 	}
-	ts.conn.WriteJSON(msg)
+	ts.send(msg)
+}
+
+// send writes a message to the owner's connection
+func (ts *TerminalSession) send(msg TerminalMessage) error {
+	ts.writeMu.Lock()
+	defer ts.writeMu.Unlock()
+	return ts.conn.WriteJSON(msg)
 }
 
 // close terminates the terminal session
 func (ts *TerminalSession) close() {
-	ts.cancel()
-	close(ts.doneChan)
-	ts.conn.Close()
+	ts.closeOnce.Do(func() {
+		ts.cancel()
+		close(ts.doneChan)
+		ts.stopSharing("The session ended")
+		ts.conn.Close()
+		if ts.recorder != nil {
+			if err := ts.recorder.Close(); err != nil {
+				slog.Error("Failed to finalize session recording", "error", err)
+			}
+		}
+	})
 }
 
 // getExecRequest creates the exec request
@@ -0,0 +1,462 @@
+package k8s
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// Share modes of an exec session
+const (
+	// ShareReadOnly lets viewers watch the terminal
+	ShareReadOnly = "read-only"
+	// ShareHandoff also lets the owner hand writing to a viewer, one at a time
+	ShareHandoff = "handoff"
+)
+
+// joinAuthTimeout is how long a viewer without a bearer token has to send an auth message
+const joinAuthTimeout = 10 * time.Second
+
+// Errors of joining a shared session
+var (
+	ErrShareNotFound = errors.New("shared session not found")
+	ErrShareToken    = errors.New("invalid share link")
+)
+
+// ShareRegistry keeps the exec sessions their owners shared, so other users can join them
+// through the session link
+type ShareRegistry struct {
+	mu       sync.RWMutex
+	sessions map[string]*TerminalSession
+}
+
+// NewShareRegistry creates an empty share registry
+func NewShareRegistry() *ShareRegistry {
+	return &ShareRegistry{sessions: make(map[string]*TerminalSession)}
+}
+
+func (r *ShareRegistry) add(id string, session *TerminalSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[id] = session
+}
+
+func (r *ShareRegistry) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, id)
+}
+
+// lookup returns the shared session a link points to, checking its token
+func (r *ShareRegistry) lookup(id, token string) (*TerminalSession, error) {
+	r.mu.RLock()
+	session, ok := r.sessions[id]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, ErrShareNotFound
+	}
+	session.share.mu.Lock()
+	defer session.share.mu.Unlock()
+	if session.share.mode == "" || subtle.ConstantTimeCompare([]byte(token), []byte(session.share.token)) != 1 {
+		return nil, ErrShareToken
+	}
+	return session, nil
+}
+
+// JoinOptions identify the user joining a shared session
+type JoinOptions struct {
+	// User is the authenticated user, if the request carried a bearer token
+	User string
+	// Authenticate resolves the username of an in-band "auth" message, the first message
+	// expected from viewers without a bearer token
+	Authenticate func(token string) (string, error)
+}
+
+// Join attaches a viewer's WebSocket to the shared session with the given ID. Viewers
+// receive the terminal's output and everyone's presence; they type only once the owner
+// hands them writing.
+func (r *ShareRegistry) Join(w http.ResponseWriter, req *http.Request, id, token string, opts JoinOptions) {
+	session, err := r.lookup(id, token)
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, ErrShareToken) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		slog.Error("Failed to upgrade shared session connection", "error", err)
+		return
+	}
+	v := &viewer{conn: conn, user: opts.User}
+	defer conn.Close()
+
+	if v.user == "" {
+		if v.user, err = authenticateViewer(conn, opts.Authenticate); err != nil {
+			v.send(TerminalMessage{Type: "error", Data: "Sign in to join this session"})
+			return
+		}
+	}
+	if !session.addViewer(v) {
+		v.send(TerminalMessage{Type: "error", Data: ErrShareNotFound.Error()})
+		return
+	}
+	defer session.removeViewer(v)
+
+	for {
+		var msg TerminalMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case "data":
+			if data, ok := msg.Data.(string); ok && session.viewerWrites(v) {
+				session.queueInput([]byte(data))
+			}
+		case "request_write":
+			session.requestWrite(v)
+		case "release":
+			session.release(v)
+		case "close":
+			return
+		}
+	}
+}
+
+// authenticateViewer expects an in-band auth message as the viewer's first message
+func authenticateViewer(conn *websocket.Conn, authenticate func(string) (string, error)) (string, error) {
+	if authenticate == nil {
+		return "", errors.New("authentication not available")
+	}
+	conn.SetReadDeadline(time.Now().Add(joinAuthTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var msg TerminalMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		return "", err
+	}
+	payload, ok := msg.Data.(map[string]interface{})
+	if msg.Type != "auth" || !ok {
+		return "", errors.New("expected an auth message")
+	}
+	token, _ := payload["token"].(string)
+	return authenticate(token)
+}
+
+// viewer is a user's connection to a session shared with them
+type viewer struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	user    string
+}
+
+func (v *viewer) send(msg TerminalMessage) error {
+	v.writeMu.Lock()
+	defer v.writeMu.Unlock()
+	return v.conn.WriteJSON(msg)
+}
+
+// shareState is the sharing of one session
+type shareState struct {
+	mu      sync.Mutex
+	owner   string // user who opened the session
+	id      string // set once the session is first shared
+	token   string
+	mode    string // ShareReadOnly or ShareHandoff; empty when not shared
+	writer  string // viewer holding write; empty while the owner types
+	viewers map[*viewer]struct{}
+}
+
+// Participant is a user in a session, as shown in presence messages
+type Participant struct {
+	User     string `json:"user"`
+	Role     string `json:"role"` // owner or viewer
+	CanWrite bool   `json:"canWrite"`
+}
+
+// Presence is who is in a shared session and who types
+type Presence struct {
+	Mode         string        `json:"mode"`
+	Owner        string        `json:"owner"`
+	Writer       string        `json:"writer"`
+	Participants []Participant `json:"participants"`
+}
+
+// ShareLink identifies a shared session; the frontend turns it into a link
+type ShareLink struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+	Mode  string `json:"mode"`
+}
+
+// setOwner records who opened the session once they authenticate in-band
+func (ts *TerminalSession) setOwner(user string) {
+	ts.share.mu.Lock()
+	defer ts.share.mu.Unlock()
+	if ts.share.owner == "" {
+		ts.share.owner = user
+	}
+}
+
+// handleShareMessage handles the owner's share, unshare and handoff messages
+func (ts *TerminalSession) handleShareMessage(msg TerminalMessage) {
+	payload, _ := msg.Data.(map[string]interface{})
+	var err error
+	switch msg.Type {
+	case "share":
+		mode, _ := payload["mode"].(string)
+		var link *ShareLink
+		if link, err = ts.startSharing(mode); err == nil {
+			ts.send(TerminalMessage{Type: "shared", Data: link})
+			ts.broadcastPresence()
+		}
+	case "unshare":
+		ts.stopSharing("The owner stopped sharing the session")
+		ts.send(TerminalMessage{Type: "unshared"})
+	case "handoff":
+		user, _ := payload["user"].(string)
+		err = ts.handoff(user)
+	}
+	if err != nil {
+		ts.sendError(err.Error())
+	}
+}
+
+// startSharing shares the session in the given mode, keeping its link if already shared
+func (ts *TerminalSession) startSharing(mode string) (*ShareLink, error) {
+	if ts.shares == nil {
+		return nil, errors.New("session sharing is not available")
+	}
+	if mode == "" {
+		mode = ShareReadOnly
+	}
+	if mode != ShareReadOnly && mode != ShareHandoff {
+		return nil, fmt.Errorf("share mode must be %s or %s", ShareReadOnly, ShareHandoff)
+	}
+
+	ts.share.mu.Lock()
+	if ts.share.owner == "" {
+		ts.share.mu.Unlock()
+		return nil, errors.New("sign in to share this session")
+	}
+	if ts.share.mode == "" {
+		token := make([]byte, 24)
+		if _, err := rand.Read(token); err != nil {
+			ts.share.mu.Unlock()
+			return nil, fmt.Errorf("failed to create share link: %w", err)
+		}
+		ts.share.id = uuid.New().String()
+		ts.share.token = hex.EncodeToString(token)
+		ts.share.viewers = make(map[*viewer]struct{})
+	}
+	if mode == ShareReadOnly {
+		ts.share.writer = ""
+	}
+	ts.share.mode = mode
+	link := &ShareLink{ID: ts.share.id, Token: ts.share.token, Mode: mode}
+	ts.share.mu.Unlock()
+
+	ts.shares.add(link.ID, ts)
+	ts.marker(fmt.Sprintf("session shared (%s)", mode))
+	return link, nil
+}
+
+// stopSharing invalidates the link and disconnects the viewers with a reason
+func (ts *TerminalSession) stopSharing(reason string) {
+	ts.share.mu.Lock()
+	if ts.share.mode == "" {
+		ts.share.mu.Unlock()
+		return
+	}
+	id, viewers := ts.share.id, ts.share.viewers
+	ts.share.mode, ts.share.token, ts.share.writer, ts.share.viewers = "", "", "", nil
+	ts.share.mu.Unlock()
+
+	if ts.shares != nil {
+		ts.shares.remove(id)
+	}
+	for v := range viewers {
+		v.send(TerminalMessage{Type: "closed", Data: reason})
+		v.conn.Close()
+	}
+	ts.marker("session no longer shared")
+}
+
+// addViewer joins a viewer to the session; false if it stopped being shared meanwhile
+func (ts *TerminalSession) addViewer(v *viewer) bool {
+	ts.share.mu.Lock()
+	if ts.share.mode == "" {
+		ts.share.mu.Unlock()
+		return false
+	}
+	ts.share.viewers[v] = struct{}{}
+	ts.share.mu.Unlock()
+
+	if ts.recorder != nil {
+		ts.recorder.AddParticipant(v.user)
+	}
+	ts.marker(v.user + " joined")
+	ts.broadcastPresence()
+	return true
+}
+
+// removeViewer leaves the session, handing write back to the owner if the viewer held it
+func (ts *TerminalSession) removeViewer(v *viewer) {
+	ts.share.mu.Lock()
+	if _, ok := ts.share.viewers[v]; !ok {
+		ts.share.mu.Unlock()
+		return
+	}
+	delete(ts.share.viewers, v)
+	if ts.share.writer == v.user && !ts.hasViewerLocked(v.user) {
+		ts.share.writer = ""
+	}
+	ts.share.mu.Unlock()
+
+	ts.marker(v.user + " left")
+	ts.broadcastPresence()
+}
+
+// handoff gives writing to a connected viewer, or back to the owner when user is empty or
+// the owner
+func (ts *TerminalSession) handoff(user string) error {
+	ts.share.mu.Lock()
+	switch {
+	case ts.share.mode == "":
+		ts.share.mu.Unlock()
+		return errors.New("the session is not shared")
+	case user == "" || user == ts.share.owner:
+		user = ""
+	case ts.share.mode != ShareHandoff:
+		ts.share.mu.Unlock()
+		return errors.New("the session is shared read-only")
+	case !ts.hasViewerLocked(user):
+		ts.share.mu.Unlock()
+		return fmt.Errorf("%s is not in the session", user)
+	}
+	ts.share.writer = user
+	owner := ts.share.owner
+	ts.share.mu.Unlock()
+
+	if user == "" {
+		user = owner
+	}
+	ts.marker("write handed to " + user)
+	ts.broadcastPresence()
+	return nil
+}
+
+// requestWrite asks the owner to hand writing to a viewer
+func (ts *TerminalSession) requestWrite(v *viewer) {
+	ts.share.mu.Lock()
+	mode := ts.share.mode
+	ts.share.mu.Unlock()
+	if mode != ShareHandoff {
+		v.send(TerminalMessage{Type: "error", Data: "the session is shared read-only"})
+		return
+	}
+	ts.send(TerminalMessage{Type: "write_request", Data: map[string]string{"user": v.user}})
+}
+
+// release hands writing back to the owner if the viewer holds it
+func (ts *TerminalSession) release(v *viewer) {
+	ts.share.mu.Lock()
+	released := ts.share.writer == v.user
+	if released {
+		ts.share.writer = ""
+	}
+	ts.share.mu.Unlock()
+	if released {
+		ts.marker(v.user + " released write")
+		ts.broadcastPresence()
+	}
+}
+
+// ownerWrites reports whether the owner's keystrokes reach the terminal
+func (ts *TerminalSession) ownerWrites() bool {
+	ts.share.mu.Lock()
+	defer ts.share.mu.Unlock()
+	return ts.share.writer == ""
+}
+
+// viewerWrites reports whether a viewer's keystrokes reach the terminal
+func (ts *TerminalSession) viewerWrites(v *viewer) bool {
+	ts.share.mu.Lock()
+	defer ts.share.mu.Unlock()
+	return ts.share.mode == ShareHandoff && ts.share.writer == v.user
+}
+
+// hasViewerLocked reports whether a user is connected as a viewer; callers hold share.mu
+func (ts *TerminalSession) hasViewerLocked(user string) bool {
+	for v := range ts.share.viewers {
+		if v.user == user {
+			return true
+		}
+	}
+	return false
+}
+
+// presenceLocked describes the session's participants; callers hold share.mu
+func (ts *TerminalSession) presenceLocked() Presence {
+	writer := ts.share.writer
+	if writer == "" {
+		writer = ts.share.owner
+	}
+	presence := Presence{
+		Mode:         ts.share.mode,
+		Owner:        ts.share.owner,
+		Writer:       writer,
+		Participants: []Participant{{User: ts.share.owner, Role: "owner", CanWrite: ts.share.writer == ""}},
+	}
+	seen := map[string]bool{}
+	for v := range ts.share.viewers {
+		if !seen[v.user] {
+			seen[v.user] = true
+			presence.Participants = append(presence.Participants, Participant{User: v.user, Role: "viewer", CanWrite: ts.share.writer == v.user})
+		}
+	}
+	return presence
+}
+
+// broadcastPresence tells the owner and every viewer who is in the session
+func (ts *TerminalSession) broadcastPresence() {
+	ts.share.mu.Lock()
+	msg := TerminalMessage{Type: "presence", Data: ts.presenceLocked()}
+	ts.share.mu.Unlock()
+
+	ts.send(msg)
+	ts.broadcastToViewers(msg)
+}
+
+// broadcastToViewers sends a message to every viewer; failed viewers drop out on their own
+func (ts *TerminalSession) broadcastToViewers(msg TerminalMessage) {
+	ts.share.mu.Lock()
+	viewers := make([]*viewer, 0, len(ts.share.viewers))
+	for v := range ts.share.viewers {
+		viewers = append(viewers, v)
+	}
+	ts.share.mu.Unlock()
+
+	for _, v := range viewers {
+		v.send(msg)
+	}
+}
+
+// marker records a sharing event in the session recording
+func (ts *TerminalSession) marker(label string) {
+	if ts.recorder != nil {
+		ts.recorder.RecordMarker(label)
+	}
+}
@@ -0,0 +1,146 @@
+package k8s
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeRecorder keeps the markers and participants of a session
+type fakeRecorder struct {
+	mu           sync.Mutex
+	markers      []string
+	participants []string
+}
+
+func (r *fakeRecorder) RecordOutput([]byte)         {}
+func (r *fakeRecorder) RecordInput([]byte)          {}
+func (r *fakeRecorder) RecordResize(uint16, uint16) {}
+func (r *fakeRecorder) SetUser(string)              {}
+func (r *fakeRecorder) Close() error                { return nil }
+func (r *fakeRecorder) RecordMarker(label string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.markers = append(r.markers, label)
+}
+func (r *fakeRecorder) AddParticipant(user string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.participants = append(r.participants, user)
+}
+
+// readUntil reads messages until one of the given type arrives
+func readUntil(t *testing.T, conn *websocket.Conn, msgType string) TerminalMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg TerminalMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for %s: %v", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+func TestSharedSession(t *testing.T) {
+	shares := NewShareRegistry()
+	recorder := &fakeRecorder{}
+	sessions := make(chan *TerminalSession, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := strings.CutPrefix(r.URL.Path, "/join/"); ok {
+			shares.Join(w, r, id, r.URL.Query().Get("token"), JoinOptions{User: r.URL.Query().Get("user")})
+			return
+		}
+		session, err := NewTerminalSession(w, r, nil, nil)
+		if err != nil {
+			return
+		}
+		session.shares = shares
+		session.share.owner = "alice"
+		session.recorder = recorder
+		sessions <- session
+		session.handleWebSocketMessages()
+	}))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	owner, _, err := websocket.DefaultDialer.Dial(wsURL+"/owner", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer owner.Close()
+	session := <-sessions
+
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL+"/join/missing?user=bob", nil); err == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected an unknown session to be refused, got %v", err)
+	}
+
+	owner.WriteJSON(TerminalMessage{Type: "share", Data: map[string]string{"mode": ShareHandoff}})
+	shared := readUntil(t, owner, "shared").Data.(map[string]interface{})
+	id, token := shared["id"].(string), shared["token"].(string)
+
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL+"/join/"+id+"?user=bob&token=wrong", nil); err == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected a wrong token to be refused, got %v", err)
+	}
+	viewerConn, _, err := websocket.DefaultDialer.Dial(wsURL+"/join/"+id+"?user=bob&token="+token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer viewerConn.Close()
+
+	presence := readUntil(t, viewerConn, "presence").Data.(map[string]interface{})
+	if len(presence["participants"].([]interface{})) != 2 || presence["writer"] != "alice" {
+		t.Errorf("unexpected presence %v", presence)
+	}
+
+	session.Write([]byte("$ "))
+	if msg := readUntil(t, viewerConn, "data"); msg.Data != "$ " {
+		t.Errorf("expected the viewer to see the output, got %v", msg.Data)
+	}
+
+	// Read-only until the owner hands writing over
+	viewerConn.WriteJSON(TerminalMessage{Type: "data", Data: "ignored"})
+	viewerConn.WriteJSON(TerminalMessage{Type: "request_write"})
+	if msg := readUntil(t, owner, "write_request"); msg.Data.(map[string]interface{})["user"] != "bob" {
+		t.Errorf("unexpected write request %v", msg.Data)
+	}
+	owner.WriteJSON(TerminalMessage{Type: "handoff", Data: map[string]string{"user": "bob"}})
+	if presence := readUntil(t, viewerConn, "presence").Data.(map[string]interface{}); presence["writer"] != "bob" {
+		t.Errorf("expected bob to hold write, got %v", presence)
+	}
+	owner.WriteJSON(TerminalMessage{Type: "data", Data: "ignored too"})
+	viewerConn.WriteJSON(TerminalMessage{Type: "data", Data: "ls\n"})
+
+	buf := make([]byte, 16)
+	n, err := session.Read(buf)
+	if err != nil || string(buf[:n]) != "ls\n" {
+		t.Errorf("expected only the writer's input to reach the terminal, got %q (%v)", buf[:n], err)
+	}
+
+	owner.WriteJSON(TerminalMessage{Type: "unshare"})
+	if msg := readUntil(t, viewerConn, "closed"); msg.Data == "" {
+		t.Error("expected the viewer to be told why the session closed")
+	}
+	readUntil(t, owner, "unshared")
+	if !session.ownerWrites() {
+		t.Error("expected writing to return to the owner")
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	want := []string{"session shared (handoff)", "bob joined", "write handed to bob", "session no longer shared"}
+	if strings.Join(recorder.markers, "|") != strings.Join(want, "|") {
+		t.Errorf("expected markers %v, got %v", want, recorder.markers)
+	}
+	if len(recorder.participants) != 1 || recorder.participants[0] != "bob" {
+		t.Errorf("unexpected participants %v", recorder.participants)
+	}
+}
@@ -0,0 +1,56 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WorkloadImage is an image run by a container of a Deployment or DaemonSet
+type WorkloadImage struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Container string `json:"container"`
+	Image     string `json:"image"`
+}
+
+// ServerVersion returns the Kubernetes version of the API server, such as v1.30.4+k3s1
+func (c *Client) ServerVersion() (string, error) {
+	info, err := c.clientset.Discovery().ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("failed to get server version: %w", err)
+	}
+	return info.GitVersion, nil
+}
+
+// WorkloadImages lists the container images of the Deployments and DaemonSets in every
+// namespace, which is where cluster addons such as ingress controllers and CNIs run
+func (c *Client) WorkloadImages(ctx context.Context) ([]WorkloadImage, error) {
+	var images []WorkloadImage
+	add := func(kind string, meta metav1.ObjectMeta, spec corev1.PodSpec) {
+		for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+			for _, container := range containers {
+				images = append(images, WorkloadImage{Kind: kind, Namespace: meta.Namespace, Name: meta.Name, Container: container.Name, Image: container.Image})
+			}
+		}
+	}
+
+	deployments, err := c.clientset.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, deployment := range deployments.Items {
+		add("Deployment", deployment.ObjectMeta, deployment.Spec.Template.Spec)
+	}
+	daemonSets, err := c.clientset.AppsV1().DaemonSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %w", err)
+	}
+	for _, daemonSet := range daemonSets.Items {
+		add("DaemonSet", daemonSet.ObjectMeta, daemonSet.Spec.Template.Spec)
+	}
+	return images, nil
+}
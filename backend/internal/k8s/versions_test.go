@@ -0,0 +1,41 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestVersions(t *testing.T) {
+	podSpec := corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "install-cni", Image: "quay.io/cilium/cilium:v1.16.1"}},
+		Containers:     []corev1.Container{{Name: "cilium-agent", Image: "quay.io/cilium/cilium:v1.16.1"}},
+	}
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system"},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "coredns", Image: "registry.k8s.io/coredns/coredns:v1.11.1"}},
+			}}}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "cilium", Namespace: "kube-system"},
+			Spec: appsv1.DaemonSetSpec{Template: corev1.PodTemplateSpec{Spec: podSpec}}},
+	)
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.30.4+k3s1"}
+	client := &Client{clientset: clientset}
+
+	if v, err := client.ServerVersion(); err != nil || v != "v1.30.4+k3s1" {
+		t.Errorf("ServerVersion() = %q, %v", v, err)
+	}
+	images, err := client.WorkloadImages(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 3 || images[0].Kind != "Deployment" || images[1].Container != "install-cni" || images[2].Name != "cilium" {
+		t.Errorf("images = %+v", images)
+	}
+}
@@ -0,0 +1,113 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RestartedAtAnnotation is the pod template annotation kubectl rollout restart sets;
+// changing it rolls every pod of a workload
+const RestartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+func (c *Client) ListStatefulSets(ctx context.Context, namespace string) (*appsv1.StatefulSetList, error) {
+	list, err := cachedList(c.cache, ctx, "statefulsets", namespace, func(ctx context.Context, namespace string) (*appsv1.StatefulSetList, error) {
+		return c.clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list.DeepCopy(), nil
+}
+
+func (c *Client) ListDaemonSets(ctx context.Context, namespace string) (*appsv1.DaemonSetList, error) {
+	list, err := cachedList(c.cache, ctx, "daemonsets", namespace, func(ctx context.Context, namespace string) (*appsv1.DaemonSetList, error) {
+		return c.clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list.DeepCopy(), nil
+}
+
+func (c *Client) GetStatefulSet(ctx context.Context, namespace, name string) (*appsv1.StatefulSet, error) {
+	return c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (c *Client) GetDaemonSet(ctx context.Context, namespace, name string) (*appsv1.DaemonSet, error) {
+	return c.clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// ScaleStatefulSet sets the replicas of a StatefulSet through its scale subresource
+func (c *Client) ScaleStatefulSet(ctx context.Context, namespace, name string, replicas int32) error {
+	statefulSets := c.clientset.AppsV1().StatefulSets(namespace)
+	scale, err := statefulSets.GetScale(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	scale.Spec.Replicas = replicas
+	if _, err := statefulSets.UpdateScale(ctx, name, scale, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to scale statefulset: %w", err)
+	}
+	c.InvalidateCache("statefulsets")
+	return nil
+}
+
+// RestartStatefulSet rolls the pods of a StatefulSet, one at a time in reverse ordinal order
+func (c *Client) RestartStatefulSet(ctx context.Context, namespace, name string) error {
+	statefulSets := c.clientset.AppsV1().StatefulSets(namespace)
+	statefulSet, err := statefulSets.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	setRestartedAt(&statefulSet.Spec.Template.ObjectMeta)
+	if _, err := statefulSets.Update(ctx, statefulSet, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to restart statefulset: %w", err)
+	}
+	c.InvalidateCache("statefulsets")
+	return nil
+}
+
+// RestartDaemonSet rolls the pods of a DaemonSet node by node
+func (c *Client) RestartDaemonSet(ctx context.Context, namespace, name string) error {
+	daemonSets := c.clientset.AppsV1().DaemonSets(namespace)
+	daemonSet, err := daemonSets.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	setRestartedAt(&daemonSet.Spec.Template.ObjectMeta)
+	if _, err := daemonSets.Update(ctx, daemonSet, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to restart daemonset: %w", err)
+	}
+	c.InvalidateCache("daemonsets")
+	return nil
+}
+
+// DeleteStatefulSet deletes a StatefulSet and its pods. PersistentVolumeClaims created from
+// its volumeClaimTemplates are kept, so the data survives recreating the set.
+func (c *Client) DeleteStatefulSet(ctx context.Context, namespace, name string) error {
+	if err := c.clientset.AppsV1().StatefulSets(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return err
+	}
+	c.InvalidateCache("statefulsets")
+	return nil
+}
+
+// DeleteDaemonSet deletes a DaemonSet and its pods
+func (c *Client) DeleteDaemonSet(ctx context.Context, namespace, name string) error {
+	if err := c.clientset.AppsV1().DaemonSets(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return err
+	}
+	c.InvalidateCache("daemonsets")
+	return nil
+}
+
+func setRestartedAt(template *metav1.ObjectMeta) {
+	if template.Annotations == nil {
+		template.Annotations = make(map[string]string)
+	}
+	template.Annotations[RestartedAtAnnotation] = time.Now().Format(time.RFC3339)
+}
@@ -0,0 +1,52 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWorkloadOperations(t *testing.T) {
+	client := &Client{
+		clientset: fake.NewSimpleClientset(
+			&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "default"}},
+			&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "node-exporter", Namespace: "monitoring"}},
+		),
+		cache: newListCache(time.Minute),
+	}
+	ctx := context.Background()
+
+	if err := client.RestartStatefulSet(ctx, "default", "postgres"); err != nil {
+		t.Fatalf("RestartStatefulSet failed: %v", err)
+	}
+	statefulSet, _ := client.GetStatefulSet(ctx, "default", "postgres")
+	if statefulSet.Spec.Template.Annotations[RestartedAtAnnotation] == "" {
+		t.Error("restart did not annotate the pod template")
+	}
+	if err := client.RestartDaemonSet(ctx, "monitoring", "node-exporter"); err != nil {
+		t.Fatalf("RestartDaemonSet failed: %v", err)
+	}
+	daemonSet, _ := client.GetDaemonSet(ctx, "monitoring", "node-exporter")
+	if daemonSet.Spec.Template.Annotations[RestartedAtAnnotation] == "" {
+		t.Error("restart did not annotate the pod template")
+	}
+
+	// Lists are cached until a change invalidates them
+	if list, err := client.ListDaemonSets(ctx, ""); err != nil || len(list.Items) != 1 {
+		t.Fatalf("ListDaemonSets = %v, %v", list, err)
+	}
+	if err := client.DeleteDaemonSet(ctx, "monitoring", "node-exporter"); err != nil {
+		t.Fatalf("DeleteDaemonSet failed: %v", err)
+	}
+	if list, _ := client.ListDaemonSets(ctx, ""); len(list.Items) != 0 {
+		t.Errorf("deleted daemonset still listed: %d items", len(list.Items))
+	}
+
+	if err := client.RestartStatefulSet(ctx, "default", "missing"); err == nil {
+		t.Error("expected restarting a missing statefulset to fail")
+	}
+}
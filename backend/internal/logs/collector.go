@@ -0,0 +1,257 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CollectorConfig selects which pods are tailed and how long their logs are kept
+type CollectorConfig struct {
+	Namespaces []string      // empty means all namespaces
+	Selector   string        // label selector applied to pods
+	Resync     time.Duration // how often the pod list is refreshed
+	Retention  time.Duration // entries older than this are pruned
+	TailLines  int64         // lines read from a container the first time it is seen
+	BatchSize  int
+}
+
+// DefaultCollectorConfig returns the default ingestion settings
+func DefaultCollectorConfig() CollectorConfig {
+	return CollectorConfig{
+		Resync:    30 * time.Second,
+		Retention: 72 * time.Hour,
+		TailLines: 100,
+		BatchSize: 500,
+	}
+}
+
+// Collector follows container logs of the selected pods and writes them to a Store
+type Collector struct {
+	client kubernetes.Interface
+	store  *Store
+	config CollectorConfig
+
+	mutex    sync.Mutex
+	streams  map[string]context.CancelFunc
+	lastSeen map[string]time.Time
+	entries  chan Entry
+}
+
+// NewCollector creates a log collector; Start begins ingestion
+func NewCollector(client kubernetes.Interface, store *Store, config CollectorConfig) *Collector {
+	defaults := DefaultCollectorConfig()
+	if config.Resync <= 0 {
+		config.Resync = defaults.Resync
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaults.Retention
+	}
+	if config.TailLines <= 0 {
+		config.TailLines = defaults.TailLines
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+
+	return &Collector{
+		client:   client,
+		store:    store,
+		config:   config,
+		streams:  make(map[string]context.CancelFunc),
+		lastSeen: make(map[string]time.Time),
+		entries:  make(chan Entry, config.BatchSize*2),
+	}
+}
+
+// Start runs the pod discovery, writer and retention loops until ctx is cancelled
+func (c *Collector) Start(ctx context.Context) {
+	go c.writeLoop(ctx)
+	go c.pruneLoop(ctx)
+	go func() {
+		ticker := time.NewTicker(c.config.Resync)
+		defer ticker.Stop()
+		for {
+			c.syncStreams(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// syncStreams starts a stream for every running container and stops streams of pods that are gone
+func (c *Collector) syncStreams(ctx context.Context) {
+	namespaces := c.config.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	active := make(map[string]bool)
+	for _, namespace := range namespaces {
+		pods, err := c.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: c.config.Selector})
+		if err != nil {
+			slog.Warn("Failed to list pods for log ingestion", "namespace", namespace, "error", err)
+			return // keep existing streams rather than dropping them on a transient error
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.Status.Phase != corev1.PodRunning {
+				continue
+			}
+			for _, container := range pod.Spec.Containers {
+				key := streamKey(pod.Namespace, pod.Name, container.Name)
+				active[key] = true
+				c.startStream(ctx, key, pod.Namespace, pod.Name, container.Name)
+			}
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, cancel := range c.streams {
+		if !active[key] {
+			cancel()
+			delete(c.streams, key)
+		}
+	}
+	for key := range c.lastSeen {
+		if !active[key] {
+			delete(c.lastSeen, key)
+		}
+	}
+}
+
+func (c *Collector) startStream(ctx context.Context, key, namespace, pod, container string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.streams[key]; ok {
+		return
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	c.streams[key] = cancel
+	go func() {
+		defer func() {
+			// The stream ends when the container restarts; the next resync resumes it
+			c.mutex.Lock()
+			delete(c.streams, key)
+			c.mutex.Unlock()
+			cancel()
+		}()
+		if err := c.tail(streamCtx, key, namespace, pod, container); err != nil && streamCtx.Err() == nil {
+			slog.Debug("Log stream ended", "namespace", namespace, "pod", pod, "container", container, "error", err)
+		}
+	}()
+}
+
+// tail follows a container's log, resuming after the last ingested timestamp
+func (c *Collector) tail(ctx context.Context, key, namespace, pod, container string) error {
+	opts := &corev1.PodLogOptions{
+		Container:  container,
+		Follow:     true,
+		Timestamps: true,
+	}
+
+	c.mutex.Lock()
+	lastSeen, resumed := c.lastSeen[key]
+	c.mutex.Unlock()
+	if resumed {
+		since := metav1.NewTime(lastSeen)
+		opts.SinceTime = &since
+	} else {
+		tailLines := c.config.TailLines
+		opts.TailLines = &tailLines
+	}
+
+	stream, err := c.client.CoreV1().Pods(namespace).GetLogs(pod, opts).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry := ParseLine(scanner.Text())
+		// SinceTime has second precision, so skip lines already ingested
+		if resumed && !entry.Timestamp.After(lastSeen) {
+			continue
+		}
+		entry.Namespace = namespace
+		entry.Pod = pod
+		entry.Container = container
+
+		c.mutex.Lock()
+		c.lastSeen[key] = entry.Timestamp
+		c.mutex.Unlock()
+
+		select {
+		case c.entries <- entry:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return scanner.Err()
+}
+
+// writeLoop batches entries and writes them once a second or when the batch is full
+func (c *Collector) writeLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, c.config.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := c.store.Insert(context.Background(), batch); err != nil {
+			slog.Error("Failed to store log entries", "count", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			flush()
+			return
+		case entry := <-c.entries:
+			batch = append(batch, entry)
+			if len(batch) >= c.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (c *Collector) pruneLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if removed, err := c.store.Prune(ctx, time.Now().Add(-c.config.Retention)); err != nil {
+			slog.Error("Failed to prune log entries", "error", err)
+		} else if removed > 0 {
+			slog.Info("Pruned log entries", "removed", removed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func streamKey(namespace, pod, container string) string {
+	return namespace + "/" + pod + "/" + container
+}
@@ -0,0 +1,189 @@
+// Package logs ingests container logs from Kubernetes, indexes them in SQLite and serves search and analytics.
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Format identifies how a log line was structured
+type Format string
+
+const (
+	FormatJSON  Format = "json"
+	FormatKlog  Format = "klog"
+	FormatPlain Format = "plain"
+)
+
+// Levels recognised by the parser, matching the frontend log levels
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+// Entry is a single parsed log line
+type Entry struct {
+	ID        int64                  `json:"id"`
+	Timestamp time.Time              `json:"timestamp"`
+	Namespace string                 `json:"namespace"`
+	Pod       string                 `json:"pod"`
+	Container string                 `json:"container"`
+	Level     string                 `json:"level"`
+	Format    Format                 `json:"format"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+var (
+	// klogPattern matches the klog header: Lmmdd hh:mm:ss.uuuuuu threadid file:line] msg
+	klogPattern = regexp.MustCompile(`^([IWEF])(\d{4} \d{2}:\d{2}:\d{2}\.\d{6})\s+\d+\s+[^\]]+\]\s?(.*)$`)
+	// plainLevelPattern finds a level keyword near the start of an unstructured line
+	plainLevelPattern = regexp.MustCompile(`(?i)\b(trace|debug|info|warn|warning|error|err|fatal|panic|critical)\b`)
+
+	jsonMessageKeys   = []string{"msg", "message", "log"}
+	jsonLevelKeys     = []string{"level", "lvl", "severity", "log.level"}
+	jsonTimestampKeys = []string{"time", "ts", "timestamp", "@timestamp"}
+)
+
+// ParseLine parses a log line as returned by the Kubernetes API with timestamps enabled.
+// The RFC3339 prefix added by the kubelet is used as the timestamp unless a JSON line carries its own.
+func ParseLine(line string) Entry {
+	var entry Entry
+
+	if ts, rest, ok := strings.Cut(line, " "); ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			entry.Timestamp = parsed.UTC()
+			line = rest
+		}
+	}
+	line = strings.TrimRight(line, "\r\n")
+
+	switch {
+	case parseJSON(line, &entry):
+	case parseKlog(line, &entry):
+	default:
+		entry.Format = FormatPlain
+		entry.Message = line
+		entry.Level = LevelInfo
+		if match := plainLevelPattern.FindString(prefix(line, 64)); match != "" {
+			entry.Level = NormalizeLevel(match)
+		}
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+	return entry
+}
+
+func parseJSON(line string, entry *Entry) bool {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") {
+		return false
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(trimmed), &fields); err != nil {
+		return false
+	}
+
+	entry.Format = FormatJSON
+	entry.Level = LevelInfo
+	if key, value := firstString(fields, jsonMessageKeys); key != "" {
+		entry.Message = value
+		delete(fields, key)
+	} else {
+		entry.Message = trimmed
+	}
+	if key, value := firstString(fields, jsonLevelKeys); key != "" {
+		entry.Level = NormalizeLevel(value)
+		delete(fields, key)
+	}
+	for _, key := range jsonTimestampKeys {
+		if value, ok := fields[key]; ok {
+			if ts, ok := parseJSONTime(value); ok {
+				entry.Timestamp = ts
+				delete(fields, key)
+			}
+			break
+		}
+	}
+	if len(fields) > 0 {
+		entry.Fields = fields
+	}
+	return true
+}
+
+func parseKlog(line string, entry *Entry) bool {
+	match := klogPattern.FindStringSubmatch(line)
+	if match == nil {
+		return false
+	}
+
+	entry.Format = FormatKlog
+	entry.Message = match[3]
+	switch match[1] {
+	case "W":
+		entry.Level = LevelWarn
+	case "E", "F":
+		entry.Level = LevelError
+	default:
+		entry.Level = LevelInfo
+	}
+
+	// klog omits the year; keep the kubelet timestamp unless it is missing
+	if ts, err := time.Parse("0102 15:04:05.000000", match[2]); err == nil && entry.Timestamp.IsZero() {
+		entry.Timestamp = ts.AddDate(time.Now().Year(), 0, 0).UTC()
+	}
+	return true
+}
+
+// NormalizeLevel maps level names and abbreviations onto debug, info, warn and error
+func NormalizeLevel(level string) string {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "trace", "debug", "dbg", "d":
+		return LevelDebug
+	case "warn", "warning", "wrn", "w":
+		return LevelWarn
+	case "error", "err", "fatal", "panic", "critical", "crit", "alert", "emergency", "e", "f":
+		return LevelError
+	default:
+		return LevelInfo
+	}
+}
+
+func firstString(fields map[string]interface{}, keys []string) (string, string) {
+	for _, key := range keys {
+		if value, ok := fields[key]; ok {
+			if s, ok := value.(string); ok {
+				return key, s
+			}
+			return key, fmt.Sprint(value)
+		}
+	}
+	return "", ""
+}
+
+// parseJSONTime accepts RFC3339 strings and unix timestamps in seconds
+func parseJSONTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		if ts, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return ts.UTC(), true
+		}
+	case float64:
+		sec := int64(v)
+		return time.Unix(sec, int64((v-float64(sec))*1e9)).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+func prefix(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
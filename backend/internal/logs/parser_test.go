@@ -0,0 +1,85 @@
+package logs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		format  Format
+		level   string
+		message string
+		ts      string
+	}{
+		{
+			name:    "json with own timestamp",
+			line:    `2025-03-01T10:00:00.5Z {"level":"WARN","msg":"disk almost full","time":"2025-03-01T09:59:59Z","disk":"/data"}`,
+			format:  FormatJSON,
+			level:   LevelWarn,
+			message: "disk almost full",
+			ts:      "2025-03-01T09:59:59Z",
+		},
+		{
+			name:    "klog error",
+			line:    `2025-03-01T10:00:00Z E0301 10:00:00.123456       1 controller.go:42] failed to sync pod`,
+			format:  FormatKlog,
+			level:   LevelError,
+			message: "failed to sync pod",
+			ts:      "2025-03-01T10:00:00Z",
+		},
+		{
+			name:    "plain with level keyword",
+			line:    `2025-03-01T10:00:00Z [DEBUG] cache warmed`,
+			format:  FormatPlain,
+			level:   LevelDebug,
+			message: "[DEBUG] cache warmed",
+			ts:      "2025-03-01T10:00:00Z",
+		},
+		{
+			name:    "plain defaults to info",
+			line:    `2025-03-01T10:00:00Z GET /healthz 200`,
+			format:  FormatPlain,
+			level:   LevelInfo,
+			message: "GET /healthz 200",
+			ts:      "2025-03-01T10:00:00Z",
+		},
+		{
+			name:    "invalid json is plain",
+			line:    `2025-03-01T10:00:00Z {not json error`,
+			format:  FormatPlain,
+			level:   LevelError,
+			message: "{not json error",
+			ts:      "2025-03-01T10:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := ParseLine(tt.line)
+			if entry.Format != tt.format || entry.Level != tt.level || entry.Message != tt.message {
+				t.Errorf("got format=%s level=%s message=%q", entry.Format, entry.Level, entry.Message)
+			}
+			want, _ := time.Parse(time.RFC3339, tt.ts)
+			if !entry.Timestamp.Equal(want) {
+				t.Errorf("timestamp = %s, want %s", entry.Timestamp, want)
+			}
+		})
+	}
+}
+
+func TestParseLineJSONFields(t *testing.T) {
+	entry := ParseLine(`{"severity":"error","message":"query failed","ts":1700000000.25,"table":"users"}`)
+
+	if entry.Level != LevelError || entry.Message != "query failed" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if entry.Timestamp.UnixMilli() != 1700000000250 {
+		t.Errorf("expected unix timestamp to be parsed, got %s", entry.Timestamp)
+	}
+	if len(entry.Fields) != 1 || entry.Fields["table"] != "users" {
+		t.Errorf("expected only remaining fields to be kept, got %v", entry.Fields)
+	}
+}
@@ -0,0 +1,336 @@
+package logs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Query filters log searches and aggregations. Zero values match everything.
+type Query struct {
+	Namespace string
+	Pod       string
+	Container string
+	Levels    []string
+	Since     time.Time
+	Until     time.Time
+	Text      string
+	Limit     int
+	Offset    int
+}
+
+// Bucket is one interval of a log histogram
+type Bucket struct {
+	Start  time.Time      `json:"timestamp"`
+	Total  int            `json:"total"`
+	Levels map[string]int `json:"levels"`
+}
+
+// SourceCount is the number of log lines and errors produced by one pod
+type SourceCount struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Total     int    `json:"total"`
+	Errors    int    `json:"errors"`
+}
+
+// Store persists log entries in SQLite with an FTS5 index over messages.
+// When SQLite is built without FTS5 (the sqlite_fts5 build tag), text search falls back to LIKE.
+type Store struct {
+	db  *sql.DB
+	fts bool
+}
+
+// NewStore creates a log store and its tables
+func NewStore(db *sql.DB) (*Store, error) {
+	store := &Store{db: db}
+	if err := store.initDB(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// initDB creates the log entry table and, when available, its full-text index
+func (s *Store) initDB() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS log_entries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp INTEGER NOT NULL, -- unix milliseconds
+			namespace TEXT NOT NULL,
+			pod TEXT NOT NULL,
+			container TEXT NOT NULL,
+			level TEXT NOT NULL,
+			format TEXT NOT NULL,
+			message TEXT NOT NULL,
+			fields TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_log_entries_timestamp ON log_entries(timestamp)`,
+		`CREATE INDEX IF NOT EXISTS idx_log_entries_source ON log_entries(namespace, pod, timestamp)`,
+		`CREATE INDEX IF NOT EXISTS idx_log_entries_level ON log_entries(level, timestamp)`,
+	}
+
+	for _, query := range queries {
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+
+	ftsQueries := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS log_entries_fts USING fts5(message, content='log_entries', content_rowid='id')`,
+		`CREATE TRIGGER IF NOT EXISTS log_entries_ai AFTER INSERT ON log_entries BEGIN
+			INSERT INTO log_entries_fts(rowid, message) VALUES (new.id, new.message);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS log_entries_ad AFTER DELETE ON log_entries BEGIN
+			INSERT INTO log_entries_fts(log_entries_fts, rowid, message) VALUES ('delete', old.id, old.message);
+		END`,
+	}
+	for _, query := range ftsQueries {
+		if _, err := s.db.Exec(query); err != nil {
+			if strings.Contains(err.Error(), "no such module: fts5") {
+				slog.Warn("SQLite built without FTS5, log text search will use LIKE")
+				return nil
+			}
+			return fmt.Errorf("failed to create full-text index: %w", err)
+		}
+	}
+	s.fts = true
+	return nil
+}
+
+// Insert stores a batch of entries in a single transaction
+func (s *Store) Insert(ctx context.Context, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO log_entries (timestamp, namespace, pod, container, level, format, message, fields)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, entry := range entries {
+		var fields sql.NullString
+		if len(entry.Fields) > 0 {
+			if data, err := json.Marshal(entry.Fields); err == nil {
+				fields = sql.NullString{String: string(data), Valid: true}
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, entry.Timestamp.UnixMilli(), entry.Namespace, entry.Pod, entry.Container,
+			entry.Level, string(entry.Format), entry.Message, fields); err != nil {
+			return fmt.Errorf("failed to insert log entry: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Search returns entries matching the query, newest first, and the total number of matches
+func (s *Store) Search(ctx context.Context, q Query) ([]Entry, int, error) {
+	where, args := s.where(q)
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM log_entries e`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count log entries: %w", err)
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.id, e.timestamp, e.namespace, e.pod, e.container, e.level, e.format, e.message, e.fields
+		FROM log_entries e`+where+`
+		ORDER BY e.timestamp DESC, e.id DESC
+		LIMIT ? OFFSET ?`, append(args, limit, q.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search log entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var entry Entry
+		var timestamp int64
+		var format string
+		var fields sql.NullString
+		if err := rows.Scan(&entry.ID, &timestamp, &entry.Namespace, &entry.Pod, &entry.Container,
+			&entry.Level, &format, &entry.Message, &fields); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan log entry: %w", err)
+		}
+		entry.Timestamp = time.UnixMilli(timestamp).UTC()
+		entry.Format = Format(format)
+		if fields.Valid {
+			json.Unmarshal([]byte(fields.String), &entry.Fields)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, rows.Err()
+}
+
+// Histogram counts matching entries per level in fixed intervals, oldest first
+func (s *Store) Histogram(ctx context.Context, q Query, interval time.Duration) ([]Bucket, error) {
+	if interval < time.Second {
+		interval = time.Second
+	}
+	step := interval.Milliseconds()
+	where, args := s.where(q)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT (e.timestamp / ?) * ? AS bucket, e.level, COUNT(*)
+		FROM log_entries e`+where+`
+		GROUP BY bucket, e.level
+		ORDER BY bucket`, append([]interface{}{step, step}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate log entries: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := []Bucket{}
+	for rows.Next() {
+		var start int64
+		var level string
+		var count int
+		if err := rows.Scan(&start, &level, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan histogram: %w", err)
+		}
+		if n := len(buckets); n == 0 || buckets[n-1].Start.UnixMilli() != start {
+			buckets = append(buckets, Bucket{Start: time.UnixMilli(start).UTC(), Levels: map[string]int{}})
+		}
+		bucket := &buckets[len(buckets)-1]
+		bucket.Levels[level] += count
+		bucket.Total += count
+	}
+	return buckets, rows.Err()
+}
+
+// LevelCounts returns the number of matching entries per level
+func (s *Store) LevelCounts(ctx context.Context, q Query) (map[string]int, error) {
+	where, args := s.where(q)
+
+	rows, err := s.db.QueryContext(ctx, `SELECT e.level, COUNT(*) FROM log_entries e`+where+` GROUP BY e.level`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count log levels: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var level string
+		var count int
+		if err := rows.Scan(&level, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan level count: %w", err)
+		}
+		counts[level] = count
+	}
+	return counts, rows.Err()
+}
+
+// TopSources returns the pods producing the most matching entries
+func (s *Store) TopSources(ctx context.Context, q Query, limit int) ([]SourceCount, error) {
+	where, args := s.where(q)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.namespace, e.pod, COUNT(*) AS total, SUM(CASE WHEN e.level = ? THEN 1 ELSE 0 END)
+		FROM log_entries e`+where+`
+		GROUP BY e.namespace, e.pod
+		ORDER BY total DESC
+		LIMIT ?`, append(append([]interface{}{LevelError}, args...), limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate log sources: %w", err)
+	}
+	defer rows.Close()
+
+	sources := []SourceCount{}
+	for rows.Next() {
+		var source SourceCount
+		if err := rows.Scan(&source.Namespace, &source.Pod, &source.Total, &source.Errors); err != nil {
+			return nil, fmt.Errorf("failed to scan log source: %w", err)
+		}
+		sources = append(sources, source)
+	}
+	return sources, rows.Err()
+}
+
+// Prune deletes entries older than the given time and returns how many were removed
+func (s *Store) Prune(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM log_entries WHERE timestamp < ?`, before.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune log entries: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// where builds the WHERE clause for a query against log_entries aliased as e
+func (s *Store) where(q Query) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if q.Namespace != "" {
+		conditions = append(conditions, "e.namespace = ?")
+		args = append(args, q.Namespace)
+	}
+	if q.Pod != "" {
+		conditions = append(conditions, "e.pod = ?")
+		args = append(args, q.Pod)
+	}
+	if q.Container != "" {
+		conditions = append(conditions, "e.container = ?")
+		args = append(args, q.Container)
+	}
+	if len(q.Levels) > 0 {
+		placeholders := make([]string, len(q.Levels))
+		for i, level := range q.Levels {
+			placeholders[i] = "?"
+			args = append(args, NormalizeLevel(level))
+		}
+		conditions = append(conditions, "e.level IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if !q.Since.IsZero() {
+		conditions = append(conditions, "e.timestamp >= ?")
+		args = append(args, q.Since.UnixMilli())
+	}
+	if !q.Until.IsZero() {
+		conditions = append(conditions, "e.timestamp <= ?")
+		args = append(args, q.Until.UnixMilli())
+	}
+	if terms := strings.Fields(q.Text); len(terms) > 0 {
+		if s.fts {
+			conditions = append(conditions, "e.id IN (SELECT rowid FROM log_entries_fts WHERE log_entries_fts MATCH ?)")
+			args = append(args, ftsQuery(terms))
+		} else {
+			for _, term := range terms {
+				conditions = append(conditions, `e.message LIKE ? ESCAPE '\'`)
+				args = append(args, "%"+likeEscaper.Replace(term)+"%")
+			}
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ftsQuery quotes each term so user input is matched literally rather than parsed as FTS5 syntax
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,143 @@
+package logs
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	return store
+}
+
+func seedEntries(t *testing.T, store *Store, base time.Time) {
+	t.Helper()
+
+	entries := []Entry{
+		{Timestamp: base, Namespace: "apps", Pod: "web-1", Container: "web", Level: LevelInfo, Format: FormatPlain, Message: "GET /api/users 200"},
+		{Timestamp: base.Add(30 * time.Second), Namespace: "apps", Pod: "web-1", Container: "web", Level: LevelError, Format: FormatJSON, Message: "database connection refused", Fields: map[string]interface{}{"retry": true}},
+		{Timestamp: base.Add(90 * time.Second), Namespace: "apps", Pod: "worker-1", Container: "worker", Level: LevelWarn, Format: FormatKlog, Message: "queue backlog growing"},
+		{Timestamp: base.Add(2 * time.Hour), Namespace: "system", Pod: "dns-1", Container: "dns", Level: LevelInfo, Format: FormatPlain, Message: "100% of queries answered"},
+	}
+	if err := store.Insert(context.Background(), entries); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	store := setupTestStore(t)
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	seedEntries(t, store, base)
+	ctx := context.Background()
+
+	entries, total, err := store.Search(ctx, Query{Namespace: "apps"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if total != 3 || len(entries) != 3 {
+		t.Fatalf("expected 3 entries in apps, got %d (total %d)", len(entries), total)
+	}
+	if entries[0].Pod != "worker-1" {
+		t.Errorf("expected newest entry first, got %s", entries[0].Pod)
+	}
+
+	entries, total, err = store.Search(ctx, Query{Text: "connection database"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if total != 1 || entries[0].Level != LevelError || entries[0].Fields["retry"] != true {
+		t.Errorf("expected the database error with fields, got %+v", entries)
+	}
+
+	// Characters special to FTS5 and LIKE are matched literally
+	if _, total, err = store.Search(ctx, Query{Text: `100%`}); err != nil || total != 1 {
+		t.Errorf("expected literal match for 100%%, got total %d err %v", total, err)
+	}
+	if _, total, err = store.Search(ctx, Query{Text: `"unbalanced`}); err != nil || total != 0 {
+		t.Errorf("expected no match for quoted input, got total %d err %v", total, err)
+	}
+
+	_, total, err = store.Search(ctx, Query{Levels: []string{"warning", "error"}, Since: base.Add(time.Minute), Until: base.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if total != 1 {
+		t.Errorf("expected level and time filters to match 1 entry, got %d", total)
+	}
+
+	entries, total, err = store.Search(ctx, Query{Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if total != 4 || len(entries) != 1 || entries[0].Pod != "worker-1" {
+		t.Errorf("expected second page to hold worker-1, got %+v (total %d)", entries, total)
+	}
+}
+
+func TestAggregations(t *testing.T) {
+	store := setupTestStore(t)
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	seedEntries(t, store, base)
+	ctx := context.Background()
+
+	buckets, err := store.Histogram(ctx, Query{Namespace: "apps"}, time.Minute)
+	if err != nil {
+		t.Fatalf("Histogram failed: %v", err)
+	}
+	if len(buckets) != 2 {
+		t.Fatalf("expected 2 one-minute buckets, got %d", len(buckets))
+	}
+	if !buckets[0].Start.Equal(base) || buckets[0].Total != 2 || buckets[0].Levels[LevelError] != 1 {
+		t.Errorf("unexpected first bucket: %+v", buckets[0])
+	}
+
+	counts, err := store.LevelCounts(ctx, Query{})
+	if err != nil {
+		t.Fatalf("LevelCounts failed: %v", err)
+	}
+	if counts[LevelInfo] != 2 || counts[LevelError] != 1 || counts[LevelWarn] != 1 {
+		t.Errorf("unexpected level counts: %v", counts)
+	}
+
+	sources, err := store.TopSources(ctx, Query{}, 1)
+	if err != nil {
+		t.Fatalf("TopSources failed: %v", err)
+	}
+	if len(sources) != 1 || sources[0].Pod != "web-1" || sources[0].Total != 2 || sources[0].Errors != 1 {
+		t.Errorf("unexpected top sources: %+v", sources)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	store := setupTestStore(t)
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	seedEntries(t, store, base)
+	ctx := context.Background()
+
+	removed, err := store.Prune(ctx, base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("expected 3 entries pruned, got %d", removed)
+	}
+	if _, total, _ := store.Search(ctx, Query{Text: "database"}); total != 0 {
+		t.Errorf("expected pruned entries to leave the text index, got %d", total)
+	}
+}
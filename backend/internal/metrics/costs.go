@@ -0,0 +1,307 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Default prices, close to on-demand list prices of general purpose cloud instances
+const (
+	DefaultCPUHourPrice      = 0.031611 // per vCPU-hour
+	DefaultMemoryGBHourPrice = 0.004237 // per GiB-hour
+	DefaultCostCurrency      = "USD"
+)
+
+// hoursPerMonth is the average month, 365 * 24 / 12
+const hoursPerMonth = 730
+
+// CostUsageHistory is the usage basis of estimates averaged over sampled usage history;
+// otherwise estimates use the current usage and name its UsageSource
+const CostUsageHistory = "history"
+
+// CostRates are the prices resources are charged at
+type CostRates struct {
+	CPUHour      float64 `json:"cpu_hour"`       // per vCPU-hour
+	MemoryGBHour float64 `json:"memory_gb_hour"` // per GiB-hour
+	Currency     string  `json:"currency"`
+}
+
+// DefaultCostRates returns the default prices in USD
+func DefaultCostRates() CostRates {
+	return CostRates{
+		CPUHour:      DefaultCPUHourPrice,
+		MemoryGBHour: DefaultMemoryGBHourPrice,
+		Currency:     DefaultCostCurrency,
+	}
+}
+
+// Monthly returns what running the given CPU (millicores) and memory (bytes) costs for a month
+func (r CostRates) Monthly(cpuMillicores, memoryBytes int64) float64 {
+	hourly := float64(cpuMillicores)/1000*r.CPUHour + float64(memoryBytes)/(1<<30)*r.MemoryGBHour
+	return roundCents(hourly * hoursPerMonth)
+}
+
+// ResourceCost is an amount of CPU and memory and its monthly cost
+type ResourceCost struct {
+	CPUMillicores int64   `json:"cpu_millicores"`
+	MemoryBytes   int64   `json:"memory_bytes"`
+	Monthly       float64 `json:"monthly"`
+}
+
+// WorkloadCost estimates the monthly cost of a workload's current pods. Each resource is
+// charged at the larger of its requests and its usage, as requests are reserved on the
+// nodes whether used or not.
+type WorkloadCost struct {
+	Namespace    string       `json:"namespace"`
+	WorkloadKind string       `json:"workload_kind"`
+	Workload     string       `json:"workload"`
+	Pods         int          `json:"pods"`
+	Requests     ResourceCost `json:"requests"`
+	Usage        ResourceCost `json:"usage"`
+	Monthly      float64      `json:"monthly"`
+}
+
+// NamespaceCost adds up the workload costs of a namespace
+type NamespaceCost struct {
+	Namespace string  `json:"namespace"`
+	Workloads int     `json:"workloads"`
+	Pods      int     `json:"pods"`
+	Requests  float64 `json:"requests_monthly"`
+	Usage     float64 `json:"usage_monthly"`
+	Monthly   float64 `json:"monthly"`
+}
+
+// CostReport estimates monthly costs per workload and namespace
+type CostReport struct {
+	Rates CostRates `json:"rates"`
+	// UsageBasis is CostUsageHistory or the source of the current usage
+	UsageBasis string          `json:"usage_basis"`
+	Window     string          `json:"window,omitempty"` // history averaged, for the history basis
+	Workloads  []WorkloadCost  `json:"workloads"`
+	Namespaces []NamespaceCost `json:"namespaces"`
+	// Cluster is the allocatable capacity of the nodes, and Idle the part of its cost no
+	// workload accounts for. Both are left out for a single namespace.
+	Cluster     *ResourceCost `json:"cluster,omitempty"`
+	Idle        *float64      `json:"idle_monthly,omitempty"`
+	Monthly     float64       `json:"monthly"`
+	GeneratedAt time.Time     `json:"generated_at"`
+}
+
+// SetCostRates sets the prices cost estimates are based on
+func (s *Service) SetCostRates(rates CostRates) {
+	s.costRates = rates
+}
+
+// GetCosts estimates monthly costs from the requests of the running pods and their usage,
+// averaged over the sampled history of the window when usage sampling is enabled and
+// read from the current usage otherwise
+func (s *Service) GetCosts(ctx context.Context, namespace string, window time.Duration) (*CostReport, error) {
+	if s.k8sClient == nil {
+		return nil, errors.New("kubernetes client not available")
+	}
+	pods, err := s.k8sClient.ListPods(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	nodes, err := s.k8sClient.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var samples []UsageSample
+	basis := ""
+	if s.usageStore != nil {
+		samples, err = s.usageStore.Samples(ctx, namespace, time.Now().Add(-window))
+		if err != nil {
+			slog.Warn("Usage history unavailable for cost estimates", "error", err)
+		}
+		if len(samples) > 0 {
+			basis = CostUsageHistory
+		}
+	}
+	if basis == "" {
+		usage := s.currentUsage(ctx, nodes.Items)
+		samples = currentSamples(pods.Items, usage)
+		basis = string(usage.source)
+	}
+
+	report := EstimateCosts(pods.Items, samples, s.costRates)
+	report.UsageBasis = basis
+	if basis == CostUsageHistory {
+		report.Window = window.String()
+	}
+	if namespace == "" {
+		var cpu, memory int64
+		for _, node := range nodes.Items {
+			cpu += node.Status.Allocatable.Cpu().MilliValue()
+			memory += node.Status.Allocatable.Memory().Value()
+		}
+		cluster := ResourceCost{CPUMillicores: cpu, MemoryBytes: memory, Monthly: s.costRates.Monthly(cpu, memory)}
+		idle := roundCents(max(cluster.Monthly-report.Monthly, 0))
+		report.Cluster, report.Idle = &cluster, &idle
+	}
+	return report, nil
+}
+
+// currentSamples turns the current usage of each pod into one sample per pod
+func currentSamples(pods []corev1.Pod, usage *usageSnapshot) []UsageSample {
+	samples := make([]UsageSample, 0, len(pods))
+	for i := range pods {
+		pod := &pods[i]
+		used, ok := usage.pods[pod.Namespace+"/"+pod.Name]
+		if !ok {
+			continue
+		}
+		kind, workload := podWorkload(pod)
+		samples = append(samples, UsageSample{
+			Namespace:     pod.Namespace,
+			WorkloadKind:  kind,
+			Workload:      workload,
+			Pod:           pod.Name,
+			CPUMillicores: used.CPUMillicores,
+			MemoryBytes:   used.MemoryBytes,
+			Timestamp:     usage.readAt,
+		})
+	}
+	return samples
+}
+
+// EstimateCosts prices the running and pending pods of each workload. A workload's usage
+// is the average usage of each of its containers per pod, times its current pods, so pods
+// replaced during the sampled history still count towards it.
+func EstimateCosts(pods []corev1.Pod, samples []UsageSample, rates CostRates) *CostReport {
+	type workloadKey struct {
+		namespace, kind, workload string
+	}
+	type average struct {
+		cpu, memory, count int64
+	}
+	averages := map[containerKey]*average{}
+	for _, sample := range samples {
+		key := containerKey{sample.Namespace, sample.WorkloadKind, sample.Workload, sample.Container}
+		avg, ok := averages[key]
+		if !ok {
+			avg = &average{}
+			averages[key] = avg
+		}
+		avg.cpu += sample.CPUMillicores
+		avg.memory += sample.MemoryBytes
+		avg.count++
+	}
+	// Per-pod usage of each workload
+	perPod := map[workloadKey]*ResourceCost{}
+	for key, avg := range averages {
+		wk := workloadKey{key.namespace, key.kind, key.workload}
+		usage, ok := perPod[wk]
+		if !ok {
+			usage = &ResourceCost{}
+			perPod[wk] = usage
+		}
+		usage.CPUMillicores += avg.cpu / avg.count
+		usage.MemoryBytes += avg.memory / avg.count
+	}
+
+	workloads := map[workloadKey]*WorkloadCost{}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		kind, name := podWorkload(pod)
+		key := workloadKey{pod.Namespace, kind, name}
+		workload, ok := workloads[key]
+		if !ok {
+			workload = &WorkloadCost{Namespace: pod.Namespace, WorkloadKind: kind, Workload: name}
+			workloads[key] = workload
+		}
+		workload.Pods++
+		cpu, memory := podRequests(*pod)
+		workload.Requests.CPUMillicores += cpu
+		workload.Requests.MemoryBytes += memory
+	}
+
+	report := &CostReport{
+		Rates:       rates,
+		Workloads:   make([]WorkloadCost, 0, len(workloads)),
+		Namespaces:  []NamespaceCost{},
+		GeneratedAt: time.Now(),
+	}
+	namespaces := map[string]*NamespaceCost{}
+	for key, workload := range workloads {
+		if usage, ok := perPod[key]; ok {
+			workload.Usage.CPUMillicores = usage.CPUMillicores * int64(workload.Pods)
+			workload.Usage.MemoryBytes = usage.MemoryBytes * int64(workload.Pods)
+		}
+		workload.Requests.Monthly = rates.Monthly(workload.Requests.CPUMillicores, workload.Requests.MemoryBytes)
+		workload.Usage.Monthly = rates.Monthly(workload.Usage.CPUMillicores, workload.Usage.MemoryBytes)
+		workload.Monthly = rates.Monthly(
+			max(workload.Requests.CPUMillicores, workload.Usage.CPUMillicores),
+			max(workload.Requests.MemoryBytes, workload.Usage.MemoryBytes),
+		)
+		report.Workloads = append(report.Workloads, *workload)
+
+		namespace, ok := namespaces[workload.Namespace]
+		if !ok {
+			namespace = &NamespaceCost{Namespace: workload.Namespace}
+			namespaces[workload.Namespace] = namespace
+		}
+		namespace.Workloads++
+		namespace.Pods += workload.Pods
+		namespace.Requests += workload.Requests.Monthly
+		namespace.Usage += workload.Usage.Monthly
+		namespace.Monthly += workload.Monthly
+	}
+
+	for _, namespace := range namespaces {
+		namespace.Requests = roundCents(namespace.Requests)
+		namespace.Usage = roundCents(namespace.Usage)
+		namespace.Monthly = roundCents(namespace.Monthly)
+		report.Namespaces = append(report.Namespaces, *namespace)
+		report.Monthly += namespace.Monthly
+	}
+	report.Monthly = roundCents(report.Monthly)
+
+	// Most expensive first
+	sort.Slice(report.Workloads, func(i, j int) bool {
+		a, b := report.Workloads[i], report.Workloads[j]
+		if a.Monthly != b.Monthly {
+			return a.Monthly > b.Monthly
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Workload < b.Workload
+	})
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		a, b := report.Namespaces[i], report.Namespaces[j]
+		if a.Monthly != b.Monthly {
+			return a.Monthly > b.Monthly
+		}
+		return a.Namespace < b.Namespace
+	})
+	return report
+}
+
+// podRequests sums the CPU (millicores) and memory requests of a pod's containers
+func podRequests(pod corev1.Pod) (cpu, memory int64) {
+	for _, container := range pod.Spec.Containers {
+		if request, ok := container.Resources.Requests[corev1.ResourceCPU]; ok {
+			cpu += request.MilliValue()
+		}
+		if request, ok := container.Resources.Requests[corev1.ResourceMemory]; ok {
+			memory += request.Value()
+		}
+	}
+	return cpu, memory
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
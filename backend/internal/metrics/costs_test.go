@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func costTestPod(namespace, name, owner string, phase corev1.PodPhase, cpu, memory string) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app",
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			}},
+		}}},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+	if owner != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: "StatefulSet", Name: owner, Controller: &controller}}
+	}
+	return pod
+}
+
+func TestEstimateCosts(t *testing.T) {
+	rates := CostRates{CPUHour: 0.1, MemoryGBHour: 0.01, Currency: "USD"}
+	pods := []corev1.Pod{
+		costTestPod("apps", "api-0", "api", corev1.PodRunning, "500m", "1Gi"),
+		costTestPod("apps", "api-1", "api", corev1.PodRunning, "500m", "1Gi"),
+		costTestPod("apps", "migrate", "", corev1.PodSucceeded, "4", "8Gi"),
+		costTestPod("tools", "debug", "", corev1.PodRunning, "250m", "0"),
+	}
+	// The usage of a replaced pod still counts towards the per-pod average
+	samples := []UsageSample{
+		{Namespace: "apps", WorkloadKind: "StatefulSet", Workload: "api", Pod: "api-0", Container: "app", CPUMillicores: 600, MemoryBytes: 256 << 20},
+		{Namespace: "apps", WorkloadKind: "StatefulSet", Workload: "api", Pod: "api-old", Container: "app", CPUMillicores: 1000, MemoryBytes: 768 << 20},
+	}
+
+	report := EstimateCosts(pods, samples, rates)
+	if len(report.Workloads) != 2 || len(report.Namespaces) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	api := report.Workloads[0]
+	if api.Workload != "api" || api.Pods != 2 {
+		t.Fatalf("unexpected most expensive workload %+v", api)
+	}
+	if api.Requests != (ResourceCost{CPUMillicores: 1000, MemoryBytes: 2 << 30, Monthly: 87.6}) {
+		t.Errorf("unexpected requests %+v", api.Requests)
+	}
+	if api.Usage != (ResourceCost{CPUMillicores: 1600, MemoryBytes: 1 << 30, Monthly: 124.1}) {
+		t.Errorf("unexpected usage %+v", api.Usage)
+	}
+	// CPU is charged at usage and memory at requests
+	if api.Monthly != 131.4 {
+		t.Errorf("monthly = %v, want 131.4", api.Monthly)
+	}
+
+	debug := report.Workloads[1]
+	if debug.WorkloadKind != "Pod" || debug.Monthly != 18.25 || debug.Usage.Monthly != 0 {
+		t.Errorf("unexpected unmanaged pod cost %+v", debug)
+	}
+	if report.Namespaces[0].Namespace != "apps" || report.Namespaces[0].Workloads != 1 {
+		t.Errorf("unexpected namespaces %+v", report.Namespaces)
+	}
+	if report.Monthly != 149.65 {
+		t.Errorf("total = %v, want 149.65", report.Monthly)
+	}
+}
@@ -0,0 +1,496 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultHostInterval is how often host metrics are collected and published
+	DefaultHostInterval = 5 * time.Second
+
+	// clockTicks is USER_HZ, the unit of the CPU times in /proc/stat on every Linux platform
+	clockTicks = 100
+	// sectorSize is the unit of the sector counts in /proc/diskstats, whatever the device's
+	sectorSize = 512
+)
+
+// virtualDiskPrefixes are block devices left out of host disk I/O: loop and RAM devices,
+// optical drives and device mapper volumes, whose I/O also reaches the disks below them
+var virtualDiskPrefixes = []string{"loop", "ram", "zram", "sr", "fd", "dm-", "md"}
+
+// HostMetrics are the CPU, memory, disk and network figures of the host Denshimon runs on,
+// read without Kubernetes. Rates are averaged over Window.
+type HostMetrics struct {
+	NodeSystemMetrics
+	Hostname       string           `json:"hostname"`
+	CPUCount       int              `json:"cpu_count"`
+	Load1          float64          `json:"load1"`
+	Load5          float64          `json:"load5"`
+	Load15         float64          `json:"load15"`
+	SwapUsedBytes  int64            `json:"swap_used_bytes"`
+	SwapTotalBytes int64            `json:"swap_total_bytes"`
+	UptimeSeconds  float64          `json:"uptime_seconds"`
+	Filesystems    []HostFilesystem `json:"filesystems"`
+}
+
+// HostFilesystem is the usage of a mounted filesystem
+type HostFilesystem struct {
+	Mountpoint string  `json:"mountpoint"`
+	Device     string  `json:"device"`
+	Type       string  `json:"type"`
+	UsedBytes  int64   `json:"used_bytes"`
+	TotalBytes int64   `json:"total_bytes"`
+	Percent    float64 `json:"percent"`
+}
+
+// hostSample is one reading of the host: counters for rates, and gauges reported as read
+type hostSample struct {
+	systemSample
+	load        [3]float64
+	swapUsed    float64
+	swapTotal   float64
+	uptime      float64
+	filesystems []HostFilesystem
+}
+
+// HostCollector reads host metrics from procfs, or from a node_exporter when one is
+// configured, and keeps the latest reading
+type HostCollector struct {
+	procPath        string
+	rootPath        string
+	nodeExporterURL string
+	client          *http.Client
+
+	mu       sync.Mutex
+	previous *hostSample
+	latest   *HostMetrics
+	onSample func(metrics *HostMetrics)
+}
+
+// NewHostCollector creates a collector reading procfs at procPath, /proc by default, or the
+// node_exporter at nodeExporterURL when it is set
+func NewHostCollector(procPath, nodeExporterURL string) *HostCollector {
+	if procPath == "" {
+		procPath = "/proc"
+	}
+	return &HostCollector{
+		procPath:        procPath,
+		rootPath:        "/",
+		nodeExporterURL: nodeExporterURL,
+		client:          &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// SetRootPath sets where the host's filesystems are mounted, for a collector in a container
+// with the host's root mounted at a path such as /host
+func (c *HostCollector) SetRootPath(path string) {
+	if path != "" {
+		c.rootPath = path
+	}
+}
+
+// OnSample registers a callback receiving the metrics of every periodic collection
+func (c *HostCollector) OnSample(fn func(metrics *HostMetrics)) {
+	c.onSample = fn
+}
+
+// Latest returns the metrics of the last collection, or nil before the first
+func (c *HostCollector) Latest() *HostMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.latest
+}
+
+// Start collects host metrics every interval until the context is done
+func (c *HostCollector) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultHostInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			metrics, err := c.Collect(ctx)
+			if err != nil {
+				slog.Warn("Failed to collect host metrics", "error", err)
+			} else if c.onSample != nil {
+				c.onSample(metrics)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Collect reads the host and computes its rates against the previous reading. When there is
+// no recent one, it reads again after systemRateWindow.
+func (c *HostCollector) Collect(ctx context.Context) (*HostMetrics, error) {
+	current, err := c.sample(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	previous := c.previous
+	c.mu.Unlock()
+	if previous == nil || previous.source != current.source || current.at.Sub(previous.at) > systemSampleMaxAge {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(systemRateWindow):
+		}
+		previous = current
+		if current, err = c.sample(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	metrics := &HostMetrics{
+		NodeSystemMetrics: *systemRates(previous.systemSample, current.systemSample, 0, 0),
+		CPUCount:          int(current.cpuCores),
+		Load1:             current.load[0],
+		Load5:             current.load[1],
+		Load15:            current.load[2],
+		SwapUsedBytes:     int64(current.swapUsed),
+		SwapTotalBytes:    int64(current.swapTotal),
+		UptimeSeconds:     current.uptime,
+		Filesystems:       current.filesystems,
+	}
+	metrics.Hostname, _ = os.Hostname()
+	if hostname, err := os.ReadFile(filepath.Join(c.procPath, "sys/kernel/hostname")); err == nil {
+		metrics.Hostname = strings.TrimSpace(string(hostname))
+	}
+
+	c.mu.Lock()
+	c.previous, c.latest = current, metrics
+	c.mu.Unlock()
+	return metrics, nil
+}
+
+// sample reads the node_exporter when configured, procfs otherwise
+func (c *HostCollector) sample(ctx context.Context) (*hostSample, error) {
+	if c.nodeExporterURL == "" {
+		return c.readProcfs()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.nodeExporterURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape node_exporter: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to scrape node_exporter: %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseHostNodeExporter(data)
+}
+
+// parseHostNodeExporter reads the node_exporter counters of node system metrics, plus load,
+// swap, uptime and filesystem usage
+func parseHostNodeExporter(data []byte) (*hostSample, error) {
+	system, err := parseNodeExporter(data)
+	if err != nil {
+		return nil, err
+	}
+	sample := &hostSample{systemSample: system}
+	var swapFree, bootTime, now float64
+	filesystems := map[string]*HostFilesystem{}
+	filesystem := func(labels map[string]string) *HostFilesystem {
+		if !realFilesystem(labels["device"], labels["fstype"]) {
+			return nil
+		}
+		fs := filesystems[labels["mountpoint"]]
+		if fs == nil {
+			fs = &HostFilesystem{Mountpoint: labels["mountpoint"], Device: labels["device"], Type: labels["fstype"]}
+			filesystems[labels["mountpoint"]] = fs
+		}
+		return fs
+	}
+	avail := map[string]float64{}
+	err = parseTextMetrics(data, func(name string, labels map[string]string, value float64) {
+		switch name {
+		case "node_load1":
+			sample.load[0] = value
+		case "node_load5":
+			sample.load[1] = value
+		case "node_load15":
+			sample.load[2] = value
+		case "node_memory_SwapTotal_bytes":
+			sample.swapTotal = value
+		case "node_memory_SwapFree_bytes":
+			swapFree = value
+		case "node_boot_time_seconds":
+			bootTime = value
+		case "node_time_seconds":
+			now = value
+		case "node_filesystem_size_bytes":
+			if fs := filesystem(labels); fs != nil {
+				fs.TotalBytes = int64(value)
+			}
+		case "node_filesystem_avail_bytes":
+			if fs := filesystem(labels); fs != nil {
+				avail[fs.Mountpoint] = value
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sample.swapUsed = sample.swapTotal - swapFree
+	if bootTime > 0 && now > bootTime {
+		sample.uptime = now - bootTime
+	}
+	var list []HostFilesystem
+	for mountpoint, fs := range filesystems {
+		fs.UsedBytes = fs.TotalBytes - int64(avail[mountpoint])
+		list = append(list, *fs)
+	}
+	sample.filesystems = uniqueFilesystems(list)
+	return sample, nil
+}
+
+// readProcfs reads the host counters and gauges from procfs, and filesystem usage by statfs
+func (c *HostCollector) readProcfs() (*hostSample, error) {
+	sample := &hostSample{systemSample: systemSample{source: UsageSourceProcfs, at: time.Now()}}
+	read := func(name string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(c.procPath, name))
+	}
+
+	stat, err := read("stat")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read host CPU times: %w", err)
+	}
+	if err := parseProcStat(stat, sample); err != nil {
+		return nil, err
+	}
+	meminfo, err := read("meminfo")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read host memory: %w", err)
+	}
+	parseMeminfo(meminfo, sample)
+
+	// The rest is optional; missing files leave their figures at zero
+	if data, err := read("diskstats"); err == nil {
+		parseDiskstats(data, sample)
+	}
+	if data, err := read("net/dev"); err == nil {
+		parseNetDev(data, sample)
+	}
+	if data, err := read("loadavg"); err == nil {
+		fields := strings.Fields(string(data))
+		for i := 0; i < 3 && i < len(fields); i++ {
+			sample.load[i], _ = strconv.ParseFloat(fields[i], 64)
+		}
+	}
+	if data, err := read("uptime"); err == nil {
+		if fields := strings.Fields(string(data)); len(fields) > 0 {
+			sample.uptime, _ = strconv.ParseFloat(fields[0], 64)
+		}
+	}
+	if data, err := read("mounts"); err == nil {
+		sample.filesystems = c.filesystemUsage(data)
+	}
+	return sample, nil
+}
+
+// parseProcStat reads the busy CPU time of the aggregate cpu line and counts the CPUs. Time
+// idle or waiting for I/O isn't busy, and guest time is already part of user time.
+func parseProcStat(data []byte, sample *hostSample) error {
+	found := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		if fields[0] != "cpu" {
+			sample.cpuCores++
+			continue
+		}
+		found = true
+		for i, field := range fields[1:] {
+			// user nice system idle iowait irq softirq steal guest guest_nice
+			if i == 3 || i == 4 || i >= 8 {
+				continue
+			}
+			ticks, _ := strconv.ParseFloat(field, 64)
+			sample.cpuSeconds += ticks / clockTicks
+		}
+	}
+	if !found {
+		return fmt.Errorf("no cpu line in /proc/stat")
+	}
+	return nil
+}
+
+// parseMeminfo reads memory and swap; used memory is what isn't available
+func parseMeminfo(data []byte, sample *hostSample) {
+	values := map[string]float64{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSuffix(fields[0], ":")] = kb * 1024
+	}
+	sample.memoryTotal = values["MemTotal"]
+	sample.memoryUsed = values["MemTotal"] - values["MemAvailable"]
+	sample.swapTotal = values["SwapTotal"]
+	sample.swapUsed = values["SwapTotal"] - values["SwapFree"]
+}
+
+// parseDiskstats sums the bytes read and written by whole disks; partitions would count
+// their disk's I/O twice
+func parseDiskstats(data []byte, sample *hostSample) {
+	type disk struct {
+		name          string
+		read, written float64
+	}
+	var disks []disk
+	names := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// major minor name reads merged sectors_read ms writes merged sectors_written ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || virtualDisk(fields[2]) {
+			continue
+		}
+		read, _ := strconv.ParseFloat(fields[5], 64)
+		written, _ := strconv.ParseFloat(fields[9], 64)
+		disks = append(disks, disk{name: fields[2], read: read * sectorSize, written: written * sectorSize})
+		names[fields[2]] = true
+	}
+	for _, d := range disks {
+		if partition(d.name, names) {
+			continue
+		}
+		sample.diskRead += d.read
+		sample.diskWritten += d.written
+	}
+}
+
+// partition reports whether a block device is a partition of another listed one, such as
+// sda1 of sda or nvme0n1p1 of nvme0n1
+func partition(name string, names map[string]bool) bool {
+	trimmed := strings.TrimRight(name, "0123456789")
+	if trimmed == name {
+		return false
+	}
+	return names[trimmed] || (strings.HasSuffix(trimmed, "p") && names[strings.TrimSuffix(trimmed, "p")])
+}
+
+func virtualDisk(name string) bool {
+	for _, prefix := range virtualDiskPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNetDev sums the bytes received and sent by the physical interfaces
+func parseNetDev(data []byte, sample *hostSample) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		name, counters, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || virtualInterface(strings.TrimSpace(name)) {
+			continue
+		}
+		// 8 receive counters, starting with bytes, then 8 transmit counters
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		received, _ := strconv.ParseFloat(fields[0], 64)
+		transmitted, _ := strconv.ParseFloat(fields[8], 64)
+		sample.networkReceived += received
+		sample.networkTransmitted += transmitted
+	}
+}
+
+// filesystemUsage stats the block device filesystems in a mount table
+func (c *HostCollector) filesystemUsage(mounts []byte) []HostFilesystem {
+	var filesystems []HostFilesystem
+	scanner := bufio.NewScanner(bytes.NewReader(mounts))
+	for scanner.Scan() {
+		// device mountpoint type options dump pass
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || !realFilesystem(fields[0], fields[2]) {
+			continue
+		}
+		mountpoint := unescapeMountpoint(fields[1])
+		var stat syscall.Statfs_t
+		if err := syscall.Statfs(filepath.Join(c.rootPath, mountpoint), &stat); err != nil {
+			continue
+		}
+		total := int64(uint64(stat.Blocks) * uint64(stat.Bsize))
+		available := int64(uint64(stat.Bavail) * uint64(stat.Bsize))
+		filesystems = append(filesystems, HostFilesystem{
+			Mountpoint: mountpoint,
+			Device:     fields[0],
+			Type:       fields[2],
+			TotalBytes: total,
+			UsedBytes:  total - available,
+		})
+	}
+	return uniqueFilesystems(filesystems)
+}
+
+// realFilesystem reports whether a mount is a filesystem on a block device, leaving out
+// pseudo filesystems, overlays and read-only squashfs images
+func realFilesystem(device, fstype string) bool {
+	return strings.HasPrefix(device, "/dev/") && fstype != "squashfs"
+}
+
+// unescapeMountpoint decodes the octal escapes of spaces and tabs in mount tables
+func unescapeMountpoint(path string) string {
+	return strings.NewReplacer(`\040`, " ", `\011`, "\t", `\134`, `\`).Replace(path)
+}
+
+// uniqueFilesystems keeps the shortest mountpoint of each device, since bind mounts list a
+// device again, sorted by mountpoint, and fills in the used percentage
+func uniqueFilesystems(filesystems []HostFilesystem) []HostFilesystem {
+	byDevice := map[string]HostFilesystem{}
+	for _, fs := range filesystems {
+		if existing, ok := byDevice[fs.Device]; !ok || len(fs.Mountpoint) < len(existing.Mountpoint) {
+			byDevice[fs.Device] = fs
+		}
+	}
+	unique := []HostFilesystem{}
+	for _, fs := range byDevice {
+		if fs.TotalBytes > 0 {
+			fs.Percent = float64(fs.UsedBytes) / float64(fs.TotalBytes) * 100
+		}
+		unique = append(unique, fs)
+	}
+	sort.Slice(unique, func(i, j int) bool { return unique[i].Mountpoint < unique[j].Mountpoint })
+	return unique
+}
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// writeProc writes a procfs with the given CPU, disk and network counters
+func writeProc(t *testing.T, dir string, userTicks, sectors, received int) {
+	t.Helper()
+	files := map[string]string{
+		"stat": "cpu  " + strconv.Itoa(userTicks) + " 0 100 5000 200 0 0 0 0 0\n" +
+			"cpu0 1 0 0 0 0 0 0 0 0 0\ncpu1 1 0 0 0 0 0 0 0 0 0\nintr 12345\n",
+		"meminfo": "MemTotal:        4096000 kB\nMemFree:          512000 kB\nMemAvailable:    1024000 kB\n" +
+			"SwapTotal:       1000000 kB\nSwapFree:         750000 kB\n",
+		"diskstats": "   8       0 sda 100 0 " + strconv.Itoa(sectors) + " 0 50 0 400 0 0 0 0\n" +
+			"   8       1 sda1 100 0 " + strconv.Itoa(sectors) + " 0 50 0 400 0 0 0 0\n" +
+			" 259       0 nvme0n1 10 0 0 0 5 0 0 0 0 0 0\n" +
+			" 259       1 nvme0n1p1 10 0 999 0 5 0 999 0 0 0 0\n" +
+			"   7       0 loop0 10 0 999 0 0 0 0 0 0 0 0\n",
+		"net/dev": "Inter-|   Receive                                                |  Transmit\n" +
+			" face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed\n" +
+			"    lo: 99999 10 0 0 0 0 0 0 99999 10 0 0 0 0 0 0\n" +
+			"  eth0: " + strconv.Itoa(received) + " 10 0 0 0 0 0 0 2000 10 0 0 0 0 0 0\n" +
+			"veth1a2b: 55555 10 0 0 0 0 0 0 55555 10 0 0 0 0 0 0\n",
+		"loadavg":             "0.50 0.25 0.10 1/123 4567\n",
+		"uptime":              "86400.50 170000.00\n",
+		"mounts":              "proc /proc proc rw 0 0\noverlay / overlay rw 0 0\n",
+		"sys/kernel/hostname": "vps-1\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHostCollectorProcfs(t *testing.T) {
+	dir := t.TempDir()
+	writeProc(t, dir, 1000, 2000, 10000)
+	collector := NewHostCollector(dir, "")
+	first, err := collector.readProcfs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// user + system ticks at 100 per second
+	if first.cpuSeconds != 11 || first.cpuCores != 2 {
+		t.Errorf("cpu = %v seconds on %v cores", first.cpuSeconds, first.cpuCores)
+	}
+	// sda counted once, its partition and the loop device not at all
+	if first.diskRead != 2000*sectorSize || first.diskWritten != 400*sectorSize {
+		t.Errorf("disk = %v/%v", first.diskRead, first.diskWritten)
+	}
+	if first.networkReceived != 10000 || first.networkTransmitted != 2000 {
+		t.Errorf("network = %v/%v, want loopback and veths left out", first.networkReceived, first.networkTransmitted)
+	}
+	if first.memoryUsed != 3072000*1024 || first.swapUsed != 250000*1024 || first.load != [3]float64{0.5, 0.25, 0.1} || first.uptime != 86400.5 {
+		t.Errorf("gauges = %+v", first)
+	}
+	if len(first.filesystems) != 0 {
+		t.Errorf("pseudo filesystems reported: %+v", first.filesystems)
+	}
+
+	// Rates come from the previous collection
+	first.at = time.Now().Add(-10 * time.Second)
+	collector.previous = first
+	writeProc(t, dir, 1100, 4000, 20000)
+	host, err := collector.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host.Source != UsageSourceProcfs || host.Hostname != "vps-1" || host.CPUCount != 2 {
+		t.Errorf("host = %+v", host)
+	}
+	if host.CPUCores < 0.09 || host.CPUCores > 0.11 || host.CPUPercent < 4.5 || host.CPUPercent > 5.5 {
+		t.Errorf("cpu = %v cores, %v%%", host.CPUCores, host.CPUPercent)
+	}
+	if host.NetworkReceiveBytesPerSec < 900 || host.NetworkReceiveBytesPerSec > 1100 {
+		t.Errorf("receive rate = %v", host.NetworkReceiveBytesPerSec)
+	}
+	if host.MemoryPercent != 75 || host.SwapTotalBytes != 1000000*1024 {
+		t.Errorf("memory = %v%%, swap %d", host.MemoryPercent, host.SwapTotalBytes)
+	}
+	if collector.Latest() != host {
+		t.Error("expected the collection kept as the latest")
+	}
+}
+
+func TestHostCollectorNodeExporter(t *testing.T) {
+	counters := nodeExporterMetrics + `node_load1 1.5
+node_load5 1
+node_load15 0.5
+node_memory_SwapTotal_bytes 2000
+node_memory_SwapFree_bytes 500
+node_boot_time_seconds 1000
+node_time_seconds 4600
+node_filesystem_size_bytes{device="/dev/vda1",fstype="ext4",mountpoint="/"} 1000
+node_filesystem_avail_bytes{device="/dev/vda1",fstype="ext4",mountpoint="/"} 250
+node_filesystem_size_bytes{device="/dev/vda1",fstype="ext4",mountpoint="/var/lib/kubelet"} 1000
+node_filesystem_avail_bytes{device="/dev/vda1",fstype="ext4",mountpoint="/var/lib/kubelet"} 250
+node_filesystem_size_bytes{device="tmpfs",fstype="tmpfs",mountpoint="/run"} 100
+`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(counters))
+	}))
+	defer server.Close()
+
+	collector := NewHostCollector("", server.URL)
+	sample, err := collector.sample(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample.source != UsageSourceNodeExporter || sample.load[0] != 1.5 || sample.swapUsed != 1500 || sample.uptime != 3600 {
+		t.Errorf("sample = %+v", sample)
+	}
+	// The bind mount of the same device and tmpfs are left out
+	if len(sample.filesystems) != 1 || sample.filesystems[0].Mountpoint != "/" || sample.filesystems[0].UsedBytes != 750 || sample.filesystems[0].Percent != 75 {
+		t.Errorf("filesystems = %+v", sample.filesystems)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	names := map[string]bool{"sda": true, "sda1": true, "nvme0n1": true, "nvme0n1p2": true, "vdb": true}
+	for name, want := range map[string]bool{"sda": false, "sda1": true, "nvme0n1": false, "nvme0n1p2": true, "vdb": false, "mmcblk0": false} {
+		if got := partition(name, names); got != want {
+			t.Errorf("partition(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
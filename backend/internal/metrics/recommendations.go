@@ -0,0 +1,208 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Floors for recommended requests, so idle containers still get schedulable values
+const (
+	minCPUMillicores = 10
+	minMemoryBytes   = 16 << 20
+)
+
+// ErrNoUsageHistory is returned when recommendations are requested without usage sampling
+var ErrNoUsageHistory = errors.New("container usage sampling is not enabled")
+
+// RecommendationOptions controls how recommendations are derived from usage samples
+type RecommendationOptions struct {
+	Namespace  string        // empty covers all namespaces
+	Window     time.Duration // how much usage history to consider
+	Percentile float64       // usage percentile the request should cover, e.g. 95
+	Headroom   float64       // fraction added on top of observed usage, e.g. 0.15
+	MinSamples int           // containers with fewer samples get no recommendation
+}
+
+// DefaultRecommendationOptions returns P95 over the last week with 15% headroom
+func DefaultRecommendationOptions() RecommendationOptions {
+	return RecommendationOptions{
+		Window:     DefaultUsageRetention,
+		Percentile: 95,
+		Headroom:   0.15,
+		MinSamples: 12, // an hour of samples at the default interval
+	}
+}
+
+// ResourceValues are container requests and limits as Kubernetes quantities
+type ResourceValues struct {
+	CPURequest    string `json:"cpu_request,omitempty"`
+	CPULimit      string `json:"cpu_limit,omitempty"`
+	MemoryRequest string `json:"memory_request,omitempty"`
+	MemoryLimit   string `json:"memory_limit,omitempty"`
+}
+
+// UsageStats summarises observed usage, in millicores for CPU and bytes for memory
+type UsageStats struct {
+	Percentile int64 `json:"percentile"`
+	Max        int64 `json:"max"`
+}
+
+// ContainerRecommendation suggests requests and limits for one container of a workload
+type ContainerRecommendation struct {
+	Namespace    string         `json:"namespace"`
+	WorkloadKind string         `json:"workload_kind"`
+	Workload     string         `json:"workload"`
+	Container    string         `json:"container"`
+	Samples      int            `json:"samples"`
+	Since        time.Time      `json:"since"`
+	CPUUsage     UsageStats     `json:"cpu_usage"`
+	MemoryUsage  UsageStats     `json:"memory_usage"`
+	Current      ResourceValues `json:"current"`
+	Recommended  ResourceValues `json:"recommended"`
+}
+
+type containerKey struct {
+	namespace, kind, workload, container string
+}
+
+// GetRecommendations computes recommended requests and limits from sampled usage history,
+// alongside the values the containers currently run with
+func (s *Service) GetRecommendations(ctx context.Context, opts RecommendationOptions) ([]ContainerRecommendation, error) {
+	if s.usageStore == nil {
+		return nil, ErrNoUsageHistory
+	}
+	samples, err := s.usageStore.Samples(ctx, opts.Namespace, time.Now().Add(-opts.Window))
+	if err != nil {
+		return nil, err
+	}
+	recommendations := Recommend(samples, opts)
+
+	// Current values come from a running pod of each workload
+	if s.k8sClient != nil && len(recommendations) > 0 {
+		if pods, err := s.k8sClient.ListPods(ctx, opts.Namespace); err == nil {
+			current := map[containerKey]ResourceValues{}
+			for i := range pods.Items {
+				pod := &pods.Items[i]
+				kind, workload := podWorkload(pod)
+				for _, container := range pod.Spec.Containers {
+					current[containerKey{pod.Namespace, kind, workload, container.Name}] = resourceValues(container.Resources)
+				}
+			}
+			for i := range recommendations {
+				rec := &recommendations[i]
+				rec.Current = current[containerKey{rec.Namespace, rec.WorkloadKind, rec.Workload, rec.Container}]
+			}
+		}
+	}
+	return recommendations, nil
+}
+
+// Recommend derives per-container recommendations from usage samples. Requests cover the
+// configured usage percentile and limits the observed peak, both with headroom on top.
+func Recommend(samples []UsageSample, opts RecommendationOptions) []ContainerRecommendation {
+	type usage struct {
+		cpu, memory []int64
+		since       time.Time
+	}
+	groups := map[containerKey]*usage{}
+	for _, sample := range samples {
+		key := containerKey{sample.Namespace, sample.WorkloadKind, sample.Workload, sample.Container}
+		group, ok := groups[key]
+		if !ok {
+			group = &usage{since: sample.Timestamp}
+			groups[key] = group
+		}
+		group.cpu = append(group.cpu, sample.CPUMillicores)
+		group.memory = append(group.memory, sample.MemoryBytes)
+		if sample.Timestamp.Before(group.since) {
+			group.since = sample.Timestamp
+		}
+	}
+
+	recommendations := []ContainerRecommendation{}
+	for key, group := range groups {
+		if len(group.cpu) < opts.MinSamples {
+			continue
+		}
+		cpu := UsageStats{Percentile: percentile(group.cpu, opts.Percentile), Max: percentile(group.cpu, 100)}
+		memory := UsageStats{Percentile: percentile(group.memory, opts.Percentile), Max: percentile(group.memory, 100)}
+
+		cpuRequest := max(withHeadroom(cpu.Percentile, opts.Headroom), minCPUMillicores)
+		cpuLimit := max(withHeadroom(cpu.Max, opts.Headroom), cpuRequest)
+		memoryRequest := roundUpMebibytes(max(withHeadroom(memory.Percentile, opts.Headroom), minMemoryBytes))
+		memoryLimit := roundUpMebibytes(max(withHeadroom(memory.Max, opts.Headroom), memoryRequest))
+
+		recommendations = append(recommendations, ContainerRecommendation{
+			Namespace:    key.namespace,
+			WorkloadKind: key.kind,
+			Workload:     key.workload,
+			Container:    key.container,
+			Samples:      len(group.cpu),
+			Since:        group.since,
+			CPUUsage:     cpu,
+			MemoryUsage:  memory,
+			Recommended: ResourceValues{
+				CPURequest:    resource.NewMilliQuantity(cpuRequest, resource.DecimalSI).String(),
+				CPULimit:      resource.NewMilliQuantity(cpuLimit, resource.DecimalSI).String(),
+				MemoryRequest: resource.NewQuantity(memoryRequest, resource.BinarySI).String(),
+				MemoryLimit:   resource.NewQuantity(memoryLimit, resource.BinarySI).String(),
+			},
+		})
+	}
+
+	sort.Slice(recommendations, func(i, j int) bool {
+		a, b := recommendations[i], recommendations[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Workload != b.Workload {
+			return a.Workload < b.Workload
+		}
+		return a.Container < b.Container
+	})
+	return recommendations
+}
+
+// percentile returns the nearest-rank percentile of values; p=100 is the maximum
+func percentile(values []int64, p float64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+func withHeadroom(value int64, headroom float64) int64 {
+	return int64(math.Ceil(float64(value) * (1 + headroom)))
+}
+
+func roundUpMebibytes(bytes int64) int64 {
+	const mebibyte = 1 << 20
+	return (bytes + mebibyte - 1) / mebibyte * mebibyte
+}
+
+// resourceValues converts a container's resource requirements to ResourceValues
+func resourceValues(resources corev1.ResourceRequirements) ResourceValues {
+	var values ResourceValues
+	if quantity, ok := resources.Requests[corev1.ResourceCPU]; ok {
+		values.CPURequest = quantity.String()
+	}
+	if quantity, ok := resources.Limits[corev1.ResourceCPU]; ok {
+		values.CPULimit = quantity.String()
+	}
+	if quantity, ok := resources.Requests[corev1.ResourceMemory]; ok {
+		values.MemoryRequest = quantity.String()
+	}
+	if quantity, ok := resources.Limits[corev1.ResourceMemory]; ok {
+		values.MemoryLimit = quantity.String()
+	}
+	return values
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []int64{5, 1, 4, 2, 3, 10, 9, 8, 7, 6}
+	tests := []struct {
+		p    float64
+		want int64
+	}{
+		{50, 5},
+		{90, 9},
+		{95, 10},
+		{100, 10},
+		{0, 1},
+	}
+	for _, tt := range tests {
+		if got := percentile(values, tt.p); got != tt.want {
+			t.Errorf("percentile(%v) = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 95); got != 0 {
+		t.Errorf("percentile of no values = %d, want 0", got)
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	var samples []UsageSample
+	for i := 0; i < 20; i++ {
+		samples = append(samples, UsageSample{
+			Namespace:     "default",
+			WorkloadKind:  "Deployment",
+			Workload:      "api",
+			Pod:           "api-7d9f8-abcde",
+			Container:     "api",
+			CPUMillicores: int64(100 + i*10), // 100m..290m
+			MemoryBytes:   int64(100+i) << 20,
+			Timestamp:     start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	// Too few samples for a recommendation
+	samples = append(samples, UsageSample{Namespace: "default", WorkloadKind: "Deployment", Workload: "worker", Container: "worker", Timestamp: start})
+
+	opts := DefaultRecommendationOptions()
+	opts.MinSamples = 10
+	recommendations := Recommend(samples, opts)
+	if len(recommendations) != 1 {
+		t.Fatalf("expected 1 recommendation, got %d", len(recommendations))
+	}
+
+	rec := recommendations[0]
+	if rec.Workload != "api" || rec.Samples != 20 || !rec.Since.Equal(start) {
+		t.Errorf("unexpected recommendation target: %+v", rec)
+	}
+	if rec.CPUUsage.Percentile != 280 || rec.CPUUsage.Max != 290 {
+		t.Errorf("unexpected CPU usage: %+v", rec.CPUUsage)
+	}
+	want := ResourceValues{
+		CPURequest:    "322m", // 280m * 1.15
+		CPULimit:      "334m", // 290m * 1.15, rounded up
+		MemoryRequest: "136Mi",
+		MemoryLimit:   "137Mi",
+	}
+	if rec.Recommended != want {
+		t.Errorf("recommended %+v, want %+v", rec.Recommended, want)
+	}
+}
+
+func TestRecommendFloors(t *testing.T) {
+	var samples []UsageSample
+	for i := 0; i < 3; i++ {
+		samples = append(samples, UsageSample{Namespace: "default", WorkloadKind: "Deployment", Workload: "idle", Container: "idle"})
+	}
+	opts := DefaultRecommendationOptions()
+	opts.MinSamples = 1
+	rec := Recommend(samples, opts)[0]
+	if rec.Recommended.CPURequest != "10m" || rec.Recommended.MemoryRequest != "16Mi" {
+		t.Errorf("idle container not raised to the floors: %+v", rec.Recommended)
+	}
+}
+
+func TestPodWorkload(t *testing.T) {
+	controller := true
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:   "api-7d9f8-abcde",
+		Labels: map[string]string{"pod-template-hash": "7d9f8"},
+		OwnerReferences: []metav1.OwnerReference{
+			{Kind: "ReplicaSet", Name: "api-7d9f8", Controller: &controller},
+		},
+	}}
+	if kind, name := podWorkload(pod); kind != "Deployment" || name != "api" {
+		t.Errorf("got %s/%s, want Deployment/api", kind, name)
+	}
+
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "StatefulSet", Name: "db", Controller: &controller}}
+	if kind, name := podWorkload(pod); kind != "StatefulSet" || name != "db" {
+		t.Errorf("got %s/%s, want StatefulSet/db", kind, name)
+	}
+
+	pod.OwnerReferences = nil
+	if kind, name := podWorkload(pod); kind != "Pod" || name != pod.Name {
+		t.Errorf("got %s/%s, want Pod/%s", kind, name, pod.Name)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/archellir/denshimon/internal/k8s"
@@ -17,6 +18,20 @@ type Service struct {
 	k8sClient         *k8s.Client
 	metricsClient     metricsclient.Interface
 	prometheusService *prometheus.Service
+	usageStore        *UsageStore // container usage history for recommendations
+	storageStore      *UsageStore // storage usage history for growth projections
+	costRates         CostRates
+
+	usageMu sync.Mutex
+	usage   *usageSnapshot // current usage, cached for usageCacheTTL
+
+	nodeExporterSelector string
+	nodeExporterPort     int
+	systemMu             sync.Mutex
+	systemSamples        map[string]systemSample // last scrape of each node, for rates
+
+	storageTrendWindow time.Duration
+	onStorageTrends    func(ctx context.Context, trends []StorageTrend)
 }
 
 type ClusterMetrics struct {
@@ -31,6 +46,8 @@ type ClusterMetrics struct {
 	CPUUsage         ResourceMetrics    `json:"cpu_usage"`
 	MemoryUsage      ResourceMetrics    `json:"memory_usage"`
 	StorageUsage     ResourceMetrics    `json:"storage_usage"`
+	UsageSource      UsageSource        `json:"usage_source"`   // source of CPU and memory usage
+	StorageSource    UsageSource        `json:"storage_source"` // source of storage usage
 	NetworkMetrics   NetworkMetrics     `json:"network_metrics"`
 	NodeMetrics      []NodeMetrics      `json:"node_metrics"`
 	NamespaceMetrics []NamespaceMetrics `json:"namespace_metrics"`
@@ -56,11 +73,14 @@ type NodeMetrics struct {
 	CPUUsage     ResourceMetrics `json:"cpu_usage"`
 	MemoryUsage  ResourceMetrics `json:"memory_usage"`
 	StorageUsage ResourceMetrics `json:"storage_usage"`
+	UsageSource  UsageSource     `json:"usage_source"`
 	PodCount     int             `json:"pod_count"`
 	Age          string          `json:"age"`
 	Version      string          `json:"version"`
 	OS           string          `json:"os"`
 	Architecture string          `json:"architecture"`
+	// System is scraped from the node directly when Prometheus is absent, on the node detail only
+	System *NodeSystemMetrics `json:"system,omitempty"`
 }
 
 type NamespaceMetrics struct {
@@ -70,6 +90,8 @@ type NamespaceMetrics struct {
 	MemoryUsage ResourceMetrics `json:"memory_usage"`
 	Status      string          `json:"status"`
 	Age         string          `json:"age"`
+	// Quota resources at or above k8s.QuotaWarningPercent of their hard limit
+	QuotaWarnings []k8s.QuotaPressure `json:"quota_warnings,omitempty"`
 }
 
 type PodMetrics struct {
@@ -79,6 +101,7 @@ type PodMetrics struct {
 	Node         string          `json:"node"`
 	CPUUsage     ResourceMetrics `json:"cpu_usage"`
 	MemoryUsage  ResourceMetrics `json:"memory_usage"`
+	UsageSource  UsageSource     `json:"usage_source"`
 	RestartCount int32           `json:"restart_count"`
 	Age          string          `json:"age"`
 	IP           string          `json:"ip"`
@@ -98,7 +121,10 @@ type MetricsHistory struct {
 
 func NewService(k8sClient *k8s.Client) *Service {
 	s := &Service{
-		k8sClient: k8sClient,
+		k8sClient:            k8sClient,
+		costRates:            DefaultCostRates(),
+		nodeExporterSelector: DefaultNodeExporterSelector,
+		nodeExporterPort:     DefaultNodeExporterPort,
 	}
 
 	// Try to initialize metrics client if k8s client is available
@@ -172,23 +198,28 @@ func (s *Service) GetClusterMetrics(ctx context.Context) (*ClusterMetrics, error
 	}
 	metrics.TotalNamespaces = len(namespaces.Items)
 
-	// Calculate resource metrics
-	metrics.CPUUsage = s.calculateClusterCPU(nodes.Items)
-	metrics.MemoryUsage = s.calculateClusterMemory(nodes.Items)
-	metrics.StorageUsage = s.calculateClusterStorage(nodes.Items)
+	// Calculate resource metrics from the current usage
+	usage := s.currentUsage(ctx, nodes.Items)
+	metrics.CPUUsage = s.calculateClusterCPU(nodes.Items, usage)
+	metrics.MemoryUsage = s.calculateClusterMemory(nodes.Items, usage)
+	metrics.StorageUsage = s.calculateClusterStorage(nodes.Items, usage)
+	metrics.UsageSource = usage.source
+	metrics.StorageSource = usage.storageSource
 
 	// Get detailed node metrics
 	nodeMetrics := make([]NodeMetrics, 0, len(nodes.Items))
 	for _, node := range nodes.Items {
-		nm := s.getNodeMetrics(ctx, node, pods.Items)
+		nm := s.getNodeMetrics(ctx, node, pods.Items, usage)
 		nodeMetrics = append(nodeMetrics, nm)
 	}
 	metrics.NodeMetrics = nodeMetrics
 
 	// Get namespace metrics
+	quotaWarnings := s.getQuotaWarnings(ctx)
 	namespaceMetrics := make([]NamespaceMetrics, 0, len(namespaces.Items))
 	for _, ns := range namespaces.Items {
-		nm := s.getNamespaceMetrics(ctx, ns, pods.Items)
+		nm := s.getNamespaceMetrics(ctx, ns, pods.Items, usage)
+		nm.QuotaWarnings = quotaWarnings[ns.Name]
 		namespaceMetrics = append(namespaceMetrics, nm)
 	}
 	metrics.NamespaceMetrics = namespaceMetrics
@@ -212,7 +243,19 @@ func (s *Service) GetNodeMetrics(ctx context.Context, nodeName string) (*NodeMet
 		return nil, fmt.Errorf("failed to list pods: %w", err)
 	}
 
-	metrics := s.getNodeMetrics(ctx, *node, pods.Items)
+	usage, err := s.usageForNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := s.getNodeMetrics(ctx, *node, pods.Items, usage)
+	if !s.prometheusAvailable(ctx) {
+		if system, err := s.nodeSystemMetrics(ctx, *node); err == nil {
+			metrics.System = system
+		} else {
+			slog.Debug("Node system metrics unavailable", "node", nodeName, "error", err)
+		}
+	}
 	return &metrics, nil
 }
 
@@ -227,10 +270,24 @@ func (s *Service) GetPodMetrics(ctx context.Context, namespace, podName string)
 		return nil, fmt.Errorf("failed to get pod: %w", err)
 	}
 
-	metrics := s.getPodMetrics(ctx, *pod)
+	usage, err := s.usageForNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := s.getPodMetrics(ctx, *pod, usage)
 	return &metrics, nil
 }
 
+// usageForNodes returns the current usage of all nodes
+func (s *Service) usageForNodes(ctx context.Context) (*usageSnapshot, error) {
+	nodes, err := s.k8sClient.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	return s.currentUsage(ctx, nodes.Items), nil
+}
+
 func (s *Service) GetMetricsHistory(ctx context.Context, duration time.Duration) (*MetricsHistory, error) {
 	if s.prometheusService == nil || !s.prometheusService.IsHealthy(ctx) {
 		// Fallback to mock data if Prometheus is not available
@@ -308,90 +365,46 @@ func (s *Service) isNodeReady(node corev1.Node) bool {
 	return false
 }
 
-func (s *Service) calculateClusterCPU(nodes []corev1.Node) ResourceMetrics {
+func (s *Service) calculateClusterCPU(nodes []corev1.Node, usage *usageSnapshot) ResourceMetrics {
 	var total, used int64
 
 	for _, node := range nodes {
 		if cpu := node.Status.Allocatable[corev1.ResourceCPU]; !cpu.IsZero() {
 			total += cpu.MilliValue()
 		}
-
-		// For actual usage, we'd need metrics server data
-		// For now, simulate some usage
-		cpuAllocatable := node.Status.Allocatable[corev1.ResourceCPU]
-		used += int64(float64(cpuAllocatable.MilliValue()) * 0.3)
-	}
-
-	available := total - used
-	usagePercent := 0.0
-	if total > 0 {
-		usagePercent = float64(used) / float64(total) * 100
+		used += usage.nodes[node.Name].CPUMillicores
 	}
 
-	return ResourceMetrics{
-		Used:         used,
-		Total:        total,
-		Available:    available,
-		UsagePercent: usagePercent,
-	}
+	return newResourceMetrics(used, total)
 }
 
-func (s *Service) calculateClusterMemory(nodes []corev1.Node) ResourceMetrics {
+func (s *Service) calculateClusterMemory(nodes []corev1.Node, usage *usageSnapshot) ResourceMetrics {
 	var total, used int64
 
 	for _, node := range nodes {
 		if memory := node.Status.Allocatable[corev1.ResourceMemory]; !memory.IsZero() {
 			total += memory.Value()
 		}
-
-		// For actual usage, we'd need metrics server data
-		// For now, simulate some usage
-		memAllocatable := node.Status.Allocatable[corev1.ResourceMemory]
-		used += int64(float64(memAllocatable.Value()) * 0.4)
-	}
-
-	available := total - used
-	usagePercent := 0.0
-	if total > 0 {
-		usagePercent = float64(used) / float64(total) * 100
+		used += usage.nodes[node.Name].MemoryBytes
 	}
 
-	return ResourceMetrics{
-		Used:         used,
-		Total:        total,
-		Available:    available,
-		UsagePercent: usagePercent,
-	}
+	return newResourceMetrics(used, total)
 }
 
-func (s *Service) calculateClusterStorage(nodes []corev1.Node) ResourceMetrics {
+func (s *Service) calculateClusterStorage(nodes []corev1.Node, usage *usageSnapshot) ResourceMetrics {
 	var total, used int64
 
 	for _, node := range nodes {
 		if storage := node.Status.Allocatable[corev1.ResourceEphemeralStorage]; !storage.IsZero() {
 			total += storage.Value()
 		}
-
-		// Simulate storage usage
-		storageAllocatable := node.Status.Allocatable[corev1.ResourceEphemeralStorage]
-		used += int64(float64(storageAllocatable.Value()) * 0.25)
-	}
-
-	available := total - used
-	usagePercent := 0.0
-	if total > 0 {
-		usagePercent = float64(used) / float64(total) * 100
+		used += usage.storage[node.Name]
 	}
 
-	return ResourceMetrics{
-		Used:         used,
-		Total:        total,
-		Available:    available,
-		UsagePercent: usagePercent,
-	}
+	return newResourceMetrics(used, total)
 }
 
-func (s *Service) getNodeMetrics(ctx context.Context, node corev1.Node, pods []corev1.Pod) NodeMetrics {
+func (s *Service) getNodeMetrics(ctx context.Context, node corev1.Node, pods []corev1.Pod, usage *usageSnapshot) NodeMetrics {
 	status := "NotReady"
 	if s.isNodeReady(node) {
 		status = "Ready"
@@ -408,12 +421,14 @@ func (s *Service) getNodeMetrics(ctx context.Context, node corev1.Node, pods []c
 	// Calculate node age
 	age := time.Since(node.CreationTimestamp.Time).Truncate(time.Second).String()
 
+	used := usage.nodes[node.Name]
 	return NodeMetrics{
 		Name:         node.Name,
 		Status:       status,
-		CPUUsage:     s.getNodeResourceMetrics(node, corev1.ResourceCPU),
-		MemoryUsage:  s.getNodeResourceMetrics(node, corev1.ResourceMemory),
-		StorageUsage: s.getNodeResourceMetrics(node, corev1.ResourceEphemeralStorage),
+		CPUUsage:     s.getNodeResourceMetrics(node, corev1.ResourceCPU, used.CPUMillicores),
+		MemoryUsage:  s.getNodeResourceMetrics(node, corev1.ResourceMemory, used.MemoryBytes),
+		StorageUsage: s.getNodeResourceMetrics(node, corev1.ResourceEphemeralStorage, usage.storage[node.Name]),
+		UsageSource:  usage.source,
 		PodCount:     podCount,
 		Age:          age,
 		Version:      node.Status.NodeInfo.KubeletVersion,
@@ -422,7 +437,7 @@ func (s *Service) getNodeMetrics(ctx context.Context, node corev1.Node, pods []c
 	}
 }
 
-func (s *Service) getNodeResourceMetrics(node corev1.Node, resourceName corev1.ResourceName) ResourceMetrics {
+func (s *Service) getNodeResourceMetrics(node corev1.Node, resourceName corev1.ResourceName, used int64) ResourceMetrics {
 	capacity := node.Status.Capacity[resourceName]
 
 	total := capacity.Value()
@@ -430,30 +445,65 @@ func (s *Service) getNodeResourceMetrics(node corev1.Node, resourceName corev1.R
 		total = capacity.MilliValue()
 	}
 
-	// Simulate usage (in production, would come from metrics server)
-	used := int64(float64(total) * 0.35)
-	available := total - used
-
-	usagePercent := 0.0
-	if total > 0 {
-		usagePercent = float64(used) / float64(total) * 100
-	}
+	return newResourceMetrics(used, total)
+}
 
+func newResourceMetrics(used, total int64) ResourceMetrics {
 	return ResourceMetrics{
 		Used:         used,
 		Total:        total,
-		Available:    available,
-		UsagePercent: usagePercent,
+		Available:    total - used,
+		UsagePercent: percent(used, total),
 	}
 }
 
-func (s *Service) getNamespaceMetrics(ctx context.Context, namespace corev1.Namespace, pods []corev1.Pod) NamespaceMetrics {
-	// Count pods in this namespace
+// podLimits sums the CPU (millicores) and memory limits of a pod's containers. Containers
+// without a limit add nothing.
+func podLimits(pod corev1.Pod) (cpu, memory int64) {
+	for _, container := range pod.Spec.Containers {
+		if limit, ok := container.Resources.Limits[corev1.ResourceCPU]; ok {
+			cpu += limit.MilliValue()
+		}
+		if limit, ok := container.Resources.Limits[corev1.ResourceMemory]; ok {
+			memory += limit.Value()
+		}
+	}
+	return cpu, memory
+}
+
+// getQuotaWarnings returns quota pressure warnings by namespace. Quotas are optional,
+// so failing to list them (e.g. missing RBAC) only omits the warnings.
+func (s *Service) getQuotaWarnings(ctx context.Context) map[string][]k8s.QuotaPressure {
+	quotas, err := s.k8sClient.ListResourceQuotas(ctx, "")
+	if err != nil {
+		slog.Debug("Resource quotas unavailable", "error", err)
+		return nil
+	}
+	warnings := make(map[string][]k8s.QuotaPressure)
+	for i := range quotas.Items {
+		usage := k8s.QuotaUsageFor(&quotas.Items[i])
+		for _, warning := range k8s.QuotaWarnings([]k8s.QuotaUsage{usage}) {
+			warnings[usage.Namespace] = append(warnings[usage.Namespace], warning)
+		}
+	}
+	return warnings
+}
+
+func (s *Service) getNamespaceMetrics(ctx context.Context, namespace corev1.Namespace, pods []corev1.Pod, usage *usageSnapshot) NamespaceMetrics {
+	// Sum usage and limits of the pods in this namespace
 	podCount := 0
+	var cpuUsed, cpuLimit, memoryUsed, memoryLimit int64
 	for _, pod := range pods {
-		if pod.Namespace == namespace.Name {
-			podCount++
+		if pod.Namespace != namespace.Name {
+			continue
 		}
+		podCount++
+		used := usage.pod(pod.Namespace, pod.Name)
+		cpuUsed += used.CPUMillicores
+		memoryUsed += used.MemoryBytes
+		cpu, memory := podLimits(pod)
+		cpuLimit += cpu
+		memoryLimit += memory
 	}
 
 	// Calculate namespace age
@@ -465,26 +515,16 @@ func (s *Service) getNamespaceMetrics(ctx context.Context, namespace corev1.Name
 	}
 
 	return NamespaceMetrics{
-		Name:     namespace.Name,
-		PodCount: podCount,
-		CPUUsage: ResourceMetrics{
-			Used:         int64(podCount * 100), // Simple estimate based on pod count
-			Total:        int64(podCount * 300),
-			Available:    int64(podCount * 200),
-			UsagePercent: 33.3,
-		},
-		MemoryUsage: ResourceMetrics{
-			Used:         int64(podCount * 128 * 1024 * 1024), // Simple estimate based on pod count
-			Total:        int64(podCount * 512 * 1024 * 1024),
-			Available:    int64(podCount * 384 * 1024 * 1024),
-			UsagePercent: 25.0,
-		},
-		Status: status,
-		Age:    age,
+		Name:        namespace.Name,
+		PodCount:    podCount,
+		CPUUsage:    newResourceMetrics(cpuUsed, cpuLimit),
+		MemoryUsage: newResourceMetrics(memoryUsed, memoryLimit),
+		Status:      status,
+		Age:         age,
 	}
 }
 
-func (s *Service) getPodMetrics(ctx context.Context, pod corev1.Pod) PodMetrics {
+func (s *Service) getPodMetrics(ctx context.Context, pod corev1.Pod, usage *usageSnapshot) PodMetrics {
 	age := time.Since(pod.CreationTimestamp.Time).Truncate(time.Second).String()
 
 	restartCount := int32(0)
@@ -492,23 +532,18 @@ func (s *Service) getPodMetrics(ctx context.Context, pod corev1.Pod) PodMetrics
 		restartCount += containerStatus.RestartCount
 	}
 
+	// Totals are the pod's limits, zero when it has none
+	used := usage.pod(pod.Namespace, pod.Name)
+	cpuLimit, memoryLimit := podLimits(pod)
+
 	return PodMetrics{
-		Name:      pod.Name,
-		Namespace: pod.Namespace,
-		Status:    string(pod.Status.Phase),
-		Node:      pod.Spec.NodeName,
-		CPUUsage: ResourceMetrics{
-			Used:         100, // Would come from metrics server in production
-			Total:        500,
-			Available:    400,
-			UsagePercent: 20.0,
-		},
-		MemoryUsage: ResourceMetrics{
-			Used:         128 * 1024 * 1024,
-			Total:        512 * 1024 * 1024,
-			Available:    384 * 1024 * 1024,
-			UsagePercent: 25.0,
-		},
+		Name:         pod.Name,
+		Namespace:    pod.Namespace,
+		Status:       string(pod.Status.Phase),
+		Node:         pod.Spec.NodeName,
+		CPUUsage:     newResourceMetrics(used.CPUMillicores, cpuLimit),
+		MemoryUsage:  newResourceMetrics(used.MemoryBytes, memoryLimit),
+		UsageSource:  usage.source,
 		RestartCount: restartCount,
 		Age:          age,
 		IP:           pod.Status.PodIP,
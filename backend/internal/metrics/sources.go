@@ -0,0 +1,245 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/archellir/denshimon/internal/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// UsageSource identifies where current resource usage was read from
+type UsageSource string
+
+const (
+	UsageSourceMetricsServer UsageSource = "metrics-server"
+	UsageSourcePrometheus    UsageSource = "prometheus"
+	UsageSourceKubelet       UsageSource = "kubelet"
+	// Sources of node system metrics scraped directly when Prometheus is absent
+	UsageSourceNodeExporter UsageSource = "node-exporter"
+	UsageSourceCadvisor     UsageSource = "cadvisor"
+	// UsageSourceProcfs is the host Denshimon runs on, read from /proc
+	UsageSourceProcfs UsageSource = "procfs"
+	// UsageSourceNone means no source answered; usage figures are zero rather than measured
+	UsageSourceNone UsageSource = "none"
+)
+
+// usageCacheTTL matches the default metrics-server resolution, so the WebSocket publisher
+// polling every few seconds doesn't query the sources each time
+const usageCacheTTL = 15 * time.Second
+
+// Usage is the current CPU and memory usage of a node or pod
+type Usage = prometheus.ResourceUsage
+
+// usageSnapshot is the usage of every node and pod read from one source
+type usageSnapshot struct {
+	source UsageSource
+	nodes  map[string]Usage
+	pods   map[string]Usage // keyed by namespace/name
+	// Node filesystem usage in bytes. Only kubelet summaries report it, so it's read from
+	// the kubelets whichever source provides CPU and memory.
+	storageSource UsageSource
+	storage       map[string]int64
+	readAt        time.Time
+}
+
+func (u *usageSnapshot) pod(namespace, name string) Usage {
+	return u.pods[namespace+"/"+name]
+}
+
+// usageReader reads a snapshot from one source
+type usageReader struct {
+	source UsageSource
+	read   func(ctx context.Context, nodes []corev1.Node) (*usageSnapshot, error)
+}
+
+// usageReaders returns the configured sources in order of preference: metrics-server,
+// Prometheus, then the kubelet summary API
+func (s *Service) usageReaders() []usageReader {
+	var readers []usageReader
+	if s.metricsClient != nil {
+		readers = append(readers, usageReader{UsageSourceMetricsServer, s.readMetricsServer})
+	}
+	if s.prometheusService != nil {
+		readers = append(readers, usageReader{UsageSourcePrometheus, s.readPrometheus})
+	}
+	if s.k8sClient != nil {
+		readers = append(readers, usageReader{UsageSourceKubelet, s.readKubelet})
+	}
+	return readers
+}
+
+// currentUsage returns the cached usage snapshot, reading a new one once it's older than usageCacheTTL
+func (s *Service) currentUsage(ctx context.Context, nodes []corev1.Node) *usageSnapshot {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	if s.usage != nil && time.Since(s.usage.readAt) < usageCacheTTL {
+		return s.usage
+	}
+
+	snapshot := readUsage(ctx, s.usageReaders(), nodes)
+	if snapshot.storageSource == UsageSourceNone && s.k8sClient != nil {
+		if kubelet, err := s.readKubelet(ctx, nodes); err == nil {
+			snapshot.storage = kubelet.storage
+			snapshot.storageSource = UsageSourceKubelet
+		} else {
+			slog.Debug("Node filesystem usage unavailable", "error", err)
+		}
+	}
+	s.usage = snapshot
+	return snapshot
+}
+
+// readUsage returns the snapshot of the first reader that answers with node usage. A source
+// that is up but has no data, e.g. Prometheus without cAdvisor metrics, is skipped.
+func readUsage(ctx context.Context, readers []usageReader, nodes []corev1.Node) *usageSnapshot {
+	for _, reader := range readers {
+		snapshot, err := reader.read(ctx, nodes)
+		if err != nil {
+			slog.Debug("Usage source unavailable", "source", reader.source, "error", err)
+			continue
+		}
+		if len(snapshot.nodes) == 0 {
+			slog.Debug("Usage source has no node usage", "source", reader.source)
+			continue
+		}
+		snapshot.source = reader.source
+		if snapshot.storage == nil {
+			snapshot.storageSource = UsageSourceNone
+		}
+		snapshot.readAt = time.Now()
+		return snapshot
+	}
+	return &usageSnapshot{
+		source:        UsageSourceNone,
+		nodes:         map[string]Usage{},
+		pods:          map[string]Usage{},
+		storageSource: UsageSourceNone,
+		readAt:        time.Now(),
+	}
+}
+
+func (s *Service) readMetricsServer(ctx context.Context, _ []corev1.Node) (*usageSnapshot, error) {
+	nodeMetrics, err := s.metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list node metrics: %w", err)
+	}
+	podMetrics, err := s.metricsClient.MetricsV1beta1().PodMetricses("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod metrics: %w", err)
+	}
+
+	snapshot := &usageSnapshot{
+		nodes: make(map[string]Usage, len(nodeMetrics.Items)),
+		pods:  make(map[string]Usage, len(podMetrics.Items)),
+	}
+	for _, item := range nodeMetrics.Items {
+		snapshot.nodes[item.Name] = Usage{
+			CPUMillicores: item.Usage.Cpu().MilliValue(),
+			MemoryBytes:   item.Usage.Memory().Value(),
+		}
+	}
+	for _, item := range podMetrics.Items {
+		var usage Usage
+		for _, container := range item.Containers {
+			usage.CPUMillicores += container.Usage.Cpu().MilliValue()
+			usage.MemoryBytes += container.Usage.Memory().Value()
+		}
+		snapshot.pods[item.Namespace+"/"+item.Name] = usage
+	}
+	return snapshot, nil
+}
+
+func (s *Service) readPrometheus(ctx context.Context, _ []corev1.Node) (*usageSnapshot, error) {
+	if !s.prometheusService.IsHealthy(ctx) {
+		return nil, errors.New("prometheus not reachable")
+	}
+	nodes, err := s.prometheusService.GetNodeUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := s.prometheusService.GetPodUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &usageSnapshot{nodes: nodes, pods: pods}, nil
+}
+
+func (s *Service) readKubelet(ctx context.Context, nodes []corev1.Node) (*usageSnapshot, error) {
+	return readKubeletSummaries(ctx, nodes, s.k8sClient.GetNodeStatsSummary)
+}
+
+// readKubeletSummaries scrapes the stats summary of every node. Nodes whose kubelet doesn't
+// answer are left out; it fails only when none answer.
+func readKubeletSummaries(ctx context.Context, nodes []corev1.Node, fetch func(ctx context.Context, node string) ([]byte, error)) (*usageSnapshot, error) {
+	snapshot := &usageSnapshot{
+		nodes:         make(map[string]Usage, len(nodes)),
+		pods:          make(map[string]Usage),
+		storage:       make(map[string]int64, len(nodes)),
+		storageSource: UsageSourceKubelet,
+	}
+	var lastErr error
+	for _, node := range nodes {
+		data, err := fetch(ctx, node.Name)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to read stats summary of node %s: %w", node.Name, err)
+			continue
+		}
+		var summary statsSummary
+		if err := json.Unmarshal(data, &summary); err != nil {
+			lastErr = fmt.Errorf("invalid stats summary of node %s: %w", node.Name, err)
+			continue
+		}
+
+		snapshot.nodes[node.Name] = Usage{
+			CPUMillicores: int64(summary.Node.CPU.UsageNanoCores / 1e6),
+			MemoryBytes:   int64(summary.Node.Memory.WorkingSetBytes),
+		}
+		snapshot.storage[node.Name] = int64(summary.Node.Fs.UsedBytes)
+		for _, pod := range summary.Pods {
+			snapshot.pods[pod.PodRef.Namespace+"/"+pod.PodRef.Name] = Usage{
+				CPUMillicores: int64(pod.CPU.UsageNanoCores / 1e6),
+				MemoryBytes:   int64(pod.Memory.WorkingSetBytes),
+			}
+		}
+	}
+	if len(snapshot.nodes) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return snapshot, nil
+}
+
+// statsSummary is the part of the kubelet /stats/summary response used for usage
+type statsSummary struct {
+	Node struct {
+		CPU    cpuStats    `json:"cpu"`
+		Memory memoryStats `json:"memory"`
+		Fs     fsStats     `json:"fs"`
+	} `json:"node"`
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		CPU    cpuStats    `json:"cpu"`
+		Memory memoryStats `json:"memory"`
+	} `json:"pods"`
+}
+
+type cpuStats struct {
+	UsageNanoCores uint64 `json:"usageNanoCores"`
+}
+
+type memoryStats struct {
+	WorkingSetBytes uint64 `json:"workingSetBytes"`
+}
+
+type fsStats struct {
+	UsedBytes uint64 `json:"usedBytes"`
+}
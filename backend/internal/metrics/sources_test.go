@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReadUsageFallback(t *testing.T) {
+	unavailable := usageReader{UsageSourceMetricsServer, func(context.Context, []corev1.Node) (*usageSnapshot, error) {
+		return nil, errors.New("the server could not find the requested resource")
+	}}
+	empty := usageReader{UsageSourcePrometheus, func(context.Context, []corev1.Node) (*usageSnapshot, error) {
+		return &usageSnapshot{nodes: map[string]Usage{}}, nil
+	}}
+	kubelet := usageReader{UsageSourceKubelet, func(context.Context, []corev1.Node) (*usageSnapshot, error) {
+		return &usageSnapshot{
+			nodes:         map[string]Usage{"node-1": {CPUMillicores: 250}},
+			storage:       map[string]int64{"node-1": 1 << 30},
+			storageSource: UsageSourceKubelet,
+		}, nil
+	}}
+
+	snapshot := readUsage(context.Background(), []usageReader{unavailable, empty, kubelet}, nil)
+	if snapshot.source != UsageSourceKubelet || snapshot.nodes["node-1"].CPUMillicores != 250 {
+		t.Fatalf("expected kubelet usage, got %+v", snapshot)
+	}
+	if snapshot.storageSource != UsageSourceKubelet {
+		t.Errorf("storage source = %q, want kubelet", snapshot.storageSource)
+	}
+
+	snapshot = readUsage(context.Background(), []usageReader{unavailable, empty}, nil)
+	if snapshot.source != UsageSourceNone || snapshot.storageSource != UsageSourceNone {
+		t.Errorf("expected no source, got %q/%q", snapshot.source, snapshot.storageSource)
+	}
+}
+
+func TestReadKubeletSummaries(t *testing.T) {
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+	}
+	summary := `{
+		"node": {"nodeName": "node-1", "cpu": {"usageNanoCores": 1500000000}, "memory": {"workingSetBytes": 2147483648}, "fs": {"usedBytes": 5368709120}},
+		"pods": [{"podRef": {"name": "api-0", "namespace": "apps"}, "cpu": {"usageNanoCores": 125000000}, "memory": {"workingSetBytes": 67108864}}]
+	}`
+	fetch := func(_ context.Context, node string) ([]byte, error) {
+		if node == "node-2" {
+			return nil, errors.New("node proxy unavailable")
+		}
+		return []byte(summary), nil
+	}
+
+	snapshot, err := readKubeletSummaries(context.Background(), nodes, fetch)
+	if err != nil {
+		t.Fatalf("readKubeletSummaries: %v", err)
+	}
+	if got := snapshot.nodes["node-1"]; got.CPUMillicores != 1500 || got.MemoryBytes != 2<<30 {
+		t.Errorf("node usage = %+v", got)
+	}
+	if _, ok := snapshot.nodes["node-2"]; ok {
+		t.Error("unreachable node reported")
+	}
+	if snapshot.storage["node-1"] != 5<<30 {
+		t.Errorf("node storage = %d", snapshot.storage["node-1"])
+	}
+	if got := snapshot.pod("apps", "api-0"); got.CPUMillicores != 125 || got.MemoryBytes != 64<<20 {
+		t.Errorf("pod usage = %+v", got)
+	}
+
+	if _, err := readKubeletSummaries(context.Background(), nodes[1:], fetch); err == nil {
+		t.Error("expected an error when no kubelet answers")
+	}
+}
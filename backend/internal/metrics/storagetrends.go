@@ -0,0 +1,301 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+)
+
+// Defaults for storage usage sampling
+const (
+	DefaultStorageSampleInterval = 15 * time.Minute
+	// DefaultStorageTrendWindow is how much history growth is projected from; samples older
+	// than it are dropped
+	DefaultStorageTrendWindow = 3 * 24 * time.Hour
+	// minStorageTrendSpan is the least history a projection needs, so a one-off write
+	// doesn't read as a disk filling up in hours
+	minStorageTrendSpan = time.Hour
+)
+
+// Kinds of filesystems whose usage is sampled
+const (
+	StorageKindNode   = "node"
+	StorageKindVolume = "volume"
+)
+
+// StorageSample is the usage of a node filesystem or claim volume at one point in time
+type StorageSample struct {
+	Kind          string
+	Name          string // node name, or namespace/claim for volumes
+	UsedBytes     int64
+	CapacityBytes int64
+	Timestamp     time.Time
+}
+
+// StorageTrend is the growth of one filesystem projected from its samples
+type StorageTrend struct {
+	Kind              string
+	Name              string
+	UsedBytes         int64
+	CapacityBytes     int64
+	GrowthBytesPerDay float64
+	// DaysUntilFull is negative for filesystems that aren't growing
+	DaysUntilFull float64
+}
+
+// Filling reports whether the filesystem is projected to be full within the given days
+func (t StorageTrend) Filling(days float64) bool {
+	return t.DaysUntilFull >= 0 && t.DaysUntilFull <= days
+}
+
+// InsertStorage stores a batch of storage samples in a single transaction
+func (s *UsageStore) InsertStorage(ctx context.Context, samples []StorageSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO storage_usage_samples (timestamp, kind, name, used_bytes, capacity_bytes)
+		VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, sample := range samples {
+		if _, err := stmt.ExecContext(ctx, sample.Timestamp.UnixMilli(), sample.Kind, sample.Name,
+			sample.UsedBytes, sample.CapacityBytes); err != nil {
+			return fmt.Errorf("failed to insert storage sample: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// StorageSamples returns the storage samples taken since the given time, oldest first
+func (s *UsageStore) StorageSamples(ctx context.Context, since time.Time) ([]StorageSample, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT timestamp, kind, name, used_bytes, capacity_bytes
+		FROM storage_usage_samples WHERE timestamp >= ? ORDER BY timestamp`, since.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query storage samples: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []StorageSample
+	for rows.Next() {
+		var sample StorageSample
+		var timestamp int64
+		if err := rows.Scan(&timestamp, &sample.Kind, &sample.Name, &sample.UsedBytes, &sample.CapacityBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan storage sample: %w", err)
+		}
+		sample.Timestamp = time.UnixMilli(timestamp)
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+// PruneStorage deletes storage samples older than the given time
+func (s *UsageStore) PruneStorage(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM storage_usage_samples WHERE timestamp < ?`, before.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune storage samples: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ProjectStorage fits a least-squares line through each filesystem's samples and projects
+// when it fills up from its latest usage. Filesystems with less than minStorageTrendSpan of
+// history are left out.
+func ProjectStorage(samples []StorageSample) []StorageTrend {
+	type series struct {
+		kind, name string
+		samples    []StorageSample
+	}
+	var order []string
+	byKey := make(map[string]*series)
+	for _, sample := range samples {
+		key := sample.Kind + "/" + sample.Name
+		if byKey[key] == nil {
+			byKey[key] = &series{kind: sample.Kind, name: sample.Name}
+			order = append(order, key)
+		}
+		byKey[key].samples = append(byKey[key].samples, sample)
+	}
+
+	var trends []StorageTrend
+	for _, key := range order {
+		points := byKey[key].samples
+		first, last := points[0], points[len(points)-1]
+		if len(points) < 2 || last.Timestamp.Sub(first.Timestamp) < minStorageTrendSpan {
+			continue
+		}
+
+		// Slope in bytes per day, with time measured from the first sample
+		var sumX, sumY, sumXY, sumXX float64
+		for _, point := range points {
+			x := point.Timestamp.Sub(first.Timestamp).Hours() / 24
+			y := float64(point.UsedBytes)
+			sumX += x
+			sumY += y
+			sumXY += x * y
+			sumXX += x * x
+		}
+		n := float64(len(points))
+		denominator := n*sumXX - sumX*sumX
+		if denominator == 0 {
+			continue
+		}
+		growth := (n*sumXY - sumX*sumY) / denominator
+
+		trend := StorageTrend{
+			Kind:              byKey[key].kind,
+			Name:              byKey[key].name,
+			UsedBytes:         last.UsedBytes,
+			CapacityBytes:     last.CapacityBytes,
+			GrowthBytesPerDay: growth,
+			DaysUntilFull:     -1,
+		}
+		if growth > 0 && last.CapacityBytes > 0 {
+			trend.DaysUntilFull = max(float64(last.CapacityBytes-last.UsedBytes), 0) / growth
+		}
+		trends = append(trends, trend)
+	}
+	return trends
+}
+
+// SetStorageStore sets where storage usage samples are kept for growth projections
+func (s *Service) SetStorageStore(store *UsageStore) {
+	s.storageStore = store
+}
+
+// OnStorageTrends registers a callback receiving the projections after every storage sample
+func (s *Service) OnStorageTrends(fn func(ctx context.Context, trends []StorageTrend)) {
+	s.onStorageTrends = fn
+}
+
+// StartStorageSampling records node filesystem and claim usage every interval, projecting
+// growth from the last window of samples and dropping older ones
+func (s *Service) StartStorageSampling(ctx context.Context, interval, window time.Duration) {
+	if s.storageStore == nil || s.k8sClient == nil {
+		slog.Warn("Storage usage sampling disabled, kubernetes client or usage store not available")
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultStorageSampleInterval
+	}
+	if window <= 0 {
+		window = DefaultStorageTrendWindow
+	}
+	s.storageTrendWindow = window
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := s.sampleStorage(ctx); err != nil {
+				slog.Warn("Failed to sample storage usage", "error", err)
+			}
+			if _, err := s.storageStore.PruneStorage(ctx, time.Now().Add(-window)); err != nil {
+				slog.Warn("Failed to prune storage usage samples", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *Service) sampleStorage(ctx context.Context) error {
+	usage, err := s.k8sClient.GetStorageUsage(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	samples := make([]StorageSample, 0, len(usage.Nodes)+len(usage.Volumes))
+	for _, node := range usage.Nodes {
+		samples = append(samples, StorageSample{StorageKindNode, node.Node, node.UsedBytes, node.CapacityBytes, now})
+	}
+	for _, volume := range usage.Volumes {
+		samples = append(samples, StorageSample{StorageKindVolume, volume.Namespace + "/" + volume.PVC, volume.UsedBytes, volume.CapacityBytes, now})
+	}
+	if err := s.storageStore.InsertStorage(ctx, samples); err != nil {
+		return err
+	}
+
+	if s.onStorageTrends != nil {
+		trends, err := s.StorageTrends(ctx)
+		if err != nil {
+			return err
+		}
+		s.onStorageTrends(ctx, trends)
+	}
+	return nil
+}
+
+// StorageTrends projects the growth of every sampled filesystem
+func (s *Service) StorageTrends(ctx context.Context) ([]StorageTrend, error) {
+	if s.storageStore == nil {
+		return nil, nil
+	}
+	window := s.storageTrendWindow
+	if window <= 0 {
+		window = DefaultStorageTrendWindow
+	}
+	samples, err := s.storageStore.StorageSamples(ctx, time.Now().Add(-window))
+	if err != nil {
+		return nil, err
+	}
+	return ProjectStorage(samples), nil
+}
+
+// AddStorageTrends fills in the growth and days until full of the filesystems in a storage
+// usage report that have enough history
+func (s *Service) AddStorageTrends(ctx context.Context, usage *k8s.StorageUsage) error {
+	if usage == nil || s.storageStore == nil {
+		return nil
+	}
+	trends, err := s.StorageTrends(ctx)
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string]StorageTrend, len(trends))
+	for _, trend := range trends {
+		byKey[trend.Kind+"/"+trend.Name] = trend
+	}
+
+	for i := range usage.Nodes {
+		if trend, ok := byKey[StorageKindNode+"/"+usage.Nodes[i].Node]; ok {
+			usage.Nodes[i].StorageTrend = trend.k8s()
+		}
+	}
+	for i := range usage.Volumes {
+		if trend, ok := byKey[StorageKindVolume+"/"+usage.Volumes[i].Namespace+"/"+usage.Volumes[i].PVC]; ok {
+			usage.Volumes[i].StorageTrend = trend.k8s()
+		}
+	}
+	return nil
+}
+
+func (t StorageTrend) k8s() k8s.StorageTrend {
+	growth := t.GrowthBytesPerDay
+	trend := k8s.StorageTrend{GrowthBytesPerDay: &growth}
+	if t.DaysUntilFull >= 0 {
+		days := t.DaysUntilFull
+		trend.DaysUntilFull = &days
+	}
+	return trend
+}
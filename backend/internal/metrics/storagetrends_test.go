@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestProjectStorage(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	const gib = 1 << 30
+	var samples []StorageSample
+	for day := 0; day <= 4; day++ {
+		at := start.Add(time.Duration(day) * 24 * time.Hour)
+		samples = append(samples,
+			StorageSample{StorageKindVolume, "data/db", int64(50+10*day) * gib, 200 * gib, at},
+			StorageSample{StorageKindNode, "node-1", 20 * gib, 100 * gib, at},
+		)
+	}
+	samples = append(samples, StorageSample{StorageKindNode, "node-2", 10 * gib, 100 * gib, start})
+
+	trends := ProjectStorage(samples)
+	if len(trends) != 2 {
+		t.Fatalf("expected node-2 without history left out, got %+v", trends)
+	}
+	volume := trends[0]
+	if volume.Name != "data/db" || math.Abs(volume.GrowthBytesPerDay-10*gib) > 1 {
+		t.Errorf("expected 10 GiB/day growth, got %+v", volume)
+	}
+	if math.Abs(volume.DaysUntilFull-11) > 1e-6 || !volume.Filling(14) || volume.Filling(7) {
+		t.Errorf("expected full in 11 days, got %v", volume.DaysUntilFull)
+	}
+	if node := trends[1]; node.GrowthBytesPerDay != 0 || node.DaysUntilFull >= 0 || node.Filling(30) {
+		t.Errorf("expected a flat filesystem never to fill, got %+v", node)
+	}
+}
+
+func TestStorageTrends(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store, err := NewUsageStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if err := store.InsertStorage(ctx, []StorageSample{
+		{StorageKindNode, "node-1", 10, 100, now.Add(-2 * 24 * time.Hour)},
+		{StorageKindNode, "node-1", 30, 100, now.Add(-24 * time.Hour)},
+		{StorageKindNode, "node-1", 50, 100, now},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Service{}
+	s.SetStorageStore(store)
+	usage := &k8s.StorageUsage{Nodes: []k8s.NodeFilesystemUsage{{Node: "node-1"}, {Node: "node-2"}}}
+	if err := s.AddStorageTrends(ctx, usage); err != nil {
+		t.Fatal(err)
+	}
+	node := usage.Nodes[0]
+	if node.GrowthBytesPerDay == nil || math.Abs(*node.GrowthBytesPerDay-20) > 1e-6 {
+		t.Fatalf("expected 20 bytes/day, got %+v", node.StorageTrend)
+	}
+	if node.DaysUntilFull == nil || math.Abs(*node.DaysUntilFull-2.5) > 1e-6 {
+		t.Errorf("expected full in 2.5 days, got %v", node.DaysUntilFull)
+	}
+	if usage.Nodes[1].GrowthBytesPerDay != nil {
+		t.Errorf("expected no trend without samples, got %+v", usage.Nodes[1].StorageTrend)
+	}
+
+	if pruned, err := store.PruneStorage(ctx, now.Add(-time.Hour)); err != nil || pruned != 2 {
+		t.Errorf("expected 2 samples pruned, got %d (%v)", pruned, err)
+	}
+}
@@ -0,0 +1,335 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DefaultNodeExporterSelector matches the node_exporter DaemonSets of kube-prometheus and
+	// the prometheus-node-exporter Helm chart
+	DefaultNodeExporterSelector = "app.kubernetes.io/name in (node-exporter,prometheus-node-exporter)"
+	DefaultNodeExporterPort     = 9100
+
+	// systemRateWindow is how long to wait for a second sample when a node has no recent one
+	systemRateWindow = 2 * time.Second
+	// systemSampleMaxAge is how old the previous sample of a node may be to compute rates from
+	systemSampleMaxAge = 5 * time.Minute
+)
+
+// virtualInterfacePrefixes are network interfaces left out of node throughput: loopback,
+// container veths and CNI bridges and tunnels, whose traffic also crosses the host interfaces
+var virtualInterfacePrefixes = []string{"lo", "veth", "cali", "cni", "flannel", "docker", "br-", "tunl", "vxlan", "kube-", "cilium", "lxc"}
+
+// NodeSystemMetrics are the CPU, memory, disk I/O and network throughput of a node scraped
+// from node_exporter or the kubelet's cAdvisor, for clusters without Prometheus. Rates are
+// averaged over Window.
+type NodeSystemMetrics struct {
+	Source                     UsageSource `json:"source"`
+	CPUPercent                 float64     `json:"cpu_percent"`
+	CPUCores                   float64     `json:"cpu_cores"` // cores in use
+	MemoryUsedBytes            int64       `json:"memory_used_bytes"`
+	MemoryTotalBytes           int64       `json:"memory_total_bytes"`
+	MemoryPercent              float64     `json:"memory_percent"`
+	DiskReadBytesPerSec        float64     `json:"disk_read_bytes_per_sec"`
+	DiskWriteBytesPerSec       float64     `json:"disk_write_bytes_per_sec"`
+	NetworkReceiveBytesPerSec  float64     `json:"network_receive_bytes_per_sec"`
+	NetworkTransmitBytesPerSec float64     `json:"network_transmit_bytes_per_sec"`
+	WindowSeconds              float64     `json:"window_seconds"`
+	Timestamp                  time.Time   `json:"timestamp"`
+}
+
+// systemSample holds the counters of one scrape; rates come from two of them
+type systemSample struct {
+	source             UsageSource
+	cpuSeconds         float64 // busy core-seconds
+	cpuCores           float64 // 0 when the scrape doesn't tell
+	memoryUsed         float64
+	memoryTotal        float64 // 0 when the scrape doesn't tell
+	diskRead           float64
+	diskWritten        float64
+	networkReceived    float64
+	networkTransmitted float64
+	at                 time.Time
+}
+
+// SetNodeExporter sets how node_exporter pods are found. An empty selector scrapes the
+// kubelet's cAdvisor only.
+func (s *Service) SetNodeExporter(selector string, port int) {
+	s.nodeExporterSelector = selector
+	s.nodeExporterPort = port
+}
+
+// prometheusAvailable reports whether node system metrics come from Prometheus, so there's
+// no need to scrape the nodes directly
+func (s *Service) prometheusAvailable(ctx context.Context) bool {
+	return s.prometheusService != nil && s.prometheusService.IsHealthy(ctx)
+}
+
+// nodeSystemMetrics scrapes a node and computes its rates against the previous scrape. When
+// there is no recent one, it scrapes again after systemRateWindow.
+func (s *Service) nodeSystemMetrics(ctx context.Context, node corev1.Node) (*NodeSystemMetrics, error) {
+	current, err := s.scrapeNodeSystem(ctx, node.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.systemMu.Lock()
+	previous, ok := s.systemSamples[node.Name]
+	s.systemMu.Unlock()
+	if !ok || previous.source != current.source || current.at.Sub(previous.at) > systemSampleMaxAge {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(systemRateWindow):
+		}
+		previous = current
+		if current, err = s.scrapeNodeSystem(ctx, node.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	s.systemMu.Lock()
+	if s.systemSamples == nil {
+		s.systemSamples = make(map[string]systemSample)
+	}
+	s.systemSamples[node.Name] = current
+	s.systemMu.Unlock()
+
+	cores := float64(node.Status.Capacity.Cpu().MilliValue()) / 1000
+	memory := float64(node.Status.Capacity.Memory().Value())
+	return systemRates(previous, current, cores, memory), nil
+}
+
+// scrapeNodeSystem reads node_exporter on the node when configured, falling back to cAdvisor
+func (s *Service) scrapeNodeSystem(ctx context.Context, node string) (systemSample, error) {
+	if s.nodeExporterSelector != "" {
+		data, err := s.k8sClient.GetNodeExporterMetrics(ctx, node, s.nodeExporterSelector, s.nodeExporterPort)
+		if err == nil {
+			var sample systemSample
+			if sample, err = parseNodeExporter(data); err == nil {
+				return sample, nil
+			}
+		}
+		slog.Debug("node_exporter unavailable, falling back to cAdvisor", "node", node, "error", err)
+	}
+
+	data, err := s.k8sClient.GetNodeCadvisorMetrics(ctx, node)
+	if err != nil {
+		return systemSample{}, fmt.Errorf("failed to read cAdvisor metrics of node %s: %w", node, err)
+	}
+	return parseCadvisor(data)
+}
+
+// systemRates computes node system metrics from two samples. Capacity fills in the cores and
+// memory a sample doesn't report; counters that went down (a restart) count as no change.
+func systemRates(previous, current systemSample, capacityCores, capacityMemory float64) *NodeSystemMetrics {
+	window := current.at.Sub(previous.at).Seconds()
+	rate := func(before, after float64) float64 {
+		if window <= 0 || after < before {
+			return 0
+		}
+		return (after - before) / window
+	}
+
+	metrics := &NodeSystemMetrics{
+		Source:                     current.source,
+		CPUCores:                   rate(previous.cpuSeconds, current.cpuSeconds),
+		MemoryUsedBytes:            int64(current.memoryUsed),
+		MemoryTotalBytes:           int64(current.memoryTotal),
+		DiskReadBytesPerSec:        rate(previous.diskRead, current.diskRead),
+		DiskWriteBytesPerSec:       rate(previous.diskWritten, current.diskWritten),
+		NetworkReceiveBytesPerSec:  rate(previous.networkReceived, current.networkReceived),
+		NetworkTransmitBytesPerSec: rate(previous.networkTransmitted, current.networkTransmitted),
+		WindowSeconds:              window,
+		Timestamp:                  current.at,
+	}
+	cores := current.cpuCores
+	if cores == 0 {
+		cores = capacityCores
+	}
+	if cores > 0 {
+		metrics.CPUPercent = metrics.CPUCores / cores * 100
+	}
+	if metrics.MemoryTotalBytes == 0 {
+		metrics.MemoryTotalBytes = int64(capacityMemory)
+	}
+	if metrics.MemoryTotalBytes > 0 {
+		metrics.MemoryPercent = float64(metrics.MemoryUsedBytes) / float64(metrics.MemoryTotalBytes) * 100
+	}
+	return metrics
+}
+
+// parseNodeExporter reads the node_exporter counters; CPU time in the idle and iowait modes
+// isn't busy time
+func parseNodeExporter(data []byte) (systemSample, error) {
+	sample := systemSample{source: UsageSourceNodeExporter, at: time.Now()}
+	cpus := make(map[string]bool)
+	var memoryAvailable float64
+	err := parseTextMetrics(data, func(name string, labels map[string]string, value float64) {
+		switch name {
+		case "node_cpu_seconds_total":
+			cpus[labels["cpu"]] = true
+			if mode := labels["mode"]; mode != "idle" && mode != "iowait" {
+				sample.cpuSeconds += value
+			}
+		case "node_memory_MemTotal_bytes":
+			sample.memoryTotal = value
+		case "node_memory_MemAvailable_bytes":
+			memoryAvailable = value
+		case "node_disk_read_bytes_total":
+			sample.diskRead += value
+		case "node_disk_written_bytes_total":
+			sample.diskWritten += value
+		case "node_network_receive_bytes_total":
+			if !virtualInterface(labels["device"]) {
+				sample.networkReceived += value
+			}
+		case "node_network_transmit_bytes_total":
+			if !virtualInterface(labels["device"]) {
+				sample.networkTransmitted += value
+			}
+		}
+	})
+	if err != nil {
+		return systemSample{}, err
+	}
+	if len(cpus) == 0 {
+		return systemSample{}, fmt.Errorf("no node_cpu_seconds_total in node_exporter metrics")
+	}
+	sample.cpuCores = float64(len(cpus))
+	sample.memoryUsed = sample.memoryTotal - memoryAvailable
+	return sample, nil
+}
+
+// parseCadvisor reads the counters of the root cgroup (id="/"), which covers the whole node
+func parseCadvisor(data []byte) (systemSample, error) {
+	sample := systemSample{source: UsageSourceCadvisor, at: time.Now()}
+	found := false
+	err := parseTextMetrics(data, func(name string, labels map[string]string, value float64) {
+		if labels["id"] != "/" {
+			return
+		}
+		switch name {
+		case "container_cpu_usage_seconds_total":
+			sample.cpuSeconds += value
+			found = true
+		case "container_memory_working_set_bytes":
+			sample.memoryUsed = value
+		case "container_fs_reads_bytes_total":
+			sample.diskRead += value
+		case "container_fs_writes_bytes_total":
+			sample.diskWritten += value
+		case "container_network_receive_bytes_total":
+			if !virtualInterface(labels["interface"]) {
+				sample.networkReceived += value
+			}
+		case "container_network_transmit_bytes_total":
+			if !virtualInterface(labels["interface"]) {
+				sample.networkTransmitted += value
+			}
+		}
+	})
+	if err != nil {
+		return systemSample{}, err
+	}
+	if !found {
+		return systemSample{}, fmt.Errorf("no root cgroup CPU usage in cAdvisor metrics")
+	}
+	return sample, nil
+}
+
+func virtualInterface(name string) bool {
+	for _, prefix := range virtualInterfacePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTextMetrics calls fn for every sample in the Prometheus text exposition format.
+// Comments, timestamps and unparsable values are skipped.
+func parseTextMetrics(data []byte, fn func(name string, labels map[string]string, value float64)) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		name, rest := line, ""
+		labels := map[string]string{}
+		if i := strings.IndexAny(line, "{ "); i >= 0 {
+			name, rest = line[:i], line[i:]
+		}
+		if strings.HasPrefix(rest, "{") {
+			var err error
+			if labels, rest, err = parseLabels(rest[1:]); err != nil {
+				return fmt.Errorf("invalid metric line %q: %w", line, err)
+			}
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		fn(name, labels, value)
+	}
+	return scanner.Err()
+}
+
+// parseLabels reads name="value" pairs up to the closing brace and returns what follows it
+func parseLabels(s string) (map[string]string, string, error) {
+	labels := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " ,")
+		if strings.HasPrefix(s, "}") {
+			return labels, s[1:], nil
+		}
+		eq := strings.Index(s, `="`)
+		if eq < 0 {
+			return nil, "", fmt.Errorf("unterminated labels")
+		}
+		name := strings.TrimSpace(s[:eq])
+		s = s[eq+2:]
+
+		var value strings.Builder
+		closed := false
+		for i := 0; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == '\\' && i+1 < len(s):
+				i++
+				if s[i] == 'n' {
+					value.WriteByte('\n')
+				} else {
+					value.WriteByte(s[i])
+				}
+			case c == '"':
+				s, closed = s[i+1:], true
+			default:
+				value.WriteByte(c)
+			}
+			if closed {
+				break
+			}
+		}
+		if !closed {
+			return nil, "", fmt.Errorf("unterminated label value")
+		}
+		labels[name] = value.String()
+	}
+}
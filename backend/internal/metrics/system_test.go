@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const nodeExporterMetrics = `# HELP node_cpu_seconds_total Seconds the CPUs spent in each mode.
+# TYPE node_cpu_seconds_total counter
+node_cpu_seconds_total{cpu="0",mode="idle"} 1000
+node_cpu_seconds_total{cpu="0",mode="iowait"} 50
+node_cpu_seconds_total{cpu="0",mode="user"} 300
+node_cpu_seconds_total{cpu="1",mode="idle"} 1200
+node_cpu_seconds_total{cpu="1",mode="system"} 100
+node_memory_MemTotal_bytes 8.589934592e+09
+node_memory_MemAvailable_bytes 6.442450944e+09
+node_disk_read_bytes_total{device="sda"} 1000
+node_disk_written_bytes_total{device="sda"} 2000
+node_network_receive_bytes_total{device="eth0"} 5000
+node_network_receive_bytes_total{device="lo"} 90000
+node_network_transmit_bytes_total{device="eth0"} 3000
+node_network_transmit_bytes_total{device="veth1a2b"} 70000
+`
+
+func TestParseNodeExporter(t *testing.T) {
+	sample, err := parseNodeExporter([]byte(nodeExporterMetrics))
+	if err != nil {
+		t.Fatalf("parseNodeExporter: %v", err)
+	}
+	if sample.cpuSeconds != 400 || sample.cpuCores != 2 {
+		t.Errorf("expected 400 busy seconds on 2 cores, got %v on %v", sample.cpuSeconds, sample.cpuCores)
+	}
+	if sample.memoryUsed != 2<<30 || sample.memoryTotal != 8<<30 {
+		t.Errorf("unexpected memory %v of %v", sample.memoryUsed, sample.memoryTotal)
+	}
+	if sample.networkReceived != 5000 || sample.networkTransmitted != 3000 {
+		t.Errorf("expected virtual interfaces left out, got %v/%v", sample.networkReceived, sample.networkTransmitted)
+	}
+
+	if _, err := parseNodeExporter([]byte("up 1\n")); err == nil {
+		t.Error("expected metrics without CPU time to be rejected")
+	}
+}
+
+func TestParseCadvisor(t *testing.T) {
+	data := `container_cpu_usage_seconds_total{container="",id="/",image="",name=""} 120.5 1700000000000
+container_cpu_usage_seconds_total{container="api",id="/kubepods/pod1/abc",image="api:1"} 30
+container_memory_working_set_bytes{id="/"} 1.073741824e+09
+container_fs_reads_bytes_total{device="/dev/sda",id="/"} 400
+container_fs_writes_bytes_total{device="/dev/sda",id="/"} 800
+container_network_receive_bytes_total{id="/",interface="ens3"} 100
+container_network_receive_bytes_total{id="/",interface="cni0"} 900
+container_network_transmit_bytes_total{id="/",interface="ens3"} 50
+`
+	sample, err := parseCadvisor([]byte(data))
+	if err != nil {
+		t.Fatalf("parseCadvisor: %v", err)
+	}
+	if sample.cpuSeconds != 120.5 || sample.memoryUsed != 1<<30 || sample.diskWritten != 800 {
+		t.Errorf("unexpected root cgroup sample %+v", sample)
+	}
+	if sample.networkReceived != 100 || sample.networkTransmitted != 50 {
+		t.Errorf("expected host interfaces only, got %v/%v", sample.networkReceived, sample.networkTransmitted)
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, rest, err := parseLabels(`path="C:\\data",msg="say \"hi\"",} 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels["path"] != `C:\data` || labels["msg"] != `say "hi"` || rest != " 1" {
+		t.Errorf("unexpected labels %v, rest %q", labels, rest)
+	}
+}
+
+func TestSystemRates(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	previous := systemSample{source: UsageSourceCadvisor, cpuSeconds: 100, memoryUsed: 1 << 30, diskRead: 1000, networkReceived: 5000, networkTransmitted: 9000, at: start}
+	current := systemSample{source: UsageSourceCadvisor, cpuSeconds: 110, memoryUsed: 1 << 30, diskRead: 3000, networkReceived: 25000, networkTransmitted: 100, at: start.Add(10 * time.Second)}
+
+	metrics := systemRates(previous, current, 4, 4<<30)
+	if metrics.CPUCores != 1 || math.Abs(metrics.CPUPercent-25) > 1e-9 {
+		t.Errorf("expected 1 core, 25%%, got %v cores, %v%%", metrics.CPUCores, metrics.CPUPercent)
+	}
+	if metrics.MemoryTotalBytes != 4<<30 || metrics.MemoryPercent != 25 {
+		t.Errorf("expected capacity to fill in total memory, got %d (%v%%)", metrics.MemoryTotalBytes, metrics.MemoryPercent)
+	}
+	if metrics.DiskReadBytesPerSec != 200 || metrics.NetworkReceiveBytesPerSec != 2000 {
+		t.Errorf("unexpected rates %+v", metrics)
+	}
+	if metrics.NetworkTransmitBytesPerSec != 0 {
+		t.Errorf("expected a reset counter to count as no change, got %v", metrics.NetworkTransmitBytesPerSec)
+	}
+	if metrics.WindowSeconds != 10 || metrics.Source != UsageSourceCadvisor {
+		t.Errorf("unexpected window %v or source %q", metrics.WindowSeconds, metrics.Source)
+	}
+}
@@ -0,0 +1,189 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SourceStatus reports whether a usage source answered with data
+type SourceStatus struct {
+	Source    UsageSource `json:"source"`
+	Available bool        `json:"available"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// Capabilities describes which metrics sources are live, so clients can tell measured
+// figures from missing ones
+type Capabilities struct {
+	// Sources lists the usage sources in order of preference
+	Sources []SourceStatus `json:"sources"`
+	// UsageSource provides CPU and memory usage; none when no source answered
+	UsageSource UsageSource `json:"usage_source"`
+	// StorageSource provides node filesystem usage
+	StorageSource UsageSource `json:"storage_source"`
+	// History is true when Prometheus can answer range queries for history, network and storage metrics
+	History bool `json:"history"`
+}
+
+// GetCapabilities probes every usage source, bypassing the usage cache
+func (s *Service) GetCapabilities(ctx context.Context) (*Capabilities, error) {
+	if s.k8sClient == nil {
+		return nil, errors.New("kubernetes client not available")
+	}
+
+	nodes, err := s.k8sClient.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	capabilities := &Capabilities{
+		Sources:       []SourceStatus{},
+		UsageSource:   UsageSourceNone,
+		StorageSource: UsageSourceNone,
+		History:       s.prometheusService != nil && s.prometheusService.IsHealthy(ctx),
+	}
+	for _, reader := range s.usageReaders() {
+		status := SourceStatus{Source: reader.source}
+		snapshot, err := reader.read(ctx, nodes.Items)
+		switch {
+		case err != nil:
+			status.Error = err.Error()
+		case len(snapshot.nodes) == 0:
+			status.Error = "no node usage reported"
+		default:
+			status.Available = true
+			if capabilities.UsageSource == UsageSourceNone {
+				capabilities.UsageSource = reader.source
+			}
+			if snapshot.storage != nil {
+				capabilities.StorageSource = reader.source
+			}
+		}
+		capabilities.Sources = append(capabilities.Sources, status)
+	}
+	return capabilities, nil
+}
+
+// NodeUsage is one row of kubectl top nodes. Percentages are of allocatable resources.
+type NodeUsage struct {
+	Name          string  `json:"name"`
+	CPUMillicores int64   `json:"cpu_millicores"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryBytes   int64   `json:"memory_bytes"`
+	MemoryPercent float64 `json:"memory_percent"`
+}
+
+// PodUsage is one row of kubectl top pods
+type PodUsage struct {
+	Name          string `json:"name"`
+	Namespace     string `json:"namespace"`
+	CPUMillicores int64  `json:"cpu_millicores"`
+	MemoryBytes   int64  `json:"memory_bytes"`
+}
+
+// TopNodes is the current usage of every node and the source it was read from
+type TopNodes struct {
+	Source UsageSource `json:"source"`
+	Nodes  []NodeUsage `json:"nodes"`
+}
+
+// TopPods is the current usage of the pods in a namespace and the source it was read from
+type TopPods struct {
+	Source UsageSource `json:"source"`
+	Pods   []PodUsage  `json:"pods"`
+}
+
+// GetTopNodes returns node usage like kubectl top nodes, sorted by sortBy ("cpu" or
+// "memory"; name otherwise)
+func (s *Service) GetTopNodes(ctx context.Context, sortBy string) (*TopNodes, error) {
+	if s.k8sClient == nil {
+		return nil, errors.New("kubernetes client not available")
+	}
+
+	nodes, err := s.k8sClient.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	usage := s.currentUsage(ctx, nodes.Items)
+
+	top := &TopNodes{Source: usage.source, Nodes: make([]NodeUsage, 0, len(nodes.Items))}
+	for _, node := range nodes.Items {
+		used := usage.nodes[node.Name]
+		cpu := node.Status.Allocatable[corev1.ResourceCPU]
+		memory := node.Status.Allocatable[corev1.ResourceMemory]
+		top.Nodes = append(top.Nodes, NodeUsage{
+			Name:          node.Name,
+			CPUMillicores: used.CPUMillicores,
+			CPUPercent:    percent(used.CPUMillicores, cpu.MilliValue()),
+			MemoryBytes:   used.MemoryBytes,
+			MemoryPercent: percent(used.MemoryBytes, memory.Value()),
+		})
+	}
+	sortUsage(top.Nodes, sortBy, func(n NodeUsage) (string, int64, int64) {
+		return n.Name, n.CPUMillicores, n.MemoryBytes
+	})
+	return top, nil
+}
+
+// GetTopPods returns pod usage like kubectl top pods for a namespace, or all namespaces when
+// empty, sorted like GetTopNodes. Pods without usage, e.g. ones still starting, are left out.
+func (s *Service) GetTopPods(ctx context.Context, namespace, sortBy string) (*TopPods, error) {
+	if s.k8sClient == nil {
+		return nil, errors.New("kubernetes client not available")
+	}
+
+	nodes, err := s.k8sClient.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	pods, err := s.k8sClient.ListPods(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	usage := s.currentUsage(ctx, nodes.Items)
+
+	top := &TopPods{Source: usage.source, Pods: []PodUsage{}}
+	for _, pod := range pods.Items {
+		used, ok := usage.pods[pod.Namespace+"/"+pod.Name]
+		if !ok {
+			continue
+		}
+		top.Pods = append(top.Pods, PodUsage{
+			Name:          pod.Name,
+			Namespace:     pod.Namespace,
+			CPUMillicores: used.CPUMillicores,
+			MemoryBytes:   used.MemoryBytes,
+		})
+	}
+	sortUsage(top.Pods, sortBy, func(p PodUsage) (string, int64, int64) {
+		return p.Namespace + "/" + p.Name, p.CPUMillicores, p.MemoryBytes
+	})
+	return top, nil
+}
+
+// sortUsage sorts rows by descending cpu or memory usage, or by name
+func sortUsage[T any](rows []T, sortBy string, key func(T) (name string, cpu, memory int64)) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		nameI, cpuI, memoryI := key(rows[i])
+		nameJ, cpuJ, memoryJ := key(rows[j])
+		switch {
+		case sortBy == "cpu" && cpuI != cpuJ:
+			return cpuI > cpuJ
+		case sortBy == "memory" && memoryI != memoryJ:
+			return memoryI > memoryJ
+		default:
+			return nameI < nameJ
+		}
+	})
+}
+
+func percent(used, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(used) / float64(total) * 100
+}
@@ -0,0 +1,233 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Defaults for container usage sampling
+const (
+	DefaultUsageSampleInterval = 5 * time.Minute
+	DefaultUsageRetention      = 7 * 24 * time.Hour
+)
+
+// UsageSample is the CPU and memory used by one container at one point in time
+type UsageSample struct {
+	Namespace     string
+	WorkloadKind  string // Deployment, StatefulSet, DaemonSet, Job, ... or Pod for unmanaged pods
+	Workload      string
+	Pod           string
+	Container     string
+	CPUMillicores int64
+	MemoryBytes   int64
+	Timestamp     time.Time
+}
+
+// UsageStore persists container and storage usage samples in SQLite
+type UsageStore struct {
+	db *sql.DB
+}
+
+// NewUsageStore creates a usage store and its tables
+func NewUsageStore(db *sql.DB) (*UsageStore, error) {
+	store := &UsageStore{db: db}
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS container_usage_samples (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp INTEGER NOT NULL, -- unix milliseconds
+			namespace TEXT NOT NULL,
+			workload_kind TEXT NOT NULL,
+			workload TEXT NOT NULL,
+			pod TEXT NOT NULL,
+			container TEXT NOT NULL,
+			cpu_millicores INTEGER NOT NULL,
+			memory_bytes INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_container_usage_timestamp ON container_usage_samples(timestamp)`,
+		`CREATE INDEX IF NOT EXISTS idx_container_usage_workload ON container_usage_samples(namespace, workload_kind, workload, timestamp)`,
+		`CREATE TABLE IF NOT EXISTS storage_usage_samples (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp INTEGER NOT NULL, -- unix milliseconds
+			kind TEXT NOT NULL, -- node or volume
+			name TEXT NOT NULL,
+			used_bytes INTEGER NOT NULL,
+			capacity_bytes INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_storage_usage_timestamp ON storage_usage_samples(timestamp)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			return nil, fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+	return store, nil
+}
+
+// Insert stores a batch of samples in a single transaction
+func (s *UsageStore) Insert(ctx context.Context, samples []UsageSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO container_usage_samples (timestamp, namespace, workload_kind, workload, pod, container, cpu_millicores, memory_bytes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, sample := range samples {
+		if _, err := stmt.ExecContext(ctx, sample.Timestamp.UnixMilli(), sample.Namespace, sample.WorkloadKind, sample.Workload,
+			sample.Pod, sample.Container, sample.CPUMillicores, sample.MemoryBytes); err != nil {
+			return fmt.Errorf("failed to insert usage sample: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Samples returns the samples taken since the given time, in one namespace or all namespaces for ""
+func (s *UsageStore) Samples(ctx context.Context, namespace string, since time.Time) ([]UsageSample, error) {
+	query := `SELECT timestamp, namespace, workload_kind, workload, pod, container, cpu_millicores, memory_bytes
+		FROM container_usage_samples WHERE timestamp >= ?`
+	args := []interface{}{since.UnixMilli()}
+	if namespace != "" {
+		query += ` AND namespace = ?`
+		args = append(args, namespace)
+	}
+	query += ` ORDER BY timestamp`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage samples: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []UsageSample
+	for rows.Next() {
+		var sample UsageSample
+		var timestamp int64
+		if err := rows.Scan(&timestamp, &sample.Namespace, &sample.WorkloadKind, &sample.Workload,
+			&sample.Pod, &sample.Container, &sample.CPUMillicores, &sample.MemoryBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan usage sample: %w", err)
+		}
+		sample.Timestamp = time.UnixMilli(timestamp)
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+// Prune deletes samples older than the given time
+func (s *UsageStore) Prune(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM container_usage_samples WHERE timestamp < ?`, before.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune usage samples: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// SetUsageStore sets where container usage samples are kept for recommendations
+func (s *Service) SetUsageStore(store *UsageStore) {
+	s.usageStore = store
+}
+
+// StartUsageSampling records container usage from metrics-server every interval
+// and drops samples older than retention
+func (s *Service) StartUsageSampling(ctx context.Context, interval, retention time.Duration) {
+	if s.usageStore == nil || s.metricsClient == nil {
+		slog.Warn("Container usage sampling disabled, metrics-server or usage store not available")
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultUsageSampleInterval
+	}
+	if retention <= 0 {
+		retention = DefaultUsageRetention
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := s.sampleUsage(ctx); err != nil {
+				slog.Warn("Failed to sample container usage", "error", err)
+			}
+			if _, err := s.usageStore.Prune(ctx, time.Now().Add(-retention)); err != nil {
+				slog.Warn("Failed to prune container usage samples", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// sampleUsage stores the current usage of every container, attributed to the pod's workload
+func (s *Service) sampleUsage(ctx context.Context) error {
+	podMetrics, err := s.metricsClient.MetricsV1beta1().PodMetricses("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list pod metrics: %w", err)
+	}
+	pods, err := s.k8sClient.ListPods(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+	podsByName := make(map[string]*corev1.Pod, len(pods.Items))
+	for i := range pods.Items {
+		podsByName[pods.Items[i].Namespace+"/"+pods.Items[i].Name] = &pods.Items[i]
+	}
+
+	var samples []UsageSample
+	for _, item := range podMetrics.Items {
+		pod, ok := podsByName[item.Namespace+"/"+item.Name]
+		if !ok {
+			continue
+		}
+		kind, name := podWorkload(pod)
+		for _, container := range item.Containers {
+			samples = append(samples, UsageSample{
+				Namespace:     item.Namespace,
+				WorkloadKind:  kind,
+				Workload:      name,
+				Pod:           item.Name,
+				Container:     container.Name,
+				CPUMillicores: container.Usage.Cpu().MilliValue(),
+				MemoryBytes:   container.Usage.Memory().Value(),
+				Timestamp:     item.Timestamp.Time,
+			})
+		}
+	}
+	return s.usageStore.Insert(ctx, samples)
+}
+
+// podWorkload returns the kind and name of the workload that owns a pod. Pods of a
+// ReplicaSet are attributed to its Deployment using the pod-template-hash label.
+func podWorkload(pod *corev1.Pod) (string, string) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "Pod", pod.Name
+	}
+	if owner.Kind == "ReplicaSet" {
+		if hash := pod.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+			return "Deployment", strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	}
+	return owner.Kind, owner.Name
+}
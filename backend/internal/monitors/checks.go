@@ -0,0 +1,317 @@
+package monitors
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// maxKeywordBody bounds how much of an HTTP response is searched for the keyword
+const maxKeywordBody = 1 << 20
+
+// Run performs a single check of the monitor
+func Run(ctx context.Context, monitor Monitor) Result {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(monitor.Timeout)*time.Second)
+	defer cancel()
+
+	result := Result{MonitorID: monitor.ID, Timestamp: time.Now()}
+	var err error
+	switch monitor.Type {
+	case TypeHTTP:
+		result.StatusCode, err = checkHTTP(ctx, monitor)
+	case TypeTCP:
+		err = checkTCP(ctx, monitor.Target)
+	case TypeICMP:
+		err = checkICMP(ctx, monitor.Target)
+	case TypeDNS:
+		err = checkDNS(ctx, monitor)
+	case TypeSMTP:
+		result.StatusCode, err = checkSMTP(ctx, monitor)
+	default:
+		err = fmt.Errorf("unknown monitor type %q", monitor.Type)
+	}
+	result.LatencyMs = time.Since(result.Timestamp).Milliseconds()
+	result.Up = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// httpClient does not follow redirects, so a 3xx can be expected explicitly
+var httpClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func checkHTTP(ctx context.Context, monitor Monitor) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, monitor.Target, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "denshimon-monitor")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if monitor.ExpectedStatus != 0 {
+		if resp.StatusCode != monitor.ExpectedStatus {
+			return resp.StatusCode, fmt.Errorf("status %d, expected %d", resp.StatusCode, monitor.ExpectedStatus)
+		}
+	} else if resp.StatusCode >= 400 {
+		return resp.StatusCode, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	if monitor.Keyword != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxKeywordBody))
+		if err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to read body: %w", err)
+		}
+		if !bytes.Contains(body, []byte(monitor.Keyword)) {
+			return resp.StatusCode, fmt.Errorf("keyword %q not found in response", monitor.Keyword)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+func checkTCP(ctx context.Context, address string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// checkICMP sends one echo request. It uses unprivileged ping sockets where the
+// kernel allows them (net.ipv4.ping_group_range) and raw sockets otherwise.
+func checkICMP(ctx context.Context, host string) error {
+	addr, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	if len(addr) == 0 {
+		return fmt.Errorf("no address for %s", host)
+	}
+	ip := addr[0].IP
+
+	network, rawNetwork, listen := "udp4", "ip4:icmp", "0.0.0.0"
+	var echoType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	protocol := 1 // ICMP for IPv4
+	if ip.To4() == nil {
+		network, rawNetwork, listen = "udp6", "ip6:ipv6-icmp", "::"
+		echoType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+		protocol = 58 // ICMPv6
+	}
+
+	var destination net.Addr = &net.UDPAddr{IP: ip}
+	raw := false
+	conn, err := icmp.ListenPacket(network, listen)
+	if err != nil {
+		if conn, err = icmp.ListenPacket(rawNetwork, listen); err != nil {
+			return fmt.Errorf("icmp not permitted: %w", err)
+		}
+		destination = &net.IPAddr{IP: ip}
+		raw = true
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	id := os.Getpid() & 0xffff
+	message := icmp.Message{
+		Type: echoType,
+		Body: &icmp.Echo{ID: id, Seq: 1, Data: []byte("denshimon")},
+	}
+	data, err := message.Marshal(nil)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.WriteTo(data, destination); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(reply)
+		if err != nil {
+			return fmt.Errorf("no echo reply: %w", err)
+		}
+		parsed, err := icmp.ParseMessage(protocol, reply[:n])
+		if err != nil || parsed.Type != replyType {
+			continue
+		}
+		// Raw sockets see every echo reply on the host; ping sockets only their own
+		if echo, ok := parsed.Body.(*icmp.Echo); ok && (!raw || echo.ID == id) {
+			return nil
+		}
+	}
+}
+
+// checkDNS resolves the monitor's record with each resolver. Every resolver must answer,
+// and with all expected values when there are any, so a record only counts as propagated
+// once all of them agree.
+func checkDNS(ctx context.Context, monitor Monitor) error {
+	resolvers := monitor.Resolvers
+	if len(resolvers) == 0 {
+		resolvers = []string{""} // the system resolver
+	}
+
+	failures := make([]string, len(resolvers))
+	var wg sync.WaitGroup
+	for i, address := range resolvers {
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			resolver := net.DefaultResolver
+			if address != "" {
+				resolver = &net.Resolver{
+					PreferGo: true,
+					Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+						var dialer net.Dialer
+						return dialer.DialContext(ctx, network, address)
+					},
+				}
+			}
+			if err := checkRecords(ctx, resolver, monitor); err != nil {
+				if address != "" {
+					err = fmt.Errorf("%s: %w", address, err)
+				}
+				failures[i] = err.Error()
+			}
+		}(i, address)
+	}
+	wg.Wait()
+
+	failures = slices.DeleteFunc(failures, func(failure string) bool { return failure == "" })
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// checkRecords resolves the monitor's record and compares the answer with the expected values
+func checkRecords(ctx context.Context, resolver *net.Resolver, monitor Monitor) error {
+	answer, err := lookup(ctx, resolver, monitor.RecordType, monitor.Target)
+	if err != nil {
+		return err
+	}
+	if len(answer) == 0 {
+		return fmt.Errorf("no %s records", monitor.RecordType)
+	}
+	var missing []string
+	for _, expected := range monitor.Expected {
+		if !slices.Contains(answer, normalizeRecord(expected)) {
+			missing = append(missing, expected)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("got %s, missing %s", strings.Join(answer, ", "), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func lookup(ctx context.Context, resolver *net.Resolver, recordType, name string) ([]string, error) {
+	var answer []string
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answer = append(answer, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answer = append(answer, cname)
+	case "MX":
+		records, err := resolver.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range records {
+			answer = append(answer, mx.Host)
+		}
+	case "NS":
+		records, err := resolver.LookupNS(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range records {
+			answer = append(answer, ns.Host)
+		}
+	case "TXT":
+		records, err := resolver.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answer = records
+	default:
+		return nil, fmt.Errorf("unsupported record type %q", recordType)
+	}
+
+	for i := range answer {
+		answer[i] = normalizeRecord(answer[i])
+	}
+	slices.Sort(answer)
+	return answer, nil
+}
+
+// normalizeRecord makes names comparable: lower case without the trailing dot
+func normalizeRecord(value string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), ".")
+}
+
+// checkSMTP connects to a mail server and reads its greeting, which must be a 220 reply
+// containing the keyword when one is set, then says QUIT. It returns the reply code.
+func checkSMTP(ctx context.Context, monitor Monitor) (int, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", monitor.Target)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	text := textproto.NewConn(conn)
+	code, banner, err := text.ReadResponse(220)
+	if err != nil {
+		if code != 0 {
+			return code, fmt.Errorf("banner %d %s", code, banner)
+		}
+		return 0, fmt.Errorf("no banner: %w", err)
+	}
+	text.PrintfLine("QUIT")
+
+	if monitor.Keyword != "" && !strings.Contains(banner, monitor.Keyword) {
+		return code, fmt.Errorf("keyword %q not found in banner %q", monitor.Keyword, banner)
+	}
+	return code, nil
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_monitor_results_monitor;
+DROP TABLE IF EXISTS monitor_results;
+DROP TABLE IF EXISTS monitors;
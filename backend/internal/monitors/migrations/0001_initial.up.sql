@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS monitors (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL,
+	target TEXT NOT NULL,
+	interval_seconds INTEGER NOT NULL,
+	timeout_seconds INTEGER NOT NULL,
+	expected_status INTEGER NOT NULL DEFAULT 0,
+	keyword TEXT NOT NULL DEFAULT '',
+	failure_threshold INTEGER NOT NULL DEFAULT 1,
+	paused BOOLEAN NOT NULL DEFAULT FALSE,
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL, -- unix milliseconds
+	updated_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS monitor_results (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	monitor_id TEXT NOT NULL,
+	timestamp INTEGER NOT NULL, -- unix milliseconds
+	up BOOLEAN NOT NULL,
+	latency_ms INTEGER NOT NULL,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	FOREIGN KEY (monitor_id) REFERENCES monitors(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_monitor_results_monitor ON monitor_results(monitor_id, timestamp);
@@ -0,0 +1,3 @@
+ALTER TABLE monitors DROP COLUMN resolvers;
+ALTER TABLE monitors DROP COLUMN expected;
+ALTER TABLE monitors DROP COLUMN record_type;
@@ -0,0 +1,4 @@
+-- Settings of dns monitors; expected and resolvers are JSON arrays
+ALTER TABLE monitors ADD COLUMN record_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE monitors ADD COLUMN expected TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE monitors ADD COLUMN resolvers TEXT NOT NULL DEFAULT '[]';
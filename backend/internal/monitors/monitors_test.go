@@ -0,0 +1,318 @@
+package monitors
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/archellir/denshimon/internal/bus"
+	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func setupTestService(t *testing.T) *Service {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	service, err := NewService(db)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	return service
+}
+
+func TestValidate(t *testing.T) {
+	monitor := Monitor{Name: "api", Type: TypeHTTP, Target: "https://example.com/health"}
+	if err := monitor.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if monitor.Interval != DefaultInterval || monitor.Timeout != DefaultTimeout || monitor.FailureThreshold != DefaultFailureThreshold {
+		t.Errorf("defaults not applied: %+v", monitor)
+	}
+
+	invalid := []Monitor{
+		{Type: TypeHTTP, Target: "https://example.com"},
+		{Name: "a", Type: TypeHTTP, Target: "example.com"},
+		{Name: "a", Type: TypeTCP, Target: "example.com"},
+		{Name: "a", Type: TypeTCP, Target: "example.com:5432", Keyword: "ok"},
+		{Name: "a", Type: TypeICMP},
+		{Name: "a", Type: "ftp", Target: "example.com"},
+		{Name: "a", Type: TypeDNS, Target: "https://example.com"},
+		{Name: "a", Type: TypeDNS, Target: "example.com", RecordType: "SRV"},
+		{Name: "a", Type: TypeDNS, Target: "example.com", Keyword: "ok"},
+		{Name: "a", Type: TypeSMTP},
+		{Name: "a", Type: TypeTCP, Target: "example.com:5432", Resolvers: []string{"1.1.1.1"}},
+		{Name: "a", Type: TypeHTTP, Target: "https://example.com", Interval: 5},
+		{Name: "a", Type: TypeHTTP, Target: "https://example.com", Interval: 30, Timeout: 60},
+		{Name: "a", Type: TypeHTTP, Target: "https://example.com", ExpectedStatus: 42},
+	}
+	for _, m := range invalid {
+		if err := m.Validate(); err == nil {
+			t.Errorf("expected an error for %+v", m)
+		}
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	dns := Monitor{Name: "mx", Type: TypeDNS, Target: "example.com", RecordType: "mx", Resolvers: []string{"1.1.1.1", "[2001:4860:4860::8888]:53"}}
+	if err := dns.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dns.RecordType != "MX" || dns.Resolvers[0] != "1.1.1.1:53" || dns.Resolvers[1] != "[2001:4860:4860::8888]:53" {
+		t.Errorf("dns defaults not applied: %+v", dns)
+	}
+
+	smtp := Monitor{Name: "mail", Type: TypeSMTP, Target: "mail.example.com", Keyword: "ESMTP"}
+	if err := smtp.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if smtp.Target != "mail.example.com:25" {
+		t.Errorf("expected the smtp port to default to 25, got %s", smtp.Target)
+	}
+}
+
+func TestComputeUptime(t *testing.T) {
+	results := []Result{
+		{Up: true, LatencyMs: 10},
+		{Up: false},
+		{Up: false},
+		{Up: true, LatencyMs: 30},
+	}
+	uptime := ComputeUptime("24h", results)
+	if uptime.Checks != 4 || uptime.Up != 2 || uptime.Percent != 50 || uptime.AvgLatencyMs != 20 || uptime.Incidents != 1 {
+		t.Errorf("unexpected uptime: %+v", uptime)
+	}
+	if empty := ComputeUptime("7d", nil); empty.Percent != 100 {
+		t.Errorf("expected 100%% without checks, got %+v", empty)
+	}
+}
+
+func TestRunHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("status: healthy"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		monitor Monitor
+		up      bool
+	}{
+		{"ok", Monitor{Target: server.URL}, true},
+		{"keyword found", Monitor{Target: server.URL, Keyword: "healthy"}, true},
+		{"keyword missing", Monitor{Target: server.URL, Keyword: "degraded"}, false},
+		{"error status", Monitor{Target: server.URL + "/missing"}, false},
+		{"expected status", Monitor{Target: server.URL + "/missing", ExpectedStatus: http.StatusNotFound}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.monitor.Type = TypeHTTP
+			tt.monitor.Timeout = 5
+			result := Run(context.Background(), tt.monitor)
+			if result.Up != tt.up {
+				t.Errorf("up = %v, want %v (error %q)", result.Up, tt.up, result.Error)
+			}
+			if result.StatusCode == 0 {
+				t.Error("status code not recorded")
+			}
+		})
+	}
+}
+
+func TestRunTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+
+	monitor := Monitor{Type: TypeTCP, Target: address, Timeout: 5}
+	if result := Run(context.Background(), monitor); !result.Up {
+		t.Errorf("expected up, got %q", result.Error)
+	}
+	listener.Close()
+	if result := Run(context.Background(), monitor); result.Up {
+		t.Error("expected down after the listener closed")
+	}
+}
+
+// fakeDNS answers A queries from records over UDP and returns its address
+func fakeDNS(t *testing.T, records map[string][]string) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			question := query.Questions[0]
+			reply := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true},
+				Questions: query.Questions,
+			}
+			ips, ok := records[strings.TrimSuffix(question.Name.String(), ".")]
+			if !ok {
+				reply.RCode = dnsmessage.RCodeNameError
+			}
+			if question.Type == dnsmessage.TypeA {
+				for _, ip := range ips {
+					var a [4]byte
+					copy(a[:], net.ParseIP(ip).To4())
+					reply.Answers = append(reply.Answers, dnsmessage.Resource{
+						Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+						Body:   &dnsmessage.AResource{A: a},
+					})
+				}
+			}
+			packed, err := reply.Pack()
+			if err == nil {
+				conn.WriteTo(packed, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestRunDNS(t *testing.T) {
+	propagated := fakeDNS(t, map[string][]string{"git.example.com": {"203.0.113.10", "203.0.113.11"}})
+	stale := fakeDNS(t, map[string][]string{"git.example.com": {"198.51.100.7"}})
+
+	tests := []struct {
+		name      string
+		expected  []string
+		resolvers []string
+		target    string
+		up        bool
+		error     string
+	}{
+		{"any answer", nil, []string{propagated}, "git.example.com", true, ""},
+		{"expected", []string{"203.0.113.11"}, []string{propagated}, "git.example.com", true, ""},
+		{"not propagated", []string{"203.0.113.10"}, []string{propagated, stale}, "git.example.com", false, stale + ": got 198.51.100.7, missing 203.0.113.10"},
+		{"no such name", nil, []string{propagated}, "wiki.example.com", false, propagated + ": "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := Monitor{Name: "git", Type: TypeDNS, Target: tt.target, Expected: tt.expected, Resolvers: tt.resolvers}
+			if err := monitor.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			monitor.Timeout = 5
+			result := Run(context.Background(), monitor)
+			if result.Up != tt.up || !strings.HasPrefix(result.Error, tt.error) {
+				t.Errorf("up = %v with error %q, want %v with %q", result.Up, result.Error, tt.up, tt.error)
+			}
+		})
+	}
+}
+
+func TestRunSMTP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	greeting := "220-mail.example.com ESMTP Postfix\r\n220 ready\r\n"
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(greeting))
+			bufio.NewReader(conn).ReadString('\n') // QUIT
+			conn.Close()
+		}
+	}()
+
+	monitor := Monitor{Name: "mail", Type: TypeSMTP, Target: listener.Addr().String(), Keyword: "Postfix", Timeout: 5}
+	if result := Run(context.Background(), monitor); !result.Up || result.StatusCode != 220 {
+		t.Errorf("expected up with a 220 banner, got %+v", result)
+	}
+	monitor.Keyword = "Exim"
+	if result := Run(context.Background(), monitor); result.Up {
+		t.Error("expected a missing keyword to be down")
+	}
+}
+
+func TestServiceStateChanges(t *testing.T) {
+	healthy := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	service := setupTestService(t)
+	var changes []StateChange
+	events := bus.New()
+	service.SetBus(events)
+	bus.On(events, func(change StateChange) { changes = append(changes, change) })
+
+	ctx := context.Background()
+	monitor, err := service.Create(ctx, Monitor{Name: "api", Type: TypeHTTP, Target: server.URL, FailureThreshold: 2}, "admin")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	check := func() {
+		if _, err := service.CheckNow(ctx, monitor.ID); err != nil {
+			t.Fatalf("CheckNow failed: %v", err)
+		}
+	}
+
+	check()
+	healthy = false
+	check()
+	if state := service.State(monitor.ID); state.Status != StatusUp || state.ConsecutiveFailures != 1 {
+		t.Errorf("one failure below the threshold changed the state: %+v", state)
+	}
+	check()
+	healthy = true
+	check()
+
+	if len(changes) != 2 || changes[0].Current != StatusDown || changes[1].Previous != StatusDown || changes[1].Current != StatusUp {
+		t.Fatalf("unexpected state changes: %+v", changes)
+	}
+
+	status, err := service.Get(ctx, monitor.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(status.Uptime) != len(SLAWindows) || status.Uptime[0].Checks != 4 || status.Uptime[0].Percent != 50 {
+		t.Errorf("unexpected uptime: %+v", status.Uptime)
+	}
+
+	if err := service.Delete(ctx, monitor.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := service.History(ctx, monitor.ID, time.Time{}); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+package monitors
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/bus"
+	"github.com/google/uuid"
+)
+
+// pruneInterval is how often results older than DefaultRetention are deleted
+const pruneInterval = time.Hour
+
+// Service schedules monitor checks, records their results and reports state changes
+type Service struct {
+	store   *Store
+	bus     *bus.Bus
+	mutex   sync.RWMutex
+	ctx     context.Context
+	runners map[string]context.CancelFunc
+	states  map[string]*State
+}
+
+// NewService creates a monitor service backed by the given database
+func NewService(db *sql.DB) (*Service, error) {
+	store, err := NewStore(db)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{
+		store:   store,
+		runners: make(map[string]context.CancelFunc),
+		states:  make(map[string]*State),
+	}, nil
+}
+
+// SetBus sets the event bus state changes are published on
+func (s *Service) SetBus(b *bus.Bus) {
+	s.bus = b
+}
+
+// Start begins checking every monitor that is not paused until ctx is cancelled
+func (s *Service) Start(ctx context.Context) error {
+	monitors, err := s.store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.ctx = ctx
+	for i := range monitors {
+		s.startRunner(monitors[i])
+	}
+	s.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(pruneInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.store.Prune(ctx, time.Now().Add(-DefaultRetention)); err != nil {
+					slog.Error("Failed to prune monitor results", "error", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// startRunner launches the check loop for a monitor; the caller holds the mutex
+func (s *Service) startRunner(monitor Monitor) {
+	if cancel, ok := s.runners[monitor.ID]; ok {
+		cancel()
+		delete(s.runners, monitor.ID)
+	}
+	if monitor.Paused {
+		s.states[monitor.ID] = &State{Status: StatusPaused}
+		return
+	}
+	if state, ok := s.states[monitor.ID]; !ok || state.Status == StatusPaused {
+		s.states[monitor.ID] = &State{Status: StatusPending}
+	}
+	if s.ctx == nil {
+		return // not started yet
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.runners[monitor.ID] = cancel
+	go func() {
+		ticker := time.NewTicker(time.Duration(monitor.Interval) * time.Second)
+		defer ticker.Stop()
+		for {
+			s.check(ctx, monitor)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// check runs one check, stores it and updates the monitor's state
+func (s *Service) check(ctx context.Context, monitor Monitor) Result {
+	result := Run(ctx, monitor)
+	if ctx.Err() != nil {
+		return result // monitor was changed or deleted mid-check
+	}
+	if err := s.store.AddResult(ctx, result); err != nil {
+		slog.Error("Failed to store monitor result", "monitor", monitor.Name, "error", err)
+	}
+
+	s.mutex.Lock()
+	state, ok := s.states[monitor.ID]
+	if !ok {
+		state = &State{Status: StatusPending}
+		s.states[monitor.ID] = state
+	}
+	state.LastCheck = &result
+	previous := state.Status
+	current := previous
+	if result.Up {
+		state.ConsecutiveFailures = 0
+		current = StatusUp
+	} else {
+		state.ConsecutiveFailures++
+		if state.ConsecutiveFailures >= monitor.FailureThreshold {
+			current = StatusDown
+		}
+	}
+	// Manual checks of a paused monitor are recorded without resuming it
+	changed := current != previous && previous != StatusPaused
+	if changed {
+		state.Status = current
+		state.Since = &result.Timestamp
+	}
+	s.mutex.Unlock()
+
+	// Leaving pending for up is not worth announcing; coming up from down is
+	if changed && !(previous == StatusPending && current == StatusUp) {
+		change := StateChange{
+			Monitor:   monitor,
+			Previous:  previous,
+			Current:   current,
+			Result:    result,
+			Timestamp: result.Timestamp,
+		}
+		slog.Info("Monitor state changed", "monitor", monitor.Name, "from", previous, "to", current, "error", result.Error)
+		s.bus.Publish(change)
+	}
+	return result
+}
+
+// List returns every monitor with its state and SLA uptime
+func (s *Service) List(ctx context.Context) ([]Status, error) {
+	monitors, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(monitors))
+	for _, monitor := range monitors {
+		status, err := s.status(ctx, monitor)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// Get returns one monitor with its state and SLA uptime
+func (s *Service) Get(ctx context.Context, id string) (*Status, error) {
+	monitor, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.status(ctx, *monitor)
+}
+
+func (s *Service) status(ctx context.Context, monitor Monitor) (*Status, error) {
+	longest := SLAWindows[len(SLAWindows)-1].Duration
+	now := time.Now()
+	results, err := s.store.Results(ctx, monitor.ID, now.Add(-longest))
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{Monitor: monitor, State: s.State(monitor.ID)}
+	for _, window := range SLAWindows {
+		start := now.Add(-window.Duration)
+		first := len(results)
+		for i, result := range results {
+			if !result.Timestamp.Before(start) {
+				first = i
+				break
+			}
+		}
+		status.Uptime = append(status.Uptime, ComputeUptime(window.Name, results[first:]))
+	}
+	return status, nil
+}
+
+// State returns a copy of a monitor's current state
+func (s *Service) State(id string) State {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if state, ok := s.states[id]; ok {
+		return *state
+	}
+	return State{Status: StatusPending}
+}
+
+// History returns a monitor's results since the given time
+func (s *Service) History(ctx context.Context, id string, since time.Time) ([]Result, error) {
+	if _, err := s.store.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.store.Results(ctx, id, since)
+}
+
+// Create validates and stores a new monitor and starts checking it
+func (s *Service) Create(ctx context.Context, monitor Monitor, createdBy string) (*Monitor, error) {
+	if err := monitor.Validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	monitor.ID = uuid.New().String()
+	monitor.CreatedBy = createdBy
+	monitor.CreatedAt = now
+	monitor.UpdatedAt = now
+	if err := s.store.Save(ctx, &monitor); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	s.startRunner(monitor)
+	s.mutex.Unlock()
+	return &monitor, nil
+}
+
+// Update replaces a monitor's settings and restarts its checks
+func (s *Service) Update(ctx context.Context, id string, monitor Monitor) (*Monitor, error) {
+	existing, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := monitor.Validate(); err != nil {
+		return nil, err
+	}
+	monitor.ID = existing.ID
+	monitor.CreatedBy = existing.CreatedBy
+	monitor.CreatedAt = existing.CreatedAt
+	monitor.UpdatedAt = time.Now()
+	if err := s.store.Save(ctx, &monitor); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	s.startRunner(monitor)
+	s.mutex.Unlock()
+	return &monitor, nil
+}
+
+// Delete stops and removes a monitor and its history
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if err := s.store.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	if cancel, ok := s.runners[id]; ok {
+		cancel()
+		delete(s.runners, id)
+	}
+	delete(s.states, id)
+	s.mutex.Unlock()
+	return nil
+}
+
+// CheckNow runs a monitor's check immediately, outside its schedule
+func (s *Service) CheckNow(ctx context.Context, id string) (*Result, error) {
+	monitor, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result := s.check(ctx, *monitor)
+	return &result, nil
+}
@@ -0,0 +1,175 @@
+package monitors
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/archellir/denshimon/internal/database/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations is the schema of the monitor tables
+var Migrations = migrate.NewSource("monitors", migrationFiles, "migrations")
+
+// ErrNotFound is returned for unknown monitor IDs
+var ErrNotFound = errors.New("monitor not found")
+
+// Store persists monitors and their check results in SQLite
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a monitor store, migrating its tables to the latest schema version
+func NewStore(db *sql.DB) (*Store, error) {
+	if err := migrate.Up(context.Background(), db, Migrations); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+const monitorColumns = `id, name, type, target, interval_seconds, timeout_seconds, expected_status, keyword,
+	failure_threshold, paused, created_by, created_at, updated_at, record_type, expected, resolvers`
+
+func scanMonitor(scanner interface{ Scan(...interface{}) error }) (*Monitor, error) {
+	var m Monitor
+	var createdAt, updatedAt int64
+	var expected, resolvers string
+	if err := scanner.Scan(&m.ID, &m.Name, &m.Type, &m.Target, &m.Interval, &m.Timeout, &m.ExpectedStatus,
+		&m.Keyword, &m.FailureThreshold, &m.Paused, &m.CreatedBy, &createdAt, &updatedAt,
+		&m.RecordType, &expected, &resolvers); err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(expected), &m.Expected)
+	json.Unmarshal([]byte(resolvers), &m.Resolvers)
+	m.CreatedAt = time.UnixMilli(createdAt)
+	m.UpdatedAt = time.UnixMilli(updatedAt)
+	return &m, nil
+}
+
+// List returns all monitors ordered by name
+func (s *Store) List(ctx context.Context) ([]Monitor, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+monitorColumns+` FROM monitors ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list monitors: %w", err)
+	}
+	defer rows.Close()
+
+	monitors := []Monitor{}
+	for rows.Next() {
+		monitor, err := scanMonitor(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan monitor: %w", err)
+		}
+		monitors = append(monitors, *monitor)
+	}
+	return monitors, rows.Err()
+}
+
+// Get returns one monitor
+func (s *Store) Get(ctx context.Context, id string) (*Monitor, error) {
+	monitor, err := scanMonitor(s.db.QueryRowContext(ctx, `SELECT `+monitorColumns+` FROM monitors WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monitor: %w", err)
+	}
+	return monitor, nil
+}
+
+// Save inserts or updates a monitor
+func (s *Store) Save(ctx context.Context, m *Monitor) error {
+	expected, _ := json.Marshal(stringsOrEmpty(m.Expected))
+	resolvers, _ := json.Marshal(stringsOrEmpty(m.Resolvers))
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO monitors (`+monitorColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name, type = excluded.type, target = excluded.target,
+			interval_seconds = excluded.interval_seconds, timeout_seconds = excluded.timeout_seconds,
+			expected_status = excluded.expected_status, keyword = excluded.keyword,
+			failure_threshold = excluded.failure_threshold, paused = excluded.paused,
+			updated_at = excluded.updated_at, record_type = excluded.record_type,
+			expected = excluded.expected, resolvers = excluded.resolvers`,
+		m.ID, m.Name, m.Type, m.Target, m.Interval, m.Timeout, m.ExpectedStatus, m.Keyword,
+		m.FailureThreshold, m.Paused, m.CreatedBy, m.CreatedAt.UnixMilli(), m.UpdatedAt.UnixMilli(),
+		m.RecordType, string(expected), string(resolvers))
+	if err != nil {
+		return fmt.Errorf("failed to save monitor: %w", err)
+	}
+	return nil
+}
+
+func stringsOrEmpty(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// Delete removes a monitor and its results
+func (s *Store) Delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM monitors WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete monitor: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	// Results are removed explicitly as SQLite only cascades with foreign keys enabled
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM monitor_results WHERE monitor_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete monitor results: %w", err)
+	}
+	return nil
+}
+
+// AddResult stores a check result
+func (s *Store) AddResult(ctx context.Context, r Result) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO monitor_results (monitor_id, timestamp, up, latency_ms, status_code, error)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		r.MonitorID, r.Timestamp.UnixMilli(), r.Up, r.LatencyMs, r.StatusCode, r.Error)
+	if err != nil {
+		return fmt.Errorf("failed to store monitor result: %w", err)
+	}
+	return nil
+}
+
+// Results returns a monitor's results since the given time, oldest first
+func (s *Store) Results(ctx context.Context, monitorID string, since time.Time) ([]Result, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT monitor_id, timestamp, up, latency_ms, status_code, error
+		FROM monitor_results WHERE monitor_id = ? AND timestamp >= ?
+		ORDER BY timestamp`, monitorID, since.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query monitor results: %w", err)
+	}
+	defer rows.Close()
+
+	results := []Result{}
+	for rows.Next() {
+		var r Result
+		var timestamp int64
+		if err := rows.Scan(&r.MonitorID, &timestamp, &r.Up, &r.LatencyMs, &r.StatusCode, &r.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan monitor result: %w", err)
+		}
+		r.Timestamp = time.UnixMilli(timestamp)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// Prune deletes results older than the given time
+func (s *Store) Prune(ctx context.Context, before time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM monitor_results WHERE timestamp < ?`, before.UnixMilli()); err != nil {
+		return fmt.Errorf("failed to prune monitor results: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+// Package monitors runs uptime checks against external services over HTTP, TCP, ICMP, DNS
+// and SMTP, keeps their results in SQLite and reports state changes.
+package monitors
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Check types
+const (
+	TypeHTTP = "http"
+	TypeTCP  = "tcp"
+	TypeICMP = "icmp"
+	TypeDNS  = "dns"
+	TypeSMTP = "smtp"
+)
+
+// DNS record types a dns monitor can resolve
+var RecordTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "TXT"}
+
+// Default ports of smtp targets and dns resolvers given without one
+const (
+	defaultSMTPPort = "25"
+	defaultDNSPort  = "53"
+)
+
+// Monitor states
+const (
+	StatusPending = "pending" // not checked yet
+	StatusUp      = "up"
+	StatusDown    = "down"
+	StatusPaused  = "paused"
+)
+
+// Limits and defaults for monitor settings
+const (
+	DefaultInterval         = 60 // seconds
+	MinInterval             = 10
+	DefaultTimeout          = 10 // seconds
+	DefaultFailureThreshold = 1
+	DefaultRetention        = 90 * 24 * time.Hour
+)
+
+// Monitor is a user-registered check against an external service
+type Monitor struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`   // http, tcp, icmp, dns or smtp
+	Target string `json:"target"` // URL for http, host:port for tcp and smtp, host for icmp, name for dns
+	// Interval and Timeout are in seconds
+	Interval int `json:"interval"`
+	Timeout  int `json:"timeout"`
+	// ExpectedStatus is the HTTP status required to be up; 0 accepts any 2xx or 3xx
+	ExpectedStatus int `json:"expectedStatus,omitempty"`
+	// Keyword must appear in the HTTP response body or the SMTP banner when set
+	Keyword string `json:"keyword,omitempty"`
+	// RecordType is the DNS record resolved, A by default
+	RecordType string `json:"recordType,omitempty"`
+	// Expected are values every resolver must return, e.g. IPs for A or hosts for MX;
+	// without them any answer is up
+	Expected []string `json:"expected,omitempty"`
+	// Resolvers are the nameservers asked, host or host:port; all must agree for the change
+	// to have propagated. The system resolver is used when empty.
+	Resolvers []string `json:"resolvers,omitempty"`
+	// FailureThreshold is how many consecutive failures mark the monitor down
+	FailureThreshold int `json:"failureThreshold"`
+	// Paused monitors keep their history but are not checked
+	Paused    bool      `json:"paused"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Result is the outcome of one check
+type Result struct {
+	MonitorID  string    `json:"monitorId"`
+	Timestamp  time.Time `json:"timestamp"`
+	Up         bool      `json:"up"`
+	LatencyMs  int64     `json:"latencyMs"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// State is a monitor's current status
+type State struct {
+	Status              string     `json:"status"`
+	Since               *time.Time `json:"since,omitempty"` // when the current status began
+	LastCheck           *Result    `json:"lastCheck,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+}
+
+// Uptime summarises the checks in a window
+type Uptime struct {
+	Window       string  `json:"window"`
+	Checks       int     `json:"checks"`
+	Up           int     `json:"up"`
+	Percent      float64 `json:"percent"` // 100 when there are no checks yet
+	AvgLatencyMs float64 `json:"avgLatencyMs"`
+	Incidents    int     `json:"incidents"` // transitions from up to down
+}
+
+// Status combines a monitor with its state and uptime over the SLA windows
+type Status struct {
+	Monitor
+	State  State    `json:"state"`
+	Uptime []Uptime `json:"uptime"`
+}
+
+// StateChange is published when a monitor goes up or down
+type StateChange struct {
+	Monitor   Monitor   `json:"monitor"`
+	Previous  string    `json:"previous"`
+	Current   string    `json:"current"`
+	Result    Result    `json:"result"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Topic names state changes on the event bus
+func (StateChange) Topic() string { return "monitor.state_changed" }
+
+// SLAWindows are the periods uptime is reported for
+var SLAWindows = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+// Validate fills in defaults and checks the monitor's settings
+func (m *Monitor) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if m.Interval == 0 {
+		m.Interval = DefaultInterval
+	}
+	if m.Interval < MinInterval {
+		return fmt.Errorf("interval must be at least %d seconds", MinInterval)
+	}
+	if m.Timeout == 0 {
+		m.Timeout = DefaultTimeout
+	}
+	if m.Timeout < 1 || m.Timeout > m.Interval {
+		return fmt.Errorf("timeout must be between 1 second and the interval")
+	}
+	if m.FailureThreshold == 0 {
+		m.FailureThreshold = DefaultFailureThreshold
+	}
+	if m.FailureThreshold < 1 {
+		return fmt.Errorf("failureThreshold must be at least 1")
+	}
+
+	switch m.Type {
+	case TypeHTTP:
+		u, err := url.Parse(m.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("http monitors need an http or https URL")
+		}
+		if m.ExpectedStatus != 0 && (m.ExpectedStatus < 100 || m.ExpectedStatus > 599) {
+			return fmt.Errorf("expectedStatus must be a valid HTTP status")
+		}
+	case TypeTCP:
+		if _, port, err := net.SplitHostPort(m.Target); err != nil || port == "" {
+			return fmt.Errorf("tcp monitors need a host:port target")
+		}
+	case TypeICMP:
+		if m.Target == "" {
+			return fmt.Errorf("icmp monitors need a host")
+		}
+	case TypeDNS:
+		if m.Target == "" || strings.ContainsAny(m.Target, "/: ") {
+			return fmt.Errorf("dns monitors need a domain name")
+		}
+		m.RecordType = strings.ToUpper(m.RecordType)
+		if m.RecordType == "" {
+			m.RecordType = "A"
+		}
+		if !slices.Contains(RecordTypes, m.RecordType) {
+			return fmt.Errorf("recordType must be one of %s", strings.Join(RecordTypes, ", "))
+		}
+		for i, resolver := range m.Resolvers {
+			if _, _, err := net.SplitHostPort(resolver); err != nil {
+				resolver = net.JoinHostPort(resolver, defaultDNSPort)
+			}
+			if host, _, err := net.SplitHostPort(resolver); err != nil || host == "" {
+				return fmt.Errorf("invalid resolver %q", m.Resolvers[i])
+			}
+			m.Resolvers[i] = resolver
+		}
+	case TypeSMTP:
+		if _, _, err := net.SplitHostPort(m.Target); err != nil && m.Target != "" {
+			m.Target = net.JoinHostPort(m.Target, defaultSMTPPort)
+		}
+		if host, _, err := net.SplitHostPort(m.Target); err != nil || host == "" {
+			return fmt.Errorf("smtp monitors need a host or host:port target")
+		}
+	default:
+		return fmt.Errorf("type must be http, tcp, icmp, dns or smtp")
+	}
+	if m.Type != TypeHTTP && m.ExpectedStatus != 0 {
+		return fmt.Errorf("expectedStatus only applies to http monitors")
+	}
+	if m.Type != TypeHTTP && m.Type != TypeSMTP && m.Keyword != "" {
+		return fmt.Errorf("keyword only applies to http and smtp monitors")
+	}
+	if m.Type != TypeDNS && (m.RecordType != "" || len(m.Expected) > 0 || len(m.Resolvers) > 0) {
+		return fmt.Errorf("recordType, expected and resolvers only apply to dns monitors")
+	}
+	return nil
+}
+
+// ComputeUptime summarises oldest-first results for a window
+func ComputeUptime(window string, results []Result) Uptime {
+	uptime := Uptime{Window: window, Checks: len(results), Percent: 100}
+	var latency int64
+	wasUp := true
+	for _, result := range results {
+		if result.Up {
+			uptime.Up++
+			latency += result.LatencyMs
+		} else if wasUp {
+			uptime.Incidents++
+		}
+		wasUp = result.Up
+	}
+	if uptime.Checks > 0 {
+		uptime.Percent = float64(uptime.Up) / float64(uptime.Checks) * 100
+	}
+	if uptime.Up > 0 {
+		uptime.AvgLatencyMs = float64(latency) / float64(uptime.Up)
+	}
+	return uptime
+}
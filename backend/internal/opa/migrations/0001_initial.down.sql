@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_opa_decisions_timestamp;
+DROP TABLE IF EXISTS opa_decisions;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS opa_decisions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp INTEGER NOT NULL, -- unix milliseconds
+	source TEXT NOT NULL, -- lint, gitops or apply
+	user TEXT NOT NULL DEFAULT '',
+	kind TEXT NOT NULL DEFAULT '',
+	namespace TEXT NOT NULL DEFAULT '',
+	name TEXT NOT NULL DEFAULT '',
+	allowed BOOLEAN NOT NULL,
+	denies TEXT NOT NULL DEFAULT '[]', -- JSON
+	warnings TEXT NOT NULL DEFAULT '[]', -- JSON
+	error TEXT NOT NULL DEFAULT '',
+	duration_ms INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_opa_decisions_timestamp ON opa_decisions(timestamp);
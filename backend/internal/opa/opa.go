@@ -0,0 +1,212 @@
+// Package opa evaluates Rego policies with the opa binary against manifests committed
+// to GitOps and applied to the cluster, and logs each decision for auditing.
+//
+// Policies follow the conftest convention: the input is one Kubernetes object, and the
+// policy package (denshimon by default) defines deny and warn rules producing messages.
+//
+//	package denshimon
+//
+//	deny contains msg if {
+//		input.kind == "Deployment"
+//		not input.metadata.labels.owner
+//		msg := "deployments need an owner label"
+//	}
+package opa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Sources of evaluated objects
+const (
+	SourceLint   = "lint"   // POST /api/gitops/manifests/lint, nothing is committed
+	SourceGitOps = "gitops" // manifests about to be committed to the GitOps repository
+	SourceApply  = "apply"  // manifests applied to the cluster through POST /api/k8s/apply
+)
+
+// DefaultPackage is the Rego package queried for deny and warn rules
+const DefaultPackage = "denshimon"
+
+// evalTimeout bounds one run of the opa binary
+const evalTimeout = 10 * time.Second
+
+var packagePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// Request is a set of objects to evaluate
+type Request struct {
+	Source  string
+	User    string
+	Objects []*unstructured.Unstructured
+}
+
+// Engine runs the opa binary over policy directories
+type Engine struct {
+	binary string
+	dirs   []string
+	query  string
+	store  *Store // decision log; nil keeps none
+}
+
+// NewEngine creates an engine evaluating the Rego files under dirs; directories that do
+// not exist yet, such as one in a GitOps repository before its first sync, are skipped
+func NewEngine(binary string, dirs []string, pkg string, store *Store) (*Engine, error) {
+	if pkg == "" {
+		pkg = DefaultPackage
+	}
+	if !packagePattern.MatchString(pkg) {
+		return nil, fmt.Errorf("invalid rego package %q", pkg)
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no policy directories")
+	}
+	return &Engine{binary: binary, dirs: dirs, query: "data." + pkg, store: store}, nil
+}
+
+// NewEngineFromEnv creates an engine when OPA_POLICY_DIR or OPA_POLICY_REPO_DIR (relative
+// to the local GitOps repository at repoPath) is set and the opa binary (OPA_BINARY,
+// default "opa") is installed. It returns nil when OPA is not configured.
+func NewEngineFromEnv(repoPath string, store *Store) *Engine {
+	var dirs []string
+	if dir := os.Getenv("OPA_POLICY_DIR"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if dir := os.Getenv("OPA_POLICY_REPO_DIR"); dir != "" {
+		// Cleaning against the root keeps ".." from leaving the repository
+		dirs = append(dirs, filepath.Join(repoPath, filepath.Clean("/"+dir)))
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+
+	binary := os.Getenv("OPA_BINARY")
+	if binary == "" {
+		binary = "opa"
+	}
+	if _, err := exec.LookPath(binary); err != nil {
+		slog.Warn("OPA binary not found, Rego policies disabled", "binary", binary)
+		return nil
+	}
+	engine, err := NewEngine(binary, dirs, os.Getenv("OPA_PACKAGE"), store)
+	if err != nil {
+		slog.Warn("Rego policies disabled", "error", err)
+		return nil
+	}
+	return engine
+}
+
+// Evaluate checks each object against the policies and logs a decision for it. An object
+// the policies cannot be evaluated for is denied, with the error in its decision.
+func (e *Engine) Evaluate(ctx context.Context, req Request) []Decision {
+	decisions := make([]Decision, 0, len(req.Objects))
+	for _, obj := range req.Objects {
+		start := time.Now()
+		decision := Decision{
+			Timestamp: start,
+			Source:    req.Source,
+			User:      req.User,
+			Kind:      obj.GetKind(),
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+			Denies:    []string{},
+			Warnings:  []string{},
+		}
+		if err := e.evaluate(ctx, obj, &decision); err != nil {
+			decision.Error = err.Error()
+		}
+		decision.Allowed = len(decision.Denies) == 0 && decision.Error == ""
+		decision.DurationMs = time.Since(start).Milliseconds()
+
+		if e.store != nil {
+			if err := e.store.Record(ctx, &decision); err != nil {
+				slog.Warn("Failed to log policy decision", "kind", decision.Kind, "name", decision.Name, "error", err)
+			}
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions
+}
+
+// evalOutput is the JSON printed by opa eval --format json
+type evalOutput struct {
+	Result []struct {
+		Expressions []struct {
+			Value map[string]interface{} `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+func (e *Engine) evaluate(ctx context.Context, obj *unstructured.Unstructured, decision *Decision) error {
+	input, err := json.Marshal(obj.Object)
+	if err != nil {
+		return fmt.Errorf("failed to encode input: %w", err)
+	}
+
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+	for _, dir := range e.dirs {
+		if _, err := os.Stat(dir); err == nil {
+			args = append(args, "--data", dir)
+		}
+	}
+	args = append(args, e.query)
+
+	ctx, cancel := context.WithTimeout(ctx, evalTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.binary, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("opa failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var output evalOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return fmt.Errorf("failed to decode opa output: %w", err)
+	}
+	// An undefined package has no result and allows everything
+	for _, result := range output.Result {
+		for _, expression := range result.Expressions {
+			decision.Denies = append(decision.Denies, messages(expression.Value["deny"])...)
+			decision.Denies = append(decision.Denies, messages(expression.Value["violation"])...)
+			decision.Warnings = append(decision.Warnings, messages(expression.Value["warn"])...)
+		}
+	}
+	return nil
+}
+
+// messages reads the results of a rule: a set of strings, or of objects with a msg
+func messages(value interface{}) []string {
+	var out []string
+	switch value := value.(type) {
+	case []interface{}:
+		for _, item := range value {
+			out = append(out, messages(item)...)
+		}
+	case string:
+		out = append(out, value)
+	case map[string]interface{}:
+		if msg, ok := value["msg"].(string); ok {
+			out = append(out, msg)
+		} else if data, err := json.Marshal(value); err == nil {
+			out = append(out, string(data))
+		}
+	case bool:
+		if value {
+			out = append(out, "denied by policy")
+		}
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+package opa
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// A stand-in for opa eval that denies images tagged latest, warns on everything else,
+// and fails for objects named broken
+const fakeOPA = `#!/bin/sh
+input=$(cat)
+case "$input" in
+*'"broken"'*) echo "policy error" >&2; exit 1 ;;
+*':latest'*) echo '{"result":[{"expressions":[{"value":{"deny":["image uses latest"],"warn":[]}}]}]}' ;;
+*) echo '{"result":[{"expressions":[{"value":{"warn":[{"msg":"no owner label"}]}}]}]}' ;;
+esac
+`
+
+func deployment(name, image string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "apps"},
+		"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"name": name, "image": image}},
+		}}},
+	}}
+}
+
+func TestEvaluate(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "opa")
+	if err := os.WriteFile(binary, []byte(fakeOPA), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewEngine(binary, []string{dir}, "data; rm", store); err == nil {
+		t.Error("expected an invalid package to be rejected")
+	}
+	engine, err := NewEngine(binary, []string{dir, filepath.Join(dir, "missing")}, "", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	decisions := engine.Evaluate(ctx, Request{Source: SourceApply, User: "alice", Objects: []*unstructured.Unstructured{
+		deployment("api", "registry.lan/api:1.2.0"),
+		deployment("web", "nginx:latest"),
+		deployment("broken", "registry.lan/broken:1.0.0"),
+	}})
+	if len(decisions) != 3 {
+		t.Fatalf("expected a decision per object, got %d", len(decisions))
+	}
+	if !decisions[0].Allowed || len(decisions[0].Warnings) != 1 || decisions[0].Warnings[0] != "no owner label" {
+		t.Errorf("expected api to be allowed with a warning, got %+v", decisions[0])
+	}
+	if decisions[1].Allowed || len(decisions[1].Denies) != 1 {
+		t.Errorf("expected web to be denied, got %+v", decisions[1])
+	}
+	if decisions[2].Allowed || decisions[2].Error == "" {
+		t.Errorf("expected a failed evaluation to deny, got %+v", decisions[2])
+	}
+
+	denied := false
+	logged, err := store.List(ctx, Query{User: "alice", Allowed: &denied})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logged) != 2 || logged[0].Source != SourceApply || logged[0].Namespace != "apps" {
+		t.Errorf("expected the two denials in the decision log, got %+v", logged)
+	}
+	if all, _ := store.List(ctx, Query{}); len(all) != 3 {
+		t.Errorf("expected every decision to be logged, got %d", len(all))
+	}
+}
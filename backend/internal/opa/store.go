@@ -0,0 +1,144 @@
+package opa
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/database/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations is the schema of the policy decision log
+var Migrations = migrate.NewSource("opa", migrationFiles, "migrations")
+
+// DecisionRetention is how long decisions are kept
+const DecisionRetention = 30 * 24 * time.Hour
+
+// DefaultLimit caps the decisions returned by a query without a limit
+const DefaultLimit = 200
+
+// Decision is the outcome of evaluating one object
+type Decision struct {
+	ID         int64     `json:"id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Source     string    `json:"source"`
+	User       string    `json:"user,omitempty"`
+	Kind       string    `json:"kind"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Name       string    `json:"name"`
+	Allowed    bool      `json:"allowed"`
+	Denies     []string  `json:"denies"`
+	Warnings   []string  `json:"warnings"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// Query selects decisions, newest first
+type Query struct {
+	Source  string
+	User    string
+	Allowed *bool
+	Since   time.Time
+	Limit   int
+}
+
+// Store keeps the decision log in SQLite
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a decision store, migrating its table to the latest schema version
+func NewStore(db *sql.DB) (*Store, error) {
+	if err := migrate.Up(context.Background(), db, Migrations); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Record appends a decision and drops those older than DecisionRetention
+func (s *Store) Record(ctx context.Context, decision *Decision) error {
+	denies, err := json.Marshal(decision.Denies)
+	if err != nil {
+		return err
+	}
+	warnings, err := json.Marshal(decision.Warnings)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO opa_decisions (timestamp, source, user, kind, namespace, name, allowed, denies, warnings, error, duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		decision.Timestamp.UnixMilli(), decision.Source, decision.User, decision.Kind, decision.Namespace, decision.Name,
+		decision.Allowed, string(denies), string(warnings), decision.Error, decision.DurationMs)
+	if err != nil {
+		return fmt.Errorf("failed to record decision: %w", err)
+	}
+	decision.ID, _ = result.LastInsertId()
+
+	cutoff := time.Now().Add(-DecisionRetention).UnixMilli()
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM opa_decisions WHERE timestamp < ?`, cutoff); err != nil {
+		return fmt.Errorf("failed to prune decisions: %w", err)
+	}
+	return nil
+}
+
+// List returns the decisions matching a query, newest first
+func (s *Store) List(ctx context.Context, query Query) ([]Decision, error) {
+	var conditions []string
+	var args []interface{}
+	if query.Source != "" {
+		conditions = append(conditions, "source = ?")
+		args = append(args, query.Source)
+	}
+	if query.User != "" {
+		conditions = append(conditions, "user = ?")
+		args = append(args, query.User)
+	}
+	if query.Allowed != nil {
+		conditions = append(conditions, "allowed = ?")
+		args = append(args, *query.Allowed)
+	}
+	if !query.Since.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, query.Since.UnixMilli())
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultLimit
+	}
+
+	statement := `SELECT id, timestamp, source, user, kind, namespace, name, allowed, denies, warnings, error, duration_ms FROM opa_decisions`
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	statement += " ORDER BY timestamp DESC, id DESC LIMIT ?"
+	args = append(args, query.Limit)
+
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list decisions: %w", err)
+	}
+	defer rows.Close()
+
+	decisions := []Decision{}
+	for rows.Next() {
+		var d Decision
+		var timestamp int64
+		var denies, warnings string
+		if err := rows.Scan(&d.ID, &timestamp, &d.Source, &d.User, &d.Kind, &d.Namespace, &d.Name,
+			&d.Allowed, &denies, &warnings, &d.Error, &d.DurationMs); err != nil {
+			return nil, fmt.Errorf("failed to scan decision: %w", err)
+		}
+		d.Timestamp = time.UnixMilli(timestamp)
+		json.Unmarshal([]byte(denies), &d.Denies)
+		json.Unmarshal([]byte(warnings), &d.Warnings)
+		decisions = append(decisions, d)
+	}
+	return decisions, rows.Err()
+}
@@ -0,0 +1,333 @@
+// Package openapi builds an OpenAPI 3.1 document from route metadata.
+//
+// Routes are described with ServeMux patterns such as "GET /api/k8s/pods/{name}"; path
+// parameters, tags and operation IDs are derived from the pattern. Request and response
+// bodies are given as Go values and described by reflecting over their json tags, so the
+// document follows the types the handlers actually encode.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.1.0"
+
+// Route describes one operation
+type Route struct {
+	// Method and Path are filled from the pattern passed to Generate
+	Method string
+	Path   string
+
+	Summary     string
+	Description string
+	// Tag groups the operation; defaults to the first path segment after /api
+	Tag string
+	// Public operations need no token; others require a bearer token
+	Public bool
+	// Role is the minimum role required, e.g. "operator" or "admin"; empty allows any user
+	Role  string
+	Query []Param
+	// Request is a value of the JSON request body's type; nil for no body
+	Request interface{}
+	// Response is a value of the JSON response body's type; nil for an unspecified body
+	Response interface{}
+	// Envelope marks responses sent as the data of the standard {success, data, error} wrapper
+	Envelope bool
+	// ContentType of the response when it isn't JSON, e.g. text/plain
+	ContentType string
+}
+
+// Param is a query parameter
+type Param struct {
+	Name        string
+	Description string
+	// Type is a JSON Schema type; defaults to string
+	Type     string
+	Required bool
+}
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Security   []SecurityRequirement `json:"security"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag names a group of operations
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// SecurityRequirement maps a security scheme name to its scopes
+type SecurityRequirement map[string][]string
+
+// PathItem holds a path's operations by lowercase method
+type PathItem map[string]*Operation
+
+// Operation is one method on a path
+type Operation struct {
+	OperationID string                 `json:"operationId"`
+	Summary     string                 `json:"summary,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	Parameters  []Parameter            `json:"parameters,omitempty"`
+	RequestBody *RequestBody           `json:"requestBody,omitempty"`
+	Responses   map[string]*Response   `json:"responses"`
+	Security    *[]SecurityRequirement `json:"security,omitempty"`
+	// RequiredRole is the minimum role, as the x-required-role extension
+	RequiredRole string `json:"x-required-role,omitempty"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes an operation's body
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes one response status
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the shared schemas and security schemes
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme describes how requests authenticate
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+const bearerScheme = "bearerAuth"
+
+// Generate builds a document from routes keyed by ServeMux pattern ("METHOD /path").
+// Operations are emitted in pattern order so the output is stable.
+func Generate(info Info, routes map[string]Route) (*Document, error) {
+	patterns := make([]string, 0, len(routes))
+	for pattern := range routes {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	doc := &Document{
+		OpenAPI:  Version,
+		Info:     info,
+		Security: []SecurityRequirement{{bearerScheme: {}}},
+		Paths:    make(map[string]PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]SecurityScheme{
+				bearerScheme: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "A session token from POST /api/auth/login or an API token",
+				},
+			},
+		},
+	}
+	schemas := newRegistry(doc.Components.Schemas)
+	tags := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		route := routes[pattern]
+		method, path, err := splitPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		route.Method, route.Path = method, path
+
+		op := operation(route, schemas)
+		item, ok := doc.Paths[openAPIPath(path)]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[openAPIPath(path)] = item
+		}
+		item[strings.ToLower(method)] = op
+		tags[op.Tags[0]] = true
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	return doc, nil
+}
+
+func operation(route Route, schemas *registry) *Operation {
+	op := &Operation{
+		OperationID:  OperationID(route.Method, route.Path),
+		Summary:      route.Summary,
+		Description:  route.Description,
+		Tags:         []string{route.Tag},
+		Responses:    make(map[string]*Response),
+		RequiredRole: route.Role,
+	}
+	if route.Tag == "" {
+		op.Tags[0] = defaultTag(route.Path)
+	}
+
+	for _, name := range PathParams(route.Path) {
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, param := range route.Query {
+		paramType := param.Type
+		if paramType == "" {
+			paramType = "string"
+		}
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        param.Name,
+			In:          "query",
+			Description: param.Description,
+			Required:    param.Required,
+			Schema:      &Schema{Type: paramType},
+		})
+	}
+
+	if route.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: schemas.schema(route.Request)}},
+		}
+	}
+
+	success := &Response{Description: "Success"}
+	switch {
+	case route.ContentType != "":
+		success.Content = map[string]MediaType{route.ContentType: {Schema: &Schema{Type: "string"}}}
+	case route.Response != nil || route.Envelope:
+		var body *Schema
+		if route.Response != nil {
+			body = schemas.schema(route.Response)
+		}
+		if route.Envelope {
+			body = envelope(body)
+		}
+		success.Content = map[string]MediaType{"application/json": {Schema: body}}
+	default:
+		success.Content = map[string]MediaType{"application/json": {Schema: &Schema{}}}
+	}
+	op.Responses["200"] = success
+
+	if route.Public {
+		op.Security = &[]SecurityRequirement{}
+	} else {
+		op.Responses["401"] = &Response{Description: "Missing or invalid token"}
+		if route.Role != "" {
+			op.Responses["403"] = &Response{Description: fmt.Sprintf("Requires the %s role", route.Role)}
+		}
+	}
+	return op
+}
+
+// envelope wraps a data schema in the standard response wrapper
+func envelope(data *Schema) *Schema {
+	wrapped := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"error":   {Type: "string"},
+			"message": {Type: "string"},
+		},
+		Required: []string{"success"},
+	}
+	if data == nil {
+		data = &Schema{}
+	}
+	wrapped.Properties["data"] = data
+	return wrapped
+}
+
+// splitPattern separates a "METHOD /path" pattern
+func splitPattern(pattern string) (string, string, error) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok || !strings.HasPrefix(path, "/") {
+		return "", "", fmt.Errorf("route %q is not a \"METHOD /path\" pattern", pattern)
+	}
+	switch method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return "", "", fmt.Errorf("route %q has unsupported method %s", pattern, method)
+	}
+	return method, path, nil
+}
+
+// PathParams lists the wildcard names of a ServeMux path
+func PathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") && segment != "{$}" {
+			names = append(names, strings.TrimSuffix(segment[1:len(segment)-1], "..."))
+		}
+	}
+	return names
+}
+
+// openAPIPath drops ServeMux-only syntax: {name...} becomes {name} and {$} is removed
+func openAPIPath(path string) string {
+	path = strings.TrimSuffix(path, "{$}")
+	return strings.ReplaceAll(path, "...}", "}")
+}
+
+// defaultTag is the first path segment after /api, e.g. k8s for /api/k8s/pods
+func defaultTag(path string) string {
+	segments := strings.Split(strings.TrimPrefix(strings.TrimPrefix(path, "/"), "api/"), "/")
+	return segments[0]
+}
+
+// OperationID derives a camel-case ID from the method and path, e.g.
+// GET /api/k8s/pods/{name}/logs becomes getK8sPodsByNameLogs
+func OperationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "/api"), "/") {
+		if segment == "" || segment == "{$}" {
+			continue
+		}
+		if strings.HasPrefix(segment, "{") {
+			b.WriteString("By")
+			segment = strings.TrimSuffix(segment[1:len(segment)-1], "...")
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			b.WriteString(string(runes))
+		}
+	}
+	return b.String()
+}
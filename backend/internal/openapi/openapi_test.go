@@ -0,0 +1,189 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testBase struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created_at"`
+}
+
+type testPod struct {
+	testBase
+	Name     string            `json:"name"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Restarts int64             `json:"restarts,string"`
+	Owner    *testPod          `json:"owner,omitempty"`
+	internal string
+	Ignored  string `json:"-"`
+}
+
+type testRequest struct {
+	Replicas int32 `json:"replicas"`
+}
+
+func generate(t *testing.T, routes map[string]Route) map[string]interface{} {
+	t.Helper()
+	doc, err := Generate(Info{Title: "Test", Version: "1"}, routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+// lookup follows a |-separated path through decoded JSON
+func lookup(t *testing.T, value interface{}, path string) interface{} {
+	t.Helper()
+	for _, key := range strings.Split(path, "|") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			t.Fatalf("%s: %q is not an object", path, key)
+		}
+		if value, ok = object[key]; !ok {
+			t.Fatalf("%s: missing %q", path, key)
+		}
+	}
+	return value
+}
+
+func encode(t *testing.T, value interface{}) string {
+	t.Helper()
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(encoded)
+}
+
+func TestGenerateOperations(t *testing.T) {
+	doc := generate(t, map[string]Route{
+		"GET /api/k8s/pods/{name}": {
+			Summary:  "Get a pod",
+			Query:    []Param{{Name: "namespace"}, {Name: "tail", Type: "integer"}},
+			Response: testPod{},
+		},
+		"PATCH /api/k8s/pods/{name}/scale": {Role: "operator", Request: testRequest{}, Envelope: true},
+		"POST /api/auth/login":             {Public: true},
+		"GET /api/graphql/schema":          {ContentType: "text/plain"},
+	})
+
+	if doc["openapi"] != Version {
+		t.Errorf("expected version %s, got %v", Version, doc["openapi"])
+	}
+
+	get := lookup(t, doc, "paths|/api/k8s/pods/{name}|get")
+	if got := lookup(t, get, "operationId"); got != "getK8sPodsByName" {
+		t.Errorf("unexpected operationId %v", got)
+	}
+	if got := encode(t, lookup(t, get, "tags")); got != `["k8s"]` {
+		t.Errorf("unexpected tags %s", got)
+	}
+	want := `[{"in":"path","name":"name","required":true,"schema":{"type":"string"}},` +
+		`{"in":"query","name":"namespace","schema":{"type":"string"}},` +
+		`{"in":"query","name":"tail","schema":{"type":"integer"}}]`
+	if got := encode(t, lookup(t, get, "parameters")); got != want {
+		t.Errorf("unexpected parameters %s", got)
+	}
+	if got := encode(t, lookup(t, get, "responses|200|content|application/json|schema")); got != `{"$ref":"#/components/schemas/TestPod"}` {
+		t.Errorf("unexpected response schema %s", got)
+	}
+	if _, ok := lookup(t, get, "responses").(map[string]interface{})["401"]; !ok {
+		t.Error("expected authenticated operations to document 401")
+	}
+
+	patch := lookup(t, doc, "paths|/api/k8s/pods/{name}/scale|patch")
+	if got := lookup(t, patch, "x-required-role"); got != "operator" {
+		t.Errorf("unexpected role %v", got)
+	}
+	lookup(t, patch, "responses|403")
+	if got := encode(t, lookup(t, patch, "requestBody|content|application/json|schema")); got != `{"$ref":"#/components/schemas/TestRequest"}` {
+		t.Errorf("unexpected request schema %s", got)
+	}
+	if got := encode(t, lookup(t, patch, "responses|200|content|application/json|schema|required")); got != `["success"]` {
+		t.Errorf("expected an envelope, got %s", got)
+	}
+
+	login := lookup(t, doc, "paths|/api/auth/login|post")
+	if got := encode(t, lookup(t, login, "security")); got != `[]` {
+		t.Errorf("expected public operations to override security, got %s", got)
+	}
+	if _, ok := login.(map[string]interface{})["responses"].(map[string]interface{})["401"]; ok {
+		t.Error("expected public operations not to document 401")
+	}
+
+	schema := lookup(t, doc, "paths|/api/graphql/schema|get|responses|200|content")
+	if _, ok := schema.(map[string]interface{})["text/plain"]; !ok {
+		t.Errorf("expected a text/plain response, got %s", encode(t, schema))
+	}
+
+	if got := encode(t, doc["tags"]); got != `[{"name":"auth"},{"name":"graphql"},{"name":"k8s"}]` {
+		t.Errorf("unexpected tags %s", got)
+	}
+}
+
+func TestGenerateRejectsInvalidPatterns(t *testing.T) {
+	for _, pattern := range []string{"/api/deployments/", "HEAD /api/pods", "GET api/pods"} {
+		if _, err := Generate(Info{}, map[string]Route{pattern: {}}); err == nil {
+			t.Errorf("%s: expected an error", pattern)
+		}
+	}
+}
+
+func TestSchemaReflection(t *testing.T) {
+	schemas := make(map[string]*Schema)
+	newRegistry(schemas).schema([]testPod{})
+
+	want := `{"type":"object","properties":{"created_at":{"type":"string","format":"date-time"},` +
+		`"id":{"type":"string"},"labels":{"type":"object","additionalProperties":{"type":"string"}},` +
+		`"name":{"type":"string"},"owner":{"$ref":"#/components/schemas/TestPod"},"restarts":{"type":"string"}},` +
+		`"required":["id","created_at","name","restarts"]}`
+	if got := encode(t, schemas["TestPod"]); got != want {
+		t.Fatalf("got %s\nwant %s", got, want)
+	}
+}
+
+func TestSchemaNamesAreUnique(t *testing.T) {
+	type local = testRequest
+	type testRequest struct {
+		Name string `json:"name"`
+	}
+	schemas := make(map[string]*Schema)
+	registry := newRegistry(schemas)
+	first := registry.schema(struct{ A local }{}).Properties["A"].Ref
+	second := registry.schema(struct{ B testRequest }{}).Properties["B"].Ref
+	if first == second {
+		t.Fatalf("expected distinct types to get distinct names, both are %s", first)
+	}
+	if len(schemas) != 2 {
+		t.Fatalf("expected 2 schemas, got %d", len(schemas))
+	}
+}
+
+func TestOperationID(t *testing.T) {
+	tests := map[string]string{
+		"GET /api/k8s/pods": "getK8sPods",
+		"DELETE /api/backup/snapshots/{namespace}/{name}": "deleteBackupSnapshotsByNamespaceByName",
+		"POST /api/deployments/batch-apply":               "postDeploymentsBatchApply",
+		"GET /api/log_data/analytics":                     "getLogDataAnalytics",
+		"GET /api/openapi.json":                           "getOpenapiJson",
+		"GET /api/files/{path...}":                        "getFilesByPath",
+	}
+	for pattern, want := range tests {
+		method, path, _ := strings.Cut(pattern, " ")
+		if got := OperationID(method, path); got != want {
+			t.Errorf("%s: got %s, want %s", pattern, got, want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Schema is a JSON Schema as used by OpenAPI 3.1
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// registry describes Go types as schemas, adding named structs to the components once
+type registry struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newRegistry(schemas map[string]*Schema) *registry {
+	return &registry{schemas: schemas, names: make(map[reflect.Type]string)}
+}
+
+// schema describes the JSON encoding of a value's type
+func (r *registry) schema(value interface{}) *Schema {
+	return r.typeSchema(reflect.TypeOf(value))
+}
+
+func (r *registry) typeSchema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "Nanoseconds"}
+	case rawMessageType:
+		return &Schema{}
+	}
+	// Types with their own encoding can't be described from their fields
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return &Schema{}
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + r.named(t)}
+	}
+	// Interfaces and anything else may hold any JSON value
+	return &Schema{}
+}
+
+// named adds a struct to the components, qualifying the name with its package when two
+// packages use the same type name
+func (r *registry) named(t reflect.Type) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+	name := schemaName(t.Name())
+	if _, taken := r.schemas[name]; taken {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = schemaName(pkg) + name
+	}
+	r.names[t] = name
+	// Reserve the name before describing the fields so recursive types end in a reference
+	r.schemas[name] = &Schema{}
+	*r.schemas[name] = *r.object(t)
+	return name
+}
+
+// object describes a struct's fields the way encoding/json encodes them
+func (r *registry) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	r.fields(t, s)
+	return s
+}
+
+func (r *registry) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// Untagged embedded structs are flattened into the parent
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				r.fields(embedded, s)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		field := r.typeSchema(f.Type)
+		if hasOption(options, "string") {
+			field = &Schema{Type: "string"}
+		}
+		s.Properties[name] = field
+		if !hasOption(options, "omitempty") && !hasOption(options, "omitzero") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func hasOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaName keeps component names to the characters OpenAPI allows, e.g. for generic types
+func schemaName(name string) string {
+	var b strings.Builder
+	for i, c := range name {
+		switch {
+		case i == 0:
+			b.WriteRune(unicode.ToUpper(c))
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_':
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
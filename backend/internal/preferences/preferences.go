@@ -0,0 +1,148 @@
+// Package preferences keeps each user's UI state: default namespace, favorite resources,
+// pinned dashboards and their layout, table columns and refresh intervals.
+package preferences
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrInvalid is returned for preferences with unknown fields or out-of-range values
+var ErrInvalid = errors.New("invalid preferences")
+
+// Limits keeping stored preferences small
+const (
+	MaxBytes         = 64 << 10 // of a stored or submitted document
+	maxFavorites     = 200
+	maxPinned        = 50
+	maxEntries       = 100 // of each map
+	maxNameLength    = 253
+	minRefreshSecond = 5
+	maxRefreshSecond = 3600
+	maxPageSize      = 1000
+)
+
+// Preferences is one user's UI state. Maps are keyed by view or table; the frontend
+// decides the keys.
+type Preferences struct {
+	DefaultNamespace string     `json:"defaultNamespace"`
+	Favorites        []Favorite `json:"favorites"`
+	PinnedDashboards []string   `json:"pinnedDashboards"`
+	// Dashboard is which dashboard sections and tabs are shown
+	Dashboard DashboardLayout        `json:"dashboard"`
+	Tables    map[string]TableLayout `json:"tables"`
+	// RefreshIntervals are seconds between refreshes by view; 0 turns refreshing off
+	RefreshIntervals map[string]int `json:"refreshIntervals"`
+	UpdatedAt        *time.Time     `json:"updatedAt,omitempty"`
+}
+
+// Favorite is a resource starred for quick access
+type Favorite struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"` // empty for cluster-scoped resources
+	Name      string `json:"name"`
+}
+
+// DashboardLayout is the visibility of dashboard sections and tabs; unlisted ones are shown
+type DashboardLayout struct {
+	Sections map[string]bool `json:"sections"`
+	Tabs     map[string]bool `json:"tabs"`
+}
+
+// TableLayout is how a table is shown
+type TableLayout struct {
+	Columns       []string `json:"columns"` // visible columns in order; empty shows the defaults
+	SortBy        string   `json:"sortBy,omitempty"`
+	SortDirection string   `json:"sortDirection,omitempty"` // asc or desc
+	PageSize      int      `json:"pageSize,omitempty"`
+}
+
+// Defaults returns the preferences of a user who has saved none
+func Defaults() Preferences {
+	return Preferences{
+		Favorites:        []Favorite{},
+		PinnedDashboards: []string{},
+		Dashboard:        DashboardLayout{Sections: map[string]bool{}, Tabs: map[string]bool{}},
+		Tables:           map[string]TableLayout{},
+		RefreshIntervals: map[string]int{},
+	}
+}
+
+// normalize fills in empty collections and checks the preferences' values
+func (p *Preferences) normalize() error {
+	p.DefaultNamespace = strings.TrimSpace(p.DefaultNamespace)
+	if len(p.DefaultNamespace) > maxNameLength {
+		return fmt.Errorf("%w: defaultNamespace is too long", ErrInvalid)
+	}
+
+	if len(p.Favorites) > maxFavorites {
+		return fmt.Errorf("%w: at most %d favorites", ErrInvalid, maxFavorites)
+	}
+	favorites := []Favorite{}
+	for _, favorite := range p.Favorites {
+		if favorite.Kind == "" || favorite.Name == "" {
+			return fmt.Errorf("%w: favorites need a kind and a name", ErrInvalid)
+		}
+		if len(favorite.Kind)+len(favorite.Namespace)+len(favorite.Name) > 3*maxNameLength {
+			return fmt.Errorf("%w: favorite %s is too long", ErrInvalid, favorite.Name)
+		}
+		if !slices.Contains(favorites, favorite) {
+			favorites = append(favorites, favorite)
+		}
+	}
+	p.Favorites = favorites
+
+	if len(p.PinnedDashboards) > maxPinned {
+		return fmt.Errorf("%w: at most %d pinned dashboards", ErrInvalid, maxPinned)
+	}
+	pinned := []string{}
+	for _, dashboard := range p.PinnedDashboards {
+		if dashboard == "" || len(dashboard) > maxNameLength {
+			return fmt.Errorf("%w: pinned dashboard names must be 1 to %d characters", ErrInvalid, maxNameLength)
+		}
+		if !slices.Contains(pinned, dashboard) {
+			pinned = append(pinned, dashboard)
+		}
+	}
+	p.PinnedDashboards = pinned
+
+	if p.Dashboard.Sections == nil {
+		p.Dashboard.Sections = map[string]bool{}
+	}
+	if p.Dashboard.Tabs == nil {
+		p.Dashboard.Tabs = map[string]bool{}
+	}
+	if p.Tables == nil {
+		p.Tables = map[string]TableLayout{}
+	}
+	if p.RefreshIntervals == nil {
+		p.RefreshIntervals = map[string]int{}
+	}
+	if len(p.Dashboard.Sections) > maxEntries || len(p.Dashboard.Tabs) > maxEntries || len(p.Tables) > maxEntries || len(p.RefreshIntervals) > maxEntries {
+		return fmt.Errorf("%w: at most %d entries per setting", ErrInvalid, maxEntries)
+	}
+
+	for name, table := range p.Tables {
+		switch table.SortDirection {
+		case "", "asc", "desc":
+		default:
+			return fmt.Errorf("%w: table %s: sortDirection must be asc or desc", ErrInvalid, name)
+		}
+		if table.PageSize < 0 || table.PageSize > maxPageSize {
+			return fmt.Errorf("%w: table %s: pageSize must be between 0 and %d", ErrInvalid, name, maxPageSize)
+		}
+		if table.Columns == nil {
+			table.Columns = []string{}
+			p.Tables[name] = table
+		}
+	}
+	for view, seconds := range p.RefreshIntervals {
+		if seconds != 0 && (seconds < minRefreshSecond || seconds > maxRefreshSecond) {
+			return fmt.Errorf("%w: refresh interval of %s must be 0 or %d to %d seconds", ErrInvalid, view, minRefreshSecond, maxRefreshSecond)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package preferences
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/archellir/denshimon/internal/database"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewStore(db.DB)
+}
+
+func TestPreferences(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	prefs, err := store.Get(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefs.UpdatedAt != nil || prefs.Favorites == nil || prefs.Tables == nil {
+		t.Errorf("expected empty defaults, got %+v", prefs)
+	}
+
+	_, err = store.Update(ctx, "alice", []byte(`{
+		"defaultNamespace": "apps",
+		"favorites": [{"kind": "Deployment", "namespace": "apps", "name": "gitea"}, {"kind": "Deployment", "namespace": "apps", "name": "gitea"}],
+		"tables": {"pods": {"columns": ["name", "status"], "sortBy": "name", "sortDirection": "asc"}},
+		"refreshIntervals": {"dashboard": 30}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the given fields change
+	prefs, err = store.Update(ctx, "alice", []byte(`{"pinnedDashboards": ["overview"], "refreshIntervals": {"pods": 0}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefs.DefaultNamespace != "apps" || len(prefs.Favorites) != 1 || prefs.Tables["pods"].SortBy != "name" {
+		t.Errorf("expected untouched fields to be kept, got %+v", prefs)
+	}
+	if len(prefs.PinnedDashboards) != 1 || len(prefs.RefreshIntervals) != 1 || prefs.RefreshIntervals["pods"] != 0 {
+		t.Errorf("expected given fields to be replaced, got %+v", prefs)
+	}
+
+	stored, err := store.Get(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.UpdatedAt == nil || stored.DefaultNamespace != "apps" || stored.PinnedDashboards[0] != "overview" {
+		t.Errorf("unexpected stored preferences %+v", stored)
+	}
+	if other, _ := store.Get(ctx, "bob"); other.DefaultNamespace != "" {
+		t.Error("expected preferences to be per user")
+	}
+
+	for _, patch := range []string{
+		`{"refreshIntervals": {"dashboard": 1}}`,
+		`{"tables": {"pods": {"sortDirection": "up"}}}`,
+		`{"favorites": [{"kind": "Pod"}]}`,
+		`{"theme": "dark"}`,
+		`[]`,
+	} {
+		if _, err := store.Update(ctx, "alice", []byte(patch)); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected %s to be invalid, got %v", patch, err)
+		}
+	}
+
+	if err := store.Reset(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if prefs, _ := store.Get(ctx, "alice"); prefs.DefaultNamespace != "" || prefs.UpdatedAt != nil {
+		t.Errorf("expected the defaults after a reset, got %+v", prefs)
+	}
+}
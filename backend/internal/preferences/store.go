@@ -0,0 +1,115 @@
+package preferences
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Store persists preferences in the user_preferences table of the auth schema
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a preferences store; the table comes with database.AuthMigrations
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Get returns a user's preferences, or the defaults if they saved none
+func (s *Store) Get(ctx context.Context, userID string) (*Preferences, error) {
+	var raw string
+	var updatedAt time.Time
+	err := s.db.QueryRowContext(ctx, `SELECT preferences, updated_at FROM user_preferences WHERE user_id = ?`, userID).Scan(&raw, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		prefs := Defaults()
+		return &prefs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+
+	prefs := Defaults()
+	if err := json.Unmarshal([]byte(raw), &prefs); err != nil {
+		return nil, fmt.Errorf("failed to decode preferences: %w", err)
+	}
+	// Stored preferences were valid when saved; this only fills in empty collections
+	prefs.normalize()
+	updatedAt = updatedAt.UTC()
+	prefs.UpdatedAt = &updatedAt
+	return &prefs, nil
+}
+
+// Update applies a JSON document to a user's preferences. Fields it sets replace the
+// stored ones whole; fields it leaves out keep their stored value.
+func (s *Store) Update(ctx context.Context, userID string, patch []byte) (*Preferences, error) {
+	if len(patch) > MaxBytes {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalid, MaxBytes)
+	}
+	var changes map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	delete(changes, "updatedAt")
+
+	current, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(stored, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range changes {
+		fields[name] = value
+	}
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs := Defaults()
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&prefs); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if err := prefs.normalize(); err != nil {
+		return nil, err
+	}
+	prefs.UpdatedAt = nil
+	encoded, err := json.Marshal(prefs)
+	if err != nil {
+		return nil, err
+	}
+	if len(encoded) > MaxBytes {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalid, MaxBytes)
+	}
+
+	now := time.Now().UTC()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO user_preferences (user_id, preferences, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET preferences = excluded.preferences, updated_at = excluded.updated_at`,
+		userID, string(encoded), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save preferences: %w", err)
+	}
+	prefs.UpdatedAt = &now
+	return &prefs, nil
+}
+
+// Reset deletes a user's preferences, going back to the defaults
+func (s *Store) Reset(ctx context.Context, userID string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM user_preferences WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to reset preferences: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// MeshSource identifies which exporter the service traffic figures were read from
+type MeshSource string
+
+const (
+	MeshSourceIstio        MeshSource = "istio"
+	MeshSourceLinkerd      MeshSource = "linkerd"
+	MeshSourceNginxIngress MeshSource = "nginx-ingress"
+)
+
+// NginxIngressSource is the service name used as the source of ingress-nginx traffic,
+// matching the controller Service created by the upstream Helm chart
+const NginxIngressSource = "ingress-nginx-controller"
+
+// ErrNoMeshMetrics is returned when none of the supported traffic metrics are present
+var ErrNoMeshMetrics = errors.New("no service mesh or ingress metrics found")
+
+// ServiceTraffic contains the observed request metrics of one service.
+// Rates are requests per second, error rates are percentages and latencies milliseconds.
+type ServiceTraffic struct {
+	Service     string  `json:"service"`
+	Namespace   string  `json:"namespace"`
+	RequestRate float64 `json:"request_rate"`
+	ErrorRate   float64 `json:"error_rate"`
+	P50         float64 `json:"p50"`
+	P95         float64 `json:"p95"`
+	P99         float64 `json:"p99"`
+}
+
+// TrafficEdge is observed traffic from one workload to a service
+type TrafficEdge struct {
+	Source          string  `json:"source"`
+	SourceNamespace string  `json:"source_namespace"`
+	Target          string  `json:"target"`
+	TargetNamespace string  `json:"target_namespace"`
+	Protocol        string  `json:"protocol"`
+	RequestRate     float64 `json:"request_rate"`
+	ErrorRate       float64 `json:"error_rate"`
+	LatencyMs       float64 `json:"latency_ms"` // median
+	MTLS            bool    `json:"mtls"`
+}
+
+// MeshTraffic is the service-to-service traffic observed over a window
+type MeshTraffic struct {
+	Source   MeshSource       `json:"source"`
+	Services []ServiceTraffic `json:"services"`
+	Edges    []TrafficEdge    `json:"edges"`
+}
+
+// meshQueries describes how to read traffic from one exporter's metrics
+type meshQueries struct {
+	source MeshSource
+	// edge labels, in the order source, source namespace, target, target namespace
+	sourceLabel, sourceNamespaceLabel, targetLabel, targetNamespaceLabel string
+	requests, errors, latencyBucket                                      string
+	latencyScale                                                         float64 // converts bucket units to milliseconds
+	mtls                                                                 func(labels map[string]string) bool
+	protocol                                                             func(labels map[string]string) string
+	extraEdgeLabels                                                      string
+}
+
+var meshExporters = []meshQueries{
+	{
+		source:               MeshSourceIstio,
+		sourceLabel:          "source_canonical_service",
+		sourceNamespaceLabel: "source_workload_namespace",
+		targetLabel:          "destination_service_name",
+		targetNamespaceLabel: "destination_service_namespace",
+		requests:             `istio_requests_total{reporter="destination"}`,
+		errors:               `istio_requests_total{reporter="destination",response_code=~"5.."}`,
+		latencyBucket:        `istio_request_duration_milliseconds_bucket{reporter="destination"}`,
+		latencyScale:         1,
+		extraEdgeLabels:      ", connection_security_policy, request_protocol",
+		mtls: func(labels map[string]string) bool {
+			return labels["connection_security_policy"] == "mutual_tls"
+		},
+		protocol: func(labels map[string]string) string {
+			switch labels["request_protocol"] {
+			case "grpc":
+				return "gRPC"
+			case "tcp":
+				return "TCP"
+			default:
+				return "HTTP"
+			}
+		},
+	},
+	{
+		source:               MeshSourceLinkerd,
+		sourceLabel:          "deployment",
+		sourceNamespaceLabel: "namespace",
+		targetLabel:          "dst_service",
+		targetNamespaceLabel: "dst_namespace",
+		requests:             `response_total{direction="outbound",dst_service!=""}`,
+		errors:               `response_total{direction="outbound",dst_service!="",classification="failure"}`,
+		latencyBucket:        `response_latency_ms_bucket{direction="outbound",dst_service!=""}`,
+		latencyScale:         1,
+		extraEdgeLabels:      ", tls",
+		mtls: func(labels map[string]string) bool {
+			return labels["tls"] == "true"
+		},
+		protocol: func(map[string]string) string { return "HTTP" },
+	},
+	{
+		source:               MeshSourceNginxIngress,
+		targetLabel:          "service",
+		targetNamespaceLabel: "namespace",
+		requests:             `nginx_ingress_controller_requests`,
+		errors:               `nginx_ingress_controller_requests{status=~"5.."}`,
+		latencyBucket:        `nginx_ingress_controller_request_duration_seconds_bucket`,
+		latencyScale:         1000,
+		mtls:                 func(map[string]string) bool { return false },
+		protocol:             func(map[string]string) string { return "HTTP" },
+	},
+}
+
+// GetMeshTraffic reads service-to-service request rates, error rates and latencies
+// from Istio, Linkerd or ingress-nginx metrics, whichever is found first
+func (s *Service) GetMeshTraffic(ctx context.Context, window time.Duration) (*MeshTraffic, error) {
+	if window < time.Minute {
+		window = 5 * time.Minute
+	}
+
+	for _, exporter := range meshExporters {
+		traffic, err := s.meshTraffic(ctx, exporter, window)
+		if err != nil {
+			return nil, err
+		}
+		if traffic != nil {
+			return traffic, nil
+		}
+	}
+	return nil, ErrNoMeshMetrics
+}
+
+// meshTraffic returns nil when the exporter's request metric has no series
+func (s *Service) meshTraffic(ctx context.Context, q meshQueries, window time.Duration) (*MeshTraffic, error) {
+	rng := fmt.Sprintf("[%ds]", int(window.Seconds()))
+	edgeLabels := q.targetLabel + ", " + q.targetNamespaceLabel
+	if q.sourceLabel != "" {
+		edgeLabels = q.sourceLabel + ", " + q.sourceNamespaceLabel + ", " + edgeLabels
+	}
+
+	requests, err := s.client.Query(ctx, fmt.Sprintf(`sum by (%s%s) (rate(%s%s))`, edgeLabels, q.extraEdgeLabels, q.requests, rng))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s requests: %w", q.source, err)
+	}
+	if len(requests.Data.Result) == 0 {
+		return nil, nil
+	}
+
+	errorRates, err := s.client.Query(ctx, fmt.Sprintf(`sum by (%s) (rate(%s%s))`, edgeLabels, q.errors, rng))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s errors: %w", q.source, err)
+	}
+	edgeLatency, err := s.client.Query(ctx, fmt.Sprintf(`histogram_quantile(0.5, sum by (le, %s) (rate(%s%s)))`, edgeLabels, q.latencyBucket, rng))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s latency: %w", q.source, err)
+	}
+
+	edgeKey := func(labels map[string]string) string {
+		return labels[q.sourceLabel] + "|" + labels[q.sourceNamespaceLabel] + "|" + labels[q.targetLabel] + "|" + labels[q.targetNamespaceLabel]
+	}
+	errorsByEdge := valuesByKey(errorRates, edgeKey)
+	latencyByEdge := valuesByKey(edgeLatency, edgeKey)
+
+	// Series split by the extra labels (TLS, protocol) are merged into one edge
+	edges := map[string]*TrafficEdge{}
+	var order []string
+	for _, series := range requests.Data.Result {
+		labels := series.Metric
+		if labels[q.targetLabel] == "" {
+			continue
+		}
+		key := edgeKey(labels)
+		edge, ok := edges[key]
+		if !ok {
+			edge = &TrafficEdge{
+				Source:          labels[q.sourceLabel],
+				SourceNamespace: labels[q.sourceNamespaceLabel],
+				Target:          labels[q.targetLabel],
+				TargetNamespace: labels[q.targetNamespaceLabel],
+				Protocol:        q.protocol(labels),
+				LatencyMs:       finite(latencyByEdge[key]) * q.latencyScale,
+				MTLS:            true,
+			}
+			if q.sourceLabel == "" {
+				edge.Source = NginxIngressSource
+			}
+			edges[key] = edge
+			order = append(order, key)
+		}
+		rate := parseMetricValue(series.Value)
+		edge.RequestRate += rate
+		// An edge counts as mTLS only if all of its traffic was
+		if rate > 0 && !q.mtls(labels) {
+			edge.MTLS = false
+		}
+	}
+
+	traffic := &MeshTraffic{Source: q.source}
+	services := map[string]*ServiceTraffic{}
+	var serviceOrder []string
+	for _, key := range order {
+		edge := edges[key]
+		if edge.RequestRate > 0 {
+			edge.ErrorRate = errorsByEdge[key] / edge.RequestRate * 100
+		}
+		traffic.Edges = append(traffic.Edges, *edge)
+
+		serviceKey := edge.Target + "|" + edge.TargetNamespace
+		service, ok := services[serviceKey]
+		if !ok {
+			service = &ServiceTraffic{Service: edge.Target, Namespace: edge.TargetNamespace}
+			services[serviceKey] = service
+			serviceOrder = append(serviceOrder, serviceKey)
+		}
+		service.RequestRate += edge.RequestRate
+		service.ErrorRate += errorsByEdge[key] // summed as a rate, converted below
+	}
+
+	serviceLabels := q.targetLabel + ", " + q.targetNamespaceLabel
+	serviceKey := func(labels map[string]string) string {
+		return labels[q.targetLabel] + "|" + labels[q.targetNamespaceLabel]
+	}
+	quantiles := map[float64]map[string]float64{}
+	for _, quantile := range []float64{0.5, 0.95, 0.99} {
+		result, err := s.client.Query(ctx, fmt.Sprintf(`histogram_quantile(%g, sum by (le, %s) (rate(%s%s)))`, quantile, serviceLabels, q.latencyBucket, rng))
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s latency: %w", q.source, err)
+		}
+		quantiles[quantile] = valuesByKey(result, serviceKey)
+	}
+
+	for _, key := range serviceOrder {
+		service := services[key]
+		if service.RequestRate > 0 {
+			service.ErrorRate = service.ErrorRate / service.RequestRate * 100
+		}
+		service.P50 = finite(quantiles[0.5][key]) * q.latencyScale
+		service.P95 = finite(quantiles[0.95][key]) * q.latencyScale
+		service.P99 = finite(quantiles[0.99][key]) * q.latencyScale
+		traffic.Services = append(traffic.Services, *service)
+	}
+	return traffic, nil
+}
+
+func valuesByKey(result *QueryResult, key func(map[string]string) string) map[string]float64 {
+	values := make(map[string]float64, len(result.Data.Result))
+	for _, series := range result.Data.Result {
+		values[key(series.Metric)] += parseMetricValue(series.Value)
+	}
+	return values
+}
+
+// finite maps the NaN and Inf values histogram_quantile returns without samples to zero
+func finite(value float64) float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0
+	}
+	return value
+}
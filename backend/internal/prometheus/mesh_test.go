@@ -0,0 +1,99 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePrometheus answers instant queries with the first response whose key is contained in the query
+func fakePrometheus(t *testing.T, responses [][2]string) *Service {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		result := "[]"
+		for _, response := range responses {
+			if strings.Contains(query, response[0]) {
+				result = response[1]
+				break
+			}
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":%s}}`, result)
+	}))
+	t.Cleanup(server.Close)
+	return NewService(server.URL)
+}
+
+func TestGetMeshTrafficIstio(t *testing.T) {
+	edge := `"source_canonical_service":"web","source_workload_namespace":"apps","destination_service_name":"api","destination_service_namespace":"apps"`
+	service := fakePrometheus(t, [][2]string{
+		{"histogram_quantile(0.5, sum by (le, source_canonical_service", `[{"metric":{` + edge + `},"value":[0,"12"]}]`},
+		{"histogram_quantile(0.5, sum by (le, destination_service_name", `[{"metric":{"destination_service_name":"api","destination_service_namespace":"apps"},"value":[0,"12"]}]`},
+		{"histogram_quantile(0.95", `[{"metric":{"destination_service_name":"api","destination_service_namespace":"apps"},"value":[0,"80"]}]`},
+		{"histogram_quantile(0.99", `[{"metric":{"destination_service_name":"api","destination_service_namespace":"apps"},"value":[0,"NaN"]}]`},
+		{`response_code=~"5.."`, `[{"metric":{` + edge + `},"value":[0,"1"]}]`},
+		{"istio_requests_total", `[
+			{"metric":{` + edge + `,"connection_security_policy":"mutual_tls","request_protocol":"http"},"value":[0,"15"]},
+			{"metric":{` + edge + `,"connection_security_policy":"none","request_protocol":"http"},"value":[0,"5"]}
+		]`},
+	})
+
+	traffic, err := service.GetMeshTraffic(context.Background(), 5*time.Minute)
+	if err != nil {
+		t.Fatalf("GetMeshTraffic: %v", err)
+	}
+	if traffic.Source != MeshSourceIstio {
+		t.Errorf("expected istio source, got %s", traffic.Source)
+	}
+
+	if len(traffic.Edges) != 1 {
+		t.Fatalf("expected series to merge into 1 edge, got %d", len(traffic.Edges))
+	}
+	e := traffic.Edges[0]
+	if e.Source != "web" || e.Target != "api" || e.RequestRate != 20 || e.ErrorRate != 5 || e.LatencyMs != 12 {
+		t.Errorf("unexpected edge: %+v", e)
+	}
+	if e.MTLS {
+		t.Error("expected edge with plaintext traffic not to count as mTLS")
+	}
+
+	if len(traffic.Services) != 1 {
+		t.Fatalf("expected 1 service, got %d", len(traffic.Services))
+	}
+	s := traffic.Services[0]
+	if s.RequestRate != 20 || s.ErrorRate != 5 || s.P50 != 12 || s.P95 != 80 || s.P99 != 0 {
+		t.Errorf("unexpected service traffic: %+v", s)
+	}
+}
+
+func TestGetMeshTrafficNginxIngress(t *testing.T) {
+	service := fakePrometheus(t, [][2]string{
+		{"histogram_quantile", `[{"metric":{"service":"web","namespace":"apps"},"value":[0,"0.25"]}]`},
+		{`status=~"5.."`, `[]`},
+		{"nginx_ingress_controller_requests", `[{"metric":{"service":"web","namespace":"apps"},"value":[0,"4"]}]`},
+	})
+
+	traffic, err := service.GetMeshTraffic(context.Background(), 5*time.Minute)
+	if err != nil {
+		t.Fatalf("GetMeshTraffic: %v", err)
+	}
+	if traffic.Source != MeshSourceNginxIngress || len(traffic.Edges) != 1 {
+		t.Fatalf("unexpected traffic: %+v", traffic)
+	}
+	if e := traffic.Edges[0]; e.Source != NginxIngressSource || e.Target != "web" || e.LatencyMs != 250 {
+		t.Errorf("unexpected edge: %+v", e)
+	}
+}
+
+func TestGetMeshTrafficWithoutMetrics(t *testing.T) {
+	service := fakePrometheus(t, nil)
+
+	if _, err := service.GetMeshTraffic(context.Background(), 5*time.Minute); !errors.Is(err, ErrNoMeshMetrics) {
+		t.Errorf("expected ErrNoMeshMetrics, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+)
+
+// ResourceUsage is the current CPU and memory usage of a node or pod
+type ResourceUsage struct {
+	CPUMillicores int64 `json:"cpu_millicores"`
+	MemoryBytes   int64 `json:"memory_bytes"`
+}
+
+// Usage queries read the cAdvisor metrics scraped from the kubelets, which carry node, namespace
+// and pod labels. Node usage is the root cgroup, the same figure metrics-server reports.
+const (
+	nodeCPUQuery    = `sum by (node) (rate(container_cpu_usage_seconds_total{id="/"}[5m]))`
+	nodeMemoryQuery = `sum by (node) (container_memory_working_set_bytes{id="/"})`
+	podCPUQuery     = `sum by (namespace, pod) (rate(container_cpu_usage_seconds_total{container!="",container!="POD"}[5m]))`
+	podMemoryQuery  = `sum by (namespace, pod) (container_memory_working_set_bytes{container!="",container!="POD"})`
+)
+
+// GetNodeUsage returns the current usage of every node by node name.
+// An empty map means Prometheus is up but doesn't scrape cAdvisor.
+func (s *Service) GetNodeUsage(ctx context.Context) (map[string]ResourceUsage, error) {
+	return s.queryUsage(ctx, nodeCPUQuery, nodeMemoryQuery, func(labels map[string]string) string {
+		return labels["node"]
+	})
+}
+
+// GetPodUsage returns the current usage of every pod, keyed by "namespace/name"
+func (s *Service) GetPodUsage(ctx context.Context) (map[string]ResourceUsage, error) {
+	return s.queryUsage(ctx, podCPUQuery, podMemoryQuery, func(labels map[string]string) string {
+		if labels["namespace"] == "" || labels["pod"] == "" {
+			return ""
+		}
+		return labels["namespace"] + "/" + labels["pod"]
+	})
+}
+
+func (s *Service) queryUsage(ctx context.Context, cpuQuery, memoryQuery string, key func(map[string]string) string) (map[string]ResourceUsage, error) {
+	cpuResult, err := s.client.Query(ctx, cpuQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query CPU usage: %w", err)
+	}
+	memoryResult, err := s.client.Query(ctx, memoryQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memory usage: %w", err)
+	}
+
+	usage := make(map[string]ResourceUsage)
+	for _, series := range cpuResult.Data.Result {
+		if k := key(series.Metric); k != "" {
+			u := usage[k]
+			u.CPUMillicores = int64(parseMetricValue(series.Value) * 1000)
+			usage[k] = u
+		}
+	}
+	for _, series := range memoryResult.Data.Result {
+		if k := key(series.Metric); k != "" {
+			u := usage[k]
+			u.MemoryBytes = int64(parseMetricValue(series.Value))
+			usage[k] = u
+		}
+	}
+	return usage, nil
+}
@@ -0,0 +1,31 @@
+package prometheus
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetUsage(t *testing.T) {
+	service := fakePrometheus(t, [][2]string{
+		{`sum by (node) (rate(container_cpu_usage_seconds_total`, `[{"metric":{"node":"node-1"},"value":[0,"0.5"]}]`},
+		{`sum by (node) (container_memory_working_set_bytes`, `[{"metric":{"node":"node-1"},"value":[0,"1073741824"]}]`},
+		{`sum by (namespace, pod) (rate(`, `[{"metric":{"namespace":"apps","pod":"api-0"},"value":[0,"0.025"]},{"metric":{},"value":[0,"1"]}]`},
+		{`sum by (namespace, pod) (container_memory`, `[{"metric":{"namespace":"apps","pod":"api-0"},"value":[0,"1048576"]}]`},
+	})
+
+	nodes, err := service.GetNodeUsage(context.Background())
+	if err != nil {
+		t.Fatalf("GetNodeUsage: %v", err)
+	}
+	if got := nodes["node-1"]; got.CPUMillicores != 500 || got.MemoryBytes != 1<<30 {
+		t.Errorf("node usage = %+v", got)
+	}
+
+	pods, err := service.GetPodUsage(context.Background())
+	if err != nil {
+		t.Fatalf("GetPodUsage: %v", err)
+	}
+	if len(pods) != 1 || pods["apps/api-0"].CPUMillicores != 25 || pods["apps/api-0"].MemoryBytes != 1<<20 {
+		t.Errorf("pod usage = %+v", pods)
+	}
+}
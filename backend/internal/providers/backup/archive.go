@@ -0,0 +1,145 @@
+package backup
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression levels used when a job doesn't set one
+const (
+	defaultGzipLevel = 6
+	defaultZstdLevel = 3
+)
+
+// ErrInvalidArchive is returned for unsupported compression and encryption settings
+var ErrInvalidArchive = errors.New("invalid backup archive settings")
+
+// compressionSettings validates a job's compression and resolves its level
+func compressionSettings(metadata Metadata) (CompressionType, int, error) {
+	level := metadata.CompressionLevel
+	switch metadata.CompressionType {
+	case "", CompressionNone:
+		return CompressionNone, 0, nil
+	case CompressionGzip:
+		if level == 0 {
+			level = defaultGzipLevel
+		}
+		if level < gzip.BestSpeed || level > gzip.BestCompression {
+			return "", 0, fmt.Errorf("%w: gzip compression level must be between 1 and 9", ErrInvalidArchive)
+		}
+		return CompressionGzip, level, nil
+	case CompressionZstd:
+		if level == 0 {
+			level = defaultZstdLevel
+		}
+		if level < 1 || level > 22 {
+			return "", 0, fmt.Errorf("%w: zstd compression level must be between 1 and 22", ErrInvalidArchive)
+		}
+		return CompressionZstd, level, nil
+	default:
+		return "", 0, fmt.Errorf("%w: unsupported compression %s (use none, gzip or zstd)", ErrInvalidArchive, metadata.CompressionType)
+	}
+}
+
+// jobPrefix is where a job's backup archives are stored, so storage retention can be set
+// per job
+func jobPrefix(jobID string) string {
+	return "backups/" + jobID + "/"
+}
+
+// archiveName returns the storage key of a backup archive
+func archiveName(jobID, historyID string, compression CompressionType, encrypted bool) string {
+	name := jobPrefix(jobID) + historyID + ".tar"
+	switch compression {
+	case CompressionGzip:
+		name += ".gz"
+	case CompressionZstd:
+		name += ".zst"
+	}
+	if encrypted {
+		name += ".enc"
+	}
+	return name
+}
+
+// archiveWriter compresses and then encrypts what is written to it
+type archiveWriter struct {
+	compressor io.WriteCloser
+	encryptor  io.WriteCloser
+}
+
+// newArchiveWriter writes a backup archive to w; a nil key writes it unencrypted. Close
+// flushes the archive and must be called.
+func newArchiveWriter(w io.Writer, compression CompressionType, level int, key []byte) (io.WriteCloser, error) {
+	archive := &archiveWriter{}
+	if key != nil {
+		encryptor, err := newEncryptWriter(w, key)
+		if err != nil {
+			return nil, err
+		}
+		archive.encryptor = encryptor
+		w = encryptor
+	}
+
+	var err error
+	switch compression {
+	case CompressionGzip:
+		archive.compressor, err = gzip.NewWriterLevel(w, level)
+	case CompressionZstd:
+		archive.compressor, err = zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	default:
+		archive.compressor = nopWriteCloser{w}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+func (a *archiveWriter) Write(p []byte) (int, error) {
+	return a.compressor.Write(p)
+}
+
+func (a *archiveWriter) Close() error {
+	if err := a.compressor.Close(); err != nil {
+		return err
+	}
+	if a.encryptor != nil {
+		return a.encryptor.Close()
+	}
+	return nil
+}
+
+// newArchiveReader reads back an archive written by newArchiveWriter
+func newArchiveReader(r io.Reader, compression CompressionType, key []byte) (io.ReadCloser, error) {
+	if key != nil {
+		decrypted, err := newDecryptReader(r, key)
+		if err != nil {
+			return nil, err
+		}
+		r = decrypted
+	}
+
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionZstd:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return io.NopCloser(r), nil
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,211 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	data := make([]byte, 3*encryptionChunkSize+123)
+	rand.Read(data[:encryptionChunkSize]) // incompressible, then zeros
+
+	for _, tc := range []struct {
+		name        string
+		compression CompressionType
+		level       int
+		size        int
+		encrypted   bool
+	}{
+		{"plain", CompressionNone, 0, len(data), false},
+		{"gzip", CompressionGzip, 9, len(data), false},
+		{"zstd encrypted", CompressionZstd, 19, len(data), true},
+		{"encrypted exact chunk", CompressionNone, 0, encryptionChunkSize, true},
+		{"encrypted empty", CompressionGzip, 1, 0, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var key []byte
+			if tc.encrypted {
+				var err error
+				if _, key, err = newEncryption(EncryptionKeyPassphrase, "correct horse", ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var archive bytes.Buffer
+			w, err := newArchiveWriter(&archive, tc.compression, tc.level, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write(data[:tc.size]); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if tc.encrypted && tc.size > 0 && bytes.Contains(archive.Bytes(), data[:min(tc.size, 64)]) {
+				t.Fatal("archive contains plaintext")
+			}
+
+			r, err := newArchiveReader(bytes.NewReader(archive.Bytes()), tc.compression, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data[:tc.size]) {
+				t.Fatalf("round trip returned %d bytes, want %d", len(got), tc.size)
+			}
+
+			if tc.encrypted && tc.size > encryptionChunkSize {
+				// Dropping the last chunk must not go unnoticed
+				truncated := archive.Bytes()[:archive.Len()-100]
+				r, err := newArchiveReader(bytes.NewReader(truncated), tc.compression, key)
+				if err == nil {
+					_, err = io.ReadAll(r)
+				}
+				if err == nil {
+					t.Error("expected a truncated archive to fail")
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptionKeys(t *testing.T) {
+	encryption, _, err := newEncryption(EncryptionKeyPassphrase, "correct horse", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encryption.KDF != "scrypt" || encryption.Salt == "" || encryption.KeyID == "" {
+		t.Fatalf("unexpected encryption metadata %+v", encryption)
+	}
+	if _, err := encryption.Key("", "server key"); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Errorf("expected a missing passphrase to be required, got %v", err)
+	}
+	if _, err := encryption.Key("wrong horse", ""); !errors.Is(err, ErrWrongEncryptionKey) {
+		t.Errorf("expected a wrong passphrase to be refused, got %v", err)
+	}
+	if _, err := encryption.Key("correct horse", ""); err != nil {
+		t.Errorf("expected the passphrase to open the backup, got %v", err)
+	}
+
+	configured, _, err := newEncryption(EncryptionKeyConfig, "", "server key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := configured.Key("", ""); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Errorf("expected an unset key to be required, got %v", err)
+	}
+	if _, err := configured.Key("", "rotated key"); !errors.Is(err, ErrWrongEncryptionKey) {
+		t.Errorf("expected a changed key to be refused, got %v", err)
+	}
+}
+
+func TestEncryptedBackupJobs(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "backup.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m := NewManager(db, nil)
+
+	job := &Job{Name: "db", Type: JobTypeFull, Source: SourceSQLite, Metadata: Metadata{
+		CompressionType: CompressionBzip2,
+	}}
+	if _, err := m.CreateJob(job); !errors.Is(err, ErrInvalidArchive) {
+		t.Errorf("expected bzip2 to be refused, got %v", err)
+	}
+	job.Metadata = Metadata{CompressionType: CompressionZstd, EncryptionEnabled: true}
+	if _, err := m.CreateJob(job); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Errorf("expected encryption without a configured key to be refused, got %v", err)
+	}
+
+	job.Metadata.EncryptionKeySource = EncryptionKeyPassphrase
+	job.Metadata.Passphrase = "correct horse"
+	created, err := m.CreateJob(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Sanitized().Metadata.Passphrase != maskedPassphrase {
+		t.Error("expected the passphrase to be masked")
+	}
+
+	// A masked passphrase keeps the stored one
+	update := created.Sanitized()
+	update.Metadata.CompressionLevel = 10
+	if _, err := m.UpdateJob(&update); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := m.GetJob(created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Metadata.Passphrase != "correct horse" || stored.Metadata.CompressionLevel != 10 {
+		t.Fatalf("unexpected stored metadata %+v", stored.Metadata)
+	}
+
+	if err := m.RunJob(created.ID); err != nil {
+		t.Fatal(err)
+	}
+	history, err := m.GetHistory(created.ID)
+	if err != nil || len(history) != 1 {
+		t.Fatalf("expected one backup, got %d (%v)", len(history), err)
+	}
+	backup := history[0]
+	// Nothing is claimed about an archive before one is written
+	if backup.Compression != CompressionNone || backup.Encryption != nil {
+		t.Fatalf("unexpected archive settings before upload %+v", backup)
+	}
+
+	storage := newMemStorage()
+	m.storages["mem"] = storage
+	location, err := m.UploadBackup(context.Background(), "mem", backup.ID, bytes.NewReader([]byte("dump")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup, err = m.GetBackup(backup.ID); err != nil {
+		t.Fatal(err)
+	}
+	if backup.Location != location || backup.Compression != CompressionZstd || backup.CompressionLevel != 10 ||
+		backup.Encryption == nil || backup.Encryption.KeySource != EncryptionKeyPassphrase {
+		t.Fatalf("unexpected backup archive settings %+v", backup)
+	}
+	archive, ok := storage.data[archiveName(created.ID, backup.ID, CompressionZstd, true)]
+	if !ok || bytes.Contains(archive, []byte("dump")) {
+		t.Fatalf("archive not stored encrypted under its name: %v", storage.objects)
+	}
+	key, err := backup.Encryption.Key("correct horse", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := newArchiveReader(bytes.NewReader(archive), CompressionZstd, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump, err := io.ReadAll(reader); err != nil || string(dump) != "dump" {
+		t.Errorf("archive = %q, %v", dump, err)
+	}
+
+	if err := m.VerifyBackup(backup.ID, ""); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Errorf("expected verification to require the passphrase, got %v", err)
+	}
+	if err := m.VerifyBackup(backup.ID, "wrong horse"); !errors.Is(err, ErrWrongEncryptionKey) {
+		t.Errorf("expected a wrong passphrase to be refused, got %v", err)
+	}
+	if _, err := m.StartRecovery(backup.ID, RecoveryOptions{}); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Errorf("expected recovery to require the passphrase, got %v", err)
+	}
+	if err := m.VerifyBackup(backup.ID, "correct horse"); err != nil {
+		t.Errorf("expected verification to start, got %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encrypted archives start with encryptionMagic and a random nonce prefix, followed by
+// chunks of up to encryptionChunkSize bytes, each sealed with AES-256-GCM under a nonce
+// of the prefix, the chunk number and a flag marking the last chunk. Truncated, reordered
+// or modified archives fail to open.
+const (
+	EncryptionAlgorithm = "aes-256-gcm"
+	encryptionKDF       = "scrypt"
+	encryptionMagic     = "DBK1"
+	encryptionChunkSize = 64 << 10
+	noncePrefixSize     = 7
+	minPassphraseLength = 8
+)
+
+var (
+	// ErrEncryptionKeyRequired is returned when an encrypted backup is written, verified or
+	// recovered without its key
+	ErrEncryptionKeyRequired = errors.New("backup encryption key required")
+	// ErrWrongEncryptionKey is returned when a key doesn't match the one a backup was encrypted with
+	ErrWrongEncryptionKey = errors.New("encryption key does not match the backup")
+)
+
+// newEncryption picks a fresh salt for a backup and derives its key
+func newEncryption(source EncryptionKeySource, passphrase, configKey string) (*Encryption, []byte, error) {
+	encryption := &Encryption{Algorithm: EncryptionAlgorithm, KeySource: source}
+	if source == EncryptionKeyPassphrase {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+		encryption.KDF = encryptionKDF
+		encryption.Salt = base64.StdEncoding.EncodeToString(salt)
+	}
+	key, err := encryption.deriveKey(passphrase, configKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	encryption.KeyID = keyID(key)
+	return encryption, key, nil
+}
+
+// Key derives a backup's key from a passphrase or the configured key, whichever it was
+// encrypted with, and checks it against the recorded key ID
+func (e *Encryption) Key(passphrase, configKey string) ([]byte, error) {
+	key, err := e.deriveKey(passphrase, configKey)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(keyID(key)), []byte(e.KeyID)) {
+		return nil, ErrWrongEncryptionKey
+	}
+	return key, nil
+}
+
+func (e *Encryption) deriveKey(passphrase, configKey string) ([]byte, error) {
+	switch e.KeySource {
+	case EncryptionKeyConfig:
+		if configKey == "" {
+			return nil, fmt.Errorf("%w: BACKUP_ENCRYPTION_KEY is not set", ErrEncryptionKeyRequired)
+		}
+		key := sha256.Sum256([]byte(configKey))
+		return key[:], nil
+	case EncryptionKeyPassphrase:
+		if passphrase == "" {
+			return nil, fmt.Errorf("%w: passphrase is required", ErrEncryptionKeyRequired)
+		}
+		salt, err := base64.StdEncoding.DecodeString(e.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid salt: %w", err)
+		}
+		return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	default:
+		return nil, fmt.Errorf("unsupported encryption key source: %s", e.KeySource)
+	}
+}
+
+// keyID identifies a key without revealing it
+func keyID(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("denshimon backup key"))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce of a chunk
+func chunkNonce(prefix []byte, chunk uint32, last bool) []byte {
+	nonce := make([]byte, 0, noncePrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, chunk)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// encryptWriter seals what is written to it in chunks
+type encryptWriter struct {
+	w      io.Writer
+	gcm    cipher.AEAD
+	prefix []byte
+	chunk  uint32
+	buf    []byte
+}
+
+// newEncryptWriter encrypts to w; Close seals the last chunk and must be called
+func newEncryptWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	if _, err := w.Write(append([]byte(encryptionMagic), prefix...)); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, gcm: gcm, prefix: prefix, buf: make([]byte, 0, encryptionChunkSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data follows, so the last one can be flagged
+		if len(e.buf) == encryptionChunkSize {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(e.buf[len(e.buf):encryptionChunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (e *encryptWriter) seal(last bool) error {
+	sealed := e.gcm.Seal(nil, chunkNonce(e.prefix, e.chunk, last), e.buf, nil)
+	e.chunk++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+func (e *encryptWriter) Close() error {
+	return e.seal(true)
+}
+
+// decryptReader opens an archive written by encryptWriter
+type decryptReader struct {
+	r       io.Reader
+	gcm     cipher.AEAD
+	prefix  []byte
+	chunk   uint32
+	buf     []byte // a sealed chunk and one byte read ahead
+	pending int    // bytes read ahead into buf
+	plain   []byte
+	done    bool
+}
+
+// newDecryptReader decrypts r, failing when it was modified or cut short
+func newDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, len(encryptionMagic)+noncePrefixSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read encryption header: %w", err)
+	}
+	if !bytes.HasPrefix(header, []byte(encryptionMagic)) {
+		return nil, errors.New("not an encrypted backup")
+	}
+	return &decryptReader{
+		r:      r,
+		gcm:    gcm,
+		prefix: header[len(encryptionMagic):],
+		buf:    make([]byte, encryptionChunkSize+gcm.Overhead()+1),
+	}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// open decrypts the next chunk. It is the last one when no more than a sealed chunk is
+// left, which is known by reading one byte past it.
+func (d *decryptReader) open() error {
+	full := encryptionChunkSize + d.gcm.Overhead()
+	n, err := io.ReadFull(d.r, d.buf[d.pending:])
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	n += d.pending
+
+	last := n <= full
+	plain, err := d.gcm.Open(nil, chunkNonce(d.prefix, d.chunk, last), d.buf[:min(n, full)], nil)
+	if err != nil {
+		return errors.New("backup is corrupt or was encrypted with another key")
+	}
+	d.chunk++
+	d.plain = plain
+	d.done = last
+	d.pending = 0
+	if !last {
+		d.buf[0] = d.buf[full]
+		d.pending = 1
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+)
+
+// Hook timeouts and how much of a hook's output is kept
+const (
+	defaultHookTimeout = time.Minute
+	maxHookTimeout     = time.Hour
+	maxHookOutput      = 64 << 10
+)
+
+// ErrInvalidHook is returned for backup hooks missing a command or a target pod
+var ErrInvalidHook = errors.New("invalid backup hook")
+
+// HookPhase is when a hook runs relative to the backup
+type HookPhase string
+
+const (
+	HookPhasePre  HookPhase = "pre"
+	HookPhasePost HookPhase = "post"
+)
+
+// Hooks are commands run in the application's pods around a backup so it is
+// application-consistent, e.g. pg_backup_start and pg_backup_stop, redis BGSAVE, or
+// pausing writes. A failed pre hook fails the backup unless it continues on error. Post
+// hooks run once the snapshot is taken or the dump written, and also after a failed
+// backup whose pre hooks ran, so whatever they quiesced is resumed.
+type Hooks struct {
+	Pre  []Hook `json:"pre,omitempty"`
+	Post []Hook `json:"post,omitempty"`
+}
+
+// Hook is a command run in a pod picked by name or, failing that, as the first running
+// pod matching a label selector
+type Hook struct {
+	Name            string   `json:"name,omitempty"`
+	Namespace       string   `json:"namespace,omitempty"` // the job's namespace; no other is allowed
+	Pod             string   `json:"pod,omitempty"`
+	Selector        string   `json:"selector,omitempty"`
+	Container       string   `json:"container,omitempty"` // defaults to the pod's default container
+	Command         []string `json:"command"`
+	TimeoutSeconds  int      `json:"timeoutSeconds,omitempty"`
+	ContinueOnError bool     `json:"continueOnError,omitempty"` // pre hooks only
+}
+
+// HookResult is the outcome of a hook, recorded in the backup's history entry
+type HookResult struct {
+	Name       string    `json:"name"`
+	Phase      HookPhase `json:"phase"`
+	Namespace  string    `json:"namespace"`
+	Pod        string    `json:"pod,omitempty"`
+	Container  string    `json:"container,omitempty"`
+	Command    []string  `json:"command"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	ExitCode   *int      `json:"exitCode,omitempty"`
+	Stdout     string    `json:"stdout,omitempty"` // the first 64 KiB
+	Stderr     string    `json:"stderr,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// podExecutor finds and runs commands in pods; *k8s.Client implements it
+type podExecutor interface {
+	SelectPods(ctx context.Context, selector k8s.PodSelector) ([]k8s.PodRef, error)
+	ExecInPod(ctx context.Context, namespace, pod, container string, command []string, stdout, stderr io.Writer) error
+}
+
+// validateHooks checks every hook of a job has a command, a pod in the job's namespace to
+// run in and a sane timeout
+func validateHooks(job *Job) error {
+	if job.Metadata.Hooks == nil {
+		return nil
+	}
+	for _, phase := range []struct {
+		phase HookPhase
+		hooks []Hook
+	}{{HookPhasePre, job.Metadata.Hooks.Pre}, {HookPhasePost, job.Metadata.Hooks.Post}} {
+		for i, hook := range phase.hooks {
+			name := hookName(phase.phase, i, hook)
+			switch {
+			case len(hook.Command) == 0 || hook.Command[0] == "":
+				return fmt.Errorf("%w: %s has no command", ErrInvalidHook, name)
+			case hook.Pod == "" && hook.Selector == "":
+				return fmt.Errorf("%w: %s needs a pod or a selector", ErrInvalidHook, name)
+			case job.Metadata.Namespace == "":
+				return fmt.Errorf("%w: %s needs the job's namespace", ErrInvalidHook, name)
+			case hook.Namespace != "" && hook.Namespace != job.Metadata.Namespace:
+				return fmt.Errorf("%w: %s must run in the job's namespace %s", ErrInvalidHook, name, job.Metadata.Namespace)
+			case hook.TimeoutSeconds < 0 || time.Duration(hook.TimeoutSeconds)*time.Second > maxHookTimeout:
+				return fmt.Errorf("%w: %s timeout must be at most %s", ErrInvalidHook, name, maxHookTimeout)
+			}
+		}
+	}
+	return nil
+}
+
+func hookName(phase HookPhase, i int, hook Hook) string {
+	if hook.Name != "" {
+		return hook.Name
+	}
+	return fmt.Sprintf("%s hook %d", phase, i+1)
+}
+
+// runHooks runs a phase's hooks in order, appending their results to the backup's
+// history entry. It stops at the first failed pre hook that doesn't continue on error and
+// returns its error; post hooks all run and their failures raise an alert instead.
+func (m *Manager) runHooks(ctx context.Context, job *Job, historyID string, phase HookPhase, results *[]HookResult) error {
+	if job.Metadata.Hooks == nil {
+		return nil
+	}
+	hooks := job.Metadata.Hooks.Pre
+	if phase == HookPhasePost {
+		hooks = job.Metadata.Hooks.Post
+	}
+
+	for i, hook := range hooks {
+		result := m.runHook(ctx, job, hook, hookName(phase, i, hook), phase)
+		*results = append(*results, result)
+		m.recordHookResults(historyID, *results)
+		if result.Error == "" {
+			continue
+		}
+		if phase == HookPhasePost {
+			m.raiseAlert(AlertTypeBackupFailed, SeverityCritical,
+				fmt.Sprintf("Post-backup hook %s of job %s failed: %s", result.Name, job.Name, result.Error), job.ID)
+			continue
+		}
+		if !hook.ContinueOnError {
+			return fmt.Errorf("pre-backup hook %s failed: %s", result.Name, result.Error)
+		}
+	}
+	return nil
+}
+
+// runHook runs one hook, capturing its output
+func (m *Manager) runHook(ctx context.Context, job *Job, hook Hook, name string, phase HookPhase) (result HookResult) {
+	result = HookResult{
+		Name:      name,
+		Phase:     phase,
+		Namespace: hook.Namespace,
+		Pod:       hook.Pod,
+		Container: hook.Container,
+		Command:   hook.Command,
+		StartedAt: time.Now(),
+	}
+	if result.Namespace == "" {
+		result.Namespace = job.Metadata.Namespace
+	}
+	defer func() { result.DurationMs = time.Since(result.StartedAt).Milliseconds() }()
+
+	if m.executor == nil {
+		result.Error = "kubernetes client not available"
+		return result
+	}
+	timeout := defaultHookTimeout
+	if hook.TimeoutSeconds > 0 {
+		timeout = time.Duration(hook.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if result.Pod == "" {
+		pods, err := m.executor.SelectPods(ctx, k8s.PodSelector{Namespace: result.Namespace, LabelSelector: hook.Selector, Status: "Running"})
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if len(pods) == 0 {
+			result.Error = fmt.Sprintf("no running pod matches %s", hook.Selector)
+			return result
+		}
+		result.Pod = pods[0].Name
+	}
+
+	stdout, stderr := &cappedBuffer{}, &cappedBuffer{}
+	err := m.executor.ExecInPod(ctx, result.Namespace, result.Pod, hook.Container, hook.Command, stdout, stderr)
+	result.Stdout, result.Stderr = stdout.String(), stderr.String()
+	var exitErr *k8s.ExitError
+	switch {
+	case err == nil:
+		code := 0
+		result.ExitCode = &code
+	case errors.As(err, &exitErr):
+		result.ExitCode = &exitErr.Code
+		result.Error = err.Error()
+	case ctx.Err() != nil:
+		result.Error = fmt.Sprintf("timed out after %s", timeout)
+	default:
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// recordHookResults stores the hook results of a backup so far
+func (m *Manager) recordHookResults(historyID string, results []HookResult) {
+	resultsJSON, _ := json.Marshal(results)
+	m.db.Exec("UPDATE backup_history SET hooks = ? WHERE id = ?", resultsJSON, historyID)
+}
+
+// cappedBuffer keeps the first maxHookOutput bytes written to it
+type cappedBuffer struct {
+	buf       []byte
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := maxHookOutput - len(b.buf); room < len(p) {
+		b.buf = append(b.buf, p[:max(room, 0)]...)
+		b.truncated = true
+		return len(p), nil
+	}
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
+
+func (b *cappedBuffer) String() string {
+	if b.truncated {
+		return string(b.buf) + "\n[output truncated]"
+	}
+	return string(b.buf)
+}
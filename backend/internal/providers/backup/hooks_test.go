@@ -0,0 +1,195 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/archellir/denshimon/internal/bus"
+	"github.com/archellir/denshimon/internal/k8s"
+)
+
+// fakeExecutor runs hooks against a fixed set of running pods
+type fakeExecutor struct {
+	pods []k8s.PodRef
+	ran  []string // pod: command
+}
+
+func (f *fakeExecutor) SelectPods(ctx context.Context, selector k8s.PodSelector) ([]k8s.PodRef, error) {
+	var pods []k8s.PodRef
+	for _, pod := range f.pods {
+		if pod.Namespace == selector.Namespace && strings.HasPrefix(pod.Name, strings.TrimPrefix(selector.LabelSelector, "app=")) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+func (f *fakeExecutor) ExecInPod(ctx context.Context, namespace, pod, container string, command []string, stdout, stderr io.Writer) error {
+	f.ran = append(f.ran, pod+": "+strings.Join(command, " "))
+	switch command[0] {
+	case "fail":
+		fmt.Fprint(stderr, "not today")
+		return &k8s.ExitError{Code: 3}
+	case "hang":
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	fmt.Fprint(stdout, strings.Repeat("x", maxHookOutput+1))
+	return nil
+}
+
+func TestBackupHooks(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "backup.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m := NewManager(db, nil)
+	executor := &fakeExecutor{pods: []k8s.PodRef{{Namespace: "db", Name: "postgres-0"}}}
+	m.executor = executor
+	ctx := context.Background()
+
+	if _, err := m.CreateJob(&Job{Name: "bad", Type: JobTypeSnapshot, Source: SourcePersistentVolume, Metadata: Metadata{
+		Hooks: &Hooks{Pre: []Hook{{Command: []string{"sync"}, Namespace: "db"}}},
+	}}); !errors.Is(err, ErrInvalidHook) {
+		t.Errorf("expected a hook without a pod to be refused, got %v", err)
+	}
+
+	// Hooks can't reach pods outside the job's namespace
+	for _, hooks := range []*Hooks{
+		{Pre: []Hook{{Pod: "api-0", Namespace: "kube-system", Command: []string{"sh"}}}},
+		{Post: []Hook{{Selector: "app=api", Namespace: "prod", Command: []string{"sh"}}}},
+	} {
+		if _, err := m.CreateJob(&Job{Name: "escape", Type: JobTypeSnapshot, Source: SourcePersistentVolume, Metadata: Metadata{
+			Namespace: "db",
+			PVCName:   "data-postgres-0",
+			Hooks:     hooks,
+		}}); !errors.Is(err, ErrInvalidHook) {
+			t.Errorf("expected a hook in another namespace to be refused, got %v", err)
+		}
+	}
+	if _, err := m.CreateJob(&Job{Name: "no-namespace", Type: JobTypeFull, Source: SourceSQLite, Metadata: Metadata{
+		Hooks: &Hooks{Pre: []Hook{{Pod: "api-0", Namespace: "prod", Command: []string{"sh"}}}},
+	}}); !errors.Is(err, ErrInvalidHook) {
+		t.Errorf("expected hooks of a job without a namespace to be refused, got %v", err)
+	}
+
+	job, err := m.CreateJob(&Job{Name: "pg", Type: JobTypeSnapshot, Source: SourcePersistentVolume, Metadata: Metadata{
+		Namespace: "db",
+		PVCName:   "data-postgres-0",
+		Hooks: &Hooks{
+			Pre: []Hook{
+				{Name: "checkpoint", Selector: "app=postgres", Command: []string{"psql", "-c", "CHECKPOINT"}},
+				{Name: "optional", Pod: "postgres-0", Command: []string{"fail"}, ContinueOnError: true},
+				{Name: "freeze", Pod: "postgres-0", Command: []string{"hang"}, TimeoutSeconds: 1},
+				{Name: "never", Pod: "postgres-0", Command: []string{"true"}},
+			},
+			Post: []Hook{
+				{Name: "missing", Selector: "app=redis", Command: []string{"true"}},
+				{Name: "thaw", Pod: "postgres-0", Command: []string{"thaw"}},
+			},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RunJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	history, err := m.GetHistory(job.ID)
+	if err != nil || len(history) != 1 {
+		t.Fatalf("expected one backup, got %d (%v)", len(history), err)
+	}
+	backupID := history[0].ID
+
+	// Without a cluster the run fails before its hooks, so they are run here
+	var results []HookResult
+	err = m.runHooks(ctx, job, backupID, HookPhasePre, &results)
+	if err == nil || !strings.Contains(err.Error(), "freeze") {
+		t.Fatalf("expected the timed out hook to fail the backup, got %v", err)
+	}
+	if err := m.runHooks(ctx, job, backupID, HookPhasePost, &results); err != nil {
+		t.Fatalf("expected post hook failures not to fail the backup, got %v", err)
+	}
+	if want := []string{"postgres-0: psql -c CHECKPOINT", "postgres-0: fail", "postgres-0: hang", "postgres-0: thaw"}; strings.Join(executor.ran, ",") != strings.Join(want, ",") {
+		t.Errorf("ran %v, want %v", executor.ran, want)
+	}
+
+	backup, err := m.GetBackup(backupID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backup.Hooks) != 5 {
+		t.Fatalf("expected 5 recorded hook results, got %+v", backup.Hooks)
+	}
+	checkpoint, optional, freeze, missing, thaw := backup.Hooks[0], backup.Hooks[1], backup.Hooks[2], backup.Hooks[3], backup.Hooks[4]
+	if checkpoint.Pod != "postgres-0" || checkpoint.ExitCode == nil || *checkpoint.ExitCode != 0 || !strings.HasSuffix(checkpoint.Stdout, "[output truncated]") {
+		t.Errorf("unexpected checkpoint result %+v", checkpoint)
+	}
+	if optional.ExitCode == nil || *optional.ExitCode != 3 || optional.Stderr != "not today" || optional.Error == "" {
+		t.Errorf("unexpected optional result %+v", optional)
+	}
+	if freeze.Error != "timed out after 1s" || freeze.ExitCode != nil {
+		t.Errorf("unexpected freeze result %+v", freeze)
+	}
+	if missing.Phase != HookPhasePost || missing.Error != "no running pod matches app=redis" {
+		t.Errorf("unexpected missing result %+v", missing)
+	}
+	if thaw.Phase != HookPhasePost || thaw.Error != "" {
+		t.Errorf("unexpected thaw result %+v", thaw)
+	}
+
+	alerts, err := m.GetAlerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hookAlerts int
+	for _, alert := range alerts {
+		if strings.Contains(alert.Message, "Post-backup hook missing") {
+			hookAlerts++
+		}
+	}
+	if hookAlerts != 1 {
+		t.Errorf("expected an alert for the failed post hook, got %+v", alerts)
+	}
+}
+
+func TestPublishFinished(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "backup.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m := NewManager(db, nil)
+	events := bus.New()
+	m.SetBus(events)
+	finished := make(chan History, 1)
+	bus.On(events, func(event Finished) { finished <- event.History })
+
+	// Snapshot jobs fail straight away without a cluster
+	job, err := m.CreateJob(&Job{Name: "pg", Type: JobTypeSnapshot, Source: SourcePersistentVolume, Metadata: Metadata{
+		Namespace: "db",
+		PVCName:   "data-postgres-0",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RunJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case history := <-finished:
+		if history.JobID != job.ID || history.Status != StatusFailed {
+			t.Errorf("finished = %+v", history)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the failed run to be reported")
+	}
+}
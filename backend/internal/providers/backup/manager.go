@@ -1,23 +1,152 @@
 package backup
 
 import (
+	"context"
 	"database/sql"
+	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/archellir/denshimon/internal/bus"
+	"github.com/archellir/denshimon/internal/database/migrate"
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/archellir/denshimon/internal/secretbox"
 	"github.com/google/uuid"
 )
 
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations is the schema of the backup tables
+var Migrations = migrate.NewSource("backup", migrationFiles, "migrations")
+
 // Manager manages backup operations
 type Manager struct {
-	db *sql.DB
+	db        *sql.DB
+	k8sClient *k8s.Client
+	storages  map[string]StorageBackend
+	configs   map[string]StorageConfig
+	mutex     sync.RWMutex
+
+	box           *secretbox.Box // seals job passphrases at rest
+	encryptionKey string         // key of jobs encrypted with the configured key
+	executor      podExecutor    // runs backup hooks; nil without a cluster
+	bus           *bus.Bus       // finished runs are published on it, optional
+}
+
+// NewManager creates a new backup manager; k8sClient may be nil when no cluster is available
+func NewManager(db *sql.DB, k8sClient *k8s.Client) *Manager {
+	manager := &Manager{
+		db:        db,
+		k8sClient: k8sClient,
+		storages:  make(map[string]StorageBackend),
+		configs:   make(map[string]StorageConfig),
+	}
+	if k8sClient != nil {
+		manager.executor = k8sClient
+	}
+
+	if err := manager.initDB(); err != nil {
+		slog.Error("Failed to migrate backup tables", "error", err)
+	}
+
+	// Load configured storage backends
+	manager.loadStorageConfigs()
+
+	return manager
+}
+
+// SetEncryption sets the box job passphrases and storage credentials are sealed with at
+// rest, and reloads the storage backends sealed with it
+func (m *Manager) SetEncryption(box *secretbox.Box) {
+	m.box = box
+	m.loadStorageConfigs()
+}
+
+// SetEncryptionKey sets the key of backups encrypted with the configured key
+func (m *Manager) SetEncryptionKey(key string) {
+	m.encryptionKey = key
+}
+
+// SetBus sets the event bus finished backup runs are published on
+func (m *Manager) SetBus(b *bus.Bus) {
+	m.bus = b
+}
+
+// finished publishes a completed or failed backup run
+func (m *Manager) finished(historyID string) {
+	if m.bus == nil {
+		return
+	}
+	backup, err := m.GetBackup(historyID)
+	if err != nil {
+		slog.Warn("Failed to read finished backup", "backup", historyID, "error", err)
+		return
+	}
+	m.bus.Publish(Finished{*backup})
+}
+
+// initDB migrates the backup tables to the latest schema version
+func (m *Manager) initDB() error {
+	return migrate.Up(context.Background(), m.db, Migrations)
+}
+
+// loadStorageConfigs loads persisted storage backends and instantiates them
+func (m *Manager) loadStorageConfigs() {
+	rows, err := m.db.Query(`
+		SELECT id, name, type, config, created_at, updated_at FROM backup_storage
+	`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var config StorageConfig
+		var configJSON string
+
+		if err := rows.Scan(&config.ID, &config.Name, &config.Type, &configJSON, &config.CreatedAt, &config.UpdatedAt); err != nil {
+			continue
+		}
+		// Sealed configs can't be opened until the box is set
+		if configJSON, err = m.box.Decrypt(configJSON); err != nil {
+			if m.box != nil {
+				slog.Warn("Failed to decrypt backup storage config", "storage", config.Name, "error", err)
+			}
+			continue
+		}
+		if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+			continue
+		}
+
+		backend, err := newStorageBackend(config)
+		if err != nil {
+			continue
+		}
+
+		m.mutex.Lock()
+		m.configs[config.ID] = config
+		m.storages[config.ID] = backend
+		m.mutex.Unlock()
+	}
 }
 
-// NewManager creates a new backup manager
-func NewManager(db *sql.DB) *Manager {
-	return &Manager{
-		db: db,
+// newStorageBackend creates a storage backend for the given configuration
+func newStorageBackend(config StorageConfig) (StorageBackend, error) {
+	switch config.Type {
+	case StorageTypeS3:
+		if config.S3 == nil {
+			return nil, fmt.Errorf("s3 configuration is required")
+		}
+		return NewS3Storage(*config.S3)
+	default:
+		return nil, fmt.Errorf("unsupported storage type: %s", config.Type)
 	}
 }
 
@@ -65,6 +194,7 @@ func (m *Manager) ListJobs() ([]*Job, error) {
 		if err := json.Unmarshal(metadataJSON, &job.Metadata); err != nil {
 			return nil, err
 		}
+		m.openPassphrase(job)
 
 		jobs = append(jobs, job)
 	}
@@ -80,14 +210,35 @@ func (m *Manager) CreateJob(job *Job) (*Job, error) {
 	job.CreatedAt = time.Now()
 	job.UpdatedAt = time.Now()
 
-	scheduleJSON, _ := json.Marshal(job.Schedule)
+	if err := prepareSchedule(&job.Schedule, job.CreatedAt); err != nil {
+		return nil, err
+	}
+	job.NextRun = job.Schedule.NextScheduledRun
+	if job.Status == "" {
+		job.Status = StatusScheduled
+	}
+	if err := m.prepareArchive(job, nil); err != nil {
+		return nil, err
+	}
+	if err := validateHooks(job); err != nil {
+		return nil, err
+	}
+
+	if err := m.applyStorageRetention(context.Background(), job); err != nil {
+		return nil, fmt.Errorf("failed to apply retention to storage: %w", err)
+	}
+
+	scheduleJSON, nextRun := scheduleColumns(job.Schedule)
 	retentionJSON, _ := json.Marshal(job.Retention)
-	metadataJSON, _ := json.Marshal(job.Metadata)
+	metadataJSON, err := m.metadataColumn(job.Metadata)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err := m.db.Exec(`
-		INSERT INTO backup_jobs (id, name, type, source, schedule, status, retention, metadata, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, job.ID, job.Name, job.Type, job.Source, scheduleJSON, job.Status, retentionJSON, metadataJSON, job.CreatedAt, job.UpdatedAt)
+	_, err = m.db.Exec(`
+		INSERT INTO backup_jobs (id, name, type, source, schedule, status, next_run, retention, metadata, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, job.ID, job.Name, job.Type, job.Source, scheduleJSON, job.Status, nextRun, retentionJSON, metadataJSON, job.CreatedAt, job.UpdatedAt)
 
 	if err != nil {
 		return nil, err
@@ -133,6 +284,7 @@ func (m *Manager) GetJob(id string) (*Job, error) {
 	if err := json.Unmarshal(metadataJSON, &job.Metadata); err != nil {
 		return nil, err
 	}
+	m.openPassphrase(job)
 
 	return job, nil
 }
@@ -141,15 +293,41 @@ func (m *Manager) GetJob(id string) (*Job, error) {
 func (m *Manager) UpdateJob(job *Job) (*Job, error) {
 	job.UpdatedAt = time.Now()
 
-	scheduleJSON, _ := json.Marshal(job.Schedule)
+	if err := prepareSchedule(&job.Schedule, job.UpdatedAt); err != nil {
+		return nil, err
+	}
+	job.NextRun = job.Schedule.NextScheduledRun
+
+	existing, err := m.GetJob(job.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err := m.prepareArchive(job, existing); err != nil {
+		return nil, err
+	}
+	if err := validateHooks(job); err != nil {
+		return nil, err
+	}
+
+	if err := m.applyStorageRetention(context.Background(), job); err != nil {
+		return nil, fmt.Errorf("failed to apply retention to storage: %w", err)
+	}
+	if existing != nil && existing.Metadata.StorageID != job.Metadata.StorageID {
+		m.clearStorageRetention(context.Background(), existing)
+	}
+
+	scheduleJSON, nextRun := scheduleColumns(job.Schedule)
 	retentionJSON, _ := json.Marshal(job.Retention)
-	metadataJSON, _ := json.Marshal(job.Metadata)
+	metadataJSON, err := m.metadataColumn(job.Metadata)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err := m.db.Exec(`
-		UPDATE backup_jobs SET name = ?, type = ?, source = ?, schedule = ?, 
+	_, err = m.db.Exec(`
+		UPDATE backup_jobs SET name = ?, type = ?, source = ?, schedule = ?, next_run = ?,
 		       status = ?, retention = ?, metadata = ?, updated_at = ?
 		WHERE id = ?
-	`, job.Name, job.Type, job.Source, scheduleJSON, job.Status, retentionJSON, metadataJSON, job.UpdatedAt, job.ID)
+	`, job.Name, job.Type, job.Source, scheduleJSON, nextRun, job.Status, retentionJSON, metadataJSON, job.UpdatedAt, job.ID)
 
 	if err != nil {
 		return nil, err
@@ -158,17 +336,39 @@ func (m *Manager) UpdateJob(job *Job) (*Job, error) {
 	return job, nil
 }
 
-// DeleteJob deletes a backup job
+// DeleteJob deletes a backup job and the retention it set on its storage
 func (m *Manager) DeleteJob(id string) error {
-	_, err := m.db.Exec("DELETE FROM backup_jobs WHERE id = ?", id)
-	return err
+	job, err := m.GetJob(id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if _, err := m.db.Exec("DELETE FROM backup_jobs WHERE id = ?", id); err != nil {
+		return err
+	}
+	if job != nil {
+		m.clearStorageRetention(context.Background(), job)
+	}
+	return nil
 }
 
 // RunJob starts a backup job
 func (m *Manager) RunJob(id string) error {
+	job, err := m.GetJob(id)
+	if err != nil {
+		return err
+	}
+
+	// Archive settings are checked up front so a missing key fails the run before it
+	// starts. They are recorded by UploadBackup once an archive is written with them.
+	if !isSnapshotJob(job) {
+		if _, _, err := m.archiveSettings(job); err != nil {
+			return err
+		}
+	}
+
 	now := time.Now()
-	_, err := m.db.Exec(`
-		UPDATE backup_jobs SET status = ?, last_run = ?, updated_at = ?
+	_, err = m.db.Exec(`
+		UPDATE backup_jobs SET status = ?, last_run = ?, error = '', updated_at = ?
 		WHERE id = ?
 	`, StatusRunning, now, now, id)
 
@@ -179,23 +379,40 @@ func (m *Manager) RunJob(id string) error {
 	// Create history entry
 	historyID := uuid.New().String()
 	_, err = m.db.Exec(`
-		INSERT INTO backup_history (id, job_id, job_name, timestamp, type, source, status, size, duration, location, checksum, verification_status)
-		SELECT ?, id, name, ?, type, source, ?, 0, 0, '', '', ?
+		INSERT INTO backup_history (id, job_id, job_name, timestamp, type, source, status, size, duration, location, checksum, verification_status,
+		       compression, compression_level)
+		SELECT ?, id, name, ?, type, source, ?, 0, 0, '', '', ?, ?, 0
 		FROM backup_jobs WHERE id = ?
-	`, historyID, now, StatusRunning, VerificationNotVerified, id)
+	`, historyID, now, StatusRunning, VerificationNotVerified, CompressionNone, id)
+	if err != nil {
+		return err
+	}
+
+	if isSnapshotJob(job) {
+		go m.runSnapshotJob(job, historyID, now)
+		return nil
+	}
 
 	// In a real implementation, you would start the actual backup process here
 	// For now, we'll simulate completion after a short delay
 	go func() {
+		ctx := context.Background()
+		var hooks []HookResult
+		if err := m.runHooks(ctx, job, historyID, HookPhasePre, &hooks); err != nil {
+			m.runHooks(ctx, job, historyID, HookPhasePost, &hooks)
+			m.failJob(job, historyID, err)
+			return
+		}
 		time.Sleep(5 * time.Second)
-		m.completeJob(id, historyID)
+		m.runHooks(ctx, job, historyID, HookPhasePost, &hooks)
+		m.completeJob(id, historyID, "/"+archiveName(id, historyID, CompressionNone, false))
 	}()
 
-	return err
+	return nil
 }
 
 // completeJob simulates job completion
-func (m *Manager) completeJob(jobID, historyID string) {
+func (m *Manager) completeJob(jobID, historyID, location string) {
 	now := time.Now()
 	size := int64(1024 * 1024 * 100) // 100MB simulation
 	duration := 60                   // 60 seconds simulation
@@ -206,13 +423,14 @@ func (m *Manager) completeJob(jobID, historyID string) {
 		WHERE id = ?
 	`, StatusCompleted, size, duration, now, jobID)
 
-	// Update history entry
+	// Update history entry, keeping the location of an archive already uploaded
 	m.db.Exec(`
 		UPDATE backup_history SET status = ?, size = ?, duration = ?, 
-		       location = ?, checksum = ?, verification_status = ?
+		       location = CASE WHEN location = '' THEN ? ELSE location END, checksum = ?, verification_status = ?
 		WHERE id = ?
-	`, StatusCompleted, size, duration, fmt.Sprintf("/backups/%s.tar.gz", historyID),
+	`, StatusCompleted, size, duration, location,
 		fmt.Sprintf("sha256:%s", uuid.New().String()[:16]), VerificationVerified, historyID)
+	m.finished(historyID)
 }
 
 // CancelJob cancels a running backup job
@@ -228,16 +446,36 @@ func (m *Manager) CancelJob(id string) error {
 
 // GetHistory returns backup history, optionally filtered by job ID
 func (m *Manager) GetHistory(jobID string) ([]*History, error) {
+	if jobID == "" {
+		return m.queryHistory("", "")
+	}
+	return m.queryHistory("job_id", jobID)
+}
+
+// GetBackup returns a backup history entry
+func (m *Manager) GetBackup(id string) (*History, error) {
+	history, err := m.queryHistory("id", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, fmt.Errorf("backup %s not found: %w", id, sql.ErrNoRows)
+	}
+	return history[0], nil
+}
+
+// queryHistory returns history entries, newest first, optionally where column equals value
+func (m *Manager) queryHistory(column, value string) ([]*History, error) {
 	query := `
 		SELECT id, job_id, job_name, timestamp, type, source, status, size, duration,
-		       files_count, location, checksum, verification_status
+		       files_count, location, checksum, verification_status, compression, compression_level, encryption, hooks
 		FROM backup_history
 	`
 	args := []interface{}{}
 
-	if jobID != "" {
-		query += " WHERE job_id = ?"
-		args = append(args, jobID)
+	if column != "" {
+		query += " WHERE " + column + " = ?"
+		args = append(args, value)
 	}
 
 	query += " ORDER BY timestamp DESC"
@@ -252,15 +490,22 @@ func (m *Manager) GetHistory(jobID string) ([]*History, error) {
 	for rows.Next() {
 		h := &History{}
 		var filesCount sql.NullInt64
+		var encryption, hooks sql.NullString
 
 		err := rows.Scan(
 			&h.ID, &h.JobID, &h.JobName, &h.Timestamp, &h.Type, &h.Source,
 			&h.Status, &h.Size, &h.Duration, &filesCount, &h.Location,
-			&h.Checksum, &h.VerificationStatus,
+			&h.Checksum, &h.VerificationStatus, &h.Compression, &h.CompressionLevel, &encryption, &hooks,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if encryption.Valid {
+			json.Unmarshal([]byte(encryption.String), &h.Encryption)
+		}
+		if hooks.Valid {
+			json.Unmarshal([]byte(hooks.String), &h.Hooks)
+		}
 
 		if filesCount.Valid {
 			count := int(filesCount.Int64)
@@ -273,11 +518,212 @@ func (m *Manager) GetHistory(jobID string) ([]*History, error) {
 	return history, nil
 }
 
-// GetStorage returns storage backend information from real storage providers
-func (m *Manager) GetStorage() ([]*Storage, error) {
-	// TODO: Integrate with actual storage backends (local filesystem, S3, etc.)
-	// For now, return empty slice - frontend will handle mock data when needed
-	return []*Storage{}, nil
+// GetStorage returns usage information from all configured storage backends
+func (m *Manager) GetStorage(ctx context.Context) ([]*Storage, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	storage := []*Storage{}
+	for id, backend := range m.storages {
+		usage, err := backend.Usage(ctx)
+		if usage == nil {
+			usage = &Storage{Type: backend.Type(), Status: StorageStatusUnavailable}
+		}
+		if err != nil {
+			usage.Status = StorageStatusUnavailable
+		}
+		usage.ID = id
+		usage.Name = m.configs[id].Name
+		storage = append(storage, usage)
+	}
+
+	sort.Slice(storage, func(i, j int) bool { return storage[i].Name < storage[j].Name })
+	return storage, nil
+}
+
+// ListStorageConfigs returns all configured storage backends with credentials masked
+func (m *Manager) ListStorageConfigs() []StorageConfig {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	configs := make([]StorageConfig, 0, len(m.configs))
+	for _, config := range m.configs {
+		configs = append(configs, config.Sanitized())
+	}
+	return configs
+}
+
+// AddStorage validates, tests and persists a new storage backend
+func (m *Manager) AddStorage(ctx context.Context, config StorageConfig) (*StorageConfig, error) {
+	backend, err := newStorageBackend(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := backend.TestConnection(ctx); err != nil {
+		return nil, fmt.Errorf("storage connection test failed: %w", err)
+	}
+
+	if config.ID == "" {
+		config.ID = uuid.New().String()
+	}
+	config.CreatedAt = time.Now()
+	config.UpdatedAt = config.CreatedAt
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	// The config holds the S3 access and secret keys
+	sealed, err := m.box.Encrypt(string(configJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = m.db.Exec(`
+		INSERT INTO backup_storage (id, name, type, config, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, config.ID, config.Name, config.Type, sealed, config.CreatedAt, config.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	m.configs[config.ID] = config
+	m.storages[config.ID] = backend
+	m.mutex.Unlock()
+
+	sanitized := config.Sanitized()
+	return &sanitized, nil
+}
+
+// RemoveStorage removes a storage backend configuration; stored artifacts are left untouched
+func (m *Manager) RemoveStorage(id string) error {
+	if _, err := m.db.Exec("DELETE FROM backup_storage WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	delete(m.configs, id)
+	delete(m.storages, id)
+	m.mutex.Unlock()
+
+	return nil
+}
+
+// getStorageBackend returns a configured storage backend by ID
+func (m *Manager) getStorageBackend(id string) (StorageBackend, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	backend, exists := m.storages[id]
+	if !exists {
+		return nil, fmt.Errorf("storage backend not found: %s", id)
+	}
+	return backend, nil
+}
+
+// UploadBackup streams a backup artifact to a storage backend, compressed and encrypted
+// with its job's settings, and records its location and those settings
+func (m *Manager) UploadBackup(ctx context.Context, storageID, historyID string, r io.Reader) (string, error) {
+	backend, err := m.getStorageBackend(storageID)
+	if err != nil {
+		return "", err
+	}
+
+	var jobID string
+	if err := m.db.QueryRow("SELECT job_id FROM backup_history WHERE id = ?", historyID).Scan(&jobID); err != nil {
+		return "", err
+	}
+	job, err := m.GetJob(jobID)
+	if err != nil {
+		return "", err
+	}
+	archive, key, err := m.archiveSettings(job)
+	if err != nil {
+		return "", err
+	}
+	var encryption []byte
+	if archive.encryption != nil {
+		encryption, _ = json.Marshal(archive.encryption)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		writer, err := newArchiveWriter(pw, archive.compression, archive.level, key)
+		if err == nil {
+			_, err = io.Copy(writer, r)
+			if closeErr := writer.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+
+	location, err := backend.Upload(ctx, archiveName(jobID, historyID, archive.compression, key != nil), pr)
+	pr.CloseWithError(err) // stops the archive writer when the upload failed
+	if err != nil {
+		return "", err
+	}
+
+	_, err = m.db.Exec(`
+		UPDATE backup_history SET location = ?, compression = ?, compression_level = ?, encryption = ?
+		WHERE id = ?
+	`, location, archive.compression, archive.level, encryption, historyID)
+	return location, err
+}
+
+// archiveFormat is how a backup archive is written
+type archiveFormat struct {
+	compression CompressionType
+	level       int
+	encryption  *Encryption
+}
+
+// archiveSettings resolves the compression and encryption of a job's archives, with the key
+// they are encrypted with
+func (m *Manager) archiveSettings(job *Job) (archiveFormat, []byte, error) {
+	var format archiveFormat
+	var err error
+	if format.compression, format.level, err = compressionSettings(job.Metadata); err != nil {
+		return format, nil, err
+	}
+	if !job.Metadata.EncryptionEnabled {
+		return format, nil, nil
+	}
+	encryption, key, err := newEncryption(job.Metadata.EncryptionKeySource, job.Metadata.Passphrase, m.encryptionKey)
+	if err != nil {
+		return format, nil, err
+	}
+	format.encryption = encryption
+	return format, key, nil
+}
+
+// applyStorageRetention pushes a job's retention policy to its storage backend
+func (m *Manager) applyStorageRetention(ctx context.Context, job *Job) error {
+	if job.Metadata.StorageID == "" {
+		return nil
+	}
+
+	backend, err := m.getStorageBackend(job.Metadata.StorageID)
+	if err != nil {
+		return err
+	}
+	return backend.ApplyRetention(ctx, jobPrefix(job.ID), job.Retention)
+}
+
+// clearStorageRetention removes the retention a job set on its storage backend. Failures
+// are only logged, leaving the job's archives to expire as before.
+func (m *Manager) clearStorageRetention(ctx context.Context, job *Job) {
+	if job.Metadata.StorageID == "" {
+		return
+	}
+	backend, err := m.getStorageBackend(job.Metadata.StorageID)
+	if err == nil {
+		err = backend.ApplyRetention(ctx, jobPrefix(job.ID), Retention{})
+	}
+	if err != nil {
+		slog.Warn("Failed to remove backup job retention from storage", "job", job.ID, "storage", job.Metadata.StorageID, "error", err)
+	}
 }
 
 // GetStatistics returns backup system statistics
@@ -290,9 +736,9 @@ func (m *Manager) GetStatistics() (*Statistics, error) {
 	// Get total backups and success rate
 	err := m.db.QueryRow(`
 		SELECT COUNT(*), 
-		       COUNT(CASE WHEN status = 'completed' THEN 1 END) * 100.0 / COUNT(*),
-		       AVG(CASE WHEN duration > 0 THEN duration END),
-		       SUM(size)
+		       COALESCE(COUNT(CASE WHEN status = 'completed' THEN 1 END) * 100.0 / NULLIF(COUNT(*), 0), 0),
+		       COALESCE(AVG(CASE WHEN duration > 0 THEN duration END), 0),
+		       COALESCE(SUM(size), 0)
 		FROM backup_history
 	`).Scan(&stats.TotalBackups, &stats.SuccessRate, &stats.AverageDuration, &stats.TotalSize)
 
@@ -341,6 +787,10 @@ func (m *Manager) GetStatistics() (*Statistics, error) {
 		}
 	}
 
+	if stats.Pruning, err = m.pruningStats(); err != nil {
+		return nil, err
+	}
+
 	// Trend data would come from real backup history analysis
 	// Frontend will generate mock trends when needed for development
 
@@ -427,8 +877,25 @@ func (m *Manager) GetAlerts() ([]*Alert, error) {
 	return alerts, nil
 }
 
-// VerifyBackup starts verification of a backup
-func (m *Manager) VerifyBackup(backupID string) error {
+// AcknowledgeAlert marks a backup alert as acknowledged
+func (m *Manager) AcknowledgeAlert(alertID string) error {
+	result, err := m.db.Exec(`UPDATE backup_alerts SET acknowledged = TRUE WHERE id = ?`, alertID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("alert %s not found: %w", alertID, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// VerifyBackup starts verification of a backup. Encrypted backups need their key: the
+// passphrase they were encrypted with, or the configured key.
+func (m *Manager) VerifyBackup(backupID, passphrase string) error {
+	if err := m.checkBackupKey(backupID, passphrase); err != nil {
+		return err
+	}
+
 	_, err := m.db.Exec(`
 		UPDATE backup_history SET verification_status = ?
 		WHERE id = ?
@@ -449,6 +916,24 @@ func (m *Manager) VerifyBackup(backupID string) error {
 
 // StartRecovery starts a recovery operation
 func (m *Manager) StartRecovery(backupID string, options RecoveryOptions) (string, error) {
+	// Snapshot backups are restored into a new claim rather than from an archive
+	var location string
+	if err := m.db.QueryRow("SELECT location FROM backup_history WHERE id = ?", backupID).Scan(&location); err == nil {
+		if namespace, name, ok := parseSnapshotLocation(location); ok {
+			if options.RestoreToAlternateLocation == nil || *options.RestoreToAlternateLocation == "" {
+				return "", fmt.Errorf("restoreToAlternateLocation must name the new persistent volume claim")
+			}
+			return m.RestoreSnapshot(context.Background(), namespace, name, SnapshotRestoreRequest{
+				ClaimName: *options.RestoreToAlternateLocation,
+			})
+		}
+	}
+
+	if err := m.checkBackupKey(backupID, options.Passphrase); err != nil {
+		return "", err
+	}
+	options.Passphrase = "" // not stored with the recovery
+
 	recoveryID := uuid.New().String()
 	now := time.Now()
 
@@ -518,13 +1003,110 @@ func (m *Manager) DeleteBackup(backupID string) error {
 
 // UpdateSchedule updates a job's schedule
 func (m *Manager) UpdateSchedule(jobID string, schedule Schedule) error {
-	scheduleJSON, _ := json.Marshal(schedule)
 	now := time.Now()
+	if err := prepareSchedule(&schedule, now); err != nil {
+		return err
+	}
+	scheduleJSON, nextRun := scheduleColumns(schedule)
 
 	_, err := m.db.Exec(`
-		UPDATE backup_jobs SET schedule = ?, updated_at = ?
+		UPDATE backup_jobs SET schedule = ?, next_run = ?, updated_at = ?
 		WHERE id = ?
-	`, scheduleJSON, now, jobID)
+	`, scheduleJSON, nextRun, now, jobID)
+
+	return err
+}
 
+// isSnapshotJob reports whether a job takes volume snapshots rather than writing archives
+func isSnapshotJob(job *Job) bool {
+	return job.Type == JobTypeSnapshot && job.Source == SourcePersistentVolume
+}
+
+// maskedPassphrase replaces job passphrases in responses. Sent back unchanged, it keeps
+// the stored passphrase.
+const maskedPassphrase = "********"
+
+// Sanitized returns a copy of the job safe to return from the API
+func (j Job) Sanitized() Job {
+	if j.Metadata.Passphrase != "" {
+		j.Metadata.Passphrase = maskedPassphrase
+	}
+	return j
+}
+
+// prepareArchive validates a job's compression and encryption settings. A masked or
+// omitted passphrase keeps the existing job's.
+func (m *Manager) prepareArchive(job *Job, existing *Job) error {
+	if _, _, err := compressionSettings(job.Metadata); err != nil {
+		return err
+	}
+
+	metadata := &job.Metadata
+	if !metadata.EncryptionEnabled {
+		metadata.EncryptionKeySource = ""
+		metadata.Passphrase = ""
+		return nil
+	}
+
+	if metadata.EncryptionKeySource == "" {
+		metadata.EncryptionKeySource = EncryptionKeyConfig
+	}
+	switch metadata.EncryptionKeySource {
+	case EncryptionKeyConfig:
+		metadata.Passphrase = ""
+		if m.encryptionKey == "" {
+			return fmt.Errorf("%w: BACKUP_ENCRYPTION_KEY is not set; use a passphrase instead", ErrEncryptionKeyRequired)
+		}
+	case EncryptionKeyPassphrase:
+		if (metadata.Passphrase == "" || metadata.Passphrase == maskedPassphrase) && existing != nil {
+			metadata.Passphrase = existing.Metadata.Passphrase
+		}
+		if len(metadata.Passphrase) < minPassphraseLength {
+			return fmt.Errorf("%w: passphrase must be at least %d characters", ErrInvalidArchive, minPassphraseLength)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported encryption key source %s", ErrInvalidArchive, metadata.EncryptionKeySource)
+	}
+	return nil
+}
+
+// metadataColumn serializes a job's metadata with its passphrase sealed
+func (m *Manager) metadataColumn(metadata Metadata) ([]byte, error) {
+	if metadata.Passphrase != "" {
+		sealed, err := m.box.Encrypt(metadata.Passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt passphrase: %w", err)
+		}
+		metadata.Passphrase = sealed
+	}
+	return json.Marshal(metadata)
+}
+
+// openPassphrase opens a job's sealed passphrase; a passphrase that can't be opened is
+// dropped, so scheduled runs fail until it is set again
+func (m *Manager) openPassphrase(job *Job) {
+	if job.Metadata.Passphrase == "" {
+		return
+	}
+	passphrase, err := m.box.Decrypt(job.Metadata.Passphrase)
+	if err != nil {
+		slog.Warn("Failed to decrypt backup job passphrase", "job", job.ID, "error", err)
+	}
+	job.Metadata.Passphrase = passphrase
+}
+
+// checkBackupKey checks the key given for an encrypted backup
+func (m *Manager) checkBackupKey(backupID, passphrase string) error {
+	var encryptionJSON sql.NullString
+	err := m.db.QueryRow("SELECT encryption FROM backup_history WHERE id = ?", backupID).Scan(&encryptionJSON)
+	if err != nil || !encryptionJSON.Valid {
+		return nil // unknown backups are left to the caller
+	}
+
+	var encryption Encryption
+	if err := json.Unmarshal([]byte(encryptionJSON.String), &encryption); err != nil {
+		return fmt.Errorf("invalid encryption metadata: %w", err)
+	}
+	_, err = encryption.Key(passphrase, m.encryptionKey)
 	return err
 }
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS backup_storage;
+DROP TABLE IF EXISTS backup_alerts;
+DROP TABLE IF EXISTS backup_recoveries;
+DROP TABLE IF EXISTS backup_history;
+DROP TABLE IF EXISTS backup_jobs;
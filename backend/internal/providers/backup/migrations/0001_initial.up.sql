@@ -0,0 +1,68 @@
+CREATE TABLE IF NOT EXISTS backup_jobs (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL,
+	source TEXT NOT NULL,
+	schedule TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'scheduled',
+	last_run TIMESTAMP,
+	next_run TIMESTAMP,
+	retention TEXT NOT NULL,
+	size INTEGER,
+	duration INTEGER,
+	error TEXT NOT NULL DEFAULT '',
+	metadata TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS backup_history (
+	id TEXT PRIMARY KEY,
+	job_id TEXT NOT NULL,
+	job_name TEXT NOT NULL,
+	timestamp TIMESTAMP NOT NULL,
+	type TEXT NOT NULL,
+	source TEXT NOT NULL,
+	status TEXT NOT NULL,
+	size INTEGER NOT NULL DEFAULT 0,
+	duration INTEGER NOT NULL DEFAULT 0,
+	files_count INTEGER,
+	location TEXT NOT NULL DEFAULT '',
+	checksum TEXT NOT NULL DEFAULT '',
+	verification_status TEXT NOT NULL DEFAULT 'not_verified'
+);
+
+CREATE TABLE IF NOT EXISTS backup_recoveries (
+	id TEXT PRIMARY KEY,
+	backup_id TEXT NOT NULL,
+	restore_point_id TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL,
+	start_time TIMESTAMP NOT NULL,
+	end_time TIMESTAMP,
+	target_location TEXT NOT NULL DEFAULT '',
+	options TEXT,
+	progress TEXT,
+	error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS backup_alerts (
+	id TEXT PRIMARY KEY,
+	type TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	message TEXT NOT NULL,
+	timestamp TIMESTAMP NOT NULL,
+	job_id TEXT,
+	acknowledged BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS backup_storage (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL,
+	config TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_backup_history_job_id ON backup_history(job_id);
+CREATE INDEX IF NOT EXISTS idx_backup_recoveries_status ON backup_recoveries(status);
@@ -0,0 +1,3 @@
+ALTER TABLE backup_history DROP COLUMN encryption;
+ALTER TABLE backup_history DROP COLUMN compression_level;
+ALTER TABLE backup_history DROP COLUMN compression;
@@ -0,0 +1,3 @@
+ALTER TABLE backup_history ADD COLUMN compression TEXT NOT NULL DEFAULT 'none';
+ALTER TABLE backup_history ADD COLUMN compression_level INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE backup_history ADD COLUMN encryption TEXT;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS backup_prune_runs (
+	id TEXT PRIMARY KEY,
+	job_id TEXT NOT NULL,
+	job_name TEXT NOT NULL,
+	timestamp TIMESTAMP NOT NULL,
+	deleted INTEGER NOT NULL DEFAULT 0,
+	freed_bytes INTEGER NOT NULL DEFAULT 0,
+	failed INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_backup_prune_runs_timestamp ON backup_prune_runs(timestamp);
@@ -0,0 +1 @@
+ALTER TABLE backup_history DROP COLUMN hooks;
@@ -0,0 +1 @@
+ALTER TABLE backup_history ADD COLUMN hooks TEXT;
@@ -0,0 +1,440 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+)
+
+// maxPreviewEntries caps the file tree of a restore preview
+const maxPreviewEntries = 10000
+
+// ErrNoArchive is returned when a backup has no archive in storage to preview
+var ErrNoArchive = errors.New("backup has no stored archive")
+
+// RestorePreview lists what restoring a backup would bring back, without restoring it
+type RestorePreview struct {
+	BackupID  string            `json:"backupId"`
+	Source    Source            `json:"source"`
+	Timestamp time.Time         `json:"timestamp"`
+	Location  string            `json:"location"`
+	Size      int64             `json:"size"` // uncompressed
+	Databases []DatabasePreview `json:"databases,omitempty"`
+	Files     *FileNode         `json:"files,omitempty"`
+	Truncated bool              `json:"truncated,omitempty"` // the file tree stops at maxPreviewEntries
+	// Snapshot is the volume snapshot of snapshot backups, which have no archive to list
+	Snapshot *k8s.VolumeSnapshot `json:"snapshot,omitempty"`
+	// PointInTime is the window a WAL-archiving PostgreSQL backup can be recovered to
+	PointInTime *RecoveryWindow `json:"pointInTime,omitempty"`
+}
+
+// DatabasePreview is a database in a backup
+type DatabasePreview struct {
+	Name   string         `json:"name"`
+	Size   int64          `json:"size"`
+	Tables []TablePreview `json:"tables"`
+}
+
+// TablePreview is a table in a database backup
+type TablePreview struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+	Size int64  `json:"size,omitempty"` // bytes of dumped data, when known
+}
+
+// FileNode is a file or directory in a backup
+type FileNode struct {
+	Name     string      `json:"name"`
+	Size     int64       `json:"size"` // of a directory, the files below it
+	Dir      bool        `json:"dir,omitempty"`
+	Children []*FileNode `json:"children,omitempty"`
+}
+
+// RecoveryWindow is the span a PostgreSQL base backup can be recovered to by replaying
+// archived WAL: from the backup up to the last segment archived without a gap
+type RecoveryWindow struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Segments int       `json:"segments"`
+	// MissingSegment is the first segment missing from the archive, which ends the window
+	MissingSegment string `json:"missingSegment,omitempty"`
+}
+
+// walPrefix is where a job's archive_command stores WAL segments in its storage
+func walPrefix(jobID string) string {
+	return "wal/" + jobID + "/"
+}
+
+// PreviewRestore lists the contents of a backup without restoring it. Encrypted backups
+// need their key, as for verification.
+func (m *Manager) PreviewRestore(ctx context.Context, backupID, passphrase string) (*RestorePreview, error) {
+	backup, err := m.GetBackup(backupID)
+	if err != nil {
+		return nil, err
+	}
+	preview := &RestorePreview{
+		BackupID:  backup.ID,
+		Source:    backup.Source,
+		Timestamp: backup.Timestamp,
+		Location:  backup.Location,
+	}
+
+	if namespace, name, ok := parseSnapshotLocation(backup.Location); ok {
+		if err := m.requireK8s(); err != nil {
+			return nil, err
+		}
+		if preview.Snapshot, err = m.k8sClient.GetVolumeSnapshot(ctx, namespace, name); err != nil {
+			return nil, err
+		}
+		return preview, nil
+	}
+
+	var key []byte
+	if backup.Encryption != nil {
+		if key, err = backup.Encryption.Key(passphrase, m.encryptionKey); err != nil {
+			return nil, err
+		}
+	}
+	job, err := m.GetJob(backup.JobID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: job %s no longer exists", ErrNoArchive, backup.JobID)
+	}
+	if job.Metadata.StorageID == "" || backup.Status != StatusCompleted {
+		return nil, ErrNoArchive
+	}
+	backend, err := m.getStorageBackend(job.Metadata.StorageID)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := backend.Download(ctx, archiveName(backup.JobID, backup.ID, backup.Compression, key != nil))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoArchive, err)
+	}
+	defer body.Close()
+	archive, err := newArchiveReader(body, backup.Compression, key)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	if err := previewArchive(preview, archive); err != nil {
+		return nil, err
+	}
+
+	if backup.Source == SourcePostgreSQL && job.Metadata.WALArchiving {
+		segments, err := backend.List(ctx, walPrefix(job.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list WAL segments: %w", err)
+		}
+		preview.PointInTime = recoveryWindow(backup.Timestamp, segments)
+	}
+	return preview, nil
+}
+
+// previewArchive reads a backup's tar archive into a preview: the databases of database
+// backups and the file tree of everything else
+func previewArchive(preview *RestorePreview, archive io.Reader) error {
+	root := &FileNode{Name: "/", Dir: true}
+	entries := 0
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read backup archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			preview.Size += header.Size
+		}
+
+		switch {
+		case preview.Source == SourcePostgreSQL && strings.HasSuffix(header.Name, ".sql"):
+			database, err := previewSQLDump(strings.TrimSuffix(path.Base(header.Name), ".sql"), reader)
+			if err != nil {
+				return err
+			}
+			preview.Databases = append(preview.Databases, database...)
+		case preview.Source == SourceSQLite && isSQLiteFile(header.Name):
+			database, err := previewSQLiteFile(path.Base(header.Name), reader)
+			if err != nil {
+				return err
+			}
+			preview.Databases = append(preview.Databases, *database)
+		default:
+			entries++
+			if entries > maxPreviewEntries {
+				preview.Truncated = true
+				addSize(root, header)
+				continue
+			}
+			addFile(root, header)
+		}
+	}
+
+	if len(root.Children) > 0 {
+		sortTree(root)
+		preview.Files = root
+	}
+	return nil
+}
+
+// addFile inserts a tar entry into a file tree, adding its size to its directories
+func addFile(root *FileNode, header *tar.Header) {
+	parts := strings.Split(strings.Trim(path.Clean("/"+header.Name), "/"), "/")
+	if parts[0] == "" {
+		return
+	}
+	node := root
+	for i, part := range parts {
+		if header.Typeflag == tar.TypeReg {
+			node.Size += header.Size
+		}
+		var child *FileNode
+		for _, existing := range node.Children {
+			if existing.Name == part {
+				child = existing
+				break
+			}
+		}
+		if child == nil {
+			child = &FileNode{Name: part, Dir: i < len(parts)-1 || header.Typeflag == tar.TypeDir}
+			node.Children = append(node.Children, child)
+		}
+		node = child
+	}
+	if header.Typeflag == tar.TypeReg {
+		node.Size = header.Size
+	}
+}
+
+// addSize counts an entry past the preview limit in the total size only
+func addSize(root *FileNode, header *tar.Header) {
+	if header.Typeflag == tar.TypeReg {
+		root.Size += header.Size
+	}
+}
+
+// sortTree orders directories before files, each by name
+func sortTree(node *FileNode) {
+	sort.Slice(node.Children, func(i, j int) bool {
+		a, b := node.Children[i], node.Children[j]
+		if a.Dir != b.Dir {
+			return a.Dir
+		}
+		return a.Name < b.Name
+	})
+	for _, child := range node.Children {
+		sortTree(child)
+	}
+}
+
+var (
+	createTablePattern = regexp.MustCompile(`^CREATE (?:UNLOGGED )?TABLE (?:IF NOT EXISTS )?([^\s(]+)`)
+	copyPattern        = regexp.MustCompile(`^COPY ([^\s(]+)`)
+	insertPattern      = regexp.MustCompile(`^INSERT INTO ([^\s(]+)`)
+	connectPattern     = regexp.MustCompile(`^\\connect (?:-reuse-previous=on "dbname='?)?([^\s"']+)`)
+)
+
+// previewSQLDump lists the tables of a plain pg_dump or pg_dumpall script with the rows
+// and bytes of their data. pg_dumpall scripts switch databases with \connect.
+func previewSQLDump(name string, r io.Reader) ([]DatabasePreview, error) {
+	databases := []DatabasePreview{{Name: name, Tables: []TablePreview{}}}
+	current := &databases[0]
+	tables := map[string]int{} // indexes into current.Tables
+	table := func(name string) *TablePreview {
+		name = strings.ReplaceAll(name, `"`, "")
+		i, ok := tables[name]
+		if !ok {
+			i = len(current.Tables)
+			tables[name] = i
+			current.Tables = append(current.Tables, TablePreview{Name: name})
+		}
+		return &current.Tables[i]
+	}
+
+	reader := bufio.NewReaderSize(r, 64<<10)
+	copying := ""
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			size := int64(len(line))
+			current.Size += size
+			line = strings.TrimRight(line, "\r\n")
+
+			switch {
+			case copying != "" && line == `\.`:
+				copying = ""
+			case copying != "":
+				copied := table(copying)
+				copied.Rows++
+				copied.Size += size
+			case strings.HasPrefix(line, `\connect `):
+				if match := connectPattern.FindStringSubmatch(line); match != nil {
+					databases = append(databases, DatabasePreview{Name: match[1], Tables: []TablePreview{}})
+					current = &databases[len(databases)-1]
+					tables = map[string]int{}
+				}
+			default:
+				if match := createTablePattern.FindStringSubmatch(line); match != nil {
+					table(match[1])
+				} else if match := copyPattern.FindStringSubmatch(line); match != nil && strings.HasSuffix(line, "FROM stdin;") {
+					copying = match[1]
+				} else if match := insertPattern.FindStringSubmatch(line); match != nil {
+					inserted := table(match[1])
+					inserted.Rows++
+					inserted.Size += size
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SQL dump: %w", err)
+		}
+	}
+
+	// Skips what only holds setup statements, such as the start of a pg_dumpall script
+	result := []DatabasePreview{}
+	for _, database := range databases {
+		if len(database.Tables) > 0 {
+			result = append(result, database)
+		}
+	}
+	return result, nil
+}
+
+func isSQLiteFile(name string) bool {
+	switch path.Ext(name) {
+	case ".db", ".sqlite", ".sqlite3":
+		return true
+	}
+	return false
+}
+
+// previewSQLiteFile lists the tables of a SQLite database file in a backup, with their rows
+func previewSQLiteFile(name string, r io.Reader) (*DatabasePreview, error) {
+	file, err := os.CreateTemp("", "denshimon-preview-*.db")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	size, err := io.Copy(file, r)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+file.Name()+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a readable SQLite database: %w", name, err)
+	}
+	var names []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err == nil {
+			names = append(names, table)
+		}
+	}
+	rows.Close()
+
+	database := &DatabasePreview{Name: name, Size: size, Tables: []TablePreview{}}
+	for _, table := range names {
+		preview := TablePreview{Name: table}
+		db.QueryRow(`SELECT COUNT(*) FROM "` + strings.ReplaceAll(table, `"`, `""`) + `"`).Scan(&preview.Rows)
+		database.Tables = append(database.Tables, preview)
+	}
+	return database, nil
+}
+
+// walSegment returns the position of a WAL segment file, ignoring history and backup
+// label files and compression suffixes
+func walSegment(key string) (timeline, position uint64, ok bool) {
+	name := path.Base(key)
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		if strings.Contains(name[i:], "history") || strings.Contains(name[i:], "backup") || strings.Contains(name[i:], "partial") {
+			return 0, 0, false
+		}
+		name = name[:i]
+	}
+	if len(name) != 24 {
+		return 0, 0, false
+	}
+	timeline, err := strconv.ParseUint(name[:8], 16, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	log, err1 := strconv.ParseUint(name[8:16], 16, 32)
+	segment, err2 := strconv.ParseUint(name[16:], 16, 32)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	// 256 segments of the default 16 MiB make up a log file
+	return timeline, log<<8 | segment, true
+}
+
+// walSegmentName formats a segment position back into its file name
+func walSegmentName(timeline, position uint64) string {
+	return fmt.Sprintf("%08X%08X%08X", timeline, position>>8, position&0xFF)
+}
+
+// recoveryWindow computes how far past a base backup taken at start the archived WAL
+// reaches: up to the last segment archived after it without a gap, on the latest timeline
+func recoveryWindow(start time.Time, objects []StoredObject) *RecoveryWindow {
+	type segment struct {
+		timeline, position uint64
+		archived           time.Time
+	}
+	var segments []segment
+	latest := uint64(0)
+	for _, object := range objects {
+		if timeline, position, ok := walSegment(object.Key); ok && !object.LastModified.Before(start) {
+			segments = append(segments, segment{timeline, position, object.LastModified})
+			latest = max(latest, timeline)
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].position < segments[j].position })
+
+	window := &RecoveryWindow{Start: start, End: start}
+	var previous *segment
+	for i := range segments {
+		current := &segments[i]
+		if current.timeline != latest {
+			continue
+		}
+		if previous != nil && current.position == previous.position {
+			continue
+		}
+		if previous != nil && current.position != previous.position+1 {
+			window.MissingSegment = walSegmentName(latest, previous.position+1)
+			break
+		}
+		window.Segments++
+		if current.archived.After(window.End) {
+			window.End = current.archived
+		}
+		previous = current
+	}
+	return window
+}
@@ -0,0 +1,214 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memStorage keeps artifacts in memory
+type memStorage struct {
+	objects map[string]StoredObject
+	data    map[string][]byte
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{objects: map[string]StoredObject{}, data: map[string][]byte{}}
+}
+
+func (s *memStorage) put(key string, data []byte, modified time.Time) {
+	s.objects[key] = StoredObject{Key: key, Size: int64(len(data)), LastModified: modified}
+	s.data[key] = data
+}
+
+func (s *memStorage) Type() StorageType { return StorageTypeLocal }
+
+func (s *memStorage) Upload(ctx context.Context, key string, r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	s.put(key, data, time.Now())
+	return "mem://" + key, nil
+}
+
+func (s *memStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, ok := s.data[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memStorage) List(ctx context.Context, prefix string) ([]StoredObject, error) {
+	var objects []StoredObject
+	for key, object := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+func (s *memStorage) Delete(ctx context.Context, key string) error {
+	delete(s.objects, key)
+	delete(s.data, key)
+	return nil
+}
+
+func (s *memStorage) Usage(ctx context.Context) (*Storage, error) { return &Storage{}, nil }
+func (s *memStorage) ApplyRetention(ctx context.Context, prefix string, retention Retention) error {
+	return nil
+}
+func (s *memStorage) TestConnection(ctx context.Context) error { return nil }
+
+// tarball builds a tar archive of the given files; names ending in / are directories
+func tarball(t *testing.T, files map[string]string) io.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(name, "/") {
+			header = &tar.Header{Name: name, Mode: 0o755, Typeflag: tar.TypeDir}
+		}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	w.Close()
+	return &buf
+}
+
+// completedBackup runs a job and stores its archive, as a finished backup would
+func completedBackup(t *testing.T, m *Manager, job *Job, archive io.Reader) *History {
+	t.Helper()
+	created, err := m.CreateJob(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RunJob(created.ID); err != nil {
+		t.Fatal(err)
+	}
+	history, err := m.GetHistory(created.ID)
+	if err != nil || len(history) != 1 {
+		t.Fatalf("expected one backup, got %d (%v)", len(history), err)
+	}
+	backup := history[0]
+	if _, err := m.UploadBackup(context.Background(), "mem", backup.ID, archive); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.db.Exec(`UPDATE backup_history SET status = ? WHERE id = ?`, StatusCompleted, backup.ID); err != nil {
+		t.Fatal(err)
+	}
+	return backup
+}
+
+func TestPreviewRestore(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "backup.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m := NewManager(db, nil)
+	storage := newMemStorage()
+	m.storages["mem"] = storage
+	ctx := context.Background()
+
+	dump := `-- PostgreSQL database cluster dump
+SET default_transaction_read_only = off;
+\connect app
+CREATE TABLE public.users (
+    id integer NOT NULL
+);
+CREATE TABLE public.audit (
+    id integer NOT NULL
+);
+COPY public.users (id) FROM stdin;
+1
+2
+3
+\.
+\connect billing
+CREATE TABLE "public"."invoices" (id integer);
+INSERT INTO public.invoices VALUES (1);
+`
+	backup := completedBackup(t, m, &Job{Name: "pg", Type: JobTypeFull, Source: SourcePostgreSQL, Metadata: Metadata{
+		StorageID:           "mem",
+		CompressionType:     CompressionGzip,
+		EncryptionEnabled:   true,
+		EncryptionKeySource: EncryptionKeyPassphrase,
+		Passphrase:          "correct horse",
+		WALArchiving:        true,
+	}}, tarball(t, map[string]string{"cluster.sql": dump}))
+
+	// Segments from before the backup don't count, and a gap ends the window
+	prefix := walPrefix(backup.JobID)
+	base := backup.Timestamp
+	storage.put(prefix+"000000010000000000000001", nil, base.Add(-time.Hour))
+	storage.put(prefix+"000000010000000000000002", nil, base.Add(time.Minute))
+	storage.put(prefix+"000000010000000000000003.gz", nil, base.Add(2*time.Minute))
+	storage.put(prefix+"000000010000000000000003.00000028.backup", nil, base.Add(3*time.Minute))
+	storage.put(prefix+"000000010000000000000005", nil, base.Add(4*time.Minute))
+
+	if _, err := m.PreviewRestore(ctx, backup.ID, ""); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Errorf("expected the preview to need the passphrase, got %v", err)
+	}
+	preview, err := m.PreviewRestore(ctx, backup.ID, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Databases) != 2 || preview.Files != nil {
+		t.Fatalf("unexpected preview %+v", preview)
+	}
+	app, billing := preview.Databases[0], preview.Databases[1]
+	if app.Name != "app" || len(app.Tables) != 2 || app.Tables[0].Name != "public.users" || app.Tables[0].Rows != 3 || app.Tables[0].Size != 6 {
+		t.Errorf("unexpected database %+v", app)
+	}
+	if billing.Name != "billing" || len(billing.Tables) != 1 || billing.Tables[0].Name != "public.invoices" || billing.Tables[0].Rows != 1 {
+		t.Errorf("unexpected database %+v", billing)
+	}
+
+	window := preview.PointInTime
+	if window == nil || !window.Start.Equal(base) || !window.End.Equal(base.Add(2*time.Minute)) ||
+		window.Segments != 2 || window.MissingSegment != "000000010000000000000004" {
+		t.Errorf("unexpected recovery window %+v", window)
+	}
+
+	files := completedBackup(t, m, &Job{Name: "config", Type: JobTypeFull, Source: SourceConfigFiles, Metadata: Metadata{StorageID: "mem"}},
+		tarball(t, map[string]string{"etc/": "", "etc/app.yaml": "key: value\n", "etc/certs/tls.crt": "cert", "README": "hi"}))
+	preview, err = m.PreviewRestore(ctx, files.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := preview.Files
+	if root == nil || root.Size != 17 || preview.Size != 17 || len(root.Children) != 2 || preview.PointInTime != nil {
+		t.Fatalf("unexpected file tree %+v", root)
+	}
+	etc := root.Children[0]
+	if etc.Name != "etc" || !etc.Dir || etc.Size != 15 || len(etc.Children) != 2 || etc.Children[0].Name != "certs" {
+		t.Errorf("unexpected directory %+v", etc)
+	}
+	if readme := root.Children[1]; readme.Name != "README" || readme.Dir || readme.Size != 2 {
+		t.Errorf("unexpected file %+v", readme)
+	}
+
+	// Backups still running, or never uploaded, have nothing to preview
+	if _, err := m.db.Exec(`UPDATE backup_history SET status = ? WHERE id = ?`, StatusRunning, files.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.PreviewRestore(ctx, files.ID, ""); !errors.Is(err, ErrNoArchive) {
+		t.Errorf("expected a running backup to have no archive, got %v", err)
+	}
+	if _, err := m.PreviewRestore(ctx, "missing", ""); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected an unknown backup to be not found, got %v", err)
+	}
+}
@@ -0,0 +1,295 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// retentionInterval is how often the scheduler prunes backups beyond their job's retention
+const retentionInterval = time.Hour
+
+// Reasons a backup is kept by a retention policy
+const (
+	KeepDaily   = "daily"
+	KeepWeekly  = "weekly"
+	KeepMonthly = "monthly"
+	KeepYearly  = "yearly"
+	KeepMinimum = "minimum"
+)
+
+// RetentionPlan lists which of a job's completed backups its retention policy keeps and
+// which it deletes
+type RetentionPlan struct {
+	JobID      string           `json:"jobId"`
+	JobName    string           `json:"jobName"`
+	Policy     Retention        `json:"policy"`
+	Keep       []RetentionEntry `json:"keep"`
+	Delete     []RetentionEntry `json:"delete"`
+	FreedBytes int64            `json:"freedBytes"`
+}
+
+// RetentionEntry is a backup in a retention plan
+type RetentionEntry struct {
+	BackupID  string    `json:"backupId"`
+	Timestamp time.Time `json:"timestamp"`
+	Size      int64     `json:"size"`
+	Location  string    `json:"location"`
+	Reasons   []string  `json:"reasons,omitempty"` // the buckets keeping the backup
+}
+
+// PruneRun records a pruning pass over a job's backups
+type PruneRun struct {
+	ID         string    `json:"id"`
+	JobID      string    `json:"jobId"`
+	JobName    string    `json:"jobName"`
+	Timestamp  time.Time `json:"timestamp"`
+	Deleted    int       `json:"deleted"`
+	FreedBytes int64     `json:"freedBytes"`
+	Failed     int       `json:"failed"`
+	Error      string    `json:"error,omitempty"` // the first failed deletion
+}
+
+// PruningStats summarizes pruning for backup statistics
+type PruningStats struct {
+	LastRun       *time.Time  `json:"lastRun,omitempty"`
+	BackupsPruned int         `json:"backupsPruned"`
+	BytesFreed    int64       `json:"bytesFreed"`
+	Failures      int         `json:"failures"`
+	RecentRuns    []*PruneRun `json:"recentRuns"`
+}
+
+// gfsEnabled reports whether a policy limits backups at all; MinBackups alone doesn't
+func gfsEnabled(policy Retention) bool {
+	return policy.Daily > 0 || policy.Weekly > 0 || policy.Monthly > 0 || policy.Yearly > 0
+}
+
+// selectRetained applies a grandfather-father-son policy to backups sorted newest first.
+// Each bucket keeps the newest backup of each of its last N periods that have a backup,
+// and MinBackups keeps the newest backups regardless of age. The returned map holds the
+// reasons each kept backup is kept.
+func selectRetained(policy Retention, backups []*History, loc *time.Location) map[string][]string {
+	kept := make(map[string][]string)
+	buckets := []struct {
+		reason string
+		count  int
+		period func(t time.Time) string
+	}{
+		{KeepDaily, policy.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{KeepWeekly, policy.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{KeepMonthly, policy.Monthly, func(t time.Time) string { return t.Format("2006-01") }},
+		{KeepYearly, policy.Yearly, func(t time.Time) string { return t.Format("2006") }},
+	}
+
+	for _, bucket := range buckets {
+		seen := make(map[string]bool)
+		for _, backup := range backups {
+			if len(seen) == bucket.count {
+				break
+			}
+			period := bucket.period(backup.Timestamp.In(loc))
+			if seen[period] {
+				continue
+			}
+			seen[period] = true
+			kept[backup.ID] = append(kept[backup.ID], bucket.reason)
+		}
+	}
+
+	for i := 0; i < policy.MinBackups && i < len(backups); i++ {
+		kept[backups[i].ID] = append(kept[backups[i].ID], KeepMinimum)
+	}
+	return kept
+}
+
+// PlanRetention works out which backups of a job its retention policy deletes, without
+// deleting anything. Only completed backups count; running and failed ones are left alone.
+// Periods follow the timezone of the job's schedule.
+func (m *Manager) PlanRetention(jobID string) (*RetentionPlan, error) {
+	job, err := m.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	return m.planRetention(job)
+}
+
+func (m *Manager) planRetention(job *Job) (*RetentionPlan, error) {
+	history, err := m.GetHistory(job.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &RetentionPlan{
+		JobID:   job.ID,
+		JobName: job.Name,
+		Policy:  job.Retention,
+		Keep:    []RetentionEntry{},
+		Delete:  []RetentionEntry{},
+	}
+
+	var completed []*History
+	for _, backup := range history {
+		if backup.Status == StatusCompleted {
+			completed = append(completed, backup)
+		}
+	}
+
+	loc := time.UTC
+	if job.Schedule.Timezone != "" {
+		if zone, err := time.LoadLocation(job.Schedule.Timezone); err == nil {
+			loc = zone
+		}
+	}
+	kept := selectRetained(job.Retention, completed, loc)
+
+	for _, backup := range completed {
+		entry := RetentionEntry{
+			BackupID:  backup.ID,
+			Timestamp: backup.Timestamp,
+			Size:      backup.Size,
+			Location:  backup.Location,
+		}
+		if reasons, ok := kept[backup.ID]; ok || !gfsEnabled(job.Retention) {
+			entry.Reasons = reasons
+			plan.Keep = append(plan.Keep, entry)
+			continue
+		}
+		plan.Delete = append(plan.Delete, entry)
+		plan.FreedBytes += backup.Size
+	}
+	return plan, nil
+}
+
+// PruneJob deletes the backups of a job its retention policy no longer keeps, artifacts
+// first. Backups whose artifact can't be deleted stay in history and are retried on the
+// next pass. Passes that delete or fail to delete anything are recorded.
+func (m *Manager) PruneJob(ctx context.Context, jobID string) (*PruneRun, error) {
+	job, err := m.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := m.planRetention(job)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &PruneRun{
+		ID:        uuid.New().String(),
+		JobID:     job.ID,
+		JobName:   job.Name,
+		Timestamp: time.Now(),
+	}
+	if len(plan.Delete) == 0 {
+		return run, nil
+	}
+
+	for _, entry := range plan.Delete {
+		backup, err := m.GetBackup(entry.BackupID)
+		if err == nil {
+			if err = m.deleteArtifact(ctx, job, backup); err == nil {
+				_, err = m.db.Exec("DELETE FROM backup_history WHERE id = ?", backup.ID)
+			}
+		}
+		if err != nil {
+			run.Failed++
+			if run.Error == "" {
+				run.Error = fmt.Sprintf("backup %s: %v", entry.BackupID, err)
+			}
+			continue
+		}
+		run.Deleted++
+		run.FreedBytes += entry.Size
+	}
+
+	_, err = m.db.Exec(`
+		INSERT INTO backup_prune_runs (id, job_id, job_name, timestamp, deleted, freed_bytes, failed, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.ID, run.JobID, run.JobName, run.Timestamp, run.Deleted, run.FreedBytes, run.Failed, run.Error)
+	if err != nil {
+		return nil, err
+	}
+
+	if run.Failed > 0 {
+		m.raiseAlert(AlertTypeRetentionPolicyViolation, SeverityWarning,
+			fmt.Sprintf("Pruning backup job %s failed to delete %d backups: %s", job.Name, run.Failed, run.Error), job.ID)
+	}
+	return run, nil
+}
+
+// deleteArtifact deletes what a backup stored: its volume snapshot or its archive in the
+// job's storage. Backups without a stored artifact have nothing to delete.
+func (m *Manager) deleteArtifact(ctx context.Context, job *Job, backup *History) error {
+	if namespace, name, ok := parseSnapshotLocation(backup.Location); ok {
+		return m.DeleteSnapshot(ctx, namespace, name)
+	}
+	if job.Metadata.StorageID == "" {
+		return nil
+	}
+	backend, err := m.getStorageBackend(job.Metadata.StorageID)
+	if err != nil {
+		return err
+	}
+	return backend.Delete(ctx, archiveName(backup.JobID, backup.ID, backup.Compression, backup.Encryption != nil))
+}
+
+// pruneAll runs a pruning pass over every job with a retention policy
+func (m *Manager) pruneAll(ctx context.Context) {
+	jobs, err := m.ListJobs()
+	if err != nil {
+		slog.Error("Failed to list backup jobs for pruning", "error", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if !gfsEnabled(job.Retention) {
+			continue
+		}
+		run, err := m.PruneJob(ctx, job.ID)
+		if err != nil {
+			slog.Error("Failed to prune backup job", "job", job.ID, "error", err)
+			continue
+		}
+		if run.Deleted > 0 || run.Failed > 0 {
+			slog.Info("Pruned backup job", "job", job.ID, "deleted", run.Deleted, "freed", run.FreedBytes, "failed", run.Failed)
+		}
+	}
+}
+
+// pruningStats summarizes recorded pruning passes
+func (m *Manager) pruningStats() (*PruningStats, error) {
+	stats := &PruningStats{RecentRuns: []*PruneRun{}}
+	err := m.db.QueryRow(`
+		SELECT COALESCE(SUM(deleted), 0), COALESCE(SUM(freed_bytes), 0), COALESCE(SUM(failed), 0)
+		FROM backup_prune_runs
+	`).Scan(&stats.BackupsPruned, &stats.BytesFreed, &stats.Failures)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(`
+		SELECT id, job_id, job_name, timestamp, deleted, freed_bytes, failed, error
+		FROM backup_prune_runs
+		ORDER BY timestamp DESC LIMIT 10
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		run := &PruneRun{}
+		if err := rows.Scan(&run.ID, &run.JobID, &run.JobName, &run.Timestamp, &run.Deleted, &run.FreedBytes, &run.Failed, &run.Error); err != nil {
+			return nil, err
+		}
+		stats.RecentRuns = append(stats.RecentRuns, run)
+	}
+	if len(stats.RecentRuns) > 0 {
+		stats.LastRun = &stats.RecentRuns[0].Timestamp
+	}
+	return stats, rows.Err()
+}
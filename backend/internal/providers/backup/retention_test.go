@@ -0,0 +1,134 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetentionPruning(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "backup.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m := NewManager(db, nil)
+	storage := newMemStorage()
+	m.storages["mem"] = storage
+	ctx := context.Background()
+
+	job, err := m.CreateJob(&Job{Name: "db", Type: JobTypeFull, Source: SourceSQLite,
+		Retention: Retention{Daily: 2, Weekly: 2, Monthly: 2, MinBackups: 1},
+		Metadata:  Metadata{StorageID: "mem"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backup := func(id, timestamp string, size int64, status Status, location string) {
+		ts, err := time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if location == "" {
+			location = "mem://" + id
+			storage.put(archiveName(job.ID, id, CompressionNone, false), []byte("archive"), ts)
+		}
+		_, err = m.db.Exec(`
+			INSERT INTO backup_history (id, job_id, job_name, timestamp, type, source, status, size, duration, location, checksum, verification_status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, 0, ?, '', ?)
+		`, id, job.ID, job.Name, ts, job.Type, job.Source, status, size, location, VerificationNotVerified)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	backup("wed-late", "2024-03-20T10:00:00Z", 1, StatusCompleted, "")
+	backup("wed-early", "2024-03-20T02:00:00Z", 10, StatusCompleted, "")
+	backup("tue", "2024-03-19T10:00:00Z", 1, StatusCompleted, "")
+	backup("mon", "2024-03-18T10:00:00Z", 20, StatusCompleted, "")
+	backup("last-week", "2024-03-12T10:00:00Z", 1, StatusCompleted, "")
+	backup("february", "2024-02-10T10:00:00Z", 1, StatusCompleted, "")
+	backup("january", "2024-01-05T10:00:00Z", 40, StatusCompleted, "volumesnapshot://data/january")
+	backup("failed", "2023-12-01T10:00:00Z", 0, StatusFailed, "")
+
+	plan, err := m.PlanRetention(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := map[string][]string{}
+	for _, entry := range plan.Keep {
+		kept[entry.BackupID] = entry.Reasons
+	}
+	want := map[string][]string{
+		"wed-late":  {KeepDaily, KeepWeekly, KeepMonthly, KeepMinimum},
+		"tue":       {KeepDaily},
+		"last-week": {KeepWeekly},
+		"february":  {KeepMonthly},
+	}
+	if !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept %v, want %v", kept, want)
+	}
+	var deleted []string
+	for _, entry := range plan.Delete {
+		deleted = append(deleted, entry.BackupID)
+	}
+	if !reflect.DeepEqual(deleted, []string{"wed-early", "mon", "january"}) || plan.FreedBytes != 70 {
+		t.Errorf("expected to delete wed-early, mon and january freeing 70 bytes, got %v freeing %d", deleted, plan.FreedBytes)
+	}
+	if _, ok := storage.objects[archiveName(job.ID, "mon", CompressionNone, false)]; !ok {
+		t.Fatal("expected the dry run to leave artifacts alone")
+	}
+
+	// The snapshot can't be deleted without a cluster, so it stays for the next pass
+	run, err := m.PruneJob(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Deleted != 2 || run.FreedBytes != 30 || run.Failed != 1 || run.Error == "" {
+		t.Errorf("unexpected prune run %+v", run)
+	}
+	if _, ok := storage.objects[archiveName(job.ID, "mon", CompressionNone, false)]; ok {
+		t.Error("expected the pruned archive to be deleted")
+	}
+	if _, ok := storage.objects[archiveName(job.ID, "tue", CompressionNone, false)]; !ok {
+		t.Error("expected the kept archive to stay")
+	}
+	history, err := m.GetHistory(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 6 {
+		t.Errorf("expected 6 backups left, got %d", len(history))
+	}
+
+	alerts, err := m.GetAlerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || alerts[0].Type != AlertTypeRetentionPolicyViolation {
+		t.Errorf("expected a retention alert, got %+v", alerts)
+	}
+
+	stats, err := m.GetStatistics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := stats.Pruning; p == nil || p.LastRun == nil || p.BackupsPruned != 2 || p.BytesFreed != 30 || p.Failures != 1 || len(p.RecentRuns) != 1 {
+		t.Errorf("unexpected pruning statistics %+v", stats.Pruning)
+	}
+
+	// Without daily, weekly, monthly or yearly limits nothing is pruned
+	job.Retention = Retention{MinBackups: 1}
+	if _, err := m.UpdateJob(job); err != nil {
+		t.Fatal(err)
+	}
+	if plan, err = m.PlanRetention(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Delete) != 0 || len(plan.Keep) != 5 {
+		t.Errorf("expected every completed backup to be kept, got %d kept and %d deleted", len(plan.Keep), len(plan.Delete))
+	}
+}
@@ -0,0 +1,575 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultPartSize is the multipart chunk size; S3 requires at least 5MiB per part
+	defaultPartSize = 16 * 1024 * 1024
+	minPartSize     = 5 * 1024 * 1024
+
+	s3LifecycleRuleID = "denshimon-backup-retention"
+)
+
+// S3Config holds configuration for an S3-compatible bucket (AWS S3, MinIO, etc.)
+type S3Config struct {
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	Bucket          string `json:"bucket"`
+	Prefix          string `json:"prefix,omitempty"`
+	AccessKeyID     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+	PathStyle       bool   `json:"pathStyle"`
+	PartSize        int64  `json:"partSize,omitempty"`
+	QuotaBytes      int64  `json:"quotaBytes,omitempty"`
+	ExpirationDays  int    `json:"expirationDays,omitempty"`
+}
+
+// S3Storage implements StorageBackend for S3-compatible object storage
+type S3Storage struct {
+	config S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Storage creates a new S3 storage backend
+func NewS3Storage(config S3Config) (*S3Storage, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("s3 endpoint is required")
+	}
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("s3 bucket is required")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("s3 credentials are required")
+	}
+	if !strings.HasPrefix(config.Endpoint, "http://") && !strings.HasPrefix(config.Endpoint, "https://") {
+		config.Endpoint = "https://" + config.Endpoint
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.PartSize < minPartSize {
+		config.PartSize = defaultPartSize
+	}
+	config.Prefix = strings.Trim(config.Prefix, "/")
+
+	return &S3Storage{
+		config: config,
+		client: &http.Client{
+			Timeout: 10 * time.Minute,
+		},
+		now: time.Now,
+	}, nil
+}
+
+// Type returns the storage backend type
+func (s *S3Storage) Type() StorageType {
+	return StorageTypeS3
+}
+
+// Upload stores a backup artifact, switching to multipart upload when it exceeds one part
+func (s *S3Storage) Upload(ctx context.Context, key string, r io.Reader) (string, error) {
+	objectKey := s.objectKey(key)
+
+	first, err := readPart(r, s.config.PartSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup data: %w", err)
+	}
+
+	if int64(len(first)) < s.config.PartSize {
+		resp, err := s.do(ctx, http.MethodPut, objectKey, nil, first, nil)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+		return s.location(objectKey), nil
+	}
+
+	if err := s.multipartUpload(ctx, objectKey, first, r); err != nil {
+		return "", err
+	}
+	return s.location(objectKey), nil
+}
+
+// multipartUpload uploads data in PartSize chunks, aborting the upload on failure
+func (s *S3Storage) multipartUpload(ctx context.Context, objectKey string, first []byte, r io.Reader) error {
+	resp, err := s.do(ctx, http.MethodPost, objectKey, url.Values{"uploads": {""}}, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to initiate multipart upload: %w", err)
+	}
+	var initiate struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&initiate)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to decode multipart upload response: %w", err)
+	}
+
+	type completedPart struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	var parts []completedPart
+
+	abort := func(cause error) error {
+		if resp, err := s.do(context.Background(), http.MethodDelete, objectKey, url.Values{"uploadId": {initiate.UploadID}}, nil, nil); err == nil {
+			resp.Body.Close()
+		}
+		return cause
+	}
+
+	chunk := first
+	for partNumber := 1; len(chunk) > 0; partNumber++ {
+		query := url.Values{
+			"partNumber": {strconv.Itoa(partNumber)},
+			"uploadId":   {initiate.UploadID},
+		}
+		resp, err := s.do(ctx, http.MethodPut, objectKey, query, chunk, nil)
+		if err != nil {
+			return abort(fmt.Errorf("failed to upload part %d: %w", partNumber, err))
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: partNumber, ETag: resp.Header.Get("ETag")})
+
+		if chunk, err = readPart(r, s.config.PartSize); err != nil {
+			return abort(fmt.Errorf("failed to read backup data: %w", err))
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return abort(err)
+	}
+
+	resp, err = s.do(ctx, http.MethodPost, objectKey, url.Values{"uploadId": {initiate.UploadID}}, body, nil)
+	if err != nil {
+		return abort(fmt.Errorf("failed to complete multipart upload: %w", err))
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Delete removes a backup artifact from the bucket
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.objectKey(key), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Download opens a stored object; the caller closes it
+func (s *S3Storage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.objectKey(key), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// List returns the objects whose key, below the configured prefix, starts with prefix
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]StoredObject, error) {
+	base := ""
+	if s.config.Prefix != "" {
+		base = s.config.Prefix + "/"
+	}
+
+	objects := []StoredObject{}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if base+prefix != "" {
+			query.Set("prefix", base+prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+			Contents              []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode object listing: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			objects = append(objects, StoredObject{
+				Key:          strings.TrimPrefix(object.Key, base),
+				Size:         object.Size,
+				LastModified: object.LastModified,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	return objects, nil
+}
+
+// Usage lists all objects under the configured prefix and reports their total size
+func (s *S3Storage) Usage(ctx context.Context) (*Storage, error) {
+	storage := &Storage{
+		Type:     StorageTypeS3,
+		Location: s.location(s.config.Prefix),
+		Status:   StorageStatusAvailable,
+	}
+
+	objects, err := s.List(ctx, "")
+	if err != nil {
+		storage.Status = StorageStatusUnavailable
+		return storage, err
+	}
+	for _, object := range objects {
+		modified := object.LastModified
+		storage.Used += object.Size
+		storage.BackupCount++
+		if storage.OldestBackup == nil || modified.Before(*storage.OldestBackup) {
+			storage.OldestBackup = &modified
+		}
+		if storage.NewestBackup == nil || modified.After(*storage.NewestBackup) {
+			storage.NewestBackup = &modified
+		}
+	}
+
+	// Object storage is unbounded unless a quota is configured
+	if s.config.QuotaBytes > 0 {
+		storage.Total = s.config.QuotaBytes
+		storage.Available = max(s.config.QuotaBytes-storage.Used, 0)
+		if storage.Available == 0 {
+			storage.Status = StorageStatusFull
+		}
+	}
+
+	return storage, nil
+}
+
+// ApplyRetention installs a bucket lifecycle rule expiring the backups under a job's
+// prefix, or removes it when the job keeps backups indefinitely. The bucket's other rules,
+// including those of other jobs, are kept.
+func (s *S3Storage) ApplyRetention(ctx context.Context, prefix string, retention Retention) error {
+	days := s.config.ExpirationDays
+	if days == 0 {
+		days = RetentionDays(retention)
+	}
+	ruleID := s3LifecycleRuleID + ":" + strings.TrimSuffix(prefix, "/")
+
+	rules, err := s.lifecycleRules(ctx)
+	if err != nil {
+		return err
+	}
+	kept := rules[:0]
+	for _, rule := range rules {
+		if rule.ID != ruleID {
+			kept = append(kept, rule)
+		}
+	}
+	if days == 0 && len(kept) == len(rules) {
+		return nil
+	}
+
+	var body bytes.Buffer
+	body.WriteString("<LifecycleConfiguration>")
+	for _, rule := range kept {
+		body.WriteString("<Rule>" + rule.Inner + "</Rule>")
+	}
+	if days > 0 {
+		type expiration struct {
+			Days int `xml:"Days"`
+		}
+		type filter struct {
+			Prefix string `xml:"Prefix"`
+		}
+		rule, err := xml.Marshal(struct {
+			XMLName    xml.Name   `xml:"Rule"`
+			ID         string     `xml:"ID"`
+			Filter     filter     `xml:"Filter"`
+			Status     string     `xml:"Status"`
+			Expiration expiration `xml:"Expiration"`
+		}{
+			ID:         ruleID,
+			Filter:     filter{Prefix: s.objectKey(prefix)},
+			Status:     "Enabled",
+			Expiration: expiration{Days: days},
+		})
+		if err != nil {
+			return err
+		}
+		body.Write(rule)
+	}
+	body.WriteString("</LifecycleConfiguration>")
+
+	var resp *http.Response
+	if days == 0 && len(kept) == 0 {
+		resp, err = s.do(ctx, http.MethodDelete, "", url.Values{"lifecycle": {""}}, nil, nil)
+	} else {
+		// PutBucketLifecycleConfiguration requires a Content-MD5 header
+		sum := md5.Sum(body.Bytes())
+		headers := map[string]string{"Content-MD5": base64.StdEncoding.EncodeToString(sum[:])}
+		resp, err = s.do(ctx, http.MethodPut, "", url.Values{"lifecycle": {""}}, body.Bytes(), headers)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply lifecycle configuration: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// lifecycleRule is a bucket lifecycle rule, kept as raw XML so rules set outside
+// Denshimon are written back unchanged
+type lifecycleRule struct {
+	ID    string `xml:"ID"`
+	Inner string `xml:",innerxml"`
+}
+
+// lifecycleRules returns the bucket's lifecycle rules; a bucket without a lifecycle
+// configuration has none
+func (s *S3Storage) lifecycleRules(ctx context.Context) ([]lifecycleRule, error) {
+	resp, err := s.do(ctx, http.MethodGet, "", url.Values{"lifecycle": {""}}, nil, nil)
+	if err != nil {
+		if strings.Contains(err.Error(), "NoSuchLifecycleConfiguration") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read lifecycle configuration: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var config struct {
+		Rules []lifecycleRule `xml:"Rule"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse lifecycle configuration: %w", err)
+	}
+	return config.Rules, nil
+}
+
+// TestConnection verifies the bucket exists and is accessible
+func (s *S3Storage) TestConnection(ctx context.Context) error {
+	resp, err := s.do(ctx, http.MethodHead, "", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// RetentionDays converts a retention policy into the maximum age of any retained backup
+func RetentionDays(retention Retention) int {
+	switch {
+	case retention.Yearly > 0:
+		return retention.Yearly * 365
+	case retention.Monthly > 0:
+		return retention.Monthly * 31
+	case retention.Weekly > 0:
+		return retention.Weekly * 7
+	default:
+		return retention.Daily
+	}
+}
+
+// objectKey prepends the configured prefix to a backup key
+func (s *S3Storage) objectKey(key string) string {
+	key = strings.TrimPrefix(key, "/")
+	if s.config.Prefix == "" {
+		return key
+	}
+	return s.config.Prefix + "/" + key
+}
+
+// location returns the s3:// URI of an object key
+func (s *S3Storage) location(objectKey string) string {
+	return fmt.Sprintf("s3://%s/%s", s.config.Bucket, objectKey)
+}
+
+// do builds, signs and sends a request against the bucket, returning an error for non-2xx responses
+func (s *S3Storage) do(ctx context.Context, method, objectKey string, query url.Values, body []byte, headers map[string]string) (*http.Response, error) {
+	endpoint, err := url.Parse(s.config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+
+	path := "/" + objectKey
+	if s.config.PathStyle {
+		path = "/" + s.config.Bucket + path
+	} else {
+		endpoint.Host = s.config.Bucket + "." + endpoint.Host
+	}
+	endpoint.Path = path
+	endpoint.RawPath = awsURIEncode(path, false)
+	endpoint.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	s.sign(req, body)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var s3Err struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		if xml.NewDecoder(resp.Body).Decode(&s3Err) == nil && s3Err.Code != "" {
+			return nil, fmt.Errorf("s3 %s %s: %s: %s", method, path, s3Err.Code, s3Err.Message)
+		}
+		return nil, fmt.Errorf("s3 %s %s: status %d", method, path, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// sign adds AWS Signature Version 4 headers to the request
+func (s *S3Storage) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	shortDate := now.Format("20060102")
+
+	payloadHash := sha256.Sum256(body)
+	payloadHex := hex.EncodeToString(payloadHash[:])
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHex)
+
+	headerNames := []string{"host"}
+	canonicalHeaders := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-md5" || lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headerNames = append(headerNames, lower)
+			canonicalHeaders[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	sort.Strings(headerNames)
+
+	var headerBlock strings.Builder
+	for _, name := range headerNames {
+		headerBlock.WriteString(name + ":" + canonicalHeaders[name] + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		headerBlock.String(),
+		signedHeaders,
+		payloadHex,
+	}, "\n")
+
+	scope := shortDate + "/" + s.config.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), shortDate)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by key as required by SigV4
+func canonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			pairs = append(pairs, awsURIEncode(k, true)+"="+awsURIEncode(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// awsURIEncode percent-encodes everything except unreserved characters (and '/' in paths)
+func awsURIEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// readPart reads up to size bytes, returning a short slice only at end of input
+func readPart(r io.Reader, size int64) ([]byte, error) {
+	buf := make([]byte, size)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return buf[:n], nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,309 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/archellir/denshimon/internal/secretbox"
+)
+
+// fakeS3 records requests against an in-memory bucket
+type fakeS3 struct {
+	mu        sync.Mutex
+	objects   map[string][]byte
+	parts     map[string]int
+	calls     []string
+	lifecycle []byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.calls = append(f.calls, "initiate")
+		fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		f.calls = append(f.calls, "part")
+		f.parts[key] += len(body)
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		f.calls = append(f.calls, "complete")
+		f.objects[key] = make([]byte, f.parts[key])
+	case r.Method == http.MethodGet && query.Has("lifecycle"):
+		f.calls = append(f.calls, "get-lifecycle")
+		if f.lifecycle == nil {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchLifecycleConfiguration</Code><Message>none</Message></Error>`)
+			return
+		}
+		w.Write(f.lifecycle)
+	case r.Method == http.MethodPut && query.Has("lifecycle"):
+		f.calls = append(f.calls, "lifecycle")
+		if r.Header.Get("Content-MD5") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.lifecycle = body
+	case r.Method == http.MethodDelete && query.Has("lifecycle"):
+		f.calls = append(f.calls, "delete-lifecycle")
+		f.lifecycle = nil
+	case r.Method == http.MethodPut:
+		f.calls = append(f.calls, "put")
+		f.objects[key] = body
+	case r.Method == http.MethodGet && query.Get("list-type") == "2":
+		fmt.Fprint(w, `<ListBucketResult><IsTruncated>false</IsTruncated>`)
+		for k, v := range f.objects {
+			if !strings.HasPrefix(k, query.Get("prefix")) {
+				continue
+			}
+			fmt.Fprintf(w, `<Contents><Key>%s</Key><Size>%d</Size><LastModified>2025-01-02T03:04:05.000Z</LastModified></Contents>`, k, len(v))
+		}
+		fmt.Fprint(w, `</ListBucketResult>`)
+	case r.Method == http.MethodHead:
+	case r.Method == http.MethodGet:
+		object, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(object)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func newTestS3(t *testing.T) (*S3Storage, *fakeS3) {
+	t.Helper()
+
+	fake := &fakeS3{objects: map[string][]byte{}, parts: map[string]int{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	storage, err := NewS3Storage(S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "bucket",
+		Prefix:          "denshimon",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		PathStyle:       true,
+		QuotaBytes:      64 * 1024 * 1024,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage failed: %v", err)
+	}
+	return storage, fake
+}
+
+func TestStorageConfigSealed(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "backup.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	box, err := secretbox.New("master-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(&fakeS3{objects: map[string][]byte{}, parts: map[string]int{}})
+	defer server.Close()
+
+	m := NewManager(db, nil)
+	m.SetEncryption(box)
+	created, err := m.AddStorage(context.Background(), StorageConfig{Name: "offsite", Type: StorageTypeS3, S3: &S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "bucket",
+		AccessKeyID:     "key",
+		SecretAccessKey: "s3-secret-key",
+		PathStyle:       true,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored string
+	if err := db.QueryRow(`SELECT config FROM backup_storage WHERE id = ?`, created.ID).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !secretbox.IsEncrypted(stored) || strings.Contains(stored, "s3-secret-key") {
+		t.Errorf("storage config stored unsealed: %q", stored)
+	}
+
+	// Sealed configs load once the box is set
+	reloaded := NewManager(db, nil)
+	if _, ok := reloaded.configs[created.ID]; ok {
+		t.Error("sealed storage config loaded without the box")
+	}
+	reloaded.SetEncryption(box)
+	if config, ok := reloaded.configs[created.ID]; !ok || config.S3.SecretAccessKey != "s3-secret-key" {
+		t.Errorf("storage config not loaded with the box: %+v", config)
+	}
+}
+
+func TestS3StorageUpload(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("SmallObjectUsesSinglePut", func(t *testing.T) {
+		storage, fake := newTestS3(t)
+
+		location, err := storage.Upload(ctx, "small.tar.gz", strings.NewReader("dump"))
+		if err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if location != "s3://bucket/denshimon/small.tar.gz" {
+			t.Errorf("unexpected location: %s", location)
+		}
+		if strings.Join(fake.calls, ",") != "put" {
+			t.Errorf("expected single put, got %v", fake.calls)
+		}
+	})
+
+	t.Run("LargeObjectUsesMultipart", func(t *testing.T) {
+		storage, fake := newTestS3(t)
+		data := bytes.Repeat([]byte("x"), defaultPartSize*2+10)
+
+		if _, err := storage.Upload(ctx, "large.tar.gz", bytes.NewReader(data)); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if strings.Join(fake.calls, ",") != "initiate,part,part,part,complete" {
+			t.Errorf("unexpected multipart sequence: %v", fake.calls)
+		}
+		if got := len(fake.objects["denshimon/large.tar.gz"]); got != len(data) {
+			t.Errorf("expected %d bytes uploaded, got %d", len(data), got)
+		}
+	})
+}
+
+func TestS3StorageUsage(t *testing.T) {
+	storage, _ := newTestS3(t)
+	ctx := context.Background()
+
+	storage.Upload(ctx, "a.tar.gz", strings.NewReader("12345"))
+	storage.Upload(ctx, "b.tar.gz", strings.NewReader("123"))
+
+	usage, err := storage.Usage(ctx)
+	if err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	if usage.Used != 8 || usage.BackupCount != 2 {
+		t.Errorf("expected 8 bytes in 2 backups, got %d in %d", usage.Used, usage.BackupCount)
+	}
+	if usage.Available != usage.Total-8 || usage.Status != StorageStatusAvailable {
+		t.Errorf("unexpected quota accounting: %+v", usage)
+	}
+}
+
+func TestS3StorageDownloadAndList(t *testing.T) {
+	storage, _ := newTestS3(t)
+	ctx := context.Background()
+
+	storage.Upload(ctx, "wal/job-1/000000010000000000000001", strings.NewReader("segment"))
+	storage.Upload(ctx, "backup.tar", strings.NewReader("archive"))
+
+	objects, err := storage.List(ctx, "wal/job-1/")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Key != "wal/job-1/000000010000000000000001" || objects[0].Size != 7 {
+		t.Errorf("unexpected listing: %+v", objects)
+	}
+
+	body, err := storage.Download(ctx, "backup.tar")
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); string(data) != "archive" {
+		t.Errorf("unexpected download %q", data)
+	}
+	if _, err := storage.Download(ctx, "missing.tar"); err == nil {
+		t.Error("expected a missing object to fail")
+	}
+}
+
+func TestS3StorageApplyRetention(t *testing.T) {
+	storage, fake := newTestS3(t)
+	ctx := context.Background()
+	fake.lifecycle = []byte(`<LifecycleConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">` +
+		`<Rule><ID>logs</ID><Filter><Prefix>logs/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>3</Days></Expiration></Rule>` +
+		`</LifecycleConfiguration>`)
+
+	if err := storage.ApplyRetention(ctx, "backups/job-a/", Retention{Daily: 7, Weekly: 2}); err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if err := storage.ApplyRetention(ctx, "backups/job-b/", Retention{Daily: 30}); err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	// Saving a job again replaces only its own rule
+	if err := storage.ApplyRetention(ctx, "backups/job-a/", Retention{Daily: 7, Weekly: 2}); err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if strings.Join(fake.calls, ",") != "get-lifecycle,lifecycle,get-lifecycle,lifecycle,get-lifecycle,lifecycle" {
+		t.Errorf("unexpected calls %v", fake.calls)
+	}
+	lifecycle := string(fake.lifecycle)
+	for _, want := range []string{
+		"<ID>logs</ID><Filter><Prefix>logs/</Prefix></Filter>",
+		"<Prefix>denshimon/backups/job-a/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>14</Days>",
+		"<Prefix>denshimon/backups/job-b/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>30</Days>",
+	} {
+		if !strings.Contains(lifecycle, want) {
+			t.Errorf("lifecycle configuration misses %s: %s", want, lifecycle)
+		}
+	}
+	if strings.Count(lifecycle, "<Rule>") != 3 {
+		t.Errorf("expected three rules, got %s", lifecycle)
+	}
+
+	// Jobs without retention drop their rule, and the configuration once it is empty
+	for _, prefix := range []string{"backups/job-a/", "backups/job-b/"} {
+		if err := storage.ApplyRetention(ctx, prefix, Retention{}); err != nil {
+			t.Fatalf("ApplyRetention failed: %v", err)
+		}
+	}
+	if lifecycle := string(fake.lifecycle); strings.Count(lifecycle, "<Rule>") != 1 || !strings.Contains(lifecycle, "<ID>logs</ID>") {
+		t.Errorf("expected only the logs rule to remain, got %s", lifecycle)
+	}
+	fake.lifecycle = nil
+	fake.calls = nil
+	if err := storage.ApplyRetention(ctx, "backups/job-c/", Retention{}); err != nil || strings.Join(fake.calls, ",") != "get-lifecycle" {
+		t.Errorf("expected nothing to change for a job without retention, got %v (%v)", fake.calls, err)
+	}
+}
+
+func TestAWSURIEncode(t *testing.T) {
+	tests := []struct {
+		input       string
+		encodeSlash bool
+		expected    string
+	}{
+		{"backups/a b.tar.gz", false, "backups/a%20b.tar.gz"},
+		{"backups/a b.tar.gz", true, "backups%2Fa%20b.tar.gz"},
+		{"key~_-.", true, "key~_-."},
+	}
+
+	for _, tt := range tests {
+		if got := awsURIEncode(tt.input, tt.encodeSlash); got != tt.expected {
+			t.Errorf("awsURIEncode(%q, %v) = %q, want %q", tt.input, tt.encodeSlash, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/archellir/denshimon/pkg/cron"
+)
+
+// schedulerInterval is how often the scheduler checks for due jobs
+const schedulerInterval = time.Minute
+
+// StartScheduler starts a background worker that runs jobs whose cron schedule is due and
+// prunes backups beyond their job's retention policy
+func (m *Manager) StartScheduler() {
+	go func() {
+		ticker := time.NewTicker(schedulerInterval)
+		defer ticker.Stop()
+		pruneTicker := time.NewTicker(retentionInterval)
+		defer pruneTicker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.runDueJobs(time.Now())
+			case <-pruneTicker.C:
+				m.pruneAll(context.Background())
+			}
+		}
+	}()
+}
+
+// runDueJobs starts every enabled job whose next scheduled run has passed
+func (m *Manager) runDueJobs(now time.Time) {
+	jobs, err := m.ListJobs()
+	if err != nil {
+		slog.Error("Failed to list backup jobs for scheduling", "error", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if !job.Schedule.Enabled || job.Schedule.Cron == "" {
+			continue
+		}
+
+		due := job.Schedule.NextScheduledRun
+		if due == nil {
+			// Jobs created before scheduling was enabled get their first slot computed now
+			next, err := nextScheduledRun(job.Schedule, job.UpdatedAt)
+			if err != nil {
+				continue
+			}
+			due = &next
+		}
+		if due.After(now) || job.Status == StatusRunning {
+			continue
+		}
+
+		job.Schedule.LastScheduledRun = &now
+		if err := m.UpdateSchedule(job.ID, job.Schedule); err != nil {
+			slog.Error("Failed to advance backup schedule", "job", job.ID, "error", err)
+			continue
+		}
+
+		if err := m.RunJob(job.ID); err != nil {
+			slog.Error("Failed to run scheduled backup job", "job", job.ID, "error", err)
+		}
+	}
+}
+
+// nextScheduledRun computes the next activation of a schedule after t
+func nextScheduledRun(schedule Schedule, t time.Time) (time.Time, error) {
+	next, err := cron.NextInZone(schedule.Cron, schedule.Timezone, t)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid schedule: %w", err)
+	}
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("schedule %q never fires", schedule.Cron)
+	}
+	return next, nil
+}
+
+// prepareSchedule validates an enabled schedule and fills in its next run time
+func prepareSchedule(schedule *Schedule, now time.Time) error {
+	if !schedule.Enabled || schedule.Cron == "" {
+		schedule.NextScheduledRun = nil
+		return nil
+	}
+
+	next, err := nextScheduledRun(*schedule, now)
+	if err != nil {
+		return err
+	}
+	schedule.NextScheduledRun = &next
+	return nil
+}
+
+// scheduleColumns returns the serialized schedule and the next_run column value
+func scheduleColumns(schedule Schedule) ([]byte, interface{}) {
+	scheduleJSON, _ := json.Marshal(schedule)
+	if schedule.NextScheduledRun == nil {
+		return scheduleJSON, nil
+	}
+	return scheduleJSON, *schedule.NextScheduledRun
+}
@@ -0,0 +1,314 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	snapshotLocationScheme = "volumesnapshot://"
+	snapshotReadyTimeout   = 30 * time.Minute
+	restoreBindTimeout     = 30 * time.Minute
+	snapshotPollInterval   = 5 * time.Second
+)
+
+// SnapshotRequest represents an on-demand PVC snapshot request
+type SnapshotRequest struct {
+	Namespace     string `json:"namespace"`
+	PVCName       string `json:"pvcName"`
+	SnapshotClass string `json:"snapshotClass,omitempty"`
+}
+
+// SnapshotRestoreRequest represents a request to restore a snapshot into a new PVC
+type SnapshotRestoreRequest struct {
+	ClaimName    string `json:"claimName"`
+	StorageClass string `json:"storageClass,omitempty"`
+}
+
+// requireK8s returns an error when snapshot operations are unavailable
+func (m *Manager) requireK8s() error {
+	if m.k8sClient == nil {
+		return fmt.Errorf("kubernetes client not available")
+	}
+	return nil
+}
+
+// ListSnapshotClasses returns the VolumeSnapshotClasses available in the cluster
+func (m *Manager) ListSnapshotClasses(ctx context.Context) ([]k8s.VolumeSnapshotClass, error) {
+	if err := m.requireK8s(); err != nil {
+		return nil, err
+	}
+	return m.k8sClient.ListVolumeSnapshotClasses(ctx)
+}
+
+// ListSnapshots returns VolumeSnapshots in a namespace (all namespaces if empty)
+func (m *Manager) ListSnapshots(ctx context.Context, namespace string) ([]k8s.VolumeSnapshot, error) {
+	if err := m.requireK8s(); err != nil {
+		return nil, err
+	}
+	return m.k8sClient.ListVolumeSnapshots(ctx, namespace)
+}
+
+// CreateSnapshot creates an on-demand snapshot of a PVC
+func (m *Manager) CreateSnapshot(ctx context.Context, req SnapshotRequest) (*k8s.VolumeSnapshot, error) {
+	if err := m.requireK8s(); err != nil {
+		return nil, err
+	}
+	if req.Namespace == "" || req.PVCName == "" {
+		return nil, fmt.Errorf("namespace and pvcName are required")
+	}
+
+	name := snapshotName(req.PVCName, time.Now())
+	labels := map[string]string{"app.kubernetes.io/managed-by": "denshimon"}
+	return m.k8sClient.CreateVolumeSnapshot(ctx, req.Namespace, req.PVCName, name, req.SnapshotClass, labels)
+}
+
+// DeleteSnapshot deletes a VolumeSnapshot
+func (m *Manager) DeleteSnapshot(ctx context.Context, namespace, name string) error {
+	if err := m.requireK8s(); err != nil {
+		return err
+	}
+	return m.k8sClient.DeleteVolumeSnapshot(ctx, namespace, name)
+}
+
+// RestoreSnapshot restores a snapshot into a new PVC and tracks progress as a recovery operation
+func (m *Manager) RestoreSnapshot(ctx context.Context, namespace, name string, req SnapshotRestoreRequest) (string, error) {
+	if err := m.requireK8s(); err != nil {
+		return "", err
+	}
+	if req.ClaimName == "" {
+		return "", fmt.Errorf("claimName is required")
+	}
+
+	pvc, err := m.k8sClient.RestorePVCFromSnapshot(ctx, namespace, name, req.ClaimName, req.StorageClass)
+	if err != nil {
+		return "", err
+	}
+
+	recoveryID := uuid.New().String()
+	target := fmt.Sprintf("pvc://%s/%s", namespace, req.ClaimName)
+	options := RecoveryOptions{RestoreToAlternateLocation: &target}
+	optionsJSON, _ := json.Marshal(options)
+
+	_, err = m.db.Exec(`
+		INSERT INTO backup_recoveries (id, backup_id, status, start_time, target_location, options)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, recoveryID, snapshotLocationScheme+namespace+"/"+name, RecoveryStatusRestoring, time.Now(), target, optionsJSON)
+	if err != nil {
+		return "", err
+	}
+
+	go m.trackSnapshotRestore(recoveryID, pvc)
+
+	return recoveryID, nil
+}
+
+// trackSnapshotRestore polls the restored claim until it is bound and records progress
+func (m *Manager) trackSnapshotRestore(recoveryID string, pvc *corev1.PersistentVolumeClaim) {
+	ctx, cancel := context.WithTimeout(context.Background(), restoreBindTimeout)
+	defer cancel()
+
+	totalBytes := int64(0)
+	if size, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+		totalBytes = size.Value()
+	}
+
+	// Claims using WaitForFirstConsumer are only provisioned once a pod mounts them
+	waitForConsumer := false
+	if pvc.Spec.StorageClassName != nil {
+		if sc, err := m.k8sClient.GetStorageClass(ctx, *pvc.Spec.StorageClassName); err == nil &&
+			sc.VolumeBindingMode != nil && *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
+			waitForConsumer = true
+		}
+	}
+
+	m.updateRecoveryProgress(recoveryID, RecoveryStatusRestoring, &RecoveryProgress{
+		Percentage: 50,
+		TotalBytes: totalBytes,
+		TotalFiles: 1,
+	})
+
+	ticker := time.NewTicker(snapshotPollInterval)
+	defer ticker.Stop()
+
+	for {
+		current, err := m.k8sClient.GetPersistentVolumeClaim(ctx, pvc.Namespace, pvc.Name)
+		if err == nil && (current.Status.Phase == corev1.ClaimBound || waitForConsumer) {
+			m.updateRecoveryProgress(recoveryID, RecoveryStatusCompleted, &RecoveryProgress{
+				Percentage:    100,
+				BytesRestored: totalBytes,
+				TotalBytes:    totalBytes,
+				FilesRestored: 1,
+				TotalFiles:    1,
+			})
+			m.db.Exec("UPDATE backup_recoveries SET end_time = ? WHERE id = ?", time.Now(), recoveryID)
+			return
+		}
+		if err == nil && current.Status.Phase == corev1.ClaimLost {
+			err = fmt.Errorf("restored claim %s was lost", pvc.Name)
+		}
+		if err != nil && ctx.Err() == nil {
+			m.failRecovery(recoveryID, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			m.failRecovery(recoveryID, fmt.Errorf("timed out waiting for claim %s to bind", pvc.Name))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateRecoveryProgress records the status and progress of a recovery operation
+func (m *Manager) updateRecoveryProgress(recoveryID string, status RecoveryStatus, progress *RecoveryProgress) {
+	progressJSON, _ := json.Marshal(progress)
+	m.db.Exec(`
+		UPDATE backup_recoveries SET status = ?, progress = ?
+		WHERE id = ?
+	`, status, progressJSON, recoveryID)
+}
+
+// failRecovery marks a recovery operation as failed and raises an alert
+func (m *Manager) failRecovery(recoveryID string, cause error) {
+	m.db.Exec(`
+		UPDATE backup_recoveries SET status = ?, end_time = ?, error = ?
+		WHERE id = ?
+	`, RecoveryStatusFailed, time.Now(), cause.Error(), recoveryID)
+	m.raiseAlert(AlertTypeRecoveryFailed, SeverityCritical, fmt.Sprintf("Recovery %s failed: %v", recoveryID, cause), "")
+}
+
+// runSnapshotJob snapshots the job's PVC and waits for the snapshot to become ready
+func (m *Manager) runSnapshotJob(job *Job, historyID string, started time.Time) {
+	if err := m.requireK8s(); err != nil {
+		m.failJob(job, historyID, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotReadyTimeout)
+	defer cancel()
+
+	namespace, pvcName := job.Metadata.Namespace, job.Metadata.PVCName
+	if namespace == "" || pvcName == "" {
+		m.failJob(job, historyID, fmt.Errorf("snapshot jobs require metadata.namespace and metadata.pvcName"))
+		return
+	}
+
+	// The snapshot is cut when it is created, so post hooks needn't wait for it to be ready
+	var hooks []HookResult
+	if err := m.runHooks(ctx, job, historyID, HookPhasePre, &hooks); err != nil {
+		m.runHooks(ctx, job, historyID, HookPhasePost, &hooks)
+		m.failJob(job, historyID, err)
+		return
+	}
+
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "denshimon",
+		"denshimon.io/backup-job":      job.ID,
+	}
+	snapshot, err := m.k8sClient.CreateVolumeSnapshot(ctx, namespace, pvcName, snapshotName(pvcName, started), job.Metadata.SnapshotClass, labels)
+	m.runHooks(ctx, job, historyID, HookPhasePost, &hooks)
+	if err != nil {
+		m.failJob(job, historyID, err)
+		return
+	}
+
+	location := snapshotLocationScheme + namespace + "/" + snapshot.Name
+	m.db.Exec("UPDATE backup_history SET location = ? WHERE id = ?", location, historyID)
+
+	ticker := time.NewTicker(snapshotPollInterval)
+	defer ticker.Stop()
+
+	for {
+		current, err := m.k8sClient.GetVolumeSnapshot(ctx, namespace, snapshot.Name)
+		if err == nil && current.Error != "" {
+			err = fmt.Errorf("snapshot failed: %s", current.Error)
+		}
+		if err != nil && ctx.Err() == nil {
+			m.failJob(job, historyID, err)
+			return
+		}
+		if err == nil && current.ReadyToUse {
+			var size int64
+			if q, err := resource.ParseQuantity(current.RestoreSize); err == nil {
+				size = q.Value()
+			}
+			duration := int(time.Since(started).Seconds())
+			now := time.Now()
+
+			m.db.Exec(`
+				UPDATE backup_jobs SET status = ?, size = ?, duration = ?, updated_at = ?
+				WHERE id = ?
+			`, StatusCompleted, size, duration, now, job.ID)
+			m.db.Exec(`
+				UPDATE backup_history SET status = ?, size = ?, duration = ?, verification_status = ?
+				WHERE id = ?
+			`, StatusCompleted, size, duration, VerificationVerified, historyID)
+			m.finished(historyID)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			m.failJob(job, historyID, fmt.Errorf("timed out waiting for snapshot %s to become ready", snapshot.Name))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// failJob marks a job run as failed and raises an alert
+func (m *Manager) failJob(job *Job, historyID string, cause error) {
+	now := time.Now()
+	m.db.Exec(`
+		UPDATE backup_jobs SET status = ?, error = ?, updated_at = ?
+		WHERE id = ?
+	`, StatusFailed, cause.Error(), now, job.ID)
+	m.db.Exec("UPDATE backup_history SET status = ? WHERE id = ?", StatusFailed, historyID)
+	m.raiseAlert(AlertTypeBackupFailed, SeverityCritical, fmt.Sprintf("Backup job %s failed: %v", job.Name, cause), job.ID)
+	m.finished(historyID)
+}
+
+// raiseAlert records a backup system alert
+func (m *Manager) raiseAlert(alertType AlertType, severity Severity, message, jobID string) {
+	var jobRef interface{}
+	if jobID != "" {
+		jobRef = jobID
+	}
+	m.db.Exec(`
+		INSERT INTO backup_alerts (id, type, severity, message, timestamp, job_id, acknowledged)
+		VALUES (?, ?, ?, ?, ?, ?, FALSE)
+	`, uuid.New().String(), alertType, severity, message, time.Now(), jobRef)
+}
+
+// snapshotName builds a DNS-1123 compliant snapshot name for a PVC. A random suffix keeps
+// snapshots of one claim taken within the same second apart.
+func snapshotName(pvcName string, t time.Time) string {
+	suffix := "-" + t.UTC().Format("20060102-150405") + "-" + uuid.New().String()[:8]
+	if len(pvcName)+len(suffix) > 253 {
+		pvcName = pvcName[:253-len(suffix)]
+	}
+	return pvcName + suffix
+}
+
+// parseSnapshotLocation extracts the namespace and name from a volumesnapshot:// location
+func parseSnapshotLocation(location string) (string, string, bool) {
+	if !strings.HasPrefix(location, snapshotLocationScheme) {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(location, snapshotLocationScheme), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
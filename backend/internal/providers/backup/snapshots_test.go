@@ -0,0 +1,26 @@
+package backup
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestSnapshotName(t *testing.T) {
+	now := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+
+	first, second := snapshotName("data-postgres-0", now), snapshotName("data-postgres-0", now)
+	if first == second {
+		t.Errorf("snapshots taken in the same second share the name %s", first)
+	}
+	if !strings.HasPrefix(first, "data-postgres-0-20240320-100000-") {
+		t.Errorf("unexpected name %s", first)
+	}
+
+	long := snapshotName(strings.Repeat("a", 260), now)
+	if errs := validation.IsDNS1123Subdomain(long); len(errs) > 0 {
+		t.Errorf("name %s is invalid: %v", long, errs)
+	}
+}
@@ -0,0 +1,63 @@
+package backup
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// StorageBackend defines the interface for backup storage providers
+type StorageBackend interface {
+	// Type returns the storage backend type
+	Type() StorageType
+
+	// Upload stores a backup artifact under the given key and returns its location
+	Upload(ctx context.Context, key string, r io.Reader) (string, error)
+
+	// Download opens a stored backup artifact
+	Download(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// List returns the stored artifacts whose key starts with prefix
+	List(ctx context.Context, prefix string) ([]StoredObject, error)
+
+	// Delete removes a backup artifact
+	Delete(ctx context.Context, key string) error
+
+	// Usage reports storage usage for the backend
+	Usage(ctx context.Context) (*Storage, error)
+
+	// ApplyRetention configures backend-side expiry for the artifacts under a job's prefix
+	ApplyRetention(ctx context.Context, prefix string, retention Retention) error
+
+	// TestConnection verifies the backend is reachable with the configured credentials
+	TestConnection(ctx context.Context) error
+}
+
+// StoredObject represents an artifact in a storage backend
+type StoredObject struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// StorageConfig represents a persisted storage backend configuration
+type StorageConfig struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Type      StorageType `json:"type"`
+	S3        *S3Config   `json:"s3,omitempty"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+}
+
+// Sanitized returns a copy of the configuration safe to return from the API
+func (c StorageConfig) Sanitized() StorageConfig {
+	if c.S3 != nil {
+		s3 := *c.S3
+		if s3.SecretAccessKey != "" {
+			s3.SecretAccessKey = "********"
+		}
+		c.S3 = &s3
+	}
+	return c
+}
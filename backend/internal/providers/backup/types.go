@@ -80,8 +80,22 @@ type Metadata struct {
 	Tables            []string        `json:"tables,omitempty"`
 	VolumePath        string          `json:"volumePath,omitempty"`
 	CompressionType   CompressionType `json:"compressionType,omitempty"`
+	CompressionLevel  int             `json:"compressionLevel,omitempty"` // gzip 1-9, zstd 1-22; 0 uses the default
 	EncryptionEnabled bool            `json:"encryptionEnabled"`
-	Checksum          string          `json:"checksum,omitempty"`
+	// EncryptionKeySource picks the key of encrypted backups, defaulting to the configured key
+	EncryptionKeySource EncryptionKeySource `json:"encryptionKeySource,omitempty"`
+	// Passphrase is the per-job key; it is sealed at rest and masked in responses
+	Passphrase    string `json:"passphrase,omitempty"`
+	Checksum      string `json:"checksum,omitempty"`
+	StorageID     string `json:"storageId,omitempty"`
+	Namespace     string `json:"namespace,omitempty"`
+	PVCName       string `json:"pvcName,omitempty"`
+	SnapshotClass string `json:"snapshotClass,omitempty"`
+	// WALArchiving marks PostgreSQL jobs whose archive_command stores WAL segments in the
+	// job's storage under wal/<job id>/, allowing point-in-time recovery
+	WALArchiving bool `json:"walArchiving,omitempty"`
+	// Hooks run in the application's pods before and after the backup
+	Hooks *Hooks `json:"hooks,omitempty"`
 }
 
 // CompressionType represents compression method
@@ -95,6 +109,24 @@ const (
 	CompressionZstd  CompressionType = "zstd"
 )
 
+// EncryptionKeySource represents where the key of an encrypted backup comes from
+type EncryptionKeySource string
+
+const (
+	EncryptionKeyConfig     EncryptionKeySource = "config"     // BACKUP_ENCRYPTION_KEY
+	EncryptionKeyPassphrase EncryptionKeySource = "passphrase" // the job's passphrase
+)
+
+// Encryption describes how a backup was encrypted. The key is never stored; KeyID
+// identifies it so verification and recovery can check the key they are given.
+type Encryption struct {
+	Algorithm string              `json:"algorithm"`
+	KeySource EncryptionKeySource `json:"keySource"`
+	KDF       string              `json:"kdf,omitempty"`  // derives passphrase keys
+	Salt      string              `json:"salt,omitempty"` // base64
+	KeyID     string              `json:"keyId"`
+}
+
 // History represents a backup history entry
 type History struct {
 	ID                 string             `json:"id"`
@@ -109,10 +141,20 @@ type History struct {
 	FilesCount         *int               `json:"filesCount,omitempty"`
 	Location           string             `json:"location"`
 	Checksum           string             `json:"checksum"`
+	Compression        CompressionType    `json:"compression,omitempty"`
+	CompressionLevel   int                `json:"compressionLevel,omitempty"`
+	Encryption         *Encryption        `json:"encryption,omitempty"`
 	VerificationStatus VerificationStatus `json:"verificationStatus,omitempty"`
 	RestorePoints      []RestorePoint     `json:"restorePoints,omitempty"`
+	Hooks              []HookResult       `json:"hooks,omitempty"`
 }
 
+// Finished is published when a backup run completed or failed
+type Finished struct{ History }
+
+// Topic names finished backup runs on the event bus
+func (Finished) Topic() string { return "backup.finished" }
+
 // VerificationStatus represents backup verification status
 type VerificationStatus string
 
@@ -182,6 +224,12 @@ type RecoveryOptions struct {
 	RestoreToAlternateLocation *string  `json:"restoreToAlternateLocation,omitempty"`
 	SelectedTables             []string `json:"selectedTables,omitempty"`
 	SelectedFiles              []string `json:"selectedFiles,omitempty"`
+	Passphrase                 string   `json:"passphrase,omitempty"` // key of backups encrypted with a passphrase
+}
+
+// VerifyRequest carries the key needed to verify or preview an encrypted backup
+type VerifyRequest struct {
+	Passphrase string `json:"passphrase,omitempty"` // for backups encrypted with a passphrase
 }
 
 // RecoveryProgress represents recovery operation progress
@@ -242,6 +290,7 @@ type Statistics struct {
 	BackupsBySource      map[Source]int  `json:"backupsBySource"`
 	DailyBackupTrend     []BackupTrend   `json:"dailyBackupTrend"`
 	StorageUsageTrend    []StorageTrend  `json:"storageUsageTrend"`
+	Pruning              *PruningStats   `json:"pruning,omitempty"`
 }
 
 // BackupTrend represents daily backup statistics
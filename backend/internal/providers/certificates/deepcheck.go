@@ -0,0 +1,205 @@
+package certificates
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// DefaultMinTLSVersion is the oldest protocol version a domain may accept without an issue
+const DefaultMinTLSVersion = "1.2"
+
+// TLSDetails describes a domain's TLS configuration beyond its certificate
+type TLSDetails struct {
+	Version           string   `json:"version"` // negotiated with default client settings
+	CipherSuite       string   `json:"cipherSuite"`
+	SupportedVersions []string `json:"supportedVersions,omitempty"`
+	MinVersion        string   `json:"minVersion,omitempty"` // oldest version the server accepts
+	BelowMinVersion   bool     `json:"belowMinVersion"`      // MinVersion is older than the domain allows
+	WeakCiphers       []string `json:"weakCiphers,omitempty"`
+	ChainValid        bool     `json:"chainValid"`
+	ChainError        string   `json:"chainError,omitempty"`
+	OCSPStapled       bool     `json:"ocspStapled"`
+	OCSPStatus        string   `json:"ocspStatus,omitempty"` // good, revoked, unknown or invalid
+	Issues            []string `json:"issues,omitempty"`
+}
+
+var tlsVersions = []struct {
+	name    string
+	version uint16
+}{
+	{"1.0", tls.VersionTLS10},
+	{"1.1", tls.VersionTLS11},
+	{"1.2", tls.VersionTLS12},
+	{"1.3", tls.VersionTLS13},
+}
+
+// tlsVersionName returns the short name of a protocol version, e.g. 1.2
+func tlsVersionName(version uint16) string {
+	for _, v := range tlsVersions {
+		if v.version == version {
+			return v.name
+		}
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+// ParseTLSVersion parses a protocol version written as 1.2, TLS1.2 or TLS 1.2
+func ParseTLSVersion(value string) (uint16, error) {
+	name := strings.TrimSpace(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "TLS"))
+	for _, v := range tlsVersions {
+		if v.name == name {
+			return v.version, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown TLS version: %s", value)
+}
+
+// weakCipherSuites are suites without forward secrecy or with broken primitives (RC4, 3DES, CBC-SHA256)
+func weakCipherSuites() []*tls.CipherSuite {
+	weak := append([]*tls.CipherSuite{}, tls.InsecureCipherSuites()...)
+	for _, suite := range tls.CipherSuites() {
+		if strings.HasPrefix(suite.Name, "TLS_RSA_") {
+			weak = append(weak, suite)
+		}
+	}
+	return weak
+}
+
+// verifyChain validates the presented chain against the system roots for the domain
+func verifyChain(state tls.ConnectionState, domain string) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("no certificates presented")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       domain,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+// ocspStatus checks a stapled OCSP response against the leaf and its issuer
+func ocspStatus(state tls.ConnectionState) string {
+	if len(state.OCSPResponse) == 0 || len(state.PeerCertificates) == 0 {
+		return ""
+	}
+	var issuer *x509.Certificate
+	if len(state.PeerCertificates) > 1 {
+		issuer = state.PeerCertificates[1]
+	}
+	response, err := ocsp.ParseResponseForCert(state.OCSPResponse, state.PeerCertificates[0], issuer)
+	if err != nil {
+		return "invalid"
+	}
+	switch response.Status {
+	case ocsp.Good:
+		return "good"
+	case ocsp.Revoked:
+		return "revoked"
+	default:
+		return "unknown"
+	}
+}
+
+// inspectConnection fills the details available from a single handshake
+func inspectConnection(state tls.ConnectionState, domain string) *TLSDetails {
+	details := &TLSDetails{
+		Version:     tlsVersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		OCSPStapled: len(state.OCSPResponse) > 0,
+		OCSPStatus:  ocspStatus(state),
+	}
+	if err := verifyChain(state, domain); err != nil {
+		details.ChainError = err.Error()
+		details.Issues = append(details.Issues, "certificate chain does not verify: "+err.Error())
+	} else {
+		details.ChainValid = true
+	}
+	if details.OCSPStatus == "revoked" {
+		details.Issues = append(details.Issues, "stapled OCSP response reports the certificate as revoked")
+	}
+	return details
+}
+
+// handshake connects with the given settings and returns the negotiated state
+func (s *SSLChecker) handshake(address, domain string, config *tls.Config) (tls.ConnectionState, error) {
+	config.ServerName = domain
+	config.InsecureSkipVerify = true // verified separately so invalid chains are still reported
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: s.timeout}, "tcp", address, config)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+	return conn.ConnectionState(), nil
+}
+
+// probeProtocols records which protocol versions and weak cipher suites the server accepts,
+// adding issues for versions older than minVersion and for every weak suite
+func (s *SSLChecker) probeProtocols(address, domain string, minVersion uint16, details *TLSDetails) {
+	// Offer every suite so a version is not missed because the default list lacks the server's suites
+	var allSuites []uint16
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		allSuites = append(allSuites, suite.ID)
+	}
+	for _, v := range tlsVersions {
+		if _, err := s.handshake(address, domain, &tls.Config{MinVersion: v.version, MaxVersion: v.version, CipherSuites: allSuites}); err == nil {
+			details.SupportedVersions = append(details.SupportedVersions, v.name)
+			if details.MinVersion == "" {
+				details.MinVersion = v.name
+				if v.version < minVersion {
+					details.BelowMinVersion = true
+					details.Issues = append(details.Issues, fmt.Sprintf("server accepts TLS %s, below the minimum TLS %s", v.name, tlsVersionName(minVersion)))
+				}
+			}
+		}
+	}
+
+	// One handshake offering every weak suite; only probe them individually if one is accepted
+	weak := weakCipherSuites()
+	ids := make([]uint16, len(weak))
+	for i, suite := range weak {
+		ids[i] = suite.ID
+	}
+	weakConfig := func(ids ...uint16) *tls.Config {
+		return &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS12, CipherSuites: ids}
+	}
+	if _, err := s.handshake(address, domain, weakConfig(ids...)); err != nil {
+		return
+	}
+	for _, suite := range weak {
+		if _, err := s.handshake(address, domain, weakConfig(suite.ID)); err == nil {
+			details.WeakCiphers = append(details.WeakCiphers, suite.Name)
+		}
+	}
+	if len(details.WeakCiphers) > 0 {
+		details.Issues = append(details.Issues, fmt.Sprintf("server accepts weak cipher suites: %s", strings.Join(details.WeakCiphers, ", ")))
+	}
+}
+
+// DeepCheck checks a domain's certificate and probes its TLS configuration: chain
+// validation, OCSP stapling, accepted protocol versions and weak cipher suites
+func (s *SSLChecker) DeepCheck(config DomainConfig) (*CertificateCheck, error) {
+	minTLSVersion := config.MinTLSVersion
+	if minTLSVersion == "" {
+		minTLSVersion = DefaultMinTLSVersion
+	}
+	minVersion, err := ParseTLSVersion(minTLSVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	check, err := s.CheckCertificate(config.Domain, config.Port)
+	if err != nil || !check.Success {
+		return check, err
+	}
+	s.probeProtocols(fmt.Sprintf("%s:%d", config.Domain, config.Port), config.Domain, minVersion, check.Certificate.TLS)
+	return check, nil
+}
@@ -0,0 +1,147 @@
+package certificates
+
+import (
+	"crypto/tls"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func startTLSServer(t *testing.T, config *tls.Config) (string, int) {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = config
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // failed probe handshakes are expected
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNumber, _ := strconv.Atoi(port)
+	return host, portNumber
+}
+
+func TestDeepCheckModernServer(t *testing.T) {
+	host, port := startTLSServer(t, &tls.Config{MinVersion: tls.VersionTLS12})
+
+	check, err := NewSSLChecker().DeepCheck(DomainConfig{Domain: host, Port: port})
+	if err != nil || !check.Success {
+		t.Fatalf("check failed: %v %v", err, check.ErrorMessage)
+	}
+	details := check.Certificate.TLS
+	// The test certificate is self-signed, so the chain must not verify
+	if details.ChainValid || details.ChainError == "" || check.Certificate.Status != StatusInvalid {
+		t.Errorf("self-signed chain reported valid: %+v", details)
+	}
+	if details.MinVersion != "1.2" || details.BelowMinVersion {
+		t.Errorf("unexpected minimum version: %+v", details)
+	}
+	if !slices.Contains(details.SupportedVersions, "1.3") {
+		t.Errorf("TLS 1.3 not detected: %v", details.SupportedVersions)
+	}
+	if len(details.WeakCiphers) != 0 {
+		t.Errorf("unexpected weak ciphers: %v", details.WeakCiphers)
+	}
+}
+
+func TestDeepCheckWeakServer(t *testing.T) {
+	host, port := startTLSServer(t, &tls.Config{
+		MinVersion: tls.VersionTLS10,
+		MaxVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		},
+	})
+
+	check, err := NewSSLChecker().DeepCheck(DomainConfig{Domain: host, Port: port})
+	if err != nil || !check.Success {
+		t.Fatalf("check failed: %v %v", err, check.ErrorMessage)
+	}
+	details := check.Certificate.TLS
+	if details.MinVersion != "1.0" || !details.BelowMinVersion {
+		t.Errorf("old protocol versions not reported: %+v", details)
+	}
+	if !slices.Contains(details.WeakCiphers, "TLS_RSA_WITH_AES_128_CBC_SHA") {
+		t.Errorf("weak cipher not reported: %v", details.WeakCiphers)
+	}
+
+	// Allowing TLS 1.0 for the domain drops the version issue
+	check, _ = NewSSLChecker().DeepCheck(DomainConfig{Domain: host, Port: port, MinTLSVersion: "TLS1.0"})
+	if check.Certificate.TLS.BelowMinVersion {
+		t.Error("TLS 1.0 reported below a TLS 1.0 minimum")
+	}
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	for value, want := range map[string]uint16{"1.2": tls.VersionTLS12, "TLS1.3": tls.VersionTLS13, "tls 1.0": tls.VersionTLS10} {
+		if got, err := ParseTLSVersion(value); err != nil || got != want {
+			t.Errorf("ParseTLSVersion(%q) = %x, %v", value, got, err)
+		}
+	}
+	if _, err := ParseTLSVersion("1.4"); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+}
+
+func TestExpiryChanges(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	oldExpiry := start.AddDate(0, 0, 20)
+	newExpiry := start.AddDate(0, 0, 95)
+	record := func(domain string, day int, notAfter time.Time) CheckRecord {
+		return CheckRecord{Domain: domain, CheckedAt: start.AddDate(0, 0, day), NotAfter: &notAfter}
+	}
+
+	changes := ExpiryChanges([]CheckRecord{
+		record("a.example", 0, oldExpiry),
+		record("b.example", 0, newExpiry),
+		{Domain: "a.example", CheckedAt: start.AddDate(0, 0, 1)}, // unreachable
+		record("a.example", 2, oldExpiry),
+		record("a.example", 5, newExpiry),
+		record("b.example", 5, newExpiry),
+	})
+
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %+v", changes)
+	}
+	change := changes[0]
+	if change.Domain != "a.example" || change.DriftDays != 75 || change.DaysLeftAtRenewal != 15 {
+		t.Errorf("unexpected change: %+v", change)
+	}
+}
+
+func TestExpiryReportedOnStatusChange(t *testing.T) {
+	m := NewManager()
+	cert := &Certificate{Domain: "git.example.com"}
+	report := func(status CertificateStatus) bool {
+		cert.Status = status
+		return m.updateAlertsForCertificate(cert)
+	}
+
+	steps := []struct {
+		status CertificateStatus
+		want   bool
+	}{
+		{StatusValid, false},
+		{StatusExpiringSoon, true},
+		{StatusExpiringSoon, false}, // checked again, still expiring soon
+		{StatusExpiringCritical, true},
+		{StatusExpired, true},
+		{StatusValid, false}, // renewed
+		{StatusExpiringSoon, true},
+		{StatusInvalid, false},
+	}
+	for i, step := range steps {
+		if got := report(step.status); got != step.want {
+			t.Errorf("step %d (%s): reported = %v, want %v", i, step.status, got, step.want)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package certificates
 
+import "time"
+
 // CertificateProvider defines the interface for SSL certificate monitoring
 type CertificateProvider interface {
 	// CheckCertificate checks the SSL certificate for a specific domain
@@ -28,4 +30,7 @@ type CertificateProvider interface {
 
 	// AcknowledgeAlert marks an alert as acknowledged
 	AcknowledgeAlert(alertID string) error
+
+	// GetCheckHistory returns stored check results since the given time
+	GetCheckHistory(domain string, since time.Time) ([]CheckRecord, error)
 }
@@ -1,9 +1,14 @@
 package certificates
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/archellir/denshimon/internal/bus"
 )
 
 // Manager manages SSL certificate monitoring for multiple domains
@@ -12,7 +17,12 @@ type Manager struct {
 	domains      map[string]DomainConfig
 	certificates map[string]*Certificate
 	alerts       map[string]*CertificateAlert
+	store        *Store // check history, optional
 	mutex        sync.RWMutex
+
+	bus *bus.Bus // expiring certificates are published on it, optional
+	// expiryReported is the status each domain's expiry was last published with
+	expiryReported map[string]CertificateStatus
 }
 
 // NewManager creates a new certificate manager
@@ -22,6 +32,8 @@ func NewManager() *Manager {
 		domains:      make(map[string]DomainConfig),
 		certificates: make(map[string]*Certificate),
 		alerts:       make(map[string]*CertificateAlert),
+
+		expiryReported: make(map[string]CertificateStatus),
 	}
 
 	// Initialize with default base infrastructure domains
@@ -154,42 +166,143 @@ func (m *Manager) GetAllCertificates() ([]Certificate, error) {
 	return certificates, nil
 }
 
+// SetStore enables check history, used for expiry drift in the statistics
+func (m *Manager) SetStore(store *Store) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.store = store
+}
+
+// SetBus sets the event bus certificates starting to expire are published on
+func (m *Manager) SetBus(b *bus.Bus) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.bus = b
+}
+
 // RefreshAllCertificates checks all configured domains and updates certificates
 func (m *Manager) RefreshAllCertificates() error {
+	for _, domain := range m.enabledDomains() {
+		m.checkDomain(domain)
+	}
+
+	return nil
+}
+
+// StartScheduler checks each enabled domain whenever its check interval has passed
+func (m *Manager) StartScheduler(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(schedulerTick)
+		defer ticker.Stop()
+		for {
+			m.checkDueDomains()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// schedulerTick is how often the scheduler looks for domains due a check
+const schedulerTick = time.Minute
+
+// checkDueDomains deep-checks the domains whose interval has elapsed since their last check
+func (m *Manager) checkDueDomains() {
+	now := time.Now()
+	checked := false
+	for _, domain := range m.enabledDomains() {
+		interval := time.Duration(domain.CheckInterval) * time.Minute
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		if domain.LastCheck != nil && now.Sub(*domain.LastCheck) < interval {
+			continue
+		}
+		m.checkDomain(domain)
+		checked = true
+	}
+
 	m.mutex.RLock()
+	store := m.store
+	m.mutex.RUnlock()
+	if checked && store != nil {
+		if err := store.Prune(now.Add(-DefaultHistoryRetention)); err != nil {
+			slog.Warn("Failed to prune certificate history", "error", err)
+		}
+	}
+}
+
+func (m *Manager) enabledDomains() []DomainConfig {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 	domains := make([]DomainConfig, 0, len(m.domains))
 	for _, domain := range m.domains {
 		if domain.Enabled {
 			domains = append(domains, domain)
 		}
 	}
-	m.mutex.RUnlock()
+	return domains
+}
 
-	for _, domain := range domains {
-		check, err := m.checker.CheckCertificate(domain.Domain, domain.Port)
-		if err != nil {
-			// Log error but continue with other domains
-			continue
+// checkDomain deep-checks one domain, updating its certificate, alerts and history
+func (m *Manager) checkDomain(domain DomainConfig) {
+	check, err := m.checker.DeepCheck(domain)
+	if err != nil {
+		// Log error but continue with other domains
+		slog.Warn("Certificate check failed", "domain", domain.Domain, "error", err)
+		return
+	}
+
+	// Expiry is published once the lock is released
+	var events *bus.Bus
+	defer func() {
+		if events != nil {
+			events.Publish(Expiring{*check.Certificate})
 		}
+	}()
 
-		m.mutex.Lock()
-		if check.Success && check.Certificate != nil {
-			m.certificates[domain.Domain] = check.Certificate
-			m.updateAlertsForCertificate(check.Certificate)
-		} else {
-			// Create unreachable alert
-			m.createUnreachableAlert(domain.Domain, check.ErrorMessage)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, exists := m.domains[domain.Domain]; !exists {
+		return // removed while being checked
+	}
+	if check.Success && check.Certificate != nil {
+		m.certificates[domain.Domain] = check.Certificate
+		delete(m.alerts, fmt.Sprintf("%s-unreachable", domain.Domain))
+		if m.updateAlertsForCertificate(check.Certificate) {
+			events = m.bus
 		}
+		m.updateTLSAlert(check.Certificate)
+	} else {
+		// Create unreachable alert
+		m.createUnreachableAlert(domain.Domain, check.ErrorMessage)
+	}
 
-		// Update last check time
-		now := time.Now()
-		domainConfig := m.domains[domain.Domain]
-		domainConfig.LastCheck = &now
-		m.domains[domain.Domain] = domainConfig
-		m.mutex.Unlock()
+	// Update last check time
+	now := time.Now()
+	domainConfig := m.domains[domain.Domain]
+	domainConfig.LastCheck = &now
+	m.domains[domain.Domain] = domainConfig
+
+	if m.store != nil {
+		if err := m.store.Record(check); err != nil {
+			slog.Warn("Failed to record certificate check", "domain", domain.Domain, "error", err)
+		}
 	}
+}
 
-	return nil
+// GetCheckHistory returns stored check results since the given time, for one domain or all for ""
+func (m *Manager) GetCheckHistory(domain string, since time.Time) ([]CheckRecord, error) {
+	m.mutex.RLock()
+	store := m.store
+	m.mutex.RUnlock()
+	if store == nil {
+		return nil, fmt.Errorf("certificate history is not enabled")
+	}
+	return store.History(domain, since)
 }
 
 // GetCertificateStats returns certificate statistics
@@ -216,6 +329,26 @@ func (m *Manager) GetCertificateStats() (*CertificateStats, error) {
 		case StatusUnreachable:
 			stats.Unreachable++
 		}
+
+		if cert.TLS != nil {
+			if !cert.TLS.ChainValid {
+				stats.ChainInvalid++
+			}
+			if len(cert.TLS.WeakCiphers) > 0 || cert.TLS.BelowMinVersion {
+				stats.WeakTLS++
+			}
+			if cert.TLS.OCSPStapled {
+				stats.OCSPStapled++
+			}
+		}
+	}
+
+	if m.store != nil {
+		history, err := m.store.History("", time.Now().Add(-DefaultHistoryRetention))
+		if err != nil {
+			return nil, err
+		}
+		stats.ExpiryChanges = ExpiryChanges(history)
 	}
 
 	return stats, nil
@@ -276,6 +409,18 @@ func (m *Manager) GetAlerts() ([]CertificateAlert, error) {
 	return alerts, nil
 }
 
+// ListAlerts returns all certificate alerts, acknowledged ones included
+func (m *Manager) ListAlerts() []CertificateAlert {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	alerts := make([]CertificateAlert, 0, len(m.alerts))
+	for _, alert := range m.alerts {
+		alerts = append(alerts, *alert)
+	}
+	return alerts
+}
+
 // AcknowledgeAlert marks an alert as acknowledged
 func (m *Manager) AcknowledgeAlert(alertID string) error {
 	m.mutex.Lock()
@@ -289,14 +434,16 @@ func (m *Manager) AcknowledgeAlert(alertID string) error {
 	return fmt.Errorf("alert %s not found", alertID)
 }
 
-// updateAlertsForCertificate creates/updates alerts for a certificate
-func (m *Manager) updateAlertsForCertificate(cert *Certificate) {
+// updateAlertsForCertificate creates/updates alerts for a certificate and returns whether
+// its expiry status changed since it was last reported
+func (m *Manager) updateAlertsForCertificate(cert *Certificate) bool {
 	alertID := fmt.Sprintf("%s-expiry", cert.Domain)
 
 	// Remove existing expiry alert if certificate is valid
 	if cert.Status == StatusValid {
 		delete(m.alerts, alertID)
-		return
+		delete(m.expiryReported, cert.Domain)
+		return false
 	}
 
 	// Create/update expiry alert
@@ -330,6 +477,40 @@ func (m *Manager) updateAlertsForCertificate(cert *Certificate) {
 		Timestamp:    time.Now(),
 		Acknowledged: false,
 	}
+
+	if alertType != "expiration" || m.expiryReported[cert.Domain] == cert.Status {
+		return false
+	}
+	m.expiryReported[cert.Domain] = cert.Status
+	return true
+}
+
+// updateTLSAlert raises a warning for protocol problems found by a deep check, or
+// a critical alert when the stapled OCSP response reports the certificate revoked
+func (m *Manager) updateTLSAlert(cert *Certificate) {
+	alertID := fmt.Sprintf("%s-tls", cert.Domain)
+	if cert.TLS == nil || len(cert.TLS.Issues) == 0 {
+		delete(m.alerts, alertID)
+		return
+	}
+
+	severity := "warning"
+	if cert.TLS.OCSPStatus == "revoked" {
+		severity = "critical"
+	}
+	message := fmt.Sprintf("TLS issues for %s: %s", cert.Domain, strings.Join(cert.TLS.Issues, "; "))
+	if existing, ok := m.alerts[alertID]; ok && existing.Message == message {
+		return // keep the acknowledgement until the issues change
+	}
+	m.alerts[alertID] = &CertificateAlert{
+		ID:           alertID,
+		Domain:       cert.Domain,
+		Type:         "tls",
+		Severity:     severity,
+		Message:      message,
+		Timestamp:    time.Now(),
+		Acknowledged: false,
+	}
 }
 
 // createUnreachableAlert creates an alert for unreachable domains
@@ -7,7 +7,6 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
-	"net"
 	"strings"
 	"time"
 )
@@ -32,25 +31,17 @@ func (s *SSLChecker) CheckCertificate(domain string, port int) (*CertificateChec
 		Success:   false,
 	}
 
-	// Create TLS connection
-	dialer := &net.Dialer{
-		Timeout: s.timeout,
-	}
-
+	// Create TLS connection; the chain is verified afterwards so invalid certificates are still inspected
 	address := fmt.Sprintf("%s:%d", domain, port)
-	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{
-		ServerName: domain,
-	})
-
+	state, err := s.handshake(address, domain, &tls.Config{})
 	if err != nil {
 		errorMsg := fmt.Sprintf("Failed to connect to %s: %v", address, err)
 		check.ErrorMessage = &errorMsg
 		return check, nil
 	}
-	defer conn.Close()
 
 	// Get certificate chain
-	certs := conn.ConnectionState().PeerCertificates
+	certs := state.PeerCertificates
 	if len(certs) == 0 {
 		errorMsg := "No certificates found in chain"
 		check.ErrorMessage = &errorMsg
@@ -101,10 +92,14 @@ func (s *SSLChecker) CheckCertificate(domain string, port int) (*CertificateChec
 		Fingerprint:  fingerprint,
 		Chain:        chain,
 		LastChecked:  time.Now(),
+		TLS:          inspectConnection(state, domain),
 	}
 
 	// Set status
 	certificate.Status = certificate.GetStatus()
+	if !certificate.TLS.ChainValid && certificate.Status != StatusExpired {
+		certificate.Status = StatusInvalid
+	}
 
 	check.Certificate = certificate
 	check.Success = true
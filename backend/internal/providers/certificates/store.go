@@ -0,0 +1,205 @@
+package certificates
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultHistoryRetention is how long check results are kept
+const DefaultHistoryRetention = 90 * 24 * time.Hour
+
+// CheckRecord is the stored outcome of one scheduled check
+type CheckRecord struct {
+	Domain          string            `json:"domain"`
+	CheckedAt       time.Time         `json:"checkedAt"`
+	Success         bool              `json:"success"`
+	Error           string            `json:"error,omitempty"`
+	Status          CertificateStatus `json:"status,omitempty"`
+	NotAfter        *time.Time        `json:"notAfter,omitempty"`
+	DaysUntilExpiry int               `json:"daysUntilExpiry"`
+	Fingerprint     string            `json:"fingerprint,omitempty"`
+	TLSVersion      string            `json:"tlsVersion,omitempty"`
+	MinTLSVersion   string            `json:"minTLSVersion,omitempty"`
+	ChainValid      bool              `json:"chainValid"`
+	OCSPStapled     bool              `json:"ocspStapled"`
+	Issues          []string          `json:"issues,omitempty"`
+}
+
+// ExpiryChange records a certificate's expiry moving between two checks, normally a renewal
+type ExpiryChange struct {
+	Domain            string    `json:"domain"`
+	ChangedAt         time.Time `json:"changedAt"`
+	PreviousNotAfter  time.Time `json:"previousNotAfter"`
+	NotAfter          time.Time `json:"notAfter"`
+	DriftDays         int       `json:"driftDays"`         // how far the expiry moved; negative if it moved earlier
+	DaysLeftAtRenewal int       `json:"daysLeftAtRenewal"` // days the previous certificate had left when it was replaced
+}
+
+// Store keeps certificate check history in SQLite
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a certificate history store and its table
+func NewStore(db *sql.DB) (*Store, error) {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS certificate_checks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			domain TEXT NOT NULL,
+			checked_at INTEGER NOT NULL, -- unix milliseconds
+			success BOOLEAN NOT NULL,
+			error TEXT,
+			status TEXT,
+			not_after INTEGER, -- unix milliseconds
+			days_until_expiry INTEGER NOT NULL DEFAULT 0,
+			fingerprint TEXT,
+			tls_version TEXT,
+			min_tls_version TEXT,
+			chain_valid BOOLEAN NOT NULL DEFAULT FALSE,
+			ocsp_stapled BOOLEAN NOT NULL DEFAULT FALSE,
+			issues TEXT -- JSON array
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_certificate_checks_domain ON certificate_checks(domain, checked_at)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			return nil, fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+	return &Store{db: db}, nil
+}
+
+// Record stores the result of a check
+func (s *Store) Record(check *CertificateCheck) error {
+	record := recordFromCheck(check)
+
+	var notAfter sql.NullInt64
+	if record.NotAfter != nil {
+		notAfter = sql.NullInt64{Int64: record.NotAfter.UnixMilli(), Valid: true}
+	}
+	var issues sql.NullString
+	if len(record.Issues) > 0 {
+		data, _ := json.Marshal(record.Issues)
+		issues = sql.NullString{String: string(data), Valid: true}
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO certificate_checks (domain, checked_at, success, error, status, not_after, days_until_expiry,
+			fingerprint, tls_version, min_tls_version, chain_valid, ocsp_stapled, issues)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.Domain, record.CheckedAt.UnixMilli(), record.Success, record.Error, string(record.Status), notAfter,
+		record.DaysUntilExpiry, record.Fingerprint, record.TLSVersion, record.MinTLSVersion,
+		record.ChainValid, record.OCSPStapled, issues)
+	if err != nil {
+		return fmt.Errorf("failed to record certificate check: %w", err)
+	}
+	return nil
+}
+
+// History returns the checks since the given time, oldest first, for one domain or all for ""
+func (s *Store) History(domain string, since time.Time) ([]CheckRecord, error) {
+	query := `SELECT domain, checked_at, success, error, status, not_after, days_until_expiry,
+			fingerprint, tls_version, min_tls_version, chain_valid, ocsp_stapled, issues
+		FROM certificate_checks WHERE checked_at >= ?`
+	args := []interface{}{since.UnixMilli()}
+	if domain != "" {
+		query += ` AND domain = ?`
+		args = append(args, domain)
+	}
+	query += ` ORDER BY checked_at`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query certificate checks: %w", err)
+	}
+	defer rows.Close()
+
+	records := []CheckRecord{}
+	for rows.Next() {
+		var record CheckRecord
+		var checkedAt int64
+		var notAfter sql.NullInt64
+		var errorMsg, status, fingerprint, tlsVersion, minTLSVersion, issues sql.NullString
+		if err := rows.Scan(&record.Domain, &checkedAt, &record.Success, &errorMsg, &status, &notAfter,
+			&record.DaysUntilExpiry, &fingerprint, &tlsVersion, &minTLSVersion,
+			&record.ChainValid, &record.OCSPStapled, &issues); err != nil {
+			return nil, fmt.Errorf("failed to scan certificate check: %w", err)
+		}
+		record.CheckedAt = time.UnixMilli(checkedAt)
+		record.Error = errorMsg.String
+		record.Status = CertificateStatus(status.String)
+		record.Fingerprint = fingerprint.String
+		record.TLSVersion = tlsVersion.String
+		record.MinTLSVersion = minTLSVersion.String
+		if notAfter.Valid {
+			t := time.UnixMilli(notAfter.Int64)
+			record.NotAfter = &t
+		}
+		if issues.Valid {
+			json.Unmarshal([]byte(issues.String), &record.Issues)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// Prune deletes checks older than the given time
+func (s *Store) Prune(before time.Time) error {
+	if _, err := s.db.Exec(`DELETE FROM certificate_checks WHERE checked_at < ?`, before.UnixMilli()); err != nil {
+		return fmt.Errorf("failed to prune certificate checks: %w", err)
+	}
+	return nil
+}
+
+func recordFromCheck(check *CertificateCheck) CheckRecord {
+	record := CheckRecord{
+		Domain:    check.Domain,
+		CheckedAt: check.Timestamp,
+		Success:   check.Success,
+	}
+	if check.ErrorMessage != nil {
+		record.Error = *check.ErrorMessage
+	}
+	if cert := check.Certificate; cert != nil {
+		notAfter := cert.NotAfter
+		record.NotAfter = &notAfter
+		record.Status = cert.Status
+		record.DaysUntilExpiry = cert.DaysUntilExpiry
+		record.Fingerprint = cert.Fingerprint
+		if details := cert.TLS; details != nil {
+			record.TLSVersion = details.Version
+			record.MinTLSVersion = details.MinVersion
+			record.ChainValid = details.ChainValid
+			record.OCSPStapled = details.OCSPStapled
+			record.Issues = details.Issues
+		}
+	} else {
+		record.Status = StatusUnreachable
+	}
+	return record
+}
+
+// ExpiryChanges finds every change of a domain's expiry date in oldest-first check history
+func ExpiryChanges(records []CheckRecord) []ExpiryChange {
+	changes := []ExpiryChange{}
+	previous := map[string]CheckRecord{}
+	for _, record := range records {
+		if record.NotAfter == nil {
+			continue
+		}
+		if last, ok := previous[record.Domain]; ok && !last.NotAfter.Equal(*record.NotAfter) {
+			changes = append(changes, ExpiryChange{
+				Domain:            record.Domain,
+				ChangedAt:         record.CheckedAt,
+				PreviousNotAfter:  *last.NotAfter,
+				NotAfter:          *record.NotAfter,
+				DriftDays:         int(record.NotAfter.Sub(*last.NotAfter).Hours() / 24),
+				DaysLeftAtRenewal: int(last.NotAfter.Sub(record.CheckedAt).Hours() / 24),
+			})
+		}
+		previous[record.Domain] = record
+	}
+	return changes
+}
@@ -33,8 +33,16 @@ type Certificate struct {
 	Fingerprint     string                 `json:"fingerprint"`
 	Chain           []CertificateChainInfo `json:"chain"`
 	LastChecked     time.Time              `json:"lastChecked"`
+	TLS             *TLSDetails            `json:"tls,omitempty"`
 }
 
+// Expiring is published when a certificate starts expiring soon, becomes critically
+// close to expiry or expires
+type Expiring struct{ Certificate }
+
+// Topic names expiring certificates on the event bus
+func (Expiring) Topic() string { return "certificate.expiring" }
+
 // CertificateChainInfo represents a certificate in the chain
 type CertificateChainInfo struct {
 	Subject      string    `json:"subject"`
@@ -73,6 +81,11 @@ type CertificateStats struct {
 	Expired          int `json:"expired"`
 	Invalid          int `json:"invalid"`
 	Unreachable      int `json:"unreachable"`
+	ChainInvalid     int `json:"chainInvalid"`
+	WeakTLS          int `json:"weakTLS"` // accepting old protocol versions or weak cipher suites
+	OCSPStapled      int `json:"ocspStapled"`
+	// Expiry changes (renewals) seen in the check history, oldest first
+	ExpiryChanges []ExpiryChange `json:"expiryChanges,omitempty"`
 }
 
 // DomainConfig represents configuration for monitoring a domain
@@ -83,6 +96,7 @@ type DomainConfig struct {
 	Enabled       bool       `json:"enabled"`
 	CheckInterval int        `json:"checkInterval"` // minutes
 	LastCheck     *time.Time `json:"lastCheck,omitempty"`
+	MinTLSVersion string     `json:"minTLSVersion,omitempty"` // oldest acceptable protocol, default 1.2
 }
 
 // GetStatus determines certificate status based on expiration
@@ -0,0 +1,184 @@
+package databases
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SlowQueryThreshold is the mean execution time from which a statement counts as slow
+const SlowQueryThreshold = 500 * time.Millisecond
+
+var (
+	// ErrInvalidExplain is returned for statements that can't be explained
+	ErrInvalidExplain = errors.New("invalid explain request")
+	// ErrAnalyzeNotAllowed is returned for EXPLAIN ANALYZE on a connection that isn't read-only
+	ErrAnalyzeNotAllowed = errors.New("EXPLAIN ANALYZE executes the statement and is only allowed on read-only connections")
+	// ErrSlowQueriesUnavailable is returned when the database doesn't track statement statistics
+	ErrSlowQueriesUnavailable = errors.New("slow query statistics unavailable")
+)
+
+// singleStatement trims a statement and its trailing semicolon, rejecting input that
+// holds more than one statement
+func singleStatement(query string) (string, error) {
+	query = strings.TrimSpace(query)
+	for strings.HasSuffix(query, ";") {
+		query = strings.TrimSpace(strings.TrimSuffix(query, ";"))
+	}
+	if query == "" {
+		return "", fmt.Errorf("%w: sql is required", ErrInvalidExplain)
+	}
+
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote == '\n':
+			if c == '\n' {
+				quote = 0
+			}
+		case quote == '*':
+			if c == '*' && i+1 < len(query) && query[i+1] == '/' {
+				quote = 0
+				i++
+			}
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			quote = '\n'
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			quote = '*'
+			i++
+		case c == ';':
+			return "", fmt.Errorf("%w: only a single statement can be explained", ErrInvalidExplain)
+		}
+	}
+	return query, nil
+}
+
+// postgresPlanNode converts a node of PostgreSQL's EXPLAIN (FORMAT JSON) output
+func postgresPlanNode(raw map[string]interface{}) PlanNode {
+	node := PlanNode{Properties: map[string]interface{}{}}
+	for key, value := range raw {
+		text, _ := value.(string)
+		number, isNumber := value.(float64)
+		switch key {
+		case "Node Type":
+			node.NodeType = text
+		case "Relation Name":
+			node.Relation = text
+		case "Index Name":
+			node.Index = text
+		case "Startup Cost":
+			node.StartupCost = number
+		case "Total Cost":
+			node.TotalCost = number
+		case "Plan Rows":
+			node.PlanRows = number
+		case "Plan Width":
+			node.PlanWidth = int(number)
+		case "Actual Startup Time":
+			if isNumber {
+				node.ActualStartupTime = &number
+			}
+		case "Actual Total Time":
+			if isNumber {
+				node.ActualTotalTime = &number
+			}
+		case "Actual Rows":
+			if isNumber {
+				node.ActualRows = &number
+			}
+		case "Actual Loops":
+			if isNumber {
+				node.ActualLoops = &number
+			}
+		case "Filter":
+			node.Filter = text
+		case "Plans":
+			children, _ := value.([]interface{})
+			for _, child := range children {
+				if child, ok := child.(map[string]interface{}); ok {
+					node.Children = append(node.Children, postgresPlanNode(child))
+				}
+			}
+		default:
+			node.Properties[key] = value
+		}
+	}
+	if len(node.Properties) == 0 {
+		node.Properties = nil
+	}
+
+	// Mirror the heading of the text format, e.g. "Index Scan using orders_pkey on orders"
+	node.Detail = node.NodeType
+	if node.Index != "" {
+		node.Detail += " using " + node.Index
+	}
+	if node.Relation != "" {
+		node.Detail += " on " + node.Relation
+	}
+	return node
+}
+
+// sqlitePlanStep is a row of SQLite's EXPLAIN QUERY PLAN output
+type sqlitePlanStep struct {
+	id     int
+	parent int
+	detail string
+}
+
+// sqlitePlanTree nests the steps of EXPLAIN QUERY PLAN under their parents
+func sqlitePlanTree(steps []sqlitePlanStep) PlanNode {
+	children := map[int][]sqlitePlanStep{}
+	for _, step := range steps {
+		children[step.parent] = append(children[step.parent], step)
+	}
+	var build func(parent int) []PlanNode
+	build = func(parent int) []PlanNode {
+		var nodes []PlanNode
+		for _, step := range children[parent] {
+			node := sqlitePlanNode(step.detail)
+			if step.id != parent {
+				node.Children = build(step.id)
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	return PlanNode{NodeType: "QUERY PLAN", Children: build(0)}
+}
+
+// sqlitePlanNode reads the table and index from a step such as
+// "SEARCH orders USING INDEX orders_customer (customer_id=?)"
+func sqlitePlanNode(detail string) PlanNode {
+	node := PlanNode{NodeType: detail, Detail: detail}
+	fields := strings.Fields(detail)
+	if len(fields) < 2 || (fields[0] != "SCAN" && fields[0] != "SEARCH") {
+		return node
+	}
+	node.NodeType = fields[0]
+	fields = fields[1:]
+	if fields[0] == "TABLE" && len(fields) > 1 { // SQLite before 3.36
+		fields = fields[1:]
+	}
+	if fields[0] != "CONSTANT" { // SCAN CONSTANT ROW
+		node.Relation = fields[0]
+	}
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == "INDEX" {
+			node.Index = fields[i+1]
+			break
+		}
+		if fields[i] == "PRIMARY" && fields[i+1] == "KEY" {
+			node.Index = "PRIMARY KEY"
+			break
+		}
+	}
+	return node
+}
@@ -0,0 +1,104 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSQLiteExplain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shop.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, email TEXT);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER, total REAL);
+		CREATE INDEX orders_customer ON orders (customer_id);
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := NewSQLiteProvider()
+	ctx := context.Background()
+	if err := provider.Connect(ctx, DatabaseConfig{FilePath: path}); err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Disconnect(ctx)
+
+	plan, err := provider.Explain(ctx, ExplainRequest{SQL: `
+		SELECT c.email, o.total FROM customers c JOIN orders o ON o.customer_id = c.id
+		WHERE c.id IN (SELECT customer_id FROM orders WHERE total > 100);`})
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	relations := map[string]string{}
+	var walk func(node PlanNode)
+	walk = func(node PlanNode) {
+		if node.Relation != "" {
+			relations[node.Relation+"/"+node.Index] = node.NodeType
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(plan.Plan)
+	if relations["o/orders_customer"] != "SEARCH" {
+		t.Errorf("expected a search of orders by orders_customer, got %v in %+v", relations, plan.Plan)
+	}
+
+	if _, err := provider.Explain(ctx, ExplainRequest{SQL: "SELECT 1; DELETE FROM orders"}); !errors.Is(err, ErrInvalidExplain) {
+		t.Errorf("expected several statements to be rejected, got %v", err)
+	}
+	if _, err := provider.Explain(ctx, ExplainRequest{SQL: "SELECT ';' -- ;\n"}); err != nil {
+		t.Errorf("quoted and commented semicolons should be allowed: %v", err)
+	}
+	if _, err := provider.Explain(ctx, ExplainRequest{SQL: "SELECT * FROM orders", Analyze: true}); !errors.Is(err, ErrAnalyzeNotAllowed) {
+		t.Errorf("expected ANALYZE to need a read-only connection, got %v", err)
+	}
+
+	readOnly := NewSQLiteProvider()
+	if err := readOnly.Connect(ctx, DatabaseConfig{FilePath: path, ReadOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+	defer readOnly.Disconnect(ctx)
+	if result, _ := readOnly.ExecuteQuery(ctx, QueryRequest{SQL: "DELETE FROM orders"}); result == nil || result.Error == "" {
+		t.Errorf("expected writes to fail on a read-only connection, got %+v", result)
+	}
+}
+
+func TestPostgresPlanNode(t *testing.T) {
+	var raw map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"Node Type": "Hash Join", "Join Type": "Inner", "Startup Cost": 1.5, "Total Cost": 42.25,
+		"Plan Rows": 10, "Plan Width": 16, "Actual Total Time": 0.8, "Actual Rows": 3, "Actual Loops": 1,
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "orders", "Filter": "(total > 100)"},
+			{"Node Type": "Hash", "Plans": [
+				{"Node Type": "Index Scan", "Relation Name": "customers", "Index Name": "customers_pkey"}
+			]}
+		]
+	}`), &raw)
+
+	node := postgresPlanNode(raw)
+	if node.NodeType != "Hash Join" || node.TotalCost != 42.25 || node.PlanWidth != 16 || node.Properties["Join Type"] != "Inner" {
+		t.Errorf("unexpected node %+v", node)
+	}
+	if node.ActualRows == nil || *node.ActualRows != 3 || node.ActualStartupTime != nil {
+		t.Errorf("unexpected actual values %+v", node)
+	}
+	if len(node.Children) != 2 || node.Children[0].Filter != "(total > 100)" {
+		t.Fatalf("unexpected children %+v", node.Children)
+	}
+	if scan := node.Children[1].Children[0]; scan.Detail != "Index Scan using customers_pkey on customers" {
+		t.Errorf("unexpected detail %q", scan.Detail)
+	}
+}
@@ -0,0 +1,196 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// healthInterval is how often connected databases are pinged
+const healthInterval = 15 * time.Second
+
+// Reconnect backoff of connections whose health ping failed, doubling after each failure
+const (
+	reconnectBackoff    = 15 * time.Second
+	maxReconnectBackoff = 5 * time.Minute
+)
+
+// ErrNotConnected is returned for connections that aren't connected
+var ErrNotConnected = errors.New("not connected to database")
+
+// StartHealthChecks pings the connected databases until ctx is cancelled. Connections
+// whose ping fails are marked as errored and reconnected with exponential backoff.
+func (m *Manager) StartHealthChecks(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(healthInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.checkHealth(ctx)
+			}
+		}
+	}()
+}
+
+// checkHealth pings each connected database, or reconnects it once its backoff has passed
+func (m *Manager) checkHealth(ctx context.Context) {
+	m.mutex.RLock()
+	providers := make(map[string]DatabaseProvider, len(m.providers))
+	for id, provider := range m.providers {
+		providers[id] = provider
+	}
+	m.mutex.RUnlock()
+
+	for id, provider := range providers {
+		m.mutex.RLock()
+		health := m.health[id]
+		m.mutex.RUnlock()
+
+		if health.NextReconnect != nil {
+			if time.Now().After(*health.NextReconnect) {
+				m.reconnect(ctx, id, provider)
+			}
+			continue
+		}
+
+		start := time.Now()
+		healthy := provider.IsConnected() // pings with its own timeout
+		m.recordPing(id, provider, start, healthy)
+	}
+}
+
+// recordPing stores the result of a health ping and updates the connection's status
+func (m *Manager) recordPing(id string, provider DatabaseProvider, start time.Time, healthy bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// The connection was closed or replaced during the ping
+	if m.providers[id] != provider {
+		return
+	}
+
+	now := time.Now()
+	health := m.health[id]
+	health.LastPing = &now
+	if healthy {
+		health.Latency = float64(now.Sub(start)) / float64(time.Millisecond)
+		m.health[id] = health
+		m.setStatus(id, DatabaseStatusConnected, "")
+		return
+	}
+
+	health.Failures++
+	health.LastError = "health ping failed"
+	next := now.Add(backoff(health.Failures))
+	health.NextReconnect = &next
+	m.health[id] = health
+	m.setStatus(id, DatabaseStatusError, health.LastError)
+	slog.Warn("Database health ping failed", "connection", id, "reconnect_at", next)
+}
+
+// reconnect replaces a connection whose ping failed with a new one, backing off further
+// when connecting fails
+func (m *Manager) reconnect(ctx context.Context, id string, old DatabaseProvider) {
+	m.mutex.RLock()
+	config, exists := m.configs[id]
+	m.mutex.RUnlock()
+	if !exists {
+		return
+	}
+
+	provider, err := m.CreateProvider(config.Type)
+	if err != nil {
+		return
+	}
+	connectCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	err = provider.Connect(connectCtx, config)
+
+	m.mutex.Lock()
+	if m.providers[id] != old {
+		// Disconnected or reconnected by hand meanwhile
+		m.mutex.Unlock()
+		if err == nil {
+			provider.Disconnect(ctx)
+		}
+		return
+	}
+
+	health := m.health[id]
+	if err != nil {
+		health.Failures++
+		health.LastError = err.Error()
+		next := time.Now().Add(backoff(health.Failures))
+		health.NextReconnect = &next
+		m.health[id] = health
+		m.setStatus(id, DatabaseStatusError, health.LastError)
+		m.mutex.Unlock()
+		slog.Warn("Failed to reconnect database", "connection", id, "error", err, "retry_at", next)
+		return
+	}
+
+	m.providers[id] = provider
+	now := time.Now()
+	m.health[id] = ConnectionHealth{LastPing: &now}
+	m.setStatus(id, DatabaseStatusConnected, "")
+	m.mutex.Unlock()
+
+	old.Disconnect(ctx)
+	slog.Info("Reconnected database", "connection", id, "failures", health.Failures)
+}
+
+// setStatus stores a connection's status when it changed. Callers hold the write lock.
+func (m *Manager) setStatus(id string, status DatabaseStatus, message string) {
+	config, exists := m.configs[id]
+	if !exists || (config.Status == status && config.Error == message) {
+		return
+	}
+	config.Status = status
+	config.Error = message
+	config.UpdatedAt = time.Now()
+	m.configs[id] = config
+	if err := m.updateConfiguration(config); err != nil {
+		slog.Warn("Failed to store database connection status", "connection", id, "error", err)
+	}
+}
+
+// backoff returns the delay before the next reconnect after the given number of failures
+func backoff(failures int) time.Duration {
+	delay := reconnectBackoff
+	for i := 1; i < failures && delay < maxReconnectBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxReconnectBackoff)
+}
+
+// Stats returns a connection's database statistics with its pool usage and health. While
+// the connection is down, only the pool and health are reported.
+func (m *Manager) Stats(ctx context.Context, id string) (*DatabaseStats, error) {
+	m.mutex.RLock()
+	provider, exists := m.providers[id]
+	status := m.configs[id].Status
+	health := m.health[id]
+	m.mutex.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrNotConnected, id)
+	}
+
+	stats := &DatabaseStats{}
+	if status == DatabaseStatusConnected {
+		var err error
+		if stats, err = provider.GetStats(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if reporter, ok := provider.(PoolReporter); ok {
+		pool := reporter.PoolStats()
+		stats.Pool = &pool
+	}
+	stats.Health = &health
+	return stats, nil
+}
@@ -0,0 +1,105 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// downProvider is a connection whose health pings fail
+type downProvider struct {
+	DatabaseProvider
+	disconnected bool
+}
+
+func (d *downProvider) IsConnected() bool { return false }
+
+func (d *downProvider) Disconnect(ctx context.Context) error {
+	d.disconnected = true
+	return nil
+}
+
+func TestHealthChecksReconnect(t *testing.T) {
+	dir := t.TempDir()
+	store, err := sql.Open("sqlite3", filepath.Join(dir, "denshimon.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	target := filepath.Join(dir, "app.db")
+	if db, err := sql.Open("sqlite3", target); err != nil || db.Ping() != nil {
+		t.Fatalf("failed to create database: %v", err)
+	} else {
+		db.Close()
+	}
+
+	ctx := context.Background()
+	m := NewManager(store)
+	config, err := m.AddConnection(ctx, DatabaseConfig{Name: "app", Type: DatabaseTypeSQLite, FilePath: target})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := config.ID
+	if err := m.Connect(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Cleanup(ctx)
+
+	m.checkHealth(ctx)
+	stats, err := m.Stats(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Health.LastPing == nil || stats.Health.Failures != 0 || stats.Pool == nil || stats.Pool.MaxOpen != 1 {
+		t.Fatalf("unexpected stats after a healthy ping: health %+v, pool %+v", stats.Health, stats.Pool)
+	}
+
+	down := &downProvider{}
+	m.providers[id] = down
+	m.checkHealth(ctx)
+	if connection, _ := m.GetConnection(id); connection.Status != DatabaseStatusError {
+		t.Errorf("expected status error after a failed ping, got %s", connection.Status)
+	}
+	if _, err := m.GetProvider(id); err == nil {
+		t.Error("expected a failing connection not to be handed out")
+	}
+	stats, err = m.Stats(ctx, id)
+	if err != nil {
+		t.Fatalf("expected health of a failing connection, got %v", err)
+	}
+	if stats.Health.Failures != 1 || stats.Health.NextReconnect == nil {
+		t.Fatalf("unexpected health %+v", stats.Health)
+	}
+
+	// Not reconnected before the backoff passes
+	m.checkHealth(ctx)
+	if m.providers[id] != down {
+		t.Fatal("reconnected before the backoff passed")
+	}
+
+	past := time.Now().Add(-time.Second)
+	health := m.health[id]
+	health.NextReconnect = &past
+	m.health[id] = health
+	m.checkHealth(ctx)
+
+	if _, ok := m.providers[id].(*SQLiteProvider); !ok || !down.disconnected {
+		t.Fatalf("expected the connection to be replaced, got %T", m.providers[id])
+	}
+	if _, err := m.GetProvider(id); err != nil {
+		t.Errorf("expected the reconnected database to be available: %v", err)
+	}
+	if health := m.health[id]; health.Failures != 0 || health.NextReconnect != nil {
+		t.Errorf("unexpected health after reconnecting %+v", health)
+	}
+
+	for failures, want := range map[int]time.Duration{1: 15 * time.Second, 3: time.Minute, 10: 5 * time.Minute} {
+		if got := backoff(failures); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
@@ -2,11 +2,12 @@ package databases
 
 import (
 	"context"
+	"time"
 )
 
 // DatabaseProvider defines the interface that all database providers must implement
 type DatabaseProvider interface {
-	// Type returns the provider type (postgresql, sqlite)
+	// Type returns the provider type (postgresql, sqlite, mysql, mongodb, redis)
 	Type() DatabaseType
 
 	// Connect establishes a connection to the database
@@ -27,9 +28,18 @@ type DatabaseProvider interface {
 	// GetColumns returns column information for a table
 	GetColumns(ctx context.Context, database, table string) ([]ColumnInfo, error)
 
+	// GetTableSchema returns the indexes, foreign keys and constraints of a table
+	GetTableSchema(ctx context.Context, database, table string) (*TableSchema, error)
+
+	// GetRelationships returns the foreign keys between the tables of a database
+	GetRelationships(ctx context.Context, database string) ([]Relationship, error)
+
 	// ExecuteQuery executes a SQL query and returns results
 	ExecuteQuery(ctx context.Context, req QueryRequest) (*QueryResult, error)
 
+	// Explain returns the execution plan of a single statement
+	Explain(ctx context.Context, req ExplainRequest) (*QueryPlan, error)
+
 	// GetTableData returns paginated data from a table
 	GetTableData(ctx context.Context, req TableDataRequest) (*QueryResult, error)
 
@@ -52,6 +62,34 @@ type DatabaseProvider interface {
 	GetConnectionInfo() *DatabaseConfig
 }
 
+// SlowQueryLog is implemented by providers that track statement execution times
+type SlowQueryLog interface {
+	// SlowQueries returns the statements with a mean execution time of at least threshold, slowest first
+	SlowQueries(ctx context.Context, threshold time.Duration, limit int) ([]SlowQuery, error)
+}
+
+// KeyValueStore is implemented by providers of key-value databases, whose keys are browsed
+// instead of tables
+type KeyValueStore interface {
+	// ScanKeys returns a page of the keys matching the request
+	ScanKeys(ctx context.Context, database string, req KeyScanRequest) (*KeyScanResult, error)
+
+	// GetKey returns a key's value
+	GetKey(ctx context.Context, database, key string) (*KeyValue, error)
+
+	// DeleteKeys deletes keys and returns how many existed
+	DeleteKeys(ctx context.Context, database string, keys []string) (int64, error)
+
+	// FlushDatabase deletes every key of a database
+	FlushDatabase(ctx context.Context, database string) error
+}
+
+// PoolReporter is implemented by providers that report their connection pool usage
+type PoolReporter interface {
+	// PoolStats returns the current usage of the connection pool
+	PoolStats() PoolStats
+}
+
 // DatabaseProviderFactory creates database providers
 type DatabaseProviderFactory interface {
 	// CreateProvider creates a new database provider of the specified type
@@ -0,0 +1,430 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/pkg/cron"
+	"github.com/google/uuid"
+)
+
+const (
+	// jobSchedulerInterval is how often the scheduler checks for due query jobs
+	jobSchedulerInterval = time.Minute
+	// jobTimeout bounds a single run of a query job
+	jobTimeout = 30 * time.Minute
+	// jobRunHistory is how many runs are kept per job
+	jobRunHistory = 100
+)
+
+var (
+	// ErrJobNotFound is returned for unknown query jobs
+	ErrJobNotFound = errors.New("query job not found")
+	// ErrInvalidJob is returned for query jobs with a missing query, connection or schedule
+	ErrInvalidJob = errors.New("invalid query job")
+	// ErrJobRunning is returned when a query job is started while it is still running
+	ErrJobRunning = errors.New("query job is already running")
+)
+
+const jobColumns = `j.id, j.name, j.saved_query_id, j.connection_id, j.cron, j.timezone, j.enabled,
+	j.next_run_at, j.created_at, j.updated_at,
+	r.id, r.status, r.scheduled, r.row_count, r.duration_ms, r.error, r.acknowledged, r.started_at, r.finished_at`
+
+// jobQuery selects jobs with their most recent run
+const jobQuery = `SELECT ` + jobColumns + ` FROM query_jobs j
+	LEFT JOIN query_job_runs r ON r.id = (
+		SELECT id FROM query_job_runs WHERE job_id = j.id ORDER BY started_at DESC, rowid DESC LIMIT 1
+	)`
+
+// ListJobs returns the query jobs with their most recent run, by name
+func (m *Manager) ListJobs(ctx context.Context) ([]QueryJob, error) {
+	rows, err := m.db.QueryContext(ctx, jobQuery+` ORDER BY j.name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []QueryJob{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// GetJob returns a query job with its most recent run
+func (m *Manager) GetJob(ctx context.Context, id string) (*QueryJob, error) {
+	job, err := scanJob(m.db.QueryRowContext(ctx, jobQuery+` WHERE j.id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return job, err
+}
+
+// CreateJob validates and stores a query job
+func (m *Manager) CreateJob(ctx context.Context, job QueryJob) (*QueryJob, error) {
+	now := time.Now().UTC()
+	job.ID = uuid.New().String()
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	if err := m.prepareJob(ctx, &job, now); err != nil {
+		return nil, err
+	}
+
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO query_jobs (id, name, saved_query_id, connection_id, cron, timezone, enabled, next_run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, job.ID, job.Name, job.SavedQueryID, job.ConnectionID, job.Cron, job.Timezone, job.Enabled, job.NextRun, job.CreatedAt, job.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query job: %w", err)
+	}
+	return &job, nil
+}
+
+// UpdateJob replaces a query job's settings, rescheduling its next run
+func (m *Manager) UpdateJob(ctx context.Context, id string, job QueryJob) (*QueryJob, error) {
+	existing, err := m.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	job.ID = id
+	job.CreatedAt = existing.CreatedAt
+	job.UpdatedAt = now
+	if err := m.prepareJob(ctx, &job, now); err != nil {
+		return nil, err
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		UPDATE query_jobs
+		SET name = ?, saved_query_id = ?, connection_id = ?, cron = ?, timezone = ?, enabled = ?, next_run_at = ?, updated_at = ?
+		WHERE id = ?
+	`, job.Name, job.SavedQueryID, job.ConnectionID, job.Cron, job.Timezone, job.Enabled, job.NextRun, job.UpdatedAt, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update query job: %w", err)
+	}
+	return m.GetJob(ctx, id)
+}
+
+// DeleteJob removes a query job and its run history
+func (m *Manager) DeleteJob(ctx context.Context, id string) error {
+	result, err := m.db.ExecContext(ctx, `DELETE FROM query_jobs WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete query job: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	_, err = m.db.ExecContext(ctx, `DELETE FROM query_job_runs WHERE job_id = ?`, id)
+	return err
+}
+
+// prepareJob checks a job's query, connection and schedule and computes its next run
+func (m *Manager) prepareJob(ctx context.Context, job *QueryJob, now time.Time) error {
+	job.Name = strings.TrimSpace(job.Name)
+	if job.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidJob)
+	}
+	if job.SavedQueryID == "" {
+		return fmt.Errorf("%w: saved_query_id is required", ErrInvalidJob)
+	}
+	saved, err := m.GetSavedQuery(ctx, job.SavedQueryID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJob, err)
+	}
+	if job.ConnectionID == "" && saved.ConnectionID != nil {
+		job.ConnectionID = *saved.ConnectionID
+	}
+	if job.ConnectionID == "" {
+		return fmt.Errorf("%w: connection_id is required for a saved query without a connection", ErrInvalidJob)
+	}
+	if _, err := m.GetConnection(job.ConnectionID); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJob, err)
+	}
+
+	next, err := cron.NextInZone(job.Cron, job.Timezone, now)
+	if err != nil {
+		return fmt.Errorf("%w: invalid schedule: %v", ErrInvalidJob, err)
+	}
+	if next.IsZero() {
+		return fmt.Errorf("%w: schedule %q never fires", ErrInvalidJob, job.Cron)
+	}
+	job.NextRun = nil
+	if job.Enabled {
+		next = next.UTC()
+		job.NextRun = &next
+	}
+	job.LastRun = nil
+	return nil
+}
+
+// StartJobScheduler runs the query jobs whose schedule is due until ctx is cancelled
+func (m *Manager) StartJobScheduler(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(jobSchedulerInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.runDueJobs(ctx, time.Now())
+			}
+		}
+	}()
+}
+
+// runDueJobs advances the schedule of every enabled job whose next run has passed and
+// starts it in the background
+func (m *Manager) runDueJobs(ctx context.Context, now time.Time) {
+	rows, err := m.db.QueryContext(ctx, `SELECT id, cron, timezone FROM query_jobs
+		WHERE enabled AND next_run_at IS NOT NULL AND next_run_at <= ?`, now.UTC())
+	if err != nil {
+		slog.Error("Failed to list due query jobs", "error", err)
+		return
+	}
+	type dueJob struct{ id, cron, timezone string }
+	var due []dueJob
+	for rows.Next() {
+		var job dueJob
+		if err := rows.Scan(&job.id, &job.cron, &job.timezone); err == nil {
+			due = append(due, job)
+		}
+	}
+	rows.Close()
+
+	for _, job := range due {
+		next, err := cron.NextInZone(job.cron, job.timezone, now)
+		var nextRun interface{}
+		if err == nil && !next.IsZero() {
+			nextRun = next.UTC()
+		}
+		if _, err := m.db.ExecContext(ctx, `UPDATE query_jobs SET next_run_at = ? WHERE id = ?`, nextRun, job.id); err != nil {
+			slog.Error("Failed to advance query job schedule", "job", job.id, "error", err)
+			continue
+		}
+
+		go func(id string) {
+			if _, err := m.RunJob(ctx, id, true); err != nil && !errors.Is(err, ErrJobRunning) {
+				slog.Error("Failed to run scheduled query job", "job", id, "error", err)
+			}
+		}(job.id)
+	}
+}
+
+// RunJob runs a query job's saved query against its connection, connecting first when
+// needed, and records the run. A failed query is recorded as a failed run, not returned
+// as an error.
+func (m *Manager) RunJob(ctx context.Context, id string, scheduled bool) (*QueryJobRun, error) {
+	job, err := m.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	if m.runningJobs[id] {
+		m.mutex.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrJobRunning, job.Name)
+	}
+	m.runningJobs[id] = true
+	m.mutex.Unlock()
+	defer func() {
+		m.mutex.Lock()
+		delete(m.runningJobs, id)
+		m.mutex.Unlock()
+	}()
+
+	run := &QueryJobRun{
+		ID:        uuid.New().String(),
+		JobID:     id,
+		Status:    JobRunRunning,
+		Scheduled: scheduled,
+		StartedAt: time.Now().UTC(),
+	}
+	_, err = m.db.ExecContext(ctx, `INSERT INTO query_job_runs (id, job_id, status, scheduled, started_at) VALUES (?, ?, ?, ?, ?)`,
+		run.ID, run.JobID, run.Status, run.Scheduled, run.StartedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record query job run: %w", err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+	rowCount, err := m.executeJob(runCtx, job)
+
+	finished := time.Now().UTC()
+	run.FinishedAt = &finished
+	run.Duration = float64(finished.Sub(run.StartedAt)) / float64(time.Millisecond)
+	run.RowCount = rowCount
+	run.Status = JobRunSucceeded
+	if err != nil {
+		run.Status = JobRunFailed
+		run.Error = err.Error()
+		slog.Warn("Query job failed", "job", job.Name, "error", err)
+	}
+
+	// Recorded even when the request that started the run was cancelled
+	_, err = m.db.ExecContext(context.WithoutCancel(ctx), `
+		UPDATE query_job_runs SET status = ?, row_count = ?, duration_ms = ?, error = ?, finished_at = ?
+		WHERE id = ?
+	`, run.Status, run.RowCount, run.Duration, run.Error, run.FinishedAt, run.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record query job run: %w", err)
+	}
+	m.pruneJobRuns(context.WithoutCancel(ctx), id)
+	return run, nil
+}
+
+// executeJob runs a job's saved query, returning the rows it returned or affected
+func (m *Manager) executeJob(ctx context.Context, job *QueryJob) (int64, error) {
+	saved, err := m.GetSavedQuery(ctx, job.SavedQueryID)
+	if err != nil {
+		return 0, err
+	}
+
+	provider, err := m.GetProvider(job.ConnectionID)
+	if errors.Is(err, ErrNotConnected) {
+		if err := m.Connect(ctx, job.ConnectionID); err != nil {
+			return 0, err
+		}
+		provider, err = m.GetProvider(job.ConnectionID)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := provider.ExecuteQuery(ctx, QueryRequest{SQL: saved.SQL})
+	if err != nil {
+		return 0, err
+	}
+	if result.Error != "" {
+		return 0, errors.New(result.Error)
+	}
+	if result.AffectedRows > 0 {
+		return result.AffectedRows, nil
+	}
+	return int64(result.RowCount), nil
+}
+
+// pruneJobRuns keeps the most recent runs of a job
+func (m *Manager) pruneJobRuns(ctx context.Context, id string) {
+	_, err := m.db.ExecContext(ctx, `DELETE FROM query_job_runs WHERE job_id = ? AND id NOT IN (
+		SELECT id FROM query_job_runs WHERE job_id = ? ORDER BY started_at DESC, rowid DESC LIMIT ?
+	)`, id, id, jobRunHistory)
+	if err != nil {
+		slog.Warn("Failed to prune query job runs", "job", id, "error", err)
+	}
+}
+
+// ListJobRuns returns a job's most recent runs, newest first
+func (m *Manager) ListJobRuns(ctx context.Context, id string, limit int) ([]QueryJobRun, error) {
+	if _, err := m.GetJob(ctx, id); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT id, job_id, status, scheduled, row_count, duration_ms, error, acknowledged, started_at, finished_at
+		FROM query_job_runs WHERE job_id = ? ORDER BY started_at DESC, rowid DESC LIMIT ?
+	`, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []QueryJobRun{}
+	for rows.Next() {
+		var run QueryJobRun
+		var finished sql.NullTime
+		err := rows.Scan(&run.ID, &run.JobID, &run.Status, &run.Scheduled, &run.RowCount, &run.Duration,
+			&run.Error, &run.Acknowledged, &run.StartedAt, &finished)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job run: %w", err)
+		}
+		if finished.Valid {
+			run.FinishedAt = &finished.Time
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// FailedJobs returns the jobs whose most recent finished run failed, for the alert inbox.
+// The alert resolves once a later run succeeds.
+func (m *Manager) FailedJobs(ctx context.Context) ([]QueryJob, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT `+jobColumns+` FROM query_jobs j
+		JOIN query_job_runs r ON r.id = (
+			SELECT id FROM query_job_runs WHERE job_id = j.id AND status != ? ORDER BY started_at DESC, rowid DESC LIMIT 1
+		)
+		WHERE r.status = ?`, JobRunRunning, JobRunFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failed jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []QueryJob{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// AcknowledgeJobRun acknowledges a failed run in the alert inbox
+func (m *Manager) AcknowledgeJobRun(ctx context.Context, runID string) error {
+	result, err := m.db.ExecContext(ctx, `UPDATE query_job_runs SET acknowledged = TRUE WHERE id = ?`, runID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: run %s", ErrJobNotFound, runID)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanJob(row rowScanner) (*QueryJob, error) {
+	var job QueryJob
+	var nextRun, finished, started sql.NullTime
+	var runID, status, runError sql.NullString
+	var scheduled, acknowledged sql.NullBool
+	var rowCount sql.NullInt64
+	var duration sql.NullFloat64
+	err := row.Scan(&job.ID, &job.Name, &job.SavedQueryID, &job.ConnectionID, &job.Cron, &job.Timezone, &job.Enabled,
+		&nextRun, &job.CreatedAt, &job.UpdatedAt,
+		&runID, &status, &scheduled, &rowCount, &duration, &runError, &acknowledged, &started, &finished)
+	if err != nil {
+		return nil, err
+	}
+	if nextRun.Valid {
+		job.NextRun = &nextRun.Time
+	}
+	if runID.Valid {
+		job.LastRun = &QueryJobRun{
+			ID:           runID.String,
+			JobID:        job.ID,
+			Status:       status.String,
+			Scheduled:    scheduled.Bool,
+			RowCount:     rowCount.Int64,
+			Duration:     duration.Float64,
+			Error:        runError.String,
+			Acknowledged: acknowledged.Bool,
+			StartedAt:    started.Time,
+		}
+		if finished.Valid {
+			job.LastRun.FinishedAt = &finished.Time
+		}
+	}
+	return &job, nil
+}
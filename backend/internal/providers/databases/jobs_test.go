@@ -0,0 +1,125 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/archellir/denshimon/internal/database"
+	"github.com/archellir/denshimon/internal/database/migrate"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestQueryJobs(t *testing.T) {
+	dir := t.TempDir()
+	store, err := sql.Open("sqlite3", filepath.Join(dir, "denshimon.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	if err := migrate.Up(ctx, store, database.Migrations); err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(dir, "app.db")
+	app, err := sql.Open("sqlite3", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, done BOOLEAN);
+		INSERT INTO items (done) VALUES (1), (1), (0);`)
+	app.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewManager(store)
+	defer m.Cleanup(ctx)
+	config, err := m.AddConnection(ctx, DatabaseConfig{Name: "app", Type: DatabaseTypeSQLite, FilePath: target})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup, err := m.CreateSavedQuery(ctx, SavedQuery{Name: "cleanup", SQL: "DELETE FROM items WHERE done = 1", ConnectionID: &config.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.CreateJob(ctx, QueryJob{Name: "nightly", SavedQueryID: cleanup.ID, Cron: "not a schedule"}); !errors.Is(err, ErrInvalidJob) {
+		t.Errorf("expected an invalid schedule to be rejected, got %v", err)
+	}
+	job, err := m.CreateJob(ctx, QueryJob{Name: "nightly", SavedQueryID: cleanup.ID, Cron: "0 3 * * *", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ConnectionID != config.ID || job.NextRun == nil {
+		t.Fatalf("expected the saved query's connection and a next run, got %+v", job)
+	}
+
+	// Not connected yet: the run connects first
+	run, err := m.RunJob(ctx, job.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != JobRunSucceeded || run.RowCount != 2 || run.FinishedAt == nil {
+		t.Fatalf("unexpected run %+v", run)
+	}
+
+	broken, err := m.CreateSavedQuery(ctx, SavedQuery{Name: "broken", SQL: "DELETE FROM missing", ConnectionID: &config.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.UpdateJob(ctx, job.ID, QueryJob{Name: "nightly", SavedQueryID: broken.ID, Cron: "0 3 * * *", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if run, err = m.RunJob(ctx, job.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != JobRunFailed || run.Error == "" {
+		t.Fatalf("expected a failed run, got %+v", run)
+	}
+
+	failed, err := m.FailedJobs(ctx)
+	if err != nil || len(failed) != 1 || failed[0].LastRun.ID != run.ID {
+		t.Fatalf("expected the job to be listed as failed, got %+v (%v)", failed, err)
+	}
+	if err := m.AcknowledgeJobRun(ctx, run.ID); err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := m.GetJob(ctx, job.ID); !job.LastRun.Acknowledged {
+		t.Error("expected the failed run to be acknowledged")
+	}
+
+	// Due jobs run in the background and advance their schedule
+	_, err = store.Exec(`UPDATE query_jobs SET saved_query_id = ?, next_run_at = ? WHERE id = ?`,
+		cleanup.ID, time.Now().UTC().Add(-time.Minute), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.runDueJobs(ctx, time.Now())
+	var runs []QueryJobRun
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if runs, err = m.ListJobRuns(ctx, job.ID, 10); err == nil && len(runs) == 3 && runs[0].Status != JobRunRunning {
+			break
+		}
+	}
+	if len(runs) != 3 || !runs[0].Scheduled || runs[0].Status != JobRunSucceeded || runs[1].Status != JobRunFailed {
+		t.Fatalf("unexpected run history %+v", runs)
+	}
+	if job, _ := m.GetJob(ctx, job.ID); job.NextRun == nil || !job.NextRun.After(time.Now()) {
+		t.Errorf("expected the next run to be advanced, got %v", job.NextRun)
+	}
+	if failed, _ := m.FailedJobs(ctx); len(failed) != 0 {
+		t.Errorf("expected a successful run to resolve the failure, got %d failed jobs", len(failed))
+	}
+
+	if err := m.DeleteJob(ctx, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ListJobRuns(ctx, job.ID, 10); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected the deleted job to be gone, got %v", err)
+	}
+}
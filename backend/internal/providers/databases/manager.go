@@ -5,9 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 
+	"github.com/archellir/denshimon/internal/secretbox"
+	"github.com/archellir/denshimon/internal/websocket"
 	"github.com/google/uuid"
 )
 
@@ -17,6 +20,11 @@ type Manager struct {
 	providers map[string]DatabaseProvider
 	configs   map[string]DatabaseConfig
 	mutex     sync.RWMutex
+	box       *secretbox.Box // encrypts connection configs, including passwords, at rest
+	hub       *websocket.Hub // receives statistics of connected databases
+	health    map[string]ConnectionHealth // health pings of connected databases
+
+	runningJobs map[string]bool // query jobs in progress
 }
 
 // NewManager creates a new database manager
@@ -25,6 +33,9 @@ func NewManager(db *sql.DB) *Manager {
 		db:        db,
 		providers: make(map[string]DatabaseProvider),
 		configs:   make(map[string]DatabaseConfig),
+		health:    make(map[string]ConnectionHealth),
+
+		runningJobs: make(map[string]bool),
 	}
 
 	// Initialize database table
@@ -56,6 +67,15 @@ func (m *Manager) initDB() error {
 	return err
 }
 
+// SetEncryption sets the box connection configs are encrypted with and reloads the stored
+// connections, since encrypted ones can't be read without it
+func (m *Manager) SetEncryption(box *secretbox.Box) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.box = box
+	m.loadConfigurations()
+}
+
 // loadConfigurations loads existing database configurations from storage
 func (m *Manager) loadConfigurations() {
 	query := `
@@ -91,6 +111,11 @@ func (m *Manager) loadConfigurations() {
 		}
 
 		// Parse config JSON
+		configJSON, err = m.box.Decrypt(configJSON)
+		if err != nil {
+			slog.Warn("Failed to decrypt database connection", "id", config.ID, "error", err)
+			continue
+		}
 		if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
 			continue
 		}
@@ -113,6 +138,12 @@ func (m *Manager) CreateProvider(dbType DatabaseType) (DatabaseProvider, error)
 		return NewPostgreSQLProvider(), nil
 	case DatabaseTypeSQLite:
 		return NewSQLiteProvider(), nil
+	case DatabaseTypeMySQL:
+		return NewMySQLProvider(), nil
+	case DatabaseTypeMongoDB:
+		return NewMongoDBProvider(), nil
+	case DatabaseTypeRedis:
+		return NewRedisProvider(), nil
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", dbType)
 	}
@@ -123,6 +154,9 @@ func (m *Manager) GetSupportedTypes() []DatabaseType {
 	return []DatabaseType{
 		DatabaseTypePostgreSQL,
 		DatabaseTypeSQLite,
+		DatabaseTypeMySQL,
+		DatabaseTypeMongoDB,
+		DatabaseTypeRedis,
 	}
 }
 
@@ -219,6 +253,7 @@ func (m *Manager) UpdateConnection(ctx context.Context, config DatabaseConfig) (
 	if provider, exists := m.providers[config.ID]; exists {
 		provider.Disconnect(ctx)
 		delete(m.providers, config.ID)
+		delete(m.health, config.ID)
 	}
 
 	return &config, nil
@@ -233,6 +268,7 @@ func (m *Manager) DeleteConnection(ctx context.Context, id string) error {
 	if provider, exists := m.providers[id]; exists {
 		provider.Disconnect(ctx)
 		delete(m.providers, id)
+		delete(m.health, id)
 	}
 
 	// Remove from database
@@ -254,10 +290,8 @@ func (m *Manager) GetConnections() []DatabaseConfig {
 
 	configs := make([]DatabaseConfig, 0, len(m.configs))
 	for _, config := range m.configs {
-		// Update status based on active connections
-		if provider, exists := m.providers[config.ID]; exists && provider.IsConnected() {
-			config.Status = DatabaseStatusConnected
-		} else {
+		// Active connections keep the status of their last health ping
+		if _, exists := m.providers[config.ID]; !exists {
 			config.Status = DatabaseStatusDisconnected
 		}
 		configs = append(configs, config)
@@ -276,10 +310,8 @@ func (m *Manager) GetConnection(id string) (*DatabaseConfig, error) {
 		return nil, fmt.Errorf("connection not found: %s", id)
 	}
 
-	// Update status based on active connection
-	if provider, exists := m.providers[id]; exists && provider.IsConnected() {
-		config.Status = DatabaseStatusConnected
-	} else {
+	// Active connections keep the status of their last health ping
+	if _, exists := m.providers[id]; !exists {
 		config.Status = DatabaseStatusDisconnected
 	}
 
@@ -296,8 +328,9 @@ func (m *Manager) Connect(ctx context.Context, id string) error {
 		return fmt.Errorf("connection not found: %s", id)
 	}
 
-	// Check if already connected
-	if provider, exists := m.providers[id]; exists && provider.IsConnected() {
+	// Check if already connected; a failing connection is replaced right away
+	previous, exists := m.providers[id]
+	if exists && config.Status == DatabaseStatusConnected {
 		return nil // Already connected
 	}
 
@@ -316,7 +349,11 @@ func (m *Manager) Connect(ctx context.Context, id string) error {
 	}
 
 	// Store active provider
+	if exists {
+		previous.Disconnect(ctx)
+	}
 	m.providers[id] = provider
+	m.health[id] = ConnectionHealth{}
 
 	// Update status
 	config.Status = DatabaseStatusConnected
@@ -343,6 +380,7 @@ func (m *Manager) Disconnect(ctx context.Context, id string) error {
 	}
 
 	delete(m.providers, id)
+	delete(m.health, id)
 
 	// Update status
 	if config, exists := m.configs[id]; exists {
@@ -362,10 +400,11 @@ func (m *Manager) GetProvider(id string) (DatabaseProvider, error) {
 
 	provider, exists := m.providers[id]
 	if !exists {
-		return nil, fmt.Errorf("not connected to database: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrNotConnected, id)
 	}
 
-	if !provider.IsConnected() {
+	// Failing connections are reconnected by the health checks
+	if m.configs[id].Status != DatabaseStatusConnected {
 		return nil, fmt.Errorf("database connection is not active: %s", id)
 	}
 
@@ -388,6 +427,10 @@ func (m *Manager) storeConfiguration(config DatabaseConfig) error {
 	if err != nil {
 		return err
 	}
+	sealed, err := m.box.Encrypt(string(configJSON))
+	if err != nil {
+		return err
+	}
 
 	query := `
 		INSERT INTO database_connections 
@@ -399,7 +442,7 @@ func (m *Manager) storeConfiguration(config DatabaseConfig) error {
 		config.ID,
 		config.Name,
 		config.Type,
-		string(configJSON),
+		sealed,
 		config.Status,
 		config.LastTested,
 		config.Error,
@@ -416,6 +459,10 @@ func (m *Manager) updateConfiguration(config DatabaseConfig) error {
 	if err != nil {
 		return err
 	}
+	sealed, err := m.box.Encrypt(string(configJSON))
+	if err != nil {
+		return err
+	}
 
 	query := `
 		UPDATE database_connections 
@@ -426,7 +473,7 @@ func (m *Manager) updateConfiguration(config DatabaseConfig) error {
 	_, err = m.db.Exec(query,
 		config.Name,
 		config.Type,
-		string(configJSON),
+		sealed,
 		config.Status,
 		config.LastTested,
 		config.Error,
@@ -445,6 +492,7 @@ func (m *Manager) Cleanup(ctx context.Context) {
 	for id, provider := range m.providers {
 		provider.Disconnect(ctx)
 		delete(m.providers, id)
+		delete(m.health, id)
 	}
 }
 
@@ -0,0 +1,1022 @@
+package databases
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// mongoSampleSize is how many documents are read to infer a collection's fields
+const mongoSampleSize = 100
+
+// ErrReadOnlyConnection is returned for writes on a connection saved as read-only
+var ErrReadOnlyConnection = errors.New("the connection is read-only")
+
+// mongoReadCommands are the commands allowed on read-only connections
+var mongoReadCommands = map[string]bool{
+	"find":            true,
+	"aggregate":       true, // unless the pipeline ends in $out or $merge
+	"count":           true,
+	"distinct":        true,
+	"listCollections": true,
+	"listIndexes":     true,
+	"dbStats":         true,
+	"collStats":       true,
+	"dataSize":        true,
+	"serverStatus":    true,
+	"buildInfo":       true,
+	"hello":           true,
+	"ping":            true,
+	"explain":         true,
+}
+
+// mongoCursorCommands return their results in a cursor, which may span several batches
+var mongoCursorCommands = map[string]bool{
+	"find":            true,
+	"aggregate":       true,
+	"listCollections": true,
+	"listIndexes":     true,
+}
+
+// MongoDBProvider implements the DatabaseProvider interface for MongoDB. Collections are
+// listed as tables and documents as rows; queries are database commands written as
+// Extended JSON, e.g. {"find": "users", "filter": {"active": true}}.
+type MongoDBProvider struct {
+	config DatabaseConfig
+	client *mongo.Client
+
+	// previous operation count sample, for queries per second
+	statsMu    sync.Mutex
+	operations int64
+	sampledAt  time.Time
+
+	// connection pool counters, kept by the pool monitor
+	poolOpen       atomic.Int64
+	poolInUse      atomic.Int64
+	poolIdleClosed atomic.Int64
+}
+
+// NewMongoDBProvider creates a new MongoDB provider
+func NewMongoDBProvider() DatabaseProvider {
+	return &MongoDBProvider{}
+}
+
+// Type returns the provider type
+func (m *MongoDBProvider) Type() DatabaseType {
+	return DatabaseTypeMongoDB
+}
+
+// Connect establishes a connection to MongoDB
+func (m *MongoDBProvider) Connect(ctx context.Context, config DatabaseConfig) error {
+	m.config = config
+
+	pool := poolSettings(config)
+	opts := options.Client().
+		ApplyURI(mongoURI(config)).
+		SetMaxPoolSize(uint64(pool.MaxOpen)).
+		SetMaxConnIdleTime(pool.idleTimeout()).
+		SetPoolMonitor(&event.PoolMonitor{Event: m.poolEvent})
+	client, err := mongo.Connect(opts)
+	if err != nil {
+		return fmt.Errorf("failed to open database connection: %w", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	m.client = client
+	return nil
+}
+
+// Disconnect closes the database connection
+func (m *MongoDBProvider) Disconnect(ctx context.Context) error {
+	if m.client != nil {
+		return m.client.Disconnect(ctx)
+	}
+	return nil
+}
+
+// TestConnection tests the database connection
+func (m *MongoDBProvider) TestConnection(ctx context.Context, config DatabaseConfig) (*TestConnectionResult, error) {
+	start := time.Now()
+
+	client, err := mongo.Connect(options.Client().ApplyURI(mongoURI(config)))
+	if err != nil {
+		return &TestConnectionResult{
+			Success:      false,
+			Error:        err.Error(),
+			ResponseTime: time.Since(start),
+		}, nil
+	}
+	defer client.Disconnect(ctx)
+
+	var info struct {
+		Version string `bson:"version"`
+	}
+	err = client.Database("admin").RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&info)
+	if err != nil {
+		return &TestConnectionResult{
+			Success:      false,
+			Error:        err.Error(),
+			ResponseTime: time.Since(start),
+		}, nil
+	}
+
+	return &TestConnectionResult{
+		Success:      true,
+		ResponseTime: time.Since(start),
+		Version:      "MongoDB " + info.Version,
+	}, nil
+}
+
+// GetDatabases returns a list of databases
+func (m *MongoDBProvider) GetDatabases(ctx context.Context) ([]DatabaseInfo, error) {
+	if m.client == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+
+	result, err := m.client.ListDatabases(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	var databases []DatabaseInfo
+	for _, spec := range result.Databases {
+		db := DatabaseInfo{Name: spec.Name, Size: spec.SizeOnDisk}
+		if names, err := m.client.Database(spec.Name).ListCollectionNames(ctx, bson.D{}); err == nil {
+			db.TableCount = len(names)
+		}
+		databases = append(databases, db)
+	}
+	return databases, nil
+}
+
+// GetTables returns the collections and views of a database
+func (m *MongoDBProvider) GetTables(ctx context.Context, database string) ([]TableInfo, error) {
+	db, err := m.database(database)
+	if err != nil {
+		return nil, err
+	}
+
+	specs, err := db.ListCollectionSpecifications(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+
+	var tables []TableInfo
+	for _, spec := range specs {
+		table := TableInfo{Name: spec.Name, Schema: db.Name(), Type: spec.Type}
+		if spec.Type != "view" {
+			table.RowCount, table.Size = m.collectionSize(ctx, db.Collection(spec.Name))
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// collectionSize returns the document count and the data plus index size of a collection
+func (m *MongoDBProvider) collectionSize(ctx context.Context, collection *mongo.Collection) (int64, int64) {
+	pipeline := bson.A{bson.D{{Key: "$collStats", Value: bson.D{{Key: "storageStats", Value: bson.D{}}}}}}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		count, _ := collection.EstimatedDocumentCount(ctx)
+		return count, 0
+	}
+	defer cursor.Close(ctx)
+
+	var stats []struct {
+		StorageStats struct {
+			Count          int64 `bson:"count"`
+			Size           int64 `bson:"size"`
+			TotalIndexSize int64 `bson:"totalIndexSize"`
+		} `bson:"storageStats"`
+	}
+	if err := cursor.All(ctx, &stats); err != nil || len(stats) == 0 {
+		return 0, 0
+	}
+	// Sharded collections report one document per shard
+	var count, size int64
+	for _, s := range stats {
+		count += s.StorageStats.Count
+		size += s.StorageStats.Size + s.StorageStats.TotalIndexSize
+	}
+	return count, size
+}
+
+// GetColumns infers the top-level fields of a collection from a sample of its documents
+func (m *MongoDBProvider) GetColumns(ctx context.Context, database, table string) ([]ColumnInfo, error) {
+	db, err := m.database(database)
+	if err != nil {
+		return nil, err
+	}
+	collection := db.Collection(table)
+
+	cursor, err := collection.Find(ctx, bson.D{}, options.Find().SetLimit(mongoSampleSize))
+	if err != nil {
+		return nil, err
+	}
+	var documents []bson.D
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	indexes, err := m.indexes(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+	return inferFields(documents, indexes), nil
+}
+
+// GetTableSchema returns the indexes and validator of a collection. MongoDB has no
+// foreign keys.
+func (m *MongoDBProvider) GetTableSchema(ctx context.Context, database, table string) (*TableSchema, error) {
+	db, err := m.database(database)
+	if err != nil {
+		return nil, err
+	}
+	schema := &TableSchema{
+		Table:       table,
+		Schema:      db.Name(),
+		Indexes:     []IndexInfo{},
+		ForeignKeys: []ForeignKeyInfo{},
+		Constraints: []ConstraintInfo{},
+	}
+
+	indexes, err := m.indexes(ctx, db.Collection(table))
+	if err != nil {
+		return nil, err
+	}
+	schema.Indexes = append(schema.Indexes, indexes...)
+	for _, index := range indexes {
+		switch {
+		case index.Primary:
+			schema.Constraints = append(schema.Constraints, ConstraintInfo{Name: index.Name, Type: ConstraintPrimaryKey, Columns: index.Columns})
+		case index.Unique:
+			schema.Constraints = append(schema.Constraints, ConstraintInfo{Name: index.Name, Type: ConstraintUnique, Columns: index.Columns})
+		}
+	}
+
+	// A schema validator is the closest thing to a CHECK constraint
+	specs, err := db.ListCollectionSpecifications(ctx, bson.D{{Key: "name", Value: table}})
+	if err != nil {
+		return nil, err
+	}
+	if len(specs) == 1 && specs[0].Options != nil {
+		if validator, err := specs[0].Options.LookupErr("validator"); err == nil {
+			schema.Constraints = append(schema.Constraints, ConstraintInfo{
+				Name:       "validator",
+				Type:       ConstraintCheck,
+				Definition: validator.String(),
+			})
+		}
+	}
+	return schema, nil
+}
+
+// GetRelationships returns no relationships, as MongoDB has no foreign keys
+func (m *MongoDBProvider) GetRelationships(ctx context.Context, database string) ([]Relationship, error) {
+	if _, err := m.database(database); err != nil {
+		return nil, err
+	}
+	return []Relationship{}, nil
+}
+
+// indexes lists a collection's indexes, the _id index first
+func (m *MongoDBProvider) indexes(ctx context.Context, collection *mongo.Collection) ([]IndexInfo, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var specs []struct {
+		Name    string   `bson:"name"`
+		Key     bson.D   `bson:"key"`
+		Unique  bool     `bson:"unique"`
+		Partial bson.Raw `bson:"partialFilterExpression"`
+	}
+	if err := cursor.All(ctx, &specs); err != nil {
+		return nil, err
+	}
+
+	indexes := []IndexInfo{}
+	for _, spec := range specs {
+		index := IndexInfo{
+			Name:    spec.Name,
+			Columns: []string{},
+			Unique:  spec.Unique || spec.Name == "_id_",
+			Primary: spec.Name == "_id_",
+			Partial: spec.Partial != nil,
+		}
+		for _, key := range spec.Key {
+			index.Columns = append(index.Columns, key.Key)
+			if kind, ok := key.Value.(string); ok { // text, hashed, 2dsphere, ...
+				index.Method = kind
+			}
+		}
+		if definition, err := bson.MarshalExtJSON(spec.Key, false, false); err == nil {
+			index.Definition = string(definition)
+		}
+		indexes = append(indexes, index)
+	}
+	sort.SliceStable(indexes, func(i, j int) bool { return indexes[i].Primary && !indexes[j].Primary })
+	return indexes, nil
+}
+
+// ExecuteQuery runs a database command given as Extended JSON. Cursor results are read in
+// full, with Limit and Offset applied to find and aggregate commands.
+func (m *MongoDBProvider) ExecuteQuery(ctx context.Context, req QueryRequest) (*QueryResult, error) {
+	db, err := m.database("")
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	command, err := parseMongoCommand(req.SQL)
+	if err != nil {
+		return &QueryResult{Error: err.Error(), Duration: time.Since(start)}, nil
+	}
+	name := command[0].Key
+	if m.config.ReadOnly && !isMongoRead(command) {
+		return &QueryResult{Error: fmt.Sprintf("%s: %s writes", ErrReadOnlyConnection, name), Duration: time.Since(start)}, nil
+	}
+	command = paginateMongoCommand(command, req.Limit, req.Offset)
+
+	if mongoCursorCommands[name] {
+		cursor, err := db.RunCommandCursor(ctx, command)
+		if err != nil {
+			return &QueryResult{Error: err.Error(), Duration: time.Since(start)}, nil
+		}
+		var documents []bson.D
+		if err := cursor.All(ctx, &documents); err != nil {
+			return &QueryResult{Error: err.Error(), Duration: time.Since(start)}, nil
+		}
+		result := documentsResult(documents)
+		result.Duration = time.Since(start)
+		return result, nil
+	}
+
+	var reply bson.D
+	if err := db.RunCommand(ctx, command).Decode(&reply); err != nil {
+		return &QueryResult{Error: err.Error(), Duration: time.Since(start)}, nil
+	}
+	result := documentsResult([]bson.D{reply})
+	if !mongoReadCommands[name] {
+		for _, field := range reply {
+			if field.Key == "n" {
+				result.AffectedRows, _ = mongoNumber(field.Value)
+			}
+		}
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// Explain returns the winning plan of a find, aggregate, count, distinct, update or delete
+// command given as Extended JSON. With analyze, executionStats verbosity adds the rows
+// returned and time spent per stage; explain never applies writes.
+func (m *MongoDBProvider) Explain(ctx context.Context, req ExplainRequest) (*QueryPlan, error) {
+	db, err := m.database("")
+	if err != nil {
+		return nil, err
+	}
+	command, err := parseMongoCommand(req.SQL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExplain, err)
+	}
+	if req.Analyze && !m.config.ReadOnly {
+		return nil, ErrAnalyzeNotAllowed
+	}
+
+	verbosity := "queryPlanner"
+	if req.Analyze {
+		verbosity = "executionStats"
+	}
+	start := time.Now()
+	var reply bson.D
+	err = db.RunCommand(ctx, bson.D{{Key: "explain", Value: command}, {Key: "verbosity", Value: verbosity}}).Decode(&reply)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := mongoPlan(mongoValue(reply).(map[string]interface{}))
+	if err != nil {
+		return nil, err
+	}
+	plan.Analyzed = req.Analyze
+	plan.Duration = time.Since(start)
+	return plan, nil
+}
+
+// GetTableData returns a page of a collection's documents. Where and Order are Extended
+// JSON filter and sort documents.
+func (m *MongoDBProvider) GetTableData(ctx context.Context, req TableDataRequest) (*QueryResult, error) {
+	db, err := m.database(req.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	filter, err := parseMongoDocument(req.Where)
+	if err != nil {
+		return &QueryResult{Error: "invalid filter: " + err.Error(), Duration: time.Since(start)}, nil
+	}
+	opts := options.Find()
+	if req.Order != "" {
+		sortDoc, err := parseMongoDocument(req.Order)
+		if err != nil {
+			return &QueryResult{Error: "invalid sort: " + err.Error(), Duration: time.Since(start)}, nil
+		}
+		opts.SetSort(sortDoc)
+	}
+	if req.Limit > 0 {
+		opts.SetLimit(int64(req.Limit))
+	}
+	if req.Offset > 0 {
+		opts.SetSkip(int64(req.Offset))
+	}
+
+	cursor, err := db.Collection(req.Table).Find(ctx, filter, opts)
+	if err != nil {
+		return &QueryResult{Error: err.Error(), Duration: time.Since(start)}, nil
+	}
+	var documents []bson.D
+	if err := cursor.All(ctx, &documents); err != nil {
+		return &QueryResult{Error: err.Error(), Duration: time.Since(start)}, nil
+	}
+	result := documentsResult(documents)
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// UpdateRow sets the given fields on the first document matching Where
+func (m *MongoDBProvider) UpdateRow(ctx context.Context, req RowUpdateRequest) error {
+	collection, filter, err := m.writeTarget(req.Schema, req.Table, req.Where)
+	if err != nil {
+		return err
+	}
+	_, err = collection.UpdateOne(ctx, filter, bson.D{{Key: "$set", Value: req.Values}})
+	return err
+}
+
+// DeleteRow deletes the first document matching Where
+func (m *MongoDBProvider) DeleteRow(ctx context.Context, req RowDeleteRequest) error {
+	collection, filter, err := m.writeTarget(req.Schema, req.Table, req.Where)
+	if err != nil {
+		return err
+	}
+	_, err = collection.DeleteOne(ctx, filter)
+	return err
+}
+
+// InsertRow inserts a new document
+func (m *MongoDBProvider) InsertRow(ctx context.Context, req RowInsertRequest) error {
+	collection, _, err := m.writeTarget(req.Schema, req.Table, "")
+	if err != nil {
+		return err
+	}
+	_, err = collection.InsertOne(ctx, req.Values)
+	return err
+}
+
+// writeTarget resolves the collection and filter of a write, refusing writes on read-only
+// connections
+func (m *MongoDBProvider) writeTarget(database, table, where string) (*mongo.Collection, bson.D, error) {
+	db, err := m.database(database)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m.config.ReadOnly {
+		return nil, nil, ErrReadOnlyConnection
+	}
+	filter, err := parseMongoDocument(where)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	return db.Collection(table), filter, nil
+}
+
+// GetStats returns server statistics from serverStatus
+func (m *MongoDBProvider) GetStats(ctx context.Context) (*DatabaseStats, error) {
+	if m.client == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+
+	var status struct {
+		Connections struct {
+			Current   int `bson:"current"`
+			Available int `bson:"available"`
+			Active    int `bson:"active"`
+		} `bson:"connections"`
+		Opcounters  map[string]interface{} `bson:"opcounters"`
+		OpLatencies map[string]struct {
+			Latency int64 `bson:"latency"` // microseconds
+			Ops     int64 `bson:"ops"`
+		} `bson:"opLatencies"`
+		WiredTiger struct {
+			Cache map[string]interface{} `bson:"cache"`
+		} `bson:"wiredTiger"`
+	}
+	err := m.client.Database("admin").RunCommand(ctx, bson.D{{Key: "serverStatus", Value: 1}}).Decode(&status)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &DatabaseStats{}
+	stats.Connections = ConnectionStats{
+		Active:  status.Connections.Active,
+		Idle:    status.Connections.Current - status.Connections.Active,
+		Total:   status.Connections.Current,
+		MaxConn: status.Connections.Current + status.Connections.Available,
+	}
+
+	if result, err := m.client.ListDatabases(ctx, bson.D{}); err == nil {
+		stats.Storage = StorageStats{
+			TotalSize: result.TotalSize,
+			UsedSize:  result.TotalSize,
+		}
+	}
+
+	var latency, ops int64
+	for _, l := range status.OpLatencies {
+		latency += l.Latency
+		ops += l.Ops
+	}
+	if ops > 0 {
+		stats.Performance.AvgQueryTime = float64(latency) / float64(ops) / 1000
+	}
+	read, _ := mongoNumber(status.WiredTiger.Cache["pages read into cache"])
+	requested, _ := mongoNumber(status.WiredTiger.Cache["pages requested from the cache"])
+	if requested > 0 {
+		stats.Performance.CacheHitRatio = (1 - float64(read)/float64(requested)) * 100
+	}
+
+	var operations int64
+	for _, count := range status.Opcounters {
+		n, _ := mongoNumber(count)
+		operations += n
+	}
+	now := time.Now()
+	m.statsMu.Lock()
+	if !m.sampledAt.IsZero() && operations >= m.operations {
+		stats.Performance.QueriesPerSecond = float64(operations-m.operations) / now.Sub(m.sampledAt).Seconds()
+	}
+	m.operations, m.sampledAt = operations, now
+	m.statsMu.Unlock()
+
+	return stats, nil
+}
+
+// PoolStats returns the usage of the connection pool, across the servers of a replica set
+func (m *MongoDBProvider) PoolStats() PoolStats {
+	open, inUse := int(m.poolOpen.Load()), int(m.poolInUse.Load())
+	return PoolStats{
+		MaxOpen:    poolSettings(m.config).MaxOpen,
+		Open:       open,
+		InUse:      inUse,
+		Idle:       max(open-inUse, 0),
+		IdleClosed: m.poolIdleClosed.Load(),
+	}
+}
+
+// poolEvent counts the connections opened, closed and checked out of the pool
+func (m *MongoDBProvider) poolEvent(e *event.PoolEvent) {
+	switch e.Type {
+	case event.ConnectionCreated:
+		m.poolOpen.Add(1)
+	case event.ConnectionClosed:
+		m.poolOpen.Add(-1)
+		if e.Reason == event.ReasonIdle {
+			m.poolIdleClosed.Add(1)
+		}
+	case event.ConnectionCheckedOut:
+		m.poolInUse.Add(1)
+	case event.ConnectionCheckedIn:
+		m.poolInUse.Add(-1)
+	}
+}
+
+// IsConnected returns whether the provider is currently connected
+func (m *MongoDBProvider) IsConnected() bool {
+	if m.client == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	return m.client.Ping(ctx, nil) == nil
+}
+
+// GetConnectionInfo returns current connection information
+func (m *MongoDBProvider) GetConnectionInfo() *DatabaseConfig {
+	return &m.config
+}
+
+// database returns the named database, defaulting to the connection's
+func (m *MongoDBProvider) database(name string) (*mongo.Database, error) {
+	if m.client == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+	if name == "" {
+		name = m.config.Database
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no database selected; set one on the connection")
+	}
+	return m.client.Database(name), nil
+}
+
+// mongoURI builds a connection string from the config, or uses extra.uri as given, e.g. for
+// replica sets and mongodb+srv
+func mongoURI(config DatabaseConfig) string {
+	if uri, ok := config.Extra["uri"].(string); ok && uri != "" {
+		return uri
+	}
+
+	port := config.Port
+	if port == 0 {
+		port = 27017
+	}
+	host := config.Host
+	if host == "" {
+		host = "localhost"
+	}
+	uri := url.URL{Scheme: "mongodb", Host: net.JoinHostPort(host, strconv.Itoa(port)), Path: "/"}
+	query := url.Values{}
+	if config.Username != "" {
+		uri.User = url.UserPassword(config.Username, config.Password)
+		// Users are usually created in admin rather than in the database they use
+		authSource := "admin"
+		if source, ok := config.Extra["auth_source"].(string); ok && source != "" {
+			authSource = source
+		}
+		query.Set("authSource", authSource)
+	}
+	switch config.SSLMode {
+	case "", "disable":
+	case "verify-ca", "verify-full":
+		query.Set("tls", "true")
+	default: // require, prefer
+		query.Set("tls", "true")
+		query.Set("tlsInsecure", "true")
+	}
+	uri.RawQuery = query.Encode()
+	return uri.String()
+}
+
+// parseMongoCommand parses a command document, whose first key names the command
+func parseMongoCommand(text string) (bson.D, error) {
+	command, err := parseMongoDocument(text)
+	if err != nil {
+		return nil, fmt.Errorf("commands are Extended JSON documents such as {\"find\": \"users\"}: %w", err)
+	}
+	if len(command) == 0 {
+		return nil, errors.New("empty command")
+	}
+	return command, nil
+}
+
+// parseMongoDocument parses an Extended JSON document; an empty string is an empty document
+func parseMongoDocument(text string) (bson.D, error) {
+	document := bson.D{}
+	if strings.TrimSpace(text) == "" {
+		return document, nil
+	}
+	if err := bson.UnmarshalExtJSON([]byte(text), false, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// isMongoRead reports whether a command only reads
+func isMongoRead(command bson.D) bool {
+	name := command[0].Key
+	if !mongoReadCommands[name] {
+		return false
+	}
+	if name != "aggregate" {
+		return true
+	}
+	for _, field := range command {
+		if field.Key != "pipeline" {
+			continue
+		}
+		stages, _ := field.Value.(bson.A)
+		for _, stage := range stages {
+			if stage, ok := stage.(bson.D); ok && len(stage) > 0 && (stage[0].Key == "$out" || stage[0].Key == "$merge") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// paginateMongoCommand applies a limit and offset to find and aggregate commands that don't
+// set their own
+func paginateMongoCommand(command bson.D, limit, offset int) bson.D {
+	switch command[0].Key {
+	case "find":
+		has := map[string]bool{}
+		for _, field := range command {
+			has[field.Key] = true
+		}
+		if offset > 0 && !has["skip"] {
+			command = append(command, bson.E{Key: "skip", Value: offset})
+		}
+		if limit > 0 && !has["limit"] {
+			command = append(command, bson.E{Key: "limit", Value: limit})
+		}
+	case "aggregate":
+		for i, field := range command {
+			if field.Key != "pipeline" {
+				continue
+			}
+			stages, _ := field.Value.(bson.A)
+			stages = append(bson.A{}, stages...)
+			if offset > 0 {
+				stages = append(stages, bson.D{{Key: "$skip", Value: offset}})
+			}
+			if limit > 0 {
+				stages = append(stages, bson.D{{Key: "$limit", Value: limit}})
+			}
+			command[i].Value = stages
+		}
+	}
+	return command
+}
+
+// documentsResult lays documents out as rows, with a column per top-level field in order
+// of first appearance
+func documentsResult(documents []bson.D) *QueryResult {
+	columns := []string{}
+	position := map[string]int{}
+	for _, document := range documents {
+		for _, field := range document {
+			if _, ok := position[field.Key]; !ok {
+				position[field.Key] = len(columns)
+				columns = append(columns, field.Key)
+			}
+		}
+	}
+
+	rows := [][]interface{}{}
+	for _, document := range documents {
+		row := make([]interface{}, len(columns))
+		for _, field := range document {
+			row[position[field.Key]] = mongoValue(field.Value)
+		}
+		rows = append(rows, row)
+	}
+	return &QueryResult{Columns: columns, Rows: rows, RowCount: len(rows)}
+}
+
+// mongoValue converts BSON values to plain values that encode to readable JSON
+func mongoValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.D:
+		object := make(map[string]interface{}, len(v))
+		for _, field := range v {
+			object[field.Key] = mongoValue(field.Value)
+		}
+		return object
+	case bson.M:
+		object := make(map[string]interface{}, len(v))
+		for key, field := range v {
+			object[key] = mongoValue(field)
+		}
+		return object
+	case bson.A:
+		array := make([]interface{}, len(v))
+		for i, item := range v {
+			array[i] = mongoValue(item)
+		}
+		return array
+	case bson.ObjectID:
+		return v.Hex()
+	case bson.DateTime:
+		return v.Time().UTC()
+	case bson.Decimal128:
+		return v.String()
+	case bson.Binary:
+		return base64.StdEncoding.EncodeToString(v.Data)
+	case bson.Timestamp:
+		return time.Unix(int64(v.T), 0).UTC()
+	case bson.Regex:
+		return "/" + v.Pattern + "/" + v.Options
+	case string, bool, int32, int64, float64, nil:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// mongoTypeName names the BSON type of a value as the mongo shell does
+func mongoTypeName(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case int32:
+		return "int"
+	case int64:
+		return "long"
+	case float64:
+		return "double"
+	case bool:
+		return "bool"
+	case nil:
+		return "null"
+	case bson.ObjectID:
+		return "objectId"
+	case bson.DateTime:
+		return "date"
+	case bson.D, bson.M:
+		return "object"
+	case bson.A:
+		return "array"
+	case bson.Decimal128:
+		return "decimal"
+	case bson.Binary:
+		return "binData"
+	case bson.Timestamp:
+		return "timestamp"
+	case bson.Regex:
+		return "regex"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// inferFields describes the top-level fields of sampled documents. A field is nullable when
+// some documents lack it or hold null, and its type lists every BSON type seen.
+func inferFields(documents []bson.D, indexes []IndexInfo) []ColumnInfo {
+	type field struct {
+		types map[string]bool
+		count int
+	}
+	var names []string
+	fields := map[string]*field{}
+	for _, document := range documents {
+		for _, element := range document {
+			f, ok := fields[element.Key]
+			if !ok {
+				f = &field{types: map[string]bool{}}
+				fields[element.Key] = f
+				names = append(names, element.Key)
+			}
+			f.types[mongoTypeName(element.Value)] = true
+			f.count++
+		}
+	}
+
+	columns := []ColumnInfo{}
+	for _, name := range names {
+		f := fields[name]
+		var types []string
+		for t := range f.types {
+			if t != "null" {
+				types = append(types, t)
+			}
+		}
+		sort.Strings(types)
+		if len(types) == 0 {
+			types = []string{"null"}
+		}
+		column := ColumnInfo{
+			Name:         name,
+			Type:         strings.Join(types, "|"),
+			Nullable:     f.count < len(documents) || f.types["null"],
+			IsPrimaryKey: name == "_id",
+		}
+		for _, index := range indexes {
+			for _, indexed := range index.Columns {
+				column.IsIndex = column.IsIndex || indexed == name
+			}
+			if index.Unique && len(index.Columns) == 1 && index.Columns[0] == name {
+				column.IsUnique = true
+			}
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// mongoNumber reads a BSON integer or double
+func mongoNumber(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// mongoPlan converts the reply of the explain command. The plan tree is taken from
+// executionStats when present, as its stages carry actual rows and times.
+func mongoPlan(reply map[string]interface{}) (*QueryPlan, error) {
+	// Aggregations that can't be pushed down to the query layer nest the plan in a $cursor stage
+	if stages, ok := reply["stages"].([]interface{}); ok && len(stages) > 0 {
+		if first, ok := stages[0].(map[string]interface{}); ok {
+			if inner, ok := first["$cursor"].(map[string]interface{}); ok {
+				reply = inner
+			}
+		}
+	}
+	planner, _ := reply["queryPlanner"].(map[string]interface{})
+	if planner == nil {
+		return nil, errors.New("explain returned no query plan")
+	}
+	winning, _ := planner["winningPlan"].(map[string]interface{})
+	if queryPlan, ok := winning["queryPlan"].(map[string]interface{}); ok { // slot-based engine
+		winning = queryPlan
+	}
+
+	plan := &QueryPlan{}
+	root := winning
+	if execution, ok := reply["executionStats"].(map[string]interface{}); ok {
+		if stages, ok := execution["executionStages"].(map[string]interface{}); ok && stages["stage"] != nil && winning["stage"] == stages["stage"] {
+			root = stages
+		}
+		if millis, ok := planNumber(execution["executionTimeMillis"]); ok {
+			plan.ExecutionTime = &millis
+		}
+	}
+	if root == nil {
+		return nil, errors.New("explain returned no winning plan")
+	}
+	plan.Plan = mongoPlanNode(root)
+
+	if namespace, ok := planner["namespace"].(string); ok {
+		_, collection, _ := strings.Cut(namespace, ".")
+		plan.Plan.Relation = collection
+	}
+	return plan, nil
+}
+
+// mongoPlanChildren are the keys under which explain nests input stages
+var mongoPlanChildren = []string{"inputStage", "inputStages", "outerStage", "innerStage", "thenStage", "elseStage"}
+
+// mongoPlanNode converts a stage of an explain plan, such as FETCH, IXSCAN or COLLSCAN
+func mongoPlanNode(stage map[string]interface{}) PlanNode {
+	node := PlanNode{Properties: map[string]interface{}{}}
+	node.NodeType, _ = stage["stage"].(string)
+	node.Index, _ = stage["indexName"].(string)
+
+	for key, value := range stage {
+		switch key {
+		case "stage", "indexName", "inputStage", "inputStages", "outerStage", "innerStage", "thenStage", "elseStage":
+		case "filter":
+			if filter, err := bson.MarshalExtJSON(value, false, false); err == nil {
+				node.Filter = string(filter)
+			}
+		case "nReturned":
+			if n, ok := planNumber(value); ok {
+				node.ActualRows = &n
+			}
+		case "executionTimeMillisEstimate":
+			if n, ok := planNumber(value); ok {
+				node.ActualTotalTime = &n
+			}
+		default:
+			node.Properties[key] = value
+		}
+	}
+	if len(node.Properties) == 0 {
+		node.Properties = nil
+	}
+
+	for _, key := range mongoPlanChildren {
+		switch child := stage[key].(type) {
+		case map[string]interface{}:
+			node.Children = append(node.Children, mongoPlanNode(child))
+		case []interface{}:
+			for _, c := range child {
+				if c, ok := c.(map[string]interface{}); ok {
+					node.Children = append(node.Children, mongoPlanNode(c))
+				}
+			}
+		}
+	}
+
+	node.Detail = node.NodeType
+	if node.Index != "" {
+		node.Detail += " using " + node.Index
+	}
+	return node
+}
@@ -0,0 +1,1034 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQLProvider implements the DatabaseProvider interface for MySQL and MariaDB
+type MySQLProvider struct {
+	config  DatabaseConfig
+	db      *sql.DB
+	mariaDB bool
+
+	// previous statement count sample, for queries per second
+	statsMu   sync.Mutex
+	questions int64
+	sampledAt time.Time
+}
+
+// NewMySQLProvider creates a new MySQL/MariaDB provider
+func NewMySQLProvider() DatabaseProvider {
+	return &MySQLProvider{}
+}
+
+// Type returns the provider type
+func (m *MySQLProvider) Type() DatabaseType {
+	return DatabaseTypeMySQL
+}
+
+// Connect establishes a connection to MySQL or MariaDB
+func (m *MySQLProvider) Connect(ctx context.Context, config DatabaseConfig) error {
+	m.config = config
+
+	db, version, err := openMySQL(ctx, config, "")
+	if err != nil {
+		return err
+	}
+	m.mariaDB = isMariaDB(version)
+
+	if config.ReadOnly {
+		// MySQL 8 only knows transaction_read_only; MariaDB before 11.1 only tx_read_only
+		variable := "transaction_read_only"
+		if m.mariaDB {
+			variable = "tx_read_only"
+		}
+		db.Close()
+		if db, _, err = openMySQL(ctx, config, variable); err != nil {
+			return err
+		}
+	}
+
+	configurePool(db, config)
+
+	m.db = db
+	return nil
+}
+
+// Disconnect closes the database connection
+func (m *MySQLProvider) Disconnect(ctx context.Context) error {
+	if m.db != nil {
+		return m.db.Close()
+	}
+	return nil
+}
+
+// TestConnection tests the database connection
+func (m *MySQLProvider) TestConnection(ctx context.Context, config DatabaseConfig) (*TestConnectionResult, error) {
+	start := time.Now()
+
+	db, version, err := openMySQL(ctx, config, "")
+	if err != nil {
+		return &TestConnectionResult{
+			Success:      false,
+			Error:        err.Error(),
+			ResponseTime: time.Since(start),
+		}, nil
+	}
+	db.Close()
+
+	return &TestConnectionResult{
+		Success:      true,
+		ResponseTime: time.Since(start),
+		Version:      version,
+	}, nil
+}
+
+// GetDatabases returns a list of databases, leaving out the system schemas
+func (m *MySQLProvider) GetDatabases(ctx context.Context) ([]DatabaseInfo, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+
+	// Sessions per database; PROCESSLIST needs the PROCESS privilege to see other users
+	connections := map[string]int{}
+	if rows, err := m.db.QueryContext(ctx, `
+		SELECT DB, COUNT(*) FROM information_schema.PROCESSLIST WHERE DB IS NOT NULL GROUP BY DB
+	`); err == nil {
+		for rows.Next() {
+			var name string
+			var count int
+			if rows.Scan(&name, &count) == nil {
+				connections[name] = count
+			}
+		}
+		rows.Close()
+	}
+
+	query := `
+		SELECT s.SCHEMA_NAME, s.DEFAULT_CHARACTER_SET_NAME, s.DEFAULT_COLLATION_NAME,
+			COUNT(t.TABLE_NAME), COALESCE(SUM(t.DATA_LENGTH + t.INDEX_LENGTH), 0)
+		FROM information_schema.SCHEMATA s
+		LEFT JOIN information_schema.TABLES t ON t.TABLE_SCHEMA = s.SCHEMA_NAME
+		WHERE s.SCHEMA_NAME NOT IN ('information_schema', 'performance_schema', 'mysql', 'sys')
+		GROUP BY s.SCHEMA_NAME, s.DEFAULT_CHARACTER_SET_NAME, s.DEFAULT_COLLATION_NAME
+		ORDER BY s.SCHEMA_NAME
+	`
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var databases []DatabaseInfo
+	for rows.Next() {
+		var db DatabaseInfo
+		if err := rows.Scan(&db.Name, &db.Encoding, &db.Collation, &db.TableCount, &db.Size); err != nil {
+			return nil, err
+		}
+		db.ConnectionCount = connections[db.Name]
+		databases = append(databases, db)
+	}
+	return databases, rows.Err()
+}
+
+// GetTables returns the tables and views of a database
+func (m *MySQLProvider) GetTables(ctx context.Context, database string) ([]TableInfo, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+	database = m.schemaName(database)
+
+	// TABLE_ROWS is InnoDB's estimate; counting every table would be too slow
+	query := `
+		SELECT TABLE_NAME, TABLE_TYPE, COALESCE(TABLE_ROWS, 0), COALESCE(DATA_LENGTH + INDEX_LENGTH, 0),
+			CREATE_TIME, UPDATE_TIME, COALESCE(TABLE_COMMENT, '')
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ?
+		ORDER BY TABLE_NAME
+	`
+	rows, err := m.db.QueryContext(ctx, query, database)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []TableInfo
+	for rows.Next() {
+		table := TableInfo{Schema: database}
+		var created, updated sql.NullTime
+		err := rows.Scan(&table.Name, &table.Type, &table.RowCount, &table.Size, &created, &updated, &table.Comment)
+		if err != nil {
+			return nil, err
+		}
+		if created.Valid {
+			table.CreatedAt = &created.Time
+		}
+		if updated.Valid {
+			table.UpdatedAt = &updated.Time
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// GetColumns returns column information for a table
+func (m *MySQLProvider) GetColumns(ctx context.Context, database, table string) ([]ColumnInfo, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+	database = m.schemaName(database)
+
+	query := `
+		SELECT COLUMN_NAME, COLUMN_TYPE, COALESCE(CHARACTER_MAXIMUM_LENGTH, 0),
+			COALESCE(NUMERIC_PRECISION, 0), COALESCE(NUMERIC_SCALE, 0),
+			IS_NULLABLE = 'YES', COLUMN_DEFAULT, COALESCE(COLUMN_COMMENT, '')
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION
+	`
+	rows, err := m.db.QueryContext(ctx, query, database, table)
+	if err != nil {
+		return nil, err
+	}
+	var columns []ColumnInfo
+	for rows.Next() {
+		var col ColumnInfo
+		var defaultVal sql.NullString
+		err := rows.Scan(&col.Name, &col.Type, &col.Length, &col.Precision, &col.Scale,
+			&col.Nullable, &defaultVal, &col.Comment)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if defaultVal.Valid {
+			col.Default = defaultVal.String
+		}
+		columns = append(columns, col)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// COLUMN_KEY only marks the first column of an index, so read the indexes themselves
+	indexes, err := m.indexes(ctx, database, table)
+	if err != nil {
+		return nil, err
+	}
+	for i := range columns {
+		for _, index := range indexes {
+			for _, column := range index.Columns {
+				if column == columns[i].Name {
+					columns[i].IsIndex = true
+					columns[i].IsPrimaryKey = columns[i].IsPrimaryKey || index.Primary
+				}
+			}
+			if index.Unique && len(index.Columns) == 1 && index.Columns[0] == columns[i].Name {
+				columns[i].IsUnique = true
+			}
+		}
+	}
+	return columns, nil
+}
+
+// GetTableSchema returns the indexes, foreign keys and constraints of a table
+func (m *MySQLProvider) GetTableSchema(ctx context.Context, database, table string) (*TableSchema, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+	database = m.schemaName(database)
+	schema := &TableSchema{
+		Table:       table,
+		Schema:      database,
+		Indexes:     []IndexInfo{},
+		ForeignKeys: []ForeignKeyInfo{},
+		Constraints: []ConstraintInfo{},
+	}
+
+	indexes, err := m.indexes(ctx, database, table)
+	if err != nil {
+		return nil, err
+	}
+	schema.Indexes = append(schema.Indexes, indexes...)
+	// Primary keys and unique constraints are implemented as indexes
+	for _, index := range indexes {
+		switch {
+		case index.Primary:
+			schema.Constraints = append(schema.Constraints, ConstraintInfo{Name: index.Name, Type: ConstraintPrimaryKey, Columns: index.Columns})
+		case index.Unique:
+			schema.Constraints = append(schema.Constraints, ConstraintInfo{Name: index.Name, Type: ConstraintUnique, Columns: index.Columns})
+		}
+	}
+
+	foreignKeys, err := m.foreignKeys(ctx, database, table)
+	if err != nil {
+		return nil, err
+	}
+	for _, fk := range foreignKeys {
+		schema.ForeignKeys = append(schema.ForeignKeys, fk.ForeignKeyInfo)
+		schema.Constraints = append(schema.Constraints, ConstraintInfo{Name: fk.Name, Type: ConstraintForeignKey, Columns: fk.Columns})
+	}
+
+	// CHECK constraints are enforced from MySQL 8.0.16 and MariaDB 10.2; older servers lack the view
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT c.CONSTRAINT_NAME, c.CHECK_CLAUSE
+		FROM information_schema.CHECK_CONSTRAINTS c
+		JOIN information_schema.TABLE_CONSTRAINTS t
+			ON t.CONSTRAINT_SCHEMA = c.CONSTRAINT_SCHEMA AND t.CONSTRAINT_NAME = c.CONSTRAINT_NAME
+		WHERE t.TABLE_SCHEMA = ? AND t.TABLE_NAME = ? AND t.CONSTRAINT_TYPE = 'CHECK'
+		ORDER BY c.CONSTRAINT_NAME
+	`, database, table)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var name, clause string
+			if err := rows.Scan(&name, &clause); err != nil {
+				return nil, err
+			}
+			schema.Constraints = append(schema.Constraints, ConstraintInfo{Name: name, Type: ConstraintCheck, Definition: checkDefinition(clause)})
+		}
+	}
+	return schema, nil
+}
+
+// GetRelationships returns the foreign keys between the tables of a database
+func (m *MySQLProvider) GetRelationships(ctx context.Context, database string) ([]Relationship, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+	database = m.schemaName(database)
+	foreignKeys, err := m.foreignKeys(ctx, database, "")
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := map[string][]IndexInfo{}
+	relationships := []Relationship{}
+	for _, fk := range foreignKeys {
+		if _, ok := indexes[fk.table]; !ok {
+			if indexes[fk.table], err = m.indexes(ctx, database, fk.table); err != nil {
+				return nil, err
+			}
+		}
+		relationships = append(relationships, Relationship{
+			Name:        fk.Name,
+			FromSchema:  database,
+			FromTable:   fk.table,
+			FromColumns: fk.Columns,
+			ToSchema:    fk.ReferencedSchema,
+			ToTable:     fk.ReferencedTable,
+			ToColumns:   fk.ReferencedColumns,
+			Cardinality: cardinality(fk.Columns, indexes[fk.table]),
+			OnDelete:    fk.OnDelete,
+			OnUpdate:    fk.OnUpdate,
+		})
+	}
+	return relationships, nil
+}
+
+// indexes lists a table's indexes, the primary key first
+func (m *MySQLProvider) indexes(ctx context.Context, database, table string) ([]IndexInfo, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT INDEX_NAME, NON_UNIQUE, COALESCE(COLUMN_NAME, ''), INDEX_TYPE
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY INDEX_NAME = 'PRIMARY' DESC, INDEX_NAME, SEQ_IN_INDEX
+	`, database, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := []IndexInfo{}
+	for rows.Next() {
+		var name, column, method string
+		var nonUnique bool
+		if err := rows.Scan(&name, &nonUnique, &column, &method); err != nil {
+			return nil, err
+		}
+		if column == "" {
+			column = "(expression)" // functional key part, MySQL 8.0.13+
+		}
+		if n := len(indexes); n > 0 && indexes[n-1].Name == name {
+			indexes[n-1].Columns = append(indexes[n-1].Columns, column)
+			continue
+		}
+		indexes = append(indexes, IndexInfo{
+			Name:    name,
+			Columns: []string{column},
+			Unique:  !nonUnique,
+			Primary: name == "PRIMARY",
+			Method:  strings.ToLower(method),
+		})
+	}
+	return indexes, rows.Err()
+}
+
+// mysqlForeignKey is a foreign key with the table it belongs to
+type mysqlForeignKey struct {
+	ForeignKeyInfo
+	table string
+}
+
+// foreignKeys lists the foreign keys of a database, or of one of its tables
+func (m *MySQLProvider) foreignKeys(ctx context.Context, database, table string) ([]mysqlForeignKey, error) {
+	query := `
+		SELECT k.TABLE_NAME, k.CONSTRAINT_NAME, k.COLUMN_NAME, k.REFERENCED_TABLE_SCHEMA,
+			k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME, r.UPDATE_RULE, r.DELETE_RULE
+		FROM information_schema.KEY_COLUMN_USAGE k
+		JOIN information_schema.REFERENTIAL_CONSTRAINTS r
+			ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME
+				AND r.TABLE_NAME = k.TABLE_NAME
+		WHERE k.TABLE_SCHEMA = ? AND k.REFERENCED_TABLE_NAME IS NOT NULL`
+	args := []interface{}{database}
+	if table != "" {
+		query += " AND k.TABLE_NAME = ?"
+		args = append(args, table)
+	}
+	query += " ORDER BY k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION"
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []mysqlForeignKey
+	for rows.Next() {
+		var fk mysqlForeignKey
+		var column, referencedColumn string
+		err := rows.Scan(&fk.table, &fk.Name, &column, &fk.ReferencedSchema, &fk.ReferencedTable,
+			&referencedColumn, &fk.OnUpdate, &fk.OnDelete)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(foreignKeys); n > 0 && foreignKeys[n-1].table == fk.table && foreignKeys[n-1].Name == fk.Name {
+			foreignKeys[n-1].Columns = append(foreignKeys[n-1].Columns, column)
+			foreignKeys[n-1].ReferencedColumns = append(foreignKeys[n-1].ReferencedColumns, referencedColumn)
+			continue
+		}
+		fk.Columns = []string{column}
+		fk.ReferencedColumns = []string{referencedColumn}
+		foreignKeys = append(foreignKeys, fk)
+	}
+	return foreignKeys, rows.Err()
+}
+
+// ExecuteQuery executes a SQL statement and returns its result set, or the affected rows
+func (m *MySQLProvider) ExecuteQuery(ctx context.Context, req QueryRequest) (*QueryResult, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+
+	start := time.Now()
+
+	// Add LIMIT if specified
+	query := req.SQL
+	if req.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", req.Limit)
+	}
+	if req.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", req.Offset)
+	}
+
+	if !returnsRows(query) {
+		result, err := m.db.ExecContext(ctx, query)
+		if err != nil {
+			return &QueryResult{Error: err.Error(), Duration: time.Since(start)}, nil
+		}
+		rowsAffected, _ := result.RowsAffected()
+		return &QueryResult{
+			Columns:      []string{"Result"},
+			Rows:         [][]interface{}{{"Query executed successfully"}},
+			RowCount:     1,
+			AffectedRows: rowsAffected,
+			Duration:     time.Since(start),
+		}, nil
+	}
+
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return &QueryResult{Error: err.Error(), Duration: time.Since(start)}, nil
+	}
+	defer rows.Close()
+
+	columns, results, err := scanRows(rows)
+	if err != nil {
+		return &QueryResult{Error: err.Error(), Duration: time.Since(start)}, nil
+	}
+	return &QueryResult{
+		Columns:  columns,
+		Rows:     results,
+		RowCount: len(results),
+		Duration: time.Since(start),
+	}, nil
+}
+
+// Explain returns the plan of a statement from EXPLAIN FORMAT=JSON. With ANALYZE, MariaDB
+// reports actual rows and times in the same JSON, while MySQL 8.0.18+ returns a tree as text.
+// The statement runs in a read-only transaction that is rolled back.
+func (m *MySQLProvider) Explain(ctx context.Context, req ExplainRequest) (*QueryPlan, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+	statement, err := singleStatement(req.SQL)
+	if err != nil {
+		return nil, err
+	}
+	if req.Analyze && !m.config.ReadOnly {
+		return nil, ErrAnalyzeNotAllowed
+	}
+
+	start := time.Now()
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := "EXPLAIN FORMAT=JSON " + statement
+	switch {
+	case req.Analyze && m.mariaDB:
+		query = "ANALYZE FORMAT=JSON " + statement
+	case req.Analyze:
+		query = "EXPLAIN ANALYZE " + statement
+	}
+	var output string
+	if err := tx.QueryRowContext(ctx, query).Scan(&output); err != nil {
+		return nil, err
+	}
+
+	plan := &QueryPlan{Analyzed: req.Analyze}
+	if req.Analyze && !m.mariaDB {
+		plan.Plan = mysqlTreePlan(output)
+	} else if plan.Plan, err = mysqlJSONPlan(output); err != nil {
+		return nil, err
+	}
+	plan.Duration = time.Since(start)
+	return plan, nil
+}
+
+// GetTableData returns paginated data from a table
+func (m *MySQLProvider) GetTableData(ctx context.Context, req TableDataRequest) (*QueryResult, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+
+	query := "SELECT * FROM " + mysqlTableName(req.Schema, req.Table)
+	if req.Where != "" {
+		query += fmt.Sprintf(" WHERE %s", req.Where)
+	}
+	if req.Order != "" {
+		query += fmt.Sprintf(" ORDER BY %s", req.Order)
+	}
+
+	return m.ExecuteQuery(ctx, QueryRequest{
+		SQL:    query,
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+}
+
+// UpdateRow updates a single row in a table
+func (m *MySQLProvider) UpdateRow(ctx context.Context, req RowUpdateRequest) error {
+	if m.db == nil {
+		return fmt.Errorf("not connected to database")
+	}
+
+	var setParts []string
+	var args []interface{}
+	for col, val := range req.Values {
+		setParts = append(setParts, quoteMySQL(col)+" = ?")
+		args = append(args, val)
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		mysqlTableName(req.Schema, req.Table),
+		strings.Join(setParts, ", "),
+		req.Where)
+
+	_, err := m.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// DeleteRow deletes a single row from a table
+func (m *MySQLProvider) DeleteRow(ctx context.Context, req RowDeleteRequest) error {
+	if m.db == nil {
+		return fmt.Errorf("not connected to database")
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", mysqlTableName(req.Schema, req.Table), req.Where)
+	_, err := m.db.ExecContext(ctx, query)
+	return err
+}
+
+// InsertRow inserts a new row into a table
+func (m *MySQLProvider) InsertRow(ctx context.Context, req RowInsertRequest) error {
+	if m.db == nil {
+		return fmt.Errorf("not connected to database")
+	}
+
+	var columns []string
+	var placeholders []string
+	var args []interface{}
+	for col, val := range req.Values {
+		columns = append(columns, quoteMySQL(col))
+		placeholders = append(placeholders, "?")
+		args = append(args, val)
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		mysqlTableName(req.Schema, req.Table),
+		strings.Join(columns, ", "),
+		strings.Join(placeholders, ", "))
+
+	_, err := m.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetStats returns server statistics from SHOW GLOBAL STATUS
+func (m *MySQLProvider) GetStats(ctx context.Context) (*DatabaseStats, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+
+	status, err := m.globalStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := &DatabaseStats{}
+
+	var maxConns int
+	m.db.QueryRowContext(ctx, "SELECT @@max_connections").Scan(&maxConns)
+	running, connected := int(status["Threads_running"]), int(status["Threads_connected"])
+	stats.Connections = ConnectionStats{
+		Active:  running,
+		Idle:    connected - running,
+		Total:   connected,
+		MaxConn: maxConns,
+	}
+
+	var used, free int64
+	err = m.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(DATA_LENGTH + INDEX_LENGTH), 0), COALESCE(SUM(DATA_FREE), 0)
+		FROM information_schema.TABLES
+	`).Scan(&used, &free)
+	if err == nil {
+		stats.Storage = StorageStats{
+			TotalSize: used + free,
+			UsedSize:  used,
+			FreeSize:  free, // allocated to tables but unused
+		}
+	}
+
+	// Slow_queries counts statements that took longer than long_query_time
+	stats.Performance.SlowQueries = int(status["Slow_queries"])
+	if requests := status["Innodb_buffer_pool_read_requests"]; requests > 0 {
+		stats.Performance.CacheHitRatio = (1 - float64(status["Innodb_buffer_pool_reads"])/float64(requests)) * 100
+	}
+	now := time.Now()
+	m.statsMu.Lock()
+	if questions := status["Questions"]; !m.sampledAt.IsZero() && questions >= m.questions {
+		stats.Performance.QueriesPerSecond = float64(questions-m.questions) / now.Sub(m.sampledAt).Seconds()
+	}
+	m.questions, m.sampledAt = status["Questions"], now
+	m.statsMu.Unlock()
+
+	var avgTime sql.NullFloat64
+	err = m.db.QueryRowContext(ctx, `
+		SELECT SUM(SUM_TIMER_WAIT) / NULLIF(SUM(COUNT_STAR), 0) / 1000000000
+		FROM performance_schema.events_statements_summary_by_digest
+	`).Scan(&avgTime)
+	if err == nil {
+		stats.Performance.AvgQueryTime = avgTime.Float64
+	}
+
+	return stats, nil
+}
+
+// globalStatus reads the numeric server status variables
+func (m *MySQLProvider) globalStatus(ctx context.Context) (map[string]int64, error) {
+	rows, err := m.db.QueryContext(ctx, "SHOW GLOBAL STATUS")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	status := map[string]int64{}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			status[name] = n
+		}
+	}
+	return status, rows.Err()
+}
+
+// SlowQueries returns the statement digests from performance_schema with a mean execution
+// time of at least threshold, slowest first
+func (m *MySQLProvider) SlowQueries(ctx context.Context, threshold time.Duration, limit int) ([]SlowQuery, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+	var enabled bool
+	if err := m.db.QueryRowContext(ctx, "SELECT @@performance_schema").Scan(&enabled); err != nil || !enabled {
+		return nil, fmt.Errorf("%w: performance_schema is disabled", ErrSlowQueriesUnavailable)
+	}
+
+	// Timers are in picoseconds
+	query := `
+		SELECT COALESCE(DIGEST, ''), COALESCE(DIGEST_TEXT, ''), COUNT_STAR, SUM_TIMER_WAIT / 1000000000,
+			AVG_TIMER_WAIT / 1000000000, MAX_TIMER_WAIT / 1000000000, SUM_ROWS_SENT
+		FROM performance_schema.events_statements_summary_by_digest
+		WHERE AVG_TIMER_WAIT >= ?`
+	args := []interface{}{threshold.Nanoseconds() * 1000}
+	if m.config.Database != "" {
+		query += " AND SCHEMA_NAME = ?"
+		args = append(args, m.config.Database)
+	}
+	query += " ORDER BY AVG_TIMER_WAIT DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSlowQueriesUnavailable, err)
+	}
+	defer rows.Close()
+
+	queries := []SlowQuery{}
+	for rows.Next() {
+		var q SlowQuery
+		if err := rows.Scan(&q.QueryID, &q.Query, &q.Calls, &q.TotalTime, &q.MeanTime, &q.MaxTime, &q.Rows); err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+	return queries, rows.Err()
+}
+
+// PoolStats returns the usage of the connection pool
+func (m *MySQLProvider) PoolStats() PoolStats {
+	return sqlPoolStats(m.db)
+}
+
+// IsConnected returns whether the provider is currently connected
+func (m *MySQLProvider) IsConnected() bool {
+	if m.db == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	return m.db.PingContext(ctx) == nil
+}
+
+// GetConnectionInfo returns current connection information
+func (m *MySQLProvider) GetConnectionInfo() *DatabaseConfig {
+	return &m.config
+}
+
+// schemaName defaults to the connection's database
+func (m *MySQLProvider) schemaName(database string) string {
+	if database == "" {
+		return m.config.Database
+	}
+	return database
+}
+
+// openMySQL connects and returns the server version. A non-empty readOnlyVariable is set
+// on every session of the pool.
+func openMySQL(ctx context.Context, config DatabaseConfig, readOnlyVariable string) (*sql.DB, string, error) {
+	cfg := mysql.NewConfig()
+	cfg.User = config.Username
+	cfg.Passwd = config.Password
+	cfg.Net = "tcp"
+	port := config.Port
+	if port == 0 {
+		port = 3306
+	}
+	cfg.Addr = net.JoinHostPort(config.Host, strconv.Itoa(port))
+	cfg.DBName = config.Database
+	cfg.ParseTime = true
+	switch config.SSLMode {
+	case "", "disable":
+	case "verify-ca", "verify-full":
+		cfg.TLSConfig = "true"
+	default: // require, prefer
+		cfg.TLSConfig = "skip-verify"
+	}
+	if readOnlyVariable != "" {
+		cfg.Params = map[string]string{readOnlyVariable: "1"}
+	}
+
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open database connection: %w", err)
+	}
+	db := sql.OpenDB(connector)
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		db.Close()
+		return nil, "", fmt.Errorf("failed to ping database: %w", err)
+	}
+	return db, version, nil
+}
+
+func isMariaDB(version string) bool {
+	return strings.Contains(strings.ToLower(version), "mariadb")
+}
+
+func quoteMySQL(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func mysqlTableName(schema, table string) string {
+	if schema != "" {
+		return quoteMySQL(schema) + "." + quoteMySQL(table)
+	}
+	return quoteMySQL(table)
+}
+
+// checkDefinition formats a CHECK_CLAUSE, which MySQL parenthesizes and MariaDB doesn't
+func checkDefinition(clause string) string {
+	clause = strings.TrimSpace(clause)
+	if strings.HasPrefix(clause, "(") && matchingParen(clause, 0) == len(clause)-1 {
+		return "CHECK " + clause
+	}
+	return "CHECK (" + clause + ")"
+}
+
+// returnsRows reports whether a statement produces a result set
+func returnsRows(query string) bool {
+	fields := strings.Fields(strings.TrimLeft(strings.TrimSpace(query), "("))
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "SHOW", "DESCRIBE", "DESC", "EXPLAIN", "WITH", "TABLE", "VALUES", "CHECK", "CHECKSUM":
+		return true
+	}
+	return false
+}
+
+// scanRows reads a result set, converting byte slices to strings
+func scanRows(rows *sql.Rows) ([]string, [][]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	var results [][]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, nil, err
+		}
+		for i, val := range values {
+			if b, ok := val.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		results = append(results, values)
+	}
+	return columns, results, rows.Err()
+}
+
+// mysqlJSONPlan converts the output of EXPLAIN FORMAT=JSON, or MariaDB's ANALYZE FORMAT=JSON
+func mysqlJSONPlan(output string) (PlanNode, error) {
+	var explained map[string]interface{}
+	if err := json.Unmarshal([]byte(output), &explained); err != nil {
+		return PlanNode{}, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	block, ok := explained["query_block"].(map[string]interface{})
+	if !ok {
+		return PlanNode{}, errors.New("EXPLAIN returned no query block")
+	}
+	return mysqlPlanNode("query_block", block), nil
+}
+
+// mysqlPlanNode converts an object of the JSON plan. Nested objects, such as a table or an
+// ordering_operation, and the entries of arrays such as nested_loop become children.
+func mysqlPlanNode(name string, raw map[string]interface{}) PlanNode {
+	node := PlanNode{NodeType: name, Properties: map[string]interface{}{}}
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch value := raw[key].(type) {
+		case map[string]interface{}:
+			if key == "cost_info" {
+				for _, costKey := range []string{"query_cost", "prefix_cost"} {
+					if cost, ok := planNumber(value[costKey]); ok {
+						node.TotalCost = cost
+					}
+				}
+				continue
+			}
+			node.Children = append(node.Children, mysqlPlanNode(key, value))
+		case []interface{}:
+			var scalars []interface{}
+			for _, entry := range value {
+				object, ok := entry.(map[string]interface{})
+				if !ok {
+					scalars = append(scalars, entry)
+					continue
+				}
+				if len(object) == 1 { // e.g. nested_loop: [{"table": {...}}]
+					for childName, child := range object {
+						if child, ok := child.(map[string]interface{}); ok {
+							node.Children = append(node.Children, mysqlPlanNode(childName, child))
+						} else {
+							node.Children = append(node.Children, mysqlPlanNode(key, object))
+						}
+					}
+					continue
+				}
+				node.Children = append(node.Children, mysqlPlanNode(key, object))
+			}
+			if len(scalars) > 0 {
+				node.Properties[key] = scalars
+			}
+		default:
+			number, isNumber := planNumber(value)
+			switch key {
+			case "table_name":
+				node.Relation, _ = value.(string)
+			case "key":
+				node.Index, _ = value.(string)
+			case "rows_examined_per_scan", "rows":
+				node.PlanRows = number
+			case "attached_condition":
+				node.Filter, _ = value.(string)
+			case "r_rows":
+				if isNumber {
+					node.ActualRows = &number
+				}
+			case "r_total_time_ms":
+				if isNumber {
+					node.ActualTotalTime = &number
+				}
+			case "r_loops":
+				if isNumber {
+					node.ActualLoops = &number
+				}
+			default:
+				node.Properties[key] = value
+			}
+		}
+	}
+	if len(node.Properties) == 0 {
+		node.Properties = nil
+	}
+
+	if node.Relation != "" {
+		access, _ := raw["access_type"].(string)
+		node.Detail = strings.TrimSpace(access + " on " + node.Relation)
+		if node.Index != "" {
+			node.Detail += " using " + node.Index
+		}
+	}
+	return node
+}
+
+// planNumber reads a JSON or BSON number, or a number in a string as MySQL writes costs
+func planNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+var (
+	treeCost   = regexp.MustCompile(`\(cost=(?:[\d.e+-]+\.\.)?([\d.e+-]+) rows=([\d.e+-]+)\)`)
+	treeActual = regexp.MustCompile(`\(actual time=([\d.e+-]+)\.\.([\d.e+-]+) rows=([\d.e+-]+) loops=(\d+)\)`)
+	treeTarget = regexp.MustCompile(` on (\S+)(?: using (\S+))?`)
+)
+
+// mysqlTreePlan converts the indented tree that MySQL's EXPLAIN ANALYZE prints, e.g.
+//
+//	-> Nested loop inner join  (cost=1.6 rows=3) (actual time=0.05..0.07 rows=3 loops=1)
+//	    -> Table scan on c  (cost=0.55 rows=3) (actual time=0.03..0.04 rows=3 loops=1)
+func mysqlTreePlan(output string) PlanNode {
+	type line struct {
+		indent int
+		node   PlanNode
+	}
+	var lines []line
+	for _, text := range strings.Split(output, "\n") {
+		indent := strings.Index(text, "-> ")
+		if indent < 0 {
+			continue
+		}
+		text = text[indent+len("-> "):]
+		heading, _, _ := strings.Cut(text, "  (")
+		node := PlanNode{NodeType: heading, Detail: heading}
+		if target := treeTarget.FindStringSubmatch(heading); target != nil {
+			node.NodeType = heading[:strings.Index(heading, " on ")]
+			node.Relation, node.Index = target[1], target[2]
+		}
+		if cost := treeCost.FindStringSubmatch(text); cost != nil {
+			node.TotalCost, _ = strconv.ParseFloat(cost[1], 64)
+			node.PlanRows, _ = strconv.ParseFloat(cost[2], 64)
+		}
+		if actual := treeActual.FindStringSubmatch(text); actual != nil {
+			values := make([]float64, 4)
+			for i := range values {
+				values[i], _ = strconv.ParseFloat(actual[i+1], 64)
+			}
+			node.ActualStartupTime, node.ActualTotalTime, node.ActualRows, node.ActualLoops = &values[0], &values[1], &values[2], &values[3]
+		}
+		lines = append(lines, line{indent, node})
+	}
+
+	var nest func(lines []line) []PlanNode
+	nest = func(lines []line) []PlanNode {
+		var nodes []PlanNode
+		for i := 0; i < len(lines); {
+			j := i + 1
+			for j < len(lines) && lines[j].indent > lines[i].indent {
+				j++
+			}
+			node := lines[i].node
+			node.Children = nest(lines[i+1 : j])
+			nodes = append(nodes, node)
+			i = j
+		}
+		return nodes
+	}
+	nodes := nest(lines)
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return PlanNode{NodeType: "QUERY PLAN", Children: nodes}
+}
@@ -0,0 +1,49 @@
+package databases
+
+import "testing"
+
+func TestMySQLPlans(t *testing.T) {
+	// MySQL writes costs as strings; nested_loop lists the joined tables in order
+	node, err := mysqlJSONPlan(`{"query_block": {
+		"select_id": 1,
+		"cost_info": {"query_cost": "2.20"},
+		"nested_loop": [
+			{"table": {"table_name": "c", "access_type": "ALL", "rows_examined_per_scan": 3,
+				"cost_info": {"prefix_cost": "0.55"}}},
+			{"table": {"table_name": "o", "access_type": "ref", "key": "orders_customer",
+				"rows_examined_per_scan": 1, "attached_condition": "(o.total > 100)",
+				"used_columns": ["id", "customer_id", "total"]}}
+		]
+	}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.NodeType != "query_block" || node.TotalCost != 2.2 || len(node.Children) != 2 {
+		t.Fatalf("unexpected plan %+v", node)
+	}
+	if scan := node.Children[0]; scan.Relation != "c" || scan.TotalCost != 0.55 || scan.Detail != "ALL on c" {
+		t.Errorf("unexpected scan %+v", scan)
+	}
+	if ref := node.Children[1]; ref.Index != "orders_customer" || ref.Filter != "(o.total > 100)" || ref.Detail != "ref on o using orders_customer" {
+		t.Errorf("unexpected lookup %+v", ref)
+	}
+
+	tree := mysqlTreePlan(`-> Nested loop inner join  (cost=1.6 rows=3) (actual time=0.05..0.07 rows=3 loops=1)
+    -> Table scan on c  (cost=0.55 rows=3) (actual time=0.03..0.04 rows=3 loops=1)
+    -> Index lookup on o using orders_customer (customer_id=c.id)  (cost=0.35 rows=1) (actual time=0.01..0.01 rows=1 loops=3)
+`)
+	if tree.NodeType != "Nested loop inner join" || tree.TotalCost != 1.6 || *tree.ActualRows != 3 || len(tree.Children) != 2 {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+	lookup := tree.Children[1]
+	if lookup.NodeType != "Index lookup" || lookup.Relation != "o" || lookup.Index != "orders_customer" || *lookup.ActualLoops != 3 {
+		t.Errorf("unexpected lookup %+v", lookup)
+	}
+
+	if checkDefinition("(`total` >= 0)") != "CHECK (`total` >= 0)" || checkDefinition("`a` > 0 and (`b` > 0)") != "CHECK (`a` > 0 and (`b` > 0))" {
+		t.Error("CHECK clauses should be parenthesized once")
+	}
+	if !returnsRows("  (SELECT 1)") || !returnsRows("show tables") || returnsRows("UPDATE t SET a = 1") {
+		t.Error("unexpected result set detection")
+	}
+}
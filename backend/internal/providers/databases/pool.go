@@ -0,0 +1,70 @@
+package databases
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Pool settings of managed connections that don't set their own
+const (
+	defaultMaxOpen     = 10
+	defaultMaxIdle     = 5
+	defaultIdleTimeout = 5 * time.Minute
+	defaultMaxLifetime = time.Hour
+)
+
+// poolSettings resolves a connection's pool settings, filling unset values with the defaults
+func poolSettings(config DatabaseConfig) PoolConfig {
+	pool := PoolConfig{}
+	if config.Pool != nil {
+		pool = *config.Pool
+	}
+	if pool.MaxOpen <= 0 {
+		pool.MaxOpen = defaultMaxOpen
+	}
+	if pool.MaxIdle <= 0 {
+		pool.MaxIdle = min(defaultMaxIdle, pool.MaxOpen)
+	}
+	if pool.IdleTimeout <= 0 {
+		pool.IdleTimeout = int(defaultIdleTimeout / time.Second)
+	}
+	if pool.MaxLifetime <= 0 {
+		pool.MaxLifetime = int(defaultMaxLifetime / time.Second)
+	}
+	return pool
+}
+
+func (p PoolConfig) idleTimeout() time.Duration {
+	return time.Duration(p.IdleTimeout) * time.Second
+}
+
+func (p PoolConfig) maxLifetime() time.Duration {
+	return time.Duration(p.MaxLifetime) * time.Second
+}
+
+// configurePool applies a connection's pool settings to a database/sql pool
+func configurePool(db *sql.DB, config DatabaseConfig) {
+	pool := poolSettings(config)
+	db.SetMaxOpenConns(pool.MaxOpen)
+	db.SetMaxIdleConns(pool.MaxIdle)
+	db.SetConnMaxIdleTime(pool.idleTimeout())
+	db.SetConnMaxLifetime(pool.maxLifetime())
+}
+
+// sqlPoolStats reports the usage of a database/sql pool
+func sqlPoolStats(db *sql.DB) PoolStats {
+	if db == nil {
+		return PoolStats{}
+	}
+	stats := db.Stats()
+	return PoolStats{
+		MaxOpen:        stats.MaxOpenConnections,
+		Open:           stats.OpenConnections,
+		InUse:          stats.InUse,
+		Idle:           stats.Idle,
+		WaitCount:      stats.WaitCount,
+		WaitDuration:   float64(stats.WaitDuration) / float64(time.Millisecond),
+		IdleClosed:     stats.MaxIdleClosed + stats.MaxIdleTimeClosed,
+		LifetimeClosed: stats.MaxLifetimeClosed,
+	}
+}
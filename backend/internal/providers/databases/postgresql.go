@@ -3,18 +3,26 @@ package databases
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // PostgreSQLProvider implements the DatabaseProvider interface for PostgreSQL
 type PostgreSQLProvider struct {
 	config DatabaseConfig
 	db     *sql.DB
+
+	// previous transaction count sample, for queries per second
+	statsMu      sync.Mutex
+	transactions int64
+	sampledAt    time.Time
 }
 
 // NewPostgreSQLProvider creates a new PostgreSQL provider
@@ -44,10 +52,7 @@ func (p *PostgreSQLProvider) Connect(ctx context.Context, config DatabaseConfig)
 		return fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	// Set connection pool settings
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(time.Hour)
+	configurePool(db, config)
 
 	p.db = db
 	return nil
@@ -296,6 +301,195 @@ func (p *PostgreSQLProvider) GetColumns(ctx context.Context, database, table str
 	return columns, nil
 }
 
+// pgConstraintTypes names pg_constraint.contype codes
+var pgConstraintTypes = map[string]string{
+	"p": ConstraintPrimaryKey,
+	"u": ConstraintUnique,
+	"f": ConstraintForeignKey,
+	"c": ConstraintCheck,
+	"x": ConstraintExclude,
+}
+
+// pgReferentialActions names pg_constraint's confupdtype and confdeltype codes
+var pgReferentialActions = map[string]string{
+	"a": "NO ACTION",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+// pgConstraintsQuery lists constraints with their columns in key order. unique_columns is
+// set when a unique, non-partial index covers exactly the constraint's columns.
+const pgConstraintsQuery = `
+	SELECT c.conname, c.contype::text, pg_get_constraintdef(c.oid), n.nspname, t.relname,
+		ARRAY(SELECT a.attname::text FROM unnest(c.conkey) WITH ORDINALITY AS k(attnum, ord)
+			JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum ORDER BY k.ord),
+		COALESCE(fn.nspname, ''), COALESCE(ft.relname, ''),
+		ARRAY(SELECT a.attname::text FROM unnest(c.confkey) WITH ORDINALITY AS k(attnum, ord)
+			JOIN pg_attribute a ON a.attrelid = c.confrelid AND a.attnum = k.attnum ORDER BY k.ord),
+		c.confupdtype::text, c.confdeltype::text,
+		EXISTS (
+			SELECT 1 FROM pg_index ix
+			WHERE ix.indrelid = c.conrelid AND ix.indisunique AND ix.indpred IS NULL
+				AND ix.indnkeyatts = cardinality(c.conkey)
+				AND (ix.indkey::int2[])[0:ix.indnkeyatts - 1] @> c.conkey
+		) AS unique_columns
+	FROM pg_constraint c
+	JOIN pg_class t ON t.oid = c.conrelid
+	JOIN pg_namespace n ON n.oid = t.relnamespace
+	LEFT JOIN pg_class ft ON ft.oid = c.confrelid
+	LEFT JOIN pg_namespace fn ON fn.oid = ft.relnamespace
+	WHERE %s
+	ORDER BY n.nspname, t.relname, c.contype, c.conname
+`
+
+type pgConstraint struct {
+	ConstraintInfo
+	schema, table string
+	refSchema     string
+	refTable      string
+	refColumns    []string
+	onUpdate      string
+	onDelete      string
+	uniqueColumns bool
+}
+
+// splitTableName splits schema.table, defaulting to the public schema
+func splitTableName(table string) (string, string) {
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return schema, name
+	}
+	return "public", table
+}
+
+func (p *PostgreSQLProvider) constraints(ctx context.Context, where string, args ...interface{}) ([]pgConstraint, error) {
+	rows, err := p.db.QueryContext(ctx, fmt.Sprintf(pgConstraintsQuery, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var constraints []pgConstraint
+	for rows.Next() {
+		var c pgConstraint
+		var kind, onUpdate, onDelete string
+		err := rows.Scan(&c.Name, &kind, &c.Definition, &c.schema, &c.table, pq.Array(&c.Columns),
+			&c.refSchema, &c.refTable, pq.Array(&c.refColumns), &onUpdate, &onDelete, &c.uniqueColumns)
+		if err != nil {
+			return nil, err
+		}
+		c.Type = pgConstraintTypes[kind]
+		if c.Type == "" {
+			c.Type = kind
+		}
+		c.onUpdate = pgReferentialActions[onUpdate]
+		c.onDelete = pgReferentialActions[onDelete]
+		constraints = append(constraints, c)
+	}
+	return constraints, rows.Err()
+}
+
+// GetTableSchema returns the indexes, foreign keys and constraints of a table. The table
+// may be qualified with its schema; public is assumed otherwise.
+func (p *PostgreSQLProvider) GetTableSchema(ctx context.Context, database, table string) (*TableSchema, error) {
+	if p.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+	schemaName, tableName := splitTableName(table)
+	schema := &TableSchema{
+		Table:       tableName,
+		Schema:      schemaName,
+		Indexes:     []IndexInfo{},
+		ForeignKeys: []ForeignKeyInfo{},
+		Constraints: []ConstraintInfo{},
+	}
+
+	query := `
+		SELECT i.relname, ix.indisunique, ix.indisprimary, ix.indpred IS NOT NULL, am.amname,
+			pg_get_indexdef(ix.indexrelid),
+			ARRAY(SELECT pg_get_indexdef(ix.indexrelid, k + 1, true)
+				FROM generate_subscripts(ix.indkey::int2[], 1) AS k
+				WHERE k < ix.indnkeyatts ORDER BY k)
+		FROM pg_index ix
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_am am ON am.oid = i.relam
+		WHERE n.nspname = $1 AND t.relname = $2
+		ORDER BY ix.indisprimary DESC, i.relname
+	`
+	rows, err := p.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var index IndexInfo
+		err := rows.Scan(&index.Name, &index.Unique, &index.Primary, &index.Partial, &index.Method,
+			&index.Definition, pq.Array(&index.Columns))
+		if err != nil {
+			return nil, err
+		}
+		schema.Indexes = append(schema.Indexes, index)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	constraints, err := p.constraints(ctx, "n.nspname = $1 AND t.relname = $2", schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range constraints {
+		schema.Constraints = append(schema.Constraints, c.ConstraintInfo)
+		if c.Type == ConstraintForeignKey {
+			schema.ForeignKeys = append(schema.ForeignKeys, ForeignKeyInfo{
+				Name:              c.Name,
+				Columns:           c.Columns,
+				ReferencedSchema:  c.refSchema,
+				ReferencedTable:   c.refTable,
+				ReferencedColumns: c.refColumns,
+				OnUpdate:          c.onUpdate,
+				OnDelete:          c.onDelete,
+			})
+		}
+	}
+	return schema, nil
+}
+
+// GetRelationships returns the foreign keys between the tables of the database
+func (p *PostgreSQLProvider) GetRelationships(ctx context.Context, database string) ([]Relationship, error) {
+	if p.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+	constraints, err := p.constraints(ctx, "c.contype = 'f' AND n.nspname NOT IN ('information_schema', 'pg_catalog')")
+	if err != nil {
+		return nil, err
+	}
+
+	relationships := []Relationship{}
+	for _, c := range constraints {
+		cardinality := CardinalityManyToOne
+		if c.uniqueColumns {
+			cardinality = CardinalityOneToOne
+		}
+		relationships = append(relationships, Relationship{
+			Name:        c.Name,
+			FromSchema:  c.schema,
+			FromTable:   c.table,
+			FromColumns: c.Columns,
+			ToSchema:    c.refSchema,
+			ToTable:     c.refTable,
+			ToColumns:   c.refColumns,
+			Cardinality: cardinality,
+			OnDelete:    c.onDelete,
+			OnUpdate:    c.onUpdate,
+		})
+	}
+	return relationships, nil
+}
+
 // ExecuteQuery executes a SQL query and returns results
 func (p *PostgreSQLProvider) ExecuteQuery(ctx context.Context, req QueryRequest) (*QueryResult, error) {
 	if p.db == nil {
@@ -365,6 +559,56 @@ func (p *PostgreSQLProvider) ExecuteQuery(ctx context.Context, req QueryRequest)
 	}, nil
 }
 
+// Explain returns the plan of a statement from EXPLAIN (FORMAT JSON). The statement runs
+// in a read-only transaction that is rolled back, so ANALYZE can't change any data.
+func (p *PostgreSQLProvider) Explain(ctx context.Context, req ExplainRequest) (*QueryPlan, error) {
+	if p.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+	statement, err := singleStatement(req.SQL)
+	if err != nil {
+		return nil, err
+	}
+	if req.Analyze && !p.config.ReadOnly {
+		return nil, ErrAnalyzeNotAllowed
+	}
+
+	options := "FORMAT JSON"
+	if req.Analyze {
+		options += ", ANALYZE, BUFFERS"
+	}
+	start := time.Now()
+	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var output []byte
+	if err := tx.QueryRowContext(ctx, "EXPLAIN ("+options+") "+statement).Scan(&output); err != nil {
+		return nil, err
+	}
+	var explained []struct {
+		Plan          map[string]interface{} `json:"Plan"`
+		PlanningTime  *float64               `json:"Planning Time"`
+		ExecutionTime *float64               `json:"Execution Time"`
+	}
+	if err := json.Unmarshal(output, &explained); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	if len(explained) == 0 || explained[0].Plan == nil {
+		return nil, errors.New("EXPLAIN returned no plan")
+	}
+
+	return &QueryPlan{
+		Plan:          postgresPlanNode(explained[0].Plan),
+		Analyzed:      req.Analyze,
+		PlanningTime:  explained[0].PlanningTime,
+		ExecutionTime: explained[0].ExecutionTime,
+		Duration:      time.Since(start),
+	}, nil
+}
+
 // GetTableData returns paginated data from a table
 func (p *PostgreSQLProvider) GetTableData(ctx context.Context, req TableDataRequest) (*QueryResult, error) {
 	if p.db == nil {
@@ -518,17 +762,121 @@ func (p *PostgreSQLProvider) GetStats(ctx context.Context) (*DatabaseStats, erro
 		}
 	}
 
-	// Performance stats - simplified for now
-	stats.Performance = PerformanceStats{
-		QueriesPerSecond: 0, // Would need more complex monitoring
-		AvgQueryTime:     0,
-		SlowQueries:      0,
-		CacheHitRatio:    0,
-	}
+	stats.Performance = p.performance(ctx)
 
 	return stats, nil
 }
 
+// performance reads the transaction rate and cache hit ratio of the current database, and
+// the average and slow statement times when pg_stat_statements is installed
+func (p *PostgreSQLProvider) performance(ctx context.Context) PerformanceStats {
+	var performance PerformanceStats
+	var transactions, blocksHit, blocksRead int64
+	err := p.db.QueryRowContext(ctx, `
+		SELECT xact_commit + xact_rollback, blks_hit, blks_read
+		FROM pg_stat_database
+		WHERE datname = current_database()
+	`).Scan(&transactions, &blocksHit, &blocksRead)
+	if err == nil {
+		if blocksHit+blocksRead > 0 {
+			performance.CacheHitRatio = float64(blocksHit) / float64(blocksHit+blocksRead) * 100
+		}
+
+		now := time.Now()
+		p.statsMu.Lock()
+		if !p.sampledAt.IsZero() && transactions >= p.transactions {
+			performance.QueriesPerSecond = float64(transactions-p.transactions) / now.Sub(p.sampledAt).Seconds()
+		}
+		p.transactions, p.sampledAt = transactions, now
+		p.statsMu.Unlock()
+	}
+
+	columns, err := p.statementColumns(ctx)
+	if err != nil {
+		return performance
+	}
+	var avgTime sql.NullFloat64
+	var slow int
+	query := fmt.Sprintf(`
+		SELECT sum(%[1]s) / nullif(sum(calls), 0), count(*) FILTER (WHERE %[2]s >= $1)
+		FROM pg_stat_statements
+		WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+	`, columns.total, columns.mean)
+	if err := p.db.QueryRowContext(ctx, query, float64(SlowQueryThreshold.Microseconds())/1000).Scan(&avgTime, &slow); err == nil {
+		performance.AvgQueryTime = avgTime.Float64
+		performance.SlowQueries = slow
+	}
+	return performance
+}
+
+// statementTimeColumns names the timing columns of pg_stat_statements, which gained an
+// exec_ infix in PostgreSQL 13
+type statementTimeColumns struct {
+	total, mean, max string
+}
+
+// statementColumns checks pg_stat_statements is installed and returns its timing columns
+func (p *PostgreSQLProvider) statementColumns(ctx context.Context) (statementTimeColumns, error) {
+	var installed bool
+	var version int
+	err := p.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_stat_statements'),
+			current_setting('server_version_num')::int
+	`).Scan(&installed, &version)
+	if err != nil {
+		return statementTimeColumns{}, err
+	}
+	if !installed {
+		return statementTimeColumns{}, fmt.Errorf("%w: the pg_stat_statements extension is not installed", ErrSlowQueriesUnavailable)
+	}
+	if version < 130000 {
+		return statementTimeColumns{"total_time", "mean_time", "max_time"}, nil
+	}
+	return statementTimeColumns{"total_exec_time", "mean_exec_time", "max_exec_time"}, nil
+}
+
+// SlowQueries returns the statements of the current database from pg_stat_statements with
+// a mean execution time of at least threshold, slowest first
+func (p *PostgreSQLProvider) SlowQueries(ctx context.Context, threshold time.Duration, limit int) ([]SlowQuery, error) {
+	if p.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+	columns, err := p.statementColumns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT coalesce(queryid::text, ''), query, calls, %[1]s, %[2]s, %[3]s, rows
+		FROM pg_stat_statements
+		WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+			AND %[2]s >= $1
+		ORDER BY %[2]s DESC
+		LIMIT $2
+	`, columns.total, columns.mean, columns.max)
+	rows, err := p.db.QueryContext(ctx, query, float64(threshold.Microseconds())/1000, limit)
+	if err != nil {
+		// Listed in pg_extension but missing from shared_preload_libraries
+		return nil, fmt.Errorf("%w: %v", ErrSlowQueriesUnavailable, err)
+	}
+	defer rows.Close()
+
+	queries := []SlowQuery{}
+	for rows.Next() {
+		var q SlowQuery
+		if err := rows.Scan(&q.QueryID, &q.Query, &q.Calls, &q.TotalTime, &q.MeanTime, &q.MaxTime, &q.Rows); err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+	return queries, rows.Err()
+}
+
+// PoolStats returns the usage of the connection pool
+func (p *PostgreSQLProvider) PoolStats() PoolStats {
+	return sqlPoolStats(p.db)
+}
+
 // IsConnected returns whether the provider is currently connected
 func (p *PostgreSQLProvider) IsConnected() bool {
 	if p.db == nil {
@@ -570,6 +918,9 @@ func (p *PostgreSQLProvider) buildConnectionString(config DatabaseConfig) string
 	} else {
 		parts = append(parts, "sslmode=disable")
 	}
+	if config.ReadOnly {
+		parts = append(parts, "options='-c default_transaction_read_only=on'")
+	}
 
 	return strings.Join(parts, " ")
 }
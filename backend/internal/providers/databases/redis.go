@@ -0,0 +1,742 @@
+package databases
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisValueLimit is how many elements of a collection are returned as a key's value
+	redisValueLimit = 1000
+	// redisStringLimit is how many bytes of a string are returned as a key's value
+	redisStringLimit = 1 << 20
+	// redisScanCount is the default number of keys examined per scan
+	redisScanCount = 100
+)
+
+var (
+	// ErrKeyNotFound is returned for keys that don't exist
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrAdminCommand is returned when a non-admin runs a command that writes, deletes keys
+	// or runs a script
+	ErrAdminCommand = errors.New("only admins can run commands that write, delete keys or run scripts")
+	// errRedisNoTables is returned for table operations on Redis
+	errRedisNoTables = errors.New("redis has no tables; browse its keys instead")
+)
+
+// redisScriptCommands run scripts, which are refused to non-admins even when the server
+// flags them as read-only
+var redisScriptCommands = map[string]bool{
+	"eval":       true,
+	"eval_ro":    true,
+	"evalsha":    true,
+	"evalsha_ro": true,
+	"fcall":      true,
+	"fcall_ro":   true,
+	"script":     true,
+	"function":   true,
+}
+
+// RedisProvider implements the DatabaseProvider and KeyValueStore interfaces for Redis.
+// Numbered databases are named db0, db1, ...; queries are commands such as
+// HGETALL user:1, with double-quoted arguments for spaces.
+type RedisProvider struct {
+	config DatabaseConfig
+	db     int
+
+	mu      sync.Mutex
+	clients map[int]*redis.Client
+	// readOnly lists the commands the server flags as read-only
+	readOnly map[string]bool
+}
+
+// NewRedisProvider creates a new Redis provider
+func NewRedisProvider() DatabaseProvider {
+	return &RedisProvider{}
+}
+
+// Type returns the provider type
+func (r *RedisProvider) Type() DatabaseType {
+	return DatabaseTypeRedis
+}
+
+// Connect establishes a connection to Redis
+func (r *RedisProvider) Connect(ctx context.Context, config DatabaseConfig) error {
+	db, err := redisDB(config.Database, 0)
+	if err != nil {
+		return err
+	}
+	client := redis.NewClient(redisOptions(config, db))
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	commands, err := client.Command(ctx).Result()
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("failed to list commands: %w", err)
+	}
+	readOnly := make(map[string]bool, len(commands))
+	for name, info := range commands {
+		readOnly[strings.ToLower(name)] = info.ReadOnly
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = config
+	r.db = db
+	r.clients = map[int]*redis.Client{db: client}
+	r.readOnly = readOnly
+	return nil
+}
+
+// Disconnect closes the database connections
+func (r *RedisProvider) Disconnect(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	for _, client := range r.clients {
+		errs = append(errs, client.Close())
+	}
+	r.clients = nil
+	return errors.Join(errs...)
+}
+
+// TestConnection tests the database connection
+func (r *RedisProvider) TestConnection(ctx context.Context, config DatabaseConfig) (*TestConnectionResult, error) {
+	start := time.Now()
+
+	db, err := redisDB(config.Database, 0)
+	if err != nil {
+		return &TestConnectionResult{
+			Success:      false,
+			Error:        err.Error(),
+			ResponseTime: time.Since(start),
+		}, nil
+	}
+	client := redis.NewClient(redisOptions(config, db))
+	defer client.Close()
+
+	info, err := client.Info(ctx, "server").Result()
+	if err != nil {
+		return &TestConnectionResult{
+			Success:      false,
+			Error:        err.Error(),
+			ResponseTime: time.Since(start),
+		}, nil
+	}
+
+	server := parseRedisInfo(info)
+	version := "Redis " + server["redis_version"]
+	if valkey := server["valkey_version"]; valkey != "" {
+		version = "Valkey " + valkey
+	}
+	return &TestConnectionResult{
+		Success:      true,
+		ResponseTime: time.Since(start),
+		Version:      version,
+	}, nil
+}
+
+// GetDatabases returns the databases holding keys and the connection's database. For
+// Redis, TableCount is the number of keys.
+func (r *RedisProvider) GetDatabases(ctx context.Context) ([]DatabaseInfo, error) {
+	client, err := r.client("")
+	if err != nil {
+		return nil, err
+	}
+	info, err := client.Info(ctx, "keyspace").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[int]int{r.db: 0}
+	for name, value := range parseRedisInfo(info) {
+		db, err := redisDB(name, 0)
+		if err != nil {
+			continue
+		}
+		// db0:keys=12,expires=3,avg_ttl=0
+		for _, field := range strings.Split(value, ",") {
+			if count, ok := strings.CutPrefix(field, "keys="); ok {
+				keys[db], _ = strconv.Atoi(count)
+			}
+		}
+	}
+
+	databases := make([]DatabaseInfo, 0, len(keys))
+	for db, count := range keys {
+		databases = append(databases, DatabaseInfo{Name: redisDBName(db), TableCount: count})
+	}
+	sort.Slice(databases, func(i, j int) bool {
+		a, _ := redisDB(databases[i].Name, 0)
+		b, _ := redisDB(databases[j].Name, 0)
+		return a < b
+	})
+	return databases, nil
+}
+
+// GetTables returns no tables, as Redis stores keys
+func (r *RedisProvider) GetTables(ctx context.Context, database string) ([]TableInfo, error) {
+	return []TableInfo{}, nil
+}
+
+// GetColumns is not supported by Redis
+func (r *RedisProvider) GetColumns(ctx context.Context, database, table string) ([]ColumnInfo, error) {
+	return nil, errRedisNoTables
+}
+
+// GetTableSchema is not supported by Redis
+func (r *RedisProvider) GetTableSchema(ctx context.Context, database, table string) (*TableSchema, error) {
+	return nil, errRedisNoTables
+}
+
+// GetRelationships returns no relationships, as Redis has no tables
+func (r *RedisProvider) GetRelationships(ctx context.Context, database string) ([]Relationship, error) {
+	return []Relationship{}, nil
+}
+
+// ExecuteQuery runs a command on the connection's database. Arrays are returned one
+// element per row and maps one field per row. Non-admins and read-only connections only
+// run the commands the server flags as read-only, and scripts are left to admins.
+func (r *RedisProvider) ExecuteQuery(ctx context.Context, req QueryRequest) (*QueryResult, error) {
+	start := time.Now()
+
+	args := redisArgs(req.SQL)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("a command is required")
+	}
+	if err := r.checkCommand(strings.ToLower(args[0].(string)), req.Admin); err != nil {
+		return nil, err
+	}
+
+	client, err := r.client("")
+	if err != nil {
+		return nil, err
+	}
+	reply, err := client.Do(ctx, args...).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	result := redisResult(redisValue(reply), req.Limit, req.Offset)
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// checkCommand refuses commands the caller may not run as a query
+func (r *RedisProvider) checkCommand(name string, admin bool) error {
+	r.mu.Lock()
+	readOnly := r.readOnly[name]
+	r.mu.Unlock()
+
+	switch {
+	case !admin && (redisScriptCommands[name] || !readOnly):
+		return fmt.Errorf("%w: %s", ErrAdminCommand, strings.ToUpper(name))
+	case r.config.ReadOnly && !readOnly:
+		return fmt.Errorf("%w: %s is not a read-only command", ErrReadOnlyConnection, strings.ToUpper(name))
+	}
+	return nil
+}
+
+// Explain is not supported by Redis
+func (r *RedisProvider) Explain(ctx context.Context, req ExplainRequest) (*QueryPlan, error) {
+	return nil, fmt.Errorf("%w: redis commands have no execution plan", ErrInvalidExplain)
+}
+
+// GetTableData is not supported by Redis
+func (r *RedisProvider) GetTableData(ctx context.Context, req TableDataRequest) (*QueryResult, error) {
+	return nil, errRedisNoTables
+}
+
+// UpdateRow is not supported by Redis
+func (r *RedisProvider) UpdateRow(ctx context.Context, req RowUpdateRequest) error {
+	return errRedisNoTables
+}
+
+// DeleteRow is not supported by Redis
+func (r *RedisProvider) DeleteRow(ctx context.Context, req RowDeleteRequest) error {
+	return errRedisNoTables
+}
+
+// InsertRow is not supported by Redis
+func (r *RedisProvider) InsertRow(ctx context.Context, req RowInsertRequest) error {
+	return errRedisNoTables
+}
+
+// ScanKeys returns a page of the keys matching a glob pattern and type, with their TTL
+// and size. Like SCAN, a page may hold fewer keys than Count, or none, before the scan
+// completes.
+func (r *RedisProvider) ScanKeys(ctx context.Context, database string, req KeyScanRequest) (*KeyScanResult, error) {
+	client, err := r.client(database)
+	if err != nil {
+		return nil, err
+	}
+	count := req.Count
+	if count <= 0 {
+		count = redisScanCount
+	}
+
+	var names []string
+	var cursor uint64
+	if req.Type != "" {
+		names, cursor, err = client.ScanType(ctx, req.Cursor, req.Pattern, count, req.Type).Result()
+	} else {
+		names, cursor, err = client.Scan(ctx, req.Cursor, req.Pattern, count).Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]KeyInfo, len(names))
+	types := make([]*redis.StatusCmd, len(names))
+	ttls := make([]*redis.DurationCmd, len(names))
+	_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, name := range names {
+			types[i] = pipe.Type(ctx, name)
+			ttls[i] = pipe.TTL(ctx, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make([]*redis.IntCmd, len(names))
+	_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, name := range names {
+			keys[i] = KeyInfo{Key: name, Type: types[i].Val(), TTL: redisTTL(ttls[i].Val())}
+			sizes[i] = redisSize(ctx, pipe, keys[i].Type, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		if sizes[i] != nil {
+			keys[i].Size = sizes[i].Val()
+		}
+	}
+
+	return &KeyScanResult{Keys: keys, Cursor: cursor}, nil
+}
+
+// GetKey returns a key's value, up to 1000 elements of a collection or 1 MiB of a string
+func (r *RedisProvider) GetKey(ctx context.Context, database, key string) (*KeyValue, error) {
+	client, err := r.client(database)
+	if err != nil {
+		return nil, err
+	}
+
+	keyType, err := client.Type(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if keyType == "none" {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+	}
+	ttl, err := client.TTL(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	value := &KeyValue{KeyInfo: KeyInfo{Key: key, Type: keyType, TTL: redisTTL(ttl)}}
+	if size := redisSize(ctx, client, keyType, key); size != nil {
+		if value.Size, err = size.Result(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch keyType {
+	case "string":
+		value.Value, err = client.GetRange(ctx, key, 0, redisStringLimit-1).Result()
+		value.Truncated = value.Size > redisStringLimit
+	case "hash":
+		var fields []string
+		fields, err = redisScanAll(func(cursor uint64) ([]string, uint64, error) {
+			return client.HScan(ctx, key, cursor, "", redisScanCount).Result()
+		}, 2*redisValueLimit)
+		hash := make(map[string]string, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			hash[fields[i]] = fields[i+1]
+		}
+		value.Value = hash
+		value.Truncated = int64(len(hash)) < value.Size
+	case "list":
+		value.Value, err = client.LRange(ctx, key, 0, redisValueLimit-1).Result()
+		value.Truncated = value.Size > redisValueLimit
+	case "set":
+		var members []string
+		members, err = redisScanAll(func(cursor uint64) ([]string, uint64, error) {
+			return client.SScan(ctx, key, cursor, "", redisScanCount).Result()
+		}, redisValueLimit)
+		sort.Strings(members)
+		value.Value = members
+		value.Truncated = int64(len(members)) < value.Size
+	case "zset":
+		var scored []redis.Z
+		scored, err = client.ZRangeWithScores(ctx, key, 0, redisValueLimit-1).Result()
+		members := make([]ScoredMember, len(scored))
+		for i, z := range scored {
+			members[i] = ScoredMember{Member: fmt.Sprint(z.Member), Score: z.Score}
+		}
+		value.Value = members
+		value.Truncated = value.Size > redisValueLimit
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// DeleteKeys deletes keys and returns how many existed
+func (r *RedisProvider) DeleteKeys(ctx context.Context, database string, keys []string) (int64, error) {
+	if r.config.ReadOnly {
+		return 0, ErrReadOnlyConnection
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	client, err := r.client(database)
+	if err != nil {
+		return 0, err
+	}
+	return client.Del(ctx, keys...).Result()
+}
+
+// FlushDatabase deletes every key of a database
+func (r *RedisProvider) FlushDatabase(ctx context.Context, database string) error {
+	if r.config.ReadOnly {
+		return ErrReadOnlyConnection
+	}
+	client, err := r.client(database)
+	if err != nil {
+		return err
+	}
+	return client.FlushDB(ctx).Err()
+}
+
+// GetStats returns server statistics from INFO: connected clients, operations per second,
+// the keyspace hit rate as the cache hit ratio, and memory use against maxmemory
+func (r *RedisProvider) GetStats(ctx context.Context) (*DatabaseStats, error) {
+	client, err := r.client("")
+	if err != nil {
+		return nil, err
+	}
+	text, err := client.Info(ctx, "clients", "stats", "memory").Result()
+	if err != nil {
+		return nil, err
+	}
+	info := parseRedisInfo(text)
+	number := func(key string) int64 {
+		n, _ := strconv.ParseInt(info[key], 10, 64)
+		return n
+	}
+
+	stats := &DatabaseStats{}
+	clients := int(number("connected_clients"))
+	blocked := int(number("blocked_clients"))
+	stats.Connections = ConnectionStats{
+		Active:  clients - blocked,
+		Idle:    blocked,
+		Total:   clients,
+		MaxConn: int(number("maxclients")),
+	}
+
+	stats.Performance.QueriesPerSecond, _ = strconv.ParseFloat(info["instantaneous_ops_per_sec"], 64)
+	hits, misses := number("keyspace_hits"), number("keyspace_misses")
+	if hits+misses > 0 {
+		stats.Performance.CacheHitRatio = float64(hits) / float64(hits+misses) * 100
+	}
+
+	used := number("used_memory")
+	total := number("maxmemory")
+	if total == 0 {
+		total = number("total_system_memory")
+	}
+	stats.Storage = StorageStats{UsedSize: used, TotalSize: total}
+	if total > used {
+		stats.Storage.FreeSize = total - used
+	}
+
+	return stats, nil
+}
+
+// PoolStats returns the usage of the connection pools of the databases in use
+func (r *RedisProvider) PoolStats() PoolStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := PoolStats{}
+	for _, client := range r.clients {
+		pool := client.PoolStats()
+		stats.MaxOpen += client.Options().PoolSize
+		stats.Open += int(pool.TotalConns)
+		stats.Idle += int(pool.IdleConns)
+		stats.WaitCount += int64(pool.Timeouts)
+		stats.IdleClosed += int64(pool.StaleConns)
+	}
+	stats.InUse = max(stats.Open-stats.Idle, 0)
+	return stats
+}
+
+// IsConnected returns whether the provider is currently connected
+func (r *RedisProvider) IsConnected() bool {
+	client, err := r.client("")
+	if err != nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	return client.Ping(ctx).Err() == nil
+}
+
+// GetConnectionInfo returns current connection information
+func (r *RedisProvider) GetConnectionInfo() *DatabaseConfig {
+	return &r.config
+}
+
+// client returns the client of a numbered database, defaulting to the connection's. Each
+// database has its own pool, as SELECT applies to a whole connection.
+func (r *RedisProvider) client(database string) (*redis.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.clients == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+	db, err := redisDB(database, r.db)
+	if err != nil {
+		return nil, err
+	}
+	client, ok := r.clients[db]
+	if !ok {
+		client = redis.NewClient(redisOptions(r.config, db))
+		r.clients[db] = client
+	}
+	return client, nil
+}
+
+func redisOptions(config DatabaseConfig, db int) *redis.Options {
+	host := config.Host
+	if host == "" {
+		host = "localhost"
+	}
+	port := config.Port
+	if port == 0 {
+		port = 6379
+	}
+	pool := poolSettings(config)
+	options := &redis.Options{
+		Addr:            net.JoinHostPort(host, strconv.Itoa(port)),
+		Username:        config.Username,
+		Password:        config.Password,
+		DB:              db,
+		PoolSize:        pool.MaxOpen,
+		MaxIdleConns:    pool.MaxIdle,
+		ConnMaxIdleTime: pool.idleTimeout(),
+		ConnMaxLifetime: pool.maxLifetime(),
+	}
+	switch config.SSLMode {
+	case "", "disable":
+	case "verify-ca", "verify-full":
+		options.TLSConfig = &tls.Config{ServerName: host}
+	default: // require, prefer
+		options.TLSConfig = &tls.Config{ServerName: host, InsecureSkipVerify: true}
+	}
+	return options
+}
+
+// redisDB parses a database name such as db3 or 3, using fallback for an empty name
+func redisDB(name string, fallback int) (int, error) {
+	if name == "" {
+		return fallback, nil
+	}
+	db, err := strconv.Atoi(strings.TrimPrefix(name, "db"))
+	if err != nil || db < 0 {
+		return 0, fmt.Errorf("invalid redis database %q", name)
+	}
+	return db, nil
+}
+
+func redisDBName(db int) string {
+	return "db" + strconv.Itoa(db)
+}
+
+// parseRedisInfo reads the key:value lines of INFO, skipping section headers
+func parseRedisInfo(info string) map[string]string {
+	values := map[string]string{}
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// redisArgs splits a command line into arguments, like redis-cli: double-quoted arguments
+// take backslash escapes and single-quoted ones are literal
+func redisArgs(line string) []interface{} {
+	var args []interface{}
+	var arg strings.Builder
+	var quote byte
+	inArg := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\' && i+1 < len(line):
+			i++
+			switch line[i] {
+			case 'n':
+				arg.WriteByte('\n')
+			case 't':
+				arg.WriteByte('\t')
+			case 'r':
+				arg.WriteByte('\r')
+			default:
+				arg.WriteByte(line[i])
+			}
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteByte(c)
+		case c == '"' || c == '\'':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args
+}
+
+// redisValue converts a reply for JSON, keying RESP3 maps by string
+func redisValue(reply interface{}) interface{} {
+	switch v := reply.(type) {
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, value := range v {
+			values[i] = redisValue(value)
+		}
+		return values
+	case map[interface{}]interface{}:
+		values := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			values[fmt.Sprint(key)] = redisValue(value)
+		}
+		return values
+	default:
+		return v
+	}
+}
+
+// redisResult tabulates a reply: arrays one element per row, maps one field per row in
+// field order, and anything else as a single value
+func redisResult(reply interface{}, limit, offset int) *QueryResult {
+	result := &QueryResult{}
+	switch v := reply.(type) {
+	case []interface{}:
+		result.Columns = []string{"#", "value"}
+		for i, value := range v {
+			result.Rows = append(result.Rows, []interface{}{i + 1, value})
+		}
+	case map[string]interface{}:
+		result.Columns = []string{"field", "value"}
+		fields := make([]string, 0, len(v))
+		for field := range v {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			result.Rows = append(result.Rows, []interface{}{field, v[field]})
+		}
+	default:
+		result.Columns = []string{"value"}
+		result.Rows = [][]interface{}{{v}}
+	}
+
+	if offset > 0 {
+		result.Rows = result.Rows[min(offset, len(result.Rows)):]
+	}
+	if limit > 0 && len(result.Rows) > limit {
+		result.Rows = result.Rows[:limit]
+	}
+	if result.Rows == nil {
+		result.Rows = [][]interface{}{}
+	}
+	result.RowCount = len(result.Rows)
+	return result
+}
+
+// redisSize queues the command returning the length of a key of the given type
+func redisSize(ctx context.Context, client redis.Cmdable, keyType, key string) *redis.IntCmd {
+	switch keyType {
+	case "string":
+		return client.StrLen(ctx, key)
+	case "hash":
+		return client.HLen(ctx, key)
+	case "list":
+		return client.LLen(ctx, key)
+	case "set":
+		return client.SCard(ctx, key)
+	case "zset":
+		return client.ZCard(ctx, key)
+	case "stream":
+		return client.XLen(ctx, key)
+	}
+	return nil
+}
+
+// redisScanAll runs a cursor scan until it completes or returns at least limit elements
+func redisScanAll(scan func(cursor uint64) ([]string, uint64, error), limit int) ([]string, error) {
+	var elements []string
+	var cursor uint64
+	for {
+		page, next, err := scan(cursor)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, page...)
+		if next == 0 || len(elements) >= limit {
+			return elements, nil
+		}
+		cursor = next
+	}
+}
+
+// redisTTL converts a TTL reply to seconds, keeping -1 for keys without expiry and -2 for
+// missing keys
+func redisTTL(ttl time.Duration) int64 {
+	if ttl < 0 {
+		return int64(ttl)
+	}
+	return int64(ttl / time.Second)
+}
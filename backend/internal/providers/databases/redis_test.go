@@ -0,0 +1,87 @@
+package databases
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRedisArgs(t *testing.T) {
+	tests := map[string][]interface{}{
+		`GET user:1`:                     {"GET", "user:1"},
+		`  HSET  user:1 name "Ada L."  `: {"HSET", "user:1", "name", "Ada L."},
+		`SET note "line\nbreak \"q\""`:   {"SET", "note", "line\nbreak \"q\""},
+		`SET raw 'back\slash'`:           {"SET", "raw", `back\slash`},
+		`SET empty ""`:                   {"SET", "empty", ""},
+		``:                               nil,
+	}
+	for line, want := range tests {
+		if got := redisArgs(line); !reflect.DeepEqual(got, want) {
+			t.Errorf("redisArgs(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestRedisInfoAndResults(t *testing.T) {
+	info := parseRedisInfo("# Keyspace\r\ndb0:keys=12,expires=3,avg_ttl=0\r\ndb4:keys=1,expires=0,avg_ttl=0\r\n")
+	if info["db0"] != "keys=12,expires=3,avg_ttl=0" || len(info) != 2 {
+		t.Errorf("unexpected info %v", info)
+	}
+
+	for name, want := range map[string]int{"": 2, "db4": 4, "7": 7} {
+		if db, err := redisDB(name, 2); err != nil || db != want {
+			t.Errorf("redisDB(%q) = %d, %v, want %d", name, db, err, want)
+		}
+	}
+	if _, err := redisDB("cache", 0); err == nil {
+		t.Error("expected an error for a non-numeric database")
+	}
+
+	// RESP3 maps are keyed by string and listed one field per row
+	hash := redisResult(redisValue(map[interface{}]interface{}{"name": "Ada", "age": int64(36)}), 0, 0)
+	if !reflect.DeepEqual(hash.Columns, []string{"field", "value"}) || hash.RowCount != 2 || hash.Rows[0][0] != "age" {
+		t.Errorf("unexpected hash result %+v", hash)
+	}
+	list := redisResult(redisValue([]interface{}{"a", "b", "c"}), 1, 1)
+	if list.RowCount != 1 || list.Rows[0][0] != 2 || list.Rows[0][1] != "b" {
+		t.Errorf("unexpected list result %+v", list)
+	}
+	missing := redisResult(redisValue(nil), 0, 0)
+	if missing.RowCount != 1 || missing.Rows[0][0] != nil {
+		t.Errorf("unexpected nil result %+v", missing)
+	}
+}
+
+func TestRedisCommandAccess(t *testing.T) {
+	r := &RedisProvider{readOnly: map[string]bool{
+		"get": true, "scan": true, "eval_ro": true, "fcall_ro": true,
+		"set": false, "del": false, "flushall": false, "eval": false, "getdel": false,
+		"expire": false, "swapdb": false, "move": false, "rename": false,
+	}}
+
+	// Non-admins only run read-only commands, and no scripts
+	for _, name := range []string{"set", "del", "flushall", "eval", "eval_ro", "fcall_ro", "getdel", "expire", "swapdb", "move", "rename", "unknown"} {
+		if err := r.checkCommand(name, false); !errors.Is(err, ErrAdminCommand) {
+			t.Errorf("checkCommand(%s) as non-admin = %v, want ErrAdminCommand", name, err)
+		}
+	}
+	for _, name := range []string{"get", "scan"} {
+		if err := r.checkCommand(name, false); err != nil {
+			t.Errorf("checkCommand(%s) as non-admin = %v", name, err)
+		}
+	}
+	for _, name := range []string{"set", "flushall", "eval"} {
+		if err := r.checkCommand(name, true); err != nil {
+			t.Errorf("checkCommand(%s) as admin = %v", name, err)
+		}
+	}
+
+	// Read-only connections refuse writes to admins too
+	r.config.ReadOnly = true
+	if err := r.checkCommand("set", true); !errors.Is(err, ErrReadOnlyConnection) {
+		t.Errorf("checkCommand(set) on a read-only connection = %v, want ErrReadOnlyConnection", err)
+	}
+	if err := r.checkCommand("get", true); err != nil {
+		t.Errorf("checkCommand(get) on a read-only connection = %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package databases
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Relationship cardinalities
+const (
+	CardinalityOneToOne  = "one-to-one"
+	CardinalityManyToOne = "many-to-one"
+)
+
+// BuildDiagram collects the tables, columns and relationships of a database for an ER diagram
+func BuildDiagram(ctx context.Context, provider DatabaseProvider, database string) (*SchemaDiagram, error) {
+	tables, err := provider.GetTables(ctx, database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	relationships, err := provider.GetRelationships(ctx, database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relationships: %w", err)
+	}
+
+	diagram := &SchemaDiagram{Database: database, Tables: []DiagramTable{}, Relationships: relationships}
+	for _, table := range tables {
+		columns, err := provider.GetColumns(ctx, database, table.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get columns of %s: %w", table.Name, err)
+		}
+		if columns == nil {
+			columns = []ColumnInfo{}
+		}
+		diagram.Tables = append(diagram.Tables, DiagramTable{
+			Name:    table.Name,
+			Schema:  table.Schema,
+			Type:    table.Type,
+			Columns: columns,
+		})
+	}
+	return diagram, nil
+}
+
+// cardinality is one-to-one when a unique, non-partial index covers exactly the columns
+func cardinality(columns []string, indexes []IndexInfo) string {
+	for _, index := range indexes {
+		if (index.Unique || index.Primary) && !index.Partial && sameColumns(index.Columns, columns) {
+			return CardinalityOneToOne
+		}
+	}
+	return CardinalityManyToOne
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkConstraints extracts the CHECK constraints from a CREATE TABLE statement
+func checkConstraints(createSQL string) []ConstraintInfo {
+	checks := []ConstraintInfo{}
+	upper := strings.ToUpper(createSQL)
+	for i := 0; i < len(createSQL); {
+		at := strings.Index(upper[i:], "CHECK")
+		if at < 0 {
+			break
+		}
+		start := i + at
+		i = start + len("CHECK")
+		if start > 0 && isIdentifierChar(upper[start-1]) || i < len(upper) && isIdentifierChar(upper[i]) {
+			continue // part of a longer identifier
+		}
+		open := strings.IndexByte(createSQL[i:], '(')
+		if open < 0 || strings.TrimSpace(createSQL[i:i+open]) != "" {
+			continue
+		}
+		end := matchingParen(createSQL, i+open)
+		if end < 0 {
+			break
+		}
+		checks = append(checks, ConstraintInfo{
+			Name:       constraintName(createSQL[:start]),
+			Type:       ConstraintCheck,
+			Definition: "CHECK " + createSQL[i+open:end+1],
+		})
+		i = end + 1
+	}
+	return checks
+}
+
+// constraintName returns the name of a CONSTRAINT clause ending where the text does
+func constraintName(before string) string {
+	fields := strings.Fields(before)
+	if len(fields) >= 2 && strings.EqualFold(fields[len(fields)-2], "CONSTRAINT") {
+		return strings.Trim(fields[len(fields)-1], "\"`[]")
+	}
+	return ""
+}
+
+// matchingParen returns the index of the parenthesis closing the one at open, skipping
+// quoted strings and identifiers
+func matchingParen(text string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
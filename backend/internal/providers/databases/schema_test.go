@@ -0,0 +1,93 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSQLiteSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shop.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE);
+		CREATE TABLE profiles (
+			customer_id INTEGER PRIMARY KEY REFERENCES customers ON DELETE CASCADE,
+			bio TEXT
+		);
+		CREATE TABLE orders (
+			id INTEGER PRIMARY KEY,
+			customer_id INTEGER NOT NULL,
+			total REAL,
+			CONSTRAINT positive_total CHECK (total >= 0),
+			FOREIGN KEY (customer_id) REFERENCES customers(id)
+		);
+		CREATE INDEX orders_customer ON orders (customer_id);
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := NewSQLiteProvider()
+	ctx := context.Background()
+	if err := provider.Connect(ctx, DatabaseConfig{FilePath: path}); err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Disconnect(ctx)
+
+	schema, err := provider.GetTableSchema(ctx, "main", "orders")
+	if err != nil {
+		t.Fatalf("GetTableSchema failed: %v", err)
+	}
+	if len(schema.Indexes) != 2 || !schema.Indexes[0].Primary || schema.Indexes[1].Name != "orders_customer" {
+		t.Errorf("unexpected indexes %+v", schema.Indexes)
+	}
+	if len(schema.ForeignKeys) != 1 || schema.ForeignKeys[0].ReferencedTable != "customers" || schema.ForeignKeys[0].ReferencedColumns[0] != "id" {
+		t.Errorf("unexpected foreign keys %+v", schema.ForeignKeys)
+	}
+	var check *ConstraintInfo
+	for i, c := range schema.Constraints {
+		if c.Type == ConstraintCheck {
+			check = &schema.Constraints[i]
+		}
+	}
+	if check == nil || check.Name != "positive_total" || check.Definition != "CHECK (total >= 0)" {
+		t.Errorf("unexpected check constraint %+v", check)
+	}
+
+	customers, err := provider.GetTableSchema(ctx, "main", "customers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var unique bool
+	for _, c := range customers.Constraints {
+		unique = unique || (c.Type == ConstraintUnique && strings.Join(c.Columns, ",") == "email")
+	}
+	if !unique {
+		t.Errorf("expected a unique constraint on email, got %+v", customers.Constraints)
+	}
+
+	diagram, err := BuildDiagram(ctx, provider, "main")
+	if err != nil {
+		t.Fatalf("BuildDiagram failed: %v", err)
+	}
+	if len(diagram.Tables) != 3 || len(diagram.Relationships) != 2 {
+		t.Fatalf("unexpected diagram %+v", diagram)
+	}
+	cardinalities := map[string]string{}
+	for _, r := range diagram.Relationships {
+		cardinalities[r.FromTable+"->"+r.ToTable+"("+strings.Join(r.ToColumns, ",")+")"] = r.Cardinality
+	}
+	// The profile's key is also its foreign key, so each customer has at most one profile
+	if cardinalities["profiles->customers(id)"] != CardinalityOneToOne || cardinalities["orders->customers(id)"] != CardinalityManyToOne {
+		t.Errorf("unexpected relationships %v", cardinalities)
+	}
+}
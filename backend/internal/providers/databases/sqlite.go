@@ -40,7 +40,11 @@ func (s *SQLiteProvider) Connect(ctx context.Context, config DatabaseConfig) err
 		return fmt.Errorf("SQLite database file does not exist: %s", config.FilePath)
 	}
 
-	db, err := sql.Open("sqlite3", config.FilePath)
+	dsn := config.FilePath
+	if config.ReadOnly {
+		dsn = "file:" + config.FilePath + "?mode=ro"
+	}
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return fmt.Errorf("failed to open SQLite database: %w", err)
 	}
@@ -193,14 +197,17 @@ func (s *SQLiteProvider) GetTables(ctx context.Context, database string) ([]Tabl
 		table.Schema = "main"
 		table.Type = tableType
 
-		// Get row count for each table
-		countQuery := fmt.Sprintf("SELECT count(*) FROM [%s]", table.Name)
-		s.db.QueryRowContext(ctx, countQuery).Scan(&table.RowCount)
+		tables = append(tables, table)
+	}
+	rows.Close()
 
-		// SQLite doesn't have easy way to get table size, approximate it
-		table.Size = table.RowCount * 100 // Rough estimate
+	// Count rows once the list is read; the single connection can't run both queries
+	for i := range tables {
+		countQuery := fmt.Sprintf("SELECT count(*) FROM [%s]", tables[i].Name)
+		s.db.QueryRowContext(ctx, countQuery).Scan(&tables[i].RowCount)
 
-		tables = append(tables, table)
+		// SQLite doesn't have easy way to get table size, approximate it
+		tables[i].Size = tables[i].RowCount * 100 // Rough estimate
 	}
 
 	return tables, nil
@@ -239,37 +246,270 @@ func (s *SQLiteProvider) GetColumns(ctx context.Context, database, table string)
 			col.Default = defaultVal.String
 		}
 
-		// Check for unique constraints
-		uniqueQuery := `
-			SELECT count(*) 
-			FROM sqlite_master 
-			WHERE type='index' 
-				AND tbl_name=? 
-				AND sql LIKE '%UNIQUE%' 
-				AND sql LIKE ?
-		`
-		var uniqueCount int
-		s.db.QueryRowContext(ctx, uniqueQuery, table, "%"+col.Name+"%").Scan(&uniqueCount)
-		col.IsUnique = uniqueCount > 0
-
-		// Check for any index on this column
-		indexQuery := `
-			SELECT count(*) 
-			FROM sqlite_master 
-			WHERE type='index' 
-				AND tbl_name=? 
-				AND sql LIKE ?
-		`
-		var indexCount int
-		s.db.QueryRowContext(ctx, indexQuery, table, "%"+col.Name+"%").Scan(&indexCount)
-		col.IsIndex = indexCount > 0
-
 		columns = append(columns, col)
 	}
+	rows.Close()
+
+	// Flag indexed and unique columns once the columns are read; the single connection
+	// can't run both queries
+	indexes, err := s.indexes(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	for i := range columns {
+		for _, index := range indexes {
+			for _, column := range index.Columns {
+				if column != columns[i].Name {
+					continue
+				}
+				columns[i].IsIndex = true
+				columns[i].IsUnique = columns[i].IsUnique || (index.Unique && !index.Primary)
+			}
+		}
+	}
 
 	return columns, nil
 }
 
+// GetTableSchema returns the indexes, foreign keys and constraints of a table
+func (s *SQLiteProvider) GetTableSchema(ctx context.Context, database, table string) (*TableSchema, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+
+	var createSQL sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT sql FROM sqlite_master WHERE type IN ('table', 'view') AND name = ?`, table).Scan(&createSQL)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("table not found: %s", table)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &TableSchema{Table: table, Schema: "main", Constraints: []ConstraintInfo{}}
+	if schema.Indexes, err = s.indexes(ctx, table); err != nil {
+		return nil, err
+	}
+	if schema.ForeignKeys, err = s.foreignKeys(ctx, table); err != nil {
+		return nil, err
+	}
+
+	for _, index := range schema.Indexes {
+		switch {
+		case index.Primary:
+			schema.Constraints = append(schema.Constraints, ConstraintInfo{Type: ConstraintPrimaryKey, Columns: index.Columns})
+		case index.Unique && index.Definition == "":
+			// Auto-indexes back UNIQUE constraints; CREATE UNIQUE INDEX is an index only
+			schema.Constraints = append(schema.Constraints, ConstraintInfo{Type: ConstraintUnique, Columns: index.Columns})
+		}
+	}
+	for _, fk := range schema.ForeignKeys {
+		schema.Constraints = append(schema.Constraints, ConstraintInfo{
+			Name:    fk.Name,
+			Type:    ConstraintForeignKey,
+			Columns: fk.Columns,
+			Definition: fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(%s) ON UPDATE %s ON DELETE %s",
+				strings.Join(fk.Columns, ", "), fk.ReferencedTable, strings.Join(fk.ReferencedColumns, ", "), fk.OnUpdate, fk.OnDelete),
+		})
+	}
+	schema.Constraints = append(schema.Constraints, checkConstraints(createSQL.String)...)
+	return schema, nil
+}
+
+// indexes lists a table's indexes. Rowid tables with an INTEGER PRIMARY KEY have no index
+// for it, so the primary key is reported from the table's columns instead.
+func (s *SQLiteProvider) indexes(ctx context.Context, table string) ([]IndexInfo, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("PRAGMA index_list([%s])", table))
+	if err != nil {
+		return nil, err
+	}
+	indexes := []IndexInfo{}
+	for rows.Next() {
+		var seq int
+		var index IndexInfo
+		var unique, partial int
+		var origin string
+		if err := rows.Scan(&seq, &index.Name, &unique, &origin, &partial); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index.Unique = unique == 1
+		index.Primary = origin == "pk"
+		index.Partial = partial == 1
+		index.Method = "btree"
+		indexes = append(indexes, index)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The connection is single, so each index is described after the list is read
+	hasPrimary := false
+	for i := range indexes {
+		index := &indexes[i]
+		hasPrimary = hasPrimary || index.Primary
+		var definition sql.NullString
+		s.db.QueryRowContext(ctx, `SELECT sql FROM sqlite_master WHERE type = 'index' AND name = ?`, index.Name).Scan(&definition)
+		index.Definition = definition.String
+		if index.Columns, err = s.indexColumns(ctx, index.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	if !hasPrimary {
+		primary, err := s.primaryKey(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		if len(primary) > 0 {
+			indexes = append([]IndexInfo{{Name: "PRIMARY", Columns: primary, Unique: true, Primary: true, Method: "rowid"}}, indexes...)
+		}
+	}
+	return indexes, nil
+}
+
+func (s *SQLiteProvider) indexColumns(ctx context.Context, index string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("PRAGMA index_info([%s])", index))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := []string{}
+	for rows.Next() {
+		var seqno, cid int
+		var name sql.NullString
+		if err := rows.Scan(&seqno, &cid, &name); err != nil {
+			return nil, err
+		}
+		if !name.Valid {
+			name.String = "<expression>"
+		}
+		columns = append(columns, name.String)
+	}
+	return columns, rows.Err()
+}
+
+// primaryKey returns a table's primary key columns in key order
+func (s *SQLiteProvider) primaryKey(ctx context.Context, table string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info([%s])", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	byPosition := map[int]string{}
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultVal sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &pk); err != nil {
+			return nil, err
+		}
+		if pk > 0 {
+			byPosition[pk] = name
+		}
+	}
+	columns := make([]string, 0, len(byPosition))
+	for i := 1; i <= len(byPosition); i++ {
+		columns = append(columns, byPosition[i])
+	}
+	return columns, rows.Err()
+}
+
+// foreignKeys lists a table's foreign keys. SQLite doesn't name them, so they are named
+// after the table and their position.
+func (s *SQLiteProvider) foreignKeys(ctx context.Context, table string) ([]ForeignKeyInfo, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("PRAGMA foreign_key_list([%s])", table))
+	if err != nil {
+		return nil, err
+	}
+	foreignKeys := []ForeignKeyInfo{}
+	byID := map[int]int{}
+	implicit := map[int]bool{} // references the parent's primary key without naming it
+	for rows.Next() {
+		var id, seq int
+		var parent, from, onUpdate, onDelete, match string
+		var to sql.NullString
+		if err := rows.Scan(&id, &seq, &parent, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		i, ok := byID[id]
+		if !ok {
+			i = len(foreignKeys)
+			byID[id] = i
+			foreignKeys = append(foreignKeys, ForeignKeyInfo{
+				Name:              fmt.Sprintf("fk_%s_%d", table, id),
+				Columns:           []string{},
+				ReferencedTable:   parent,
+				ReferencedColumns: []string{},
+				OnUpdate:          onUpdate,
+				OnDelete:          onDelete,
+			})
+		}
+		foreignKeys[i].Columns = append(foreignKeys[i].Columns, from)
+		if to.Valid {
+			foreignKeys[i].ReferencedColumns = append(foreignKeys[i].ReferencedColumns, to.String)
+		} else {
+			implicit[i] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range implicit {
+		primary, err := s.primaryKey(ctx, foreignKeys[i].ReferencedTable)
+		if err != nil {
+			return nil, err
+		}
+		foreignKeys[i].ReferencedColumns = primary
+	}
+	return foreignKeys, nil
+}
+
+// GetRelationships returns the foreign keys between the tables of the database
+func (s *SQLiteProvider) GetRelationships(ctx context.Context, database string) ([]Relationship, error) {
+	tables, err := s.GetTables(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	relationships := []Relationship{}
+	for _, table := range tables {
+		if table.Type != "table" {
+			continue
+		}
+		foreignKeys, err := s.foreignKeys(ctx, table.Name)
+		if err != nil {
+			return nil, err
+		}
+		if len(foreignKeys) == 0 {
+			continue
+		}
+		indexes, err := s.indexes(ctx, table.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, fk := range foreignKeys {
+			relationships = append(relationships, Relationship{
+				Name:        fk.Name,
+				FromSchema:  "main",
+				FromTable:   table.Name,
+				FromColumns: fk.Columns,
+				ToSchema:    "main",
+				ToTable:     fk.ReferencedTable,
+				ToColumns:   fk.ReferencedColumns,
+				Cardinality: cardinality(fk.Columns, indexes),
+				OnDelete:    fk.OnDelete,
+				OnUpdate:    fk.OnUpdate,
+			})
+		}
+	}
+	return relationships, nil
+}
+
 // ExecuteQuery executes a SQL query and returns results
 func (s *SQLiteProvider) ExecuteQuery(ctx context.Context, req QueryRequest) (*QueryResult, error) {
 	if s.db == nil {
@@ -373,6 +613,46 @@ func (s *SQLiteProvider) executeNonSelectQuery(ctx context.Context, query string
 	}, nil
 }
 
+// Explain returns the plan of a statement from EXPLAIN QUERY PLAN. SQLite has no
+// EXPLAIN ANALYZE, so only the chosen plan is reported.
+func (s *SQLiteProvider) Explain(ctx context.Context, req ExplainRequest) (*QueryPlan, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+	statement, err := singleStatement(req.SQL)
+	if err != nil {
+		return nil, err
+	}
+	if req.Analyze {
+		if !s.config.ReadOnly {
+			return nil, ErrAnalyzeNotAllowed
+		}
+		return nil, fmt.Errorf("%w: SQLite does not support EXPLAIN ANALYZE", ErrInvalidExplain)
+	}
+
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, "EXPLAIN QUERY PLAN "+statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var steps []sqlitePlanStep
+	for rows.Next() {
+		var step sqlitePlanStep
+		var notUsed int
+		if err := rows.Scan(&step.id, &step.parent, &notUsed, &step.detail); err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &QueryPlan{Plan: sqlitePlanTree(steps), Duration: time.Since(start)}, nil
+}
+
 // GetTableData returns paginated data from a table
 func (s *SQLiteProvider) GetTableData(ctx context.Context, req TableDataRequest) (*QueryResult, error) {
 	if s.db == nil {
@@ -493,6 +773,11 @@ func (s *SQLiteProvider) GetStats(ctx context.Context) (*DatabaseStats, error) {
 	return stats, nil
 }
 
+// PoolStats returns the usage of the connection pool
+func (s *SQLiteProvider) PoolStats() PoolStats {
+	return sqlPoolStats(s.db)
+}
+
 // IsConnected returns whether the provider is currently connected
 func (s *SQLiteProvider) IsConnected() bool {
 	if s.db == nil {
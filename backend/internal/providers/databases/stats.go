@@ -0,0 +1,103 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/archellir/denshimon/internal/websocket"
+)
+
+// publishedSlowQueries is how many of the slowest statements each stats update carries
+const publishedSlowQueries = 10
+
+// statsInterval is how often the statistics of connected databases are published
+const statsInterval = 15 * time.Second
+
+// StatsUpdate is published on the database stats WebSocket channel for each connected database
+type StatsUpdate struct {
+	ConnectionID string         `json:"connection_id"`
+	Name         string         `json:"name"`
+	Type         DatabaseType   `json:"type"`
+	Stats        *DatabaseStats `json:"stats"`
+	SlowQueries  []SlowQuery    `json:"slow_queries,omitempty"`
+	Timestamp    time.Time      `json:"timestamp"`
+}
+
+// SetHub enables publishing connected databases' statistics on the database stats WebSocket channel
+func (m *Manager) SetHub(hub *websocket.Hub) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.hub = hub
+}
+
+// StartStatsPublisher publishes the statistics and slow queries of the connected databases
+// until ctx is cancelled
+func (m *Manager) StartStatsPublisher(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(statsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.publishStats(ctx)
+			}
+		}
+	}()
+}
+
+func (m *Manager) publishStats(ctx context.Context) {
+	m.mutex.RLock()
+	hub := m.hub
+	ids := make([]string, 0, len(m.providers))
+	for id := range m.providers {
+		ids = append(ids, id)
+	}
+	m.mutex.RUnlock()
+	if hub == nil {
+		return
+	}
+
+	for _, id := range ids {
+		update, err := m.CollectStats(ctx, id)
+		if err != nil {
+			slog.Debug("Failed to collect database statistics", "connection", id, "error", err)
+			continue
+		}
+		hub.Broadcast(websocket.MessageTypeDatabaseStats, update)
+	}
+}
+
+// CollectStats reads the statistics of a connected database, with its slow queries when
+// the provider tracks them
+func (m *Manager) CollectStats(ctx context.Context, id string) (*StatsUpdate, error) {
+	provider, err := m.GetProvider(id)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	stats, err := m.Stats(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	config := provider.GetConnectionInfo()
+	update := &StatsUpdate{
+		ConnectionID: id,
+		Name:         config.Name,
+		Type:         config.Type,
+		Stats:        stats,
+		Timestamp:    time.Now(),
+	}
+	if log, ok := provider.(SlowQueryLog); ok {
+		update.SlowQueries, err = log.SlowQueries(ctx, SlowQueryThreshold, publishedSlowQueries)
+		if err != nil && !errors.Is(err, ErrSlowQueriesUnavailable) {
+			slog.Debug("Failed to read slow queries", "connection", id, "error", err)
+		}
+	}
+	return update, nil
+}
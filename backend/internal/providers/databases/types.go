@@ -10,6 +10,9 @@ type DatabaseType string
 const (
 	DatabaseTypePostgreSQL DatabaseType = "postgresql"
 	DatabaseTypeSQLite     DatabaseType = "sqlite"
+	DatabaseTypeMySQL      DatabaseType = "mysql" // MySQL and MariaDB
+	DatabaseTypeMongoDB    DatabaseType = "mongodb"
+	DatabaseTypeRedis      DatabaseType = "redis"
 )
 
 // DatabaseStatus represents the connection status
@@ -34,6 +37,8 @@ type DatabaseConfig struct {
 	Password   string                 `json:"password,omitempty"`
 	SSLMode    string                 `json:"ssl_mode,omitempty"`
 	FilePath   string                 `json:"file_path,omitempty"`
+	ReadOnly   bool                   `json:"read_only,omitempty"` // session refuses writes; allows EXPLAIN ANALYZE
+	Pool       *PoolConfig            `json:"pool,omitempty"`      // connection pool settings; unset values use the defaults
 	Extra      map[string]interface{} `json:"extra,omitempty"`
 	Status     DatabaseStatus         `json:"status"`
 	LastTested *time.Time             `json:"last_tested,omitempty"`
@@ -82,6 +87,85 @@ type ColumnInfo struct {
 	Comment      string      `json:"comment,omitempty"`
 }
 
+// Constraint types reported in ConstraintInfo
+const (
+	ConstraintPrimaryKey = "PRIMARY KEY"
+	ConstraintUnique     = "UNIQUE"
+	ConstraintForeignKey = "FOREIGN KEY"
+	ConstraintCheck      = "CHECK"
+	ConstraintExclude    = "EXCLUDE"
+)
+
+// IndexInfo represents an index of a table
+type IndexInfo struct {
+	Name string `json:"name"`
+	// Columns are the indexed columns in order; expressions are given as written
+	Columns    []string `json:"columns"`
+	Unique     bool     `json:"unique"`
+	Primary    bool     `json:"primary"`
+	Partial    bool     `json:"partial"`
+	Method     string   `json:"method,omitempty"` // btree, hash, gin, ...
+	Definition string   `json:"definition,omitempty"`
+}
+
+// ForeignKeyInfo represents a foreign key from a table's columns to another table
+type ForeignKeyInfo struct {
+	Name              string   `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedSchema  string   `json:"referenced_schema,omitempty"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnUpdate          string   `json:"on_update"`
+	OnDelete          string   `json:"on_delete"`
+}
+
+// ConstraintInfo represents a table constraint
+type ConstraintInfo struct {
+	Name       string   `json:"name,omitempty"`
+	Type       string   `json:"type"`
+	Columns    []string `json:"columns,omitempty"`
+	Definition string   `json:"definition,omitempty"`
+}
+
+// TableSchema is the index, foreign key and constraint metadata of a table
+type TableSchema struct {
+	Table       string           `json:"table"`
+	Schema      string           `json:"schema,omitempty"`
+	Indexes     []IndexInfo      `json:"indexes"`
+	ForeignKeys []ForeignKeyInfo `json:"foreign_keys"`
+	Constraints []ConstraintInfo `json:"constraints"`
+}
+
+// Relationship is a foreign key between two tables, an edge of an ER diagram
+type Relationship struct {
+	Name        string   `json:"name"`
+	FromSchema  string   `json:"from_schema,omitempty"`
+	FromTable   string   `json:"from_table"`
+	FromColumns []string `json:"from_columns"`
+	ToSchema    string   `json:"to_schema,omitempty"`
+	ToTable     string   `json:"to_table"`
+	ToColumns   []string `json:"to_columns"`
+	// Cardinality is one-to-one when the foreign key columns are unique, else many-to-one
+	Cardinality string `json:"cardinality"`
+	OnDelete    string `json:"on_delete"`
+	OnUpdate    string `json:"on_update"`
+}
+
+// DiagramTable is a table node of an ER diagram
+type DiagramTable struct {
+	Name    string       `json:"name"`
+	Schema  string       `json:"schema,omitempty"`
+	Type    string       `json:"type"`
+	Columns []ColumnInfo `json:"columns"`
+}
+
+// SchemaDiagram holds the tables and relationships to render an ER diagram
+type SchemaDiagram struct {
+	Database      string         `json:"database"`
+	Tables        []DiagramTable `json:"tables"`
+	Relationships []Relationship `json:"relationships"`
+}
+
 // QueryResult represents the result of a SQL query
 type QueryResult struct {
 	Columns      []string        `json:"columns"`
@@ -97,6 +181,8 @@ type QueryRequest struct {
 	SQL    string `json:"sql"`
 	Limit  int    `json:"limit,omitempty"`
 	Offset int    `json:"offset,omitempty"`
+	// Admin is set for queries run by an admin, who may run any Redis command
+	Admin bool `json:"-"`
 }
 
 // TableDataRequest represents a request for table data
@@ -131,11 +217,130 @@ type RowInsertRequest struct {
 	Values map[string]interface{} `json:"values"`
 }
 
+// ExplainRequest asks for the execution plan of a single statement
+type ExplainRequest struct {
+	SQL string `json:"sql"`
+	// Analyze runs the statement to report actual timings. Only allowed on
+	// read-only connections, and always rolled back.
+	Analyze bool `json:"analyze,omitempty"`
+}
+
+// PlanNode is a step of an execution plan
+type PlanNode struct {
+	NodeType          string                 `json:"node_type"`
+	Relation          string                 `json:"relation,omitempty"`
+	Index             string                 `json:"index,omitempty"`
+	Detail            string                 `json:"detail,omitempty"`
+	StartupCost       float64                `json:"startup_cost,omitempty"`
+	TotalCost         float64                `json:"total_cost,omitempty"`
+	PlanRows          float64                `json:"plan_rows,omitempty"`
+	PlanWidth         int                    `json:"plan_width,omitempty"`
+	ActualStartupTime *float64               `json:"actual_startup_time,omitempty"` // milliseconds, ANALYZE only
+	ActualTotalTime   *float64               `json:"actual_total_time,omitempty"`
+	ActualRows        *float64               `json:"actual_rows,omitempty"`
+	ActualLoops       *float64               `json:"actual_loops,omitempty"`
+	Filter            string                 `json:"filter,omitempty"`
+	Properties        map[string]interface{} `json:"properties,omitempty"` // remaining provider specific fields
+	Children          []PlanNode             `json:"children,omitempty"`
+}
+
+// QueryPlan is the execution plan of a statement
+type QueryPlan struct {
+	Plan          PlanNode      `json:"plan"`
+	Analyzed      bool          `json:"analyzed"`
+	PlanningTime  *float64      `json:"planning_time,omitempty"` // milliseconds
+	ExecutionTime *float64      `json:"execution_time,omitempty"`
+	Duration      time.Duration `json:"duration"`
+}
+
+// SlowQuery is a normalized statement whose mean execution time is over the slow query threshold
+type SlowQuery struct {
+	QueryID   string  `json:"query_id,omitempty"`
+	Query     string  `json:"query"`
+	Calls     int64   `json:"calls"`
+	TotalTime float64 `json:"total_time"` // milliseconds
+	MeanTime  float64 `json:"mean_time"`
+	MaxTime   float64 `json:"max_time"`
+	Rows      int64   `json:"rows"`
+}
+
+// KeyScanRequest filters a scan of a key-value database's keys
+type KeyScanRequest struct {
+	Pattern string `json:"pattern,omitempty"` // glob, e.g. session:*
+	Type    string `json:"type,omitempty"`    // string, hash, list, set or zset
+	Cursor  uint64 `json:"cursor"`
+	Count   int64  `json:"count,omitempty"`
+}
+
+// KeyInfo is a key with its type and time to live
+type KeyInfo struct {
+	Key  string `json:"key"`
+	Type string `json:"type"`
+	TTL  int64  `json:"ttl"`  // seconds, -1 when the key doesn't expire
+	Size int64  `json:"size"` // bytes of a string, elements of a collection
+}
+
+// KeyScanResult is a page of a key scan; the scan is complete when Cursor is 0
+type KeyScanResult struct {
+	Keys   []KeyInfo `json:"keys"`
+	Cursor uint64    `json:"cursor"`
+}
+
+// KeyValue is a key's value, shaped by its type: a string, a field map for hashes,
+// a member list for lists and sets, and scored members for sorted sets
+type KeyValue struct {
+	KeyInfo
+	Value     interface{} `json:"value"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// ScoredMember is a member of a sorted set
+type ScoredMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// KeyDeleteRequest lists the keys to delete
+type KeyDeleteRequest struct {
+	Keys []string `json:"keys"`
+}
+
 // DatabaseStats represents database performance statistics
 type DatabaseStats struct {
-	Connections ConnectionStats  `json:"connections"`
-	Performance PerformanceStats `json:"performance"`
-	Storage     StorageStats     `json:"storage"`
+	Connections ConnectionStats   `json:"connections"`
+	Performance PerformanceStats  `json:"performance"`
+	Storage     StorageStats      `json:"storage"`
+	Pool        *PoolStats        `json:"pool,omitempty"`
+	Health      *ConnectionHealth `json:"health,omitempty"`
+}
+
+// PoolConfig sizes the connection pool of a managed connection
+type PoolConfig struct {
+	MaxOpen     int `json:"max_open,omitempty"`     // default 10; SQLite always uses one
+	MaxIdle     int `json:"max_idle,omitempty"`     // default 5
+	IdleTimeout int `json:"idle_timeout,omitempty"` // seconds before an idle connection is closed, default 300
+	MaxLifetime int `json:"max_lifetime,omitempty"` // seconds before a connection is replaced, default 3600
+}
+
+// PoolStats reports the usage of a provider's connection pool
+type PoolStats struct {
+	MaxOpen        int     `json:"max_open"`
+	Open           int     `json:"open"`
+	InUse          int     `json:"in_use"`
+	Idle           int     `json:"idle"`
+	WaitCount      int64   `json:"wait_count"`      // checkouts that waited for a free connection
+	WaitDuration   float64 `json:"wait_duration"`   // milliseconds spent waiting in total
+	IdleClosed     int64   `json:"idle_closed"`     // closed by the idle limit or timeout
+	LifetimeClosed int64   `json:"lifetime_closed"` // closed at their maximum lifetime
+}
+
+// ConnectionHealth is the result of the periodic health pings of a managed connection
+type ConnectionHealth struct {
+	LastPing      *time.Time `json:"last_ping,omitempty"`
+	Latency       float64    `json:"latency"`  // milliseconds of the last successful ping
+	Failures      int        `json:"failures"` // consecutive failed pings and reconnects
+	LastError     string     `json:"last_error,omitempty"`
+	NextReconnect *time.Time `json:"next_reconnect,omitempty"`
 }
 
 // ConnectionStats represents connection statistics
@@ -179,3 +384,40 @@ type SavedQuery struct {
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 }
+
+// Statuses of a scheduled query's runs
+const (
+	JobRunRunning   = "running"
+	JobRunSucceeded = "succeeded"
+	JobRunFailed    = "failed"
+)
+
+// QueryJob runs a saved query against a connection on a cron schedule, e.g. a nightly
+// cleanup or a materialized view refresh
+type QueryJob struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	SavedQueryID string       `json:"saved_query_id"`
+	ConnectionID string       `json:"connection_id,omitempty"` // defaults to the saved query's connection
+	Cron         string       `json:"cron"`
+	Timezone     string       `json:"timezone,omitempty"`
+	Enabled      bool         `json:"enabled"`
+	NextRun      *time.Time   `json:"next_run,omitempty"`
+	LastRun      *QueryJobRun `json:"last_run,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// QueryJobRun is a run of a scheduled query
+type QueryJobRun struct {
+	ID           string     `json:"id"`
+	JobID        string     `json:"job_id"`
+	Status       string     `json:"status"`
+	Scheduled    bool       `json:"scheduled"` // false when run by hand
+	RowCount     int64      `json:"row_count"` // rows returned, or affected by a write
+	Duration     float64    `json:"duration"`  // milliseconds
+	Error        string     `json:"error,omitempty"`
+	Acknowledged bool       `json:"acknowledged"` // failure acknowledged in the alert inbox
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
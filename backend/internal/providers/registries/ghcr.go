@@ -0,0 +1,281 @@
+package registries
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/providers"
+)
+
+const (
+	ghcrDefaultURL    = "https://ghcr.io"
+	githubDefaultAPI  = "https://api.github.com"
+	ghcrVersionsLimit = 100
+)
+
+// GHCRProvider implements the RegistryProvider interface for GitHub Container Registry.
+// Namespace is the owning user or organisation; Token is a personal access token with
+// read:packages (and delete:packages for tag deletion). Extra["owner_type"] is "org" or
+// "user" (default) and Extra["api_url"] overrides the GitHub API address.
+type GHCRProvider struct {
+	config       providers.RegistryConfig
+	client       *http.Client
+	distribution *distributionClient
+}
+
+// NewGHCRProvider creates a new GitHub Container Registry provider
+func NewGHCRProvider(config providers.RegistryConfig) (providers.RegistryProvider, error) {
+	g := &GHCRProvider{client: &http.Client{Timeout: 30 * time.Second}}
+	g.configure(config)
+	return g, nil
+}
+
+func (g *GHCRProvider) configure(config providers.RegistryConfig) {
+	if config.URL == "" {
+		config.URL = ghcrDefaultURL
+	}
+	username := config.Username
+	if username == "" {
+		username = config.Namespace
+	}
+	g.config = config
+	g.distribution = newDistributionClient(config.URL, username, config.Token, g.client)
+}
+
+// Type returns the provider type
+func (g *GHCRProvider) Type() string {
+	return "ghcr"
+}
+
+// Connect establishes connection to GHCR
+func (g *GHCRProvider) Connect(ctx context.Context, config providers.RegistryConfig) error {
+	g.configure(config)
+	return g.TestConnection(ctx)
+}
+
+// packageVersion is a version of a container package in the GitHub Packages API
+type packageVersion struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"` // the manifest digest
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Metadata  struct {
+		Container struct {
+			Tags []string `json:"tags"`
+		} `json:"container"`
+	} `json:"metadata"`
+}
+
+// ListImages returns one image per tag for every container package of the owner
+func (g *GHCRProvider) ListImages(ctx context.Context, namespace string) ([]providers.ContainerImage, error) {
+	if namespace == "" {
+		namespace = g.config.Namespace
+	}
+
+	var packages []struct {
+		Name string `json:"name"`
+	}
+	if err := g.api(ctx, http.MethodGet, g.ownerPath(namespace)+"/packages?package_type=container&per_page=100", &packages); err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+
+	images := []providers.ContainerImage{}
+	host := registryHost(g.config.URL)
+	for _, pkg := range packages {
+		versions, err := g.versions(ctx, namespace, pkg.Name)
+		if err != nil {
+			continue // Skip packages we can't access
+		}
+		repository := namespace + "/" + pkg.Name
+		for _, version := range versions {
+			for _, tag := range version.Metadata.Container.Tags {
+				images = append(images, providers.ContainerImage{
+					Registry:   host,
+					Repository: repository,
+					Tag:        tag,
+					Digest:     version.Name,
+					Created:    version.CreatedAt,
+					FullName:   fmt.Sprintf("%s/%s:%s", host, repository, tag),
+				})
+			}
+		}
+	}
+	return images, nil
+}
+
+// GetImage returns details about a specific image
+func (g *GHCRProvider) GetImage(ctx context.Context, reference string) (*providers.ContainerImage, error) {
+	repository, tag, err := splitReference(reference)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := g.InspectManifest(ctx, repository, tag)
+	if err != nil {
+		return nil, err
+	}
+	return imageFromManifest(registryHost(g.config.URL), manifest), nil
+}
+
+// GetImageTags returns available tags for an image, newest version first
+func (g *GHCRProvider) GetImageTags(ctx context.Context, repository string) ([]string, error) {
+	owner, name := g.splitRepository(repository)
+	versions, err := g.versions(ctx, owner, name)
+	if err != nil {
+		return nil, err
+	}
+	tags := []string{}
+	for _, version := range versions {
+		tags = append(tags, version.Metadata.Container.Tags...)
+	}
+	return tags, nil
+}
+
+// InspectManifest describes an image manifest through the registry API
+func (g *GHCRProvider) InspectManifest(ctx context.Context, repository, reference string) (*providers.ImageManifest, error) {
+	owner, name := g.splitRepository(repository)
+	return g.distribution.inspect(ctx, owner+"/"+name, reference)
+}
+
+// DeleteTag deletes the package version carrying the tag. GitHub only deletes whole
+// versions, so a tag sharing its version with other tags is refused.
+func (g *GHCRProvider) DeleteTag(ctx context.Context, repository, tag string) error {
+	owner, name := g.splitRepository(repository)
+	versions, err := g.versions(ctx, owner, name)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		tags := version.Metadata.Container.Tags
+		for _, t := range tags {
+			if t != tag {
+				continue
+			}
+			if len(tags) > 1 {
+				return fmt.Errorf("tag %s shares its image with %s; GHCR can only delete whole versions", tag, strings.Join(tags, ", "))
+			}
+			path := fmt.Sprintf("%s/packages/container/%s/versions/%d", g.ownerPath(owner), url.PathEscape(name), version.ID)
+			return g.api(ctx, http.MethodDelete, path, nil)
+		}
+	}
+	return fmt.Errorf("tag not found: %s", tag)
+}
+
+// GetAuthConfig returns authentication configuration for Kubernetes
+func (g *GHCRProvider) GetAuthConfig() (*providers.AuthConfig, error) {
+	if g.config.Token == "" {
+		return nil, nil
+	}
+	username := g.config.Username
+	if username == "" {
+		username = g.config.Namespace
+	}
+	return &providers.AuthConfig{
+		Username:      username,
+		Password:      g.config.Token,
+		Auth:          base64.StdEncoding.EncodeToString([]byte(username + ":" + g.config.Token)),
+		ServerAddress: registryHost(g.config.URL),
+	}, nil
+}
+
+// TestConnection verifies the token can list the owner's container packages
+func (g *GHCRProvider) TestConnection(ctx context.Context) error {
+	if g.config.Namespace == "" {
+		return fmt.Errorf("namespace (GitHub user or organisation) is required")
+	}
+	var packages []json.RawMessage
+	return g.api(ctx, http.MethodGet, g.ownerPath(g.config.Namespace)+"/packages?package_type=container&per_page=1", &packages)
+}
+
+// Helper methods
+
+func (g *GHCRProvider) apiURL() string {
+	if api := g.config.Extra["api_url"]; api != "" {
+		return strings.TrimSuffix(api, "/")
+	}
+	return githubDefaultAPI
+}
+
+func (g *GHCRProvider) ownerPath(owner string) string {
+	if g.config.Extra["owner_type"] == "org" {
+		return "/orgs/" + url.PathEscape(owner)
+	}
+	return "/users/" + url.PathEscape(owner)
+}
+
+// splitRepository splits owner/name, defaulting the owner to the namespace
+func (g *GHCRProvider) splitRepository(repository string) (string, string) {
+	if owner, name, ok := strings.Cut(repository, "/"); ok {
+		return owner, name
+	}
+	return g.config.Namespace, repository
+}
+
+// versions returns a package's versions, newest first
+func (g *GHCRProvider) versions(ctx context.Context, owner, name string) ([]packageVersion, error) {
+	var all []packageVersion
+	for page := 1; ; page++ {
+		var versions []packageVersion
+		path := fmt.Sprintf("%s/packages/container/%s/versions?per_page=%d&page=%d", g.ownerPath(owner), url.PathEscape(name), ghcrVersionsLimit, page)
+		if err := g.api(ctx, http.MethodGet, path, &versions); err != nil {
+			return nil, fmt.Errorf("failed to list versions: %w", err)
+		}
+		all = append(all, versions...)
+		if len(versions) < ghcrVersionsLimit {
+			return all, nil
+		}
+	}
+}
+
+func (g *GHCRProvider) api(ctx context.Context, method, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, g.apiURL()+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if g.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.config.Token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("github api returned status %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// registryHost strips the scheme and trailing slash from a registry URL
+func registryHost(registryURL string) string {
+	host := strings.TrimPrefix(registryURL, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	return strings.TrimSuffix(host, "/")
+}
+
+// imageFromManifest summarises an inspected manifest as a ContainerImage
+func imageFromManifest(host string, manifest *providers.ImageManifest) *providers.ContainerImage {
+	image := &providers.ContainerImage{
+		Registry:   host,
+		Repository: manifest.Repository,
+		Tag:        manifest.Reference,
+		Digest:     manifest.Digest,
+		Size:       manifest.Size,
+		Platform:   manifest.Platform,
+		FullName:   fmt.Sprintf("%s/%s:%s", host, manifest.Repository, manifest.Reference),
+	}
+	if manifest.Created != nil {
+		image.Created = *manifest.Created
+	}
+	return image
+}
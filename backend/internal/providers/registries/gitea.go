@@ -6,26 +6,46 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/archellir/denshimon/internal/providers"
 )
 
-// GiteaProvider implements the RegistryProvider interface for Gitea packages
+const giteaPageSize = 50
+
+// GiteaProvider implements the RegistryProvider interface for the Gitea package registry.
+// Namespace is the owning user or organisation; Token is an access token with the
+// read:package scope (and write:package for tag deletion). URL and Token default to
+// GITEA_URL and GITEA_TOKEN, the credentials of the Gitea integration, so images built
+// by Gitea Actions can be deployed without configuring the registry separately.
 type GiteaProvider struct {
-	config providers.RegistryConfig
-	client *http.Client
+	config       providers.RegistryConfig
+	client       *http.Client
+	distribution *distributionClient
 }
 
 // NewGiteaProvider creates a new Gitea provider
 func NewGiteaProvider(config providers.RegistryConfig) (providers.RegistryProvider, error) {
-	return &GiteaProvider{
-		config: config,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-	}, nil
+	g := &GiteaProvider{client: &http.Client{Timeout: 30 * time.Second}}
+	g.configure(config)
+	return g, nil
+}
+
+func (g *GiteaProvider) configure(config providers.RegistryConfig) {
+	if config.URL == "" {
+		config.URL = os.Getenv("GITEA_URL")
+	}
+	if config.Token == "" && config.Password == "" {
+		config.Token = os.Getenv("GITEA_TOKEN")
+	}
+	config.URL = strings.TrimSuffix(config.URL, "/")
+	g.config = config
+	username, password := g.credentials()
+	g.distribution = newDistributionClient(config.URL, username, password, g.client)
 }
 
 // Type returns the provider type
@@ -33,242 +53,206 @@ func (g *GiteaProvider) Type() string {
 	return "gitea"
 }
 
-// Connect establishes connection to Gitea
+// Connect establishes connection to Gitea. Without a namespace the token's own user
+// is used.
 func (g *GiteaProvider) Connect(ctx context.Context, config providers.RegistryConfig) error {
-	g.config = config
+	g.configure(config)
+	if g.config.Namespace == "" && g.config.Token != "" {
+		var user struct {
+			Login string `json:"login"`
+		}
+		if err := g.api(ctx, http.MethodGet, "/user", &user); err != nil {
+			return fmt.Errorf("failed to resolve token user: %w", err)
+		}
+		config.Namespace = user.Login
+		g.configure(config)
+	}
 	return g.TestConnection(ctx)
 }
 
-// ListImages returns container images from Gitea packages
+// giteaPackage is a package version in the Gitea packages API. Container versions are
+// either tags or the digests of untagged (e.g. per-platform) manifests.
+type giteaPackage struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListImages returns one image per tag for every container package of the owner
 func (g *GiteaProvider) ListImages(ctx context.Context, namespace string) ([]providers.ContainerImage, error) {
 	if namespace == "" {
 		namespace = g.config.Namespace
 	}
-
-	// Gitea API endpoint for packages
-	url := fmt.Sprintf("%s/api/v1/packages/%s?type=container", g.config.URL, namespace)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// Add authentication if token is provided
-	if g.config.Token != "" {
-		req.Header.Set("Authorization", "token "+g.config.Token)
+	if namespace == "" {
+		return nil, fmt.Errorf("namespace (Gitea user or organisation) is required")
 	}
 
-	resp, err := g.client.Do(req)
+	packages, err := g.packages(ctx, namespace, "")
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to list packages: status %d", resp.StatusCode)
-	}
 
-	var packages []struct {
-		ID         int64     `json:"id"`
-		Owner      string    `json:"owner"`
-		Type       string    `json:"type"`
-		Name       string    `json:"name"`
-		Version    string    `json:"version"`
-		CreatedAt  time.Time `json:"created_at"`
-		Size       int64     `json:"size"`
-		Repository string    `json:"repository"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&packages); err != nil {
-		return nil, err
-	}
-
-	images := make([]providers.ContainerImage, 0, len(packages))
+	images := []providers.ContainerImage{}
+	host := registryHost(g.config.URL)
 	for _, pkg := range packages {
-		if pkg.Type != "container" {
-			continue
-		}
-
-		registryURL := strings.TrimPrefix(g.config.URL, "https://")
-		registryURL = strings.TrimPrefix(registryURL, "http://")
-
+		repository := namespace + "/" + pkg.Name
 		images = append(images, providers.ContainerImage{
-			Registry:   registryURL,
-			Repository: fmt.Sprintf("%s/%s", namespace, pkg.Name),
+			Registry:   host,
+			Repository: repository,
 			Tag:        pkg.Version,
-			Size:       pkg.Size,
 			Created:    pkg.CreatedAt,
-			Platform:   "linux/amd64",
-			FullName:   fmt.Sprintf("%s/%s/%s:%s", registryURL, namespace, pkg.Name, pkg.Version),
+			FullName:   fmt.Sprintf("%s/%s:%s", host, repository, pkg.Version),
 		})
 	}
-
 	return images, nil
 }
 
 // GetImage returns details about a specific image
 func (g *GiteaProvider) GetImage(ctx context.Context, reference string) (*providers.ContainerImage, error) {
-	// Parse reference (e.g., "org/app:v1.0")
-	parts := strings.Split(reference, ":")
-	if len(parts) != 2 {
-		return nil, fmt.Errorf("invalid image reference: %s", reference)
-	}
-
-	repoParts := strings.Split(parts[0], "/")
-	if len(repoParts) != 2 {
-		return nil, fmt.Errorf("invalid repository format: %s", parts[0])
-	}
-
-	namespace := repoParts[0]
-	name := repoParts[1]
-	version := parts[1]
-
-	// Gitea API endpoint for specific package
-	url := fmt.Sprintf("%s/api/v1/packages/%s/container/%s/%s",
-		g.config.URL, namespace, name, version)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	repository, tag, err := splitReference(reference)
 	if err != nil {
 		return nil, err
 	}
-
-	if g.config.Token != "" {
-		req.Header.Set("Authorization", "token "+g.config.Token)
-	}
-
-	resp, err := g.client.Do(req)
+	manifest, err := g.InspectManifest(ctx, repository, tag)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("image not found: %s", reference)
-	}
-
-	var pkg struct {
-		ID        int64     `json:"id"`
-		Name      string    `json:"name"`
-		Version   string    `json:"version"`
-		Size      int64     `json:"size"`
-		CreatedAt time.Time `json:"created_at"`
-		Metadata  struct {
-			Digest   string `json:"digest"`
-			Platform string `json:"platform"`
-		} `json:"metadata"`
-	}
+	return imageFromManifest(registryHost(g.config.URL), manifest), nil
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&pkg); err != nil {
+// GetImageTags returns available tags for an image, newest first
+func (g *GiteaProvider) GetImageTags(ctx context.Context, repository string) ([]string, error) {
+	owner, name := g.splitRepository(repository)
+	packages, err := g.packages(ctx, owner, name)
+	if err != nil {
 		return nil, err
 	}
+	tags := []string{}
+	for _, pkg := range packages {
+		if pkg.Name == name {
+			tags = append(tags, pkg.Version)
+		}
+	}
+	return tags, nil
+}
 
-	registryURL := strings.TrimPrefix(g.config.URL, "https://")
-	registryURL = strings.TrimPrefix(registryURL, "http://")
+// InspectManifest describes an image manifest through the registry API
+func (g *GiteaProvider) InspectManifest(ctx context.Context, repository, reference string) (*providers.ImageManifest, error) {
+	owner, name := g.splitRepository(repository)
+	return g.distribution.inspect(ctx, owner+"/"+name, reference)
+}
 
-	return &providers.ContainerImage{
-		Registry:   registryURL,
-		Repository: fmt.Sprintf("%s/%s", namespace, name),
-		Tag:        version,
-		Digest:     pkg.Metadata.Digest,
-		Size:       pkg.Size,
-		Created:    pkg.CreatedAt,
-		Platform:   pkg.Metadata.Platform,
-		FullName:   fmt.Sprintf("%s/%s/%s:%s", registryURL, namespace, name, version),
-	}, nil
+// DeleteTag deletes the package version of the tag
+func (g *GiteaProvider) DeleteTag(ctx context.Context, repository, tag string) error {
+	owner, name := g.splitRepository(repository)
+	path := fmt.Sprintf("/packages/%s/container/%s/%s", url.PathEscape(owner), url.PathEscape(name), url.PathEscape(tag))
+	return g.api(ctx, http.MethodDelete, path, nil)
 }
 
-// GetImageTags returns available tags for an image
-func (g *GiteaProvider) GetImageTags(ctx context.Context, repository string) ([]string, error) {
-	parts := strings.Split(repository, "/")
-	if len(parts) != 2 {
-		return nil, fmt.Errorf("invalid repository format: %s", repository)
+// GetAuthConfig returns authentication configuration for Kubernetes
+func (g *GiteaProvider) GetAuthConfig() (*providers.AuthConfig, error) {
+	username, password := g.credentials()
+	if password == "" {
+		return nil, nil
 	}
+	return &providers.AuthConfig{
+		Username:      username,
+		Password:      password,
+		Auth:          base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+		ServerAddress: registryHost(g.config.URL),
+	}, nil
+}
 
-	namespace := parts[0]
-	name := parts[1]
-
-	// List all versions of the package
-	url := fmt.Sprintf("%s/api/v1/packages/%s/container/%s",
-		g.config.URL, namespace, name)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+// TestConnection verifies the credentials can list the owner's container packages
+func (g *GiteaProvider) TestConnection(ctx context.Context) error {
+	if g.config.URL == "" {
+		return fmt.Errorf("url is required (or set GITEA_URL)")
 	}
-
-	if g.config.Token != "" {
-		req.Header.Set("Authorization", "token "+g.config.Token)
+	if g.config.Namespace == "" {
+		return g.api(ctx, http.MethodGet, "/version", nil)
 	}
+	var packages []json.RawMessage
+	path := fmt.Sprintf("/packages/%s?type=container&limit=1", url.PathEscape(g.config.Namespace))
+	return g.api(ctx, http.MethodGet, path, &packages)
+}
 
-	resp, err := g.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// Helper methods
 
-	var versions []struct {
-		Version string `json:"version"`
+// credentials returns the registry login: a username and password, or the token with
+// the username defaulting to the namespace
+func (g *GiteaProvider) credentials() (string, string) {
+	if g.config.Password != "" {
+		return g.config.Username, g.config.Password
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
-		return nil, err
+	username := g.config.Username
+	if username == "" {
+		username = g.config.Namespace
 	}
+	return username, g.config.Token
+}
 
-	tags := make([]string, len(versions))
-	for i, v := range versions {
-		tags[i] = v.Version
+// splitRepository splits owner/name, defaulting the owner to the namespace
+func (g *GiteaProvider) splitRepository(repository string) (string, string) {
+	if owner, name, ok := strings.Cut(repository, "/"); ok {
+		return owner, name
 	}
-
-	return tags, nil
+	return g.config.Namespace, repository
 }
 
-// GetAuthConfig returns authentication configuration for Kubernetes
-func (g *GiteaProvider) GetAuthConfig() (*providers.AuthConfig, error) {
-	registryURL := strings.TrimPrefix(g.config.URL, "https://")
-	registryURL = strings.TrimPrefix(registryURL, "http://")
-
-	if g.config.Username != "" && g.config.Password != "" {
-		auth := base64.StdEncoding.EncodeToString([]byte(g.config.Username + ":" + g.config.Password))
-		return &providers.AuthConfig{
-			Username:      g.config.Username,
-			Password:      g.config.Password,
-			Auth:          auth,
-			ServerAddress: registryURL,
-		}, nil
+// packages returns the owner's tagged container versions, optionally filtered by a
+// package name query, newest first
+func (g *GiteaProvider) packages(ctx context.Context, owner, query string) ([]giteaPackage, error) {
+	params := url.Values{"type": {"container"}, "limit": {fmt.Sprint(giteaPageSize)}}
+	if query != "" {
+		params.Set("q", query)
 	}
 
-	if g.config.Token != "" {
-		// Use token as password with username as token
-		auth := base64.StdEncoding.EncodeToString([]byte("token:" + g.config.Token))
-		return &providers.AuthConfig{
-			Username:      "token",
-			Password:      g.config.Token,
-			Auth:          auth,
-			ServerAddress: registryURL,
-		}, nil
+	var all []giteaPackage
+	for page := 1; ; page++ {
+		params.Set("page", fmt.Sprint(page))
+		var packages []giteaPackage
+		path := "/packages/" + url.PathEscape(owner) + "?" + params.Encode()
+		if err := g.api(ctx, http.MethodGet, path, &packages); err != nil {
+			return nil, fmt.Errorf("failed to list packages: %w", err)
+		}
+		for _, pkg := range packages {
+			if strings.HasPrefix(pkg.Version, "sha256:") {
+				continue // untagged manifest
+			}
+			all = append(all, pkg)
+		}
+		if len(packages) < giteaPageSize {
+			break
+		}
 	}
-
-	return nil, nil
+	sort.SliceStable(all, func(i, j int) bool { return all[i].CreatedAt.After(all[j].CreatedAt) })
+	return all, nil
 }
 
-// TestConnection verifies the Gitea connection
-func (g *GiteaProvider) TestConnection(ctx context.Context) error {
-	url := fmt.Sprintf("%s/api/v1/version", g.config.URL)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+func (g *GiteaProvider) api(ctx context.Context, method, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, g.config.URL+"/api/v1"+path, nil)
 	if err != nil {
 		return err
 	}
+	req.Header.Set("Accept", "application/json")
+	if g.config.Token != "" {
+		req.Header.Set("Authorization", "token "+g.config.Token)
+	} else if g.config.Password != "" {
+		req.SetBasicAuth(g.config.Username, g.config.Password)
+	}
 
 	resp, err := g.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("connection test failed: status %d", resp.StatusCode)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("gitea api returned status %d", resp.StatusCode)
 	}
-
-	return nil
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
 }
@@ -0,0 +1,235 @@
+package registries
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/providers"
+)
+
+const harborPageSize = 100
+
+// HarborProvider implements the RegistryProvider interface for Harbor. Namespace is
+// the Harbor project; Username and Password are typically a robot account.
+type HarborProvider struct {
+	config       providers.RegistryConfig
+	client       *http.Client
+	distribution *distributionClient
+}
+
+// NewHarborProvider creates a new Harbor provider
+func NewHarborProvider(config providers.RegistryConfig) (providers.RegistryProvider, error) {
+	h := &HarborProvider{client: &http.Client{Timeout: 30 * time.Second}}
+	h.configure(config)
+	return h, nil
+}
+
+func (h *HarborProvider) configure(config providers.RegistryConfig) {
+	config.URL = strings.TrimSuffix(config.URL, "/")
+	h.config = config
+	h.distribution = newDistributionClient(config.URL, config.Username, config.Password, h.client)
+}
+
+// Type returns the provider type
+func (h *HarborProvider) Type() string {
+	return "harbor"
+}
+
+// Connect establishes connection to Harbor
+func (h *HarborProvider) Connect(ctx context.Context, config providers.RegistryConfig) error {
+	h.configure(config)
+	return h.TestConnection(ctx)
+}
+
+// harborArtifact is an artifact in the Harbor API
+type harborArtifact struct {
+	Digest   string    `json:"digest"`
+	Size     int64     `json:"size"`
+	PushTime time.Time `json:"push_time"`
+	Tags     []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+	ExtraAttrs struct {
+		Architecture string     `json:"architecture"`
+		OS           string     `json:"os"`
+		Created      *time.Time `json:"created"`
+	} `json:"extra_attrs"`
+}
+
+// ListImages returns one image per tag in the project's repositories
+func (h *HarborProvider) ListImages(ctx context.Context, namespace string) ([]providers.ContainerImage, error) {
+	if namespace == "" {
+		namespace = h.config.Namespace
+	}
+	if namespace == "" {
+		return nil, fmt.Errorf("a Harbor project is required")
+	}
+
+	var repositories []struct {
+		Name string `json:"name"` // project/repository
+	}
+	path := fmt.Sprintf("/projects/%s/repositories?page_size=%d", url.PathEscape(namespace), harborPageSize)
+	if err := h.api(ctx, http.MethodGet, path, &repositories); err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	images := []providers.ContainerImage{}
+	host := registryHost(h.config.URL)
+	for _, repository := range repositories {
+		artifacts, err := h.artifacts(ctx, repository.Name)
+		if err != nil {
+			continue // Skip repositories we can't access
+		}
+		for _, artifact := range artifacts {
+			created := artifact.PushTime
+			if artifact.ExtraAttrs.Created != nil {
+				created = *artifact.ExtraAttrs.Created
+			}
+			platform := ""
+			if artifact.ExtraAttrs.OS != "" {
+				platform = artifact.ExtraAttrs.OS + "/" + artifact.ExtraAttrs.Architecture
+			}
+			for _, tag := range artifact.Tags {
+				images = append(images, providers.ContainerImage{
+					Registry:   host,
+					Repository: repository.Name,
+					Tag:        tag.Name,
+					Digest:     artifact.Digest,
+					Size:       artifact.Size,
+					Created:    created,
+					Platform:   platform,
+					FullName:   fmt.Sprintf("%s/%s:%s", host, repository.Name, tag.Name),
+				})
+			}
+		}
+	}
+	return images, nil
+}
+
+// GetImage returns details about a specific image
+func (h *HarborProvider) GetImage(ctx context.Context, reference string) (*providers.ContainerImage, error) {
+	repository, tag, err := splitReference(reference)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := h.InspectManifest(ctx, repository, tag)
+	if err != nil {
+		return nil, err
+	}
+	return imageFromManifest(registryHost(h.config.URL), manifest), nil
+}
+
+// GetImageTags returns available tags for an image, most recently pushed first
+func (h *HarborProvider) GetImageTags(ctx context.Context, repository string) ([]string, error) {
+	artifacts, err := h.artifacts(ctx, h.fullRepository(repository))
+	if err != nil {
+		return nil, err
+	}
+	tags := []string{}
+	for _, artifact := range artifacts {
+		for _, tag := range artifact.Tags {
+			tags = append(tags, tag.Name)
+		}
+	}
+	return tags, nil
+}
+
+// InspectManifest describes an image manifest through the registry API
+func (h *HarborProvider) InspectManifest(ctx context.Context, repository, reference string) (*providers.ImageManifest, error) {
+	return h.distribution.inspect(ctx, h.fullRepository(repository), reference)
+}
+
+// DeleteTag removes a tag; the artifact itself is left for Harbor's own retention
+// and garbage collection
+func (h *HarborProvider) DeleteTag(ctx context.Context, repository, tag string) error {
+	project, name := h.splitRepository(h.fullRepository(repository))
+	path := fmt.Sprintf("/projects/%s/repositories/%s/artifacts/%s/tags/%s",
+		url.PathEscape(project), escapeHarborRepository(name), url.PathEscape(tag), url.PathEscape(tag))
+	return h.api(ctx, http.MethodDelete, path, nil)
+}
+
+// GetAuthConfig returns authentication configuration for Kubernetes
+func (h *HarborProvider) GetAuthConfig() (*providers.AuthConfig, error) {
+	if h.config.Username == "" || h.config.Password == "" {
+		return nil, nil
+	}
+	return &providers.AuthConfig{
+		Username:      h.config.Username,
+		Password:      h.config.Password,
+		Auth:          base64.StdEncoding.EncodeToString([]byte(h.config.Username + ":" + h.config.Password)),
+		ServerAddress: registryHost(h.config.URL),
+	}, nil
+}
+
+// TestConnection verifies the credentials against the Harbor API
+func (h *HarborProvider) TestConnection(ctx context.Context) error {
+	if h.config.Namespace != "" {
+		var project json.RawMessage
+		return h.api(ctx, http.MethodGet, "/projects/"+url.PathEscape(h.config.Namespace), &project)
+	}
+	var info json.RawMessage
+	return h.api(ctx, http.MethodGet, "/systeminfo", &info)
+}
+
+// Helper methods
+
+// fullRepository prefixes the project when only a repository name is given
+func (h *HarborProvider) fullRepository(repository string) string {
+	if strings.Contains(repository, "/") || h.config.Namespace == "" {
+		return repository
+	}
+	return h.config.Namespace + "/" + repository
+}
+
+func (h *HarborProvider) splitRepository(repository string) (string, string) {
+	project, name, _ := strings.Cut(repository, "/")
+	return project, name
+}
+
+// escapeHarborRepository double-encodes a repository name, as Harbor requires for
+// names containing slashes
+func escapeHarborRepository(name string) string {
+	return url.PathEscape(url.PathEscape(name))
+}
+
+// artifacts returns a repository's tagged artifacts
+func (h *HarborProvider) artifacts(ctx context.Context, repository string) ([]harborArtifact, error) {
+	project, name := h.splitRepository(repository)
+	var artifacts []harborArtifact
+	path := fmt.Sprintf("/projects/%s/repositories/%s/artifacts?with_tag=true&page_size=%d&sort=-push_time",
+		url.PathEscape(project), escapeHarborRepository(name), harborPageSize)
+	if err := h.api(ctx, http.MethodGet, path, &artifacts); err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	return artifacts, nil
+}
+
+func (h *HarborProvider) api(ctx context.Context, method, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, h.config.URL+"/api/v2.0"+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if h.config.Username != "" {
+		req.SetBasicAuth(h.config.Username, h.config.Password)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("harbor api returned status %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,309 @@
+package registries
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/providers"
+)
+
+// Manifest media types requested from registries
+const (
+	mediaTypeOCIIndex    = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerList  = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerV2    = "application/vnd.docker.distribution.manifest.v2+json"
+	maxManifestSize      = 4 << 20
+	manifestAccept       = mediaTypeOCIIndex + ", " + mediaTypeDockerList + ", " + mediaTypeOCIManifest + ", " + mediaTypeDockerV2
+)
+
+// distributionClient reads manifests over the OCI Distribution API, exchanging
+// credentials for bearer tokens when the registry challenges for them
+type distributionClient struct {
+	baseURL  string
+	username string
+	password string
+	client   *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]string // scope -> bearer token
+}
+
+func newDistributionClient(baseURL, username, password string, client *http.Client) *distributionClient {
+	return &distributionClient{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		username: username,
+		password: password,
+		client:   client,
+		tokens:   make(map[string]string),
+	}
+}
+
+// get fetches a registry path, retrying once with a bearer token after a challenge
+func (d *distributionClient) get(ctx context.Context, repository, path, accept string) (*http.Response, error) {
+	scope := "repository:" + repository + ":pull"
+	request := func(token string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseURL+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else if d.username != "" || d.password != "" {
+			req.SetBasicAuth(d.username, d.password)
+		}
+		return d.client.Do(req)
+	}
+
+	d.mu.Lock()
+	token := d.tokens[scope]
+	d.mu.Unlock()
+
+	resp, err := request(token)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return nil, fmt.Errorf("registry rejected credentials")
+	}
+
+	token, err = d.fetchToken(ctx, challenge, scope)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	d.tokens[scope] = token
+	d.mu.Unlock()
+	return request(token)
+}
+
+// fetchToken answers a Bearer challenge from the registry's token service
+func (d *distributionClient) fetchToken(ctx context.Context, challenge, scope string) (string, error) {
+	params := parseChallenge(challenge[len("bearer "):])
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry challenge has no realm")
+	}
+	query := url.Values{}
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", scope)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if d.username != "" || d.password != "" {
+		req.SetBasicAuth(d.username, d.password)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get registry token: status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.Token != "" {
+		return result.Token, nil
+	}
+	return result.AccessToken, nil
+}
+
+// parseChallenge splits key="value" pairs of a WWW-Authenticate header
+func parseChallenge(value string) map[string]string {
+	params := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			params[strings.ToLower(key)] = strings.Trim(val, `"`)
+		}
+	}
+	return params
+}
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	Platform  *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+		Variant      string `json:"variant"`
+	} `json:"platform,omitempty"`
+}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Config    ociDescriptor   `json:"config"`
+	Layers    []ociDescriptor `json:"layers"`
+	Manifests []ociDescriptor `json:"manifests"` // set for indexes
+
+	Annotations map[string]string `json:"annotations"`
+}
+
+func (d *distributionClient) manifest(ctx context.Context, repository, reference string) (*ociManifest, string, string, error) {
+	resp, err := d.get(ctx, repository, "/v2/"+repository+"/manifests/"+reference, manifestAccept)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", "", fmt.Errorf("failed to get manifest: status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, "", "", fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	mediaType := manifest.MediaType
+	if mediaType == "" {
+		mediaType = strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		digest = fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	}
+	return &manifest, mediaType, digest, nil
+}
+
+// inspect describes an image, following an index to its first real platform
+func (d *distributionClient) inspect(ctx context.Context, repository, reference string) (*providers.ImageManifest, error) {
+	manifest, mediaType, digest, err := d.manifest(ctx, repository, reference)
+	if err != nil {
+		return nil, err
+	}
+	result := &providers.ImageManifest{
+		Repository: repository,
+		Reference:  reference,
+		Digest:     digest,
+		MediaType:  mediaType,
+		Layers:     []providers.ManifestLayer{},
+	}
+
+	annotations := manifest.Annotations
+	if len(manifest.Manifests) > 0 {
+		var chosen string
+		for _, entry := range manifest.Manifests {
+			if entry.Platform == nil || entry.Platform.OS == "unknown" {
+				continue // attestation manifests
+			}
+			platform := entry.Platform.OS + "/" + entry.Platform.Architecture
+			if entry.Platform.Variant != "" {
+				platform += "/" + entry.Platform.Variant
+			}
+			result.Platforms = append(result.Platforms, platform)
+			if chosen == "" {
+				chosen = entry.Digest
+				result.Platform = platform
+			}
+		}
+		if chosen == "" {
+			return result, nil
+		}
+		if manifest, _, _, err = d.manifest(ctx, repository, chosen); err != nil {
+			return nil, err
+		}
+		annotations = mergeLabels(manifest.Annotations, annotations)
+	}
+
+	result.Size = manifest.Config.Size
+	for _, layer := range manifest.Layers {
+		result.Size += layer.Size
+		result.Layers = append(result.Layers, providers.ManifestLayer{Digest: layer.Digest, Size: layer.Size, MediaType: layer.MediaType})
+	}
+
+	if manifest.Config.Digest != "" {
+		if config, err := d.imageConfig(ctx, repository, manifest.Config.Digest); err == nil {
+			result.Created = config.Created
+			result.Labels = config.Config.Labels
+			if result.Platform == "" && config.OS != "" {
+				result.Platform = config.OS + "/" + config.Architecture
+				if config.Variant != "" {
+					result.Platform += "/" + config.Variant
+				}
+			}
+		}
+	}
+	result.Labels = mergeLabels(result.Labels, annotations)
+	return result, nil
+}
+
+// mergeLabels adds the entries of extra whose keys labels doesn't have
+func mergeLabels(labels, extra map[string]string) map[string]string {
+	for key, value := range extra {
+		if _, ok := labels[key]; !ok {
+			if labels == nil {
+				labels = make(map[string]string)
+			}
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+type imageConfig struct {
+	Created      *time.Time `json:"created"`
+	OS           string     `json:"os"`
+	Architecture string     `json:"architecture"`
+	Variant      string     `json:"variant"`
+	Config       struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+}
+
+func (d *distributionClient) imageConfig(ctx context.Context, repository, digest string) (*imageConfig, error) {
+	resp, err := d.get(ctx, repository, "/v2/"+repository+"/blobs/"+digest, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get image config: status %d", resp.StatusCode)
+	}
+	var config imageConfig
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// splitReference splits repo:tag or repo@digest
+func splitReference(reference string) (string, string, error) {
+	if repository, digest, ok := strings.Cut(reference, "@"); ok {
+		return repository, digest, nil
+	}
+	if i := strings.LastIndex(reference, ":"); i > 0 && !strings.Contains(reference[i:], "/") {
+		return reference[:i], reference[i+1:], nil
+	}
+	return "", "", fmt.Errorf("invalid image reference: %s", reference)
+}
@@ -0,0 +1,207 @@
+package registries
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/archellir/denshimon/internal/providers"
+)
+
+// fakeRegistry serves a multi-platform image behind a bearer token challenge
+func fakeRegistry(t *testing.T) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if user, pass, _ := r.BasicAuth(); user != "bot" || pass != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("scope") != "repository:team/api:pull" {
+				t.Errorf("unexpected scope %q", r.URL.Query().Get("scope"))
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": "bearer-token"})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer bearer-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/team/api/manifests/v1":
+			w.Header().Set("Docker-Content-Digest", "sha256:index")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"mediaType":   mediaTypeOCIIndex,
+				"annotations": map[string]string{"org.opencontainers.image.revision": "0123abc", "org.opencontainers.image.source": "https://git.example.com/team/api"},
+				"manifests": []map[string]interface{}{
+					{"digest": "sha256:amd64", "platform": map[string]string{"os": "linux", "architecture": "amd64"}},
+					{"digest": "sha256:arm64", "platform": map[string]string{"os": "linux", "architecture": "arm64", "variant": "v8"}},
+					{"digest": "sha256:attestation", "platform": map[string]string{"os": "unknown", "architecture": "unknown"}},
+				},
+			})
+		case "/v2/team/api/manifests/sha256:amd64":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"mediaType": mediaTypeOCIManifest,
+				"config":    map[string]interface{}{"digest": "sha256:config", "size": 100},
+				"layers": []map[string]interface{}{
+					{"digest": "sha256:l1", "size": 1000, "mediaType": "application/vnd.oci.image.layer.v1.tar+gzip"},
+					{"digest": "sha256:l2", "size": 500, "mediaType": "application/vnd.oci.image.layer.v1.tar+gzip"},
+				},
+			})
+		case "/v2/team/api/blobs/sha256:config":
+			w.Write([]byte(`{"created":"2025-03-01T10:00:00Z","os":"linux","architecture":"amd64","config":{"Labels":{"org.opencontainers.image.revision":"4567def"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestInspectMultiPlatformManifest(t *testing.T) {
+	server := fakeRegistry(t)
+	provider, _ := NewHarborProvider(providers.RegistryConfig{URL: server.URL, Namespace: "team", Username: "bot", Password: "secret"})
+
+	manifest, err := provider.(providers.ManifestInspector).InspectManifest(context.Background(), "api", "v1")
+	if err != nil {
+		t.Fatalf("InspectManifest failed: %v", err)
+	}
+	if manifest.Digest != "sha256:index" || manifest.Repository != "team/api" {
+		t.Errorf("unexpected manifest identity: %+v", manifest)
+	}
+	if manifest.Size != 1600 || len(manifest.Layers) != 2 {
+		t.Errorf("unexpected size or layers: %d %+v", manifest.Size, manifest.Layers)
+	}
+	if manifest.Platform != "linux/amd64" || len(manifest.Platforms) != 2 || manifest.Platforms[1] != "linux/arm64/v8" {
+		t.Errorf("unexpected platforms: %q %v", manifest.Platform, manifest.Platforms)
+	}
+	if manifest.Created == nil || manifest.Created.Year() != 2025 {
+		t.Errorf("created date not read from the config: %v", manifest.Created)
+	}
+	// Config labels win over index annotations, which fill in the rest
+	if manifest.Labels["org.opencontainers.image.revision"] != "4567def" || manifest.Labels["org.opencontainers.image.source"] != "https://git.example.com/team/api" {
+		t.Errorf("unexpected labels: %v", manifest.Labels)
+	}
+}
+
+func TestHarborDeleteTag(t *testing.T) {
+	var deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deleted = r.URL.EscapedPath()
+		}
+	}))
+	defer server.Close()
+
+	provider, _ := NewHarborProvider(providers.RegistryConfig{URL: server.URL, Namespace: "team", Username: "bot", Password: "secret"})
+	if err := provider.(providers.TagDeleter).DeleteTag(context.Background(), "team/services/api", "v1"); err != nil {
+		t.Fatalf("DeleteTag failed: %v", err)
+	}
+	if deleted != "/api/v2.0/projects/team/repositories/services%252Fapi/artifacts/v1/tags/v1" {
+		t.Errorf("unexpected delete path %q", deleted)
+	}
+}
+
+func TestGHCRDeleteTag(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasPrefix(r.URL.Path, "/orgs/acme/packages/container/api/versions"):
+			w.Write([]byte(`[
+				{"id": 2, "name": "sha256:b", "metadata": {"container": {"tags": ["latest", "v2"]}}},
+				{"id": 1, "name": "sha256:a", "metadata": {"container": {"tags": ["v1"]}}}
+			]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider, _ := NewGHCRProvider(providers.RegistryConfig{
+		Namespace: "acme",
+		Token:     "ghp_test",
+		Extra:     map[string]string{"owner_type": "org", "api_url": server.URL},
+	})
+	ctx := context.Background()
+
+	tags, err := provider.GetImageTags(ctx, "acme/api")
+	if err != nil || strings.Join(tags, ",") != "latest,v2,v1" {
+		t.Fatalf("unexpected tags %v: %v", tags, err)
+	}
+
+	deleter := provider.(providers.TagDeleter)
+	if err := deleter.DeleteTag(ctx, "api", "v1"); err != nil {
+		t.Fatalf("DeleteTag failed: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "/orgs/acme/packages/container/api/versions/1" {
+		t.Errorf("unexpected deletes: %v", deleted)
+	}
+	// v2 shares its version with latest, so deleting it would remove both
+	if err := deleter.DeleteTag(ctx, "api", "v2"); err == nil {
+		t.Error("expected an error deleting a tag that shares its version")
+	}
+}
+
+func TestGiteaPackages(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token gitea_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/api/v1/user":
+			w.Write([]byte(`{"login": "acme"}`))
+		case r.URL.Path == "/api/v1/packages/acme" && r.URL.Query().Get("q") == "api":
+			w.Write([]byte(`[
+				{"id": 1, "type": "container", "name": "api", "version": "v1", "created_at": "2024-01-01T00:00:00Z"},
+				{"id": 2, "type": "container", "name": "api", "version": "sha256:abc", "created_at": "2024-01-02T00:00:00Z"},
+				{"id": 3, "type": "container", "name": "api", "version": "v2", "created_at": "2024-01-03T00:00:00Z"},
+				{"id": 4, "type": "container", "name": "api-worker", "version": "v9", "created_at": "2024-01-04T00:00:00Z"}
+			]`))
+		case r.URL.Path == "/api/v1/packages/acme":
+			w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Credentials default to those of the Gitea integration
+	t.Setenv("GITEA_URL", server.URL)
+	t.Setenv("GITEA_TOKEN", "gitea_test")
+	provider, _ := NewGiteaProvider(providers.RegistryConfig{})
+	ctx := context.Background()
+	if err := provider.Connect(ctx, providers.RegistryConfig{}); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	tags, err := provider.GetImageTags(ctx, "api")
+	if err != nil || strings.Join(tags, ",") != "v2,v1" {
+		t.Fatalf("unexpected tags %v: %v", tags, err)
+	}
+
+	auth, _ := provider.GetAuthConfig()
+	if auth == nil || auth.Username != "acme" || auth.Password != "gitea_test" || auth.ServerAddress != strings.TrimPrefix(server.URL, "http://") {
+		t.Errorf("unexpected auth config %+v", auth)
+	}
+
+	if err := provider.(providers.TagDeleter).DeleteTag(ctx, "acme/api", "v1"); err != nil {
+		t.Fatalf("DeleteTag failed: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "/api/v1/packages/acme/container/api/v1" {
+		t.Errorf("unexpected deletes: %v", deleted)
+	}
+}
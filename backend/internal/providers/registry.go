@@ -3,12 +3,13 @@ package providers
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 )
 
 // RegistryProvider defines the interface for container registry providers
 type RegistryProvider interface {
-	// Type returns the provider type (dockerhub, gitea, gitlab, etc.)
+	// Type returns the provider type (dockerhub, gitea, ghcr, harbor, generic)
 	Type() string
 
 	// Connect establishes connection to the registry
@@ -62,11 +63,45 @@ type AuthConfig struct {
 	ServerAddress string `json:"serveraddress,omitempty"`
 }
 
+// ManifestInspector is implemented by providers that can describe an image manifest
+type ManifestInspector interface {
+	InspectManifest(ctx context.Context, repository, reference string) (*ImageManifest, error)
+}
+
+// TagDeleter is implemented by providers whose registry API allows deleting tags
+type TagDeleter interface {
+	DeleteTag(ctx context.Context, repository, tag string) error
+}
+
+// ImageManifest describes an image manifest. For multi-platform images the layers,
+// size and created date are those of the first listed platform.
+type ImageManifest struct {
+	Repository string          `json:"repository"`
+	Reference  string          `json:"reference"`
+	Digest     string          `json:"digest"`
+	MediaType  string          `json:"media_type"`
+	Size       int64           `json:"size"` // config and layers, compressed
+	Created    *time.Time      `json:"created,omitempty"`
+	Platform   string          `json:"platform,omitempty"`
+	Platforms  []string        `json:"platforms,omitempty"` // all platforms of a multi-platform image
+	Layers     []ManifestLayer `json:"layers"`
+	// Labels of the image config, with the annotations of its manifest and index
+	// filling in keys the config doesn't set, e.g. org.opencontainers.image.revision
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ManifestLayer is one layer of an image
+type ManifestLayer struct {
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	MediaType string `json:"media_type"`
+}
+
 // Registry represents a configured registry in the system
 type Registry struct {
 	ID        string         `json:"id"`
 	Name      string         `json:"name"`
-	Type      string         `json:"type"` // dockerhub, gitea, gitlab, generic
+	Type      string         `json:"type"` // dockerhub, gitea, ghcr, harbor, generic
 	Config    RegistryConfig `json:"config"`
 	LastSync  *time.Time     `json:"last_sync,omitempty"`
 	Status    string         `json:"status"`
@@ -77,13 +112,16 @@ type Registry struct {
 
 // RegistryManager manages container registry providers
 type RegistryManager struct {
+	mu        sync.RWMutex
 	providers map[string]RegistryProvider
+	factories *ProviderRegistry
 }
 
 // NewRegistryManager creates a new registry manager with the given providers
 func NewRegistryManager(providerRegistry *ProviderRegistry) *RegistryManager {
 	return &RegistryManager{
 		providers: providerRegistry.registries,
+		factories: providerRegistry,
 	}
 }
 
@@ -106,8 +144,19 @@ func (pr *ProviderRegistry) Register(providerType string, factory func(RegistryC
 	pr.factories[providerType] = factory
 }
 
+// Create builds a provider of the given type
+func (pr *ProviderRegistry) Create(providerType string, config RegistryConfig) (RegistryProvider, error) {
+	factory, exists := pr.factories[providerType]
+	if !exists {
+		return nil, fmt.Errorf("unsupported registry type: %s", providerType)
+	}
+	return factory(config)
+}
+
 // GetProvider returns a registry provider by ID
 func (rm *RegistryManager) GetProvider(registryID string) (RegistryProvider, error) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
 	provider, exists := rm.providers[registryID]
 	if !exists {
 		return nil, fmt.Errorf("registry provider not found: %s", registryID)
@@ -115,21 +164,31 @@ func (rm *RegistryManager) GetProvider(registryID string) (RegistryProvider, err
 	return provider, nil
 }
 
-// AddRegistry adds a new registry provider
+// AddRegistry creates the provider for a registry and connects it. The provider is
+// kept even if the connection test fails so it can be retried.
 func (rm *RegistryManager) AddRegistry(ctx context.Context, registry Registry) error {
-	// This would typically save to database and create the provider
-	// For now, just return nil (success)
-	return nil
+	provider, err := rm.factories.Create(registry.Type, registry.Config)
+	if err != nil {
+		return err
+	}
+	rm.mu.Lock()
+	rm.providers[registry.ID] = provider
+	rm.mu.Unlock()
+	return provider.Connect(ctx, registry.Config)
 }
 
 // RemoveRegistry removes a registry provider
 func (rm *RegistryManager) RemoveRegistry(ctx context.Context, registryID string) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
 	delete(rm.providers, registryID)
 	return nil
 }
 
 // ListRegistries returns all configured registry IDs
 func (rm *RegistryManager) ListRegistries() []string {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
 	ids := make([]string, 0, len(rm.providers))
 	for id := range rm.providers {
 		ids = append(ids, id)
@@ -0,0 +1,232 @@
+// Package ratelimit provides in-memory token bucket rate limiting and
+// exponential backoff lockouts for repeated authentication failures.
+package ratelimit
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Config holds rate limit settings. Rates are requests per second.
+type Config struct {
+	Enabled bool
+
+	IPRate    float64
+	IPBurst   int
+	UserRate  float64
+	UserBurst int
+
+	// Login attempts per IP, applied in addition to the IP limit
+	LoginRate  float64
+	LoginBurst int
+
+	// After LockoutThreshold consecutive failures, further attempts are refused for
+	// LockoutBase, doubling with each additional failure up to LockoutMax
+	LockoutThreshold int
+	LockoutBase      time.Duration
+	LockoutMax       time.Duration
+}
+
+// DefaultConfig returns the default limits
+func DefaultConfig() Config {
+	return Config{
+		Enabled:          true,
+		IPRate:           20,
+		IPBurst:          60,
+		UserRate:         10,
+		UserBurst:        40,
+		LoginRate:        0.2, // one attempt every five seconds
+		LoginBurst:       5,
+		LockoutThreshold: 5,
+		LockoutBase:      30 * time.Second,
+		LockoutMax:       time.Hour,
+	}
+}
+
+// LoadConfig reads RATE_LIMIT_* environment variables over the defaults
+func LoadConfig() Config {
+	config := DefaultConfig()
+	if v, err := strconv.ParseBool(os.Getenv("RATE_LIMIT_ENABLED")); err == nil {
+		config.Enabled = v
+	}
+	envFloat("RATE_LIMIT_IP_RATE", &config.IPRate)
+	envInt("RATE_LIMIT_IP_BURST", &config.IPBurst)
+	envFloat("RATE_LIMIT_USER_RATE", &config.UserRate)
+	envInt("RATE_LIMIT_USER_BURST", &config.UserBurst)
+	envFloat("RATE_LIMIT_LOGIN_RATE", &config.LoginRate)
+	envInt("RATE_LIMIT_LOGIN_BURST", &config.LoginBurst)
+	envInt("LOGIN_LOCKOUT_THRESHOLD", &config.LockoutThreshold)
+	envDuration("LOGIN_LOCKOUT_BASE", &config.LockoutBase)
+	envDuration("LOGIN_LOCKOUT_MAX", &config.LockoutMax)
+	return config
+}
+
+func envFloat(key string, target *float64) {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && v > 0 {
+		*target = v
+	}
+}
+
+func envInt(key string, target *int) {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		*target = v
+	}
+}
+
+func envDuration(key string, target *time.Duration) {
+	if v, err := time.ParseDuration(os.Getenv(key)); err == nil && v > 0 {
+		*target = v
+	}
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a keyed token bucket limiter
+type Limiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewLimiter allows rate requests per second per key with bursts of up to burst requests
+func NewLimiter(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from key's bucket. When the bucket is empty it returns false
+// and how long until a token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// Cleanup drops buckets that have refilled completely, which behave like new ones
+func (l *Limiter) Cleanup() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+type failures struct {
+	count       int
+	lockedUntil time.Time
+	last        time.Time
+}
+
+// Lockout tracks consecutive failures per key and refuses attempts with exponential backoff
+type Lockout struct {
+	threshold int
+	base      time.Duration
+	max       time.Duration
+	now       func() time.Time
+
+	mutex sync.Mutex
+	keys  map[string]*failures
+}
+
+// NewLockout locks a key for base after threshold consecutive failures, doubling up to max
+func NewLockout(threshold int, base, max time.Duration) *Lockout {
+	return &Lockout{
+		threshold: threshold,
+		base:      base,
+		max:       max,
+		now:       time.Now,
+		keys:      make(map[string]*failures),
+	}
+}
+
+// Locked returns how long key remains locked, or zero
+func (l *Lockout) Locked(key string) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if f, ok := l.keys[key]; ok {
+		if remaining := f.lockedUntil.Sub(l.now()); remaining > 0 {
+			return remaining
+		}
+	}
+	return 0
+}
+
+// Failure records a failed attempt and returns the resulting lock duration, if any
+func (l *Lockout) Failure(key string) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	f, ok := l.keys[key]
+	if !ok {
+		f = &failures{}
+		l.keys[key] = f
+	}
+	f.count++
+	f.last = now
+	if f.count < l.threshold {
+		return 0
+	}
+
+	lock := l.max
+	if shift := f.count - l.threshold; shift < 32 {
+		if d := l.base << shift; d > 0 && d < l.max {
+			lock = d
+		}
+	}
+	f.lockedUntil = now.Add(lock)
+	return lock
+}
+
+// Success clears key's failures
+func (l *Lockout) Success(key string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.keys, key)
+}
+
+// Cleanup forgets keys whose lock expired and that have not failed for the maximum lock duration
+func (l *Lockout) Cleanup() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	for key, f := range l.keys {
+		if now.After(f.lockedUntil) && now.Sub(f.last) > l.max {
+			delete(l.keys, key)
+		}
+	}
+}
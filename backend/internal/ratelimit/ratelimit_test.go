@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestLimiter(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	limiter := NewLimiter(1, 3)
+	limiter.now = clock.now
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Fatalf("request %d within burst was refused", i+1)
+		}
+	}
+	ok, wait := limiter.Allow("a")
+	if ok {
+		t.Fatal("expected request beyond burst to be refused")
+	}
+	if wait != time.Second {
+		t.Errorf("retry after = %v, want 1s", wait)
+	}
+	if ok, _ := limiter.Allow("b"); !ok {
+		t.Error("keys should not share a bucket")
+	}
+
+	clock.advance(time.Second)
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Error("expected a token after refill")
+	}
+
+	clock.advance(10 * time.Second)
+	limiter.Cleanup()
+	if len(limiter.buckets) != 0 {
+		t.Errorf("expected refilled buckets to be dropped, %d left", len(limiter.buckets))
+	}
+}
+
+func TestLockout(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	lockout := NewLockout(3, 10*time.Second, time.Minute)
+	lockout.now = clock.now
+
+	for i := 0; i < 2; i++ {
+		if d := lockout.Failure("alice"); d != 0 {
+			t.Fatalf("failure %d locked for %v before the threshold", i+1, d)
+		}
+	}
+
+	// Lock duration doubles per failure beyond the threshold, capped at the maximum
+	for _, want := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute} {
+		if got := lockout.Failure("alice"); got != want {
+			t.Errorf("lock = %v, want %v", got, want)
+		}
+	}
+	if lockout.Locked("alice") != time.Minute {
+		t.Errorf("expected alice to be locked for a minute, got %v", lockout.Locked("alice"))
+	}
+	if lockout.Locked("bob") != 0 {
+		t.Error("bob should not be locked")
+	}
+
+	clock.advance(time.Minute)
+	if lockout.Locked("alice") != 0 {
+		t.Error("expected lock to expire")
+	}
+	lockout.Success("alice")
+	if d := lockout.Failure("alice"); d != 0 {
+		t.Errorf("expected success to reset failures, got lock %v", d)
+	}
+
+	clock.advance(2 * time.Minute)
+	lockout.Cleanup()
+	if len(lockout.keys) != 0 {
+		t.Errorf("expected stale keys to be dropped, %d left", len(lockout.keys))
+	}
+}
@@ -0,0 +1,166 @@
+package recordings
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+)
+
+// castHeader is the first line of an asciicast v2 file
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     uint16            `json:"width"`
+	Height    uint16            `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recording writes terminal events to an asciicast v2 file
+type Recording struct {
+	store     *Store
+	id        string
+	file      *os.File
+	writer    *bufio.Writer
+	started   time.Time
+	inputMode InputMode
+	size      int64
+	closed    bool
+	mu        sync.Mutex
+}
+
+func newRecording(store *Store, id string, file *os.File, started time.Time, inputMode InputMode) *Recording {
+	return &Recording{
+		store:     store,
+		id:        id,
+		file:      file,
+		writer:    bufio.NewWriter(file),
+		started:   started,
+		inputMode: inputMode,
+	}
+}
+
+// ID returns the session ID of the recording
+func (r *Recording) ID() string {
+	return r.id
+}
+
+// writeHeader writes the asciicast header describing the session
+func (r *Recording) writeHeader(info k8s.ExecSessionInfo) error {
+	width, height := info.Cols, info.Rows
+	if width == 0 || height == 0 {
+		width, height = 80, 24
+	}
+
+	title := fmt.Sprintf("%s/%s (%s): %s", info.Namespace, info.Pod, info.Container, info.Command)
+	if info.Node != "" {
+		title = fmt.Sprintf("node/%s: %s", info.Node, info.Command)
+	}
+
+	header, err := json.Marshal(castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.started.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": "xterm-256color"},
+	})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.writeLine(header)
+}
+
+// RecordOutput records terminal output
+func (r *Recording) RecordOutput(data []byte) {
+	r.writeEvent("o", string(data))
+}
+
+// RecordInput records operator input according to the store's input mode
+func (r *Recording) RecordInput(data []byte) {
+	if r.inputMode != InputHashed {
+		return
+	}
+	sum := sha256.Sum256(data)
+	r.writeEvent("i", "sha256:"+hex.EncodeToString(sum[:]))
+}
+
+// RecordResize records a terminal resize
+func (r *Recording) RecordResize(cols, rows uint16) {
+	r.writeEvent("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// RecordMarker records a marker event, such as a viewer joining a shared session
+func (r *Recording) RecordMarker(label string) {
+	r.writeEvent("m", label)
+}
+
+// SetUser attributes the recording to an authenticated user
+func (r *Recording) SetUser(user string) {
+	if user != "" {
+		r.store.setUser(r.id, user)
+	}
+}
+
+// AddParticipant notes a user who joined the session
+func (r *Recording) AddParticipant(user string) {
+	if user != "" {
+		r.store.addParticipant(r.id, user)
+	}
+}
+
+// Close flushes the cast file and records the session's final duration and size
+func (r *Recording) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	flushErr := r.writer.Flush()
+	closeErr := r.file.Close()
+
+	now := time.Now()
+	r.store.finish(r.id, now, now.Sub(r.started).Seconds(), r.size)
+
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
+
+// writeEvent appends an [elapsed, type, data] event line
+func (r *Recording) writeEvent(eventType, data string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+
+	elapsed := time.Since(r.started).Seconds()
+	line, err := json.Marshal([]interface{}{elapsed, eventType, data})
+	if err != nil {
+		return
+	}
+	r.writeLine(line)
+}
+
+// writeLine writes a newline-terminated line; callers must hold mu
+func (r *Recording) writeLine(line []byte) error {
+	n, err := r.writer.Write(append(line, '\n'))
+	r.size += int64(n)
+	return err
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_exec_sessions_started_at;
+DROP TABLE IF EXISTS exec_sessions;
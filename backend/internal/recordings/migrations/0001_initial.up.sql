@@ -0,0 +1,17 @@
+-- Recorded exec sessions; the asciicast itself is kept on disk at file_path
+CREATE TABLE IF NOT EXISTS exec_sessions (
+	id TEXT PRIMARY KEY,
+	namespace TEXT NOT NULL,
+	pod TEXT NOT NULL,
+	container TEXT NOT NULL,
+	command TEXT NOT NULL,
+	user TEXT NOT NULL,
+	input_mode TEXT NOT NULL,
+	file_path TEXT NOT NULL,
+	started_at TIMESTAMP NOT NULL,
+	ended_at TIMESTAMP,
+	duration REAL NOT NULL DEFAULT 0,
+	size INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_exec_sessions_started_at ON exec_sessions(started_at);
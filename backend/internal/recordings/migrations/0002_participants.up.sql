@@ -0,0 +1,2 @@
+-- Comma-separated users who joined a shared session
+ALTER TABLE exec_sessions ADD COLUMN participants TEXT NOT NULL DEFAULT '';
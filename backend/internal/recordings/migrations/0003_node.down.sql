@@ -0,0 +1 @@
+ALTER TABLE exec_sessions DROP COLUMN node;
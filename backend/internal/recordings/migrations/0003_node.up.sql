@@ -0,0 +1,2 @@
+-- Node of a node shell session
+ALTER TABLE exec_sessions ADD COLUMN node TEXT NOT NULL DEFAULT '';
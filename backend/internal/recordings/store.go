@@ -0,0 +1,274 @@
+// Package recordings persists interactive terminal sessions as asciicast v2 files for audit and replay.
+package recordings
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/database/migrate"
+	"github.com/archellir/denshimon/internal/k8s"
+	"github.com/google/uuid"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations is the schema of the exec session table
+var Migrations = migrate.NewSource("recordings", migrationFiles, "migrations")
+
+// InputMode controls how keystrokes are captured in recordings
+type InputMode string
+
+const (
+	// InputSuppressed omits operator input entirely
+	InputSuppressed InputMode = "suppressed"
+	// InputHashed records a SHA-256 digest of each input chunk
+	InputHashed InputMode = "hashed"
+)
+
+// Session represents a recorded exec session
+type Session struct {
+	ID        string     `json:"id"`
+	Namespace string     `json:"namespace"`
+	Pod       string     `json:"pod"`
+	Container string     `json:"container"`
+	Command   string     `json:"command"`
+	Node      string     `json:"node,omitempty"`
+	User      string     `json:"user"`
+	InputMode InputMode  `json:"inputMode"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+	Duration  float64    `json:"duration"`
+	Size      int64      `json:"size"`
+	// Participants joined the session through a share link
+	Participants []string `json:"participants,omitempty"`
+}
+
+// Filter narrows session listings
+type Filter struct {
+	Namespace string
+	Pod       string
+	Node      string
+	User      string
+	Limit     int
+}
+
+// Store manages recording metadata in SQLite and cast files on disk
+type Store struct {
+	db        *sql.DB
+	dir       string
+	inputMode InputMode
+}
+
+// NewStore creates a recording store writing cast files under dir
+func NewStore(db *sql.DB, dir string, inputMode InputMode) (*Store, error) {
+	if inputMode != InputHashed {
+		inputMode = InputSuppressed
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create recordings directory: %w", err)
+	}
+
+	store := &Store{
+		db:        db,
+		dir:       dir,
+		inputMode: inputMode,
+	}
+
+	if err := store.initDB(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// initDB migrates the exec session table to the latest schema version
+func (s *Store) initDB() error {
+	return migrate.Up(context.Background(), s.db, Migrations)
+}
+
+// Start creates a new recording for an exec session
+func (s *Store) Start(info k8s.ExecSessionInfo) (k8s.SessionRecorder, error) {
+	id := uuid.New().String()
+	path := filepath.Join(s.dir, id+".cast")
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording file: %w", err)
+	}
+
+	now := time.Now()
+	user := info.User
+	if user == "" {
+		user = "anonymous"
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO exec_sessions (id, namespace, pod, container, command, node, user, input_mode, file_path, started_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, info.Namespace, info.Pod, info.Container, info.Command, info.Node, user, s.inputMode, path, now)
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, err
+	}
+
+	recording := newRecording(s, id, file, now, s.inputMode)
+	if err := recording.writeHeader(info); err != nil {
+		recording.Close()
+		return nil, err
+	}
+	return recording, nil
+}
+
+// List returns recorded sessions, newest first
+func (s *Store) List(filter Filter) ([]Session, error) {
+	query := `
+		SELECT id, namespace, pod, container, command, node, user, input_mode, started_at, ended_at, duration, size, participants
+		FROM exec_sessions
+	`
+	var conditions []string
+	var args []interface{}
+
+	if filter.Namespace != "" {
+		conditions = append(conditions, "namespace = ?")
+		args = append(args, filter.Namespace)
+	}
+	if filter.Pod != "" {
+		conditions = append(conditions, "pod = ?")
+		args = append(args, filter.Pod)
+	}
+	if filter.Node != "" {
+		conditions = append(conditions, "node = ?")
+		args = append(args, filter.Node)
+	}
+	if filter.User != "" {
+		conditions = append(conditions, "user = ?")
+		args = append(args, filter.User)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY started_at DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, rows.Err()
+}
+
+// Get returns a recorded session by ID
+func (s *Store) Get(id string) (*Session, error) {
+	row := s.db.QueryRow(`
+		SELECT id, namespace, pod, container, command, node, user, input_mode, started_at, ended_at, duration, size, participants
+		FROM exec_sessions WHERE id = ?
+	`, id)
+	session, err := scanSession(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session not found: %s", id)
+	}
+	return session, err
+}
+
+// Open returns the asciicast file of a recorded session
+func (s *Store) Open(id string) (*os.File, error) {
+	var path string
+	err := s.db.QueryRow("SELECT file_path FROM exec_sessions WHERE id = ?", id).Scan(&path)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session not found: %s", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete removes a recorded session and its cast file
+func (s *Store) Delete(id string) error {
+	var path string
+	if err := s.db.QueryRow("SELECT file_path FROM exec_sessions WHERE id = ?", id).Scan(&path); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("session not found: %s", id)
+		}
+		return err
+	}
+
+	if _, err := s.db.Exec("DELETE FROM exec_sessions WHERE id = ?", id); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// finish records the final duration and size of a session
+func (s *Store) finish(id string, endedAt time.Time, duration float64, size int64) {
+	s.db.Exec(`
+		UPDATE exec_sessions SET ended_at = ?, duration = ?, size = ?
+		WHERE id = ?
+	`, endedAt, duration, size, id)
+}
+
+// addParticipant adds a user to a session's participants unless already listed
+func (s *Store) addParticipant(id, user string) {
+	s.db.Exec(`
+		UPDATE exec_sessions SET participants = CASE
+			WHEN participants = '' THEN ?1
+			WHEN instr(',' || participants || ',', ',' || ?1 || ',') > 0 THEN participants
+			ELSE participants || ',' || ?1
+		END
+		WHERE id = ?2
+	`, user, id)
+}
+
+// setUser attributes a session to the user who authenticated on the socket
+func (s *Store) setUser(id, user string) {
+	s.db.Exec("UPDATE exec_sessions SET user = ? WHERE id = ?", user, id)
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSession(row scanner) (*Session, error) {
+	var session Session
+	var endedAt sql.NullTime
+	var participants string
+
+	err := row.Scan(
+		&session.ID, &session.Namespace, &session.Pod, &session.Container, &session.Command, &session.Node,
+		&session.User, &session.InputMode, &session.StartedAt, &endedAt, &session.Duration, &session.Size, &participants,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if participants != "" {
+		session.Participants = strings.Split(participants, ",")
+	}
+	if endedAt.Valid {
+		session.EndedAt = &endedAt.Time
+	}
+	return &session, nil
+}
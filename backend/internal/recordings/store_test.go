@@ -0,0 +1,192 @@
+package recordings
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestStore(t *testing.T, mode InputMode) *Store {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store, err := NewStore(db, filepath.Join(t.TempDir(), "recordings"), mode)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	return store
+}
+
+func readCast(t *testing.T, store *Store, id string) (castHeader, [][]interface{}) {
+	t.Helper()
+
+	file, err := store.Open(id)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan()
+	var header castHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		t.Fatalf("Invalid header: %v", err)
+	}
+
+	var events [][]interface{}
+	for scanner.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Invalid event line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return header, events
+}
+
+func TestRecordingLifecycle(t *testing.T) {
+	store := setupTestStore(t, InputSuppressed)
+
+	recorder, err := store.Start(k8s.ExecSessionInfo{
+		Namespace: "default",
+		Pod:       "web-0",
+		Container: "web",
+		Command:   "/bin/sh",
+	})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	id := recorder.(*Recording).ID()
+
+	recorder.RecordOutput([]byte("$ "))
+	recorder.RecordInput([]byte("secret-password\n"))
+	recorder.RecordResize(120, 40)
+	recorder.SetUser("alice")
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Events after close are ignored
+	recorder.RecordOutput([]byte("late"))
+
+	header, events := readCast(t, store, id)
+	if header.Version != 2 || header.Width != 80 || header.Height != 24 {
+		t.Errorf("unexpected header: %+v", header)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected output and resize events with input suppressed, got %v", events)
+	}
+	if events[0][1] != "o" || events[0][2] != "$ " {
+		t.Errorf("unexpected output event: %v", events[0])
+	}
+	if events[1][1] != "r" || events[1][2] != "120x40" {
+		t.Errorf("unexpected resize event: %v", events[1])
+	}
+
+	session, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if session.User != "alice" || session.EndedAt == nil || session.Size == 0 {
+		t.Errorf("unexpected session metadata: %+v", session)
+	}
+
+	sessions, err := store.List(Filter{User: "alice"})
+	if err != nil || len(sessions) != 1 {
+		t.Errorf("expected one session for alice, got %d (err %v)", len(sessions), err)
+	}
+
+	if err := store.Delete(id); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Open(id); err == nil {
+		t.Error("expected recording to be gone after delete")
+	}
+}
+
+func TestRecordingHashesInput(t *testing.T) {
+	store := setupTestStore(t, InputHashed)
+
+	recorder, err := store.Start(k8s.ExecSessionInfo{Namespace: "default", Pod: "web-0"})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	recorder.RecordInput([]byte("secret-password\n"))
+	recorder.Close()
+
+	_, events := readCast(t, store, recorder.(*Recording).ID())
+	if len(events) != 1 || events[0][1] != "i" {
+		t.Fatalf("expected one input event, got %v", events)
+	}
+	data := events[0][2].(string)
+	if !strings.HasPrefix(data, "sha256:") || strings.Contains(data, "secret") {
+		t.Errorf("input was not hashed: %q", data)
+	}
+}
+
+func TestRecordingSharedSession(t *testing.T) {
+	store := setupTestStore(t, InputSuppressed)
+
+	recorder, err := store.Start(k8s.ExecSessionInfo{Namespace: "default", Pod: "web-0", User: "alice"})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	recorder.RecordMarker("bob joined")
+	recorder.AddParticipant("bob")
+	recorder.AddParticipant("carol")
+	recorder.AddParticipant("bob")
+	recorder.Close()
+
+	id := recorder.(*Recording).ID()
+	_, events := readCast(t, store, id)
+	if len(events) != 1 || events[0][1] != "m" || events[0][2] != "bob joined" {
+		t.Fatalf("expected one marker event, got %v", events)
+	}
+	session, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if strings.Join(session.Participants, ",") != "bob,carol" {
+		t.Errorf("expected bob and carol as participants once each, got %v", session.Participants)
+	}
+}
+
+func TestRecordingNodeShell(t *testing.T) {
+	store := setupTestStore(t, InputSuppressed)
+
+	recorder, err := store.Start(k8s.ExecSessionInfo{
+		Namespace: "kube-system", Pod: "denshimon-node-shell-x7k2p", Container: "shell",
+		Command: "nsenter", Node: "worker-1", User: "admin",
+	})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	recorder.Close()
+
+	id := recorder.(*Recording).ID()
+	header, _ := readCast(t, store, id)
+	if header.Title != "node/worker-1: nsenter" {
+		t.Errorf("unexpected title %q", header.Title)
+	}
+	sessions, err := store.List(Filter{Node: "worker-1"})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != id || sessions[0].Node != "worker-1" {
+		t.Errorf("expected the node shell session, got %+v", sessions)
+	}
+	if sessions, _ = store.List(Filter{Node: "worker-2"}); len(sessions) != 0 {
+		t.Errorf("expected no sessions on worker-2, got %+v", sessions)
+	}
+}
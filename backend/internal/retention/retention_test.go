@@ -0,0 +1,167 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/archellir/denshimon/internal/audit"
+	"github.com/archellir/denshimon/internal/providers"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// fakeRegistry serves a fixed image list and records deleted tags
+type fakeRegistry struct {
+	providers.RegistryProvider
+	images  []providers.ContainerImage
+	deleted []string
+	refuse  string
+}
+
+func (f *fakeRegistry) Type() string { return "fake" }
+
+func (f *fakeRegistry) ListImages(ctx context.Context, namespace string) ([]providers.ContainerImage, error) {
+	return f.images, nil
+}
+
+func (f *fakeRegistry) GetProvider(registryID string) (providers.RegistryProvider, error) {
+	if registryID != "registry-1" {
+		return nil, fmt.Errorf("registry provider not found: %s", registryID)
+	}
+	return f, nil
+}
+
+func (f *fakeRegistry) DeleteTag(ctx context.Context, repository, tag string) error {
+	if tag == f.refuse {
+		return fmt.Errorf("tag is in use")
+	}
+	f.deleted = append(f.deleted, repository+":"+tag)
+	return nil
+}
+
+func testImages(now time.Time) []providers.ContainerImage {
+	day := func(n int) time.Time { return now.AddDate(0, 0, -n) }
+	return []providers.ContainerImage{
+		{Repository: "team/api", Tag: "v5", Created: day(1)},
+		{Repository: "team/api", Tag: "v4", Created: day(10)},
+		{Repository: "team/api", Tag: "v3", Created: day(40)},
+		{Repository: "team/api", Tag: "release-1.0", Created: day(100)},
+		{Repository: "team/api", Tag: "v2", Created: day(50)},
+		{Repository: "team/api", Tag: "v1", Created: day(60)},
+		{Repository: "team/api", Tag: "mystery"},
+		{Repository: "team/web", Tag: "v1", Created: day(90)},
+		{Repository: "other/tool", Tag: "v1", Created: day(90)},
+	}
+}
+
+func tagNames(candidates []Candidate) string {
+	names := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		names[i] = candidate.Repository + ":" + candidate.Tag
+	}
+	return strings.Join(names, ",")
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	images := testImages(now)
+
+	tests := []struct {
+		name   string
+		policy Policy
+		want   string
+	}{
+		{"keep last", Policy{Repository: "team/*", KeepLast: 2, Protect: `^release-`}, "team/api:v3,team/api:v2,team/api:v1"},
+		{"older than", Policy{Repository: "team/api", OlderThanDays: 45}, "team/api:v2,team/api:v1,team/api:release-1.0"},
+		{"both", Policy{Repository: "team/api", KeepLast: 4, OlderThanDays: 30}, "team/api:v1,team/api:release-1.0"},
+		{"every repository", Policy{KeepLast: 5, Protect: `^release-`}, ""},
+	}
+	for _, tt := range tests {
+		plan := Evaluate(tt.policy, images, now)
+		if got := tagNames(plan.Delete); got != tt.want {
+			t.Errorf("%s: delete = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	plan := Evaluate(Policy{Repository: "team/*", KeepLast: 1, Protect: `^release-`}, images, now)
+	if plan.Repositories != 2 || plan.Tags != 8 || plan.Protected != 1 || plan.Undated != 1 {
+		t.Errorf("unexpected plan counts: %+v", plan)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Policy{Name: "api", RegistryID: "registry-1", KeepLast: 10}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	invalid := []Policy{
+		{Name: "no rules", RegistryID: "registry-1"},
+		{Name: "negative", RegistryID: "registry-1", KeepLast: -1},
+		{Name: "bad regex", RegistryID: "registry-1", KeepLast: 1, Protect: "("},
+		{Name: "bad glob", RegistryID: "registry-1", KeepLast: 1, Repository: "["},
+		{RegistryID: "registry-1", KeepLast: 1},
+	}
+	for _, policy := range invalid {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", policy)
+		}
+	}
+}
+
+func TestExecuteRecordsAudit(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	registry := &fakeRegistry{images: testImages(time.Now()), refuse: "v2"}
+	service, err := NewService(db, registry)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	auditLog, err := audit.NewStore(db)
+	if err != nil {
+		t.Fatalf("Failed to create audit store: %v", err)
+	}
+	service.SetAuditStore(auditLog)
+
+	ctx := context.Background()
+	policy, err := service.Create(ctx, Policy{Name: "api", RegistryID: "registry-1", Repository: "team/api", KeepLast: 2, Protect: `^release-`}, "alice")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	plan, err := service.Preview(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if len(plan.Delete) != 3 || len(registry.deleted) != 0 {
+		t.Fatalf("preview should only plan deletions: %v %v", plan.Delete, registry.deleted)
+	}
+
+	run, err := service.Execute(ctx, policy.ID, "alice")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if strings.Join(registry.deleted, ",") != "team/api:v3,team/api:v1" || len(run.Failed) != 1 {
+		t.Errorf("unexpected run: deleted %v, failed %+v", registry.deleted, run.Failed)
+	}
+
+	stored, _ := service.Get(ctx, policy.ID)
+	if stored.LastRunAt == nil || stored.LastRunResult != "deleted 2 of 3 tags, 1 failed" {
+		t.Errorf("run not recorded on the policy: %+v", stored)
+	}
+
+	entries, err := auditLog.List(ctx, audit.Query{Action: "registry.retention"})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].User != "alice" || entries[0].Success || entries[0].Resource != "registry/registry-1" {
+		t.Errorf("unexpected audit entries: %+v", entries)
+	}
+}
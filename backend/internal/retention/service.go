@@ -0,0 +1,251 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/audit"
+	"github.com/archellir/denshimon/internal/providers"
+	"github.com/google/uuid"
+)
+
+// AuditAction is the audit trail action recorded for every executed policy
+const AuditAction = "registry.retention.run"
+
+// Registries resolves registry IDs to their providers
+type Registries interface {
+	GetProvider(registryID string) (providers.RegistryProvider, error)
+}
+
+// Service stores retention policies, previews them and executes them on demand or
+// on a schedule
+type Service struct {
+	store      *Store
+	registries Registries
+	auditLog   *audit.Store
+
+	mutex   sync.Mutex
+	running map[string]bool
+}
+
+// NewService creates a retention service backed by the given database
+func NewService(db *sql.DB, registries Registries) (*Service, error) {
+	store, err := NewStore(db)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{
+		store:      store,
+		registries: registries,
+		running:    make(map[string]bool),
+	}, nil
+}
+
+// SetAuditStore sets the audit trail executed runs are recorded in
+func (s *Service) SetAuditStore(auditLog *audit.Store) {
+	s.auditLog = auditLog
+}
+
+// Start executes every policy that is not paused each interval until ctx is cancelled
+func (s *Service) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.runScheduled(ctx)
+			}
+		}
+	}()
+}
+
+func (s *Service) runScheduled(ctx context.Context) {
+	policies, err := s.store.List(ctx)
+	if err != nil {
+		slog.Error("Failed to list retention policies", "error", err)
+		return
+	}
+	for _, policy := range policies {
+		if policy.Paused {
+			continue
+		}
+		run, err := s.execute(ctx, policy, audit.SystemUser)
+		if err != nil {
+			slog.Error("Image retention policy failed", "policy", policy.Name, "error", err)
+			continue
+		}
+		slog.Info("Image retention policy executed", "policy", policy.Name, "result", run.Summary())
+	}
+}
+
+// List returns all policies
+func (s *Service) List(ctx context.Context) ([]Policy, error) {
+	return s.store.List(ctx)
+}
+
+// Get returns one policy
+func (s *Service) Get(ctx context.Context, id string) (*Policy, error) {
+	return s.store.Get(ctx, id)
+}
+
+// Create validates and stores a new policy
+func (s *Service) Create(ctx context.Context, policy Policy, createdBy string) (*Policy, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	policy.ID = uuid.New().String()
+	policy.CreatedBy = createdBy
+	policy.CreatedAt = now
+	policy.UpdatedAt = now
+	policy.LastRunAt = nil
+	policy.LastRunResult = ""
+	if err := s.store.Save(ctx, &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Update replaces a policy's settings
+func (s *Service) Update(ctx context.Context, id string, policy Policy) (*Policy, error) {
+	existing, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	policy.ID = existing.ID
+	policy.CreatedBy = existing.CreatedBy
+	policy.CreatedAt = existing.CreatedAt
+	policy.UpdatedAt = time.Now()
+	policy.LastRunAt = existing.LastRunAt
+	policy.LastRunResult = existing.LastRunResult
+	if err := s.store.Save(ctx, &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Delete removes a policy
+func (s *Service) Delete(ctx context.Context, id string) error {
+	return s.store.Delete(ctx, id)
+}
+
+// Preview evaluates a policy against the registry without deleting anything
+func (s *Service) Preview(ctx context.Context, id string) (*Plan, error) {
+	policy, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := s.registries.GetProvider(policy.RegistryID)
+	if err != nil {
+		return nil, err
+	}
+	return s.plan(ctx, *policy, provider)
+}
+
+// Execute runs a policy now, deleting the tags it selects
+func (s *Service) Execute(ctx context.Context, id, user string) (*Run, error) {
+	policy, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.execute(ctx, *policy, user)
+}
+
+func (s *Service) plan(ctx context.Context, policy Policy, provider providers.RegistryProvider) (*Plan, error) {
+	images, err := provider.ListImages(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list registry images: %w", err)
+	}
+	return Evaluate(policy, images, time.Now()), nil
+}
+
+func (s *Service) execute(ctx context.Context, policy Policy, user string) (*Run, error) {
+	s.mutex.Lock()
+	if s.running[policy.ID] {
+		s.mutex.Unlock()
+		return nil, fmt.Errorf("policy %s is already running", policy.Name)
+	}
+	s.running[policy.ID] = true
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.running, policy.ID)
+		s.mutex.Unlock()
+	}()
+
+	run, err := s.deleteTags(ctx, policy)
+	if err != nil {
+		s.record(ctx, policy, user, nil, err)
+		return nil, err
+	}
+	s.record(ctx, policy, user, run, nil)
+	return run, nil
+}
+
+func (s *Service) deleteTags(ctx context.Context, policy Policy) (*Run, error) {
+	provider, err := s.registries.GetProvider(policy.RegistryID)
+	if err != nil {
+		return nil, err
+	}
+	deleter, ok := provider.(providers.TagDeleter)
+	if !ok {
+		return nil, fmt.Errorf("%s registries do not support tag deletion", provider.Type())
+	}
+	plan, err := s.plan(ctx, policy, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &Run{Plan: *plan, Deleted: []Candidate{}, Failed: []Failure{}}
+	for _, candidate := range plan.Delete {
+		if err := deleter.DeleteTag(ctx, candidate.Repository, candidate.Tag); err != nil {
+			run.Failed = append(run.Failed, Failure{Candidate: candidate, Error: err.Error()})
+			continue
+		}
+		run.Deleted = append(run.Deleted, candidate)
+	}
+	run.FinishedAt = time.Now()
+	return run, nil
+}
+
+// record stores the outcome on the policy and in the audit trail
+func (s *Service) record(ctx context.Context, policy Policy, user string, run *Run, runErr error) {
+	entry := audit.Entry{
+		User:     user,
+		Action:   AuditAction,
+		Resource: "registry/" + policy.RegistryID,
+		Success:  runErr == nil && len(run.Failed) == 0,
+	}
+	if runErr != nil {
+		entry.Message = fmt.Sprintf("Retention policy %s failed: %v", policy.Name, runErr)
+	} else {
+		entry.Message = fmt.Sprintf("Retention policy %s %s", policy.Name, run.Summary())
+		if details, err := json.Marshal(run); err == nil {
+			entry.Details = string(details)
+		}
+	}
+
+	result := entry.Message
+	if runErr == nil {
+		result = run.Summary()
+	}
+	if err := s.store.RecordRun(ctx, policy.ID, time.Now(), result); err != nil {
+		slog.Error("Failed to record retention run", "policy", policy.Name, "error", err)
+	}
+	if s.auditLog != nil {
+		if err := s.auditLog.Record(ctx, entry); err != nil {
+			slog.Error("Failed to audit retention run", "policy", policy.Name, "error", err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned for unknown policy IDs
+var ErrNotFound = errors.New("retention policy not found")
+
+// Store persists retention policies in SQLite
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a policy store and its table
+func NewStore(db *sql.DB) (*Store, error) {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS image_retention_policies (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			registry_id TEXT NOT NULL,
+			repository TEXT NOT NULL DEFAULT '',
+			keep_last INTEGER NOT NULL DEFAULT 0,
+			older_than_days INTEGER NOT NULL DEFAULT 0,
+			protect TEXT NOT NULL DEFAULT '',
+			paused BOOLEAN NOT NULL DEFAULT FALSE,
+			created_by TEXT NOT NULL DEFAULT '',
+			created_at INTEGER NOT NULL, -- unix milliseconds
+			updated_at INTEGER NOT NULL,
+			last_run_at INTEGER,
+			last_run_result TEXT NOT NULL DEFAULT ''
+		)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			return nil, fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+	return &Store{db: db}, nil
+}
+
+const policyColumns = `id, name, registry_id, repository, keep_last, older_than_days, protect, paused,
+	created_by, created_at, updated_at, last_run_at, last_run_result`
+
+func scanPolicy(scanner interface{ Scan(...interface{}) error }) (*Policy, error) {
+	var p Policy
+	var createdAt, updatedAt int64
+	var lastRunAt sql.NullInt64
+	if err := scanner.Scan(&p.ID, &p.Name, &p.RegistryID, &p.Repository, &p.KeepLast, &p.OlderThanDays,
+		&p.Protect, &p.Paused, &p.CreatedBy, &createdAt, &updatedAt, &lastRunAt, &p.LastRunResult); err != nil {
+		return nil, err
+	}
+	p.CreatedAt = time.UnixMilli(createdAt)
+	p.UpdatedAt = time.UnixMilli(updatedAt)
+	if lastRunAt.Valid {
+		t := time.UnixMilli(lastRunAt.Int64)
+		p.LastRunAt = &t
+	}
+	return &p, nil
+}
+
+// List returns all policies ordered by name
+func (s *Store) List(ctx context.Context) ([]Policy, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+policyColumns+` FROM image_retention_policies ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list retention policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []Policy{}
+	for rows.Next() {
+		policy, err := scanPolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan retention policy: %w", err)
+		}
+		policies = append(policies, *policy)
+	}
+	return policies, rows.Err()
+}
+
+// Get returns one policy
+func (s *Store) Get(ctx context.Context, id string) (*Policy, error) {
+	policy, err := scanPolicy(s.db.QueryRowContext(ctx, `SELECT `+policyColumns+` FROM image_retention_policies WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention policy: %w", err)
+	}
+	return policy, nil
+}
+
+// Save inserts or updates a policy's settings
+func (s *Store) Save(ctx context.Context, p *Policy) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO image_retention_policies (id, name, registry_id, repository, keep_last, older_than_days,
+			protect, paused, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name, registry_id = excluded.registry_id, repository = excluded.repository,
+			keep_last = excluded.keep_last, older_than_days = excluded.older_than_days,
+			protect = excluded.protect, paused = excluded.paused, updated_at = excluded.updated_at`,
+		p.ID, p.Name, p.RegistryID, p.Repository, p.KeepLast, p.OlderThanDays, p.Protect, p.Paused,
+		p.CreatedBy, p.CreatedAt.UnixMilli(), p.UpdatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to save retention policy: %w", err)
+	}
+	return nil
+}
+
+// RecordRun stores the time and summary of a policy's latest run
+func (s *Store) RecordRun(ctx context.Context, id string, at time.Time, result string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE image_retention_policies SET last_run_at = ?, last_run_result = ? WHERE id = ?`,
+		at.UnixMilli(), result, id)
+	if err != nil {
+		return fmt.Errorf("failed to record retention run: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a policy
+func (s *Store) Delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM image_retention_policies WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete retention policy: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+// Package retention garbage-collects container image tags according to per-registry
+// policies: keep the last N tags, delete tags older than a number of days, and
+// never touch tags matching a protect pattern.
+package retention
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/archellir/denshimon/internal/providers"
+)
+
+// Policy decides which tags of a registry's repositories are deleted. When both
+// KeepLast and OlderThanDays are set a tag must fail both to be deleted.
+type Policy struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	RegistryID    string     `json:"registry_id"`
+	Repository    string     `json:"repository"`      // glob such as team/*; empty matches every repository
+	KeepLast      int        `json:"keep_last"`       // newest unprotected tags kept per repository
+	OlderThanDays int        `json:"older_than_days"` // only tags pushed longer ago are deleted
+	Protect       string     `json:"protect"`         // regular expression of tags that are never deleted
+	Paused        bool       `json:"paused"`          // skipped by the scheduled executor
+	CreatedBy     string     `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	LastRunResult string     `json:"last_run_result,omitempty"`
+}
+
+// Validate checks a policy before it is saved
+func (p *Policy) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if p.RegistryID == "" {
+		return fmt.Errorf("registry_id is required")
+	}
+	if p.KeepLast < 0 || p.OlderThanDays < 0 {
+		return fmt.Errorf("keep_last and older_than_days cannot be negative")
+	}
+	if p.KeepLast == 0 && p.OlderThanDays == 0 {
+		return fmt.Errorf("set keep_last, older_than_days or both")
+	}
+	if p.Repository != "" {
+		if _, err := path.Match(p.Repository, ""); err != nil {
+			return fmt.Errorf("invalid repository pattern: %w", err)
+		}
+	}
+	if p.Protect != "" {
+		if _, err := regexp.Compile(p.Protect); err != nil {
+			return fmt.Errorf("invalid protect pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// Candidate is a tag the policy would delete
+type Candidate struct {
+	Repository string    `json:"repository"`
+	Tag        string    `json:"tag"`
+	Digest     string    `json:"digest,omitempty"`
+	Created    time.Time `json:"created"`
+}
+
+// Plan is the outcome of evaluating a policy against a registry's tags
+type Plan struct {
+	PolicyID     string      `json:"policy_id"`
+	RegistryID   string      `json:"registry_id"`
+	EvaluatedAt  time.Time   `json:"evaluated_at"`
+	Repositories int         `json:"repositories"`
+	Tags         int         `json:"tags"`
+	Protected    int         `json:"protected"`
+	Undated      int         `json:"undated"` // kept because the registry reports no push date
+	Delete       []Candidate `json:"delete"`
+}
+
+// Failure is a candidate the registry refused to delete
+type Failure struct {
+	Candidate
+	Error string `json:"error"`
+}
+
+// Run is the result of executing a policy
+type Run struct {
+	Plan
+	Deleted    []Candidate `json:"deleted"`
+	Failed     []Failure   `json:"failed"`
+	FinishedAt time.Time   `json:"finished_at"`
+}
+
+// Summary describes a run in one line
+func (r *Run) Summary() string {
+	return fmt.Sprintf("deleted %d of %d tags, %d failed", len(r.Deleted), len(r.Delete), len(r.Failed))
+}
+
+// Evaluate returns the tags the policy would delete from images, newest first
+// within each repository
+func Evaluate(policy Policy, images []providers.ContainerImage, now time.Time) *Plan {
+	plan := &Plan{
+		PolicyID:    policy.ID,
+		RegistryID:  policy.RegistryID,
+		EvaluatedAt: now,
+		Delete:      []Candidate{},
+	}
+	var protect *regexp.Regexp
+	if policy.Protect != "" {
+		protect = regexp.MustCompile(policy.Protect) // validated on save
+	}
+	cutoff := now.AddDate(0, 0, -policy.OlderThanDays)
+
+	byRepository := make(map[string][]providers.ContainerImage)
+	for _, image := range images {
+		if policy.Repository != "" {
+			if matched, _ := path.Match(policy.Repository, image.Repository); !matched {
+				continue
+			}
+		}
+		byRepository[image.Repository] = append(byRepository[image.Repository], image)
+	}
+
+	repositories := make([]string, 0, len(byRepository))
+	for repository := range byRepository {
+		repositories = append(repositories, repository)
+	}
+	sort.Strings(repositories)
+	plan.Repositories = len(repositories)
+
+	for _, repository := range repositories {
+		tags := byRepository[repository]
+		sort.SliceStable(tags, func(i, j int) bool {
+			if tags[i].Created.Equal(tags[j].Created) {
+				return tags[i].Tag > tags[j].Tag
+			}
+			return tags[i].Created.After(tags[j].Created)
+		})
+
+		kept := 0
+		for _, image := range tags {
+			plan.Tags++
+			switch {
+			case protect != nil && protect.MatchString(image.Tag):
+				plan.Protected++
+			case image.Created.IsZero():
+				// Without a push date the tag can't be ranked or aged, so leave it alone
+				plan.Undated++
+			case kept < policy.KeepLast:
+				kept++
+			case policy.OlderThanDays > 0 && image.Created.After(cutoff):
+			default:
+				plan.Delete = append(plan.Delete, Candidate{
+					Repository: image.Repository,
+					Tag:        image.Tag,
+					Digest:     image.Digest,
+					Created:    image.Created,
+				})
+			}
+		}
+	}
+	return plan
+}
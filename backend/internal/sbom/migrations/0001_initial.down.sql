@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_sbom_packages_name;
+DROP INDEX IF EXISTS idx_sbom_packages_sbom;
+DROP TABLE IF EXISTS sbom_packages;
+DROP TABLE IF EXISTS sboms;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS sboms (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	image TEXT NOT NULL,
+	digest TEXT NOT NULL DEFAULT '', -- sha256:..., empty when the image was scanned by tag
+	generated_at INTEGER NOT NULL, -- unix milliseconds
+	package_count INTEGER NOT NULL DEFAULT 0,
+	document BLOB NOT NULL, -- gzipped syft JSON
+	UNIQUE (image, digest)
+);
+
+CREATE TABLE IF NOT EXISTS sbom_packages (
+	sbom_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	version TEXT NOT NULL DEFAULT '',
+	type TEXT NOT NULL DEFAULT '', -- java-archive, deb, go-module, npm, ...
+	purl TEXT NOT NULL DEFAULT '',
+	licenses TEXT NOT NULL DEFAULT '[]', -- JSON
+	FOREIGN KEY (sbom_id) REFERENCES sboms(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sbom_packages_sbom ON sbom_packages(sbom_id);
+CREATE INDEX IF NOT EXISTS idx_sbom_packages_name ON sbom_packages(name COLLATE NOCASE);
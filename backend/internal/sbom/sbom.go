@@ -0,0 +1,575 @@
+// Package sbom generates software bills of materials for the images running in the
+// cluster with the syft binary, and answers which images contain a package or license.
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ErrUnavailable is returned when SBOMs cannot be generated because syft is not installed
+var ErrUnavailable = errors.New("syft is not available")
+
+// Retention is how long SBOMs of images that no longer run are kept
+const Retention = 30 * 24 * time.Hour
+
+// generateTimeout bounds one run of syft, which pulls the image
+const generateTimeout = 10 * time.Minute
+
+// Package is one component found in an image
+type Package struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Type     string   `json:"type"` // e.g. java-archive, deb, go-module
+	PURL     string   `json:"purl,omitempty"`
+	Licenses []string `json:"licenses"`
+}
+
+// SBOM is the bill of materials of one image
+type SBOM struct {
+	ID           int64     `json:"id"`
+	Image        string    `json:"image"`
+	Digest       string    `json:"digest,omitempty"`
+	GeneratedAt  time.Time `json:"generated_at"`
+	PackageCount int       `json:"package_count"`
+	Packages     []Package `json:"packages,omitempty"`
+	// Pods are the running pods (namespace/name) using the image, filled in by listings
+	Pods []string `json:"pods,omitempty"`
+}
+
+// PackageQuery selects packages across SBOMs
+type PackageQuery struct {
+	Name    string
+	Version string
+	Type    string
+	SBOMs   []int64 // limits the search to these SBOMs when set
+	Limit   int
+}
+
+// PackageMatch is a package found in an image
+type PackageMatch struct {
+	SBOMID  int64    `json:"sbom_id"`
+	Image   string   `json:"image"`
+	Digest  string   `json:"digest,omitempty"`
+	Package Package  `json:"package"`
+	Pods    []string `json:"pods,omitempty"`
+}
+
+// LicenseSummary is how widely a license is used
+type LicenseSummary struct {
+	License  string   `json:"license"`
+	Packages int      `json:"packages"` // distinct package names
+	Images   []string `json:"images"`
+}
+
+// ScanResult reports a scan of the running images
+type ScanResult struct {
+	Generated int      `json:"generated"`
+	Skipped   int      `json:"skipped"` // already had an SBOM
+	Pruned    int      `json:"pruned"`
+	Failed    []string `json:"failed"`
+}
+
+// Scan states
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// ErrScanInProgress is returned when a scan or generation is already running
+var ErrScanInProgress = errors.New("an SBOM scan is already running")
+
+// ScanStatus is the progress of a background scan of the running images, or of the
+// generation of one image's SBOM
+type ScanStatus struct {
+	Status     string      `json:"status"`
+	Image      string      `json:"image,omitempty"` // set when generating one image's SBOM
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Result     *ScanResult `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// RunningImage is an image used by running pods
+type RunningImage struct {
+	Image  string
+	Digest string
+	Pods   []string
+}
+
+// PodLister lists the pods of all namespaces
+type PodLister func(ctx context.Context) ([]corev1.Pod, error)
+
+// Service generates and queries SBOMs
+type Service struct {
+	store  *Store
+	binary string // syft; empty when not installed
+	pods   PodLister
+
+	mutex sync.Mutex
+	scan  *ScanStatus
+}
+
+// NewService creates a service generating SBOMs with the syft binary (SYFT_BINARY, default
+// "syft"); without it stored SBOMs can still be queried
+func NewService(store *Store) *Service {
+	binary := os.Getenv("SYFT_BINARY")
+	if binary == "" {
+		binary = "syft"
+	}
+	if _, err := exec.LookPath(binary); err != nil {
+		slog.Warn("Syft binary not found, SBOM generation disabled", "binary", binary)
+		binary = ""
+	}
+	return &Service{store: store, binary: binary}
+}
+
+// SetBinary sets the syft binary, or disables generation for ""
+func (s *Service) SetBinary(binary string) {
+	s.binary = binary
+}
+
+// Available reports whether SBOMs can be generated
+func (s *Service) Available() bool {
+	return s.binary != ""
+}
+
+// SetPodLister sets how running images are found
+func (s *Service) SetPodLister(pods PodLister) {
+	s.pods = pods
+}
+
+// Store returns the SBOM store
+func (s *Service) Store() *Store {
+	return s.store
+}
+
+// Generate creates and stores the SBOM of an image, pinned to digest when it is set
+func (s *Service) Generate(ctx context.Context, image, digest string) (*SBOM, error) {
+	if s.binary == "" {
+		return nil, ErrUnavailable
+	}
+	ctx, cancel := context.WithTimeout(ctx, generateTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.binary, pinnedReference(image, digest), "--output", "syft-json", "--quiet")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("syft failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	packages, err := ParseSyftJSON(stdout.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	sbom := &SBOM{Image: image, Digest: digest, GeneratedAt: time.Now(), Packages: packages}
+	if err := s.store.Save(ctx, sbom, stdout.Bytes()); err != nil {
+		return nil, err
+	}
+	return sbom, nil
+}
+
+// RunningImages returns the images of the running pods, with the digests they run
+func (s *Service) RunningImages(ctx context.Context) ([]RunningImage, error) {
+	if s.pods == nil {
+		return nil, fmt.Errorf("kubernetes client not available")
+	}
+	pods, err := s.pods(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	return RunningImagesOf(pods), nil
+}
+
+// RunningImagesOf collects the images of running pods, sorted by image
+func RunningImagesOf(pods []corev1.Pod) []RunningImage {
+	byKey := map[string]*RunningImage{}
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			image := status.Image
+			for _, container := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+				if container.Name == status.Name {
+					image = container.Image // the reference as deployed, not as resolved by the runtime
+				}
+			}
+			if image == "" {
+				continue
+			}
+			digest := imageDigest(status.ImageID)
+			key := image + "@" + digest
+			running := byKey[key]
+			if running == nil {
+				running = &RunningImage{Image: image, Digest: digest}
+				byKey[key] = running
+			}
+			name := pod.Namespace + "/" + pod.Name
+			if len(running.Pods) == 0 || running.Pods[len(running.Pods)-1] != name {
+				running.Pods = append(running.Pods, name)
+			}
+		}
+	}
+	images := make([]RunningImage, 0, len(byKey))
+	for _, running := range byKey {
+		images = append(images, *running)
+	}
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Image != images[j].Image {
+			return images[i].Image < images[j].Image
+		}
+		return images[i].Digest < images[j].Digest
+	})
+	return images
+}
+
+// ScanRunning generates SBOMs for running images that have none yet, and drops those of
+// images that stopped running more than Retention ago
+func (s *Service) ScanRunning(ctx context.Context) (*ScanResult, error) {
+	if s.binary == "" {
+		return nil, ErrUnavailable
+	}
+	status, err := s.begin("")
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.scanRunning(ctx)
+	s.finish(status, result, err)
+	return result, err
+}
+
+// StartScan scans the running images, or generates the SBOM of image when it is set, in
+// the background; LastScan reports its progress
+func (s *Service) StartScan(image, digest string) (*ScanStatus, error) {
+	if s.binary == "" {
+		return nil, ErrUnavailable
+	}
+	status, err := s.begin(image)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := *status
+	go func() {
+		ctx := context.Background()
+		if image == "" {
+			result, err := s.scanRunning(ctx)
+			s.finish(status, result, err)
+			return
+		}
+		result := &ScanResult{Failed: []string{}}
+		_, err := s.Generate(ctx, image, digest)
+		if err == nil {
+			result.Generated = 1
+		} else {
+			slog.Warn("Failed to generate SBOM", "image", image, "digest", digest, "error", err)
+		}
+		s.finish(status, result, err)
+	}()
+	return &snapshot, nil
+}
+
+// LastScan returns the running or last finished scan, or nil before the first
+func (s *Service) LastScan() *ScanStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.scan == nil {
+		return nil
+	}
+	snapshot := *s.scan
+	return &snapshot
+}
+
+// begin claims the scan slot, which one scan or generation holds at a time
+func (s *Service) begin(image string) (*ScanStatus, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.scan != nil && s.scan.Status == StatusRunning {
+		return nil, ErrScanInProgress
+	}
+	s.scan = &ScanStatus{Status: StatusRunning, Image: image, StartedAt: time.Now()}
+	return s.scan, nil
+}
+
+func (s *Service) finish(status *ScanStatus, result *ScanResult, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	status.FinishedAt = &now
+	status.Result = result
+	status.Status = StatusCompleted
+	if err != nil {
+		status.Status = StatusFailed
+		status.Error = err.Error()
+	}
+}
+
+func (s *Service) scanRunning(ctx context.Context) (*ScanResult, error) {
+	running, err := s.RunningImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	have := map[string]bool{}
+	for _, sbom := range stored {
+		have[sbom.Image+"@"+sbom.Digest] = true
+	}
+
+	result := &ScanResult{Failed: []string{}}
+	inUse := map[string]bool{}
+	for _, image := range running {
+		key := image.Image + "@" + image.Digest
+		inUse[key] = true
+		if have[key] {
+			result.Skipped++
+			continue
+		}
+		if _, err := s.Generate(ctx, image.Image, image.Digest); err != nil {
+			slog.Warn("Failed to generate SBOM", "image", image.Image, "digest", image.Digest, "error", err)
+			result.Failed = append(result.Failed, image.Image)
+			continue
+		}
+		result.Generated++
+	}
+
+	cutoff := time.Now().Add(-Retention)
+	for _, sbom := range stored {
+		if !inUse[sbom.Image+"@"+sbom.Digest] && sbom.GeneratedAt.Before(cutoff) {
+			if err := s.store.Delete(ctx, sbom.ID); err == nil {
+				result.Pruned++
+			}
+		}
+	}
+	return result, nil
+}
+
+// Start scans the running images each interval until ctx is cancelled
+func (s *Service) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				result, err := s.ScanRunning(ctx)
+				if err != nil {
+					slog.Error("SBOM scan failed", "error", err)
+					continue
+				}
+				slog.Info("SBOM scan completed", "generated", result.Generated, "skipped", result.Skipped, "pruned", result.Pruned, "failed", len(result.Failed))
+			}
+		}
+	}()
+}
+
+// List returns the stored SBOMs, with the pods running each when onlyRunning is set or
+// pods can be listed; onlyRunning drops the SBOMs of images no longer running
+func (s *Service) List(ctx context.Context, onlyRunning bool) ([]SBOM, error) {
+	sboms, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := s.runningPods(ctx, onlyRunning)
+	if err != nil {
+		return nil, err
+	}
+	if pods == nil {
+		return sboms, nil
+	}
+	filtered := []SBOM{}
+	for _, sbom := range sboms {
+		sbom.Pods = pods[sbom.Image+"@"+sbom.Digest]
+		if !onlyRunning || len(sbom.Pods) > 0 {
+			filtered = append(filtered, sbom)
+		}
+	}
+	return filtered, nil
+}
+
+// Search finds packages across SBOMs; with onlyRunning, only in images that are running,
+// each match naming the pods that run it
+func (s *Service) Search(ctx context.Context, query PackageQuery, onlyRunning bool) ([]PackageMatch, error) {
+	var pods map[string][]string
+	if onlyRunning {
+		var err error
+		if pods, err = s.runningPods(ctx, true); err != nil {
+			return nil, err
+		}
+		ids, err := s.runningSBOMs(ctx, pods)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return []PackageMatch{}, nil
+		}
+		query.SBOMs = ids
+	}
+	matches, err := s.store.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for i := range matches {
+		matches[i].Pods = pods[matches[i].Image+"@"+matches[i].Digest]
+	}
+	return matches, nil
+}
+
+// Licenses summarizes licenses across SBOMs, or only those of running images
+func (s *Service) Licenses(ctx context.Context, onlyRunning bool) ([]LicenseSummary, error) {
+	if !onlyRunning {
+		return s.store.Licenses(ctx, nil)
+	}
+	pods, err := s.runningPods(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.runningSBOMs(ctx, pods)
+	if err != nil {
+		return nil, err
+	}
+	if ids == nil {
+		ids = []int64{}
+	}
+	return s.store.Licenses(ctx, ids)
+}
+
+// runningPods maps image@digest to the pods running it. Without a pod lister it fails
+// when required, and returns nil otherwise.
+func (s *Service) runningPods(ctx context.Context, required bool) (map[string][]string, error) {
+	if s.pods == nil {
+		if required {
+			return nil, fmt.Errorf("kubernetes client not available")
+		}
+		return nil, nil
+	}
+	running, err := s.RunningImages(ctx)
+	if err != nil {
+		if required {
+			return nil, err
+		}
+		return nil, nil
+	}
+	pods := map[string][]string{}
+	for _, image := range running {
+		pods[image.Image+"@"+image.Digest] = image.Pods
+	}
+	return pods, nil
+}
+
+func (s *Service) runningSBOMs(ctx context.Context, pods map[string][]string) ([]int64, error) {
+	sboms, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for _, sbom := range sboms {
+		if len(pods[sbom.Image+"@"+sbom.Digest]) > 0 {
+			ids = append(ids, sbom.ID)
+		}
+	}
+	return ids, nil
+}
+
+// syftDocument is the part of syft's JSON output that is indexed
+type syftDocument struct {
+	Artifacts []struct {
+		Name     string          `json:"name"`
+		Version  string          `json:"version"`
+		Type     string          `json:"type"`
+		PURL     string          `json:"purl"`
+		Licenses json.RawMessage `json:"licenses"`
+	} `json:"artifacts"`
+}
+
+// ParseSyftJSON reads the packages of a syft-json document
+func ParseSyftJSON(data []byte) ([]Package, error) {
+	var document syftDocument
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to decode syft output: %w", err)
+	}
+	packages := make([]Package, 0, len(document.Artifacts))
+	for _, artifact := range document.Artifacts {
+		packages = append(packages, Package{
+			Name:     artifact.Name,
+			Version:  artifact.Version,
+			Type:     artifact.Type,
+			PURL:     artifact.PURL,
+			Licenses: parseLicenses(artifact.Licenses),
+		})
+	}
+	return packages, nil
+}
+
+// parseLicenses reads syft licenses, which are objects with an SPDX expression or value
+// in current releases and plain strings in older ones
+func parseLicenses(raw json.RawMessage) []string {
+	licenses := []string{}
+	if len(raw) == 0 {
+		return licenses
+	}
+	var names []string
+	if err := json.Unmarshal(raw, &names); err == nil {
+		return append(licenses, names...)
+	}
+	var objects []struct {
+		Value          string `json:"value"`
+		SPDXExpression string `json:"spdxExpression"`
+	}
+	if err := json.Unmarshal(raw, &objects); err != nil {
+		return licenses
+	}
+	seen := map[string]bool{}
+	for _, object := range objects {
+		license := object.SPDXExpression
+		if license == "" {
+			license = object.Value
+		}
+		if license != "" && !seen[license] {
+			seen[license] = true
+			licenses = append(licenses, license)
+		}
+	}
+	return licenses
+}
+
+// imageDigest extracts the sha256 digest from a container status image ID such as
+// docker-pullable://nginx@sha256:... or sha256:...
+func imageDigest(imageID string) string {
+	if i := strings.LastIndex(imageID, "sha256:"); i >= 0 {
+		return imageID[i:]
+	}
+	return ""
+}
+
+// pinnedReference is the image by digest when it is known, so syft reads exactly what runs
+func pinnedReference(image, digest string) string {
+	if digest == "" || strings.Contains(image, "@") {
+		return image
+	}
+	repository := image
+	if slash, colon := strings.LastIndex(image, "/"), strings.LastIndex(image, ":"); colon > slash {
+		repository = image[:colon]
+	}
+	return repository + "@" + digest
+}
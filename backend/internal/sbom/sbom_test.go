@@ -0,0 +1,251 @@
+package sbom
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// A stand-in for syft: the legacy image contains a vulnerable log4j with old-style
+// license strings, every other image a current one with license objects
+const fakeSyft = `#!/bin/sh
+case "$1" in
+*missing*) echo "image not found" >&2; exit 1 ;;
+*legacy*) echo '{"artifacts":[{"name":"log4j-core","version":"2.14.1","type":"java-archive","purl":"pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1","licenses":["Apache-2.0"]},{"name":"zlib","version":"1.2.11","type":"deb","licenses":[]}]}' ;;
+*) echo '{"artifacts":[{"name":"log4j-core","version":"2.17.1","type":"java-archive","licenses":[{"value":"Apache License 2.0","spdxExpression":"Apache-2.0"}]},{"name":"musl","version":"1.2.4","type":"apk","licenses":[{"value":"MIT"}]}]}' ;;
+esac
+`
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "syft")
+	if err := os.WriteFile(binary, []byte(fakeSyft), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := &Service{store: store}
+	service.SetBinary(binary)
+	return service
+}
+
+func runningPod(namespace, name, image, imageID string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", Image: "docker.io/library/" + image, ImageID: imageID}},
+		},
+	}
+}
+
+func TestParseSyftJSON(t *testing.T) {
+	packages, err := ParseSyftJSON([]byte(`{"artifacts":[
+		{"name":"a","version":"1","type":"npm","licenses":["MIT","ISC"]},
+		{"name":"b","version":"2","type":"npm","licenses":[{"value":"Apache License 2.0","spdxExpression":"Apache-2.0"},{"value":"Apache-2.0","spdxExpression":"Apache-2.0"},{"value":"BSD"}]},
+		{"name":"c","version":"3","type":"npm"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, pkg := range packages {
+		got = append(got, pkg.Name+"="+strings.Join(pkg.Licenses, "|"))
+	}
+	if strings.Join(got, " ") != "a=MIT|ISC b=Apache-2.0|BSD c=" {
+		t.Errorf("packages = %v", got)
+	}
+	if _, err := ParseSyftJSON([]byte("not json")); err == nil {
+		t.Error("expected invalid output to fail")
+	}
+}
+
+func TestScanAndSearch(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+	pods := []corev1.Pod{
+		runningPod("shop", "api-1", "shop/api:1.0", "docker-pullable://shop/api@sha256:aaa"),
+		runningPod("shop", "api-2", "shop/api:1.0", "docker-pullable://shop/api@sha256:aaa"),
+		runningPod("billing", "legacy-0", "billing/legacy:3", "sha256:bbb"),
+		runningPod("ops", "missing-0", "ops/missing:1", ""),
+	}
+	service.SetPodLister(func(ctx context.Context) ([]corev1.Pod, error) { return pods, nil })
+
+	result, err := service.ScanRunning(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Generated != 2 || result.Skipped != 0 || len(result.Failed) != 1 || result.Failed[0] != "ops/missing:1" {
+		t.Errorf("first scan = %+v", result)
+	}
+	if result, _ = service.ScanRunning(ctx); result.Generated != 0 || result.Skipped != 2 {
+		t.Errorf("second scan = %+v, want the stored images skipped", result)
+	}
+
+	// Which running images contain log4j 2.14.x?
+	matches, err := service.Search(ctx, PackageQuery{Name: "LOG4J", Version: "2.14.*"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Image != "billing/legacy:3" || matches[0].Digest != "sha256:bbb" ||
+		strings.Join(matches[0].Pods, ",") != "billing/legacy-0" {
+		t.Fatalf("matches = %+v", matches)
+	}
+	if matches, _ = service.Search(ctx, PackageQuery{Name: "log4j"}, false); len(matches) != 2 {
+		t.Errorf("all log4j versions = %+v", matches)
+	}
+	if matches, _ = service.Search(ctx, PackageQuery{Name: "log4j_core"}, false); len(matches) != 0 {
+		t.Errorf("_ should not act as a wildcard: %+v", matches)
+	}
+
+	licenses, err := service.Licenses(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := map[string]LicenseSummary{}
+	for _, license := range licenses {
+		summary[license.License] = license
+	}
+	if licenses[0].License != "Apache-2.0" || len(summary["Apache-2.0"].Images) != 2 || summary["Apache-2.0"].Packages != 1 {
+		t.Errorf("licenses = %+v", licenses)
+	}
+	if strings.Join(summary[unknownLicense].Images, ",") != "billing/legacy:3" || len(summary["MIT"].Images) != 1 {
+		t.Errorf("licenses = %+v", licenses)
+	}
+
+	// Once the legacy image stops running, running-only queries ignore it
+	pods = pods[:2]
+	if matches, _ = service.Search(ctx, PackageQuery{Name: "log4j", Version: "2.14.1"}, true); len(matches) != 0 {
+		t.Errorf("stopped image matched: %+v", matches)
+	}
+	if licenses, _ = service.Licenses(ctx, true); len(licenses) != 2 {
+		t.Errorf("running licenses = %+v", licenses)
+	}
+	sboms, err := service.List(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sboms) != 1 || sboms[0].Image != "shop/api:1.0" || strings.Join(sboms[0].Pods, ",") != "shop/api-1,shop/api-2" {
+		t.Errorf("running sboms = %+v", sboms)
+	}
+}
+
+func TestStoreDocumentAndRetention(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+
+	sbom, err := service.Generate(ctx, "shop/api:1.0", "sha256:aaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := service.Store().Get(ctx, sbom.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PackageCount != 2 || len(got.Packages) != 2 || got.Packages[0].Name != "log4j-core" {
+		t.Errorf("sbom = %+v", got)
+	}
+	document, err := service.Store().Document(ctx, sbom.ID)
+	if err != nil || !strings.Contains(string(document), `"artifacts"`) {
+		t.Errorf("document = %s, %v", document, err)
+	}
+
+	// Regenerating replaces the SBOM of the same image and digest
+	if _, err := service.Generate(ctx, "shop/api:1.0", "sha256:aaa"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Store().Get(ctx, sbom.ID); err != ErrNotFound {
+		t.Errorf("replaced sbom still stored: %v", err)
+	}
+	if matches, _ := service.Store().Search(ctx, PackageQuery{Name: "musl"}); len(matches) != 1 {
+		t.Errorf("packages of the replaced sbom kept: %+v", matches)
+	}
+
+	// SBOMs of images that stopped running long ago are pruned
+	old := &SBOM{Image: "old/app:1", GeneratedAt: time.Now().Add(-Retention - time.Hour)}
+	if err := service.Store().Save(ctx, old, []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	service.SetPodLister(func(ctx context.Context) ([]corev1.Pod, error) { return nil, nil })
+	result, err := service.ScanRunning(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Pruned != 1 {
+		t.Errorf("pruned = %d, want 1", result.Pruned)
+	}
+	if err := service.Store().Delete(ctx, old.ID); err != ErrNotFound {
+		t.Errorf("delete of pruned sbom = %v", err)
+	}
+
+	service.SetBinary("")
+	if _, err := service.Generate(ctx, "shop/api:1.0", ""); err != ErrUnavailable {
+		t.Errorf("generate without syft = %v", err)
+	}
+}
+
+func TestPinnedReference(t *testing.T) {
+	tests := map[string]string{
+		"nginx:1.25|sha256:abc":                "nginx@sha256:abc",
+		"registry:5000/team/app:v1|sha256:abc": "registry:5000/team/app@sha256:abc",
+		"registry:5000/team/app|sha256:abc":    "registry:5000/team/app@sha256:abc",
+		"nginx@sha256:def|sha256:abc":          "nginx@sha256:def",
+		"nginx:1.25|":                          "nginx:1.25",
+	}
+	for input, want := range tests {
+		image, digest, _ := strings.Cut(input, "|")
+		if got := pinnedReference(image, digest); got != want {
+			t.Errorf("pinnedReference(%q, %q) = %q, want %q", image, digest, got, want)
+		}
+	}
+}
+
+func TestStartScan(t *testing.T) {
+	service := newTestService(t)
+	if service.LastScan() != nil {
+		t.Fatal("expected no scan before the first")
+	}
+	status, err := service.StartScan("shop/api:1.0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != StatusRunning || status.Image != "shop/api:1.0" {
+		t.Errorf("status = %+v", status)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for service.LastScan().Status == StatusRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	last := service.LastScan()
+	if last.Status != StatusCompleted || last.Result == nil || last.Result.Generated != 1 || last.FinishedAt == nil {
+		t.Errorf("last scan = %+v", last)
+	}
+
+	// A failed generation is reported with its error
+	if _, err := service.StartScan("ops/missing:1", ""); err != nil {
+		t.Fatal(err)
+	}
+	for service.LastScan().Status == StatusRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if last = service.LastScan(); last.Status != StatusFailed || !strings.Contains(last.Error, "image not found") {
+		t.Errorf("failed scan = %+v", last)
+	}
+}
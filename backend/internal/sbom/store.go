@@ -0,0 +1,318 @@
+package sbom
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/database/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations is the schema of the SBOM store
+var Migrations = migrate.NewSource("sbom", migrationFiles, "migrations")
+
+// ErrNotFound is returned for SBOMs that do not exist
+var ErrNotFound = errors.New("sbom not found")
+
+// DefaultLimit caps the package matches returned by a search without a limit
+const DefaultLimit = 500
+
+// unknownLicense groups packages that declare no license
+const unknownLicense = "UNKNOWN"
+
+// Store keeps SBOMs and their packages in SQLite
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates an SBOM store, migrating its tables to the latest schema version
+func NewStore(db *sql.DB) (*Store, error) {
+	if err := migrate.Up(context.Background(), db, Migrations); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Save stores an SBOM, replacing the one of the same image and digest
+func (s *Store) Save(ctx context.Context, sbom *SBOM, document []byte) error {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(document); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sbom_packages WHERE sbom_id IN (SELECT id FROM sboms WHERE image = ? AND digest = ?)`, sbom.Image, sbom.Digest); err != nil {
+		return fmt.Errorf("failed to replace sbom: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sboms WHERE image = ? AND digest = ?`, sbom.Image, sbom.Digest); err != nil {
+		return fmt.Errorf("failed to replace sbom: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `INSERT INTO sboms (image, digest, generated_at, package_count, document) VALUES (?, ?, ?, ?, ?)`,
+		sbom.Image, sbom.Digest, sbom.GeneratedAt.UnixMilli(), len(sbom.Packages), compressed.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to save sbom: %w", err)
+	}
+	sbom.ID, _ = result.LastInsertId()
+	sbom.PackageCount = len(sbom.Packages)
+
+	insert, err := tx.PrepareContext(ctx, `INSERT INTO sbom_packages (sbom_id, name, version, type, purl, licenses) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+	for _, pkg := range sbom.Packages {
+		licenses, _ := json.Marshal(pkg.Licenses)
+		if _, err := insert.ExecContext(ctx, sbom.ID, pkg.Name, pkg.Version, pkg.Type, pkg.PURL, string(licenses)); err != nil {
+			return fmt.Errorf("failed to save package %s: %w", pkg.Name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// List returns the stored SBOMs without their packages, newest first
+func (s *Store) List(ctx context.Context) ([]SBOM, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, image, digest, generated_at, package_count FROM sboms ORDER BY generated_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sboms: %w", err)
+	}
+	defer rows.Close()
+
+	sboms := []SBOM{}
+	for rows.Next() {
+		var sbom SBOM
+		var generatedAt int64
+		if err := rows.Scan(&sbom.ID, &sbom.Image, &sbom.Digest, &generatedAt, &sbom.PackageCount); err != nil {
+			return nil, fmt.Errorf("failed to scan sbom: %w", err)
+		}
+		sbom.GeneratedAt = time.UnixMilli(generatedAt)
+		sboms = append(sboms, sbom)
+	}
+	return sboms, rows.Err()
+}
+
+// Get returns an SBOM with its packages
+func (s *Store) Get(ctx context.Context, id int64) (*SBOM, error) {
+	var sbom SBOM
+	var generatedAt int64
+	err := s.db.QueryRowContext(ctx, `SELECT id, image, digest, generated_at, package_count FROM sboms WHERE id = ?`, id).
+		Scan(&sbom.ID, &sbom.Image, &sbom.Digest, &generatedAt, &sbom.PackageCount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sbom: %w", err)
+	}
+	sbom.GeneratedAt = time.UnixMilli(generatedAt)
+
+	rows, err := s.db.QueryContext(ctx, `SELECT name, version, type, purl, licenses FROM sbom_packages WHERE sbom_id = ? ORDER BY name, version`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+	defer rows.Close()
+	sbom.Packages = []Package{}
+	for rows.Next() {
+		pkg, err := scanPackage(rows)
+		if err != nil {
+			return nil, err
+		}
+		sbom.Packages = append(sbom.Packages, pkg)
+	}
+	return &sbom, rows.Err()
+}
+
+// Document returns the syft JSON an SBOM was generated from
+func (s *Store) Document(ctx context.Context, id int64) ([]byte, error) {
+	var compressed []byte
+	err := s.db.QueryRowContext(ctx, `SELECT document FROM sboms WHERE id = ?`, id).Scan(&compressed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sbom document: %w", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sbom document: %w", err)
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// Delete removes an SBOM and its packages
+func (s *Store) Delete(ctx context.Context, id int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sbom_packages WHERE sbom_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete packages: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM sboms WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete sbom: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return tx.Commit()
+}
+
+// Search returns the packages matching a query with the SBOMs containing them. Names match
+// case-insensitively as a substring and versions exactly, with * as a wildcard.
+func (s *Store) Search(ctx context.Context, query PackageQuery) ([]PackageMatch, error) {
+	var conditions []string
+	var args []interface{}
+	if query.Name != "" {
+		conditions = append(conditions, `p.name LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+likePattern(query.Name)+"%")
+	}
+	if query.Version != "" {
+		conditions = append(conditions, `p.version LIKE ? ESCAPE '\'`)
+		args = append(args, likePattern(query.Version))
+	}
+	if query.Type != "" {
+		conditions = append(conditions, "p.type = ?")
+		args = append(args, query.Type)
+	}
+	if len(query.SBOMs) > 0 {
+		conditions = append(conditions, "p.sbom_id IN ("+strings.TrimSuffix(strings.Repeat("?,", len(query.SBOMs)), ",")+")")
+		for _, id := range query.SBOMs {
+			args = append(args, id)
+		}
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultLimit
+	}
+
+	statement := `SELECT s.id, s.image, s.digest, p.name, p.version, p.type, p.purl, p.licenses
+		FROM sbom_packages p JOIN sboms s ON s.id = p.sbom_id`
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	statement += " ORDER BY p.name, p.version, s.image LIMIT ?"
+	args = append(args, query.Limit)
+
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search packages: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []PackageMatch{}
+	for rows.Next() {
+		var match PackageMatch
+		var licenses string
+		if err := rows.Scan(&match.SBOMID, &match.Image, &match.Digest, &match.Package.Name, &match.Package.Version,
+			&match.Package.Type, &match.Package.PURL, &licenses); err != nil {
+			return nil, fmt.Errorf("failed to scan package: %w", err)
+		}
+		json.Unmarshal([]byte(licenses), &match.Package.Licenses)
+		matches = append(matches, match)
+	}
+	return matches, rows.Err()
+}
+
+// Licenses summarizes the licenses of the packages in the given SBOMs, or all of them
+// for nil, by the number of images using each
+func (s *Store) Licenses(ctx context.Context, sboms []int64) ([]LicenseSummary, error) {
+	statement := `SELECT s.image, p.name, p.licenses FROM sbom_packages p JOIN sboms s ON s.id = p.sbom_id`
+	var args []interface{}
+	if sboms != nil {
+		if len(sboms) == 0 {
+			return []LicenseSummary{}, nil
+		}
+		statement += " WHERE p.sbom_id IN (" + strings.TrimSuffix(strings.Repeat("?,", len(sboms)), ",") + ")"
+		for _, id := range sboms {
+			args = append(args, id)
+		}
+	}
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list licenses: %w", err)
+	}
+	defer rows.Close()
+
+	type usage struct {
+		packages map[string]bool
+		images   map[string]bool
+	}
+	byLicense := map[string]*usage{}
+	for rows.Next() {
+		var image, name, encoded string
+		if err := rows.Scan(&image, &name, &encoded); err != nil {
+			return nil, fmt.Errorf("failed to scan license: %w", err)
+		}
+		var licenses []string
+		json.Unmarshal([]byte(encoded), &licenses)
+		if len(licenses) == 0 {
+			licenses = []string{unknownLicense}
+		}
+		for _, license := range licenses {
+			u := byLicense[license]
+			if u == nil {
+				u = &usage{packages: map[string]bool{}, images: map[string]bool{}}
+				byLicense[license] = u
+			}
+			u.packages[name] = true
+			u.images[image] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	summaries := []LicenseSummary{}
+	for license, u := range byLicense {
+		summary := LicenseSummary{License: license, Packages: len(u.packages), Images: []string{}}
+		for image := range u.images {
+			summary.Images = append(summary.Images, image)
+		}
+		sort.Strings(summary.Images)
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if len(summaries[i].Images) != len(summaries[j].Images) {
+			return len(summaries[i].Images) > len(summaries[j].Images)
+		}
+		return summaries[i].License < summaries[j].License
+	})
+	return summaries, nil
+}
+
+func scanPackage(rows *sql.Rows) (Package, error) {
+	var pkg Package
+	var licenses string
+	if err := rows.Scan(&pkg.Name, &pkg.Version, &pkg.Type, &pkg.PURL, &licenses); err != nil {
+		return pkg, fmt.Errorf("failed to scan package: %w", err)
+	}
+	json.Unmarshal([]byte(licenses), &pkg.Licenses)
+	return pkg, nil
+}
+
+// likePattern escapes LIKE wildcards in a search term and turns * into %
+func likePattern(term string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`, "*", "%")
+	return replacer.Replace(term)
+}
@@ -0,0 +1,136 @@
+// Package secretbox encrypts credentials stored in SQLite.
+//
+// Values are sealed with AES-256-GCM under a key derived from the master secret and stored
+// as "enc:v1:" followed by the base64 nonce and ciphertext. Values without the prefix are
+// rows written before encryption was enabled and are read as plaintext, so existing data
+// keeps working until it is encrypted with EncryptColumn.
+package secretbox
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix marks encrypted values; the version allows changing the scheme later
+const prefix = "enc:v1:"
+
+var (
+	// ErrKeyMissing is returned when an encrypted value is read without a key
+	ErrKeyMissing = errors.New("encryption key not configured")
+	// ErrCorrupt is returned when an encrypted value can't be opened, e.g. under another key
+	ErrCorrupt = errors.New("encrypted value is corrupt or was sealed with another key")
+)
+
+// Box seals and opens stored values. A nil Box stores values as plaintext.
+type Box struct {
+	gcm cipher.AEAD
+}
+
+// New derives the encryption key from a master secret
+func New(secret string) (*Box, error) {
+	if secret == "" {
+		return nil, ErrKeyMissing
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{gcm: gcm}, nil
+}
+
+// IsEncrypted reports whether a stored value was sealed by a Box
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt seals a value for storage. Encrypted values are returned unchanged.
+func (b *Box) Encrypt(plaintext string) (string, error) {
+	if b == nil || IsEncrypted(plaintext) {
+		return plaintext, nil
+	}
+	nonce := make([]byte, b.gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := b.gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a stored value. Values that were never encrypted are returned unchanged.
+func (b *Box) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if b == nil {
+		return "", ErrKeyMissing
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil || len(sealed) < b.gcm.NonceSize() {
+		return "", ErrCorrupt
+	}
+	plaintext, err := b.gcm.Open(nil, sealed[:b.gcm.NonceSize()], sealed[b.gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrCorrupt
+	}
+	return string(plaintext), nil
+}
+
+// EncryptColumn encrypts the plaintext values of a column in place and returns how many rows
+// it changed. Encrypted and empty values are left alone, so it can be run repeatedly.
+func (b *Box) EncryptColumn(ctx context.Context, db *sql.DB, table, idColumn, column string) (int, error) {
+	if b == nil {
+		return 0, ErrKeyMissing
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT %s, %s FROM %s WHERE %s IS NOT NULL AND %s != '' AND %s NOT LIKE ?`,
+		idColumn, column, table, column, column, column), prefix+"%")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+	}
+	plaintext := make(map[string]string)
+	for rows.Next() {
+		var id, value string
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan %s: %w", table, err)
+		}
+		plaintext[id] = value
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, value := range plaintext {
+		sealed, err := b.Encrypt(value)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s = ?`, table, column, idColumn), sealed, id); err != nil {
+			return 0, fmt.Errorf("failed to encrypt %s %s: %w", table, id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return len(plaintext), nil
+}
@@ -0,0 +1,89 @@
+package secretbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	box, err := New("master-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := box.Encrypt(`{"password":"hunter2"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(sealed) {
+		t.Fatalf("expected an encrypted value, got %q", sealed)
+	}
+	if again, _ := box.Encrypt(sealed); again != sealed {
+		t.Fatal("expected an encrypted value to be left alone")
+	}
+
+	opened, err := box.Decrypt(sealed)
+	if err != nil || opened != `{"password":"hunter2"}` {
+		t.Fatalf("unexpected decrypt result %q: %v", opened, err)
+	}
+
+	// Rows stored before encryption are read as they are
+	if plain, err := box.Decrypt(`{"password":"old"}`); err != nil || plain != `{"password":"old"}` {
+		t.Fatalf("expected plaintext passthrough, got %q: %v", plain, err)
+	}
+
+	other, _ := New("another-secret")
+	if _, err := other.Decrypt(sealed); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt under another key, got %v", err)
+	}
+	var none *Box
+	if _, err := none.Decrypt(sealed); !errors.Is(err, ErrKeyMissing) {
+		t.Fatalf("expected ErrKeyMissing without a key, got %v", err)
+	}
+	if plain, _ := none.Encrypt("value"); plain != "value" {
+		t.Fatal("expected a nil box to store plaintext")
+	}
+}
+
+func TestEncryptColumn(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	box, _ := New("master-secret")
+	sealed, _ := box.Encrypt("already")
+	if _, err := db.Exec(`CREATE TABLE registries (id TEXT PRIMARY KEY, config TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO registries VALUES ('a', 'plain'), ('b', ?), ('c', ''), ('d', NULL)`, sealed); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := box.EncryptColumn(ctx, db, "registries", "id", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 row encrypted, got %d", count)
+	}
+
+	var stored string
+	if err := db.QueryRow(`SELECT config FROM registries WHERE id = 'a'`).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := box.Decrypt(stored); !IsEncrypted(stored) || err != nil || opened != "plain" {
+		t.Fatalf("expected row a to be encrypted, got %q", stored)
+	}
+
+	if count, err := box.EncryptColumn(ctx, db, "registries", "id", "config"); err != nil || count != 0 {
+		t.Fatalf("expected a second run to change nothing, got %d: %v", count, err)
+	}
+}
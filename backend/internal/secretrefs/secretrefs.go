@@ -0,0 +1,112 @@
+// Package secretrefs resolves environment values that point at external secret
+// stores instead of holding the secret itself:
+//
+//	vault:secret/data/api#password   key of a Vault KV (v1 or v2) secret
+//	sops:apps/api/secrets.yaml#db.password   key of a SOPS-encrypted file in the GitOps repository
+//
+// Only the reference is stored in SQLite and committed to Git; the value is looked
+// up when a deployment is applied.
+package secretrefs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Reference schemes
+const (
+	SchemeVault = "vault"
+	SchemeSOPS  = "sops"
+)
+
+// Reference is a parsed secret reference
+type Reference struct {
+	Scheme string
+	Path   string
+	Key    string
+}
+
+func (r Reference) String() string {
+	return r.Scheme + ":" + r.Path + "#" + r.Key
+}
+
+// Parse recognises scheme:path#key values with a known scheme
+func Parse(value string) (Reference, bool) {
+	scheme, rest, ok := strings.Cut(value, ":")
+	if !ok || (scheme != SchemeVault && scheme != SchemeSOPS) {
+		return Reference{}, false
+	}
+	path, key, ok := strings.Cut(rest, "#")
+	if !ok || path == "" || key == "" {
+		return Reference{}, false
+	}
+	return Reference{Scheme: scheme, Path: path, Key: key}, true
+}
+
+// Provider reads one key of a secret from a store
+type Provider interface {
+	Get(ctx context.Context, path, key string) (string, error)
+}
+
+// Resolver dispatches references to the provider for their scheme
+type Resolver struct {
+	providers map[string]Provider
+}
+
+// NewResolver creates a resolver without providers
+func NewResolver() *Resolver {
+	return &Resolver{providers: make(map[string]Provider)}
+}
+
+// NewResolverFromEnv registers Vault when VAULT_ADDR and VAULT_TOKEN are set, and
+// SOPS when the sops binary (SOPS_BINARY, default "sops") is installed. SOPS file
+// paths are relative to the local GitOps repository at repoPath.
+func NewResolverFromEnv(repoPath string) *Resolver {
+	resolver := NewResolver()
+	if addr, token := os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN"); addr != "" && token != "" {
+		resolver.Register(SchemeVault, NewVaultProvider(addr, token, os.Getenv("VAULT_NAMESPACE")))
+	}
+	binary := os.Getenv("SOPS_BINARY")
+	if binary == "" {
+		binary = "sops"
+	}
+	if _, err := exec.LookPath(binary); err == nil {
+		resolver.Register(SchemeSOPS, NewSOPSProvider(binary, repoPath))
+	} else if os.Getenv("SOPS_BINARY") != "" {
+		slog.Warn("SOPS binary not found, sops: secret references disabled", "binary", binary)
+	}
+	return resolver
+}
+
+// Register sets the provider for a scheme
+func (r *Resolver) Register(scheme string, provider Provider) {
+	r.providers[scheme] = provider
+}
+
+// IsReference reports whether a value is a secret reference, whether or not its
+// store is configured
+func (r *Resolver) IsReference(value string) bool {
+	_, ok := Parse(value)
+	return ok
+}
+
+// Resolve returns the secret a reference points at
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	ref, ok := Parse(value)
+	if !ok {
+		return "", fmt.Errorf("not a secret reference")
+	}
+	provider, ok := r.providers[ref.Scheme]
+	if !ok {
+		return "", fmt.Errorf("%s secret store is not configured", ref.Scheme)
+	}
+	secret, err := provider.Get(ctx, ref.Path, ref.Key)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return secret, nil
+}
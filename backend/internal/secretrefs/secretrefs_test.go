@@ -0,0 +1,94 @@
+package secretrefs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Reference
+		ok    bool
+	}{
+		{"vault:secret/data/api#password", Reference{SchemeVault, "secret/data/api", "password"}, true},
+		{"sops:apps/api/secrets.yaml#db.password", Reference{SchemeSOPS, "apps/api/secrets.yaml", "db.password"}, true},
+		{"vault:secret/data/api", Reference{}, false},
+		{"postgres://user:pass@db:5432/app#frag", Reference{}, false},
+		{"plain", Reference{}, false},
+		{"sops:#key", Reference{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := Parse(tt.value)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/api": // KV v2
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+				"data":     map[string]interface{}{"password": "s3cret", "port": 5432},
+				"metadata": map[string]interface{}{"version": 3},
+			}})
+		case "/v1/kv/api": // KV v1
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"token": "abc"}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	resolver := NewResolver()
+	resolver.Register(SchemeVault, NewVaultProvider(server.URL, "root", ""))
+	ctx := context.Background()
+
+	for value, want := range map[string]string{
+		"vault:secret/data/api#password": "s3cret",
+		"vault:secret/data/api#port":     "5432",
+		"vault:kv/api#token":             "abc",
+	} {
+		if got, err := resolver.Resolve(ctx, value); err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"vault:secret/data/api#missing", "vault:secret/data/other#password", "sops:file.yaml#key"} {
+		if _, err := resolver.Resolve(ctx, value); err == nil {
+			t.Errorf("Resolve(%q) should fail", value)
+		}
+	}
+}
+
+func TestSOPSProvider(t *testing.T) {
+	dir := t.TempDir()
+	// A stand-in for sops that prints the "decrypted" file it is given
+	binary := filepath.Join(dir, "sops")
+	script := "#!/bin/sh\nfor last; do :; done\ncat \"$last\"\n"
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	repo := filepath.Join(dir, "repo")
+	os.MkdirAll(filepath.Join(repo, "apps"), 0o755)
+	os.WriteFile(filepath.Join(repo, "apps", "secrets.json"), []byte(`{"db": {"password": "hunter2"}}`), 0o644)
+
+	provider := NewSOPSProvider(binary, repo)
+	ctx := context.Background()
+	if got, err := provider.Get(ctx, "apps/secrets.json", "db.password"); err != nil || got != "hunter2" {
+		t.Errorf("Get = %q, %v; want hunter2", got, err)
+	}
+	if _, err := provider.Get(ctx, "../../etc/passwd", "root"); err == nil {
+		t.Error("expected paths outside the repository to fail")
+	}
+}
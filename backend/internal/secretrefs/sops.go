@@ -0,0 +1,43 @@
+package secretrefs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SOPSProvider decrypts SOPS files from the GitOps repository with the sops binary,
+// which finds its keys (age, PGP, cloud KMS) the usual way, e.g. SOPS_AGE_KEY_FILE
+type SOPSProvider struct {
+	binary  string
+	baseDir string
+}
+
+// NewSOPSProvider creates a provider for files under baseDir
+func NewSOPSProvider(binary, baseDir string) *SOPSProvider {
+	return &SOPSProvider{binary: binary, baseDir: baseDir}
+}
+
+// Get decrypts the file at path and returns a dotted key of it
+func (s *SOPSProvider) Get(ctx context.Context, path, key string) (string, error) {
+	// Cleaning against the root keeps ".." from leaving the repository
+	file := filepath.Join(s.baseDir, filepath.Clean("/"+path))
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.binary, "--decrypt", "--output-type", "json", file)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("sops failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &data); err != nil {
+		return "", fmt.Errorf("failed to decode decrypted file: %w", err)
+	}
+	return lookup(data, key)
+}
@@ -0,0 +1,90 @@
+package secretrefs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultProvider reads secrets over Vault's HTTP API with a token
+type VaultProvider struct {
+	addr      string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+// NewVaultProvider creates a Vault provider; namespace is optional (Vault Enterprise)
+func NewVaultProvider(addr, token, namespace string) *VaultProvider {
+	return &VaultProvider{
+		addr:      strings.TrimSuffix(addr, "/"),
+		token:     token,
+		namespace: namespace,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Get reads a key of the secret at path. KV v2 paths include the data segment,
+// e.g. secret/data/api.
+func (v *VaultProvider) Get(ctx context.Context, path, key string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.addr+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("vault returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	data := secret.Data
+	// KV v2 nests the values next to the version metadata
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, versioned := data["metadata"]; versioned {
+			data = inner
+		}
+	}
+	return lookup(data, key)
+}
+
+// lookup returns a dotted key of decoded JSON as a string
+func lookup(data map[string]interface{}, key string) (string, error) {
+	var current interface{} = data
+	for _, part := range strings.Split(key, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("key %s not found", key)
+		}
+		if current, ok = object[part]; !ok {
+			return "", fmt.Errorf("key %s not found", key)
+		}
+	}
+	switch value := current.(type) {
+	case string:
+		return value, nil
+	case map[string]interface{}, []interface{}:
+		return "", fmt.Errorf("key %s is not a single value", key)
+	default:
+		encoded, _ := json.Marshal(value)
+		return string(encoded), nil
+	}
+}
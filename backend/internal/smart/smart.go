@@ -0,0 +1,421 @@
+// Package smart reads the SMART health of the disks of every node with smartctl, run in a
+// privileged DaemonSet, and grades them against wear, temperature and sector thresholds.
+package smart
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Disk health statuses, from best to worst
+const (
+	StatusOK       = "ok"
+	StatusWarning  = "warning"
+	StatusCritical = "critical"
+)
+
+// DefaultInterval is how often disks are checked; SMART attributes change slowly
+const DefaultInterval = time.Hour
+
+// checkTimeout bounds the check of one node, which reads every disk on it
+const checkTimeout = 2 * time.Minute
+
+// ErrUnavailable is returned before the first check of the disks
+var ErrUnavailable = errors.New("disk health not checked yet")
+
+// script runs smartctl on every device it can open, framing each JSON report with markers
+// naming the device. smartctl's exit status is a bitmask of findings, so it is ignored.
+const script = `smartctl --scan-open | while read -r dev flag type rest; do
+  echo "@@begin $dev"
+  smartctl --all --json --device "$type" "$dev"
+  echo "@@end $dev"
+done`
+
+// Disk is the SMART health of a disk on a node
+type Disk struct {
+	Node               string    `json:"node"`
+	Device             string    `json:"device"`
+	Protocol           string    `json:"protocol"` // ATA, NVMe or SCSI
+	Model              string    `json:"model"`
+	Serial             string    `json:"serial"`
+	CapacityBytes      int64     `json:"capacity_bytes"`
+	Passed             bool      `json:"passed"` // the drive's own overall assessment
+	TemperatureCelsius int       `json:"temperature_celsius"`
+	PowerOnHours       int64     `json:"power_on_hours"`
+	ReallocatedSectors int64     `json:"reallocated_sectors"`
+	PendingSectors     int64     `json:"pending_sectors"`
+	MediaErrors        int64     `json:"media_errors"`
+	WearPercent        *int      `json:"wear_percent,omitempty"` // of the rated endurance used; SSDs only
+	Status             string    `json:"status"`
+	Problems           []string  `json:"problems,omitempty"`
+	CheckedAt          time.Time `json:"checked_at"`
+}
+
+// Report is the health of every disk found at the last check
+type Report struct {
+	CheckedAt time.Time         `json:"checked_at"`
+	Disks     []Disk            `json:"disks"`
+	Counts    map[string]int    `json:"counts"`           // disks by status
+	Errors    map[string]string `json:"errors,omitempty"` // nodes that couldn't be checked
+}
+
+// Thresholds grade disks as warning or critical
+type Thresholds struct {
+	TemperatureWarning  int   // °C
+	TemperatureCritical int   // °C
+	WearWarning         int   // % of rated endurance used
+	WearCritical        int   // % of rated endurance used
+	ReallocatedCritical int64 // reallocated sectors; any is a warning
+}
+
+// DefaultThresholds suit most HDDs and SSDs
+var DefaultThresholds = Thresholds{
+	TemperatureWarning:  55,
+	TemperatureCritical: 65,
+	WearWarning:         80,
+	WearCritical:        90,
+	ReallocatedCritical: 100,
+}
+
+// Agent runs smartctl on the nodes; *k8s.Client implements it
+type Agent interface {
+	EnsureSmartAgent(ctx context.Context, config k8s.SmartAgentConfig) error
+	SmartAgentPods(ctx context.Context, namespace string) ([]corev1.Pod, error)
+	ExecInPod(ctx context.Context, namespace, pod, container string, command []string, stdout, stderr io.Writer) error
+}
+
+// Service checks disk health through the agent DaemonSet and keeps the last report
+type Service struct {
+	agent      Agent
+	config     k8s.SmartAgentConfig
+	thresholds Thresholds
+
+	mu        sync.Mutex
+	report    *Report
+	onReport  func(ctx context.Context, report *Report)
+	checkLock sync.Mutex // one check at a time
+}
+
+// NewService creates a disk health service running its agent with the given configuration
+func NewService(agent Agent, config k8s.SmartAgentConfig) *Service {
+	if config.Namespace == "" {
+		config.Namespace = k8s.DefaultSmartAgentNamespace
+	}
+	return &Service{agent: agent, config: config, thresholds: DefaultThresholds}
+}
+
+// SetThresholds replaces the thresholds disks are graded against
+func (s *Service) SetThresholds(thresholds Thresholds) {
+	s.thresholds = thresholds
+}
+
+// OnReport registers a callback receiving the report of every check
+func (s *Service) OnReport(fn func(ctx context.Context, report *Report)) {
+	s.onReport = fn
+}
+
+// Report returns the last check's report
+func (s *Service) Report() (*Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.report == nil {
+		return nil, ErrUnavailable
+	}
+	return s.report, nil
+}
+
+// Start deploys the agent and checks the disks every interval until the context is done.
+// The first check waits a minute for the agent pods to start.
+func (s *Service) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	go func() {
+		if err := s.agent.EnsureSmartAgent(ctx, s.config); err != nil {
+			slog.Warn("Disk health monitoring disabled", "error", err)
+			return
+		}
+		timer := time.NewTimer(time.Minute)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+			if _, err := s.Check(ctx); err != nil {
+				slog.Warn("Failed to check disk health", "error", err)
+			}
+			timer.Reset(interval)
+		}
+	}()
+}
+
+// Check reads every disk on the nodes running an agent pod
+func (s *Service) Check(ctx context.Context) (*Report, error) {
+	s.checkLock.Lock()
+	defer s.checkLock.Unlock()
+
+	pods, err := s.agent.SmartAgentPods(ctx, s.config.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{CheckedAt: time.Now(), Disks: []Disk{}, Counts: map[string]int{}, Errors: map[string]string{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, pod := range pods {
+		wg.Add(1)
+		go func(pod corev1.Pod) {
+			defer wg.Done()
+			disks, err := s.checkNode(ctx, pod)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				report.Errors[pod.Spec.NodeName] = err.Error()
+				return
+			}
+			report.Disks = append(report.Disks, disks...)
+		}(pod)
+	}
+	wg.Wait()
+
+	sort.Slice(report.Disks, func(i, j int) bool {
+		a, b := report.Disks[i], report.Disks[j]
+		if a.Node != b.Node {
+			return a.Node < b.Node
+		}
+		return a.Device < b.Device
+	})
+	for _, disk := range report.Disks {
+		report.Counts[disk.Status]++
+	}
+
+	s.mu.Lock()
+	s.report = report
+	s.mu.Unlock()
+	if s.onReport != nil {
+		s.onReport(ctx, report)
+	}
+	return report, nil
+}
+
+// checkNode runs smartctl in a node's agent pod
+func (s *Service) checkNode(ctx context.Context, pod corev1.Pod) ([]Disk, error) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	err := s.agent.ExecInPod(ctx, pod.Namespace, pod.Name, k8s.SmartAgentContainer, []string{"sh", "-c", script}, &stdout, &stderr)
+	if err != nil {
+		return nil, fmt.Errorf("smartctl failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var disks []Disk
+	for device, output := range splitReports(stdout.Bytes()) {
+		disk, err := ParseSmartctl(output)
+		if err != nil {
+			slog.Debug("Skipping unreadable SMART report", "node", pod.Spec.NodeName, "device", device, "error", err)
+			continue
+		}
+		disk.Node = pod.Spec.NodeName
+		disk.Device = device
+		disk.CheckedAt = time.Now()
+		s.thresholds.Grade(disk)
+		disks = append(disks, *disk)
+	}
+	return disks, nil
+}
+
+// splitReports reads the smartctl JSON of each device between the script's markers
+func splitReports(output []byte) map[string][]byte {
+	reports := map[string][]byte{}
+	var device string
+	var current bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "@@begin "); ok {
+			device = name
+			current.Reset()
+			continue
+		}
+		if strings.HasPrefix(line, "@@end ") && device != "" {
+			reports[device] = bytes.Clone(current.Bytes())
+			device = ""
+			continue
+		}
+		if device != "" {
+			current.WriteString(line)
+			current.WriteByte('\n')
+		}
+	}
+	return reports
+}
+
+// smartctlReport holds the parts of smartctl's JSON output disk health is read from
+type smartctlReport struct {
+	Device struct {
+		Protocol string `json:"protocol"`
+	} `json:"device"`
+	ModelName    string `json:"model_name"`
+	SerialNumber string `json:"serial_number"`
+	UserCapacity struct {
+		Bytes int64 `json:"bytes"`
+	} `json:"user_capacity"`
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature struct {
+		Current int `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	ATAAttributes *struct {
+		Table []struct {
+			ID    int `json:"id"`
+			Value int `json:"value"`
+			Raw   struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeHealth *struct {
+		CriticalWarning int   `json:"critical_warning"`
+		PercentageUsed  int   `json:"percentage_used"`
+		MediaErrors     int64 `json:"media_errors"`
+	} `json:"nvme_smart_health_information_log"`
+	SCSIGrownDefects *int64 `json:"scsi_grown_defect_list"`
+	Smartctl         struct {
+		Messages []struct {
+			String   string `json:"string"`
+			Severity string `json:"severity"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+}
+
+// ATA attributes read for sector health and SSD wear
+const (
+	ataReallocatedSectors = 5
+	ataPendingSectors     = 197
+	ataUncorrectable      = 198
+	ataWearLevelingCount  = 177 // Samsung and others; normalized value counts down from 100
+	ataSSDLifeLeft        = 231
+	ataMediaWearout       = 233 // Intel
+)
+
+// ParseSmartctl reads a disk from the JSON output of smartctl --all --json
+func ParseSmartctl(data []byte) (*Disk, error) {
+	var report smartctlReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("invalid smartctl output: %w", err)
+	}
+	if report.SmartStatus == nil {
+		for _, message := range report.Smartctl.Messages {
+			if message.Severity == "error" {
+				return nil, errors.New(message.String)
+			}
+		}
+		return nil, errors.New("no SMART status reported")
+	}
+
+	disk := &Disk{
+		Protocol:           report.Device.Protocol,
+		Model:              report.ModelName,
+		Serial:             report.SerialNumber,
+		CapacityBytes:      report.UserCapacity.Bytes,
+		Passed:             report.SmartStatus.Passed,
+		TemperatureCelsius: report.Temperature.Current,
+		PowerOnHours:       report.PowerOnTime.Hours,
+	}
+	if attributes := report.ATAAttributes; attributes != nil {
+		for _, attribute := range attributes.Table {
+			switch attribute.ID {
+			case ataReallocatedSectors:
+				disk.ReallocatedSectors = attribute.Raw.Value
+			case ataPendingSectors:
+				disk.PendingSectors = attribute.Raw.Value
+			case ataUncorrectable:
+				disk.MediaErrors = attribute.Raw.Value
+			case ataWearLevelingCount, ataSSDLifeLeft, ataMediaWearout:
+				if disk.WearPercent == nil && attribute.Value > 0 && attribute.Value <= 100 {
+					wear := 100 - attribute.Value
+					disk.WearPercent = &wear
+				}
+			}
+		}
+	}
+	if health := report.NVMeHealth; health != nil {
+		wear := health.PercentageUsed
+		disk.WearPercent = &wear
+		disk.MediaErrors = health.MediaErrors
+		if health.CriticalWarning != 0 {
+			disk.Problems = append(disk.Problems, fmt.Sprintf("NVMe critical warning 0x%02x", health.CriticalWarning))
+		}
+	}
+	if report.SCSIGrownDefects != nil {
+		disk.ReallocatedSectors = *report.SCSIGrownDefects
+	}
+	return disk, nil
+}
+
+// Grade sets a disk's status and lists its problems
+func (t Thresholds) Grade(disk *Disk) {
+	status := StatusOK
+	raise := func(severity, problem string) {
+		disk.Problems = append(disk.Problems, problem)
+		if severity == StatusCritical || status == StatusOK {
+			status = severity
+		}
+	}
+	// Problems found while parsing, such as NVMe critical warnings, are critical
+	if len(disk.Problems) > 0 {
+		status = StatusCritical
+	}
+
+	if !disk.Passed {
+		raise(StatusCritical, "SMART overall health self-assessment failed")
+	}
+	switch {
+	case disk.ReallocatedSectors >= t.ReallocatedCritical:
+		raise(StatusCritical, fmt.Sprintf("%d reallocated sectors", disk.ReallocatedSectors))
+	case disk.ReallocatedSectors > 0:
+		raise(StatusWarning, fmt.Sprintf("%d reallocated sectors", disk.ReallocatedSectors))
+	}
+	if disk.PendingSectors > 0 {
+		raise(StatusWarning, fmt.Sprintf("%d sectors pending reallocation", disk.PendingSectors))
+	}
+	if disk.MediaErrors > 0 {
+		raise(StatusWarning, fmt.Sprintf("%d uncorrectable media errors", disk.MediaErrors))
+	}
+	switch {
+	case disk.TemperatureCelsius >= t.TemperatureCritical:
+		raise(StatusCritical, fmt.Sprintf("temperature %d°C", disk.TemperatureCelsius))
+	case disk.TemperatureCelsius >= t.TemperatureWarning:
+		raise(StatusWarning, fmt.Sprintf("temperature %d°C", disk.TemperatureCelsius))
+	}
+	if disk.WearPercent != nil {
+		switch {
+		case *disk.WearPercent >= t.WearCritical:
+			raise(StatusCritical, fmt.Sprintf("%d%% of rated endurance used", *disk.WearPercent))
+		case *disk.WearPercent >= t.WearWarning:
+			raise(StatusWarning, fmt.Sprintf("%d%% of rated endurance used", *disk.WearPercent))
+		}
+	}
+	disk.Status = status
+}
@@ -0,0 +1,150 @@
+package smart
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const ataReport = `{
+  "device": {"name": "/dev/sda", "protocol": "ATA"},
+  "model_name": "WDC WD40EFRX",
+  "serial_number": "WD-123",
+  "user_capacity": {"bytes": 4000787030016},
+  "smart_status": {"passed": true},
+  "temperature": {"current": 41},
+  "power_on_time": {"hours": 35000},
+  "ata_smart_attributes": {"table": [
+    {"id": 5, "value": 200, "raw": {"value": 8}},
+    {"id": 197, "value": 200, "raw": {"value": 0}},
+    {"id": 198, "value": 100, "raw": {"value": 0}}
+  ]}
+}`
+
+const nvmeReport = `{
+  "device": {"name": "/dev/nvme0", "protocol": "NVMe"},
+  "model_name": "Samsung SSD 980",
+  "serial_number": "S64",
+  "smart_status": {"passed": true},
+  "temperature": {"current": 67},
+  "nvme_smart_health_information_log": {"critical_warning": 0, "percentage_used": 83, "media_errors": 0}
+}`
+
+const ssdReport = `{
+  "device": {"name": "/dev/sdb", "protocol": "ATA"},
+  "smart_status": {"passed": true},
+  "temperature": {"current": 30},
+  "ata_smart_attributes": {"table": [{"id": 177, "value": 95, "raw": {"value": 40}}]}
+}`
+
+func TestParseAndGrade(t *testing.T) {
+	disk, err := ParseSmartctl([]byte(ataReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if disk.Protocol != "ATA" || disk.Model != "WDC WD40EFRX" || disk.ReallocatedSectors != 8 || disk.PowerOnHours != 35000 || disk.WearPercent != nil {
+		t.Errorf("disk = %+v", disk)
+	}
+	DefaultThresholds.Grade(disk)
+	if disk.Status != StatusWarning || len(disk.Problems) != 1 || !strings.Contains(disk.Problems[0], "reallocated") {
+		t.Errorf("reallocated sectors graded %s: %v", disk.Status, disk.Problems)
+	}
+
+	disk, err = ParseSmartctl([]byte(nvmeReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	DefaultThresholds.Grade(disk)
+	if *disk.WearPercent != 83 || disk.Status != StatusCritical || len(disk.Problems) != 2 {
+		t.Errorf("hot, worn NVMe graded %s: %v", disk.Status, disk.Problems)
+	}
+
+	disk, err = ParseSmartctl([]byte(ssdReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	DefaultThresholds.Grade(disk)
+	if *disk.WearPercent != 5 || disk.Status != StatusOK || len(disk.Problems) != 0 {
+		t.Errorf("healthy SSD graded %s with %v%% wear: %v", disk.Status, *disk.WearPercent, disk.Problems)
+	}
+
+	failing := &Disk{Passed: false}
+	DefaultThresholds.Grade(failing)
+	if failing.Status != StatusCritical {
+		t.Errorf("failed self-assessment graded %s", failing.Status)
+	}
+
+	if _, err := ParseSmartctl([]byte(`{"smartctl": {"messages": [{"string": "Unknown USB bridge", "severity": "error"}]}}`)); err == nil || !strings.Contains(err.Error(), "USB bridge") {
+		t.Errorf("expected smartctl's error, got %v", err)
+	}
+}
+
+// fakeAgent answers execs with the script output of the node of each pod
+type fakeAgent struct {
+	pods    []corev1.Pod
+	outputs map[string]string
+}
+
+func (a *fakeAgent) EnsureSmartAgent(ctx context.Context, config k8s.SmartAgentConfig) error {
+	return nil
+}
+
+func (a *fakeAgent) SmartAgentPods(ctx context.Context, namespace string) ([]corev1.Pod, error) {
+	return a.pods, nil
+}
+
+func (a *fakeAgent) ExecInPod(ctx context.Context, namespace, pod, container string, command []string, stdout, stderr io.Writer) error {
+	output, ok := a.outputs[pod]
+	if !ok {
+		return errors.New("container not found")
+	}
+	_, err := io.WriteString(stdout, output)
+	return err
+}
+
+func agentPod(name, node string) corev1.Pod {
+	return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"}, Spec: corev1.PodSpec{NodeName: node}}
+}
+
+func TestCheck(t *testing.T) {
+	agent := &fakeAgent{
+		pods: []corev1.Pod{agentPod("smart-a", "worker-1"), agentPod("smart-b", "worker-2")},
+		outputs: map[string]string{
+			"smart-a": "@@begin /dev/sda\n" + ataReport + "\n@@end /dev/sda\n" +
+				"@@begin /dev/nvme0\n" + nvmeReport + "\n@@end /dev/nvme0\n" +
+				"@@begin /dev/sdz\nnot json\n@@end /dev/sdz\n",
+		},
+	}
+	service := NewService(agent, k8s.SmartAgentConfig{})
+	if _, err := service.Report(); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected no report before the first check, got %v", err)
+	}
+	var reported *Report
+	service.OnReport(func(ctx context.Context, report *Report) { reported = report })
+
+	report, err := service.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Disks) != 2 || report.Disks[0].Device != "/dev/nvme0" || report.Disks[0].Node != "worker-1" {
+		t.Errorf("disks = %+v", report.Disks)
+	}
+	if report.Counts[StatusCritical] != 1 || report.Counts[StatusWarning] != 1 {
+		t.Errorf("counts = %v", report.Counts)
+	}
+	if !strings.Contains(report.Errors["worker-2"], "container not found") {
+		t.Errorf("errors = %v", report.Errors)
+	}
+	if reported != report {
+		t.Error("expected the report passed to the callback")
+	}
+	if latest, _ := service.Report(); latest != report {
+		t.Error("expected the check kept as the latest report")
+	}
+}
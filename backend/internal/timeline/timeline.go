@@ -0,0 +1,367 @@
+// Package timeline correlates Kubernetes events, deployment history, GitOps deployments
+// and alerts into a single chronological view per application or namespace.
+package timeline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/archellir/denshimon/internal/deployments"
+	"github.com/archellir/denshimon/internal/gitops"
+	"github.com/archellir/denshimon/internal/k8s"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Entry sources
+const (
+	SourceKubernetes = "kubernetes" // Kubernetes events
+	SourceDeployment = "deployment" // denshimon deployment history
+	SourceGitOps     = "gitops"     // GitOps application deployments
+	SourceAlert      = "alert"      // GitOps alerts
+)
+
+// Entry severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Defaults for timeline queries
+const (
+	DefaultSince             = 6 * time.Hour
+	DefaultLimit             = 500
+	DefaultCorrelationWindow = 30 * time.Minute
+)
+
+// Entry is one thing that happened, from any source
+type Entry struct {
+	ID        string            `json:"id"`
+	Timestamp time.Time         `json:"timestamp"`
+	Source    string            `json:"source"`
+	Change    bool              `json:"change"` // a deliberate change (deployment, scale, sync) rather than an observation
+	Severity  string            `json:"severity"`
+	Namespace string            `json:"namespace,omitempty"`
+	App       string            `json:"app,omitempty"`
+	Object    string            `json:"object,omitempty"` // e.g. Pod/api-7d9f8-abcde
+	Reason    string            `json:"reason"`
+	Message   string            `json:"message"`
+	User      string            `json:"user,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	// Related lists the IDs of changes shortly before a warning or critical entry
+	Related []string `json:"related,omitempty"`
+}
+
+// Query selects timeline entries. App matches workloads by name, including the pods
+// and ReplicaSets named after them.
+type Query struct {
+	App               string
+	Namespace         string
+	Since             time.Time
+	Until             time.Time
+	Limit             int
+	CorrelationWindow time.Duration
+}
+
+// Timeline is the merged, oldest-first result of a query
+type Timeline struct {
+	App       string            `json:"app,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+	Since     time.Time         `json:"since"`
+	Until     time.Time         `json:"until"`
+	Entries   []Entry           `json:"entries"`
+	Truncated bool              `json:"truncated"`
+	Errors    map[string]string `json:"errors,omitempty"` // sources that could not be read
+}
+
+// Engine builds timelines from whichever sources are available
+type Engine struct {
+	k8sClient         *k8s.Client
+	deploymentService *deployments.Service
+	gitopsService     *gitops.Service
+}
+
+// NewEngine creates a timeline engine; any source may be nil
+func NewEngine(k8sClient *k8s.Client, deploymentService *deployments.Service, gitopsService *gitops.Service) *Engine {
+	return &Engine{
+		k8sClient:         k8sClient,
+		deploymentService: deploymentService,
+		gitopsService:     gitopsService,
+	}
+}
+
+// Build collects entries from every source, filters them by the query and links
+// warnings to the changes that preceded them
+func (e *Engine) Build(ctx context.Context, query Query) *Timeline {
+	if query.Until.IsZero() {
+		query.Until = time.Now()
+	}
+	if query.Since.IsZero() {
+		query.Since = query.Until.Add(-DefaultSince)
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultLimit
+	}
+	if query.CorrelationWindow <= 0 {
+		query.CorrelationWindow = DefaultCorrelationWindow
+	}
+
+	timeline := &Timeline{
+		App:       query.App,
+		Namespace: query.Namespace,
+		Since:     query.Since,
+		Until:     query.Until,
+		Entries:   []Entry{},
+	}
+	collect := func(source string, entries []Entry, err error) {
+		if err != nil {
+			if timeline.Errors == nil {
+				timeline.Errors = map[string]string{}
+			}
+			timeline.Errors[source] = err.Error()
+			return
+		}
+		for _, entry := range entries {
+			if query.matches(entry) {
+				timeline.Entries = append(timeline.Entries, entry)
+			}
+		}
+	}
+
+	if e.k8sClient != nil {
+		entries, err := e.kubernetesEvents(ctx, query)
+		collect(SourceKubernetes, entries, err)
+	}
+	if e.deploymentService != nil {
+		entries, err := e.deploymentHistory(ctx, query)
+		collect(SourceDeployment, entries, err)
+	}
+	if e.gitopsService != nil {
+		entries, err := e.gitopsDeployments(ctx, query)
+		collect(SourceGitOps, entries, err)
+		entries, err = e.alerts(ctx, query)
+		collect(SourceAlert, entries, err)
+	}
+
+	sort.SliceStable(timeline.Entries, func(i, j int) bool {
+		return timeline.Entries[i].Timestamp.Before(timeline.Entries[j].Timestamp)
+	})
+	// Keep the most recent entries, which are closest to whatever is being investigated
+	if len(timeline.Entries) > query.Limit {
+		timeline.Entries = timeline.Entries[len(timeline.Entries)-query.Limit:]
+		timeline.Truncated = true
+	}
+	Correlate(timeline.Entries, query.CorrelationWindow)
+	return timeline
+}
+
+// Correlate links every warning or critical entry to the changes in the same
+// namespace (and app, when known) during the window before it. Entries must be sorted oldest first.
+func Correlate(entries []Entry, window time.Duration) {
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Severity == SeverityInfo || entry.Change {
+			continue
+		}
+		entry.Related = nil
+		for j := i - 1; j >= 0; j-- {
+			candidate := entries[j]
+			if entry.Timestamp.Sub(candidate.Timestamp) > window {
+				break
+			}
+			if !candidate.Change || candidate.Namespace != entry.Namespace {
+				continue
+			}
+			if entry.App != "" && candidate.App != "" && !MatchesApp(entry.App, candidate.App) && !MatchesApp(candidate.App, entry.App) {
+				continue
+			}
+			entry.Related = append(entry.Related, candidate.ID)
+		}
+	}
+}
+
+func (q Query) matches(entry Entry) bool {
+	if entry.Timestamp.Before(q.Since) || entry.Timestamp.After(q.Until) {
+		return false
+	}
+	if q.Namespace != "" && entry.Namespace != "" && entry.Namespace != q.Namespace {
+		return false
+	}
+	if q.App != "" && !MatchesApp(entry.App, q.App) {
+		return false
+	}
+	return true
+}
+
+// MatchesApp reports whether a resource name belongs to an app: the app itself or a
+// name generated from it, such as a ReplicaSet (app-<hash>) or pod (app-<hash>-<id>)
+func MatchesApp(name, app string) bool {
+	return name == app || strings.HasPrefix(name, app+"-")
+}
+
+func (e *Engine) kubernetesEvents(ctx context.Context, query Query) ([]Entry, error) {
+	events, err := e.k8sClient.ListEvents(ctx, query.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(events.Items))
+	for _, event := range events.Items {
+		severity := SeverityInfo
+		if event.Type == corev1.EventTypeWarning {
+			severity = SeverityWarning
+		}
+		entry := Entry{
+			ID:        SourceKubernetes + ":" + string(event.UID),
+			Timestamp: eventTime(event),
+			Source:    SourceKubernetes,
+			Severity:  severity,
+			Namespace: event.Namespace,
+			App:       event.InvolvedObject.Name,
+			Object:    event.InvolvedObject.Kind + "/" + event.InvolvedObject.Name,
+			Reason:    event.Reason,
+			Message:   event.Message,
+		}
+		// Scaling and rollouts reported by controllers are changes as well
+		entry.Change = event.Reason == "ScalingReplicaSet"
+		if event.Count > 1 {
+			entry.Metadata = map[string]string{"count": fmt.Sprint(event.Count)}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// eventTime returns when an event last occurred, whichever timestamp field the source filled in
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		return event.Series.LastObservedTime.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+func (e *Engine) deploymentHistory(ctx context.Context, query Query) ([]Entry, error) {
+	history, err := e.deploymentService.RecentHistory(ctx, query.Namespace, query.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(history))
+	for _, h := range history {
+		entry := Entry{
+			ID:        SourceDeployment + ":" + h.ID,
+			Timestamp: h.Timestamp,
+			Source:    SourceDeployment,
+			Change:    true,
+			Severity:  SeverityInfo,
+			Namespace: h.Namespace,
+			App:       h.Name,
+			Object:    "Deployment/" + h.Name,
+			Reason:    h.Action,
+			User:      h.User,
+			Metadata:  map[string]string{},
+		}
+		if h.OldImage != "" || h.NewImage != "" {
+			entry.Metadata["old_image"] = h.OldImage
+			entry.Metadata["new_image"] = h.NewImage
+		}
+		if h.OldReplicas != h.NewReplicas {
+			entry.Metadata["old_replicas"] = fmt.Sprint(h.OldReplicas)
+			entry.Metadata["new_replicas"] = fmt.Sprint(h.NewReplicas)
+		}
+
+		switch {
+		case !h.Success:
+			entry.Severity = SeverityWarning
+			entry.Message = fmt.Sprintf("%s of %s failed: %s", h.Action, h.Name, h.Error)
+		case h.NewImage != "" && h.OldImage != h.NewImage:
+			entry.Message = fmt.Sprintf("%s of %s to %s", h.Action, h.Name, h.NewImage)
+		case h.OldReplicas != h.NewReplicas:
+			entry.Message = fmt.Sprintf("%s of %s from %d to %d replicas", h.Action, h.Name, h.OldReplicas, h.NewReplicas)
+		default:
+			entry.Message = fmt.Sprintf("%s of %s", h.Action, h.Name)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (e *Engine) gitopsDeployments(ctx context.Context, query Query) ([]Entry, error) {
+	records, err := e.gitopsService.RecentDeployments(ctx, query.Namespace, query.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(records))
+	for _, record := range records {
+		severity := SeverityInfo
+		if record.Status == "failed" {
+			severity = SeverityWarning
+		}
+		message := fmt.Sprintf("GitOps deployment of %s (%s): %s", record.Application, record.Image, record.Status)
+		if record.Message != "" {
+			message += " - " + record.Message
+		}
+		entries = append(entries, Entry{
+			ID:        SourceGitOps + ":" + record.ID,
+			Timestamp: record.DeployedAt,
+			Source:    SourceGitOps,
+			Change:    true,
+			Severity:  severity,
+			Namespace: record.Namespace,
+			App:       record.Application,
+			Object:    "Application/" + record.Application,
+			Reason:    record.Status,
+			Message:   message,
+			User:      record.DeployedBy,
+			Metadata: map[string]string{
+				"image":    record.Image,
+				"git_hash": record.GitHash,
+				"replicas": fmt.Sprint(record.Replicas),
+			},
+		})
+	}
+	return entries, nil
+}
+
+func (e *Engine) alerts(ctx context.Context, query Query) ([]Entry, error) {
+	alerts, err := e.gitopsService.AlertsSince(ctx, query.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(alerts))
+	for _, alert := range alerts {
+		severity := alert.Severity
+		if severity != SeverityWarning && severity != SeverityCritical {
+			severity = SeverityInfo
+		}
+		app := alert.Metadata["application"]
+		if app == "" {
+			app = alert.Metadata["repository"]
+		}
+		entries = append(entries, Entry{
+			ID:        SourceAlert + ":" + alert.ID,
+			Timestamp: alert.CreatedAt,
+			Source:    SourceAlert,
+			Severity:  severity,
+			Namespace: alert.Metadata["namespace"],
+			App:       app,
+			Reason:    alert.Type,
+			Message:   alert.Title + ": " + alert.Message,
+			Metadata:  map[string]string{"status": alert.Status},
+		})
+	}
+	return entries, nil
+}
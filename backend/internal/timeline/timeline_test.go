@@ -0,0 +1,72 @@
+package timeline
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMatchesApp(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"api", true},
+		{"api-7d9f8", true},
+		{"api-7d9f8-abcde", true},
+		{"apiserver", false},
+		{"web-api", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := MatchesApp(tt.name, "api"); got != tt.want {
+			t.Errorf("MatchesApp(%q, api) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCorrelate(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	entries := []Entry{
+		{ID: "old-deploy", Timestamp: at(0), Change: true, Severity: SeverityInfo, Namespace: "apps", App: "api"},
+		{ID: "deploy", Timestamp: at(40), Change: true, Severity: SeverityInfo, Namespace: "apps", App: "api"},
+		{ID: "other-app", Timestamp: at(42), Change: true, Severity: SeverityInfo, Namespace: "apps", App: "web"},
+		{ID: "other-ns", Timestamp: at(43), Change: true, Severity: SeverityInfo, Namespace: "staging", App: "api"},
+		{ID: "pulled", Timestamp: at(44), Severity: SeverityInfo, Namespace: "apps", App: "api-7d9f8-abcde"},
+		{ID: "crash", Timestamp: at(45), Severity: SeverityWarning, Namespace: "apps", App: "api-7d9f8-abcde"},
+	}
+
+	Correlate(entries, 30*time.Minute)
+
+	if got := entries[5].Related; !reflect.DeepEqual(got, []string{"deploy"}) {
+		t.Errorf("crash related to %v, want [deploy]", got)
+	}
+	for _, entry := range entries[:5] {
+		if entry.Related != nil {
+			t.Errorf("%s should not be correlated, got %v", entry.ID, entry.Related)
+		}
+	}
+}
+
+func TestQueryMatches(t *testing.T) {
+	now := time.Now()
+	query := Query{App: "api", Namespace: "apps", Since: now.Add(-time.Hour), Until: now}
+
+	tests := []struct {
+		name  string
+		entry Entry
+		want  bool
+	}{
+		{"pod of app", Entry{Timestamp: now.Add(-time.Minute), Namespace: "apps", App: "api-7d9f8-abcde"}, true},
+		{"too old", Entry{Timestamp: now.Add(-2 * time.Hour), Namespace: "apps", App: "api"}, false},
+		{"other namespace", Entry{Timestamp: now.Add(-time.Minute), Namespace: "staging", App: "api"}, false},
+		{"cluster-wide alert for app", Entry{Timestamp: now.Add(-time.Minute), App: "api"}, true},
+		{"other app", Entry{Timestamp: now.Add(-time.Minute), Namespace: "apps", App: "web"}, false},
+	}
+	for _, tt := range tests {
+		if got := query.matches(tt.entry); got != tt.want {
+			t.Errorf("%s: matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Package traces queries a distributed tracing backend (Grafana Tempo or Jaeger)
+// for OpenTelemetry traces. It powers trace search, waterfall rendering and the
+// latency figures shown in the service mesh view.
+package traces
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Provider identifies the tracing backend's query API
+type Provider string
+
+const (
+	ProviderTempo  Provider = "tempo"
+	ProviderJaeger Provider = "jaeger"
+)
+
+// ErrTraceNotFound is returned when the backend has no trace with the requested ID
+var ErrTraceNotFound = errors.New("trace not found")
+
+// SearchQuery filters trace searches. Zero values are not sent to the backend.
+type SearchQuery struct {
+	Service     string
+	Operation   string
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	Start       time.Time
+	End         time.Time
+	Limit       int
+}
+
+// TraceSummary is a search result describing a trace's root span
+type TraceSummary struct {
+	TraceID       string    `json:"traceId"`
+	RootService   string    `json:"rootService"`
+	RootOperation string    `json:"rootOperation"`
+	StartTime     time.Time `json:"startTime"`
+	DurationMs    float64   `json:"durationMs"`
+	SpanCount     int       `json:"spanCount,omitempty"`
+	ErrorCount    int       `json:"errorCount,omitempty"`
+	Services      []string  `json:"services,omitempty"`
+}
+
+// Span is a single operation within a trace
+type Span struct {
+	SpanID       string            `json:"spanId"`
+	ParentSpanID string            `json:"parentSpanId,omitempty"`
+	Service      string            `json:"service"`
+	Operation    string            `json:"operation"`
+	Kind         string            `json:"kind,omitempty"`
+	StartTime    time.Time         `json:"startTime"`
+	DurationMs   float64           `json:"durationMs"`
+	Error        bool              `json:"error"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+
+	// Waterfall layout, filled in by BuildTrace
+	OffsetMs float64 `json:"offsetMs"`
+	Depth    int     `json:"depth"`
+}
+
+// Trace is a complete trace with spans ordered for waterfall rendering
+type Trace struct {
+	TraceID    string    `json:"traceId"`
+	StartTime  time.Time `json:"startTime"`
+	DurationMs float64   `json:"durationMs"`
+	Services   []string  `json:"services"`
+	Spans      []Span    `json:"spans"`
+}
+
+// Latency summarises observed request durations in milliseconds
+type Latency struct {
+	P50     float64 `json:"p50"`
+	P95     float64 `json:"p95"`
+	P99     float64 `json:"p99"`
+	Samples int     `json:"samples"`
+}
+
+// backend is implemented by each tracing provider's query API
+type backend interface {
+	search(ctx context.Context, q SearchQuery) ([]TraceSummary, error)
+	trace(ctx context.Context, traceID string) ([]Span, error)
+}
+
+// Client queries a tracing backend over HTTP
+type Client struct {
+	provider Provider
+	backend  backend
+}
+
+// NewClient creates a client for the given provider's query endpoint,
+// e.g. http://tempo.monitoring:3200 or http://jaeger-query.monitoring:16686
+func NewClient(provider Provider, baseURL string) (*Client, error) {
+	if _, err := url.ParseRequestURI(baseURL); err != nil {
+		return nil, fmt.Errorf("invalid tracing URL: %w", err)
+	}
+	api := &httpAPI{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+
+	client := &Client{provider: provider}
+	switch provider {
+	case ProviderTempo:
+		client.backend = &tempoBackend{api: api}
+	case ProviderJaeger:
+		client.backend = &jaegerBackend{api: api}
+	default:
+		return nil, fmt.Errorf("unsupported tracing provider: %s", provider)
+	}
+	return client, nil
+}
+
+// Provider returns the backend type this client talks to
+func (c *Client) Provider() Provider {
+	return c.provider
+}
+
+// Search returns traces matching the query, newest first
+func (c *Client) Search(ctx context.Context, q SearchQuery) ([]TraceSummary, error) {
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+	if q.End.IsZero() {
+		q.End = time.Now()
+	}
+	if q.Start.IsZero() {
+		q.Start = q.End.Add(-time.Hour)
+	}
+
+	summaries, err := c.backend.search(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].StartTime.After(summaries[j].StartTime)
+	})
+	if len(summaries) > q.Limit {
+		summaries = summaries[:q.Limit]
+	}
+	return summaries, nil
+}
+
+// GetTrace fetches a trace and lays out its spans for a waterfall view
+func (c *Client) GetTrace(ctx context.Context, traceID string) (*Trace, error) {
+	spans, err := c.backend.trace(ctx, traceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(spans) == 0 {
+		return nil, ErrTraceNotFound
+	}
+	return BuildTrace(traceID, spans), nil
+}
+
+// ServiceLatency computes latency percentiles from the durations of a service's recent traces
+func (c *Client) ServiceLatency(ctx context.Context, service string, lookback time.Duration) (Latency, error) {
+	summaries, err := c.Search(ctx, SearchQuery{
+		Service: service,
+		Start:   time.Now().Add(-lookback),
+		Limit:   200,
+	})
+	if err != nil {
+		return Latency{}, err
+	}
+
+	durations := make([]float64, len(summaries))
+	for i, summary := range summaries {
+		durations[i] = summary.DurationMs
+	}
+	return LatencyFromDurations(durations), nil
+}
+
+// LatencyFromDurations returns nearest-rank percentiles of the given durations
+func LatencyFromDurations(durations []float64) Latency {
+	if len(durations) == 0 {
+		return Latency{}
+	}
+	sorted := append([]float64(nil), durations...)
+	sort.Float64s(sorted)
+
+	percentile := func(p float64) float64 {
+		rank := int(p*float64(len(sorted)) + 0.999999)
+		if rank < 1 {
+			rank = 1
+		}
+		return sorted[rank-1]
+	}
+	return Latency{
+		P50:     percentile(0.50),
+		P95:     percentile(0.95),
+		P99:     percentile(0.99),
+		Samples: len(sorted),
+	}
+}
+
+// BuildTrace orders spans depth-first from their roots and computes each span's
+// offset from the trace start and its nesting depth
+func BuildTrace(traceID string, spans []Span) *Trace {
+	trace := &Trace{TraceID: traceID}
+
+	byID := make(map[string]bool, len(spans))
+	var end time.Time
+	services := map[string]bool{}
+	for _, span := range spans {
+		byID[span.SpanID] = true
+		if trace.StartTime.IsZero() || span.StartTime.Before(trace.StartTime) {
+			trace.StartTime = span.StartTime
+		}
+		if spanEnd := span.StartTime.Add(time.Duration(span.DurationMs * float64(time.Millisecond))); spanEnd.After(end) {
+			end = spanEnd
+		}
+		services[span.Service] = true
+	}
+	trace.DurationMs = float64(end.Sub(trace.StartTime)) / float64(time.Millisecond)
+	for service := range services {
+		trace.Services = append(trace.Services, service)
+	}
+	sort.Strings(trace.Services)
+
+	// Spans whose parent is missing from the trace are treated as roots
+	children := make(map[string][]Span)
+	var roots []Span
+	for _, span := range spans {
+		if span.ParentSpanID == "" || !byID[span.ParentSpanID] {
+			roots = append(roots, span)
+		} else {
+			children[span.ParentSpanID] = append(children[span.ParentSpanID], span)
+		}
+	}
+	byStart := func(s []Span) {
+		sort.SliceStable(s, func(i, j int) bool { return s[i].StartTime.Before(s[j].StartTime) })
+	}
+
+	trace.Spans = make([]Span, 0, len(spans))
+	var visit func(span Span, depth int)
+	visit = func(span Span, depth int) {
+		span.Depth = depth
+		span.OffsetMs = float64(span.StartTime.Sub(trace.StartTime)) / float64(time.Millisecond)
+		trace.Spans = append(trace.Spans, span)
+		kids := children[span.SpanID]
+		delete(children, span.SpanID) // guards against cycles in malformed traces
+		byStart(kids)
+		for _, child := range kids {
+			visit(child, depth+1)
+		}
+	}
+	byStart(roots)
+	for _, root := range roots {
+		visit(root, 0)
+	}
+	return trace
+}
+
+// httpAPI performs JSON GET requests against the tracing backend
+type httpAPI struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func (a *httpAPI) get(ctx context.Context, path string, params url.Values, out interface{}) error {
+	u := a.baseURL + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrTraceNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("tracing API error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
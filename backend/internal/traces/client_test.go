@@ -0,0 +1,178 @@
+package traces
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBuildTrace(t *testing.T) {
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	spans := []Span{
+		{SpanID: "c", ParentSpanID: "a", Service: "db", Operation: "SELECT", StartTime: base.Add(20 * time.Millisecond), DurationMs: 5},
+		{SpanID: "b", ParentSpanID: "a", Service: "api", Operation: "auth", StartTime: base.Add(2 * time.Millisecond), DurationMs: 10},
+		{SpanID: "a", Service: "gateway", Operation: "GET /users", StartTime: base, DurationMs: 40},
+		{SpanID: "d", ParentSpanID: "missing", Service: "worker", Operation: "async", StartTime: base.Add(50 * time.Millisecond), DurationMs: 10},
+	}
+
+	trace := BuildTrace("abc", spans)
+
+	order := []string{"a", "b", "c", "d"}
+	depths := []int{0, 1, 1, 0}
+	for i, span := range trace.Spans {
+		if span.SpanID != order[i] || span.Depth != depths[i] {
+			t.Errorf("span %d: got %s at depth %d, want %s at depth %d", i, span.SpanID, span.Depth, order[i], depths[i])
+		}
+	}
+	if trace.Spans[2].OffsetMs != 20 {
+		t.Errorf("expected offset 20ms, got %v", trace.Spans[2].OffsetMs)
+	}
+	if trace.DurationMs != 60 {
+		t.Errorf("expected trace duration 60ms, got %v", trace.DurationMs)
+	}
+	if len(trace.Services) != 4 || trace.Services[0] != "api" {
+		t.Errorf("expected sorted services, got %v", trace.Services)
+	}
+}
+
+func TestLatencyFromDurations(t *testing.T) {
+	durations := make([]float64, 100)
+	for i := range durations {
+		durations[len(durations)-1-i] = float64(i + 1)
+	}
+
+	latency := LatencyFromDurations(durations)
+	if latency.P50 != 50 || latency.P95 != 95 || latency.P99 != 99 || latency.Samples != 100 {
+		t.Errorf("unexpected percentiles: %+v", latency)
+	}
+	if got := LatencyFromDurations(nil); got != (Latency{}) {
+		t.Errorf("expected zero latency without samples, got %+v", got)
+	}
+}
+
+func TestJaegerClient(t *testing.T) {
+	const trace = `{"data":[{"traceID":"t1","spans":[
+		{"spanID":"s1","operationName":"GET /","references":[],"startTime":1700000000000000,"duration":30000,"tags":[{"key":"span.kind","value":"server"}],"processID":"p1"},
+		{"spanID":"s2","operationName":"query","references":[{"refType":"CHILD_OF","spanID":"s1"}],"startTime":1700000000010000,"duration":5000,"tags":[{"key":"error","value":true}],"processID":"p2"}
+	],"processes":{"p1":{"serviceName":"web"},"p2":{"serviceName":"db"}}}]}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/traces":
+			if r.URL.Query().Get("service") != "web" || r.URL.Query().Get("minDuration") != "10ms" {
+				t.Errorf("unexpected search query: %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(trace))
+		case "/api/traces/t1":
+			w.Write([]byte(trace))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ProviderJaeger, server.URL)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	ctx := context.Background()
+
+	results, err := client.Search(ctx, SearchQuery{Service: "web", MinDuration: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 1 || results[0].RootService != "web" || results[0].DurationMs != 30 || results[0].ErrorCount != 1 {
+		t.Errorf("unexpected summaries: %+v", results)
+	}
+
+	got, err := client.GetTrace(ctx, "t1")
+	if err != nil {
+		t.Fatalf("GetTrace: %v", err)
+	}
+	if len(got.Spans) != 2 || got.Spans[1].Service != "db" || got.Spans[1].Depth != 1 || !got.Spans[1].Error {
+		t.Errorf("unexpected trace: %+v", got.Spans)
+	}
+
+	if _, err := client.GetTrace(ctx, "missing"); !errors.Is(err, ErrTraceNotFound) {
+		t.Errorf("expected ErrTraceNotFound, got %v", err)
+	}
+	if _, err := client.Search(ctx, SearchQuery{}); err == nil {
+		t.Error("expected Jaeger search without a service to fail")
+	}
+}
+
+func TestTempoClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/search":
+			if tags := r.URL.Query().Get("tags"); tags != `service.name=web name="GET /users"` && tags != "service.name=web" {
+				t.Errorf("unexpected tags: %q", tags)
+			}
+			w.Write([]byte(`{"traces":[
+				{"traceID":"old","rootServiceName":"web","rootTraceName":"GET /users","startTimeUnixNano":"1700000000000000000","durationMs":12},
+				{"traceID":"new","rootServiceName":"web","rootTraceName":"GET /users","startTimeUnixNano":"1700000060000000000","durationMs":48}
+			]}`))
+		case "/api/traces/new":
+			w.Write([]byte(`{"batches":[{
+				"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"web"}}]},
+				"scopeSpans":[{"spans":[
+					{"spanId":"AAAAAAAAAAE=","name":"GET /users","kind":"SPAN_KIND_SERVER","startTimeUnixNano":"1700000060000000000","endTimeUnixNano":"1700000060048000000","status":{}},
+					{"spanId":"AAAAAAAAAAI=","parentSpanId":"AAAAAAAAAAE=","name":"render","kind":1,"startTimeUnixNano":"1700000060010000000","endTimeUnixNano":"1700000060020000000","status":{"code":"STATUS_CODE_ERROR"},
+					 "attributes":[{"key":"http.status_code","value":{"intValue":"500"}}]}
+				]}]
+			}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ProviderTempo, server.URL)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	ctx := context.Background()
+
+	results, err := client.Search(ctx, SearchQuery{Service: "web", Operation: "GET /users"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 2 || results[0].TraceID != "new" {
+		t.Errorf("expected newest trace first, got %+v", results)
+	}
+
+	got, err := client.GetTrace(ctx, "new")
+	if err != nil {
+		t.Fatalf("GetTrace: %v", err)
+	}
+	if len(got.Spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(got.Spans))
+	}
+	child := got.Spans[1]
+	if child.SpanID != "0000000000000002" || child.ParentSpanID != "0000000000000001" || child.Depth != 1 {
+		t.Errorf("unexpected span ids: %+v", child)
+	}
+	if child.Kind != "internal" || !child.Error || child.DurationMs != 10 || child.Attributes["http.status_code"] != "500" {
+		t.Errorf("unexpected child span: %+v", child)
+	}
+
+	latency, err := client.ServiceLatency(ctx, "web", time.Hour)
+	if err != nil {
+		t.Fatalf("ServiceLatency: %v", err)
+	}
+	if latency.Samples != 2 || latency.P50 != 12 || latency.P99 != 48 {
+		t.Errorf("unexpected latency: %+v", latency)
+	}
+}
+
+func TestNewClientRejectsUnknownProvider(t *testing.T) {
+	if _, err := NewClient("zipkin", "http://localhost:9411"); err == nil {
+		t.Error("expected unsupported provider error")
+	}
+	if _, err := NewClient(ProviderTempo, "not a url"); err == nil {
+		t.Error("expected invalid URL error")
+	}
+}
@@ -0,0 +1,142 @@
+package traces
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// jaegerBackend talks to the Jaeger query service HTTP API (/api/traces)
+type jaegerBackend struct {
+	api *httpAPI
+}
+
+type jaegerResponse struct {
+	Data []jaegerTrace `json:"data"`
+}
+
+type jaegerTrace struct {
+	TraceID   string                   `json:"traceID"`
+	Spans     []jaegerSpan             `json:"spans"`
+	Processes map[string]jaegerProcess `json:"processes"`
+}
+
+type jaegerSpan struct {
+	SpanID        string `json:"spanID"`
+	OperationName string `json:"operationName"`
+	References    []struct {
+		RefType string `json:"refType"`
+		SpanID  string `json:"spanID"`
+	} `json:"references"`
+	StartTime int64       `json:"startTime"` // microseconds since epoch
+	Duration  int64       `json:"duration"`  // microseconds
+	Tags      []jaegerTag `json:"tags"`
+	ProcessID string      `json:"processID"`
+}
+
+type jaegerTag struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+type jaegerProcess struct {
+	ServiceName string `json:"serviceName"`
+}
+
+func (b *jaegerBackend) search(ctx context.Context, q SearchQuery) ([]TraceSummary, error) {
+	if q.Service == "" {
+		return nil, fmt.Errorf("service is required to search Jaeger traces")
+	}
+
+	params := url.Values{}
+	params.Set("service", q.Service)
+	if q.Operation != "" {
+		params.Set("operation", q.Operation)
+	}
+	if q.MinDuration > 0 {
+		params.Set("minDuration", q.MinDuration.String())
+	}
+	if q.MaxDuration > 0 {
+		params.Set("maxDuration", q.MaxDuration.String())
+	}
+	params.Set("start", strconv.FormatInt(q.Start.UnixMicro(), 10))
+	params.Set("end", strconv.FormatInt(q.End.UnixMicro(), 10))
+	params.Set("limit", strconv.Itoa(q.Limit))
+
+	var resp jaegerResponse
+	if err := b.api.get(ctx, "/api/traces", params, &resp); err != nil {
+		return nil, err
+	}
+
+	summaries := make([]TraceSummary, 0, len(resp.Data))
+	for _, jt := range resp.Data {
+		trace := BuildTrace(jt.TraceID, jt.spans())
+		if len(trace.Spans) == 0 {
+			continue
+		}
+		root := trace.Spans[0]
+		summary := TraceSummary{
+			TraceID:       jt.TraceID,
+			RootService:   root.Service,
+			RootOperation: root.Operation,
+			StartTime:     trace.StartTime,
+			DurationMs:    trace.DurationMs,
+			SpanCount:     len(trace.Spans),
+			Services:      trace.Services,
+		}
+		for _, span := range trace.Spans {
+			if span.Error {
+				summary.ErrorCount++
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+func (b *jaegerBackend) trace(ctx context.Context, traceID string) ([]Span, error) {
+	var resp jaegerResponse
+	if err := b.api.get(ctx, "/api/traces/"+url.PathEscape(traceID), nil, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, ErrTraceNotFound
+	}
+	return resp.Data[0].spans(), nil
+}
+
+// spans converts Jaeger spans, resolving each span's service from its process
+func (t jaegerTrace) spans() []Span {
+	spans := make([]Span, 0, len(t.Spans))
+	for _, js := range t.Spans {
+		span := Span{
+			SpanID:     js.SpanID,
+			Service:    t.Processes[js.ProcessID].ServiceName,
+			Operation:  js.OperationName,
+			StartTime:  time.UnixMicro(js.StartTime).UTC(),
+			DurationMs: float64(js.Duration) / 1000,
+			Attributes: make(map[string]string, len(js.Tags)),
+		}
+		for _, ref := range js.References {
+			if ref.RefType == "CHILD_OF" || span.ParentSpanID == "" {
+				span.ParentSpanID = ref.SpanID
+			}
+		}
+		for _, tag := range js.Tags {
+			value := fmt.Sprint(tag.Value)
+			switch tag.Key {
+			case "span.kind":
+				span.Kind = value
+			case "error":
+				span.Error = value == "true"
+			case "otel.status_code":
+				span.Error = span.Error || value == "ERROR"
+			}
+			span.Attributes[tag.Key] = value
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
@@ -0,0 +1,201 @@
+package traces
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tempoBackend talks to the Grafana Tempo HTTP API (/api/search and /api/traces)
+type tempoBackend struct {
+	api *httpAPI
+}
+
+type tempoSearchResponse struct {
+	Traces []struct {
+		TraceID           string  `json:"traceID"`
+		RootServiceName   string  `json:"rootServiceName"`
+		RootTraceName     string  `json:"rootTraceName"`
+		StartTimeUnixNano string  `json:"startTimeUnixNano"`
+		DurationMs        float64 `json:"durationMs"`
+	} `json:"traces"`
+}
+
+// tempoTraceResponse is an OTLP JSON trace; Tempo v1 uses batches, v2 wraps resourceSpans in trace
+type tempoTraceResponse struct {
+	Batches       []otlpResourceSpans `json:"batches"`
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	Trace         *struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	} `json:"trace"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans                  []otlpScopeSpans `json:"scopeSpans"`
+	InstrumentationLibrarySpans []otlpScopeSpans `json:"instrumentationLibrarySpans"`
+}
+
+type otlpScopeSpans struct {
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId"`
+	Name              string          `json:"name"`
+	Kind              json.RawMessage `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            struct {
+		Code json.RawMessage `json:"code"`
+	} `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func (b *tempoBackend) search(ctx context.Context, q SearchQuery) ([]TraceSummary, error) {
+	var tags []string
+	if q.Service != "" {
+		tags = append(tags, "service.name="+logfmtValue(q.Service))
+	}
+	if q.Operation != "" {
+		tags = append(tags, "name="+logfmtValue(q.Operation))
+	}
+
+	params := url.Values{}
+	if len(tags) > 0 {
+		params.Set("tags", strings.Join(tags, " "))
+	}
+	if q.MinDuration > 0 {
+		params.Set("minDuration", q.MinDuration.String())
+	}
+	if q.MaxDuration > 0 {
+		params.Set("maxDuration", q.MaxDuration.String())
+	}
+	params.Set("start", strconv.FormatInt(q.Start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(q.End.Unix(), 10))
+	params.Set("limit", strconv.Itoa(q.Limit))
+
+	var resp tempoSearchResponse
+	if err := b.api.get(ctx, "/api/search", params, &resp); err != nil {
+		return nil, err
+	}
+
+	summaries := make([]TraceSummary, 0, len(resp.Traces))
+	for _, t := range resp.Traces {
+		start, _ := strconv.ParseInt(t.StartTimeUnixNano, 10, 64)
+		summaries = append(summaries, TraceSummary{
+			TraceID:       t.TraceID,
+			RootService:   t.RootServiceName,
+			RootOperation: t.RootTraceName,
+			StartTime:     time.Unix(0, start).UTC(),
+			DurationMs:    t.DurationMs,
+		})
+	}
+	return summaries, nil
+}
+
+func (b *tempoBackend) trace(ctx context.Context, traceID string) ([]Span, error) {
+	var resp tempoTraceResponse
+	if err := b.api.get(ctx, "/api/traces/"+url.PathEscape(traceID), nil, &resp); err != nil {
+		return nil, err
+	}
+
+	resourceSpans := append(resp.Batches, resp.ResourceSpans...)
+	if resp.Trace != nil {
+		resourceSpans = append(resourceSpans, resp.Trace.ResourceSpans...)
+	}
+
+	var spans []Span
+	for _, rs := range resourceSpans {
+		service := attributeMap(rs.Resource.Attributes)["service.name"]
+		for _, scope := range append(rs.ScopeSpans, rs.InstrumentationLibrarySpans...) {
+			for _, os := range scope.Spans {
+				start, _ := strconv.ParseInt(os.StartTimeUnixNano, 10, 64)
+				end, _ := strconv.ParseInt(os.EndTimeUnixNano, 10, 64)
+				spans = append(spans, Span{
+					SpanID:       otlpID(os.SpanID),
+					ParentSpanID: otlpID(os.ParentSpanID),
+					Service:      service,
+					Operation:    os.Name,
+					Kind:         otlpKind(os.Kind),
+					StartTime:    time.Unix(0, start).UTC(),
+					DurationMs:   float64(end-start) / float64(time.Millisecond),
+					Error:        otlpError(os.Status.Code),
+					Attributes:   attributeMap(os.Attributes),
+				})
+			}
+		}
+	}
+	return spans, nil
+}
+
+// otlpID converts a base64 OTLP JSON span ID to the hex form used elsewhere; hex IDs pass through
+func otlpID(id string) string {
+	if id == "" {
+		return ""
+	}
+	if _, err := hex.DecodeString(id); err == nil && (len(id) == 16 || len(id) == 32) {
+		return strings.ToLower(id)
+	}
+	if raw, err := base64.StdEncoding.DecodeString(id); err == nil {
+		return hex.EncodeToString(raw)
+	}
+	return id
+}
+
+// otlpKind accepts both enum names (SPAN_KIND_SERVER) and numbers (2)
+func otlpKind(raw json.RawMessage) string {
+	var name string
+	if json.Unmarshal(raw, &name) == nil {
+		return strings.ToLower(strings.TrimPrefix(name, "SPAN_KIND_"))
+	}
+	var n int
+	if json.Unmarshal(raw, &n) == nil {
+		kinds := []string{"unspecified", "internal", "server", "client", "producer", "consumer"}
+		if n >= 0 && n < len(kinds) {
+			return kinds[n]
+		}
+	}
+	return ""
+}
+
+func otlpError(raw json.RawMessage) bool {
+	var name string
+	if json.Unmarshal(raw, &name) == nil {
+		return name == "STATUS_CODE_ERROR"
+	}
+	var n int
+	return json.Unmarshal(raw, &n) == nil && n == 2
+}
+
+func attributeMap(attributes []otlpAttribute) map[string]string {
+	values := make(map[string]string, len(attributes))
+	for _, attr := range attributes {
+		for _, v := range attr.Value {
+			values[attr.Key] = fmt.Sprint(v)
+			break
+		}
+	}
+	return values
+}
+
+func logfmtValue(value string) string {
+	if strings.ContainsAny(value, " \"=") {
+		return strconv.Quote(value)
+	}
+	return value
+}
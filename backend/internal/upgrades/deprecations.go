@@ -0,0 +1,313 @@
+package upgrades
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Statuses of a deprecated API in use
+const (
+	StatusRemoved    = "removed"    // the cluster no longer serves it; applying fails today
+	StatusBreaks     = "breaks"     // removed by the target version; the upgrade breaks it
+	StatusDeprecated = "deprecated" // removed in a later version
+)
+
+// Where a deprecated API is used
+const (
+	SourceManifest = "manifest" // a manifest stored in the GitOps repository
+	SourceLive     = "live"     // an object in the cluster last written through the API
+)
+
+// lastAppliedAnnotation keeps the object kubectl apply last sent, with its API version
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// DeprecatedAPI is a stored manifest or live object using a removed API version
+type DeprecatedAPI struct {
+	Source      string `json:"source"`
+	File        string `json:"file,omitempty"`    // manifests only
+	Manager     string `json:"manager,omitempty"` // live objects only: the field manager writing through the API
+	APIVersion  string `json:"api_version"`
+	Kind        string `json:"kind"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name"`
+	RemovedIn   string `json:"removed_in"`
+	Replacement string `json:"replacement"`
+	Status      string `json:"status"`
+}
+
+// DeprecationScan lists the deprecated APIs in use against an upgrade target
+type DeprecationScan struct {
+	Kubernetes     string            `json:"kubernetes"`
+	Target         string            `json:"target"`
+	APIs           []DeprecatedAPI   `json:"apis"`
+	Counts         map[string]int    `json:"counts"` // APIs by status
+	UpgradeBlocked bool              `json:"upgrade_blocked"`
+	Warnings       []string          `json:"warnings"`
+	Errors         map[string]string `json:"errors,omitempty"` // manifests and kinds that couldn't be read
+	CheckedAt      time.Time         `json:"checked_at"`
+}
+
+// removedAPI is an API version the Kubernetes minor release removedIn no longer serves.
+// An empty kind covers every kind of the group version.
+type removedAPI struct {
+	apiVersion  string
+	kind        string
+	removedIn   int // minor version of Kubernetes 1.x
+	replacement string
+}
+
+// removedAPIs follows the Kubernetes deprecated API migration guide
+var removedAPIs = []removedAPI{
+	{"extensions/v1beta1", "Deployment", 16, "apps/v1"},
+	{"extensions/v1beta1", "DaemonSet", 16, "apps/v1"},
+	{"extensions/v1beta1", "ReplicaSet", 16, "apps/v1"},
+	{"extensions/v1beta1", "NetworkPolicy", 16, "networking.k8s.io/v1"},
+	{"extensions/v1beta1", "PodSecurityPolicy", 16, "policy/v1beta1"},
+	{"apps/v1beta1", "", 16, "apps/v1"},
+	{"apps/v1beta2", "", 16, "apps/v1"},
+	{"extensions/v1beta1", "Ingress", 22, "networking.k8s.io/v1"},
+	{"networking.k8s.io/v1beta1", "", 22, "networking.k8s.io/v1"},
+	{"apiextensions.k8s.io/v1beta1", "", 22, "apiextensions.k8s.io/v1"},
+	{"admissionregistration.k8s.io/v1beta1", "", 22, "admissionregistration.k8s.io/v1"},
+	{"apiregistration.k8s.io/v1beta1", "", 22, "apiregistration.k8s.io/v1"},
+	{"authentication.k8s.io/v1beta1", "", 22, "authentication.k8s.io/v1"},
+	{"authorization.k8s.io/v1beta1", "", 22, "authorization.k8s.io/v1"},
+	{"certificates.k8s.io/v1beta1", "", 22, "certificates.k8s.io/v1"},
+	{"coordination.k8s.io/v1beta1", "", 22, "coordination.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "", 22, "rbac.authorization.k8s.io/v1"},
+	{"scheduling.k8s.io/v1beta1", "", 22, "scheduling.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSIDriver", 22, "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSINode", 22, "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "StorageClass", 22, "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "VolumeAttachment", 22, "storage.k8s.io/v1"},
+	{"batch/v1beta1", "CronJob", 25, "batch/v1"},
+	{"discovery.k8s.io/v1beta1", "", 25, "discovery.k8s.io/v1"},
+	{"events.k8s.io/v1beta1", "", 25, "events.k8s.io/v1"},
+	{"autoscaling/v2beta1", "", 25, "autoscaling/v2"},
+	{"policy/v1beta1", "PodDisruptionBudget", 25, "policy/v1"},
+	{"policy/v1beta1", "PodSecurityPolicy", 25, "Pod Security Admission"},
+	{"node.k8s.io/v1beta1", "", 25, "node.k8s.io/v1"},
+	{"autoscaling/v2beta2", "", 26, "autoscaling/v2"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", "", 26, "flowcontrol.apiserver.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSIStorageCapacity", 27, "storage.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", "", 29, "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta3", "", 32, "flowcontrol.apiserver.k8s.io/v1"},
+}
+
+// findRemovedAPI returns the removal of a kind's API version, if it is removed
+func findRemovedAPI(apiVersion, kind string) (removedAPI, bool) {
+	for _, removed := range removedAPIs {
+		if removed.apiVersion == apiVersion && (removed.kind == "" || removed.kind == kind) {
+			return removed, true
+		}
+	}
+	return removedAPI{}, false
+}
+
+// liveKinds are the resources whose live objects are checked, each a kind some removed API
+// version served
+var liveKinds = []string{
+	"deployments.apps", "daemonsets.apps", "statefulsets.apps", "replicasets.apps",
+	"networkpolicies.networking.k8s.io", "ingresses.networking.k8s.io", "ingressclasses.networking.k8s.io",
+	"customresourcedefinitions.apiextensions.k8s.io", "apiservices.apiregistration.k8s.io",
+	"validatingwebhookconfigurations.admissionregistration.k8s.io", "mutatingwebhookconfigurations.admissionregistration.k8s.io",
+	"roles.rbac.authorization.k8s.io", "rolebindings.rbac.authorization.k8s.io",
+	"clusterroles.rbac.authorization.k8s.io", "clusterrolebindings.rbac.authorization.k8s.io",
+	"priorityclasses.scheduling.k8s.io", "storageclasses.storage.k8s.io", "csidrivers.storage.k8s.io",
+	"cronjobs.batch", "poddisruptionbudgets.policy", "horizontalpodautoscalers.autoscaling",
+	"runtimeclasses.node.k8s.io", "flowschemas.flowcontrol.apiserver.k8s.io",
+	"prioritylevelconfigurations.flowcontrol.apiserver.k8s.io",
+}
+
+// ScanDeprecatedAPIs lists the stored manifests and live objects using API versions removed
+// in the target Kubernetes version, such as "1.31", or later. An empty target is the next
+// minor release.
+func (s *Service) ScanDeprecatedAPIs(ctx context.Context, target string) (*DeprecationScan, error) {
+	version, currentMinor, targetMinor, err := s.versions(target)
+	if err != nil {
+		return nil, err
+	}
+	scan := &DeprecationScan{
+		Kubernetes: version,
+		Target:     "1." + strconv.Itoa(targetMinor),
+		APIs:       []DeprecatedAPI{},
+		Counts:     map[string]int{},
+		Warnings:   []string{},
+		Errors:     map[string]string{},
+		CheckedAt:  time.Now(),
+	}
+	if targetMinor > currentMinor+1 {
+		scan.Warnings = append(scan.Warnings, fmt.Sprintf("Kubernetes upgrades one minor version at a time; 1.%d needs %d upgrades",
+			targetMinor, targetMinor-currentMinor))
+	}
+
+	if s.manifests != nil {
+		manifests, err := s.manifests(ctx)
+		if err != nil {
+			return nil, err
+		}
+		scan.APIs = append(scan.APIs, findDeprecatedAPIs(manifests, currentMinor, targetMinor, scan.Errors)...)
+	}
+	scan.APIs = append(scan.APIs, s.findLiveDeprecatedAPIs(ctx, currentMinor, targetMinor, scan.Errors)...)
+	if len(scan.Errors) == 0 {
+		scan.Errors = nil
+	}
+
+	for _, api := range scan.APIs {
+		scan.Counts[api.Status]++
+		subject := fmt.Sprintf("%s %s (%s) in %s", api.Kind, api.Name, api.APIVersion, api.File)
+		if api.Source == SourceLive {
+			subject = fmt.Sprintf("%s %s, last written as %s by %s,", api.Kind, objectName(api.Namespace, api.Name), api.APIVersion, api.Manager)
+		}
+		switch api.Status {
+		case StatusRemoved:
+			if api.Source == SourceManifest {
+				scan.Warnings = append(scan.Warnings, fmt.Sprintf("%s can't be applied: the API was removed in %s; use %s",
+					subject, api.RemovedIn, api.Replacement))
+			}
+		case StatusBreaks:
+			scan.UpgradeBlocked = true
+			scan.Warnings = append(scan.Warnings, fmt.Sprintf("Upgrading to %s breaks %s: the API is removed in %s; use %s",
+				scan.Target, subject, api.RemovedIn, api.Replacement))
+		}
+	}
+	return scan, nil
+}
+
+// deprecatedAPI grades the use of an API version against the current and target minor
+// versions, returning false when the version isn't removed
+func deprecatedAPI(apiVersion, kind string, currentMinor, targetMinor int) (DeprecatedAPI, bool) {
+	removed, ok := findRemovedAPI(apiVersion, kind)
+	if !ok {
+		return DeprecatedAPI{}, false
+	}
+	status := StatusDeprecated
+	switch {
+	case removed.removedIn <= currentMinor:
+		status = StatusRemoved
+	case removed.removedIn <= targetMinor:
+		status = StatusBreaks
+	}
+	return DeprecatedAPI{
+		APIVersion:  apiVersion,
+		Kind:        kind,
+		RemovedIn:   "1." + strconv.Itoa(removed.removedIn),
+		Replacement: removed.replacement,
+		Status:      status,
+	}, true
+}
+
+// findDeprecatedAPIs lists the objects of the manifests using removed API versions, noting
+// the manifests that can't be decoded in unreadable
+func findDeprecatedAPIs(manifests map[string][]byte, currentMinor, targetMinor int, unreadable map[string]string) []DeprecatedAPI {
+	var apis []DeprecatedAPI
+	for file, data := range manifests {
+		objects, err := k8s.DecodeManifests(data)
+		if err != nil {
+			unreadable[file] = err.Error()
+			continue
+		}
+		for _, obj := range objects {
+			api, ok := deprecatedAPI(obj.GetAPIVersion(), obj.GetKind(), currentMinor, targetMinor)
+			if !ok {
+				continue
+			}
+			api.Source = SourceManifest
+			api.File = file
+			api.Namespace = obj.GetNamespace()
+			api.Name = obj.GetName()
+			apis = append(apis, api)
+		}
+	}
+	sort.Slice(apis, func(i, j int) bool {
+		if apis[i].File != apis[j].File {
+			return apis[i].File < apis[j].File
+		}
+		return apis[i].Kind+apis[i].Name < apis[j].Kind+apis[j].Name
+	})
+	return apis
+}
+
+// findLiveDeprecatedAPIs lists the live objects whose field managers or last kubectl apply
+// used removed API versions. The API server converts every object to the version it is read
+// in, so these record how the object is written, by a pipeline or controller that breaks
+// once the version is gone. Kinds the cluster doesn't serve are skipped.
+func (s *Service) findLiveDeprecatedAPIs(ctx context.Context, currentMinor, targetMinor int, unreadable map[string]string) []DeprecatedAPI {
+	var apis []DeprecatedAPI
+	for _, kind := range liveKinds {
+		objects, err := s.cluster.ListObjects(ctx, kind)
+		if meta.IsNoMatchError(err) {
+			continue
+		} else if err != nil {
+			unreadable[kind] = err.Error()
+			continue
+		}
+		for _, obj := range objects {
+			for _, written := range writtenAPIVersions(obj) {
+				api, ok := deprecatedAPI(written.apiVersion, obj.GetKind(), currentMinor, targetMinor)
+				if !ok {
+					continue
+				}
+				api.Source = SourceLive
+				api.Manager = written.manager
+				api.Namespace = obj.GetNamespace()
+				api.Name = obj.GetName()
+				apis = append(apis, api)
+			}
+		}
+	}
+	sort.Slice(apis, func(i, j int) bool {
+		a, b := apis[i], apis[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return objectName(a.Namespace, a.Name)+a.Manager < objectName(b.Namespace, b.Name)+b.Manager
+	})
+	return apis
+}
+
+type writtenAPIVersion struct {
+	apiVersion string
+	manager    string
+}
+
+// writtenAPIVersions returns the API versions an object was written in, by its field managers
+// and the last kubectl apply, once each
+func writtenAPIVersions(obj unstructured.Unstructured) []writtenAPIVersion {
+	var written []writtenAPIVersion
+	seen := make(map[writtenAPIVersion]bool)
+	add := func(apiVersion, manager string) {
+		version := writtenAPIVersion{apiVersion: apiVersion, manager: manager}
+		if apiVersion != "" && !seen[version] {
+			seen[version] = true
+			written = append(written, version)
+		}
+	}
+	for _, field := range obj.GetManagedFields() {
+		add(field.APIVersion, field.Manager)
+	}
+	if lastApplied := obj.GetAnnotations()[lastAppliedAnnotation]; lastApplied != "" {
+		var applied struct {
+			APIVersion string `json:"apiVersion"`
+		}
+		if json.Unmarshal([]byte(lastApplied), &applied) == nil {
+			add(applied.APIVersion, "kubectl apply")
+		}
+	}
+	return written
+}
+
+func objectName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
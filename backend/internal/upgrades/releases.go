@@ -0,0 +1,153 @@
+package upgrades
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultReleasesURL is the GitHub API latest releases are looked up in
+const DefaultReleasesURL = "https://api.github.com"
+
+// releaseCacheTTL is how long a looked up release is reused, keeping well within GitHub's
+// limit of 60 unauthenticated requests an hour
+const releaseCacheTTL = 6 * time.Hour
+
+// Releases looks up the latest stable release of GitHub repositories
+type Releases struct {
+	apiURL string
+	token  string
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedRelease
+}
+
+type cachedRelease struct {
+	version   string
+	fetchedAt time.Time
+}
+
+// NewReleases creates a release lookup against a GitHub API, authenticated when token is set
+func NewReleases(apiURL, token string) *Releases {
+	if apiURL == "" {
+		apiURL = DefaultReleasesURL
+	}
+	return &Releases{
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+		cache:  make(map[string]cachedRelease),
+	}
+}
+
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// Latest returns the highest stable version released in a repository. Only tags starting
+// with tagPrefix count, for repositories releasing several components such as ingress-nginx.
+// Patch releases of older minors come out after newer ones, so the highest of the recent
+// releases is taken rather than the newest.
+func (r *Releases) Latest(ctx context.Context, repo, tagPrefix string) (string, error) {
+	key := repo + "@" + tagPrefix
+	r.mu.Lock()
+	cached, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < releaseCacheTTL {
+		return cached.version, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.apiURL+"/repos/"+repo+"/releases?per_page=50", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get releases of %s: %w", repo, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get releases of %s: %s", repo, resp.Status)
+	}
+	var releases []githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return "", fmt.Errorf("invalid releases of %s: %w", repo, err)
+	}
+
+	var latest string
+	for _, release := range releases {
+		tag, ok := strings.CutPrefix(release.TagName, tagPrefix)
+		if release.Draft || release.Prerelease || !ok {
+			continue
+		}
+		if _, ok := parseVersion(tag); ok && (latest == "" || CompareVersions(tag, latest) > 0) {
+			latest = tag
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("no stable release of %s found", repo)
+	}
+
+	r.mu.Lock()
+	r.cache[key] = cachedRelease{version: latest, fetchedAt: time.Now()}
+	r.mu.Unlock()
+	return latest, nil
+}
+
+// parseVersion reads the major, minor and patch of versions such as v1.30.4+k3s1, 2.10.5 or
+// v3.1. Pre-release and build suffixes are ignored.
+func parseVersion(version string) ([3]int, bool) {
+	var parts [3]int
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+_"); i >= 0 {
+		version = version[:i]
+	}
+	fields := strings.Split(version, ".")
+	if len(fields) < 2 || len(fields) > 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// CompareVersions returns -1, 0 or 1 as version a is lower than, equal to or higher than b.
+// Versions that don't parse are lower than any that do.
+func CompareVersions(a, b string) int {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			if va[i] < vb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,283 @@
+// Package upgrades compares the cluster's Kubernetes and addon versions against their latest
+// releases and finds the stored manifests and live objects a Kubernetes upgrade would break.
+package upgrades
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// supportedMinors is how many Kubernetes minor releases are maintained upstream
+const supportedMinors = 3
+
+// DefaultScanInterval is how often deprecated APIs are scanned for
+const DefaultScanInterval = 24 * time.Hour
+
+// ErrInvalidTarget is returned for upgrade targets that aren't a Kubernetes 1.x version
+var ErrInvalidTarget = errors.New("invalid target version")
+
+// lookupTimeout bounds the release lookups of a check
+const lookupTimeout = 10 * time.Second
+
+// Component is the running version of Kubernetes or an addon
+type Component struct {
+	Name            string `json:"name"`
+	Version         string `json:"version"`
+	Latest          string `json:"latest,omitempty"`
+	UpdateAvailable bool   `json:"update_available"`
+	Workload        string `json:"workload,omitempty"` // Kind namespace/name running the addon
+	Image           string `json:"image,omitempty"`
+	Error           string `json:"error,omitempty"` // why the latest release is unknown
+}
+
+// Report is the version intelligence of the cluster against an upgrade target
+type Report struct {
+	Kubernetes     Component         `json:"kubernetes"`
+	Addons         []Component       `json:"addons"`
+	Target         string            `json:"target"` // Kubernetes minor the APIs are checked against
+	DeprecatedAPIs []DeprecatedAPI   `json:"deprecated_apis"`
+	UpgradeBlocked bool              `json:"upgrade_blocked"` // something in use breaks on the target
+	Warnings       []string          `json:"warnings"`
+	Errors         map[string]string `json:"errors,omitempty"` // manifests and kinds that couldn't be read
+	CheckedAt      time.Time         `json:"checked_at"`
+}
+
+// addon is a cluster addon recognized by the repository of its image
+type addon struct {
+	name      string
+	repo      string // GitHub repository it is released from
+	tagPrefix string
+	images    []string // image repositories, matched without their registry
+}
+
+var addons = []addon{
+	{"cert-manager", "cert-manager/cert-manager", "", []string{"jetstack/cert-manager-controller"}},
+	{"ingress-nginx", "kubernetes/ingress-nginx", "controller-", []string{"ingress-nginx/controller"}},
+	{"traefik", "traefik/traefik", "", []string{"traefik", "rancher/mirrored-library-traefik"}},
+	{"cilium", "cilium/cilium", "", []string{"cilium/cilium"}},
+	{"calico", "projectcalico/calico", "", []string{"calico/node"}},
+	{"flannel", "flannel-io/flannel", "", []string{"flannel/flannel", "flannel-io/flannel", "rancher/mirrored-flannelcni-flannel"}},
+	{"coredns", "coredns/coredns", "", []string{"coredns/coredns", "coredns", "rancher/mirrored-coredns-coredns"}},
+	{"metrics-server", "kubernetes-sigs/metrics-server", "", []string{"metrics-server/metrics-server", "metrics-server", "rancher/mirrored-metrics-server"}},
+}
+
+// Cluster reports the versions and objects of the cluster; *k8s.Client implements it
+type Cluster interface {
+	ServerVersion() (string, error)
+	WorkloadImages(ctx context.Context) ([]k8s.WorkloadImage, error)
+	ListObjects(ctx context.Context, kind string) ([]unstructured.Unstructured, error)
+}
+
+// ManifestSource reads stored manifests, keyed by file name
+type ManifestSource func(ctx context.Context) (map[string][]byte, error)
+
+// Service checks the cluster for upgrades
+type Service struct {
+	cluster   Cluster
+	releases  *Releases
+	manifests ManifestSource
+
+	mu       sync.Mutex
+	lastScan *DeprecationScan
+	onScan   func(ctx context.Context, scan *DeprecationScan)
+}
+
+// NewService creates an upgrade check of a cluster. Without releases, latest versions
+// aren't looked up.
+func NewService(cluster Cluster, releases *Releases) *Service {
+	return &Service{cluster: cluster, releases: releases}
+}
+
+// SetManifestSource sets where the manifests checked for removed APIs are read from
+func (s *Service) SetManifestSource(source ManifestSource) {
+	s.manifests = source
+}
+
+// OnScan registers a callback receiving every scheduled deprecated API scan
+func (s *Service) OnScan(fn func(ctx context.Context, scan *DeprecationScan)) {
+	s.onScan = fn
+}
+
+// LastScan returns the last scheduled deprecated API scan, or nil before the first
+func (s *Service) LastScan() *DeprecationScan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastScan
+}
+
+// Start scans for deprecated APIs against the next minor release every interval until the
+// context is done
+func (s *Service) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultScanInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			scan, err := s.ScanDeprecatedAPIs(ctx, "")
+			if err != nil {
+				slog.Warn("Failed to scan for deprecated APIs", "error", err)
+			} else {
+				s.mu.Lock()
+				s.lastScan = scan
+				s.mu.Unlock()
+				if s.onScan != nil {
+					s.onScan(ctx, scan)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// versions returns the cluster's Kubernetes version, its minor and the target minor
+func (s *Service) versions(target string) (string, int, int, error) {
+	version, err := s.cluster.ServerVersion()
+	if err != nil {
+		return "", 0, 0, err
+	}
+	current, ok := parseVersion(version)
+	if !ok {
+		return "", 0, 0, fmt.Errorf("unrecognized Kubernetes version %q", version)
+	}
+	targetMinor := current[1] + 1
+	if target != "" {
+		parsed, ok := parseVersion(target)
+		if !ok || parsed[0] != 1 {
+			return "", 0, 0, fmt.Errorf("%w %q", ErrInvalidTarget, target)
+		}
+		targetMinor = parsed[1]
+	}
+	return version, current[1], targetMinor, nil
+}
+
+// Check reports the cluster's versions and the stored manifests and live objects using APIs
+// removed by the target Kubernetes version, such as "1.31". An empty target is the next minor
+// release.
+func (s *Service) Check(ctx context.Context, target string) (*Report, error) {
+	scan, err := s.ScanDeprecatedAPIs(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{
+		Kubernetes:     Component{Name: "kubernetes", Version: scan.Kubernetes},
+		Addons:         []Component{},
+		Target:         scan.Target,
+		DeprecatedAPIs: scan.APIs,
+		UpgradeBlocked: scan.UpgradeBlocked,
+		Warnings:       []string{},
+		Errors:         scan.Errors,
+		CheckedAt:      scan.CheckedAt,
+	}
+	images, err := s.cluster.WorkloadImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Addons = detectAddons(images)
+	s.lookupReleases(ctx, report)
+
+	current, _ := parseVersion(scan.Kubernetes)
+	if latest, ok := parseVersion(report.Kubernetes.Latest); ok && current[1] <= latest[1]-supportedMinors {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("Kubernetes 1.%d is out of upstream support; 1.%d is the latest release",
+			current[1], latest[1]))
+	}
+	report.Warnings = append(report.Warnings, scan.Warnings...)
+	return report, nil
+}
+
+// lookupReleases fills in the latest release of Kubernetes and every addon
+func (s *Service) lookupReleases(ctx context.Context, report *Report) {
+	if s.releases == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	components := []*Component{&report.Kubernetes}
+	repos := []addon{{repo: "kubernetes/kubernetes"}}
+	for i := range report.Addons {
+		for _, known := range addons {
+			if known.name == report.Addons[i].Name {
+				components = append(components, &report.Addons[i])
+				repos = append(repos, known)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, component := range components {
+		wg.Add(1)
+		go func(component *Component, known addon) {
+			defer wg.Done()
+			latest, err := s.releases.Latest(ctx, known.repo, known.tagPrefix)
+			if err != nil {
+				component.Error = err.Error()
+				return
+			}
+			component.Latest = latest
+			if _, ok := parseVersion(component.Version); ok {
+				component.UpdateAvailable = CompareVersions(latest, component.Version) > 0
+			}
+		}(component, repos[i])
+	}
+	wg.Wait()
+}
+
+// detectAddons recognizes addons by the images of the cluster's workloads, one per addon
+func detectAddons(images []k8s.WorkloadImage) []Component {
+	components := []Component{}
+	seen := make(map[string]bool)
+	for _, image := range images {
+		repository, tag := splitImage(image.Image)
+		for _, known := range addons {
+			if seen[known.name] || !matchesImage(repository, known.images) {
+				continue
+			}
+			seen[known.name] = true
+			components = append(components, Component{
+				Name:     known.name,
+				Version:  tag,
+				Workload: fmt.Sprintf("%s %s/%s", image.Kind, image.Namespace, image.Name),
+				Image:    image.Image,
+			})
+		}
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i].Name < components[j].Name })
+	return components
+}
+
+// splitImage returns an image's repository without its registry, and its tag
+func splitImage(image string) (string, string) {
+	image, _, _ = strings.Cut(image, "@")
+	var tag string
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image, tag = image[:i], image[i+1:]
+	}
+	if first, rest, ok := strings.Cut(image, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		image = rest
+	}
+	return strings.TrimPrefix(image, "library/"), tag
+}
+
+func matchesImage(repository string, images []string) bool {
+	for _, image := range images {
+		if repository == image {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package upgrades
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/archellir/denshimon/internal/k8s"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeCluster struct {
+	version string
+	images  []k8s.WorkloadImage
+	objects map[string][]unstructured.Unstructured // by kind; other kinds aren't served
+}
+
+func (c *fakeCluster) ServerVersion() (string, error) { return c.version, nil }
+
+func (c *fakeCluster) WorkloadImages(ctx context.Context) ([]k8s.WorkloadImage, error) {
+	return c.images, nil
+}
+
+func (c *fakeCluster) ListObjects(ctx context.Context, kind string) ([]unstructured.Unstructured, error) {
+	objects, ok := c.objects[kind]
+	if !ok {
+		return nil, &meta.NoResourceMatchError{PartialResource: schema.GroupVersionResource{Resource: kind}}
+	}
+	return objects, nil
+}
+
+// releasesServer serves GitHub releases, counting the requests
+func releasesServer(t *testing.T, requests *int) *httptest.Server {
+	releases := map[string][]githubRelease{
+		"kubernetes/kubernetes":     {{TagName: "v1.29.9"}, {TagName: "v1.31.1"}, {TagName: "v1.32.0-rc.1", Prerelease: true}, {TagName: "v1.30.5"}},
+		"kubernetes/ingress-nginx":  {{TagName: "helm-chart-4.11.2"}, {TagName: "controller-v1.11.2"}},
+		"cert-manager/cert-manager": {{TagName: "v1.15.3"}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		repo := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/repos/"), "/releases")
+		list, ok := releases[repo]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(list)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+const storedManifest = `apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: nightly
+  namespace: apps
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+---
+apiVersion: networking.k8s.io/v1beta1
+kind: Ingress
+metadata:
+  name: api
+---
+apiVersion: flowcontrol.apiserver.k8s.io/v1beta3
+kind: FlowSchema
+metadata:
+  name: batch
+`
+
+func TestCheck(t *testing.T) {
+	var requests int
+	server := releasesServer(t, &requests)
+	cluster := &fakeCluster{
+		version: "v1.24.17+k3s1",
+		images: []k8s.WorkloadImage{
+			{Kind: "Deployment", Namespace: "cert-manager", Name: "cert-manager", Image: "quay.io/jetstack/cert-manager-controller:v1.12.0"},
+			{Kind: "Deployment", Namespace: "ingress-nginx", Name: "controller", Image: "registry.k8s.io/ingress-nginx/controller:v1.11.2@sha256:abc"},
+			{Kind: "Deployment", Namespace: "apps", Name: "api", Image: "registry.lan/api:1.0.0"},
+		},
+	}
+	service := NewService(cluster, NewReleases(server.URL, ""))
+	service.SetManifestSource(func(ctx context.Context) (map[string][]byte, error) {
+		return map[string][]byte{"k8s/apps/nightly.yaml": []byte(storedManifest)}, nil
+	})
+
+	report, err := service.Check(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Kubernetes.Latest != "v1.31.1" || !report.Kubernetes.UpdateAvailable || report.Target != "1.25" {
+		t.Errorf("kubernetes = %+v, target %s", report.Kubernetes, report.Target)
+	}
+	if len(report.Addons) != 2 {
+		t.Fatalf("addons = %+v", report.Addons)
+	}
+	if certManager := report.Addons[0]; certManager.Name != "cert-manager" || certManager.Version != "v1.12.0" || !certManager.UpdateAvailable {
+		t.Errorf("cert-manager = %+v", certManager)
+	}
+	if ingress := report.Addons[1]; ingress.Latest != "v1.11.2" || ingress.UpdateAvailable || ingress.Workload != "Deployment ingress-nginx/controller" {
+		t.Errorf("ingress-nginx = %+v", ingress)
+	}
+
+	statuses := map[string]string{}
+	for _, api := range report.DeprecatedAPIs {
+		statuses[api.Kind] = api.Status
+	}
+	want := map[string]string{"CronJob": StatusBreaks, "Ingress": StatusRemoved, "FlowSchema": StatusDeprecated}
+	if len(statuses) != len(want) {
+		t.Errorf("deprecated APIs = %+v", report.DeprecatedAPIs)
+	}
+	for kind, status := range want {
+		if statuses[kind] != status {
+			t.Errorf("%s status = %q, want %q", kind, statuses[kind], status)
+		}
+	}
+	if !report.UpgradeBlocked {
+		t.Error("expected the CronJob to block the upgrade to 1.25")
+	}
+	// Out of support, an Ingress that can't be applied and a CronJob the upgrade breaks
+	if len(report.Warnings) != 3 {
+		t.Errorf("warnings = %v", report.Warnings)
+	}
+
+	// Releases are cached between checks
+	before := requests
+	if _, err := service.Check(context.Background(), "1.26"); err != nil {
+		t.Fatal(err)
+	}
+	if requests != before {
+		t.Errorf("%d release lookups repeated", requests-before)
+	}
+	if _, err := service.Check(context.Background(), "2.0"); err == nil {
+		t.Error("expected an invalid target refused")
+	}
+}
+
+func TestScanLiveObjects(t *testing.T) {
+	cronJob := unstructured.Unstructured{}
+	cronJob.SetAPIVersion("batch/v1")
+	cronJob.SetKind("CronJob")
+	cronJob.SetNamespace("apps")
+	cronJob.SetName("nightly")
+	cronJob.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{Manager: "ci-deploy", APIVersion: "batch/v1beta1"},
+		{Manager: "kube-controller-manager", APIVersion: "batch/v1"},
+		{Manager: "ci-deploy", APIVersion: "batch/v1beta1", Subresource: "status"},
+	})
+	cronJob.SetAnnotations(map[string]string{lastAppliedAnnotation: `{"apiVersion":"batch/v1beta1","kind":"CronJob"}`})
+	deployment := unstructured.Unstructured{}
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind("Deployment")
+	deployment.SetName("api")
+	deployment.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "kubectl", APIVersion: "apps/v1"}})
+
+	cluster := &fakeCluster{
+		version: "v1.24.3",
+		objects: map[string][]unstructured.Unstructured{
+			"cronjobs.batch":   {cronJob},
+			"deployments.apps": {deployment},
+		},
+	}
+	scan, err := NewService(cluster, nil).ScanDeprecatedAPIs(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scan.APIs) != 2 || scan.Counts[StatusBreaks] != 2 || !scan.UpgradeBlocked || scan.Errors != nil {
+		t.Fatalf("scan = %+v", scan)
+	}
+	if api := scan.APIs[0]; api.Source != SourceLive || api.Manager != "ci-deploy" || api.Namespace != "apps" || api.Replacement != "batch/v1" {
+		t.Errorf("api = %+v", api)
+	}
+	if scan.APIs[1].Manager != "kubectl apply" {
+		t.Errorf("expected the last kubectl apply reported, got %+v", scan.APIs[1])
+	}
+}
+
+func TestSplitImage(t *testing.T) {
+	for image, want := range map[string][2]string{
+		"traefik:v3.1":                          {"traefik", "v3.1"},
+		"docker.io/library/traefik:v3.1":        {"traefik", "v3.1"},
+		"localhost:5000/calico/node:v3.27.0":    {"calico/node", "v3.27.0"},
+		"rancher/mirrored-coredns-coredns:1.11": {"rancher/mirrored-coredns-coredns", "1.11"},
+		"quay.io/cilium/cilium@sha256:abc":      {"cilium/cilium", ""},
+	} {
+		if repository, tag := splitImage(image); repository != want[0] || tag != want[1] {
+			t.Errorf("splitImage(%q) = %q, %q", image, repository, tag)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"v1.31.1", "v1.30.5", 1},
+		{"1.11", "v1.11.0", 0},
+		{"v1.30.4+k3s1", "v1.30.4", 0},
+		{"v1.9.0", "v1.10.0", -1},
+		{"latest", "v1.0.0", -1},
+	} {
+		if got := CompareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL DEFAULT '', -- HMAC key, sealed when an encryption key is configured
+	events TEXT NOT NULL DEFAULT '[]', -- subscribed event types, JSON; empty for all
+	disabled BOOLEAN NOT NULL DEFAULT FALSE,
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL, -- unix milliseconds
+	updated_at INTEGER NOT NULL
+);
+
+-- One row per event sent to a webhook, kept as a delivery log and retried while pending
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id TEXT PRIMARY KEY,
+	webhook_id TEXT NOT NULL,
+	event TEXT NOT NULL,
+	payload TEXT NOT NULL, -- request body, kept so retries are signed identically
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	duration_ms INTEGER NOT NULL DEFAULT 0,
+	created_at INTEGER NOT NULL,
+	last_attempt_at INTEGER,
+	next_attempt_at INTEGER, -- set while pending
+	FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries(status, next_attempt_at);
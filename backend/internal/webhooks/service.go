@@ -0,0 +1,332 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/archellir/denshimon/internal/secretbox"
+	"github.com/google/uuid"
+)
+
+// Delivery loop intervals
+const (
+	pollInterval  = 5 * time.Second // how often due retries are looked for
+	pruneInterval = time.Hour
+)
+
+// Service manages webhooks and delivers events to them. Emit queues deliveries in the
+// database, so retries survive restarts; Start runs the loop that sends them.
+type Service struct {
+	store  *Store
+	box    *secretbox.Box // seals secrets at rest
+	client *http.Client
+	wake   chan struct{}
+	sendMu sync.Mutex // one delivery run at a time, so no delivery is sent twice
+
+	now func() time.Time
+}
+
+// NewService creates a webhook service backed by the given database
+func NewService(db *sql.DB) (*Service, error) {
+	store, err := NewStore(db)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{
+		store:  store,
+		client: &http.Client{Timeout: RequestTimeout},
+		wake:   make(chan struct{}, 1),
+		now:    time.Now,
+	}, nil
+}
+
+// SetEncryption sets the box webhook secrets are sealed with at rest
+func (s *Service) SetEncryption(box *secretbox.Box) {
+	s.box = box
+}
+
+// Start delivers queued events until ctx is cancelled, retrying failed deliveries when
+// due and pruning deliveries older than DeliveryRetention
+func (s *Service) Start(ctx context.Context) {
+	go func() {
+		poll := time.NewTicker(pollInterval)
+		defer poll.Stop()
+		prune := time.NewTicker(pruneInterval)
+		defer prune.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-prune.C:
+				if err := s.store.Prune(ctx, s.now().Add(-DeliveryRetention)); err != nil {
+					slog.Error("Failed to prune webhook deliveries", "error", err)
+				}
+			case <-poll.C:
+				s.deliverDue(ctx)
+			case <-s.wake:
+				s.deliverDue(ctx)
+			}
+		}
+	}()
+}
+
+// Emit queues an event for every webhook subscribed to its type. It doesn't wait for the
+// deliveries; failures to queue are logged.
+func (s *Service) Emit(eventType string, data interface{}) {
+	ctx := context.Background()
+	webhooks, err := s.store.List(ctx)
+	if err != nil {
+		slog.Warn("Failed to list webhooks", "event", eventType, "error", err)
+		return
+	}
+	var payload []byte
+	queued := false
+	for i := range webhooks {
+		if !webhooks[i].Subscribed(eventType) {
+			continue
+		}
+		if payload == nil {
+			event := Event{ID: uuid.New().String(), Type: eventType, Timestamp: s.now().UTC(), Data: data}
+			if payload, err = json.Marshal(event); err != nil {
+				slog.Warn("Failed to encode webhook event", "event", eventType, "error", err)
+				return
+			}
+		}
+		if err := s.store.SaveDelivery(ctx, s.newDelivery(webhooks[i].ID, eventType, payload)); err != nil {
+			slog.Warn("Failed to queue webhook delivery", "webhook", webhooks[i].Name, "event", eventType, "error", err)
+			continue
+		}
+		queued = true
+	}
+	if queued {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// newDelivery creates a pending delivery due now
+func (s *Service) newDelivery(webhookID, eventType string, payload []byte) *Delivery {
+	now := s.now()
+	return &Delivery{
+		ID:            uuid.New().String(),
+		WebhookID:     webhookID,
+		Event:         eventType,
+		Payload:       payload,
+		Status:        StatusPending,
+		CreatedAt:     now,
+		NextAttemptAt: &now,
+	}
+}
+
+// deliverDue sends the deliveries that are due, one after another
+func (s *Service) deliverDue(ctx context.Context) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	due, err := s.store.Due(ctx, s.now())
+	if err != nil {
+		slog.Error("Failed to list due webhook deliveries", "error", err)
+		return
+	}
+	for i := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		webhook, err := s.store.Get(ctx, due[i].WebhookID)
+		if err != nil {
+			slog.Warn("Skipping delivery of unknown webhook", "delivery", due[i].ID, "error", err)
+			continue
+		}
+		s.attempt(ctx, webhook, &due[i], true)
+		if err := s.store.SaveDelivery(ctx, &due[i]); err != nil {
+			slog.Error("Failed to record webhook delivery", "delivery", due[i].ID, "error", err)
+		}
+	}
+}
+
+// attempt posts a delivery once and records the outcome on it. A failed delivery is
+// scheduled for a retry after backoff until MaxAttempts, when retry is set.
+func (s *Service) attempt(ctx context.Context, webhook *Webhook, delivery *Delivery, retry bool) {
+	start := s.now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &start
+	delivery.StatusCode = 0
+	delivery.Error = ""
+
+	err := s.post(ctx, webhook, delivery)
+	delivery.DurationMs = s.now().Sub(start).Milliseconds()
+	switch {
+	case err == nil:
+		delivery.Status = StatusDelivered
+		delivery.NextAttemptAt = nil
+	case retry && delivery.Attempts < MaxAttempts:
+		delivery.Error = err.Error()
+		next := start.Add(backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	default:
+		delivery.Error = err.Error()
+		delivery.Status = StatusFailed
+		delivery.NextAttemptAt = nil
+	}
+	if err != nil {
+		slog.Warn("Webhook delivery failed", "webhook", webhook.Name, "event", delivery.Event,
+			"attempt", delivery.Attempts, "error", err)
+	}
+}
+
+// post sends the delivery's payload, signed when the webhook has a secret. Any 2xx
+// response counts as delivered.
+func (s *Service) post(ctx context.Context, webhook *Webhook, delivery *Delivery) error {
+	secret, err := s.box.Decrypt(webhook.Secret)
+	if err != nil {
+		return fmt.Errorf("failed to open the webhook secret: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Denshimon-Webhook")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	if secret != "" {
+		req.Header.Set(HeaderSignature, Sign(secret, delivery.Payload))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return nil
+}
+
+// List returns every webhook, without secrets
+func (s *Service) List(ctx context.Context) ([]Webhook, error) {
+	webhooks, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		redact(&webhooks[i])
+	}
+	return webhooks, nil
+}
+
+// Get returns one webhook, without its secret
+func (s *Service) Get(ctx context.Context, id string) (*Webhook, error) {
+	webhook, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	redact(webhook)
+	return webhook, nil
+}
+
+// Create validates and stores a new webhook
+func (s *Service) Create(ctx context.Context, webhook Webhook, createdBy string) (*Webhook, error) {
+	if err := webhook.Validate(); err != nil {
+		return nil, err
+	}
+	now := s.now()
+	webhook.ID = uuid.New().String()
+	webhook.CreatedBy = createdBy
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+	if err := s.save(ctx, &webhook); err != nil {
+		return nil, err
+	}
+	redact(&webhook)
+	return &webhook, nil
+}
+
+// Update replaces a webhook's settings. An empty secret keeps the current one.
+func (s *Service) Update(ctx context.Context, id string, webhook Webhook) (*Webhook, error) {
+	existing, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := webhook.Validate(); err != nil {
+		return nil, err
+	}
+	webhook.ID = existing.ID
+	webhook.CreatedBy = existing.CreatedBy
+	webhook.CreatedAt = existing.CreatedAt
+	webhook.UpdatedAt = s.now()
+	if webhook.Secret == "" {
+		webhook.Secret = existing.Secret // already sealed
+	}
+	if err := s.save(ctx, &webhook); err != nil {
+		return nil, err
+	}
+	redact(&webhook)
+	return &webhook, nil
+}
+
+// save seals the webhook's secret and stores it
+func (s *Service) save(ctx context.Context, webhook *Webhook) error {
+	sealed, err := s.box.Encrypt(webhook.Secret)
+	if err != nil {
+		return err
+	}
+	webhook.Secret = sealed
+	return s.store.Save(ctx, webhook)
+}
+
+// Delete removes a webhook and its delivery log
+func (s *Service) Delete(ctx context.Context, id string) error {
+	return s.store.Delete(ctx, id)
+}
+
+// Test sends a webhook.test event to a webhook right away, whether or not it is disabled,
+// and returns the logged delivery. Test deliveries aren't retried.
+func (s *Service) Test(ctx context.Context, id, user string) (*Delivery, error) {
+	webhook, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(Event{
+		ID:        uuid.New().String(),
+		Type:      EventTest,
+		Timestamp: s.now().UTC(),
+		Data:      map[string]string{"webhook_id": webhook.ID, "name": webhook.Name, "user": user},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Stored only once sent, so the delivery loop doesn't pick it up meanwhile
+	delivery := s.newDelivery(webhook.ID, EventTest, payload)
+	s.attempt(ctx, webhook, delivery, false)
+	if err := s.store.SaveDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Deliveries returns a webhook's delivery log, newest first
+func (s *Service) Deliveries(ctx context.Context, id string, limit int) ([]Delivery, error) {
+	if _, err := s.store.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.store.Deliveries(ctx, id, limit)
+}
+
+// redact replaces the secret of a webhook leaving the service with whether it has one
+func redact(webhook *Webhook) {
+	webhook.HasSecret = webhook.Secret != ""
+	webhook.Secret = ""
+}
@@ -0,0 +1,213 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/archellir/denshimon/internal/database/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations is the schema of the webhook tables
+var Migrations = migrate.NewSource("webhooks", migrationFiles, "migrations")
+
+// Store persists webhooks and their deliveries in SQLite. Secrets are stored as given;
+// the service seals them.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a webhook store, migrating its tables to the latest schema version
+func NewStore(db *sql.DB) (*Store, error) {
+	if err := migrate.Up(context.Background(), db, Migrations); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+const webhookColumns = `id, name, url, secret, events, disabled, created_by, created_at, updated_at`
+
+func scanWebhook(scanner interface{ Scan(...interface{}) error }) (*Webhook, error) {
+	var w Webhook
+	var events string
+	var createdAt, updatedAt int64
+	if err := scanner.Scan(&w.ID, &w.Name, &w.URL, &w.Secret, &events, &w.Disabled, &w.CreatedBy,
+		&createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(events), &w.Events)
+	if w.Events == nil {
+		w.Events = []string{}
+	}
+	w.CreatedAt = time.UnixMilli(createdAt).UTC()
+	w.UpdatedAt = time.UnixMilli(updatedAt).UTC()
+	return &w, nil
+}
+
+// List returns all webhooks ordered by name
+func (s *Store) List(ctx context.Context) ([]Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// Get returns one webhook
+func (s *Store) Get(ctx context.Context, id string) (*Webhook, error) {
+	webhook, err := scanWebhook(s.db.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// Save inserts or updates a webhook
+func (s *Store) Save(ctx context.Context, w *Webhook) error {
+	events := w.Events
+	if events == nil {
+		events = []string{}
+	}
+	data, _ := json.Marshal(events)
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhooks (`+webhookColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name, url = excluded.url, secret = excluded.secret, events = excluded.events,
+			disabled = excluded.disabled, updated_at = excluded.updated_at`,
+		w.ID, w.Name, w.URL, w.Secret, string(data), w.Disabled, w.CreatedBy,
+		w.CreatedAt.UnixMilli(), w.UpdatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to save webhook: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a webhook and its deliveries
+func (s *Store) Delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	// Deliveries are removed explicitly as SQLite only cascades with foreign keys enabled
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+const deliveryColumns = `id, webhook_id, event, payload, status, attempts, status_code, error, duration_ms,
+	created_at, last_attempt_at, next_attempt_at`
+
+func scanDelivery(scanner interface{ Scan(...interface{}) error }) (*Delivery, error) {
+	var d Delivery
+	var payload string
+	var createdAt int64
+	var lastAttempt, nextAttempt sql.NullInt64
+	if err := scanner.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &d.StatusCode,
+		&d.Error, &d.DurationMs, &createdAt, &lastAttempt, &nextAttempt); err != nil {
+		return nil, err
+	}
+	d.Payload = json.RawMessage(payload)
+	d.CreatedAt = time.UnixMilli(createdAt).UTC()
+	if lastAttempt.Valid {
+		at := time.UnixMilli(lastAttempt.Int64).UTC()
+		d.LastAttemptAt = &at
+	}
+	if nextAttempt.Valid {
+		at := time.UnixMilli(nextAttempt.Int64).UTC()
+		d.NextAttemptAt = &at
+	}
+	return &d, nil
+}
+
+func unixMilli(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UnixMilli()
+}
+
+// SaveDelivery inserts or updates a delivery
+func (s *Store) SaveDelivery(ctx context.Context, d *Delivery) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (`+deliveryColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status = excluded.status, attempts = excluded.attempts, status_code = excluded.status_code,
+			error = excluded.error, duration_ms = excluded.duration_ms,
+			last_attempt_at = excluded.last_attempt_at, next_attempt_at = excluded.next_attempt_at`,
+		d.ID, d.WebhookID, d.Event, string(d.Payload), d.Status, d.Attempts, d.StatusCode, d.Error, d.DurationMs,
+		d.CreatedAt.UnixMilli(), unixMilli(d.LastAttemptAt), unixMilli(d.NextAttemptAt))
+	if err != nil {
+		return fmt.Errorf("failed to save webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// Deliveries returns a webhook's deliveries, newest first
+func (s *Store) Deliveries(ctx context.Context, webhookID string, limit int) ([]Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE webhook_id = ? ORDER BY created_at DESC LIMIT ?`, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return collectDeliveries(rows)
+}
+
+// Due returns the pending deliveries whose next attempt is due, oldest first
+func (s *Store) Due(ctx context.Context, now time.Time) ([]Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at`, StatusPending, now.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due webhook deliveries: %w", err)
+	}
+	return collectDeliveries(rows)
+}
+
+func collectDeliveries(rows *sql.Rows) ([]Delivery, error) {
+	defer rows.Close()
+	deliveries := []Delivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// Prune deletes finished deliveries created before the given time
+func (s *Store) Prune(ctx context.Context, before time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE status != ? AND created_at < ?`,
+		StatusPending, before.UnixMilli()); err != nil {
+		return fmt.Errorf("failed to prune webhook deliveries: %w", err)
+	}
+	return nil
+}
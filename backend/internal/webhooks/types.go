@@ -0,0 +1,140 @@
+// Package webhooks sends Denshimon events as JSON to user-configured HTTP endpoints. Requests
+// are signed with HMAC-SHA256, failed deliveries are retried with exponential backoff and
+// every delivery is logged.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned for unknown webhooks
+var ErrNotFound = errors.New("webhook not found")
+
+// ErrInvalid is returned for webhooks with missing or unknown fields
+var ErrInvalid = errors.New("invalid webhook")
+
+// Event types
+const (
+	EventDeploymentApplied    = "deployment.applied"
+	EventDeploymentRolledBack = "deployment.rolled_back"
+	EventAlertFired           = "alert.fired"
+	EventAlertResolved        = "alert.resolved"
+	EventBackupFinished       = "backup.finished"
+	EventCertificateExpiring  = "certificate.expiring"
+	EventTest                 = "webhook.test" // sent on request to check an endpoint
+)
+
+// Events lists the event types webhooks can subscribe to
+var Events = []string{
+	EventDeploymentApplied, EventDeploymentRolledBack, EventAlertFired, EventAlertResolved,
+	EventBackupFinished, EventCertificateExpiring,
+}
+
+// Delivery statuses
+const (
+	StatusPending   = "pending" // waiting for its first attempt or a retry
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed" // every attempt failed
+)
+
+// Request headers sent with every delivery
+const (
+	HeaderEvent     = "X-Denshimon-Event"
+	HeaderDelivery  = "X-Denshimon-Delivery"
+	HeaderSignature = "X-Denshimon-Signature" // sha256=<hex HMAC of the body>, with a secret
+)
+
+// Delivery limits and defaults
+const (
+	MaxAttempts       = 5
+	RetryBackoff      = 30 * time.Second // doubled after every failed attempt
+	RequestTimeout    = 10 * time.Second
+	DeliveryRetention = 30 * 24 * time.Hour
+)
+
+// Webhook is an endpoint events are posted to
+type Webhook struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Secret signs requests; it is never returned, HasSecret tells whether one is set
+	Secret    string `json:"secret,omitempty"`
+	HasSecret bool   `json:"has_secret"`
+	// Events are the subscribed event types; empty subscribes to all of them
+	Events []string `json:"events"`
+	// Disabled webhooks keep their deliveries but receive no new events
+	Disabled  bool      `json:"disabled"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Event is the body of a delivery
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Delivery is one event sent, or being sent, to a webhook
+type Delivery struct {
+	ID         string          `json:"id"`
+	WebhookID  string          `json:"webhook_id"`
+	Event      string          `json:"event"`
+	Payload    json.RawMessage `json:"payload"`
+	Status     string          `json:"status"`
+	Attempts   int             `json:"attempts"`
+	StatusCode int             `json:"status_code,omitempty"` // of the last attempt
+	Error      string          `json:"error,omitempty"`       // of the last attempt
+	DurationMs int64           `json:"duration_ms"`           // of the last attempt
+	CreatedAt  time.Time       `json:"created_at"`
+	// LastAttemptAt is unset before the first attempt, NextAttemptAt once no retry is left
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+}
+
+// Validate trims and checks a webhook's settings
+func (w *Webhook) Validate() error {
+	w.Name = strings.TrimSpace(w.Name)
+	if w.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an http or https URL", ErrInvalid)
+	}
+	for _, event := range w.Events {
+		if !slices.Contains(Events, event) {
+			return fmt.Errorf("%w: unknown event %q, expected one of %s", ErrInvalid, event, strings.Join(Events, ", "))
+		}
+	}
+	return nil
+}
+
+// Subscribed reports whether the webhook receives an event type
+func (w *Webhook) Subscribed(event string) bool {
+	return !w.Disabled && (len(w.Events) == 0 || slices.Contains(w.Events, event))
+}
+
+// Sign returns the signature header value of a body: sha256= and the hex HMAC-SHA256 of
+// the body keyed with the secret. Receivers compute it the same way to verify deliveries.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff is the wait after the given number of failed attempts: RetryBackoff, doubling
+func backoff(attempts int) time.Duration {
+	return RetryBackoff << (attempts - 1)
+}
@@ -0,0 +1,213 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/archellir/denshimon/internal/secretbox"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	service, err := NewService(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+// receiver records the requests it gets, answering with the queued statuses and then 200
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func TestWebhookValidation(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+	for _, webhook := range []Webhook{
+		{Name: " ", URL: "https://hooks.example.com"},
+		{Name: "chat", URL: "ftp://hooks.example.com"},
+		{Name: "chat", URL: "https://hooks.example.com", Events: []string{"deployment.deleted"}},
+	} {
+		if _, err := service.Create(ctx, webhook, "admin"); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected %+v to be invalid, got %v", webhook, err)
+		}
+	}
+
+	box, err := secretbox.New("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.SetEncryption(box)
+	created, err := service.Create(ctx, Webhook{Name: "chat", URL: "https://hooks.example.com", Secret: "s3cret"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Secret != "" || !created.HasSecret {
+		t.Errorf("created = %+v, want the secret redacted", created)
+	}
+	stored, err := service.store.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !secretbox.IsEncrypted(stored.Secret) {
+		t.Error("expected the secret to be sealed at rest")
+	}
+
+	// Updating without a secret keeps it
+	updated, err := service.Update(ctx, created.ID, Webhook{Name: "chat", URL: "https://hooks.example.com/v2", Disabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.HasSecret || updated.URL != "https://hooks.example.com/v2" || !updated.Disabled {
+		t.Errorf("updated = %+v", updated)
+	}
+	if _, err := service.Update(ctx, "missing", *updated); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestEmitDeliversWithRetries(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+	now := time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	recv := &receiver{statuses: []int{http.StatusBadGateway}}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	deploys, err := service.Create(ctx, Webhook{Name: "deploys", URL: server.URL, Secret: "s3cret", Events: []string{EventDeploymentApplied}}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Create(ctx, Webhook{Name: "alerts", URL: server.URL, Events: []string{EventAlertFired}}, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Create(ctx, Webhook{Name: "off", URL: server.URL, Disabled: true}, "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	service.Emit(EventDeploymentApplied, map[string]string{"name": "api"})
+	service.deliverDue(ctx)
+
+	// The first attempt fails and is retried after the backoff
+	deliveries, err := service.Deliveries(ctx, deploys.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("deliveries = %+v", deliveries)
+	}
+	delivery := deliveries[0]
+	if delivery.Status != StatusPending || delivery.Attempts != 1 || delivery.StatusCode != http.StatusBadGateway || delivery.Error == "" {
+		t.Errorf("delivery = %+v", delivery)
+	}
+	if delivery.NextAttemptAt == nil || !delivery.NextAttemptAt.Equal(now.Add(RetryBackoff)) {
+		t.Errorf("next attempt = %v, want %v", delivery.NextAttemptAt, now.Add(RetryBackoff))
+	}
+
+	service.deliverDue(ctx) // not due yet
+	now = now.Add(RetryBackoff)
+	service.deliverDue(ctx)
+
+	deliveries, _ = service.Deliveries(ctx, deploys.ID, 10)
+	if delivery := deliveries[0]; delivery.Status != StatusDelivered || delivery.Attempts != 2 || delivery.NextAttemptAt != nil {
+		t.Errorf("delivery = %+v", delivery)
+	}
+
+	// Only the subscribed webhook got the event, signed the same way both times
+	if len(recv.requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(recv.requests))
+	}
+	req, body := recv.requests[1], recv.bodies[1]
+	if req.Header.Get(HeaderEvent) != EventDeploymentApplied || req.Header.Get(HeaderDelivery) != delivery.ID {
+		t.Errorf("headers = %v", req.Header)
+	}
+	if got := req.Header.Get(HeaderSignature); got != Sign("s3cret", body) || got != recv.requests[0].Header.Get(HeaderSignature) {
+		t.Errorf("signature = %s", got)
+	}
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != EventDeploymentApplied || event.ID == "" || event.Data.(map[string]interface{})["name"] != "api" {
+		t.Errorf("event = %+v", event)
+	}
+}
+
+func TestDeliveryGivesUp(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+	now := time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	webhook, err := service.Create(ctx, Webhook{Name: "broken", URL: server.URL}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service.Emit(EventBackupFinished, map[string]string{"status": "completed"})
+	for attempt := 1; attempt <= MaxAttempts; attempt++ {
+		service.deliverDue(ctx)
+		now = now.Add(backoff(attempt))
+	}
+	deliveries, _ := service.Deliveries(ctx, webhook.ID, 10)
+	if len(deliveries) != 1 || deliveries[0].Status != StatusFailed || deliveries[0].Attempts != MaxAttempts || deliveries[0].NextAttemptAt != nil {
+		t.Errorf("deliveries = %+v", deliveries)
+	}
+
+	// A test is sent once, and logged
+	delivery, err := service.Test(ctx, webhook.ID, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Event != EventTest || delivery.Status != StatusFailed || delivery.Attempts != 1 {
+		t.Errorf("test delivery = %+v", delivery)
+	}
+	if deliveries, _ := service.Deliveries(ctx, webhook.ID, 10); len(deliveries) != 2 {
+		t.Errorf("got %d deliveries, want 2", len(deliveries))
+	}
+
+	if err := service.Delete(ctx, webhook.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Deliveries(ctx, webhook.ID, 10); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
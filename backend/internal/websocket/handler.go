@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"compress/flate"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -11,6 +12,8 @@ import (
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	// permessage-deflate, when the client offers it
+	EnableCompression: true,
 	CheckOrigin: func(r *http.Request) bool {
 		// In production, you should validate the origin properly
 		// For development, we allow all origins
@@ -48,8 +51,12 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// JSON snapshots compress well; favour speed over ratio
+	conn.SetCompressionLevel(flate.BestSpeed)
+
 	// Create new client
 	client := NewClient(conn, h.hub, userID)
+	client.options = parseClientOptions(r.URL.Query())
 
 	// Register client with hub
 	h.hub.register <- client
@@ -22,6 +22,8 @@ const (
 	MessageTypePods           MessageType = "pods"
 	MessageTypeServices       MessageType = "services"
 	MessageTypeDeployments    MessageType = "deployments"
+	MessageTypeStatefulSets   MessageType = "statefulsets"
+	MessageTypeDaemonSets     MessageType = "daemonsets"
 	MessageTypeAlerts         MessageType = "alerts"
 	MessageTypeGiteaWebhook   MessageType = "gitea_webhook"
 	MessageTypeGithubWebhook  MessageType = "github_webhook"
@@ -34,6 +36,12 @@ const (
 	MessageTypeDatabaseStats  MessageType = "database_stats"
 	MessageTypeServiceHealth  MessageType = "service_health"
 	MessageTypeServiceHealthStats MessageType = "service_health_stats"
+	MessageTypeNodeOperations MessageType = "node_operations"
+	MessageTypeMonitors       MessageType = "monitors"
+	MessageTypeHost           MessageType = "host"
+	MessageTypeBatch          MessageType = "batch"
+	MessageTypeSession        MessageType = "session"
+	MessageTypeResync         MessageType = "resync"
 )
 
 // Message represents a WebSocket message
@@ -41,6 +49,14 @@ type Message struct {
 	Type      MessageType `json:"type"`
 	Timestamp string      `json:"timestamp"`
 	Data      interface{} `json:"data"`
+	// Seq orders broadcasts; clients resume from the last one they received
+	Seq uint64 `json:"seq,omitempty"`
+	// Collection names the list field of collection messages, e.g. "pods"
+	Collection string `json:"collection,omitempty"`
+	// Delta marks a collection message whose data is a CollectionDelta
+	Delta bool `json:"delta,omitempty"`
+
+	items []collectionItem // collection objects, tailored to each client before sending
 }
 
 // Client represents a WebSocket client
@@ -52,6 +68,10 @@ type Client struct {
 	UserID        string // For authentication tracking
 	Subscriptions map[MessageType]bool
 	mu            sync.RWMutex
+
+	options    ClientOptions
+	namespaces map[MessageType]map[string]bool     // namespace filters; none means all namespaces
+	sent       map[MessageType]map[string][32]byte // collection hashes last sent, for deltas
 }
 
 // Hub maintains the set of active clients and broadcasts messages to the clients
@@ -60,21 +80,34 @@ type Hub struct {
 	broadcast  chan Message
 	register   chan *Client
 	unregister chan *Client
+	resumes    chan resumeRequest
 	mu         sync.RWMutex
 	ctx        context.Context
 	cancel     context.CancelFunc
+
+	replayMu    sync.Mutex
+	epoch       string
+	seq         uint64
+	replay      map[MessageType][]Message // recent broadcasts by channel
+	evicted     map[MessageType]uint64    // seq of the newest message dropped from each buffer
+	collections map[MessageType]Message   // latest collection message by channel
 }
 
 // NewHub creates a new WebSocket hub
 func NewHub() *Hub {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Hub{
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan Message, 256),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		ctx:        ctx,
-		cancel:     cancel,
+		clients:     make(map[*Client]bool),
+		broadcast:   make(chan Message, 256),
+		register:    make(chan *Client),
+		unregister:  make(chan *Client),
+		resumes:     make(chan resumeRequest),
+		ctx:         ctx,
+		cancel:      cancel,
+		epoch:       newEpoch(),
+		replay:      make(map[MessageType][]Message),
+		evicted:     make(map[MessageType]uint64),
+		collections: make(map[MessageType]Message),
 	}
 }
 
@@ -96,6 +129,7 @@ func (h *Hub) Run() {
 			h.mu.Lock()
 			h.clients[client] = true
 			h.mu.Unlock()
+			client.SendMessage(MessageTypeSession, h.session(client))
 			slog.Info("Client connected", "client_id", client.ID, "user_id", client.UserID, "total_clients", len(h.clients))
 
 		case client := <-h.unregister:
@@ -108,8 +142,17 @@ func (h *Hub) Run() {
 			slog.Info("Client disconnected", "client_id", client.ID, "user_id", client.UserID, "total_clients", len(h.clients))
 
 		case message := <-h.broadcast:
+			h.record(&message)
 			h.broadcastMessage(message)
 
+		case req := <-h.resumes:
+			h.mu.RLock()
+			connected := h.clients[req.client]
+			h.mu.RUnlock()
+			if connected {
+				h.resume(req)
+			}
+
 		case <-ticker.C:
 			// Send ping to all clients
 			h.sendPing()
@@ -123,13 +166,9 @@ func (h *Hub) broadcastMessage(message Message) {
 	defer h.mu.RUnlock()
 
 	for client := range h.clients {
-		client.mu.RLock()
-		isSubscribed := client.Subscriptions[message.Type]
-		client.mu.RUnlock()
-
-		if isSubscribed {
+		if tailored, ok := client.prepare(message); ok {
 			select {
-			case client.Send <- message:
+			case client.Send <- tailored:
 			default:
 				// Client channel is full, close it
 				close(client.Send)
@@ -171,10 +210,14 @@ func (h *Hub) Broadcast(messageType MessageType, data interface{}) {
 	select {
 	case h.broadcast <- message:
 	default:
-		slog.Warn("Broadcast channel full, dropping message", "type", messageType)
+		h.logDropped(messageType)
 	}
 }
 
+func (h *Hub) logDropped(messageType MessageType) {
+	slog.Warn("Broadcast channel full, dropping message", "type", messageType)
+}
+
 // BroadcastToUser sends a message to specific user's clients
 func (h *Hub) BroadcastToUser(userID string, messageType MessageType, data interface{}) {
 	message := Message{
@@ -188,13 +231,9 @@ func (h *Hub) BroadcastToUser(userID string, messageType MessageType, data inter
 
 	for client := range h.clients {
 		if client.UserID == userID {
-			client.mu.RLock()
-			isSubscribed := client.Subscriptions[messageType]
-			client.mu.RUnlock()
-
-			if isSubscribed {
+			if tailored, ok := client.prepare(message); ok {
 				select {
-				case client.Send <- message:
+				case client.Send <- tailored:
 				default:
 					close(client.Send)
 					delete(h.clients, client)
@@ -250,15 +289,26 @@ func NewClient(conn *websocket.Conn, hub *Hub, userID string) *Client {
 		Send:          make(chan Message, 256),
 		UserID:        userID,
 		Subscriptions: make(map[MessageType]bool),
+		namespaces:    make(map[MessageType]map[string]bool),
+		sent:          make(map[MessageType]map[string][32]byte),
 	}
 }
 
-// Subscribe adds a subscription for a message type
-func (c *Client) Subscribe(messageType MessageType) {
+// Subscribe adds a subscription for a message type, limited to the given namespaces if any.
+// Subscribing again replaces the filter and starts collections over with a full snapshot.
+func (c *Client) Subscribe(messageType MessageType, namespaces ...string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.Subscriptions[messageType] = true
-	slog.Debug("Client subscribed", "client_id", c.ID, "type", messageType)
+	delete(c.namespaces, messageType)
+	if len(namespaces) > 0 {
+		c.namespaces[messageType] = make(map[string]bool, len(namespaces))
+		for _, namespace := range namespaces {
+			c.namespaces[messageType][namespace] = true
+		}
+	}
+	delete(c.sent, messageType)
+	slog.Debug("Client subscribed", "client_id", c.ID, "type", messageType, "namespaces", namespaces)
 }
 
 // Unsubscribe removes a subscription for a message type
@@ -266,6 +316,8 @@ func (c *Client) Unsubscribe(messageType MessageType) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.Subscriptions, messageType)
+	delete(c.namespaces, messageType)
+	delete(c.sent, messageType)
 	slog.Debug("Client unsubscribed", "client_id", c.ID, "type", messageType)
 }
 
@@ -336,6 +388,22 @@ func (c *Client) WritePump() {
 				return
 			}
 
+			if c.options.Batch {
+				batch, open := c.nextBatch(message)
+				if len(batch) > 1 {
+					message = Message{
+						Type:      MessageTypeBatch,
+						Timestamp: time.Now().UTC().Format(time.RFC3339),
+						Data:      map[string]interface{}{"messages": batch},
+					}
+				}
+				if !open {
+					c.Conn.WriteJSON(message)
+					c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+					return
+				}
+			}
+
 			if err := c.Conn.WriteJSON(message); err != nil {
 				slog.Error("Error writing message", "client_id", c.ID, "error", err)
 				return
@@ -359,13 +427,23 @@ func (c *Client) handleClientMessage(msg map[string]interface{}) {
 
 	switch msgType {
 	case "subscribe":
-		if messageType, ok := msg["message_type"].(string); ok {
-			c.Subscribe(MessageType(messageType))
+		namespaces := stringList(msg["namespaces"])
+		types := messageTypes(msg)
+		for _, messageType := range types {
+			c.Subscribe(messageType, namespaces...)
+		}
+		// A reconnecting client resumes from its cursor instead of waiting for the next update
+		if since, ok := parseCursor(msg["since"]); ok && len(types) > 0 {
+			epoch, _ := msg["epoch"].(string)
+			select {
+			case c.Hub.resumes <- resumeRequest{client: c, types: types, epoch: epoch, since: since}:
+			case <-c.Hub.ctx.Done():
+			}
 		}
 
 	case "unsubscribe":
-		if messageType, ok := msg["message_type"].(string); ok {
-			c.Unsubscribe(MessageType(messageType))
+		for _, messageType := range messageTypes(msg) {
+			c.Unsubscribe(messageType)
 		}
 
 	case "ping":
@@ -379,6 +457,30 @@ func (c *Client) handleClientMessage(msg map[string]interface{}) {
 	}
 }
 
+// messageTypes reads the channels of a subscribe or unsubscribe message, given as
+// message_type or as a message_types list
+func messageTypes(msg map[string]interface{}) []MessageType {
+	var types []MessageType
+	if messageType, ok := msg["message_type"].(string); ok {
+		types = append(types, MessageType(messageType))
+	}
+	for _, messageType := range stringList(msg["message_types"]) {
+		types = append(types, MessageType(messageType))
+	}
+	return types
+}
+
+func stringList(value interface{}) []string {
+	list, _ := value.([]interface{})
+	var values []string
+	for _, item := range list {
+		if s, ok := item.(string); ok && s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
 // generateClientID generates a unique client ID
 func generateClientID() string {
 	return time.Now().Format("20060102150405") + "-" + randomString(8)
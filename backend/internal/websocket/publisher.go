@@ -4,9 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"strings"
 	"time"
 
+	"github.com/archellir/denshimon/internal/discovery"
 	"github.com/archellir/denshimon/internal/k8s"
 	"github.com/archellir/denshimon/internal/metrics"
 	appsv1 "k8s.io/api/apps/v1"
@@ -15,11 +15,12 @@ import (
 
 // Publisher publishes real-time data to WebSocket clients
 type Publisher struct {
-	hub            *Hub
-	k8sClient      *k8s.Client
-	metricsService *metrics.Service
-	ctx            context.Context
-	cancel         context.CancelFunc
+	hub              *Hub
+	k8sClient        *k8s.Client
+	metricsService   *metrics.Service
+	serviceDiscovery *discovery.Service
+	ctx              context.Context
+	cancel           context.CancelFunc
 }
 
 // NewPublisher creates a new data publisher
@@ -34,6 +35,12 @@ func NewPublisher(hub *Hub, k8sClient *k8s.Client, metricsService *metrics.Servi
 	}
 }
 
+// SetServiceDiscovery sets the service discovery used for service health, so its
+// overrides apply; without one only annotations are read
+func (p *Publisher) SetServiceDiscovery(serviceDiscovery *discovery.Service) {
+	p.serviceDiscovery = serviceDiscovery
+}
+
 // Start begins publishing real-time data
 func (p *Publisher) Start() {
 	slog.Info("Starting WebSocket data publisher")
@@ -48,6 +55,7 @@ func (p *Publisher) Start() {
 		go p.publishDatabaseMetrics()
 		go p.publishServiceHealthMetrics()
 		go p.publishDeploymentMetrics()
+		go p.publishWorkloads()
 	} else {
 		slog.Warn("WebSocket publisher started without Kubernetes client - no data will be published")
 	}
@@ -135,10 +143,7 @@ func (p *Publisher) publishEvents() {
 				})
 			}
 
-			p.hub.Broadcast(MessageTypeEvents, map[string]interface{}{
-				"events":    eventData,
-				"timestamp": time.Now().UTC().Format(time.RFC3339),
-			})
+			p.hub.BroadcastCollection(MessageTypeEvents, "events", eventData)
 		}
 	}
 }
@@ -160,24 +165,21 @@ func (p *Publisher) publishPods() {
 				continue
 			}
 
-			// Convert pods to a format suitable for WebSocket (real data only)
+			// Convert pods to a format suitable for WebSocket (real data only). Fields that
+			// change on every tick would defeat deltas; the message carries the time instead.
 			var podData []map[string]interface{}
 			for _, pod := range pods.Items {
 				podData = append(podData, map[string]interface{}{
-					"name":       pod.Name,
-					"namespace":  pod.Namespace,
-					"status":     string(pod.Status.Phase),
-					"nodeName":   pod.Spec.NodeName,
-					"ip":         pod.Status.PodIP,
-					"startTime":  pod.Status.StartTime,
-					"lastUpdate": time.Now().UTC().Format(time.RFC3339),
+					"name":      pod.Name,
+					"namespace": pod.Namespace,
+					"status":    string(pod.Status.Phase),
+					"nodeName":  pod.Spec.NodeName,
+					"ip":        pod.Status.PodIP,
+					"startTime": pod.Status.StartTime,
 				})
 			}
 
-			p.hub.Broadcast(MessageTypePods, map[string]interface{}{
-				"pods":      podData,
-				"timestamp": time.Now().UTC().Format(time.RFC3339),
-			})
+			p.hub.BroadcastCollection(MessageTypePods, "pods", podData)
 		}
 	}
 }
@@ -352,48 +354,54 @@ func getDatabaseType(podName string) string {
 }
 
 
-// publishServiceHealthMetrics publishes service health metrics every 30 seconds
+// publishServiceHealthMetrics publishes the health of services discovered via the
+// denshimon.io/monitor annotation every 30 seconds
 func (p *Publisher) publishServiceHealthMetrics() {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
+	serviceDiscovery := p.serviceDiscovery
+	if serviceDiscovery == nil {
+		serviceDiscovery = discovery.NewService(p.k8sClient, nil)
+	}
+
 	for {
 		select {
 		case <-p.ctx.Done():
 			return
 		case <-ticker.C:
 			ctx := context.Background()
-			
-			// Get all pods to check for service health
-			pods, err := p.k8sClient.ListPods(ctx, "")
+
+			health, err := serviceDiscovery.Check(ctx)
 			if err != nil {
-				slog.Error("Failed to get pods for service health metrics", "error", err)
+				slog.Error("Failed to discover services for service health metrics", "error", err)
 				continue
 			}
 
 			var services []map[string]interface{}
-			var infrastructureStatus map[string]interface{}
-			
-			// Analyze pods for service health (only real Kubernetes data)
-			for _, pod := range pods.Items {
-				if isServiceHealthTarget(pod.Name) {
-					service := map[string]interface{}{
-						"id":          pod.Name,
-						"name":        getServiceDisplayName(pod.Name),
-						"type":        getServiceType(pod.Name),
-						"status":      getServiceStatus(string(pod.Status.Phase)),
-						"uptime":      calculateUptime(pod.Status.StartTime),
-						"lastChecked": time.Now().UTC().Format(time.RFC3339),
-						"url":         getServiceURL(pod.Name),
-						"namespace":   pod.Namespace,
-						"nodeName":    pod.Spec.NodeName,
-						"podIP":       pod.Status.PodIP,
-						"startTime":   pod.Status.StartTime,
-					}
-					services = append(services, service)
-				}
+			for _, h := range health {
+				services = append(services, map[string]interface{}{
+					"id":          h.ID,
+					"name":        h.Name,
+					"type":        h.Type,
+					"status":      h.Status,
+					"uptime":      calculateUptime(h.StartTime),
+					"lastChecked": h.LastChecked.Format(time.RFC3339),
+					"url":         h.URL,
+					"namespace":   h.Namespace,
+					"service":     h.Service,
+					"source":      h.Source,
+					"pods":        h.Pods,
+					"readyPods":   h.ReadyPods,
+					"nodeName":    h.NodeName,
+					"podIP":       h.PodIP,
+					"startTime":   h.StartTime,
+					"healthCheck": h.HealthCheck,
+				})
 			}
 
+			var infrastructureStatus map[string]interface{}
+
 			// Basic infrastructure status from Kubernetes services
 			services_k8s, err := p.k8sClient.ListServices(ctx, "")
 			if err == nil {
@@ -418,85 +426,13 @@ func (p *Publisher) publishServiceHealthMetrics() {
 					"services":       services,
 					"infrastructure": infrastructureStatus,
 					"timestamp":      time.Now().UTC().Format(time.RFC3339),
-					"source":         "k8s_pods",
+					"source":         "service_discovery",
 				})
 			}
 		}
 	}
 }
 
-// Service health helper functions
-func isServiceHealthTarget(podName string) bool {
-	serviceNames := []string{
-		"gitea", "filebrowser", "umami", "memos", "uptime", "kuma", 
-		"postgresql", "postgres", "mysql", "redis", "nginx", "traefik",
-	}
-	
-	for _, service := range serviceNames {
-		if containsIgnoreCase(podName, service) {
-			return true
-		}
-	}
-	return false
-}
-
-func getServiceDisplayName(podName string) string {
-	if containsIgnoreCase(podName, "gitea") {
-		return "Gitea"
-	}
-	if containsIgnoreCase(podName, "filebrowser") {
-		return "File Browser"
-	}
-	if containsIgnoreCase(podName, "umami") {
-		return "Umami Analytics"
-	}
-	if containsIgnoreCase(podName, "memos") {
-		return "Memos"
-	}
-	if containsIgnoreCase(podName, "uptime") || containsIgnoreCase(podName, "kuma") {
-		return "Uptime Kuma"
-	}
-	if containsIgnoreCase(podName, "postgresql") || containsIgnoreCase(podName, "postgres") {
-		return "PostgreSQL"
-	}
-	return podName
-}
-
-func getServiceType(podName string) string {
-	if containsIgnoreCase(podName, "gitea") {
-		return "gitea"
-	}
-	if containsIgnoreCase(podName, "filebrowser") {
-		return "filebrowser"
-	}
-	if containsIgnoreCase(podName, "umami") {
-		return "umami"
-	}
-	if containsIgnoreCase(podName, "memos") {
-		return "memos"
-	}
-	if containsIgnoreCase(podName, "uptime") || containsIgnoreCase(podName, "kuma") {
-		return "uptime_kuma"
-	}
-	if containsIgnoreCase(podName, "postgresql") || containsIgnoreCase(podName, "postgres") {
-		return "postgresql"
-	}
-	return "generic"
-}
-
-func getServiceStatus(podPhase string) string {
-	switch podPhase {
-	case "Running":
-		return "healthy"
-	case "Pending":
-		return "warning"
-	case "Failed":
-		return "critical"
-	default:
-		return "unknown"
-	}
-}
-
 func calculateUptime(startTime *metav1.Time) float64 {
 	if startTime == nil {
 		return 0.0
@@ -515,28 +451,6 @@ func calculateUptime(startTime *metav1.Time) float64 {
 	return baseUptime + (float64(time.Now().Unix()%5) * 0.02)
 }
 
-func getServiceURL(podName string) string {
-	if containsIgnoreCase(podName, "gitea") {
-		return "https://git.example.com"
-	}
-	if containsIgnoreCase(podName, "filebrowser") {
-		return "https://files.example.com"
-	}
-	if containsIgnoreCase(podName, "umami") {
-		return "https://analytics.example.com"
-	}
-	if containsIgnoreCase(podName, "uptime") {
-		return "https://status.example.com"
-	}
-	return ""
-}
-
-// containsIgnoreCase checks if str contains substr (case insensitive)
-func containsIgnoreCase(str, substr string) bool {
-	return strings.Contains(strings.ToLower(str), strings.ToLower(substr))
-}
-
-
 // publishDeploymentMetrics publishes deployment metrics every 15 seconds
 func (p *Publisher) publishDeploymentMetrics() {
 	ticker := time.NewTicker(15 * time.Second)
@@ -591,14 +505,84 @@ func (p *Publisher) publishDeploymentMetrics() {
 			}
 
 			// Publish deployments data
-			p.hub.Broadcast(MessageTypeDeployments, map[string]interface{}{
-				"deployments": deploymentData,
-				"timestamp":   time.Now().UTC().Format(time.RFC3339),
-			})
+			p.hub.BroadcastCollection(MessageTypeDeployments, "deployments", deploymentData)
 		}
 	}
 }
 
+// publishWorkloads publishes StatefulSets and DaemonSets every 15 seconds
+func (p *Publisher) publishWorkloads() {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			ctx := context.Background()
+
+			if statefulSets, err := p.k8sClient.ListStatefulSets(ctx, ""); err != nil {
+				slog.Error("Failed to get statefulsets", "error", err)
+			} else {
+				statefulSetData := []map[string]interface{}{}
+				for _, statefulSet := range statefulSets.Items {
+					desired := int32(1)
+					if statefulSet.Spec.Replicas != nil {
+						desired = *statefulSet.Spec.Replicas
+					}
+					statefulSetData = append(statefulSetData, map[string]interface{}{
+						"name":      statefulSet.Name,
+						"namespace": statefulSet.Namespace,
+						"status":    workloadStatus(statefulSet.Status.ReadyReplicas, statefulSet.Status.UpdatedReplicas, desired),
+						"replicas": map[string]interface{}{
+							"current": statefulSet.Status.Replicas,
+							"desired": desired,
+							"ready":   statefulSet.Status.ReadyReplicas,
+							"updated": statefulSet.Status.UpdatedReplicas,
+						},
+					})
+				}
+				p.hub.BroadcastCollection(MessageTypeStatefulSets, "statefulsets", statefulSetData)
+			}
+
+			if daemonSets, err := p.k8sClient.ListDaemonSets(ctx, ""); err != nil {
+				slog.Error("Failed to get daemonsets", "error", err)
+			} else {
+				daemonSetData := []map[string]interface{}{}
+				for _, daemonSet := range daemonSets.Items {
+					desired := daemonSet.Status.DesiredNumberScheduled
+					daemonSetData = append(daemonSetData, map[string]interface{}{
+						"name":      daemonSet.Name,
+						"namespace": daemonSet.Namespace,
+						"status":    workloadStatus(daemonSet.Status.NumberReady, daemonSet.Status.UpdatedNumberScheduled, desired),
+						"nodes": map[string]interface{}{
+							"desired":   desired,
+							"current":   daemonSet.Status.CurrentNumberScheduled,
+							"ready":     daemonSet.Status.NumberReady,
+							"updated":   daemonSet.Status.UpdatedNumberScheduled,
+							"available": daemonSet.Status.NumberAvailable,
+						},
+					})
+				}
+				p.hub.BroadcastCollection(MessageTypeDaemonSets, "daemonsets", daemonSetData)
+			}
+		}
+	}
+}
+
+// workloadStatus summarises a StatefulSet or DaemonSet from its ready and updated pod counts
+func workloadStatus(ready, updated, desired int32) string {
+	switch {
+	case updated < desired:
+		return "progressing"
+	case ready < desired:
+		return "degraded"
+	default:
+		return "ready"
+	}
+}
+
 // Helper functions for deployment processing
 func isDeploymentActive(deployment appsv1.Deployment) bool {
 	// Consider deployment active if it's not fully ready or has recent updates
@@ -0,0 +1,171 @@
+package websocket
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/archellir/denshimon/pkg/response"
+)
+
+// replaySize is how many broadcasts each channel keeps for reconnecting clients. Of
+// collection messages only the latest is kept, since it is a complete list.
+const replaySize = 64
+
+// Session is sent to every client when it connects. A client that reconnects passes the
+// epoch and the seq of the last message it received as since to resume where it left off.
+type Session struct {
+	ClientID string `json:"client_id"`
+	// Epoch changes when the server restarts; cursors from another epoch can't be resumed
+	Epoch string `json:"epoch"`
+	// Seq is the latest broadcast sequence number. Numbers increase across all channels, so
+	// a client sees gaps for the channels it doesn't subscribe to.
+	Seq uint64 `json:"seq"`
+}
+
+// Snapshot is the replay buffer of a channel, for hydrating a view before subscribing
+type Snapshot struct {
+	Channel  MessageType `json:"channel"`
+	Epoch    string      `json:"epoch"`
+	Seq      uint64      `json:"seq"`
+	Messages []Message   `json:"messages"`
+}
+
+// resumeRequest asks the hub loop to replay missed messages, so replayed messages are
+// queued before any live broadcast that follows
+type resumeRequest struct {
+	client *Client
+	types  []MessageType
+	epoch  string
+	since  uint64
+}
+
+// record numbers a broadcast and keeps it for replay
+func (h *Hub) record(message *Message) {
+	h.replayMu.Lock()
+	defer h.replayMu.Unlock()
+
+	h.seq++
+	message.Seq = h.seq
+
+	if message.Collection != "" {
+		h.collections[message.Type] = *message
+		return
+	}
+	buffer := append(h.replay[message.Type], *message)
+	if len(buffer) > replaySize {
+		h.evicted[message.Type] = buffer[len(buffer)-replaySize-1].Seq
+		buffer = append([]Message(nil), buffer[len(buffer)-replaySize:]...)
+	}
+	h.replay[message.Type] = buffer
+}
+
+// buffered returns a channel's kept messages in order: its recent messages and the latest
+// collection, if newer than since
+func (h *Hub) buffered(messageType MessageType, since uint64) []Message {
+	var messages []Message
+	for _, message := range h.replay[messageType] {
+		if message.Seq > since {
+			messages = append(messages, message)
+		}
+	}
+	if latest, ok := h.collections[messageType]; ok && latest.Seq > since {
+		messages = append(messages, latest)
+		sort.Slice(messages, func(i, j int) bool { return messages[i].Seq < messages[j].Seq })
+	}
+	return messages
+}
+
+// resume replays the buffered messages a client missed. Channels whose missed messages were
+// already evicted, or whose cursor is from another epoch, get a resync message instead, and
+// the client should fetch their snapshot.
+func (h *Hub) resume(req resumeRequest) {
+	h.replayMu.Lock()
+	var replay []Message
+	var resync []MessageType
+	for _, messageType := range req.types {
+		if req.epoch != h.epoch || h.evicted[messageType] > req.since {
+			resync = append(resync, messageType)
+			continue
+		}
+		replay = append(replay, h.buffered(messageType, req.since)...)
+	}
+	h.replayMu.Unlock()
+
+	for _, messageType := range resync {
+		req.client.SendMessage(MessageTypeResync, map[string]interface{}{"message_type": messageType})
+	}
+	for _, message := range replay {
+		if tailored, ok := req.client.prepare(message); ok {
+			select {
+			case req.client.Send <- tailored:
+			default:
+				slog.Warn("Client send channel full during replay", "client_id", req.client.ID)
+				return
+			}
+		}
+	}
+}
+
+// session describes the hub's current position for a newly connected client
+func (h *Hub) session(client *Client) Session {
+	h.replayMu.Lock()
+	defer h.replayMu.Unlock()
+	return Session{ClientID: client.ID, Epoch: h.epoch, Seq: h.seq}
+}
+
+// GetSnapshot returns a channel's buffered messages, with collections as their latest complete
+// list. Namespaced data is limited to the given namespaces, if any.
+func (h *Hub) GetSnapshot(channel MessageType, namespaces ...string) Snapshot {
+	h.replayMu.Lock()
+	buffer := h.buffered(channel, 0)
+	snapshot := Snapshot{Channel: channel, Epoch: h.epoch, Seq: h.seq, Messages: []Message{}}
+	h.replayMu.Unlock()
+
+	// Tailor messages the way a subscriber without deltas receives them
+	client := &Client{
+		Subscriptions: make(map[MessageType]bool),
+		namespaces:    make(map[MessageType]map[string]bool),
+		sent:          make(map[MessageType]map[string][32]byte),
+	}
+	client.Subscribe(channel, namespaces...)
+	for _, message := range buffer {
+		if tailored, ok := client.prepare(message); ok {
+			snapshot.Messages = append(snapshot.Messages, tailored)
+		}
+	}
+	return snapshot
+}
+
+// HandleSnapshot returns the buffered messages of the channel in the path, e.g.
+// GET /api/ws/snapshot/pods?namespace=prod&namespace=staging
+func (h *Handler) HandleSnapshot(w http.ResponseWriter, r *http.Request) {
+	channel := MessageType(r.PathValue("channel"))
+	if channel == "" {
+		response.SendError(w, http.StatusBadRequest, "channel is required")
+		return
+	}
+	response.SendSuccess(w, h.hub.GetSnapshot(channel, r.URL.Query()["namespace"]...))
+}
+
+// parseCursor reads a since cursor sent as a JSON number or string
+func parseCursor(value interface{}) (uint64, bool) {
+	switch v := value.(type) {
+	case float64:
+		if v < 0 {
+			return 0, false
+		}
+		return uint64(v), true
+	case string:
+		cursor, err := strconv.ParseUint(v, 10, 64)
+		return cursor, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func newEpoch() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
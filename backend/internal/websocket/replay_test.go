@@ -0,0 +1,115 @@
+package websocket
+
+import "testing"
+
+func recordEvent(hub *Hub, reason string) Message {
+	message := Message{Type: MessageTypeEvents, Data: map[string]interface{}{"reason": reason}}
+	hub.record(&message)
+	return message
+}
+
+// drain returns the messages queued for a client
+func drain(client *Client) []Message {
+	var messages []Message
+	for {
+		select {
+		case message := <-client.Send:
+			messages = append(messages, message)
+		default:
+			return messages
+		}
+	}
+}
+
+func TestRecordNumbersBroadcasts(t *testing.T) {
+	hub := NewHub()
+	first := recordEvent(hub, "Scheduled")
+	second := recordEvent(hub, "Pulled")
+	if first.Seq != 1 || second.Seq != 2 {
+		t.Fatalf("expected seq 1 and 2, got %d and %d", first.Seq, second.Seq)
+	}
+	if session := hub.session(&Client{ID: "c1"}); session.Seq != 2 || session.Epoch != hub.epoch {
+		t.Fatalf("unexpected session: %+v", session)
+	}
+}
+
+func TestResumeReplaysMissedMessages(t *testing.T) {
+	hub := NewHub()
+	for _, reason := range []string{"Scheduled", "Pulled", "Started"} {
+		recordEvent(hub, reason)
+	}
+	client := newTestClient(ClientOptions{})
+	client.Subscribe(MessageTypeEvents)
+
+	hub.resume(resumeRequest{client: client, types: []MessageType{MessageTypeEvents}, epoch: hub.epoch, since: 1})
+	messages := drain(client)
+	if len(messages) != 2 || messages[0].Seq != 2 || messages[1].Seq != 3 {
+		t.Fatalf("expected messages 2 and 3, got %+v", messages)
+	}
+}
+
+func TestResumeRequestsResync(t *testing.T) {
+	hub := NewHub()
+	for i := 0; i < replaySize+2; i++ {
+		recordEvent(hub, "Pulled")
+	}
+	client := newTestClient(ClientOptions{})
+	client.Subscribe(MessageTypeEvents)
+
+	// The messages after seq 1 were evicted
+	hub.resume(resumeRequest{client: client, types: []MessageType{MessageTypeEvents}, epoch: hub.epoch, since: 1})
+	if messages := drain(client); len(messages) != 1 || messages[0].Type != MessageTypeResync {
+		t.Fatalf("expected a resync after eviction, got %+v", messages)
+	}
+
+	// Cursors from before a restart can't be resumed
+	hub.resume(resumeRequest{client: client, types: []MessageType{MessageTypeEvents}, epoch: "old", since: replaySize})
+	if messages := drain(client); len(messages) != 1 || messages[0].Type != MessageTypeResync {
+		t.Fatalf("expected a resync for another epoch, got %+v", messages)
+	}
+
+	// A recent cursor resumes normally
+	hub.resume(resumeRequest{client: client, types: []MessageType{MessageTypeEvents}, epoch: hub.epoch, since: replaySize})
+	if messages := drain(client); len(messages) != 2 {
+		t.Fatalf("expected 2 replayed messages, got %+v", messages)
+	}
+}
+
+func TestCollectionsKeepLatestMessage(t *testing.T) {
+	hub := NewHub()
+	for _, status := range []string{"Pending", "Running"} {
+		message := collectionMessage(t, pod("default", "web", status), pod("prod", "api", status))
+		hub.record(&message)
+	}
+	health := Message{Type: MessageTypePods, Data: map[string]interface{}{"alert": "restarts"}}
+	hub.record(&health)
+
+	snapshot := hub.GetSnapshot(MessageTypePods, "prod")
+	if len(snapshot.Messages) != 2 || snapshot.Seq != 3 {
+		t.Fatalf("expected the latest collection and the plain message, got %+v", snapshot)
+	}
+	pods := snapshot.Messages[0].Data.(map[string]interface{})["pods"].([]map[string]interface{})
+	if len(pods) != 1 || pods[0]["name"] != "api" || pods[0]["status"] != "Running" {
+		t.Fatalf("expected the latest prod pod, got %v", pods)
+	}
+
+	// Replacing a collection doesn't evict, so old cursors still resume
+	client := newTestClient(ClientOptions{})
+	client.Subscribe(MessageTypePods)
+	hub.resume(resumeRequest{client: client, types: []MessageType{MessageTypePods}, epoch: hub.epoch, since: 1})
+	if messages := drain(client); len(messages) != 2 || messages[0].Seq != 2 {
+		t.Fatalf("expected the latest collection and the plain message, got %+v", messages)
+	}
+}
+
+func TestParseCursor(t *testing.T) {
+	if cursor, ok := parseCursor(float64(42)); !ok || cursor != 42 {
+		t.Fatalf("unexpected cursor %d", cursor)
+	}
+	if cursor, ok := parseCursor("42"); !ok || cursor != 42 {
+		t.Fatalf("unexpected cursor %d", cursor)
+	}
+	if _, ok := parseCursor(float64(-1)); ok {
+		t.Fatal("expected negative cursors to be rejected")
+	}
+}
@@ -0,0 +1,186 @@
+package websocket
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// maxBatchSize is how many queued messages a batching client receives in one frame
+const maxBatchSize = 32
+
+// ClientOptions are set when the connection is opened, e.g. /ws?deltas=true&batch=true
+type ClientOptions struct {
+	// Deltas sends collections as changes since the last message instead of full snapshots
+	Deltas bool
+	// Batch combines messages queued at the same time into one batch frame
+	Batch bool
+}
+
+// parseClientOptions reads the connection options from the query string
+func parseClientOptions(query url.Values) ClientOptions {
+	deltas, _ := strconv.ParseBool(query.Get("deltas"))
+	batch, _ := strconv.ParseBool(query.Get("batch"))
+	return ClientOptions{Deltas: deltas, Batch: batch}
+}
+
+// collectionItem is one object of a collection with its identity and content hash, computed
+// once per broadcast rather than once per client
+type collectionItem struct {
+	key       string // namespace/name
+	namespace string
+	hash      [32]byte
+	value     map[string]interface{}
+}
+
+// CollectionDelta is the data of a delta message: the objects added or changed since the
+// client's previous message of the same type, and the keys (namespace/name) of removed ones
+type CollectionDelta struct {
+	Upserted  []map[string]interface{} `json:"upserted"`
+	Removed   []string                 `json:"removed"`
+	Timestamp string                   `json:"timestamp"`
+}
+
+// BroadcastCollection sends a list of namespaced objects, such as pods, to subscribed
+// clients. Clients receive only the namespaces they subscribed to, and clients using deltas
+// receive only what changed since their last message; unchanged collections send nothing.
+// Items are identified by their "namespace" and "name" fields.
+func (h *Hub) BroadcastCollection(messageType MessageType, collection string, items []map[string]interface{}) {
+	prepared := make([]collectionItem, 0, len(items))
+	for _, item := range items {
+		namespace, _ := item["namespace"].(string)
+		name, _ := item["name"].(string)
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			continue
+		}
+		prepared = append(prepared, collectionItem{
+			key:       namespace + "/" + name,
+			namespace: namespace,
+			hash:      sha256.Sum256(encoded),
+			value:     item,
+		})
+	}
+
+	message := Message{
+		Type:       messageType,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		Collection: collection,
+		items:      prepared,
+	}
+	select {
+	case h.broadcast <- message:
+	default:
+		h.logDropped(messageType)
+	}
+}
+
+// prepare tailors a broadcast message to the client's namespace filter and options. It
+// returns false when the client has nothing new to receive.
+func (c *Client) prepare(message Message) (Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.Subscriptions[message.Type] {
+		return message, false
+	}
+	namespaces := c.namespaces[message.Type]
+
+	if message.Collection == "" {
+		if len(namespaces) > 0 {
+			message.Data = filterNamespaces(message.Data, namespaces)
+		}
+		return message, true
+	}
+
+	items := make([]collectionItem, 0, len(message.items))
+	for _, item := range message.items {
+		if len(namespaces) == 0 || namespaces[item.namespace] {
+			items = append(items, item)
+		}
+	}
+
+	previous, seen := c.sent[message.Type]
+	current := make(map[string][32]byte, len(items))
+	for _, item := range items {
+		current[item.key] = item.hash
+	}
+	if c.options.Deltas {
+		c.sent[message.Type] = current
+	}
+
+	if !c.options.Deltas || !seen {
+		values := make([]map[string]interface{}, 0, len(items))
+		for _, item := range items {
+			values = append(values, item.value)
+		}
+		message.Data = map[string]interface{}{
+			message.Collection: values,
+			"timestamp":        message.Timestamp,
+		}
+		return message, true
+	}
+
+	delta := CollectionDelta{Upserted: []map[string]interface{}{}, Removed: []string{}, Timestamp: message.Timestamp}
+	for _, item := range items {
+		if hash, ok := previous[item.key]; !ok || hash != item.hash {
+			delta.Upserted = append(delta.Upserted, item.value)
+		}
+	}
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			delta.Removed = append(delta.Removed, key)
+		}
+	}
+	if len(delta.Upserted) == 0 && len(delta.Removed) == 0 {
+		return message, false
+	}
+	message.Delta = true
+	message.Data = delta
+	return message, true
+}
+
+// filterNamespaces drops the objects of other namespaces from the lists in a message's
+// data. Data that isn't a map of lists is returned as it is.
+func filterNamespaces(data interface{}, namespaces map[string]bool) interface{} {
+	fields, ok := data.(map[string]interface{})
+	if !ok {
+		return data
+	}
+	filtered := make(map[string]interface{}, len(fields))
+	for field, value := range fields {
+		list, ok := value.([]map[string]interface{})
+		if !ok {
+			filtered[field] = value
+			continue
+		}
+		kept := make([]map[string]interface{}, 0, len(list))
+		for _, item := range list {
+			if namespace, _ := item["namespace"].(string); namespaces[namespace] {
+				kept = append(kept, item)
+			}
+		}
+		filtered[field] = kept
+	}
+	return filtered
+}
+
+// nextBatch collects the messages already queued behind first, up to maxBatchSize. ok is
+// false when the send channel was closed while collecting.
+func (c *Client) nextBatch(first Message) (batch []Message, ok bool) {
+	batch = []Message{first}
+	for len(batch) < maxBatchSize {
+		select {
+		case message, open := <-c.Send:
+			if !open {
+				return batch, false
+			}
+			batch = append(batch, message)
+		default:
+			return batch, true
+		}
+	}
+	return batch, true
+}
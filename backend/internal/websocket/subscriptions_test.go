@@ -0,0 +1,111 @@
+package websocket
+
+import (
+	"net/url"
+	"testing"
+)
+
+func newTestClient(options ClientOptions) *Client {
+	client := NewClient(nil, NewHub(), "admin")
+	client.options = options
+	return client
+}
+
+// collectionMessage builds the message BroadcastCollection queues, without the hub loop
+func collectionMessage(t *testing.T, items ...map[string]interface{}) Message {
+	t.Helper()
+	hub := NewHub()
+	hub.BroadcastCollection(MessageTypePods, "pods", items)
+	return <-hub.broadcast
+}
+
+func pod(namespace, name, status string) map[string]interface{} {
+	return map[string]interface{}{"namespace": namespace, "name": name, "status": status}
+}
+
+func TestPrepareRequiresSubscription(t *testing.T) {
+	client := newTestClient(ClientOptions{})
+	if _, ok := client.prepare(collectionMessage(t, pod("default", "web", "Running"))); ok {
+		t.Fatal("expected unsubscribed client to receive nothing")
+	}
+}
+
+func TestPrepareFiltersNamespaces(t *testing.T) {
+	client := newTestClient(ClientOptions{})
+	client.Subscribe(MessageTypePods, "prod")
+
+	message, ok := client.prepare(collectionMessage(t, pod("default", "web", "Running"), pod("prod", "api", "Running")))
+	if !ok {
+		t.Fatal("expected a message")
+	}
+	pods := message.Data.(map[string]interface{})["pods"].([]map[string]interface{})
+	if len(pods) != 1 || pods[0]["name"] != "api" {
+		t.Fatalf("expected only the prod pod, got %v", pods)
+	}
+
+	// Plain messages have the lists in their data filtered
+	client.Subscribe(MessageTypeDatabase, "prod")
+	message, _ = client.prepare(Message{Type: MessageTypeDatabase, Data: map[string]interface{}{
+		"databases": []map[string]interface{}{pod("default", "pg", "Running"), pod("prod", "pg", "Running")},
+		"source":    "k8s_pods",
+	}})
+	data := message.Data.(map[string]interface{})
+	if databases := data["databases"].([]map[string]interface{}); len(databases) != 1 || data["source"] != "k8s_pods" {
+		t.Fatalf("unexpected filtered data: %v", data)
+	}
+}
+
+func TestPrepareSendsDeltas(t *testing.T) {
+	client := newTestClient(ClientOptions{Deltas: true})
+	client.Subscribe(MessageTypePods)
+
+	first, ok := client.prepare(collectionMessage(t, pod("default", "web", "Pending"), pod("default", "db", "Running")))
+	if !ok || first.Delta {
+		t.Fatalf("expected a full snapshot first, got %+v", first)
+	}
+
+	if _, ok := client.prepare(collectionMessage(t, pod("default", "web", "Pending"), pod("default", "db", "Running"))); ok {
+		t.Fatal("expected no message when nothing changed")
+	}
+
+	message, ok := client.prepare(collectionMessage(t, pod("default", "web", "Running"), pod("default", "cache", "Running")))
+	if !ok || !message.Delta || message.Collection != "pods" {
+		t.Fatalf("expected a delta, got %+v", message)
+	}
+	delta := message.Data.(CollectionDelta)
+	if len(delta.Upserted) != 2 || len(delta.Removed) != 1 || delta.Removed[0] != "default/db" {
+		t.Fatalf("unexpected delta: %+v", delta)
+	}
+
+	// Subscribing again starts over with a snapshot
+	client.Subscribe(MessageTypePods)
+	if message, _ := client.prepare(collectionMessage(t, pod("default", "web", "Running"))); message.Delta {
+		t.Fatal("expected a snapshot after resubscribing")
+	}
+}
+
+func TestNextBatch(t *testing.T) {
+	client := newTestClient(ClientOptions{Batch: true})
+	for i := 0; i < maxBatchSize+5; i++ {
+		client.Send <- Message{Type: MessageTypeEvents}
+	}
+
+	batch, open := client.nextBatch(<-client.Send)
+	if !open || len(batch) != maxBatchSize {
+		t.Fatalf("expected a full batch, got %d (open %v)", len(batch), open)
+	}
+	batch, _ = client.nextBatch(<-client.Send)
+	if len(batch) != 5 {
+		t.Fatalf("expected the remaining 5 messages, got %d", len(batch))
+	}
+}
+
+func TestParseClientOptions(t *testing.T) {
+	options := parseClientOptions(url.Values{"deltas": {"true"}, "batch": {"1"}})
+	if !options.Deltas || !options.Batch {
+		t.Fatalf("unexpected options: %+v", options)
+	}
+	if options := parseClientOptions(url.Values{}); options.Deltas || options.Batch {
+		t.Fatalf("expected options to default off, got %+v", options)
+	}
+}
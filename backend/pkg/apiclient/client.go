@@ -0,0 +1,250 @@
+// Package apiclient is a Go client for the Denshimon REST API.
+//
+// It authenticates with a bearer token, either a session token from Login or an API token
+// created with CreateAPIToken. Request and response types are shared with the server, which
+// aliases them, so the client decodes exactly what the handlers encode.
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client calls one Denshimon server
+type Client struct {
+	// BaseURL is the server's address, e.g. https://denshimon.example.com
+	BaseURL string
+	// Token is sent as a bearer token; empty for unauthenticated calls
+	Token      string
+	HTTPClient *http.Client
+}
+
+// New creates a client for a server. The HTTP client has no timeout, as PodLogs may follow
+// a log indefinitely; bound calls with their context instead.
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{},
+	}
+}
+
+// Error is a response with a non-2xx status
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server returned %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// envelope is the server's standard {success, data, error} response wrapper
+type envelope struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+// Login exchanges credentials for a session token. The client's Token is not changed.
+func (c *Client) Login(ctx context.Context, username, password string) (*LoginResponse, error) {
+	var resp LoginResponse
+	if err := c.call(ctx, http.MethodPost, "/api/auth/login", nil, LoginRequest{Username: username, Password: password}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateAPIToken issues an API token for the authenticated user. The server refuses this
+// for clients that are themselves authenticated with an API token.
+func (c *Client) CreateAPIToken(ctx context.Context, req CreateAPITokenRequest) (*CreateAPITokenResponse, error) {
+	var resp CreateAPITokenResponse
+	if err := c.call(ctx, http.MethodPost, "/api/auth/tokens", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListPods lists the pods in a namespace; the server defaults an empty namespace to "default"
+func (c *Client) ListPods(ctx context.Context, namespace string) ([]PodInfo, error) {
+	query := url.Values{}
+	if namespace != "" {
+		query.Set("namespace", namespace)
+	}
+	var pods []PodInfo
+	if err := c.call(ctx, http.MethodGet, "/api/k8s/pods", query, nil, &pods); err != nil {
+		return nil, err
+	}
+	return pods, nil
+}
+
+// PendingDeployments lists deployments committed to git and waiting to be applied
+func (c *Client) PendingDeployments(ctx context.Context) ([]Deployment, error) {
+	var deployments []Deployment
+	if err := c.call(ctx, http.MethodGet, "/api/deployments/pending", nil, nil, &deployments); err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
+// ApplyDeployment applies a pending deployment to the cluster
+func (c *Client) ApplyDeployment(ctx context.Context, id string) (*ApplyResult, error) {
+	var result ApplyResult
+	if err := c.call(ctx, http.MethodPost, "/api/deployments/"+url.PathEscape(id)+"/apply", nil, ApplyDeploymentRequest{}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListBackupJobs lists the configured backup jobs
+func (c *Client) ListBackupJobs(ctx context.Context) ([]BackupJob, error) {
+	var jobs []BackupJob
+	if err := c.callEnvelope(ctx, http.MethodGet, "/api/backup/jobs", nil, nil, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// RunBackupJob starts a backup job; it runs in the background on the server
+func (c *Client) RunBackupJob(ctx context.Context, id string) error {
+	return c.callEnvelope(ctx, http.MethodPost, "/api/backup/jobs/"+url.PathEscape(id)+"/run", nil, nil, nil)
+}
+
+// LogOptions selects the logs PodLogs returns
+type LogOptions struct {
+	Namespace string
+	Container string
+	// Tail limits the output to the last lines; zero returns the whole log
+	Tail int
+	// Follow keeps the stream open for new lines until the context is cancelled
+	Follow bool
+}
+
+// PodLogs streams a pod's logs as plain text. The caller must close the reader.
+func (c *Client) PodLogs(ctx context.Context, name string, opts LogOptions) (io.ReadCloser, error) {
+	query := url.Values{}
+	if opts.Namespace != "" {
+		query.Set("namespace", opts.Namespace)
+	}
+	if opts.Container != "" {
+		query.Set("container", opts.Container)
+	}
+	if opts.Tail > 0 {
+		query.Set("tail", strconv.Itoa(opts.Tail))
+	}
+	if opts.Follow {
+		query.Set("follow", "true")
+	}
+	resp, err := c.send(ctx, http.MethodGet, "/api/k8s/pods/"+url.PathEscape(name)+"/logs", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// call sends a request and decodes a bare JSON response into out
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// callEnvelope sends a request and decodes the data of a wrapped response into out
+func (c *Client) callEnvelope(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var wrapped envelope
+	if err := json.NewDecoder(resp.Body).Decode(&wrapped); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !wrapped.Success {
+		return &Error{StatusCode: resp.StatusCode, Message: wrapped.Error}
+	}
+	if out == nil || len(wrapped.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(wrapped.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// send performs a request, returning an *Error for non-2xx responses
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// responseError reads the message of an error response, which handlers send either as
+// plain text or in the standard wrapper
+func responseError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	message := strings.TrimSpace(string(data))
+
+	var wrapped envelope
+	if json.Unmarshal(data, &wrapped) == nil {
+		switch {
+		case wrapped.Error != "":
+			message = wrapped.Error
+		case wrapped.Message != "":
+			message = wrapped.Message
+		}
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: message}
+}
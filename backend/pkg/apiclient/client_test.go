@@ -0,0 +1,152 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return New(server.URL+"/", "dsm_test")
+}
+
+func TestListPods(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/k8s/pods" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer dsm_test" {
+			t.Errorf("unexpected Authorization %q", got)
+		}
+		if got := r.URL.Query().Get("namespace"); got != "apps" {
+			t.Errorf("unexpected namespace %q", got)
+		}
+		json.NewEncoder(w).Encode([]PodInfo{{Name: "web-1", Namespace: "apps", Restarts: 2}})
+	})
+
+	pods, err := client.ListPods(context.Background(), "apps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 1 || pods[0].Name != "web-1" || pods[0].Restarts != 2 {
+		t.Fatalf("unexpected pods %+v", pods)
+	}
+}
+
+func TestApplyDeployment(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/deployments/dep-1/apply" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req ApplyDeploymentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("expected a JSON body: %v", err)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":   "applied",
+			"message":  "Deployment applied successfully",
+			"warnings": []BudgetConflict{{Budget: "web", DisruptionsAllowed: 0}},
+		})
+	})
+
+	result, err := client.ApplyDeployment(context.Background(), "dep-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != "applied" || len(result.Warnings) != 1 || result.Warnings[0].Budget != "web" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestEnvelopeResponses(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/backup/jobs":
+			w.Write([]byte(`{"success":true,"data":[{"id":"nightly","name":"Nightly","status":"scheduled"}]}`))
+		case "/api/backup/jobs/nightly/run":
+			w.Write([]byte(`{"success":true,"message":"Job started successfully"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	jobs, err := client.ListBackupJobs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != "nightly" || jobs[0].Status != "scheduled" {
+		t.Fatalf("unexpected jobs %+v", jobs)
+	}
+	if err := client.RunBackupJob(context.Background(), "nightly"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := map[string]struct {
+		handler http.HandlerFunc
+		status  int
+		message string
+	}{
+		"plain text": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "Deployment ID is required", http.StatusBadRequest)
+			},
+			status:  http.StatusBadRequest,
+			message: "Deployment ID is required",
+		},
+		"wrapped": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"success":false,"error":"Kubernetes client not available"}`))
+			},
+			status:  http.StatusServiceUnavailable,
+			message: "Kubernetes client not available",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := newTestClient(t, tt.handler).ListPods(context.Background(), "")
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected an *Error, got %v", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Message != tt.message {
+				t.Fatalf("unexpected error %+v", apiErr)
+			}
+		})
+	}
+}
+
+func TestPodLogs(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/k8s/pods/web-1/logs" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.URL.RawQuery; got != "container=app&follow=true&namespace=apps&tail=50" {
+			t.Errorf("unexpected query %s", got)
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("line 1\nline 2\n"))
+	})
+
+	logs, err := client.PodLogs(context.Background(), "web-1", LogOptions{Namespace: "apps", Container: "app", Tail: 50, Follow: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logs.Close()
+	data, err := io.ReadAll(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "line 1\nline 2\n" {
+		t.Fatalf("unexpected logs %q", data)
+	}
+}
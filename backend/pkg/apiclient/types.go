@@ -0,0 +1,119 @@
+package apiclient
+
+import "time"
+
+// The types below are the server's own request and response bodies; internal/http aliases them.
+
+// LoginRequest is the body of POST /api/auth/login
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse carries a session token
+type LoginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      UserInfo  `json:"user"`
+}
+
+// UserInfo describes the authenticated user
+type UserInfo struct {
+	ID       string   `json:"id"`
+	Username string   `json:"username"`
+	Role     string   `json:"role"`
+	Scopes   []string `json:"scopes"`
+}
+
+// CreateAPITokenRequest is the body of POST /api/auth/tokens
+type CreateAPITokenRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn string   `json:"expires_in,omitempty"` // Go duration, e.g. "2160h"; empty never expires
+}
+
+// PodInfo is a pod as listed by GET /api/k8s/pods
+type PodInfo struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Status      string            `json:"status"`
+	Ready       string            `json:"ready"`
+	Restarts    int32             `json:"restarts"`
+	Age         string            `json:"age"`
+	Node        string            `json:"node"`
+	IP          string            `json:"ip"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// ApplyDeploymentRequest is the body of POST /api/deployments/{id}/apply. The server
+// records the authenticated user in preference to AppliedBy.
+type ApplyDeploymentRequest struct {
+	AppliedBy string `json:"applied_by"`
+}
+
+// The types below mirror server types that live in internal packages, keeping the fields
+// the client reads.
+
+// APIToken describes an issued API token
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CreateAPITokenResponse carries the token, which the server shows only once
+type CreateAPITokenResponse struct {
+	Token    string    `json:"token"`
+	APIToken *APIToken `json:"api_token"`
+}
+
+// Deployment is a managed deployment
+type Deployment struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Namespace    string     `json:"namespace"`
+	Image        string     `json:"image"`
+	Replicas     int32      `json:"replicas"`
+	Status       string     `json:"status"`
+	Source       string     `json:"source"`
+	Author       string     `json:"author,omitempty"`
+	GitCommitSHA string     `json:"git_commit_sha,omitempty"`
+	AppliedBy    string     `json:"applied_by,omitempty"`
+	AppliedAt    *time.Time `json:"applied_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ApplyResult reports an applied deployment
+type ApplyResult struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	// Warnings are disruption budgets the change conflicts with
+	Warnings []BudgetConflict `json:"warnings,omitempty"`
+}
+
+// BudgetConflict is a pod disruption budget a change would violate
+type BudgetConflict struct {
+	Namespace          string   `json:"namespace"`
+	Budget             string   `json:"budget"`
+	Pods               []string `json:"pods"`
+	DisruptionsAllowed int32    `json:"disruptionsAllowed"`
+	Message            string   `json:"message"`
+}
+
+// BackupJob is a configured backup
+type BackupJob struct {
+	ID      string     `json:"id"`
+	Name    string     `json:"name"`
+	Type    string     `json:"type"`
+	Source  string     `json:"source"`
+	Status  string     `json:"status"`
+	LastRun *time.Time `json:"lastRun,omitempty"`
+	NextRun *time.Time `json:"nextRun,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
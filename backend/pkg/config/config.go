@@ -14,11 +14,17 @@ type Config struct {
 	DatabasePath string
 
 	// Auth
-	PasetoKey     string
-	TokenDuration time.Duration
+	PasetoKey           string
+	TokenDuration       time.Duration
+	KeyRotationInterval time.Duration // 0 disables scheduled rotation
+	EncryptionKey       string        // encrypts stored credentials; defaults to PasetoKey
+
+	// Backups
+	BackupEncryptionKey string // encrypts backups of jobs that don't use a passphrase
 
 	// Kubernetes
-	KubeConfig string
+	KubeConfig  string
+	K8sCacheTTL time.Duration // how long list results are shared between requests; 0 disables
 
 	// GitOps
 	GitTimeout time.Duration
@@ -30,17 +36,25 @@ type Config struct {
 	LogLevel string
 }
 
+// Load reads configuration from the environment, after exporting the settings of the
+// config file named by CONFIG_FILE, if any
 func Load() *Config {
+	FileWatcher()
+	pasetoKey := getEnv("PASETO_SECRET_KEY", generateDefaultKey())
 	return &Config{
-		Port:            getEnv("PORT", "8080"),
-		Environment:     getEnv("ENVIRONMENT", "development"),
-		DatabasePath:    getEnv("DATABASE_PATH", "/app/data/denshimon.db"),
-		PasetoKey:       getEnv("PASETO_SECRET_KEY", generateDefaultKey()),
-		TokenDuration:   getDuration("TOKEN_DURATION", 24*time.Hour),
-		KubeConfig:      getEnv("KUBECONFIG", ""),
-		GitTimeout:      getDuration("GIT_TIMEOUT", 60*time.Second),
-		MetricsInterval: getDuration("METRICS_INTERVAL", 15*time.Second),
-		LogLevel:        getEnv("LOG_LEVEL", "info"),
+		Port:                getEnv("PORT", "8080"),
+		Environment:         getEnv("ENVIRONMENT", "development"),
+		DatabasePath:        getEnv("DATABASE_PATH", "/app/data/denshimon.db"),
+		PasetoKey:           pasetoKey,
+		EncryptionKey:       getEnv("ENCRYPTION_KEY", pasetoKey),
+		BackupEncryptionKey: getEnv("BACKUP_ENCRYPTION_KEY", ""),
+		TokenDuration:       getDuration("TOKEN_DURATION", 24*time.Hour),
+		KeyRotationInterval: getDuration("PASETO_KEY_ROTATION", 7*24*time.Hour),
+		KubeConfig:          getEnv("KUBECONFIG", ""),
+		K8sCacheTTL:         getDuration("K8S_CACHE_TTL", 5*time.Second),
+		GitTimeout:          getDuration("GIT_TIMEOUT", 60*time.Second),
+		MetricsInterval:     getDuration("METRICS_INTERVAL", 15*time.Second),
+		LogLevel:            getEnv("LOG_LEVEL", "info"),
 	}
 }
 
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// File is the schema of the optional config file named by CONFIG_FILE. Every setting
+// can also be given as an environment variable, which takes precedence over the file.
+type File struct {
+	Server struct {
+		Port        string `json:"port,omitempty"`
+		Environment string `json:"environment,omitempty"`
+		LogLevel    string `json:"log_level,omitempty"`
+	} `json:"server"`
+
+	Database struct {
+		Path string `json:"path,omitempty"`
+	} `json:"database"`
+
+	Auth struct {
+		TokenDuration string `json:"token_duration,omitempty"`
+		KeyRotation   string `json:"key_rotation,omitempty"`
+	} `json:"auth"`
+
+	Kubernetes struct {
+		Kubeconfig string `json:"kubeconfig,omitempty"`
+		CacheTTL   string `json:"cache_ttl,omitempty"`
+	} `json:"kubernetes"`
+
+	GitOps struct {
+		RepoURL       string `json:"repo_url,omitempty"`
+		LocalPath     string `json:"local_path,omitempty"`
+		Branch        string `json:"branch,omitempty"`
+		Username      string `json:"username,omitempty"`
+		Token         string `json:"token,omitempty"`
+		WebhookSecret string `json:"webhook_secret,omitempty"`
+		SyncInterval  string `json:"sync_interval,omitempty"`
+	} `json:"gitops"`
+
+	Observability struct {
+		PrometheusURL   string `json:"prometheus_url,omitempty"`
+		LokiURL         string `json:"loki_url,omitempty"`
+		TracingProvider string `json:"tracing_provider,omitempty"`
+		TracingURL      string `json:"tracing_url,omitempty"`
+	} `json:"observability"`
+
+	Notifications []NotificationTarget `json:"notifications,omitempty"`
+	Features      map[string]bool      `json:"features,omitempty"`
+}
+
+// NotificationTarget is a destination for alert and event notifications
+type NotificationTarget struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"` // webhook, slack or discord
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"` // signs webhook payloads
+	Events []string `json:"events,omitempty"` // empty receives all events
+}
+
+// NotificationTypes lists the supported notification target types
+var NotificationTypes = []string{"webhook", "slack", "discord"}
+
+// binding ties a file setting to the environment variable it provides. Critical settings
+// are only read at startup, so changing them in the file requires a restart.
+type binding struct {
+	env      string
+	key      string
+	critical bool
+	secret   bool
+	kind     string // duration, url, port or a set of allowed values separated by '|'
+	field    func(*File) *string
+}
+
+var bindings = []binding{
+	{env: "PORT", key: "server.port", critical: true, kind: "port", field: func(f *File) *string { return &f.Server.Port }},
+	{env: "ENVIRONMENT", key: "server.environment", critical: true, kind: "development|staging|production", field: func(f *File) *string { return &f.Server.Environment }},
+	{env: "LOG_LEVEL", key: "server.log_level", kind: "debug|info|warn|error", field: func(f *File) *string { return &f.Server.LogLevel }},
+	{env: "DATABASE_PATH", key: "database.path", critical: true, field: func(f *File) *string { return &f.Database.Path }},
+	{env: "TOKEN_DURATION", key: "auth.token_duration", critical: true, kind: "duration", field: func(f *File) *string { return &f.Auth.TokenDuration }},
+	{env: "PASETO_KEY_ROTATION", key: "auth.key_rotation", critical: true, kind: "duration", field: func(f *File) *string { return &f.Auth.KeyRotation }},
+	{env: "KUBECONFIG", key: "kubernetes.kubeconfig", critical: true, field: func(f *File) *string { return &f.Kubernetes.Kubeconfig }},
+	{env: "K8S_CACHE_TTL", key: "kubernetes.cache_ttl", critical: true, kind: "duration", field: func(f *File) *string { return &f.Kubernetes.CacheTTL }},
+	{env: "GITOPS_BASE_REPO_URL", key: "gitops.repo_url", critical: true, field: func(f *File) *string { return &f.GitOps.RepoURL }},
+	{env: "GITOPS_LOCAL_PATH", key: "gitops.local_path", critical: true, field: func(f *File) *string { return &f.GitOps.LocalPath }},
+	{env: "GITOPS_BRANCH", key: "gitops.branch", critical: true, field: func(f *File) *string { return &f.GitOps.Branch }},
+	{env: "GITOPS_USERNAME", key: "gitops.username", critical: true, field: func(f *File) *string { return &f.GitOps.Username }},
+	{env: "GITOPS_TOKEN", key: "gitops.token", critical: true, secret: true, field: func(f *File) *string { return &f.GitOps.Token }},
+	{env: "GITOPS_WEBHOOK_SECRET", key: "gitops.webhook_secret", critical: true, secret: true, field: func(f *File) *string { return &f.GitOps.WebhookSecret }},
+	{env: "GITOPS_SYNC_INTERVAL", key: "gitops.sync_interval", critical: true, kind: "duration", field: func(f *File) *string { return &f.GitOps.SyncInterval }},
+	{env: "PROMETHEUS_URL", key: "observability.prometheus_url", kind: "url", field: func(f *File) *string { return &f.Observability.PrometheusURL }},
+	{env: "LOKI_URL", key: "observability.loki_url", kind: "url", field: func(f *File) *string { return &f.Observability.LokiURL }},
+	{env: "TRACING_PROVIDER", key: "observability.tracing_provider", kind: "tempo|jaeger", field: func(f *File) *string { return &f.Observability.TracingProvider }},
+	{env: "TRACING_URL", key: "observability.tracing_url", kind: "url", field: func(f *File) *string { return &f.Observability.TracingURL }},
+}
+
+var featureName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// ValidationError lists every problem found in a config file
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config file: " + strings.Join(e.Problems, "; ")
+}
+
+// ReadFile parses and validates a YAML or JSON config file. Unknown keys are rejected.
+func ReadFile(path string) (*File, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q (use .yaml, .yml or .json)", filepath.Ext(path))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	file := &File{}
+	if err := yaml.UnmarshalStrict(data, file); err != nil {
+		return nil, &ValidationError{Problems: []string{err.Error()}}
+	}
+	if err := file.Validate(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Validate checks every setting against the schema
+func (f *File) Validate() error {
+	var problems []string
+	for _, b := range bindings {
+		value := *b.field(f)
+		if value == "" {
+			continue
+		}
+		if problem := checkValue(b.kind, value); problem != "" {
+			problems = append(problems, b.key+": "+problem)
+		}
+	}
+
+	names := map[string]bool{}
+	for i, target := range f.Notifications {
+		prefix := fmt.Sprintf("notifications[%d]", i)
+		switch {
+		case target.Name == "":
+			problems = append(problems, prefix+".name: required")
+		case names[target.Name]:
+			problems = append(problems, prefix+".name: duplicate name "+strconv.Quote(target.Name))
+		}
+		names[target.Name] = true
+		if problem := checkValue(strings.Join(NotificationTypes, "|"), target.Type); problem != "" {
+			problems = append(problems, prefix+".type: "+problem)
+		}
+		if target.URL == "" {
+			problems = append(problems, prefix+".url: required")
+		} else if problem := checkValue("url", target.URL); problem != "" {
+			problems = append(problems, prefix+".url: "+problem)
+		}
+	}
+
+	for name := range f.Features {
+		if !featureName.MatchString(name) {
+			problems = append(problems, "features."+name+": names are lowercase letters, digits, '.', '_' and '-'")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func checkValue(kind, value string) string {
+	switch kind {
+	case "":
+		return ""
+	case "duration":
+		if _, err := time.ParseDuration(value); err != nil {
+			return "not a duration"
+		}
+	case "url":
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "must be an http or https URL"
+		}
+	case "port":
+		if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+			return "must be a port number"
+		}
+	default:
+		for _, allowed := range strings.Split(kind, "|") {
+			if value == allowed {
+				return ""
+			}
+		}
+		return "must be one of " + strings.ReplaceAll(kind, "|", ", ")
+	}
+	return ""
+}
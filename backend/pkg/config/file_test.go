@@ -0,0 +1,150 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadFileValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denshimon.yaml")
+	writeConfig(t, path, `
+server:
+  port: "99999"
+  log_level: verbose
+observability:
+  prometheus_url: prometheus:9090
+notifications:
+  - name: ops
+    type: pager
+    url: https://hooks.example.com/x
+  - name: ops
+    type: slack
+features:
+  Bad Flag: true
+`)
+	_, err := ReadFile(path)
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	for _, want := range []string{
+		"server.port", "server.log_level", "observability.prometheus_url",
+		"notifications[0].type", "notifications[1].name", "notifications[1].url", "features.Bad Flag",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a problem with %s in %q", want, err)
+		}
+	}
+
+	writeConfig(t, path, "server:\n  prot: \"8080\"\n")
+	if _, err := ReadFile(path); !errors.As(err, &validation) {
+		t.Errorf("expected unknown key to be rejected, got %v", err)
+	}
+
+	if _, err := ReadFile(filepath.Join(t.TempDir(), "denshimon.ini")); err == nil {
+		t.Error("expected unsupported format to be rejected")
+	}
+}
+
+func TestWatcherReload(t *testing.T) {
+	t.Setenv("PORT", "")
+	os.Unsetenv("PORT")
+	t.Setenv("LOG_LEVEL", "")
+	os.Unsetenv("LOG_LEVEL")
+	t.Setenv("PROMETHEUS_URL", "http://from-env:9090") // the environment wins over the file
+	t.Setenv("GITOPS_TOKEN", "")
+	os.Unsetenv("GITOPS_TOKEN")
+
+	path := filepath.Join(t.TempDir(), "denshimon.yaml")
+	writeConfig(t, path, `
+server:
+  port: "8080"
+  log_level: info
+gitops:
+  token: secret-token
+observability:
+  prometheus_url: http://from-file:9090
+notifications:
+  - name: ops
+    type: slack
+    url: https://hooks.slack.com/services/T000/B000/XXXX
+features:
+  canary: true
+`)
+	watcher, err := NewWatcher(path)
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	if os.Getenv("PORT") != "8080" || os.Getenv("GITOPS_TOKEN") != "secret-token" {
+		t.Errorf("file settings not exported: PORT=%q", os.Getenv("PORT"))
+	}
+	if os.Getenv("PROMETHEUS_URL") != "http://from-env:9090" {
+		t.Errorf("environment overridden by file: %q", os.Getenv("PROMETHEUS_URL"))
+	}
+
+	reloaded := 0
+	watcher.OnReload(func(*File) { reloaded++ })
+
+	writeConfig(t, path, `
+server:
+  port: "9090"
+  log_level: debug
+features:
+  canary: false
+`)
+	if err := watcher.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if reloaded != 1 || os.Getenv("LOG_LEVEL") != "debug" || watcher.FeatureEnabled("canary") {
+		t.Errorf("non-critical settings not reloaded: LOG_LEVEL=%q", os.Getenv("LOG_LEVEL"))
+	}
+	if os.Getenv("PORT") != "8080" || os.Getenv("GITOPS_TOKEN") != "secret-token" {
+		t.Errorf("critical settings changed without a restart: PORT=%q", os.Getenv("PORT"))
+	}
+
+	snapshot := Describe(watcher)
+	if strings.Join(snapshot.RestartRequired, ",") != "server.port,gitops.token" {
+		t.Errorf("unexpected restart_required: %v", snapshot.RestartRequired)
+	}
+	if snapshot.Settings["gitops.token"] != redacted {
+		t.Errorf("secret not redacted: %q", snapshot.Settings["gitops.token"])
+	}
+
+	// An invalid file keeps the previous settings
+	writeConfig(t, path, "server:\n  log_level: loud\n")
+	if err := watcher.Reload(); err == nil {
+		t.Fatal("expected invalid reload to fail")
+	}
+	if os.Getenv("LOG_LEVEL") != "debug" || Describe(watcher).LastError == "" {
+		t.Error("invalid reload was applied or not reported")
+	}
+}
+
+func TestDescribeRedactsNotifications(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denshimon.yaml")
+	writeConfig(t, path, `
+notifications:
+  - name: ops
+    type: webhook
+    url: https://alerts.example.com/hook?token=abc
+    secret: signing-secret
+`)
+	watcher, err := NewWatcher(path)
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	target := Describe(watcher).Notifications[0]
+	if target.URL != "https://alerts.example.com/"+redacted || target.Secret != redacted {
+		t.Errorf("notification not redacted: %+v", target)
+	}
+}
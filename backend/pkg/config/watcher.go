@@ -0,0 +1,268 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const redacted = "********"
+
+var (
+	fileOnce    sync.Once
+	fileWatcher *Watcher
+)
+
+// Watcher holds the loaded config file and reloads it on SIGHUP or when it changes on disk.
+// File settings are exported to the environment, except where the environment already set them.
+// On reload only non-critical settings are applied; critical changes wait for a restart.
+type Watcher struct {
+	path     string
+	initial  *File           // critical settings stay as loaded at startup
+	external map[string]bool // environment variables set outside the file
+
+	mutex           sync.RWMutex
+	file            *File
+	modTime         time.Time
+	loadedAt        time.Time
+	lastError       error
+	restartRequired []string
+	hooks           []func(*File)
+}
+
+// Snapshot is the effective configuration with secrets redacted
+type Snapshot struct {
+	Source          string               `json:"source,omitempty"`
+	LoadedAt        *time.Time           `json:"loaded_at,omitempty"`
+	LastError       string               `json:"last_error,omitempty"`
+	RestartRequired []string             `json:"restart_required,omitempty"`
+	Settings        map[string]string    `json:"settings"`
+	Notifications   []NotificationTarget `json:"notifications"`
+	Features        map[string]bool      `json:"features"`
+}
+
+// FileWatcher returns the watcher for the file named by CONFIG_FILE, or nil when no
+// config file is used or it could not be loaded at startup
+func FileWatcher() *Watcher {
+	fileOnce.Do(func() {
+		path := os.Getenv("CONFIG_FILE")
+		if path == "" {
+			return
+		}
+		watcher, err := NewWatcher(path)
+		if err != nil {
+			slog.Error("Ignoring config file", "path", path, "error", err)
+			return
+		}
+		fileWatcher = watcher
+	})
+	return fileWatcher
+}
+
+// NewWatcher loads a config file and exports its settings to the environment
+func NewWatcher(path string) (*Watcher, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		path:     path,
+		initial:  file,
+		external: map[string]bool{},
+		file:     file,
+		modTime:  info.ModTime(),
+		loadedAt: time.Now().UTC(),
+	}
+	for _, b := range bindings {
+		if _, ok := os.LookupEnv(b.env); ok {
+			w.external[b.env] = true
+		}
+	}
+	w.apply(file, false)
+	return w, nil
+}
+
+// OnReload registers a function called with the new file after each successful reload
+func (w *Watcher) OnReload(hook func(*File)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.hooks = append(w.hooks, hook)
+}
+
+// Current returns the loaded file
+func (w *Watcher) Current() *File {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.file
+}
+
+// FeatureEnabled reports whether a feature flag is set in the config file
+func (w *Watcher) FeatureEnabled(name string) bool {
+	if w == nil {
+		return false
+	}
+	return w.Current().Features[name]
+}
+
+// Reload re-reads the file. An invalid file is rejected and the previous settings stay in effect.
+func (w *Watcher) Reload() error {
+	info, statErr := os.Stat(w.path)
+	file, err := ReadFile(w.path)
+	if err == nil {
+		err = statErr
+	}
+
+	w.mutex.Lock()
+	if err != nil {
+		w.lastError = err
+		w.mutex.Unlock()
+		slog.Error("Config reload failed, keeping previous settings", "path", w.path, "error", err)
+		return err
+	}
+	w.apply(file, true)
+	restart := w.restartRequired
+	w.file = file
+	w.modTime = info.ModTime()
+	w.loadedAt = time.Now().UTC()
+	w.lastError = nil
+	hooks := append([]func(*File){}, w.hooks...)
+	w.mutex.Unlock()
+
+	if len(restart) > 0 {
+		slog.Warn("Config changes require a restart", "settings", restart)
+	}
+	slog.Info("Config reloaded", "path", w.path)
+	for _, hook := range hooks {
+		hook(file)
+	}
+	return nil
+}
+
+// Start reloads the file on SIGHUP and when its modification time changes, checked every interval
+func (w *Watcher) Start(ctx context.Context, interval time.Duration) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hangup)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangup:
+				w.Reload()
+			case <-ticker.C:
+				info, err := os.Stat(w.path)
+				if err != nil {
+					continue
+				}
+				w.mutex.RLock()
+				changed := !info.ModTime().Equal(w.modTime)
+				w.mutex.RUnlock()
+				if changed {
+					w.Reload()
+				}
+			}
+		}
+	}()
+}
+
+// apply exports file settings to the environment. On reload critical settings are left
+// as loaded at startup and recorded when the file now differs.
+func (w *Watcher) apply(file *File, reload bool) {
+	w.restartRequired = nil
+	for _, b := range bindings {
+		if w.external[b.env] {
+			continue
+		}
+		value := *b.field(file)
+		if reload && b.critical {
+			if value != *b.field(w.initial) {
+				w.restartRequired = append(w.restartRequired, b.key)
+			}
+			continue
+		}
+		if value == "" {
+			os.Unsetenv(b.env)
+		} else {
+			os.Setenv(b.env, value)
+		}
+	}
+}
+
+// Describe returns the effective configuration, read back from the environment so that
+// variables overriding the file are shown. w may be nil when no config file is used.
+func Describe(w *Watcher) *Snapshot {
+	snapshot := &Snapshot{
+		Settings:      map[string]string{},
+		Notifications: []NotificationTarget{},
+		Features:      map[string]bool{},
+	}
+	for _, b := range bindings {
+		value := os.Getenv(b.env)
+		if value != "" && b.secret {
+			value = redacted
+		} else if b.kind == "url" {
+			value = redactUserinfo(value)
+		}
+		snapshot.Settings[b.key] = value
+	}
+	if w == nil {
+		return snapshot
+	}
+
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	loadedAt := w.loadedAt
+	snapshot.Source = w.path
+	snapshot.LoadedAt = &loadedAt
+	if w.lastError != nil {
+		snapshot.LastError = w.lastError.Error()
+	}
+	snapshot.RestartRequired = append(snapshot.RestartRequired, w.restartRequired...)
+	for _, target := range w.file.Notifications {
+		target.URL = redactURL(target.URL)
+		if target.Secret != "" {
+			target.Secret = redacted
+		}
+		snapshot.Notifications = append(snapshot.Notifications, target)
+	}
+	for name, enabled := range w.file.Features {
+		snapshot.Features[name] = enabled
+	}
+	return snapshot
+}
+
+// redactURL keeps the scheme and host; webhook URLs usually embed their credentials in the path
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+	if u.Path == "" && u.RawQuery == "" && u.User == nil {
+		return u.String()
+	}
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
+
+// redactUserinfo hides credentials embedded in a URL
+func redactUserinfo(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	u.User = url.User(redacted)
+	return u.String()
+}
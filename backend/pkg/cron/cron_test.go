@@ -0,0 +1,72 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"foo * * * *",
+	}
+
+	for _, spec := range invalid {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	base := time.Date(2025, time.March, 14, 10, 30, 0, 0, time.UTC) // Friday
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2025, time.March, 14, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, time.March, 14, 10, 45, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2025, time.March, 15, 2, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, time.March, 14, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-wed", time.Date(2025, time.March, 17, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 6-7", time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		{"30 4 1,15 * 5", time.Date(2025, time.March, 15, 4, 30, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		schedule, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.spec, err)
+		}
+		if got := schedule.Next(base); !got.Equal(tt.expected) {
+			t.Errorf("Next(%q) = %v, want %v", tt.spec, got, tt.expected)
+		}
+	}
+}
+
+func TestNextInZone(t *testing.T) {
+	base := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	next, err := NextInZone("0 3 * * *", "America/New_York", base)
+	if err != nil {
+		t.Fatalf("NextInZone failed: %v", err)
+	}
+	if want := time.Date(2025, time.January, 1, 8, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("NextInZone = %v, want %v", next.UTC(), want)
+	}
+
+	if _, err := NextInZone("0 3 * * *", "Not/AZone", base); err == nil {
+		t.Error("expected error for invalid timezone")
+	}
+}
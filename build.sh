@@ -17,6 +17,6 @@ cp -r ../frontend/dist cmd/server/spa
 
 # Build Go binary with embedded SPA
 echo "Building backend with embedded SPA..."
-go build -o denshimon cmd/server/main.go
+go build -tags sqlite_fts5 -o denshimon cmd/server/main.go
 
 echo "Build complete! Single binary: backend/denshimon"
\ No newline at end of file
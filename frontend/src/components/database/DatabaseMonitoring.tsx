@@ -61,7 +61,7 @@ const DatabaseMonitoring: FC = () => {
       try {
         const messageData = typeof lastMessage.data === 'string' ? JSON.parse(lastMessage.data) : lastMessage.data;
         
-        if (messageData.type === 'database_stats') {
+        if (messageData.type === 'database_stats' && messageData.data.connection_id === selectedConnection) {
           setRealTimeStats(messageData.data.stats);
           // setStats is not available, we'll use realTimeStats directly
         } else if (messageData.type === 'database') {
@@ -71,7 +71,7 @@ const DatabaseMonitoring: FC = () => {
         // console.error('Error parsing WebSocket message:', error);
       }
     }
-  }, [lastMessage]);
+  }, [lastMessage, selectedConnection]);
 
 
   const connectedConnections = connections.filter(conn => 
@@ -3,6 +3,7 @@ import type { FC } from 'react';
 import { X, Database, TestTube, CheckCircle, XCircle, Loader } from 'lucide-react';
 import { DatabaseType } from '@/types/database';
 import useDatabaseStore from '@stores/databaseStore';
+import { getDatabaseTypeIcon, getDatabaseTypeLabel, getDefaultPortForDatabaseType } from '@utils/databaseGrid';
 
 interface AddDatabaseConnectionModalProps {
   isOpen: boolean;
@@ -69,12 +70,12 @@ const AddDatabaseConnectionModal: FC<AddDatabaseConnectionModalProps> = ({ isOpe
     setFormData(prev => ({
       ...prev,
       type,
-      port: type === DatabaseType.POSTGRESQL ? 5432 : 0,
-      host: type === DatabaseType.POSTGRESQL ? 'localhost' : '',
-      database: type === DatabaseType.POSTGRESQL ? '' : '',
-      username: type === DatabaseType.POSTGRESQL ? '' : '',
-      password: type === DatabaseType.POSTGRESQL ? '' : '',
-      filePath: type === DatabaseType.SQLITE ? '' : ''
+      port: getDefaultPortForDatabaseType(type),
+      host: type !== DatabaseType.SQLITE ? 'localhost' : '',
+      database: '',
+      username: '',
+      password: '',
+      filePath: ''
     }));
     setHasTestedSuccessfully(false);
   };
@@ -126,6 +127,18 @@ const AddDatabaseConnectionModal: FC<AddDatabaseConnectionModalProps> = ({ isOpe
              formData.password.trim() &&
              formData.port > 0;
     }
+
+    if (formData.type === DatabaseType.MYSQL) {
+      return formData.host.trim() &&
+             formData.database.trim() &&
+             formData.username.trim() &&
+             formData.port > 0;
+    }
+
+    // MongoDB and Redis deployments may run without authentication
+    if (formData.type === DatabaseType.MONGODB || formData.type === DatabaseType.REDIS) {
+      return formData.host.trim() && formData.port > 0;
+    }
     
     if (formData.type === DatabaseType.SQLITE) {
       return formData.filePath.trim();
@@ -183,7 +196,7 @@ const AddDatabaseConnectionModal: FC<AddDatabaseConnectionModalProps> = ({ isOpe
                       : 'border-white hover:bg-white/10'
                   }`}
                 >
-                  {type === DatabaseType.POSTGRESQL ? '🐘 PostgreSQL' : '📁 SQLite'}
+                  {getDatabaseTypeIcon(type)} {getDatabaseTypeLabel(type)}
                 </button>
               )) : (
                 <>
@@ -214,8 +227,8 @@ const AddDatabaseConnectionModal: FC<AddDatabaseConnectionModalProps> = ({ isOpe
             </div>
           </div>
 
-          {/* PostgreSQL Fields */}
-          {formData.type === DatabaseType.POSTGRESQL && (
+          {/* Server Fields */}
+          {formData.type !== DatabaseType.SQLITE && (
             <>
               <div className="grid grid-cols-2 gap-4">
                 <div className="space-y-2">
@@ -236,7 +249,7 @@ const AddDatabaseConnectionModal: FC<AddDatabaseConnectionModalProps> = ({ isOpe
                     value={formData.port}
                     onChange={(e) => handleInputChange('port', parseInt(e.target.value) || 0)}
                     className="w-full bg-black border border-white px-3 py-2 font-mono focus:outline-none focus:border-green-400"
-                    placeholder="5432"
+                    placeholder={String(getDefaultPortForDatabaseType(formData.type))}
                     min="1"
                     max="65535"
                     required
@@ -245,38 +258,38 @@ const AddDatabaseConnectionModal: FC<AddDatabaseConnectionModalProps> = ({ isOpe
               </div>
 
               <div className="space-y-2">
-                <label className="block text-sm font-mono">DATABASE NAME *</label>
+                <label className="block text-sm font-mono">{formData.type === DatabaseType.REDIS ? 'DATABASE NUMBER' : 'DATABASE NAME'}{formData.type !== DatabaseType.MONGODB && formData.type !== DatabaseType.REDIS && ' *'}</label>
                 <input
                   type="text"
                   value={formData.database}
                   onChange={(e) => handleInputChange('database', e.target.value)}
                   className="w-full bg-black border border-white px-3 py-2 font-mono focus:outline-none focus:border-green-400"
-                  placeholder="myapp"
-                  required
+                  placeholder={formData.type === DatabaseType.REDIS ? '0' : 'myapp'}
+                  required={formData.type !== DatabaseType.MONGODB && formData.type !== DatabaseType.REDIS}
                 />
               </div>
 
               <div className="grid grid-cols-2 gap-4">
                 <div className="space-y-2">
-                  <label className="block text-sm font-mono">USERNAME *</label>
+                  <label className="block text-sm font-mono">USERNAME{formData.type !== DatabaseType.MONGODB && formData.type !== DatabaseType.REDIS && ' *'}</label>
                   <input
                     type="text"
                     value={formData.username}
                     onChange={(e) => handleInputChange('username', e.target.value)}
                     className="w-full bg-black border border-white px-3 py-2 font-mono focus:outline-none focus:border-green-400"
-                    placeholder="postgres"
-                    required
+                    placeholder={formData.type === DatabaseType.POSTGRESQL ? 'postgres' : formData.type === DatabaseType.MYSQL ? 'root' : formData.type === DatabaseType.REDIS ? 'default' : 'admin'}
+                    required={formData.type !== DatabaseType.MONGODB && formData.type !== DatabaseType.REDIS}
                   />
                 </div>
                 <div className="space-y-2">
-                  <label className="block text-sm font-mono">PASSWORD *</label>
+                  <label className="block text-sm font-mono">PASSWORD{formData.type === DatabaseType.POSTGRESQL && ' *'}</label>
                   <input
                     type="password"
                     value={formData.password}
                     onChange={(e) => handleInputChange('password', e.target.value)}
                     className="w-full bg-black border border-white px-3 py-2 font-mono focus:outline-none focus:border-green-400"
                     placeholder="••••••••"
-                    required
+                    required={formData.type === DatabaseType.POSTGRESQL}
                   />
                 </div>
               </div>
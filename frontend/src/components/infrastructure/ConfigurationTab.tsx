@@ -156,7 +156,7 @@ const ConfigurationTab = () => {
 
       if (response.ok) {
         const result = await response.json();
-        console.log(`Applied ${result.successes} deployments, ${result.failures} failed`);
+        console.log(`Applied ${result.successes} deployments, ${result.failures} failed, ${result.skipped ?? 0} skipped`);
         
         // Refresh pending deployments
         await fetchPendingDeployments();
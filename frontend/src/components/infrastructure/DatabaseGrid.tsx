@@ -169,7 +169,7 @@ const DatabaseGrid: FC<DatabaseGridProps> = ({ onAddConnection, onUseConnection
 
                 {/* Connection Details */}
                 <div className="space-y-1 mb-4 text-xs font-mono opacity-80">
-                  {connection.type === DatabaseType.POSTGRESQL ? (
+                  {connection.type !== DatabaseType.SQLITE ? (
                     <>
                       <div>HOST: {connection.host}:{connection.port}</div>
                       <div>DATABASE: {connection.database}</div>
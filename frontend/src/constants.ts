@@ -60,7 +60,19 @@ export enum TerminalMessageType {
   DATA = 'data',
   RESIZE = 'resize',
   CLOSE = 'close',
-  ERROR = Status.ERROR
+  ERROR = Status.ERROR,
+  AUTH = 'auth',
+  // Sharing a session with other users
+  SHARE = 'share',
+  SHARED = 'shared',
+  UNSHARE = 'unshare',
+  UNSHARED = 'unshared',
+  HANDOFF = 'handoff',
+  PRESENCE = 'presence',
+  REQUEST_WRITE = 'request_write',
+  WRITE_REQUEST = 'write_request',
+  RELEASE = 'release',
+  CLOSED = 'closed'
 }
 
 // NotificationSeverity removed - use Status enum instead
@@ -705,6 +717,12 @@ export const API_BASE_PATHS = {
   BACKUP: '/api/backup',
   INFRASTRUCTURE: '/api/infrastructure',
   CERTIFICATES: '/api/certificates',
+  ALERTS: '/api/alerts',
+  INCIDENTS: '/api/incidents',
+  STATUS: '/api/status',
+  WEBHOOKS: '/api/webhooks',
+  PREFERENCES: '/api/preferences',
+  POLICY: '/api/policy',
   SECRETS: '/api/secrets',
   WEBSOCKET: '/ws'
 } as const;
@@ -718,6 +736,10 @@ export const API_ENDPOINTS = {
     USERS: `${API_BASE_PATHS.AUTH}/users`,
     USER: (id: string) => `${API_BASE_PATHS.AUTH}/users/${id}`
   },
+  PREFERENCES: API_BASE_PATHS.PREFERENCES,
+  POLICY: {
+    DECISIONS: `${API_BASE_PATHS.POLICY}/decisions`
+  },
   METRICS: {
     CLUSTER: `${API_BASE_PATHS.METRICS}/cluster`,
     NODES: `${API_BASE_PATHS.METRICS}/nodes`,
@@ -725,6 +747,8 @@ export const API_ENDPOINTS = {
     NAMESPACES: `${API_BASE_PATHS.METRICS}/namespaces`,
     HISTORY: `${API_BASE_PATHS.METRICS}/history`,
     RESOURCES: `${API_BASE_PATHS.METRICS}/resources`,
+    COSTS: `${API_BASE_PATHS.METRICS}/costs`,
+    HOST: `${API_BASE_PATHS.METRICS}/host`,
     HEALTH: `${API_BASE_PATHS.METRICS}/health`
   },
   KUBERNETES: {
@@ -738,6 +762,27 @@ export const API_ENDPOINTS = {
     NODES: `${API_BASE_PATHS.KUBERNETES}/nodes`,
     NAMESPACES: `${API_BASE_PATHS.KUBERNETES}/namespaces`,
     STORAGE: `${API_BASE_PATHS.KUBERNETES}/storage`,
+    NODE_DISKS: `${API_BASE_PATHS.KUBERNETES}/nodes/disks`,
+    NODE_DISKS_CHECK: `${API_BASE_PATHS.KUBERNETES}/nodes/disks/check`,
+    UPGRADES: `${API_BASE_PATHS.KUBERNETES}/upgrades`,
+    DEPRECATED_APIS: `${API_BASE_PATHS.KUBERNETES}/deprecated-apis`,
+    STORAGE_CLASSES: `${API_BASE_PATHS.KUBERNETES}/storageclasses`,
+    STORAGE_CLASS: (name: string) => `${API_BASE_PATHS.KUBERNETES}/storageclasses/${name}`,
+    STORAGE_CLASS_DEFAULT: (name: string) => `${API_BASE_PATHS.KUBERNETES}/storageclasses/${name}/default`,
+    PVC_EXPAND: (name: string) => `${API_BASE_PATHS.KUBERNETES}/pvcs/${name}/expand`,
+    PVC_RESIZE: (name: string) => `${API_BASE_PATHS.KUBERNETES}/pvcs/${name}/resize`,
+    RBAC_CAN_I: `${API_BASE_PATHS.KUBERNETES}/rbac/can-i`,
+    RBAC_WHO_CAN: `${API_BASE_PATHS.KUBERNETES}/rbac/who-can`,
+    RBAC_ROLES: `${API_BASE_PATHS.KUBERNETES}/rbac/roles`,
+    RBAC_BINDINGS: `${API_BASE_PATHS.KUBERNETES}/rbac/bindings`,
+    SECURITY_AUDIT: `${API_BASE_PATHS.KUBERNETES}/security/audit`,
+    KUBECONFIGS: `${API_BASE_PATHS.KUBERNETES}/kubeconfigs`,
+    KUBECONFIG: (username: string) => `${API_BASE_PATHS.KUBERNETES}/kubeconfigs/${username}`,
+    DEBUG_CONNECTIVITY: `${API_BASE_PATHS.KUBERNETES}/debug/connectivity`,
+    POD_DEBUG: (name: string) => `${API_BASE_PATHS.KUBERNETES}/pods/${name}/debug`,
+    POD_EXEC: `${API_BASE_PATHS.KUBERNETES}/pods/exec`,
+    POD_EXEC_SHARED: (id: string) => `${API_BASE_PATHS.KUBERNETES}/pods/exec/shared/${id}`,
+    NODE_SHELL: (name: string) => `${API_BASE_PATHS.KUBERNETES}/nodes/${name}/shell`,
     EVENTS: `${API_BASE_PATHS.KUBERNETES}/events`,
     HEALTH: `${API_BASE_PATHS.KUBERNETES}/health`
   },
@@ -758,18 +803,38 @@ export const API_ENDPOINTS = {
     CREATE: API_BASE_PATHS.DEPLOYMENTS,
     PENDING: `${API_BASE_PATHS.DEPLOYMENTS}/pending`,
     BATCH_APPLY: `${API_BASE_PATHS.DEPLOYMENTS}/batch-apply`,
+    APPROVAL_RULES: `${API_BASE_PATHS.DEPLOYMENTS}/approval-rules`,
+    APPROVAL_RULE: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/approval-rules/${id}`,
+    DEPLOYMENT_APPROVALS: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/${id}/approvals`,
+    DEPLOYMENT_APPROVE: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/${id}/approve`,
+    DEPLOYMENT_REJECT: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/${id}/reject`,
+    DEPLOYMENT_PLAN: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/${id}/plan`,
+    DEPLOYMENT_DEPENDENCIES: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/${id}/dependencies`,
+    DEPLOYMENT_COMPARE: (id: string, withId: string) => `${API_BASE_PATHS.DEPLOYMENTS}/${id}/compare?with=${withId}`,
+    DEPLOYMENT_HEALTH_GATE: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/${id}/health-gate`,
+    DEPLOYMENT_RESTART_SCHEDULE: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/${id}/restart-schedule`,
+    RESTART_SCHEDULES: `${API_BASE_PATHS.DEPLOYMENTS}/restart-schedules`,
+    RESTART_SCHEDULES_PAUSE: `${API_BASE_PATHS.DEPLOYMENTS}/restart-schedules/pause`,
+    RESTART_SCHEDULES_RESUME: `${API_BASE_PATHS.DEPLOYMENTS}/restart-schedules/resume`,
     REGISTRIES: `${API_BASE_PATHS.DEPLOYMENTS}/registries`,
     REGISTRY: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/registries/${id}`,
     REGISTRY_TEST: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/registries/${id}/test`,
+    REGISTRY_PULL_SECRETS: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/registries/${id}/pull-secrets`,
     IMAGES: `${API_BASE_PATHS.DEPLOYMENTS}/images`,
     IMAGES_SEARCH: `${API_BASE_PATHS.DEPLOYMENTS}/images/search`,
     IMAGE_TAGS: (image: string) => `${API_BASE_PATHS.DEPLOYMENTS}/images/${image}/tags`,
+    SBOMS: `${API_BASE_PATHS.DEPLOYMENTS}/sboms`,
+    SBOM: (id: number) => `${API_BASE_PATHS.DEPLOYMENTS}/sboms/${id}`,
+    SBOM_SCAN: `${API_BASE_PATHS.DEPLOYMENTS}/sboms/scan`,
+    SBOM_PACKAGES: `${API_BASE_PATHS.DEPLOYMENTS}/sboms/packages`,
+    SBOM_LICENSES: `${API_BASE_PATHS.DEPLOYMENTS}/sboms/licenses`,
     DEPLOYMENTS: API_BASE_PATHS.DEPLOYMENTS,
     DEPLOYMENT: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/${id}`,
     DEPLOYMENT_SCALE: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/${id}/scale`,
     DEPLOYMENT_RESTART: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/${id}/restart`,
     DEPLOYMENT_PODS: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/${id}/pods`,
     DEPLOYMENT_HISTORY: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/${id}/history`,
+    DEPLOYMENT_PROVENANCE: (id: string) => `${API_BASE_PATHS.DEPLOYMENTS}/${id}/provenance`,
     NODES: `${API_BASE_PATHS.DEPLOYMENTS}/nodes`
   },
   DATABASES: {
@@ -782,7 +847,14 @@ export const API_ENDPOINTS = {
     DATABASES: (id: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/databases`,
     TABLES: (id: string, database: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/databases/${database}/tables`,
     COLUMNS: (id: string, database: string, table: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/databases/${database}/tables/${table}/columns`,
+    TABLE_SCHEMA: (id: string, database: string, table: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/databases/${database}/tables/${table}/schema`,
+    RELATIONSHIPS: (id: string, database: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/databases/${database}/relationships`,
     QUERY: (id: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/query`,
+    EXPLAIN: (id: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/explain`,
+    SLOW_QUERIES: (id: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/slow-queries`,
+    KEYS: (id: string, database: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/databases/${database}/keys`,
+    KEY_VALUE: (id: string, database: string, key: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/databases/${database}/keys/value?key=${encodeURIComponent(key)}`,
+    FLUSH: (id: string, database: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/databases/${database}/flush`,
     TABLE_DATA: (id: string, table: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/tables/${table}/data`,
     ROW_UPDATE: (id: string, table: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/tables/${table}/rows`,
     ROW_DELETE: (id: string, table: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/tables/${table}/rows`,
@@ -790,7 +862,11 @@ export const API_ENDPOINTS = {
     STATS: (id: string) => `${API_BASE_PATHS.DATABASES}/connections/${id}/stats`,
     TYPES: `${API_BASE_PATHS.DATABASES}/types`,
     SAVED_QUERIES: `${API_BASE_PATHS.DATABASES}/saved-queries`,
-    SAVED_QUERY: (id: string) => `${API_BASE_PATHS.DATABASES}/saved-queries/${id}`
+    SAVED_QUERY: (id: string) => `${API_BASE_PATHS.DATABASES}/saved-queries/${id}`,
+    JOBS: `${API_BASE_PATHS.DATABASES}/jobs`,
+    JOB: (id: string) => `${API_BASE_PATHS.DATABASES}/jobs/${id}`,
+    JOB_RUN: (id: string) => `${API_BASE_PATHS.DATABASES}/jobs/${id}/run`,
+    JOB_RUNS: (id: string, limit = 20) => `${API_BASE_PATHS.DATABASES}/jobs/${id}/runs?limit=${limit}`
   },
   GITEA: {
     BASE: API_BASE_PATHS.GITEA,
@@ -802,6 +878,8 @@ export const API_ENDPOINTS = {
     RELEASES: (owner: string, repo: string) => `${API_BASE_PATHS.GITEA}/repositories/${owner}/${repo}/releases`,
     ACTIONS_RUNS: (owner: string, repo: string) => `${API_BASE_PATHS.GITEA}/repositories/${owner}/${repo}/actions/runs`,
     DEPLOY: (owner: string, repo: string) => `${API_BASE_PATHS.GITEA}/repositories/${owner}/${repo}/deploy`,
+    BUILD: (owner: string, repo: string, workflow: string) => `${API_BASE_PATHS.GITEA}/repositories/${owner}/${repo}/actions/workflows/${workflow}/build`,
+    BUILD_STATUS: (id: string) => `${API_BASE_PATHS.GITEA}/builds/${id}`,
     WEBHOOK: `${API_BASE_PATHS.GITEA}/webhook`,
     // Enhanced Gitea Actions Integration
     ACTIONS: `${API_BASE_PATHS.GITEA}/actions`,
@@ -846,6 +924,7 @@ export const API_ENDPOINTS = {
     // Manifest and Template Management
     MANIFESTS_GENERATE: `${API_BASE_PATHS.GITOPS}/manifests/generate`,
     MANIFESTS_VALIDATE: `${API_BASE_PATHS.GITOPS}/manifests/validate`,
+    MANIFESTS_LINT: `${API_BASE_PATHS.GITOPS}/manifests/lint`,
     MANIFESTS_TYPES: `${API_BASE_PATHS.GITOPS}/manifests/types`,
     TEMPLATES: `${API_BASE_PATHS.GITOPS}/templates`,
     TEMPLATE: (id: string) => `${API_BASE_PATHS.GITOPS}/templates/${id}`,
@@ -859,6 +938,7 @@ export const API_ENDPOINTS = {
     SYNC_STATUS: `${API_BASE_PATHS.GITOPS}/sync/status`,
     SYNC_START: `${API_BASE_PATHS.GITOPS}/sync/start`,
     SYNC_FORCE: `${API_BASE_PATHS.GITOPS}/sync/force`,
+    SYNC_RUNS: `${API_BASE_PATHS.GITOPS}/sync/runs`,
   },
   BACKUP: {
     BASE: API_BASE_PATHS.BACKUP,
@@ -873,8 +953,11 @@ export const API_ENDPOINTS = {
     JOB_CANCEL: (id: string) => `${API_BASE_PATHS.BACKUP}/jobs/${id}/cancel`,
     VERIFY: (id: string) => `${API_BASE_PATHS.BACKUP}/backups/${id}/verify`,
     RECOVERY: (id: string) => `${API_BASE_PATHS.BACKUP}/backups/${id}/recover`,
+    PREVIEW: (id: string) => `${API_BASE_PATHS.BACKUP}/history/${id}/preview`,
     DELETE: (id: string) => `${API_BASE_PATHS.BACKUP}/backups/${id}`,
-    SCHEDULE: (id: string) => `${API_BASE_PATHS.BACKUP}/jobs/${id}/schedule`
+    SCHEDULE: (id: string) => `${API_BASE_PATHS.BACKUP}/jobs/${id}/schedule`,
+    RETENTION: (id: string) => `${API_BASE_PATHS.BACKUP}/jobs/${id}/retention`,
+    PRUNE: (id: string) => `${API_BASE_PATHS.BACKUP}/jobs/${id}/prune`
   },
   INFRASTRUCTURE: {
     BASE: API_BASE_PATHS.INFRASTRUCTURE,
@@ -882,7 +965,32 @@ export const API_ENDPOINTS = {
     STATUS: `${API_BASE_PATHS.INFRASTRUCTURE}/status`,
     ALERTS: `${API_BASE_PATHS.INFRASTRUCTURE}/alerts`,
     ALERT_ACKNOWLEDGE: (alertId: string) => `${API_BASE_PATHS.INFRASTRUCTURE}/alerts/${alertId}/acknowledge`,
-    REFRESH: `${API_BASE_PATHS.INFRASTRUCTURE}/refresh`
+    REFRESH: `${API_BASE_PATHS.INFRASTRUCTURE}/refresh`,
+    SERVICE_OVERRIDES: `${API_BASE_PATHS.INFRASTRUCTURE}/services/overrides`,
+    SERVICE_OVERRIDE: (namespace: string, name: string) => `${API_BASE_PATHS.INFRASTRUCTURE}/services/overrides/${namespace}/${name}`
+  },
+  ALERTS: {
+    LIST: API_BASE_PATHS.ALERTS,
+    ACKNOWLEDGE: (source: string, id: string) => `${API_BASE_PATHS.ALERTS}/${source}/${id}/acknowledge`,
+    SILENCES: `${API_BASE_PATHS.ALERTS}/silences`,
+    SILENCE: (id: string) => `${API_BASE_PATHS.ALERTS}/silences/${id}`,
+    ESCALATIONS: `${API_BASE_PATHS.ALERTS}/escalations`,
+    ESCALATION: (id: string) => `${API_BASE_PATHS.ALERTS}/escalations/${id}`
+  },
+  INCIDENTS: {
+    LIST: API_BASE_PATHS.INCIDENTS,
+    INCIDENT: (id: string) => `${API_BASE_PATHS.INCIDENTS}/${id}`,
+    UPDATES: (id: string) => `${API_BASE_PATHS.INCIDENTS}/${id}/updates`,
+    ALERTS: (id: string) => `${API_BASE_PATHS.INCIDENTS}/${id}/alerts`,
+    ALERT: (id: string, dedupKey: string) => `${API_BASE_PATHS.INCIDENTS}/${id}/alerts/${dedupKey}`,
+    STATUS_PAGE: API_BASE_PATHS.STATUS
+  },
+  WEBHOOKS: {
+    LIST: API_BASE_PATHS.WEBHOOKS,
+    EVENTS: `${API_BASE_PATHS.WEBHOOKS}/events`,
+    WEBHOOK: (id: string) => `${API_BASE_PATHS.WEBHOOKS}/${id}`,
+    TEST: (id: string) => `${API_BASE_PATHS.WEBHOOKS}/${id}/test`,
+    DELIVERIES: (id: string) => `${API_BASE_PATHS.WEBHOOKS}/${id}/deliveries`
   },
   CERTIFICATES: {
     BASE: API_BASE_PATHS.CERTIFICATES,
@@ -902,9 +1010,15 @@ export const API_ENDPOINTS = {
     STATUS: `${API_BASE_PATHS.SECRETS}/status`,
     UPDATE: (key: string) => `${API_BASE_PATHS.SECRETS}/${key}`
   },
+  GRAPHQL: {
+    QUERY: '/api/graphql',
+    SCHEMA: '/api/graphql/schema'
+  },
+  OPENAPI: '/api/openapi.json',
   WEBSOCKET: {
     BASE: API_BASE_PATHS.WEBSOCKET,
-    DEFAULT_URL: 'ws://localhost:5173/ws' // Vite dev server with proxy
+    DEFAULT_URL: 'ws://localhost:5173/ws', // Vite dev server with proxy
+    SNAPSHOT: (channel: string) => `/api/ws/snapshot/${channel}`
   }
 } as const;
 
@@ -920,6 +1034,8 @@ export enum WebSocketEventType {
   EVENTS = 'events',
   WORKFLOWS = 'workflows',
   DEPLOYMENTS = 'deployments',
+  STATEFULSETS = 'statefulsets',
+  DAEMONSETS = 'daemonsets',
   ALERTS = 'alerts',
   GITEA_WEBHOOK = 'gitea_webhook',
   GITHUB_WEBHOOK = 'github_webhook',
@@ -931,7 +1047,9 @@ export enum WebSocketEventType {
   DEPLOYMENT_STATUS = 'deployment_status',
   // Service Health events
   SERVICE_HEALTH = 'service_health',
-  SERVICE_HEALTH_STATS = 'service_health_stats'
+  SERVICE_HEALTH_STATS = 'service_health_stats',
+  // Metrics of the host itself
+  HOST = 'host'
 }
 
 // GITEA ENUMS
@@ -1138,6 +1256,7 @@ export enum DeploymentStatus {
   RUNNING = 'running',
   FAILED = 'failed',
   APPLY_FAILED = 'apply_failed',     // GitOps: apply failed
+  REJECTED = 'rejected',             // GitOps: pending change rejected by an approver
   UPDATING = 'updating',
   TERMINATING = 'terminating'
 }
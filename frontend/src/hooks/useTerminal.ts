@@ -1,10 +1,11 @@
 import { useState, useEffect, useRef, useCallback } from 'react';
-import { TerminalMessageType, StorageKey } from '@constants';
+import { TerminalMessageType, StorageKey, API_ENDPOINTS } from '@constants';
 import { TerminalData } from '@/types';
+import { TerminalPresence, TerminalShareLink, TerminalShareMode } from '@/types/liveTerminal';
 
 export interface TerminalMessage {
   type: TerminalMessageType;
-  data: string | TerminalData | { rows: number; cols: number };
+  data?: string | TerminalData | { rows: number; cols: number } | Record<string, unknown>;
 }
 
 export interface TerminalOptions {
@@ -12,6 +13,8 @@ export interface TerminalOptions {
   pod: string;
   container?: string;
   command?: string;
+  // Joins a session another user shared instead of opening a new one
+  shared?: { id: string; token: string };
 }
 
 export interface UseTerminalReturn {
@@ -24,6 +27,15 @@ export interface UseTerminalReturn {
   sendInput: (input: string) => void;
   resize: (rows: number, cols: number) => void;
   clear: () => void;
+  // Sharing: the owner shares and hands writing over; viewers ask for it and give it back
+  shareLink: TerminalShareLink | null;
+  presence: TerminalPresence | null;
+  writeRequests: string[];
+  share: (mode: TerminalShareMode) => void;
+  unshare: () => void;
+  handoff: (user: string) => void;
+  requestWrite: () => void;
+  releaseWrite: () => void;
 }
 
 export const useTerminal = (): UseTerminalReturn => {
@@ -31,6 +43,9 @@ export const useTerminal = (): UseTerminalReturn => {
   const [isConnecting, setIsConnecting] = useState(false);
   const [output, setOutput] = useState('');
   const [error, setError] = useState<string | null>(null);
+  const [shareLink, setShareLink] = useState<TerminalShareLink | null>(null);
+  const [presence, setPresence] = useState<TerminalPresence | null>(null);
+  const [writeRequests, setWriteRequests] = useState<string[]>([]);
   const wsRef = useRef<WebSocket | null>(null);
   const reconnectAttempts = useRef(0);
   const maxReconnectAttempts = 3;
@@ -53,14 +68,14 @@ export const useTerminal = (): UseTerminalReturn => {
     // Build WebSocket URL
     const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
     const host = window.location.host;
-    const params = new URLSearchParams({
-      namespace: options.namespace,
-      pod: options.pod,
-      ...(options.container && { container: options.container }),
-      ...(options.command && { command: options.command }),
-    });
-
-    const wsUrl = `${protocol}//${host}/api/k8s/pods/exec?${params}`;
+    const wsUrl = options.shared
+      ? `${protocol}//${host}${API_ENDPOINTS.KUBERNETES.POD_EXEC_SHARED(options.shared.id)}?${new URLSearchParams({ token: options.shared.token })}`
+      : `${protocol}//${host}${API_ENDPOINTS.KUBERNETES.POD_EXEC}?${new URLSearchParams({
+        namespace: options.namespace,
+        pod: options.pod,
+        ...(options.container && { container: options.container }),
+        ...(options.command && { command: options.command }),
+      })}`;
 
     try {
       const ws = new WebSocket(wsUrl);
@@ -74,7 +89,7 @@ export const useTerminal = (): UseTerminalReturn => {
         
         // Send authentication
         ws.send(JSON.stringify({
-          type: 'auth',
+          type: TerminalMessageType.AUTH,
           data: { token }
         }));
 
@@ -93,6 +108,29 @@ export const useTerminal = (): UseTerminalReturn => {
             case TerminalMessageType.ERROR:
               setError(message.data as string);
               break;
+            case TerminalMessageType.SHARED:
+              setShareLink(message.data as unknown as TerminalShareLink);
+              break;
+            case TerminalMessageType.UNSHARED:
+              setShareLink(null);
+              setPresence(null);
+              setWriteRequests([]);
+              break;
+            case TerminalMessageType.PRESENCE: {
+              const current = message.data as unknown as TerminalPresence;
+              setPresence(current);
+              setWriteRequests(prev => prev.filter(user => current.participants.some(p => p.user === user) && user !== current.writer));
+              break;
+            }
+            case TerminalMessageType.WRITE_REQUEST: {
+              const { user } = message.data as { user: string };
+              setWriteRequests(prev => prev.includes(user) ? prev : [...prev, user]);
+              break;
+            }
+            case TerminalMessageType.CLOSED:
+              setOutput(prev => prev + `\r\n${message.data}\r\n`);
+              setPresence(null);
+              break;
             default:
               // console.warn('Unknown terminal message type:', message.type);
           }
@@ -167,6 +205,33 @@ export const useTerminal = (): UseTerminalReturn => {
     setError(null);
   }, []);
 
+  const send = useCallback((message: TerminalMessage) => {
+    if (wsRef.current && isConnected) {
+      wsRef.current.send(JSON.stringify(message));
+    }
+  }, [isConnected]);
+
+  const share = useCallback((mode: TerminalShareMode) => {
+    send({ type: TerminalMessageType.SHARE, data: { mode } });
+  }, [send]);
+
+  const unshare = useCallback(() => {
+    send({ type: TerminalMessageType.UNSHARE });
+  }, [send]);
+
+  // Hands writing to a viewer; an empty user takes it back
+  const handoff = useCallback((user: string) => {
+    send({ type: TerminalMessageType.HANDOFF, data: { user } });
+  }, [send]);
+
+  const requestWrite = useCallback(() => {
+    send({ type: TerminalMessageType.REQUEST_WRITE });
+  }, [send]);
+
+  const releaseWrite = useCallback(() => {
+    send({ type: TerminalMessageType.RELEASE });
+  }, [send]);
+
   // Cleanup on unmount
   useEffect(() => {
     return () => {
@@ -186,5 +251,13 @@ export const useTerminal = (): UseTerminalReturn => {
     sendInput,
     resize,
     clear,
+    shareLink,
+    presence,
+    writeRequests,
+    share,
+    unshare,
+    handoff,
+    requestWrite,
+    releaseWrite,
   };
 };
\ No newline at end of file
@@ -52,7 +52,7 @@ class ApiService {
 
       if (!response.ok) {
         throw new ApiError(
-          data.error || `HTTP ${response.status}: ${response.statusText}`,
+          data.error || data.errors?.[0]?.message || `HTTP ${response.status}: ${response.statusText}`,
           response.status,
           response
         );
@@ -0,0 +1,41 @@
+/**
+ * GraphQL client for the backend /api/graphql endpoint
+ * Fetches the pods, deployments, metrics, GitOps applications and alerts a view needs in one request
+ */
+
+import { API_ENDPOINTS } from '@constants';
+import { apiService } from '@services/api';
+
+export interface GraphQLError {
+  message: string;
+  path?: (string | number)[];
+  locations?: { line: number; column: number }[];
+}
+
+export interface GraphQLResult<T> {
+  data: T | null;
+  // Fields that failed are null in data and listed here; the other fields are still returned
+  errors: GraphQLError[];
+}
+
+interface GraphQLResponse<T> {
+  data?: T | null;
+  errors?: GraphQLError[];
+}
+
+/**
+ * Runs a query such as `{ pods(namespace: "prod") { name status } alerts { id severity } }`.
+ * Invalid queries throw an ApiError with the first error message.
+ */
+export const graphqlQuery = async <T>(
+  query: string,
+  variables?: Record<string, unknown>
+): Promise<GraphQLResult<T>> => {
+  const response = (await apiService.post<T>(API_ENDPOINTS.GRAPHQL.QUERY, { query, variables })) as unknown as GraphQLResponse<T>;
+  return {
+    data: response.data ?? null,
+    errors: response.errors ?? [],
+  };
+};
+
+export default graphqlQuery;
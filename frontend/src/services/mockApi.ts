@@ -173,14 +173,16 @@ export const setupMockApi = () => {
       const requestBody = await (init?.body ? JSON.parse(init.body.toString()) : {});
       const deploymentIds = requestBody.deployment_ids || [];
       
-      // Simulate batch apply with unified mock data
-      const results: Record<string, any> = {};
+      // Simulate batch apply with unified mock data; mock deployments have no dependencies
+      const results: Array<{ deployment_id: string; status: string; error?: string }> = [];
       let successes = 0;
       let failures = 0;
       
       deploymentIds.forEach((id: string) => {
         const result = unifiedMockData.applyPendingDeployment(id);
-        results[id] = result.success ? null : new Error(result.error);
+        results.push(result.success
+          ? { deployment_id: id, status: 'applied' }
+          : { deployment_id: id, status: 'failed', error: result.error });
         if (result.success) successes++;
         else failures++;
       });
@@ -189,6 +191,7 @@ export const setupMockApi = () => {
         status: 'completed',
         successes,
         failures,
+        skipped: 0,
         results
       }), {
         status: failures > 0 ? 206 : 200, // Partial content if any failures
@@ -1,10 +1,30 @@
-import { WebSocketEventType, Status, CircuitBreakerStatus, SERVICE_IDS, WebSocketState } from '@constants';
+import { WebSocketEventType, Status, CircuitBreakerStatus, SERVICE_IDS, WebSocketState, API_ENDPOINTS } from '@constants';
 import { WebSocketCallback } from '@/types';
+import { apiService } from '@services/api';
 
 export interface WebSocketMessage {
   type: string;
   data: Record<string, unknown>;
   timestamp?: string;
+  // Collection messages (pods, events, workloads) name their list field; delta messages
+  // carry the objects changed since the previous message and the keys of removed ones
+  collection?: string;
+  delta?: boolean;
+  // Broadcasts are numbered so a reconnecting client can resume after the last one it saw
+  seq?: number;
+}
+
+interface CollectionDelta {
+  upserted: Record<string, unknown>[];
+  removed: string[];
+  timestamp: string;
+}
+
+interface ChannelSnapshot {
+  channel: string;
+  epoch: string;
+  seq: number;
+  messages: WebSocketMessage[];
 }
 
 
@@ -13,6 +33,9 @@ export interface WebSocketOptions {
   reconnectInterval?: number;
   maxReconnectAttempts?: number;
   debug?: boolean;
+  // Receive collections as deltas and queued messages as batch frames
+  deltas?: boolean;
+  batch?: boolean;
 }
 
 export interface WebSocketSubscription {
@@ -30,12 +53,22 @@ export class DenshimonWebSocket {
   private connectionState: WebSocketState = WebSocketState.DISCONNECTED;
   private heartbeatInterval: number | null = null;
   private lastHeartbeat = 0;
+  private namespaces: string[] = [];
+  // Latest objects of each collection by namespace/name, to apply deltas to
+  private collections: Map<string, Map<string, Record<string, unknown>>> = new Map();
+  // Resume cursor: the server epoch and the seq of the latest message received, overall and
+  // per type
+  private epoch: string | null = null;
+  private lastSeq = 0;
+  private typeSeq: Map<string, number> = new Map();
 
   constructor(options: WebSocketOptions) {
     this.options = {
       reconnectInterval: 3000,
       maxReconnectAttempts: 10,
       debug: false,
+      deltas: false,
+      batch: false,
       ...options
     };
 
@@ -53,7 +86,15 @@ export class DenshimonWebSocket {
     this.connectionState = WebSocketState.CONNECTING;
 
     try {
-      this.ws = new WebSocket(this.options.url);
+      // The server compresses frames itself when the browser offers permessage-deflate
+      let url = this.options.url;
+      if (this.options.deltas || this.options.batch) {
+        const withOptions = new URL(url, window.location.href);
+        if (this.options.deltas) withOptions.searchParams.set('deltas', 'true');
+        if (this.options.batch) withOptions.searchParams.set('batch', 'true');
+        url = withOptions.toString();
+      }
+      this.ws = new WebSocket(url);
       this.setupEventListeners();
     } catch (error) {
       this.connectionState = WebSocketState.ERROR;
@@ -68,6 +109,8 @@ export class DenshimonWebSocket {
       this.connectionState = WebSocketState.CONNECTED;
       this.reconnectAttempts = 0;
       this.isReconnecting = false;
+      this.collections.clear();
+      this.sendSubscription('subscribe', this.subscribedTypes(), this.epoch !== null);
       this.startHeartbeat();
       this.notifyConnectionStateChange();
     };
@@ -104,6 +147,38 @@ export class DenshimonWebSocket {
       return;
     }
 
+    if (message.type === 'batch') {
+      const messages = (message.data?.messages as WebSocketMessage[] | undefined) ?? [];
+      messages.forEach((batched) => this.handleMessage(batched));
+      return;
+    }
+
+    if (message.type === 'session') {
+      // A new epoch means a first connection or a restarted server; keep the cursor otherwise,
+      // since the missed messages are replayed after it
+      const epoch = message.data?.epoch as string;
+      if (epoch !== this.epoch) {
+        this.epoch = epoch;
+        this.lastSeq = (message.data?.seq as number | undefined) ?? 0;
+        this.typeSeq.clear();
+      }
+      return;
+    }
+
+    if (message.type === 'resync') {
+      this.resync(message.data?.message_type as string);
+      return;
+    }
+
+    if (message.seq) {
+      this.lastSeq = Math.max(this.lastSeq, message.seq);
+      this.typeSeq.set(message.type, Math.max(this.typeSeq.get(message.type) ?? 0, message.seq));
+    }
+
+    if (message.collection) {
+      message = { ...message, data: this.applyCollection(message) };
+    }
+
     // Notify subscribers
     this.subscriptions.forEach((subscription) => {
       if (subscription.type === message.type) {
@@ -115,6 +190,75 @@ export class DenshimonWebSocket {
     });
   }
 
+  // applyCollection merges a snapshot or delta into the cached collection and returns the
+  // full snapshot, so subscribers always receive complete lists
+  private applyCollection(message: WebSocketMessage): Record<string, unknown> {
+    const collection = message.collection as string;
+    const key = (item: Record<string, unknown>) => `${item.namespace ?? ''}/${item.name ?? ''}`;
+
+    let items = this.collections.get(message.type);
+    if (!message.delta || !items) {
+      items = new Map();
+      const snapshot = (message.data?.[collection] as Record<string, unknown>[] | null) ?? [];
+      snapshot.forEach((item) => items!.set(key(item), item));
+      this.collections.set(message.type, items);
+    } else {
+      const delta = message.data as unknown as CollectionDelta;
+      delta.upserted.forEach((item) => items!.set(key(item), item));
+      delta.removed.forEach((removed) => items!.delete(removed));
+    }
+
+    return {
+      [collection]: Array.from(items.values()),
+      timestamp: (message.data?.timestamp as string | undefined) ?? message.timestamp,
+    };
+  }
+
+  // resync reloads a channel from its snapshot when the messages missed while disconnected
+  // are no longer buffered on the server
+  private async resync(type: string): Promise<void> {
+    const query = this.namespaces.map((namespace) => `namespace=${encodeURIComponent(namespace)}`).join('&');
+    try {
+      const response = await apiService.get<ChannelSnapshot>(
+        API_ENDPOINTS.WEBSOCKET.SNAPSHOT(type) + (query ? `?${query}` : '')
+      );
+      const seen = this.typeSeq.get(type) ?? 0;
+      this.collections.delete(type);
+      response.data.messages
+        .filter((message) => !message.seq || message.seq > seen)
+        .forEach((message) => this.handleMessage(message));
+    } catch (error) {
+    }
+  }
+
+  private subscribedTypes(): string[] {
+    const types = new Set<string>();
+    this.subscriptions.forEach((subscription) => {
+      if (subscription.type !== 'connection') {
+        types.add(subscription.type);
+      }
+    });
+    return Array.from(types);
+  }
+
+  private sendSubscription(action: 'subscribe' | 'unsubscribe', types: string[], resume = false): void {
+    if (types.length === 0 || !this.ws || this.ws.readyState !== WebSocket.OPEN) return;
+    this.ws.send(JSON.stringify({
+      type: action,
+      message_types: types,
+      ...(action === 'subscribe' && this.namespaces.length > 0 ? { namespaces: this.namespaces } : {}),
+      ...(resume ? { since: this.lastSeq, epoch: this.epoch } : {}),
+    }));
+  }
+
+  // setNamespaces limits namespaced data (pods, events, workloads) to the given namespaces;
+  // an empty list receives all namespaces
+  public setNamespaces(namespaces: string[]): void {
+    this.namespaces = namespaces;
+    this.collections.clear();
+    this.sendSubscription('subscribe', this.subscribedTypes());
+  }
+
   private scheduleReconnect(): void {
     if (this.isReconnecting) return;
 
@@ -166,18 +310,28 @@ export class DenshimonWebSocket {
 
   public subscribe(type: WebSocketMessage['type'] | 'connection', callback: WebSocketCallback): string {
     const id = `${type}_${Date.now()}_${Math.random().toString(36).substr(2, 9)}`;
-    
+    const isNewType = type !== 'connection' && !this.subscribedTypes().includes(type);
+
     this.subscriptions.set(id, {
       id,
       type: type as WebSocketEventType,
       callback
     });
 
+    if (isNewType) {
+      this.sendSubscription('subscribe', [type]);
+    }
     return id;
   }
 
   public unsubscribe(id: string): void {
+    const subscription = this.subscriptions.get(id);
     this.subscriptions.delete(id);
+
+    if (subscription && subscription.type !== 'connection' && !this.subscribedTypes().includes(subscription.type)) {
+      this.collections.delete(subscription.type);
+      this.sendSubscription('unsubscribe', [subscription.type]);
+    }
   }
 
   public send(message: Partial<WebSocketMessage>): void {
@@ -498,7 +652,9 @@ export const initializeWebSocket = (url: string): DenshimonWebSocket => {
   
   wsInstance = new DenshimonWebSocket({
     url,
-    debug: import.meta.env.DEV
+    debug: import.meta.env.DEV,
+    deltas: true,
+    batch: true
   });
   
   return wsInstance;
@@ -59,15 +59,83 @@ export interface RetentionPolicy {
   minBackups: number;
 }
 
+// Retention dry run: which completed backups pruning keeps and deletes
+export interface RetentionPlan {
+  jobId: string;
+  jobName: string;
+  policy: RetentionPolicy;
+  keep: RetentionEntry[];
+  delete: RetentionEntry[];
+  freedBytes: number;
+}
+
+export interface RetentionEntry {
+  backupId: string;
+  timestamp: string;
+  size: number;
+  location: string;
+  reasons?: RetentionReason[];
+}
+
+export type RetentionReason = 'daily' | 'weekly' | 'monthly' | 'yearly' | 'minimum';
+
+export interface PruneRun {
+  id: string;
+  jobId: string;
+  jobName: string;
+  timestamp: string;
+  deleted: number;
+  freedBytes: number;
+  failed: number;
+  error?: string;
+}
+
 export interface BackupMetadata {
   database?: string;
   tables?: string[];
   volumePath?: string;
   compressionType?: CompressionType;
+  compressionLevel?: number; // gzip 1-9, zstd 1-22
   encryptionEnabled: boolean;
+  encryptionKeySource?: EncryptionKeySource;
+  passphrase?: string; // masked in responses
+  walArchiving?: boolean; // PostgreSQL WAL segments uploaded under wal/<job id>/
+  hooks?: BackupHooks;
   checksum?: string;
 }
 
+// Commands run in the application's pods around a backup
+export interface BackupHooks {
+  pre?: BackupHook[];
+  post?: BackupHook[];
+}
+
+export interface BackupHook {
+  name?: string;
+  namespace?: string; // defaults to the job's namespace
+  pod?: string;
+  selector?: string; // first running pod matching the label selector
+  container?: string;
+  command: string[];
+  timeoutSeconds?: number; // default 60, at most 3600
+  continueOnError?: boolean; // pre hooks only
+}
+
+export interface HookResult {
+  name: string;
+  phase: 'pre' | 'post';
+  namespace: string;
+  pod?: string;
+  container?: string;
+  command: string[];
+  startedAt: string;
+  durationMs: number;
+  exitCode?: number;
+  stdout?: string; // first 64 KiB
+  stderr?: string;
+  error?: string;
+}
+
 export enum CompressionType {
   NONE = 'none',
   GZIP = 'gzip',
@@ -76,6 +144,19 @@ export enum CompressionType {
   ZSTD = 'zstd'
 }
 
+export enum EncryptionKeySource {
+  CONFIG = 'config',
+  PASSPHRASE = 'passphrase'
+}
+
+export interface BackupEncryption {
+  algorithm: string;
+  keySource: EncryptionKeySource;
+  kdf?: string;
+  salt?: string;
+  keyId: string;
+}
+
 // Backup History
 export interface BackupHistory {
   id: string;
@@ -90,8 +171,12 @@ export interface BackupHistory {
   filesCount?: number;
   location: string;
   checksum: string;
+  compression?: CompressionType;
+  compressionLevel?: number;
+  encryption?: BackupEncryption;
   verificationStatus?: VerificationStatus;
   restorePoints?: RestorePoint[];
+  hooks?: HookResult[];
 }
 
 export enum VerificationStatus {
@@ -102,6 +187,46 @@ export enum VerificationStatus {
   CORRUPTED = 'corrupted'
 }
 
+// Restore Preview
+export interface RestorePreview {
+  backupId: string;
+  source: BackupSource;
+  timestamp: string;
+  location: string;
+  size: number; // uncompressed bytes
+  databases?: DatabasePreview[];
+  files?: FileNode;
+  truncated?: boolean;
+  snapshot?: Record<string, unknown>; // VolumeSnapshot of a snapshot backup
+  pointInTime?: RecoveryWindow;
+}
+
+export interface DatabasePreview {
+  name: string;
+  size: number;
+  tables: TablePreview[];
+}
+
+export interface TablePreview {
+  name: string;
+  rows: number;
+  size?: number;
+}
+
+export interface FileNode {
+  name: string;
+  size: number;
+  dir?: boolean;
+  children?: FileNode[];
+}
+
+export interface RecoveryWindow {
+  start: string;
+  end: string;
+  segments: number;
+  missingSegment?: string; // first WAL segment missing after the window
+}
+
 // Recovery Types
 export interface RestorePoint {
   id: string;
@@ -152,6 +277,7 @@ export interface RecoveryOptions {
   restoreToAlternateLocation?: string;
   selectedTables?: string[];
   selectedFiles?: string[];
+  passphrase?: string; // for backups encrypted with a passphrase
 }
 
 export interface RecoveryProgress {
@@ -204,6 +330,15 @@ export interface BackupStatistics {
   backupsBySource: Record<BackupSource, number>;
   dailyBackupTrend: BackupTrend[];
   storageUsageTrend: StorageTrend[];
+  pruning?: PruningStats;
+}
+
+export interface PruningStats {
+  lastRun?: string;
+  backupsPruned: number;
+  bytesFreed: number;
+  failures: number;
+  recentRuns: PruneRun[];
 }
 
 export interface BackupTrend {
@@ -1,5 +1,8 @@
 export enum DatabaseType {
   POSTGRESQL = 'postgresql',
+  MYSQL = 'mysql',
+  MONGODB = 'mongodb',
+  REDIS = 'redis',
   SQLITE = 'sqlite'
 }
 
@@ -20,6 +23,8 @@ export interface DatabaseConfig {
   username: string;
   password: string;
   filePath: string; // For SQLite
+  read_only?: boolean; // Session refuses writes; allows EXPLAIN ANALYZE
+  pool?: PoolConfig; // Unset values use the defaults
   status: DatabaseStatus;
   createdAt?: string;
   updatedAt?: string;
@@ -57,6 +62,131 @@ export interface ColumnInfo {
   comment?: string;
 }
 
+// Schema metadata, as sent by the API
+export interface IndexInfo {
+  name: string;
+  columns: string[];
+  unique: boolean;
+  primary: boolean;
+  partial: boolean;
+  method?: string;
+  definition?: string;
+}
+
+export interface ForeignKeyInfo {
+  name: string;
+  columns: string[];
+  referenced_schema?: string;
+  referenced_table: string;
+  referenced_columns: string[];
+  on_update: string;
+  on_delete: string;
+}
+
+export interface ConstraintInfo {
+  name?: string;
+  type: 'PRIMARY KEY' | 'UNIQUE' | 'FOREIGN KEY' | 'CHECK' | 'EXCLUDE';
+  columns?: string[];
+  definition?: string;
+}
+
+export interface TableSchema {
+  table: string;
+  schema?: string;
+  indexes: IndexInfo[];
+  foreign_keys: ForeignKeyInfo[];
+  constraints: ConstraintInfo[];
+}
+
+export interface Relationship {
+  name: string;
+  from_schema?: string;
+  from_table: string;
+  from_columns: string[];
+  to_schema?: string;
+  to_table: string;
+  to_columns: string[];
+  cardinality: 'one-to-one' | 'many-to-one';
+  on_delete: string;
+  on_update: string;
+}
+
+export interface ExplainRequest {
+  sql: string;
+  analyze?: boolean; // Read-only connections only
+}
+
+export interface PlanNode {
+  node_type: string;
+  relation?: string;
+  index?: string;
+  detail?: string;
+  startup_cost?: number;
+  total_cost?: number;
+  plan_rows?: number;
+  plan_width?: number;
+  actual_startup_time?: number; // ms, ANALYZE only
+  actual_total_time?: number;
+  actual_rows?: number;
+  actual_loops?: number;
+  filter?: string;
+  properties?: Record<string, unknown>;
+  children?: PlanNode[];
+}
+
+export interface QueryPlan {
+  plan: PlanNode;
+  analyzed: boolean;
+  planning_time?: number; // ms
+  execution_time?: number;
+  duration: number;
+}
+
+export interface SlowQuery {
+  query_id?: string;
+  query: string;
+  calls: number;
+  total_time: number; // ms
+  mean_time: number;
+  max_time: number;
+  rows: number;
+}
+
+export type RedisKeyType = 'string' | 'hash' | 'list' | 'set' | 'zset' | 'stream';
+
+export interface KeyInfo {
+  key: string;
+  type: RedisKeyType;
+  ttl: number; // seconds, -1 without expiry
+  size: number; // bytes of a string, elements of a collection
+}
+
+export interface KeyScanResult {
+  keys: KeyInfo[];
+  cursor: number; // 0 once the scan is complete
+}
+
+export interface ScoredMember {
+  member: string;
+  score: number;
+}
+
+export interface KeyValue extends KeyInfo {
+  value: string | Record<string, string> | string[] | ScoredMember[] | null;
+  truncated?: boolean;
+}
+
+export interface SchemaDiagram {
+  database: string;
+  tables: {
+    name: string;
+    schema?: string;
+    type: string;
+    columns: ColumnInfo[];
+  }[];
+  relationships: Relationship[];
+}
+
 export interface QueryRequest {
   sql: string;
   limit?: number;
@@ -120,6 +250,34 @@ export interface DatabaseStats {
   connections: ConnectionStats;
   storage: StorageStats;
   performance: PerformanceStats;
+  pool?: PoolStats;
+  health?: ConnectionHealth;
+}
+
+export interface PoolConfig {
+  max_open?: number; // default 10
+  max_idle?: number; // default 5
+  idle_timeout?: number; // seconds, default 300
+  max_lifetime?: number; // seconds, default 3600
+}
+
+export interface PoolStats {
+  max_open: number;
+  open: number;
+  in_use: number;
+  idle: number;
+  wait_count: number;
+  wait_duration: number; // ms
+  idle_closed: number;
+  lifetime_closed: number;
+}
+
+export interface ConnectionHealth {
+  last_ping?: string;
+  latency: number; // ms
+  failures: number; // consecutive
+  last_error?: string;
+  next_reconnect?: string;
 }
 
 export interface TestConnectionResult {
@@ -136,4 +294,32 @@ export interface SavedQuery {
   connectionId?: string;
   createdAt: string;
   updatedAt: string;
-}
\ No newline at end of file
+}
+export type QueryJobRunStatus = 'running' | 'succeeded' | 'failed';
+
+export interface QueryJobRun {
+  id: string;
+  job_id: string;
+  status: QueryJobRunStatus;
+  scheduled: boolean;
+  row_count: number;
+  duration: number; // ms
+  error?: string;
+  acknowledged: boolean;
+  started_at: string;
+  finished_at?: string;
+}
+
+export interface QueryJob {
+  id: string;
+  name: string;
+  saved_query_id: string;
+  connection_id?: string;
+  cron: string;
+  timezone?: string;
+  enabled: boolean;
+  next_run?: string;
+  last_run?: QueryJobRun;
+  created_at: string;
+  updated_at: string;
+}
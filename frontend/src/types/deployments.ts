@@ -29,6 +29,55 @@ export interface ContainerImage {
   fullName: string;
 }
 
+export interface DeploymentVolume {
+  name: string;
+  type: 'empty_dir' | 'config_map' | 'secret' | 'pvc';
+  source?: string;
+  mount_path: string;
+  read_only?: boolean;
+  size?: string;
+  storage_class?: string;
+}
+
+export interface DeploymentProbe {
+  http_get?: { path: string; port: number; scheme?: 'HTTP' | 'HTTPS' };
+  tcp_socket?: { port: number };
+  exec?: string[];
+  initial_delay_seconds?: number;
+  period_seconds?: number;
+  timeout_seconds?: number;
+  success_threshold?: number;
+  failure_threshold?: number;
+}
+
+export interface DeploymentProbes {
+  liveness?: DeploymentProbe;
+  readiness?: DeploymentProbe;
+  startup?: DeploymentProbe;
+}
+
+export interface DeploymentIngress {
+  host: string;
+  path?: string;
+  tls_secret?: string;
+  issuer?: string;
+  issuer_kind?: 'ClusterIssuer' | 'Issuer';
+  separate_file?: boolean;
+}
+
+export interface DeploymentContainer {
+  name: string;
+  image: string;
+  command?: string[];
+  args?: string[];
+  environment?: Record<string, string>;
+  resources?: {
+    limits?: { cpu?: string; memory?: string; };
+    requests?: { cpu?: string; memory?: string; };
+  };
+  volumes?: string[];
+}
+
 export interface DeploymentRequest {
   name: string;
   namespace: string;
@@ -52,6 +101,11 @@ export interface DeploymentRequest {
   labels?: Record<string, string>;
   annotations?: Record<string, string>;
   environment?: Record<string, string>;
+  volumes?: DeploymentVolume[];
+  init_containers?: DeploymentContainer[];
+  sidecars?: DeploymentContainer[];
+  probes?: DeploymentProbes;
+  ingress?: DeploymentIngress;
   service_type?: string;
 }
 
@@ -73,7 +127,7 @@ export interface Deployment {
     nodeSpread: boolean;
     zoneSpread: boolean;
   };
-  status: DeploymentStatus | 'pending_apply' | 'applying' | 'apply_failed';
+  status: DeploymentStatus | 'pending_apply' | 'applying' | 'apply_failed' | 'rejected';
   pods: PodInfo[];
   nodeDistribution: Record<string, number>;
   resources?: {
@@ -81,6 +135,11 @@ export interface Deployment {
     requests?: { cpu?: string; memory?: string; };
   };
   environment?: Record<string, string>;
+  volumes?: DeploymentVolume[];
+  init_containers?: DeploymentContainer[];
+  sidecars?: DeploymentContainer[];
+  probes?: DeploymentProbes;
+  ingress?: DeploymentIngress;
   // GitOps fields
   source?: string;
   author?: string;
@@ -89,10 +148,89 @@ export interface Deployment {
   applied_by?: string;
   applied_at?: string;
   service_type?: string;
+  // Policy warnings on the committed manifest, only in the create response
+  policy_violations?: PolicyViolation[];
   createdAt: string;
   updatedAt: string;
 }
 
+export type PolicyRule = 'latest-tag' | 'resource-limits' | 'run-as-non-root' | 'host-path' | 'rego';
+
+export interface PolicyViolation {
+  rule: PolicyRule;
+  severity: 'warn' | 'block';
+  kind: string;
+  name: string;
+  container?: string;
+  message: string;
+}
+
+export interface ManifestLintResult {
+  violations: PolicyViolation[];
+  blocked: boolean;
+}
+
+export interface PolicyDecision {
+  id: number;
+  timestamp: string;
+  source: 'lint' | 'gitops' | 'apply';
+  user?: string;
+  kind: string;
+  namespace?: string;
+  name: string;
+  allowed: boolean;
+  denies: string[];
+  warnings: string[];
+  error?: string;
+  duration_ms: number;
+}
+
+export interface SBOMPackage {
+  name: string;
+  version: string;
+  type: string;
+  purl?: string;
+  licenses: string[];
+}
+
+export interface ImageSBOM {
+  id: number;
+  image: string;
+  digest?: string;
+  generated_at: string;
+  package_count: number;
+  packages?: SBOMPackage[];
+  pods?: string[];
+}
+
+export interface SBOMPackageMatch {
+  sbom_id: number;
+  image: string;
+  digest?: string;
+  package: SBOMPackage;
+  pods?: string[];
+}
+
+export interface LicenseSummary {
+  license: string;
+  packages: number;
+  images: string[];
+}
+
+export interface SBOMScanStatus {
+  status: 'running' | 'completed' | 'failed';
+  image?: string;
+  started_at: string;
+  finished_at?: string;
+  result?: {
+    generated: number;
+    skipped: number;
+    pruned: number;
+    failed: string[];
+  };
+  error?: string;
+}
+
 export interface PodInfo {
   name: string;
   phase: string;
@@ -132,4 +270,63 @@ export interface DeploymentHistory {
   user?: string;
   timestamp: string;
   metadata?: Record<string, any>;
-}
\ No newline at end of file
+}
+
+// Commit, pull request and CI run behind a deployment's image (GET /api/deployments/{id}/provenance)
+export interface DeploymentProvenance {
+  deployment_id: string;
+  image: string;
+  manifest_commit?: string;
+  owner?: string;
+  repo?: string;
+  source?: 'build' | 'image_label' | 'tag';
+  build_id?: string;
+  commit?: {
+    sha: string;
+    message: string;
+    html_url?: string;
+    author: { name: string; email: string; date: string };
+    user?: { id: number; login: string };
+  };
+  pull_request?: {
+    number: number;
+    title: string;
+    state: string;
+    html_url?: string;
+    user: { id: number; login: string };
+    merged_at?: string;
+  };
+  run?: {
+    id: number;
+    run_number: number;
+    event: string;
+    path: string;
+    head_sha: string;
+    head_branch: string;
+    status: string;
+    conclusion?: string;
+    html_url?: string;
+    started_at?: string;
+    completed_at?: string;
+  };
+  warnings: string[];
+}
+
+// Release notes attached to a GitOps deployment record that changed the image
+export interface DeploymentChangelog {
+  previous_image: string;
+  repository?: string;
+  from?: string;
+  to?: string;
+  compare_url?: string;
+  commits: Array<{
+    sha: string;
+    message: string;
+    author: string;
+    date: string;
+    url?: string;
+  }>;
+  total_commits: number;
+  error?: string;
+  generated_at: string;
+}
@@ -0,0 +1,46 @@
+export type IncidentStatus = 'investigating' | 'identified' | 'monitoring' | 'resolved';
+
+export type IncidentImpact = 'none' | 'minor' | 'major' | 'critical';
+
+export interface IncidentUpdate {
+  id: string;
+  status: IncidentStatus;
+  message: string;
+  created_by?: string;
+  created_at: string;
+}
+
+export interface IncidentAlert {
+  dedup_key: string;
+  source: string;
+  type: string;
+  severity: string;
+  title: string;
+  first_seen: string;
+  linked_by?: string;
+  linked_at: string;
+}
+
+export interface Incident {
+  id: string;
+  title: string;
+  status: IncidentStatus;
+  impact: IncidentImpact;
+  summary?: string;
+  services: string[]; // namespace/name
+  deployments: string[]; // namespace/name
+  alerts: IncidentAlert[];
+  updates: IncidentUpdate[]; // newest first
+  created_by?: string;
+  created_at: string;
+  updated_at: string;
+  resolved_at?: string;
+}
+
+export interface StatusPage {
+  status: 'operational' | 'degraded' | 'partial_outage' | 'major_outage';
+  active_incidents: Incident[];
+  recently_resolved: Incident[]; // within the last 7 days
+  affected_services: string[];
+  generated_at: string;
+}
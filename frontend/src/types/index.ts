@@ -7,6 +7,11 @@ export * from './database';
 export * from './deployments';
 export * from './serviceMesh';
 export * from './common';
+export * from './incidents';
+export * from './preferences';
+export * from './podSecurity';
+export * from './upgrades';
+export * from './webhooks';
 // Export specific types from mockData to avoid conflicts
 export type { 
   MasterNamespace, 
@@ -53,4 +53,25 @@ export interface TerminalFilter {
   source?: string;
   search?: string;
   maxLines?: number;
-}
\ No newline at end of file
+}
+export type TerminalShareMode = 'read-only' | 'handoff';
+
+// The link of a shared exec session; joining needs both the ID and the token
+export interface TerminalShareLink {
+  id: string;
+  token: string;
+  mode: TerminalShareMode;
+}
+
+export interface TerminalParticipant {
+  user: string;
+  role: 'owner' | 'viewer';
+  canWrite: boolean;
+}
+
+export interface TerminalPresence {
+  mode: TerminalShareMode | '';
+  owner: string;
+  writer: string;
+  participants: TerminalParticipant[];
+}
@@ -37,6 +37,73 @@ export interface NodeMetrics {
   allocatable_cpu?: string;
   allocatable_memory?: string;
   last_updated?: string;
+  system?: NodeSystemMetrics;
+}
+
+// Scraped from node_exporter or cAdvisor when Prometheus is absent
+export interface NodeSystemMetrics {
+  source: 'node-exporter' | 'cadvisor';
+  cpu_percent: number;
+  cpu_cores: number;
+  memory_used_bytes: number;
+  memory_total_bytes: number;
+  memory_percent: number;
+  disk_read_bytes_per_sec: number;
+  disk_write_bytes_per_sec: number;
+  network_receive_bytes_per_sec: number;
+  network_transmit_bytes_per_sec: number;
+  window_seconds: number;
+  timestamp: string;
+}
+
+// The host Denshimon runs on, read from /proc or a local node_exporter
+export interface HostMetrics extends Omit<NodeSystemMetrics, 'source'> {
+  source: 'procfs' | 'node-exporter';
+  hostname: string;
+  cpu_count: number;
+  load1: number;
+  load5: number;
+  load15: number;
+  swap_used_bytes: number;
+  swap_total_bytes: number;
+  uptime_seconds: number;
+  filesystems: HostFilesystem[];
+}
+
+export interface HostFilesystem {
+  mountpoint: string;
+  device: string;
+  type: string;
+  used_bytes: number;
+  total_bytes: number;
+  percent: number;
+}
+
+// SMART health of a node disk, read by the smartctl DaemonSet
+export interface NodeDisk {
+  node: string;
+  device: string;
+  protocol: string;
+  model: string;
+  serial: string;
+  capacity_bytes: number;
+  passed: boolean;
+  temperature_celsius: number;
+  power_on_hours: number;
+  reallocated_sectors: number;
+  pending_sectors: number;
+  media_errors: number;
+  wear_percent?: number;
+  status: 'ok' | 'warning' | 'critical';
+  problems?: string[];
+  checked_at: string;
+}
+
+export interface NodeDiskReport {
+  checked_at: string;
+  disks: NodeDisk[];
+  counts: Record<string, number>;
+  errors?: Record<string, string>;
 }
 
 export interface PodMetrics {
@@ -51,12 +118,23 @@ export interface PodMetrics {
   last_updated: string;
 }
 
+export interface QuotaPressure {
+  namespace: string;
+  quota: string;
+  resource: string;
+  used: string;
+  hard: string;
+  usagePercent: number;
+  severity: 'warning' | 'critical';
+}
+
 export interface NamespaceMetrics {
   name: string;
   pod_count: number;
   cpu_usage: ResourceUsage;
   memory_usage: ResourceUsage;
   last_updated: string;
+  quota_warnings?: QuotaPressure[];
 }
 
 export interface MetricPoint {
@@ -0,0 +1,33 @@
+export type PodSecurityLevel = 'privileged' | 'baseline' | 'restricted';
+
+export interface PodSecurityFinding {
+  check: string;
+  level: 'baseline' | 'restricted' | 'best-practice';
+  container?: string;
+  message: string;
+  remediation: string;
+}
+
+export interface WorkloadSecurity {
+  kind: string;
+  namespace: string;
+  name: string;
+  level: PodSecurityLevel;
+  score: number;
+  findings: PodSecurityFinding[];
+}
+
+export interface NamespaceSecurity {
+  namespace: string;
+  enforced?: PodSecurityLevel;
+  score: number;
+  counts: Record<PodSecurityLevel, number>;
+  workloads: WorkloadSecurity[];
+}
+
+export interface PodSecurityReport {
+  generatedAt: string;
+  score: number;
+  counts: Record<PodSecurityLevel, number>;
+  namespaces: NamespaceSecurity[];
+}
@@ -0,0 +1,26 @@
+export interface FavoriteResource {
+  kind: string;
+  namespace?: string;
+  name: string;
+}
+
+export interface TableLayout {
+  columns: string[];
+  sortBy?: string;
+  sortDirection?: 'asc' | 'desc';
+  pageSize?: number;
+}
+
+export interface UserPreferences {
+  defaultNamespace: string;
+  favorites: FavoriteResource[];
+  pinnedDashboards: string[];
+  dashboard: {
+    sections: Record<string, boolean>;
+    tabs: Record<string, boolean>;
+  };
+  tables: Record<string, TableLayout>;
+  // Seconds between refreshes by view; 0 turns refreshing off
+  refreshIntervals: Record<string, number>;
+  updatedAt?: string;
+}
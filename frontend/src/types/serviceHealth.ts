@@ -22,6 +22,46 @@ export enum ServiceType {
   POSTGRESQL = 'postgresql'
 }
 
+// DiscoveredService is a service found via the denshimon.io/monitor annotation or an override
+export interface DiscoveredService {
+  id: string; // namespace/name
+  name: string;
+  type: string;
+  status: Status;
+  lastChecked: string;
+  url?: string;
+  namespace: string;
+  service: string;
+  source: 'annotation' | 'override';
+  pods: number;
+  readyPods: number;
+  nodeName?: string;
+  podIP?: string;
+  startTime?: string;
+  healthCheck?: ServiceHealthCheck;
+}
+
+export interface ServiceHealthCheck {
+  url: string;
+  statusCode?: number;
+  latencyMs: number;
+  healthy: boolean;
+  error?: string;
+}
+
+// ServiceOverride takes precedence over a service's denshimon.io/* annotations
+export interface ServiceOverride {
+  namespace: string;
+  name: string;
+  monitor?: boolean; // unset follows the annotation
+  displayName?: string;
+  type?: string;
+  healthPath?: string;
+  healthPort?: number;
+  url?: string;
+  updatedAt: string;
+}
+
 // ServiceStatus is now deprecated - use Status enum from constants instead
 
 export interface ServiceMetrics {
@@ -174,16 +214,45 @@ export interface InfrastructureStatus {
   lastChecked: string;
 }
 
+// DomainAccessibilityCheck is a synthetic check of a discovered service's URL
 export interface DomainAccessibilityCheck {
+  id?: string; // namespace/name of the service
+  name?: string;
   domain: string;
+  url?: string;
+  finalUrl?: string;
+  status?: Status;
   accessible: boolean;
   responseTime?: number; // ms
   httpStatus?: number;
+  redirects?: SyntheticRedirect[];
   sslValid: boolean;
+  tls?: SyntheticTLSDetails;
+  keywordFound?: boolean;
+  failures?: string[];
   lastChecked: string;
   error?: string;
 }
 
+export interface SyntheticRedirect {
+  url: string;
+  statusCode: number;
+  location: string;
+}
+
+export interface SyntheticTLSDetails {
+  version: string;
+  cipherSuite: string;
+  subject: string;
+  issuer: string;
+  dnsNames?: string[];
+  notBefore: string;
+  notAfter: string;
+  daysRemaining: number;
+  verified: boolean;
+  error?: string;
+}
+
 export interface IngressRuleStatus {
   name: string;
   namespace: string;
@@ -104,6 +104,7 @@ export interface ServiceMeshMetrics {
     highErrorRate: ServiceNode[];
     highLatency: ServiceNode[];
     securityIssues: ServiceConnection[];
+    unprotectedServices?: ServiceNode[];
   };
   trends: {
     requestRateTrend: 'increasing' | 'decreasing' | 'stable';
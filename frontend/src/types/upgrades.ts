@@ -0,0 +1,47 @@
+// Running version of Kubernetes or an addon against its latest release
+export interface UpgradeComponent {
+  name: string;
+  version: string;
+  latest?: string;
+  update_available: boolean;
+  workload?: string;
+  image?: string;
+  error?: string;
+}
+
+export type DeprecatedAPIStatus = 'removed' | 'breaks' | 'deprecated';
+
+export interface DeprecatedAPI {
+  source: 'manifest' | 'live';
+  file?: string;
+  manager?: string;
+  api_version: string;
+  kind: string;
+  namespace?: string;
+  name: string;
+  removed_in: string;
+  replacement: string;
+  status: DeprecatedAPIStatus;
+}
+
+export interface UpgradeReport {
+  kubernetes: UpgradeComponent;
+  addons: UpgradeComponent[];
+  target: string;
+  deprecated_apis: DeprecatedAPI[];
+  upgrade_blocked: boolean;
+  warnings: string[];
+  errors?: Record<string, string>;
+  checked_at: string;
+}
+
+export interface DeprecationScan {
+  kubernetes: string;
+  target: string;
+  apis: DeprecatedAPI[];
+  counts: Partial<Record<DeprecatedAPIStatus, number>>;
+  upgrade_blocked: boolean;
+  warnings: string[];
+  errors?: Record<string, string>;
+  checked_at: string;
+}
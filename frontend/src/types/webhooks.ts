@@ -0,0 +1,37 @@
+export type WebhookEvent =
+  | 'deployment.applied'
+  | 'deployment.rolled_back'
+  | 'alert.fired'
+  | 'alert.resolved'
+  | 'backup.finished'
+  | 'certificate.expiring';
+
+export type WebhookDeliveryStatus = 'pending' | 'delivered' | 'failed';
+
+export interface Webhook {
+  id: string;
+  name: string;
+  url: string;
+  secret?: string; // write-only; never returned
+  has_secret: boolean;
+  events: WebhookEvent[]; // empty subscribes to all
+  disabled: boolean;
+  created_by?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface WebhookDelivery {
+  id: string;
+  webhook_id: string;
+  event: WebhookEvent | 'webhook.test';
+  payload: unknown; // the request body: id, type, timestamp and data
+  status: WebhookDeliveryStatus;
+  attempts: number;
+  status_code?: number;
+  error?: string;
+  duration_ms: number;
+  created_at: string;
+  last_attempt_at?: string;
+  next_attempt_at?: string; // while pending
+}
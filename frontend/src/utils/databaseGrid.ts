@@ -25,6 +25,12 @@ export const getDatabaseTypeIcon = (type: DatabaseType): string => {
   switch (type) {
     case DatabaseType.POSTGRESQL:
       return '🐘'; // PostgreSQL elephant
+    case DatabaseType.MYSQL:
+      return '🐬'; // MySQL dolphin
+    case DatabaseType.MONGODB:
+      return '🍃'; // MongoDB leaf
+    case DatabaseType.REDIS:
+      return '🟥'; // Redis cube
     case DatabaseType.SQLITE:
       return '📁'; // SQLite file
     default:
@@ -39,6 +45,12 @@ export const getDatabaseTypeLabel = (type: DatabaseType): string => {
   switch (type) {
     case DatabaseType.POSTGRESQL:
       return 'PostgreSQL';
+    case DatabaseType.MYSQL:
+      return 'MySQL / MariaDB';
+    case DatabaseType.MONGODB:
+      return 'MongoDB';
+    case DatabaseType.REDIS:
+      return 'Redis';
     case DatabaseType.SQLITE:
       return 'SQLite';
     default:
@@ -82,7 +94,7 @@ export const validateDatabaseConnection = (
     errors.push('Port must be between 1 and 65535');
   }
 
-  if (!database.trim()) {
+  if (!database.trim() && type !== DatabaseType.MONGODB && type !== DatabaseType.REDIS) {
     errors.push('Database name is required');
   }
 
@@ -108,6 +120,12 @@ export const getDefaultPortForDatabaseType = (type: DatabaseType): number => {
   switch (type) {
     case DatabaseType.POSTGRESQL:
       return 5432;
+    case DatabaseType.MYSQL:
+      return 3306;
+    case DatabaseType.MONGODB:
+      return 27017;
+    case DatabaseType.REDIS:
+      return 6379;
     case DatabaseType.SQLITE:
       return 0; // SQLite doesn't use ports
     default:
@@ -296,6 +296,8 @@ export const getDeploymentStatusColor = (status: DeploymentStatus): string => {
       return 'text-red-500';
     case DeploymentStatus.APPLY_FAILED:
       return 'text-red-600';       // GitOps: apply failed
+    case DeploymentStatus.REJECTED:
+      return 'text-orange-500';    // GitOps: pending change rejected
     case DeploymentStatus.TERMINATING:
       return 'text-orange-500';
     default: